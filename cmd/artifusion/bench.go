@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/bench"
+)
+
+// listFlag collects a repeatable or comma-separated flag
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// runBench implements `artifusion bench`: it sends a mixed workload to a running
+// instance and prints a latency report. It returns the exit code.
+func runBench(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "Usage: artifusion bench --image repo:tag --package name [flags]")
+		_, _ = fmt.Fprintln(stderr)
+		_, _ = fmt.Fprintln(stderr, "Sends a weighted mix of manifest pulls, blob streams, packument and tarball")
+		_, _ = fmt.Fprintln(stderr, "fetches to a running instance and reports latencies per request kind.")
+		_, _ = fmt.Fprintln(stderr)
+		fs.PrintDefaults()
+	}
+
+	var images, packages listFlag
+	fs.Var(&images, "image", "OCI image to pull as repository:tag (repeatable)")
+	fs.Var(&packages, "package", "npm package to fetch (repeatable)")
+	ociTarget := fs.String("target", "http://localhost:8080", "Base URL of the OCI protocol on the instance")
+	npmTarget := fs.String("npm-target", "", "Base URL of the npm protocol on the instance (default <target>/npm)")
+	mix := fs.String("mix", "", "Request kind weights, e.g. manifest=5,blob=3,packument=4,tarball=2 (default that mix)")
+	concurrency := fs.Int("concurrency", 10, "Requests in flight")
+	duration := fs.Duration("duration", 30*time.Second, "Length of the run (0 = until --requests are sent)")
+	requests := fs.Int("requests", 0, "Number of requests to send (0 = until --duration expires)")
+	rps := fs.Float64("rate", 0, "Maximum requests per second (0 = unlimited)")
+	username := fs.String("username", "bench", "Username for basic auth")
+	token := fs.String("token", os.Getenv("ARTIFUSION_TOKEN"), "GitHub token for basic auth (default $ARTIFUSION_TOKEN)")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if len(images) == 0 && len(packages) == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	opts := bench.Options{
+		OCITarget:   *ociTarget,
+		NPMTarget:   *npmTarget,
+		Images:      images,
+		Packages:    packages,
+		Concurrency: *concurrency,
+		Duration:    *duration,
+		Requests:    *requests,
+		Rate:        *rps,
+		Username:    *username,
+		Token:       *token,
+	}
+	if opts.NPMTarget == "" {
+		opts.NPMTarget = strings.TrimSuffix(*ociTarget, "/") + "/npm"
+	}
+	if *mix != "" {
+		weights, err := bench.ParseMix(*mix)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, err)
+			return 2
+		}
+		opts.Mix = weights
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	_, _ = fmt.Fprintf(stdout, "Running %d concurrent requests against %s...\n\n", opts.Concurrency, *ociTarget)
+	report, err := bench.Run(ctx, opts)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if err := report.Write(stdout); err != nil {
+		return 1
+	}
+	return 0
+}
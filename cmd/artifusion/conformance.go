@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/conformance"
+)
+
+// runConformance implements `artifusion conformance`: it runs a protocol's
+// conformance suite against a running instance and returns the exit code
+func runConformance(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("conformance", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(stderr, "Usage: artifusion conformance --protocol %s [flags]\n\n", strings.Join(conformance.Protocols(), "|"))
+		_, _ = fmt.Fprintln(stderr, "Runs protocol-level requests against a running instance to verify it before rollout.")
+		_, _ = fmt.Fprintln(stderr, "The suite pushes test artifacts: point the instance's backends at --fake-backend.")
+		_, _ = fmt.Fprintln(stderr)
+		fs.PrintDefaults()
+	}
+
+	protocol := fs.String("protocol", "", "Protocol to test: "+strings.Join(conformance.Protocols(), ", "))
+	target := fs.String("target", "http://localhost:8080", "Base URL of the protocol on the instance, e.g. http://localhost:8080/npm")
+	username := fs.String("username", "conformance", "Username for basic auth")
+	token := fs.String("token", os.Getenv("ARTIFUSION_TOKEN"), "GitHub token for basic auth (default $ARTIFUSION_TOKEN)")
+	fakeBackend := fs.String("fake-backend", "", "Serve an in-memory backend for the protocol on this address (e.g. :5001) while the suite runs")
+	timeout := fs.Duration("timeout", 5*time.Minute, "Timeout of the whole suite")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *protocol == "" {
+		fs.Usage()
+		return 2
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	ctx, cancelTimeout := context.WithTimeout(ctx, *timeout)
+	defer cancelTimeout()
+
+	if *fakeBackend != "" {
+		stop, err := serveFakeBackend(*protocol, *fakeBackend)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "Failed to start fake backend: %v\n", err)
+			return 1
+		}
+		defer stop()
+		_, _ = fmt.Fprintf(stdout, "Fake %s backend listening on %s\n", *protocol, *fakeBackend)
+	}
+
+	results, err := conformance.Run(ctx, *protocol, conformance.Options{
+		Target:   *target,
+		Username: *username,
+		Token:    *token,
+	})
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	passed, failed, skipped := 0, 0, 0
+	for _, result := range results {
+		switch {
+		case result.Skipped:
+			skipped++
+			_, _ = fmt.Fprintf(stdout, "SKIP  %s\n", result.Name)
+		case result.Err != nil:
+			failed++
+			_, _ = fmt.Fprintf(stdout, "FAIL  %s (%s)\n      %v\n", result.Name, result.Duration.Round(time.Millisecond), result.Err)
+		default:
+			passed++
+			_, _ = fmt.Fprintf(stdout, "PASS  %s (%s)\n", result.Name, result.Duration.Round(time.Millisecond))
+		}
+	}
+	_, _ = fmt.Fprintf(stdout, "\n%s conformance: %d passed, %d failed, %d skipped\n", *protocol, passed, failed, skipped)
+
+	if !conformance.Passed(results) {
+		return 1
+	}
+	return 0
+}
+
+// serveFakeBackend serves the in-memory backend of protocol on addr until the
+// returned function is called
+func serveFakeBackend(protocol, addr string) (func(), error) {
+	backend, err := conformance.NewFakeBackend(protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{
+		Handler:           backend,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() { _ = server.Serve(listener) }()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}, nil
+}
@@ -2,29 +2,60 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mainuli/artifusion/internal/api"
+	"github.com/mainuli/artifusion/internal/audit"
 	"github.com/mainuli/artifusion/internal/auth"
 	"github.com/mainuli/artifusion/internal/config"
 	"github.com/mainuli/artifusion/internal/constants"
 	"github.com/mainuli/artifusion/internal/detector"
 	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/featureflags"
+	"github.com/mainuli/artifusion/internal/forwardproxy"
+	"github.com/mainuli/artifusion/internal/handler"
+	"github.com/mainuli/artifusion/internal/handler/apk"
+	"github.com/mainuli/artifusion/internal/handler/apt"
+	"github.com/mainuli/artifusion/internal/handler/cocoapods"
+	"github.com/mainuli/artifusion/internal/handler/composer"
+	"github.com/mainuli/artifusion/internal/handler/conda"
+	"github.com/mainuli/artifusion/internal/handler/helm"
+	"github.com/mainuli/artifusion/internal/handler/hex"
+	"github.com/mainuli/artifusion/internal/handler/lfs"
 	"github.com/mainuli/artifusion/internal/handler/maven"
 	"github.com/mainuli/artifusion/internal/handler/npm"
 	"github.com/mainuli/artifusion/internal/handler/oci"
+	"github.com/mainuli/artifusion/internal/handler/pub"
+	"github.com/mainuli/artifusion/internal/handler/raw"
+	"github.com/mainuli/artifusion/internal/handler/rubygems"
+	"github.com/mainuli/artifusion/internal/handler/terraform"
+	"github.com/mainuli/artifusion/internal/handler/vagrant"
+	"github.com/mainuli/artifusion/internal/handler/webui"
 	"github.com/mainuli/artifusion/internal/health"
 	"github.com/mainuli/artifusion/internal/logging"
+	"github.com/mainuli/artifusion/internal/metadata"
 	"github.com/mainuli/artifusion/internal/metrics"
 	"github.com/mainuli/artifusion/internal/middleware"
 	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/replication"
+	"github.com/mainuli/artifusion/internal/synthetic"
+	"github.com/mainuli/artifusion/internal/trash"
+	"github.com/mainuli/artifusion/internal/utils"
+	"github.com/mainuli/artifusion/internal/vault"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 // Build-time version information
@@ -38,6 +69,16 @@ var (
 )
 
 func main() {
+	// Subcommands; without one, run the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "conformance":
+			os.Exit(runConformance(os.Args[2:], os.Stdout, os.Stderr))
+		case "bench":
+			os.Exit(runBench(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
 	// Setup initial logging for early startup (before config is loaded)
 	// This ensures config loading/validation logs are also formatted nicely
 	initialFormat := getEnvOrDefault("ARTIFUSION_LOGGING_FORMAT", "console")
@@ -88,6 +129,11 @@ func main() {
 
 	// Create metrics collector
 	metricsCollector := metrics.NewMetrics("artifusion") // Initialize metrics (automatically registered with Prometheus)
+	metricsCollector.SetBuildInfo(version, gitCommit)
+	features := cfg.Features()
+	for feature, enabled := range features {
+		metricsCollector.SetFeatureEnabled(feature, enabled)
+	}
 
 	// Create circuit breaker manager with logger and metrics
 	circuitBreakerManager := proxy.NewCircuitBreakerManager(logger, metricsCollector)
@@ -101,6 +147,37 @@ func main() {
 		cfg.GitHub.RateLimitBuffer,
 		logger,
 	)
+	githubClient.SetRoutingTeams(cfg.RoutingTeams())
+	githubClient.SetMetrics(metricsCollector)
+	githubClient.SetAuthTimeout(cfg.GitHub.AuthTimeout)
+	if cfg.GitHub.FailurePolicy == config.FailurePolicyOpen {
+		githubClient.SetFailOpen(cfg.GitHub.FailOpenGrace)
+		logger.Warn().
+			Dur("grace", cfg.GitHub.FailOpenGrace).
+			Msg("Auth fail-open enabled: recently valid tokens are accepted while GitHub is unavailable")
+	}
+	if cfg.GitHub.AuthCacheRefreshAhead > 0 {
+		githubClient.SetCacheRefreshAhead(cfg.GitHub.AuthCacheRefreshAhead)
+		logger.Info().
+			Dur("refresh_ahead", cfg.GitHub.AuthCacheRefreshAhead).
+			Msg("Auth cache background refresh enabled")
+	}
+	if cfg.GitHub.ValidationBudget > 0 {
+		githubClient.SetValidationBudget(auth.NewValidationBudget(cfg.GitHub.ValidationBudget, cfg.GitHub.ValidationBudgetWindow))
+		logger.Info().
+			Int("validation_budget", cfg.GitHub.ValidationBudget).
+			Dur("window", cfg.GitHub.ValidationBudgetWindow).
+			Msg("Per-client GitHub validation budget enabled")
+	}
+
+	// Feature flags for dark-launching risky subsystems (toggled at runtime by admins).
+	// The subsystems enabled by their own configuration consult theirs per request.
+	featureFlags := featureflags.New(featureflags.WithSubsystems(cfg.FeatureFlags))
+	if len(cfg.FeatureFlags) > 0 {
+		logger.Info().
+			Interface("feature_flags", cfg.FeatureFlags).
+			Msg("Feature flags configured")
+	}
 
 	// Create shared client authenticator
 	clientAuthenticator := auth.NewClientAuthenticator(
@@ -109,12 +186,214 @@ func main() {
 		cfg.GitHub.RequiredTeams,
 		logger,
 	)
+	auditor := audit.New(logger)
+	clientAuthenticator.SetAdmin(&cfg.Admin, auditor)
+
+	// Signed download URLs for sharing artifacts without GitHub access
+	var urlSigner *auth.URLSigner
+	if cfg.SignedURLs.Enabled {
+		urlSigner = auth.NewURLSigner(&cfg.SignedURLs)
+		urlSigner.SetFeatureFlags(featureFlags)
+		clientAuthenticator.SetURLSigner(urlSigner)
+		logger.Info().
+			Dur("max_ttl", cfg.SignedURLs.MaxTTL).
+			Msg("Signed download URLs enabled")
+	}
+
+	// Duplicate credential detection (leaked tokens reused from many source IPs)
+	if cfg.CredentialSharing.Enabled {
+		sharingDetector := auth.NewSharingDetector(&cfg.CredentialSharing, auditor, metricsCollector, logger)
+		sharingDetector.SetFeatureFlags(featureFlags)
+		clientAuthenticator.SetCredentialSharing(sharingDetector)
+		logger.Info().
+			Int("max_source_ips", cfg.CredentialSharing.MaxSourceIPs).
+			Dur("window", cfg.CredentialSharing.Window).
+			Bool("block", cfg.CredentialSharing.Block).
+			Bool("webhook", cfg.CredentialSharing.WebhookURL != "").
+			Msg("Credential sharing detection enabled")
+	}
+
+	// Brute-force lockout after repeated failed authentications
+	if cfg.AuthLockout.Enabled {
+		clientAuthenticator.SetLockout(auth.NewLockout(&cfg.AuthLockout, auditor, metricsCollector))
+		logger.Info().
+			Int("max_failures", cfg.AuthLockout.MaxFailures).
+			Dur("window", cfg.AuthLockout.Window).
+			Dur("duration", cfg.AuthLockout.Duration).
+			Dur("max_duration", cfg.AuthLockout.MaxDuration).
+			Msg("Auth lockout enabled")
+	}
+
+	// Identity providers besides GitHub (e.g. Okta), selectable globally and per protocol
+	for i := range cfg.Identity.OIDC {
+		oidcCfg := &cfg.Identity.OIDC[i]
+		clientAuthenticator.RegisterProvider(auth.NewOIDCAuthenticator(oidcCfg, logger))
+		logger.Info().
+			Str("name", oidcCfg.Name).
+			Str("issuer", oidcCfg.Issuer).
+			Strs("audiences", oidcCfg.Audiences).
+			Msg("OIDC identity provider enabled")
+	}
+	if cfg.GitLab.Enabled {
+		clientAuthenticator.RegisterProvider(auth.NewGitLabClient(&cfg.GitLab, githubClient.AuthCache(), logger))
+		logger.Info().
+			Str("api_url", cfg.GitLab.APIURL).
+			Str("required_group", cfg.GitLab.RequiredGroup).
+			Msg("GitLab identity provider enabled")
+	}
+	if cfg.LDAP.Enabled {
+		ldapAuthenticator, err := auth.NewLDAPAuthenticator(&cfg.LDAP, githubClient.AuthCache(), logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to create LDAP identity provider")
+		}
+		defer ldapAuthenticator.Close()
+		clientAuthenticator.RegisterProvider(ldapAuthenticator)
+		logger.Info().
+			Str("url", cfg.LDAP.URL).
+			Bool("start_tls", cfg.LDAP.StartTLS).
+			Strs("required_groups", cfg.LDAP.RequiredGroups).
+			Msg("LDAP identity provider enabled")
+	}
+	if cfg.ServiceAccounts.Enabled {
+		serviceAccounts, err := auth.NewServiceAccountAuthenticator(&cfg.ServiceAccounts, cfg.GitHub.RequiredOrg)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to load service accounts")
+		}
+		clientAuthenticator.RegisterProvider(serviceAccounts)
+		logger.Info().
+			Str("file", cfg.ServiceAccounts.File).
+			Int("inline_accounts", len(cfg.ServiceAccounts.Accounts)).
+			Msg("Service account identity provider enabled")
+	}
+	// TLS client certificates, verified by the server's TLS handshake
+	var serverTLSConfig *tls.Config
+	if cfg.MTLS.Enabled {
+		certificates, err := auth.NewCertificateAuthenticator(&cfg.MTLS)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to load client certificate CAs")
+		}
+		clientAuthenticator.SetClientCertificates(certificates)
+		serverTLSConfig = certificates.ServerTLSConfig()
+		logger.Info().
+			Str("ca_file", cfg.MTLS.CAFile).
+			Bool("required", cfg.MTLS.Required).
+			Str("username_from", cfg.MTLS.UsernameFrom).
+			Strs("allowed_ous", cfg.MTLS.AllowedOUs).
+			Msg("Client certificate authentication enabled")
+	}
+	if err := clientAuthenticator.SetProviders(cfg.Identity.Providers); err != nil {
+		logger.Fatal().Err(err).Msg("Invalid identity providers")
+	}
+
+	// protocolAuthenticator returns the authenticator of a protocol, which may select
+	// its own identity providers with client_auth.providers
+	protocolAuthenticator := func(protocol detector.Protocol, clientAuth *config.ClientAuthConfig) *auth.ClientAuthenticator {
+		authenticator, err := clientAuthenticator.WithProviders(clientAuth.Providers)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Invalid client_auth providers")
+		}
+		return authenticator.ForProtocol(string(protocol))
+	}
+
+	// Local answers to OPTIONS requests and CORS preflights of protocol paths
+	var optionsResponder *middleware.OptionsResponder
+	if cfg.OptionsRequests.AnswerLocally || cfg.OptionsRequests.CORS.Enabled() {
+		optionsResponder = middleware.NewOptionsResponder(&cfg.OptionsRequests)
+		logger.Info().
+			Bool("answer_locally", cfg.OptionsRequests.AnswerLocally).
+			Strs("cors_allowed_origins", cfg.OptionsRequests.CORS.AllowedOrigins).
+			Msg("Local OPTIONS handling enabled")
+	}
+
+	// Client classification by User-Agent for metrics, and deny rules per client type
+	clientPolicy := middleware.NewClientPolicy(&cfg.ClientPolicy, metricsCollector, logger)
+	if cfg.ClientPolicy.Enabled {
+		logger.Info().
+			Int("rules", len(cfg.ClientPolicy.Rules)).
+			Interface("min_versions", cfg.ClientPolicy.MinVersions).
+			Msg("Client policy enabled")
+	}
+
+	// Allow/deny rules on the file extensions and content types served per protocol
+	var contentPolicy *middleware.ContentPolicy
+	if cfg.ContentPolicy.Enabled {
+		contentPolicy = middleware.NewContentPolicy(&cfg.ContentPolicy, metricsCollector, logger)
+		contentPolicy.SetErrorHelp(cfg.EnabledClientAuth())
+		logger.Info().
+			Int("rules", len(cfg.ContentPolicy.Rules)).
+			Msg("Content policy enabled")
+	}
+
+	if cfg.Admin.Impersonation {
+		logger.Warn().Strs("admins", cfg.Admin.Users).Msg("Admin impersonation enabled")
+	}
 
 	// Create shared proxy client with circuit breaker support
-	proxyClient := proxy.NewClient(logger, circuitBreakerManager)
+	proxyClient := proxy.NewClient(logger, circuitBreakerManager, metricsCollector)
+
+	// Backend credentials given as Vault references, kept current at runtime
+	var vaultRefs []string
+	for _, backend := range backends(cfg) {
+		if authBackend, ok := backend.(interface{ GetAuth() *config.AuthConfig }); ok && authBackend.GetAuth() != nil {
+			vaultRefs = append(vaultRefs, vault.References(authBackend.GetAuth())...)
+		}
+	}
+	if cfg.Vault.Enabled {
+		vaultClient := vault.New(&cfg.Vault, logger)
+		resolveCtx, cancelResolve := context.WithTimeout(context.Background(), 30*time.Second)
+		err = vaultClient.Resolve(resolveCtx, vaultRefs)
+		cancelResolve()
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to resolve backend credentials from Vault")
+		}
+		vaultClient.Start()
+		defer vaultClient.Stop()
+		proxyClient.SetVault(vaultClient)
+
+		logger.Info().
+			Str("address", cfg.Vault.Address).
+			Int("references", len(vaultRefs)).
+			Dur("refresh_interval", cfg.Vault.RefreshInterval).
+			Msg("Vault backend credentials enabled")
+	} else if len(vaultRefs) > 0 {
+		logger.Fatal().Msg("Backend credentials reference Vault secrets, but vault is not enabled")
+	}
+
+	// Shadow logging of backend traffic, started per backend by admins
+	var shadowLog *proxy.ShadowLog
+	if cfg.ShadowLog.Enabled {
+		var backendNames []string
+		for _, backend := range backends(cfg) {
+			backendNames = append(backendNames, backend.GetName())
+		}
+		shadowLog, err = proxy.NewShadowLog(&cfg.ShadowLog, backendNames, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to create shadow log")
+		}
+		defer shadowLog.Close()
+		proxyClient.SetShadowLog(shadowLog)
+
+		logger.Info().
+			Str("dir", cfg.ShadowLog.Dir).
+			Dur("max_duration", cfg.ShadowLog.MaxDuration).
+			Msg("Shadow logging available")
+	}
+
+	// Re-establish warm connections to backends recovering from an outage
+	circuitBreakerManager.SetRecoveryHook(func(backend proxy.BackendConfig) {
+		proxyClient.WarmConnections(context.Background(), backend)
+	})
 
 	// Create health check handler
 	healthHandler := health.NewHandler(version)
+	healthHandler.SetVersionInfo(health.VersionInfo{
+		Version:   version,
+		GitCommit: gitCommit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+		Protocols: cfg.EnabledProtocols(),
+		Features:  features,
+	})
 
 	// Register health checkers
 	healthHandler.RegisterChecker("github_api", func(ctx context.Context) error {
@@ -122,6 +401,9 @@ func main() {
 		return nil
 	})
 
+	// Setup protocol detection chain
+	detectorChain := detector.NewChain()
+
 	// Setup router
 	router := chi.NewRouter()
 
@@ -130,8 +412,21 @@ func main() {
 	// 1. Request ID - must be first to ensure all logs have request ID
 	router.Use(middleware.RequestID)
 
+	// Client IP for lockouts, validation budgets and credential sharing detection,
+	// read from forwarding headers only when sent by a trusted proxy
+	trustedProxies, err := utils.NewTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Invalid trusted proxies")
+	}
+	router.Use(middleware.ClientIP(trustedProxies))
+	if len(cfg.Server.TrustedProxies) > 0 {
+		logger.Info().
+			Strs("trusted_proxies", cfg.Server.TrustedProxies).
+			Msg("Client IPs are read from forwarding headers of trusted proxies")
+	}
+
 	// 2. Security Headers - set security headers early
-	router.Use(middleware.SecurityHeaders)
+	router.Use(middleware.SecurityHeaders(&cfg.Server.SecurityHeaders, classifyPath(cfg, detectorChain)))
 
 	// 3. Recovery - catch panics early
 	router.Use(middleware.Recovery(logger))
@@ -152,25 +447,38 @@ func main() {
 		// Use server write timeout if it's lower (more restrictive)
 		requestTimeout = cfg.Server.WriteTimeout
 	}
-	router.Use(middleware.Timeout(requestTimeout))
+	router.Use(middleware.Timeout(requestTimeout, metricsCollector))
 
 	logger.Info().
 		Dur("timeout", requestTimeout).
 		Msg("Request timeout middleware enabled")
 
-	// 6. Concurrency limiting - limit total concurrent requests
+	// 6. In-flight tracking - list and cancel active requests for admins (after
+	// logging, whose fields carry the user and backend, and after the timeout, which
+	// would otherwise abandon canceled requests)
+	inFlightTracker := middleware.NewInFlightTracker()
+	router.Use(inFlightTracker.Middleware)
+
+	// 7. Concurrency limiting - limit total concurrent requests
+	var concurrencyLimiter *middleware.ConcurrencyLimiter
 	if cfg.Server.MaxConcurrentReqs > 0 {
-		concurrencyLimiter := middleware.NewConcurrencyLimiter(cfg.Server.MaxConcurrentReqs)
+		concurrencyLimiter = middleware.NewConcurrencyLimiter(cfg.Server.MaxConcurrentReqs)
+		if cfg.Server.QueueTimeout > 0 {
+			concurrencyLimiter.EnableQueue(cfg.Server.QueueTimeout, cfg.Server.MaxQueuedReqs, metricsCollector)
+		}
 		router.Use(concurrencyLimiter.Middleware)
 
 		logger.Info().
 			Int("max_concurrent_requests", cfg.Server.MaxConcurrentReqs).
+			Dur("queue_timeout", cfg.Server.QueueTimeout).
+			Int("max_queued_requests", cfg.Server.MaxQueuedReqs).
 			Msg("Concurrency limiting enabled")
 	}
 
-	// 7. Rate limiting - global and per-user rate limiting
+	// 8. Rate limiting - global and per-user rate limiting
+	var rateLimiter *middleware.RateLimiter
 	if cfg.RateLimit.Enabled || cfg.RateLimit.PerUserEnabled {
-		rateLimiter := middleware.NewRateLimiter(&cfg.RateLimit)
+		rateLimiter = middleware.NewRateLimiter(&cfg.RateLimit, metricsCollector)
 		router.Use(rateLimiter.Middleware)
 		defer rateLimiter.Stop()
 
@@ -179,12 +487,15 @@ func main() {
 			Float64("global_rps", cfg.RateLimit.RequestsPerSec).
 			Bool("per_user_enabled", cfg.RateLimit.PerUserEnabled).
 			Float64("per_user_rps", cfg.RateLimit.PerUserRequests).
+			Float64("per_user_write_rps", cfg.RateLimit.PerUserWriteRequests).
+			Dur("queue_timeout", cfg.RateLimit.QueueTimeout).
 			Msg("Rate limiting enabled")
 	}
 
 	// Health endpoints
 	router.Get("/health", healthHandler.LivenessHandler())
 	router.Get("/ready", healthHandler.ReadinessHandler())
+	router.Get("/version", healthHandler.VersionHandler())
 
 	// Metrics endpoint (if enabled)
 	if cfg.Metrics.Enabled {
@@ -195,23 +506,55 @@ func main() {
 			Msg("Prometheus metrics endpoint enabled")
 	}
 
-	// Setup protocol detection chain
-	detectorChain := detector.NewChain()
+	// Open the artifact metadata database if enabled (nil = disabled)
+	var metadataStore *metadata.Store
+	if cfg.Metadata.Enabled {
+		metadataStore, err = metadata.Open(&cfg.Metadata, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to open artifact metadata database")
+		}
+		metadataStore.Start()
+		defer func() {
+			if err := metadataStore.Close(); err != nil {
+				logger.Error().Err(err).Msg("Failed to close artifact metadata database")
+			}
+		}()
+
+		logger.Info().
+			Str("path", cfg.Metadata.Path).
+			Dur("flush_interval", cfg.Metadata.FlushInterval).
+			Msg("Artifact metadata database enabled")
+	}
 
 	// Initialize protocol handlers
 	var ociHandler *oci.Handler
 	var mavenHandler *maven.Handler
 	var npmHandler *npm.Handler
+	var rubyGemsHandler *rubygems.Handler
+	var helmHandler *helm.Handler
+	var aptHandler *apt.Handler
+	var composerHandler *composer.Handler
+	var condaHandler *conda.Handler
+	var terraformHandler *terraform.Handler
+	var apkHandler *apk.Handler
+	var rawHandler *raw.Handler
+	var lfsHandler *lfs.Handler
+	var cocoaPodsHandler *cocoapods.Handler
+	var hexHandler *hex.Handler
+	var pubHandler *pub.Handler
+	var vagrantHandler *vagrant.Handler
+	var ociTrash *trash.Trash
 
 	// Register OCI handler if enabled
 	if cfg.Protocols.OCI.Enabled {
 		ociHandler = oci.NewHandler(
 			&cfg.Protocols.OCI,
-			clientAuthenticator,
+			protocolAuthenticator(detector.ProtocolOCI, &cfg.Protocols.OCI.ClientAuth),
 			proxyClient,
 			metricsCollector,
 			logger,
 		)
+		ociHandler.SetMetadata(metadataStore)
 
 		// Register OCI detector with host
 		detectorChain.Register(detector.NewOCIDetector(cfg.Protocols.OCI.Host))
@@ -221,17 +564,46 @@ func main() {
 			Int("pull_backends", len(cfg.Protocols.OCI.PullBackends)).
 			Str("push_backend", cfg.Protocols.OCI.PushBackend.URL).
 			Msg("OCI/Docker protocol handler enabled")
+
+		if replicationCfg := &cfg.Protocols.OCI.Replication; replicationCfg.Enabled {
+			replicator := replication.New(&cfg.Protocols.OCI, proxyClient, metricsCollector, logger)
+			replicator.SetFeatureFlags(featureFlags)
+			defer replicator.Stop()
+			ociHandler.SetReplicator(replicator)
+
+			logger.Info().
+				Str("target", replicationCfg.Target.URL).
+				Int("workers", replicationCfg.Workers).
+				Dur("reconcile_interval", replicationCfg.ReconcileInterval).
+				Msg("OCI push replication enabled")
+		}
+
+		if trashCfg := &cfg.Protocols.OCI.Trash; trashCfg.Enabled {
+			ociTrash, err = trash.New(trashCfg, logger)
+			if err != nil {
+				logger.Fatal().Err(err).Msg("Failed to load OCI trash")
+			}
+			ociTrash.Start(ociHandler.PurgeTrashed)
+			defer ociTrash.Stop()
+			ociHandler.SetTrash(ociTrash)
+
+			logger.Info().
+				Dur("retention", trashCfg.Retention).
+				Str("state_file", trashCfg.StateFile).
+				Msg("OCI soft-delete enabled")
+		}
 	}
 
 	// Register Maven handler if enabled
 	if cfg.Protocols.Maven.Enabled {
 		mavenHandler = maven.NewHandler(
 			&cfg.Protocols.Maven,
-			clientAuthenticator,
+			protocolAuthenticator(detector.ProtocolMaven, &cfg.Protocols.Maven.ClientAuth),
 			proxyClient,
 			metricsCollector,
 			logger,
 		)
+		mavenHandler.SetMetadata(metadataStore)
 
 		// Register Maven detector with host and path prefix
 		detectorChain.Register(detector.NewMavenDetector(
@@ -244,17 +616,32 @@ func main() {
 			Str("path_prefix", cfg.Protocols.Maven.PathPrefix).
 			Str("backend", cfg.Protocols.Maven.Backend.URL).
 			Msg("Maven protocol handler enabled")
+
+		if candidate := cfg.Protocols.Maven.Candidate; candidate != nil {
+			logger.Info().
+				Str("candidate", candidate.URL).
+				Float64("candidate_percent", cfg.Protocols.Maven.CandidatePercent).
+				Msg("Maven backend experiment enabled")
+		}
+
+		if upstream := cfg.Protocols.Maven.Upstream; upstream != nil {
+			logger.Info().
+				Str("upstream", upstream.URL).
+				Bool("write_back", cfg.Protocols.Maven.WriteBack).
+				Msg("Maven read-through upstream enabled")
+		}
 	}
 
 	// Register NPM handler if enabled
 	if cfg.Protocols.NPM.Enabled {
 		npmHandler = npm.NewHandler(
 			&cfg.Protocols.NPM,
-			clientAuthenticator,
+			protocolAuthenticator(detector.ProtocolNPM, &cfg.Protocols.NPM.ClientAuth),
 			proxyClient,
 			metricsCollector,
 			logger,
 		)
+		npmHandler.SetMetadata(metadataStore)
 
 		// Register NPM detector with host and path prefix
 		detectorChain.Register(detector.NewNPMDetector(
@@ -267,8 +654,464 @@ func main() {
 			Str("path_prefix", cfg.Protocols.NPM.PathPrefix).
 			Str("backend", cfg.Protocols.NPM.Backend.URL).
 			Msg("NPM protocol handler enabled")
+
+		if candidate := cfg.Protocols.NPM.Candidate; candidate != nil {
+			logger.Info().
+				Str("candidate", candidate.URL).
+				Float64("candidate_percent", cfg.Protocols.NPM.CandidatePercent).
+				Msg("NPM backend experiment enabled")
+		}
+
+		if upstream := cfg.Protocols.NPM.Upstream; upstream != nil {
+			logger.Info().
+				Str("upstream", upstream.URL).
+				Bool("write_back", cfg.Protocols.NPM.WriteBack).
+				Msg("NPM read-through upstream enabled")
+		}
+	}
+
+	// Register RubyGems handler if enabled
+	if cfg.Protocols.RubyGems.Enabled {
+		rubyGemsHandler = rubygems.NewHandler(
+			&cfg.Protocols.RubyGems,
+			protocolAuthenticator(detector.ProtocolRubyGems, &cfg.Protocols.RubyGems.ClientAuth),
+			proxyClient,
+			metricsCollector,
+			logger,
+		)
+		rubyGemsHandler.SetMetadata(metadataStore)
+
+		// Register RubyGems detector with host and path prefix
+		detectorChain.Register(detector.NewRubyGemsDetector(
+			cfg.Protocols.RubyGems.Host,
+			cfg.Protocols.RubyGems.PathPrefix,
+		))
+
+		logger.Info().
+			Str("host", cfg.Protocols.RubyGems.Host).
+			Str("path_prefix", cfg.Protocols.RubyGems.PathPrefix).
+			Str("backend", cfg.Protocols.RubyGems.Backend.URL).
+			Msg("RubyGems protocol handler enabled")
+	}
+
+	// Register Helm handler if enabled
+	if cfg.Protocols.Helm.Enabled {
+		helmHandler = helm.NewHandler(
+			&cfg.Protocols.Helm,
+			protocolAuthenticator(detector.ProtocolHelm, &cfg.Protocols.Helm.ClientAuth),
+			proxyClient,
+			metricsCollector,
+			logger,
+		)
+		helmHandler.SetMetadata(metadataStore)
+		if ociHandler != nil {
+			// OCI-based charts (helm pull oci://...) requested on the Helm host
+			helmHandler.SetOCIHandler(ociHandler)
+		}
+
+		// Register Helm detector with host and path prefix
+		detectorChain.Register(detector.NewHelmDetector(
+			cfg.Protocols.Helm.Host,
+			cfg.Protocols.Helm.PathPrefix,
+		))
+
+		logger.Info().
+			Str("host", cfg.Protocols.Helm.Host).
+			Str("path_prefix", cfg.Protocols.Helm.PathPrefix).
+			Str("backend", cfg.Protocols.Helm.Backend.URL).
+			Bool("oci_charts", ociHandler != nil).
+			Msg("Helm protocol handler enabled")
+	}
+
+	// Register APT handler if enabled
+	if cfg.Protocols.APT.Enabled {
+		aptHandler = apt.NewHandler(
+			&cfg.Protocols.APT,
+			protocolAuthenticator(detector.ProtocolAPT, &cfg.Protocols.APT.ClientAuth),
+			proxyClient,
+			metricsCollector,
+			logger,
+		)
+		aptHandler.SetMetadata(metadataStore)
+
+		// Register APT detector with host and path prefix
+		detectorChain.Register(detector.NewAPTDetector(
+			cfg.Protocols.APT.Host,
+			cfg.Protocols.APT.PathPrefix,
+		))
+
+		logger.Info().
+			Str("host", cfg.Protocols.APT.Host).
+			Str("path_prefix", cfg.Protocols.APT.PathPrefix).
+			Str("backend", cfg.Protocols.APT.Backend.URL).
+			Msg("APT protocol handler enabled")
+	}
+
+	// Register Composer handler if enabled
+	if cfg.Protocols.Composer.Enabled {
+		composerHandler = composer.NewHandler(
+			&cfg.Protocols.Composer,
+			protocolAuthenticator(detector.ProtocolComposer, &cfg.Protocols.Composer.ClientAuth),
+			proxyClient,
+			metricsCollector,
+			logger,
+		)
+
+		// Register Composer detector with host and path prefix
+		detectorChain.Register(detector.NewComposerDetector(
+			cfg.Protocols.Composer.Host,
+			cfg.Protocols.Composer.PathPrefix,
+		))
+
+		logger.Info().
+			Str("host", cfg.Protocols.Composer.Host).
+			Str("path_prefix", cfg.Protocols.Composer.PathPrefix).
+			Str("backend", cfg.Protocols.Composer.Backend.URL).
+			Msg("Composer protocol handler enabled")
+
+		if upstream := cfg.Protocols.Composer.Upstream; upstream != nil {
+			logger.Info().
+				Str("upstream", upstream.URL).
+				Msg("Composer cascade to upstream enabled")
+		}
 	}
 
+	// Register Conda handler if enabled
+	if cfg.Protocols.Conda.Enabled {
+		condaHandler = conda.NewHandler(
+			&cfg.Protocols.Conda,
+			protocolAuthenticator(detector.ProtocolConda, &cfg.Protocols.Conda.ClientAuth),
+			proxyClient,
+			metricsCollector,
+			logger,
+		)
+		condaHandler.SetMetadata(metadataStore)
+		condaHandler.SetResponseCache(conda.NewMemoryCache())
+
+		// Register Conda detector with host and path prefix
+		detectorChain.Register(detector.NewCondaDetector(
+			cfg.Protocols.Conda.Host,
+			cfg.Protocols.Conda.PathPrefix,
+		))
+
+		for _, channel := range cfg.Protocols.Conda.Channels {
+			logger.Info().
+				Str("channel", channel.Name).
+				Str("backend", channel.Backend.URL).
+				Dur("repodata_cache_ttl", channel.RepodataCacheTTL).
+				Msg("Conda channel enabled")
+		}
+		logger.Info().
+			Str("host", cfg.Protocols.Conda.Host).
+			Str("path_prefix", cfg.Protocols.Conda.PathPrefix).
+			Int("channels", len(cfg.Protocols.Conda.Channels)).
+			Msg("Conda protocol handler enabled")
+	}
+
+	// Register Terraform handler if enabled
+	if cfg.Protocols.Terraform.Enabled {
+		terraformHandler = terraform.NewHandler(
+			&cfg.Protocols.Terraform,
+			protocolAuthenticator(detector.ProtocolTerraform, &cfg.Protocols.Terraform.ClientAuth),
+			proxyClient,
+			metricsCollector,
+			logger,
+		)
+		terraformHandler.SetMetadata(metadataStore)
+		if urlSigner != nil {
+			terraformHandler.SetURLSigner(urlSigner)
+		}
+
+		// Register Terraform detector with host and path prefix
+		detectorChain.Register(detector.NewTerraformDetector(
+			cfg.Protocols.Terraform.Host,
+			cfg.Protocols.Terraform.PathPrefix,
+		))
+
+		logger.Info().
+			Str("host", cfg.Protocols.Terraform.Host).
+			Str("path_prefix", cfg.Protocols.Terraform.PathPrefix).
+			Str("backend", cfg.Protocols.Terraform.Backend.URL).
+			Bool("signed_downloads", urlSigner != nil).
+			Msg("Terraform protocol handler enabled")
+	}
+
+	// Register APK handler if enabled
+	if cfg.Protocols.APK.Enabled {
+		apkHandler = apk.NewHandler(
+			&cfg.Protocols.APK,
+			protocolAuthenticator(detector.ProtocolAPK, &cfg.Protocols.APK.ClientAuth),
+			proxyClient,
+			metricsCollector,
+			logger,
+		)
+		apkHandler.SetMetadata(metadataStore)
+
+		// Register APK detector with host and path prefix
+		detectorChain.Register(detector.NewAPKDetector(
+			cfg.Protocols.APK.Host,
+			cfg.Protocols.APK.PathPrefix,
+		))
+
+		logger.Info().
+			Str("host", cfg.Protocols.APK.Host).
+			Str("path_prefix", cfg.Protocols.APK.PathPrefix).
+			Str("backend", cfg.Protocols.APK.Backend.URL).
+			Msg("APK protocol handler enabled")
+	}
+
+	// Register raw repository handler if enabled
+	if cfg.Protocols.Raw.Enabled {
+		rawHandler = raw.NewHandler(
+			&cfg.Protocols.Raw,
+			protocolAuthenticator(detector.ProtocolRaw, &cfg.Protocols.Raw.ClientAuth),
+			proxyClient,
+			metricsCollector,
+			logger,
+		)
+		rawHandler.SetMetadata(metadataStore)
+
+		// Register raw detector with host and path prefix
+		detectorChain.Register(detector.NewRawDetector(
+			cfg.Protocols.Raw.Host,
+			cfg.Protocols.Raw.PathPrefix,
+		))
+
+		for _, repository := range cfg.Protocols.Raw.Repositories {
+			logger.Info().
+				Str("repository", repository.Path).
+				Str("backend", repository.Backend.URL).
+				Int64("max_upload_size", repository.MaxUploadSize).
+				Bool("read_only", repository.ReadOnly).
+				Msg("Raw repository enabled")
+		}
+		logger.Info().
+			Str("host", cfg.Protocols.Raw.Host).
+			Str("path_prefix", cfg.Protocols.Raw.PathPrefix).
+			Int("repositories", len(cfg.Protocols.Raw.Repositories)).
+			Msg("Raw protocol handler enabled")
+	}
+
+	// Register Git LFS handler if enabled
+	if cfg.Protocols.LFS.Enabled {
+		lfsHandler = lfs.NewHandler(
+			&cfg.Protocols.LFS,
+			protocolAuthenticator(detector.ProtocolLFS, &cfg.Protocols.LFS.ClientAuth),
+			proxyClient,
+			metricsCollector,
+			logger,
+		)
+
+		// Register Git LFS detector with host and path prefix
+		detectorChain.Register(detector.NewLFSDetector(
+			cfg.Protocols.LFS.Host,
+			cfg.Protocols.LFS.PathPrefix,
+		))
+
+		logger.Info().
+			Str("host", cfg.Protocols.LFS.Host).
+			Str("path_prefix", cfg.Protocols.LFS.PathPrefix).
+			Str("backend", cfg.Protocols.LFS.Backend.URL).
+			Msg("Git LFS protocol handler enabled")
+	}
+
+	// Register CocoaPods handler if enabled
+	if cfg.Protocols.CocoaPods.Enabled {
+		cocoaPodsHandler = cocoapods.NewHandler(
+			&cfg.Protocols.CocoaPods,
+			protocolAuthenticator(detector.ProtocolCocoaPods, &cfg.Protocols.CocoaPods.ClientAuth),
+			proxyClient,
+			metricsCollector,
+			logger,
+		)
+		cocoaPodsHandler.SetMetadata(metadataStore)
+
+		// Register CocoaPods detector with host and path prefix
+		detectorChain.Register(detector.NewCocoaPodsDetector(
+			cfg.Protocols.CocoaPods.Host,
+			cfg.Protocols.CocoaPods.PathPrefix,
+		))
+
+		logger.Info().
+			Str("host", cfg.Protocols.CocoaPods.Host).
+			Str("path_prefix", cfg.Protocols.CocoaPods.PathPrefix).
+			Str("backend", cfg.Protocols.CocoaPods.Backend.URL).
+			Msg("CocoaPods protocol handler enabled")
+
+		if binaries := cfg.Protocols.CocoaPods.BinaryBackend; binaries != nil {
+			logger.Info().
+				Str("binary_backend", binaries.URL).
+				Msg("CocoaPods binaries enabled")
+		}
+	}
+
+	// Register Hex handler if enabled
+	if cfg.Protocols.Hex.Enabled {
+		hexHandler = hex.NewHandler(
+			&cfg.Protocols.Hex,
+			protocolAuthenticator(detector.ProtocolHex, &cfg.Protocols.Hex.ClientAuth),
+			proxyClient,
+			metricsCollector,
+			logger,
+		)
+		hexHandler.SetMetadata(metadataStore)
+
+		// Register Hex detector with host and path prefix
+		detectorChain.Register(detector.NewHexDetector(
+			cfg.Protocols.Hex.Host,
+			cfg.Protocols.Hex.PathPrefix,
+		))
+
+		logger.Info().
+			Str("host", cfg.Protocols.Hex.Host).
+			Str("path_prefix", cfg.Protocols.Hex.PathPrefix).
+			Str("backend", cfg.Protocols.Hex.Backend.URL).
+			Msg("Hex protocol handler enabled")
+
+		if upstream := cfg.Protocols.Hex.Upstream; upstream != nil {
+			logger.Info().
+				Str("upstream", upstream.URL).
+				Msg("Hex cascade to upstream enabled")
+		}
+		if publish := cfg.Protocols.Hex.PublishBackend; publish != nil {
+			logger.Info().
+				Str("publish_backend", publish.URL).
+				Msg("Hex publishing enabled")
+		}
+	}
+
+	// Register Dart pub handler if enabled
+	if cfg.Protocols.Pub.Enabled {
+		pubHandler = pub.NewHandler(
+			&cfg.Protocols.Pub,
+			protocolAuthenticator(detector.ProtocolPub, &cfg.Protocols.Pub.ClientAuth),
+			proxyClient,
+			metricsCollector,
+			logger,
+		)
+		pubHandler.SetMetadata(metadataStore)
+
+		// Register pub detector with host and path prefix
+		detectorChain.Register(detector.NewPubDetector(
+			cfg.Protocols.Pub.Host,
+			cfg.Protocols.Pub.PathPrefix,
+		))
+
+		logger.Info().
+			Str("host", cfg.Protocols.Pub.Host).
+			Str("path_prefix", cfg.Protocols.Pub.PathPrefix).
+			Str("backend", cfg.Protocols.Pub.Backend.URL).
+			Msg("Pub protocol handler enabled")
+	}
+
+	// Register Vagrant handler if enabled
+	if cfg.Protocols.Vagrant.Enabled {
+		vagrantHandler = vagrant.NewHandler(
+			&cfg.Protocols.Vagrant,
+			protocolAuthenticator(detector.ProtocolVagrant, &cfg.Protocols.Vagrant.ClientAuth),
+			proxyClient,
+			metricsCollector,
+			logger,
+		)
+		vagrantHandler.SetMetadata(metadataStore)
+
+		// Register Vagrant detector with host and path prefix
+		detectorChain.Register(detector.NewVagrantDetector(
+			cfg.Protocols.Vagrant.Host,
+			cfg.Protocols.Vagrant.PathPrefix,
+		))
+
+		logger.Info().
+			Str("host", cfg.Protocols.Vagrant.Host).
+			Str("path_prefix", cfg.Protocols.Vagrant.PathPrefix).
+			Str("backend", cfg.Protocols.Vagrant.Backend.URL).
+			Int("download_backends", len(cfg.Protocols.Vagrant.DownloadBackends)).
+			Msg("Vagrant protocol handler enabled")
+	}
+
+	// Browser sessions signed in with GitHub, accepted by the API and web UIs only
+	uiAuthenticator := clientAuthenticator
+	var sessionIssuer *auth.SessionIssuer
+	if cfg.BrowserSessions.Enabled {
+		sessionIssuer = auth.NewSessionIssuer(&cfg.BrowserSessions)
+		sessionIssuer.SetFeatureFlags(featureFlags)
+		uiAuthenticator = clientAuthenticator.WithSessions(sessionIssuer)
+
+		logger.Info().
+			Str("github_url", cfg.BrowserSessions.GitHubURL).
+			Dur("ttl", cfg.BrowserSessions.TTL).
+			Msg("Browser sessions enabled")
+	}
+
+	// Artifusion API (authorization dry-runs, etc.)
+	apiHandler := api.NewHandler(uiAuthenticator, detectorChain, logger)
+	apiHandler.SetLimiters(rateLimiter, concurrencyLimiter)
+	apiHandler.SetInFlightTracker(inFlightTracker, auditor)
+	apiHandler.SetEffectiveConfig(cfg)
+	apiHandler.SetPackageSources(&cfg.Protocols, proxyClient)
+	if metadataStore != nil {
+		apiHandler.SetMetadata(metadataStore)
+	}
+	apiHandler.SetFeatureFlags(featureFlags, auditor)
+	if ociTrash != nil {
+		apiHandler.SetTrash(ociTrash, auditor)
+	}
+	if urlSigner != nil {
+		apiHandler.SetURLSigner(urlSigner, cfg.SignedURLs.BaseURL)
+	}
+	if shadowLog != nil {
+		apiHandler.SetShadowLog(shadowLog, auditor)
+	}
+	if sessionIssuer != nil {
+		apiHandler.SetBrowserSessions(sessionIssuer, auth.NewDeviceFlow(&cfg.BrowserSessions))
+	}
+	if ociHandler != nil {
+		apiHandler.RegisterExplainer(detector.ProtocolOCI, ociHandler)
+	}
+	if contentPolicy != nil {
+		apiHandler.SetContentPolicy(contentPolicy)
+	}
+	router.Mount("/api/v1", apiHandler.Routes())
+
+	// Optional gRPC listener serving the admin API to typed clients
+	var grpcServer *grpc.Server
+	var grpcListener net.Listener
+	if cfg.GRPC.Enabled {
+		var opts []grpc.ServerOption
+		if cfg.GRPC.TLSCertFile != "" {
+			creds, err := credentials.NewServerTLSFromFile(cfg.GRPC.TLSCertFile, cfg.GRPC.TLSKeyFile)
+			if err != nil {
+				logger.Fatal().Err(err).Msg("Failed to load gRPC TLS certificate")
+			}
+			opts = append(opts, grpc.Creds(creds))
+		}
+		grpcServer = grpc.NewServer(opts...)
+		apiHandler.RegisterGRPC(grpcServer)
+
+		grpcListener, err = net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to listen for gRPC admin API")
+		}
+	}
+
+	// Backend web UIs for authenticated users
+	if cfg.WebUI.Enabled {
+		webUIHandler := webui.NewHandler(&cfg.WebUI, &cfg.Protocols, uiAuthenticator, proxyClient, logger)
+		if sessionIssuer != nil {
+			webUIHandler.SetBrowserSessions("/api/v1")
+		}
+		router.Handle(cfg.WebUI.PathPrefix, webUIHandler)
+		router.Handle(cfg.WebUI.PathPrefix+"/*", webUIHandler)
+
+		logger.Info().
+			Str("path_prefix", cfg.WebUI.PathPrefix).
+			Int("mounts", len(cfg.WebUI.Mounts)).
+			Msg("Backend web UIs enabled")
+	}
+
+	// Answers requests of unsupported protocols in the client's error format
+	unsupportedHandler := handler.NewUnsupported(handler.Endpoints(&cfg.Protocols))
+
 	// Main request handler with protocol detection
 	router.HandleFunc("/*", func(w http.ResponseWriter, r *http.Request) {
 		// Detect protocol
@@ -280,6 +1123,27 @@ func main() {
 			Str("path", r.URL.Path).
 			Msg("Protocol detected")
 
+		// Answer OPTIONS requests locally instead of proxying them, if configured
+		if optionsResponder != nil && protocol != detector.ProtocolUnknown {
+			var proceed bool
+			if w, proceed = optionsResponder.Handle(w, r, detector.Methods(protocol)); !proceed {
+				return
+			}
+		}
+
+		// Classify the client and deny the clients of the client policy
+		if !clientPolicy.Enforce(w, r, string(protocol)) {
+			return
+		}
+
+		// Enforce the content policy of the detected protocol
+		if contentPolicy != nil {
+			var allowed bool
+			if w, allowed = contentPolicy.Enforce(w, r, string(protocol)); !allowed {
+				return
+			}
+		}
+
 		// Route to appropriate handler
 		switch protocol {
 		case detector.ProtocolOCI:
@@ -300,11 +1164,89 @@ func main() {
 				return
 			}
 
+		case detector.ProtocolRubyGems:
+			if rubyGemsHandler != nil {
+				rubyGemsHandler.ServeHTTP(w, r)
+				return
+			}
+
+		case detector.ProtocolHelm:
+			if helmHandler != nil {
+				helmHandler.ServeHTTP(w, r)
+				return
+			}
+
+		case detector.ProtocolAPT:
+			if aptHandler != nil {
+				aptHandler.ServeHTTP(w, r)
+				return
+			}
+
+		case detector.ProtocolComposer:
+			if composerHandler != nil {
+				composerHandler.ServeHTTP(w, r)
+				return
+			}
+
+		case detector.ProtocolConda:
+			if condaHandler != nil {
+				condaHandler.ServeHTTP(w, r)
+				return
+			}
+
+		case detector.ProtocolTerraform:
+			if terraformHandler != nil {
+				terraformHandler.ServeHTTP(w, r)
+				return
+			}
+
+		case detector.ProtocolAPK:
+			if apkHandler != nil {
+				apkHandler.ServeHTTP(w, r)
+				return
+			}
+
+		case detector.ProtocolRaw:
+			if rawHandler != nil {
+				rawHandler.ServeHTTP(w, r)
+				return
+			}
+
+		case detector.ProtocolLFS:
+			if lfsHandler != nil {
+				lfsHandler.ServeHTTP(w, r)
+				return
+			}
+
+		case detector.ProtocolCocoaPods:
+			if cocoaPodsHandler != nil {
+				cocoaPodsHandler.ServeHTTP(w, r)
+				return
+			}
+
+		case detector.ProtocolHex:
+			if hexHandler != nil {
+				hexHandler.ServeHTTP(w, r)
+				return
+			}
+
+		case detector.ProtocolPub:
+			if pubHandler != nil {
+				pubHandler.ServeHTTP(w, r)
+				return
+			}
+
+		case detector.ProtocolVagrant:
+			if vagrantHandler != nil {
+				vagrantHandler.ServeHTTP(w, r)
+				return
+			}
+
 		case detector.ProtocolUnknown:
 			fallthrough
 		default:
 			// Unknown protocol
-			errors.ErrorResponse(w, errors.ErrProtocolNotSupported)
+			unsupportedHandler.ServeHTTP(w, r)
 			return
 		}
 
@@ -321,6 +1263,7 @@ func main() {
 		IdleTimeout:       cfg.Server.IdleTimeout,
 		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
 		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         serverTLSConfig,
 	}
 
 	// Log server configuration
@@ -332,20 +1275,84 @@ func main() {
 		Int("max_header_bytes", cfg.Server.MaxHeaderBytes).
 		Msg("HTTP server configuration")
 
+	// Optional forward-proxy listener routing intercepted registry hosts through the router
+	var forwardProxy *forwardproxy.Proxy
+	var forwardProxyServer *http.Server
+	if cfg.ForwardProxy.Enabled {
+		forwardProxy, err = forwardproxy.New(cfg, router, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to create forward proxy")
+		}
+		forwardProxy.SetFeatureFlags(featureFlags)
+
+		forwardProxyServer = &http.Server{
+			Addr:              fmt.Sprintf(":%d", cfg.ForwardProxy.Port),
+			Handler:           forwardProxy,
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			WriteTimeout:      cfg.Server.WriteTimeout,
+			IdleTimeout:       cfg.Server.IdleTimeout,
+			MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+	}
+
+	// Synthetic checks pull canary artifacts through this server once it listens
+	if cfg.SyntheticChecks.Enabled {
+		syntheticRunner := synthetic.New(&cfg.SyntheticChecks, &cfg.Protocols, metricsCollector, logger)
+		syntheticRunner.Start()
+		defer syntheticRunner.Stop()
+
+		logger.Info().
+			Int("checks", len(cfg.SyntheticChecks.Checks)).
+			Dur("interval", cfg.SyntheticChecks.Interval).
+			Msg("Synthetic checks enabled")
+	}
+
+	// Warm backend connections while the server starts
+	go proxyClient.WarmConnections(context.Background(), backends(cfg)...)
+
 	// Setup graceful shutdown
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
 
-	// Start server in goroutine
-	serverErrors := make(chan error, 1)
+	// Start servers in goroutines
+	serverErrors := make(chan error, 3)
 	go func() {
 		logger.Info().
 			Str("address", server.Addr).
+			Bool("tls", cfg.Server.TLSCertFile != "").
 			Msg("HTTP server starting")
 
+		if cfg.Server.TLSCertFile != "" {
+			serverErrors <- server.ListenAndServeTLS(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+			return
+		}
 		serverErrors <- server.ListenAndServe()
 	}()
 
+	if forwardProxyServer != nil {
+		go func() {
+			logger.Info().
+				Str("address", forwardProxyServer.Addr).
+				Int("intercepted_hosts", len(cfg.ForwardProxy.Intercept)).
+				Bool("connect", cfg.ForwardProxy.TLSCertFile != "").
+				Msg("Forward proxy starting")
+
+			serverErrors <- forwardProxyServer.ListenAndServe()
+		}()
+	}
+
+	if grpcServer != nil {
+		go func() {
+			logger.Info().
+				Str("address", grpcListener.Addr().String()).
+				Bool("tls", cfg.GRPC.TLSCertFile != "").
+				Msg("gRPC admin API starting")
+
+			serverErrors <- grpcServer.Serve(grpcListener)
+		}()
+	}
+
 	// Block until shutdown signal or server error
 	select {
 	case err := <-serverErrors:
@@ -360,6 +1367,31 @@ func main() {
 		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 		defer cancel()
 
+		// Stop accepting forward proxy connections, then drain its CONNECT tunnels
+		if forwardProxyServer != nil {
+			if err := forwardProxyServer.Shutdown(ctx); err != nil {
+				logger.Error().Err(err).Msg("Forward proxy forced to shutdown")
+			}
+			if err := forwardProxy.Shutdown(ctx); err != nil {
+				logger.Error().Err(err).Msg("Forward proxy tunnels forced to shutdown")
+			}
+		}
+
+		// Let in-flight admin RPCs finish, canceling them at the shutdown deadline
+		if grpcServer != nil {
+			stopped := make(chan struct{})
+			go func() {
+				grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+				logger.Error().Msg("gRPC admin API forced to shutdown")
+				grpcServer.Stop()
+			}
+		}
+
 		// Attempt graceful shutdown
 		if err := server.Shutdown(ctx); err != nil {
 			logger.Error().Err(err).Msg("Server forced to shutdown")
@@ -383,6 +1415,118 @@ func main() {
 		Msg("GitHub auth cache statistics")
 }
 
+// backends returns every backend of the enabled protocols
+func backends(cfg *config.Config) []proxy.BackendConfig {
+	var all []proxy.BackendConfig
+	if oci := &cfg.Protocols.OCI; oci.Enabled {
+		for i := range oci.PullBackends {
+			all = append(all, &oci.PullBackends[i])
+		}
+		if oci.PushBackend.URL != "" {
+			all = append(all, &oci.PushBackend)
+		}
+		if oci.Replication.Enabled {
+			all = append(all, &oci.Replication.Target)
+		}
+	}
+	if maven := &cfg.Protocols.Maven; maven.Enabled {
+		all = append(all, &maven.Backend)
+		if maven.Candidate != nil {
+			all = append(all, maven.Candidate)
+		}
+		if maven.Upstream != nil {
+			all = append(all, maven.Upstream)
+		}
+	}
+	if npm := &cfg.Protocols.NPM; npm.Enabled {
+		all = append(all, &npm.Backend)
+		if npm.Candidate != nil {
+			all = append(all, npm.Candidate)
+		}
+		if npm.Upstream != nil {
+			all = append(all, npm.Upstream)
+		}
+	}
+	if rubyGems := &cfg.Protocols.RubyGems; rubyGems.Enabled {
+		all = append(all, &rubyGems.Backend)
+	}
+	if helm := &cfg.Protocols.Helm; helm.Enabled {
+		all = append(all, &helm.Backend)
+	}
+	if apt := &cfg.Protocols.APT; apt.Enabled {
+		all = append(all, &apt.Backend)
+	}
+	if composer := &cfg.Protocols.Composer; composer.Enabled {
+		all = append(all, &composer.Backend)
+		if composer.Upstream != nil {
+			all = append(all, composer.Upstream)
+		}
+	}
+	if conda := &cfg.Protocols.Conda; conda.Enabled {
+		for i := range conda.Channels {
+			all = append(all, &conda.Channels[i].Backend)
+		}
+	}
+	if terraform := &cfg.Protocols.Terraform; terraform.Enabled {
+		all = append(all, &terraform.Backend)
+	}
+	if apk := &cfg.Protocols.APK; apk.Enabled {
+		all = append(all, &apk.Backend)
+	}
+	if raw := &cfg.Protocols.Raw; raw.Enabled {
+		for i := range raw.Repositories {
+			all = append(all, &raw.Repositories[i].Backend)
+		}
+	}
+	if lfs := &cfg.Protocols.LFS; lfs.Enabled {
+		all = append(all, &lfs.Backend)
+	}
+	if cocoaPods := &cfg.Protocols.CocoaPods; cocoaPods.Enabled {
+		all = append(all, &cocoaPods.Backend)
+		if cocoaPods.BinaryBackend != nil {
+			all = append(all, cocoaPods.BinaryBackend)
+		}
+	}
+	if hex := &cfg.Protocols.Hex; hex.Enabled {
+		all = append(all, &hex.Backend)
+		if hex.Upstream != nil {
+			all = append(all, hex.Upstream)
+		}
+		if hex.PublishBackend != nil {
+			all = append(all, hex.PublishBackend)
+		}
+	}
+	if pub := &cfg.Protocols.Pub; pub.Enabled {
+		all = append(all, &pub.Backend)
+	}
+	if vagrant := &cfg.Protocols.Vagrant; vagrant.Enabled {
+		all = append(all, &vagrant.Backend)
+		for i := range vagrant.DownloadBackends {
+			all = append(all, &vagrant.DownloadBackends[i])
+		}
+	}
+	return all
+}
+
+// classifyPath returns the function assigning requests their security header path
+// class: the web UI, package protocol requests, and Artifusion's own endpoints. The
+// detector chain is consulted per request, so it may be populated afterwards.
+func classifyPath(cfg *config.Config, detectorChain *detector.Chain) func(*http.Request) middleware.PathClass {
+	return func(r *http.Request) middleware.PathClass {
+		path := r.URL.Path
+		if prefix := cfg.WebUI.PathPrefix; cfg.WebUI.Enabled && (path == prefix || strings.HasPrefix(path, prefix+"/")) {
+			return middleware.PathClassUI
+		}
+		if strings.HasPrefix(path, "/api/") {
+			return middleware.PathClassDefault
+		}
+		if detectorChain.Detect(r) != detector.ProtocolUnknown {
+			return middleware.PathClassProtocol
+		}
+		return middleware.PathClassDefault
+	}
+}
+
 // getEnvOrDefault returns the value of an environment variable or a default value if not set
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -0,0 +1,256 @@
+// Package api implements Artifusion's own HTTP API, served under /api/v1.
+//
+// Unlike protocol handlers, which proxy requests to backends, API endpoints answer
+// questions about Artifusion itself (e.g. authorization dry-runs) for authenticated
+// users. Callers authenticate with the same GitHub tokens used for artifact access.
+package api
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mainuli/artifusion/internal/audit"
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/authz"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/detector"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/featureflags"
+	"github.com/mainuli/artifusion/internal/metadata"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/trash"
+	"github.com/rs/zerolog"
+)
+
+// Handler serves the Artifusion API
+type Handler struct {
+	authenticator *auth.ClientAuthenticator
+	detectorChain *detector.Chain
+	explainers    map[detector.Protocol]authz.Explainer
+	logger        zerolog.Logger
+
+	// Content policy evaluated by /authz/check (nil when disabled)
+	contentPolicy *middleware.ContentPolicy
+
+	// Optional limiters for introspection (nil when disabled)
+	rateLimiter        *middleware.RateLimiter
+	concurrencyLimiter *middleware.ConcurrencyLimiter
+
+	// Tracker of the requests being served (nil when not set)
+	inFlight *middleware.InFlightTracker
+
+	// Effective configuration candidates are compared to (nil when not set)
+	effectiveConfig *config.Config
+
+	// Feature flags managed by admins (nil when not set) and the audit log for toggles
+	featureFlags *featureflags.Flags
+	auditor      *audit.Logger
+
+	// Trash of soft-deleted OCI artifacts (nil when disabled)
+	trash *trash.Trash
+
+	// Protocol backends listed by /packages (nil when not set)
+	protocols   *config.ProtocolsConfig
+	proxyClient *proxy.Client
+
+	// Artifact metadata database (nil when disabled)
+	metadata *metadata.Store
+
+	// Signer of download URLs minted under /signed-urls (nil when disabled)
+	signer     *auth.URLSigner
+	signerBase string
+
+	// Browser sessions signed in with the GitHub device flow (nil when disabled)
+	sessions   *auth.SessionIssuer
+	deviceFlow *auth.DeviceFlow
+
+	// Shadow log of backend traffic managed by admins (nil when disabled)
+	shadowLog *proxy.ShadowLog
+}
+
+// NewHandler creates a new API handler
+func NewHandler(
+	authenticator *auth.ClientAuthenticator,
+	detectorChain *detector.Chain,
+	logger zerolog.Logger,
+) *Handler {
+	return &Handler{
+		authenticator: authenticator,
+		detectorChain: detectorChain,
+		explainers:    make(map[detector.Protocol]authz.Explainer),
+		logger:        logger.With().Str("component", "api").Logger(),
+	}
+}
+
+// RegisterExplainer registers a protocol handler that can explain its routing decisions.
+// Must be called before Routes is served.
+func (h *Handler) RegisterExplainer(protocol detector.Protocol, explainer authz.Explainer) {
+	h.explainers[protocol] = explainer
+}
+
+// SetContentPolicy registers the content policy /authz/check evaluates requests
+// against. Must be called before Routes is served.
+func (h *Handler) SetContentPolicy(policy *middleware.ContentPolicy) {
+	h.contentPolicy = policy
+}
+
+// SetLimiters registers the rate and concurrency limiters reported by /limits.
+// Either may be nil when the corresponding limit is disabled.
+func (h *Handler) SetLimiters(rateLimiter *middleware.RateLimiter, concurrencyLimiter *middleware.ConcurrencyLimiter) {
+	h.rateLimiter = rateLimiter
+	h.concurrencyLimiter = concurrencyLimiter
+}
+
+// SetInFlightTracker registers the tracker of the requests being served, which
+// admins list and cancel under /admin/requests. Cancellations are recorded by
+// auditor. Must be called before Routes is served.
+func (h *Handler) SetInFlightTracker(tracker *middleware.InFlightTracker, auditor *audit.Logger) {
+	h.inFlight = tracker
+	h.auditor = auditor
+}
+
+// SetEffectiveConfig registers the configuration /admin/config/validate compares
+// candidates to. Must be called before Routes is served.
+func (h *Handler) SetEffectiveConfig(cfg *config.Config) {
+	h.effectiveConfig = cfg
+}
+
+// SetFeatureFlags registers the feature flags admins can toggle under /admin/flags.
+// Toggles are recorded by auditor. Must be called before Routes is served.
+func (h *Handler) SetFeatureFlags(flags *featureflags.Flags, auditor *audit.Logger) {
+	h.featureFlags = flags
+	h.auditor = auditor
+}
+
+// SetTrash registers the trash of soft-deleted artifacts admins can list and restore
+// under /admin/trash. Restores are recorded by auditor. Must be called before Routes
+// is served.
+func (h *Handler) SetTrash(t *trash.Trash, auditor *audit.Logger) {
+	h.trash = t
+	h.auditor = auditor
+}
+
+// SetPackageSources registers the protocol backends whose packages /packages lists,
+// queried with proxyClient. Must be called before Routes is served.
+func (h *Handler) SetPackageSources(protocols *config.ProtocolsConfig, proxyClient *proxy.Client) {
+	h.protocols = protocols
+	h.proxyClient = proxyClient
+}
+
+// SetMetadata registers the artifact metadata database that /packages draws on and
+// admins query under /admin/artifacts. Must be called before Routes is served.
+func (h *Handler) SetMetadata(store *metadata.Store) {
+	h.metadata = store
+}
+
+// SetURLSigner registers the signer of the download URLs users mint under
+// /signed-urls. baseURL is the external URL of Artifusion the URLs point at, or
+// empty to derive it from each request. Must be called before Routes is served.
+func (h *Handler) SetURLSigner(signer *auth.URLSigner, baseURL string) {
+	h.signer = signer
+	h.signerBase = strings.TrimSuffix(baseURL, "/")
+}
+
+// SetBrowserSessions registers the issuer of the browser sessions signed in under
+// /session with the GitHub device flow. The handler's authenticator must accept
+// the sessions (see auth.ClientAuthenticator.WithSessions). Must be called before
+// Routes is served.
+func (h *Handler) SetBrowserSessions(sessions *auth.SessionIssuer, deviceFlow *auth.DeviceFlow) {
+	h.sessions = sessions
+	h.deviceFlow = deviceFlow
+}
+
+// SetShadowLog registers the shadow log whose windows admins start and stop under
+// /admin/shadow-log. Windows are recorded by auditor. Must be called before Routes
+// is served.
+func (h *Handler) SetShadowLog(shadowLog *proxy.ShadowLog, auditor *audit.Logger) {
+	h.shadowLog = shadowLog
+	h.auditor = auditor
+}
+
+// Routes returns the API router, to be mounted at /api/v1
+func (h *Handler) Routes() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/authz/check", h.handleAuthzCheck)
+	r.Get("/limits", h.handleLimits)
+	if h.protocols != nil {
+		r.Get("/packages", h.handlePackages)
+	}
+	if h.signer != nil {
+		r.Post("/signed-urls", h.handleSignURL)
+	}
+	if h.sessions != nil {
+		r.Post("/session/device", h.handleStartSession)
+		r.Post("/session/token", h.handleCreateSession)
+	}
+	if h.protocols != nil && h.protocols.OCI.Enabled {
+		r.Get("/node-config", h.handleNodeConfig)
+		r.Get("/node-config/containerd/{registry}", h.handleContainerdHosts)
+		r.Get("/node-config/crio", h.handleCRIORegistries)
+	}
+	if h.protocols != nil && h.protocols.Maven.Enabled {
+		r.Get("/maven/settings.xml", h.handleMavenSettings)
+		r.Get("/maven/init.gradle", h.handleGradleInitScript)
+	}
+	if h.protocols != nil && h.protocols.NPM.Enabled {
+		r.Get("/npmrc", h.handleNPMRC)
+	}
+	r.Post("/admin/config/validate", h.handleValidateConfig)
+	if h.featureFlags != nil {
+		r.Get("/admin/flags", h.handleListFeatureFlags)
+		r.Put("/admin/flags/{name}", h.handleSetFeatureFlag)
+		r.Delete("/admin/flags/{name}", h.handleResetFeatureFlag)
+	}
+	if h.metadata != nil {
+		r.Get("/admin/artifacts", h.handleListArtifacts)
+	}
+	if h.trash != nil {
+		r.Get("/admin/trash", h.handleListTrash)
+		r.Post("/admin/trash/restore", h.handleRestoreTrash)
+	}
+	if h.inFlight != nil {
+		r.Get("/admin/requests", h.handleListInFlightRequests)
+		r.Post("/admin/requests/{id}/cancel", h.handleCancelInFlightRequest)
+	}
+	if h.shadowLog != nil {
+		r.Get("/admin/shadow-log", h.handleListShadowLog)
+		r.Post("/admin/shadow-log", h.handleStartShadowLog)
+		r.Delete("/admin/shadow-log/{backend}", h.handleStopShadowLog)
+	}
+	return r
+}
+
+// authenticate authenticates the API caller, writing an error response on failure
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (*auth.AuthResult, *http.Request, bool) {
+	authResult, r, err := h.authenticator.AuthenticateAndInjectContext(r)
+	if err == nil && authResult.TokenType == auth.TokenTypeSignedURL {
+		// Signed URLs grant artifact downloads, not the API
+		err = fmt.Errorf("signed URLs are not accepted by the API")
+	}
+	if err != nil {
+		h.logger.Debug().Err(err).Msg("API authentication failed")
+		if stderrors.Is(err, auth.ErrInsufficientPermissions) {
+			errors.ErrorResponse(w, errors.ErrForbidden.WithMessage(auth.InsufficientPermissionsMessage))
+			return nil, r, false
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="Artifusion API"`)
+		errors.ErrorResponse(w, errors.ErrUnauthorized)
+		return nil, r, false
+	}
+	return authResult, r, true
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func (h *Handler) writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode API response")
+	}
+}
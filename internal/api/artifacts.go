@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/metadata"
+)
+
+// ArtifactsResponse lists recorded artifacts, ordered by protocol, name and version
+type ArtifactsResponse struct {
+	Artifacts []metadata.Artifact `json:"artifacts"`
+}
+
+// handleListArtifacts queries the metadata database for audits and retention
+// decisions. Admin only.
+//
+// Query parameters:
+//   - protocol: Only artifacts of this protocol
+//   - q: Case-insensitive substring the artifact name must contain
+//   - uploader: Only artifacts last pushed by this user
+//   - not_pulled_for: Only artifacts not pulled for this duration, e.g. 720h
+//   - limit: Maximum number of artifacts returned (default 100, at most 1000)
+func (h *Handler) handleListArtifacts(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authenticateAdmin(w, r); !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := metadata.Filter{
+		Protocol: query.Get("protocol"),
+		Query:    query.Get("q"),
+		Uploader: query.Get("uploader"),
+	}
+
+	var ok bool
+	if filter.Limit, ok = parseLimit(query.Get("limit")); !ok {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessagef("limit must be between 1 and %d", maxPackagesLimit))
+		return
+	}
+	if value := query.Get("not_pulled_for"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage("not_pulled_for must be a positive duration, e.g. 720h"))
+			return
+		}
+		filter.NotPulledSince = time.Now().Add(-d)
+	}
+
+	artifacts, err := h.metadata.List(filter)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to list recorded artifacts")
+		errors.ErrorResponse(w, errors.ErrInternal.WithInternal(err))
+		return
+	}
+	h.writeJSON(w, http.StatusOK, ArtifactsResponse{Artifacts: artifacts})
+}
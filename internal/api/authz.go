@@ -0,0 +1,171 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/authz"
+	"github.com/mainuli/artifusion/internal/detector"
+	"github.com/mainuli/artifusion/internal/errors"
+)
+
+// handleAuthzCheck answers "would user X be allowed to do METHOD on PATH?" without
+// proxying anything.
+//
+// Query parameters:
+//   - path: Request path to evaluate (required), e.g. /v2/myorg/app/manifests/latest
+//   - method: HTTP method to evaluate (default GET)
+//   - host: Host header to evaluate, for host-based routing (default: this request's host)
+//   - user: GitHub username to evaluate (default: the caller; other users require admin)
+//
+// The response is always 200 with a Decision when the check could be evaluated;
+// "allowed" reports the outcome and "rules" lists each rule that was applied. The
+// checks requests get before reaching the protocol handler are evaluated for every
+// protocol: credentials restricted to protocols and the content policy. The
+// handler's own rules (routing, naming conventions) are only known for protocols
+// registered with RegisterExplainer; other protocols report an "unsupported" rule
+// and are never reported as allowed.
+func (h *Handler) handleAuthzCheck(w http.ResponseWriter, r *http.Request) {
+	caller, r, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" || !strings.HasPrefix(path, "/") {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage("path query parameter must be an absolute path"))
+		return
+	}
+
+	method := strings.ToUpper(query.Get("method"))
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	host := query.Get("host")
+	if host == "" {
+		// Honors X-Forwarded-Host so the default matches what clients behind a proxy see
+		host = detector.GetRequestHost(r)
+	}
+
+	// Build the request being evaluated; it is only inspected, never sent
+	target, err := http.NewRequestWithContext(r.Context(), method, path, nil)
+	if err != nil {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessagef("invalid method or path: %v", err))
+		return
+	}
+	target.Host = host
+
+	decision := &authz.Decision{
+		Method:    method,
+		Path:      target.URL.Path,
+		Operation: "read",
+	}
+	if auth.IsWriteMethod(method) {
+		decision.Operation = "write"
+	}
+
+	subject := caller
+	if user := query.Get("user"); user != "" && !strings.EqualFold(user, caller.Username) {
+		if !h.authenticator.IsAdmin(caller) {
+			errors.ErrorResponse(w, errors.ErrForbidden.WithMessage("checking other users requires admin privileges"))
+			return
+		}
+
+		decision.User = user
+		subject, err = h.authenticator.ResolveUser(r, caller, user)
+		if err != nil {
+			decision.Add("authentication", authz.ResultFail, err.Error())
+		} else {
+			decision.Add("authentication", authz.ResultPass, describeAuth(subject))
+		}
+		// Token permissions belong to a token, not a user
+		decision.Add("token_permissions", authz.ResultSkip, "not evaluated for other users")
+	} else {
+		decision.Add("authentication", authz.ResultPass, describeAuth(subject))
+		h.checkTokenPermissions(decision, subject)
+	}
+	if subject != nil {
+		decision.User = subject.Username
+	}
+
+	protocol := h.detectorChain.Detect(target)
+	decision.Protocol = string(protocol)
+	if protocol == detector.ProtocolUnknown {
+		decision.Add("protocol", authz.ResultFail, "no enabled protocol handler matches this host and path")
+	} else {
+		decision.Add("protocol", authz.ResultPass, fmt.Sprintf("handled by %s", protocol))
+		h.checkContentPolicy(decision, target, protocol)
+
+		// Protocol restrictions and routing rules only make sense for a user that
+		// authenticated
+		if subject != nil {
+			checkProtocolRestriction(decision, subject, protocol)
+			if explainer, ok := h.explainers[protocol]; ok {
+				decision.Rules = append(decision.Rules, explainer.Explain(target, subject)...)
+			} else {
+				decision.Add("routing", authz.ResultUnsupported, fmt.Sprintf("%s requests cannot be explained without proxying them", protocol))
+			}
+		}
+	}
+
+	decision.Finalize()
+	h.writeJSON(w, http.StatusOK, decision)
+}
+
+// checkTokenPermissions adds the fine-grained PAT permission rule for the caller's own token
+func (h *Handler) checkTokenPermissions(decision *authz.Decision, subject *auth.AuthResult) {
+	switch {
+	case subject.Permissions == nil:
+		decision.Add("token_permissions", authz.ResultSkip, "token is not restricted by operation")
+	case subject.Permissions.Allows(decision.Operation == "write"):
+		decision.Add("token_permissions", authz.ResultPass, fmt.Sprintf("fine-grained PAT grants %s", decision.Operation))
+	default:
+		decision.Add("token_permissions", authz.ResultFail, fmt.Sprintf("fine-grained PAT does not grant %s", decision.Operation))
+	}
+}
+
+// checkProtocolRestriction adds the rule of credentials restricted to protocols
+// (service accounts), which the protocol's authenticator enforces
+func checkProtocolRestriction(decision *authz.Decision, subject *auth.AuthResult, protocol detector.Protocol) {
+	switch {
+	case subject.Protocols == nil:
+		decision.Add("protocol_restriction", authz.ResultSkip, "credentials are not restricted by protocol")
+	case slices.Contains(subject.Protocols, string(protocol)):
+		decision.Add("protocol_restriction", authz.ResultPass, fmt.Sprintf("credentials are allowed for %s", protocol))
+	default:
+		decision.Add("protocol_restriction", authz.ResultFail, fmt.Sprintf("credentials are restricted to %s", strings.Join(subject.Protocols, ", ")))
+	}
+}
+
+// checkContentPolicy adds the content policy rule for the requested file extension.
+// Content types are only known from a backend's response, so they are not checked.
+func (h *Handler) checkContentPolicy(decision *authz.Decision, target *http.Request, protocol detector.Protocol) {
+	if h.contentPolicy == nil {
+		return
+	}
+	switch applies, allowed := h.contentPolicy.CheckExtension(string(protocol), target.URL.Path); {
+	case !applies:
+		decision.Add("content_policy", authz.ResultSkip, "no content policy rule applies to this path")
+	case allowed:
+		decision.Add("content_policy", authz.ResultPass, "file type is allowed; content types are checked on the response")
+	default:
+		decision.Add("content_policy", authz.ResultFail, "file type not served by this repository")
+	}
+}
+
+// describeAuth summarizes an authentication result for a rule detail
+func describeAuth(result *auth.AuthResult) string {
+	detail := fmt.Sprintf("authenticated as %s (%s)", result.Username, result.TokenType)
+	if result.Org != "" {
+		detail += fmt.Sprintf(", member of %s", result.Org)
+	}
+	if len(result.Teams) > 0 {
+		detail += fmt.Sprintf(", teams: %s", strings.Join(result.Teams, ", "))
+	}
+	return detail
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/audit"
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/authz"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/detector"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/rs/zerolog"
+)
+
+// TestHandleAuthzCheck_Unauthenticated tests that the endpoint requires authentication
+func TestHandleAuthzCheck_Unauthenticated(t *testing.T) {
+	authenticator := auth.NewClientAuthenticator(nil, "", nil, zerolog.Nop())
+	h := NewHandler(authenticator, detector.NewChain(), zerolog.Nop())
+
+	req := httptest.NewRequest(http.MethodGet, "/authz/check?path=/v2/", nil)
+	rec := httptest.NewRecorder()
+	h.Routes().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if rec.Header().Get("X-Error-Code") != "UNAUTHORIZED" {
+		t.Errorf("expected X-Error-Code UNAUTHORIZED, got %q", rec.Header().Get("X-Error-Code"))
+	}
+}
+
+// routingExplainer explains every request as routed to a single backend
+type routingExplainer struct{}
+
+func (routingExplainer) Explain(*http.Request, *auth.AuthResult) []authz.Rule {
+	return []authz.Rule{{Name: "backend", Result: authz.ResultPass}}
+}
+
+// TestHandleAuthzCheck tests that checks evaluate the protocol restrictions of
+// service accounts and the content policy for every protocol, and that protocols
+// without an explainer are never reported as allowed
+func TestHandleAuthzCheck(t *testing.T) {
+	h := newPackagesHandler(t, &config.ProtocolsConfig{})
+	h.detectorChain.Register(detector.NewOCIDetector(""))
+	h.detectorChain.Register(detector.NewMavenDetector("maven.example.com", ""))
+	h.RegisterExplainer(detector.ProtocolOCI, routingExplainer{})
+	h.SetContentPolicy(middleware.NewContentPolicy(&config.ContentPolicyConfig{
+		Enabled: true,
+		Rules:   []config.ContentPolicyRule{{Protocol: "maven", DenyExtensions: []string{".exe"}}},
+	}, nil, zerolog.Nop()))
+
+	h.authenticator.SetAdmin(&config.AdminConfig{Users: []string{"alice"}}, audit.New(zerolog.Nop()))
+	sum := sha256.Sum256([]byte(auth.ServiceAccountKeyPrefix + strings.Repeat("j", 40)))
+	serviceAccounts, err := auth.NewServiceAccountAuthenticator(&config.ServiceAccountsConfig{
+		Accounts: []config.ServiceAccountConfig{
+			{Name: "jenkins", KeyHash: "sha256:" + hex.EncodeToString(sum[:]), Protocols: []string{"maven"}},
+		},
+	}, "")
+	if err != nil {
+		t.Fatalf("NewServiceAccountAuthenticator() error = %v", err)
+	}
+	h.authenticator.RegisterProvider(serviceAccounts)
+	routes := h.Routes()
+
+	tests := []struct {
+		name        string
+		user        string
+		host        string
+		path        string
+		wantAllowed bool
+		wantRules   map[string]string
+	}{
+		{
+			name:        "explained protocol",
+			path:        "/v2/myorg/app/manifests/latest",
+			wantAllowed: true,
+			wantRules:   map[string]string{"protocol_restriction": authz.ResultSkip, "backend": authz.ResultPass},
+		},
+		{
+			name:      "protocol without explainer",
+			host:      "maven.example.com",
+			path:      "/com/acme/app/1.0/app-1.0.jar",
+			wantRules: map[string]string{"content_policy": authz.ResultPass, "routing": authz.ResultUnsupported},
+		},
+		{
+			name:      "denied by content policy",
+			host:      "maven.example.com",
+			path:      "/com/acme/tools/1.0/tools-1.0.exe",
+			wantRules: map[string]string{"content_policy": authz.ResultFail},
+		},
+		{
+			name:      "service account of another protocol",
+			user:      "jenkins",
+			path:      "/v2/myorg/app/manifests/latest",
+			wantRules: map[string]string{"authentication": authz.ResultPass, "protocol_restriction": authz.ResultFail},
+		},
+		{
+			name:      "service account of the protocol",
+			user:      "jenkins",
+			host:      "maven.example.com",
+			path:      "/com/acme/app/1.0/app-1.0.jar",
+			wantRules: map[string]string{"protocol_restriction": authz.ResultPass, "routing": authz.ResultUnsupported},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{"path": {tt.path}, "host": {tt.host}, "user": {tt.user}}
+			r := httptest.NewRequest(http.MethodGet, "/authz/check?"+query.Encode(), nil)
+			r.Header.Set("Authorization", "Bearer "+testToken)
+			w := httptest.NewRecorder()
+			routes.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+			}
+			var decision authz.Decision
+			if err := json.NewDecoder(w.Body).Decode(&decision); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if decision.Allowed != tt.wantAllowed {
+				t.Errorf("allowed = %v, want %v: %+v", decision.Allowed, tt.wantAllowed, decision.Rules)
+			}
+			results := make(map[string]string, len(decision.Rules))
+			for _, rule := range decision.Rules {
+				results[rule.Name] = rule.Result
+			}
+			for name, want := range tt.wantRules {
+				if results[name] != want {
+					t.Errorf("rule %s = %q, want %q: %+v", name, results[name], want, decision.Rules)
+				}
+			}
+		})
+	}
+}
+
+func TestDescribeAuth(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *auth.AuthResult
+		want   string
+	}{
+		{
+			name:   "user without org",
+			result: &auth.AuthResult{Username: "alice", TokenType: auth.TokenTypePAT},
+			want:   "authenticated as alice (pat)",
+		},
+		{
+			name:   "user with org and teams",
+			result: &auth.AuthResult{Username: "alice", TokenType: auth.TokenTypeOAuth, Org: "myorg", Teams: []string{"platform", "sre"}},
+			want:   "authenticated as alice (oauth), member of myorg, teams: platform, sre",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeAuth(tt.result); got != tt.want {
+				t.Errorf("describeAuth() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
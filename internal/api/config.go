@@ -0,0 +1,237 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/proxy"
+)
+
+// maxCandidateConfigSize bounds the candidate configuration /admin/config/validate accepts
+const maxCandidateConfigSize = 1 << 20
+
+// backendCheckTimeout bounds each backend reachability check of /admin/config/validate
+const backendCheckTimeout = 10 * time.Second
+
+// ConfigValidateResponse reports whether a candidate configuration would load, what
+// it would change and whether its backends are reachable
+type ConfigValidateResponse struct {
+	Valid    bool            `json:"valid"`
+	Error    string          `json:"error,omitempty"`    // Why the candidate is invalid
+	Changes  []config.Change `json:"changes"`            // Compared to the effective configuration
+	Backends []BackendCheck  `json:"backends,omitempty"` // One per configured backend
+}
+
+// BackendCheck is the result of a candidate backend reachability check. Any HTTP
+// response counts as reachable; Status shows whether the credentials were accepted.
+type BackendCheck struct {
+	Protocol  string  `json:"protocol"`
+	Role      string  `json:"role"` // pull, push, replication, backend, candidate or upstream
+	Name      string  `json:"name"`
+	URL       string  `json:"url"`
+	Reachable bool    `json:"reachable"`
+	Status    int     `json:"status,omitempty"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// handleValidateConfig dry-runs a configuration reload: the request body is a
+// candidate YAML configuration, which is parsed and validated like at startup,
+// compared to the effective configuration (secrets redacted) and whose backends
+// are contacted with their configured credentials. Nothing is applied. Admin only.
+func (h *Handler) handleValidateConfig(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticateAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCandidateConfigSize))
+	if err != nil || len(data) == 0 {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessagef("request body must be a YAML configuration of at most %d bytes", maxCandidateConfigSize))
+		return
+	}
+
+	h.logger.Info().Str("admin", caller.Username).Msg("Validating candidate configuration")
+	h.writeJSON(w, http.StatusOK, h.validateConfig(r.Context(), data))
+}
+
+// validateConfig parses and validates the candidate configuration data, diffs it
+// against the effective configuration and checks its backends
+func (h *Handler) validateConfig(ctx context.Context, data []byte) ConfigValidateResponse {
+	response := ConfigValidateResponse{Changes: []config.Change{}}
+	candidate, err := config.Parse(data)
+	if err != nil {
+		response.Error = err.Error()
+		return response
+	}
+
+	if err := candidate.Validate(); err != nil {
+		response.Error = err.Error()
+	} else {
+		response.Valid = true
+	}
+	if h.effectiveConfig != nil {
+		if changes := config.Diff(h.effectiveConfig, candidate); changes != nil {
+			response.Changes = changes
+		}
+	}
+	response.Backends = h.checkBackends(ctx, candidate)
+	return response
+}
+
+// checkBackends contacts every backend of the enabled protocols in cfg in parallel,
+// with a proxy client of its own so the running backends' connection pools and
+// circuit breakers are untouched
+func (h *Handler) checkBackends(ctx context.Context, cfg *config.Config) []BackendCheck {
+	type target struct {
+		check   BackendCheck
+		backend proxy.BackendConfig
+		path    string
+	}
+	var targets []target
+	add := func(protocol, role string, backend proxy.BackendConfig, path string) {
+		targets = append(targets, target{
+			check:   BackendCheck{Protocol: protocol, Role: role, Name: backend.GetName(), URL: backend.GetURL()},
+			backend: backend,
+			path:    path,
+		})
+	}
+
+	if oci := &cfg.Protocols.OCI; oci.Enabled {
+		for i := range oci.PullBackends {
+			add("oci", "pull", &oci.PullBackends[i], "/v2/")
+		}
+		if oci.PushBackend.URL != "" {
+			add("oci", "push", &oci.PushBackend, "/v2/")
+		}
+		if oci.Replication.Enabled {
+			add("oci", "replication", &oci.Replication.Target, "/v2/")
+		}
+	}
+	if maven := &cfg.Protocols.Maven; maven.Enabled {
+		add("maven", "backend", &maven.Backend, "/")
+		if maven.Candidate != nil {
+			add("maven", "candidate", maven.Candidate, "/")
+		}
+		if maven.Upstream != nil {
+			add("maven", "upstream", maven.Upstream, "/")
+		}
+	}
+	if npm := &cfg.Protocols.NPM; npm.Enabled {
+		add("npm", "backend", &npm.Backend, "/-/ping")
+		if npm.Candidate != nil {
+			add("npm", "candidate", npm.Candidate, "/-/ping")
+		}
+		if npm.Upstream != nil {
+			add("npm", "upstream", npm.Upstream, "/-/ping")
+		}
+	}
+	if rubyGems := &cfg.Protocols.RubyGems; rubyGems.Enabled {
+		add("rubygems", "backend", &rubyGems.Backend, "/versions")
+	}
+	if helm := &cfg.Protocols.Helm; helm.Enabled {
+		add("helm", "backend", &helm.Backend, "/index.yaml")
+	}
+	if apt := &cfg.Protocols.APT; apt.Enabled {
+		add("apt", "backend", &apt.Backend, "/dists/")
+	}
+	if composer := &cfg.Protocols.Composer; composer.Enabled {
+		add("composer", "backend", &composer.Backend, "/packages.json")
+		if composer.Upstream != nil {
+			add("composer", "upstream", composer.Upstream, "/packages.json")
+		}
+	}
+	if conda := &cfg.Protocols.Conda; conda.Enabled {
+		for i := range conda.Channels {
+			add("conda", "channel", &conda.Channels[i].Backend, "/noarch/repodata.json")
+		}
+	}
+	if terraform := &cfg.Protocols.Terraform; terraform.Enabled {
+		add("terraform", "backend", &terraform.Backend, "/.well-known/terraform.json")
+	}
+	if apk := &cfg.Protocols.APK; apk.Enabled {
+		add("apk", "backend", &apk.Backend, "/")
+	}
+	if raw := &cfg.Protocols.Raw; raw.Enabled {
+		for i := range raw.Repositories {
+			add("raw", "repository", &raw.Repositories[i].Backend, "/")
+		}
+	}
+	if lfs := &cfg.Protocols.LFS; lfs.Enabled {
+		add("lfs", "backend", &lfs.Backend, "/")
+	}
+	if cocoaPods := &cfg.Protocols.CocoaPods; cocoaPods.Enabled {
+		add("cocoapods", "backend", &cocoaPods.Backend, "/CocoaPods-version.yml")
+		if cocoaPods.BinaryBackend != nil {
+			add("cocoapods", "binary_backend", cocoaPods.BinaryBackend, "/")
+		}
+	}
+	if hex := &cfg.Protocols.Hex; hex.Enabled {
+		add("hex", "backend", &hex.Backend, "/names")
+		if hex.Upstream != nil {
+			add("hex", "upstream", hex.Upstream, "/names")
+		}
+		if hex.PublishBackend != nil {
+			add("hex", "publish_backend", hex.PublishBackend, "/")
+		}
+	}
+	if pub := &cfg.Protocols.Pub; pub.Enabled {
+		add("pub", "backend", &pub.Backend, "/")
+	}
+	if vagrant := &cfg.Protocols.Vagrant; vagrant.Enabled {
+		add("vagrant", "backend", &vagrant.Backend, "/")
+		for i := range vagrant.DownloadBackends {
+			add("vagrant", "download_backend", &vagrant.DownloadBackends[i], "/")
+		}
+	}
+
+	client := proxy.NewClient(h.logger, nil, nil)
+	checks := make([]BackendCheck, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checks[i] = t.check
+			checkBackend(ctx, client, t.backend, t.path, &checks[i])
+		}()
+	}
+	wg.Wait()
+	return checks
+}
+
+// checkBackend sends a GET for path to backend and records the outcome in check
+func checkBackend(ctx context.Context, client *proxy.Client, backend proxy.BackendConfig, path string, check *BackendCheck) {
+	ctx, cancel := context.WithTimeout(ctx, backendCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		check.Error = err.Error()
+		return
+	}
+
+	start := time.Now()
+	resp, err := client.ProxyRequest(&proxy.Request{
+		Method:      http.MethodGet,
+		Path:        path,
+		Headers:     http.Header{},
+		Backend:     backend,
+		OriginalReq: req,
+	})
+	check.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		check.Error = err.Error()
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxCandidateConfigSize))
+	_ = resp.Body.Close()
+
+	check.Reachable = true
+	check.Status = resp.StatusCode
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/audit"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func TestHandleValidateConfig(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer registry.Close()
+
+	h := newPackagesHandler(t, &config.ProtocolsConfig{})
+	h.authenticator.SetAdmin(&config.AdminConfig{Users: []string{"alice"}}, audit.New(zerolog.Nop()))
+	current, err := config.Parse([]byte("server:\n  port: 8080\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	h.SetEffectiveConfig(current)
+	routes := h.Routes()
+
+	validate := func(candidate string) (int, ConfigValidateResponse) {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodPost, "/admin/config/validate", strings.NewReader(candidate))
+		r.Header.Set("Authorization", "Bearer "+testToken)
+		w := httptest.NewRecorder()
+		routes.ServeHTTP(w, r)
+
+		var response ConfigValidateResponse
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+		}
+		return w.Code, response
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		status, response := validate(`
+server:
+  port: 9090
+protocols:
+  oci:
+    enabled: true
+    pull_backends:
+      - name: registry
+        url: ` + registry.URL + `
+      - name: down
+        url: http://127.0.0.1:1
+    push_backend:
+      name: local
+      url: ` + registry.URL + `
+`)
+		if status != http.StatusOK || !response.Valid {
+			t.Fatalf("status = %d, response = %+v, want a valid candidate", status, response)
+		}
+		var portChanged bool
+		for _, change := range response.Changes {
+			if change.Key == "server.port" && change.Old == "8080" && change.New == "9090" {
+				portChanged = true
+			}
+		}
+		if !portChanged {
+			t.Errorf("changes = %+v, want server.port 8080 -> 9090", response.Changes)
+		}
+
+		if len(response.Backends) != 3 {
+			t.Fatalf("backends = %+v, want the pull and push backends", response.Backends)
+		}
+		if b := response.Backends[0]; !b.Reachable || b.Status != http.StatusUnauthorized {
+			t.Errorf("registry check = %+v, want reachable with status 401", b)
+		}
+		if b := response.Backends[1]; b.Reachable || b.Error == "" {
+			t.Errorf("down check = %+v, want unreachable with an error", b)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		status, response := validate("server:\n  port: 70000\n")
+		if status != http.StatusOK || response.Valid || !strings.Contains(response.Error, "invalid port") {
+			t.Errorf("status = %d, response = %+v, want an invalid port error", status, response)
+		}
+	})
+
+	t.Run("unparsable", func(t *testing.T) {
+		status, response := validate("server: [")
+		if status != http.StatusOK || response.Valid || response.Error == "" {
+			t.Errorf("status = %d, response = %+v, want a parse error", status, response)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		if status, _ := validate(""); status != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", status, http.StatusBadRequest)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/featureflags"
+)
+
+// FeatureFlagsResponse lists the configured feature flags and their current state
+type FeatureFlagsResponse struct {
+	Flags []featureflags.Flag `json:"flags"`
+}
+
+// SetFeatureFlagRequest overrides a feature flag
+type SetFeatureFlagRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// authenticateAdmin authenticates the API caller and requires admin privileges,
+// writing an error response on failure
+func (h *Handler) authenticateAdmin(w http.ResponseWriter, r *http.Request) (*auth.AuthResult, bool) {
+	caller, _, ok := h.authenticate(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if !h.authenticator.IsAdmin(caller) {
+		errors.ErrorResponse(w, errors.ErrForbidden)
+		return nil, false
+	}
+
+	return caller, true
+}
+
+// handleListFeatureFlags returns every configured feature flag. Admin only.
+func (h *Handler) handleListFeatureFlags(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authenticateAdmin(w, r); !ok {
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, FeatureFlagsResponse{Flags: h.featureFlags.All()})
+}
+
+// handleSetFeatureFlag overrides a feature flag at runtime with {"enabled": bool}.
+// Admin only; every toggle is audited.
+func (h *Handler) handleSetFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticateAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetFeatureFlagRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil || req.Enabled == nil {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage(`request body must be {"enabled": true|false}`))
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	flag, err := h.featureFlags.Set(name, *req.Enabled)
+	if err != nil {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage(err.Error()))
+		return
+	}
+
+	h.auditor.Record(r, "feature_flag_set").
+		Str("admin", caller.Username).
+		Str("flag", name).
+		Bool("enabled", flag.Enabled).
+		Msg("Feature flag overridden")
+
+	h.writeJSON(w, http.StatusOK, flag)
+}
+
+// handleResetFeatureFlag removes a feature flag's runtime override, restoring its
+// configured state. Admin only; every reset is audited.
+func (h *Handler) handleResetFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticateAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	flag, err := h.featureFlags.Reset(name)
+	if err != nil {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage(err.Error()))
+		return
+	}
+
+	h.auditor.Record(r, "feature_flag_reset").
+		Str("admin", caller.Username).
+		Str("flag", name).
+		Bool("enabled", flag.Enabled).
+		Msg("Feature flag reset to configured state")
+
+	h.writeJSON(w, http.StatusOK, flag)
+}
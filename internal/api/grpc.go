@@ -0,0 +1,316 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	adminv1 "github.com/mainuli/artifusion/api/admin/v1"
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/featureflags"
+	"github.com/mainuli/artifusion/internal/metadata"
+	"github.com/mainuli/artifusion/internal/trash"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcAdminServer serves the admin endpoints over gRPC, sharing the handler's stores,
+// authentication and audit log with the HTTP API
+type grpcAdminServer struct {
+	adminv1.UnimplementedAdminServiceServer
+	h *Handler
+}
+
+// RegisterGRPC registers the admin API with a gRPC server. Must be called after the
+// handler's stores are set and before the server is started.
+func (h *Handler) RegisterGRPC(s *grpc.Server) {
+	adminv1.RegisterAdminServiceServer(s, &grpcAdminServer{h: h})
+}
+
+// authenticateAdmin authenticates the RPC caller from its "authorization" metadata
+// like an HTTP API caller and requires admin privileges. It returns an HTTP request
+// standing in for the RPC, so audit records look like those of the HTTP API.
+func (s *grpcAdminServer) authenticateAdmin(ctx context.Context, mutation bool) (*auth.AuthResult, *http.Request, error) {
+	method := http.MethodGet
+	if mutation {
+		method = http.MethodPost
+	}
+	fullMethod, _ := grpc.Method(ctx) // e.g. /artifusion.admin.v1.AdminService/SetFeatureFlag
+	r, err := http.NewRequestWithContext(ctx, method, fullMethod, nil)
+	if err != nil {
+		return nil, nil, status.Error(codes.Internal, err.Error())
+	}
+	if md, ok := grpcmetadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			r.Header.Set("Authorization", values[0])
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.RemoteAddr = p.Addr.String()
+	}
+
+	caller, r, err := s.h.authenticator.AuthenticateAndInjectContext(r)
+	if err == nil && caller.TokenType == auth.TokenTypeSignedURL {
+		err = fmt.Errorf("signed URLs are not accepted by the API")
+	}
+	if err != nil {
+		s.h.logger.Debug().Err(err).Str("method", fullMethod).Msg("gRPC authentication failed")
+		return nil, nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+	if !s.h.authenticator.IsAdmin(caller) {
+		return nil, nil, status.Error(codes.PermissionDenied, "admin privileges required")
+	}
+	return caller, r, nil
+}
+
+// ValidateConfig dry-runs a configuration reload of the candidate configuration
+func (s *grpcAdminServer) ValidateConfig(ctx context.Context, req *adminv1.ValidateConfigRequest) (*adminv1.ValidateConfigResponse, error) {
+	caller, _, err := s.authenticateAdmin(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.GetConfig()) == 0 || len(req.GetConfig()) > maxCandidateConfigSize {
+		return nil, status.Errorf(codes.InvalidArgument, "config must be a YAML configuration of at most %d bytes", maxCandidateConfigSize)
+	}
+
+	s.h.logger.Info().Str("admin", caller.Username).Msg("Validating candidate configuration")
+	result := s.h.validateConfig(ctx, req.GetConfig())
+
+	response := &adminv1.ValidateConfigResponse{
+		Valid:   result.Valid,
+		Error:   result.Error,
+		Changes: configChangesToProto(result.Changes),
+	}
+	for _, check := range result.Backends {
+		response.Backends = append(response.Backends, &adminv1.BackendCheck{
+			Protocol:  check.Protocol,
+			Role:      check.Role,
+			Name:      check.Name,
+			Url:       check.URL,
+			Reachable: check.Reachable,
+			Status:    int32(check.Status),
+			Error:     check.Error,
+			Latency:   durationpb.New(time.Duration(check.LatencyMs * float64(time.Millisecond))),
+		})
+	}
+	return response, nil
+}
+
+// ListFeatureFlags returns every configured feature flag
+func (s *grpcAdminServer) ListFeatureFlags(ctx context.Context, _ *adminv1.ListFeatureFlagsRequest) (*adminv1.ListFeatureFlagsResponse, error) {
+	if _, _, err := s.authenticateAdmin(ctx, false); err != nil {
+		return nil, err
+	}
+	if s.h.featureFlags == nil {
+		return nil, status.Error(codes.FailedPrecondition, "feature flags are not available")
+	}
+
+	response := &adminv1.ListFeatureFlagsResponse{}
+	for _, flag := range s.h.featureFlags.All() {
+		response.Flags = append(response.Flags, featureFlagToProto(flag))
+	}
+	return response, nil
+}
+
+// SetFeatureFlag overrides a feature flag at runtime. Every toggle is audited.
+func (s *grpcAdminServer) SetFeatureFlag(ctx context.Context, req *adminv1.SetFeatureFlagRequest) (*adminv1.FeatureFlag, error) {
+	caller, r, err := s.authenticateAdmin(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	if s.h.featureFlags == nil {
+		return nil, status.Error(codes.FailedPrecondition, "feature flags are not available")
+	}
+
+	flag, err := s.h.featureFlags.Set(req.GetName(), req.GetEnabled())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	s.h.auditor.Record(r, "feature_flag_set").
+		Str("admin", caller.Username).
+		Str("flag", flag.Name).
+		Bool("enabled", flag.Enabled).
+		Msg("Feature flag overridden")
+
+	return featureFlagToProto(flag), nil
+}
+
+// ResetFeatureFlag restores a feature flag's configured state. Every reset is audited.
+func (s *grpcAdminServer) ResetFeatureFlag(ctx context.Context, req *adminv1.ResetFeatureFlagRequest) (*adminv1.FeatureFlag, error) {
+	caller, r, err := s.authenticateAdmin(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	if s.h.featureFlags == nil {
+		return nil, status.Error(codes.FailedPrecondition, "feature flags are not available")
+	}
+
+	flag, err := s.h.featureFlags.Reset(req.GetName())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	s.h.auditor.Record(r, "feature_flag_reset").
+		Str("admin", caller.Username).
+		Str("flag", flag.Name).
+		Bool("enabled", flag.Enabled).
+		Msg("Feature flag reset to configured state")
+
+	return featureFlagToProto(flag), nil
+}
+
+// ListTrash returns the artifacts held in the trash
+func (s *grpcAdminServer) ListTrash(ctx context.Context, _ *adminv1.ListTrashRequest) (*adminv1.ListTrashResponse, error) {
+	if _, _, err := s.authenticateAdmin(ctx, false); err != nil {
+		return nil, err
+	}
+	if s.h.trash == nil {
+		return nil, status.Error(codes.FailedPrecondition, "trash is disabled")
+	}
+
+	response := &adminv1.ListTrashResponse{}
+	for _, entry := range s.h.trash.Entries() {
+		response.Entries = append(response.Entries, trashEntryToProto(entry))
+	}
+	return response, nil
+}
+
+// RestoreTrash takes an artifact out of the trash. Every restore is audited.
+func (s *grpcAdminServer) RestoreTrash(ctx context.Context, req *adminv1.RestoreTrashRequest) (*adminv1.TrashEntry, error) {
+	caller, r, err := s.authenticateAdmin(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	if s.h.trash == nil {
+		return nil, status.Error(codes.FailedPrecondition, "trash is disabled")
+	}
+	if req.GetPath() == "" {
+		return nil, status.Error(codes.InvalidArgument, "path is required")
+	}
+
+	entry, ok := s.h.trash.Remove(req.GetPath())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "%s is not in the trash", req.GetPath())
+	}
+
+	s.h.auditor.Record(r, "trash_restore").
+		Str("admin", caller.Username).
+		Str("artifact", entry.Path).
+		Str("deleted_by", entry.DeletedBy).
+		Time("deleted_at", entry.DeletedAt).
+		Msg("Trashed artifact restored")
+
+	return trashEntryToProto(entry), nil
+}
+
+// ListArtifacts queries the artifact metadata database
+func (s *grpcAdminServer) ListArtifacts(ctx context.Context, req *adminv1.ListArtifactsRequest) (*adminv1.ListArtifactsResponse, error) {
+	if _, _, err := s.authenticateAdmin(ctx, false); err != nil {
+		return nil, err
+	}
+	if s.h.metadata == nil {
+		return nil, status.Error(codes.FailedPrecondition, "metadata database is disabled")
+	}
+
+	filter := metadata.Filter{
+		Protocol: req.GetProtocol(),
+		Query:    req.GetQuery(),
+		Uploader: req.GetUploader(),
+		Limit:    int(req.GetLimit()),
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultPackagesLimit
+	}
+	if filter.Limit < 1 || filter.Limit > maxPackagesLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 1 and %d", maxPackagesLimit)
+	}
+	if req.GetNotPulledFor() != nil {
+		d := req.GetNotPulledFor().AsDuration()
+		if d <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "not_pulled_for must be a positive duration")
+		}
+		filter.NotPulledSince = time.Now().Add(-d)
+	}
+
+	artifacts, err := s.h.metadata.List(filter)
+	if err != nil {
+		s.h.logger.Error().Err(err).Msg("Failed to list recorded artifacts")
+		return nil, status.Error(codes.Internal, "failed to list artifacts")
+	}
+
+	response := &adminv1.ListArtifactsResponse{}
+	for i := range artifacts {
+		response.Artifacts = append(response.Artifacts, artifactToProto(&artifacts[i]))
+	}
+	return response, nil
+}
+
+// configChangesToProto converts configuration changes to their protobuf messages
+func configChangesToProto(changes []config.Change) []*adminv1.ConfigChange {
+	messages := make([]*adminv1.ConfigChange, 0, len(changes))
+	for _, change := range changes {
+		messages = append(messages, &adminv1.ConfigChange{Key: change.Key, Old: change.Old, New: change.New})
+	}
+	return messages
+}
+
+// featureFlagToProto converts a feature flag to its protobuf message
+func featureFlagToProto(flag featureflags.Flag) *adminv1.FeatureFlag {
+	return &adminv1.FeatureFlag{
+		Name:       flag.Name,
+		Enabled:    flag.Enabled,
+		Default:    flag.Default,
+		Overridden: flag.Overridden,
+	}
+}
+
+// trashEntryToProto converts a trash entry to its protobuf message
+func trashEntryToProto(entry trash.Entry) *adminv1.TrashEntry {
+	return &adminv1.TrashEntry{
+		Path:      entry.Path,
+		DeletedBy: entry.DeletedBy,
+		DeletedAt: timestamppb.New(entry.DeletedAt),
+		PurgeAt:   timestamppb.New(entry.PurgeAt),
+	}
+}
+
+// artifactToProto converts a recorded artifact to its protobuf message, leaving
+// unknown times unset
+func artifactToProto(artifact *metadata.Artifact) *adminv1.Artifact {
+	timestamp := func(t time.Time) *timestamppb.Timestamp {
+		if t.IsZero() {
+			return nil
+		}
+		return timestamppb.New(t)
+	}
+
+	message := &adminv1.Artifact{
+		Protocol:   artifact.Protocol,
+		Name:       artifact.Name,
+		Version:    artifact.Version,
+		Digest:     artifact.Digest,
+		FirstSeen:  timestamp(artifact.FirstSeen),
+		LastSeen:   timestamp(artifact.LastSeen),
+		LastPulled: timestamp(artifact.LastPulled),
+		Pulls:      artifact.Pulls,
+		UploadedAt: timestamp(artifact.UploadedAt),
+	}
+	if p := artifact.Provenance; p != nil {
+		message.Provenance = &adminv1.Provenance{
+			User:           p.User,
+			ImpersonatedBy: p.ImpersonatedBy,
+			TokenType:      p.TokenType,
+			Repository:     p.Repository,
+			Ci:             p.CI,
+		}
+	}
+	return message
+}
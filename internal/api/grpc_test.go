@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	adminv1 "github.com/mainuli/artifusion/api/admin/v1"
+	"github.com/mainuli/artifusion/internal/audit"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/featureflags"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGRPCAdminService(t *testing.T) {
+	h := newPackagesHandler(t, &config.ProtocolsConfig{})
+	auditor := audit.New(zerolog.Nop())
+	h.SetFeatureFlags(featureflags.New(map[string]bool{"new_ui": false}), auditor)
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	h.RegisterGRPC(server)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	client := adminv1.NewAdminServiceClient(conn)
+
+	authorized := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+testToken)
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := client.ListFeatureFlags(context.Background(), &adminv1.ListFeatureFlagsRequest{})
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("ListFeatureFlags() error = %v, want Unauthenticated", err)
+		}
+	})
+
+	t.Run("not admin", func(t *testing.T) {
+		_, err := client.ListFeatureFlags(authorized, &adminv1.ListFeatureFlagsRequest{})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("ListFeatureFlags() error = %v, want PermissionDenied", err)
+		}
+	})
+
+	h.authenticator.SetAdmin(&config.AdminConfig{Users: []string{"alice"}}, auditor)
+
+	t.Run("set feature flag", func(t *testing.T) {
+		flag, err := client.SetFeatureFlag(authorized, &adminv1.SetFeatureFlagRequest{Name: "new_ui", Enabled: true})
+		if err != nil {
+			t.Fatalf("SetFeatureFlag() error = %v", err)
+		}
+		if !flag.GetEnabled() || !flag.GetOverridden() || flag.GetDefault() {
+			t.Errorf("SetFeatureFlag() = %v, want enabled override of a disabled flag", flag)
+		}
+
+		flags, err := client.ListFeatureFlags(authorized, &adminv1.ListFeatureFlagsRequest{})
+		if err != nil {
+			t.Fatalf("ListFeatureFlags() error = %v", err)
+		}
+		if len(flags.GetFlags()) != 1 || !flags.GetFlags()[0].GetEnabled() {
+			t.Errorf("ListFeatureFlags() = %v, want new_ui enabled", flags.GetFlags())
+		}
+	})
+
+	t.Run("unknown feature flag", func(t *testing.T) {
+		_, err := client.ResetFeatureFlag(authorized, &adminv1.ResetFeatureFlagRequest{Name: "missing"})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("ResetFeatureFlag() error = %v, want InvalidArgument", err)
+		}
+	})
+
+	t.Run("trash disabled", func(t *testing.T) {
+		_, err := client.ListTrash(authorized, &adminv1.ListTrashRequest{})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("ListTrash() error = %v, want FailedPrecondition", err)
+		}
+	})
+
+	t.Run("validate config", func(t *testing.T) {
+		response, err := client.ValidateConfig(authorized, &adminv1.ValidateConfigRequest{Config: []byte("server:\n  port: 0\n")})
+		if err != nil {
+			t.Fatalf("ValidateConfig() error = %v", err)
+		}
+		if response.GetValid() || response.GetError() == "" {
+			t.Errorf("ValidateConfig() = %v, want invalid with an error", response)
+		}
+	})
+}
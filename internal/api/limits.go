@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// LimitsResponse reports the rate limits and concurrency usage that apply to the caller
+type LimitsResponse struct {
+	User         string                  `json:"user"`
+	Global       middleware.BucketStatus `json:"global"`
+	PerUser      middleware.BucketStatus `json:"per_user"`
+	PerUserWrite middleware.BucketStatus `json:"per_user_write"` // Enabled only if writes have their own limit
+	Concurrency  ConcurrencyStatus       `json:"concurrency"`
+}
+
+// ConcurrencyStatus reports server-wide concurrent request usage
+type ConcurrencyStatus struct {
+	Enabled   bool `json:"enabled"`
+	Active    int  `json:"active"`
+	Max       int  `json:"max,omitempty"`
+	Available int  `json:"available,omitempty"`
+	Queued    int  `json:"queued"`
+}
+
+// handleLimits returns the caller's current rate limit buckets and concurrency usage,
+// so clients such as CI jobs can throttle themselves before hitting 429/503 responses.
+//
+// Bucket values are a snapshot: this request itself has already been counted.
+func (h *Handler) handleLimits(w http.ResponseWriter, r *http.Request) {
+	caller, _, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	response := LimitsResponse{User: caller.Username}
+
+	if h.rateLimiter != nil {
+		response.Global = h.rateLimiter.GlobalStatus()
+		response.PerUser = h.rateLimiter.UserStatus(caller.Username)
+		response.PerUserWrite = h.rateLimiter.UserWriteStatus(caller.Username)
+	}
+
+	if h.concurrencyLimiter != nil {
+		active := int(h.concurrencyLimiter.ActiveRequests())
+		maxConcurrent := h.concurrencyLimiter.MaxConcurrent()
+		response.Concurrency = ConcurrencyStatus{
+			Enabled:   true,
+			Active:    active,
+			Max:       maxConcurrent,
+			Available: max(maxConcurrent-active, 0),
+			Queued:    int(h.concurrencyLimiter.QueuedRequests()),
+		}
+	}
+
+	h.writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/detector"
+)
+
+// Generated Maven and Gradle configuration reads the client's credentials from
+// these environment variables, and names the repository mavenServerID
+const (
+	mavenUsernameVariable = "ARTIFUSION_USERNAME"
+	mavenTokenVariable    = "ARTIFUSION_TOKEN"
+	mavenServerID         = "artifusion"
+)
+
+// handleMavenSettings returns a Maven settings.xml that mirrors every repository
+// through Artifusion, with the credentials read from the environment
+func (h *Handler) handleMavenSettings(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := h.authenticate(w, r); !ok {
+		return
+	}
+
+	repositoryURL := xmlEscape(h.mavenRepositoryURL(r))
+
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&b, "<!-- Generated by Artifusion: resolve Maven artifacts through %s -->\n", repositoryURL)
+	fmt.Fprintf(&b, "<!-- Set %s and %s (a GitHub token) before running Maven -->\n", mavenUsernameVariable, mavenTokenVariable)
+	b.WriteString("<settings xmlns=\"http://maven.apache.org/SETTINGS/1.2.0\"\n")
+	b.WriteString("          xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\"\n")
+	b.WriteString("          xsi:schemaLocation=\"http://maven.apache.org/SETTINGS/1.2.0 https://maven.apache.org/xsd/settings-1.2.0.xsd\">\n")
+	b.WriteString("  <servers>\n")
+	b.WriteString("    <server>\n")
+	fmt.Fprintf(&b, "      <id>%s</id>\n", mavenServerID)
+	fmt.Fprintf(&b, "      <username>${env.%s}</username>\n", mavenUsernameVariable)
+	fmt.Fprintf(&b, "      <password>${env.%s}</password>\n", mavenTokenVariable)
+	b.WriteString("    </server>\n")
+	b.WriteString("  </servers>\n")
+	b.WriteString("  <mirrors>\n")
+	b.WriteString("    <mirror>\n")
+	fmt.Fprintf(&b, "      <id>%s</id>\n", mavenServerID)
+	b.WriteString("      <name>Artifusion</name>\n")
+	b.WriteString("      <mirrorOf>*</mirrorOf>\n")
+	fmt.Fprintf(&b, "      <url>%s</url>\n", repositoryURL)
+	b.WriteString("    </mirror>\n")
+	b.WriteString("  </mirrors>\n")
+	b.WriteString("</settings>\n")
+
+	h.writeConfigFile(w, "application/xml; charset=utf-8", b.String())
+}
+
+// handleGradleInitScript returns a Gradle init script that replaces the Maven
+// repositories of every project, and of plugin resolution, with Artifusion, with
+// the credentials read from the environment. Install it as
+// ~/.gradle/init.d/artifusion.gradle.
+func (h *Handler) handleGradleInitScript(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := h.authenticate(w, r); !ok {
+		return
+	}
+
+	repositoryURL := h.mavenRepositoryURL(r)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Generated by Artifusion: resolve Maven artifacts through %s\n", repositoryURL)
+	b.WriteString("// Install as ~/.gradle/init.d/artifusion.gradle\n")
+	fmt.Fprintf(&b, "// Set %s and %s (a GitHub token) before running Gradle\n", mavenUsernameVariable, mavenTokenVariable)
+	fmt.Fprintf(&b, "def artifusionUrl = %s\n\n", groovyQuote(repositoryURL))
+	b.WriteString("def artifusion = { RepositoryHandler repositories ->\n")
+	b.WriteString("    repositories.all { ArtifactRepository repo ->\n")
+	b.WriteString("        if (repo instanceof MavenArtifactRepository && repo.url.toString() != artifusionUrl) {\n")
+	b.WriteString("            repositories.remove repo\n")
+	b.WriteString("        }\n")
+	b.WriteString("    }\n")
+	b.WriteString("    repositories.maven {\n")
+	fmt.Fprintf(&b, "        name = %s\n", groovyQuote(mavenServerID))
+	b.WriteString("        url = artifusionUrl\n")
+	b.WriteString("        credentials {\n")
+	fmt.Fprintf(&b, "            username = System.getenv(%s)\n", groovyQuote(mavenUsernameVariable))
+	fmt.Fprintf(&b, "            password = System.getenv(%s)\n", groovyQuote(mavenTokenVariable))
+	b.WriteString("        }\n")
+	b.WriteString("    }\n")
+	b.WriteString("}\n\n")
+	b.WriteString("settingsEvaluated { settings ->\n")
+	b.WriteString("    artifusion(settings.pluginManagement.repositories)\n")
+	b.WriteString("}\n\n")
+	b.WriteString("allprojects {\n")
+	b.WriteString("    artifusion(buildscript.repositories)\n")
+	b.WriteString("    artifusion(repositories)\n")
+	b.WriteString("}\n")
+
+	h.writeConfigFile(w, "text/plain; charset=utf-8", b.String())
+}
+
+// mavenRepositoryURL returns the URL Maven and Gradle reach Artifusion's Maven
+// repository at: the configured Maven host, or else the host the request was sent
+// to, followed by the path prefix
+func (h *Handler) mavenRepositoryURL(r *http.Request) string {
+	host := h.protocols.Maven.Host
+	if host == "" {
+		host = detector.GetRequestHost(r)
+	}
+	return detector.GetRequestScheme(r) + "://" + host + h.protocols.Maven.PathPrefix + "/"
+}
+
+// xmlEscape escapes s for use as XML character data
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// groovyQuote returns s as a single-quoted Groovy string literal
+func groovyQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
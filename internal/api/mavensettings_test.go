@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+func TestHandleMavenSettings(t *testing.T) {
+	protocols := &config.ProtocolsConfig{}
+	protocols.Maven.Enabled = true
+	protocols.Maven.Host = "maven.example.com"
+	protocols.Maven.PathPrefix = "/maven"
+
+	h := newPackagesHandler(t, protocols)
+	get := func(path string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.SetBasicAuth("alice", testToken)
+		rec := httptest.NewRecorder()
+		h.Routes().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, want %d", path, rec.Code, http.StatusOK)
+		}
+		return rec
+	}
+
+	t.Run("settings.xml", func(t *testing.T) {
+		var settings struct {
+			Servers []struct {
+				ID       string `xml:"id"`
+				Username string `xml:"username"`
+				Password string `xml:"password"`
+			} `xml:"servers>server"`
+			Mirrors []struct {
+				ID       string `xml:"id"`
+				MirrorOf string `xml:"mirrorOf"`
+				URL      string `xml:"url"`
+			} `xml:"mirrors>mirror"`
+		}
+		if err := xml.Unmarshal(get("/maven/settings.xml").Body.Bytes(), &settings); err != nil {
+			t.Fatalf("invalid settings.xml: %v", err)
+		}
+
+		if len(settings.Servers) != 1 || settings.Servers[0].ID != "artifusion" ||
+			settings.Servers[0].Username != "${env.ARTIFUSION_USERNAME}" || settings.Servers[0].Password != "${env.ARTIFUSION_TOKEN}" {
+			t.Errorf("servers = %+v, want the artifusion server with credentials from the environment", settings.Servers)
+		}
+		if len(settings.Mirrors) != 1 || settings.Mirrors[0].ID != "artifusion" ||
+			settings.Mirrors[0].MirrorOf != "*" || settings.Mirrors[0].URL != "https://maven.example.com/maven/" {
+			t.Errorf("mirrors = %+v, want artifusion mirroring * at https://maven.example.com/maven/", settings.Mirrors)
+		}
+	})
+
+	t.Run("init.gradle", func(t *testing.T) {
+		body := get("/maven/init.gradle").Body.String()
+		for _, line := range []string{
+			"def artifusionUrl = 'https://maven.example.com/maven/'",
+			"        name = 'artifusion'",
+			"            username = System.getenv('ARTIFUSION_USERNAME')",
+			"            password = System.getenv('ARTIFUSION_TOKEN')",
+			"    artifusion(settings.pluginManagement.repositories)",
+			"    artifusion(repositories)",
+		} {
+			if !strings.Contains(body, line+"\n") {
+				t.Errorf("init script lacks %q:\n%s", line, body)
+			}
+		}
+	})
+}
+
+func TestGroovyQuote(t *testing.T) {
+	if got, want := groovyQuote(`https://host/it's\here`), `'https://host/it\'s\\here'`; got != want {
+		t.Errorf("groovyQuote() = %s, want %s", got, want)
+	}
+}
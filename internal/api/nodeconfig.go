@@ -0,0 +1,184 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/detector"
+	"github.com/mainuli/artifusion/internal/errors"
+)
+
+// NodeMirror is an upstream registry container runtimes can pull through Artifusion
+type NodeMirror struct {
+	Registry string `json:"registry"`
+
+	// Location is where the registry's images are read on Artifusion, as
+	// host[/namespace]: below the mirror namespace if enabled, otherwise at the root,
+	// resolved by the pull cascade
+	Location string `json:"location"`
+
+	// Containerd is the API path serving the registry's hosts.toml
+	Containerd string `json:"containerd"`
+}
+
+// NodeConfigResponse lists the registries /node-config generates runtime
+// configuration for
+type NodeConfigResponse struct {
+	URL     string       `json:"url"`
+	Mirrors []NodeMirror `json:"mirrors"`
+	CRIO    string       `json:"crio"` // API path serving the CRI-O registries.conf drop-in
+}
+
+// handleNodeConfig lists the registries nodes can pull through Artifusion, with
+// where to fetch the container runtime configuration for each
+func (h *Handler) handleNodeConfig(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := h.authenticate(w, r); !ok {
+		return
+	}
+
+	baseURL := h.nodeBaseURL(r)
+	response := NodeConfigResponse{
+		URL:     baseURL,
+		Mirrors: []NodeMirror{},
+		CRIO:    "/api/v1/node-config/crio",
+	}
+	for _, registry := range nodeRegistries(&h.protocols.OCI) {
+		response.Mirrors = append(response.Mirrors, NodeMirror{
+			Registry:   registry,
+			Location:   h.mirrorLocation(baseURL, registry),
+			Containerd: "/api/v1/node-config/containerd/" + registry,
+		})
+	}
+
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// handleContainerdHosts returns the containerd hosts.toml that pulls a registry's
+// images through Artifusion, to be installed as
+// /etc/containerd/certs.d/<registry>/hosts.toml
+func (h *Handler) handleContainerdHosts(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := h.authenticate(w, r); !ok {
+		return
+	}
+
+	registry := chi.URLParam(r, "registry")
+	if !slices.Contains(nodeRegistries(&h.protocols.OCI), registry) {
+		errors.ErrorResponse(w, errors.ErrNotFound.WithMessage(fmt.Sprintf("Registry %q is not mirrored", registry)))
+		return
+	}
+
+	baseURL := h.nodeBaseURL(r)
+	hostURL := baseURL
+	namespaced := h.protocols.OCI.MirrorNamespace.Enabled
+	if namespaced {
+		// containerd appends the repository path to the host URL as-is
+		hostURL += "/v2/" + h.protocols.OCI.MirrorNamespace.Prefix + "/" + registry
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by Artifusion: pull %s images through %s\n", registry, baseURL)
+	fmt.Fprintf(&b, "# Install as /etc/containerd/certs.d/%s/hosts.toml\n", registry)
+	fmt.Fprintf(&b, "server = %s\n\n", strconv.Quote(upstreamRegistryURL(registry)))
+	fmt.Fprintf(&b, "[host.%s]\n", strconv.Quote(hostURL))
+	b.WriteString("  capabilities = [\"pull\", \"resolve\"]\n")
+	if namespaced {
+		b.WriteString("  override_path = true\n")
+	}
+	b.WriteString("  # Artifusion requires a GitHub token: configure credentials for this host in the\n")
+	b.WriteString("  # CRI plugin's registry configs, or send them with every request:\n")
+	fmt.Fprintf(&b, "  # [host.%s.header]\n", strconv.Quote(hostURL))
+	b.WriteString("  #   Authorization = [\"Basic <base64 of username:token>\"]\n")
+
+	h.writeConfigFile(w, "application/toml; charset=utf-8", b.String())
+}
+
+// handleCRIORegistries returns the registries.conf drop-in that pulls every mirrored
+// registry's images through Artifusion, to be installed in
+// /etc/containers/registries.conf.d/
+func (h *Handler) handleCRIORegistries(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := h.authenticate(w, r); !ok {
+		return
+	}
+
+	baseURL := h.nodeBaseURL(r)
+	insecure := strings.HasPrefix(baseURL, "http://")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by Artifusion: pull images through %s\n", baseURL)
+	b.WriteString("# Install as /etc/containers/registries.conf.d/50-artifusion.conf\n")
+	b.WriteString("# Artifusion requires a GitHub token: add credentials for its host to CRI-O's\n")
+	b.WriteString("# global auth file (--global-auth-file) or use image pull secrets.\n")
+	for _, registry := range nodeRegistries(&h.protocols.OCI) {
+		b.WriteString("\n[[registry]]\n")
+		fmt.Fprintf(&b, "prefix = %s\n", strconv.Quote(registry))
+		fmt.Fprintf(&b, "location = %s\n\n", strconv.Quote(registry))
+		b.WriteString("[[registry.mirror]]\n")
+		fmt.Fprintf(&b, "location = %s\n", strconv.Quote(h.mirrorLocation(baseURL, registry)))
+		fmt.Fprintf(&b, "insecure = %t\n", insecure)
+	}
+
+	h.writeConfigFile(w, "application/toml; charset=utf-8", b.String())
+}
+
+// nodeBaseURL returns the URL nodes reach Artifusion's OCI registry at: the
+// configured OCI host, or else the host the request was sent to
+func (h *Handler) nodeBaseURL(r *http.Request) string {
+	host := h.protocols.OCI.Host
+	if host == "" {
+		host = detector.GetRequestHost(r)
+	}
+	return detector.GetRequestScheme(r) + "://" + host
+}
+
+// mirrorLocation returns where a registry's images are read on Artifusion, as
+// host[/namespace]
+func (h *Handler) mirrorLocation(baseURL, registry string) string {
+	location := strings.TrimPrefix(strings.TrimPrefix(baseURL, "https://"), "http://")
+	if mirror := &h.protocols.OCI.MirrorNamespace; mirror.Enabled {
+		location += "/" + mirror.Prefix + "/" + registry
+	}
+	return location
+}
+
+// writeConfigFile writes a generated configuration file
+func (h *Handler) writeConfigFile(w http.ResponseWriter, contentType, body string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	if _, err := w.Write([]byte(body)); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to write generated configuration")
+	}
+}
+
+// nodeRegistries returns the upstream registries pull backends mirror, in cascade
+// order: their upstream namespaces and, with the mirror namespace enabled, the
+// registries it maps explicitly
+func nodeRegistries(oci *config.OCIConfig) []string {
+	var registries []string
+	for _, backend := range oci.PullBackends {
+		if backend.UpstreamNamespace != "" && !slices.Contains(registries, backend.UpstreamNamespace) {
+			registries = append(registries, backend.UpstreamNamespace)
+		}
+	}
+	if oci.MirrorNamespace.Enabled {
+		for _, mapping := range oci.MirrorNamespace.Registries {
+			if !slices.Contains(registries, mapping.Registry) {
+				registries = append(registries, mapping.Registry)
+			}
+		}
+	}
+	return registries
+}
+
+// upstreamRegistryURL returns the URL of a registry's API, which containerd falls
+// back to when Artifusion is unreachable
+func upstreamRegistryURL(registry string) string {
+	if registry == "docker.io" {
+		return "https://registry-1.docker.io"
+	}
+	return "https://" + registry
+}
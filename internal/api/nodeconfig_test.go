@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+func TestHandleNodeConfig(t *testing.T) {
+	protocols := &config.ProtocolsConfig{}
+	protocols.OCI.Enabled = true
+	protocols.OCI.Host = "docker.example.com"
+	protocols.OCI.PullBackends = []config.OCIBackendConfig{
+		{Name: "internal"},
+		{Name: "ghcr", UpstreamNamespace: "ghcr.io"},
+		{Name: "dockerhub", UpstreamNamespace: "docker.io"},
+		{Name: "dockerhub-fallback", UpstreamNamespace: "docker.io"},
+	}
+	protocols.OCI.MirrorNamespace = config.MirrorNamespaceConfig{
+		Enabled:    true,
+		Prefix:     "mirror",
+		Registries: []config.MirrorRegistryConfig{{Registry: "quay.io", Backend: "internal"}},
+	}
+
+	h := newPackagesHandler(t, protocols)
+	get := func(path string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.SetBasicAuth("alice", testToken)
+		rec := httptest.NewRecorder()
+		h.Routes().ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("index", func(t *testing.T) {
+		rec := get("/node-config")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		var response NodeConfigResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		var got []string
+		for _, mirror := range response.Mirrors {
+			got = append(got, mirror.Registry+"="+mirror.Location)
+		}
+		want := []string{
+			"ghcr.io=docker.example.com/mirror/ghcr.io",
+			"docker.io=docker.example.com/mirror/docker.io",
+			"quay.io=docker.example.com/mirror/quay.io",
+		}
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("mirrors = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("containerd", func(t *testing.T) {
+		rec := get("/node-config/containerd/docker.io")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		for _, line := range []string{
+			`server = "https://registry-1.docker.io"`,
+			`[host."https://docker.example.com/v2/mirror/docker.io"]`,
+			`  capabilities = ["pull", "resolve"]`,
+			`  override_path = true`,
+		} {
+			if !strings.Contains(rec.Body.String(), line+"\n") {
+				t.Errorf("hosts.toml lacks %q:\n%s", line, rec.Body.String())
+			}
+		}
+	})
+
+	t.Run("containerd unknown registry", func(t *testing.T) {
+		if rec := get("/node-config/containerd/registry.k8s.io"); rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("crio", func(t *testing.T) {
+		rec := get("/node-config/crio")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		want := "[[registry]]\n" +
+			"prefix = \"quay.io\"\n" +
+			"location = \"quay.io\"\n\n" +
+			"[[registry.mirror]]\n" +
+			"location = \"docker.example.com/mirror/quay.io\"\n" +
+			"insecure = false\n"
+		if !strings.Contains(rec.Body.String(), want) || strings.Count(rec.Body.String(), "[[registry]]") != 3 {
+			t.Errorf("registries.conf lacks quay.io mirror or has wrong registries:\n%s", rec.Body.String())
+		}
+	})
+
+	t.Run("without mirror namespace", func(t *testing.T) {
+		protocols.OCI.MirrorNamespace.Enabled = false
+		defer func() { protocols.OCI.MirrorNamespace.Enabled = true }()
+
+		rec := get("/node-config/containerd/ghcr.io")
+		if !strings.Contains(rec.Body.String(), `[host."https://docker.example.com"]`) || strings.Contains(rec.Body.String(), "override_path") {
+			t.Errorf("hosts.toml should pull through the cascade:\n%s", rec.Body.String())
+		}
+		if rec := get("/node-config/containerd/quay.io"); rec.Code != http.StatusNotFound {
+			t.Errorf("quay.io status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/detector"
+	"github.com/mainuli/artifusion/internal/errors"
+)
+
+// npmrcTokenVariable is the environment variable the generated .npmrc reads the
+// client's token from. npm expands ${...} in .npmrc values.
+const npmrcTokenVariable = "NPM_TOKEN"
+
+// handleNPMRC returns an .npmrc that installs npm packages through Artifusion: the
+// default registry and each configured scope point at the NPM handler, with the
+// token read from $NPM_TOKEN. With scopes_only=true the default registry is left
+// alone and only the scopes are mapped.
+func (h *Handler) handleNPMRC(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := h.authenticate(w, r); !ok {
+		return
+	}
+
+	scopesOnly := false
+	if value := r.URL.Query().Get("scopes_only"); value != "" {
+		var err error
+		if scopesOnly, err = strconv.ParseBool(value); err != nil {
+			errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage("scopes_only must be true or false"))
+			return
+		}
+	}
+	scopes := h.protocols.NPM.Scopes
+	if scopesOnly && len(scopes) == 0 {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage("No npm scopes are configured"))
+		return
+	}
+
+	registryURL := h.npmRegistryURL(r)
+	// npm matches credentials to registries by the URL without its scheme
+	nerfDart := strings.TrimPrefix(strings.TrimPrefix(registryURL, "https:"), "http:")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by Artifusion: install npm packages through %s\n", registryURL)
+	fmt.Fprintf(&b, "# Set %s to a GitHub token before running npm\n", npmrcTokenVariable)
+	if !scopesOnly {
+		fmt.Fprintf(&b, "registry=%s\n", registryURL)
+	}
+	for _, scope := range scopes {
+		fmt.Fprintf(&b, "%s:registry=%s\n", scope, registryURL)
+	}
+	fmt.Fprintf(&b, "%s:_authToken=${%s}\n", nerfDart, npmrcTokenVariable)
+	fmt.Fprintf(&b, "%s:always-auth=true\n", nerfDart)
+
+	h.writeConfigFile(w, "text/plain; charset=utf-8", b.String())
+}
+
+// npmRegistryURL returns the URL npm reaches Artifusion's NPM registry at, with the
+// trailing slash npm expects: the configured NPM host, or else the host the request
+// was sent to, followed by the path prefix
+func (h *Handler) npmRegistryURL(r *http.Request) string {
+	host := h.protocols.NPM.Host
+	if host == "" {
+		host = detector.GetRequestHost(r)
+	}
+	return detector.GetRequestScheme(r) + "://" + host + h.protocols.NPM.PathPrefix + "/"
+}
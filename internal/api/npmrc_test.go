@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+func TestHandleNPMRC(t *testing.T) {
+	protocols := &config.ProtocolsConfig{}
+	protocols.NPM.Enabled = true
+	protocols.NPM.PathPrefix = "/npm"
+	protocols.NPM.Scopes = []string{"@acme", "@acme-tools"}
+
+	h := newPackagesHandler(t, protocols)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "default registry and scopes",
+			wantStatus: http.StatusOK,
+			want: "# Generated by Artifusion: install npm packages through https://example.com/npm/\n" +
+				"# Set NPM_TOKEN to a GitHub token before running npm\n" +
+				"registry=https://example.com/npm/\n" +
+				"@acme:registry=https://example.com/npm/\n" +
+				"@acme-tools:registry=https://example.com/npm/\n" +
+				"//example.com/npm/:_authToken=${NPM_TOKEN}\n" +
+				"//example.com/npm/:always-auth=true\n",
+		},
+		{
+			name:       "scopes only",
+			query:      "?scopes_only=true",
+			wantStatus: http.StatusOK,
+			want: "# Generated by Artifusion: install npm packages through https://example.com/npm/\n" +
+				"# Set NPM_TOKEN to a GitHub token before running npm\n" +
+				"@acme:registry=https://example.com/npm/\n" +
+				"@acme-tools:registry=https://example.com/npm/\n" +
+				"//example.com/npm/:_authToken=${NPM_TOKEN}\n" +
+				"//example.com/npm/:always-auth=true\n",
+		},
+		{
+			name:       "invalid scopes_only",
+			query:      "?scopes_only=maybe",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/npmrc"+tt.query, nil)
+			req.SetBasicAuth("alice", testToken)
+			rec := httptest.NewRecorder()
+			h.Routes().ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.want != "" && rec.Body.String() != tt.want {
+				t.Errorf("body =\n%s\nwant\n%s", rec.Body.String(), tt.want)
+			}
+		})
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.Routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/npmrc", nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+}
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/metadata"
+	"github.com/mainuli/artifusion/internal/proxy"
+)
+
+const (
+	// defaultPackagesLimit and maxPackagesLimit bound the packages returned by /packages
+	defaultPackagesLimit = 100
+	maxPackagesLimit     = 1000
+
+	// catalogPageSize is the number of repositories requested from an OCI backend's catalog
+	catalogPageSize = 1000
+)
+
+// Package is a package one or more backends list
+type Package struct {
+	Protocol    string   `json:"protocol"`
+	Name        string   `json:"name"`
+	Version     string   `json:"version,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Backends    []string `json:"backends"` // Empty for packages only the metadata database knows
+
+	// Pull statistics from the metadata database, when enabled
+	Pulls      uint64    `json:"pulls,omitempty"`
+	LastPulled time.Time `json:"last_pulled,omitzero"`
+}
+
+// PackageSourceError reports a backend whose packages could not be listed
+type PackageSourceError struct {
+	Protocol string `json:"protocol"`
+	Backend  string `json:"backend,omitempty"`
+	Error    string `json:"error"`
+}
+
+// PackagesResponse lists the packages matching a /packages query
+type PackagesResponse struct {
+	Packages  []Package            `json:"packages"`
+	Truncated bool                 `json:"truncated"`
+	Errors    []PackageSourceError `json:"errors,omitempty"`
+}
+
+// packageSource lists the packages of one backend
+type packageSource struct {
+	protocol string
+	backend  proxy.BackendConfig
+	list     func(r *http.Request, backend proxy.BackendConfig, query string, limit int) ([]Package, error)
+}
+
+// handlePackages lists the packages the backends of enabled protocols hold, so UIs
+// and portals can browse them without talking to each backend's own API.
+//
+// Query parameters:
+//   - protocol: Only list packages of this protocol (default: all enabled protocols)
+//   - q: Case-insensitive substring the package name must contain
+//   - limit: Maximum number of packages returned (default 100, at most 1000)
+//
+// OCI repositories come from the catalogs of the push backend and of the pull
+// backends the caller may use, which for pull-through caches lists what is cached.
+// npm packages come from the backend's search API. With the metadata database
+// enabled, recorded packages (including Maven artifacts) are added with their pull
+// statistics. Backends that fail are reported in "errors" and the remaining
+// listings are still returned.
+func (h *Handler) handlePackages(w http.ResponseWriter, r *http.Request) {
+	caller, r, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	protocol := query.Get("protocol")
+	switch protocol {
+	case "", "oci", "maven", "npm":
+	default:
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessagef("unknown protocol %q", protocol))
+		return
+	}
+
+	if protocol != "" && !h.protocolEnabled(protocol) {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessagef("protocol %s is not enabled", protocol))
+		return
+	}
+
+	limit, ok := parseLimit(query.Get("limit"))
+	if !ok {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessagef("limit must be between 1 and %d", maxPackagesLimit))
+		return
+	}
+	q := strings.ToLower(strings.TrimSpace(query.Get("q")))
+
+	response := PackagesResponse{Packages: []Package{}}
+	sources := h.packageSources(protocol, caller)
+	if protocol == "maven" && h.metadata == nil {
+		// Maven repositories have no standard way to list artifacts
+		response.Errors = append(response.Errors, PackageSourceError{
+			Protocol: "maven",
+			Error:    "listing packages is not supported for maven without the metadata database",
+		})
+	}
+
+	// Query all backends in parallel, keeping results in source order
+	results := make([][]Package, len(sources))
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = source.list(r, source.backend, q, limit)
+		}()
+	}
+	wg.Wait()
+
+	// Merge packages several backends hold into one entry
+	seen := make(map[string]int)
+	for i, source := range sources {
+		if errs[i] != nil {
+			h.logger.Warn().Err(errs[i]).
+				Str("protocol", source.protocol).
+				Str("backend", source.backend.GetName()).
+				Msg("Failed to list backend packages")
+			response.Errors = append(response.Errors, PackageSourceError{
+				Protocol: source.protocol,
+				Backend:  source.backend.GetName(),
+				Error:    errs[i].Error(),
+			})
+			continue
+		}
+		for _, pkg := range results[i] {
+			if q != "" && !strings.Contains(strings.ToLower(pkg.Name), q) {
+				continue
+			}
+			key := pkg.Protocol + "\x00" + pkg.Name
+			if j, ok := seen[key]; ok {
+				response.Packages[j].Backends = append(response.Packages[j].Backends, source.backend.GetName())
+				continue
+			}
+			pkg.Backends = []string{source.backend.GetName()}
+			seen[key] = len(response.Packages)
+			response.Packages = append(response.Packages, pkg)
+		}
+	}
+
+	// Add what the metadata database knows, including packages no backend listed
+	if h.metadata != nil {
+		if err := h.addRecordedPackages(&response, seen, protocol, q); err != nil {
+			h.logger.Warn().Err(err).Msg("Failed to list recorded packages")
+			response.Errors = append(response.Errors, PackageSourceError{Protocol: protocol, Error: err.Error()})
+		}
+	}
+
+	sort.SliceStable(response.Packages, func(i, j int) bool {
+		a, b := response.Packages[i], response.Packages[j]
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.Name < b.Name
+	})
+	if len(response.Packages) > limit {
+		response.Packages = response.Packages[:limit]
+		response.Truncated = true
+	}
+
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// addRecordedPackages adds the pull statistics of recorded packages to the listed
+// ones, and adds the recorded packages of enabled protocols that no backend listed
+// at their most recently seen version
+func (h *Handler) addRecordedPackages(response *PackagesResponse, seen map[string]int, protocol, q string) error {
+	artifacts, err := h.metadata.List(metadata.Filter{Protocol: protocol, Query: q})
+	if err != nil {
+		return err
+	}
+
+	lastSeen := make(map[string]time.Time)
+	for _, artifact := range artifacts {
+		if !h.protocolEnabled(artifact.Protocol) {
+			continue
+		}
+		key := artifact.Protocol + "\x00" + artifact.Name
+		j, ok := seen[key]
+		if !ok {
+			j = len(response.Packages)
+			seen[key] = j
+			response.Packages = append(response.Packages, Package{
+				Protocol: artifact.Protocol,
+				Name:     artifact.Name,
+				Backends: []string{},
+			})
+		}
+
+		pkg := &response.Packages[j]
+		pkg.Pulls += artifact.Pulls
+		if artifact.LastPulled.After(pkg.LastPulled) {
+			pkg.LastPulled = artifact.LastPulled
+		}
+		if len(pkg.Backends) == 0 && artifact.Version != "" && artifact.LastSeen.After(lastSeen[key]) {
+			pkg.Version = artifact.Version
+			lastSeen[key] = artifact.LastSeen
+		}
+	}
+	return nil
+}
+
+// protocolEnabled reports whether protocol is enabled
+func (h *Handler) protocolEnabled(protocol string) bool {
+	switch protocol {
+	case "oci":
+		return h.protocols.OCI.Enabled
+	case "maven":
+		return h.protocols.Maven.Enabled
+	case "npm":
+		return h.protocols.NPM.Enabled
+	}
+	return false
+}
+
+// parseLimit parses a limit query parameter, defaulting to defaultPackagesLimit
+func parseLimit(value string) (int, bool) {
+	if value == "" {
+		return defaultPackagesLimit, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > maxPackagesLimit {
+		return 0, false
+	}
+	return n, true
+}
+
+// packageSources returns the backends to list for protocol ("" for all enabled
+// protocols), skipping pull backends restricted to teams the caller is not in
+func (h *Handler) packageSources(protocol string, caller *auth.AuthResult) []packageSource {
+	var sources []packageSource
+
+	oci := &h.protocols.OCI
+	if oci.Enabled && (protocol == "" || protocol == "oci") {
+		if oci.PushBackend.URL != "" {
+			sources = append(sources, packageSource{protocol: "oci", backend: &oci.PushBackend, list: h.listCatalog})
+		}
+		for i := range oci.PullBackends {
+			backend := &oci.PullBackends[i]
+			if len(backend.Teams) > 0 && !slices.ContainsFunc(backend.Teams, func(team string) bool {
+				return slices.Contains(caller.Teams, team)
+			}) {
+				continue
+			}
+			sources = append(sources, packageSource{protocol: "oci", backend: backend, list: h.listCatalog})
+		}
+	}
+
+	if h.protocols.NPM.Enabled && (protocol == "" || protocol == "npm") {
+		sources = append(sources, packageSource{protocol: "npm", backend: &h.protocols.NPM.Backend, list: h.searchNPM})
+	}
+
+	return sources
+}
+
+// listCatalog lists the repositories in an OCI backend's catalog under the names
+// clients pull them by
+func (h *Handler) listCatalog(r *http.Request, backend proxy.BackendConfig, _ string, _ int) ([]Package, error) {
+	var catalog struct {
+		Repositories []string `json:"repositories"`
+	}
+	if err := h.getBackendJSON(r, backend, "/v2/_catalog", "n="+strconv.Itoa(catalogPageSize), &catalog); err != nil {
+		return nil, err
+	}
+
+	ociBackend, _ := backend.(*config.OCIBackendConfig)
+	packages := make([]Package, 0, len(catalog.Repositories))
+	for _, name := range catalog.Repositories {
+		if ociBackend != nil && ociBackend.UpstreamNamespace != "" {
+			// A shared cache holds several upstreams; only this namespace is served by the backend
+			var ok bool
+			if name, ok = strings.CutPrefix(name, ociBackend.UpstreamNamespace+"/"); !ok {
+				continue
+			}
+		}
+		if ociBackend != nil && ociBackend.PathRewrite.AddLibraryPrefix {
+			if official, ok := strings.CutPrefix(name, "library/"); ok && !strings.Contains(official, "/") {
+				name = official
+			}
+		}
+		packages = append(packages, Package{Protocol: "oci", Name: name})
+	}
+	return packages, nil
+}
+
+// searchNPM lists the packages matching query with the npm registry search API
+func (h *Handler) searchNPM(r *http.Request, backend proxy.BackendConfig, query string, limit int) ([]Package, error) {
+	var result struct {
+		Objects []struct {
+			Package struct {
+				Name        string `json:"name"`
+				Version     string `json:"version"`
+				Description string `json:"description"`
+			} `json:"package"`
+		} `json:"objects"`
+	}
+	params := url.Values{"text": {query}, "size": {strconv.Itoa(limit)}}
+	if err := h.getBackendJSON(r, backend, "/-/v1/search", params.Encode(), &result); err != nil {
+		return nil, err
+	}
+
+	packages := make([]Package, 0, len(result.Objects))
+	for _, object := range result.Objects {
+		packages = append(packages, Package{
+			Protocol:    "npm",
+			Name:        object.Package.Name,
+			Version:     object.Package.Version,
+			Description: object.Package.Description,
+		})
+	}
+	return packages, nil
+}
+
+// getBackendJSON sends a GET request with the backend's credentials and decodes the
+// JSON response into v
+func (h *Handler) getBackendJSON(r *http.Request, backend proxy.BackendConfig, path, query string, v interface{}) error {
+	resp, err := h.proxyClient.ProxyRequest(&proxy.Request{
+		Method:      http.MethodGet,
+		Path:        path,
+		Query:       query,
+		Headers:     http.Header{"Accept": {"application/json"}},
+		Backend:     backend,
+		OriginalReq: r,
+	})
+	if err != nil {
+		return err
+	}
+
+	body, err := h.proxyClient.ReadResponseBody(resp)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("backend returned status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("invalid backend response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// InFlightRequestsResponse lists the requests being served, longest running first
+type InFlightRequestsResponse struct {
+	Requests []middleware.InFlightRequest `json:"requests"`
+}
+
+// handleListInFlightRequests returns the requests being served with their user,
+// backend, bytes transferred and elapsed time, to find stuck requests during
+// incidents. Admin only.
+//
+// Query parameters:
+//   - user: Only requests of this user
+//   - backend: Only requests routed to this backend
+//   - min_elapsed: Only requests running for at least this duration, e.g. 30s
+func (h *Handler) handleListInFlightRequests(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authenticateAdmin(w, r); !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	var minElapsed time.Duration
+	if value := query.Get("min_elapsed"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage("min_elapsed must be a duration, e.g. 30s"))
+			return
+		}
+		minElapsed = d
+	}
+	user, backend := query.Get("user"), query.Get("backend")
+
+	response := InFlightRequestsResponse{Requests: []middleware.InFlightRequest{}}
+	for _, req := range h.inFlight.Requests() {
+		if (user != "" && req.User != user) || (backend != "" && req.Backend != backend) ||
+			req.ElapsedSeconds < minElapsed.Seconds() {
+			continue
+		}
+		response.Requests = append(response.Requests, req)
+	}
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// handleCancelInFlightRequest cancels the request with the ID listed by
+// /admin/requests, e.g. a runaway pull saturating the link. Its backend requests
+// are aborted and the client gets a REQUEST_CANCELED error, or a closed connection
+// if the response had already started. Admin only; every cancellation is audited.
+func (h *Handler) handleCancelInFlightRequest(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticateAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	req, ok := h.inFlight.Cancel(id)
+	if !ok {
+		errors.ErrorResponse(w, errors.ErrNotFound.WithMessagef("No request %s is in flight", id))
+		return
+	}
+
+	h.auditor.Record(r, "request_cancel").
+		Str("admin", caller.Username).
+		Str("canceled_request_id", req.RequestID).
+		Str("canceled_method", req.Method).
+		Str("canceled_path", req.Path).
+		Str("user", req.User).
+		Str("backend", req.Backend).
+		Int64("bytes_received", req.BytesReceived).
+		Int64("bytes_sent", req.BytesSent).
+		Float64("elapsed_seconds", req.ElapsedSeconds).
+		Msg("In-flight request canceled")
+
+	h.writeJSON(w, http.StatusOK, req)
+}
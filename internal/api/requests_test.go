@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/audit"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/rs/zerolog"
+)
+
+func TestHandleListInFlightRequests(t *testing.T) {
+	tracker := middleware.NewInFlightTracker()
+
+	// Hold a pull in flight for the duration of the test
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	pull := middleware.Logger(zerolog.Nop(), false, false)(tracker.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		middleware.SetUsername(r.Context(), "bob")
+		middleware.AddLogField(r.Context(), "backend", "dockerhub")
+		close(started)
+		<-release
+	})))
+	go func() {
+		defer close(done)
+		pull.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v2/library/nginx/blobs/sha256:abc", nil))
+	}()
+	defer func() {
+		close(release)
+		<-done
+	}()
+	<-started
+
+	h := newPackagesHandler(t, &config.ProtocolsConfig{})
+	h.SetInFlightTracker(tracker, audit.New(zerolog.Nop()))
+	routes := h.Routes()
+
+	list := func(query string) (int, InFlightRequestsResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/admin/requests"+query, nil)
+		req.SetBasicAuth("alice", testToken)
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+
+		var response InFlightRequestsResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+		}
+		return rec.Code, response
+	}
+
+	if status, _ := list(""); status != http.StatusForbidden {
+		t.Errorf("non-admin status = %d, want %d", status, http.StatusForbidden)
+	}
+	h.authenticator.SetAdmin(&config.AdminConfig{Users: []string{"alice"}}, audit.New(zerolog.Nop()))
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantCount  int
+	}{
+		{query: "", wantStatus: http.StatusOK, wantCount: 1},
+		{query: "?user=bob&backend=dockerhub", wantStatus: http.StatusOK, wantCount: 1},
+		{query: "?user=carol", wantStatus: http.StatusOK, wantCount: 0},
+		{query: "?min_elapsed=1h", wantStatus: http.StatusOK, wantCount: 0},
+		{query: "?min_elapsed=soon", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			status, response := list(tt.query)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if len(response.Requests) != tt.wantCount {
+				t.Fatalf("requests = %+v, want %d", response.Requests, tt.wantCount)
+			}
+			if tt.wantCount == 1 {
+				got := response.Requests[0]
+				if got.Path != "/v2/library/nginx/blobs/sha256:abc" || got.User != "bob" || got.Backend != "dockerhub" {
+					t.Errorf("request = %+v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleCancelInFlightRequest(t *testing.T) {
+	tracker := middleware.NewInFlightTracker()
+
+	// A pull that runs until canceled
+	started := make(chan struct{})
+	pulled := httptest.NewRecorder()
+	done := make(chan struct{})
+	pull := middleware.Logger(zerolog.Nop(), false, false)(tracker.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		middleware.SetUsername(r.Context(), "bob")
+		close(started)
+		<-r.Context().Done()
+	})))
+	go func() {
+		defer close(done)
+		pull.ServeHTTP(pulled, httptest.NewRequest(http.MethodGet, "/v2/library/nginx/blobs/sha256:abc", nil))
+	}()
+	<-started
+
+	var auditLog bytes.Buffer
+	h := newPackagesHandler(t, &config.ProtocolsConfig{})
+	auditor := audit.New(zerolog.New(&auditLog))
+	h.authenticator.SetAdmin(&config.AdminConfig{Users: []string{"alice"}}, auditor)
+	h.SetInFlightTracker(tracker, auditor)
+	routes := h.Routes()
+
+	cancel := func(id string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/admin/requests/"+id+"/cancel", nil)
+		req.SetBasicAuth("alice", testToken)
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := cancel("999"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown request status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec := cancel(tracker.Requests()[0].ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var canceled middleware.InFlightRequest
+	if err := json.Unmarshal(rec.Body.Bytes(), &canceled); err != nil || canceled.User != "bob" {
+		t.Errorf("canceled request = %+v, %v", canceled, err)
+	}
+
+	<-done
+	if pulled.Code != http.StatusServiceUnavailable {
+		t.Errorf("canceled pull status = %d, want %d", pulled.Code, http.StatusServiceUnavailable)
+	}
+	if !strings.Contains(auditLog.String(), `"action":"request_cancel"`) || !strings.Contains(auditLog.String(), `"admin":"alice"`) {
+		t.Errorf("audit log lacks the cancellation: %s", auditLog.String())
+	}
+}
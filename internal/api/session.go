@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/errors"
+)
+
+// CreateSessionRequest polls a device authorization started with /session/device
+type CreateSessionRequest struct {
+	DeviceCode string `json:"device_code"`
+}
+
+// SessionPendingResponse tells the browser to poll again after Interval seconds
+type SessionPendingResponse struct {
+	Status   string `json:"status"` // authorization_pending or slow_down
+	Interval int    `json:"interval"`
+}
+
+// SessionResponse is an issued browser session. The token is sent as
+// "Authorization: Bearer <token>" to the API, and exchanged for the web UI cookie.
+type SessionResponse struct {
+	Token     string    `json:"token"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// defaultDevicePollInterval is the polling interval when GitHub names none (RFC 8628)
+const defaultDevicePollInterval = 5
+
+// handleStartSession starts signing in a browser with GitHub's device flow. The
+// user enters the returned user code on GitHub, while the browser polls
+// /session/token with the device code.
+func (h *Handler) handleStartSession(w http.ResponseWriter, r *http.Request) {
+	if !h.sessions.Enabled() {
+		errors.ErrorResponse(w, errors.ErrServiceUnavailable.WithMessage("browser sessions are disabled"))
+		return
+	}
+	code, err := h.deviceFlow.Start(r.Context())
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to start GitHub device flow")
+		errors.ErrorResponse(w, errors.ErrServiceUnavailable.WithMessage("GitHub device authorization is unavailable"))
+		return
+	}
+	if code.Interval == 0 {
+		code.Interval = defaultDevicePollInterval
+	}
+	h.writeJSON(w, http.StatusOK, code)
+}
+
+// handleCreateSession polls GitHub for the device authorization of the request.
+// Once the user authorized it, the OAuth token is validated like any client token
+// (organization and team membership) and exchanged for a session token; the OAuth
+// token itself is never returned to the browser.
+func (h *Handler) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	if !h.sessions.Enabled() {
+		errors.ErrorResponse(w, errors.ErrServiceUnavailable.WithMessage("browser sessions are disabled"))
+		return
+	}
+	var req CreateSessionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil || req.DeviceCode == "" {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage(`request body must be {"device_code": "<device code>"}`))
+		return
+	}
+
+	accessToken, err := h.deviceFlow.Poll(r.Context(), req.DeviceCode)
+	switch {
+	case stderrors.Is(err, auth.ErrAuthorizationPending):
+		h.writeJSON(w, http.StatusAccepted, SessionPendingResponse{Status: "authorization_pending", Interval: defaultDevicePollInterval})
+		return
+	case stderrors.Is(err, auth.ErrSlowDown):
+		h.writeJSON(w, http.StatusAccepted, SessionPendingResponse{Status: "slow_down", Interval: 2 * defaultDevicePollInterval})
+		return
+	case stderrors.Is(err, auth.ErrGitHubUnavailable):
+		h.logger.Warn().Err(err).Msg("Failed to poll GitHub device flow")
+		errors.ErrorResponse(w, errors.ErrServiceUnavailable.WithMessage("GitHub device authorization is unavailable"))
+		return
+	case err != nil:
+		errors.ErrorResponse(w, errors.ErrUnauthorized.WithMessage(err.Error()))
+		return
+	}
+
+	tokenReq := r.Clone(r.Context())
+	tokenReq.Header.Set("Authorization", "Bearer "+accessToken)
+	result, err := h.authenticator.AuthenticateRequest(tokenReq)
+	if err != nil {
+		h.logger.Info().Err(err).Msg("Browser session denied")
+		errors.ErrorResponse(w, errors.ErrUnauthorized.WithMessage("GitHub user is not authorized to use Artifusion"))
+		return
+	}
+
+	token, expiresAt, err := h.sessions.Issue(result)
+	if err != nil {
+		errors.ErrorResponse(w, errors.ErrForbidden.WithMessage(err.Error()))
+		return
+	}
+
+	h.logger.Info().
+		Str("username", result.Username).
+		Time("expires_at", expiresAt).
+		Msg("Browser session issued")
+
+	h.writeJSON(w, http.StatusCreated, SessionResponse{
+		Token:     token,
+		Username:  result.Username,
+		ExpiresAt: expiresAt,
+	})
+}
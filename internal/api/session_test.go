@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/detector"
+	"github.com/rs/zerolog"
+)
+
+func TestBrowserSessions(t *testing.T) {
+	// GitHub authorizes the device code "authorized" for alice and keeps "waiting" pending
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch path := r.URL.Path; {
+		case path == "/login/device/code":
+			_, _ = w.Write([]byte(`{"device_code":"authorized","user_code":"ABCD-1234","verification_uri":"https://github.com/login/device","expires_in":900,"interval":5}`))
+		case path == "/login/oauth/access_token":
+			switch r.FormValue("device_code") {
+			case "authorized":
+				_, _ = w.Write([]byte(`{"access_token":"` + testToken + `","token_type":"bearer"}`))
+			case "waiting":
+				_, _ = w.Write([]byte(`{"error":"authorization_pending"}`))
+			default:
+				_, _ = w.Write([]byte(`{"error":"expired_token"}`))
+			}
+		case strings.HasSuffix(path, "/user"):
+			if r.Header.Get("Authorization") != "Bearer "+testToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"login":"alice"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer github.Close()
+
+	cfg := &config.BrowserSessionsConfig{
+		Enabled:   true,
+		ClientID:  "Iv1.abc",
+		Secret:    strings.Repeat("s", 32),
+		TTL:       30 * time.Minute,
+		GitHubURL: github.URL,
+	}
+	sessions := auth.NewSessionIssuer(cfg)
+	githubClient := auth.NewGitHubClient(github.URL+"/", time.Minute, 0, zerolog.Nop())
+	authenticator := auth.NewClientAuthenticator(githubClient, "", nil, zerolog.Nop()).WithSessions(sessions)
+	h := NewHandler(authenticator, detector.NewChain(), zerolog.Nop())
+	h.SetBrowserSessions(sessions, auth.NewDeviceFlow(cfg))
+	routes := h.Routes()
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/session/"+path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("start", func(t *testing.T) {
+		rec := post("device", "")
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"user_code":"ABCD-1234"`) {
+			t.Errorf("POST /session/device = %d %s, want the user code", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		if rec := post("token", `{"device_code":"waiting"}`); rec.Code != http.StatusAccepted {
+			t.Errorf("POST /session/token = %d, want %d", rec.Code, http.StatusAccepted)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		if rec := post("token", `{"device_code":"other"}`); rec.Code != http.StatusUnauthorized {
+			t.Errorf("POST /session/token = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("authorized", func(t *testing.T) {
+		rec := post("token", `{"device_code":"authorized"}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("POST /session/token = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusCreated)
+		}
+		if strings.Contains(rec.Body.String(), testToken) {
+			t.Error("session response contains the GitHub OAuth token")
+		}
+		var session SessionResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+			t.Fatalf("decode session: %v", err)
+		}
+		if session.Username != "alice" || !strings.HasPrefix(session.Token, auth.SessionTokenPrefix) {
+			t.Fatalf("session = %+v, want a session token for alice", session)
+		}
+
+		// The session authenticates the API without GitHub
+		github.Close()
+		req := httptest.NewRequest(http.MethodGet, "/limits", nil)
+		req.Header.Set("Authorization", "Bearer "+session.Token)
+		limits := httptest.NewRecorder()
+		routes.ServeHTTP(limits, req)
+		if limits.Code != http.StatusOK {
+			t.Errorf("GET /limits with session = %d, want %d", limits.Code, http.StatusOK)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/proxy"
+)
+
+// ShadowLogResponse lists the active shadow log windows
+type ShadowLogResponse struct {
+	Windows []proxy.ShadowWindow `json:"windows"`
+}
+
+// StartShadowLogRequest starts a shadow log window for Backend
+type StartShadowLogRequest struct {
+	Backend  string `json:"backend"`
+	Duration string `json:"duration,omitempty"` // e.g. "15m" (default: shadow_log.max_duration)
+}
+
+// handleListShadowLog returns the active shadow log windows with their files and
+// entry counts. Admin only.
+func (h *Handler) handleListShadowLog(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authenticateAdmin(w, r); !ok {
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, ShadowLogResponse{Windows: h.shadowLog.Windows()})
+}
+
+// handleStartShadowLog starts logging the sanitized metadata of every request to a
+// backend for a bounded duration, to compare its behavior during a registry
+// migration. Admin only; every window is audited.
+func (h *Handler) handleStartShadowLog(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticateAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req StartShadowLogRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil || req.Backend == "" {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage(`request body must be {"backend": "<name>", "duration": "<duration>"}`))
+		return
+	}
+
+	duration := h.shadowLog.MaxDuration()
+	if req.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(req.Duration); err != nil {
+			errors.ErrorResponse(w, errors.ErrBadRequest.WithMessagef("invalid duration %q", req.Duration))
+			return
+		}
+	}
+
+	window, err := h.shadowLog.Start(req.Backend, duration, caller.Username)
+	switch {
+	case stderrors.Is(err, proxy.ErrShadowLogUnknownBackend):
+		errors.ErrorResponse(w, errors.ErrNotFound.WithMessagef("No backend %s is configured", req.Backend))
+		return
+	case err != nil:
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage(err.Error()))
+		return
+	}
+
+	h.auditor.Record(r, "shadow_log_start").
+		Str("admin", caller.Username).
+		Str("backend", window.Backend).
+		Str("file", window.File).
+		Time("until", window.Until).
+		Msg("Shadow log window started")
+
+	h.writeJSON(w, http.StatusCreated, window)
+}
+
+// handleStopShadowLog ends the shadow log window of a backend before its time.
+// Admin only; every early stop is audited.
+func (h *Handler) handleStopShadowLog(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticateAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	backend := chi.URLParam(r, "backend")
+	window, ok := h.shadowLog.Stop(backend)
+	if !ok {
+		errors.ErrorResponse(w, errors.ErrNotFound.WithMessagef("No shadow log window is active for %s", backend))
+		return
+	}
+
+	h.auditor.Record(r, "shadow_log_stop").
+		Str("admin", caller.Username).
+		Str("backend", window.Backend).
+		Str("file", window.File).
+		Int64("entries", window.Entries).
+		Msg("Shadow log window stopped")
+
+	h.writeJSON(w, http.StatusOK, window)
+}
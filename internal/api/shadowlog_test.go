@@ -0,0 +1,82 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/audit"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+func TestShadowLogAdmin(t *testing.T) {
+	shadowLog, err := proxy.NewShadowLog(&config.ShadowLogConfig{Dir: t.TempDir(), MaxDuration: time.Hour}, []string{"old-registry"}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewShadowLog() error = %v", err)
+	}
+	defer shadowLog.Close()
+
+	var auditLog bytes.Buffer
+	h := newPackagesHandler(t, &config.ProtocolsConfig{})
+	auditor := audit.New(zerolog.New(&auditLog))
+	h.SetShadowLog(shadowLog, auditor)
+	routes := h.Routes()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.SetBasicAuth("alice", testToken)
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/admin/shadow-log", `{"backend":"old-registry"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("non-admin status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	h.authenticator.SetAdmin(&config.AdminConfig{Users: []string{"alice"}}, auditor)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "unknown backend", method: http.MethodPost, path: "/admin/shadow-log", body: `{"backend":"other"}`, wantStatus: http.StatusNotFound},
+		{name: "over max duration", method: http.MethodPost, path: "/admin/shadow-log", body: `{"backend":"old-registry","duration":"2h"}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid duration", method: http.MethodPost, path: "/admin/shadow-log", body: `{"backend":"old-registry","duration":"soon"}`, wantStatus: http.StatusBadRequest},
+		{name: "start", method: http.MethodPost, path: "/admin/shadow-log", body: `{"backend":"old-registry","duration":"15m"}`, wantStatus: http.StatusCreated},
+		{name: "already active", method: http.MethodPost, path: "/admin/shadow-log", body: `{"backend":"old-registry"}`, wantStatus: http.StatusBadRequest},
+		{name: "list", method: http.MethodGet, path: "/admin/shadow-log", wantStatus: http.StatusOK},
+		{name: "stop", method: http.MethodDelete, path: "/admin/shadow-log/old-registry", wantStatus: http.StatusOK},
+		{name: "stop inactive", method: http.MethodDelete, path: "/admin/shadow-log/old-registry", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(tt.method, tt.path, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.name == "list" {
+				var response ShadowLogResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || len(response.Windows) != 1 || response.Windows[0].StartedBy != "alice" {
+					t.Errorf("windows = %+v, %v, want the window started by alice", response.Windows, err)
+				}
+			}
+		})
+	}
+
+	for _, action := range []string{"shadow_log_start", "shadow_log_stop"} {
+		if !strings.Contains(auditLog.String(), action) {
+			t.Errorf("audit log = %s, want %s recorded", auditLog.String(), action)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/detector"
+	"github.com/mainuli/artifusion/internal/errors"
+)
+
+// SignURLRequest asks for a signed URL downloading the artifact at Path
+type SignURLRequest struct {
+	Path string `json:"path"`          // e.g. /v2/team/app/blobs/sha256:..., /com/acme/lib/1.0/lib-1.0.jar
+	TTL  string `json:"ttl,omitempty"` // Lifetime, e.g. "72h" (default: signed_urls.default_ttl)
+}
+
+// SignURLResponse is a minted signed URL
+type SignURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// handleSignURL mints a time-limited URL that downloads one artifact through the
+// proxy without credentials, for sharing builds with people who have no GitHub
+// access. Downloads are served as the caller, without team-restricted backends.
+// Impersonated callers cannot mint URLs.
+func (h *Handler) handleSignURL(w http.ResponseWriter, r *http.Request) {
+	if !h.signer.Enabled() {
+		errors.ErrorResponse(w, errors.ErrServiceUnavailable.WithMessage("signed URLs are disabled"))
+		return
+	}
+	caller, r, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if caller.ImpersonatedBy != "" {
+		errors.ErrorResponse(w, errors.ErrForbidden.WithMessage("signed URLs cannot be minted while impersonating"))
+		return
+	}
+
+	var req SignURLRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil || req.Path == "" {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage(`request body must be {"path": "<artifact path>", "ttl": "<duration>"}`))
+		return
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			errors.ErrorResponse(w, errors.ErrBadRequest.WithMessagef("invalid ttl %q", req.TTL))
+			return
+		}
+	}
+
+	if !h.isArtifactPath(r, req.Path) {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessagef("%s is not an artifact path served by Artifusion", req.Path))
+		return
+	}
+
+	base := h.externalURL(r)
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		errors.ErrorResponse(w, errors.ErrInternal.WithInternal(err))
+		return
+	}
+	params, expiresAt, err := h.signer.Sign(baseURL.Host, req.Path, caller.Username, ttl)
+	if err != nil {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage(err.Error()))
+		return
+	}
+
+	h.logger.Info().
+		Str("username", caller.Username).
+		Str("path", req.Path).
+		Time("expires_at", expiresAt).
+		Msg("Signed URL minted")
+
+	h.writeJSON(w, http.StatusCreated, SignURLResponse{
+		URL:       base + req.Path + "?" + params.Encode(),
+		ExpiresAt: expiresAt,
+	})
+}
+
+// isArtifactPath reports whether p is a clean path a protocol handler serves on
+// the host the caller used
+func (h *Handler) isArtifactPath(r *http.Request, p string) bool {
+	if !strings.HasPrefix(p, "/") || path.Clean(p) != p || strings.HasPrefix(p, "/api/") {
+		return false
+	}
+
+	probe, err := http.NewRequestWithContext(r.Context(), http.MethodGet, p, nil)
+	if err != nil {
+		return false
+	}
+	probe.Host = r.Host
+	return h.detectorChain.Detect(probe) != detector.ProtocolUnknown
+}
+
+// externalURL returns the URL clients reach Artifusion at
+func (h *Handler) externalURL(r *http.Request) string {
+	if h.signerBase != "" {
+		return h.signerBase
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/detector"
+)
+
+func TestHandleSignURL(t *testing.T) {
+	h := newPackagesHandler(t, &config.ProtocolsConfig{})
+	h.detectorChain.Register(detector.NewOCIDetector(""))
+	signer := auth.NewURLSigner(&config.SignedURLsConfig{
+		Secret:     strings.Repeat("s", 32),
+		DefaultTTL: time.Hour,
+		MaxTTL:     24 * time.Hour,
+	})
+	h.authenticator.SetURLSigner(signer)
+	h.SetURLSigner(signer, "https://artifacts.example.com/")
+	routes := h.Routes()
+
+	sign := func(body, token string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodPost, "/signed-urls", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		routes.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "blob", body: `{"path": "/v2/team/app/blobs/sha256:aaa", "ttl": "2h"}`, wantStatus: http.StatusCreated},
+		{name: "api path", body: `{"path": "/api/v1/packages"}`, wantStatus: http.StatusBadRequest},
+		{name: "unclean path", body: `{"path": "/v2/team/../app/blobs/sha256:aaa"}`, wantStatus: http.StatusBadRequest},
+		{name: "ttl above max", body: `{"path": "/v2/team/app/blobs/sha256:aaa", "ttl": "48h"}`, wantStatus: http.StatusBadRequest},
+		{name: "missing path", body: `{}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := sign(tt.body, testToken); w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+
+	if w := sign(`{"path": "/v2/team/app/blobs/sha256:aaa"}`, "ghp_"+strings.Repeat("b", 36)); w.Code != http.StatusUnauthorized {
+		t.Errorf("status without valid token = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	w := sign(`{"path": "/v2/team/app/blobs/sha256:aaa"}`, testToken)
+	var resp SignURLResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	signed, err := url.Parse(resp.URL)
+	if err != nil || signed.Host != "artifacts.example.com" || signed.Path != "/v2/team/app/blobs/sha256:aaa" {
+		t.Fatalf("URL = %q, want the blob on the base URL", resp.URL)
+	}
+
+	// The URL downloads the artifact without credentials, but grants no API access
+	download := httptest.NewRequest(http.MethodGet, resp.URL, nil)
+	if result, err := h.authenticator.AuthenticateRequest(download); err != nil || result.Username != "alice" {
+		t.Errorf("AuthenticateRequest() = %+v, %v, want alice", result, err)
+	}
+
+	// Nor is it valid for the same path on another host
+	replay := httptest.NewRequest(http.MethodGet, resp.URL, nil)
+	replay.Host = "other.example.com"
+	if _, err := h.authenticator.AuthenticateRequest(replay); err == nil {
+		t.Error("AuthenticateRequest() accepted a signed URL replayed on another host")
+	}
+
+	params, _, err := signer.Sign("example.com", "/limits", "alice", 0)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	apiReq := httptest.NewRequest(http.MethodGet, "/limits?"+params.Encode(), nil)
+	apiResp := httptest.NewRecorder()
+	routes.ServeHTTP(apiResp, apiReq)
+	if apiResp.Code != http.StatusUnauthorized {
+		t.Errorf("API status with signed URL = %d, want %d", apiResp.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/trash"
+)
+
+// TrashResponse lists the soft-deleted artifacts, oldest deletion first
+type TrashResponse struct {
+	Entries []trash.Entry `json:"entries"`
+}
+
+// RestoreTrashRequest restores a soft-deleted artifact by its path
+type RestoreTrashRequest struct {
+	Path string `json:"path"`
+}
+
+// handleListTrash returns the artifacts held in the trash and when each will be
+// purged. Admin only.
+func (h *Handler) handleListTrash(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authenticateAdmin(w, r); !ok {
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, TrashResponse{Entries: h.trash.Entries()})
+}
+
+// handleRestoreTrash takes an artifact out of the trash with {"path": "..."}, making
+// it readable again and canceling its delete. Admin only; every restore is audited.
+func (h *Handler) handleRestoreTrash(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticateAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req RestoreTrashRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil || req.Path == "" {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage(`request body must be {"path": "<artifact path>"}`))
+		return
+	}
+
+	entry, ok := h.trash.Remove(req.Path)
+	if !ok {
+		errors.ErrorResponse(w, errors.ErrNotFound.WithMessagef("%s is not in the trash", req.Path))
+		return
+	}
+
+	h.auditor.Record(r, "trash_restore").
+		Str("admin", caller.Username).
+		Str("artifact", entry.Path).
+		Str("deleted_by", entry.DeletedBy).
+		Time("deleted_at", entry.DeletedAt).
+		Msg("Trashed artifact restored")
+
+	h.writeJSON(w, http.StatusOK, entry)
+}
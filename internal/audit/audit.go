@@ -0,0 +1,39 @@
+// Package audit provides a dedicated logger for security-relevant events.
+//
+// Audit events are written through the application logger with component=audit,
+// so they can be routed or retained separately from regular request logs.
+package audit
+
+import (
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/mainuli/artifusion/internal/utils"
+	"github.com/rs/zerolog"
+)
+
+// Logger records audit events
+type Logger struct {
+	logger zerolog.Logger
+}
+
+// New creates an audit logger derived from the application logger
+func New(logger zerolog.Logger) *Logger {
+	return &Logger{
+		logger: logger.With().Str("component", "audit").Logger(),
+	}
+}
+
+// Record starts an audit event for a request. The event is pre-populated with
+// the action and request metadata; callers add fields and finish it with Msg.
+//
+// Audit events are always logged at warn level so they survive typical
+// production log levels.
+func (l *Logger) Record(r *http.Request, action string) *zerolog.Event {
+	return l.logger.Warn().
+		Str("action", action).
+		Str("request_id", middleware.GetRequestID(r.Context())).
+		Str("client_ip", utils.GetClientIP(r)).
+		Str("method", r.Method).
+		Str("path", r.URL.Path)
+}
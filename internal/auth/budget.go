@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/patrickmn/go-cache"
+)
+
+// ErrValidationBudgetExceeded is returned when a client has triggered too many
+// uncached GitHub validations within the budget window.
+var ErrValidationBudgetExceeded = errors.New("github validation budget exceeded")
+
+// ValidationBudget caps the number of GitHub API validations each client may trigger
+// per window. Cached results do not count, so clients reusing a valid token are never
+// affected; a client cycling unique (typically invalid) tokens is cut off until the
+// window ends, protecting the shared GitHub rate limit.
+//
+// Windows are fixed: they start at a client's first counted validation.
+//
+// Thread safety: All methods are safe for concurrent use.
+type ValidationBudget struct {
+	max     int
+	window  time.Duration
+	counter *cache.Cache
+}
+
+// NewValidationBudget creates a budget allowing max validations per client per window
+func NewValidationBudget(max int, window time.Duration) *ValidationBudget {
+	return &ValidationBudget{
+		max:     max,
+		window:  window,
+		counter: cache.New(window, window),
+	}
+}
+
+// Allow counts a validation for key and reports whether it is within budget
+func (b *ValidationBudget) Allow(key string) bool {
+	// Add fails if the key exists, i.e. the window is already open
+	if err := b.counter.Add(key, 1, b.window); err == nil {
+		return b.max >= 1
+	}
+
+	count, err := b.counter.IncrementInt(key, 1)
+	if err != nil {
+		// Window expired between Add and IncrementInt: start a new one
+		b.counter.Set(key, 1, b.window)
+		return b.max >= 1
+	}
+	return count <= b.max
+}
+
+// Remaining returns how many validations key has left in its current window
+func (b *ValidationBudget) Remaining(key string) int {
+	count, found := b.counter.Get(key)
+	if !found {
+		return b.max
+	}
+	return max(b.max-count.(int), 0)
+}
+
+// budgetKey identifies the client a validation is charged to: its client IP, which
+// only comes from forwarding headers set by a trusted proxy. Usernames are never
+// used: they are unverified, so a client could cycle them to escape the budget or
+// exhaust another user's.
+func budgetKey(r *http.Request) string {
+	return "ip:" + middleware.TrustedClientIP(r)
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestValidationBudget_Allow(t *testing.T) {
+	budget := NewValidationBudget(3, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if !budget.Allow("ip:10.0.0.1") {
+			t.Fatalf("validation %d should be within budget", i+1)
+		}
+	}
+	if budget.Allow("ip:10.0.0.1") {
+		t.Error("4th validation should exceed budget")
+	}
+	if remaining := budget.Remaining("ip:10.0.0.1"); remaining != 0 {
+		t.Errorf("Remaining() = %d, want 0", remaining)
+	}
+
+	// Other clients have their own budget
+	if !budget.Allow("ip:10.0.0.2") {
+		t.Error("other client should be within budget")
+	}
+	if remaining := budget.Remaining("ip:10.0.0.3"); remaining != 3 {
+		t.Errorf("Remaining() for unseen client = %d, want 3", remaining)
+	}
+}
+
+func TestValidationBudget_WindowExpiry(t *testing.T) {
+	budget := NewValidationBudget(1, 50*time.Millisecond)
+
+	if !budget.Allow("ip:10.0.0.1") {
+		t.Fatal("first validation should be within budget")
+	}
+	if budget.Allow("ip:10.0.0.1") {
+		t.Fatal("second validation should exceed budget")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if !budget.Allow("ip:10.0.0.1") {
+		t.Error("budget should reset after the window")
+	}
+}
+
+func TestBudgetKey(t *testing.T) {
+	token := "ghp_" + strings.Repeat("a", 36)
+	basic := func(user, pass string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+	}
+
+	tests := []struct {
+		name         string
+		authHeader   string
+		forwardedFor string
+	}{
+		{name: "basic auth username is not trusted", authHeader: basic("Alice", token)},
+		{name: "token in username", authHeader: basic(token, "x-oauth-basic")},
+		{name: "bearer", authHeader: "Bearer " + token},
+		{name: "no auth header"},
+		{name: "spoofed X-Forwarded-For", authHeader: basic("alice", token), forwardedFor: "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if got := budgetKey(req); got != "ip:192.0.2.1" {
+				t.Errorf("budgetKey() = %q, want the connection IP", got)
+			}
+		})
+	}
+}
+
+// TestValidateForClient_BudgetExceeded tests that an exhausted budget rejects uncached
+// tokens without calling GitHub, while cached tokens are still accepted
+func TestValidateForClient_BudgetExceeded(t *testing.T) {
+	client := NewGitHubClient("https://api.github.com", 5*time.Minute, 0, zerolog.Nop())
+	client.SetValidationBudget(NewValidationBudget(1, time.Minute))
+
+	cachedToken := "ghp_" + strings.Repeat("c", 36)
+	client.cache.cache.Set(client.cache.hashPAT(cachedToken), &AuthResult{Username: "alice"}, time.Minute)
+
+	// Exhaust the budget
+	client.budget.Allow("ip:10.0.0.1")
+
+	_, err := client.ValidateForClient(context.Background(), "ip:10.0.0.1", "ghp_"+strings.Repeat("n", 36), "", nil)
+	if !errors.Is(err, ErrValidationBudgetExceeded) {
+		t.Errorf("expected ErrValidationBudgetExceeded, got %v", err)
+	}
+
+	result, err := client.ValidateForClient(context.Background(), "ip:10.0.0.1", cachedToken, "", nil)
+	if err != nil {
+		t.Fatalf("cached token should be accepted over budget: %v", err)
+	}
+	if result.Username != "alice" {
+		t.Errorf("expected cached result for alice, got %s", result.Username)
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/mainuli/artifusion/internal/constants"
+	"github.com/mainuli/artifusion/internal/metrics"
 	"github.com/patrickmn/go-cache"
 	"golang.org/x/sync/singleflight"
 )
@@ -16,9 +18,20 @@ import (
 type AuthResult struct {
 	Username   string
 	Org        string
-	Teams      []string
-	TokenType  string // "pat" or "github_actions"
-	Repository string // For GitHub Actions: "owner/repo" (empty for PATs)
+	Teams      []string // Required and routing teams the user is an active member of
+	TokenType  string   // "pat", "oauth", or "github_actions"
+	Repository string   // For GitHub Actions: "owner/repo" (empty for PATs)
+
+	// Permissions holds the introspected packages permissions for fine-grained PATs.
+	// Nil means the token was not introspected and is not restricted by operation.
+	Permissions *TokenPermissions
+
+	// ImpersonatedBy is the admin username when this result was produced by impersonation
+	ImpersonatedBy string
+
+	// Protocols restricts the credentials to these protocols (nil = unrestricted),
+	// checked by protocol authenticators (see ClientAuthenticator.ForProtocol)
+	Protocols []string
 }
 
 // AuthCache provides thread-safe caching of authentication results
@@ -28,9 +41,27 @@ type AuthCache struct {
 	ttl          time.Duration
 	singleflight singleflight.Group
 
+	// Refresh-ahead: hits within refreshAhead of expiry re-validate in the background
+	// (0 = disabled). refreshing tracks keys with a refresh in flight.
+	refreshAhead time.Duration
+	refreshing   sync.Map
+
+	// lastGood keeps successful results for a grace period past their TTL, for use
+	// when GitHub is unavailable (nil = disabled, see SetGracePeriod)
+	lastGood *cache.Cache
+	grace    time.Duration
+
 	// Metrics (atomic for thread-safety)
-	hits   atomic.Int64
-	misses atomic.Int64
+	hits            atomic.Int64
+	misses          atomic.Int64
+	coalesced       atomic.Int64
+	evictions       atomic.Int64
+	refreshes       atomic.Int64
+	refreshFailures atomic.Int64
+
+	// Prometheus collector (nil = disabled). Atomic because evictions are
+	// reported from the cache's cleanup goroutine.
+	metrics atomic.Pointer[metrics.Metrics]
 }
 
 // NewAuthCache creates a new authentication cache
@@ -40,10 +71,62 @@ func NewAuthCache(ttl time.Duration) *AuthCache {
 	cleanupInterval := ttl * constants.CacheCleanupMultiplier
 	c := cache.New(ttl, cleanupInterval)
 
-	return &AuthCache{
+	ac := &AuthCache{
 		cache: c,
 		ttl:   ttl,
 	}
+
+	// Called for expired entries removed by the cleanup goroutine and for invalidations,
+	// but not for entries overwritten by a refresh
+	c.OnEvicted(func(string, interface{}) {
+		ac.evictions.Add(1)
+		if m := ac.metrics.Load(); m != nil {
+			m.RecordAuthCacheEviction()
+			m.SetAuthCacheSize(c.ItemCount())
+		}
+	})
+
+	return ac
+}
+
+// SetMetrics exports cache hits, misses, coalesced lookups, evictions and size
+// to m as they happen.
+func (c *AuthCache) SetMetrics(m *metrics.Metrics) {
+	c.metrics.Store(m)
+	m.SetAuthCacheSize(c.cache.ItemCount())
+}
+
+// SetRefreshAhead enables stale-while-revalidate: a cache hit within window of the
+// entry's expiry returns the cached result immediately and re-validates the token in
+// the background, so tokens in regular use never wait on GitHub. 0 disables it.
+// Must be called before the cache is used concurrently.
+func (c *AuthCache) SetRefreshAhead(window time.Duration) {
+	c.refreshAhead = window
+}
+
+// SetGracePeriod retains successful results for grace past their TTL, available
+// through LastKnownGood once the regular entry has expired. 0 disables it.
+// Must be called before the cache is used concurrently.
+func (c *AuthCache) SetGracePeriod(grace time.Duration) {
+	c.grace = grace
+	c.lastGood = nil
+	if grace > 0 {
+		retention := c.ttl + grace
+		c.lastGood = cache.New(retention, retention*constants.CacheCleanupMultiplier)
+	}
+}
+
+// LastKnownGood returns the most recent successful result for pat if it was
+// validated within the TTL plus grace period. It does not count as a hit or miss.
+func (c *AuthCache) LastKnownGood(pat string) (*AuthResult, bool) {
+	if c.lastGood == nil {
+		return nil, false
+	}
+	result, found := c.lastGood.Get(c.hashPAT(pat))
+	if !found {
+		return nil, false
+	}
+	return result.(*AuthResult), true
 }
 
 // Get retrieves cached auth result or validates with GitHub
@@ -52,16 +135,28 @@ func (c *AuthCache) Get(ctx context.Context, pat string, validator func(context.
 	key := c.hashPAT(pat)
 
 	// Try cache first (fast path - no lock contention)
-	if result, found := c.cache.Get(key); found {
+	if result, expiration, found := c.cache.GetWithExpiration(key); found {
 		c.hits.Add(1)
+		if m := c.metrics.Load(); m != nil {
+			m.RecordAuthCacheHit()
+		}
+		if c.refreshAhead > 0 && time.Until(expiration) <= c.refreshAhead {
+			c.refreshAsync(key, validator)
+		}
 		return result.(*AuthResult), nil
 	}
 
 	c.misses.Add(1)
+	if m := c.metrics.Load(); m != nil {
+		m.RecordAuthCacheMiss()
+	}
 
 	// Use singleflight to ensure only one validation per PAT
 	// This prevents thundering herd when cache expires
-	result, err, _ := c.singleflight.Do(key, func() (interface{}, error) {
+	leader := false
+	result, err, shared := c.singleflight.Do(key, func() (interface{}, error) {
+		leader = true
+
 		// Double-check cache (might have been populated while waiting)
 		if result, found := c.cache.Get(key); found {
 			return result.(*AuthResult), nil
@@ -74,11 +169,19 @@ func (c *AuthCache) Get(ctx context.Context, pat string, validator func(context.
 		}
 
 		// Cache the result
-		c.cache.Set(key, authResult, c.ttl)
+		c.store(key, authResult)
 
 		return authResult, nil
 	})
 
+	// Callers that waited on another caller's validation were coalesced
+	if shared && !leader {
+		c.coalesced.Add(1)
+		if m := c.metrics.Load(); m != nil {
+			m.RecordAuthCacheCoalesced()
+		}
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -86,23 +189,82 @@ func (c *AuthCache) Get(ctx context.Context, pat string, validator func(context.
 	return result.(*AuthResult), nil
 }
 
+// refreshAsync re-validates key in the background and replaces the cached entry,
+// resetting its TTL. At most one refresh per key runs at a time, and it shares the
+// singleflight group with cache misses so an entry expiring mid-refresh does not
+// trigger a second validation.
+//
+// The refresh runs under its own context: the request that triggered it has already
+// been served and may be canceled. On failure the existing entry is kept until it
+// expires, so a transient GitHub error does not evict a valid token early.
+func (c *AuthCache) refreshAsync(key string, validator func(context.Context) (*AuthResult, error)) {
+	if _, inFlight := c.refreshing.LoadOrStore(key, struct{}{}); inFlight {
+		return
+	}
+
+	go func() {
+		defer c.refreshing.Delete(key)
+
+		ctx, cancel := context.WithTimeout(context.Background(), constants.AuthCacheRefreshTimeout)
+		defer cancel()
+
+		_, err, _ := c.singleflight.Do(key, func() (interface{}, error) {
+			authResult, err := validator(ctx)
+			if err != nil {
+				return nil, err
+			}
+			c.store(key, authResult)
+			return authResult, nil
+		})
+		if err != nil {
+			c.refreshFailures.Add(1)
+			return
+		}
+		c.refreshes.Add(1)
+	}()
+}
+
+// store caches a successful result, and retains it for the grace period if enabled
+func (c *AuthCache) store(key string, authResult *AuthResult) {
+	c.cache.Set(key, authResult, c.ttl)
+	if c.lastGood != nil {
+		c.lastGood.Set(key, authResult, c.ttl+c.grace)
+	}
+	if m := c.metrics.Load(); m != nil {
+		m.SetAuthCacheSize(c.cache.ItemCount())
+	}
+}
+
 // Invalidate removes a PAT from the cache
 func (c *AuthCache) Invalidate(pat string) {
 	key := c.hashPAT(pat)
 	c.cache.Delete(key)
+	if c.lastGood != nil {
+		c.lastGood.Delete(key)
+	}
 }
 
 // Clear removes all entries from the cache
 func (c *AuthCache) Clear() {
 	c.cache.Flush()
+	if c.lastGood != nil {
+		c.lastGood.Flush()
+	}
+	if m := c.metrics.Load(); m != nil {
+		m.SetAuthCacheSize(0)
+	}
 }
 
 // Stats returns cache statistics
 func (c *AuthCache) Stats() CacheStats {
 	return CacheStats{
-		Hits:   c.hits.Load(),
-		Misses: c.misses.Load(),
-		Size:   c.cache.ItemCount(),
+		Hits:            c.hits.Load(),
+		Misses:          c.misses.Load(),
+		Coalesced:       c.coalesced.Load(),
+		Evictions:       c.evictions.Load(),
+		Refreshes:       c.refreshes.Load(),
+		RefreshFailures: c.refreshFailures.Load(),
+		Size:            c.cache.ItemCount(),
 		HitRate: func() float64 {
 			hits := c.hits.Load()
 			misses := c.misses.Load()
@@ -117,10 +279,14 @@ func (c *AuthCache) Stats() CacheStats {
 
 // CacheStats represents cache statistics
 type CacheStats struct {
-	Hits    int64
-	Misses  int64
-	Size    int
-	HitRate float64
+	Hits            int64
+	Misses          int64
+	Coalesced       int64 // Misses that waited on a concurrent validation of the same token
+	Evictions       int64 // Entries removed by expiry cleanup or invalidation
+	Refreshes       int64 // Background refreshes that replaced an entry
+	RefreshFailures int64 // Background refreshes that failed (entry kept until expiry)
+	Size            int
+	HitRate         float64
 }
 
 // hashPAT creates a SHA256 hash of the PAT for cache key
@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -149,6 +150,11 @@ func TestAuthCache_Singleflight(t *testing.T) {
 	if stats.Misses < 1 {
 		t.Errorf("expected at least 1 cache miss, got %d", stats.Misses)
 	}
+
+	// Every miss other than the one that validated waited on it
+	if stats.Coalesced < 1 || stats.Coalesced > stats.Misses-1 {
+		t.Errorf("expected between 1 and %d coalesced misses, got %d", stats.Misses-1, stats.Coalesced)
+	}
 }
 
 // TestAuthCache_Singleflight_DifferentPATs tests that different PATs are not coalesced
@@ -257,6 +263,10 @@ func TestAuthCache_Invalidate(t *testing.T) {
 	if validatorCalls.Load() != 2 {
 		t.Errorf("expected 2 validator calls after invalidation, got %d", validatorCalls.Load())
 	}
+
+	if evictions := cache.Stats().Evictions; evictions != 1 {
+		t.Errorf("expected 1 eviction after invalidation, got %d", evictions)
+	}
 }
 
 // TestAuthCache_Clear tests clearing the entire cache
@@ -359,6 +369,103 @@ func TestAuthCache_Expiration(t *testing.T) {
 	}
 }
 
+// TestAuthCache_RefreshAhead tests that hits near expiry are served from cache
+// while the entry is re-validated in the background
+func TestAuthCache_RefreshAhead(t *testing.T) {
+	tests := []struct {
+		name          string
+		refreshAhead  time.Duration
+		refreshErr    error
+		wantCalls     int32
+		wantRefreshes int64
+		wantFailures  int64
+		wantUsername  string
+	}{
+		{
+			name:         "disabled",
+			refreshAhead: 0,
+			wantCalls:    1,
+			wantUsername: "v1",
+		},
+		{
+			name:          "refreshes entry",
+			refreshAhead:  time.Hour,
+			wantCalls:     2,
+			wantRefreshes: 1,
+			wantUsername:  "v2",
+		},
+		{
+			name:         "failed refresh keeps entry",
+			refreshAhead: time.Hour,
+			refreshErr:   errors.New("github unavailable"),
+			wantCalls:    2,
+			wantFailures: 1,
+			wantUsername: "v1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Refresh window longer than the TTL: every hit is a refresh candidate
+			cache := NewAuthCache(time.Minute)
+			cache.SetRefreshAhead(tt.refreshAhead)
+
+			calls := atomic.Int32{}
+			validator := func(ctx context.Context) (*AuthResult, error) {
+				n := calls.Add(1)
+				if n > 1 && tt.refreshErr != nil {
+					return nil, tt.refreshErr
+				}
+				return &AuthResult{Username: fmt.Sprintf("v%d", n)}, nil
+			}
+
+			if _, err := cache.Get(context.Background(), "test-pat", validator); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Hit: served from cache without waiting for the refresh
+			result, err := cache.Get(context.Background(), "test-pat", validator)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Username != "v1" {
+				t.Errorf("expected cached result v1 on hit, got %s", result.Username)
+			}
+
+			// Wait for the background refresh to settle
+			deadline := time.Now().Add(time.Second)
+			for time.Now().Before(deadline) {
+				stats := cache.Stats()
+				if stats.Refreshes+stats.RefreshFailures >= tt.wantRefreshes+tt.wantFailures {
+					break
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			stats := cache.Stats()
+			if stats.Refreshes != tt.wantRefreshes {
+				t.Errorf("expected %d refreshes, got %d", tt.wantRefreshes, stats.Refreshes)
+			}
+			if stats.RefreshFailures != tt.wantFailures {
+				t.Errorf("expected %d refresh failures, got %d", tt.wantFailures, stats.RefreshFailures)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("expected %d validator calls, got %d", tt.wantCalls, calls.Load())
+			}
+
+			// Disable further refreshes so the lookup below does not start another
+			cache.SetRefreshAhead(0)
+			result, err = cache.Get(context.Background(), "test-pat", validator)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Username != tt.wantUsername {
+				t.Errorf("expected username %s after refresh, got %s", tt.wantUsername, result.Username)
+			}
+		})
+	}
+}
+
 // TestAuthCache_PATHashing tests that PATs are hashed, not stored directly
 func TestAuthCache_PATHashing(t *testing.T) {
 	cache := NewAuthCache(5 * time.Minute)
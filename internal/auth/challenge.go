@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+// Authentication schemes of client_auth.supported_schemes
+const (
+	SchemeBasic  = "basic"
+	SchemeBearer = "bearer"
+)
+
+// ChallengeOptions are a protocol's defaults and request-specific parameters for the
+// WWW-Authenticate challenges of its 401 responses
+type ChallengeOptions struct {
+	// Schemes are challenged when client_auth.supported_schemes is empty
+	Schemes []string
+
+	// Realm is used when client_auth.realm is empty
+	Realm string
+
+	// TokenEndpoint means client_auth.realm is the URL of a token endpoint clients
+	// exchange their credentials at (Docker token authentication), so Bearer is only
+	// challenged when it is configured. Basic challenges use Realm instead.
+	TokenEndpoint bool
+
+	// Scope is the default scope of Bearer challenges, e.g. the OCI repository scope
+	// of the request
+	Scope string
+
+	// BearerParams are additional Bearer auth-params as name, value pairs, e.g.
+	// "service", "artifusion"
+	BearerParams []string
+}
+
+// Challenges returns the WWW-Authenticate challenges (RFC 7235) of a protocol's 401
+// responses: one per supported scheme, in order. Basic challenges (RFC 7617) carry
+// the realm; Bearer challenges (RFC 6750) the realm, scope and the protocol's own
+// parameters. Parameter values are quoted and escaped.
+func Challenges(cfg *config.ClientAuthConfig, opts ChallengeOptions) []string {
+	schemes := cfg.SupportedSchemes
+	if len(schemes) == 0 {
+		schemes = opts.Schemes
+	}
+	realm := cfg.Realm
+	if realm == "" {
+		realm = opts.Realm
+	}
+	scope := cfg.Scope
+	if scope == "" {
+		scope = opts.Scope
+	}
+
+	challenges := make([]string, 0, len(schemes))
+	for _, scheme := range schemes {
+		switch strings.ToLower(scheme) {
+		case SchemeBasic:
+			basicRealm := realm
+			if opts.TokenEndpoint {
+				basicRealm = opts.Realm
+			}
+			challenges = append(challenges, formatChallenge("Basic", "realm", basicRealm))
+		case SchemeBearer:
+			// Without a token endpoint, clients would have nowhere to get a token
+			if opts.TokenEndpoint && cfg.Realm == "" {
+				continue
+			}
+			params := []string{"realm", realm}
+			params = append(params, opts.BearerParams...)
+			if scope != "" {
+				params = append(params, "scope", scope)
+			}
+			challenges = append(challenges, formatChallenge("Bearer", params...))
+		}
+	}
+
+	// Clients only prompt for credentials when challenged
+	if len(challenges) == 0 {
+		challenges = append(challenges, formatChallenge("Basic", "realm", opts.Realm))
+	}
+	return challenges
+}
+
+// SetChallenges sets the WWW-Authenticate headers of a 401 response, one per
+// challenge of Challenges, and returns the challenges
+func SetChallenges(h http.Header, cfg *config.ClientAuthConfig, opts ChallengeOptions) []string {
+	challenges := Challenges(cfg, opts)
+	h.Del("WWW-Authenticate")
+	for _, challenge := range challenges {
+		h.Add("WWW-Authenticate", challenge)
+	}
+	return challenges
+}
+
+// formatChallenge formats a challenge of scheme with the auth-params given as name,
+// value pairs, skipping empty values
+func formatChallenge(scheme string, params ...string) string {
+	var b strings.Builder
+	b.WriteString(scheme)
+	sep := " "
+	for i := 0; i+1 < len(params); i += 2 {
+		if params[i+1] == "" {
+			continue
+		}
+		b.WriteString(sep)
+		b.WriteString(params[i])
+		b.WriteString("=")
+		b.WriteString(quoteParam(params[i+1]))
+		sep = ", "
+	}
+	return b.String()
+}
+
+// quoteParam returns value as an HTTP quoted-string
+func quoteParam(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	// Header values can't span lines
+	value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+	return `"` + value + `"`
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+func TestChallenges(t *testing.T) {
+	oci := ChallengeOptions{
+		Schemes:       []string{SchemeBearer, SchemeBasic},
+		Realm:         "artifusion",
+		TokenEndpoint: true,
+		Scope:         "repository:team/app:pull",
+		BearerParams:  []string{"service", "artifusion"},
+	}
+
+	tests := []struct {
+		name string
+		cfg  config.ClientAuthConfig
+		opts ChallengeOptions
+		want []string
+	}{
+		{
+			name: "protocol default",
+			opts: ChallengeOptions{Schemes: []string{SchemeBasic}, Realm: "Artifusion Maven Repository"},
+			want: []string{`Basic realm="Artifusion Maven Repository"`},
+		},
+		{
+			name: "configured schemes and realm",
+			cfg:  config.ClientAuthConfig{SupportedSchemes: []string{"Basic", "bearer"}, Realm: "Packages", Scope: "read:packages"},
+			opts: ChallengeOptions{Schemes: []string{SchemeBearer}, Realm: "Artifusion NPM Registry"},
+			want: []string{`Basic realm="Packages"`, `Bearer realm="Packages", scope="read:packages"`},
+		},
+		{
+			name: "quoted values",
+			opts: ChallengeOptions{Schemes: []string{SchemeBearer}, Realm: "pub", BearerParams: []string{"message", `run "dart pub token add" \ retry` + "\n"}},
+			want: []string{`Bearer realm="pub", message="run \"dart pub token add\" \\ retry "`},
+		},
+		{
+			name: "token endpoint not configured",
+			opts: oci,
+			want: []string{`Basic realm="artifusion"`},
+		},
+		{
+			name: "token endpoint",
+			cfg:  config.ClientAuthConfig{Realm: "https://auth.example.com/token"},
+			opts: oci,
+			want: []string{
+				`Bearer realm="https://auth.example.com/token", service="artifusion", scope="repository:team/app:pull"`,
+				`Basic realm="artifusion"`,
+			},
+		},
+		{
+			name: "bearer only without token endpoint",
+			cfg:  config.ClientAuthConfig{SupportedSchemes: []string{"bearer"}},
+			opts: oci,
+			want: []string{`Basic realm="artifusion"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			SetChallenges(header, &tt.cfg, tt.opts)
+			if got := header.Values("WWW-Authenticate"); !slices.Equal(got, tt.want) {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
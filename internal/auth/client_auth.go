@@ -1,22 +1,56 @@
 package auth
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/mainuli/artifusion/internal/audit"
 	"github.com/mainuli/artifusion/internal/config"
 	"github.com/mainuli/artifusion/internal/middleware"
 	"github.com/rs/zerolog"
 )
 
+// ImpersonateHeader is the request header admins use to act as another user
+const ImpersonateHeader = "X-Artifusion-Impersonate"
+
 // ClientAuthenticator handles client authentication for all protocols
 type ClientAuthenticator struct {
 	githubClient  *GitHubClient
 	requiredOrg   string
 	requiredTeams []string
 	logger        zerolog.Logger
+
+	// Admin features (disabled unless SetAdmin is called)
+	admin   *config.AdminConfig
+	auditor *audit.Logger
+
+	// Signed download URLs (nil when disabled)
+	signer *URLSigner
+
+	// Duplicate credential detection (nil when disabled)
+	sharing *SharingDetector
+
+	// Brute-force lockout (nil when disabled)
+	lockout *Lockout
+
+	// Browser session tokens, accepted by the API and web UI only (nil otherwise)
+	sessions *SessionIssuer
+
+	// TLS client certificate authentication (nil when disabled)
+	certificates *CertificateAuthenticator
+
+	// Protocol the authenticator serves ("" for the API and web UIs), checked
+	// against credentials restricted to protocols
+	protocol string
+
+	// Identity providers tokens are offered to, in order (nil = GitHub only),
+	// and the providers available for selection by name
+	providers  []Authenticator
+	registered map[string]Authenticator
 }
 
 // NewClientAuthenticator creates a new client authenticator
@@ -34,61 +68,133 @@ func NewClientAuthenticator(
 	}
 }
 
-// AuthenticateRequest extracts credentials from request and validates with GitHub.
+// SetAdmin configures the admin users and the audit logger for admin features.
+// If adminCfg.Impersonation is set, admins may impersonate other users via the
+// X-Artifusion-Impersonate header. Every admin action is recorded by auditor.
+//
+// Must be called before the authenticator is used concurrently.
+func (a *ClientAuthenticator) SetAdmin(adminCfg *config.AdminConfig, auditor *audit.Logger) {
+	a.admin = adminCfg
+	a.auditor = auditor
+}
+
+// SetURLSigner enables authenticating download requests with URLs signed by signer.
+//
+// Must be called before the authenticator is used concurrently.
+func (a *ClientAuthenticator) SetURLSigner(signer *URLSigner) {
+	a.signer = signer
+}
+
+// SetCredentialSharing enables detecting tokens used from too many source IPs.
+//
+// Must be called before the authenticator is used concurrently.
+func (a *ClientAuthenticator) SetCredentialSharing(detector *SharingDetector) {
+	a.sharing = detector
+}
+
+// SetLockout enables locking out client IPs and tokens after repeated failed
+// authentications.
+//
+// Must be called before the authenticator is used concurrently.
+func (a *ClientAuthenticator) SetLockout(lockout *Lockout) {
+	a.lockout = lockout
+}
+
+// AuthenticateRequest extracts credentials from request and validates them with the
+// selected identity providers (GitHub by default, see SetProviders).
 // It supports both Bearer and Basic authentication schemes.
 //
 // Supported authentication schemes:
-//   - Bearer: Authorization: Bearer <github-token>
+//   - Bearer: Authorization: Bearer <token>
 //   - Basic: Authorization: Basic <base64(username:password)>
 //
-// For Basic auth, the GitHub token can be in either username or password field.
+// For Basic auth, the token can be in either username or password field.
 // This is common with Docker and Maven clients that send: username=<anything>, password=<github-token>
 func (a *ClientAuthenticator) AuthenticateRequest(r *http.Request) (*AuthResult, error) {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		return nil, fmt.Errorf("no authorization header")
+	if a.signer != nil {
+		if username, ok, err := a.signer.verify(r); ok {
+			return a.authenticateSigned(r, username, err)
+		}
+	}
+	if a.sessions != nil {
+		if token, ok := sessionToken(r); ok {
+			return a.authenticateSession(r, token)
+		}
+	}
+	if a.certificates != nil {
+		if cert, ok := clientCertificate(r); ok {
+			return a.authenticateCertificate(cert)
+		}
 	}
 
-	// Extract token based on authentication scheme
-	var githubToken string
-	var err error
-
-	switch {
-	case strings.HasPrefix(authHeader, "Bearer "):
-		githubToken, err = extractBearerToken(authHeader)
-		if err != nil {
-			return nil, err
+	candidates, basic, err := requestCandidateTokens(r)
+	if err != nil {
+		// Requests without credentials are how clients discover the auth challenge
+		if r.Header.Get("Authorization") != "" {
+			a.recordFailure(r, "")
 		}
+		return nil, err
+	}
 
-	case strings.HasPrefix(authHeader, "Basic "):
-		githubToken, err = extractBasicAuthToken(authHeader)
-		if err != nil {
+	// PREEMPTIVE VALIDATION: Check token format BEFORE calling the identity provider
+	// This prevents API abuse and rate limit exhaustion from invalid tokens
+	providers := a.activeProviders()
+	token, provider, ok := selectProvider(providers, candidates)
+	if !ok && basic {
+		a.recordFailure(r, "")
+		return nil, fmt.Errorf("no valid %s token found in basic auth credentials", providerDisplayNames(providers))
+	}
+	if !ok {
+		token = candidates[0]
+	}
+
+	if a.lockout != nil {
+		if err := a.lockout.Check(r, token); err != nil {
 			return nil, err
 		}
-
-	default:
-		return nil, fmt.Errorf("unsupported auth scheme")
 	}
 
-	// PREEMPTIVE VALIDATION: Check token format BEFORE making GitHub API call
-	// This prevents API abuse and rate limit exhaustion from invalid tokens
-	tokenType, err := ValidateTokenFormat(githubToken)
-	if err != nil {
+	if !ok {
 		a.logger.Warn().
-			Str("error", err.Error()).
-			Int("token_length", len(githubToken)).
+			Int("token_length", len(token)).
 			Msg("Invalid token format rejected")
+		a.recordFailure(r, token)
 		return nil, fmt.Errorf("invalid token format")
 	}
 
 	a.logger.Debug().
-		Str("token_type", tokenType).
+		Str("provider", provider.Name()).
 		Msg("Token format validated")
 
-	// Validate token with GitHub API (with caching)
-	authResult, err := a.githubClient.Validate(r.Context(), githubToken, a.requiredOrg, a.requiredTeams)
+	authResult, err := provider.Authenticate(r, token)
 	if err != nil {
-		return nil, fmt.Errorf("github validation failed: %w", err)
+		if isCredentialFailure(err) {
+			a.recordFailure(r, token)
+		}
+		return nil, fmt.Errorf("%s validation failed: %w", provider.Name(), err)
+	}
+
+	// Fine-grained PATs are checked against the operation (read vs write) on every request;
+	// the introspected permissions themselves come from the auth cache.
+	if authResult.Permissions != nil && !authResult.Permissions.Allows(IsWriteMethod(r.Method)) {
+		a.logger.Warn().
+			Str("username", authResult.Username).
+			Str("method", r.Method).
+			Bool("read", authResult.Permissions.Read).
+			Bool("write", authResult.Permissions.Write).
+			Msg("Fine-grained PAT lacks permission for requested operation")
+		return nil, fmt.Errorf("authentication failed: %w", ErrInsufficientPermissions)
+	}
+
+	// Credentials restricted to protocols (service accounts) are checked against the
+	// protocol of the authenticator; the API and web UIs serve none
+	if authResult.Protocols != nil && !slices.Contains(authResult.Protocols, a.protocol) {
+		a.logger.Warn().
+			Str("username", authResult.Username).
+			Str("protocol", a.protocol).
+			Strs("allowed_protocols", authResult.Protocols).
+			Msg("Credentials not allowed for protocol")
+		return nil, fmt.Errorf("authentication failed: credentials are not allowed for this protocol")
 	}
 
 	a.logger.Debug().
@@ -96,11 +202,166 @@ func (a *ClientAuthenticator) AuthenticateRequest(r *http.Request) (*AuthResult,
 		Str("org", authResult.Org).
 		Strs("teams", authResult.Teams).
 		Str("token_type", authResult.TokenType).
+		Str("provider", provider.Name()).
 		Msg("Client authenticated successfully")
 
+	if a.sharing != nil {
+		if err := a.sharing.Observe(r, token, authResult.Username); err != nil {
+			return nil, fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+
+	if target := r.Header.Get(ImpersonateHeader); target != "" {
+		return a.impersonate(r, token, authResult, target)
+	}
+
 	return authResult, nil
 }
 
+// isGitHubResult reports whether result was authenticated with a GitHub token
+func isGitHubResult(result *AuthResult) bool {
+	switch result.TokenType {
+	case TokenTypePAT, TokenTypeGitHubActions, TokenTypeOAuth:
+		return true
+	}
+	return false
+}
+
+// recordFailure counts a failed authentication against the lockout, if enabled
+func (a *ClientAuthenticator) recordFailure(r *http.Request, token string) {
+	if a.lockout != nil {
+		a.lockout.RecordFailure(r, token)
+	}
+}
+
+// authenticateSigned returns the AuthResult of a request carrying a signed URL,
+// given the outcome of verifying it. Signed URLs only grant downloads, and act as
+// the minting user without team memberships, so they never reach backends
+// restricted to teams.
+func (a *ClientAuthenticator) authenticateSigned(r *http.Request, username string, err error) (*AuthResult, error) {
+	if err != nil {
+		return nil, err
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return nil, fmt.Errorf("signed URLs only permit downloads")
+	}
+
+	a.logger.Debug().
+		Str("username", username).
+		Str("path", r.URL.Path).
+		Msg("Client authenticated with signed URL")
+
+	return &AuthResult{
+		Username:  username,
+		Org:       a.requiredOrg,
+		TokenType: TokenTypeSignedURL,
+	}, nil
+}
+
+// IsAdmin reports whether the authenticated user is a configured admin.
+// Impersonated and signed URL results are never admins, even when the user is one.
+func (a *ClientAuthenticator) IsAdmin(authResult *AuthResult) bool {
+	return a.admin != nil && authResult.ImpersonatedBy == "" && authResult.TokenType != TokenTypeSignedURL &&
+		a.admin.IsAdmin(authResult.Username)
+}
+
+// ResolveUser returns the AuthResult target would get when authenticating, on behalf of
+// an admin caller authenticated from r. Unlike impersonation, it is not tied to a request
+// being proxied, so it is available to admins whether or not impersonation is enabled.
+// Used to explain another user's authorization decisions. Service accounts are
+// resolved by name before GitHub users.
+func (a *ClientAuthenticator) ResolveUser(r *http.Request, caller *AuthResult, target string) (*AuthResult, error) {
+	if !a.IsAdmin(caller) {
+		return nil, fmt.Errorf("resolving other users requires admin privileges")
+	}
+
+	if serviceAccounts, ok := a.registered[ServiceAccountProviderName].(*ServiceAccountAuthenticator); ok {
+		if result, found := serviceAccounts.Lookup(target); found {
+			a.auditor.Record(r, "resolve_user").
+				Str("admin", caller.Username).
+				Str("target", target).
+				Msg("Admin resolved a service account's authentication")
+			return result, nil
+		}
+	}
+
+	if !isGitHubResult(caller) {
+		return nil, fmt.Errorf("resolving other users requires a GitHub token")
+	}
+
+	callerToken, err := extractRequestToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	a.auditor.Record(r, "resolve_user").
+		Str("admin", caller.Username).
+		Str("target", target).
+		Msg("Admin resolved another user's authentication")
+
+	return a.githubClient.ResolveUser(r.Context(), callerToken, target, a.requiredOrg, a.requiredTeams)
+}
+
+// impersonate returns the AuthResult of target when the authenticated caller is an admin.
+//
+// Impersonation is restricted to read requests so an admin debugging access cannot
+// publish or delete artifacts on another user's behalf. The header is removed from the
+// request so it is never forwarded to backends.
+func (a *ClientAuthenticator) impersonate(r *http.Request, callerToken string, caller *AuthResult, target string) (*AuthResult, error) {
+	r.Header.Del(ImpersonateHeader)
+
+	if a.admin == nil || !a.admin.Impersonation {
+		return nil, fmt.Errorf("impersonation is not enabled")
+	}
+
+	if !a.admin.IsAdmin(caller.Username) {
+		a.auditor.Record(r, "impersonation_denied").
+			Str("admin", caller.Username).
+			Str("target", target).
+			Str("reason", "not an admin").
+			Msg("Impersonation attempt by non-admin user")
+		return nil, fmt.Errorf("impersonation not permitted")
+	}
+
+	if IsWriteMethod(r.Method) {
+		a.auditor.Record(r, "impersonation_denied").
+			Str("admin", caller.Username).
+			Str("target", target).
+			Str("reason", "write operation").
+			Msg("Impersonation rejected for write operation")
+		return nil, fmt.Errorf("impersonation is only permitted for read operations")
+	}
+
+	// Other users are resolved through GitHub with the caller's token
+	if !isGitHubResult(caller) {
+		return nil, fmt.Errorf("impersonation requires a GitHub token")
+	}
+
+	result, err := a.githubClient.ResolveUser(r.Context(), callerToken, target, a.requiredOrg, a.requiredTeams)
+	if err != nil {
+		// The target failing auth is the expected outcome when debugging their access,
+		// so the reason is recorded for the admin to inspect
+		a.auditor.Record(r, "impersonation_failed").
+			Str("admin", caller.Username).
+			Str("target", target).
+			Str("error", err.Error()).
+			Msg("Impersonated user failed authentication")
+		return nil, fmt.Errorf("impersonated user authentication failed: %w", err)
+	}
+
+	// Copy so the cached result is never mutated
+	impersonated := *result
+	impersonated.ImpersonatedBy = caller.Username
+
+	a.auditor.Record(r, "impersonation").
+		Str("admin", caller.Username).
+		Str("target", impersonated.Username).
+		Strs("teams", impersonated.Teams).
+		Msg("Request processed as impersonated user")
+
+	return &impersonated, nil
+}
+
 // AuthenticateAndInjectContext authenticates the request and injects AuthResult into context
 func (a *ClientAuthenticator) AuthenticateAndInjectContext(r *http.Request) (*AuthResult, *http.Request, error) {
 	authResult, err := a.AuthenticateRequest(r)
@@ -110,11 +371,69 @@ func (a *ClientAuthenticator) AuthenticateAndInjectContext(r *http.Request) (*Au
 
 	// Add username to request context for logging/rate limiting
 	ctx := middleware.SetUsername(r.Context(), authResult.Username)
+	ctx = context.WithValue(ctx, authResultKey{}, authResult)
 	newReq := r.WithContext(ctx)
 
 	return authResult, newReq, nil
 }
 
+// authResultKey is the context key of the AuthResult of an authenticated request
+type authResultKey struct{}
+
+// ResultFromContext returns the AuthResult AuthenticateAndInjectContext added to
+// ctx, or nil if the request was not authenticated
+func ResultFromContext(ctx context.Context) *AuthResult {
+	result, _ := ctx.Value(authResultKey{}).(*AuthResult)
+	return result
+}
+
+// extractRequestToken extracts the GitHub token from the request's Authorization header
+func extractRequestToken(r *http.Request) (string, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return "", fmt.Errorf("no authorization header")
+	}
+
+	// Extract token based on authentication scheme
+	switch {
+	case strings.HasPrefix(authHeader, "Bearer "):
+		return extractBearerToken(authHeader)
+	case strings.HasPrefix(authHeader, "Basic "):
+		return extractBasicAuthToken(authHeader)
+	default:
+		return "", fmt.Errorf("unsupported auth scheme")
+	}
+}
+
+// requestCandidateTokens returns the tokens the request's Authorization header may
+// carry: the Bearer token, or the Basic auth password followed by the username and,
+// for password-based providers such as LDAP, the "username:password" pair.
+// basic reports whether the header used the Basic scheme.
+func requestCandidateTokens(r *http.Request) (candidates []string, basic bool, err error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, false, fmt.Errorf("no authorization header")
+	}
+
+	switch {
+	case strings.HasPrefix(authHeader, "Bearer "):
+		token, err := extractBearerToken(authHeader)
+		if err != nil {
+			return nil, false, err
+		}
+		return []string{token}, false, nil
+	case strings.HasPrefix(authHeader, "Basic "):
+		username, password, err := ParseBasicAuth(authHeader)
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid basic auth: %w", err)
+		}
+		// Password first (most common pattern for Docker/Maven clients)
+		return []string{password, username, username + ":" + password}, true, nil
+	default:
+		return nil, false, fmt.Errorf("unsupported auth scheme")
+	}
+}
+
 // extractBearerToken extracts the token from a Bearer authentication header.
 //
 // Expected format: "Bearer <token>"
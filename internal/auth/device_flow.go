@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/constants"
+)
+
+// Device flow polling outcomes that are not failures: the user has not entered the
+// code yet, or the browser polls too often and must wait Interval seconds longer
+var (
+	ErrAuthorizationPending = errors.New("authorization pending")
+	ErrSlowDown             = errors.New("slow down")
+)
+
+// DeviceCode is a started device authorization: the user enters UserCode at
+// VerificationURI while the browser polls with DeviceCode every Interval seconds
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// DeviceFlow runs GitHub's OAuth device authorization flow (RFC 8628) for an OAuth
+// App. It needs no client secret or callback URL, so nothing is kept between the
+// browser's calls.
+type DeviceFlow struct {
+	githubURL  string
+	clientID   string
+	scopes     []string
+	httpClient *http.Client
+}
+
+// NewDeviceFlow creates the device flow of the browser session configuration
+func NewDeviceFlow(cfg *config.BrowserSessionsConfig) *DeviceFlow {
+	return &DeviceFlow{
+		githubURL:  strings.TrimSuffix(cfg.GitHubURL, "/"),
+		clientID:   cfg.ClientID,
+		scopes:     cfg.Scopes,
+		httpClient: &http.Client{Timeout: constants.GitHubHTTPTimeout},
+	}
+}
+
+// Start requests a device and user code from GitHub
+func (f *DeviceFlow) Start(ctx context.Context) (*DeviceCode, error) {
+	var code DeviceCode
+	err := f.post(ctx, "/login/device/code", url.Values{
+		"client_id": {f.clientID},
+		"scope":     {strings.Join(f.scopes, " ")},
+	}, &code)
+	if err != nil {
+		return nil, err
+	}
+	if code.DeviceCode == "" || code.UserCode == "" {
+		return nil, fmt.Errorf("GitHub returned no device code")
+	}
+	return &code, nil
+}
+
+// Poll asks GitHub once whether the user authorized deviceCode, returning the OAuth
+// access token if so. ErrAuthorizationPending and ErrSlowDown mean the browser
+// should poll again later.
+func (f *DeviceFlow) Poll(ctx context.Context, deviceCode string) (string, error) {
+	var resp struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	err := f.post(ctx, "/login/oauth/access_token", url.Values{
+		"client_id":   {f.clientID},
+		"device_code": {deviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+
+	switch resp.Error {
+	case "":
+		if resp.AccessToken == "" {
+			return "", fmt.Errorf("GitHub returned no access token")
+		}
+		return resp.AccessToken, nil
+	case "authorization_pending":
+		return "", ErrAuthorizationPending
+	case "slow_down":
+		return "", ErrSlowDown
+	default:
+		// expired_token, access_denied, incorrect_device_code, ...
+		return "", fmt.Errorf("device authorization failed: %s", resp.Error)
+	}
+}
+
+// post sends form to the GitHub OAuth endpoint path and decodes the JSON response
+// into v. GitHub reports device flow errors with status 200.
+func (f *DeviceFlow) post(ctx context.Context, path string, form url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.githubURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrGitHubUnavailable, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: GitHub returned %d", ErrGitHubUnavailable, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("%w: invalid response: %v", ErrGitHubUnavailable, err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// ErrGitHubUnavailable indicates GitHub could not answer a validation request
+// (timeout, network error, server error, or API rate limit), as opposed to
+// answering that the token is invalid. Only such failures are subject to the
+// fail-open policy (see GitHubClient.SetFailOpen).
+var ErrGitHubUnavailable = errors.New("github unavailable")
+
+// Auth failure policy decisions, recorded when GitHub is unavailable
+const (
+	decisionFailOpen   = "fail_open"
+	decisionFailClosed = "fail_closed"
+)
+
+// isGitHubUnavailable reports whether err means GitHub could not be asked, rather
+// than GitHub rejecting the token. A canceled request is neither: the client left.
+func isGitHubUnavailable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrGitHubUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var rateLimitErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &rateLimitErr) || errors.As(err, &abuseErr) {
+		return true
+	}
+
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) {
+		return respErr.Response != nil && respErr.Response.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// sanitizedError returns msg as an error, wrapping ErrGitHubUnavailable when the
+// underlying err was an availability failure. Used where err itself is not returned
+// to avoid exposing internal details, so the failure policy can still classify it.
+func sanitizedError(err error, msg string) error {
+	if isGitHubUnavailable(err) {
+		return fmt.Errorf("%s: %w", msg, ErrGitHubUnavailable)
+	}
+	return errors.New(msg)
+}
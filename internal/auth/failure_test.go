@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog"
+)
+
+func TestIsGitHubUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "sentinel", err: fmt.Errorf("membership: %w", ErrGitHubUnavailable), want: true},
+		{name: "deadline exceeded", err: fmt.Errorf("invalid token: %w", context.DeadlineExceeded), want: true},
+		{name: "canceled", err: &url.Error{Op: "Get", URL: "https://api.github.com/user", Err: context.Canceled}, want: false},
+		{name: "network error", err: &url.Error{Op: "Get", URL: "https://api.github.com/user", Err: errors.New("connection refused")}, want: true},
+		{name: "server error", err: &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusBadGateway}}, want: true},
+		{name: "unauthorized", err: &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusUnauthorized}}, want: false},
+		{name: "api rate limit", err: &github.RateLimitError{Response: &http.Response{StatusCode: http.StatusForbidden}}, want: true},
+		{name: "sanitized membership error", err: sanitizedError(context.DeadlineExceeded, "authentication failed"), want: true},
+		{name: "sanitized rejection", err: sanitizedError(errors.New("not found"), "authentication failed"), want: false},
+		{name: "budget exceeded", err: ErrValidationBudgetExceeded, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isGitHubUnavailable(tt.err); got != tt.want {
+				t.Errorf("isGitHubUnavailable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateForClient_FailurePolicy tests that only tokens with a last known good
+// result are accepted, and only when GitHub is unavailable under the fail-open policy
+func TestValidateForClient_FailurePolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		failOpen bool
+		lastGood bool
+		status   int  // GitHub response status for GET /user
+		hang     bool // GitHub does not answer within the auth timeout
+		wantErr  bool
+	}{
+		{name: "fail closed on outage", failOpen: false, lastGood: true, status: http.StatusServiceUnavailable, wantErr: true},
+		{name: "fail open on outage", failOpen: true, lastGood: true, status: http.StatusServiceUnavailable, wantErr: false},
+		{name: "fail open on timeout", failOpen: true, lastGood: true, hang: true, wantErr: false},
+		{name: "fail open without last known good", failOpen: true, lastGood: false, status: http.StatusServiceUnavailable, wantErr: true},
+		{name: "fail open never overrides rejection", failOpen: true, lastGood: true, status: http.StatusUnauthorized, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.hang {
+					select {
+					case <-r.Context().Done():
+					case <-time.After(5 * time.Second):
+					}
+					return
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := NewGitHubClient(server.URL, time.Minute, 0, zerolog.Nop())
+			client.SetAuthTimeout(100 * time.Millisecond)
+			if tt.failOpen {
+				client.SetFailOpen(time.Hour)
+			}
+
+			token := "ghp_" + strings.Repeat("a", 36)
+			if tt.lastGood {
+				client.cache.SetGracePeriod(time.Hour)
+				client.cache.lastGood.Set(client.cache.hashPAT(token), &AuthResult{Username: "alice"}, time.Hour)
+			}
+
+			result, err := client.ValidateForClient(context.Background(), "", token, "", nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got result for %s", result.Username)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Username != "alice" {
+				t.Errorf("expected last known good result for alice, got %s", result.Username)
+			}
+		})
+	}
+}
+
+// TestValidateForClient_RoutingTeamOutage tests that a routing team lookup failing
+// because GitHub is unavailable fails validation instead of caching a result
+// without the team
+func TestValidateForClient_RoutingTeamOutage(t *testing.T) {
+	var outage atomic.Bool
+	outage.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/user":
+			fmt.Fprint(w, `{"login":"alice"}`)
+		case "/api/v3/orgs/myorg/members/alice":
+			w.WriteHeader(http.StatusNoContent)
+		case "/api/v3/orgs/myorg/teams/ops/memberships/alice":
+			if outage.Load() {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			fmt.Fprint(w, `{"state":"active"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient(server.URL, time.Minute, 0, zerolog.Nop())
+	client.SetRoutingTeams([]string{"ops", "release"})
+	token := "ghp_" + strings.Repeat("a", 36)
+
+	if _, err := client.ValidateForClient(context.Background(), "", token, "myorg", nil); !errors.Is(err, ErrGitHubUnavailable) {
+		t.Fatalf("ValidateForClient() error = %v, want ErrGitHubUnavailable", err)
+	}
+
+	outage.Store(false)
+	result, err := client.ValidateForClient(context.Background(), "", token, "myorg", nil)
+	if err != nil {
+		t.Fatalf("ValidateForClient() error = %v", err)
+	}
+	if !slices.Equal(result.Teams, []string{"ops"}) {
+		t.Errorf("Teams = %v, want [ops] once GitHub recovered", result.Teams)
+	}
+}
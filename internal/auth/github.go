@@ -3,6 +3,7 @@
 // The package implements a high-performance authentication system with the following features:
 //   - Support for GitHub Personal Access Tokens (classic and fine-grained)
 //   - Support for GitHub Actions installation tokens
+//   - Support for OAuth access tokens (gho_) issued to users via OAuth/GitHub Apps
 //   - Organization and team membership validation
 //   - LRU caching with TTL to reduce GitHub API load
 //   - Singleflight request coalescing to prevent thundering herd
@@ -31,10 +32,13 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/v58/github"
 	"github.com/mainuli/artifusion/internal/constants"
+	"github.com/mainuli/artifusion/internal/metrics"
 	"github.com/rs/zerolog"
 	"golang.org/x/oauth2"
 	"golang.org/x/time/rate"
@@ -51,10 +55,15 @@ import (
 //
 // Thread safety: All methods are safe for concurrent use.
 type GitHubClient struct {
-	baseURL         string        // GitHub API base URL (supports enterprise)
-	rateLimit       *rate.Limiter // Token bucket rate limiter
-	rateLimitBuffer int           // Buffer to stay below GitHub's actual limits
-	cache           *AuthCache    // LRU cache with TTL and singleflight
+	baseURL         string            // GitHub API base URL (supports enterprise)
+	rateLimit       *rate.Limiter     // Token bucket rate limiter
+	rateLimitBuffer int               // Buffer to stay below GitHub's actual limits
+	cache           *AuthCache        // LRU cache with TTL and singleflight
+	routingTeams    []string          // Teams used for backend routing (membership resolved, not required)
+	budget          *ValidationBudget // Per-client cap on uncached validations (nil = unlimited)
+	authTimeout     time.Duration     // Bound on an uncached validation (0 = none)
+	failOpen        bool              // Accept recently valid tokens when GitHub is unavailable
+	metrics         *metrics.Metrics  // Optional (nil = no metrics)
 	logger          zerolog.Logger
 }
 
@@ -104,17 +113,113 @@ func NewGitHubClient(apiURL string, cacheTTL time.Duration, rateLimitBuffer int,
 // The validation is cached based on the token, so subsequent calls with the same
 // token will return cached results (until TTL expires) without hitting GitHub API.
 func (c *GitHubClient) Validate(ctx context.Context, pat string, requiredOrg string, requiredTeams []string) (*AuthResult, error) {
+	return c.ValidateForClient(ctx, "", pat, requiredOrg, requiredTeams)
+}
+
+// ValidateForClient is Validate with the GitHub API call charged to clientKey's
+// validation budget (see SetValidationBudget). Cached results are always returned;
+// on a cache miss, a client over budget gets ErrValidationBudgetExceeded without
+// GitHub being called. An empty clientKey is not subject to the budget.
+//
+// If GitHub is unavailable (see ErrGitHubUnavailable) or does not answer within the
+// auth timeout, the failure policy decides: fail closed returns the error, fail open
+// returns the token's last known good result if it is within the grace period.
+func (c *GitHubClient) ValidateForClient(ctx context.Context, clientKey string, pat string, requiredOrg string, requiredTeams []string) (*AuthResult, error) {
 	// Use cache with singleflight
-	return c.cache.Get(ctx, pat, func(ctx context.Context) (*AuthResult, error) {
+	authResult, err := c.cache.Get(ctx, pat, func(ctx context.Context) (*AuthResult, error) {
+		if c.budget != nil && clientKey != "" && !c.budget.Allow(clientKey) {
+			c.logger.Warn().
+				Str("client", clientKey).
+				Msg("GitHub validation budget exceeded, rejecting uncached token")
+			return nil, ErrValidationBudgetExceeded
+		}
+
+		if c.authTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.authTimeout)
+			defer cancel()
+		}
 		return c.validateWithGitHub(ctx, pat, requiredOrg, requiredTeams)
 	})
+	if err == nil || !isGitHubUnavailable(err) {
+		return authResult, err
+	}
+
+	if c.failOpen {
+		if lastGood, ok := c.cache.LastKnownGood(pat); ok {
+			c.recordFailurePolicy(decisionFailOpen)
+			c.logger.Warn().
+				Err(err).
+				Str("username", lastGood.Username).
+				Msg("GitHub unavailable, accepting recently valid token (fail open)")
+			return lastGood, nil
+		}
+	}
+
+	c.recordFailurePolicy(decisionFailClosed)
+	c.logger.Warn().
+		Err(err).
+		Msg("GitHub unavailable, rejecting token (fail closed)")
+	return nil, err
+}
+
+// SetAuthTimeout bounds each uncached validation; exceeding it is treated as GitHub
+// being unavailable. Must be called before the client is used concurrently.
+func (c *GitHubClient) SetAuthTimeout(timeout time.Duration) {
+	c.authTimeout = timeout
+}
+
+// SetFailOpen switches the failure policy to fail open: while GitHub is unavailable,
+// tokens that validated successfully within grace past their cache expiry are still
+// accepted. Tokens GitHub rejects are never accepted.
+// Must be called before the client is used concurrently.
+func (c *GitHubClient) SetFailOpen(grace time.Duration) {
+	c.failOpen = true
+	c.cache.SetGracePeriod(grace)
+}
+
+// SetMetrics enables recording of auth cache statistics and failure policy decisions.
+// Must be called before the client is used concurrently.
+func (c *GitHubClient) SetMetrics(m *metrics.Metrics) {
+	c.metrics = m
+	c.cache.SetMetrics(m)
+}
+
+// recordFailurePolicy records a failure policy decision if metrics are enabled
+func (c *GitHubClient) recordFailurePolicy(decision string) {
+	if c.metrics != nil {
+		c.metrics.RecordAuthFailurePolicy(decision)
+	}
+}
+
+// SetCacheRefreshAhead enables background re-validation of cached tokens used
+// within window of their expiry (see AuthCache.SetRefreshAhead).
+// Must be called before the client is used concurrently.
+func (c *GitHubClient) SetCacheRefreshAhead(window time.Duration) {
+	c.cache.SetRefreshAhead(window)
+}
+
+// SetValidationBudget limits the uncached validations each client may trigger.
+// Must be called before the client is used concurrently.
+func (c *GitHubClient) SetValidationBudget(budget *ValidationBudget) {
+	c.budget = budget
+}
+
+// SetRoutingTeams configures teams whose membership is resolved during validation
+// and recorded in AuthResult.Teams, without being required for authentication.
+// Handlers use these to route requests to team-scoped backends.
+//
+// Must be called before the client is used concurrently.
+func (c *GitHubClient) SetRoutingTeams(teams []string) {
+	c.routingTeams = teams
 }
 
 // validateWithGitHub performs actual GitHub API validation and routes to appropriate validator
 func (c *GitHubClient) validateWithGitHub(ctx context.Context, token string, requiredOrg string, requiredTeams []string) (*AuthResult, error) {
-	// Wait for rate limit slot
+	// Wait for rate limit slot. Failing to get one within the auth timeout means
+	// GitHub cannot be asked in time, so it counts as unavailability
 	if err := c.rateLimit.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit: %w", err)
+		return nil, fmt.Errorf("rate limit: %w: %w", ErrGitHubUnavailable, err)
 	}
 
 	// Determine token type (already validated by caller via ValidateTokenFormat)
@@ -124,8 +229,9 @@ func (c *GitHubClient) validateWithGitHub(ctx context.Context, token string, req
 	switch tokenType {
 	case TokenTypeGitHubActions:
 		return c.validateGitHubActionsToken(ctx, token, requiredOrg)
-	case TokenTypePAT:
-		return c.validatePATToken(ctx, token, requiredOrg, requiredTeams)
+	case TokenTypePAT, TokenTypeOAuth:
+		// OAuth tokens act on behalf of a user, so they get the same user/org/team checks as PATs
+		return c.validateUserToken(ctx, token, tokenType, requiredOrg, requiredTeams)
 	default:
 		// Should never reach here due to preemptive validation
 		return nil, fmt.Errorf("unsupported token type: %s", tokenType)
@@ -133,7 +239,7 @@ func (c *GitHubClient) validateWithGitHub(ctx context.Context, token string, req
 }
 
 // createGitHubClient creates a GitHub API client with OAuth2 authentication and enterprise URL support.
-// This helper eliminates code duplication between PAT and GitHub Actions token validation flows.
+// This helper eliminates code duplication between user token and GitHub Actions token validation flows.
 //
 // Parameters:
 //   - token: The GitHub token (PAT or GitHub Actions token) to authenticate with
@@ -162,23 +268,27 @@ func (c *GitHubClient) createGitHubClient(token string) (*github.Client, error)
 	return client, nil
 }
 
-// validatePATToken validates a Personal Access Token (classic or fine-grained).
+// validateUserToken validates a token that authenticates as a GitHub user:
+// Personal Access Tokens (classic or fine-grained) and OAuth access tokens (gho_).
 //
 // Validation steps:
 //  1. Authenticate with GitHub API using the PAT
 //  2. Retrieve the authenticated user's username
 //  3. If requiredOrg is set, verify organization membership
 //  4. If requiredTeams is set, verify membership in at least one required team
+//  5. If routing teams are configured, record which of them the user belongs to
+//  6. For fine-grained PATs, introspect read/write permissions (see introspectPermissions)
 //
 // Parameters:
 //   - ctx: Context for cancellation and timeout
-//   - token: GitHub Personal Access Token (ghp_* or github_pat_*)
+//   - token: GitHub Personal Access Token (ghp_* or github_pat_*) or OAuth token (gho_*)
+//   - tokenType: TokenTypePAT or TokenTypeOAuth (recorded in the AuthResult)
 //   - requiredOrg: Organization to check membership (empty to skip)
 //   - requiredTeams: Teams to check membership (empty to skip)
 //
 // Returns AuthResult with username, org, and teams on success.
 // Returns error if token is invalid or membership checks fail.
-func (c *GitHubClient) validatePATToken(ctx context.Context, token string, requiredOrg string, requiredTeams []string) (*AuthResult, error) {
+func (c *GitHubClient) validateUserToken(ctx context.Context, token string, tokenType string, requiredOrg string, requiredTeams []string) (*AuthResult, error) {
 	// Create GitHub client with enterprise URL support
 	client, err := c.createGitHubClient(token)
 	if err != nil {
@@ -196,56 +306,172 @@ func (c *GitHubClient) validatePATToken(ctx context.Context, token string, requi
 		return nil, fmt.Errorf("failed to get username")
 	}
 
-	// Check organization membership (only if requiredOrg is specified)
-	var userTeams []string
-	orgToReturn := requiredOrg
+	// Check organization and team membership (only if requiredOrg is specified)
+	userTeams, err := c.checkMembership(ctx, client, requiredOrg, requiredTeams, username)
+	if err != nil {
+		return nil, err
+	}
 
-	if requiredOrg != "" {
-		isMember, _, err := client.Organizations.IsMember(ctx, requiredOrg, username)
+	// Fine-grained PATs can be restricted to specific repositories and permissions,
+	// so record what the token can actually do. The result is cached with the AuthResult.
+	var permissions *TokenPermissions
+	if IsFineGrainedPAT(token) {
+		permissions, err = c.introspectPermissions(ctx, client, requiredOrg)
 		if err != nil {
-			// SECURITY: Sanitize error to avoid exposing internal details
-			// Log the actual error internally, but return a generic message to the client
 			c.logger.Debug().
 				Err(err).
-				Str("org", requiredOrg).
 				Str("username", username).
-				Msg("GitHub API error during organization membership check")
-			return nil, fmt.Errorf("authentication failed: unable to verify organization membership")
+				Msg("GitHub API error during fine-grained PAT permission introspection")
+			return nil, sanitizedError(err, "authentication failed: unable to verify token permissions")
 		}
+	}
 
-		if !isMember {
-			// SECURITY: Generic error message that doesn't reveal the organization name
+	return &AuthResult{
+		Username:    username,
+		Org:         requiredOrg,
+		Teams:       userTeams,
+		TokenType:   tokenType,
+		Repository:  "", // Not applicable for user tokens
+		Permissions: permissions,
+	}, nil
+}
+
+// checkMembership verifies that username is a member of requiredOrg and, if requiredTeams
+// is set, of at least one required team. It returns the required and routing teams the
+// user is an active member of. Membership of username is checked with the permissions
+// of client's token, which need not belong to username (see ResolveUser).
+//
+// If requiredOrg is empty, no checks are performed.
+func (c *GitHubClient) checkMembership(ctx context.Context, client *github.Client, requiredOrg string, requiredTeams []string, username string) ([]string, error) {
+	if requiredOrg == "" {
+		return nil, nil
+	}
+
+	isMember, _, err := client.Organizations.IsMember(ctx, requiredOrg, username)
+	if err != nil {
+		// SECURITY: Sanitize error to avoid exposing internal details
+		// Log the actual error internally, but return a generic message to the client
+		c.logger.Debug().
+			Err(err).
+			Str("org", requiredOrg).
+			Str("username", username).
+			Msg("GitHub API error during organization membership check")
+		return nil, sanitizedError(err, "authentication failed: unable to verify organization membership")
+	}
+
+	if !isMember {
+		// SECURITY: Generic error message that doesn't reveal the organization name
+		// This prevents enumeration attacks
+		return nil, fmt.Errorf("authentication failed: insufficient permissions")
+	}
+
+	// Check team membership if required
+	var userTeams []string
+	if len(requiredTeams) > 0 {
+		found := false
+		for _, team := range requiredTeams {
+			active, err := c.isActiveTeamMember(ctx, client, requiredOrg, team, username)
+			if err != nil {
+				return nil, err
+			}
+			if active {
+				userTeams = append(userTeams, team)
+				found = true
+			}
+		}
+
+		if !found {
+			// SECURITY: Generic error message that doesn't reveal team names
 			// This prevents enumeration attacks
 			return nil, fmt.Errorf("authentication failed: insufficient permissions")
 		}
+	}
 
-		// Check team membership if required
-		if len(requiredTeams) > 0 {
-			found := false
-			for _, team := range requiredTeams {
-				membership, _, err := client.Teams.GetTeamMembershipBySlug(ctx, requiredOrg, team, username)
-				if err == nil && membership.GetState() == "active" {
-					userTeams = append(userTeams, team)
-					found = true
-				}
-			}
+	// Resolve routing team membership. Not being a member never fails authentication,
+	// but an outage does: the result is cached, and would hide the user's teams for
+	// its whole TTL.
+	return c.appendRoutingTeams(ctx, client, requiredOrg, username, userTeams)
+}
 
-			if !found {
-				// SECURITY: Generic error message that doesn't reveal team names
-				// This prevents enumeration attacks
-				return nil, fmt.Errorf("authentication failed: insufficient permissions")
-			}
+// ResolveUser builds the AuthResult username would get, without their token.
+//
+// It is used for admin impersonation: the admin's own token (callerToken) is used to look
+// up the user and check their org and team membership, so the admin's token must be able
+// to see the org's members and teams. Results are cached per user like token validations.
+//
+// Fine-grained PAT permissions cannot be resolved for another user, so the result is not
+// restricted by operation.
+func (c *GitHubClient) ResolveUser(ctx context.Context, callerToken string, username string, requiredOrg string, requiredTeams []string) (*AuthResult, error) {
+	// Prefix keeps impersonation entries distinct from token entries in the shared cache
+	return c.cache.Get(ctx, "impersonate:"+strings.ToLower(username), func(ctx context.Context) (*AuthResult, error) {
+		if err := c.rateLimit.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("rate limit: %w", err)
+		}
+
+		client, err := c.createGitHubClient(callerToken)
+		if err != nil {
+			return nil, err
+		}
+
+		user, _, err := client.Users.Get(ctx, username)
+		if err != nil {
+			return nil, fmt.Errorf("unknown user: %w", err)
+		}
+
+		// Use the canonical login so team routing and logs match a real authentication
+		login := user.GetLogin()
+		userTeams, err := c.checkMembership(ctx, client, requiredOrg, requiredTeams, login)
+		if err != nil {
+			return nil, err
+		}
+
+		return &AuthResult{
+			Username:  login,
+			Org:       requiredOrg,
+			Teams:     userTeams,
+			TokenType: TokenTypePAT,
+		}, nil
+	})
+}
+
+// appendRoutingTeams adds the routing teams the user is an active member of to teams.
+// Teams already present (e.g. checked as required teams) are not queried again.
+func (c *GitHubClient) appendRoutingTeams(ctx context.Context, client *github.Client, org, username string, teams []string) ([]string, error) {
+	for _, team := range c.routingTeams {
+		if slices.Contains(teams, team) {
+			continue
+		}
+
+		active, err := c.isActiveTeamMember(ctx, client, org, team, username)
+		if err != nil {
+			return nil, err
+		}
+		if active {
+			teams = append(teams, team)
 		}
 	}
-	// If requiredOrg is empty, skip org/team checks - PAT validation via Users.Get is sufficient
+	return teams, nil
+}
 
-	return &AuthResult{
-		Username:   username,
-		Org:        orgToReturn,
-		Teams:      userTeams,
-		TokenType:  TokenTypePAT,
-		Repository: "", // Not applicable for PATs
-	}, nil
+// isActiveTeamMember reports whether username is an active member of team. Lookups
+// failing because GitHub is unavailable return an ErrGitHubUnavailable error, so the
+// failure policy applies and no result built from them is cached; any other
+// failure (e.g. the team is not visible to the token) means no membership.
+func (c *GitHubClient) isActiveTeamMember(ctx context.Context, client *github.Client, org, team, username string) (bool, error) {
+	membership, _, err := client.Teams.GetTeamMembershipBySlug(ctx, org, team, username)
+	if err != nil {
+		if isGitHubUnavailable(err) {
+			c.logger.Debug().
+				Err(err).
+				Str("org", org).
+				Str("team", team).
+				Str("username", username).
+				Msg("GitHub API error during team membership check")
+			return false, sanitizedError(err, "authentication failed: unable to verify team membership")
+		}
+		return false, nil
+	}
+	return membership.GetState() == "active", nil
 }
 
 // validateGitHubActionsToken validates a GitHub Actions installation token (ghs_).
@@ -383,3 +609,9 @@ func (c *GitHubClient) ClearCache() {
 func (c *GitHubClient) CacheStats() CacheStats {
 	return c.cache.Stats()
 }
+
+// AuthCache returns the cache of validation results, shared with the other token
+// providers so cached entries are invalidated and reported together
+func (c *GitHubClient) AuthCache() *AuthCache {
+	return c.cache
+}
@@ -81,6 +81,11 @@ func TestTokenTypeRouting(t *testing.T) {
 			token:    "ghp_" + strings.Repeat("a", 36),
 			wantType: TokenTypePAT,
 		},
+		{
+			name:     "OAuth token routes to user token validator",
+			token:    "gho_" + strings.Repeat("a", 36),
+			wantType: TokenTypeOAuth,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/constants"
+	"github.com/rs/zerolog"
+)
+
+// GitLabProviderName is the name of the built-in GitLab identity provider
+const GitLabProviderName = "gitlab"
+
+// GitLab token types
+const (
+	TokenTypeGitLabPAT = "gitlab_pat" // Personal, project and group access tokens
+	TokenTypeGitLabJob = "gitlab_job" // CI/CD job tokens
+)
+
+// gitlabJobUsername is the username of clients authenticated with a job token.
+// Job tokens act for a pipeline, not a person, so they never match admins or
+// user allowlists.
+const gitlabJobUsername = "gitlab-ci[bot]"
+
+// GitLab token formats. Access tokens are glpat- followed by at least 20
+// characters (longer for routable tokens); job tokens carry the glcbt- prefix
+// (GitLab 16.8+).
+var (
+	regexGitLabPAT = regexp.MustCompile(`^glpat-[a-zA-Z0-9_.-]{20,}$`)
+	regexGitLabJob = regexp.MustCompile(`^glcbt-[a-zA-Z0-9_.-]{20,}$`)
+)
+
+// ErrGitLabUnavailable indicates GitLab could not answer a validation request
+// (network error, server error or rate limit), as opposed to rejecting the token
+var ErrGitLabUnavailable = errors.New("gitlab unavailable")
+
+// ValidateGitLabTokenFormat returns the type of a GitLab token without calling
+// GitLab: TokenTypeGitLabPAT or TokenTypeGitLabJob
+func ValidateGitLabTokenFormat(token string) (string, error) {
+	switch {
+	case regexGitLabPAT.MatchString(token):
+		return TokenTypeGitLabPAT, nil
+	case regexGitLabJob.MatchString(token):
+		return TokenTypeGitLabJob, nil
+	default:
+		return TokenTypeUnknown, fmt.Errorf("invalid GitLab token format")
+	}
+}
+
+// GitLabClient validates GitLab tokens with the GitLab REST API, requiring users
+// to be members of a group and jobs to run in one of its projects. Results are
+// cached in an AuthCache shared with GitHub validations.
+//
+// Thread safety: All methods are safe for concurrent use.
+type GitLabClient struct {
+	apiURL         string // e.g. https://gitlab.com/api/v4
+	requiredGroup  string // Full group path (empty = any GitLab user)
+	usernamePrefix string
+	httpClient     *http.Client
+	cache          *AuthCache
+	logger         zerolog.Logger
+}
+
+// NewGitLabClient creates a GitLab identity provider for the GitLab instance of cfg,
+// caching results in cache
+func NewGitLabClient(cfg *config.GitLabConfig, cache *AuthCache, logger zerolog.Logger) *GitLabClient {
+	return &GitLabClient{
+		apiURL:         strings.TrimSuffix(cfg.APIURL, "/"),
+		requiredGroup:  strings.Trim(cfg.RequiredGroup, "/"),
+		usernamePrefix: cfg.UsernamePrefix,
+		httpClient:     &http.Client{Timeout: constants.GitHubHTTPTimeout},
+		cache:          cache,
+		logger:         logger,
+	}
+}
+
+// Name returns "gitlab"
+func (c *GitLabClient) Name() string {
+	return GitLabProviderName
+}
+
+// Accepts reports whether token has the format of a GitLab access or job token
+func (c *GitLabClient) Accepts(token string) bool {
+	_, err := ValidateGitLabTokenFormat(token)
+	return err == nil
+}
+
+// Authenticate validates token with the GitLab API (with caching)
+func (c *GitLabClient) Authenticate(r *http.Request, token string) (*AuthResult, error) {
+	return c.cache.Get(r.Context(), token, func(ctx context.Context) (*AuthResult, error) {
+		tokenType, err := ValidateGitLabTokenFormat(token)
+		if err != nil {
+			return nil, err
+		}
+		if tokenType == TokenTypeGitLabJob {
+			return c.validateJobToken(ctx, token)
+		}
+		return c.validateAccessToken(ctx, token)
+	})
+}
+
+// gitlabUser is the subset of a GitLab user used for authentication
+type gitlabUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	State    string `json:"state"`
+}
+
+// validateAccessToken validates a personal, project or group access token and the
+// group membership of the user it belongs to (a bot user for project and group
+// tokens)
+func (c *GitLabClient) validateAccessToken(ctx context.Context, token string) (*AuthResult, error) {
+	var user gitlabUser
+	if err := c.get(ctx, "/user", "PRIVATE-TOKEN", token, &user); err != nil {
+		return nil, err
+	}
+	if user.Username == "" {
+		return nil, fmt.Errorf("failed to get username")
+	}
+	if user.State != "active" {
+		return nil, fmt.Errorf("authentication failed: user is not active")
+	}
+
+	if c.requiredGroup != "" {
+		// members/all includes members inherited from ancestor groups
+		var member struct {
+			State string `json:"state"`
+		}
+		path := "/groups/" + url.PathEscape(c.requiredGroup) + "/members/all/" + strconv.FormatInt(user.ID, 10)
+		err := c.get(ctx, path, "PRIVATE-TOKEN", token, &member)
+		if err != nil && !errors.Is(err, ErrGitLabUnavailable) {
+			// SECURITY: Generic error message that doesn't reveal the group name
+			c.logger.Debug().
+				Err(err).
+				Str("username", user.Username).
+				Msg("GitLab group membership check failed")
+			return nil, fmt.Errorf("authentication failed: insufficient permissions")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if member.State != "" && member.State != "active" {
+			return nil, fmt.Errorf("authentication failed: insufficient permissions")
+		}
+	}
+
+	return &AuthResult{
+		Username:  c.usernamePrefix + user.Username,
+		Org:       c.requiredGroup,
+		TokenType: TokenTypeGitLabPAT,
+	}, nil
+}
+
+// validateJobToken validates a CI/CD job token of a running job, whose project
+// must be in the required group
+func (c *GitLabClient) validateJobToken(ctx context.Context, token string) (*AuthResult, error) {
+	var job struct {
+		ID     int64      `json:"id"`
+		WebURL string     `json:"web_url"`
+		User   gitlabUser `json:"user"`
+	}
+	if err := c.get(ctx, "/job", "JOB-TOKEN", token, &job); err != nil {
+		return nil, err
+	}
+
+	project, err := c.jobProject(job.WebURL)
+	if err != nil {
+		return nil, err
+	}
+	if c.requiredGroup != "" && !strings.HasPrefix(project, c.requiredGroup+"/") {
+		// SECURITY: Generic error message that doesn't reveal the group name
+		return nil, fmt.Errorf("authentication failed: insufficient permissions")
+	}
+
+	c.logger.Debug().
+		Int64("job_id", job.ID).
+		Str("project", project).
+		Str("triggered_by", job.User.Username).
+		Msg("GitLab job token validated")
+
+	return &AuthResult{
+		Username:   gitlabJobUsername,
+		Org:        c.requiredGroup,
+		Repository: project,
+		TokenType:  TokenTypeGitLabJob,
+	}, nil
+}
+
+// jobProject returns the full project path of a job from its web URL,
+// e.g. "acme/platform/api" for https://gitlab.example.com/acme/platform/api/-/jobs/42.
+// The API URL's path before /api/v4 is the instance's relative URL root.
+func (c *GitLabClient) jobProject(webURL string) (string, error) {
+	u, err := url.Parse(webURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid job URL")
+	}
+
+	root := ""
+	if apiURL, err := url.Parse(c.apiURL); err == nil {
+		root = strings.TrimSuffix(strings.TrimSuffix(apiURL.Path, "/"), "/api/v4")
+	}
+	project, _, found := strings.Cut(strings.TrimPrefix(u.Path, root), "/-/jobs/")
+	project = strings.Trim(project, "/")
+	if !found || project == "" {
+		return "", fmt.Errorf("invalid job URL")
+	}
+	return project, nil
+}
+
+// get calls the GitLab API endpoint path with token in header and decodes the JSON
+// response into v. Failures GitLab is not answering for wrap ErrGitLabUnavailable.
+func (c *GitLabClient) get(ctx context.Context, path, header, token string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(header, token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %v", ErrGitLabUnavailable, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("%w: GitLab returned %d", ErrGitLabUnavailable, resp.StatusCode)
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("invalid token")
+	default:
+		return fmt.Errorf("GitLab returned %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("%w: invalid response: %v", ErrGitLabUnavailable, err)
+	}
+	return nil
+}
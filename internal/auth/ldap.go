@@ -0,0 +1,338 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// LDAPProviderName is the name of the built-in LDAP identity provider
+const LDAPProviderName = "ldap"
+
+// TokenTypeLDAP is the token type of clients authenticated with a directory
+// username and password
+const TokenTypeLDAP = "ldap"
+
+// ldapCacheKeyPrefix separates cached LDAP credentials from tokens in the shared
+// AuthCache
+const ldapCacheKeyPrefix = "ldap:"
+
+// ErrLDAPUnavailable indicates the directory could not answer a validation request
+// (network error, busy server, or the service account being rejected), as opposed
+// to rejecting the user's credentials
+var ErrLDAPUnavailable = errors.New("ldap unavailable")
+
+// ldapConn is the subset of *ldap.Conn used for authentication
+type ldapConn interface {
+	Bind(username, password string) error
+	UnauthenticatedBind(username string) error
+	Search(req *ldap.SearchRequest) (*ldap.SearchResult, error)
+	IsClosing() bool
+	Close() error
+}
+
+// LDAPAuthenticator validates Basic auth usernames and passwords against an LDAP
+// directory: the user is searched for with the service account, authenticated by
+// binding as the user, and must be a member of one of the required groups.
+// Its tokens are the "username:password" candidates of Basic auth credentials.
+// Results are cached in an AuthCache shared with GitHub validations, so a changed
+// password keeps working until the cached result expires.
+//
+// Thread safety: All methods are safe for concurrent use.
+type LDAPAuthenticator struct {
+	bindDN         string
+	bindPassword   string
+	userBaseDN     string
+	userFilter     string // Contains %s for the escaped username
+	groupAttribute string
+	requiredGroups []*ldap.DN
+	usernamePrefix string
+	timeout        time.Duration
+	pool           *ldapPool
+	cache          *AuthCache
+	logger         zerolog.Logger
+}
+
+// NewLDAPAuthenticator creates an LDAP identity provider for the directory of cfg,
+// caching results in cache. Connections are opened on demand and kept for reuse.
+func NewLDAPAuthenticator(cfg *config.LDAPConfig, cache *AuthCache, logger zerolog.Logger) (*LDAPAuthenticator, error) {
+	requiredGroups := make([]*ldap.DN, 0, len(cfg.RequiredGroups))
+	for _, group := range cfg.RequiredGroups {
+		dn, err := ldap.ParseDN(group)
+		if err != nil {
+			return nil, fmt.Errorf("invalid required group %q: %w", group, err)
+		}
+		requiredGroups = append(requiredGroups, dn)
+	}
+
+	tlsConfig, err := ldapTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &LDAPAuthenticator{
+		bindDN:         cfg.BindDN,
+		bindPassword:   cfg.BindPassword,
+		userBaseDN:     cfg.UserBaseDN,
+		userFilter:     cfg.UserFilter,
+		groupAttribute: cfg.GroupAttribute,
+		requiredGroups: requiredGroups,
+		usernamePrefix: cfg.UsernamePrefix,
+		timeout:        cfg.Timeout,
+		cache:          cache,
+		logger:         logger,
+	}
+	a.pool = newLDAPPool(cfg.PoolSize, func() (ldapConn, error) {
+		return dialLDAP(cfg.URL, cfg.StartTLS, tlsConfig, cfg.Timeout)
+	})
+	return a, nil
+}
+
+// ldapTLSConfig returns the TLS configuration of ldaps:// and StartTLS connections
+func ldapTLSConfig(cfg *config.LDAPConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if tlsConfig.ServerName == "" {
+		// StartTLS does not derive the name from the dialed address
+		if u, err := url.Parse(cfg.URL); err == nil {
+			tlsConfig.ServerName = u.Hostname()
+		}
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read LDAP CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in LDAP CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// dialLDAP opens a connection to the directory at rawURL, upgrading it with
+// StartTLS if requested
+func dialLDAP(rawURL string, startTLS bool, tlsConfig *tls.Config, timeout time.Duration) (ldapConn, error) {
+	conn, err := ldap.DialURL(rawURL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: timeout}),
+		ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(timeout)
+
+	if startTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// Name returns "ldap"
+func (a *LDAPAuthenticator) Name() string {
+	return LDAPProviderName
+}
+
+// Accepts reports whether token is a "username:password" pair of Basic auth
+// credentials. LDAP passwords have no format of their own, so token providers
+// listed before LDAP take precedence.
+func (a *LDAPAuthenticator) Accepts(token string) bool {
+	username, password, ok := strings.Cut(token, ":")
+	return ok && username != "" && password != ""
+}
+
+// Authenticate validates the "username:password" pair token against the directory
+// (with caching)
+func (a *LDAPAuthenticator) Authenticate(r *http.Request, token string) (*AuthResult, error) {
+	username, password, ok := strings.Cut(token, ":")
+	// SECURITY: An empty password would be an unauthenticated bind, which
+	// directories accept for any DN
+	if !ok || username == "" || password == "" {
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	return a.cache.Get(r.Context(), ldapCacheKeyPrefix+token, func(ctx context.Context) (*AuthResult, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return a.authenticate(username, password)
+	})
+}
+
+// Close closes the idle directory connections
+func (a *LDAPAuthenticator) Close() {
+	a.pool.close()
+}
+
+// authenticate validates username and password on a pooled connection. Connections
+// that failed at the network level are discarded rather than reused.
+func (a *LDAPAuthenticator) authenticate(username, password string) (*AuthResult, error) {
+	conn, err := a.pool.get()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLDAPUnavailable, err)
+	}
+
+	result, err := a.authenticateConn(conn, username, password)
+	if errors.Is(err, ErrLDAPUnavailable) {
+		_ = conn.Close()
+	} else {
+		a.pool.put(conn)
+	}
+	return result, err
+}
+
+// authenticateConn looks the user up as the service account, binds as the user and
+// checks group membership. The connection is left bound as the user; it is bound
+// as the service account again when next used.
+func (a *LDAPAuthenticator) authenticateConn(conn ldapConn, username, password string) (*AuthResult, error) {
+	var err error
+	if a.bindDN != "" {
+		err = conn.Bind(a.bindDN, a.bindPassword)
+	} else {
+		err = conn.UnauthenticatedBind("")
+	}
+	if err != nil {
+		// A rejected service account is a configuration problem, not the client's
+		return nil, fmt.Errorf("%w: service account bind failed: %v", ErrLDAPUnavailable, err)
+	}
+
+	search := ldap.NewSearchRequest(a.userBaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(a.timeout.Seconds()), false,
+		fmt.Sprintf(a.userFilter, ldap.EscapeFilter(username)),
+		[]string{a.groupAttribute}, nil)
+	found, err := conn.Search(search)
+	if err != nil {
+		return nil, fmt.Errorf("%w: user search failed: %v", ErrLDAPUnavailable, err)
+	}
+	// SECURITY: Unknown users fail like wrong passwords, so usernames can't be probed
+	if len(found.Entries) != 1 {
+		if len(found.Entries) > 1 {
+			a.logger.Warn().
+				Str("username", username).
+				Int("entries", len(found.Entries)).
+				Msg("LDAP user filter matched several entries")
+		}
+		return nil, fmt.Errorf("authentication failed: invalid credentials")
+	}
+	entry := found.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if isLDAPUnavailable(err) {
+			return nil, fmt.Errorf("%w: user bind failed: %v", ErrLDAPUnavailable, err)
+		}
+		return nil, fmt.Errorf("authentication failed: invalid credentials")
+	}
+
+	teams, member := a.groupMembership(entry.GetEqualFoldAttributeValues(a.groupAttribute))
+	if !member {
+		// SECURITY: Generic error message that doesn't reveal the group names
+		a.logger.Debug().
+			Str("username", username).
+			Msg("LDAP group membership check failed")
+		return nil, fmt.Errorf("authentication failed: insufficient permissions")
+	}
+
+	return &AuthResult{
+		// Directory usernames are case-insensitive; fold them so allowlists and
+		// admin lists can't be sidestepped by changing case
+		Username:  a.usernamePrefix + strings.ToLower(username),
+		Teams:     teams,
+		TokenType: TokenTypeLDAP,
+	}, nil
+}
+
+// groupMembership returns the names (first RDN values, e.g. the cn) of the groups
+// with the given DNs, and whether one of them is a required group
+func (a *LDAPAuthenticator) groupMembership(groupDNs []string) ([]string, bool) {
+	teams := make([]string, 0, len(groupDNs))
+	member := len(a.requiredGroups) == 0
+	for _, groupDN := range groupDNs {
+		dn, err := ldap.ParseDN(groupDN)
+		if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+			continue
+		}
+		teams = append(teams, dn.RDNs[0].Attributes[0].Value)
+		for _, required := range a.requiredGroups {
+			if dn.EqualFold(required) {
+				member = true
+			}
+		}
+	}
+	return teams, member
+}
+
+// isLDAPUnavailable reports whether err means the directory did not answer, as
+// opposed to rejecting the operation
+func isLDAPUnavailable(err error) bool {
+	return ldap.IsErrorWithCode(err, ldap.ErrorNetwork) ||
+		ldap.IsErrorWithCode(err, ldap.LDAPResultBusy) ||
+		ldap.IsErrorWithCode(err, ldap.LDAPResultUnavailable)
+}
+
+// ldapPool keeps up to size idle directory connections for reuse. Connections
+// beyond it are opened on demand and closed after use.
+type ldapPool struct {
+	dial func() (ldapConn, error)
+	idle chan ldapConn
+}
+
+func newLDAPPool(size int, dial func() (ldapConn, error)) *ldapPool {
+	return &ldapPool{dial: dial, idle: make(chan ldapConn, size)}
+}
+
+// get returns an idle connection, or a new one if none is left
+func (p *ldapPool) get() (ldapConn, error) {
+	for {
+		select {
+		case conn := <-p.idle:
+			// The server may have closed idle connections
+			if conn.IsClosing() {
+				_ = conn.Close()
+				continue
+			}
+			return conn, nil
+		default:
+			return p.dial()
+		}
+	}
+}
+
+// put returns conn to the pool, closing it if the pool is full
+func (p *ldapPool) put(conn ldapConn) {
+	select {
+	case p.idle <- conn:
+	default:
+		_ = conn.Close()
+	}
+}
+
+// close closes the idle connections
+func (p *ldapPool) close() {
+	for {
+		select {
+		case conn := <-p.idle:
+			_ = conn.Close()
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/audit"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/patrickmn/go-cache"
+)
+
+// ErrLockedOut is returned for requests whose client IP or token is locked out
+// after repeated failed authentications.
+var ErrLockedOut = errors.New("too many failed authentications")
+
+// Lockout keys, also used as metric labels
+const (
+	lockoutKeyIP    = "ip"
+	lockoutKeyToken = "token"
+)
+
+// lockoutState tracks the failed authentications of one client IP or token
+type lockoutState struct {
+	failures    int
+	windowEnd   time.Time
+	lockouts    int // lockouts so far; each doubles the next one
+	lockedUntil time.Time
+}
+
+// Lockout blocks client IPs and tokens after repeated failed authentications, with
+// lockouts doubling in length while failures continue. Tokens are tracked by hash.
+//
+// Windows are fixed: they start at a key's first failure.
+//
+// Thread safety: All methods are safe for concurrent use.
+type Lockout struct {
+	cfg     *config.AuthLockoutConfig
+	auditor *audit.Logger
+	metrics *metrics.Metrics
+
+	mu     sync.Mutex
+	states *cache.Cache
+}
+
+// NewLockout creates a lockout following cfg. Lockouts are recorded by auditor;
+// m may be nil.
+func NewLockout(cfg *config.AuthLockoutConfig, auditor *audit.Logger, m *metrics.Metrics) *Lockout {
+	return &Lockout{
+		cfg:     cfg,
+		auditor: auditor,
+		metrics: m,
+		states:  cache.New(cfg.Window, cfg.Window),
+	}
+}
+
+// Check returns an error wrapping ErrLockedOut if the request's client IP or token
+// (empty if none was presented) is locked out
+func (l *Lockout) Check(r *http.Request, token string) error {
+	now := time.Now()
+	for _, key := range lockoutKeys(r, token) {
+		l.mu.Lock()
+		var lockedUntil time.Time
+		if cached, found := l.states.Get(key.id()); found {
+			lockedUntil = cached.(*lockoutState).lockedUntil
+		}
+		l.mu.Unlock()
+
+		if now.Before(lockedUntil) {
+			if l.metrics != nil {
+				l.metrics.RecordAuthLockoutRejection(key.kind)
+			}
+			return fmt.Errorf("%w: %s locked out for %s", ErrLockedOut, key.kind, lockedUntil.Sub(now).Round(time.Second))
+		}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed authentication of the request's client IP and
+// token (empty if none could be extracted), locking out keys reaching the limit
+func (l *Lockout) RecordFailure(r *http.Request, token string) {
+	now := time.Now()
+	for _, key := range lockoutKeys(r, token) {
+		l.mu.Lock()
+		state := &lockoutState{}
+		if cached, found := l.states.Get(key.id()); found {
+			state = cached.(*lockoutState)
+		}
+
+		if !now.Before(state.windowEnd) {
+			state.failures = 0
+			state.windowEnd = now.Add(l.cfg.Window)
+		}
+		state.failures++
+
+		var duration time.Duration
+		if state.failures >= l.cfg.MaxFailures {
+			state.lockouts++
+			duration = l.lockoutDuration(state.lockouts)
+			state.lockedUntil = now.Add(duration)
+			state.failures = 0
+			state.windowEnd = state.lockedUntil
+		}
+
+		// Forget the key one window after its last failure or lockout ended
+		ttl := l.cfg.Window
+		if now.Before(state.lockedUntil) {
+			ttl += state.lockedUntil.Sub(now)
+		}
+		l.states.Set(key.id(), state, ttl)
+		lockouts := state.lockouts
+		l.mu.Unlock()
+
+		if duration > 0 {
+			l.lock(r, key, lockouts, duration)
+		}
+	}
+}
+
+// lockoutDuration returns the length of a key's nth lockout
+func (l *Lockout) lockoutDuration(n int) time.Duration {
+	duration := l.cfg.Duration
+	for i := 1; i < n && duration < l.cfg.MaxDuration; i++ {
+		duration *= 2
+	}
+	return min(duration, l.cfg.MaxDuration)
+}
+
+// lock records a new lockout of key
+func (l *Lockout) lock(r *http.Request, key lockoutKey, lockouts int, duration time.Duration) {
+	if l.metrics != nil {
+		l.metrics.RecordAuthLockout(key.kind)
+	}
+
+	event := l.auditor.Record(r, "auth_lockout").
+		Str("key", key.kind).
+		Int("max_failures", l.cfg.MaxFailures).
+		Int("lockouts", lockouts).
+		Dur("duration", duration)
+	if key.kind == lockoutKeyToken {
+		event = event.Str("token_hash", key.value[:16])
+	}
+	event.Msg("Locked out after repeated failed authentications")
+}
+
+// lockoutKey identifies a client IP or token tracked by the lockout
+type lockoutKey struct {
+	kind  string // lockoutKeyIP or lockoutKeyToken
+	value string // client IP or token hash
+}
+
+// id returns the cache key of k
+func (k lockoutKey) id() string {
+	return k.kind + ":" + k.value
+}
+
+// lockoutKeys returns the keys of a request: its client IP and, if presented, the
+// hash of its token. The client IP only comes from forwarding headers set by a
+// trusted proxy, so clients can't dodge a lockout or lock out others with them.
+func lockoutKeys(r *http.Request, token string) []lockoutKey {
+	keys := []lockoutKey{{kind: lockoutKeyIP, value: middleware.TrustedClientIP(r)}}
+	if token != "" {
+		keys = append(keys, lockoutKey{kind: lockoutKeyToken, value: hashToken(token)})
+	}
+	return keys
+}
+
+// isCredentialFailure reports whether a validation error means the credentials were
+// rejected, as opposed to the validation not taking place
+func isCredentialFailure(err error) bool {
+	return !isGitHubUnavailable(err) &&
+		!errors.Is(err, ErrOIDCUnavailable) &&
+		!errors.Is(err, ErrGitLabUnavailable) &&
+		!errors.Is(err, ErrLDAPUnavailable) &&
+		!errors.Is(err, ErrValidationBudgetExceeded) &&
+		!errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/audit"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/mainuli/artifusion/internal/utils"
+	"github.com/rs/zerolog"
+)
+
+func newLockoutRequest(ip string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/pkg", nil)
+	r.RemoteAddr = ip + ":1234"
+	return r
+}
+
+func TestLockout(t *testing.T) {
+	cfg := &config.AuthLockoutConfig{
+		Enabled:     true,
+		MaxFailures: 3,
+		Window:      time.Minute,
+		Duration:    50 * time.Millisecond,
+		MaxDuration: time.Second,
+	}
+	lockout := NewLockout(cfg, audit.New(zerolog.Nop()), nil)
+
+	for i := 0; i < 2; i++ {
+		lockout.RecordFailure(newLockoutRequest("10.0.0.1"), "ghp_probe")
+	}
+	if err := lockout.Check(newLockoutRequest("10.0.0.1"), "ghp_probe"); err != nil {
+		t.Fatalf("Check() below the limit error = %v, want nil", err)
+	}
+
+	lockout.RecordFailure(newLockoutRequest("10.0.0.1"), "ghp_probe")
+
+	tests := []struct {
+		name    string
+		ip      string
+		token   string
+		wantErr bool
+	}{
+		{name: "locked out IP", ip: "10.0.0.1", token: "ghp_other", wantErr: true},
+		{name: "locked out IP without token", ip: "10.0.0.1", wantErr: true},
+		{name: "locked out token from another IP", ip: "10.0.0.2", token: "ghp_probe", wantErr: true},
+		{name: "other client", ip: "10.0.0.2", token: "ghp_other", wantErr: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := lockout.Check(newLockoutRequest(tt.ip), tt.token)
+			if gotErr := errors.Is(err, ErrLockedOut); gotErr != tt.wantErr {
+				t.Errorf("Check() error = %v, want locked out = %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := lockout.Check(newLockoutRequest("10.0.0.1"), "ghp_probe"); err != nil {
+		t.Fatalf("Check() after the lockout error = %v, want nil", err)
+	}
+
+	// Failures continuing after a lockout lock out for twice as long
+	for i := 0; i < 3; i++ {
+		lockout.RecordFailure(newLockoutRequest("10.0.0.1"), "")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if err := lockout.Check(newLockoutRequest("10.0.0.1"), ""); !errors.Is(err, ErrLockedOut) {
+		t.Errorf("Check() during second lockout error = %v, want ErrLockedOut", err)
+	}
+}
+
+// TestLockout_SpoofedForwardedFor tests that forwarding headers a client sets itself
+// neither escape its lockout nor lock out the address they name
+func TestLockout_SpoofedForwardedFor(t *testing.T) {
+	lockout := NewLockout(&config.AuthLockoutConfig{
+		Enabled:     true,
+		MaxFailures: 3,
+		Window:      time.Minute,
+		Duration:    time.Minute,
+		MaxDuration: time.Hour,
+	}, audit.New(zerolog.Nop()), nil)
+	proxies, err := utils.NewTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests pass through the client IP middleware, as in the server
+	request := func(remoteIP, forwardedFor string) *http.Request {
+		var resolved *http.Request
+		r := newLockoutRequest(remoteIP)
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		middleware.ClientIP(proxies)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			resolved = r
+		})).ServeHTTP(httptest.NewRecorder(), r)
+		return resolved
+	}
+
+	// An attacker cycling X-Forwarded-For on direct connections is still locked out,
+	// and the addresses it names are not
+	for i := 0; i < 3; i++ {
+		lockout.RecordFailure(request("203.0.113.66", fmt.Sprintf("198.51.100.%d", i)), "")
+	}
+	if err := lockout.Check(request("203.0.113.66", "198.51.100.99"), ""); !errors.Is(err, ErrLockedOut) {
+		t.Errorf("Check() of the attacker error = %v, want ErrLockedOut", err)
+	}
+	if err := lockout.Check(newLockoutRequest("198.51.100.1"), ""); err != nil {
+		t.Errorf("Check() of a spoofed address error = %v, want nil", err)
+	}
+
+	// Behind a trusted proxy, the address it appends is the client: prepending a
+	// victim's address locks out the attacker, not the victim
+	for i := 0; i < 3; i++ {
+		lockout.RecordFailure(request("10.0.0.5", "192.0.2.1, 203.0.113.77"), "")
+	}
+	if err := lockout.Check(request("10.0.0.5", "203.0.113.77"), ""); !errors.Is(err, ErrLockedOut) {
+		t.Errorf("Check() of the proxied attacker error = %v, want ErrLockedOut", err)
+	}
+	if err := lockout.Check(request("10.0.0.5", "192.0.2.1"), ""); err != nil {
+		t.Errorf("Check() of the proxied victim error = %v, want nil", err)
+	}
+}
+
+func TestLockout_Duration(t *testing.T) {
+	lockout := NewLockout(&config.AuthLockoutConfig{Duration: time.Minute, MaxDuration: 5 * time.Minute}, nil, nil)
+
+	tests := []struct {
+		lockouts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 5 * time.Minute},
+		{100, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := lockout.lockoutDuration(tt.lockouts); got != tt.want {
+			t.Errorf("lockoutDuration(%d) = %s, want %s", tt.lockouts, got, tt.want)
+		}
+	}
+}
+
+func TestAuthenticateRequest_Lockout(t *testing.T) {
+	authenticator := &ClientAuthenticator{logger: zerolog.Nop()}
+	authenticator.SetLockout(NewLockout(&config.AuthLockoutConfig{
+		Enabled:     true,
+		MaxFailures: 2,
+		Window:      time.Minute,
+		Duration:    time.Minute,
+		MaxDuration: time.Hour,
+	}, audit.New(zerolog.Nop()), nil))
+
+	authenticate := func(authHeader string) error {
+		r := newLockoutRequest("10.0.0.1")
+		if authHeader != "" {
+			r.Header.Set("Authorization", authHeader)
+		}
+		_, err := authenticator.AuthenticateRequest(r)
+		return err
+	}
+
+	// Requests without credentials (auth challenges) are not failures
+	for i := 0; i < 3; i++ {
+		if err := authenticate(""); errors.Is(err, ErrLockedOut) {
+			t.Fatalf("request %d without credentials locked out", i+1)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := authenticate("Bearer invalid_token"); errors.Is(err, ErrLockedOut) {
+			t.Fatalf("failure %d locked out", i+1)
+		}
+	}
+	if err := authenticate("Bearer invalid_token"); !errors.Is(err, ErrLockedOut) {
+		t.Errorf("AuthenticateRequest() after max failures error = %v, want ErrLockedOut", err)
+	}
+}
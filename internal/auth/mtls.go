@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+// TokenTypeClientCertificate is the token type of requests authenticated with a
+// TLS client certificate
+const TokenTypeClientCertificate = "client_certificate"
+
+// CertificateAuthenticator maps verified TLS client certificates to identities:
+// the username from a subject alternative name or the common name, and the teams
+// from the organizational units. Certificates are verified by the TLS handshake
+// against the CAs of ServerTLSConfig.
+type CertificateAuthenticator struct {
+	clientCAs      *x509.CertPool
+	required       bool
+	usernameFrom   string
+	usernamePrefix string
+	allowedOUs     []string
+}
+
+// NewCertificateAuthenticator creates a client certificate authenticator trusting
+// the CAs of cfg.CAFile
+func NewCertificateAuthenticator(cfg *config.MTLSConfig) (*CertificateAuthenticator, error) {
+	pem, err := os.ReadFile(cfg.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", cfg.CAFile)
+	}
+
+	return &CertificateAuthenticator{
+		clientCAs:      pool,
+		required:       cfg.Required,
+		usernameFrom:   cfg.UsernameFrom,
+		usernamePrefix: cfg.UsernamePrefix,
+		allowedOUs:     cfg.AllowedOUs,
+	}, nil
+}
+
+// ServerTLSConfig returns the server TLS configuration asking clients for a
+// certificate of the trusted CAs, and requiring one if configured
+func (c *CertificateAuthenticator) ServerTLSConfig() *tls.Config {
+	clientAuth := tls.VerifyClientCertIfGiven
+	if c.required {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientCAs:  c.clientCAs,
+		ClientAuth: clientAuth,
+	}
+}
+
+// SetClientCertificates enables authenticating requests presenting a verified TLS
+// client certificate and no Authorization header.
+//
+// Must be called before the authenticator is used concurrently.
+func (a *ClientAuthenticator) SetClientCertificates(certificates *CertificateAuthenticator) {
+	a.certificates = certificates
+}
+
+// clientCertificate returns the verified client certificate of r, if it presents
+// one and no other credentials
+func clientCertificate(r *http.Request) (*x509.Certificate, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	if r.Header.Get("Authorization") != "" {
+		return nil, false
+	}
+	return r.TLS.VerifiedChains[0][0], true
+}
+
+// authenticate returns the identity of a verified client certificate. The OUs are
+// the teams routing scopes match. They are chosen by whoever requests the
+// certificate, so a certificate is a member of requiredOrg only by an OU of the
+// allow-list, and never of an org it names itself.
+func (c *CertificateAuthenticator) authenticate(cert *x509.Certificate, requiredOrg string) (*AuthResult, error) {
+	username := c.username(cert)
+	if username == "" {
+		return nil, fmt.Errorf("client certificate has no usable subject name")
+	}
+
+	ous := cert.Subject.OrganizationalUnit
+	if len(c.allowedOUs) > 0 {
+		ous = slices.DeleteFunc(slices.Clone(ous), func(ou string) bool {
+			return !slices.Contains(c.allowedOUs, ou)
+		})
+		if len(ous) == 0 {
+			return nil, fmt.Errorf("authentication failed: client certificate is not of an allowed organizational unit")
+		}
+	} else if requiredOrg != "" {
+		// Rejected by config validation; checked again so a certificate can't
+		// stand in for org membership
+		return nil, fmt.Errorf("authentication failed: client certificates require allowed_ous to be members of %s", requiredOrg)
+	}
+
+	return &AuthResult{
+		Username:  c.usernamePrefix + username,
+		Org:       requiredOrg,
+		Teams:     ous,
+		TokenType: TokenTypeClientCertificate,
+	}, nil
+}
+
+// username returns the username of cert according to the configured source
+func (c *CertificateAuthenticator) username(cert *x509.Certificate) string {
+	if c.usernameFrom == config.MTLSUsernameFromSAN {
+		switch {
+		case len(cert.EmailAddresses) > 0:
+			return cert.EmailAddresses[0]
+		case len(cert.DNSNames) > 0:
+			return cert.DNSNames[0]
+		case len(cert.URIs) > 0:
+			return cert.URIs[0].String()
+		}
+	}
+	return cert.Subject.CommonName
+}
+
+// authenticateCertificate returns the identity of a request presenting a verified
+// client certificate
+func (a *ClientAuthenticator) authenticateCertificate(cert *x509.Certificate) (*AuthResult, error) {
+	result, err := a.certificates.authenticate(cert, a.requiredOrg)
+	if err != nil {
+		a.logger.Warn().Err(err).
+			Str("subject", cert.Subject.String()).
+			Msg("Client certificate rejected")
+		return nil, err
+	}
+
+	a.logger.Debug().
+		Str("username", result.Username).
+		Str("org", result.Org).
+		Str("serial", cert.SerialNumber.String()).
+		Msg("Client authenticated with certificate")
+	return result, nil
+}
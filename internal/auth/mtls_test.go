@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// testCA issues client certificates for tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a client certificate of the CA for template's subject and names
+func (ca *testCA) issue(t *testing.T, template *x509.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func newTestCertificateAuthenticator(t *testing.T, ca *testCA, cfg config.MTLSConfig) *CertificateAuthenticator {
+	t.Helper()
+	cfg.CAFile = filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(cfg.CAFile, ca.pem, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	certificates, err := NewCertificateAuthenticator(&cfg)
+	if err != nil {
+		t.Fatalf("NewCertificateAuthenticator() error = %v", err)
+	}
+	return certificates
+}
+
+func TestClientAuthenticator_ClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	ciRunner := ca.issue(t, &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "ci-runner-1", OrganizationalUnit: []string{"build", "platform"}},
+		EmailAddresses: []string{"ci@example.com"},
+	})
+	contractor := ca.issue(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "bob", OrganizationalUnit: []string{"contractors"}},
+	})
+
+	// A certificate naming the required org as its OU is no member of it
+	impostor := ca.issue(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "mallory", OrganizationalUnit: []string{"myorg"}},
+	})
+
+	tests := []struct {
+		name          string
+		cfg           config.MTLSConfig
+		requiredOrg   string
+		cert          tls.Certificate
+		authorization string
+		wantUser      string
+		wantOrg       string
+		wantTeam      string
+		wantErr       string
+	}{
+		{name: "san", cfg: config.MTLSConfig{UsernameFrom: config.MTLSUsernameFromSAN}, cert: ciRunner, wantUser: "ci@example.com", wantTeam: "build"},
+		{name: "cn with prefix", cfg: config.MTLSConfig{UsernameFrom: config.MTLSUsernameFromCN, UsernamePrefix: "mtls:"}, cert: ciRunner, wantUser: "mtls:ci-runner-1", wantTeam: "build"},
+		{name: "san falls back to cn", cfg: config.MTLSConfig{UsernameFrom: config.MTLSUsernameFromSAN}, cert: contractor, wantUser: "bob", wantTeam: "contractors"},
+		{name: "allowed ou", cfg: config.MTLSConfig{UsernameFrom: config.MTLSUsernameFromSAN, AllowedOUs: []string{"platform"}}, cert: ciRunner, wantUser: "ci@example.com", wantTeam: "platform"},
+		{name: "other ou", cfg: config.MTLSConfig{UsernameFrom: config.MTLSUsernameFromSAN, AllowedOUs: []string{"platform"}}, cert: contractor, wantErr: "not of an allowed organizational unit"},
+		{name: "required org by allowed ou", cfg: config.MTLSConfig{UsernameFrom: config.MTLSUsernameFromSAN, AllowedOUs: []string{"platform"}}, requiredOrg: "myorg", cert: ciRunner, wantUser: "ci@example.com", wantOrg: "myorg", wantTeam: "platform"},
+		{name: "required org named by ou", cfg: config.MTLSConfig{UsernameFrom: config.MTLSUsernameFromSAN, AllowedOUs: []string{"platform"}}, requiredOrg: "myorg", cert: impostor, wantErr: "not of an allowed organizational unit"},
+		{name: "required org without allowed ous", cfg: config.MTLSConfig{UsernameFrom: config.MTLSUsernameFromSAN}, requiredOrg: "myorg", cert: impostor, wantErr: "require allowed_ous"},
+		{name: "authorization takes precedence", cfg: config.MTLSConfig{UsernameFrom: config.MTLSUsernameFromSAN}, cert: ciRunner, authorization: "Bearer invalid", wantErr: "invalid token format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewClientAuthenticator(nil, tt.requiredOrg, nil, zerolog.Nop())
+			a.SetClientCertificates(newTestCertificateAuthenticator(t, ca, tt.cfg))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tt.cert.Leaf, ca.cert}}}
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+
+			result, err := a.AuthenticateRequest(r)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("AuthenticateRequest() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AuthenticateRequest() error = %v", err)
+			}
+			if result.Username != tt.wantUser || result.Org != tt.wantOrg || result.Teams[0] != tt.wantTeam || result.TokenType != TokenTypeClientCertificate {
+				t.Errorf("AuthenticateRequest() = %+v, want %s of %q in %s", result, tt.wantUser, tt.wantOrg, tt.wantTeam)
+			}
+		})
+	}
+}
+
+func TestCertificateAuthenticator_ServerTLSConfig(t *testing.T) {
+	ca := newTestCA(t)
+	otherCA := newTestCA(t)
+	certificates := newTestCertificateAuthenticator(t, ca, config.MTLSConfig{UsernameFrom: config.MTLSUsernameFromSAN})
+
+	a := NewClientAuthenticator(nil, "", nil, zerolog.Nop())
+	a.SetClientCertificates(certificates)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result, err := a.AuthenticateRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(result.Username))
+	}))
+	server.TLS = certificates.ServerTLSConfig()
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // Rejected handshakes
+	server.StartTLS()
+	defer server.Close()
+
+	get := func(cert *tls.Certificate) int {
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		if cert != nil {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+		}
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err != nil {
+			return 0
+		}
+		defer func() { _ = resp.Body.Close() }()
+		return resp.StatusCode
+	}
+
+	trusted := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "ci-runner-1"}})
+	untrusted := otherCA.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "mallory"}})
+	if status := get(&trusted); status != http.StatusOK {
+		t.Errorf("trusted certificate: status = %d, want %d", status, http.StatusOK)
+	}
+	if status := get(nil); status != http.StatusUnauthorized {
+		t.Errorf("no certificate: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status := get(&untrusted); status == http.StatusOK {
+		t.Error("certificate of another CA was accepted")
+	}
+}
@@ -0,0 +1,504 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// TokenTypeOIDC is the token type of clients authenticated with an OIDC provider's JWT
+const TokenTypeOIDC = "oidc"
+
+// ErrOIDCUnavailable indicates the signing keys of an OIDC provider could not be
+// fetched, as opposed to the provider's token being invalid
+var ErrOIDCUnavailable = errors.New("oidc provider unavailable")
+
+const (
+	// oidcFetchTimeout bounds discovery and JWKS requests
+	oidcFetchTimeout = 10 * time.Second
+
+	// oidcMinRefreshInterval limits JWKS refreshes triggered by tokens signed with
+	// unknown keys, so forged key IDs cannot hammer the provider
+	oidcMinRefreshInterval = 30 * time.Second
+
+	// oidcMaxDocumentSize bounds discovery and JWKS documents
+	oidcMaxDocumentSize = 1 << 20
+)
+
+// OIDCAuthenticator validates JWTs issued by an OpenID Connect provider: the
+// signature against the provider's published keys (JWKS), the issuer, audience,
+// expiry and the configured required claims. Validation is local once the keys are
+// cached, so results are not cached.
+type OIDCAuthenticator struct {
+	config     *config.OIDCProviderConfig
+	httpClient *http.Client
+	logger     zerolog.Logger
+	now        func() time.Time
+
+	mu          sync.Mutex
+	jwksURL     string                      // Discovered or configured
+	keys        map[string]crypto.PublicKey // By key ID
+	fetchedAt   time.Time                   // Last successful JWKS fetch
+	attemptedAt time.Time                   // Last JWKS fetch attempt
+}
+
+// NewOIDCAuthenticator creates an identity provider accepting the JWTs of the OIDC
+// provider cfg. Discovery happens on first use, so an unreachable provider does
+// not prevent startup.
+func NewOIDCAuthenticator(cfg *config.OIDCProviderConfig, logger zerolog.Logger) *OIDCAuthenticator {
+	return &OIDCAuthenticator{
+		config:     cfg,
+		httpClient: &http.Client{Timeout: oidcFetchTimeout},
+		logger:     logger.With().Str("oidc_provider", cfg.Name).Logger(),
+		now:        time.Now,
+		jwksURL:    cfg.JWKSURL,
+	}
+}
+
+// Name returns the configured provider name
+func (o *OIDCAuthenticator) Name() string {
+	return o.config.Name
+}
+
+// Accepts reports whether token is a JWT naming this provider's issuer.
+// The claims are not trusted until Authenticate verifies the signature.
+func (o *OIDCAuthenticator) Accepts(token string) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return false
+	}
+	return sameIssuer(claims.Issuer, o.config.Issuer)
+}
+
+// jwtHeader is the JOSE header of a JWT
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// Authenticate verifies token's signature and claims, returning the user named by
+// the username claim, with the groups claim as teams
+func (o *OIDCAuthenticator) Authenticate(r *http.Request, token string) (*AuthResult, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature")
+	}
+
+	key, err := o.signingKey(r.Context(), header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Algorithm, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token claims")
+	}
+	claims := make(map[string]any)
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims")
+	}
+	if err := o.verifyClaims(claims); err != nil {
+		return nil, err
+	}
+
+	username, _ := claims[o.config.UsernameClaim].(string)
+	if username == "" {
+		return nil, fmt.Errorf("token has no %s claim", o.config.UsernameClaim)
+	}
+
+	return &AuthResult{
+		Username:  o.config.UsernamePrefix + username,
+		Teams:     claimStrings(claims[o.config.GroupsClaim]),
+		TokenType: TokenTypeOIDC,
+	}, nil
+}
+
+// verifyClaims checks the registered claims and the configured required claims
+func (o *OIDCAuthenticator) verifyClaims(claims map[string]any) error {
+	if iss, _ := claims["iss"].(string); !sameIssuer(iss, o.config.Issuer) {
+		return fmt.Errorf("token issuer mismatch")
+	}
+
+	audiences := claimStrings(claims["aud"])
+	if !slices.ContainsFunc(o.config.Audiences, func(aud string) bool { return slices.Contains(audiences, aud) }) {
+		return fmt.Errorf("token audience not accepted")
+	}
+
+	now := o.now()
+	exp, ok := numericDate(claims["exp"])
+	if !ok {
+		return fmt.Errorf("token has no expiry")
+	}
+	if now.After(exp.Add(o.config.ClockSkew)) {
+		return fmt.Errorf("token expired")
+	}
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Add(o.config.ClockSkew).Before(nbf) {
+		return fmt.Errorf("token not yet valid")
+	}
+
+	for _, req := range o.config.RequiredClaims {
+		if !slices.Contains(claimStrings(claims[req.Claim]), req.Value) {
+			return fmt.Errorf("token claim %s does not match", req.Claim)
+		}
+	}
+	return nil
+}
+
+// signingKey returns the provider key with kid, fetching the JWKS when the cache
+// has expired or does not know kid. A token without a key ID is accepted when the
+// provider publishes a single key.
+func (o *OIDCAuthenticator) signingKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := o.now()
+	stale := o.keys == nil || now.Sub(o.fetchedAt) >= o.config.JWKSCacheTTL
+	_, known := o.lookupKey(kid)
+	if (stale || !known) && now.Sub(o.attemptedAt) >= oidcMinRefreshInterval {
+		o.attemptedAt = now
+		if err := o.refreshKeys(ctx); err != nil {
+			o.logger.Warn().Err(err).Msg("Failed to refresh OIDC signing keys")
+			// Stale keys keep working until a refresh succeeds
+			if o.keys == nil {
+				return nil, fmt.Errorf("fetching signing keys: %w", err)
+			}
+		}
+	}
+
+	if o.keys == nil {
+		return nil, fmt.Errorf("no signing keys fetched yet: %w", ErrOIDCUnavailable)
+	}
+	key, ok := o.lookupKey(kid)
+	if !ok {
+		return nil, fmt.Errorf("token signed with unknown key")
+	}
+	return key, nil
+}
+
+// lookupKey returns the cached key with kid. Must be called with mu held.
+func (o *OIDCAuthenticator) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(o.keys) == 1 {
+		for _, key := range o.keys {
+			return key, true
+		}
+	}
+	key, ok := o.keys[kid]
+	return key, ok
+}
+
+// refreshKeys discovers the JWKS URL if needed and replaces the cached keys.
+// Must be called with mu held.
+func (o *OIDCAuthenticator) refreshKeys(ctx context.Context) error {
+	if o.jwksURL == "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		discoveryURL := strings.TrimSuffix(o.config.Issuer, "/") + "/.well-known/openid-configuration"
+		if err := o.fetchJSON(ctx, discoveryURL, &discovery); err != nil {
+			return err
+		}
+		// The discovery document must be the issuer's own (OpenID Connect Discovery 4.3)
+		if !sameIssuer(discovery.Issuer, o.config.Issuer) {
+			return fmt.Errorf("discovery document issuer %q does not match %q", discovery.Issuer, o.config.Issuer)
+		}
+		if discovery.JWKSURI == "" {
+			return fmt.Errorf("discovery document has no jwks_uri")
+		}
+		o.jwksURL = discovery.JWKSURI
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := o.fetchJSON(ctx, o.jwksURL, &jwks); err != nil {
+		return err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Keys of unsupported types are skipped, as providers publish several
+			o.logger.Debug().Err(err).Str("kid", jwk.KeyID).Msg("Skipping OIDC signing key")
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("JWKS has no usable signing keys")
+	}
+
+	o.keys = keys
+	o.fetchedAt = o.now()
+	o.logger.Debug().Int("keys", len(keys)).Msg("Refreshed OIDC signing keys")
+	return nil
+}
+
+// fetchJSON GETs url and decodes the JSON response into v. Failures wrap
+// ErrOIDCUnavailable, as they are not the client's fault.
+func (o *OIDCAuthenticator) fetchJSON(ctx context.Context, url string, v any) error {
+	// Detached from the client request so a canceled request does not fail a
+	// refresh other requests are waiting on
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), oidcFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOIDCUnavailable, err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOIDCUnavailable, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: GET %s returned %d", ErrOIDCUnavailable, url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxDocumentSize)).Decode(v); err != nil {
+		return fmt.Errorf("%w: invalid JSON from %s: %v", ErrOIDCUnavailable, url, err)
+	}
+	return nil
+}
+
+// jsonWebKey is a public key of a JWKS (RFC 7517)
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC
+	Curve string `json:"crv"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+}
+
+// publicKey returns the RSA or ECDSA public key of jwk
+func (jwk *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		var ecdhCurve ecdh.Curve
+		switch jwk.Curve {
+		case "P-256":
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Curve)
+		}
+		x, errX := decodeBigInt(jwk.X)
+		y, errY := decodeBigInt(jwk.Y)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("invalid EC point")
+		}
+		// Parsing the uncompressed point checks it is on the curve
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x.Bytes()) > size || len(y.Bytes()) > size {
+			return nil, fmt.Errorf("invalid EC point")
+		}
+		point := make([]byte, 1+2*size)
+		point[0] = 4
+		x.FillBytes(point[1 : 1+size])
+		y.FillBytes(point[1+size:])
+		if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+			return nil, fmt.Errorf("EC point not on curve %s", jwk.Curve)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+	}
+}
+
+// verifySignature verifies a JWS signature over signingInput with key.
+// Only asymmetric algorithms are accepted: "none" and HMAC would let anyone who
+// knows the public key (or nothing at all) mint tokens.
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "PS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "PS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	digest := hashSum(hash, signingInput)
+
+	switch alg[:2] {
+	case "RS", "PS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("signing key does not match algorithm %s", alg)
+		}
+		if alg[0] == 'R' {
+			err := rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature)
+			if err != nil {
+				return fmt.Errorf("invalid token signature")
+			}
+			return nil
+		}
+		if err := rsa.VerifyPSS(rsaKey, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}); err != nil {
+			return fmt.Errorf("invalid token signature")
+		}
+		return nil
+
+	default:
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("signing key does not match algorithm %s", alg)
+		}
+		// JWS ECDSA signatures are R || S, each the size of the curve order (RFC 7518 3.4)
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return fmt.Errorf("invalid token signature")
+		}
+		return nil
+	}
+}
+
+// hashSum returns the digest of data with hash (SHA-256, SHA-384 or SHA-512)
+func hashSum(hash crypto.Hash, data string) []byte {
+	switch hash {
+	case crypto.SHA384:
+		sum := sha512.Sum384([]byte(data))
+		return sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512([]byte(data))
+		return sum[:]
+	default:
+		sum := sha256.Sum256([]byte(data))
+		return sum[:]
+	}
+}
+
+// decodeSegment decodes a base64url JWT segment as JSON into v
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// decodeBigInt decodes a base64url unsigned big-endian integer of a JWK
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// numericDate returns the time of a JWT NumericDate claim (seconds since the epoch)
+func numericDate(claim any) (time.Time, bool) {
+	number, ok := claim.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+// claimStrings returns a claim holding a string, or an array of strings, as a
+// slice. Booleans and numbers are formatted, so required claims can match them.
+func claimStrings(claim any) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case bool, json.Number:
+		return []string{fmt.Sprint(v)}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// sameIssuer compares issuer URLs, ignoring a trailing slash
+func sameIssuer(a, b string) bool {
+	return a != "" && strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// permissionProbePackageType is the package type the packages permission is probed
+// with. A fine-grained PAT's packages permission covers every package type.
+const permissionProbePackageType = "container"
+
+// packagesWriteScope is the token scope that allows publishing packages
+const packagesWriteScope = "write:packages"
+
+// ErrInsufficientPermissions is returned for requests whose credentials are valid but
+// don't allow the requested operation, e.g. a push with a read-only fine-grained PAT.
+// Handlers refuse such requests with 403 instead of challenging for credentials again.
+var ErrInsufficientPermissions = errors.New("insufficient token permissions")
+
+// InsufficientPermissionsMessage is the message of the 403 responses to requests
+// rejected with ErrInsufficientPermissions
+const InsufficientPermissionsMessage = "Forbidden: the token's packages permission does not allow this operation"
+
+// TokenPermissions describes what a fine-grained PAT is allowed to do.
+//
+// Fine-grained PATs are granted explicit permissions, unlike classic PATs whose access
+// follows the user's org/team membership.
+type TokenPermissions struct {
+	Read  bool // Token has the packages read permission
+	Write bool // Token has the packages write permission
+}
+
+// Allows reports whether the permissions cover the requested operation.
+func (p *TokenPermissions) Allows(write bool) bool {
+	if write {
+		return p.Write
+	}
+	return p.Read
+}
+
+// IsWriteMethod reports whether the HTTP method modifies artifacts.
+// Used to pick the permission required for a request (read vs write).
+func IsWriteMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// introspectPermissions determines the packages permissions granted to a fine-grained PAT.
+//
+// GitHub has no endpoint that returns a fine-grained token's grants, so they are read
+// from a request that never changes anything: listing the packages of requiredOrg when
+// set, otherwise the user's own packages.
+//   - read: the listing succeeds only with the packages read permission
+//   - write: granted only when the listing's X-OAuth-Scopes header names write:packages.
+//     Nothing else reveals the grant without a mutating request, so write is unknown,
+//     and denied, for tokens without it.
+func (c *GitHubClient) introspectPermissions(ctx context.Context, client *github.Client, requiredOrg string) (*TokenPermissions, error) {
+	listOpts := &github.PackageListOptions{
+		PackageType: github.String(permissionProbePackageType),
+		ListOptions: github.ListOptions{PerPage: 1},
+	}
+	var resp *github.Response
+	var err error
+	if requiredOrg != "" {
+		_, resp, err = client.Organizations.ListPackages(ctx, requiredOrg, listOpts)
+	} else {
+		_, resp, err = client.Users.ListPackages(ctx, "", listOpts)
+	}
+	switch status := errorStatus(err); {
+	case err == nil:
+	case status == http.StatusForbidden || status == http.StatusNotFound:
+		// Without packages read the token can't push packages either
+		return &TokenPermissions{}, nil
+	default:
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+
+	return &TokenPermissions{Read: true, Write: hasScope(resp, packagesWriteScope)}, nil
+}
+
+// hasScope reports whether the X-OAuth-Scopes header of resp names scope
+func hasScope(resp *github.Response, scope string) bool {
+	if resp == nil {
+		return false
+	}
+	for _, s := range strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",") {
+		if strings.TrimSpace(s) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// errorStatus returns the HTTP status of a GitHub API error response, or 0 for other
+// errors. Rate limit errors are not error responses, so they never read as a refusal.
+func errorStatus(err error) int {
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) && respErr.Response != nil {
+		return respErr.Response.StatusCode
+	}
+	return 0
+}
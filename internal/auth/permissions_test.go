@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/rs/zerolog"
+)
+
+func TestIntrospectPermissions(t *testing.T) {
+	tests := []struct {
+		name       string
+		org        string
+		listStatus int
+		scopes     string
+		wantRead   bool
+		wantWrite  bool
+		wantErr    bool
+	}{
+		{"read and write scope", "", http.StatusOK, "read:org, write:packages", true, true, false},
+		{"read only", "", http.StatusOK, "", true, false, false},
+		{"read scope only", "", http.StatusOK, "read:packages", true, false, false},
+		{"no packages access", "", http.StatusForbidden, "", false, false, false},
+		{"org packages", "myorg", http.StatusOK, "write:packages", true, true, false},
+		{"org not visible", "myorg", http.StatusNotFound, "write:packages", false, false, false},
+		{"GitHub error", "", http.StatusBadGateway, "", false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix := "/user/packages"
+			if tt.org != "" {
+				prefix = "/orgs/" + tt.org + "/packages"
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.Path != prefix {
+					// Introspection must never change anything on GitHub
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				if got := r.URL.Query().Get("package_type"); got != permissionProbePackageType {
+					t.Errorf("package_type = %q, want %q", got, permissionProbePackageType)
+				}
+				if tt.scopes != "" {
+					w.Header().Set("X-OAuth-Scopes", tt.scopes)
+				}
+				w.WriteHeader(tt.listStatus)
+				fmt.Fprint(w, "[]")
+			}))
+			defer srv.Close()
+
+			client := github.NewClient(nil)
+			client.BaseURL, _ = url.Parse(srv.URL + "/")
+
+			c := &GitHubClient{}
+			got, err := c.introspectPermissions(context.Background(), client, tt.org)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("introspectPermissions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Read != tt.wantRead || got.Write != tt.wantWrite {
+				t.Errorf("introspectPermissions() = {Read: %v, Write: %v}, want {Read: %v, Write: %v}",
+					got.Read, got.Write, tt.wantRead, tt.wantWrite)
+			}
+		})
+	}
+}
+
+func TestIntrospectPermissions_RateLimited(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A rate limited request is not a missing packages permission
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", "4102444800")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+
+	c := &GitHubClient{}
+	if _, err := c.introspectPermissions(context.Background(), client, ""); !isGitHubUnavailable(err) {
+		t.Errorf("introspectPermissions() error = %v, want GitHub unavailable", err)
+	}
+}
+
+// permissionsProvider authenticates any token with fixed permissions
+type permissionsProvider struct {
+	permissions *TokenPermissions
+}
+
+func (p *permissionsProvider) Name() string          { return "permissions" }
+func (p *permissionsProvider) Accepts(_ string) bool { return true }
+func (p *permissionsProvider) Authenticate(_ *http.Request, _ string) (*AuthResult, error) {
+	return &AuthResult{Username: "alice", TokenType: TokenTypePAT, Permissions: p.permissions}, nil
+}
+
+func TestAuthenticateRequest_InsufficientPermissions(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions *TokenPermissions
+		method      string
+		wantDenied  bool
+	}{
+		{"read-only token pulls", &TokenPermissions{Read: true}, http.MethodGet, false},
+		{"read-only token pushes", &TokenPermissions{Read: true}, http.MethodPut, true},
+		{"token without packages access pulls", &TokenPermissions{}, http.MethodGet, true},
+		{"read and write token pushes", &TokenPermissions{Read: true, Write: true}, http.MethodPut, false},
+		{"unrestricted token pushes", nil, http.MethodPut, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewClientAuthenticator(nil, "", nil, zerolog.Nop())
+			a.RegisterProvider(&permissionsProvider{permissions: tt.permissions})
+			if err := a.SetProviders([]string{"permissions"}); err != nil {
+				t.Fatalf("SetProviders() error = %v", err)
+			}
+
+			req := httptest.NewRequest(tt.method, "/v2/team/app/manifests/v1", nil)
+			req.Header.Set("Authorization", "Bearer token")
+			_, err := a.AuthenticateRequest(req)
+			if got := errors.Is(err, ErrInsufficientPermissions); got != tt.wantDenied {
+				t.Errorf("AuthenticateRequest() error = %v, want insufficient permissions %v", err, tt.wantDenied)
+			}
+			if !tt.wantDenied && err != nil {
+				t.Errorf("AuthenticateRequest() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestTokenPermissions_Allows(t *testing.T) {
+	tests := []struct {
+		name   string
+		perms  TokenPermissions
+		method string
+		want   bool
+	}{
+		{"read allowed for GET", TokenPermissions{Read: true}, http.MethodGet, true},
+		{"read allowed for HEAD", TokenPermissions{Read: true}, http.MethodHead, true},
+		{"write denied for read-only PUT", TokenPermissions{Read: true}, http.MethodPut, false},
+		{"write allowed for PUT", TokenPermissions{Read: true, Write: true}, http.MethodPut, true},
+		{"write denied for read-only POST", TokenPermissions{Read: true}, http.MethodPost, false},
+		{"read denied without permissions", TokenPermissions{}, http.MethodGet, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.perms.Allows(IsWriteMethod(tt.method)); got != tt.want {
+				t.Errorf("Allows(%s) = %v, want %v", tt.method, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsFineGrainedPAT(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{"fine-grained PAT", "github_pat_" + strings.Repeat("a", 22) + "_" + strings.Repeat("b", 59), true},
+		{"classic PAT", "ghp_" + strings.Repeat("a", 36), false},
+		{"OAuth token", "gho_" + strings.Repeat("a", 36), false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsFineGrainedPAT(tt.token); got != tt.want {
+				t.Errorf("IsFineGrainedPAT() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// GitHubProviderName is the name of the built-in GitHub identity provider
+const GitHubProviderName = "github"
+
+// Authenticator validates client tokens issued by one identity provider.
+// ClientAuthenticator offers each token a client sends to its providers in order;
+// the first provider that accepts the token's format validates it.
+type Authenticator interface {
+	// Name returns the provider name referenced in configuration
+	Name() string
+
+	// Accepts reports whether token has this provider's format, without validating it.
+	// It must be cheap, as it runs for every candidate token of every request.
+	Accepts(token string) bool
+
+	// Authenticate validates token for request r. Errors must wrap the provider
+	// being unavailable (e.g. ErrGitHubUnavailable) distinctly from the token being
+	// rejected, so failures of the provider are not counted against the client.
+	Authenticate(r *http.Request, token string) (*AuthResult, error)
+}
+
+// GitHubAuthenticator validates GitHub personal access, OAuth and Actions tokens
+// against the GitHub API, requiring membership of an organization and teams
+type GitHubAuthenticator struct {
+	client        *GitHubClient
+	requiredOrg   string
+	requiredTeams []string
+}
+
+// NewGitHubAuthenticator creates the GitHub identity provider
+func NewGitHubAuthenticator(client *GitHubClient, requiredOrg string, requiredTeams []string) *GitHubAuthenticator {
+	return &GitHubAuthenticator{
+		client:        client,
+		requiredOrg:   requiredOrg,
+		requiredTeams: requiredTeams,
+	}
+}
+
+// Name returns "github"
+func (g *GitHubAuthenticator) Name() string {
+	return GitHubProviderName
+}
+
+// Accepts reports whether token has the format of a GitHub token.
+// This preemptive check keeps random tokens from exhausting the GitHub rate limit.
+func (g *GitHubAuthenticator) Accepts(token string) bool {
+	_, err := ValidateTokenFormat(token)
+	return err == nil
+}
+
+// Authenticate validates token with the GitHub API (with caching), charging the
+// call to the client's validation budget
+func (g *GitHubAuthenticator) Authenticate(r *http.Request, token string) (*AuthResult, error) {
+	return g.client.ValidateForClient(r.Context(), budgetKey(r), token, g.requiredOrg, g.requiredTeams)
+}
+
+// RegisterProvider adds an identity provider that protocols can select by name.
+// Providers are only tried when selected with SetProviders or WithProviders.
+//
+// Must be called before the authenticator is used concurrently.
+func (a *ClientAuthenticator) RegisterProvider(provider Authenticator) {
+	if a.registered == nil {
+		a.registered = make(map[string]Authenticator)
+	}
+	a.registered[provider.Name()] = provider
+}
+
+// SetProviders selects the identity providers tokens are offered to, in order.
+// An empty list selects GitHub only.
+//
+// Must be called before the authenticator is used concurrently.
+func (a *ClientAuthenticator) SetProviders(names []string) error {
+	providers, err := a.lookupProviders(names)
+	if err != nil {
+		return err
+	}
+	a.providers = providers
+	return nil
+}
+
+// WithProviders returns an authenticator sharing a's configuration and state
+// (admins, lockout, credential sharing, signed URLs) that offers tokens to the named
+// identity providers instead, for protocols selecting their own providers.
+// An empty list returns a itself.
+func (a *ClientAuthenticator) WithProviders(names []string) (*ClientAuthenticator, error) {
+	if len(names) == 0 {
+		return a, nil
+	}
+	providers, err := a.lookupProviders(names)
+	if err != nil {
+		return nil, err
+	}
+	selected := *a
+	selected.providers = providers
+	return &selected, nil
+}
+
+// ForProtocol returns an authenticator sharing a's configuration and state for the
+// handler of protocol, which rejects credentials restricted to other protocols
+func (a *ClientAuthenticator) ForProtocol(protocol string) *ClientAuthenticator {
+	forProtocol := *a
+	forProtocol.protocol = protocol
+	return &forProtocol
+}
+
+// lookupProviders resolves provider names to the built-in GitHub provider or a
+// registered provider
+func (a *ClientAuthenticator) lookupProviders(names []string) ([]Authenticator, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	providers := make([]Authenticator, 0, len(names))
+	for _, name := range names {
+		if name == GitHubProviderName {
+			providers = append(providers, a.githubProvider())
+			continue
+		}
+		provider, ok := a.registered[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown identity provider %q", name)
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// activeProviders returns the providers tokens are offered to, GitHub by default
+func (a *ClientAuthenticator) activeProviders() []Authenticator {
+	if len(a.providers) == 0 {
+		return []Authenticator{a.githubProvider()}
+	}
+	return a.providers
+}
+
+// githubProvider returns the GitHub provider of the authenticator's GitHub client
+func (a *ClientAuthenticator) githubProvider() Authenticator {
+	return NewGitHubAuthenticator(a.githubClient, a.requiredOrg, a.requiredTeams)
+}
+
+// selectProvider returns the first candidate token accepted by a provider, and
+// that provider. Candidates are tried in order, each against every provider, so a
+// Basic auth password takes precedence over the username.
+func selectProvider(providers []Authenticator, candidates []string) (string, Authenticator, bool) {
+	for _, token := range candidates {
+		if token == "" {
+			continue
+		}
+		for _, provider := range providers {
+			if provider.Accepts(token) {
+				return token, provider, true
+			}
+		}
+	}
+	return "", nil, false
+}
+
+// providerDisplayNames describes providers in error messages, e.g. "GitHub or okta"
+func providerDisplayNames(providers []Authenticator) string {
+	names := make([]string, len(providers))
+	for i, provider := range providers {
+		names[i] = provider.Name()
+		if names[i] == GitHubProviderName {
+			names[i] = "GitHub"
+		}
+	}
+	return strings.Join(names, " or ")
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func keyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestServiceAccountAuthenticator(t *testing.T) {
+	jenkinsKey := ServiceAccountKeyPrefix + strings.Repeat("j", 40)
+	buildkiteKey := ServiceAccountKeyPrefix + strings.Repeat("b", 40)
+
+	// Buildkite's key comes from the secrets file
+	file := filepath.Join(t.TempDir(), "service-accounts.yaml")
+	secrets := "accounts:\n  - name: buildkite\n    key_hash: " + keyHash(buildkiteKey) + "\n    orgs: [myorg]\n    teams: [platform]\n"
+	if err := os.WriteFile(file, []byte(secrets), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	serviceAccounts, err := NewServiceAccountAuthenticator(&config.ServiceAccountsConfig{
+		File: file,
+		Accounts: []config.ServiceAccountConfig{
+			{Name: "jenkins", KeyHash: keyHash(jenkinsKey), Protocols: []string{"maven"}, Orgs: []string{"myorg"}},
+		},
+	}, "myorg")
+	if err != nil {
+		t.Fatalf("NewServiceAccountAuthenticator() error = %v", err)
+	}
+
+	a := NewClientAuthenticator(nil, "myorg", nil, zerolog.Nop())
+	a.RegisterProvider(serviceAccounts)
+	if err := a.SetProviders([]string{ServiceAccountProviderName}); err != nil {
+		t.Fatalf("SetProviders() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		protocol  string
+		key       string
+		basicAuth bool
+		wantUser  string
+		wantErr   string
+	}{
+		{name: "bearer", protocol: "maven", key: jenkinsKey, wantUser: "jenkins"},
+		{name: "basic password", protocol: "maven", key: jenkinsKey, basicAuth: true, wantUser: "jenkins"},
+		{name: "from secrets file", protocol: "oci", key: buildkiteKey, wantUser: "buildkite"},
+		{name: "unrestricted on api", key: buildkiteKey, wantUser: "buildkite"},
+		{name: "other protocol", protocol: "oci", key: jenkinsKey, wantErr: "not allowed for this protocol"},
+		{name: "restricted on api", key: jenkinsKey, wantErr: "not allowed for this protocol"},
+		{name: "unknown key", protocol: "maven", key: ServiceAccountKeyPrefix + strings.Repeat("x", 40), wantErr: "unknown API key"},
+		{name: "short key", protocol: "maven", key: ServiceAccountKeyPrefix + "x", wantErr: "invalid token format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.basicAuth {
+				r.SetBasicAuth("ci", tt.key)
+			} else {
+				r.Header.Set("Authorization", "Bearer "+tt.key)
+			}
+
+			result, err := a.ForProtocol(tt.protocol).AuthenticateRequest(r)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("AuthenticateRequest() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AuthenticateRequest() error = %v", err)
+			}
+			if result.Username != tt.wantUser || result.Org != "myorg" || result.TokenType != TokenTypeServiceAccount {
+				t.Errorf("AuthenticateRequest() = %+v, want service account %s of myorg", result, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestNewServiceAccountAuthenticator_InvalidFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "service-accounts.yaml")
+	if err := os.WriteFile(file, []byte("accounts:\n  - name: jenkins\n    key_hash: afk_plaintext\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewServiceAccountAuthenticator(&config.ServiceAccountsConfig{File: file}, "")
+	if err == nil || !strings.Contains(err.Error(), "key_hash must be") {
+		t.Errorf("NewServiceAccountAuthenticator() error = %v, want the plaintext key rejected", err)
+	}
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/featureflags"
+)
+
+// SessionTokenPrefix starts every browser session token
+const SessionTokenPrefix = "afs_"
+
+// SessionCookieName is the web UI cookie carrying a browser session token
+const SessionCookieName = "artifusion_session"
+
+// TokenTypeSession is the token type of requests authenticated by a browser session
+const TokenTypeSession = "session"
+
+// errSessionExpired is returned for sessions that were valid, which are not failed
+// authentications
+var errSessionExpired = errors.New("session expired")
+
+// sessionClaims is the signed payload of a session token
+type sessionClaims struct {
+	Username  string   `json:"u"`
+	Org       string   `json:"o,omitempty"`
+	Teams     []string `json:"t,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// SessionIssuer mints and verifies browser session tokens: the identity a browser
+// proved with a GitHub OAuth token, signed and short-lived, so no session state is
+// kept and the OAuth token never reaches the browser
+type SessionIssuer struct {
+	secret []byte
+	ttl    time.Duration
+	flags  *featureflags.Flags // nil = always enabled
+	now    func() time.Time
+}
+
+// NewSessionIssuer creates a session issuer from the browser session configuration
+func NewSessionIssuer(cfg *config.BrowserSessionsConfig) *SessionIssuer {
+	return &SessionIssuer{
+		secret: []byte(cfg.Secret),
+		ttl:    cfg.TTL,
+		now:    time.Now,
+	}
+}
+
+// SetFeatureFlags gates browser sessions on the featureflags.BrowserSessions flag,
+// checked on every session issued or verified. Must be called before the issuer is
+// used concurrently.
+func (s *SessionIssuer) SetFeatureFlags(flags *featureflags.Flags) {
+	s.flags = flags
+}
+
+// Enabled reports whether sessions may be issued and accepted
+func (s *SessionIssuer) Enabled() bool {
+	return s.flags == nil || s.flags.Enabled(featureflags.BrowserSessions)
+}
+
+// Issue returns a session token for the identity of result, and when it expires.
+// Only GitHub identities are issued sessions.
+func (s *SessionIssuer) Issue(result *AuthResult) (string, time.Time, error) {
+	if !s.Enabled() {
+		return "", time.Time{}, fmt.Errorf("browser sessions are disabled")
+	}
+	if !isGitHubResult(result) || result.ImpersonatedBy != "" {
+		return "", time.Time{}, fmt.Errorf("sessions can only be issued for GitHub users")
+	}
+
+	expires := s.now().Add(s.ttl).Truncate(time.Second)
+	payload, err := json.Marshal(sessionClaims{
+		Username:  result.Username,
+		Org:       result.Org,
+		Teams:     result.Teams,
+		ExpiresAt: expires.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	encoded := SessionTokenPrefix + base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.signature(encoded), expires, nil
+}
+
+// verify returns the identity of a session token if its signature is valid and it
+// has not expired
+func (s *SessionIssuer) verify(token string) (*AuthResult, error) {
+	if !s.Enabled() {
+		return nil, fmt.Errorf("browser sessions are disabled")
+	}
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !strings.HasPrefix(encoded, SessionTokenPrefix) ||
+		!hmac.Equal([]byte(signature), []byte(s.signature(encoded))) {
+		return nil, fmt.Errorf("invalid session token")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(encoded, SessionTokenPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid session token")
+	}
+	var claims sessionClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Username == "" {
+		return nil, fmt.Errorf("invalid session token")
+	}
+	if !s.now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, errSessionExpired
+	}
+
+	return &AuthResult{
+		Username:  claims.Username,
+		Org:       claims.Org,
+		Teams:     claims.Teams,
+		TokenType: TokenTypeSession,
+	}, nil
+}
+
+// signature returns the HMAC-SHA256 signature of an encoded session
+func (s *SessionIssuer) signature(encoded string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// WithSessions returns an authenticator sharing a's configuration and state that
+// also accepts the browser session tokens of sessions as Bearer tokens, for the API
+// and web UI. Protocol handlers keep using a, so sessions never reach package clients.
+func (a *ClientAuthenticator) WithSessions(sessions *SessionIssuer) *ClientAuthenticator {
+	withSessions := *a
+	withSessions.sessions = sessions
+	return &withSessions
+}
+
+// sessionToken returns the session token of r's Bearer Authorization header, if any
+func sessionToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || !strings.HasPrefix(token, SessionTokenPrefix) {
+		return "", false
+	}
+	return token, true
+}
+
+// authenticateSession returns the identity of a request carrying a session token
+func (a *ClientAuthenticator) authenticateSession(r *http.Request, token string) (*AuthResult, error) {
+	if a.lockout != nil {
+		if err := a.lockout.Check(r, token); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := a.sessions.verify(token)
+	if err != nil {
+		if !errors.Is(err, errSessionExpired) {
+			a.recordFailure(r, token)
+		}
+		return nil, err
+	}
+
+	a.logger.Debug().
+		Str("username", result.Username).
+		Msg("Client authenticated with browser session")
+	return result, nil
+}
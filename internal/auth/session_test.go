@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func TestClientAuthenticator_Session(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sessions := NewSessionIssuer(&config.BrowserSessionsConfig{
+		Secret: strings.Repeat("s", 32),
+		TTL:    30 * time.Minute,
+	})
+	sessions.now = func() time.Time { return now }
+
+	token, expires, err := sessions.Issue(&AuthResult{Username: "alice", Org: "myorg", Teams: []string{"dev"}, TokenType: TokenTypeOAuth})
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if want := now.Add(30 * time.Minute); !expires.Equal(want) {
+		t.Errorf("Issue() expires = %v, want %v", expires, want)
+	}
+	if _, _, err := sessions.Issue(&AuthResult{Username: "ci", TokenType: TokenTypeSignedURL}); err == nil {
+		t.Error("Issue() issued a session for a signed URL")
+	}
+	if _, _, err := sessions.Issue(&AuthResult{Username: "bob", TokenType: TokenTypePAT, ImpersonatedBy: "alice"}); err == nil {
+		t.Error("Issue() issued a session for an impersonated user")
+	}
+
+	// No GitHub client: sessions never reach GitHub
+	plain := NewClientAuthenticator(nil, "myorg", nil, zerolog.Nop())
+	a := plain.WithSessions(sessions)
+
+	tests := []struct {
+		name    string
+		token   string
+		after   time.Duration
+		wantErr string
+	}{
+		{name: "valid", token: token},
+		{name: "tampered", token: strings.Replace(token, ".", "x.", 1), wantErr: "invalid session token"},
+		{name: "other secret", token: token[:strings.Index(token, ".")] + ".c2lnbmF0dXJl", wantErr: "invalid session token"},
+		{name: "expired", token: token, after: 30 * time.Minute, wantErr: "session expired"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions.now = func() time.Time { return now.Add(tt.after) }
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/limits", nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+
+			result, err := a.AuthenticateRequest(r)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("AuthenticateRequest() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AuthenticateRequest() error = %v", err)
+			}
+			if result.Username != "alice" || result.Org != "myorg" || result.TokenType != TokenTypeSession {
+				t.Errorf("AuthenticateRequest() = %+v, want alice's session", result)
+			}
+		})
+	}
+
+	t.Run("not accepted without sessions", func(t *testing.T) {
+		sessions.now = func() time.Time { return now }
+		r := httptest.NewRequest(http.MethodGet, "/v2/", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		if _, err := plain.AuthenticateRequest(r); err == nil {
+			t.Error("AuthenticateRequest() accepted a session token on an authenticator without sessions")
+		}
+	})
+}
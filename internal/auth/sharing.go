@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/audit"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/constants"
+	"github.com/mainuli/artifusion/internal/featureflags"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/patrickmn/go-cache"
+	"github.com/rs/zerolog"
+)
+
+// ErrCredentialShared is returned when a token is used from more source IPs than
+// allowed and blocking is enabled.
+var ErrCredentialShared = errors.New("credential used from too many source IPs")
+
+// tokenSources tracks the client IPs a token has been used from in the current window
+type tokenSources struct {
+	ips     map[string]struct{}
+	alerted bool
+}
+
+// SharingAlert is the JSON body posted to the credential sharing webhook
+type SharingAlert struct {
+	Event     string    `json:"event"`
+	Username  string    `json:"username"`
+	TokenHash string    `json:"token_hash"`
+	ClientIP  string    `json:"client_ip"`
+	SourceIPs int       `json:"source_ips"`
+	Window    int64     `json:"window_seconds"`
+	Blocked   bool      `json:"blocked"`
+	RequestID string    `json:"request_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SharingDetector detects tokens used from an unusual number of distinct source IPs,
+// which typically means a CI token leaked and is being reused elsewhere.
+//
+// Windows are fixed: they start at a token's first observed request. The first IP
+// beyond the limit in a window raises an audit event and a webhook alert; with
+// blocking enabled, requests from such IPs are rejected until the window ends.
+//
+// Thread safety: All methods are safe for concurrent use.
+type SharingDetector struct {
+	cfg     *config.CredentialSharingConfig
+	auditor *audit.Logger
+	metrics *metrics.Metrics
+	client  *http.Client
+	flags   *featureflags.Flags // nil = always enabled
+	logger  zerolog.Logger
+
+	mu      sync.Mutex
+	sources *cache.Cache
+}
+
+// NewSharingDetector creates a detector following cfg. Alerts are recorded by auditor;
+// m may be nil.
+func NewSharingDetector(cfg *config.CredentialSharingConfig, auditor *audit.Logger, m *metrics.Metrics, logger zerolog.Logger) *SharingDetector {
+	return &SharingDetector{
+		cfg:     cfg,
+		auditor: auditor,
+		metrics: m,
+		client:  &http.Client{Timeout: constants.CredentialSharingWebhookTimeout},
+		logger:  logger,
+		sources: cache.New(cfg.Window, cfg.Window),
+	}
+}
+
+// SetFeatureFlags gates detection on the featureflags.CredentialSharing flag,
+// checked on every request observed. Must be called before the detector is used
+// concurrently.
+func (d *SharingDetector) SetFeatureFlags(flags *featureflags.Flags) {
+	d.flags = flags
+}
+
+// Observe records a request authenticated by token as username. It returns
+// ErrCredentialShared if the request must be rejected. Requests are neither
+// recorded nor rejected while the feature flag is disabled.
+func (d *SharingDetector) Observe(r *http.Request, token, username string) error {
+	if d.flags != nil && !d.flags.Enabled(featureflags.CredentialSharing) {
+		return nil
+	}
+	key := hashToken(token)
+	// Forwarding headers only count when set by a trusted proxy: a leaked token's
+	// user could otherwise claim the source IP of its owner
+	ip := middleware.TrustedClientIP(r)
+
+	d.mu.Lock()
+	var sources *tokenSources
+	if cached, found := d.sources.Get(key); found {
+		sources = cached.(*tokenSources)
+	} else {
+		sources = &tokenSources{ips: make(map[string]struct{})}
+		d.sources.Set(key, sources, d.cfg.Window)
+	}
+
+	if _, seen := sources.ips[ip]; seen || len(sources.ips) < d.cfg.MaxSourceIPs {
+		sources.ips[ip] = struct{}{}
+		d.mu.Unlock()
+		return nil
+	}
+
+	if !d.cfg.Block {
+		sources.ips[ip] = struct{}{}
+	}
+	count := len(sources.ips)
+	alert := !sources.alerted
+	sources.alerted = true
+	d.mu.Unlock()
+
+	if d.metrics != nil {
+		d.metrics.RecordCredentialSharing(d.cfg.Block)
+	}
+
+	if alert {
+		d.alert(r, SharingAlert{
+			Event:     "credential_sharing_detected",
+			Username:  username,
+			TokenHash: key[:16],
+			ClientIP:  ip,
+			SourceIPs: count,
+			Window:    int64(d.cfg.Window.Seconds()),
+			Blocked:   d.cfg.Block,
+			RequestID: middleware.GetRequestID(r.Context()),
+			Timestamp: time.Now().UTC(),
+		})
+	}
+
+	if d.cfg.Block {
+		return ErrCredentialShared
+	}
+	return nil
+}
+
+// alert records the audit event and delivers the webhook in the background
+func (d *SharingDetector) alert(r *http.Request, alert SharingAlert) {
+	d.auditor.Record(r, alert.Event).
+		Str("username", alert.Username).
+		Str("token_hash", alert.TokenHash).
+		Int("source_ips", alert.SourceIPs).
+		Dur("window", d.cfg.Window).
+		Bool("blocked", alert.Blocked).
+		Msg("Token used from an unusual number of source IPs")
+
+	if d.cfg.WebhookURL == "" {
+		return
+	}
+	go func() {
+		if err := d.sendWebhook(alert); err != nil {
+			d.logger.Warn().Err(err).Str("username", alert.Username).Msg("Failed to deliver credential sharing alert")
+		}
+	}()
+}
+
+// sendWebhook posts alert to the configured webhook
+func (d *SharingDetector) sendWebhook(alert SharingAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.CredentialSharingWebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		// The error would include the URL, which is a secret
+		return errors.New("failed to create webhook request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return errors.New("webhook request failed")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// hashToken returns the hex SHA256 of token, so tokens are never held in memory
+// longer than the request
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
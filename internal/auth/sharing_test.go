@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/audit"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/featureflags"
+	"github.com/rs/zerolog"
+)
+
+func TestSharingDetector_Observe(t *testing.T) {
+	const token = "ghp_shared"
+
+	tests := []struct {
+		name      string
+		block     bool
+		disabled  bool
+		ips       []string
+		wantErr   []bool
+		wantAlert bool
+	}{
+		{
+			name:    "within limit",
+			ips:     []string{"10.0.0.1", "10.0.0.2", "10.0.0.1"},
+			wantErr: []bool{false, false, false},
+		},
+		{
+			name:      "alert only",
+			ips:       []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"},
+			wantErr:   []bool{false, false, false, false},
+			wantAlert: true,
+		},
+		{
+			name:      "block new sources, keep known ones",
+			block:     true,
+			ips:       []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.3", "10.0.0.1"},
+			wantErr:   []bool{false, false, true, true, false},
+			wantAlert: true,
+		},
+		{
+			name:     "disabled by feature flag",
+			block:    true,
+			disabled: true,
+			ips:      []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"},
+			wantErr:  []bool{false, false, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerts := make(chan SharingAlert, 10)
+			webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var alert SharingAlert
+				if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+					t.Errorf("failed to decode alert: %v", err)
+				}
+				alerts <- alert
+			}))
+			defer webhook.Close()
+
+			cfg := &config.CredentialSharingConfig{
+				Enabled:      true,
+				MaxSourceIPs: 2,
+				Window:       time.Minute,
+				Block:        tt.block,
+				WebhookURL:   webhook.URL,
+			}
+			detector := NewSharingDetector(cfg, audit.New(zerolog.Nop()), nil, zerolog.Nop())
+			detector.SetFeatureFlags(featureflags.New(featureflags.WithSubsystems(map[string]bool{featureflags.CredentialSharing: !tt.disabled})))
+
+			for i, ip := range tt.ips {
+				r := httptest.NewRequest(http.MethodGet, "/pkg", nil)
+				r.RemoteAddr = ip + ":1234"
+				err := detector.Observe(r, token, "alice")
+				if gotErr := errors.Is(err, ErrCredentialShared); gotErr != tt.wantErr[i] {
+					t.Errorf("request %d from %s: error = %v, want rejected = %v", i+1, ip, err, tt.wantErr[i])
+				}
+			}
+
+			if !tt.wantAlert {
+				select {
+				case alert := <-alerts:
+					t.Errorf("unexpected alert %+v", alert)
+				case <-time.After(50 * time.Millisecond):
+				}
+				return
+			}
+
+			select {
+			case alert := <-alerts:
+				if alert.Username != "alice" || alert.Blocked != tt.block || alert.ClientIP != "10.0.0.3" {
+					t.Errorf("alert = %+v, want alice from 10.0.0.3 with blocked = %v", alert, tt.block)
+				}
+				if alert.TokenHash == "" || alert.TokenHash == token {
+					t.Errorf("alert token hash = %q, want a hash prefix", alert.TokenHash)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("no alert delivered to webhook")
+			}
+
+			// One alert per window
+			select {
+			case alert := <-alerts:
+				t.Errorf("unexpected second alert %+v", alert)
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
+// TestSharingDetector_SpoofedForwardedFor tests that sources are the connections'
+// addresses, not the X-Forwarded-For the client sets
+func TestSharingDetector_SpoofedForwardedFor(t *testing.T) {
+	cfg := &config.CredentialSharingConfig{
+		Enabled:      true,
+		MaxSourceIPs: 1,
+		Window:       time.Minute,
+		Block:        true,
+	}
+	detector := NewSharingDetector(cfg, audit.New(zerolog.Nop()), nil, zerolog.Nop())
+
+	observe := func(remoteIP, forwardedFor string) error {
+		r := httptest.NewRequest(http.MethodGet, "/pkg", nil)
+		r.RemoteAddr = remoteIP + ":1234"
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		return detector.Observe(r, "ghp_leaked", "ci-bot")
+	}
+
+	// The CI runner, naming various addresses, is one source
+	for _, forwardedFor := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+		if err := observe("192.0.2.10", forwardedFor); err != nil {
+			t.Fatalf("Observe() from the runner error = %v, want nil", err)
+		}
+	}
+
+	// The leaked token used elsewhere, claiming to be the runner, is a new source
+	if err := observe("203.0.113.66", "192.0.2.10"); !errors.Is(err, ErrCredentialShared) {
+		t.Errorf("Observe() from a spoofing source error = %v, want ErrCredentialShared", err)
+	}
+}
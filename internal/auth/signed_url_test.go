@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/featureflags"
+	"github.com/rs/zerolog"
+)
+
+func TestClientAuthenticator_SignedURL(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	signer := NewURLSigner(&config.SignedURLsConfig{
+		Secret:     strings.Repeat("s", 32),
+		DefaultTTL: time.Hour,
+		MaxTTL:     24 * time.Hour,
+	})
+	signer.now = func() time.Time { return now }
+
+	// No GitHub client: signed requests never reach GitHub
+	a := NewClientAuthenticator(nil, "myorg", nil, zerolog.Nop())
+	a.SetURLSigner(signer)
+
+	// httptest requests are addressed to example.com
+	const host = "example.com"
+	const path = "/v2/team/app/blobs/sha256:aaa"
+	params, expires, err := signer.Sign(host, path, "alice", 0)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if want := now.Add(time.Hour); !expires.Equal(want) {
+		t.Errorf("Sign() expires = %v, want %v", expires, want)
+	}
+	if _, _, err := signer.Sign(host, path, "alice", 48*time.Hour); err == nil {
+		t.Error("Sign() accepted a ttl above max_ttl")
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		host    string
+		path    string
+		query   string
+		after   time.Duration
+		wantErr string
+	}{
+		{name: "valid", method: http.MethodGet, path: path, query: params.Encode() + "&n=1"},
+		{name: "head", method: http.MethodHead, path: path, query: params.Encode()},
+		{name: "host case", method: http.MethodGet, host: "Example.COM", path: path, query: params.Encode()},
+		{name: "other host", method: http.MethodGet, host: "npm.example.com", path: path, query: params.Encode(), wantErr: "invalid signed URL signature"},
+		{name: "other path", method: http.MethodGet, path: "/v2/team/app/blobs/sha256:bbb", query: params.Encode(), wantErr: "invalid signed URL signature"},
+		{name: "other user", method: http.MethodGet, path: path, query: strings.Replace(params.Encode(), "alice", "bob", 1), wantErr: "invalid signed URL signature"},
+		{name: "expired", method: http.MethodGet, path: path, query: params.Encode(), after: time.Hour, wantErr: "signed URL expired"},
+		{name: "upload", method: http.MethodPut, path: path, query: params.Encode(), wantErr: "only permit downloads"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer.now = func() time.Time { return now.Add(tt.after) }
+			r := httptest.NewRequest(tt.method, tt.path+"?"+tt.query, nil)
+			if tt.host != "" {
+				r.Host = tt.host
+			}
+
+			result, err := a.AuthenticateRequest(r)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("AuthenticateRequest() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AuthenticateRequest() error = %v", err)
+			}
+			if result.Username != "alice" || result.TokenType != TokenTypeSignedURL || len(result.Teams) != 0 {
+				t.Errorf("AuthenticateRequest() = %+v, want alice without teams", result)
+			}
+			if strings.Contains(r.URL.RawQuery, "artifusion_") {
+				t.Errorf("signed URL parameters left in query %q", r.URL.RawQuery)
+			}
+		})
+	}
+
+	// Disabling the feature flag revokes minted URLs until it is enabled again
+	signer.now = func() time.Time { return now }
+	flags := featureflags.New(featureflags.WithSubsystems(nil))
+	signer.SetFeatureFlags(flags)
+	if _, err := flags.Set(featureflags.SignedURLs, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := signer.Sign(host, path, "alice", 0); err == nil {
+		t.Error("Sign() succeeded with signed URLs disabled")
+	}
+	r := httptest.NewRequest(http.MethodGet, path+"?"+params.Encode(), nil)
+	if _, err := a.AuthenticateRequest(r); err == nil || !strings.Contains(err.Error(), "signed URLs are disabled") {
+		t.Errorf("AuthenticateRequest() error = %v, want signed URLs disabled", err)
+	}
+	if _, err := flags.Reset(featureflags.SignedURLs); err != nil {
+		t.Fatal(err)
+	}
+	r = httptest.NewRequest(http.MethodGet, path+"?"+params.Encode(), nil)
+	if _, err := a.AuthenticateRequest(r); err != nil {
+		t.Errorf("AuthenticateRequest() error = %v after enabling signed URLs again", err)
+	}
+}
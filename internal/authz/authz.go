@@ -0,0 +1,66 @@
+// Package authz describes authorization decisions for dry-run access checks.
+//
+// A Decision is the outcome of evaluating a request (method, path, user) without
+// proxying it. Each evaluated rule is reported with its result so callers can see
+// why access would be granted or denied.
+package authz
+
+import (
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/auth"
+)
+
+// Rule results
+const (
+	ResultPass = "pass" // Rule evaluated and allows the request
+	ResultFail = "fail" // Rule evaluated and denies the request
+	ResultSkip = "skip" // Rule not applicable or not evaluated
+
+	// ResultUnsupported marks a rule that applies but cannot be evaluated without
+	// proxying the request, so the decision cannot be relied on
+	ResultUnsupported = "unsupported"
+)
+
+// Rule is a single evaluated policy rule
+type Rule struct {
+	Name   string `json:"rule"`
+	Result string `json:"result"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Decision is the outcome of a dry-run authorization check
+type Decision struct {
+	Allowed   bool   `json:"allowed"`
+	User      string `json:"user"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Protocol  string `json:"protocol"`
+	Operation string `json:"operation"` // "read" or "write"
+	Rules     []Rule `json:"rules"`
+}
+
+// Add appends a rule to the decision
+func (d *Decision) Add(name, result, detail string) {
+	d.Rules = append(d.Rules, Rule{Name: name, Result: result, Detail: detail})
+}
+
+// Finalize sets Allowed: a request is allowed when no rule failed or could not be
+// evaluated
+func (d *Decision) Finalize() {
+	d.Allowed = true
+	for _, rule := range d.Rules {
+		if rule.Result == ResultFail || rule.Result == ResultUnsupported {
+			d.Allowed = false
+			return
+		}
+	}
+}
+
+// Explainer is implemented by protocol handlers that can explain how they would
+// route a request for an authenticated user (e.g. which backends are eligible).
+//
+// r carries the method, path and host being checked; it is never proxied.
+type Explainer interface {
+	Explain(r *http.Request, authResult *auth.AuthResult) []Rule
+}
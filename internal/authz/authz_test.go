@@ -0,0 +1,37 @@
+package authz
+
+import "testing"
+
+func TestDecision_Finalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []Rule
+		allowed bool
+	}{
+		{
+			name:    "no rules",
+			rules:   nil,
+			allowed: true,
+		},
+		{
+			name:    "pass and skip",
+			rules:   []Rule{{Name: "authentication", Result: ResultPass}, {Name: "token_permissions", Result: ResultSkip}},
+			allowed: true,
+		},
+		{
+			name:    "any failure denies",
+			rules:   []Rule{{Name: "authentication", Result: ResultPass}, {Name: "protocol", Result: ResultFail}},
+			allowed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Decision{Allowed: !tt.allowed, Rules: tt.rules}
+			d.Finalize()
+			if d.Allowed != tt.allowed {
+				t.Errorf("Finalize() allowed = %v, want %v", d.Allowed, tt.allowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,367 @@
+// Package bench generates load against a running Artifusion instance for capacity
+// tests.
+//
+// A run first discovers real request targets from the images and packages it is
+// given (the blobs an image references, the tarball a package's latest version
+// points to), then replays a weighted mix of manifest pulls, blob streams,
+// packument and tarball fetches with bounded concurrency and an optional request
+// rate, recording per-operation latencies.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Kind is a type of request in the workload
+type Kind string
+
+// Workload request kinds
+const (
+	KindManifest  Kind = "manifest"  // OCI manifest pull by tag
+	KindBlob      Kind = "blob"      // OCI blob (config and layers) stream
+	KindPackument Kind = "packument" // npm package metadata fetch
+	KindTarball   Kind = "tarball"   // npm tarball download of the latest version
+)
+
+// Kinds lists the request kinds in report order
+var Kinds = []Kind{KindManifest, KindBlob, KindPackument, KindTarball}
+
+// DefaultMix weights the request kinds like a typical CI fleet: mostly metadata,
+// with a smaller share of large downloads
+var DefaultMix = map[Kind]int{
+	KindManifest:  5,
+	KindBlob:      3,
+	KindPackument: 4,
+	KindTarball:   2,
+}
+
+// ociManifestAccept is the Accept header of manifest pulls, as sent by docker pull
+const ociManifestAccept = "application/vnd.oci.image.index.v1+json, " +
+	"application/vnd.oci.image.manifest.v1+json, " +
+	"application/vnd.docker.distribution.manifest.list.v2+json, " +
+	"application/vnd.docker.distribution.manifest.v2+json"
+
+// npmAbbreviatedAccept is the Accept header of packument fetches, as sent by npm install
+const npmAbbreviatedAccept = "application/vnd.npm.install-v1+json; q=1.0, application/json; q=0.8, */*"
+
+// maxMetadataSize bounds the manifests and packuments read during discovery
+const maxMetadataSize = 32 << 20
+
+// Options configures a load test
+type Options struct {
+	// OCITarget and NPMTarget are the base URLs of the protocols on the instance,
+	// e.g. http://localhost:8080 and http://localhost:8080/npm. Either may be empty
+	// to leave that protocol out of the workload.
+	OCITarget string
+	NPMTarget string
+
+	// Images ("repository:tag") and Packages (npm package names) to request
+	Images   []string
+	Packages []string
+
+	// Mix weights the request kinds (nil = DefaultMix). Kinds with no targets are
+	// left out.
+	Mix map[Kind]int
+
+	// Concurrency is the number of requests in flight (default 10)
+	Concurrency int
+
+	// Duration bounds the run (0 = until Requests are sent)
+	Duration time.Duration
+
+	// Requests bounds the number of requests sent (0 = until Duration expires)
+	Requests int
+
+	// Rate caps the requests sent per second (0 = as fast as Concurrency allows)
+	Rate float64
+
+	// Username and Token are sent as basic auth (GitHub token as password)
+	Username string
+	Token    string
+
+	// Client sends the requests (nil = a client pooling Concurrency connections)
+	Client *http.Client
+}
+
+// ParseMix parses a mix such as "manifest=5,blob=3,packument=2"; kinds not
+// listed get weight 0
+func ParseMix(s string) (map[Kind]int, error) {
+	mix := make(map[Kind]int)
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mix entry %q, want kind=weight", part)
+		}
+
+		kind := Kind(name)
+		known := false
+		for _, k := range Kinds {
+			known = known || k == kind
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown request kind %q", name)
+		}
+
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight %q for %s", value, name)
+		}
+		mix[kind] = weight
+	}
+	return mix, nil
+}
+
+// target is one request of the workload
+type target struct {
+	url    string
+	accept string
+}
+
+// runner holds the state of a load test
+type runner struct {
+	opts   Options
+	client *http.Client
+}
+
+// Run discovers the workload targets, then sends requests until opts.Duration
+// expires, opts.Requests are sent or ctx is cancelled
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if opts.Duration <= 0 && opts.Requests <= 0 {
+		return nil, fmt.Errorf("either a duration or a request count is required")
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 10
+	}
+	if opts.Mix == nil {
+		opts.Mix = DefaultMix
+	}
+
+	r := &runner{opts: opts, client: opts.Client}
+	if r.client == nil {
+		r.client = &http.Client{
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				MaxIdleConns:        opts.Concurrency,
+				MaxIdleConnsPerHost: opts.Concurrency,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		}
+	}
+
+	targets, err := r.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Weighted draw over the kinds that have targets
+	var kinds []Kind
+	total := 0
+	for _, kind := range Kinds {
+		if opts.Mix[kind] > 0 && len(targets[kind]) > 0 {
+			kinds = append(kinds, kind)
+			total += opts.Mix[kind]
+		}
+	}
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("no requests to send: give images and/or packages with a non-zero mix weight")
+	}
+	pick := func(rnd *rand.Rand) (Kind, target) {
+		n := rnd.IntN(total)
+		for _, kind := range kinds {
+			if n < opts.Mix[kind] {
+				candidates := targets[kind]
+				return kind, candidates[rnd.IntN(len(candidates))]
+			}
+			n -= opts.Mix[kind]
+		}
+		panic("unreachable")
+	}
+
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if opts.Rate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(opts.Rate), 1)
+	}
+
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	report := newReport()
+	var sent atomic.Int64
+	var wg sync.WaitGroup
+	start := time.Now()
+
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func(seed uint64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewPCG(seed, uint64(start.UnixNano())))
+
+			for {
+				if opts.Requests > 0 && sent.Add(1) > int64(opts.Requests) {
+					return
+				}
+				if err := limiter.Wait(ctx); err != nil {
+					return
+				}
+
+				kind, t := pick(rnd)
+				status, bytes, latency, err := r.fetch(ctx, t)
+				if ctx.Err() != nil {
+					return // Interrupted by the end of the run, not a result
+				}
+				report.record(kind, status, bytes, latency, err)
+			}
+		}(uint64(i))
+	}
+
+	wg.Wait()
+	report.Elapsed = time.Since(start)
+	return report, nil
+}
+
+// fetch sends a request and streams the response body to the end, as a client
+// would, returning the time until the body was fully read
+func (r *runner) fetch(ctx context.Context, t target) (status int, bytes int64, latency time.Duration, err error) {
+	start := time.Now()
+	resp, err := r.get(ctx, t)
+	if err != nil {
+		return 0, 0, time.Since(start), err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	bytes, err = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, bytes, time.Since(start), err
+}
+
+func (r *runner) get(ctx context.Context, t target) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if t.accept != "" {
+		req.Header.Set("Accept", t.accept)
+	}
+	if r.opts.Token != "" {
+		req.SetBasicAuth(r.opts.Username, r.opts.Token)
+	}
+	req.Header.Set("User-Agent", "artifusion-bench")
+	return r.client.Do(req)
+}
+
+// getJSON fetches t during discovery and decodes the response into v
+func (r *runner) getJSON(ctx context.Context, t target, v any) (http.Header, error) {
+	resp, err := r.get(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: status %d", t.url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxMetadataSize)).Decode(v); err != nil {
+		return nil, fmt.Errorf("GET %s: %w", t.url, err)
+	}
+	return resp.Header, nil
+}
+
+// discover builds the targets of each kind from the images and packages
+func (r *runner) discover(ctx context.Context) (map[Kind][]target, error) {
+	targets := make(map[Kind][]target)
+
+	if len(r.opts.Images) > 0 && r.opts.OCITarget == "" {
+		return nil, fmt.Errorf("images given without an OCI target")
+	}
+	ociBase := strings.TrimSuffix(r.opts.OCITarget, "/")
+	for _, image := range r.opts.Images {
+		repository, tag, ok := strings.Cut(image, ":")
+		if !ok || repository == "" || tag == "" {
+			return nil, fmt.Errorf("invalid image %q, want repository:tag", image)
+		}
+
+		manifest := target{url: ociBase + "/v2/" + repository + "/manifests/" + tag, accept: ociManifestAccept}
+		blobs, err := r.discoverBlobs(ctx, ociBase, repository, manifest)
+		if err != nil {
+			return nil, fmt.Errorf("image %s: %w", image, err)
+		}
+		targets[KindManifest] = append(targets[KindManifest], manifest)
+		targets[KindBlob] = append(targets[KindBlob], blobs...)
+	}
+
+	if len(r.opts.Packages) > 0 && r.opts.NPMTarget == "" {
+		return nil, fmt.Errorf("packages given without an npm target")
+	}
+	npmBase := strings.TrimSuffix(r.opts.NPMTarget, "/")
+	for _, name := range r.opts.Packages {
+		packument := target{url: npmBase + "/" + strings.Replace(name, "/", "%2f", 1), accept: npmAbbreviatedAccept}
+
+		var body struct {
+			DistTags map[string]string `json:"dist-tags"`
+			Versions map[string]struct {
+				Dist struct {
+					Tarball string `json:"tarball"`
+				} `json:"dist"`
+			} `json:"versions"`
+		}
+		if _, err := r.getJSON(ctx, packument, &body); err != nil {
+			return nil, fmt.Errorf("package %s: %w", name, err)
+		}
+		targets[KindPackument] = append(targets[KindPackument], packument)
+		if tarball := body.Versions[body.DistTags["latest"]].Dist.Tarball; tarball != "" {
+			targets[KindTarball] = append(targets[KindTarball], target{url: tarball})
+		}
+	}
+
+	return targets, nil
+}
+
+// discoverBlobs returns the config and layer blobs of the manifest, following an
+// index to its first image
+func (r *runner) discoverBlobs(ctx context.Context, base, repository string, manifest target) ([]target, error) {
+	var body struct {
+		Manifests []struct {
+			Digest string `json:"digest"`
+		} `json:"manifests"`
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+		Layers []struct {
+			Digest string `json:"digest"`
+		} `json:"layers"`
+	}
+	if _, err := r.getJSON(ctx, manifest, &body); err != nil {
+		return nil, err
+	}
+
+	if len(body.Manifests) > 0 {
+		child := target{url: base + "/v2/" + repository + "/manifests/" + body.Manifests[0].Digest, accept: ociManifestAccept}
+		return r.discoverBlobs(ctx, base, repository, child)
+	}
+
+	var blobs []target
+	digests := []string{body.Config.Digest}
+	for _, layer := range body.Layers {
+		digests = append(digests, layer.Digest)
+	}
+	for _, digest := range digests {
+		if digest != "" {
+			blobs = append(blobs, target{url: base + "/v2/" + repository + "/blobs/" + digest})
+		}
+	}
+	return blobs, nil
+}
@@ -0,0 +1,176 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRegistry serves one image and one npm package, counting requests by path
+type fakeRegistry struct {
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests[r.URL.Path]++
+	f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/v2/team/app/manifests/v1":
+		w.Header().Set("Content-Type", "application/vnd.oci.image.index.v1+json")
+		_, _ = w.Write([]byte(`{"manifests":[{"digest":"sha256:image"}]}`))
+	case "/v2/team/app/manifests/sha256:image":
+		w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+		_, _ = w.Write([]byte(`{"config":{"digest":"sha256:config"},"layers":[{"digest":"sha256:layer"}]}`))
+	case "/v2/team/app/blobs/sha256:config", "/v2/team/app/blobs/sha256:layer":
+		_, _ = w.Write(bytes.Repeat([]byte("x"), 1024))
+	case "/npm/lodash":
+		tarball := "http://" + r.Host + "/npm/lodash/-/lodash-4.17.21.tgz"
+		_, _ = w.Write([]byte(`{"dist-tags":{"latest":"4.17.21"},"versions":{"4.17.21":{"dist":{"tarball":"` + tarball + `"}}}}`))
+	case "/npm/lodash/-/lodash-4.17.21.tgz":
+		_, _ = w.Write(bytes.Repeat([]byte("t"), 2048))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestRun(t *testing.T) {
+	registry := &fakeRegistry{requests: make(map[string]int)}
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	report, err := Run(context.Background(), Options{
+		OCITarget:   server.URL,
+		NPMTarget:   server.URL + "/npm",
+		Images:      []string{"team/app:v1"},
+		Packages:    []string{"lodash"},
+		Concurrency: 4,
+		Requests:    200,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	requests, errors := report.Total()
+	if requests != 200 || errors != 0 {
+		t.Errorf("Total() = %d requests, %d errors, want 200, 0", requests, errors)
+	}
+	ops := report.Ops()
+	for _, kind := range Kinds {
+		if ops[kind] == nil || ops[kind].Requests == 0 {
+			t.Errorf("no %s requests sent", kind)
+		}
+	}
+	if blob := ops[KindBlob]; blob != nil && blob.Bytes != int64(blob.Requests)*1024 {
+		t.Errorf("blob bytes = %d, want %d", blob.Bytes, blob.Requests*1024)
+	}
+	if registry.requests["/v2/team/app/blobs/sha256:layer"] == 0 {
+		t.Error("layer discovered through the index was never requested")
+	}
+
+	var out strings.Builder
+	if err := report.Write(&out); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for _, want := range []string{"manifest", "tarball", "200 requests"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRun_MixAndErrors(t *testing.T) {
+	registry := &fakeRegistry{requests: make(map[string]int)}
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	mix, err := ParseMix("manifest=1")
+	if err != nil {
+		t.Fatalf("ParseMix() error = %v", err)
+	}
+	report, err := Run(context.Background(), Options{
+		OCITarget: server.URL,
+		Images:    []string{"team/app:v1"},
+		Mix:       mix,
+		Duration:  50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	ops := report.Ops()
+	if len(ops) != 1 || ops[KindManifest] == nil {
+		t.Errorf("Ops() = %v, want only manifest requests", ops)
+	}
+
+	if _, err := Run(context.Background(), Options{OCITarget: server.URL, Images: []string{"team/missing:v1"}, Requests: 1}); err == nil {
+		t.Error("Run() error = nil for an image that can't be discovered")
+	}
+	if _, err := Run(context.Background(), Options{Requests: 1}); err == nil {
+		t.Error("Run() error = nil without images or packages")
+	}
+	if _, err := Run(context.Background(), Options{Images: []string{"team/app:v1"}}); err == nil {
+		t.Error("Run() error = nil without a duration or request count")
+	}
+}
+
+func TestParseMix(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[Kind]int
+		wantErr bool
+	}{
+		{name: "all kinds", input: "manifest=5,blob=3,packument=2,tarball=1", want: map[Kind]int{KindManifest: 5, KindBlob: 3, KindPackument: 2, KindTarball: 1}},
+		{name: "spaces", input: "manifest=1, blob=0", want: map[Kind]int{KindManifest: 1, KindBlob: 0}},
+		{name: "unknown kind", input: "wheel=1", wantErr: true},
+		{name: "missing weight", input: "manifest", wantErr: true},
+		{name: "negative weight", input: "blob=-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMix(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMix() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseMix() = %v, want %v", got, tt.want)
+			}
+			for kind, weight := range tt.want {
+				if got[kind] != weight {
+					t.Errorf("ParseMix()[%s] = %d, want %d", kind, got[kind], weight)
+				}
+			}
+		})
+	}
+}
+
+func TestOpStats_Percentile(t *testing.T) {
+	report := newReport()
+	for i := 100; i >= 1; i-- {
+		report.record(KindManifest, http.StatusOK, 0, time.Duration(i)*time.Millisecond, nil)
+	}
+	report.record(KindManifest, http.StatusBadGateway, 0, time.Millisecond, nil)
+
+	op := report.Ops()[KindManifest]
+	if op.Errors != 1 || op.Statuses[http.StatusOK] != 100 {
+		t.Errorf("Errors = %d, Statuses = %v", op.Errors, op.Statuses)
+	}
+	if p := op.Percentile(50); p != 50*time.Millisecond {
+		t.Errorf("Percentile(50) = %v, want 50ms", p)
+	}
+	if p := op.Percentile(100); p != 100*time.Millisecond {
+		t.Errorf("Percentile(100) = %v, want 100ms", p)
+	}
+}
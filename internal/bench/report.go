@@ -0,0 +1,140 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Report is the outcome of a load test
+type Report struct {
+	Elapsed time.Duration
+
+	mu  sync.Mutex
+	ops map[Kind]*OpStats
+}
+
+// OpStats are the results of one request kind
+type OpStats struct {
+	Requests int
+	Errors   int         // Transport errors and responses with status >= 400
+	Statuses map[int]int // Responses by status code (0 = transport error)
+	Bytes    int64       // Response body bytes read
+
+	latencies []time.Duration // Sorted by Report.Ops
+}
+
+func newReport() *Report {
+	return &Report{ops: make(map[Kind]*OpStats)}
+}
+
+func (r *Report) record(kind Kind, status int, bytes int64, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	op, ok := r.ops[kind]
+	if !ok {
+		op = &OpStats{Statuses: make(map[int]int)}
+		r.ops[kind] = op
+	}
+	op.Requests++
+	op.Bytes += bytes
+	op.Statuses[status]++
+	op.latencies = append(op.latencies, latency)
+	if err != nil || status >= 400 {
+		op.Errors++
+	}
+}
+
+// Ops returns the results of each request kind sent
+func (r *Report) Ops() map[Kind]*OpStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, op := range r.ops {
+		sort.Slice(op.latencies, func(i, j int) bool { return op.latencies[i] < op.latencies[j] })
+	}
+	return r.ops
+}
+
+// Total returns the number of requests sent and of those that failed
+func (r *Report) Total() (requests, errors int) {
+	for _, op := range r.Ops() {
+		requests += op.Requests
+		errors += op.Errors
+	}
+	return requests, errors
+}
+
+// Percentile returns the latency below which p (0-100) percent of the requests
+// completed, after Report.Ops sorted them
+func (s *OpStats) Percentile(p float64) time.Duration {
+	if len(s.latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(s.latencies)-1) * p / 100)
+	return s.latencies[i]
+}
+
+// Write prints a latency and throughput table per request kind
+func (r *Report) Write(w io.Writer) error {
+	ops := r.Ops()
+	seconds := r.Elapsed.Seconds()
+	if seconds <= 0 {
+		seconds = 1
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintln(tw, "kind\trequests\terrors\treq/s\tMB/s\tp50\tp90\tp99\tmax\tstatuses\t")
+	for _, kind := range Kinds {
+		op, ok := ops[kind]
+		if !ok {
+			continue
+		}
+
+		statuses := make([]string, 0, len(op.Statuses))
+		for _, status := range sortedStatuses(op.Statuses) {
+			statuses = append(statuses, fmt.Sprintf("%d:%d", status, op.Statuses[status]))
+		}
+
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%.2f\t%s\t%s\t%s\t%s\t%s\t\n",
+			kind, op.Requests, op.Errors,
+			float64(op.Requests)/seconds, float64(op.Bytes)/seconds/(1<<20),
+			round(op.Percentile(50)), round(op.Percentile(90)), round(op.Percentile(99)), round(op.Percentile(100)),
+			strings.Join(statuses, " "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	requests, errors := r.Total()
+	_, err := fmt.Fprintf(w, "\n%d requests in %s (%.1f req/s), %d errors\n",
+		requests, r.Elapsed.Round(time.Millisecond), float64(requests)/seconds, errors)
+	return err
+}
+
+// round shortens a latency for display
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
+
+// sortedStatuses returns the keys of statuses in ascending order
+func sortedStatuses(statuses map[int]int) []int {
+	keys := make([]int, 0, len(statuses))
+	for status := range statuses {
+		keys = append(keys, status)
+	}
+	sort.Ints(keys)
+	return keys
+}
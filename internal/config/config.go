@@ -1,6 +1,8 @@
 package config
 
 import (
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,16 +13,38 @@ type CircuitBreakerConfig struct {
 	Interval         time.Duration `mapstructure:"interval"`
 	Timeout          time.Duration `mapstructure:"timeout"`
 	FailureThreshold float64       `mapstructure:"failure_threshold"`
+
+	// Type selects the breaker implementation (default: failure_ratio). A latency
+	// breaker also counts successful calls slower than SlowCallThreshold as failures,
+	// so it trips on backends that are slow but still succeed.
+	Type              string        `mapstructure:"type"`
+	SlowCallThreshold time.Duration `mapstructure:"slow_call_threshold"` // Time to response headers
 }
 
+// Circuit breaker types accepted by CircuitBreakerConfig.Type
+const (
+	CircuitBreakerFailureRatio = "failure_ratio"
+	CircuitBreakerLatency      = "latency"
+)
+
 // AuthConfig contains backend authentication configuration
 type AuthConfig struct {
-	Type        string `mapstructure:"type"`
+	Type        string `mapstructure:"type"` // basic, bearer, header or github_app
 	Username    string `mapstructure:"username"`
-	Password    string `mapstructure:"password"`
-	Token       string `mapstructure:"token"`
+	Password    string `mapstructure:"password" secret:"true"`
+	Token       string `mapstructure:"token" secret:"true"`
 	HeaderName  string `mapstructure:"header_name"`
-	HeaderValue string `mapstructure:"header_value"`
+	HeaderValue string `mapstructure:"header_value" secret:"true"`
+
+	// GitHub App credentials (type github_app) for GHCR and GitHub Packages: short-lived
+	// installation tokens are minted with the app's private key (PEM, inline or from a
+	// file) and refreshed before they expire. Tokens are sent as Bearer tokens, or as
+	// the Basic auth password of Username if set (e.g. "x-access-token").
+	AppID          int64  `mapstructure:"app_id"`
+	InstallationID int64  `mapstructure:"installation_id"`
+	PrivateKey     string `mapstructure:"private_key" secret:"true"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	GitHubAPIURL   string `mapstructure:"github_api_url"` // Default: https://api.github.com
 }
 
 // Config represents the complete application configuration
@@ -31,6 +55,87 @@ type Config struct {
 	Logging   LoggingConfig   `mapstructure:"logging"`
 	Metrics   MetricsConfig   `mapstructure:"metrics"`
 	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	Admin     AdminConfig     `mapstructure:"admin"`
+
+	// ForwardProxy configures the optional forward-proxy listener for tools that
+	// cannot be pointed at a custom registry URL
+	ForwardProxy ForwardProxyConfig `mapstructure:"forward_proxy"`
+
+	// WebUI serves the backends' web UIs (e.g. Verdaccio, Nexus) to authenticated
+	// users, so they can browse packages without the backends being exposed
+	WebUI WebUIConfig `mapstructure:"web_ui"`
+
+	// Metadata records the artifacts clients pull and push in an embedded database,
+	// for browsing, retention decisions and audits across restarts
+	Metadata MetadataConfig `mapstructure:"metadata"`
+
+	// SignedURLs lets users mint time-limited URLs that download one artifact
+	// without credentials, for sharing builds with people outside the org
+	SignedURLs SignedURLsConfig `mapstructure:"signed_urls"`
+
+	// BrowserSessions lets operators sign in to the web UI and API from a browser
+	// with GitHub's OAuth device flow instead of pasting tokens into it
+	BrowserSessions BrowserSessionsConfig `mapstructure:"browser_sessions"`
+
+	// SyntheticChecks periodically pulls canary artifacts through Artifusion itself,
+	// exercising authentication, routing and rewriting end to end
+	SyntheticChecks SyntheticChecksConfig `mapstructure:"synthetic_checks"`
+
+	// GRPC serves the admin API over gRPC on a listener of its own, for automation
+	// using the typed clients generated from api/admin/v1/admin.proto
+	GRPC GRPCConfig `mapstructure:"grpc"`
+
+	// CredentialSharing detects GitHub tokens used from an unusual number of source
+	// IPs, such as a leaked CI token being reused elsewhere
+	CredentialSharing CredentialSharingConfig `mapstructure:"credential_sharing"`
+
+	// AuthLockout temporarily blocks clients and tokens after repeated failed
+	// authentications, so tokens cannot be probed at line rate
+	AuthLockout AuthLockoutConfig `mapstructure:"auth_lockout"`
+
+	// GitLab validates GitLab access and CI job tokens, once selected as the "gitlab"
+	// identity provider in identity.providers or a protocol's client_auth.providers
+	GitLab GitLabConfig `mapstructure:"gitlab"`
+
+	// LDAP validates Basic auth usernames and passwords against an LDAP directory
+	// (e.g. Active Directory), once selected as the "ldap" identity provider
+	LDAP LDAPConfig `mapstructure:"ldap"`
+
+	// ServiceAccounts authenticates locally defined accounts with static API keys,
+	// for CI systems outside GitHub, once selected as the "service_accounts" provider
+	ServiceAccounts ServiceAccountsConfig `mapstructure:"service_accounts"`
+
+	// MTLS authenticates clients presenting a certificate issued by a trusted CA,
+	// when the server itself serves TLS
+	MTLS MTLSConfig `mapstructure:"mtls"`
+
+	// Identity selects the identity providers client tokens are validated with:
+	// GitHub, and OpenID Connect providers (e.g. Okta) issuing JWTs
+	Identity IdentityConfig `mapstructure:"identity"`
+
+	// ContentPolicy restricts the file extensions and content types served per
+	// protocol, so the proxy can't be misused as a generic file tunnel
+	ContentPolicy ContentPolicyConfig `mapstructure:"content_policy"`
+
+	// ClientPolicy denies requests of client types and versions by User-Agent, e.g.
+	// npm versions with known vulnerabilities
+	ClientPolicy ClientPolicyConfig `mapstructure:"client_policy"`
+
+	// OptionsRequests answers OPTIONS requests to protocol paths locally instead of
+	// proxying them, as some backends reject them, and CORS preflights of browser tools
+	OptionsRequests OptionsRequestsConfig `mapstructure:"options_requests"`
+
+	// ShadowLog lets admins record the sanitized upstream request and response
+	// metadata of one backend to a file for a while, e.g. during registry migrations
+	ShadowLog ShadowLogConfig `mapstructure:"shadow_log"`
+
+	// Vault resolves backend credentials given as vault: references from HashiCorp
+	// Vault, and keeps them current as leases are renewed and secrets rotated
+	Vault VaultConfig `mapstructure:"vault"`
+
+	// FeatureFlags defines runtime-toggleable flags and their default state
+	// (see package featureflags). Names are lowercase snake_case
+	FeatureFlags map[string]bool `mapstructure:"feature_flags"`
 }
 
 // ServerConfig contains HTTP server configuration
@@ -44,6 +149,50 @@ type ServerConfig struct {
 	ReadBufferSize    int           `mapstructure:"read_buffer_size"`
 	WriteBufferSize   int           `mapstructure:"write_buffer_size"`
 	MaxConcurrentReqs int           `mapstructure:"max_concurrent_requests"`
+
+	// Queueing for requests over max_concurrent_requests (0 = reject immediately)
+	QueueTimeout  time.Duration `mapstructure:"queue_timeout"`       // Max time a request waits for a slot
+	MaxQueuedReqs int           `mapstructure:"max_queued_requests"` // Max requests waiting at once (default: max_concurrent_requests)
+
+	// Security headers added to responses
+	SecurityHeaders SecurityHeadersConfig `mapstructure:"security_headers"`
+
+	// TLSCertFile and TLSKeyFile serve HTTPS instead of plain HTTP, as needed for
+	// client certificate authentication (see MTLSConfig)
+	TLSCertFile string `mapstructure:"tls_cert_file"`
+	TLSKeyFile  string `mapstructure:"tls_key_file"`
+
+	// TrustedProxies are the reverse proxies (IP addresses or CIDR ranges) whose
+	// X-Forwarded-For, X-Real-IP and Forwarded headers are trusted to identify the
+	// client for auth lockouts, credential sharing detection and validation budgets.
+	// Without any, those key on the connection's peer address.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+}
+
+// X-Frame-Options values
+const (
+	FrameOptionsDeny       = "DENY"
+	FrameOptionsSameOrigin = "SAMEORIGIN"
+)
+
+// SecurityHeadersConfig contains the security header policy. Responses are classed
+// by path: package protocol paths only get X-Content-Type-Options and HSTS, as
+// package clients ignore the browser-oriented headers; the web UI
+// (web_ui.path_prefix) gets its own CSP and frame options; every other path (API,
+// health, landing page) gets the strict defaults.
+type SecurityHeadersConfig struct {
+	// Strict-Transport-Security, sent on HTTPS requests only
+	HSTSDisabled          bool          `mapstructure:"hsts_disabled"`
+	HSTSMaxAge            time.Duration `mapstructure:"hsts_max_age"`            // Rounded down to seconds (default: 1 year)
+	HSTSExcludeSubdomains bool          `mapstructure:"hsts_exclude_subdomains"` // Omit includeSubDomains
+	HSTSPreload           bool          `mapstructure:"hsts_preload"`            // Requires a max age of at least 1 year and subdomains
+
+	// Content-Security-Policy and X-Frame-Options (DENY or SAMEORIGIN) of the API and
+	// other non-protocol paths, and of the web UI
+	ContentSecurityPolicy   string `mapstructure:"content_security_policy"`
+	FrameOptions            string `mapstructure:"frame_options"`
+	UIContentSecurityPolicy string `mapstructure:"ui_content_security_policy"`
+	UIFrameOptions          string `mapstructure:"ui_frame_options"`
 }
 
 // GitHubConfig contains GitHub authentication configuration
@@ -53,13 +202,161 @@ type GitHubConfig struct {
 	RequiredTeams   []string      `mapstructure:"required_teams"`
 	AuthCacheTTL    time.Duration `mapstructure:"auth_cache_ttl"`
 	RateLimitBuffer int           `mapstructure:"rate_limit_buffer"`
+
+	// AuthCacheRefreshAhead re-validates cached tokens in the background when they are
+	// used within this long of expiry, keeping GitHub off the request path. 0 = disabled
+	AuthCacheRefreshAhead time.Duration `mapstructure:"auth_cache_refresh_ahead"`
+
+	// ValidationBudget caps uncached GitHub validations per client IP (see
+	// ServerConfig.TrustedProxies) per ValidationBudgetWindow. Cached tokens are
+	// unaffected. 0 = unlimited
+	ValidationBudget       int           `mapstructure:"validation_budget"`
+	ValidationBudgetWindow time.Duration `mapstructure:"validation_budget_window"`
+
+	// AuthTimeout bounds an uncached token validation against GitHub. 0 = no limit
+	// beyond the GitHub HTTP client timeout
+	AuthTimeout time.Duration `mapstructure:"auth_timeout"`
+
+	// FailurePolicy decides uncached validations GitHub cannot answer (timeout, network
+	// error, 5xx, API rate limit): "closed" (default) rejects them, "open" accepts tokens
+	// that validated successfully within FailOpenGrace past their cache expiry
+	FailurePolicy string        `mapstructure:"failure_policy"`
+	FailOpenGrace time.Duration `mapstructure:"fail_open_grace"`
+}
+
+// GitLabConfig contains GitLab token validation configuration. Access tokens
+// (glpat-) must belong to an active member of RequiredGroup, directly or inherited;
+// job tokens (glcbt-) of running jobs must belong to a project below it.
+// Results share the GitHub auth cache and its TTL.
+type GitLabConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`
+	APIURL        string `mapstructure:"api_url"`        // Default: https://gitlab.com/api/v4
+	RequiredGroup string `mapstructure:"required_group"` // Full path, e.g. "acme" or "acme/platform"
+
+	// UsernamePrefix is prepended to GitLab usernames, which share a namespace with
+	// GitHub logins (admin.users, allowlists), e.g. "gitlab:"
+	UsernamePrefix string `mapstructure:"username_prefix"`
+}
+
+// LDAPConfig contains LDAP authentication configuration. Clients send their
+// directory username and password with Basic auth; the user is looked up with the
+// service account (BindDN), authenticated by binding as the user, and must be a
+// member of one of RequiredGroups. Results share the GitHub auth cache and its TTL.
+type LDAPConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	URL     string `mapstructure:"url"` // ldap://host:389 or ldaps://host:636
+
+	// TLS: StartTLS upgrades ldap:// connections. CAFile verifies the server with a
+	// private CA; ServerName overrides the host name verified (default: URL host)
+	StartTLS           bool   `mapstructure:"start_tls"`
+	CAFile             string `mapstructure:"ca_file"`
+	ServerName         string `mapstructure:"server_name"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"` // Testing only
+
+	// Service account searching for users (empty = anonymous search)
+	BindDN       string `mapstructure:"bind_dn"`
+	BindPassword string `mapstructure:"bind_password" secret:"true"`
+
+	// UserFilter finds a user below UserBaseDN, with %s replaced by the escaped
+	// username, e.g. "(uid=%s)" or "(sAMAccountName=%s)" for Active Directory
+	UserBaseDN string `mapstructure:"user_base_dn"`
+	UserFilter string `mapstructure:"user_filter"` // Default: (uid=%s)
+
+	// Group membership: the user attribute listing group DNs (default: memberOf) and
+	// the group DNs of which the user must be a member of at least one (empty = any
+	// user). The groups' first RDN values (e.g. cn) are the user's teams.
+	GroupAttribute string   `mapstructure:"group_attribute"`
+	RequiredGroups []string `mapstructure:"required_groups"`
+
+	// UsernamePrefix is prepended to directory usernames, which share a namespace
+	// with GitHub logins (admin.users, allowlists), e.g. "ldap:"
+	UsernamePrefix string `mapstructure:"username_prefix"`
+
+	PoolSize int           `mapstructure:"pool_size"` // Idle connections kept open (default: 4)
+	Timeout  time.Duration `mapstructure:"timeout"`   // Per connection attempt and operation (default: 10s)
+}
+
+// ServiceAccountsConfig contains locally defined service accounts authenticating
+// with static API keys (afk_...), sent as the Bearer token or Basic auth password.
+// Only the SHA-256 hashes of the keys are configured: inline in Accounts and/or in
+// File, a YAML or JSON secrets file with an "accounts" list, read at startup.
+type ServiceAccountsConfig struct {
+	Enabled  bool                   `mapstructure:"enabled"`
+	File     string                 `mapstructure:"file"`
+	Accounts []ServiceAccountConfig `mapstructure:"accounts"`
+}
+
+// ServiceAccountConfig is a service account and the hash of its API key
+type ServiceAccountConfig struct {
+	Name    string `mapstructure:"name"`                   // Username, e.g. "jenkins"
+	KeyHash string `mapstructure:"key_hash" secret:"true"` // "sha256:" followed by the hex SHA-256 of the key
+
+	// Protocols the key is accepted by (empty = all protocols, the API and web UIs)
+	Protocols []string `mapstructure:"protocols"`
+
+	// Orgs the account acts in; when github.required_org is set, it must be listed
+	Orgs []string `mapstructure:"orgs"`
+
+	// Teams of the account, for team routing
+	Teams []string `mapstructure:"teams"`
+}
+
+// MTLSConfig contains client certificate authentication. The server (which must
+// serve TLS, see ServerConfig.TLSCertFile) asks clients for a certificate and
+// verifies it against the CAs of CAFile. Requests presenting a verified certificate
+// and no Authorization header are authenticated as the certificate's subject, with
+// its organizational units (OUs) as teams. The OUs are chosen by whoever requests
+// the certificate, so they never name the org: with github.required_org set,
+// AllowedOUs is required and certificates of an allowed OU are members of it.
+type MTLSConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	CAFile  string `mapstructure:"ca_file"` // PEM bundle of the CAs issuing client certificates
+
+	// Required rejects TLS handshakes without a valid client certificate, including
+	// those of health probes. Otherwise clients without one use other credentials.
+	Required bool `mapstructure:"required"`
+
+	// UsernameFrom selects the username: "san" (default) takes the first email, DNS
+	// or URI subject alternative name, falling back to the common name; "cn" the
+	// common name only
+	UsernameFrom   string `mapstructure:"username_from"`
+	UsernamePrefix string `mapstructure:"username_prefix"` // e.g. "mtls:"
+
+	// AllowedOUs restricts certificates to these organizational units, and their
+	// teams to the allowed ones (empty = any certificate of the CAs)
+	AllowedOUs []string `mapstructure:"allowed_ous"`
 }
 
+// Client certificate username sources (see MTLSConfig.UsernameFrom)
+const (
+	MTLSUsernameFromSAN = "san"
+	MTLSUsernameFromCN  = "cn"
+)
+
+// GitHub failure policies (see GitHubConfig.FailurePolicy)
+const (
+	FailurePolicyClosed = "closed"
+	FailurePolicyOpen   = "open"
+)
+
 // ProtocolsConfig contains configuration for all protocol handlers
 type ProtocolsConfig struct {
-	OCI   OCIConfig   `mapstructure:"oci"`
-	Maven MavenConfig `mapstructure:"maven"`
-	NPM   NPMConfig   `mapstructure:"npm"`
+	OCI       OCIConfig       `mapstructure:"oci"`
+	Maven     MavenConfig     `mapstructure:"maven"`
+	NPM       NPMConfig       `mapstructure:"npm"`
+	RubyGems  RubyGemsConfig  `mapstructure:"rubygems"`
+	Helm      HelmConfig      `mapstructure:"helm"`
+	APT       APTConfig       `mapstructure:"apt"`
+	Composer  ComposerConfig  `mapstructure:"composer"`
+	Conda     CondaConfig     `mapstructure:"conda"`
+	Terraform TerraformConfig `mapstructure:"terraform"`
+	APK       APKConfig       `mapstructure:"apk"`
+	Raw       RawConfig       `mapstructure:"raw"`
+	LFS       LFSConfig       `mapstructure:"lfs"`
+	CocoaPods CocoaPodsConfig `mapstructure:"cocoapods"`
+	Hex       HexConfig       `mapstructure:"hex"`
+	Pub       PubConfig       `mapstructure:"pub"`
+	Vagrant   VagrantConfig   `mapstructure:"vagrant"`
 }
 
 // OCIConfig contains OCI/Docker registry configuration
@@ -69,6 +366,135 @@ type OCIConfig struct {
 	ClientAuth   ClientAuthConfig   `mapstructure:"client_auth"`
 	PullBackends []OCIBackendConfig `mapstructure:"pull_backends"`
 	PushBackend  OCIBackendConfig   `mapstructure:"push_backend"`
+
+	// ExposeCascadeResult reports to clients which pull backends a read tried and what
+	// each returned, in the X-Artifusion-Backends-Tried header and in the detail of
+	// the error returned when no backend has the image. Reveals backend names.
+	ExposeCascadeResult bool `mapstructure:"expose_cascade_result"`
+
+	// ProbeManifests sends HEAD requests to all eligible pull backends in parallel for
+	// manifest GETs and starts the cascade at the first backend (in cascade order)
+	// that doesn't report the manifest missing, instead of trying each in turn
+	ProbeManifests bool `mapstructure:"probe_manifests"`
+
+	// NotFoundCacheTTL remembers a pull backend's 404 for an artifact for this long,
+	// so the many requests of an image pull skip backends that just reported it
+	// missing (0 = disabled). Pushes forget the pushed manifest or blob.
+	NotFoundCacheTTL time.Duration `mapstructure:"not_found_cache_ttl"`
+
+	// Replication copies pushes to a secondary registry for disaster recovery
+	Replication ReplicationConfig `mapstructure:"replication"`
+
+	// Trash soft-deletes pushed artifacts, so accidental deletions can be restored
+	Trash TrashConfig `mapstructure:"trash"`
+
+	// ProvenanceAnnotations attaches who pushed each tagged manifest (user, GitHub
+	// Actions repository, CI metadata) to it as an OCI artifact in the push backend,
+	// listed by the referrers API. The pushed image is not modified.
+	ProvenanceAnnotations bool `mapstructure:"provenance_annotations"`
+
+	// PlatformFilter serves multi-platform image indexes pulled by tag with only the
+	// manifests of the client's platforms
+	PlatformFilter PlatformFilterConfig `mapstructure:"platform_filter"`
+
+	// MirrorNamespace serves images of upstream registries below a fixed namespace,
+	// named by registry, instead of through the cascade
+	MirrorNamespace MirrorNamespaceConfig `mapstructure:"mirror_namespace"`
+
+	// Naming restricts the repository names pushes may use (e.g. "^myorg/")
+	Naming NamingConfig `mapstructure:"naming"`
+}
+
+// NamingConfig configures the naming convention enforced on pushes. A pushed name
+// must match at least one of Patterns; without patterns any name is accepted.
+// Reads are never restricted, so artifacts pushed before the convention was
+// introduced can still be pulled.
+type NamingConfig struct {
+	// Patterns are regular expressions, unanchored unless they use ^ and $
+	Patterns []string `mapstructure:"patterns"`
+
+	// Message is appended to the rejection, e.g. to point to the naming guidelines
+	Message string `mapstructure:"message"`
+}
+
+// MirrorNamespaceConfig configures a virtual namespace that maps image names to
+// upstream registries by convention: /v2/<prefix>/<registry>/<image> is read as
+// <image> from the pull backend for <registry>, so image references in existing
+// manifests can be rewritten by prefixing them (docker.io/library/nginx becomes
+// docker.example.com/mirror/docker.io/library/nginx). Reads skip the cascade and
+// the org scope of pull backends; team restrictions still apply. The namespace is
+// read-only.
+type MirrorNamespaceConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// Prefix is the first component of image names in the namespace (default "mirror")
+	Prefix string `mapstructure:"prefix"`
+
+	// Registries maps registries to pull backends by name. Registries not listed are
+	// read from the first pull backend whose upstream_namespace is the registry.
+	Registries []MirrorRegistryConfig `mapstructure:"registries"`
+}
+
+// MirrorRegistryConfig maps a registry of the mirror namespace to a pull backend
+type MirrorRegistryConfig struct {
+	Registry string `mapstructure:"registry"` // e.g., "quay.io"
+	Backend  string `mapstructure:"backend"`  // Pull backend name
+}
+
+// PlatformFilterConfig configures filtering of image indexes (OCI indexes and Docker
+// manifest lists) to the platforms relevant to the client, so clients that mirror
+// every platform of an image only pull the blobs they need. Only indexes pulled by
+// tag are filtered: the filtered index has a different digest, which is served from
+// memory for CacheTTL. Indexes pulled by digest are served unmodified.
+type PlatformFilterConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// Platforms kept when the client sends no platform hint, as os/arch[/variant]
+	// (e.g. linux/amd64, linux/arm64/v8). Without any, only hinting clients are
+	// filtered.
+	Platforms []string `mapstructure:"platforms"`
+
+	// ClientHints takes the client's platforms from the X-Artifusion-Platform header
+	// (comma-separated os/arch[/variant]) or, failing that, the os/arch in Docker's
+	// User-Agent
+	ClientHints bool `mapstructure:"client_hints"`
+
+	// CacheTTL is how long filtered indexes can be fetched by their digest
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+}
+
+// TrashConfig configures soft-delete of pushed OCI artifacts. A manifest or blob
+// DELETE is accepted but held in the trash, hiding the artifact from reads, and only
+// forwarded to the push backend once Retention has passed. Admins can restore
+// trashed artifacts until then; pushing the artifact again also restores it.
+type TrashConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Retention time.Duration `mapstructure:"retention"`
+
+	// StateFile persists the trash across restarts. Without it the trash is held in
+	// memory, and a restart restores every trashed artifact.
+	StateFile string `mapstructure:"state_file"`
+}
+
+// ReplicationConfig configures asynchronous replication of OCI pushes from the push
+// backend to a secondary registry. Each pushed manifest is copied with the blobs it
+// references once the push succeeds; the reconciliation job periodically compares
+// the two registries and replicates tags that are missing or differ on the target,
+// catching pushes whose replication failed or was dropped. Deletes are not replicated.
+type ReplicationConfig struct {
+	Enabled bool             `mapstructure:"enabled"`
+	Target  OCIBackendConfig `mapstructure:"target"`
+
+	// Workers is the number of manifests replicated concurrently
+	Workers int `mapstructure:"workers"`
+
+	// QueueSize is the number of pushes waiting for replication; pushes beyond it
+	// are left to the reconciliation job
+	QueueSize int `mapstructure:"queue_size"`
+
+	// ReconcileInterval is how often the push backend and target are compared
+	// (0 = disabled)
+	ReconcileInterval time.Duration `mapstructure:"reconcile_interval"`
 }
 
 // MavenConfig contains Maven repository configuration
@@ -78,6 +504,27 @@ type MavenConfig struct {
 	PathPrefix string             `mapstructure:"path_prefix"` // URL path prefix - required when host is empty
 	ClientAuth ClientAuthConfig   `mapstructure:"client_auth"`
 	Backend    MavenBackendConfig `mapstructure:"backend"`
+
+	// Optional A/B experiment: CandidatePercent (0-100) of read traffic is sent to
+	// Candidate instead of Backend. Writes always go to Backend.
+	Candidate        *MavenBackendConfig `mapstructure:"candidate"`
+	CandidatePercent float64             `mapstructure:"candidate_percent"`
+
+	// Optional read-through upstream (e.g. the public registry): reads Backend answers
+	// with 404 are retried against Upstream. With WriteBack, artifacts fetched from
+	// Upstream are published into Backend in the background, building a durable
+	// internal mirror. Backend's auth must allow publishing.
+	Upstream  *MavenBackendConfig `mapstructure:"upstream"`
+	WriteBack bool                `mapstructure:"write_back"`
+
+	// DirectoryListing answers reads of directories (paths ending in /) the backends
+	// answer with 404 with a listing synthesized from the directory's
+	// maven-metadata.xml and the artifacts in the metadata database, for tooling that
+	// browses the repository such as Gradle dependency verification helpers
+	DirectoryListing bool `mapstructure:"directory_listing"`
+
+	// Naming restricts the groupIds deploys may use (e.g. `^com\.myorg(\.|$)`)
+	Naming NamingConfig `mapstructure:"naming"`
 }
 
 // NPMConfig contains NPM registry configuration
@@ -87,13 +534,268 @@ type NPMConfig struct {
 	PathPrefix string           `mapstructure:"path_prefix"` // URL path prefix - required when host is empty
 	ClientAuth ClientAuthConfig `mapstructure:"client_auth"`
 	Backend    NPMBackendConfig `mapstructure:"backend"`
+
+	// Optional A/B experiment: CandidatePercent (0-100) of read traffic is sent to
+	// Candidate instead of Backend. Writes always go to Backend.
+	Candidate        *NPMBackendConfig `mapstructure:"candidate"`
+	CandidatePercent float64           `mapstructure:"candidate_percent"`
+
+	// Optional read-through upstream (e.g. the public registry): reads Backend answers
+	// with 404 are retried against Upstream. With WriteBack, artifacts fetched from
+	// Upstream are published into Backend in the background, building a durable
+	// internal mirror. Backend's auth must allow publishing.
+	Upstream  *NPMBackendConfig `mapstructure:"upstream"`
+	WriteBack bool              `mapstructure:"write_back"`
+
+	// Scopes are the package scopes published to the private registry (e.g. "@acme").
+	// The .npmrc served by the API maps each to Artifusion.
+	Scopes []string `mapstructure:"scopes"`
+
+	// Naming restricts the scopes publishes may use (e.g. "^@myorg$"). Unscoped
+	// packages have an empty scope, so they are rejected by such patterns.
+	Naming NamingConfig `mapstructure:"naming"`
+}
+
+// RubyGemsConfig contains RubyGems repository configuration
+type RubyGemsConfig struct {
+	Enabled    bool                  `mapstructure:"enabled"`
+	Host       string                `mapstructure:"host"`        // Optional: domain for host-based routing (e.g., "gems.example.com")
+	PathPrefix string                `mapstructure:"path_prefix"` // URL path prefix - required when host is empty
+	ClientAuth ClientAuthConfig      `mapstructure:"client_auth"`
+	Backend    RubyGemsBackendConfig `mapstructure:"backend"`
+}
+
+// HelmConfig contains Helm chart repository configuration. Classic repositories
+// (index.yaml and chart archives) are served from Backend; OCI-based charts are
+// served by the OCI protocol handler, also on Host when it is set.
+type HelmConfig struct {
+	Enabled    bool              `mapstructure:"enabled"`
+	Host       string            `mapstructure:"host"`        // Optional: domain for host-based routing (e.g., "charts.example.com")
+	PathPrefix string            `mapstructure:"path_prefix"` // URL path prefix - required when host is empty
+	ClientAuth ClientAuthConfig  `mapstructure:"client_auth"`
+	Backend    HelmBackendConfig `mapstructure:"backend"`
+}
+
+// APTConfig contains Debian/APT repository configuration
+type APTConfig struct {
+	Enabled    bool             `mapstructure:"enabled"`
+	Host       string           `mapstructure:"host"`        // Optional: domain for host-based routing (e.g., "apt.example.com")
+	PathPrefix string           `mapstructure:"path_prefix"` // URL path prefix - required when host is empty
+	ClientAuth ClientAuthConfig `mapstructure:"client_auth"`
+	Backend    APTBackendConfig `mapstructure:"backend"`
+}
+
+// ComposerConfig contains PHP Composer repository configuration. Backend is
+// typically a private Satis or Private Packagist repository.
+type ComposerConfig struct {
+	Enabled    bool                  `mapstructure:"enabled"`
+	Host       string                `mapstructure:"host"`        // Optional: domain for host-based routing (e.g., "composer.example.com")
+	PathPrefix string                `mapstructure:"path_prefix"` // URL path prefix - required when host is empty
+	ClientAuth ClientAuthConfig      `mapstructure:"client_auth"`
+	Backend    ComposerBackendConfig `mapstructure:"backend"`
+
+	// Optional cascade to a public repository (e.g. https://repo.packagist.org):
+	// reads Backend answers with 404 are retried against Upstream, so packages the
+	// private repository doesn't have resolve from the public one
+	Upstream *ComposerBackendConfig `mapstructure:"upstream"`
+}
+
+// CondaConfig contains Conda channel configuration. Each channel is served below
+// its name (e.g. /conda/conda-forge/linux-64/repodata.json) from its own backend.
+type CondaConfig struct {
+	Enabled    bool                 `mapstructure:"enabled"`
+	Host       string               `mapstructure:"host"`        // Optional: domain for host-based routing (e.g., "conda.example.com")
+	PathPrefix string               `mapstructure:"path_prefix"` // URL path prefix - required when host is empty
+	ClientAuth ClientAuthConfig     `mapstructure:"client_auth"`
+	Channels   []CondaChannelConfig `mapstructure:"channels"`
+}
+
+// CondaChannelConfig configures a Conda channel and the backend serving it
+type CondaChannelConfig struct {
+	// Name is the channel's name in request paths (e.g. "conda-forge")
+	Name string `mapstructure:"name"`
+
+	// Backend is the channel's URL, holding the <subdir>/repodata.json indexes
+	// (e.g. https://conda.anaconda.org/conda-forge)
+	Backend CondaBackendConfig `mapstructure:"backend"`
+
+	// RepodataCacheTTL serves the channel's repodata.json and current_repodata.json
+	// from the response cache for this long (0 = disabled). Large channels' repodata
+	// runs to hundreds of megabytes, and every conda solve fetches it.
+	RepodataCacheTTL time.Duration `mapstructure:"repodata_cache_ttl"`
+}
+
+// TerraformConfig contains Terraform registry configuration. Artifusion answers
+// service discovery (/.well-known/terraform.json) itself, pointing Terraform at the
+// module and provider registry APIs below the path prefix, and proxies those to the
+// backend registry.
+type TerraformConfig struct {
+	Enabled    bool                   `mapstructure:"enabled"`
+	Host       string                 `mapstructure:"host"`        // Optional: domain for host-based routing (e.g., "terraform.example.com")
+	PathPrefix string                 `mapstructure:"path_prefix"` // URL path prefix - required when host is empty
+	ClientAuth ClientAuthConfig       `mapstructure:"client_auth"`
+	Backend    TerraformBackendConfig `mapstructure:"backend"`
+}
+
+// APKConfig contains Alpine APK repository configuration
+type APKConfig struct {
+	Enabled    bool             `mapstructure:"enabled"`
+	Host       string           `mapstructure:"host"`        // Optional: domain for host-based routing (e.g., "apk.example.com")
+	PathPrefix string           `mapstructure:"path_prefix"` // URL path prefix - required when host is empty
+	ClientAuth ClientAuthConfig `mapstructure:"client_auth"`
+	Backend    APKBackendConfig `mapstructure:"backend"`
+}
+
+// CocoaPodsConfig contains CocoaPods configuration: a CDN-style spec repo
+// (CocoaPods-version.yml, all_pods_versions_*.txt and Specs/) and, optionally, the
+// prebuilt binaries private podspecs download below <path_prefix>/binaries/
+type CocoaPodsConfig struct {
+	Enabled    bool                   `mapstructure:"enabled"`
+	Host       string                 `mapstructure:"host"`        // Optional: domain for host-based routing (e.g., "pods.example.com")
+	PathPrefix string                 `mapstructure:"path_prefix"` // URL path prefix - required when host is empty
+	ClientAuth ClientAuthConfig       `mapstructure:"client_auth"`
+	Backend    CocoaPodsBackendConfig `mapstructure:"backend"`
+
+	// BinaryBackend serves <path_prefix>/binaries/ (e.g. a raw repository holding
+	// xcframework zips); nil = disabled
+	BinaryBackend *CocoaPodsBackendConfig `mapstructure:"binary_backend"`
+}
+
+// HexConfig contains Hex (Elixir/Erlang) repository configuration. Backend is a
+// private repository such as mini_repo; registry resources (names, versions and
+// packages/) and tarballs are proxied as-is, signed by the repository serving them.
+type HexConfig struct {
+	Enabled    bool             `mapstructure:"enabled"`
+	Host       string           `mapstructure:"host"`        // Optional: domain for host-based routing (e.g., "hex.example.com")
+	PathPrefix string           `mapstructure:"path_prefix"` // URL path prefix - required when host is empty
+	ClientAuth ClientAuthConfig `mapstructure:"client_auth"`
+	Backend    HexBackendConfig `mapstructure:"backend"`
+
+	// Optional cascade to a public repository (e.g. https://repo.hex.pm): package
+	// resources and tarballs Backend answers with 404 are retried against Upstream,
+	// so packages the private repository doesn't have resolve from the public one
+	Upstream *HexBackendConfig `mapstructure:"upstream"`
+
+	// PublishBackend serves the HTTP API below <path_prefix>/api/ that mix hex.publish
+	// uploads to (e.g. http://mini-repo:4000/api/repos/acme); nil = publishing disabled
+	PublishBackend *HexBackendConfig `mapstructure:"publish_backend"`
+}
+
+// PubConfig contains Dart pub hosted repository configuration. Backend is a
+// repository implementing the hosted pub repository spec (e.g. unpub or a private
+// pub.dev replacement); archive and upload URLs in its JSON responses are pointed
+// at the proxy.
+type PubConfig struct {
+	Enabled    bool             `mapstructure:"enabled"`
+	Host       string           `mapstructure:"host"`        // Optional: domain for host-based routing (e.g., "pub.example.com")
+	PathPrefix string           `mapstructure:"path_prefix"` // URL path prefix - required when host is empty
+	ClientAuth ClientAuthConfig `mapstructure:"client_auth"`
+	Backend    PubBackendConfig `mapstructure:"backend"`
+}
+
+// VagrantConfig contains Vagrant box catalog configuration. Backend serves Vagrant
+// Cloud-style box metadata (JSON listing each version's providers and their download
+// URLs) and the Vagrant Cloud API; provider URLs pointing at it are pointed at the
+// proxy. Box files hosted elsewhere are served below
+// <path_prefix>/downloads/<name>/ from the download backend of that name, with its
+// own credentials, and provider URLs pointing at a download backend are rewritten
+// accordingly.
+type VagrantConfig struct {
+	Enabled    bool                 `mapstructure:"enabled"`
+	Host       string               `mapstructure:"host"`        // Optional: domain for host-based routing (e.g., "boxes.example.com")
+	PathPrefix string               `mapstructure:"path_prefix"` // URL path prefix - required when host is empty
+	ClientAuth ClientAuthConfig     `mapstructure:"client_auth"`
+	Backend    VagrantBackendConfig `mapstructure:"backend"`
+
+	// DownloadBackends serve box files the catalog points at other hosts (e.g. a
+	// storage bucket or file server); each is served below downloads/<name>/
+	DownloadBackends []VagrantBackendConfig `mapstructure:"download_backends"`
+}
+
+// LFSConfig contains Git LFS server configuration. Clients use
+// <path_prefix>/<repository path on the backend> as their LFS URL; object transfer
+// URLs the backend returns from the Batch API are pointed at the proxy.
+type LFSConfig struct {
+	Enabled    bool             `mapstructure:"enabled"`
+	Host       string           `mapstructure:"host"`        // Optional: domain for host-based routing (e.g., "lfs.example.com")
+	PathPrefix string           `mapstructure:"path_prefix"` // URL path prefix - required when host is empty
+	ClientAuth ClientAuthConfig `mapstructure:"client_auth"`
+	Backend    LFSBackendConfig `mapstructure:"backend"`
+}
+
+// RawConfig contains raw artifact repository configuration: arbitrary files read
+// (GET, HEAD), uploaded (PUT) and deleted (DELETE) below the path prefix. Each
+// repository serves the paths below its own path from its own backend.
+type RawConfig struct {
+	Enabled      bool                  `mapstructure:"enabled"`
+	Host         string                `mapstructure:"host"`        // Optional: domain for host-based routing (e.g., "files.example.com")
+	PathPrefix   string                `mapstructure:"path_prefix"` // URL path prefix - required when host is empty
+	ClientAuth   ClientAuthConfig      `mapstructure:"client_auth"`
+	Repositories []RawRepositoryConfig `mapstructure:"repositories"`
+}
+
+// RawRepositoryConfig configures a raw repository and the backend serving it
+type RawRepositoryConfig struct {
+	// Path is the repository's path below the path prefix (e.g. "/builds"). Requests
+	// go to the repository with the longest matching path; "/" matches all paths.
+	// The backend sees the path within the repository.
+	Path string `mapstructure:"path"`
+
+	Backend RawBackendConfig `mapstructure:"backend"`
+
+	// MaxUploadSize rejects uploads larger than this many bytes (0 = unlimited)
+	MaxUploadSize int64 `mapstructure:"max_upload_size"`
+
+	// ReadOnly rejects uploads and deletes
+	ReadOnly bool `mapstructure:"read_only"`
 }
 
 // ClientAuthConfig contains client authentication configuration
 type ClientAuthConfig struct {
+	// WWW-Authenticate challenges of 401 responses, which make clients prompt for or
+	// send credentials: one per supported scheme ("basic", "bearer"), in order
+	// (default: the protocol's client scheme). Realm defaults to the protocol's name;
+	// for OCI it is the token endpoint URL of Bearer challenges, whose service is
+	// Service. Scope is the scope of Bearer challenges (OCI: derived from the request)
 	SupportedSchemes []string `mapstructure:"supported_schemes"`
 	Realm            string   `mapstructure:"realm"`
 	Service          string   `mapstructure:"service"`
+	Scope            string   `mapstructure:"scope"`
+
+	// Providers overrides identity.providers for this protocol
+	Providers []string `mapstructure:"providers"`
+
+	// ErrorHelp is guidance for developers appended to the protocol's 401 and 403
+	// error messages
+	ErrorHelp ErrorHelpConfig `mapstructure:"error_help"`
+}
+
+// ErrorHelpConfig contains human-readable guidance appended to error responses
+type ErrorHelpConfig struct {
+	Unauthorized string `mapstructure:"unauthorized"` // 401, e.g. how to create a token and log in
+	Forbidden    string `mapstructure:"forbidden"`    // 403, e.g. where to request access
+}
+
+// UnauthorizedMessage returns message followed by the configured 401 guidance
+func (c *ClientAuthConfig) UnauthorizedMessage(message string) string {
+	return appendHelp(message, c.ErrorHelp.Unauthorized)
+}
+
+// ForbiddenMessage returns message followed by the configured 403 guidance
+func (c *ClientAuthConfig) ForbiddenMessage(message string) string {
+	return appendHelp(message, c.ErrorHelp.Forbidden)
+}
+
+// appendHelp appends help to message as a separate sentence
+func appendHelp(message, help string) string {
+	help = strings.TrimSpace(help)
+	if help == "" {
+		return message
+	}
+	if strings.HasSuffix(message, ".") || strings.HasSuffix(message, "!") || strings.HasSuffix(message, "?") {
+		return message + " " + help
+	}
+	return message + ". " + help
 }
 
 // OCIBackendConfig contains OCI/Docker registry backend configuration
@@ -113,6 +815,17 @@ type OCIBackendConfig struct {
 	// Examples: ["myorg", "anotherorg"], ["*"]
 	Scope []string `mapstructure:"scope"`
 
+	// Teams restricts a pull backend to members of at least one of these GitHub teams
+	// (slugs within github.required_org). If empty, the backend is available to all users.
+	// Example: ["platform"] to let only the platform team fall through to an external mirror
+	Teams []string `mapstructure:"teams"`
+
+	// FallthroughStatuses are the pull backend response statuses on which a read moves
+	// on to the next backend instead of returning the response. Entries are status
+	// codes ("429") or classes ("5xx"). Empty = DefaultFallthroughStatuses.
+	// Example: ["404", "429", "5xx"] to return an internal registry's 401/403 as-is
+	FallthroughStatuses []string `mapstructure:"fallthrough_statuses"`
+
 	// HTTP client pool settings
 	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
 	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
@@ -120,8 +833,16 @@ type OCIBackendConfig struct {
 	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
 	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
 
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
 	// Circuit breaker settings
 	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
 }
 
 // Interface implementation for proxy.BackendConfig
@@ -133,9 +854,44 @@ func (o *OCIBackendConfig) GetMaxIdleConnsPerHost() int       { return o.MaxIdle
 func (o *OCIBackendConfig) GetIdleConnTimeout() time.Duration { return o.IdleConnTimeout }
 func (o *OCIBackendConfig) GetDialTimeout() time.Duration     { return o.DialTimeout }
 func (o *OCIBackendConfig) GetRequestTimeout() time.Duration  { return o.RequestTimeout }
+func (o *OCIBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return o.ResponseHeaderTimeout
+}
 func (o *OCIBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
 	return &o.CircuitBreaker
 }
+func (o *OCIBackendConfig) GetTransport() *TransportConfig {
+	return &o.Transport
+}
+
+// DefaultFallthroughStatuses are the statuses a pull backend falls through on when
+// fallthrough_statuses is not set: not found, no access, and backend errors
+var DefaultFallthroughStatuses = []string{"401", "403", "404", "5xx"}
+
+// FallsThrough reports whether a pull backend response with status should make a
+// read try the next backend
+func (o *OCIBackendConfig) FallsThrough(status int) bool {
+	patterns := o.FallthroughStatuses
+	if len(patterns) == 0 {
+		patterns = DefaultFallthroughStatuses
+	}
+	for _, pattern := range patterns {
+		if matchStatus(pattern, status) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchStatus reports whether status matches a status code ("404") or class ("4xx")
+func matchStatus(pattern string, status int) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if len(pattern) == 3 && strings.HasSuffix(pattern, "xx") {
+		return pattern[0] >= '1' && pattern[0] <= '9' && status/100 == int(pattern[0]-'0')
+	}
+	code, err := strconv.Atoi(pattern)
+	return err == nil && code == status
+}
 
 // MavenBackendConfig contains Maven repository backend configuration
 type MavenBackendConfig struct {
@@ -151,8 +907,16 @@ type MavenBackendConfig struct {
 	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
 	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
 
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
 	// Circuit breaker settings
 	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
 }
 
 // Interface implementation for proxy.BackendConfig
@@ -164,9 +928,15 @@ func (m *MavenBackendConfig) GetMaxIdleConnsPerHost() int       { return m.MaxId
 func (m *MavenBackendConfig) GetIdleConnTimeout() time.Duration { return m.IdleConnTimeout }
 func (m *MavenBackendConfig) GetDialTimeout() time.Duration     { return m.DialTimeout }
 func (m *MavenBackendConfig) GetRequestTimeout() time.Duration  { return m.RequestTimeout }
+func (m *MavenBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return m.ResponseHeaderTimeout
+}
 func (m *MavenBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
 	return &m.CircuitBreaker
 }
+func (m *MavenBackendConfig) GetTransport() *TransportConfig {
+	return &m.Transport
+}
 
 // NPMBackendConfig contains NPM registry backend configuration
 type NPMBackendConfig struct {
@@ -182,8 +952,16 @@ type NPMBackendConfig struct {
 	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
 	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
 
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
 	// Circuit breaker settings
 	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
 }
 
 // Interface implementation for proxy.BackendConfig
@@ -195,41 +973,1099 @@ func (n *NPMBackendConfig) GetMaxIdleConnsPerHost() int       { return n.MaxIdle
 func (n *NPMBackendConfig) GetIdleConnTimeout() time.Duration { return n.IdleConnTimeout }
 func (n *NPMBackendConfig) GetDialTimeout() time.Duration     { return n.DialTimeout }
 func (n *NPMBackendConfig) GetRequestTimeout() time.Duration  { return n.RequestTimeout }
+func (n *NPMBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return n.ResponseHeaderTimeout
+}
 func (n *NPMBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
 	return &n.CircuitBreaker
 }
-
-// PathRewriteConfig contains path rewriting rules
-type PathRewriteConfig struct {
-	AddLibraryPrefix bool `mapstructure:"add_library_prefix"`
+func (n *NPMBackendConfig) GetTransport() *TransportConfig {
+	return &n.Transport
 }
 
-// LoggingConfig contains logging configuration
-type LoggingConfig struct {
-	Level          string `mapstructure:"level"`
-	Format         string `mapstructure:"format"`
-	ForceColor     bool   `mapstructure:"force_color"`
-	IncludeHeaders bool   `mapstructure:"include_headers"`
-	IncludeBody    bool   `mapstructure:"include_body"`
+// RubyGemsBackendConfig contains RubyGems repository backend configuration
+type RubyGemsBackendConfig struct {
+	// Common fields
+	Name string      `mapstructure:"name"`
+	URL  string      `mapstructure:"url"`
+	Auth *AuthConfig `mapstructure:"auth"` // gem push expects the API key as a custom Authorization header
+
+	// HTTP client pool settings
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
+	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
+
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
+	// Circuit breaker settings
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
 }
 
-// MetricsConfig contains Prometheus metrics configuration
-type MetricsConfig struct {
-	Enabled bool   `mapstructure:"enabled"`
-	Path    string `mapstructure:"path"`
+// Interface implementation for proxy.BackendConfig
+func (g *RubyGemsBackendConfig) GetName() string                   { return g.Name }
+func (g *RubyGemsBackendConfig) GetURL() string                    { return g.URL }
+func (g *RubyGemsBackendConfig) GetAuth() *AuthConfig              { return g.Auth }
+func (g *RubyGemsBackendConfig) GetMaxIdleConns() int              { return g.MaxIdleConns }
+func (g *RubyGemsBackendConfig) GetMaxIdleConnsPerHost() int       { return g.MaxIdleConnsPerHost }
+func (g *RubyGemsBackendConfig) GetIdleConnTimeout() time.Duration { return g.IdleConnTimeout }
+func (g *RubyGemsBackendConfig) GetDialTimeout() time.Duration     { return g.DialTimeout }
+func (g *RubyGemsBackendConfig) GetRequestTimeout() time.Duration  { return g.RequestTimeout }
+func (g *RubyGemsBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return g.ResponseHeaderTimeout
+}
+func (g *RubyGemsBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
+	return &g.CircuitBreaker
+}
+func (g *RubyGemsBackendConfig) GetTransport() *TransportConfig {
+	return &g.Transport
 }
 
-// RateLimitConfig contains rate limiting configuration
-type RateLimitConfig struct {
-	Enabled         bool    `mapstructure:"enabled"`
-	RequestsPerSec  float64 `mapstructure:"requests_per_sec"`
-	Burst           int     `mapstructure:"burst"`
-	PerUserEnabled  bool    `mapstructure:"per_user_enabled"`
-	PerUserRequests float64 `mapstructure:"per_user_requests"`
-	PerUserBurst    int     `mapstructure:"per_user_burst"`
+// HelmBackendConfig contains Helm chart repository backend configuration
+type HelmBackendConfig struct {
+	// Common fields
+	Name string      `mapstructure:"name"`
+	URL  string      `mapstructure:"url"`
+	Auth *AuthConfig `mapstructure:"auth"`
+
+	// HTTP client pool settings
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
+	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
+
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
+	// Circuit breaker settings
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
 }
 
-// Default values
+// Interface implementation for proxy.BackendConfig
+func (h *HelmBackendConfig) GetName() string                   { return h.Name }
+func (h *HelmBackendConfig) GetURL() string                    { return h.URL }
+func (h *HelmBackendConfig) GetAuth() *AuthConfig              { return h.Auth }
+func (h *HelmBackendConfig) GetMaxIdleConns() int              { return h.MaxIdleConns }
+func (h *HelmBackendConfig) GetMaxIdleConnsPerHost() int       { return h.MaxIdleConnsPerHost }
+func (h *HelmBackendConfig) GetIdleConnTimeout() time.Duration { return h.IdleConnTimeout }
+func (h *HelmBackendConfig) GetDialTimeout() time.Duration     { return h.DialTimeout }
+func (h *HelmBackendConfig) GetRequestTimeout() time.Duration  { return h.RequestTimeout }
+func (h *HelmBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return h.ResponseHeaderTimeout
+}
+func (h *HelmBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
+	return &h.CircuitBreaker
+}
+func (h *HelmBackendConfig) GetTransport() *TransportConfig {
+	return &h.Transport
+}
+
+// APTBackendConfig contains Debian/APT repository backend configuration
+type APTBackendConfig struct {
+	// Common fields
+	Name string      `mapstructure:"name"`
+	URL  string      `mapstructure:"url"`
+	Auth *AuthConfig `mapstructure:"auth"`
+
+	// HTTP client pool settings
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
+	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
+
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
+	// Circuit breaker settings
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
+}
+
+// Interface implementation for proxy.BackendConfig
+func (a *APTBackendConfig) GetName() string                   { return a.Name }
+func (a *APTBackendConfig) GetURL() string                    { return a.URL }
+func (a *APTBackendConfig) GetAuth() *AuthConfig              { return a.Auth }
+func (a *APTBackendConfig) GetMaxIdleConns() int              { return a.MaxIdleConns }
+func (a *APTBackendConfig) GetMaxIdleConnsPerHost() int       { return a.MaxIdleConnsPerHost }
+func (a *APTBackendConfig) GetIdleConnTimeout() time.Duration { return a.IdleConnTimeout }
+func (a *APTBackendConfig) GetDialTimeout() time.Duration     { return a.DialTimeout }
+func (a *APTBackendConfig) GetRequestTimeout() time.Duration  { return a.RequestTimeout }
+func (a *APTBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return a.ResponseHeaderTimeout
+}
+func (a *APTBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
+	return &a.CircuitBreaker
+}
+func (a *APTBackendConfig) GetTransport() *TransportConfig {
+	return &a.Transport
+}
+
+// ComposerBackendConfig contains PHP Composer repository backend configuration
+type ComposerBackendConfig struct {
+	// Common fields
+	Name string      `mapstructure:"name"`
+	URL  string      `mapstructure:"url"`
+	Auth *AuthConfig `mapstructure:"auth"`
+
+	// HTTP client pool settings
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
+	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
+
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
+	// Circuit breaker settings
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
+}
+
+// Interface implementation for proxy.BackendConfig
+func (c *ComposerBackendConfig) GetName() string                   { return c.Name }
+func (c *ComposerBackendConfig) GetURL() string                    { return c.URL }
+func (c *ComposerBackendConfig) GetAuth() *AuthConfig              { return c.Auth }
+func (c *ComposerBackendConfig) GetMaxIdleConns() int              { return c.MaxIdleConns }
+func (c *ComposerBackendConfig) GetMaxIdleConnsPerHost() int       { return c.MaxIdleConnsPerHost }
+func (c *ComposerBackendConfig) GetIdleConnTimeout() time.Duration { return c.IdleConnTimeout }
+func (c *ComposerBackendConfig) GetDialTimeout() time.Duration     { return c.DialTimeout }
+func (c *ComposerBackendConfig) GetRequestTimeout() time.Duration  { return c.RequestTimeout }
+func (c *ComposerBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return c.ResponseHeaderTimeout
+}
+func (c *ComposerBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
+	return &c.CircuitBreaker
+}
+func (c *ComposerBackendConfig) GetTransport() *TransportConfig {
+	return &c.Transport
+}
+
+// CondaBackendConfig contains Conda channel backend configuration
+type CondaBackendConfig struct {
+	// Common fields
+	Name string      `mapstructure:"name"`
+	URL  string      `mapstructure:"url"`
+	Auth *AuthConfig `mapstructure:"auth"`
+
+	// HTTP client pool settings
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
+	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
+
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
+	// Circuit breaker settings
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
+}
+
+// Interface implementation for proxy.BackendConfig
+func (c *CondaBackendConfig) GetName() string                   { return c.Name }
+func (c *CondaBackendConfig) GetURL() string                    { return c.URL }
+func (c *CondaBackendConfig) GetAuth() *AuthConfig              { return c.Auth }
+func (c *CondaBackendConfig) GetMaxIdleConns() int              { return c.MaxIdleConns }
+func (c *CondaBackendConfig) GetMaxIdleConnsPerHost() int       { return c.MaxIdleConnsPerHost }
+func (c *CondaBackendConfig) GetIdleConnTimeout() time.Duration { return c.IdleConnTimeout }
+func (c *CondaBackendConfig) GetDialTimeout() time.Duration     { return c.DialTimeout }
+func (c *CondaBackendConfig) GetRequestTimeout() time.Duration  { return c.RequestTimeout }
+func (c *CondaBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return c.ResponseHeaderTimeout
+}
+func (c *CondaBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
+	return &c.CircuitBreaker
+}
+func (c *CondaBackendConfig) GetTransport() *TransportConfig {
+	return &c.Transport
+}
+
+// TerraformBackendConfig contains Terraform registry backend configuration
+type TerraformBackendConfig struct {
+	// Common fields
+	Name string      `mapstructure:"name"`
+	URL  string      `mapstructure:"url"`
+	Auth *AuthConfig `mapstructure:"auth"`
+
+	// Terraform-specific fields: where the backend serves the module and provider
+	// registry APIs, as advertised in its service discovery document (defaults:
+	// /v1/modules/ and /v1/providers/, as on registry.terraform.io)
+	ModulesPath   string `mapstructure:"modules_path"`
+	ProvidersPath string `mapstructure:"providers_path"`
+
+	// HTTP client pool settings
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
+	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
+
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
+	// Circuit breaker settings
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
+}
+
+// Interface implementation for proxy.BackendConfig
+func (t *TerraformBackendConfig) GetName() string                   { return t.Name }
+func (t *TerraformBackendConfig) GetURL() string                    { return t.URL }
+func (t *TerraformBackendConfig) GetAuth() *AuthConfig              { return t.Auth }
+func (t *TerraformBackendConfig) GetMaxIdleConns() int              { return t.MaxIdleConns }
+func (t *TerraformBackendConfig) GetMaxIdleConnsPerHost() int       { return t.MaxIdleConnsPerHost }
+func (t *TerraformBackendConfig) GetIdleConnTimeout() time.Duration { return t.IdleConnTimeout }
+func (t *TerraformBackendConfig) GetDialTimeout() time.Duration     { return t.DialTimeout }
+func (t *TerraformBackendConfig) GetRequestTimeout() time.Duration  { return t.RequestTimeout }
+func (t *TerraformBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return t.ResponseHeaderTimeout
+}
+func (t *TerraformBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
+	return &t.CircuitBreaker
+}
+func (t *TerraformBackendConfig) GetTransport() *TransportConfig {
+	return &t.Transport
+}
+
+// APKBackendConfig contains Alpine APK repository backend configuration
+type APKBackendConfig struct {
+	// Common fields
+	Name string      `mapstructure:"name"`
+	URL  string      `mapstructure:"url"`
+	Auth *AuthConfig `mapstructure:"auth"`
+
+	// HTTP client pool settings
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
+	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
+
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
+	// Circuit breaker settings
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
+}
+
+// Interface implementation for proxy.BackendConfig
+func (a *APKBackendConfig) GetName() string                   { return a.Name }
+func (a *APKBackendConfig) GetURL() string                    { return a.URL }
+func (a *APKBackendConfig) GetAuth() *AuthConfig              { return a.Auth }
+func (a *APKBackendConfig) GetMaxIdleConns() int              { return a.MaxIdleConns }
+func (a *APKBackendConfig) GetMaxIdleConnsPerHost() int       { return a.MaxIdleConnsPerHost }
+func (a *APKBackendConfig) GetIdleConnTimeout() time.Duration { return a.IdleConnTimeout }
+func (a *APKBackendConfig) GetDialTimeout() time.Duration     { return a.DialTimeout }
+func (a *APKBackendConfig) GetRequestTimeout() time.Duration  { return a.RequestTimeout }
+func (a *APKBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return a.ResponseHeaderTimeout
+}
+func (a *APKBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
+	return &a.CircuitBreaker
+}
+func (a *APKBackendConfig) GetTransport() *TransportConfig {
+	return &a.Transport
+}
+
+// CocoaPodsBackendConfig contains CocoaPods backend configuration
+type CocoaPodsBackendConfig struct {
+	// Common fields
+	Name string      `mapstructure:"name"`
+	URL  string      `mapstructure:"url"`
+	Auth *AuthConfig `mapstructure:"auth"`
+
+	// HTTP client pool settings
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
+	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
+
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
+	// Circuit breaker settings
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
+}
+
+// Interface implementation for proxy.BackendConfig
+func (c *CocoaPodsBackendConfig) GetName() string                   { return c.Name }
+func (c *CocoaPodsBackendConfig) GetURL() string                    { return c.URL }
+func (c *CocoaPodsBackendConfig) GetAuth() *AuthConfig              { return c.Auth }
+func (c *CocoaPodsBackendConfig) GetMaxIdleConns() int              { return c.MaxIdleConns }
+func (c *CocoaPodsBackendConfig) GetMaxIdleConnsPerHost() int       { return c.MaxIdleConnsPerHost }
+func (c *CocoaPodsBackendConfig) GetIdleConnTimeout() time.Duration { return c.IdleConnTimeout }
+func (c *CocoaPodsBackendConfig) GetDialTimeout() time.Duration     { return c.DialTimeout }
+func (c *CocoaPodsBackendConfig) GetRequestTimeout() time.Duration  { return c.RequestTimeout }
+func (c *CocoaPodsBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return c.ResponseHeaderTimeout
+}
+func (c *CocoaPodsBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
+	return &c.CircuitBreaker
+}
+func (c *CocoaPodsBackendConfig) GetTransport() *TransportConfig {
+	return &c.Transport
+}
+
+// HexBackendConfig contains Hex repository backend configuration
+type HexBackendConfig struct {
+	// Common fields
+	Name string      `mapstructure:"name"`
+	URL  string      `mapstructure:"url"`
+	Auth *AuthConfig `mapstructure:"auth"`
+
+	// HTTP client pool settings
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
+	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
+
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
+	// Circuit breaker settings
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
+}
+
+// Interface implementation for proxy.BackendConfig
+func (h *HexBackendConfig) GetName() string                   { return h.Name }
+func (h *HexBackendConfig) GetURL() string                    { return h.URL }
+func (h *HexBackendConfig) GetAuth() *AuthConfig              { return h.Auth }
+func (h *HexBackendConfig) GetMaxIdleConns() int              { return h.MaxIdleConns }
+func (h *HexBackendConfig) GetMaxIdleConnsPerHost() int       { return h.MaxIdleConnsPerHost }
+func (h *HexBackendConfig) GetIdleConnTimeout() time.Duration { return h.IdleConnTimeout }
+func (h *HexBackendConfig) GetDialTimeout() time.Duration     { return h.DialTimeout }
+func (h *HexBackendConfig) GetRequestTimeout() time.Duration  { return h.RequestTimeout }
+func (h *HexBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return h.ResponseHeaderTimeout
+}
+func (h *HexBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
+	return &h.CircuitBreaker
+}
+func (h *HexBackendConfig) GetTransport() *TransportConfig {
+	return &h.Transport
+}
+
+// PubBackendConfig contains Dart pub repository backend configuration
+type PubBackendConfig struct {
+	// Common fields
+	Name string      `mapstructure:"name"`
+	URL  string      `mapstructure:"url"`
+	Auth *AuthConfig `mapstructure:"auth"`
+
+	// HTTP client pool settings
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
+	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
+
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
+	// Circuit breaker settings
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
+}
+
+// Interface implementation for proxy.BackendConfig
+func (p *PubBackendConfig) GetName() string                   { return p.Name }
+func (p *PubBackendConfig) GetURL() string                    { return p.URL }
+func (p *PubBackendConfig) GetAuth() *AuthConfig              { return p.Auth }
+func (p *PubBackendConfig) GetMaxIdleConns() int              { return p.MaxIdleConns }
+func (p *PubBackendConfig) GetMaxIdleConnsPerHost() int       { return p.MaxIdleConnsPerHost }
+func (p *PubBackendConfig) GetIdleConnTimeout() time.Duration { return p.IdleConnTimeout }
+func (p *PubBackendConfig) GetDialTimeout() time.Duration     { return p.DialTimeout }
+func (p *PubBackendConfig) GetRequestTimeout() time.Duration  { return p.RequestTimeout }
+func (p *PubBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return p.ResponseHeaderTimeout
+}
+func (p *PubBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
+	return &p.CircuitBreaker
+}
+func (p *PubBackendConfig) GetTransport() *TransportConfig {
+	return &p.Transport
+}
+
+// VagrantBackendConfig contains Vagrant box catalog and box download backend configuration
+type VagrantBackendConfig struct {
+	// Common fields
+	Name string      `mapstructure:"name"`
+	URL  string      `mapstructure:"url"`
+	Auth *AuthConfig `mapstructure:"auth"`
+
+	// HTTP client pool settings
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
+	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
+
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
+	// Circuit breaker settings
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
+}
+
+// Interface implementation for proxy.BackendConfig
+func (v *VagrantBackendConfig) GetName() string                   { return v.Name }
+func (v *VagrantBackendConfig) GetURL() string                    { return v.URL }
+func (v *VagrantBackendConfig) GetAuth() *AuthConfig              { return v.Auth }
+func (v *VagrantBackendConfig) GetMaxIdleConns() int              { return v.MaxIdleConns }
+func (v *VagrantBackendConfig) GetMaxIdleConnsPerHost() int       { return v.MaxIdleConnsPerHost }
+func (v *VagrantBackendConfig) GetIdleConnTimeout() time.Duration { return v.IdleConnTimeout }
+func (v *VagrantBackendConfig) GetDialTimeout() time.Duration     { return v.DialTimeout }
+func (v *VagrantBackendConfig) GetRequestTimeout() time.Duration  { return v.RequestTimeout }
+func (v *VagrantBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return v.ResponseHeaderTimeout
+}
+func (v *VagrantBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
+	return &v.CircuitBreaker
+}
+func (v *VagrantBackendConfig) GetTransport() *TransportConfig {
+	return &v.Transport
+}
+
+// LFSBackendConfig contains Git LFS server backend configuration
+type LFSBackendConfig struct {
+	// Common fields
+	Name string      `mapstructure:"name"`
+	URL  string      `mapstructure:"url"`
+	Auth *AuthConfig `mapstructure:"auth"`
+
+	// HTTP client pool settings
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
+	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
+
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
+	// Circuit breaker settings
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
+}
+
+// Interface implementation for proxy.BackendConfig
+func (l *LFSBackendConfig) GetName() string                   { return l.Name }
+func (l *LFSBackendConfig) GetURL() string                    { return l.URL }
+func (l *LFSBackendConfig) GetAuth() *AuthConfig              { return l.Auth }
+func (l *LFSBackendConfig) GetMaxIdleConns() int              { return l.MaxIdleConns }
+func (l *LFSBackendConfig) GetMaxIdleConnsPerHost() int       { return l.MaxIdleConnsPerHost }
+func (l *LFSBackendConfig) GetIdleConnTimeout() time.Duration { return l.IdleConnTimeout }
+func (l *LFSBackendConfig) GetDialTimeout() time.Duration     { return l.DialTimeout }
+func (l *LFSBackendConfig) GetRequestTimeout() time.Duration  { return l.RequestTimeout }
+func (l *LFSBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return l.ResponseHeaderTimeout
+}
+func (l *LFSBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
+	return &l.CircuitBreaker
+}
+func (l *LFSBackendConfig) GetTransport() *TransportConfig {
+	return &l.Transport
+}
+
+// RawBackendConfig contains raw repository backend configuration
+type RawBackendConfig struct {
+	// Common fields
+	Name string      `mapstructure:"name"`
+	URL  string      `mapstructure:"url"`
+	Auth *AuthConfig `mapstructure:"auth"`
+
+	// HTTP client pool settings
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	DialTimeout         time.Duration `mapstructure:"dial_timeout"`
+	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
+
+	// ResponseHeaderTimeout fails a request whose backend accepted the connection but
+	// sent no response headers within this time, instead of waiting out the full
+	// request timeout meant for large transfers (0 = disabled)
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+
+	// Circuit breaker settings
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Low-level connection settings
+	Transport TransportConfig `mapstructure:"transport"`
+}
+
+// Interface implementation for proxy.BackendConfig
+func (b *RawBackendConfig) GetName() string                   { return b.Name }
+func (b *RawBackendConfig) GetURL() string                    { return b.URL }
+func (b *RawBackendConfig) GetAuth() *AuthConfig              { return b.Auth }
+func (b *RawBackendConfig) GetMaxIdleConns() int              { return b.MaxIdleConns }
+func (b *RawBackendConfig) GetMaxIdleConnsPerHost() int       { return b.MaxIdleConnsPerHost }
+func (b *RawBackendConfig) GetIdleConnTimeout() time.Duration { return b.IdleConnTimeout }
+func (b *RawBackendConfig) GetDialTimeout() time.Duration     { return b.DialTimeout }
+func (b *RawBackendConfig) GetRequestTimeout() time.Duration  { return b.RequestTimeout }
+func (b *RawBackendConfig) GetResponseHeaderTimeout() time.Duration {
+	return b.ResponseHeaderTimeout
+}
+func (b *RawBackendConfig) GetCircuitBreaker() *CircuitBreakerConfig {
+	return &b.CircuitBreaker
+}
+func (b *RawBackendConfig) GetTransport() *TransportConfig {
+	return &b.Transport
+}
+
+// TransportConfig contains low-level connection settings for a backend
+type TransportConfig struct {
+	// DNSRefreshInterval re-resolves the backend hostname at this interval and rotates
+	// dials across all resolved addresses, skipping addresses that recently failed to
+	// connect (0 = disabled, each dial resolves through the system resolver)
+	DNSRefreshInterval time.Duration `mapstructure:"dns_refresh_interval"`
+
+	// DNSFailureCooldown is how long an address that failed to connect is skipped
+	DNSFailureCooldown time.Duration `mapstructure:"dns_failure_cooldown"`
+
+	// IPFamily restricts or orders the address families dialed: ipv4 or ipv6 dial only
+	// that family, prefer_ipv4 or prefer_ipv6 try that family first and fall back to the
+	// other. Empty uses the system's address order.
+	IPFamily string `mapstructure:"ip_family"`
+
+	// FallbackDelay is how long a dial to the first address family may take before the
+	// other family is tried in parallel (Happy Eyeballs, RFC 6555). 0 uses the Go
+	// default of 300ms; a negative value disables the parallel fallback.
+	FallbackDelay time.Duration `mapstructure:"fallback_delay"`
+
+	// KeepAlive is the interval between TCP keep-alive probes on backend connections
+	// (negative disables keep-alive probes)
+	KeepAlive time.Duration `mapstructure:"keep_alive"`
+
+	// TLSHandshakeTimeout bounds the TLS handshake with the backend
+	TLSHandshakeTimeout time.Duration `mapstructure:"tls_handshake_timeout"`
+
+	// ExpectContinueTimeout is how long to wait for the backend's first response
+	// headers before sending the body of a request with "Expect: 100-continue"
+	ExpectContinueTimeout time.Duration `mapstructure:"expect_continue_timeout"`
+
+	// ForceAttemptHTTP2 negotiates HTTP/2 with TLS backends. Off by default, so backend
+	// connections use HTTP/1.1 and large transfers don't share one connection.
+	ForceAttemptHTTP2 bool `mapstructure:"force_attempt_http2"`
+
+	// MaxStreamDuration aborts a backend response body still streaming after this
+	// long, counted from the response headers (0 = no limit)
+	MaxStreamDuration time.Duration `mapstructure:"max_stream_duration"`
+
+	// MinBytesPerSec aborts a backend response body transferring less than this many
+	// bytes per second over MinThroughputWindow, freeing the worker slot held by a
+	// stalled upstream (0 = disabled)
+	MinBytesPerSec      int64         `mapstructure:"min_bytes_per_sec"`
+	MinThroughputWindow time.Duration `mapstructure:"min_throughput_window"`
+
+	// MaxInFlightBytes caps the bytes left to stream of the backend's responses being
+	// transferred at once, so one backend's large artifacts can't monopolize the
+	// bandwidth shared with other protocols. A response that doesn't fit waits for
+	// transfers in progress to drain; one is always admitted when none is in
+	// progress. Responses of unknown length aren't counted (0 = unlimited).
+	MaxInFlightBytes int64 `mapstructure:"max_in_flight_bytes"`
+
+	// VerifyContentDigest verifies the Content-Digest (RFC 9530) a backend sends as a
+	// header or trailer against the streamed body. On mismatch the client's transfer
+	// is aborted before its last byte, so a corrupted artifact never completes.
+	VerifyContentDigest bool `mapstructure:"verify_content_digest"`
+
+	// Redirects decides what happens to redirects from the backend: pass_through
+	// (the default) returns them to the client, follow follows them in the proxy and
+	// streams the final target, for backends that redirect to storage URLs clients
+	// can't reach
+	Redirects string `mapstructure:"redirects"`
+
+	// MaxRedirects bounds the redirects followed per request when Redirects is follow
+	MaxRedirects int `mapstructure:"max_redirects"`
+
+	// RewriteRedirects maps Location headers pointing anywhere under the backend URL,
+	// relative or absolute with any spelling of the backend host, to the proxy's URL
+	// with the protocol's path prefix, so clients don't bypass the proxy. Without it
+	// only absolute locations starting with the configured backend URL are rewritten.
+	RewriteRedirects bool `mapstructure:"rewrite_redirects"`
+
+	// HostHeader is sent as the Host header instead of the backend URL's host, for
+	// upstreams behind shared load balancers that route on it
+	HostHeader string `mapstructure:"host_header"`
+
+	// TLSServerName is sent in the TLS handshake (SNI) and verified against the
+	// backend's certificate instead of the backend URL's hostname
+	TLSServerName string `mapstructure:"tls_server_name"`
+
+	// WarmConnections is the number of connections opened to the backend at startup
+	// and whenever its circuit breaker closes, so the first requests after a deploy
+	// or recovery don't all wait for TCP and TLS handshakes at once (0 = disabled).
+	// At most max_idle_conns_per_host are kept.
+	WarmConnections int `mapstructure:"warm_connections"`
+}
+
+// IP families accepted by TransportConfig.IPFamily
+const (
+	IPFamilyIPv4       = "ipv4"
+	IPFamilyIPv6       = "ipv6"
+	IPFamilyPreferIPv4 = "prefer_ipv4"
+	IPFamilyPreferIPv6 = "prefer_ipv6"
+)
+
+// Redirect policies accepted by TransportConfig.Redirects
+const (
+	RedirectsPassThrough = "pass_through"
+	RedirectsFollow      = "follow"
+)
+
+// PathRewriteConfig contains path rewriting rules
+type PathRewriteConfig struct {
+	AddLibraryPrefix bool `mapstructure:"add_library_prefix"`
+}
+
+// LoggingConfig contains logging configuration
+type LoggingConfig struct {
+	Level          string `mapstructure:"level"`
+	Format         string `mapstructure:"format"`
+	ForceColor     bool   `mapstructure:"force_color"`
+	IncludeHeaders bool   `mapstructure:"include_headers"`
+	IncludeBody    bool   `mapstructure:"include_body"`
+}
+
+// MetricsConfig contains Prometheus metrics configuration
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Path    string `mapstructure:"path"`
+}
+
+// AdminConfig contains configuration for administrative features
+type AdminConfig struct {
+	// Users lists GitHub usernames granted admin privileges (case-insensitive)
+	Users []string `mapstructure:"users"`
+
+	// Impersonation allows admins to send X-Artifusion-Impersonate: <user> to process
+	// read requests as another user for debugging authorization issues
+	Impersonation bool `mapstructure:"impersonation"`
+}
+
+// ForwardProxyConfig contains configuration for the forward-proxy listener.
+//
+// Clients configured with Artifusion as their HTTP(S) proxy have requests to the
+// intercepted registry hosts routed through the protocol handlers as if they had been
+// sent to Artifusion directly. Requests to any other host are rejected, so the listener
+// is never an open proxy.
+type ForwardProxyConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Port    int  `mapstructure:"port"`
+
+	// TLSCertFile and TLSKeyFile are the certificate presented to clients for CONNECT
+	// tunnels. It must be valid for every intercepted host and trusted by the clients.
+	// Without a certificate only plain HTTP proxy requests are intercepted.
+	TLSCertFile string `mapstructure:"tls_cert_file"`
+	TLSKeyFile  string `mapstructure:"tls_key_file"`
+
+	// Intercept maps registry hosts to the protocol handling their requests
+	Intercept []InterceptConfig `mapstructure:"intercept"`
+}
+
+// InterceptConfig routes requests for a registry host to a protocol handler
+type InterceptConfig struct {
+	Host     string `mapstructure:"host"`     // Registry hostname, e.g. "registry.npmjs.org"
+	Protocol string `mapstructure:"protocol"` // oci, maven or npm
+}
+
+// WebUIConfig contains configuration for serving backend web UIs.
+// Each mounted UI is served at <path_prefix>/<protocol>/ with the usual GitHub
+// authentication (browsers prompt for username and token), and its requests carry
+// the backend's configured credentials.
+type WebUIConfig struct {
+	Enabled    bool               `mapstructure:"enabled"`
+	PathPrefix string             `mapstructure:"path_prefix"`
+	Mounts     []WebUIMountConfig `mapstructure:"mounts"`
+}
+
+// WebUIMountConfig exposes the web UI of a protocol's backend
+type WebUIMountConfig struct {
+	Protocol string `mapstructure:"protocol"` // maven or npm
+
+	// URL is the UI's base URL (default: the protocol backend's URL). The backend's
+	// auth and transport settings apply.
+	URL string `mapstructure:"url"`
+
+	// Paths are the backend paths, relative to URL, that are served (default: all),
+	// e.g. Verdaccio's /-/web, /-/static and /-/verdaccio
+	Paths []string `mapstructure:"paths"`
+
+	// Home is the backend path the mount's root redirects to (default: /)
+	Home string `mapstructure:"home"`
+}
+
+// MetadataConfig configures the artifact metadata database. Pulls and pushes are
+// buffered in memory and written to the bbolt database at Path every FlushInterval,
+// so a crash loses at most one interval of pull counts.
+type MetadataConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	Path          string        `mapstructure:"path"` // Database file, created if missing
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+}
+
+// SyntheticChecksConfig configures synthetic transactions: every Interval, each check
+// downloads its canary artifact from Artifusion like a client would, verifying the
+// content, and records the outcome and latency in metrics.
+type SyntheticChecksConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	Timeout  time.Duration `mapstructure:"timeout"` // Per check run
+
+	// URL is where the checks reach Artifusion (default: http://127.0.0.1:<server.port>)
+	URL string `mapstructure:"url"`
+
+	// Token is the GitHub token the checks authenticate with, e.g. of a bot account
+	Token string `mapstructure:"token" secret:"true"`
+
+	Checks []SyntheticCheckConfig `mapstructure:"checks"`
+}
+
+// SyntheticCheckConfig is one synthetic check
+type SyntheticCheckConfig struct {
+	Name     string `mapstructure:"name"`     // Metric label, e.g. oci-canary
+	Protocol string `mapstructure:"protocol"` // oci, npm or maven
+
+	// Artifact is the canary to download:
+	//   oci:   image reference, e.g. library/alpine:3.20 (manifest, config and layers)
+	//   npm:   package, optionally with a version, e.g. is-number or is-number@7.0.0
+	//   maven: group:artifact:version, e.g. org.slf4j:slf4j-api:2.0.13 (POM)
+	Artifact string `mapstructure:"artifact"`
+}
+
+// SignedURLsConfig configures signed download URLs. A signed URL carries its expiry,
+// the user who minted it and an HMAC-SHA256 signature over both and the artifact
+// path, keyed with Secret. Rotating Secret revokes every URL minted so far.
+type SignedURLsConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Secret     string        `mapstructure:"secret" secret:"true"` // At least 32 characters
+	DefaultTTL time.Duration `mapstructure:"default_ttl"`
+	MaxTTL     time.Duration `mapstructure:"max_ttl"` // Longest lifetime users may request
+
+	// BaseURL is the external URL clients reach Artifusion at, e.g.
+	// https://artifacts.example.com (default: derived from the minting request)
+	BaseURL string `mapstructure:"base_url"`
+}
+
+// BrowserSessionsConfig configures browser sessions. The browser obtains a GitHub
+// OAuth token through the device flow of the OAuth App ClientID, which Artifusion
+// validates like any GitHub token and exchanges for a session token signed with
+// Secret. Session tokens are self-contained (no server-side state) and expire after
+// TTL. The API only accepts them in the Authorization header and the web UI in a
+// SameSite=Strict cookie, so other sites can't make requests with them (CSRF).
+type BrowserSessionsConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	ClientID string        `mapstructure:"client_id"`            // OAuth App with device flow enabled
+	Secret   string        `mapstructure:"secret" secret:"true"` // At least 32 characters
+	TTL      time.Duration `mapstructure:"ttl"`                  // Default: 30m
+
+	// GitHubURL is the GitHub web URL serving the OAuth endpoints, e.g.
+	// https://github.example.com for GitHub Enterprise Server (default: https://github.com)
+	GitHubURL string `mapstructure:"github_url"`
+
+	// Scopes requested for the OAuth token; read:org lets organization membership be
+	// checked (default: [read:org])
+	Scopes []string `mapstructure:"scopes"`
+}
+
+// GRPCConfig contains configuration for the gRPC admin API listener. Callers
+// authenticate with the GitHub token of an admin user in "authorization" metadata.
+type GRPCConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Port    int  `mapstructure:"port"`
+
+	// TLSCertFile and TLSKeyFile enable TLS. Without them the listener is plaintext
+	// and should only be reachable from trusted networks.
+	TLSCertFile string `mapstructure:"tls_cert_file"`
+	TLSKeyFile  string `mapstructure:"tls_key_file"`
+}
+
+// CredentialSharingConfig contains configuration for duplicate credential detection.
+// Each token may be used from MaxSourceIPs distinct client IPs per Window; the first
+// IP beyond that raises an audit event and, if WebhookURL is set, a webhook alert.
+// With Block, requests from IPs beyond the limit are rejected for the rest of the
+// window, while the IPs already seen keep working.
+type CredentialSharingConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	MaxSourceIPs int           `mapstructure:"max_source_ips"`
+	Window       time.Duration `mapstructure:"window"`
+	Block        bool          `mapstructure:"block"`
+
+	// WebhookURL receives alerts as JSON POSTs (optional). Chat webhook URLs embed
+	// their credentials, so the URL is treated as a secret.
+	WebhookURL string `mapstructure:"webhook_url" secret:"true"`
+}
+
+// AuthLockoutConfig contains configuration for brute-force lockout. Failed
+// authentications are counted per client IP and per token; MaxFailures within
+// Window locks the IP or token out for Duration, doubling with each further lockout
+// up to MaxDuration. A key's history is forgotten one Window after its last failure
+// or lockout ended. Requests without credentials and GitHub outages are not counted.
+type AuthLockoutConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	MaxFailures int           `mapstructure:"max_failures"`
+	Window      time.Duration `mapstructure:"window"`
+	Duration    time.Duration `mapstructure:"duration"`
+	MaxDuration time.Duration `mapstructure:"max_duration"`
+}
+
+// IdentityConfig contains the identity providers that authenticate client tokens.
+// Providers lists the providers tokens are offered to, in order: "github" (the
+// default) and the names of OIDC providers. Protocols may select their own with
+// client_auth.providers. A token is validated by the first provider recognizing it:
+// GitHub by the token prefix, an OIDC provider by a JWT issued by its issuer.
+type IdentityConfig struct {
+	Providers []string             `mapstructure:"providers"`
+	OIDC      []OIDCProviderConfig `mapstructure:"oidc"`
+}
+
+// OIDCProviderConfig contains configuration for accepting the JWTs (ID or access
+// tokens) of an OpenID Connect provider. Signing keys are discovered from the
+// issuer's /.well-known/openid-configuration unless JWKSURL is set, and cached for
+// JWKSCacheTTL; a token signed with an unknown key refreshes them early.
+//
+// Usernames share a namespace with GitHub logins (admin.users, team routing
+// allowlists), so set UsernamePrefix (e.g. "okta:") when they may collide.
+type OIDCProviderConfig struct {
+	Name    string `mapstructure:"name"`     // Referenced by identity.providers and client_auth.providers
+	Issuer  string `mapstructure:"issuer"`   // Must equal the tokens' iss claim
+	JWKSURL string `mapstructure:"jwks_url"` // Skips discovery (optional)
+
+	// Audiences accepted in the aud claim; a token must name at least one
+	Audiences []string `mapstructure:"audiences"`
+
+	UsernameClaim  string `mapstructure:"username_claim"`  // Default: sub
+	UsernamePrefix string `mapstructure:"username_prefix"` // Prepended to the username claim
+	GroupsClaim    string `mapstructure:"groups_claim"`    // Used as teams for routing (default: groups)
+
+	// RequiredClaims must all be present with the given value (or, for array claims,
+	// contain it), e.g. {claim: email_verified, value: "true"}
+	RequiredClaims []ClaimRequirement `mapstructure:"required_claims"`
+
+	JWKSCacheTTL time.Duration `mapstructure:"jwks_cache_ttl"` // Default: 1h
+	ClockSkew    time.Duration `mapstructure:"clock_skew"`     // Leeway for exp and nbf (default: 1m)
+}
+
+// ClaimRequirement requires a JWT claim to have a value
+type ClaimRequirement struct {
+	Claim string `mapstructure:"claim"`
+	Value string `mapstructure:"value"`
+}
+
+// ContentPolicyConfig contains allow/deny rules on what protocol requests may serve.
+// A request is denied if any rule of its protocol matching its path denies it: by the
+// file extension of the request path, before reaching the backend, or by the
+// Content-Type of a successful response, which is then replaced with a 403.
+type ContentPolicyConfig struct {
+	Enabled bool                `mapstructure:"enabled"`
+	Rules   []ContentPolicyRule `mapstructure:"rules"`
+}
+
+// ContentPolicyRule restricts the requests of one protocol
+type ContentPolicyRule struct {
+	Protocol string `mapstructure:"protocol"` // oci, maven, npm, rubygems, helm, apt, composer, conda, terraform, apk, raw, lfs, cocoapods, hex, pub or vagrant
+
+	// Path is a regular expression matched against the request path, including any
+	// protocol path prefix (default: all paths).
+	// Example: "^/v2/.+/manifests/" for OCI manifest routes
+	Path string `mapstructure:"path"`
+
+	// Extensions are matched case-insensitively against the end of the request path,
+	// e.g. ".exe" or ".tar.gz". With AllowExtensions set, only paths ending in one of
+	// them are served.
+	AllowExtensions []string `mapstructure:"allow_extensions"`
+	DenyExtensions  []string `mapstructure:"deny_extensions"`
+
+	// Content types are media types without parameters and may use wildcards, e.g.
+	// "application/json", "text/*" or "application/*+json". With AllowContentTypes
+	// set, successful responses of any other or no content type are denied.
+	AllowContentTypes []string `mapstructure:"allow_content_types"`
+	DenyContentTypes  []string `mapstructure:"deny_content_types"`
+}
+
+// ClientPolicyConfig contains policies on the clients requests are sent with,
+// classified by User-Agent as docker, containerd, npm, yarn, pnpm, maven or gradle.
+// Clients older than the minimum version of their type are asked to upgrade with a
+// 400, and a request is denied with a 403 if any rule matches its client. Clients
+// are counted in artifusion_client_requests_total whether or not the policy is
+// enabled.
+type ClientPolicyConfig struct {
+	Enabled bool               `mapstructure:"enabled"`
+	Rules   []ClientPolicyRule `mapstructure:"rules"`
+
+	// MinVersions maps client types to the oldest version served, e.g.
+	// {docker: "20.10.0", npm: "7.0.0"}, to deprecate clients with broken resume or
+	// auth behavior. Clients without a version are served.
+	MinVersions map[string]string `mapstructure:"min_versions"`
+
+	// UpgradeMessage is appended to the responses of outdated clients, e.g. a link to
+	// upgrade instructions
+	UpgradeMessage string `mapstructure:"upgrade_message"`
+}
+
+// ClientPolicyRule denies the requests of one client type
+type ClientPolicyRule struct {
+	Client   string `mapstructure:"client"`   // docker, containerd, npm, yarn, pnpm, maven or gradle
+	Protocol string `mapstructure:"protocol"` // Only requests of this protocol (default: all)
+
+	// DenyVersions are version constraints such as "<6.14.6" or ">=7.0.0 <7.1.2"
+	// (all terms must match); the rule matches clients whose version satisfies any of
+	// them, and never clients without a version. Empty denies every version.
+	DenyVersions []string `mapstructure:"deny_versions"`
+
+	// Message is appended to the 403 response, e.g. "npm < 6.14.6 is vulnerable to
+	// CVE-2020-15095, please upgrade"
+	Message string `mapstructure:"message"`
+}
+
+// OptionsRequestsConfig contains the handling of OPTIONS requests to protocol paths.
+// Requests are proxied to the backend unless AnswerLocally is set, or they are CORS
+// preflights (with Origin and Access-Control-Request-Method) and CORS is configured.
+// Local answers are 204 No Content with the protocol's methods in Allow, and are
+// not authenticated, as preflights never carry credentials.
+type OptionsRequestsConfig struct {
+	AnswerLocally bool       `mapstructure:"answer_locally"`
+	CORS          CORSConfig `mapstructure:"cors"`
+}
+
+// CORSConfig lets browser-based tools on other origins call protocol endpoints.
+// Preflights from allowed origins are answered with the protocol's methods and
+// AllowedHeaders, and responses to their requests carry Access-Control-Allow-Origin.
+type CORSConfig struct {
+	AllowedOrigins   []string      `mapstructure:"allowed_origins"`   // e.g. https://tools.example.com, or "*" (empty = disabled)
+	AllowedHeaders   []string      `mapstructure:"allowed_headers"`   // Request headers preflights allow (default: Authorization, Accept, Content-Type)
+	ExposedHeaders   []string      `mapstructure:"exposed_headers"`   // Response headers scripts may read, e.g. Docker-Content-Digest
+	AllowCredentials bool          `mapstructure:"allow_credentials"` // Send Access-Control-Allow-Credentials; not with "*"
+	MaxAge           time.Duration `mapstructure:"max_age"`           // How long browsers cache preflights (default: 10m)
+}
+
+// Enabled reports whether any origin is allowed
+func (c *CORSConfig) Enabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+// ShadowLogConfig contains shadow logging of backend traffic. Admins start a window
+// for one backend under /api/v1/admin/shadow-log; until it ends, every request to
+// the backend is written as a JSON line to <dir>/<backend>-<start time>.jsonl with
+// its method, URL, headers, status and timing. Credentials in headers and query
+// strings are redacted; bodies are never logged.
+type ShadowLogConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Dir         string        `mapstructure:"dir"`          // Directory of the log files (created if missing)
+	MaxDuration time.Duration `mapstructure:"max_duration"` // Longest window admins may start (default: 1h)
+}
+
+// VaultConfig contains the HashiCorp Vault server backend credentials are read from.
+// Backend auth username, password, token, header_value and private_key may be given
+// as vault:<path>#<field> references, e.g. vault:secret/data/artifusion/nexus#password
+// (KV v1 and v2 and dynamic secrets engines). References are resolved at startup,
+// which fails if any can't be; afterwards leased secrets are renewed, and re-read
+// once their lease can't be, and other secrets are re-read every RefreshInterval,
+// so rotated credentials are used without a restart. GitHub App private keys are
+// read once, when first used.
+type VaultConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Address         string        `mapstructure:"address"`             // e.g. https://vault.example.com:8200
+	Token           string        `mapstructure:"token" secret:"true"` // Renewed while it is renewable
+	TokenFile       string        `mapstructure:"token_file"`          // Alternative to token, re-read on every request (e.g. a Vault Agent sink)
+	Namespace       string        `mapstructure:"namespace"`           // Vault Enterprise namespace (optional)
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`    // How often secrets are checked (default: 5m)
+}
+
+// IsAdmin reports whether username is a configured admin user
+func (a *AdminConfig) IsAdmin(username string) bool {
+	for _, admin := range a.Users {
+		if strings.EqualFold(admin, username) {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitConfig contains rate limiting configuration
+type RateLimitConfig struct {
+	Enabled         bool    `mapstructure:"enabled"`
+	RequestsPerSec  float64 `mapstructure:"requests_per_sec"`
+	Burst           int     `mapstructure:"burst"`
+	PerUserEnabled  bool    `mapstructure:"per_user_enabled"`
+	PerUserRequests float64 `mapstructure:"per_user_requests"`
+	PerUserBurst    int     `mapstructure:"per_user_burst"`
+
+	// PerUserWriteRequests gives write requests (push, publish, upload, delete) a
+	// per-user bucket of their own, independent of the read limit above, so push
+	// bursts and metadata-heavy installs don't starve each other (0 = writes share
+	// the per-user limit). Requires per_user_enabled.
+	PerUserWriteRequests float64 `mapstructure:"per_user_write_requests"`
+	PerUserWriteBurst    int     `mapstructure:"per_user_write_burst"` // Defaults to twice per_user_write_requests
+
+	// QueueTimeout delays rate-limited requests until a token is available, up to
+	// this long, instead of rejecting them immediately (0 = reject immediately)
+	QueueTimeout time.Duration `mapstructure:"queue_timeout"`
+}
+
+// Default values
 const (
 	DefaultServerPort        = 8080
 	DefaultReadTimeout       = 60 * time.Second
@@ -241,14 +2077,80 @@ const (
 	DefaultWriteBufferSize   = 32 * 1024 // 32 KB
 	DefaultMaxConcurrentReqs = 10000
 
-	DefaultAuthCacheTTL    = 30 * time.Minute
-	DefaultRateLimitBuffer = 100
+	DefaultHSTSMaxAge              = 365 * 24 * time.Hour
+	DefaultContentSecurityPolicy   = "default-src 'none'"
+	DefaultUIContentSecurityPolicy = "default-src 'self'; img-src 'self' data:; style-src 'self' 'unsafe-inline'; frame-ancestors 'self'"
+
+	DefaultAuthCacheTTL    = 30 * time.Minute
+	DefaultRateLimitBuffer = 100
+
+	DefaultValidationBudgetWindow = time.Hour
+
+	DefaultAuthTimeout   = 15 * time.Second
+	DefaultFailOpenGrace = time.Hour
+
+	DefaultMaxIdleConns        = 200
+	DefaultMaxIdleConnsPerHost = 100
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultDialTimeout         = 10 * time.Second
+	DefaultRequestTimeout      = 300 * time.Second
+
+	DefaultDNSFailureCooldown    = 30 * time.Second
+	DefaultKeepAlive             = 30 * time.Second
+	DefaultTLSHandshakeTimeout   = 10 * time.Second
+	DefaultExpectContinueTimeout = 1 * time.Second
+	DefaultMinThroughputWindow   = 30 * time.Second
+	DefaultMaxRedirects          = 10
+
+	DefaultWebUIPathPrefix = "/ui"
+
+	DefaultReplicationWorkers   = 2
+	DefaultReplicationQueueSize = 1000
+
+	DefaultTrashRetention = 7 * 24 * time.Hour
+
+	DefaultPlatformFilterCacheTTL = 24 * time.Hour
+	DefaultMirrorNamespacePrefix  = "mirror"
+
+	DefaultMetadataFlushInterval = 10 * time.Second
+
+	DefaultSignedURLTTL    = 24 * time.Hour
+	DefaultSignedURLMaxTTL = 7 * 24 * time.Hour
+
+	DefaultBrowserSessionTTL = 30 * time.Minute
+	MaxBrowserSessionTTL     = 24 * time.Hour
+	DefaultGitHubWebURL      = "https://github.com"
+
+	DefaultSyntheticCheckInterval = time.Minute
+	DefaultSyntheticCheckTimeout  = 30 * time.Second
+
+	DefaultGRPCPort = 9090
+
+	DefaultCredentialSharingMaxSourceIPs = 10
+	DefaultCredentialSharingWindow       = time.Hour
+
+	DefaultAuthLockoutMaxFailures = 10
+	DefaultAuthLockoutWindow      = 10 * time.Minute
+	DefaultAuthLockoutDuration    = time.Minute
+	DefaultAuthLockoutMaxDuration = time.Hour
+
+	DefaultGitLabAPIURL = "https://gitlab.com/api/v4"
+
+	DefaultLDAPUserFilter     = "(uid=%s)"
+	DefaultLDAPGroupAttribute = "memberOf"
+	DefaultLDAPPoolSize       = 4
+	DefaultLDAPTimeout        = 10 * time.Second
+
+	DefaultOIDCUsernameClaim = "sub"
+	DefaultOIDCGroupsClaim   = "groups"
+	DefaultOIDCJWKSCacheTTL  = time.Hour
+	DefaultOIDCClockSkew     = time.Minute
+
+	DefaultShadowLogMaxDuration = time.Hour
 
-	DefaultMaxIdleConns        = 200
-	DefaultMaxIdleConnsPerHost = 100
-	DefaultIdleConnTimeout     = 90 * time.Second
-	DefaultDialTimeout         = 10 * time.Second
-	DefaultRequestTimeout      = 300 * time.Second
+	DefaultCORSMaxAge = 10 * time.Minute
+
+	DefaultVaultRefreshInterval = 5 * time.Minute
 
 	DefaultCircuitBreakerMaxRequests      = 10
 	DefaultCircuitBreakerInterval         = 60 * time.Second
@@ -291,6 +2193,27 @@ func (c *Config) SetDefaults() {
 	if c.Server.MaxConcurrentReqs == 0 {
 		c.Server.MaxConcurrentReqs = DefaultMaxConcurrentReqs
 	}
+	if c.Server.QueueTimeout > 0 && c.Server.MaxQueuedReqs == 0 {
+		c.Server.MaxQueuedReqs = c.Server.MaxConcurrentReqs
+	}
+
+	// Security header defaults
+	headers := &c.Server.SecurityHeaders
+	if headers.HSTSMaxAge == 0 {
+		headers.HSTSMaxAge = DefaultHSTSMaxAge
+	}
+	if headers.ContentSecurityPolicy == "" {
+		headers.ContentSecurityPolicy = DefaultContentSecurityPolicy
+	}
+	if headers.FrameOptions == "" {
+		headers.FrameOptions = FrameOptionsDeny
+	}
+	if headers.UIContentSecurityPolicy == "" {
+		headers.UIContentSecurityPolicy = DefaultUIContentSecurityPolicy
+	}
+	if headers.UIFrameOptions == "" {
+		headers.UIFrameOptions = FrameOptionsSameOrigin
+	}
 
 	// GitHub defaults
 	if c.GitHub.APIURL == "" {
@@ -302,6 +2225,18 @@ func (c *Config) SetDefaults() {
 	if c.GitHub.RateLimitBuffer == 0 {
 		c.GitHub.RateLimitBuffer = DefaultRateLimitBuffer
 	}
+	if c.GitHub.ValidationBudget > 0 && c.GitHub.ValidationBudgetWindow == 0 {
+		c.GitHub.ValidationBudgetWindow = DefaultValidationBudgetWindow
+	}
+	if c.GitHub.AuthTimeout == 0 {
+		c.GitHub.AuthTimeout = DefaultAuthTimeout
+	}
+	if c.GitHub.FailurePolicy == "" {
+		c.GitHub.FailurePolicy = FailurePolicyClosed
+	}
+	if c.GitHub.FailurePolicy == FailurePolicyOpen && c.GitHub.FailOpenGrace == 0 {
+		c.GitHub.FailOpenGrace = DefaultFailOpenGrace
+	}
 
 	// Rate limit defaults - each field independently checked for resilient partial configuration
 	if c.RateLimit.Enabled {
@@ -319,6 +2254,9 @@ func (c *Config) SetDefaults() {
 		if c.RateLimit.PerUserBurst == 0 {
 			c.RateLimit.PerUserBurst = DefaultPerUserBurst
 		}
+		if c.RateLimit.PerUserWriteRequests > 0 && c.RateLimit.PerUserWriteBurst == 0 {
+			c.RateLimit.PerUserWriteBurst = max(int(2*c.RateLimit.PerUserWriteRequests), 1)
+		}
 	}
 
 	// Protocol-specific backend defaults
@@ -326,8 +2264,80 @@ func (c *Config) SetDefaults() {
 		c.setOCIBackendDefaults(&c.Protocols.OCI.PullBackends[i])
 	}
 	c.setOCIBackendDefaults(&c.Protocols.OCI.PushBackend)
+	if replication := &c.Protocols.OCI.Replication; replication.Enabled {
+		c.setOCIBackendDefaults(&replication.Target)
+		if replication.Workers == 0 {
+			replication.Workers = DefaultReplicationWorkers
+		}
+		if replication.QueueSize == 0 {
+			replication.QueueSize = DefaultReplicationQueueSize
+		}
+	}
+	if c.Protocols.OCI.Trash.Enabled && c.Protocols.OCI.Trash.Retention == 0 {
+		c.Protocols.OCI.Trash.Retention = DefaultTrashRetention
+	}
+	if c.Protocols.OCI.PlatformFilter.Enabled && c.Protocols.OCI.PlatformFilter.CacheTTL == 0 {
+		c.Protocols.OCI.PlatformFilter.CacheTTL = DefaultPlatformFilterCacheTTL
+	}
+	if c.Protocols.OCI.MirrorNamespace.Enabled && c.Protocols.OCI.MirrorNamespace.Prefix == "" {
+		c.Protocols.OCI.MirrorNamespace.Prefix = DefaultMirrorNamespacePrefix
+	}
 	c.setMavenBackendDefaults(&c.Protocols.Maven.Backend)
 	c.setNPMBackendDefaults(&c.Protocols.NPM.Backend)
+	if c.Protocols.Maven.Candidate != nil {
+		c.setMavenBackendDefaults(c.Protocols.Maven.Candidate)
+	}
+	if c.Protocols.NPM.Candidate != nil {
+		c.setNPMBackendDefaults(c.Protocols.NPM.Candidate)
+	}
+	if c.Protocols.Maven.Upstream != nil {
+		c.setMavenBackendDefaults(c.Protocols.Maven.Upstream)
+	}
+	if c.Protocols.NPM.Upstream != nil {
+		c.setNPMBackendDefaults(c.Protocols.NPM.Upstream)
+	}
+	c.setRubyGemsBackendDefaults(&c.Protocols.RubyGems.Backend)
+	c.setHelmBackendDefaults(&c.Protocols.Helm.Backend)
+	c.setAPTBackendDefaults(&c.Protocols.APT.Backend)
+	c.setComposerBackendDefaults(&c.Protocols.Composer.Backend)
+	if c.Protocols.Composer.Upstream != nil {
+		c.setComposerBackendDefaults(c.Protocols.Composer.Upstream)
+	}
+	for i := range c.Protocols.Conda.Channels {
+		channel := &c.Protocols.Conda.Channels[i]
+		// Backends are keyed by name; default to the channel's
+		if channel.Backend.Name == "" {
+			channel.Backend.Name = channel.Name
+		}
+		c.setCondaBackendDefaults(&channel.Backend)
+	}
+	c.setTerraformBackendDefaults(&c.Protocols.Terraform.Backend)
+	c.setAPKBackendDefaults(&c.Protocols.APK.Backend)
+	for i := range c.Protocols.Raw.Repositories {
+		repository := &c.Protocols.Raw.Repositories[i]
+		// Backends are keyed by name; default to one derived from the repository path
+		if repository.Backend.Name == "" {
+			repository.Backend.Name = strings.TrimSuffix("raw"+strings.ReplaceAll(repository.Path, "/", "-"), "-")
+		}
+		c.setRawBackendDefaults(&repository.Backend)
+	}
+	c.setLFSBackendDefaults(&c.Protocols.LFS.Backend)
+	c.setCocoaPodsBackendDefaults(&c.Protocols.CocoaPods.Backend)
+	if c.Protocols.CocoaPods.BinaryBackend != nil {
+		c.setCocoaPodsBackendDefaults(c.Protocols.CocoaPods.BinaryBackend)
+	}
+	c.setHexBackendDefaults(&c.Protocols.Hex.Backend)
+	if c.Protocols.Hex.Upstream != nil {
+		c.setHexBackendDefaults(c.Protocols.Hex.Upstream)
+	}
+	if c.Protocols.Hex.PublishBackend != nil {
+		c.setHexBackendDefaults(c.Protocols.Hex.PublishBackend)
+	}
+	c.setPubBackendDefaults(&c.Protocols.Pub.Backend)
+	c.setVagrantBackendDefaults(&c.Protocols.Vagrant.Backend)
+	for i := range c.Protocols.Vagrant.DownloadBackends {
+		c.setVagrantBackendDefaults(&c.Protocols.Vagrant.DownloadBackends[i])
+	}
 
 	// Maven path prefix default
 	if c.Protocols.Maven.PathPrefix == "" {
@@ -339,6 +2349,71 @@ func (c *Config) SetDefaults() {
 		c.Protocols.NPM.PathPrefix = "/npm"
 	}
 
+	// RubyGems path prefix default
+	if c.Protocols.RubyGems.PathPrefix == "" {
+		c.Protocols.RubyGems.PathPrefix = "/rubygems"
+	}
+
+	// Helm path prefix default
+	if c.Protocols.Helm.PathPrefix == "" {
+		c.Protocols.Helm.PathPrefix = "/helm"
+	}
+
+	// APT path prefix default
+	if c.Protocols.APT.PathPrefix == "" {
+		c.Protocols.APT.PathPrefix = "/apt"
+	}
+
+	// Composer path prefix default
+	if c.Protocols.Composer.PathPrefix == "" {
+		c.Protocols.Composer.PathPrefix = "/composer"
+	}
+
+	// Conda path prefix default
+	if c.Protocols.Conda.PathPrefix == "" {
+		c.Protocols.Conda.PathPrefix = "/conda"
+	}
+
+	// Terraform path prefix default
+	if c.Protocols.Terraform.PathPrefix == "" {
+		c.Protocols.Terraform.PathPrefix = "/terraform"
+	}
+
+	// APK path prefix default
+	if c.Protocols.APK.PathPrefix == "" {
+		c.Protocols.APK.PathPrefix = "/apk"
+	}
+
+	// Raw path prefix default
+	if c.Protocols.Raw.PathPrefix == "" {
+		c.Protocols.Raw.PathPrefix = "/raw"
+	}
+
+	// LFS path prefix default
+	if c.Protocols.LFS.PathPrefix == "" {
+		c.Protocols.LFS.PathPrefix = "/lfs"
+	}
+
+	// CocoaPods path prefix default
+	if c.Protocols.CocoaPods.PathPrefix == "" {
+		c.Protocols.CocoaPods.PathPrefix = "/cocoapods"
+	}
+
+	// Hex path prefix default
+	if c.Protocols.Hex.PathPrefix == "" {
+		c.Protocols.Hex.PathPrefix = "/hex"
+	}
+
+	// Pub path prefix default
+	if c.Protocols.Pub.PathPrefix == "" {
+		c.Protocols.Pub.PathPrefix = "/pub"
+	}
+
+	// Vagrant path prefix default
+	if c.Protocols.Vagrant.PathPrefix == "" {
+		c.Protocols.Vagrant.PathPrefix = "/vagrant"
+	}
+
 	// Logging defaults
 	if c.Logging.Level == "" {
 		c.Logging.Level = "info"
@@ -351,12 +2426,160 @@ func (c *Config) SetDefaults() {
 	if c.Metrics.Path == "" {
 		c.Metrics.Path = "/metrics"
 	}
+
+	// Web UI defaults
+	if c.WebUI.Enabled {
+		if c.WebUI.PathPrefix == "" {
+			c.WebUI.PathPrefix = DefaultWebUIPathPrefix
+		}
+		for i := range c.WebUI.Mounts {
+			if c.WebUI.Mounts[i].Home == "" {
+				c.WebUI.Mounts[i].Home = "/"
+			}
+		}
+	}
+
+	// Metadata defaults
+	if c.Metadata.Enabled && c.Metadata.FlushInterval == 0 {
+		c.Metadata.FlushInterval = DefaultMetadataFlushInterval
+	}
+
+	// Signed URL defaults
+	if c.SignedURLs.Enabled {
+		if c.SignedURLs.DefaultTTL == 0 {
+			c.SignedURLs.DefaultTTL = DefaultSignedURLTTL
+		}
+		if c.SignedURLs.MaxTTL == 0 {
+			c.SignedURLs.MaxTTL = DefaultSignedURLMaxTTL
+		}
+	}
+
+	// Browser session defaults
+	if c.BrowserSessions.Enabled {
+		if c.BrowserSessions.TTL == 0 {
+			c.BrowserSessions.TTL = DefaultBrowserSessionTTL
+		}
+		if c.BrowserSessions.GitHubURL == "" {
+			c.BrowserSessions.GitHubURL = DefaultGitHubWebURL
+		}
+		if len(c.BrowserSessions.Scopes) == 0 {
+			c.BrowserSessions.Scopes = []string{"read:org"}
+		}
+	}
+
+	// Synthetic check defaults
+	if c.SyntheticChecks.Enabled {
+		if c.SyntheticChecks.Interval == 0 {
+			c.SyntheticChecks.Interval = DefaultSyntheticCheckInterval
+		}
+		if c.SyntheticChecks.Timeout == 0 {
+			c.SyntheticChecks.Timeout = DefaultSyntheticCheckTimeout
+		}
+		if c.SyntheticChecks.URL == "" {
+			c.SyntheticChecks.URL = "http://127.0.0.1:" + strconv.Itoa(c.Server.Port)
+		}
+	}
+
+	// gRPC defaults
+	if c.GRPC.Enabled && c.GRPC.Port == 0 {
+		c.GRPC.Port = DefaultGRPCPort
+	}
+
+	// Credential sharing defaults
+	if sharing := &c.CredentialSharing; sharing.Enabled {
+		if sharing.MaxSourceIPs == 0 {
+			sharing.MaxSourceIPs = DefaultCredentialSharingMaxSourceIPs
+		}
+		if sharing.Window == 0 {
+			sharing.Window = DefaultCredentialSharingWindow
+		}
+	}
+
+	// Auth lockout defaults
+	if lockout := &c.AuthLockout; lockout.Enabled {
+		if lockout.MaxFailures == 0 {
+			lockout.MaxFailures = DefaultAuthLockoutMaxFailures
+		}
+		if lockout.Window == 0 {
+			lockout.Window = DefaultAuthLockoutWindow
+		}
+		if lockout.Duration == 0 {
+			lockout.Duration = DefaultAuthLockoutDuration
+		}
+		if lockout.MaxDuration == 0 {
+			lockout.MaxDuration = max(DefaultAuthLockoutMaxDuration, lockout.Duration)
+		}
+	}
+
+	// GitLab defaults
+	if c.GitLab.Enabled && c.GitLab.APIURL == "" {
+		c.GitLab.APIURL = DefaultGitLabAPIURL
+	}
+
+	// LDAP defaults
+	if c.LDAP.Enabled {
+		if c.LDAP.UserFilter == "" {
+			c.LDAP.UserFilter = DefaultLDAPUserFilter
+		}
+		if c.LDAP.GroupAttribute == "" {
+			c.LDAP.GroupAttribute = DefaultLDAPGroupAttribute
+		}
+		if c.LDAP.PoolSize == 0 {
+			c.LDAP.PoolSize = DefaultLDAPPoolSize
+		}
+		if c.LDAP.Timeout == 0 {
+			c.LDAP.Timeout = DefaultLDAPTimeout
+		}
+	}
+
+	// Client certificate defaults
+	if c.MTLS.Enabled && c.MTLS.UsernameFrom == "" {
+		c.MTLS.UsernameFrom = MTLSUsernameFromSAN
+	}
+
+	// CORS defaults
+	if c.OptionsRequests.CORS.Enabled() {
+		if len(c.OptionsRequests.CORS.AllowedHeaders) == 0 {
+			c.OptionsRequests.CORS.AllowedHeaders = []string{"Authorization", "Accept", "Content-Type"}
+		}
+		if c.OptionsRequests.CORS.MaxAge == 0 {
+			c.OptionsRequests.CORS.MaxAge = DefaultCORSMaxAge
+		}
+	}
+
+	// Shadow log defaults
+	if c.ShadowLog.Enabled && c.ShadowLog.MaxDuration == 0 {
+		c.ShadowLog.MaxDuration = DefaultShadowLogMaxDuration
+	}
+
+	// Vault defaults
+	if c.Vault.Enabled && c.Vault.RefreshInterval == 0 {
+		c.Vault.RefreshInterval = DefaultVaultRefreshInterval
+	}
+
+	// OIDC identity provider defaults
+	for i := range c.Identity.OIDC {
+		oidc := &c.Identity.OIDC[i]
+		if oidc.UsernameClaim == "" {
+			oidc.UsernameClaim = DefaultOIDCUsernameClaim
+		}
+		if oidc.GroupsClaim == "" {
+			oidc.GroupsClaim = DefaultOIDCGroupsClaim
+		}
+		if oidc.JWKSCacheTTL == 0 {
+			oidc.JWKSCacheTTL = DefaultOIDCJWKSCacheTTL
+		}
+		if oidc.ClockSkew == 0 {
+			oidc.ClockSkew = DefaultOIDCClockSkew
+		}
+	}
 }
 
 // backendDefaults is an interface for backend configs that need default values
 type backendDefaults interface {
 	getConnectionSettings() *backendConnectionSettings
 	getCircuitBreaker() *CircuitBreakerConfig
+	GetTransport() *TransportConfig
 }
 
 // backendConnectionSettings holds pointers to connection-related fields
@@ -417,6 +2640,214 @@ func (n *NPMBackendConfig) getCircuitBreaker() *CircuitBreakerConfig {
 	return &n.CircuitBreaker
 }
 
+// getConnectionSettings returns pointers to RubyGemsBackendConfig connection fields
+func (g *RubyGemsBackendConfig) getConnectionSettings() *backendConnectionSettings {
+	return &backendConnectionSettings{
+		MaxIdleConns:        &g.MaxIdleConns,
+		MaxIdleConnsPerHost: &g.MaxIdleConnsPerHost,
+		IdleConnTimeout:     &g.IdleConnTimeout,
+		DialTimeout:         &g.DialTimeout,
+		RequestTimeout:      &g.RequestTimeout,
+	}
+}
+
+// getCircuitBreaker returns pointer to RubyGemsBackendConfig circuit breaker
+func (g *RubyGemsBackendConfig) getCircuitBreaker() *CircuitBreakerConfig {
+	return &g.CircuitBreaker
+}
+
+// getConnectionSettings returns pointers to HelmBackendConfig connection fields
+func (h *HelmBackendConfig) getConnectionSettings() *backendConnectionSettings {
+	return &backendConnectionSettings{
+		MaxIdleConns:        &h.MaxIdleConns,
+		MaxIdleConnsPerHost: &h.MaxIdleConnsPerHost,
+		IdleConnTimeout:     &h.IdleConnTimeout,
+		DialTimeout:         &h.DialTimeout,
+		RequestTimeout:      &h.RequestTimeout,
+	}
+}
+
+// getCircuitBreaker returns pointer to HelmBackendConfig circuit breaker
+func (h *HelmBackendConfig) getCircuitBreaker() *CircuitBreakerConfig {
+	return &h.CircuitBreaker
+}
+
+// getConnectionSettings returns pointers to APTBackendConfig connection fields
+func (a *APTBackendConfig) getConnectionSettings() *backendConnectionSettings {
+	return &backendConnectionSettings{
+		MaxIdleConns:        &a.MaxIdleConns,
+		MaxIdleConnsPerHost: &a.MaxIdleConnsPerHost,
+		IdleConnTimeout:     &a.IdleConnTimeout,
+		DialTimeout:         &a.DialTimeout,
+		RequestTimeout:      &a.RequestTimeout,
+	}
+}
+
+// getCircuitBreaker returns pointer to APTBackendConfig circuit breaker
+func (a *APTBackendConfig) getCircuitBreaker() *CircuitBreakerConfig {
+	return &a.CircuitBreaker
+}
+
+// getConnectionSettings returns pointers to ComposerBackendConfig connection fields
+func (c *ComposerBackendConfig) getConnectionSettings() *backendConnectionSettings {
+	return &backendConnectionSettings{
+		MaxIdleConns:        &c.MaxIdleConns,
+		MaxIdleConnsPerHost: &c.MaxIdleConnsPerHost,
+		IdleConnTimeout:     &c.IdleConnTimeout,
+		DialTimeout:         &c.DialTimeout,
+		RequestTimeout:      &c.RequestTimeout,
+	}
+}
+
+// getCircuitBreaker returns pointer to ComposerBackendConfig circuit breaker
+func (c *ComposerBackendConfig) getCircuitBreaker() *CircuitBreakerConfig {
+	return &c.CircuitBreaker
+}
+
+// getConnectionSettings returns pointers to CondaBackendConfig connection fields
+func (c *CondaBackendConfig) getConnectionSettings() *backendConnectionSettings {
+	return &backendConnectionSettings{
+		MaxIdleConns:        &c.MaxIdleConns,
+		MaxIdleConnsPerHost: &c.MaxIdleConnsPerHost,
+		IdleConnTimeout:     &c.IdleConnTimeout,
+		DialTimeout:         &c.DialTimeout,
+		RequestTimeout:      &c.RequestTimeout,
+	}
+}
+
+// getCircuitBreaker returns pointer to CondaBackendConfig circuit breaker
+func (c *CondaBackendConfig) getCircuitBreaker() *CircuitBreakerConfig {
+	return &c.CircuitBreaker
+}
+
+// getConnectionSettings returns pointers to TerraformBackendConfig connection fields
+func (t *TerraformBackendConfig) getConnectionSettings() *backendConnectionSettings {
+	return &backendConnectionSettings{
+		MaxIdleConns:        &t.MaxIdleConns,
+		MaxIdleConnsPerHost: &t.MaxIdleConnsPerHost,
+		IdleConnTimeout:     &t.IdleConnTimeout,
+		DialTimeout:         &t.DialTimeout,
+		RequestTimeout:      &t.RequestTimeout,
+	}
+}
+
+// getCircuitBreaker returns pointer to TerraformBackendConfig circuit breaker
+func (t *TerraformBackendConfig) getCircuitBreaker() *CircuitBreakerConfig {
+	return &t.CircuitBreaker
+}
+
+// getConnectionSettings returns pointers to APKBackendConfig connection fields
+func (a *APKBackendConfig) getConnectionSettings() *backendConnectionSettings {
+	return &backendConnectionSettings{
+		MaxIdleConns:        &a.MaxIdleConns,
+		MaxIdleConnsPerHost: &a.MaxIdleConnsPerHost,
+		IdleConnTimeout:     &a.IdleConnTimeout,
+		DialTimeout:         &a.DialTimeout,
+		RequestTimeout:      &a.RequestTimeout,
+	}
+}
+
+// getCircuitBreaker returns pointer to APKBackendConfig circuit breaker
+func (a *APKBackendConfig) getCircuitBreaker() *CircuitBreakerConfig {
+	return &a.CircuitBreaker
+}
+
+// getConnectionSettings returns pointers to CocoaPodsBackendConfig connection fields
+func (c *CocoaPodsBackendConfig) getConnectionSettings() *backendConnectionSettings {
+	return &backendConnectionSettings{
+		MaxIdleConns:        &c.MaxIdleConns,
+		MaxIdleConnsPerHost: &c.MaxIdleConnsPerHost,
+		IdleConnTimeout:     &c.IdleConnTimeout,
+		DialTimeout:         &c.DialTimeout,
+		RequestTimeout:      &c.RequestTimeout,
+	}
+}
+
+// getCircuitBreaker returns pointer to CocoaPodsBackendConfig circuit breaker
+func (c *CocoaPodsBackendConfig) getCircuitBreaker() *CircuitBreakerConfig {
+	return &c.CircuitBreaker
+}
+
+// getConnectionSettings returns pointers to HexBackendConfig connection fields
+func (h *HexBackendConfig) getConnectionSettings() *backendConnectionSettings {
+	return &backendConnectionSettings{
+		MaxIdleConns:        &h.MaxIdleConns,
+		MaxIdleConnsPerHost: &h.MaxIdleConnsPerHost,
+		IdleConnTimeout:     &h.IdleConnTimeout,
+		DialTimeout:         &h.DialTimeout,
+		RequestTimeout:      &h.RequestTimeout,
+	}
+}
+
+// getCircuitBreaker returns pointer to HexBackendConfig circuit breaker
+func (h *HexBackendConfig) getCircuitBreaker() *CircuitBreakerConfig {
+	return &h.CircuitBreaker
+}
+
+// getConnectionSettings returns pointers to PubBackendConfig connection fields
+func (p *PubBackendConfig) getConnectionSettings() *backendConnectionSettings {
+	return &backendConnectionSettings{
+		MaxIdleConns:        &p.MaxIdleConns,
+		MaxIdleConnsPerHost: &p.MaxIdleConnsPerHost,
+		IdleConnTimeout:     &p.IdleConnTimeout,
+		DialTimeout:         &p.DialTimeout,
+		RequestTimeout:      &p.RequestTimeout,
+	}
+}
+
+// getCircuitBreaker returns pointer to PubBackendConfig circuit breaker
+func (p *PubBackendConfig) getCircuitBreaker() *CircuitBreakerConfig {
+	return &p.CircuitBreaker
+}
+
+// getConnectionSettings returns pointers to VagrantBackendConfig connection fields
+func (v *VagrantBackendConfig) getConnectionSettings() *backendConnectionSettings {
+	return &backendConnectionSettings{
+		MaxIdleConns:        &v.MaxIdleConns,
+		MaxIdleConnsPerHost: &v.MaxIdleConnsPerHost,
+		IdleConnTimeout:     &v.IdleConnTimeout,
+		DialTimeout:         &v.DialTimeout,
+		RequestTimeout:      &v.RequestTimeout,
+	}
+}
+
+// getCircuitBreaker returns pointer to VagrantBackendConfig circuit breaker
+func (v *VagrantBackendConfig) getCircuitBreaker() *CircuitBreakerConfig {
+	return &v.CircuitBreaker
+}
+
+// getConnectionSettings returns pointers to LFSBackendConfig connection fields
+func (l *LFSBackendConfig) getConnectionSettings() *backendConnectionSettings {
+	return &backendConnectionSettings{
+		MaxIdleConns:        &l.MaxIdleConns,
+		MaxIdleConnsPerHost: &l.MaxIdleConnsPerHost,
+		IdleConnTimeout:     &l.IdleConnTimeout,
+		DialTimeout:         &l.DialTimeout,
+		RequestTimeout:      &l.RequestTimeout,
+	}
+}
+
+// getCircuitBreaker returns pointer to LFSBackendConfig circuit breaker
+func (l *LFSBackendConfig) getCircuitBreaker() *CircuitBreakerConfig {
+	return &l.CircuitBreaker
+}
+
+// getConnectionSettings returns pointers to RawBackendConfig connection fields
+func (b *RawBackendConfig) getConnectionSettings() *backendConnectionSettings {
+	return &backendConnectionSettings{
+		MaxIdleConns:        &b.MaxIdleConns,
+		MaxIdleConnsPerHost: &b.MaxIdleConnsPerHost,
+		IdleConnTimeout:     &b.IdleConnTimeout,
+		DialTimeout:         &b.DialTimeout,
+		RequestTimeout:      &b.RequestTimeout,
+	}
+}
+
+// getCircuitBreaker returns pointer to RawBackendConfig circuit breaker
+func (b *RawBackendConfig) getCircuitBreaker() *CircuitBreakerConfig {
+	return &b.CircuitBreaker
+}
+
 // setBackendDefaultsCommon sets default values for any backend configuration
 // This eliminates code duplication across protocol-specific backend defaults
 func (c *Config) setBackendDefaultsCommon(backend backendDefaults) {
@@ -439,6 +2870,30 @@ func (c *Config) setBackendDefaultsCommon(backend backendDefaults) {
 		*settings.RequestTimeout = DefaultRequestTimeout
 	}
 
+	// Transport defaults
+	transport := backend.GetTransport()
+	if transport.DNSRefreshInterval > 0 && transport.DNSFailureCooldown == 0 {
+		transport.DNSFailureCooldown = DefaultDNSFailureCooldown
+	}
+	if transport.KeepAlive == 0 {
+		transport.KeepAlive = DefaultKeepAlive
+	}
+	if transport.TLSHandshakeTimeout == 0 {
+		transport.TLSHandshakeTimeout = DefaultTLSHandshakeTimeout
+	}
+	if transport.ExpectContinueTimeout == 0 {
+		transport.ExpectContinueTimeout = DefaultExpectContinueTimeout
+	}
+	if transport.MinBytesPerSec > 0 && transport.MinThroughputWindow == 0 {
+		transport.MinThroughputWindow = DefaultMinThroughputWindow
+	}
+	if transport.Redirects == "" {
+		transport.Redirects = RedirectsPassThrough
+	}
+	if transport.Redirects == RedirectsFollow && transport.MaxRedirects == 0 {
+		transport.MaxRedirects = DefaultMaxRedirects
+	}
+
 	// Circuit breaker defaults
 	cb := backend.getCircuitBreaker()
 	if cb.Enabled {
@@ -454,6 +2909,9 @@ func (c *Config) setBackendDefaultsCommon(backend backendDefaults) {
 		if cb.FailureThreshold == 0 {
 			cb.FailureThreshold = DefaultCircuitBreakerFailureThreshold
 		}
+		if cb.Type == "" {
+			cb.Type = CircuitBreakerFailureRatio
+		}
 	}
 }
 
@@ -471,3 +2929,96 @@ func (c *Config) setMavenBackendDefaults(backend *MavenBackendConfig) {
 func (c *Config) setNPMBackendDefaults(backend *NPMBackendConfig) {
 	c.setBackendDefaultsCommon(backend)
 }
+
+// setRubyGemsBackendDefaults sets default values for RubyGems backend configuration
+func (c *Config) setRubyGemsBackendDefaults(backend *RubyGemsBackendConfig) {
+	c.setBackendDefaultsCommon(backend)
+}
+
+// setHelmBackendDefaults sets default values for Helm backend configuration
+func (c *Config) setHelmBackendDefaults(backend *HelmBackendConfig) {
+	c.setBackendDefaultsCommon(backend)
+}
+
+// setAPTBackendDefaults sets default values for APT backend configuration
+func (c *Config) setAPTBackendDefaults(backend *APTBackendConfig) {
+	c.setBackendDefaultsCommon(backend)
+}
+
+// setComposerBackendDefaults sets default values for Composer backend configuration
+func (c *Config) setComposerBackendDefaults(backend *ComposerBackendConfig) {
+	c.setBackendDefaultsCommon(backend)
+}
+
+// setCondaBackendDefaults sets default values for Conda channel backend configuration
+func (c *Config) setCondaBackendDefaults(backend *CondaBackendConfig) {
+	c.setBackendDefaultsCommon(backend)
+}
+
+// setTerraformBackendDefaults sets default values for Terraform backend configuration
+func (c *Config) setTerraformBackendDefaults(backend *TerraformBackendConfig) {
+	c.setBackendDefaultsCommon(backend)
+
+	if backend.ModulesPath == "" {
+		backend.ModulesPath = "/v1/modules/"
+	}
+	if backend.ProvidersPath == "" {
+		backend.ProvidersPath = "/v1/providers/"
+	}
+}
+
+// setAPKBackendDefaults sets default values for APK backend configuration
+func (c *Config) setAPKBackendDefaults(backend *APKBackendConfig) {
+	c.setBackendDefaultsCommon(backend)
+}
+
+// setRawBackendDefaults sets default values for raw repository backend configuration
+func (c *Config) setRawBackendDefaults(backend *RawBackendConfig) {
+	c.setBackendDefaultsCommon(backend)
+}
+
+// setLFSBackendDefaults sets default values for Git LFS backend configuration
+func (c *Config) setLFSBackendDefaults(backend *LFSBackendConfig) {
+	c.setBackendDefaultsCommon(backend)
+}
+
+// setCocoaPodsBackendDefaults sets default values for CocoaPods backend configuration
+func (c *Config) setCocoaPodsBackendDefaults(backend *CocoaPodsBackendConfig) {
+	c.setBackendDefaultsCommon(backend)
+}
+
+// setHexBackendDefaults sets default values for Hex backend configuration
+func (c *Config) setHexBackendDefaults(backend *HexBackendConfig) {
+	c.setBackendDefaultsCommon(backend)
+}
+
+// setPubBackendDefaults sets default values for Dart pub backend configuration
+func (c *Config) setPubBackendDefaults(backend *PubBackendConfig) {
+	c.setBackendDefaultsCommon(backend)
+}
+
+// setVagrantBackendDefaults sets default values for Vagrant backend configuration
+func (c *Config) setVagrantBackendDefaults(backend *VagrantBackendConfig) {
+	c.setBackendDefaultsCommon(backend)
+}
+
+// RoutingTeams returns the deduplicated GitHub team slugs referenced by backend
+// team scopes. Membership in these teams is resolved during authentication so
+// handlers can route by team without extra GitHub API calls.
+func (c *Config) RoutingTeams() []string {
+	seen := make(map[string]bool)
+	var teams []string
+
+	if c.Protocols.OCI.Enabled {
+		for _, backend := range c.Protocols.OCI.PullBackends {
+			for _, team := range backend.Teams {
+				if !seen[team] {
+					seen[team] = true
+					teams = append(teams, team)
+				}
+			}
+		}
+	}
+
+	return teams
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 )
 
 // TestSetDefaults_RateLimitBurst tests that burst defaults are applied independently
@@ -161,3 +162,143 @@ func TestSetDefaults_RateLimitRequestsPerSec(t *testing.T) {
 		})
 	}
 }
+
+// TestSetDefaults_MaxQueuedRequests tests that the concurrency queue length defaults
+// to max_concurrent_requests only when queueing is enabled
+func TestSetDefaults_MaxQueuedRequests(t *testing.T) {
+	tests := []struct {
+		name          string
+		server        ServerConfig
+		wantMaxQueued int
+	}{
+		{
+			name:          "queue disabled leaves max_queued_requests unset",
+			server:        ServerConfig{MaxConcurrentReqs: 50},
+			wantMaxQueued: 0,
+		},
+		{
+			name:          "queue enabled defaults to max_concurrent_requests",
+			server:        ServerConfig{MaxConcurrentReqs: 50, QueueTimeout: 5 * time.Second},
+			wantMaxQueued: 50,
+		},
+		{
+			name:          "explicit max_queued_requests is kept",
+			server:        ServerConfig{MaxConcurrentReqs: 50, QueueTimeout: 5 * time.Second, MaxQueuedReqs: 10},
+			wantMaxQueued: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Server: tt.server}
+			cfg.SetDefaults()
+
+			if cfg.Server.MaxQueuedReqs != tt.wantMaxQueued {
+				t.Errorf("MaxQueuedReqs = %d, want %d", cfg.Server.MaxQueuedReqs, tt.wantMaxQueued)
+			}
+		})
+	}
+}
+
+// TestSetDefaults_DNSFailureCooldown tests that the cooldown only defaults when DNS
+// refresh is enabled
+func TestSetDefaults_DNSFailureCooldown(t *testing.T) {
+	tests := []struct {
+		name         string
+		transport    TransportConfig
+		wantCooldown time.Duration
+	}{
+		{
+			name:         "dns refresh disabled",
+			transport:    TransportConfig{},
+			wantCooldown: 0,
+		},
+		{
+			name:         "dns refresh enabled without cooldown",
+			transport:    TransportConfig{DNSRefreshInterval: time.Minute},
+			wantCooldown: DefaultDNSFailureCooldown,
+		},
+		{
+			name:         "custom cooldown is kept",
+			transport:    TransportConfig{DNSRefreshInterval: time.Minute, DNSFailureCooldown: 5 * time.Second},
+			wantCooldown: 5 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{}
+			cfg.Protocols.NPM.Backend.Transport = tt.transport
+			cfg.SetDefaults()
+
+			if got := cfg.Protocols.NPM.Backend.Transport.DNSFailureCooldown; got != tt.wantCooldown {
+				t.Errorf("DNSFailureCooldown = %v, want %v", got, tt.wantCooldown)
+			}
+		})
+	}
+}
+
+// TestSetDefaults_Transport tests that transport tuning defaults match the previously
+// hardcoded values and that configured values are kept
+func TestSetDefaults_Transport(t *testing.T) {
+	cfg := Config{}
+	cfg.Protocols.OCI.PullBackends = []OCIBackendConfig{{}}
+	cfg.Protocols.Maven.Backend.Transport = TransportConfig{
+		KeepAlive:             -1,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ExpectContinueTimeout: 2 * time.Second,
+		MinBytesPerSec:        1024,
+		Redirects:             RedirectsFollow,
+	}
+	cfg.SetDefaults()
+
+	defaulted := cfg.Protocols.OCI.PullBackends[0].Transport
+	if defaulted.KeepAlive != DefaultKeepAlive {
+		t.Errorf("KeepAlive = %v, want %v", defaulted.KeepAlive, DefaultKeepAlive)
+	}
+	if defaulted.TLSHandshakeTimeout != DefaultTLSHandshakeTimeout {
+		t.Errorf("TLSHandshakeTimeout = %v, want %v", defaulted.TLSHandshakeTimeout, DefaultTLSHandshakeTimeout)
+	}
+	if defaulted.ExpectContinueTimeout != DefaultExpectContinueTimeout {
+		t.Errorf("ExpectContinueTimeout = %v, want %v", defaulted.ExpectContinueTimeout, DefaultExpectContinueTimeout)
+	}
+	if defaulted.MinThroughputWindow != 0 {
+		t.Errorf("MinThroughputWindow = %v, want 0 while the throughput check is disabled", defaulted.MinThroughputWindow)
+	}
+	if defaulted.Redirects != RedirectsPassThrough || defaulted.MaxRedirects != 0 {
+		t.Errorf("Redirects = %q, MaxRedirects = %d, want %q, 0", defaulted.Redirects, defaulted.MaxRedirects, RedirectsPassThrough)
+	}
+
+	configured := cfg.Protocols.Maven.Backend.Transport
+	if configured.KeepAlive != -1 {
+		t.Errorf("KeepAlive = %v, want -1 (disabled) to be kept", configured.KeepAlive)
+	}
+	if configured.TLSHandshakeTimeout != 5*time.Second {
+		t.Errorf("TLSHandshakeTimeout = %v, want 5s to be kept", configured.TLSHandshakeTimeout)
+	}
+	if configured.ExpectContinueTimeout != 2*time.Second {
+		t.Errorf("ExpectContinueTimeout = %v, want 2s to be kept", configured.ExpectContinueTimeout)
+	}
+	if configured.MinThroughputWindow != DefaultMinThroughputWindow {
+		t.Errorf("MinThroughputWindow = %v, want %v", configured.MinThroughputWindow, DefaultMinThroughputWindow)
+	}
+	if configured.MaxRedirects != DefaultMaxRedirects {
+		t.Errorf("MaxRedirects = %d, want %d", configured.MaxRedirects, DefaultMaxRedirects)
+	}
+}
+
+// TestSetDefaults_PerUserWriteBurst tests that the write burst defaults to twice the
+// write rate, and only when a write limit is configured
+func TestSetDefaults_PerUserWriteBurst(t *testing.T) {
+	cfg := Config{RateLimit: RateLimitConfig{PerUserEnabled: true, PerUserWriteRequests: 20}}
+	cfg.SetDefaults()
+	if cfg.RateLimit.PerUserWriteBurst != 40 {
+		t.Errorf("PerUserWriteBurst = %d, want 40", cfg.RateLimit.PerUserWriteBurst)
+	}
+
+	cfg = Config{RateLimit: RateLimitConfig{PerUserEnabled: true}}
+	cfg.SetDefaults()
+	if cfg.RateLimit.PerUserWriteBurst != 0 {
+		t.Errorf("PerUserWriteBurst = %d, want 0 without a write limit", cfg.RateLimit.PerUserWriteBurst)
+	}
+}
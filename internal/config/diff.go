@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RedactedValue replaces the value of secret fields (tagged `secret:"true"`)
+const RedactedValue = "[REDACTED]"
+
+// Change is a single configuration key whose value differs between two configurations.
+// Secret values are redacted, but a changed secret is still reported.
+type Change struct {
+	Key string `json:"key"` // Dotted path using config file names (e.g. "github.auth_cache_ttl")
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// flatValue is a flattened configuration value
+type flatValue struct {
+	raw    string
+	secret bool
+}
+
+// display returns the value safe for logs and API responses
+func (v flatValue) display() string {
+	if v.secret && v.raw != "" {
+		return RedactedValue
+	}
+	return v.raw
+}
+
+// Diff returns the keys whose effective values differ between old and new,
+// sorted by key. Keys only present in one of them (e.g. an added backend) are
+// reported with an empty value on the other side.
+func Diff(old, new *Config) []Change {
+	oldValues := old.flatten()
+	newValues := new.flatten()
+
+	var changes []Change
+	for key, oldValue := range oldValues {
+		newValue, ok := newValues[key]
+		if ok && newValue.raw == oldValue.raw {
+			continue
+		}
+		changes = append(changes, Change{Key: key, Old: oldValue.display(), New: newValue.display()})
+	}
+	for key, newValue := range newValues {
+		if _, ok := oldValues[key]; !ok {
+			changes = append(changes, Change{Key: key, New: newValue.display()})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// flatten returns the configuration as dotted keys mapped to their values
+func (c *Config) flatten() map[string]flatValue {
+	values := make(map[string]flatValue)
+	flattenValue(reflect.ValueOf(c).Elem(), "", false, values)
+	return values
+}
+
+// flattenValue adds v and its nested fields to values under prefix
+func flattenValue(v reflect.Value, prefix string, secret bool, values map[string]flatValue) {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		values[prefix] = flatValue{raw: time.Duration(v.Int()).String(), secret: secret}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+		flattenValue(v.Elem(), prefix, secret, values)
+
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name := field.Tag.Get("mapstructure")
+			if name == "" || name == "-" {
+				continue
+			}
+			key := name
+			if prefix != "" {
+				key = prefix + "." + name
+			}
+			flattenValue(v.Field(i), key, secret || field.Tag.Get("secret") == "true", values)
+		}
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			for i := 0; i < v.Len(); i++ {
+				flattenValue(v.Index(i), fmt.Sprintf("%s[%d]", prefix, i), secret, values)
+			}
+			return
+		}
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		values[prefix] = flatValue{raw: "[" + strings.Join(items, ", ") + "]", secret: secret}
+
+	default:
+		values[prefix] = flatValue{raw: fmt.Sprint(v.Interface()), secret: secret}
+	}
+}
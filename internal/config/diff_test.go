@@ -0,0 +1,87 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	base := func() *Config {
+		return &Config{
+			GitHub: GitHubConfig{
+				APIURL:       "https://api.github.com",
+				AuthCacheTTL: 30 * time.Minute,
+			},
+			Protocols: ProtocolsConfig{
+				OCI: OCIConfig{
+					PullBackends: []OCIBackendConfig{
+						{Name: "ghcr", URL: "https://ghcr.io", Auth: &AuthConfig{Type: "bearer", Token: "old-token"}},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []Change
+	}{
+		{
+			name:   "no changes",
+			modify: func(c *Config) {},
+			want:   nil,
+		},
+		{
+			name:   "duration change",
+			modify: func(c *Config) { c.GitHub.AuthCacheTTL = time.Hour },
+			want:   []Change{{Key: "github.auth_cache_ttl", Old: "30m0s", New: "1h0m0s"}},
+		},
+		{
+			name:   "secret change is redacted",
+			modify: func(c *Config) { c.Protocols.OCI.PullBackends[0].Auth.Token = "new-token" },
+			want:   []Change{{Key: "protocols.oci.pull_backends[0].auth.token", Old: RedactedValue, New: RedactedValue}},
+		},
+		{
+			name:   "slice change",
+			modify: func(c *Config) { c.GitHub.RequiredTeams = []string{"platform"} },
+			want:   []Change{{Key: "github.required_teams", Old: "[]", New: "[platform]"}},
+		},
+		{
+			name: "removed secret",
+			modify: func(c *Config) {
+				c.Protocols.OCI.PullBackends[0].Auth = nil
+			},
+			want: []Change{
+				{Key: "protocols.oci.pull_backends[0].auth.app_id", Old: "0"},
+				{Key: "protocols.oci.pull_backends[0].auth.github_api_url", Old: ""},
+				{Key: "protocols.oci.pull_backends[0].auth.header_name", Old: ""},
+				{Key: "protocols.oci.pull_backends[0].auth.header_value", Old: ""},
+				{Key: "protocols.oci.pull_backends[0].auth.installation_id", Old: "0"},
+				{Key: "protocols.oci.pull_backends[0].auth.password", Old: ""},
+				{Key: "protocols.oci.pull_backends[0].auth.private_key", Old: ""},
+				{Key: "protocols.oci.pull_backends[0].auth.private_key_file", Old: ""},
+				{Key: "protocols.oci.pull_backends[0].auth.token", Old: RedactedValue},
+				{Key: "protocols.oci.pull_backends[0].auth.type", Old: "bearer"},
+				{Key: "protocols.oci.pull_backends[0].auth.username", Old: ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, updated := base(), base()
+			tt.modify(updated)
+
+			got := Diff(old, updated)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Diff() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("change %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package config
+
+// EnabledProtocols returns the names of the enabled protocols
+func (c *Config) EnabledProtocols() []string {
+	var protocols []string
+	if c.Protocols.OCI.Enabled {
+		protocols = append(protocols, "oci")
+	}
+	if c.Protocols.Maven.Enabled {
+		protocols = append(protocols, "maven")
+	}
+	if c.Protocols.NPM.Enabled {
+		protocols = append(protocols, "npm")
+	}
+	if c.Protocols.RubyGems.Enabled {
+		protocols = append(protocols, "rubygems")
+	}
+	if c.Protocols.Helm.Enabled {
+		protocols = append(protocols, "helm")
+	}
+	if c.Protocols.APT.Enabled {
+		protocols = append(protocols, "apt")
+	}
+	if c.Protocols.Composer.Enabled {
+		protocols = append(protocols, "composer")
+	}
+	if c.Protocols.Conda.Enabled {
+		protocols = append(protocols, "conda")
+	}
+	if c.Protocols.Terraform.Enabled {
+		protocols = append(protocols, "terraform")
+	}
+	if c.Protocols.APK.Enabled {
+		protocols = append(protocols, "apk")
+	}
+	if c.Protocols.Raw.Enabled {
+		protocols = append(protocols, "raw")
+	}
+	if c.Protocols.LFS.Enabled {
+		protocols = append(protocols, "lfs")
+	}
+	if c.Protocols.CocoaPods.Enabled {
+		protocols = append(protocols, "cocoapods")
+	}
+	if c.Protocols.Hex.Enabled {
+		protocols = append(protocols, "hex")
+	}
+	if c.Protocols.Pub.Enabled {
+		protocols = append(protocols, "pub")
+	}
+	if c.Protocols.Vagrant.Enabled {
+		protocols = append(protocols, "vagrant")
+	}
+	return protocols
+}
+
+// EnabledClientAuth returns the client auth configuration of the enabled protocols,
+// keyed by protocol name
+func (c *Config) EnabledClientAuth() map[string]*ClientAuthConfig {
+	clientAuth := make(map[string]*ClientAuthConfig)
+	if c.Protocols.OCI.Enabled {
+		clientAuth["oci"] = &c.Protocols.OCI.ClientAuth
+	}
+	if c.Protocols.Maven.Enabled {
+		clientAuth["maven"] = &c.Protocols.Maven.ClientAuth
+	}
+	if c.Protocols.NPM.Enabled {
+		clientAuth["npm"] = &c.Protocols.NPM.ClientAuth
+	}
+	if c.Protocols.RubyGems.Enabled {
+		clientAuth["rubygems"] = &c.Protocols.RubyGems.ClientAuth
+	}
+	if c.Protocols.Helm.Enabled {
+		clientAuth["helm"] = &c.Protocols.Helm.ClientAuth
+	}
+	if c.Protocols.APT.Enabled {
+		clientAuth["apt"] = &c.Protocols.APT.ClientAuth
+	}
+	if c.Protocols.Composer.Enabled {
+		clientAuth["composer"] = &c.Protocols.Composer.ClientAuth
+	}
+	if c.Protocols.Conda.Enabled {
+		clientAuth["conda"] = &c.Protocols.Conda.ClientAuth
+	}
+	if c.Protocols.Terraform.Enabled {
+		clientAuth["terraform"] = &c.Protocols.Terraform.ClientAuth
+	}
+	if c.Protocols.APK.Enabled {
+		clientAuth["apk"] = &c.Protocols.APK.ClientAuth
+	}
+	if c.Protocols.Raw.Enabled {
+		clientAuth["raw"] = &c.Protocols.Raw.ClientAuth
+	}
+	if c.Protocols.LFS.Enabled {
+		clientAuth["lfs"] = &c.Protocols.LFS.ClientAuth
+	}
+	if c.Protocols.CocoaPods.Enabled {
+		clientAuth["cocoapods"] = &c.Protocols.CocoaPods.ClientAuth
+	}
+	if c.Protocols.Hex.Enabled {
+		clientAuth["hex"] = &c.Protocols.Hex.ClientAuth
+	}
+	if c.Protocols.Pub.Enabled {
+		clientAuth["pub"] = &c.Protocols.Pub.ClientAuth
+	}
+	if c.Protocols.Vagrant.Enabled {
+		clientAuth["vagrant"] = &c.Protocols.Vagrant.ClientAuth
+	}
+	return clientAuth
+}
+
+// Features reports whether each optional feature is enabled, keyed by feature name.
+// Every known feature is present so dashboards can tell disabled from unknown.
+func (c *Config) Features() map[string]bool {
+	return map[string]bool{
+		"metrics":                c.Metrics.Enabled,
+		"rate_limit":             c.RateLimit.Enabled,
+		"per_user_rate_limit":    c.RateLimit.PerUserEnabled,
+		"rate_limit_queue":       c.RateLimit.QueueTimeout > 0,
+		"write_rate_limit":       c.RateLimit.PerUserEnabled && c.RateLimit.PerUserWriteRequests > 0,
+		"concurrency_queue":      c.Server.QueueTimeout > 0,
+		"team_routing":           len(c.RoutingTeams()) > 0,
+		"validation_budget":      c.GitHub.ValidationBudget > 0,
+		"auth_cache_refresh":     c.GitHub.AuthCacheRefreshAhead > 0,
+		"auth_fail_open":         c.GitHub.FailurePolicy == FailurePolicyOpen,
+		"admin_impersonation":    c.Admin.Impersonation,
+		"header_logging":         c.Logging.IncludeHeaders,
+		"backend_experiment":     c.Protocols.Maven.Candidate != nil || c.Protocols.NPM.Candidate != nil,
+		"forward_proxy":          c.ForwardProxy.Enabled,
+		"write_back":             c.Protocols.Maven.WriteBack || c.Protocols.NPM.WriteBack,
+		"directory_listing":      c.Protocols.Maven.DirectoryListing,
+		"replication":            c.Protocols.OCI.Replication.Enabled,
+		"trash":                  c.Protocols.OCI.Trash.Enabled,
+		"provenance_annotations": c.Protocols.OCI.ProvenanceAnnotations,
+		"platform_filter":        c.Protocols.OCI.PlatformFilter.Enabled,
+		"mirror_namespace":       c.Protocols.OCI.MirrorNamespace.Enabled,
+		"web_ui":                 c.WebUI.Enabled,
+		"metadata":               c.Metadata.Enabled,
+		"signed_urls":            c.SignedURLs.Enabled,
+		"browser_sessions":       c.BrowserSessions.Enabled,
+		"synthetic_checks":       c.SyntheticChecks.Enabled,
+		"grpc_admin_api":         c.GRPC.Enabled,
+		"credential_sharing":     c.CredentialSharing.Enabled,
+		"auth_lockout":           c.AuthLockout.Enabled,
+		"oidc":                   len(c.Identity.OIDC) > 0,
+		"gitlab":                 c.GitLab.Enabled,
+		"ldap":                   c.LDAP.Enabled,
+		"service_accounts":       c.ServiceAccounts.Enabled,
+		"mtls":                   c.MTLS.Enabled,
+		"content_policy":         c.ContentPolicy.Enabled,
+		"client_policy":          c.ClientPolicy.Enabled,
+		"shadow_log":             c.ShadowLog.Enabled,
+		"vault":                  c.Vault.Enabled,
+		"local_options":          c.OptionsRequests.AnswerLocally,
+		"cors":                   c.OptionsRequests.CORS.Enabled(),
+	}
+}
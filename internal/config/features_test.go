@@ -0,0 +1,94 @@
+package config
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestConfig_EnabledProtocols(t *testing.T) {
+	cfg := &Config{}
+	cfg.Protocols.OCI.Enabled = true
+	cfg.Protocols.NPM.Enabled = true
+	cfg.Protocols.RubyGems.Enabled = true
+	cfg.Protocols.Helm.Enabled = true
+	cfg.Protocols.APT.Enabled = true
+	cfg.Protocols.Composer.Enabled = true
+	cfg.Protocols.Conda.Enabled = true
+	cfg.Protocols.Terraform.Enabled = true
+	cfg.Protocols.APK.Enabled = true
+	cfg.Protocols.Raw.Enabled = true
+	cfg.Protocols.LFS.Enabled = true
+	cfg.Protocols.CocoaPods.Enabled = true
+	cfg.Protocols.Hex.Enabled = true
+	cfg.Protocols.Pub.Enabled = true
+	cfg.Protocols.Vagrant.Enabled = true
+
+	got := cfg.EnabledProtocols()
+	if want := []string{"oci", "npm", "rubygems", "helm", "apt", "composer", "conda", "terraform", "apk", "raw", "lfs", "cocoapods", "hex", "pub", "vagrant"}; !slices.Equal(got, want) {
+		t.Errorf("EnabledProtocols() = %v, want %v", got, want)
+	}
+}
+
+func TestConfig_Features(t *testing.T) {
+	cfg := &Config{}
+	cfg.Metrics.Enabled = true
+	cfg.RateLimit.QueueTimeout = time.Second
+	cfg.GitHub.FailurePolicy = FailurePolicyOpen
+	cfg.Protocols.NPM.WriteBack = true
+	cfg.Protocols.OCI.ProvenanceAnnotations = true
+
+	features := cfg.Features()
+
+	tests := []struct {
+		feature string
+		want    bool
+	}{
+		{"metrics", true},
+		{"rate_limit_queue", true},
+		{"auth_fail_open", true},
+		{"write_back", true},
+		{"provenance_annotations", true},
+		{"rate_limit", false},
+		{"write_rate_limit", false},
+		{"concurrency_queue", false},
+		{"team_routing", false},
+		{"admin_impersonation", false},
+		{"backend_experiment", false},
+		{"replication", false},
+		{"directory_listing", false},
+		{"web_ui", false},
+		{"metadata", false},
+		{"signed_urls", false},
+		{"browser_sessions", false},
+		{"synthetic_checks", false},
+		{"grpc_admin_api", false},
+		{"credential_sharing", false},
+		{"auth_lockout", false},
+		{"oidc", false},
+		{"gitlab", false},
+		{"ldap", false},
+		{"service_accounts", false},
+		{"mtls", false},
+		{"content_policy", false},
+		{"client_policy", false},
+		{"shadow_log", false},
+		{"vault", false},
+		{"local_options", false},
+		{"cors", false},
+		{"platform_filter", false},
+		{"mirror_namespace", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.feature, func(t *testing.T) {
+			got, ok := features[tt.feature]
+			if !ok {
+				t.Fatalf("feature %q not reported", tt.feature)
+			}
+			if got != tt.want {
+				t.Errorf("feature %q = %v, want %v", tt.feature, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"strings"
@@ -25,10 +26,7 @@ func Load(configPath string) (*Config, error) {
 		v.AddConfigPath(".")
 	}
 
-	// Environment variables
-	v.SetEnvPrefix("ARTIFUSION")
-	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	v.AutomaticEnv()
+	setEnvOverrides(v)
 
 	// Read config file
 	if err := v.ReadInConfig(); err != nil {
@@ -38,7 +36,52 @@ func Load(configPath string) (*Config, error) {
 		// Config file not found is OK if we have env vars
 	}
 
-	// Unmarshal into config struct
+	return unmarshal(v)
+}
+
+// Parse parses a YAML configuration document the way Load reads a config file,
+// including environment variable overrides and defaults. The result is not
+// validated.
+func Parse(data []byte) (*Config, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	setEnvOverrides(v)
+
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	return unmarshal(v)
+}
+
+// LoadServiceAccounts reads the accounts list of a service account secrets file
+// (YAML or JSON, by extension). The accounts are not validated.
+func LoadServiceAccounts(path string) ([]ServiceAccountConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read service accounts file: %w", err)
+	}
+
+	var file struct {
+		Accounts []ServiceAccountConfig `mapstructure:"accounts"`
+	}
+	if err := v.Unmarshal(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse service accounts file: %w", err)
+	}
+	return file.Accounts, nil
+}
+
+// setEnvOverrides lets ARTIFUSION_* environment variables override config keys
+func setEnvOverrides(v *viper.Viper) {
+	v.SetEnvPrefix("ARTIFUSION")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+}
+
+// unmarshal decodes the configuration v read, expanding environment variables in
+// credentials and applying defaults
+func unmarshal(v *viper.Viper) (*Config, error) {
 	var cfg Config
 	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
@@ -60,12 +103,96 @@ func (c *Config) expandEnvVars() {
 		c.expandOCIBackendAuthEnvVars(&c.Protocols.OCI.PullBackends[i])
 	}
 	c.expandOCIBackendAuthEnvVars(&c.Protocols.OCI.PushBackend)
+	c.expandOCIBackendAuthEnvVars(&c.Protocols.OCI.Replication.Target)
 
 	// Expand Maven backend auth credentials
 	c.expandMavenBackendAuthEnvVars(&c.Protocols.Maven.Backend)
 
 	// Expand NPM backend auth credentials
 	c.expandNPMBackendAuthEnvVars(&c.Protocols.NPM.Backend)
+
+	// Expand RubyGems backend auth credentials
+	c.expandRubyGemsBackendAuthEnvVars(&c.Protocols.RubyGems.Backend)
+
+	// Expand Helm backend auth credentials
+	c.expandHelmBackendAuthEnvVars(&c.Protocols.Helm.Backend)
+
+	// Expand APT backend auth credentials
+	c.expandAPTBackendAuthEnvVars(&c.Protocols.APT.Backend)
+
+	// Expand Composer backend and upstream auth credentials
+	c.expandComposerBackendAuthEnvVars(&c.Protocols.Composer.Backend)
+	if c.Protocols.Composer.Upstream != nil {
+		c.expandComposerBackendAuthEnvVars(c.Protocols.Composer.Upstream)
+	}
+
+	// Expand Conda channel backend auth credentials
+	for i := range c.Protocols.Conda.Channels {
+		c.expandCondaBackendAuthEnvVars(&c.Protocols.Conda.Channels[i].Backend)
+	}
+
+	// Expand Terraform backend auth credentials
+	c.expandTerraformBackendAuthEnvVars(&c.Protocols.Terraform.Backend)
+
+	// Expand APK backend auth credentials
+	c.expandAPKBackendAuthEnvVars(&c.Protocols.APK.Backend)
+
+	// Expand raw repository backend auth credentials
+	for i := range c.Protocols.Raw.Repositories {
+		c.expandRawBackendAuthEnvVars(&c.Protocols.Raw.Repositories[i].Backend)
+	}
+
+	// Expand Git LFS backend auth credentials
+	c.expandLFSBackendAuthEnvVars(&c.Protocols.LFS.Backend)
+
+	// Expand CocoaPods backend auth credentials
+	c.expandCocoaPodsBackendAuthEnvVars(&c.Protocols.CocoaPods.Backend)
+	if c.Protocols.CocoaPods.BinaryBackend != nil {
+		c.expandCocoaPodsBackendAuthEnvVars(c.Protocols.CocoaPods.BinaryBackend)
+	}
+
+	// Expand Hex backend, upstream and publish backend auth credentials
+	c.expandHexBackendAuthEnvVars(&c.Protocols.Hex.Backend)
+	if c.Protocols.Hex.Upstream != nil {
+		c.expandHexBackendAuthEnvVars(c.Protocols.Hex.Upstream)
+	}
+	if c.Protocols.Hex.PublishBackend != nil {
+		c.expandHexBackendAuthEnvVars(c.Protocols.Hex.PublishBackend)
+	}
+
+	// Expand Pub backend auth credentials
+	c.expandPubBackendAuthEnvVars(&c.Protocols.Pub.Backend)
+
+	// Expand Vagrant catalog and download backend auth credentials
+	c.expandVagrantBackendAuthEnvVars(&c.Protocols.Vagrant.Backend)
+	for i := range c.Protocols.Vagrant.DownloadBackends {
+		c.expandVagrantBackendAuthEnvVars(&c.Protocols.Vagrant.DownloadBackends[i])
+	}
+
+	// Expand the signed URL secret
+	c.SignedURLs.Secret = os.ExpandEnv(c.SignedURLs.Secret)
+
+	// Expand the browser session secret
+	c.BrowserSessions.Secret = os.ExpandEnv(c.BrowserSessions.Secret)
+
+	// Expand the synthetic check token
+	c.SyntheticChecks.Token = os.ExpandEnv(c.SyntheticChecks.Token)
+
+	// Expand the credential sharing webhook URL
+	c.CredentialSharing.WebhookURL = os.ExpandEnv(c.CredentialSharing.WebhookURL)
+
+	// Expand the LDAP service account password
+	c.LDAP.BindPassword = os.ExpandEnv(c.LDAP.BindPassword)
+
+	// Expand the Vault token and token file path
+	c.Vault.Token = os.ExpandEnv(c.Vault.Token)
+	c.Vault.TokenFile = os.ExpandEnv(c.Vault.TokenFile)
+
+	// Expand the service account secrets file path and key hashes
+	c.ServiceAccounts.File = os.ExpandEnv(c.ServiceAccounts.File)
+	for i := range c.ServiceAccounts.Accounts {
+		c.ServiceAccounts.Accounts[i].KeyHash = os.ExpandEnv(c.ServiceAccounts.Accounts[i].KeyHash)
+	}
 }
 
 func (c *Config) expandOCIBackendAuthEnvVars(backend *OCIBackendConfig) {
@@ -77,6 +204,8 @@ func (c *Config) expandOCIBackendAuthEnvVars(backend *OCIBackendConfig) {
 	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
 	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
 	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
 }
 
 func (c *Config) expandMavenBackendAuthEnvVars(backend *MavenBackendConfig) {
@@ -88,6 +217,8 @@ func (c *Config) expandMavenBackendAuthEnvVars(backend *MavenBackendConfig) {
 	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
 	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
 	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
 }
 
 func (c *Config) expandNPMBackendAuthEnvVars(backend *NPMBackendConfig) {
@@ -99,4 +230,175 @@ func (c *Config) expandNPMBackendAuthEnvVars(backend *NPMBackendConfig) {
 	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
 	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
 	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
+}
+
+func (c *Config) expandRubyGemsBackendAuthEnvVars(backend *RubyGemsBackendConfig) {
+	if backend.Auth == nil {
+		return
+	}
+
+	backend.Auth.Username = os.ExpandEnv(backend.Auth.Username)
+	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
+	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
+	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
+}
+
+func (c *Config) expandHelmBackendAuthEnvVars(backend *HelmBackendConfig) {
+	if backend.Auth == nil {
+		return
+	}
+
+	backend.Auth.Username = os.ExpandEnv(backend.Auth.Username)
+	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
+	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
+	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
+}
+
+func (c *Config) expandAPTBackendAuthEnvVars(backend *APTBackendConfig) {
+	if backend.Auth == nil {
+		return
+	}
+
+	backend.Auth.Username = os.ExpandEnv(backend.Auth.Username)
+	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
+	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
+	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
+}
+
+func (c *Config) expandComposerBackendAuthEnvVars(backend *ComposerBackendConfig) {
+	if backend.Auth == nil {
+		return
+	}
+
+	backend.Auth.Username = os.ExpandEnv(backend.Auth.Username)
+	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
+	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
+	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
+}
+
+func (c *Config) expandCondaBackendAuthEnvVars(backend *CondaBackendConfig) {
+	if backend.Auth == nil {
+		return
+	}
+
+	backend.Auth.Username = os.ExpandEnv(backend.Auth.Username)
+	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
+	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
+	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
+}
+
+func (c *Config) expandTerraformBackendAuthEnvVars(backend *TerraformBackendConfig) {
+	if backend.Auth == nil {
+		return
+	}
+
+	backend.Auth.Username = os.ExpandEnv(backend.Auth.Username)
+	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
+	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
+	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
+}
+
+func (c *Config) expandAPKBackendAuthEnvVars(backend *APKBackendConfig) {
+	if backend.Auth == nil {
+		return
+	}
+
+	backend.Auth.Username = os.ExpandEnv(backend.Auth.Username)
+	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
+	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
+	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
+}
+
+func (c *Config) expandRawBackendAuthEnvVars(backend *RawBackendConfig) {
+	if backend.Auth == nil {
+		return
+	}
+
+	backend.Auth.Username = os.ExpandEnv(backend.Auth.Username)
+	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
+	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
+	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
+}
+
+func (c *Config) expandLFSBackendAuthEnvVars(backend *LFSBackendConfig) {
+	if backend.Auth == nil {
+		return
+	}
+
+	backend.Auth.Username = os.ExpandEnv(backend.Auth.Username)
+	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
+	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
+	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
+}
+
+func (c *Config) expandCocoaPodsBackendAuthEnvVars(backend *CocoaPodsBackendConfig) {
+	if backend.Auth == nil {
+		return
+	}
+
+	backend.Auth.Username = os.ExpandEnv(backend.Auth.Username)
+	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
+	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
+	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
+}
+
+func (c *Config) expandHexBackendAuthEnvVars(backend *HexBackendConfig) {
+	if backend.Auth == nil {
+		return
+	}
+
+	backend.Auth.Username = os.ExpandEnv(backend.Auth.Username)
+	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
+	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
+	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
+}
+
+func (c *Config) expandPubBackendAuthEnvVars(backend *PubBackendConfig) {
+	if backend.Auth == nil {
+		return
+	}
+
+	backend.Auth.Username = os.ExpandEnv(backend.Auth.Username)
+	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
+	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
+	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
+}
+
+func (c *Config) expandVagrantBackendAuthEnvVars(backend *VagrantBackendConfig) {
+	if backend.Auth == nil {
+		return
+	}
+
+	backend.Auth.Username = os.ExpandEnv(backend.Auth.Username)
+	backend.Auth.Password = os.ExpandEnv(backend.Auth.Password)
+	backend.Auth.Token = os.ExpandEnv(backend.Auth.Token)
+	backend.Auth.HeaderValue = os.ExpandEnv(backend.Auth.HeaderValue)
+	backend.Auth.PrivateKey = os.ExpandEnv(backend.Auth.PrivateKey)
+	backend.Auth.PrivateKeyFile = os.ExpandEnv(backend.Auth.PrivateKeyFile)
 }
@@ -1,12 +1,33 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
+	"maps"
 	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/mainuli/artifusion/internal/useragent"
+	"github.com/mainuli/artifusion/internal/utils"
 )
 
+// featureFlagNamePattern matches valid feature flag names
+var featureFlagNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// repositoryComponentPattern matches a component of an OCI repository name
+var repositoryComponentPattern = regexp.MustCompile(`^[a-z0-9]+((\.|_|__|-+)[a-z0-9]+)*$`)
+
+// npmScopePattern matches an npm package scope
+var npmScopePattern = regexp.MustCompile(`^@[a-z0-9][a-z0-9._~-]*$`)
+
+// MinSignedURLSecretLength is the shortest accepted signed URL secret
+const MinSignedURLSecretLength = 32
+
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	// Validate server config
@@ -24,247 +45,2307 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("protocols config: %w", err)
 	}
 
-	// Validate logging
-	if err := c.Logging.Validate(); err != nil {
-		return fmt.Errorf("logging config: %w", err)
+	// Validate the client auth challenges of enabled protocols
+	clientAuth := c.EnabledClientAuth()
+	for _, protocol := range slices.Sorted(maps.Keys(clientAuth)) {
+		if err := clientAuth[protocol].Validate(); err != nil {
+			return fmt.Errorf("%s client_auth config: %w", protocol, err)
+		}
+	}
+
+	// Validate logging
+	if err := c.Logging.Validate(); err != nil {
+		return fmt.Errorf("logging config: %w", err)
+	}
+
+	// Validate rate limiting
+	if err := c.RateLimit.Validate(); err != nil {
+		return fmt.Errorf("rate limit config: %w", err)
+	}
+
+	// Validate admin
+	if err := c.Admin.Validate(); err != nil {
+		return fmt.Errorf("admin config: %w", err)
+	}
+
+	// Validate forward proxy
+	if c.ForwardProxy.Enabled {
+		if err := c.ForwardProxy.Validate(&c.Protocols); err != nil {
+			return fmt.Errorf("forward proxy config: %w", err)
+		}
+		if c.ForwardProxy.Port == c.Server.Port {
+			return fmt.Errorf("forward proxy config: port %d is already used by the server", c.ForwardProxy.Port)
+		}
+	}
+
+	// Validate web UIs
+	if c.WebUI.Enabled {
+		if err := c.WebUI.Validate(&c.Protocols); err != nil {
+			return fmt.Errorf("web ui config: %w", err)
+		}
+	}
+
+	// Validate metadata database
+	if c.Metadata.Enabled {
+		if err := c.Metadata.Validate(); err != nil {
+			return fmt.Errorf("metadata config: %w", err)
+		}
+	}
+
+	// Validate signed URLs
+	if c.SignedURLs.Enabled {
+		if err := c.SignedURLs.Validate(); err != nil {
+			return fmt.Errorf("signed_urls config: %w", err)
+		}
+	}
+
+	if c.BrowserSessions.Enabled {
+		if err := c.BrowserSessions.Validate(); err != nil {
+			return fmt.Errorf("browser_sessions config: %w", err)
+		}
+	}
+
+	// Validate synthetic checks
+	if c.SyntheticChecks.Enabled {
+		if err := c.SyntheticChecks.Validate(&c.Protocols); err != nil {
+			return fmt.Errorf("synthetic_checks config: %w", err)
+		}
+	}
+
+	// Validate credential sharing detection
+	if c.CredentialSharing.Enabled {
+		if err := c.CredentialSharing.Validate(); err != nil {
+			return fmt.Errorf("credential sharing config: %w", err)
+		}
+	}
+
+	if c.AuthLockout.Enabled {
+		if err := c.AuthLockout.Validate(); err != nil {
+			return fmt.Errorf("auth lockout config: %w", err)
+		}
+	}
+
+	if c.GitLab.Enabled {
+		if err := c.GitLab.Validate(); err != nil {
+			return fmt.Errorf("gitlab config: %w", err)
+		}
+	}
+
+	if c.LDAP.Enabled {
+		if err := c.LDAP.Validate(); err != nil {
+			return fmt.Errorf("ldap config: %w", err)
+		}
+	}
+
+	if c.ServiceAccounts.Enabled {
+		if err := c.ServiceAccounts.Validate(c.GitHub.RequiredOrg); err != nil {
+			return fmt.Errorf("service accounts config: %w", err)
+		}
+	}
+
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		return fmt.Errorf("server config: tls_cert_file and tls_key_file must be set together")
+	}
+	if c.MTLS.Enabled {
+		if err := c.MTLS.Validate(&c.Server, c.GitHub.RequiredOrg); err != nil {
+			return fmt.Errorf("mtls config: %w", err)
+		}
+	}
+
+	// Validate identity providers, including those selected by protocols
+	builtinProviders := []string{"github"}
+	if c.GitLab.Enabled {
+		builtinProviders = append(builtinProviders, "gitlab")
+	}
+	if c.LDAP.Enabled {
+		builtinProviders = append(builtinProviders, "ldap")
+	}
+	if c.ServiceAccounts.Enabled {
+		builtinProviders = append(builtinProviders, "service_accounts")
+	}
+	if err := c.Identity.Validate(builtinProviders, c.EnabledClientAuth()); err != nil {
+		return fmt.Errorf("identity config: %w", err)
+	}
+
+	if c.ContentPolicy.Enabled {
+		if err := c.ContentPolicy.Validate(); err != nil {
+			return fmt.Errorf("content policy config: %w", err)
+		}
+	}
+
+	if c.ClientPolicy.Enabled {
+		if err := c.ClientPolicy.Validate(); err != nil {
+			return fmt.Errorf("client policy config: %w", err)
+		}
+	}
+
+	if c.OptionsRequests.CORS.Enabled() {
+		if err := c.OptionsRequests.CORS.Validate(); err != nil {
+			return fmt.Errorf("options_requests config: cors: %w", err)
+		}
+	}
+
+	if c.ShadowLog.Enabled {
+		if err := c.ShadowLog.Validate(); err != nil {
+			return fmt.Errorf("shadow log config: %w", err)
+		}
+	}
+
+	if c.Vault.Enabled {
+		if err := c.Vault.Validate(); err != nil {
+			return fmt.Errorf("vault config: %w", err)
+		}
+	}
+
+	// Validate gRPC admin API
+	if c.GRPC.Enabled {
+		if err := c.GRPC.Validate(&c.Admin); err != nil {
+			return fmt.Errorf("grpc config: %w", err)
+		}
+		if c.GRPC.Port == c.Server.Port || (c.ForwardProxy.Enabled && c.GRPC.Port == c.ForwardProxy.Port) {
+			return fmt.Errorf("grpc config: port %d is already in use", c.GRPC.Port)
+		}
+	}
+
+	// Validate feature flags
+	for name := range c.FeatureFlags {
+		if !featureFlagNamePattern.MatchString(name) {
+			return fmt.Errorf("feature_flags: invalid flag name %q (must be lowercase snake_case)", name)
+		}
+	}
+
+	// Team-scoped backends need an org to resolve team membership against
+	if len(c.RoutingTeams()) > 0 && c.GitHub.RequiredOrg == "" {
+		return fmt.Errorf("github.required_org must be specified when backend teams are configured")
+	}
+
+	// At least one protocol must be enabled
+	if !c.Protocols.OCI.Enabled && !c.Protocols.Maven.Enabled && !c.Protocols.NPM.Enabled && !c.Protocols.RubyGems.Enabled && !c.Protocols.Helm.Enabled && !c.Protocols.APT.Enabled && !c.Protocols.Composer.Enabled && !c.Protocols.Conda.Enabled && !c.Protocols.Terraform.Enabled && !c.Protocols.APK.Enabled && !c.Protocols.Raw.Enabled && !c.Protocols.LFS.Enabled && !c.Protocols.CocoaPods.Enabled && !c.Protocols.Hex.Enabled && !c.Protocols.Pub.Enabled && !c.Protocols.Vagrant.Enabled {
+		return fmt.Errorf("at least one protocol must be enabled")
+	}
+
+	return nil
+}
+
+// Validate validates forward proxy configuration against the enabled protocols
+func (f *ForwardProxyConfig) Validate(protocols *ProtocolsConfig) error {
+	if f.Port < 1 || f.Port > 65535 {
+		return fmt.Errorf("invalid port: %d", f.Port)
+	}
+
+	if (f.TLSCertFile == "") != (f.TLSKeyFile == "") {
+		return fmt.Errorf("tls_cert_file and tls_key_file must be set together")
+	}
+
+	if len(f.Intercept) == 0 {
+		return fmt.Errorf("at least one intercept host is required")
+	}
+
+	enabled := map[string]bool{
+		"oci":   protocols.OCI.Enabled,
+		"maven": protocols.Maven.Enabled,
+		"npm":   protocols.NPM.Enabled,
+	}
+	hosts := make(map[string]bool, len(f.Intercept))
+	for i, intercept := range f.Intercept {
+		host := strings.ToLower(intercept.Host)
+		if host == "" || strings.ContainsAny(host, ":/") {
+			return fmt.Errorf("intercept[%d]: host must be a hostname without port or scheme (got: %q)", i, intercept.Host)
+		}
+		if hosts[host] {
+			return fmt.Errorf("intercept[%d]: duplicate host %s", i, intercept.Host)
+		}
+		hosts[host] = true
+
+		isEnabled, known := enabled[intercept.Protocol]
+		if !known {
+			return fmt.Errorf("intercept[%d]: unknown protocol %q (must be oci, maven or npm)", i, intercept.Protocol)
+		}
+		if !isEnabled {
+			return fmt.Errorf("intercept[%d]: protocol %s is not enabled", i, intercept.Protocol)
+		}
+	}
+
+	return nil
+}
+
+// Validate validates metadata database configuration
+func (m *MetadataConfig) Validate() error {
+	if m.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if m.FlushInterval < 0 {
+		return fmt.Errorf("flush_interval must be non-negative")
+	}
+	return nil
+}
+
+// Validate validates signed URL configuration
+func (s *SignedURLsConfig) Validate() error {
+	if len(s.Secret) < MinSignedURLSecretLength {
+		return fmt.Errorf("secret must be at least %d characters", MinSignedURLSecretLength)
+	}
+	if s.DefaultTTL <= 0 || s.MaxTTL <= 0 {
+		return fmt.Errorf("default_ttl and max_ttl must be positive")
+	}
+	if s.DefaultTTL > s.MaxTTL {
+		return fmt.Errorf("default_ttl (%s) must not exceed max_ttl (%s)", s.DefaultTTL, s.MaxTTL)
+	}
+	if s.BaseURL != "" {
+		u, err := url.Parse(s.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("base_url must be an absolute http(s) URL (got: %q)", s.BaseURL)
+		}
+	}
+	return nil
+}
+
+// Validate validates browser session configuration
+func (s *BrowserSessionsConfig) Validate() error {
+	if s.ClientID == "" {
+		return fmt.Errorf("client_id is required")
+	}
+	if len(s.Secret) < MinSignedURLSecretLength {
+		return fmt.Errorf("secret must be at least %d characters", MinSignedURLSecretLength)
+	}
+	if s.TTL <= 0 || s.TTL > MaxBrowserSessionTTL {
+		return fmt.Errorf("ttl must be positive and at most %s (got: %s)", MaxBrowserSessionTTL, s.TTL)
+	}
+	if u, err := url.Parse(s.GitHubURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("github_url must be an absolute http(s) URL (got: %q)", s.GitHubURL)
+	}
+	return nil
+}
+
+// Validate validates gRPC admin API configuration. Every RPC is admin only, so the
+// API is unusable without admin users.
+func (g *GRPCConfig) Validate(admin *AdminConfig) error {
+	if g.Port < 1 || g.Port > 65535 {
+		return fmt.Errorf("invalid port: %d", g.Port)
+	}
+	if (g.TLSCertFile == "") != (g.TLSKeyFile == "") {
+		return fmt.Errorf("tls_cert_file and tls_key_file must be set together")
+	}
+	if len(admin.Users) == 0 {
+		return fmt.Errorf("admin.users must list at least one admin")
+	}
+	return nil
+}
+
+// Validate validates duplicate credential detection configuration
+func (s *CredentialSharingConfig) Validate() error {
+	if s.MaxSourceIPs < 1 {
+		return fmt.Errorf("max_source_ips must be at least 1")
+	}
+	if s.Window <= 0 {
+		return fmt.Errorf("window must be positive")
+	}
+	if s.WebhookURL != "" {
+		if u, err := url.Parse(s.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			// The URL is a secret, so it is not echoed back
+			return fmt.Errorf("webhook_url must be an absolute http(s) URL")
+		}
+	}
+	return nil
+}
+
+// Validate validates brute-force lockout configuration
+func (l *AuthLockoutConfig) Validate() error {
+	if l.MaxFailures < 1 {
+		return fmt.Errorf("max_failures must be at least 1")
+	}
+	if l.Window <= 0 || l.Duration <= 0 {
+		return fmt.Errorf("window and duration must be positive")
+	}
+	if l.MaxDuration < l.Duration {
+		return fmt.Errorf("max_duration (%s) must not be less than duration (%s)", l.MaxDuration, l.Duration)
+	}
+	return nil
+}
+
+// Validate validates identity provider configuration. builtin names the enabled
+// built-in providers; clientAuth holds the client auth configuration of the enabled
+// protocols by name, whose selected providers must exist.
+func (i *IdentityConfig) Validate(builtin []string, clientAuth map[string]*ClientAuthConfig) error {
+	names := make(map[string]bool)
+	for _, name := range builtin {
+		names[name] = true
+	}
+	for idx := range i.OIDC {
+		oidc := &i.OIDC[idx]
+		if err := oidc.Validate(); err != nil {
+			return fmt.Errorf("oidc[%d]: %w", idx, err)
+		}
+		if names[oidc.Name] {
+			return fmt.Errorf("oidc[%d]: duplicate provider name %q", idx, oidc.Name)
+		}
+		names[oidc.Name] = true
+	}
+
+	if err := validateProviderNames(i.Providers, names); err != nil {
+		return fmt.Errorf("providers: %w", err)
+	}
+
+	// Sorted so the reported protocol is deterministic
+	for _, protocol := range slices.Sorted(maps.Keys(clientAuth)) {
+		if err := validateProviderNames(clientAuth[protocol].Providers, names); err != nil {
+			return fmt.Errorf("%s client_auth.providers: %w", protocol, err)
+		}
+	}
+	return nil
+}
+
+// validateProviderNames checks that a provider selection only names known providers,
+// each once
+func validateProviderNames(selected []string, known map[string]bool) error {
+	seen := make(map[string]bool)
+	for _, name := range selected {
+		if !known[name] {
+			return fmt.Errorf("unknown provider %q", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("provider %q listed twice", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// Validate validates client auth configuration
+func (c *ClientAuthConfig) Validate() error {
+	seen := make(map[string]bool, len(c.SupportedSchemes))
+	for _, scheme := range c.SupportedSchemes {
+		scheme = strings.ToLower(scheme)
+		if scheme != "basic" && scheme != "bearer" {
+			return fmt.Errorf("supported_schemes: unknown scheme %q (must be basic or bearer)", scheme)
+		}
+		if seen[scheme] {
+			return fmt.Errorf("supported_schemes: scheme %q listed twice", scheme)
+		}
+		seen[scheme] = true
+	}
+	return nil
+}
+
+// Validate validates GitLab configuration
+func (g *GitLabConfig) Validate() error {
+	if u, err := url.Parse(g.APIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("api_url must be an absolute http(s) URL (got: %q)", g.APIURL)
+	}
+	if strings.Trim(g.RequiredGroup, "/") != g.RequiredGroup {
+		return fmt.Errorf("required_group must be a group path without leading or trailing slashes (got: %q)", g.RequiredGroup)
+	}
+	return nil
+}
+
+// Validate validates LDAP configuration
+func (l *LDAPConfig) Validate() error {
+	u, err := url.Parse(l.URL)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+		return fmt.Errorf("url must be an ldap:// or ldaps:// URL (got: %q)", l.URL)
+	}
+	if l.StartTLS && u.Scheme == "ldaps" {
+		return fmt.Errorf("start_tls cannot be used with an ldaps:// url")
+	}
+	if l.BindDN != "" && l.BindPassword == "" {
+		return fmt.Errorf("bind_password is required with bind_dn")
+	}
+	if l.UserBaseDN == "" {
+		return fmt.Errorf("user_base_dn is required")
+	}
+	if strings.Count(l.UserFilter, "%s") != 1 || !strings.HasPrefix(l.UserFilter, "(") || !strings.HasSuffix(l.UserFilter, ")") {
+		return fmt.Errorf("user_filter must be a parenthesized filter containing %%s once (got: %q)", l.UserFilter)
+	}
+	for i, group := range l.RequiredGroups {
+		if !strings.Contains(group, "=") {
+			return fmt.Errorf("required_groups[%d] must be a group DN (got: %q)", i, group)
+		}
+	}
+	if l.PoolSize < 0 {
+		return fmt.Errorf("pool_size cannot be negative (got: %d)", l.PoolSize)
+	}
+	if l.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative (got: %s)", l.Timeout)
+	}
+	return nil
+}
+
+// Validate validates service account configuration. Accounts of the secrets file
+// are validated when it is read.
+func (s *ServiceAccountsConfig) Validate(requiredOrg string) error {
+	if s.File == "" && len(s.Accounts) == 0 {
+		return fmt.Errorf("file or at least one account is required")
+	}
+	return ValidateServiceAccounts(s.Accounts, requiredOrg)
+}
+
+// ValidateServiceAccounts validates service accounts, which must have unique names
+// and keys and list requiredOrg (if set) in their orgs
+func ValidateServiceAccounts(accounts []ServiceAccountConfig, requiredOrg string) error {
+	names := make(map[string]bool, len(accounts))
+	hashes := make(map[string]bool, len(accounts))
+	for i, account := range accounts {
+		if account.Name == "" {
+			return fmt.Errorf("accounts[%d]: name is required", i)
+		}
+		if names[account.Name] {
+			return fmt.Errorf("accounts[%d]: duplicate name %q", i, account.Name)
+		}
+		names[account.Name] = true
+
+		digest, ok := strings.CutPrefix(account.KeyHash, "sha256:")
+		if _, err := hex.DecodeString(digest); !ok || err != nil || len(digest) != 64 {
+			return fmt.Errorf("accounts[%d]: key_hash must be \"sha256:\" followed by 64 hex digits", i)
+		}
+		digest = strings.ToLower(digest)
+		if hashes[digest] {
+			return fmt.Errorf("accounts[%d]: key_hash is already used by another account", i)
+		}
+		hashes[digest] = true
+
+		for _, protocol := range account.Protocols {
+			switch protocol {
+			case "oci", "maven", "npm", "rubygems", "helm", "apt", "composer", "conda", "terraform", "apk", "raw", "lfs", "cocoapods", "hex", "pub", "vagrant":
+			default:
+				return fmt.Errorf("accounts[%d]: unknown protocol %q", i, protocol)
+			}
+		}
+		if requiredOrg != "" && !slices.Contains(account.Orgs, requiredOrg) {
+			return fmt.Errorf("accounts[%d]: orgs must include the required org %q", i, requiredOrg)
+		}
+	}
+	return nil
+}
+
+// Validate validates client certificate authentication against the server
+// configuration, which must serve TLS. With requiredOrg set, certificates are
+// members of it only by an allowed OU, so allowed_ous is required.
+func (m *MTLSConfig) Validate(server *ServerConfig, requiredOrg string) error {
+	if server.TLSCertFile == "" {
+		return fmt.Errorf("server.tls_cert_file and server.tls_key_file are required")
+	}
+	if m.CAFile == "" {
+		return fmt.Errorf("ca_file is required")
+	}
+	if m.UsernameFrom != MTLSUsernameFromSAN && m.UsernameFrom != MTLSUsernameFromCN {
+		return fmt.Errorf("username_from must be %q or %q (got: %q)", MTLSUsernameFromSAN, MTLSUsernameFromCN, m.UsernameFrom)
+	}
+	for i, ou := range m.AllowedOUs {
+		if strings.TrimSpace(ou) == "" {
+			return fmt.Errorf("allowed_ous[%d] cannot be empty", i)
+		}
+	}
+	if requiredOrg != "" && len(m.AllowedOUs) == 0 {
+		return fmt.Errorf("allowed_ous is required with github.required_org, as the OUs of %s members must be listed", requiredOrg)
+	}
+	return nil
+}
+
+// Validate validates CORS configuration
+func (c *CORSConfig) Validate() error {
+	for i, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				return fmt.Errorf("allowed_origins cannot contain \"*\" with allow_credentials")
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return fmt.Errorf("allowed_origins[%d] must be \"*\" or an origin like https://tools.example.com (got: %q)", i, origin)
+		}
+	}
+	for i, header := range c.AllowedHeaders {
+		if strings.TrimSpace(header) == "" {
+			return fmt.Errorf("allowed_headers[%d] cannot be empty", i)
+		}
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("max_age cannot be negative")
+	}
+	return nil
+}
+
+// Validate validates shadow logging configuration
+func (s *ShadowLogConfig) Validate() error {
+	if s.Dir == "" {
+		return fmt.Errorf("dir is required")
+	}
+	if s.MaxDuration < 0 {
+		return fmt.Errorf("max_duration cannot be negative")
+	}
+	return nil
+}
+
+// Validate validates Vault configuration
+func (v *VaultConfig) Validate() error {
+	if u, err := url.Parse(v.Address); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("address must be an absolute http(s) URL (got: %q)", v.Address)
+	}
+	if (v.Token == "") == (v.TokenFile == "") {
+		return fmt.Errorf("exactly one of token or token_file is required")
+	}
+	if v.RefreshInterval <= 0 {
+		return fmt.Errorf("refresh_interval must be positive")
+	}
+	return nil
+}
+
+// Validate validates OIDC identity provider configuration
+func (o *OIDCProviderConfig) Validate() error {
+	if o.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if o.Name == "github" || o.Name == "gitlab" || o.Name == "ldap" || o.Name == "service_accounts" {
+		return fmt.Errorf("name %q is reserved for the built-in provider", o.Name)
+	}
+	if u, err := url.Parse(o.Issuer); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("issuer must be an absolute http(s) URL (got: %q)", o.Issuer)
+	}
+	if o.JWKSURL != "" {
+		if u, err := url.Parse(o.JWKSURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("jwks_url must be an absolute http(s) URL (got: %q)", o.JWKSURL)
+		}
+	}
+	// Without an audience check, any token the issuer minted for another application
+	// would be accepted
+	if len(o.Audiences) == 0 {
+		return fmt.Errorf("at least one audience is required")
+	}
+	for i, req := range o.RequiredClaims {
+		if req.Claim == "" {
+			return fmt.Errorf("required_claims[%d]: claim is required", i)
+		}
+	}
+	if o.JWKSCacheTTL <= 0 {
+		return fmt.Errorf("jwks_cache_ttl must be positive")
+	}
+	if o.ClockSkew < 0 {
+		return fmt.Errorf("clock_skew must not be negative")
+	}
+	return nil
+}
+
+// Validate validates content policy configuration
+func (c *ContentPolicyConfig) Validate() error {
+	if len(c.Rules) == 0 {
+		return fmt.Errorf("at least one rule is required")
+	}
+
+	for i, rule := range c.Rules {
+		switch rule.Protocol {
+		case "oci", "maven", "npm", "rubygems", "helm", "apt", "composer", "conda", "terraform", "apk", "raw", "lfs", "cocoapods", "hex", "pub", "vagrant":
+		default:
+			return fmt.Errorf("rules[%d]: protocol must be oci, maven, npm, rubygems, helm, apt, composer, conda, terraform, apk, raw, lfs, cocoapods, hex, pub or vagrant (got: %q)", i, rule.Protocol)
+		}
+		if _, err := regexp.Compile(rule.Path); err != nil {
+			return fmt.Errorf("rules[%d]: invalid path pattern: %w", i, err)
+		}
+		if len(rule.AllowExtensions)+len(rule.DenyExtensions)+len(rule.AllowContentTypes)+len(rule.DenyContentTypes) == 0 {
+			return fmt.Errorf("rules[%d]: at least one extension or content type must be allowed or denied", i)
+		}
+		for _, ext := range slices.Concat(rule.AllowExtensions, rule.DenyExtensions) {
+			if !strings.HasPrefix(ext, ".") || len(ext) < 2 || strings.Contains(ext, "/") {
+				return fmt.Errorf("rules[%d]: extension must start with a dot (got: %q)", i, ext)
+			}
+		}
+		for _, contentType := range slices.Concat(rule.AllowContentTypes, rule.DenyContentTypes) {
+			if _, err := path.Match(contentType, ""); err != nil || strings.Count(contentType, "/") != 1 {
+				return fmt.Errorf("rules[%d]: content type must be a media type pattern such as text/* (got: %q)", i, contentType)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate validates client policy configuration
+func (c *ClientPolicyConfig) Validate() error {
+	if len(c.Rules) == 0 && len(c.MinVersions) == 0 {
+		return fmt.Errorf("at least one rule or min version is required")
+	}
+
+	for client, version := range c.MinVersions {
+		switch client {
+		case useragent.Docker, useragent.Containerd, useragent.NPM, useragent.Yarn, useragent.PNPM, useragent.Maven, useragent.Gradle:
+		default:
+			return fmt.Errorf("min_versions: client must be docker, containerd, npm, yarn, pnpm, maven or gradle (got: %q)", client)
+		}
+		if !useragent.IsVersion(version) {
+			return fmt.Errorf("min_versions[%s]: version must be dotted numbers such as 7.0.0 (got: %q)", client, version)
+		}
+	}
+
+	for i, rule := range c.Rules {
+		switch rule.Client {
+		case useragent.Docker, useragent.Containerd, useragent.NPM, useragent.Yarn, useragent.PNPM, useragent.Maven, useragent.Gradle:
+		default:
+			return fmt.Errorf("rules[%d]: client must be docker, containerd, npm, yarn, pnpm, maven or gradle (got: %q)", i, rule.Client)
+		}
+		switch rule.Protocol {
+		case "", "oci", "maven", "npm", "rubygems", "helm", "apt", "composer", "conda", "terraform", "apk", "raw", "lfs", "cocoapods", "hex", "pub", "vagrant":
+		default:
+			return fmt.Errorf("rules[%d]: protocol must be oci, maven, npm, rubygems, helm, apt, composer, conda, terraform, apk, raw, lfs, cocoapods, hex, pub or vagrant (got: %q)", i, rule.Protocol)
+		}
+		for _, versions := range rule.DenyVersions {
+			if _, err := useragent.ParseConstraint(versions); err != nil {
+				return fmt.Errorf("rules[%d]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate validates synthetic check configuration against the enabled protocols
+func (s *SyntheticChecksConfig) Validate(protocols *ProtocolsConfig) error {
+	if s.Interval <= 0 || s.Timeout <= 0 {
+		return fmt.Errorf("interval and timeout must be positive")
+	}
+	if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http(s) URL (got: %q)", s.URL)
+	}
+	if s.Token == "" {
+		return fmt.Errorf("token is required")
+	}
+	if len(s.Checks) == 0 {
+		return fmt.Errorf("at least one check is required")
+	}
+
+	names := make(map[string]bool)
+	for i, check := range s.Checks {
+		if check.Name == "" {
+			return fmt.Errorf("checks[%d]: name is required", i)
+		}
+		if names[check.Name] {
+			return fmt.Errorf("checks[%d]: duplicate name %q", i, check.Name)
+		}
+		names[check.Name] = true
+
+		var enabled bool
+		switch check.Protocol {
+		case "oci":
+			enabled = protocols.OCI.Enabled
+		case "npm":
+			enabled = protocols.NPM.Enabled
+		case "maven":
+			enabled = protocols.Maven.Enabled
+			if parts := strings.Split(check.Artifact, ":"); len(parts) != 3 || slices.Contains(parts, "") {
+				return fmt.Errorf("checks[%d]: maven artifact must be group:artifact:version (got: %q)", i, check.Artifact)
+			}
+		default:
+			return fmt.Errorf("checks[%d]: protocol must be oci, npm or maven (got: %q)", i, check.Protocol)
+		}
+		if !enabled {
+			return fmt.Errorf("checks[%d]: protocol %s is not enabled", i, check.Protocol)
+		}
+		if check.Artifact == "" {
+			return fmt.Errorf("checks[%d]: artifact is required", i)
+		}
+	}
+	return nil
+}
+
+// Validate validates web UI configuration against the protocols whose backends
+// serve the UIs
+func (u *WebUIConfig) Validate(protocols *ProtocolsConfig) error {
+	if !strings.HasPrefix(u.PathPrefix, "/") || strings.HasSuffix(u.PathPrefix, "/") {
+		return fmt.Errorf("path_prefix must start with / and not end with / (got: %q)", u.PathPrefix)
+	}
+	for _, reserved := range []string{"/v2", "/api", protocols.Maven.PathPrefix, protocols.NPM.PathPrefix, protocols.RubyGems.PathPrefix, protocols.Helm.PathPrefix, protocols.APT.PathPrefix, protocols.Composer.PathPrefix, protocols.Conda.PathPrefix, protocols.Terraform.PathPrefix, protocols.APK.PathPrefix, protocols.Raw.PathPrefix, protocols.LFS.PathPrefix, protocols.CocoaPods.PathPrefix, protocols.Hex.PathPrefix, protocols.Pub.PathPrefix, protocols.Vagrant.PathPrefix} {
+		if reserved != "" && (u.PathPrefix == reserved || strings.HasPrefix(u.PathPrefix, reserved+"/")) {
+			return fmt.Errorf("path_prefix %s overlaps %s, which is already served", u.PathPrefix, reserved)
+		}
+	}
+
+	if len(u.Mounts) == 0 {
+		return fmt.Errorf("at least one mount is required")
+	}
+
+	enabled := map[string]bool{
+		"maven": protocols.Maven.Enabled,
+		"npm":   protocols.NPM.Enabled,
+	}
+	mounted := make(map[string]bool, len(u.Mounts))
+	for i, mount := range u.Mounts {
+		isEnabled, known := enabled[mount.Protocol]
+		if !known {
+			return fmt.Errorf("mounts[%d]: unknown protocol %q (must be maven or npm)", i, mount.Protocol)
+		}
+		if !isEnabled {
+			return fmt.Errorf("mounts[%d]: protocol %s is not enabled", i, mount.Protocol)
+		}
+		if mounted[mount.Protocol] {
+			return fmt.Errorf("mounts[%d]: duplicate protocol %s", i, mount.Protocol)
+		}
+		mounted[mount.Protocol] = true
+
+		if mount.URL != "" {
+			if parsed, err := url.Parse(mount.URL); err != nil || parsed.Host == "" {
+				return fmt.Errorf("mounts[%d]: url must be an absolute URL (got: %q)", i, mount.URL)
+			}
+		}
+		for _, path := range mount.Paths {
+			if !strings.HasPrefix(path, "/") {
+				return fmt.Errorf("mounts[%d]: path must start with / (got: %q)", i, path)
+			}
+		}
+		if !strings.HasPrefix(mount.Home, "/") {
+			return fmt.Errorf("mounts[%d]: home must start with / (got: %q)", i, mount.Home)
+		}
+	}
+
+	return nil
+}
+
+// Validate validates server configuration
+func (s *ServerConfig) Validate() error {
+	if s.Port < 1 || s.Port > 65535 {
+		return fmt.Errorf("invalid port: %d", s.Port)
+	}
+
+	if s.ReadTimeout <= 0 {
+		return fmt.Errorf("invalid read timeout: %v", s.ReadTimeout)
+	}
+
+	if s.WriteTimeout <= 0 {
+		return fmt.Errorf("invalid write timeout: %v", s.WriteTimeout)
+	}
+
+	if s.MaxConcurrentReqs < 1 {
+		return fmt.Errorf("maxConcurrentRequests must be at least 1")
+	}
+
+	if s.QueueTimeout < 0 {
+		return fmt.Errorf("queue_timeout must not be negative: %v", s.QueueTimeout)
+	}
+
+	if s.MaxQueuedReqs < 0 {
+		return fmt.Errorf("max_queued_requests must not be negative: %d", s.MaxQueuedReqs)
+	}
+
+	if err := s.SecurityHeaders.Validate(); err != nil {
+		return fmt.Errorf("security_headers: %w", err)
+	}
+
+	if _, err := utils.NewTrustedProxies(s.TrustedProxies); err != nil {
+		return fmt.Errorf("trusted_proxies: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates the security header policy
+func (h *SecurityHeadersConfig) Validate() error {
+	if h.HSTSMaxAge < 0 {
+		return fmt.Errorf("hsts_max_age must not be negative: %v", h.HSTSMaxAge)
+	}
+
+	// https://hstspreload.org/#submission-requirements
+	if h.HSTSPreload {
+		if h.HSTSDisabled {
+			return fmt.Errorf("hsts_preload requires HSTS to be enabled")
+		}
+		if h.HSTSMaxAge < DefaultHSTSMaxAge {
+			return fmt.Errorf("hsts_preload requires hsts_max_age of at least %v (got: %v)", DefaultHSTSMaxAge, h.HSTSMaxAge)
+		}
+		if h.HSTSExcludeSubdomains {
+			return fmt.Errorf("hsts_preload requires subdomains to be included")
+		}
+	}
+
+	if err := validateFrameOptions("frame_options", h.FrameOptions); err != nil {
+		return err
+	}
+	if err := validateFrameOptions("ui_frame_options", h.UIFrameOptions); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateFrameOptions validates an X-Frame-Options setting (empty = default)
+func validateFrameOptions(key, value string) error {
+	if value != "" && value != FrameOptionsDeny && value != FrameOptionsSameOrigin {
+		return fmt.Errorf("%s must be %s or %s (got: %q)", key, FrameOptionsDeny, FrameOptionsSameOrigin, value)
+	}
+
+	return nil
+}
+
+// Validate validates GitHub configuration
+func (g *GitHubConfig) Validate() error {
+	if g.APIURL == "" {
+		return fmt.Errorf("apiURL is required")
+	}
+
+	if _, err := url.Parse(g.APIURL); err != nil {
+		return fmt.Errorf("invalid apiURL: %w", err)
+	}
+
+	// RequiredOrg is optional - if empty, only PAT validation is performed
+	// If provided, organization membership will be checked
+
+	// SECURITY: Prevent team enforcement bypass
+	// If teams are required, org must also be specified since team checks
+	// only run inside the org membership validation block
+	if len(g.RequiredTeams) > 0 && g.RequiredOrg == "" {
+		return fmt.Errorf("required_org must be specified when required_teams is configured")
+	}
+
+	if g.AuthCacheTTL <= 0 {
+		return fmt.Errorf("invalid authCacheTTL: %v", g.AuthCacheTTL)
+	}
+
+	if g.AuthCacheRefreshAhead < 0 || g.AuthCacheRefreshAhead >= g.AuthCacheTTL {
+		return fmt.Errorf("auth_cache_refresh_ahead must be between 0 and auth_cache_ttl (got: %v)", g.AuthCacheRefreshAhead)
+	}
+
+	if g.ValidationBudget < 0 {
+		return fmt.Errorf("validation_budget must not be negative: %d", g.ValidationBudget)
+	}
+
+	if g.ValidationBudget > 0 && g.ValidationBudgetWindow <= 0 {
+		return fmt.Errorf("validation_budget_window must be positive when validation_budget is set")
+	}
+
+	if g.AuthTimeout < 0 {
+		return fmt.Errorf("auth_timeout must not be negative: %v", g.AuthTimeout)
+	}
+
+	switch g.FailurePolicy {
+	case "", FailurePolicyClosed:
+	case FailurePolicyOpen:
+		if g.FailOpenGrace <= 0 {
+			return fmt.Errorf("fail_open_grace must be positive when failure_policy is open")
+		}
+	default:
+		return fmt.Errorf("invalid failure_policy: %s (must be closed or open)", g.FailurePolicy)
+	}
+
+	return nil
+}
+
+// Validate validates protocols configuration
+func (p *ProtocolsConfig) Validate() error {
+	if p.OCI.Enabled {
+		if err := p.OCI.Validate(); err != nil {
+			return fmt.Errorf("oci config: %w", err)
+		}
+	}
+
+	if p.Maven.Enabled {
+		if err := p.Maven.Validate(); err != nil {
+			return fmt.Errorf("maven config: %w", err)
+		}
+	}
+
+	if p.NPM.Enabled {
+		if err := p.NPM.Validate(); err != nil {
+			return fmt.Errorf("npm config: %w", err)
+		}
+	}
+
+	if p.RubyGems.Enabled {
+		if err := p.RubyGems.Validate(); err != nil {
+			return fmt.Errorf("rubygems config: %w", err)
+		}
+	}
+
+	if p.Helm.Enabled {
+		if err := p.Helm.Validate(); err != nil {
+			return fmt.Errorf("helm config: %w", err)
+		}
+	}
+
+	if p.APT.Enabled {
+		if err := p.APT.Validate(); err != nil {
+			return fmt.Errorf("apt config: %w", err)
+		}
+	}
+
+	if p.Composer.Enabled {
+		if err := p.Composer.Validate(); err != nil {
+			return fmt.Errorf("composer config: %w", err)
+		}
+	}
+
+	if p.Conda.Enabled {
+		if err := p.Conda.Validate(); err != nil {
+			return fmt.Errorf("conda config: %w", err)
+		}
+	}
+
+	if p.Terraform.Enabled {
+		if err := p.Terraform.Validate(); err != nil {
+			return fmt.Errorf("terraform config: %w", err)
+		}
+	}
+
+	if p.APK.Enabled {
+		if err := p.APK.Validate(); err != nil {
+			return fmt.Errorf("apk config: %w", err)
+		}
+	}
+
+	if p.Raw.Enabled {
+		if err := p.Raw.Validate(); err != nil {
+			return fmt.Errorf("raw config: %w", err)
+		}
+	}
+
+	if p.LFS.Enabled {
+		if err := p.LFS.Validate(); err != nil {
+			return fmt.Errorf("lfs config: %w", err)
+		}
+	}
+
+	if p.CocoaPods.Enabled {
+		if err := p.CocoaPods.Validate(); err != nil {
+			return fmt.Errorf("cocoapods config: %w", err)
+		}
+	}
+
+	if p.Hex.Enabled {
+		if err := p.Hex.Validate(); err != nil {
+			return fmt.Errorf("hex config: %w", err)
+		}
+	}
+
+	if p.Pub.Enabled {
+		if err := p.Pub.Validate(); err != nil {
+			return fmt.Errorf("pub config: %w", err)
+		}
+	}
+
+	if p.Vagrant.Enabled {
+		if err := p.Vagrant.Validate(); err != nil {
+			return fmt.Errorf("vagrant config: %w", err)
+		}
+	}
+
+	// SECURITY: Validate path_prefix uniqueness for protocols with empty host
+	// This prevents routing conflicts where multiple protocols could match the same request
+	pathPrefixes := make(map[string]string) // map[path_prefix]protocol_name
+
+	if p.Maven.Enabled && p.Maven.Host == "" && p.Maven.PathPrefix != "" {
+		pathPrefixes[p.Maven.PathPrefix] = "maven"
+	}
+
+	if p.NPM.Enabled && p.NPM.Host == "" && p.NPM.PathPrefix != "" {
+		if existing, exists := pathPrefixes[p.NPM.PathPrefix]; exists {
+			return fmt.Errorf("path_prefix conflict: both %s and npm use path_prefix '%s' with empty host", existing, p.NPM.PathPrefix)
+		}
+		pathPrefixes[p.NPM.PathPrefix] = "npm"
+	}
+
+	if p.RubyGems.Enabled && p.RubyGems.Host == "" && p.RubyGems.PathPrefix != "" {
+		if existing, exists := pathPrefixes[p.RubyGems.PathPrefix]; exists {
+			return fmt.Errorf("path_prefix conflict: both %s and rubygems use path_prefix '%s' with empty host", existing, p.RubyGems.PathPrefix)
+		}
+		pathPrefixes[p.RubyGems.PathPrefix] = "rubygems"
+	}
+
+	if p.Helm.Enabled && p.Helm.Host == "" && p.Helm.PathPrefix != "" {
+		if existing, exists := pathPrefixes[p.Helm.PathPrefix]; exists {
+			return fmt.Errorf("path_prefix conflict: both %s and helm use path_prefix '%s' with empty host", existing, p.Helm.PathPrefix)
+		}
+		pathPrefixes[p.Helm.PathPrefix] = "helm"
+	}
+
+	if p.APT.Enabled && p.APT.Host == "" && p.APT.PathPrefix != "" {
+		if existing, exists := pathPrefixes[p.APT.PathPrefix]; exists {
+			return fmt.Errorf("path_prefix conflict: both %s and apt use path_prefix '%s' with empty host", existing, p.APT.PathPrefix)
+		}
+		pathPrefixes[p.APT.PathPrefix] = "apt"
+	}
+
+	if p.Composer.Enabled && p.Composer.Host == "" && p.Composer.PathPrefix != "" {
+		if existing, exists := pathPrefixes[p.Composer.PathPrefix]; exists {
+			return fmt.Errorf("path_prefix conflict: both %s and composer use path_prefix '%s' with empty host", existing, p.Composer.PathPrefix)
+		}
+		pathPrefixes[p.Composer.PathPrefix] = "composer"
+	}
+
+	if p.Conda.Enabled && p.Conda.Host == "" && p.Conda.PathPrefix != "" {
+		if existing, exists := pathPrefixes[p.Conda.PathPrefix]; exists {
+			return fmt.Errorf("path_prefix conflict: both %s and conda use path_prefix '%s' with empty host", existing, p.Conda.PathPrefix)
+		}
+		pathPrefixes[p.Conda.PathPrefix] = "conda"
+	}
+
+	if p.Terraform.Enabled && p.Terraform.Host == "" && p.Terraform.PathPrefix != "" {
+		if existing, exists := pathPrefixes[p.Terraform.PathPrefix]; exists {
+			return fmt.Errorf("path_prefix conflict: both %s and terraform use path_prefix '%s' with empty host", existing, p.Terraform.PathPrefix)
+		}
+		pathPrefixes[p.Terraform.PathPrefix] = "terraform"
+	}
+
+	if p.APK.Enabled && p.APK.Host == "" && p.APK.PathPrefix != "" {
+		if existing, exists := pathPrefixes[p.APK.PathPrefix]; exists {
+			return fmt.Errorf("path_prefix conflict: both %s and apk use path_prefix '%s' with empty host", existing, p.APK.PathPrefix)
+		}
+		pathPrefixes[p.APK.PathPrefix] = "apk"
+	}
+
+	if p.Raw.Enabled && p.Raw.Host == "" && p.Raw.PathPrefix != "" {
+		if existing, exists := pathPrefixes[p.Raw.PathPrefix]; exists {
+			return fmt.Errorf("path_prefix conflict: both %s and raw use path_prefix '%s' with empty host", existing, p.Raw.PathPrefix)
+		}
+		pathPrefixes[p.Raw.PathPrefix] = "raw"
+	}
+
+	if p.LFS.Enabled && p.LFS.Host == "" && p.LFS.PathPrefix != "" {
+		if existing, exists := pathPrefixes[p.LFS.PathPrefix]; exists {
+			return fmt.Errorf("path_prefix conflict: both %s and lfs use path_prefix '%s' with empty host", existing, p.LFS.PathPrefix)
+		}
+		pathPrefixes[p.LFS.PathPrefix] = "lfs"
+	}
+
+	if p.CocoaPods.Enabled && p.CocoaPods.Host == "" && p.CocoaPods.PathPrefix != "" {
+		if existing, exists := pathPrefixes[p.CocoaPods.PathPrefix]; exists {
+			return fmt.Errorf("path_prefix conflict: both %s and cocoapods use path_prefix '%s' with empty host", existing, p.CocoaPods.PathPrefix)
+		}
+		pathPrefixes[p.CocoaPods.PathPrefix] = "cocoapods"
+	}
+
+	if p.Hex.Enabled && p.Hex.Host == "" && p.Hex.PathPrefix != "" {
+		if existing, exists := pathPrefixes[p.Hex.PathPrefix]; exists {
+			return fmt.Errorf("path_prefix conflict: both %s and hex use path_prefix '%s' with empty host", existing, p.Hex.PathPrefix)
+		}
+		pathPrefixes[p.Hex.PathPrefix] = "hex"
+	}
+
+	if p.Pub.Enabled && p.Pub.Host == "" && p.Pub.PathPrefix != "" {
+		if existing, exists := pathPrefixes[p.Pub.PathPrefix]; exists {
+			return fmt.Errorf("path_prefix conflict: both %s and pub use path_prefix '%s' with empty host", existing, p.Pub.PathPrefix)
+		}
+		pathPrefixes[p.Pub.PathPrefix] = "pub"
+	}
+
+	if p.Vagrant.Enabled && p.Vagrant.Host == "" && p.Vagrant.PathPrefix != "" {
+		if existing, exists := pathPrefixes[p.Vagrant.PathPrefix]; exists {
+			return fmt.Errorf("path_prefix conflict: both %s and vagrant use path_prefix '%s' with empty host", existing, p.Vagrant.PathPrefix)
+		}
+		pathPrefixes[p.Vagrant.PathPrefix] = "vagrant"
+	}
+
+	// Note: OCI always uses /v2 path prefix, but this is implicitly unique
+	// since it's hardcoded in the detector and not configurable
+
+	return nil
+}
+
+// Validate validates OCI configuration
+func (o *OCIConfig) Validate() error {
+	if len(o.PullBackends) == 0 {
+		return fmt.Errorf("at least one pull backend is required")
+	}
+
+	for i, backend := range o.PullBackends {
+		if err := backend.Validate(); err != nil {
+			return fmt.Errorf("pull backend %d: %w", i, err)
+		}
+	}
+
+	if err := o.PushBackend.Validate(); err != nil {
+		return fmt.Errorf("push backend: %w", err)
+	}
+
+	// Team routing only applies to the pull cascade; the push backend is always used for writes
+	if len(o.PushBackend.Teams) > 0 {
+		return fmt.Errorf("push backend: teams is only supported on pull backends")
+	}
+
+	if o.NotFoundCacheTTL < 0 {
+		return fmt.Errorf("not_found_cache_ttl must be non-negative")
+	}
+
+	// Docker fetches Bearer tokens from the realm of the challenge
+	if o.ClientAuth.Realm != "" {
+		if u, err := url.Parse(o.ClientAuth.Realm); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("client_auth: realm must be the absolute http(s) URL of a token endpoint (got: %q)", o.ClientAuth.Realm)
+		}
+	}
+
+	if o.Replication.Enabled {
+		if err := o.Replication.Validate(o.PushBackend.Name); err != nil {
+			return fmt.Errorf("replication: %w", err)
+		}
+	}
+
+	if o.Trash.Retention < 0 {
+		return fmt.Errorf("trash: retention must be non-negative")
+	}
+
+	if o.PlatformFilter.Enabled {
+		if err := o.PlatformFilter.Validate(); err != nil {
+			return fmt.Errorf("platform_filter: %w", err)
+		}
+	}
+
+	if o.MirrorNamespace.Enabled {
+		if err := o.MirrorNamespace.Validate(o.PullBackends); err != nil {
+			return fmt.Errorf("mirror_namespace: %w", err)
+		}
+	}
+
+	if err := o.Naming.Validate(); err != nil {
+		return fmt.Errorf("naming: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates mirror namespace configuration against the pull backends it
+// maps registries to
+func (m *MirrorNamespaceConfig) Validate(pullBackends []OCIBackendConfig) error {
+	if !repositoryComponentPattern.MatchString(m.Prefix) {
+		return fmt.Errorf("invalid prefix %q (must be a single lowercase repository name component)", m.Prefix)
+	}
+
+	registries := make(map[string]bool)
+	for i, registry := range m.Registries {
+		if registry.Registry == "" || strings.Contains(registry.Registry, "/") {
+			return fmt.Errorf("registry %d: invalid registry %q", i, registry.Registry)
+		}
+		if registries[registry.Registry] {
+			return fmt.Errorf("registry %d: duplicate registry %q", i, registry.Registry)
+		}
+		registries[registry.Registry] = true
+
+		if !slices.ContainsFunc(pullBackends, func(b OCIBackendConfig) bool { return b.Name == registry.Backend }) {
+			return fmt.Errorf("registry %d: backend %q is not a pull backend", i, registry.Backend)
+		}
+	}
+	return nil
+}
+
+// Validate validates platform filter configuration
+func (p *PlatformFilterConfig) Validate() error {
+	if len(p.Platforms) == 0 && !p.ClientHints {
+		return fmt.Errorf("platforms or client_hints is required")
+	}
+	for _, platform := range p.Platforms {
+		parts := strings.Split(platform, "/")
+		if len(parts) < 2 || len(parts) > 3 || slices.Contains(parts, "") {
+			return fmt.Errorf("invalid platform %q (expected os/arch or os/arch/variant)", platform)
+		}
+	}
+	if p.CacheTTL < 0 {
+		return fmt.Errorf("cache_ttl must be non-negative")
+	}
+	return nil
+}
+
+// Validate validates naming convention configuration
+func (n *NamingConfig) Validate() error {
+	for _, pattern := range n.Patterns {
+		if pattern == "" {
+			return fmt.Errorf("patterns must not be empty")
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Validate validates replication configuration. pushBackendName is the name of the
+// push backend replicated from, which the target must not share.
+func (r *ReplicationConfig) Validate(pushBackendName string) error {
+	if err := r.Target.Validate(); err != nil {
+		return fmt.Errorf("target: %w", err)
+	}
+	if r.Target.Name == "" {
+		return fmt.Errorf("target: name is required")
+	}
+	if r.Target.Name == pushBackendName {
+		return fmt.Errorf("target: name must differ from the push backend name %q", pushBackendName)
+	}
+
+	if r.Workers < 0 {
+		return fmt.Errorf("workers must be non-negative")
+	}
+	if r.QueueSize < 0 {
+		return fmt.Errorf("queue_size must be non-negative")
+	}
+	if r.ReconcileInterval < 0 {
+		return fmt.Errorf("reconcile_interval must be non-negative")
+	}
+
+	return nil
+}
+
+// Validate validates Maven configuration
+func (m *MavenConfig) Validate() error {
+	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
+	if m.Host == "" && m.PathPrefix == "" {
+		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+	}
+
+	// Validate path_prefix format
+	if m.PathPrefix != "" {
+		if !strings.HasPrefix(m.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/' (got: %s)", m.PathPrefix)
+		}
+	}
+
+	if err := m.Backend.Validate(); err != nil {
+		return fmt.Errorf("backend: %w", err)
+	}
+
+	var candidateName string
+	if m.Candidate != nil {
+		if err := m.Candidate.Validate(); err != nil {
+			return fmt.Errorf("candidate: %w", err)
+		}
+		if m.Candidate.Name == "" {
+			return fmt.Errorf("candidate: name is required")
+		}
+		candidateName = m.Candidate.Name
+	}
+
+	if err := validateCandidate(m.CandidatePercent, m.Backend.Name, candidateName); err != nil {
+		return err
+	}
+
+	var upstreamName string
+	if m.Upstream != nil {
+		if err := m.Upstream.Validate(); err != nil {
+			return fmt.Errorf("upstream: %w", err)
+		}
+		if m.Upstream.Name == "" {
+			return fmt.Errorf("upstream: name is required")
+		}
+		upstreamName = m.Upstream.Name
+	}
+
+	if err := m.Naming.Validate(); err != nil {
+		return fmt.Errorf("naming: %w", err)
+	}
+
+	return validateUpstream(m.WriteBack, upstreamName, m.Backend.Name, candidateName)
+}
+
+// Validate validates NPM configuration
+func (n *NPMConfig) Validate() error {
+	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
+	if n.Host == "" && n.PathPrefix == "" {
+		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+	}
+
+	// Validate path_prefix format
+	if n.PathPrefix != "" {
+		if !strings.HasPrefix(n.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/' (got: %s)", n.PathPrefix)
+		}
+	}
+
+	if err := n.Backend.Validate(); err != nil {
+		return fmt.Errorf("backend: %w", err)
+	}
+
+	var candidateName string
+	if n.Candidate != nil {
+		if err := n.Candidate.Validate(); err != nil {
+			return fmt.Errorf("candidate: %w", err)
+		}
+		if n.Candidate.Name == "" {
+			return fmt.Errorf("candidate: name is required")
+		}
+		candidateName = n.Candidate.Name
+	}
+
+	if err := validateCandidate(n.CandidatePercent, n.Backend.Name, candidateName); err != nil {
+		return err
+	}
+
+	var upstreamName string
+	if n.Upstream != nil {
+		if err := n.Upstream.Validate(); err != nil {
+			return fmt.Errorf("upstream: %w", err)
+		}
+		if n.Upstream.Name == "" {
+			return fmt.Errorf("upstream: name is required")
+		}
+		upstreamName = n.Upstream.Name
+	}
+
+	for i, scope := range n.Scopes {
+		if !npmScopePattern.MatchString(scope) {
+			return fmt.Errorf("scopes[%d]: invalid scope %q (must be lowercase and start with @, e.g. @acme)", i, scope)
+		}
+		if slices.Contains(n.Scopes[:i], scope) {
+			return fmt.Errorf("scopes[%d]: duplicate scope %q", i, scope)
+		}
+	}
+
+	if err := n.Naming.Validate(); err != nil {
+		return fmt.Errorf("naming: %w", err)
+	}
+
+	return validateUpstream(n.WriteBack, upstreamName, n.Backend.Name, candidateName)
+}
+
+// Validate validates RubyGems configuration
+func (g *RubyGemsConfig) Validate() error {
+	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
+	if g.Host == "" && g.PathPrefix == "" {
+		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+	}
+
+	// Validate path_prefix format
+	if g.PathPrefix != "" {
+		if !strings.HasPrefix(g.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/' (got: %s)", g.PathPrefix)
+		}
+	}
+
+	if err := g.Backend.Validate(); err != nil {
+		return fmt.Errorf("backend: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates Helm configuration
+func (h *HelmConfig) Validate() error {
+	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
+	if h.Host == "" && h.PathPrefix == "" {
+		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+	}
+
+	// Validate path_prefix format
+	if h.PathPrefix != "" {
+		if !strings.HasPrefix(h.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/' (got: %s)", h.PathPrefix)
+		}
+	}
+
+	if err := h.Backend.Validate(); err != nil {
+		return fmt.Errorf("backend: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates APT configuration
+func (a *APTConfig) Validate() error {
+	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
+	if a.Host == "" && a.PathPrefix == "" {
+		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+	}
+
+	// Validate path_prefix format
+	if a.PathPrefix != "" {
+		if !strings.HasPrefix(a.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/' (got: %s)", a.PathPrefix)
+		}
+	}
+
+	if err := a.Backend.Validate(); err != nil {
+		return fmt.Errorf("backend: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates Composer configuration
+func (c *ComposerConfig) Validate() error {
+	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
+	if c.Host == "" && c.PathPrefix == "" {
+		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+	}
+
+	// Validate path_prefix format
+	if c.PathPrefix != "" {
+		if !strings.HasPrefix(c.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/' (got: %s)", c.PathPrefix)
+		}
+	}
+
+	if err := c.Backend.Validate(); err != nil {
+		return fmt.Errorf("backend: %w", err)
+	}
+
+	var upstreamName string
+	if c.Upstream != nil {
+		if err := c.Upstream.Validate(); err != nil {
+			return fmt.Errorf("upstream: %w", err)
+		}
+		if c.Upstream.Name == "" {
+			return fmt.Errorf("upstream: name is required")
+		}
+		upstreamName = c.Upstream.Name
+	}
+
+	return validateUpstream(false, upstreamName, c.Backend.Name, "")
+}
+
+// Validate validates Conda configuration
+func (c *CondaConfig) Validate() error {
+	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
+	if c.Host == "" && c.PathPrefix == "" {
+		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+	}
+
+	// Validate path_prefix format
+	if c.PathPrefix != "" {
+		if !strings.HasPrefix(c.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/' (got: %s)", c.PathPrefix)
+		}
+	}
+
+	if len(c.Channels) == 0 {
+		return fmt.Errorf("at least one channel is required")
+	}
+
+	channels := make(map[string]bool, len(c.Channels))
+	backends := make(map[string]bool, len(c.Channels))
+	for i, channel := range c.Channels {
+		if channel.Name == "" || channel.Name == "." || channel.Name == ".." || strings.ContainsAny(channel.Name, "/?#") {
+			return fmt.Errorf("channels[%d]: invalid name %q", i, channel.Name)
+		}
+		if channels[channel.Name] {
+			return fmt.Errorf("channels[%d]: duplicate channel %q", i, channel.Name)
+		}
+		channels[channel.Name] = true
+
+		if err := channel.Backend.Validate(); err != nil {
+			return fmt.Errorf("channels[%d] (%s): backend: %w", i, channel.Name, err)
+		}
+
+		// HTTP clients, circuit breakers and metrics are keyed by backend name
+		if channel.Backend.Name != "" {
+			if backends[channel.Backend.Name] {
+				return fmt.Errorf("channels[%d] (%s): duplicate backend name %q", i, channel.Name, channel.Backend.Name)
+			}
+			backends[channel.Backend.Name] = true
+		}
+
+		if channel.RepodataCacheTTL < 0 {
+			return fmt.Errorf("channels[%d] (%s): repodata_cache_ttl must be non-negative", i, channel.Name)
+		}
+	}
+
+	return nil
+}
+
+// Validate validates Terraform configuration
+func (t *TerraformConfig) Validate() error {
+	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
+	if t.Host == "" && t.PathPrefix == "" {
+		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+	}
+
+	// Validate path_prefix format
+	if t.PathPrefix != "" {
+		if !strings.HasPrefix(t.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/' (got: %s)", t.PathPrefix)
+		}
+	}
+
+	if err := t.Backend.Validate(); err != nil {
+		return fmt.Errorf("backend: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates APK configuration
+func (a *APKConfig) Validate() error {
+	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
+	if a.Host == "" && a.PathPrefix == "" {
+		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+	}
+
+	// Validate path_prefix format
+	if a.PathPrefix != "" {
+		if !strings.HasPrefix(a.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/' (got: %s)", a.PathPrefix)
+		}
+	}
+
+	if err := a.Backend.Validate(); err != nil {
+		return fmt.Errorf("backend: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates Git LFS configuration
+func (l *LFSConfig) Validate() error {
+	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
+	if l.Host == "" && l.PathPrefix == "" {
+		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+	}
+
+	// Validate path_prefix format
+	if l.PathPrefix != "" {
+		if !strings.HasPrefix(l.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/' (got: %s)", l.PathPrefix)
+		}
+	}
+
+	if err := l.Backend.Validate(); err != nil {
+		return fmt.Errorf("backend: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates CocoaPods configuration
+func (c *CocoaPodsConfig) Validate() error {
+	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
+	if c.Host == "" && c.PathPrefix == "" {
+		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+	}
+
+	// Validate path_prefix format
+	if c.PathPrefix != "" {
+		if !strings.HasPrefix(c.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/' (got: %s)", c.PathPrefix)
+		}
+	}
+
+	if err := c.Backend.Validate(); err != nil {
+		return fmt.Errorf("backend: %w", err)
+	}
+
+	if c.BinaryBackend != nil {
+		if err := c.BinaryBackend.Validate(); err != nil {
+			return fmt.Errorf("binary_backend: %w", err)
+		}
+		if c.BinaryBackend.Name == "" {
+			return fmt.Errorf("binary_backend: name is required")
+		}
+		if c.BinaryBackend.Name == c.Backend.Name {
+			return fmt.Errorf("binary_backend: name must differ from the backend's (%s)", c.Backend.Name)
+		}
+	}
+
+	return nil
+}
+
+// Validate validates Hex configuration
+func (c *HexConfig) Validate() error {
+	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
+	if c.Host == "" && c.PathPrefix == "" {
+		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+	}
+
+	// Validate path_prefix format
+	if c.PathPrefix != "" {
+		if !strings.HasPrefix(c.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/' (got: %s)", c.PathPrefix)
+		}
+	}
+
+	if err := c.Backend.Validate(); err != nil {
+		return fmt.Errorf("backend: %w", err)
+	}
+
+	var upstreamName string
+	if c.Upstream != nil {
+		if err := c.Upstream.Validate(); err != nil {
+			return fmt.Errorf("upstream: %w", err)
+		}
+		if c.Upstream.Name == "" {
+			return fmt.Errorf("upstream: name is required")
+		}
+		upstreamName = c.Upstream.Name
+	}
+
+	if c.PublishBackend != nil {
+		if err := c.PublishBackend.Validate(); err != nil {
+			return fmt.Errorf("publish_backend: %w", err)
+		}
+		if c.PublishBackend.Name == "" {
+			return fmt.Errorf("publish_backend: name is required")
+		}
+		if c.PublishBackend.Name == c.Backend.Name || c.PublishBackend.Name == upstreamName {
+			return fmt.Errorf("publish_backend: name must differ from the backend and upstream names (got: %s)", c.PublishBackend.Name)
+		}
+	}
+
+	return validateUpstream(false, upstreamName, c.Backend.Name, "")
+}
+
+// Validate validates Dart pub configuration
+func (c *PubConfig) Validate() error {
+	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
+	if c.Host == "" && c.PathPrefix == "" {
+		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+	}
+
+	// Validate path_prefix format
+	if c.PathPrefix != "" {
+		if !strings.HasPrefix(c.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/' (got: %s)", c.PathPrefix)
+		}
+	}
+
+	if err := c.Backend.Validate(); err != nil {
+		return fmt.Errorf("backend: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates Vagrant box catalog configuration
+func (c *VagrantConfig) Validate() error {
+	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
+	if c.Host == "" && c.PathPrefix == "" {
+		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+	}
+
+	// Validate path_prefix format
+	if c.PathPrefix != "" {
+		if !strings.HasPrefix(c.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/' (got: %s)", c.PathPrefix)
+		}
+	}
+
+	if err := c.Backend.Validate(); err != nil {
+		return fmt.Errorf("backend: %w", err)
+	}
+
+	// Download backends are served below downloads/<name>/, and HTTP clients,
+	// circuit breakers and metrics are keyed by backend name
+	names := map[string]bool{c.Backend.Name: true}
+	for i, backend := range c.DownloadBackends {
+		if backend.Name == "" || backend.Name == "." || backend.Name == ".." || strings.ContainsAny(backend.Name, "/?#") {
+			return fmt.Errorf("download_backends[%d]: invalid name %q", i, backend.Name)
+		}
+		if names[backend.Name] {
+			return fmt.Errorf("download_backends[%d]: duplicate backend name %q", i, backend.Name)
+		}
+		names[backend.Name] = true
+
+		if err := backend.Validate(); err != nil {
+			return fmt.Errorf("download_backends[%d] (%s): %w", i, backend.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Validate validates raw repository configuration
+func (c *RawConfig) Validate() error {
+	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
+	if c.Host == "" && c.PathPrefix == "" {
+		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+	}
+
+	// Validate path_prefix format
+	if c.PathPrefix != "" {
+		if !strings.HasPrefix(c.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/' (got: %s)", c.PathPrefix)
+		}
+	}
+
+	if len(c.Repositories) == 0 {
+		return fmt.Errorf("at least one repository is required")
+	}
+
+	paths := make(map[string]bool, len(c.Repositories))
+	backends := make(map[string]bool, len(c.Repositories))
+	for i, repository := range c.Repositories {
+		if !strings.HasPrefix(repository.Path, "/") || path.Clean(repository.Path) != repository.Path {
+			return fmt.Errorf("repositories[%d]: path must be a clean path starting with '/' (got: %q)", i, repository.Path)
+		}
+		if paths[repository.Path] {
+			return fmt.Errorf("repositories[%d]: duplicate path %q", i, repository.Path)
+		}
+		paths[repository.Path] = true
+
+		if err := repository.Backend.Validate(); err != nil {
+			return fmt.Errorf("repositories[%d] (%s): backend: %w", i, repository.Path, err)
+		}
+
+		// HTTP clients, circuit breakers and metrics are keyed by backend name
+		if repository.Backend.Name != "" {
+			if backends[repository.Backend.Name] {
+				return fmt.Errorf("repositories[%d] (%s): duplicate backend name %q", i, repository.Path, repository.Backend.Name)
+			}
+			backends[repository.Backend.Name] = true
+		}
+
+		if repository.MaxUploadSize < 0 {
+			return fmt.Errorf("repositories[%d] (%s): max_upload_size must be non-negative", i, repository.Path)
+		}
+	}
+
+	return nil
+}
+
+// validateUpstream validates the read-through upstream settings of a single-backend
+// protocol. upstreamName is empty when no upstream is configured.
+func validateUpstream(writeBack bool, upstreamName, backendName, candidateName string) error {
+	if upstreamName == "" {
+		if writeBack {
+			return fmt.Errorf("write_back requires an upstream backend")
+		}
+		return nil
+	}
+
+	// HTTP clients, circuit breakers and metrics are keyed by backend name
+	if upstreamName == backendName || upstreamName == candidateName {
+		return fmt.Errorf("upstream name must differ from the backend and candidate names (got: %s)", upstreamName)
+	}
+
+	return nil
+}
+
+// validateCandidate validates the A/B experiment settings of a single-backend protocol.
+// candidateName is empty when no candidate backend is configured.
+func validateCandidate(percent float64, backendName, candidateName string) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("candidate_percent must be between 0 and 100 (got: %v)", percent)
+	}
+
+	if candidateName == "" {
+		if percent > 0 {
+			return fmt.Errorf("candidate_percent requires a candidate backend")
+		}
+		return nil
+	}
+
+	// HTTP clients, circuit breakers and metrics are keyed by backend name
+	if candidateName == backendName {
+		return fmt.Errorf("candidate name must differ from the backend name (got: %s)", candidateName)
+	}
+
+	return nil
+}
+
+// validateBackendCommon validates common backend configuration fields
+// This is a helper to eliminate code duplication across protocol-specific backend validators
+func validateBackendCommon(backendURL string, maxIdleConns, maxIdleConnsPerHost int, dialTimeout, requestTimeout time.Duration, circuitBreaker CircuitBreakerConfig) error {
+	// Validate URL
+	if backendURL == "" {
+		return fmt.Errorf("url is required")
+	}
+
+	if _, err := url.Parse(backendURL); err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+
+	// Validate connection pool settings
+	if maxIdleConns < 1 {
+		return fmt.Errorf("maxIdleConns must be at least 1")
+	}
+
+	if maxIdleConnsPerHost < 1 {
+		return fmt.Errorf("maxIdleConnsPerHost must be at least 1")
+	}
+
+	if maxIdleConnsPerHost > maxIdleConns {
+		return fmt.Errorf("maxIdleConnsPerHost cannot exceed maxIdleConns")
+	}
+
+	// Validate timeouts
+	if dialTimeout <= 0 {
+		return fmt.Errorf("invalid dialTimeout: %v", dialTimeout)
+	}
+
+	if requestTimeout <= 0 {
+		return fmt.Errorf("invalid requestTimeout: %v", requestTimeout)
+	}
+
+	// Validate circuit breaker settings
+	if circuitBreaker.Enabled {
+		if err := circuitBreaker.Validate(); err != nil {
+			return fmt.Errorf("circuit breaker: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validateResponseHeaderTimeout validates a backend's response header timeout, which
+// only has an effect when it is shorter than the request timeout
+func validateResponseHeaderTimeout(responseHeaderTimeout, requestTimeout time.Duration) error {
+	if responseHeaderTimeout < 0 {
+		return fmt.Errorf("response_header_timeout must be non-negative")
+	}
+	if responseHeaderTimeout > 0 && responseHeaderTimeout >= requestTimeout {
+		return fmt.Errorf("response_header_timeout (%v) must be less than request_timeout (%v)", responseHeaderTimeout, requestTimeout)
+	}
+
+	return nil
+}
+
+// validateFallthroughStatus validates a fallthrough_statuses entry: an error status
+// code or class. Success and redirect responses are always returned to the client.
+func validateFallthroughStatus(pattern string) error {
+	p := strings.ToLower(strings.TrimSpace(pattern))
+	if p == "4xx" || p == "5xx" {
+		return nil
+	}
+	code, err := strconv.Atoi(p)
+	if err != nil || code < 400 || code > 599 {
+		return fmt.Errorf("fallthrough_statuses: invalid status %q (must be a 4xx/5xx status code, \"4xx\" or \"5xx\")", pattern)
+	}
+	return nil
+}
+
+// Validate validates OCI backend configuration
+func (b *OCIBackendConfig) Validate() error {
+	for _, team := range b.Teams {
+		if strings.TrimSpace(team) == "" {
+			return fmt.Errorf("teams must not contain empty team names")
+		}
+	}
+
+	for _, pattern := range b.FallthroughStatuses {
+		if err := validateFallthroughStatus(pattern); err != nil {
+			return err
+		}
+	}
+
+	if err := validateBackendCommon(
+		b.URL,
+		b.MaxIdleConns,
+		b.MaxIdleConnsPerHost,
+		b.DialTimeout,
+		b.RequestTimeout,
+		b.CircuitBreaker,
+	); err != nil {
+		return err
+	}
+
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
+	}
+
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates Maven backend configuration
+func (b *MavenBackendConfig) Validate() error {
+	if err := validateBackendCommon(
+		b.URL,
+		b.MaxIdleConns,
+		b.MaxIdleConnsPerHost,
+		b.DialTimeout,
+		b.RequestTimeout,
+		b.CircuitBreaker,
+	); err != nil {
+		return err
+	}
+
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
+	}
+
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates NPM backend configuration
+func (b *NPMBackendConfig) Validate() error {
+	if err := validateBackendCommon(
+		b.URL,
+		b.MaxIdleConns,
+		b.MaxIdleConnsPerHost,
+		b.DialTimeout,
+		b.RequestTimeout,
+		b.CircuitBreaker,
+	); err != nil {
+		return err
+	}
+
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
+	}
+
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates RubyGems backend configuration
+func (b *RubyGemsBackendConfig) Validate() error {
+	if err := validateBackendCommon(
+		b.URL,
+		b.MaxIdleConns,
+		b.MaxIdleConnsPerHost,
+		b.DialTimeout,
+		b.RequestTimeout,
+		b.CircuitBreaker,
+	); err != nil {
+		return err
+	}
+
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
 	}
 
-	// At least one protocol must be enabled
-	if !c.Protocols.OCI.Enabled && !c.Protocols.Maven.Enabled && !c.Protocols.NPM.Enabled {
-		return fmt.Errorf("at least one protocol must be enabled")
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
 	}
 
 	return nil
 }
 
-// Validate validates server configuration
-func (s *ServerConfig) Validate() error {
-	if s.Port < 1 || s.Port > 65535 {
-		return fmt.Errorf("invalid port: %d", s.Port)
-	}
-
-	if s.ReadTimeout <= 0 {
-		return fmt.Errorf("invalid read timeout: %v", s.ReadTimeout)
+// Validate validates Helm backend configuration
+func (b *HelmBackendConfig) Validate() error {
+	if err := validateBackendCommon(
+		b.URL,
+		b.MaxIdleConns,
+		b.MaxIdleConnsPerHost,
+		b.DialTimeout,
+		b.RequestTimeout,
+		b.CircuitBreaker,
+	); err != nil {
+		return err
 	}
 
-	if s.WriteTimeout <= 0 {
-		return fmt.Errorf("invalid write timeout: %v", s.WriteTimeout)
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
 	}
 
-	if s.MaxConcurrentReqs < 1 {
-		return fmt.Errorf("maxConcurrentRequests must be at least 1")
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
 	}
 
 	return nil
 }
 
-// Validate validates GitHub configuration
-func (g *GitHubConfig) Validate() error {
-	if g.APIURL == "" {
-		return fmt.Errorf("apiURL is required")
-	}
-
-	if _, err := url.Parse(g.APIURL); err != nil {
-		return fmt.Errorf("invalid apiURL: %w", err)
+// Validate validates APT backend configuration
+func (b *APTBackendConfig) Validate() error {
+	if err := validateBackendCommon(
+		b.URL,
+		b.MaxIdleConns,
+		b.MaxIdleConnsPerHost,
+		b.DialTimeout,
+		b.RequestTimeout,
+		b.CircuitBreaker,
+	); err != nil {
+		return err
 	}
 
-	// RequiredOrg is optional - if empty, only PAT validation is performed
-	// If provided, organization membership will be checked
-
-	// SECURITY: Prevent team enforcement bypass
-	// If teams are required, org must also be specified since team checks
-	// only run inside the org membership validation block
-	if len(g.RequiredTeams) > 0 && g.RequiredOrg == "" {
-		return fmt.Errorf("required_org must be specified when required_teams is configured")
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
 	}
 
-	if g.AuthCacheTTL <= 0 {
-		return fmt.Errorf("invalid authCacheTTL: %v", g.AuthCacheTTL)
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
 	}
 
 	return nil
 }
 
-// Validate validates protocols configuration
-func (p *ProtocolsConfig) Validate() error {
-	if p.OCI.Enabled {
-		if err := p.OCI.Validate(); err != nil {
-			return fmt.Errorf("oci config: %w", err)
-		}
+// Validate validates Composer backend configuration
+func (b *ComposerBackendConfig) Validate() error {
+	if err := validateBackendCommon(
+		b.URL,
+		b.MaxIdleConns,
+		b.MaxIdleConnsPerHost,
+		b.DialTimeout,
+		b.RequestTimeout,
+		b.CircuitBreaker,
+	); err != nil {
+		return err
 	}
 
-	if p.Maven.Enabled {
-		if err := p.Maven.Validate(); err != nil {
-			return fmt.Errorf("maven config: %w", err)
-		}
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
 	}
 
-	if p.NPM.Enabled {
-		if err := p.NPM.Validate(); err != nil {
-			return fmt.Errorf("npm config: %w", err)
-		}
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
 	}
 
-	// SECURITY: Validate path_prefix uniqueness for protocols with empty host
-	// This prevents routing conflicts where multiple protocols could match the same request
-	pathPrefixes := make(map[string]string) // map[path_prefix]protocol_name
+	return nil
+}
 
-	if p.Maven.Enabled && p.Maven.Host == "" && p.Maven.PathPrefix != "" {
-		pathPrefixes[p.Maven.PathPrefix] = "maven"
+// Validate validates Conda backend configuration
+func (b *CondaBackendConfig) Validate() error {
+	if err := validateBackendCommon(
+		b.URL,
+		b.MaxIdleConns,
+		b.MaxIdleConnsPerHost,
+		b.DialTimeout,
+		b.RequestTimeout,
+		b.CircuitBreaker,
+	); err != nil {
+		return err
 	}
 
-	if p.NPM.Enabled && p.NPM.Host == "" && p.NPM.PathPrefix != "" {
-		if existing, exists := pathPrefixes[p.NPM.PathPrefix]; exists {
-			return fmt.Errorf("path_prefix conflict: both %s and npm use path_prefix '%s' with empty host", existing, p.NPM.PathPrefix)
-		}
-		pathPrefixes[p.NPM.PathPrefix] = "npm"
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
 	}
 
-	// Note: OCI always uses /v2 path prefix, but this is implicitly unique
-	// since it's hardcoded in the detector and not configurable
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
+	}
 
 	return nil
 }
 
-// Validate validates OCI configuration
-func (o *OCIConfig) Validate() error {
-	if len(o.PullBackends) == 0 {
-		return fmt.Errorf("at least one pull backend is required")
+// Validate validates Terraform backend configuration
+func (b *TerraformBackendConfig) Validate() error {
+	if err := validateBackendCommon(
+		b.URL,
+		b.MaxIdleConns,
+		b.MaxIdleConnsPerHost,
+		b.DialTimeout,
+		b.RequestTimeout,
+		b.CircuitBreaker,
+	); err != nil {
+		return err
 	}
 
-	for i, backend := range o.PullBackends {
-		if err := backend.Validate(); err != nil {
-			return fmt.Errorf("pull backend %d: %w", i, err)
-		}
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
 	}
 
-	if err := o.PushBackend.Validate(); err != nil {
-		return fmt.Errorf("push backend: %w", err)
+	// The APIs are proxied below the backend URL, so they must be paths on it
+	if !strings.HasPrefix(b.ModulesPath, "/") || !strings.HasSuffix(b.ModulesPath, "/") {
+		return fmt.Errorf("modules_path must be a path starting and ending with '/' (got: %q)", b.ModulesPath)
+	}
+	if !strings.HasPrefix(b.ProvidersPath, "/") || !strings.HasSuffix(b.ProvidersPath, "/") {
+		return fmt.Errorf("providers_path must be a path starting and ending with '/' (got: %q)", b.ProvidersPath)
+	}
+
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
 	}
 
 	return nil
 }
 
-// Validate validates Maven configuration
-func (m *MavenConfig) Validate() error {
-	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
-	if m.Host == "" && m.PathPrefix == "" {
-		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+// Validate validates raw repository backend configuration
+func (b *RawBackendConfig) Validate() error {
+	if err := validateBackendCommon(
+		b.URL,
+		b.MaxIdleConns,
+		b.MaxIdleConnsPerHost,
+		b.DialTimeout,
+		b.RequestTimeout,
+		b.CircuitBreaker,
+	); err != nil {
+		return err
 	}
 
-	// Validate path_prefix format
-	if m.PathPrefix != "" {
-		if !strings.HasPrefix(m.PathPrefix, "/") {
-			return fmt.Errorf("path_prefix must start with '/' (got: %s)", m.PathPrefix)
-		}
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
 	}
 
-	if err := m.Backend.Validate(); err != nil {
-		return fmt.Errorf("backend: %w", err)
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
 	}
 
 	return nil
 }
 
-// Validate validates NPM configuration
-func (n *NPMConfig) Validate() error {
-	// SECURITY: Prevent routing conflicts - require explicit path_prefix when host is not set
-	if n.Host == "" && n.PathPrefix == "" {
-		return fmt.Errorf("path_prefix is required when host is empty (set either host for domain-based routing or path_prefix for path-based routing)")
+// Validate validates APK backend configuration
+func (b *APKBackendConfig) Validate() error {
+	if err := validateBackendCommon(
+		b.URL,
+		b.MaxIdleConns,
+		b.MaxIdleConnsPerHost,
+		b.DialTimeout,
+		b.RequestTimeout,
+		b.CircuitBreaker,
+	); err != nil {
+		return err
 	}
 
-	// Validate path_prefix format
-	if n.PathPrefix != "" {
-		if !strings.HasPrefix(n.PathPrefix, "/") {
-			return fmt.Errorf("path_prefix must start with '/' (got: %s)", n.PathPrefix)
-		}
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
 	}
 
-	if err := n.Backend.Validate(); err != nil {
-		return fmt.Errorf("backend: %w", err)
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
 	}
 
 	return nil
 }
 
-// validateBackendCommon validates common backend configuration fields
-// This is a helper to eliminate code duplication across protocol-specific backend validators
-func validateBackendCommon(backendURL string, maxIdleConns, maxIdleConnsPerHost int, dialTimeout, requestTimeout time.Duration, circuitBreaker CircuitBreakerConfig) error {
-	// Validate URL
-	if backendURL == "" {
-		return fmt.Errorf("url is required")
-	}
-
-	if _, err := url.Parse(backendURL); err != nil {
-		return fmt.Errorf("invalid url: %w", err)
+// Validate validates Git LFS backend configuration
+func (b *LFSBackendConfig) Validate() error {
+	if err := validateBackendCommon(
+		b.URL,
+		b.MaxIdleConns,
+		b.MaxIdleConnsPerHost,
+		b.DialTimeout,
+		b.RequestTimeout,
+		b.CircuitBreaker,
+	); err != nil {
+		return err
 	}
 
-	// Validate connection pool settings
-	if maxIdleConns < 1 {
-		return fmt.Errorf("maxIdleConns must be at least 1")
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
 	}
 
-	if maxIdleConnsPerHost < 1 {
-		return fmt.Errorf("maxIdleConnsPerHost must be at least 1")
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
 	}
 
-	if maxIdleConnsPerHost > maxIdleConns {
-		return fmt.Errorf("maxIdleConnsPerHost cannot exceed maxIdleConns")
-	}
+	return nil
+}
 
-	// Validate timeouts
-	if dialTimeout <= 0 {
-		return fmt.Errorf("invalid dialTimeout: %v", dialTimeout)
+// Validate validates CocoaPods backend configuration
+func (b *CocoaPodsBackendConfig) Validate() error {
+	if err := validateBackendCommon(
+		b.URL,
+		b.MaxIdleConns,
+		b.MaxIdleConnsPerHost,
+		b.DialTimeout,
+		b.RequestTimeout,
+		b.CircuitBreaker,
+	); err != nil {
+		return err
 	}
 
-	if requestTimeout <= 0 {
-		return fmt.Errorf("invalid requestTimeout: %v", requestTimeout)
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
 	}
 
-	// Validate circuit breaker settings
-	if circuitBreaker.Enabled {
-		if err := circuitBreaker.Validate(); err != nil {
-			return fmt.Errorf("circuit breaker: %w", err)
-		}
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
 	}
 
 	return nil
 }
 
-// Validate validates OCI backend configuration
-func (b *OCIBackendConfig) Validate() error {
-	return validateBackendCommon(
+// Validate validates Hex backend configuration
+func (b *HexBackendConfig) Validate() error {
+	if err := validateBackendCommon(
 		b.URL,
 		b.MaxIdleConns,
 		b.MaxIdleConnsPerHost,
 		b.DialTimeout,
 		b.RequestTimeout,
 		b.CircuitBreaker,
-	)
+	); err != nil {
+		return err
+	}
+
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
+	}
+
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
+	}
+
+	return nil
 }
 
-// Validate validates Maven backend configuration
-func (b *MavenBackendConfig) Validate() error {
-	return validateBackendCommon(
+// Validate validates Dart pub backend configuration
+func (b *PubBackendConfig) Validate() error {
+	if err := validateBackendCommon(
 		b.URL,
 		b.MaxIdleConns,
 		b.MaxIdleConnsPerHost,
 		b.DialTimeout,
 		b.RequestTimeout,
 		b.CircuitBreaker,
-	)
+	); err != nil {
+		return err
+	}
+
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
+	}
+
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
+	}
+
+	return nil
 }
 
-// Validate validates NPM backend configuration
-func (b *NPMBackendConfig) Validate() error {
-	return validateBackendCommon(
+// Validate validates Vagrant backend configuration
+func (b *VagrantBackendConfig) Validate() error {
+	if err := validateBackendCommon(
 		b.URL,
 		b.MaxIdleConns,
 		b.MaxIdleConnsPerHost,
 		b.DialTimeout,
 		b.RequestTimeout,
 		b.CircuitBreaker,
-	)
+	); err != nil {
+		return err
+	}
+
+	if err := validateResponseHeaderTimeout(b.ResponseHeaderTimeout, b.RequestTimeout); err != nil {
+		return err
+	}
+
+	if err := b.Transport.Validate(); err != nil {
+		return fmt.Errorf("transport: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates backend transport configuration
+func (t *TransportConfig) Validate() error {
+	if t.DNSRefreshInterval < 0 {
+		return fmt.Errorf("dns_refresh_interval must be non-negative")
+	}
+	if t.DNSFailureCooldown < 0 {
+		return fmt.Errorf("dns_failure_cooldown must be non-negative")
+	}
+	if t.TLSHandshakeTimeout < 0 {
+		return fmt.Errorf("tls_handshake_timeout must be non-negative")
+	}
+	if t.ExpectContinueTimeout < 0 {
+		return fmt.Errorf("expect_continue_timeout must be non-negative")
+	}
+	if t.MaxStreamDuration < 0 {
+		return fmt.Errorf("max_stream_duration must be non-negative")
+	}
+	if t.MinBytesPerSec < 0 {
+		return fmt.Errorf("min_bytes_per_sec must be non-negative")
+	}
+	if t.MinThroughputWindow < 0 {
+		return fmt.Errorf("min_throughput_window must be non-negative")
+	}
+	if t.MaxInFlightBytes < 0 {
+		return fmt.Errorf("max_in_flight_bytes must be non-negative")
+	}
+	if t.WarmConnections < 0 {
+		return fmt.Errorf("warm_connections must be non-negative")
+	}
+
+	switch t.IPFamily {
+	case "", IPFamilyIPv4, IPFamilyIPv6, IPFamilyPreferIPv4, IPFamilyPreferIPv6:
+	default:
+		return fmt.Errorf("invalid ip_family: %s (must be %s, %s, %s or %s)",
+			t.IPFamily, IPFamilyIPv4, IPFamilyIPv6, IPFamilyPreferIPv4, IPFamilyPreferIPv6)
+	}
+
+	switch t.Redirects {
+	case "", RedirectsPassThrough, RedirectsFollow:
+	default:
+		return fmt.Errorf("invalid redirects: %s (must be %s or %s)", t.Redirects, RedirectsPassThrough, RedirectsFollow)
+	}
+	if t.MaxRedirects < 0 {
+		return fmt.Errorf("max_redirects must be non-negative")
+	}
+	if strings.ContainsAny(t.HostHeader, "/ \t\r\n") {
+		return fmt.Errorf("invalid host_header: %q (must be a host with optional port)", t.HostHeader)
+	}
+	if strings.ContainsAny(t.TLSServerName, ":/ \t\r\n") {
+		return fmt.Errorf("invalid tls_server_name: %q (must be a hostname)", t.TLSServerName)
+	}
+
+	return nil
 }
 
 // Validate validates circuit breaker configuration
@@ -285,6 +2366,16 @@ func (cb *CircuitBreakerConfig) Validate() error {
 		return fmt.Errorf("failureThreshold must be between 0 and 1")
 	}
 
+	switch cb.Type {
+	case "", CircuitBreakerFailureRatio:
+	case CircuitBreakerLatency:
+		if cb.SlowCallThreshold <= 0 {
+			return fmt.Errorf("slow_call_threshold must be positive for %s circuit breakers", CircuitBreakerLatency)
+		}
+	default:
+		return fmt.Errorf("invalid type: %s (must be %s or %s)", cb.Type, CircuitBreakerFailureRatio, CircuitBreakerLatency)
+	}
+
 	return nil
 }
 
@@ -325,3 +2416,37 @@ func (l *LoggingConfig) Validate() error {
 
 	return nil
 }
+
+// Validate validates admin configuration
+func (a *AdminConfig) Validate() error {
+	for _, user := range a.Users {
+		if strings.TrimSpace(user) == "" {
+			return fmt.Errorf("users must not contain empty usernames")
+		}
+	}
+
+	// Impersonation is only reachable by admins, so it is meaningless without any
+	if a.Impersonation && len(a.Users) == 0 {
+		return fmt.Errorf("impersonation requires at least one admin user")
+	}
+
+	return nil
+}
+
+// Validate validates rate limiting configuration
+func (r *RateLimitConfig) Validate() error {
+	if r.QueueTimeout < 0 {
+		return fmt.Errorf("queue_timeout must not be negative: %v", r.QueueTimeout)
+	}
+	if r.PerUserWriteRequests < 0 {
+		return fmt.Errorf("per_user_write_requests must not be negative: %v", r.PerUserWriteRequests)
+	}
+	if r.PerUserWriteBurst < 0 {
+		return fmt.Errorf("per_user_write_burst must not be negative: %d", r.PerUserWriteBurst)
+	}
+	if r.PerUserWriteRequests > 0 && !r.PerUserEnabled {
+		return fmt.Errorf("per_user_write_requests requires per_user_enabled")
+	}
+
+	return nil
+}
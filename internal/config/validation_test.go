@@ -128,6 +128,95 @@ func TestServerConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "maxConcurrentRequests must be at least 1",
 		},
+		{
+			name: "valid trusted proxies",
+			config: ServerConfig{
+				Port:              8080,
+				ReadTimeout:       60 * time.Second,
+				WriteTimeout:      300 * time.Second,
+				MaxConcurrentReqs: 1000,
+				TrustedProxies:    []string{"10.0.0.0/8", "192.0.2.10", "2001:db8::/32"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid trusted proxy",
+			config: ServerConfig{
+				Port:              8080,
+				ReadTimeout:       60 * time.Second,
+				WriteTimeout:      300 * time.Second,
+				MaxConcurrentReqs: 1000,
+				TrustedProxies:    []string{"10.0.0.0/33"},
+			},
+			wantErr: true,
+			errMsg:  `trusted_proxies: invalid trusted proxy "10.0.0.0/33"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+// TestSecurityHeadersConfig_Validate tests security header policy validation
+func TestSecurityHeadersConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  SecurityHeadersConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid defaults",
+			config:  SecurityHeadersConfig{HSTSMaxAge: DefaultHSTSMaxAge, FrameOptions: FrameOptionsDeny, UIFrameOptions: FrameOptionsSameOrigin},
+			wantErr: false,
+		},
+		{
+			name:    "valid preload",
+			config:  SecurityHeadersConfig{HSTSMaxAge: 2 * DefaultHSTSMaxAge, HSTSPreload: true},
+			wantErr: false,
+		},
+		{
+			name:    "negative max age",
+			config:  SecurityHeadersConfig{HSTSMaxAge: -time.Second},
+			wantErr: true,
+			errMsg:  "hsts_max_age must not be negative",
+		},
+		{
+			name:    "preload with short max age",
+			config:  SecurityHeadersConfig{HSTSMaxAge: 24 * time.Hour, HSTSPreload: true},
+			wantErr: true,
+			errMsg:  "hsts_preload requires hsts_max_age",
+		},
+		{
+			name:    "preload without subdomains",
+			config:  SecurityHeadersConfig{HSTSMaxAge: DefaultHSTSMaxAge, HSTSPreload: true, HSTSExcludeSubdomains: true},
+			wantErr: true,
+			errMsg:  "hsts_preload requires subdomains",
+		},
+		{
+			name:    "preload with HSTS disabled",
+			config:  SecurityHeadersConfig{HSTSMaxAge: DefaultHSTSMaxAge, HSTSPreload: true, HSTSDisabled: true},
+			wantErr: true,
+			errMsg:  "hsts_preload requires HSTS to be enabled",
+		},
+		{
+			name:    "invalid ui frame options",
+			config:  SecurityHeadersConfig{UIFrameOptions: "ALLOW-FROM https://example.com"},
+			wantErr: true,
+			errMsg:  "ui_frame_options must be DENY or SAMEORIGIN",
+		},
 	}
 
 	for _, tt := range tests {
@@ -223,6 +312,66 @@ func TestGitHubConfig_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "refresh ahead within TTL",
+			config: GitHubConfig{
+				APIURL:                "https://api.github.com",
+				AuthCacheTTL:          30 * time.Minute,
+				AuthCacheRefreshAhead: 5 * time.Minute,
+			},
+			wantErr: false,
+		},
+		{
+			name: "refresh ahead not shorter than TTL",
+			config: GitHubConfig{
+				APIURL:                "https://api.github.com",
+				AuthCacheTTL:          30 * time.Minute,
+				AuthCacheRefreshAhead: 30 * time.Minute,
+			},
+			wantErr: true,
+			errMsg:  "auth_cache_refresh_ahead must be between 0 and auth_cache_ttl",
+		},
+		{
+			name: "fail open with grace",
+			config: GitHubConfig{
+				APIURL:        "https://api.github.com",
+				AuthCacheTTL:  30 * time.Minute,
+				AuthTimeout:   10 * time.Second,
+				FailurePolicy: FailurePolicyOpen,
+				FailOpenGrace: time.Hour,
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail open without grace",
+			config: GitHubConfig{
+				APIURL:        "https://api.github.com",
+				AuthCacheTTL:  30 * time.Minute,
+				FailurePolicy: FailurePolicyOpen,
+			},
+			wantErr: true,
+			errMsg:  "fail_open_grace must be positive when failure_policy is open",
+		},
+		{
+			name: "unknown failure policy",
+			config: GitHubConfig{
+				APIURL:        "https://api.github.com",
+				AuthCacheTTL:  30 * time.Minute,
+				FailurePolicy: "ignore",
+			},
+			wantErr: true,
+			errMsg:  "invalid failure_policy",
+		},
+		{
+			name: "negative auth timeout",
+			config: GitHubConfig{
+				APIURL:       "https://api.github.com",
+				AuthCacheTTL: 30 * time.Minute,
+				AuthTimeout:  -time.Second,
+			},
+			wantErr: true,
+			errMsg:  "auth_timeout must not be negative",
+		},
 	}
 
 	for _, tt := range tests {
@@ -314,6 +463,42 @@ func TestCircuitBreakerConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "failureThreshold must be between 0 and 1",
 		},
+		{
+			name: "valid latency breaker",
+			config: CircuitBreakerConfig{
+				MaxRequests:       10,
+				Interval:          60 * time.Second,
+				Timeout:           30 * time.Second,
+				FailureThreshold:  0.5,
+				Type:              CircuitBreakerLatency,
+				SlowCallThreshold: 5 * time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "latency breaker without slow call threshold",
+			config: CircuitBreakerConfig{
+				MaxRequests:      10,
+				Interval:         60 * time.Second,
+				Timeout:          30 * time.Second,
+				FailureThreshold: 0.5,
+				Type:             CircuitBreakerLatency,
+			},
+			wantErr: true,
+			errMsg:  "slow_call_threshold must be positive",
+		},
+		{
+			name: "unknown type",
+			config: CircuitBreakerConfig{
+				MaxRequests:      10,
+				Interval:         60 * time.Second,
+				Timeout:          30 * time.Second,
+				FailureThreshold: 0.5,
+				Type:             "adaptive",
+			},
+			wantErr: true,
+			errMsg:  "invalid type: adaptive",
+		},
 	}
 
 	for _, tt := range tests {
@@ -482,6 +667,123 @@ func TestOCIConfig_Validate(t *testing.T) {
 			t.Errorf("unexpected error: %v", err)
 		}
 	})
+
+	validBackend := OCIBackendConfig{
+		URL:                 "http://registry:5000",
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 100,
+		DialTimeout:         10 * time.Second,
+		RequestTimeout:      300 * time.Second,
+	}
+
+	t.Run("empty team name on pull backend", func(t *testing.T) {
+		pull := validBackend
+		pull.Teams = []string{"platform", " "}
+		cfg := OCIConfig{
+			Enabled:      true,
+			PullBackends: []OCIBackendConfig{pull},
+			PushBackend:  validBackend,
+		}
+
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), "empty team names") {
+			t.Errorf("expected empty team name error, got: %v", err)
+		}
+	})
+
+	t.Run("teams on push backend", func(t *testing.T) {
+		push := validBackend
+		push.Teams = []string{"platform"}
+		cfg := OCIConfig{
+			Enabled:      true,
+			PullBackends: []OCIBackendConfig{validBackend},
+			PushBackend:  push,
+		}
+
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), "only supported on pull backends") {
+			t.Errorf("expected push backend teams error, got: %v", err)
+		}
+	})
+
+	t.Run("negative not found cache ttl", func(t *testing.T) {
+		cfg := OCIConfig{
+			Enabled:          true,
+			PullBackends:     []OCIBackendConfig{validBackend},
+			PushBackend:      validBackend,
+			NotFoundCacheTTL: -time.Second,
+		}
+
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), "not_found_cache_ttl must be non-negative") {
+			t.Errorf("expected not found cache TTL error, got: %v", err)
+		}
+	})
+
+	t.Run("negative trash retention", func(t *testing.T) {
+		cfg := OCIConfig{
+			Enabled:      true,
+			PullBackends: []OCIBackendConfig{validBackend},
+			PushBackend:  validBackend,
+			Trash:        TrashConfig{Enabled: true, Retention: -time.Hour},
+		}
+
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), "trash: retention must be non-negative") {
+			t.Errorf("expected trash retention error, got: %v", err)
+		}
+	})
+}
+
+// TestConfig_Validate_BackendTeams tests that team-scoped backends require an org
+func TestConfig_Validate_BackendTeams(t *testing.T) {
+	backend := OCIBackendConfig{
+		URL:                 "http://registry:5000",
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 100,
+		DialTimeout:         10 * time.Second,
+		RequestTimeout:      300 * time.Second,
+	}
+	teamBackend := backend
+	teamBackend.Teams = []string{"platform"}
+
+	newConfig := func(requiredOrg string) *Config {
+		return &Config{
+			Server: ServerConfig{
+				Port:              8080,
+				ReadTimeout:       60 * time.Second,
+				WriteTimeout:      300 * time.Second,
+				MaxConcurrentReqs: 1000,
+			},
+			GitHub: GitHubConfig{
+				APIURL:       "https://api.github.com",
+				RequiredOrg:  requiredOrg,
+				AuthCacheTTL: 30 * time.Minute,
+			},
+			Protocols: ProtocolsConfig{
+				OCI: OCIConfig{
+					Enabled:      true,
+					PullBackends: []OCIBackendConfig{backend, teamBackend, teamBackend},
+					PushBackend:  backend,
+				},
+			},
+			Logging: LoggingConfig{Level: "info", Format: "json"},
+		}
+	}
+
+	if err := newConfig("myorg").Validate(); err != nil {
+		t.Errorf("expected valid config with required_org, got: %v", err)
+	}
+
+	err := newConfig("").Validate()
+	if err == nil || !strings.Contains(err.Error(), "required_org must be specified when backend teams") {
+		t.Errorf("expected required_org error, got: %v", err)
+	}
+
+	teams := newConfig("myorg").RoutingTeams()
+	if len(teams) != 1 || teams[0] != "platform" {
+		t.Errorf("RoutingTeams() = %v, want [platform]", teams)
+	}
 }
 
 // TestMavenConfig_Validate tests Maven protocol validation
@@ -672,307 +974,3041 @@ func TestNPMConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "path_prefix must start with '/'",
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := tt.config.Validate()
-			if tt.wantErr && err == nil {
-				t.Error("expected error but got none")
-			}
-			if !tt.wantErr && err != nil {
-				t.Errorf("unexpected error: %v", err)
-			}
-			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
-				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
-			}
-		})
-	}
-}
-
-// TestProtocolsConfig_PathPrefixUniqueness tests path_prefix uniqueness validation
-func TestProtocolsConfig_PathPrefixUniqueness(t *testing.T) {
-	t.Run("path_prefix conflict - both protocols use /registry with empty host", func(t *testing.T) {
-		cfg := ProtocolsConfig{
-			Maven: MavenConfig{
-				Enabled:    true,
-				Host:       "",
-				PathPrefix: "/registry",
-				Backend: MavenBackendConfig{
-					URL:                 "https://maven.example.com",
+		{
+			name: "valid config with scopes",
+			config: NPMConfig{
+				PathPrefix: "/npm",
+				Scopes:     []string{"@acme", "@acme-internal"},
+				Backend: NPMBackendConfig{
+					URL:                 "https://registry.npmjs.org",
 					MaxIdleConns:        200,
 					MaxIdleConnsPerHost: 100,
 					DialTimeout:         10 * time.Second,
 					RequestTimeout:      300 * time.Second,
 				},
 			},
-			NPM: NPMConfig{
-				Enabled:    true,
-				Host:       "",
-				PathPrefix: "/registry",
+			wantErr: false,
+		},
+		{
+			name: "invalid - scope without @",
+			config: NPMConfig{
+				PathPrefix: "/npm",
+				Scopes:     []string{"acme"},
 				Backend: NPMBackendConfig{
-					URL:                 "https://npm.example.com",
+					URL:                 "https://registry.npmjs.org",
 					MaxIdleConns:        200,
 					MaxIdleConnsPerHost: 100,
 					DialTimeout:         10 * time.Second,
 					RequestTimeout:      300 * time.Second,
 				},
 			},
-		}
-
-		err := cfg.Validate()
-		if err == nil {
-			t.Error("expected error for path_prefix conflict")
-		}
-		if !strings.Contains(err.Error(), "path_prefix conflict") {
-			t.Errorf("unexpected error: %v", err)
-		}
-	})
-
-	t.Run("same path_prefix allowed with different hosts", func(t *testing.T) {
-		cfg := ProtocolsConfig{
-			Maven: MavenConfig{
-				Enabled:    true,
-				Host:       "maven.example.com",
-				PathPrefix: "/api",
-				Backend: MavenBackendConfig{
-					URL:                 "https://maven.example.com",
+			wantErr: true,
+			errMsg:  `scopes[0]: invalid scope "acme"`,
+		},
+		{
+			name: "invalid - duplicate scope",
+			config: NPMConfig{
+				PathPrefix: "/npm",
+				Scopes:     []string{"@acme", "@acme"},
+				Backend: NPMBackendConfig{
+					URL:                 "https://registry.npmjs.org",
 					MaxIdleConns:        200,
 					MaxIdleConnsPerHost: 100,
 					DialTimeout:         10 * time.Second,
 					RequestTimeout:      300 * time.Second,
 				},
 			},
-			NPM: NPMConfig{
-				Enabled:    true,
-				Host:       "npm.example.com",
-				PathPrefix: "/api",
-				Backend: NPMBackendConfig{
-					URL:                 "https://npm.example.com",
-					MaxIdleConns:        200,
-					MaxIdleConnsPerHost: 100,
-					DialTimeout:         10 * time.Second,
-					RequestTimeout:      300 * time.Second,
-				},
+			wantErr: true,
+			errMsg:  `scopes[1]: duplicate scope "@acme"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+// TestRubyGemsConfig_Validate tests RubyGems protocol validation
+func TestRubyGemsConfig_Validate(t *testing.T) {
+	backend := RubyGemsBackendConfig{
+		URL:                 "https://rubygems.org",
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 100,
+		DialTimeout:         10 * time.Second,
+		RequestTimeout:      300 * time.Second,
+	}
+
+	tests := []struct {
+		name    string
+		config  RubyGemsConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid config with path_prefix",
+			config:  RubyGemsConfig{PathPrefix: "/rubygems", Backend: backend},
+			wantErr: false,
+		},
+		{
+			name:    "valid config with host and empty path_prefix",
+			config:  RubyGemsConfig{Host: "gems.example.com", Backend: backend},
+			wantErr: false,
+		},
+		{
+			name:    "invalid - empty host requires path_prefix",
+			config:  RubyGemsConfig{Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix is required when host is empty",
+		},
+		{
+			name:    "invalid - path_prefix must start with /",
+			config:  RubyGemsConfig{PathPrefix: "rubygems", Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix must start with '/'",
+		},
+		{
+			name:    "invalid - backend without URL",
+			config:  RubyGemsConfig{PathPrefix: "/rubygems", Backend: RubyGemsBackendConfig{}},
+			wantErr: true,
+			errMsg:  "backend:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+// TestHelmConfig_Validate tests Helm protocol validation
+func TestHelmConfig_Validate(t *testing.T) {
+	backend := HelmBackendConfig{
+		URL:                 "http://chartmuseum:8080",
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 100,
+		DialTimeout:         10 * time.Second,
+		RequestTimeout:      300 * time.Second,
+	}
+
+	tests := []struct {
+		name    string
+		config  HelmConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid config with path_prefix",
+			config:  HelmConfig{PathPrefix: "/helm", Backend: backend},
+			wantErr: false,
+		},
+		{
+			name:    "valid config with host and empty path_prefix",
+			config:  HelmConfig{Host: "charts.example.com", Backend: backend},
+			wantErr: false,
+		},
+		{
+			name:    "invalid - empty host requires path_prefix",
+			config:  HelmConfig{Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix is required when host is empty",
+		},
+		{
+			name:    "invalid - path_prefix must start with /",
+			config:  HelmConfig{PathPrefix: "helm", Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix must start with '/'",
+		},
+		{
+			name:    "invalid - backend without URL",
+			config:  HelmConfig{PathPrefix: "/helm", Backend: HelmBackendConfig{}},
+			wantErr: true,
+			errMsg:  "backend:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+// TestAPTConfig_Validate tests APT protocol validation
+func TestAPTConfig_Validate(t *testing.T) {
+	backend := APTBackendConfig{
+		URL:                 "http://aptly:8080",
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 100,
+		DialTimeout:         10 * time.Second,
+		RequestTimeout:      300 * time.Second,
+	}
+
+	tests := []struct {
+		name    string
+		config  APTConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid config with path_prefix",
+			config:  APTConfig{PathPrefix: "/apt", Backend: backend},
+			wantErr: false,
+		},
+		{
+			name:    "valid config with host and empty path_prefix",
+			config:  APTConfig{Host: "apt.example.com", Backend: backend},
+			wantErr: false,
+		},
+		{
+			name:    "invalid - empty host requires path_prefix",
+			config:  APTConfig{Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix is required when host is empty",
+		},
+		{
+			name:    "invalid - path_prefix must start with /",
+			config:  APTConfig{PathPrefix: "apt", Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix must start with '/'",
+		},
+		{
+			name:    "invalid - backend without URL",
+			config:  APTConfig{PathPrefix: "/apt", Backend: APTBackendConfig{}},
+			wantErr: true,
+			errMsg:  "backend:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+// TestComposerConfig_Validate tests Composer protocol validation
+func TestComposerConfig_Validate(t *testing.T) {
+	backend := ComposerBackendConfig{
+		Name:                "satis",
+		URL:                 "http://satis:8080",
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 100,
+		DialTimeout:         10 * time.Second,
+		RequestTimeout:      300 * time.Second,
+	}
+	upstream := backend
+	upstream.Name = "packagist"
+	upstream.URL = "https://repo.packagist.org"
+
+	tests := []struct {
+		name    string
+		config  ComposerConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid config with path_prefix",
+			config:  ComposerConfig{PathPrefix: "/composer", Backend: backend},
+			wantErr: false,
+		},
+		{
+			name:    "valid config with upstream",
+			config:  ComposerConfig{Host: "composer.example.com", Backend: backend, Upstream: &upstream},
+			wantErr: false,
+		},
+		{
+			name:    "invalid - empty host requires path_prefix",
+			config:  ComposerConfig{Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix is required when host is empty",
+		},
+		{
+			name:    "invalid - path_prefix must start with /",
+			config:  ComposerConfig{PathPrefix: "composer", Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix must start with '/'",
+		},
+		{
+			name:    "invalid - backend without URL",
+			config:  ComposerConfig{PathPrefix: "/composer", Backend: ComposerBackendConfig{}},
+			wantErr: true,
+			errMsg:  "backend:",
+		},
+		{
+			name:    "invalid - upstream named like the backend",
+			config:  ComposerConfig{PathPrefix: "/composer", Backend: backend, Upstream: &backend},
+			wantErr: true,
+			errMsg:  "upstream name must differ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestCondaConfig_Validate(t *testing.T) {
+	channel := func(name, backendName string) CondaChannelConfig {
+		return CondaChannelConfig{
+			Name: name,
+			Backend: CondaBackendConfig{
+				Name:                backendName,
+				URL:                 "https://conda.anaconda.org/" + name,
+				MaxIdleConns:        200,
+				MaxIdleConnsPerHost: 100,
+				DialTimeout:         10 * time.Second,
+				RequestTimeout:      300 * time.Second,
 			},
 		}
+	}
+	cached := channel("conda-forge", "conda-forge")
+	cached.RepodataCacheTTL = -time.Second
+
+	tests := []struct {
+		name    string
+		config  CondaConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid config with path_prefix",
+			config:  CondaConfig{PathPrefix: "/conda", Channels: []CondaChannelConfig{channel("conda-forge", "conda-forge"), channel("bioconda", "bioconda")}},
+			wantErr: false,
+		},
+		{
+			name:    "invalid - empty host requires path_prefix",
+			config:  CondaConfig{Channels: []CondaChannelConfig{channel("conda-forge", "conda-forge")}},
+			wantErr: true,
+			errMsg:  "path_prefix is required when host is empty",
+		},
+		{
+			name:    "invalid - no channels",
+			config:  CondaConfig{Host: "conda.example.com"},
+			wantErr: true,
+			errMsg:  "at least one channel is required",
+		},
+		{
+			name:    "invalid - channel name with slash",
+			config:  CondaConfig{PathPrefix: "/conda", Channels: []CondaChannelConfig{channel("org/channel", "org")}},
+			wantErr: true,
+			errMsg:  `invalid name "org/channel"`,
+		},
+		{
+			name:    "invalid - duplicate channel",
+			config:  CondaConfig{PathPrefix: "/conda", Channels: []CondaChannelConfig{channel("conda-forge", "a"), channel("conda-forge", "b")}},
+			wantErr: true,
+			errMsg:  `duplicate channel "conda-forge"`,
+		},
+		{
+			name:    "invalid - duplicate backend name",
+			config:  CondaConfig{PathPrefix: "/conda", Channels: []CondaChannelConfig{channel("conda-forge", "anaconda"), channel("bioconda", "anaconda")}},
+			wantErr: true,
+			errMsg:  `duplicate backend name "anaconda"`,
+		},
+		{
+			name:    "invalid - backend without URL",
+			config:  CondaConfig{PathPrefix: "/conda", Channels: []CondaChannelConfig{{Name: "conda-forge"}}},
+			wantErr: true,
+			errMsg:  "channels[0] (conda-forge): backend:",
+		},
+		{
+			name:    "invalid - negative repodata cache TTL",
+			config:  CondaConfig{PathPrefix: "/conda", Channels: []CondaChannelConfig{cached}},
+			wantErr: true,
+			errMsg:  "repodata_cache_ttl must be non-negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestTerraformConfig_Validate(t *testing.T) {
+	backend := func(modulesPath, providersPath string) TerraformBackendConfig {
+		return TerraformBackendConfig{
+			URL:                 "https://registry.terraform.io",
+			ModulesPath:         modulesPath,
+			ProvidersPath:       providersPath,
+			MaxIdleConns:        200,
+			MaxIdleConnsPerHost: 100,
+			DialTimeout:         10 * time.Second,
+			RequestTimeout:      300 * time.Second,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		config  TerraformConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid config with path_prefix",
+			config:  TerraformConfig{PathPrefix: "/terraform", Backend: backend("/v1/modules/", "/v1/providers/")},
+			wantErr: false,
+		},
+		{
+			name:    "valid config with host and custom API paths",
+			config:  TerraformConfig{Host: "terraform.example.com", Backend: backend("/api/registry/v1/modules/", "/api/registry/v1/providers/")},
+			wantErr: false,
+		},
+		{
+			name:    "invalid - empty host requires path_prefix",
+			config:  TerraformConfig{Backend: backend("/v1/modules/", "/v1/providers/")},
+			wantErr: true,
+			errMsg:  "path_prefix is required when host is empty",
+		},
+		{
+			name:    "invalid - modules_path without trailing slash",
+			config:  TerraformConfig{PathPrefix: "/terraform", Backend: backend("/v1/modules", "/v1/providers/")},
+			wantErr: true,
+			errMsg:  "modules_path must be a path",
+		},
+		{
+			name:    "invalid - providers_path is a URL",
+			config:  TerraformConfig{PathPrefix: "/terraform", Backend: backend("/v1/modules/", "https://registry.example.com/v1/providers/")},
+			wantErr: true,
+			errMsg:  "providers_path must be a path",
+		},
+		{
+			name:    "invalid - backend without URL",
+			config:  TerraformConfig{PathPrefix: "/terraform"},
+			wantErr: true,
+			errMsg:  "backend:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestAPKConfig_Validate(t *testing.T) {
+	backend := APKBackendConfig{
+		URL:                 "http://alpine-mirror:8080/alpine",
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 100,
+		DialTimeout:         10 * time.Second,
+		RequestTimeout:      300 * time.Second,
+	}
+
+	tests := []struct {
+		name    string
+		config  APKConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid config with path_prefix",
+			config:  APKConfig{PathPrefix: "/apk", Backend: backend},
+			wantErr: false,
+		},
+		{
+			name:    "valid config with host and empty path_prefix",
+			config:  APKConfig{Host: "apk.example.com", Backend: backend},
+			wantErr: false,
+		},
+		{
+			name:    "invalid - empty host requires path_prefix",
+			config:  APKConfig{Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix is required when host is empty",
+		},
+		{
+			name:    "invalid - path_prefix must start with /",
+			config:  APKConfig{PathPrefix: "apk", Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix must start with '/'",
+		},
+		{
+			name:    "invalid - backend without URL",
+			config:  APKConfig{PathPrefix: "/apk", Backend: APKBackendConfig{}},
+			wantErr: true,
+			errMsg:  "backend:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestRawConfig_Validate(t *testing.T) {
+	repository := func(path, backendName string) RawRepositoryConfig {
+		return RawRepositoryConfig{
+			Path: path,
+			Backend: RawBackendConfig{
+				Name:                backendName,
+				URL:                 "http://nexus:8081/repository/raw",
+				MaxIdleConns:        200,
+				MaxIdleConnsPerHost: 100,
+				DialTimeout:         10 * time.Second,
+				RequestTimeout:      300 * time.Second,
+			},
+		}
+	}
+	limited := repository("/builds", "builds")
+	limited.MaxUploadSize = -1
+
+	tests := []struct {
+		name    string
+		config  RawConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid config with path_prefix",
+			config:  RawConfig{PathPrefix: "/raw", Repositories: []RawRepositoryConfig{repository("/", "raw"), repository("/builds/nightly", "nightly")}},
+			wantErr: false,
+		},
+		{
+			name:    "invalid - empty host requires path_prefix",
+			config:  RawConfig{Repositories: []RawRepositoryConfig{repository("/", "raw")}},
+			wantErr: true,
+			errMsg:  "path_prefix is required when host is empty",
+		},
+		{
+			name:    "invalid - no repositories",
+			config:  RawConfig{Host: "files.example.com"},
+			wantErr: true,
+			errMsg:  "at least one repository is required",
+		},
+		{
+			name:    "invalid - path with trailing slash",
+			config:  RawConfig{PathPrefix: "/raw", Repositories: []RawRepositoryConfig{repository("/builds/", "builds")}},
+			wantErr: true,
+			errMsg:  `repositories[0]: path must be a clean path starting with '/' (got: "/builds/")`,
+		},
+		{
+			name:    "invalid - relative path",
+			config:  RawConfig{PathPrefix: "/raw", Repositories: []RawRepositoryConfig{repository("builds", "builds")}},
+			wantErr: true,
+			errMsg:  "repositories[0]: path must be a clean path",
+		},
+		{
+			name:    "invalid - duplicate path",
+			config:  RawConfig{PathPrefix: "/raw", Repositories: []RawRepositoryConfig{repository("/builds", "a"), repository("/builds", "b")}},
+			wantErr: true,
+			errMsg:  `duplicate path "/builds"`,
+		},
+		{
+			name:    "invalid - duplicate backend name",
+			config:  RawConfig{PathPrefix: "/raw", Repositories: []RawRepositoryConfig{repository("/builds", "nexus"), repository("/tools", "nexus")}},
+			wantErr: true,
+			errMsg:  `duplicate backend name "nexus"`,
+		},
+		{
+			name:    "invalid - backend without URL",
+			config:  RawConfig{PathPrefix: "/raw", Repositories: []RawRepositoryConfig{{Path: "/builds"}}},
+			wantErr: true,
+			errMsg:  "repositories[0] (/builds): backend:",
+		},
+		{
+			name:    "invalid - negative max upload size",
+			config:  RawConfig{PathPrefix: "/raw", Repositories: []RawRepositoryConfig{limited}},
+			wantErr: true,
+			errMsg:  "max_upload_size must be non-negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+// TestProtocolsConfig_PathPrefixUniqueness tests path_prefix uniqueness validation
+func TestProtocolsConfig_PathPrefixUniqueness(t *testing.T) {
+	t.Run("path_prefix conflict - both protocols use /registry with empty host", func(t *testing.T) {
+		cfg := ProtocolsConfig{
+			Maven: MavenConfig{
+				Enabled:    true,
+				Host:       "",
+				PathPrefix: "/registry",
+				Backend: MavenBackendConfig{
+					URL:                 "https://maven.example.com",
+					MaxIdleConns:        200,
+					MaxIdleConnsPerHost: 100,
+					DialTimeout:         10 * time.Second,
+					RequestTimeout:      300 * time.Second,
+				},
+			},
+			NPM: NPMConfig{
+				Enabled:    true,
+				Host:       "",
+				PathPrefix: "/registry",
+				Backend: NPMBackendConfig{
+					URL:                 "https://npm.example.com",
+					MaxIdleConns:        200,
+					MaxIdleConnsPerHost: 100,
+					DialTimeout:         10 * time.Second,
+					RequestTimeout:      300 * time.Second,
+				},
+			},
+		}
+
+		err := cfg.Validate()
+		if err == nil {
+			t.Error("expected error for path_prefix conflict")
+		}
+		if !strings.Contains(err.Error(), "path_prefix conflict") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("same path_prefix allowed with different hosts", func(t *testing.T) {
+		cfg := ProtocolsConfig{
+			Maven: MavenConfig{
+				Enabled:    true,
+				Host:       "maven.example.com",
+				PathPrefix: "/api",
+				Backend: MavenBackendConfig{
+					URL:                 "https://maven.example.com",
+					MaxIdleConns:        200,
+					MaxIdleConnsPerHost: 100,
+					DialTimeout:         10 * time.Second,
+					RequestTimeout:      300 * time.Second,
+				},
+			},
+			NPM: NPMConfig{
+				Enabled:    true,
+				Host:       "npm.example.com",
+				PathPrefix: "/api",
+				Backend: NPMBackendConfig{
+					URL:                 "https://npm.example.com",
+					MaxIdleConns:        200,
+					MaxIdleConnsPerHost: 100,
+					DialTimeout:         10 * time.Second,
+					RequestTimeout:      300 * time.Second,
+				},
+			},
+		}
+
+		err := cfg.Validate()
+		if err != nil {
+			t.Errorf("should allow same path_prefix with different hosts, got error: %v", err)
+		}
+	})
+
+	t.Run("unique path_prefix with empty host - valid", func(t *testing.T) {
+		cfg := ProtocolsConfig{
+			Maven: MavenConfig{
+				Enabled:    true,
+				Host:       "",
+				PathPrefix: "/maven",
+				Backend: MavenBackendConfig{
+					URL:                 "https://maven.example.com",
+					MaxIdleConns:        200,
+					MaxIdleConnsPerHost: 100,
+					DialTimeout:         10 * time.Second,
+					RequestTimeout:      300 * time.Second,
+				},
+			},
+			NPM: NPMConfig{
+				Enabled:    true,
+				Host:       "",
+				PathPrefix: "/npm",
+				Backend: NPMBackendConfig{
+					URL:                 "https://npm.example.com",
+					MaxIdleConns:        200,
+					MaxIdleConnsPerHost: 100,
+					DialTimeout:         10 * time.Second,
+					RequestTimeout:      300 * time.Second,
+				},
+			},
+		}
+
+		err := cfg.Validate()
+		if err != nil {
+			t.Errorf("should allow unique path_prefix, got error: %v", err)
+		}
+	})
+}
+
+// TestValidateBackendCommon tests the common backend validation helper
+func TestValidateBackendCommon(t *testing.T) {
+	// Valid circuit breaker config for tests
+	validCB := CircuitBreakerConfig{
+		Enabled:          true,
+		MaxRequests:      10,
+		Interval:         60 * time.Second,
+		Timeout:          30 * time.Second,
+		FailureThreshold: 0.5,
+	}
+
+	// Disabled circuit breaker for simpler tests
+	disabledCB := CircuitBreakerConfig{
+		Enabled: false,
+	}
+
+	tests := []struct {
+		name                string
+		backendURL          string
+		maxIdleConns        int
+		maxIdleConnsPerHost int
+		dialTimeout         time.Duration
+		requestTimeout      time.Duration
+		circuitBreaker      CircuitBreakerConfig
+		wantErr             bool
+		errMsg              string
+	}{
+		{
+			name:                "valid config",
+			backendURL:          "https://example.com",
+			maxIdleConns:        200,
+			maxIdleConnsPerHost: 100,
+			dialTimeout:         10 * time.Second,
+			requestTimeout:      300 * time.Second,
+			circuitBreaker:      disabledCB,
+			wantErr:             false,
+		},
+		{
+			name:                "valid config with circuit breaker",
+			backendURL:          "https://example.com",
+			maxIdleConns:        200,
+			maxIdleConnsPerHost: 100,
+			dialTimeout:         10 * time.Second,
+			requestTimeout:      300 * time.Second,
+			circuitBreaker:      validCB,
+			wantErr:             false,
+		},
+		{
+			name:                "missing URL",
+			backendURL:          "",
+			maxIdleConns:        200,
+			maxIdleConnsPerHost: 100,
+			dialTimeout:         10 * time.Second,
+			requestTimeout:      300 * time.Second,
+			circuitBreaker:      disabledCB,
+			wantErr:             true,
+			errMsg:              "url is required",
+		},
+		{
+			name:                "invalid URL",
+			backendURL:          "://invalid",
+			maxIdleConns:        200,
+			maxIdleConnsPerHost: 100,
+			dialTimeout:         10 * time.Second,
+			requestTimeout:      300 * time.Second,
+			circuitBreaker:      disabledCB,
+			wantErr:             true,
+			errMsg:              "invalid url",
+		},
+		{
+			name:                "maxIdleConns too low",
+			backendURL:          "https://example.com",
+			maxIdleConns:        0,
+			maxIdleConnsPerHost: 100,
+			dialTimeout:         10 * time.Second,
+			requestTimeout:      300 * time.Second,
+			circuitBreaker:      disabledCB,
+			wantErr:             true,
+			errMsg:              "maxIdleConns must be at least 1",
+		},
+		{
+			name:                "maxIdleConnsPerHost too low",
+			backendURL:          "https://example.com",
+			maxIdleConns:        200,
+			maxIdleConnsPerHost: 0,
+			dialTimeout:         10 * time.Second,
+			requestTimeout:      300 * time.Second,
+			circuitBreaker:      disabledCB,
+			wantErr:             true,
+			errMsg:              "maxIdleConnsPerHost must be at least 1",
+		},
+		{
+			name:                "maxIdleConnsPerHost exceeds maxIdleConns",
+			backendURL:          "https://example.com",
+			maxIdleConns:        100,
+			maxIdleConnsPerHost: 200,
+			dialTimeout:         10 * time.Second,
+			requestTimeout:      300 * time.Second,
+			circuitBreaker:      disabledCB,
+			wantErr:             true,
+			errMsg:              "maxIdleConnsPerHost cannot exceed maxIdleConns",
+		},
+		{
+			name:                "invalid dialTimeout",
+			backendURL:          "https://example.com",
+			maxIdleConns:        200,
+			maxIdleConnsPerHost: 100,
+			dialTimeout:         0,
+			requestTimeout:      300 * time.Second,
+			circuitBreaker:      disabledCB,
+			wantErr:             true,
+			errMsg:              "invalid dialTimeout",
+		},
+		{
+			name:                "negative dialTimeout",
+			backendURL:          "https://example.com",
+			maxIdleConns:        200,
+			maxIdleConnsPerHost: 100,
+			dialTimeout:         -5 * time.Second,
+			requestTimeout:      300 * time.Second,
+			circuitBreaker:      disabledCB,
+			wantErr:             true,
+			errMsg:              "invalid dialTimeout",
+		},
+		{
+			name:                "invalid requestTimeout",
+			backendURL:          "https://example.com",
+			maxIdleConns:        200,
+			maxIdleConnsPerHost: 100,
+			dialTimeout:         10 * time.Second,
+			requestTimeout:      0,
+			circuitBreaker:      disabledCB,
+			wantErr:             true,
+			errMsg:              "invalid requestTimeout",
+		},
+		{
+			name:                "negative requestTimeout",
+			backendURL:          "https://example.com",
+			maxIdleConns:        200,
+			maxIdleConnsPerHost: 100,
+			dialTimeout:         10 * time.Second,
+			requestTimeout:      -300 * time.Second,
+			circuitBreaker:      disabledCB,
+			wantErr:             true,
+			errMsg:              "invalid requestTimeout",
+		},
+		{
+			name:                "invalid circuit breaker config",
+			backendURL:          "https://example.com",
+			maxIdleConns:        200,
+			maxIdleConnsPerHost: 100,
+			dialTimeout:         10 * time.Second,
+			requestTimeout:      300 * time.Second,
+			circuitBreaker: CircuitBreakerConfig{
+				Enabled:          true,
+				MaxRequests:      0, // Invalid
+				Interval:         60 * time.Second,
+				Timeout:          30 * time.Second,
+				FailureThreshold: 0.5,
+			},
+			wantErr: true,
+			errMsg:  "circuit breaker",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBackendCommon(
+				tt.backendURL,
+				tt.maxIdleConns,
+				tt.maxIdleConnsPerHost,
+				tt.dialTimeout,
+				tt.requestTimeout,
+				tt.circuitBreaker,
+			)
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+// TestBackendConfig_Validate_Integration tests that all backend types use validateBackendCommon
+func TestBackendConfig_Validate_Integration(t *testing.T) {
+	// This test ensures that all backend types (OCI, Maven, NPM) properly use validateBackendCommon
+	// by testing that they all reject the same invalid configurations
+
+	invalidURL := "://invalid"
+
+	t.Run("OCIBackendConfig rejects invalid URL", func(t *testing.T) {
+		backend := OCIBackendConfig{
+			URL:                 invalidURL,
+			MaxIdleConns:        200,
+			MaxIdleConnsPerHost: 100,
+			DialTimeout:         10 * time.Second,
+			RequestTimeout:      300 * time.Second,
+		}
+		err := backend.Validate()
+		if err == nil {
+			t.Error("expected error for invalid URL")
+		}
+		if !strings.Contains(err.Error(), "invalid url") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("MavenBackendConfig rejects invalid URL", func(t *testing.T) {
+		backend := MavenBackendConfig{
+			URL:                 invalidURL,
+			MaxIdleConns:        200,
+			MaxIdleConnsPerHost: 100,
+			DialTimeout:         10 * time.Second,
+			RequestTimeout:      300 * time.Second,
+		}
+		err := backend.Validate()
+		if err == nil {
+			t.Error("expected error for invalid URL")
+		}
+		if !strings.Contains(err.Error(), "invalid url") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("NPMBackendConfig rejects invalid URL", func(t *testing.T) {
+		backend := NPMBackendConfig{
+			URL:                 invalidURL,
+			MaxIdleConns:        200,
+			MaxIdleConnsPerHost: 100,
+			DialTimeout:         10 * time.Second,
+			RequestTimeout:      300 * time.Second,
+		}
+		err := backend.Validate()
+		if err == nil {
+			t.Error("expected error for invalid URL")
+		}
+		if !strings.Contains(err.Error(), "invalid url") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+// TestMavenConfig_Validate_Candidate tests A/B experiment validation
+func TestMavenConfig_Validate_Candidate(t *testing.T) {
+	backend := func(name string) MavenBackendConfig {
+		return MavenBackendConfig{
+			Name:                name,
+			URL:                 "https://" + name + ".example.com",
+			MaxIdleConns:        200,
+			MaxIdleConnsPerHost: 100,
+			DialTimeout:         10 * time.Second,
+			RequestTimeout:      300 * time.Second,
+		}
+	}
+	candidate := func(name string) *MavenBackendConfig {
+		b := backend(name)
+		return &b
+	}
+
+	tests := []struct {
+		name      string
+		candidate *MavenBackendConfig
+		percent   float64
+		errMsg    string
+	}{
+		{
+			name: "no candidate",
+		},
+		{
+			name:      "candidate with percentage",
+			candidate: candidate("candidate"),
+			percent:   10,
+		},
+		{
+			name:      "candidate with zero percentage",
+			candidate: candidate("candidate"),
+		},
+		{
+			name:    "percentage without candidate",
+			percent: 10,
+			errMsg:  "candidate_percent requires a candidate backend",
+		},
+		{
+			name:      "percentage above 100",
+			candidate: candidate("candidate"),
+			percent:   101,
+			errMsg:    "candidate_percent must be between 0 and 100",
+		},
+		{
+			name:      "negative percentage",
+			candidate: candidate("candidate"),
+			percent:   -1,
+			errMsg:    "candidate_percent must be between 0 and 100",
+		},
+		{
+			name:      "candidate with backend name",
+			candidate: candidate("primary"),
+			percent:   10,
+			errMsg:    "candidate name must differ from the backend name",
+		},
+		{
+			name:      "candidate without name",
+			candidate: candidate(""),
+			percent:   10,
+			errMsg:    "candidate: name is required",
+		},
+		{
+			name:      "invalid candidate",
+			candidate: &MavenBackendConfig{Name: "candidate"},
+			percent:   10,
+			errMsg:    "candidate: url is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := MavenConfig{
+				PathPrefix:       "/maven",
+				Backend:          backend("primary"),
+				Candidate:        tt.candidate,
+				CandidatePercent: tt.percent,
+			}
+
+			err := cfg.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+// TestNPMConfig_Validate_Upstream tests read-through upstream validation
+func TestNPMConfig_Validate_Upstream(t *testing.T) {
+	backend := func(name string) *NPMBackendConfig {
+		return &NPMBackendConfig{
+			Name:                name,
+			URL:                 "https://" + name + ".example.com",
+			MaxIdleConns:        200,
+			MaxIdleConnsPerHost: 100,
+			DialTimeout:         10 * time.Second,
+			RequestTimeout:      300 * time.Second,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		upstream  *NPMBackendConfig
+		candidate *NPMBackendConfig
+		writeBack bool
+		errMsg    string
+	}{
+		{
+			name: "no upstream",
+		},
+		{
+			name:     "upstream",
+			upstream: backend("npmjs"),
+		},
+		{
+			name:      "upstream with write-back",
+			upstream:  backend("npmjs"),
+			writeBack: true,
+		},
+		{
+			name:      "write-back without upstream",
+			writeBack: true,
+			errMsg:    "write_back requires an upstream backend",
+		},
+		{
+			name:     "upstream with backend name",
+			upstream: backend("verdaccio"),
+			errMsg:   "upstream name must differ",
+		},
+		{
+			name:      "upstream with candidate name",
+			upstream:  backend("candidate"),
+			candidate: backend("candidate"),
+			errMsg:    "upstream name must differ",
+		},
+		{
+			name:     "upstream without name",
+			upstream: backend(""),
+			errMsg:   "upstream: name is required",
+		},
+		{
+			name:     "invalid upstream",
+			upstream: &NPMBackendConfig{Name: "npmjs"},
+			errMsg:   "upstream: url is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NPMConfig{
+				PathPrefix: "/npm",
+				Backend:    *backend("verdaccio"),
+				Candidate:  tt.candidate,
+				Upstream:   tt.upstream,
+				WriteBack:  tt.writeBack,
+			}
+
+			err := cfg.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+// TestForwardProxyConfig_Validate tests forward proxy configuration validation
+func TestForwardProxyConfig_Validate(t *testing.T) {
+	protocols := &ProtocolsConfig{}
+	protocols.NPM.Enabled = true
+
+	npm := []InterceptConfig{{Host: "registry.npmjs.org", Protocol: "npm"}}
+
+	tests := []struct {
+		name    string
+		config  ForwardProxyConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "plain HTTP interception",
+			config:  ForwardProxyConfig{Port: 3128, Intercept: npm},
+			wantErr: false,
+		},
+		{
+			name:    "with certificate",
+			config:  ForwardProxyConfig{Port: 3128, TLSCertFile: "cert.pem", TLSKeyFile: "key.pem", Intercept: npm},
+			wantErr: false,
+		},
+		{
+			name:    "invalid port",
+			config:  ForwardProxyConfig{Intercept: npm},
+			wantErr: true,
+			errMsg:  "invalid port",
+		},
+		{
+			name:    "certificate without key",
+			config:  ForwardProxyConfig{Port: 3128, TLSCertFile: "cert.pem", Intercept: npm},
+			wantErr: true,
+			errMsg:  "must be set together",
+		},
+		{
+			name:    "no intercepted hosts",
+			config:  ForwardProxyConfig{Port: 3128},
+			wantErr: true,
+			errMsg:  "at least one intercept host",
+		},
+		{
+			name:    "host with port",
+			config:  ForwardProxyConfig{Port: 3128, Intercept: []InterceptConfig{{Host: "registry.npmjs.org:443", Protocol: "npm"}}},
+			wantErr: true,
+			errMsg:  "hostname without port or scheme",
+		},
+		{
+			name: "duplicate host",
+			config: ForwardProxyConfig{Port: 3128, Intercept: []InterceptConfig{
+				{Host: "registry.npmjs.org", Protocol: "npm"},
+				{Host: "Registry.npmjs.org", Protocol: "npm"},
+			}},
+			wantErr: true,
+			errMsg:  "duplicate host",
+		},
+		{
+			name:    "unknown protocol",
+			config:  ForwardProxyConfig{Port: 3128, Intercept: []InterceptConfig{{Host: "pypi.org", Protocol: "pypi"}}},
+			wantErr: true,
+			errMsg:  "unknown protocol",
+		},
+		{
+			name:    "disabled protocol",
+			config:  ForwardProxyConfig{Port: 3128, Intercept: []InterceptConfig{{Host: "repo.maven.apache.org", Protocol: "maven"}}},
+			wantErr: true,
+			errMsg:  "protocol maven is not enabled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate(protocols)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestWebUIConfig_Validate(t *testing.T) {
+	protocols := &ProtocolsConfig{}
+	protocols.NPM.Enabled = true
+	protocols.NPM.PathPrefix = "/npm"
+	protocols.Maven.PathPrefix = "/maven"
+
+	npm := WebUIMountConfig{Protocol: "npm", Paths: []string{"/-/web"}, Home: "/-/web/"}
+
+	tests := []struct {
+		name    string
+		config  WebUIConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "npm mount",
+			config:  WebUIConfig{PathPrefix: "/ui", Mounts: []WebUIMountConfig{npm}},
+			wantErr: false,
+		},
+		{
+			name:    "mount with url",
+			config:  WebUIConfig{PathPrefix: "/ui", Mounts: []WebUIMountConfig{{Protocol: "npm", URL: "http://verdaccio:4873", Home: "/"}}},
+			wantErr: false,
+		},
+		{
+			name:    "trailing slash",
+			config:  WebUIConfig{PathPrefix: "/ui/", Mounts: []WebUIMountConfig{npm}},
+			wantErr: true,
+			errMsg:  "path_prefix must start with /",
+		},
+		{
+			name:    "overlaps protocol prefix",
+			config:  WebUIConfig{PathPrefix: "/npm/ui", Mounts: []WebUIMountConfig{npm}},
+			wantErr: true,
+			errMsg:  "overlaps /npm",
+		},
+		{
+			name:    "overlaps OCI API",
+			config:  WebUIConfig{PathPrefix: "/v2", Mounts: []WebUIMountConfig{npm}},
+			wantErr: true,
+			errMsg:  "overlaps /v2",
+		},
+		{
+			name:    "no mounts",
+			config:  WebUIConfig{PathPrefix: "/ui"},
+			wantErr: true,
+			errMsg:  "at least one mount",
+		},
+		{
+			name:    "unknown protocol",
+			config:  WebUIConfig{PathPrefix: "/ui", Mounts: []WebUIMountConfig{{Protocol: "oci", Home: "/"}}},
+			wantErr: true,
+			errMsg:  "unknown protocol",
+		},
+		{
+			name:    "disabled protocol",
+			config:  WebUIConfig{PathPrefix: "/ui", Mounts: []WebUIMountConfig{{Protocol: "maven", Home: "/"}}},
+			wantErr: true,
+			errMsg:  "protocol maven is not enabled",
+		},
+		{
+			name:    "duplicate protocol",
+			config:  WebUIConfig{PathPrefix: "/ui", Mounts: []WebUIMountConfig{npm, npm}},
+			wantErr: true,
+			errMsg:  "duplicate protocol npm",
+		},
+		{
+			name:    "relative url",
+			config:  WebUIConfig{PathPrefix: "/ui", Mounts: []WebUIMountConfig{{Protocol: "npm", URL: "verdaccio", Home: "/"}}},
+			wantErr: true,
+			errMsg:  "url must be an absolute URL",
+		},
+		{
+			name:    "relative path",
+			config:  WebUIConfig{PathPrefix: "/ui", Mounts: []WebUIMountConfig{{Protocol: "npm", Paths: []string{"-/web"}, Home: "/"}}},
+			wantErr: true,
+			errMsg:  "path must start with /",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate(protocols)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestMetadataConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  MetadataConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:   "valid",
+			config: MetadataConfig{Enabled: true, Path: "/var/lib/artifusion/metadata.db", FlushInterval: 10 * time.Second},
+		},
+		{
+			name:    "missing path",
+			config:  MetadataConfig{Enabled: true, FlushInterval: 10 * time.Second},
+			wantErr: true,
+			errMsg:  "path is required",
+		},
+		{
+			name:    "negative flush interval",
+			config:  MetadataConfig{Enabled: true, Path: "metadata.db", FlushInterval: -time.Second},
+			wantErr: true,
+			errMsg:  "flush_interval must be non-negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestBrowserSessionsConfig_Validate(t *testing.T) {
+	valid := func(modify func(*BrowserSessionsConfig)) BrowserSessionsConfig {
+		cfg := BrowserSessionsConfig{
+			Enabled:   true,
+			ClientID:  "Iv1.0123456789abcdef",
+			Secret:    strings.Repeat("s", MinSignedURLSecretLength),
+			TTL:       DefaultBrowserSessionTTL,
+			GitHubURL: DefaultGitHubWebURL,
+		}
+		if modify != nil {
+			modify(&cfg)
+		}
+		return cfg
+	}
+
+	tests := []struct {
+		name   string
+		config BrowserSessionsConfig
+		errMsg string
+	}{
+		{name: "valid", config: valid(nil)},
+		{name: "no client id", config: valid(func(s *BrowserSessionsConfig) { s.ClientID = "" }), errMsg: "client_id is required"},
+		{name: "short secret", config: valid(func(s *BrowserSessionsConfig) { s.Secret = "short" }), errMsg: "secret must be at least"},
+		{name: "ttl above max", config: valid(func(s *BrowserSessionsConfig) { s.TTL = 48 * time.Hour }), errMsg: "ttl must be positive and at most"},
+		{name: "relative github url", config: valid(func(s *BrowserSessionsConfig) { s.GitHubURL = "github.com" }), errMsg: "github_url must be an absolute"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestSignedURLsConfig_Validate(t *testing.T) {
+	secret := strings.Repeat("s", MinSignedURLSecretLength)
+	tests := []struct {
+		name    string
+		config  SignedURLsConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:   "valid",
+			config: SignedURLsConfig{Enabled: true, Secret: secret, DefaultTTL: time.Hour, MaxTTL: 24 * time.Hour, BaseURL: "https://artifacts.example.com"},
+		},
+		{
+			name:    "short secret",
+			config:  SignedURLsConfig{Enabled: true, Secret: "short", DefaultTTL: time.Hour, MaxTTL: 24 * time.Hour},
+			wantErr: true,
+			errMsg:  "secret must be at least",
+		},
+		{
+			name:    "default ttl above max",
+			config:  SignedURLsConfig{Enabled: true, Secret: secret, DefaultTTL: 48 * time.Hour, MaxTTL: 24 * time.Hour},
+			wantErr: true,
+			errMsg:  "must not exceed max_ttl",
+		},
+		{
+			name:    "relative base url",
+			config:  SignedURLsConfig{Enabled: true, Secret: secret, DefaultTTL: time.Hour, MaxTTL: 24 * time.Hour, BaseURL: "artifacts.example.com"},
+			wantErr: true,
+			errMsg:  "base_url must be an absolute",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestSyntheticChecksConfig_Validate(t *testing.T) {
+	protocols := &ProtocolsConfig{}
+	protocols.OCI.Enabled = true
+	protocols.Maven.Enabled = true
+	valid := func() SyntheticChecksConfig {
+		return SyntheticChecksConfig{
+			Enabled:  true,
+			Interval: time.Minute,
+			Timeout:  30 * time.Second,
+			URL:      "http://127.0.0.1:8080",
+			Token:    "ghp_token",
+			Checks: []SyntheticCheckConfig{
+				{Name: "oci-canary", Protocol: "oci", Artifact: "library/alpine:3.20"},
+				{Name: "maven-canary", Protocol: "maven", Artifact: "org.slf4j:slf4j-api:2.0.13"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*SyntheticChecksConfig)
+		errMsg string
+	}{
+		{name: "valid", modify: func(*SyntheticChecksConfig) {}},
+		{name: "missing token", modify: func(s *SyntheticChecksConfig) { s.Token = "" }, errMsg: "token is required"},
+		{name: "no checks", modify: func(s *SyntheticChecksConfig) { s.Checks = nil }, errMsg: "at least one check"},
+		{
+			name:   "duplicate name",
+			modify: func(s *SyntheticChecksConfig) { s.Checks[1].Name = "oci-canary" },
+			errMsg: `duplicate name "oci-canary"`,
+		},
+		{
+			name:   "disabled protocol",
+			modify: func(s *SyntheticChecksConfig) { s.Checks[0].Protocol = "npm" },
+			errMsg: "protocol npm is not enabled",
+		},
+		{
+			name:   "maven artifact without version",
+			modify: func(s *SyntheticChecksConfig) { s.Checks[1].Artifact = "org.slf4j:slf4j-api" },
+			errMsg: "must be group:artifact:version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(&cfg)
+			err := cfg.Validate(protocols)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+// TestGRPCConfig_Validate tests gRPC admin API configuration validation
+func TestGRPCConfig_Validate(t *testing.T) {
+	admins := &AdminConfig{Users: []string{"alice"}}
+
+	tests := []struct {
+		name   string
+		config GRPCConfig
+		admin  *AdminConfig
+		errMsg string
+	}{
+		{name: "valid", config: GRPCConfig{Port: 9090}, admin: admins},
+		{name: "valid with tls", config: GRPCConfig{Port: 9090, TLSCertFile: "cert.pem", TLSKeyFile: "key.pem"}, admin: admins},
+		{name: "invalid port", config: GRPCConfig{Port: 70000}, admin: admins, errMsg: "invalid port"},
+		{name: "cert without key", config: GRPCConfig{Port: 9090, TLSCertFile: "cert.pem"}, admin: admins, errMsg: "must be set together"},
+		{name: "no admins", config: GRPCConfig{Port: 9090}, admin: &AdminConfig{}, errMsg: "at least one admin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate(tt.admin)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestCredentialSharingConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config CredentialSharingConfig
+		errMsg string
+	}{
+		{name: "valid", config: CredentialSharingConfig{MaxSourceIPs: 5, Window: time.Hour}},
+		{name: "valid with webhook", config: CredentialSharingConfig{MaxSourceIPs: 5, Window: time.Hour, WebhookURL: "https://hooks.example.com/T000/B000"}},
+		{name: "no source IPs", config: CredentialSharingConfig{Window: time.Hour}, errMsg: "max_source_ips must be at least 1"},
+		{name: "no window", config: CredentialSharingConfig{MaxSourceIPs: 5}, errMsg: "window must be positive"},
+		{name: "relative webhook", config: CredentialSharingConfig{MaxSourceIPs: 5, Window: time.Hour, WebhookURL: "/alerts"}, errMsg: "webhook_url must be an absolute http(s) URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestAuthLockoutConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config AuthLockoutConfig
+		errMsg string
+	}{
+		{name: "valid", config: AuthLockoutConfig{MaxFailures: 5, Window: time.Minute, Duration: time.Minute, MaxDuration: time.Hour}},
+		{name: "no failures", config: AuthLockoutConfig{Window: time.Minute, Duration: time.Minute, MaxDuration: time.Hour}, errMsg: "max_failures must be at least 1"},
+		{name: "no window", config: AuthLockoutConfig{MaxFailures: 5, Duration: time.Minute, MaxDuration: time.Hour}, errMsg: "window and duration must be positive"},
+		{name: "max below duration", config: AuthLockoutConfig{MaxFailures: 5, Window: time.Minute, Duration: time.Hour, MaxDuration: time.Minute}, errMsg: "must not be less than duration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestClientAuthConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ClientAuthConfig
+		errMsg string
+	}{
+		{name: "protocol default", config: ClientAuthConfig{}},
+		{name: "basic and bearer", config: ClientAuthConfig{SupportedSchemes: []string{"Basic", "bearer"}}},
+		{name: "unknown scheme", config: ClientAuthConfig{SupportedSchemes: []string{"digest"}}, errMsg: "unknown scheme"},
+		{name: "duplicate scheme", config: ClientAuthConfig{SupportedSchemes: []string{"basic", "BASIC"}}, errMsg: "listed twice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestClientAuthConfig_UnauthorizedMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		help    string
+		want    string
+	}{
+		{"Authentication required", "", "Authentication required"},
+		{"Authentication required", " Run docker login with a PAT. ", "Authentication required. Run docker login with a PAT."},
+		{"Authentication required.", "Run npm login.", "Authentication required. Run npm login."},
+	}
+	for _, tt := range tests {
+		c := &ClientAuthConfig{ErrorHelp: ErrorHelpConfig{Unauthorized: tt.help}}
+		if got := c.UnauthorizedMessage(tt.message); got != tt.want {
+			t.Errorf("UnauthorizedMessage(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestGitLabConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config GitLabConfig
+		errMsg string
+	}{
+		{name: "valid", config: GitLabConfig{APIURL: "https://gitlab.example.com/api/v4", RequiredGroup: "acme/platform"}},
+		{name: "relative api url", config: GitLabConfig{APIURL: "gitlab.example.com"}, errMsg: "api_url must be an absolute http(s) URL"},
+		{name: "group with slashes", config: GitLabConfig{APIURL: DefaultGitLabAPIURL, RequiredGroup: "/acme/"}, errMsg: "required_group must be a group path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestLDAPConfig_Validate(t *testing.T) {
+	valid := func(modify func(*LDAPConfig)) LDAPConfig {
+		cfg := LDAPConfig{
+			URL:            "ldaps://ldap.example.com:636",
+			BindDN:         "cn=artifusion,ou=services,dc=example,dc=com",
+			BindPassword:   "secret",
+			UserBaseDN:     "ou=people,dc=example,dc=com",
+			UserFilter:     DefaultLDAPUserFilter,
+			RequiredGroups: []string{"cn=developers,ou=groups,dc=example,dc=com"},
+		}
+		if modify != nil {
+			modify(&cfg)
+		}
+		return cfg
+	}
+
+	tests := []struct {
+		name   string
+		config LDAPConfig
+		errMsg string
+	}{
+		{name: "valid", config: valid(nil)},
+		{name: "anonymous search", config: valid(func(l *LDAPConfig) { l.BindDN, l.BindPassword = "", "" })},
+		{name: "start tls", config: valid(func(l *LDAPConfig) { l.URL, l.StartTLS = "ldap://ldap.example.com", true })},
+		{name: "http url", config: valid(func(l *LDAPConfig) { l.URL = "https://ldap.example.com" }), errMsg: "url must be an ldap:// or ldaps:// URL"},
+		{name: "start tls with ldaps", config: valid(func(l *LDAPConfig) { l.StartTLS = true }), errMsg: "start_tls cannot be used"},
+		{name: "bind dn without password", config: valid(func(l *LDAPConfig) { l.BindPassword = "" }), errMsg: "bind_password is required"},
+		{name: "no user base dn", config: valid(func(l *LDAPConfig) { l.UserBaseDN = "" }), errMsg: "user_base_dn is required"},
+		{name: "filter without placeholder", config: valid(func(l *LDAPConfig) { l.UserFilter = "(uid=alice)" }), errMsg: "user_filter must be"},
+		{name: "unparenthesized filter", config: valid(func(l *LDAPConfig) { l.UserFilter = "uid=%s" }), errMsg: "user_filter must be"},
+		{name: "group name instead of dn", config: valid(func(l *LDAPConfig) { l.RequiredGroups = []string{"developers"} }), errMsg: "required_groups[0] must be a group DN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestServiceAccountsConfig_Validate(t *testing.T) {
+	const hash = "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	valid := func(modify func(*ServiceAccountsConfig)) ServiceAccountsConfig {
+		cfg := ServiceAccountsConfig{
+			Enabled: true,
+			Accounts: []ServiceAccountConfig{
+				{Name: "jenkins", KeyHash: hash, Protocols: []string{"maven", "npm"}, Orgs: []string{"myorg"}},
+			},
+		}
+		if modify != nil {
+			modify(&cfg)
+		}
+		return cfg
+	}
+
+	tests := []struct {
+		name   string
+		config ServiceAccountsConfig
+		errMsg string
+	}{
+		{name: "valid", config: valid(nil)},
+		{name: "file only", config: ServiceAccountsConfig{Enabled: true, File: "/run/secrets/service-accounts.yaml"}},
+		{name: "no accounts", config: ServiceAccountsConfig{Enabled: true}, errMsg: "file or at least one account is required"},
+		{name: "no name", config: valid(func(s *ServiceAccountsConfig) { s.Accounts[0].Name = "" }), errMsg: "accounts[0]: name is required"},
+		{name: "plaintext key", config: valid(func(s *ServiceAccountsConfig) { s.Accounts[0].KeyHash = "afk_secret" }), errMsg: "key_hash must be"},
+		{name: "short hash", config: valid(func(s *ServiceAccountsConfig) { s.Accounts[0].KeyHash = "sha256:abcd" }), errMsg: "key_hash must be"},
+		{
+			name: "duplicate name",
+			config: valid(func(s *ServiceAccountsConfig) {
+				s.Accounts = append(s.Accounts, ServiceAccountConfig{Name: "jenkins", KeyHash: "sha256:" + strings.Repeat("a", 64), Orgs: []string{"myorg"}})
+			}),
+			errMsg: `accounts[1]: duplicate name "jenkins"`,
+		},
+		{
+			name: "shared key",
+			config: valid(func(s *ServiceAccountsConfig) {
+				s.Accounts = append(s.Accounts, ServiceAccountConfig{Name: "buildkite", KeyHash: "sha256:" + strings.ToUpper(hash[7:]), Orgs: []string{"myorg"}})
+			}),
+			errMsg: "accounts[1]: key_hash is already used by another account",
+		},
+		{name: "unknown protocol", config: valid(func(s *ServiceAccountsConfig) { s.Accounts[0].Protocols = []string{"pypi"} }), errMsg: `unknown protocol "pypi"`},
+		{name: "outside required org", config: valid(func(s *ServiceAccountsConfig) { s.Accounts[0].Orgs = []string{"other"} }), errMsg: `orgs must include the required org "myorg"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate("myorg")
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestMTLSConfig_Validate(t *testing.T) {
+	tlsServer := &ServerConfig{TLSCertFile: "/etc/artifusion/tls.crt", TLSKeyFile: "/etc/artifusion/tls.key"}
+	valid := func(modify func(*MTLSConfig)) MTLSConfig {
+		cfg := MTLSConfig{Enabled: true, CAFile: "/etc/artifusion/client-ca.pem", UsernameFrom: MTLSUsernameFromSAN}
+		if modify != nil {
+			modify(&cfg)
+		}
+		return cfg
+	}
+
+	tests := []struct {
+		name   string
+		config MTLSConfig
+		server *ServerConfig
+		org    string
+		errMsg string
+	}{
+		{name: "valid", config: valid(nil), server: tlsServer},
+		{name: "required org with allowed ous", config: valid(func(m *MTLSConfig) { m.AllowedOUs = []string{"platform"} }), server: tlsServer, org: "myorg"},
+		{name: "required org without allowed ous", config: valid(nil), server: tlsServer, org: "myorg", errMsg: "allowed_ous is required with github.required_org"},
+		{name: "cn", config: valid(func(m *MTLSConfig) { m.UsernameFrom = MTLSUsernameFromCN }), server: tlsServer},
+		{name: "plaintext server", config: valid(nil), server: &ServerConfig{}, errMsg: "server.tls_cert_file and server.tls_key_file are required"},
+		{name: "no ca", config: valid(func(m *MTLSConfig) { m.CAFile = "" }), server: tlsServer, errMsg: "ca_file is required"},
+		{name: "unknown username source", config: valid(func(m *MTLSConfig) { m.UsernameFrom = "ou" }), server: tlsServer, errMsg: "username_from must be"},
+		{name: "empty ou", config: valid(func(m *MTLSConfig) { m.AllowedOUs = []string{" "} }), server: tlsServer, errMsg: "allowed_ous[0] cannot be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate(tt.server, tt.org)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestClientPolicyConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ClientPolicyConfig
+		errMsg string
+	}{
+		{name: "valid", config: ClientPolicyConfig{Enabled: true, Rules: []ClientPolicyRule{{Client: "npm", DenyVersions: []string{"<6.14.6", ">=7.0.0 <7.1.2"}}, {Client: "docker", Protocol: "oci"}}}},
+		{name: "min versions only", config: ClientPolicyConfig{Enabled: true, MinVersions: map[string]string{"docker": "20.10.0", "npm": "v7"}}},
+		{name: "no rules", config: ClientPolicyConfig{Enabled: true}, errMsg: "at least one rule or min version is required"},
+		{name: "min version of unknown client", config: ClientPolicyConfig{Enabled: true, MinVersions: map[string]string{"curl": "8.0.0"}}, errMsg: "min_versions: client must be"},
+		{name: "invalid min version", config: ClientPolicyConfig{Enabled: true, MinVersions: map[string]string{"npm": ">=7"}}, errMsg: "min_versions[npm]: version must be"},
+		{name: "unknown client", config: ClientPolicyConfig{Enabled: true, Rules: []ClientPolicyRule{{Client: "curl"}}}, errMsg: "rules[0]: client must be"},
+		{name: "unknown protocol", config: ClientPolicyConfig{Enabled: true, Rules: []ClientPolicyRule{{Client: "npm", Protocol: "pypi"}}}, errMsg: "rules[0]: protocol must be"},
+		{name: "invalid versions", config: ClientPolicyConfig{Enabled: true, Rules: []ClientPolicyRule{{Client: "npm", DenyVersions: []string{"~6.14"}}}}, errMsg: "invalid version constraint"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestCORSConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config CORSConfig
+		errMsg string
+	}{
+		{name: "valid", config: CORSConfig{AllowedOrigins: []string{"https://ui.example.com", "http://localhost:8080/"}, AllowedHeaders: []string{"Authorization"}, AllowCredentials: true, MaxAge: 10 * time.Minute}},
+		{name: "any origin", config: CORSConfig{AllowedOrigins: []string{"*"}}},
+		{name: "any origin with credentials", config: CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}, errMsg: "cannot contain \"*\" with allow_credentials"},
+		{name: "origin with path", config: CORSConfig{AllowedOrigins: []string{"https://ui.example.com/app"}}, errMsg: "allowed_origins[0] must be"},
+		{name: "origin without scheme", config: CORSConfig{AllowedOrigins: []string{"ui.example.com"}}, errMsg: "allowed_origins[0] must be"},
+		{name: "empty header", config: CORSConfig{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{" "}}, errMsg: "allowed_headers[0] cannot be empty"},
+		{name: "negative max age", config: CORSConfig{AllowedOrigins: []string{"*"}, MaxAge: -time.Second}, errMsg: "max_age cannot be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestShadowLogConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ShadowLogConfig
+		errMsg string
+	}{
+		{name: "valid", config: ShadowLogConfig{Enabled: true, Dir: "/var/log/artifusion/shadow", MaxDuration: time.Hour}},
+		{name: "no dir", config: ShadowLogConfig{Enabled: true, MaxDuration: time.Hour}, errMsg: "dir is required"},
+		{name: "negative max duration", config: ShadowLogConfig{Enabled: true, Dir: "/tmp", MaxDuration: -time.Minute}, errMsg: "max_duration cannot be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestVaultConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config VaultConfig
+		errMsg string
+	}{
+		{name: "token", config: VaultConfig{Enabled: true, Address: "https://vault.example.com:8200", Token: "hvs.token", RefreshInterval: time.Minute}},
+		{name: "token file", config: VaultConfig{Enabled: true, Address: "http://127.0.0.1:8200", TokenFile: "/vault/token", RefreshInterval: time.Minute}},
+		{name: "no address", config: VaultConfig{Enabled: true, Token: "hvs.token", RefreshInterval: time.Minute}, errMsg: "address must be an absolute http(s) URL"},
+		{name: "no token", config: VaultConfig{Enabled: true, Address: "https://vault.example.com", RefreshInterval: time.Minute}, errMsg: "exactly one of token or token_file"},
+		{name: "token and token file", config: VaultConfig{Enabled: true, Address: "https://vault.example.com", Token: "hvs.token", TokenFile: "/vault/token", RefreshInterval: time.Minute}, errMsg: "exactly one of token or token_file"},
+		{name: "negative refresh interval", config: VaultConfig{Enabled: true, Address: "https://vault.example.com", Token: "hvs.token", RefreshInterval: -time.Minute}, errMsg: "refresh_interval must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestIdentityConfig_Validate(t *testing.T) {
+	okta := func(modify func(*OIDCProviderConfig)) IdentityConfig {
+		oidc := OIDCProviderConfig{
+			Name:         "okta",
+			Issuer:       "https://example.okta.com/oauth2/default",
+			Audiences:    []string{"api://artifusion"},
+			JWKSCacheTTL: time.Hour,
+		}
+		if modify != nil {
+			modify(&oidc)
+		}
+		return IdentityConfig{Providers: []string{"github", "okta"}, OIDC: []OIDCProviderConfig{oidc}}
+	}
+
+	tests := []struct {
+		name       string
+		config     IdentityConfig
+		clientAuth map[string]*ClientAuthConfig
+		errMsg     string
+	}{
+		{name: "github only", config: IdentityConfig{}},
+		{name: "valid", config: okta(nil), clientAuth: map[string]*ClientAuthConfig{"npm": {Providers: []string{"okta"}}}},
+		{name: "no name", config: okta(func(o *OIDCProviderConfig) { o.Name = "" }), errMsg: "name is required"},
+		{name: "reserved name", config: okta(func(o *OIDCProviderConfig) { o.Name = "github" }), errMsg: "reserved"},
+		{name: "relative issuer", config: okta(func(o *OIDCProviderConfig) { o.Issuer = "example.okta.com" }), errMsg: "issuer must be an absolute http(s) URL"},
+		{name: "no audience", config: okta(func(o *OIDCProviderConfig) { o.Audiences = nil }), errMsg: "at least one audience"},
+		{name: "empty required claim", config: okta(func(o *OIDCProviderConfig) { o.RequiredClaims = []ClaimRequirement{{Value: "true"}} }), errMsg: "claim is required"},
+		{
+			name:   "duplicate provider",
+			config: IdentityConfig{OIDC: append(okta(nil).OIDC, okta(nil).OIDC...)},
+			errMsg: "duplicate provider name",
+		},
+		{name: "unknown provider", config: IdentityConfig{Providers: []string{"github", "okta"}}, errMsg: `providers: unknown provider "okta"`},
+		{name: "provider listed twice", config: IdentityConfig{Providers: []string{"github", "github"}}, errMsg: "listed twice"},
+		{
+			name:       "unknown protocol provider",
+			config:     okta(nil),
+			clientAuth: map[string]*ClientAuthConfig{"maven": {Providers: []string{"gitlab"}}},
+			errMsg:     `maven client_auth.providers: unknown provider "gitlab"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate([]string{"github"}, tt.clientAuth)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestContentPolicyConfig_Validate(t *testing.T) {
+	rule := func(r ContentPolicyRule) ContentPolicyConfig {
+		return ContentPolicyConfig{Enabled: true, Rules: []ContentPolicyRule{r}}
+	}
+
+	tests := []struct {
+		name   string
+		config ContentPolicyConfig
+		errMsg string
+	}{
+		{name: "valid extensions", config: rule(ContentPolicyRule{Protocol: "maven", DenyExtensions: []string{".exe", ".tar.gz"}})},
+		{name: "valid content types", config: rule(ContentPolicyRule{Protocol: "oci", Path: "^/v2/.+/manifests/", AllowContentTypes: []string{"application/json", "application/*+json"}})},
+		{name: "no rules", config: ContentPolicyConfig{Enabled: true}, errMsg: "at least one rule is required"},
+		{name: "unknown protocol", config: rule(ContentPolicyRule{Protocol: "pypi", DenyExtensions: []string{".exe"}}), errMsg: "protocol must be"},
+		{name: "invalid path", config: rule(ContentPolicyRule{Protocol: "npm", Path: "([", DenyExtensions: []string{".exe"}}), errMsg: "invalid path pattern"},
+		{name: "empty rule", config: rule(ContentPolicyRule{Protocol: "npm"}), errMsg: "at least one extension or content type"},
+		{name: "extension without dot", config: rule(ContentPolicyRule{Protocol: "npm", DenyExtensions: []string{"exe"}}), errMsg: "extension must start with a dot"},
+		{name: "content type without subtype", config: rule(ContentPolicyRule{Protocol: "npm", DenyContentTypes: []string{"text"}}), errMsg: "content type must be a media type pattern"},
+		{name: "malformed content type pattern", config: rule(ContentPolicyRule{Protocol: "npm", DenyContentTypes: []string{"text/[*"}}), errMsg: "content type must be a media type pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+// TestBackendConfig_Validate_ResponseHeaderTimeout tests response header timeout validation
+func TestBackendConfig_Validate_ResponseHeaderTimeout(t *testing.T) {
+	tests := []struct {
+		name                  string
+		responseHeaderTimeout time.Duration
+		wantErr               bool
+		errMsg                string
+	}{
+		{
+			name:                  "disabled",
+			responseHeaderTimeout: 0,
+			wantErr:               false,
+		},
+		{
+			name:                  "shorter than request timeout",
+			responseHeaderTimeout: 30 * time.Second,
+			wantErr:               false,
+		},
+		{
+			name:                  "negative",
+			responseHeaderTimeout: -time.Second,
+			wantErr:               true,
+			errMsg:                "response_header_timeout must be non-negative",
+		},
+		{
+			name:                  "not shorter than request timeout",
+			responseHeaderTimeout: 300 * time.Second,
+			wantErr:               true,
+			errMsg:                "must be less than request_timeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := NPMBackendConfig{
+				URL:                   "https://registry.example.com",
+				MaxIdleConns:          200,
+				MaxIdleConnsPerHost:   100,
+				DialTimeout:           10 * time.Second,
+				RequestTimeout:        300 * time.Second,
+				ResponseHeaderTimeout: tt.responseHeaderTimeout,
+			}
+
+			err := backend.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+// TestOCIBackendConfig_Validate_FallthroughStatuses tests fallthrough status validation
+func TestOCIBackendConfig_Validate_FallthroughStatuses(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		wantErr  bool
+		errMsg   string
+	}{
+		{
+			name:     "default",
+			statuses: nil,
+			wantErr:  false,
+		},
+		{
+			name:     "codes and classes",
+			statuses: []string{"404", "429", "5xx", "4XX"},
+			wantErr:  false,
+		},
+		{
+			name:     "success status",
+			statuses: []string{"200"},
+			wantErr:  true,
+			errMsg:   `invalid status "200"`,
+		},
+		{
+			name:     "redirect class",
+			statuses: []string{"3xx"},
+			wantErr:  true,
+			errMsg:   `invalid status "3xx"`,
+		},
+		{
+			name:     "not a status",
+			statuses: []string{"not-found"},
+			wantErr:  true,
+			errMsg:   `invalid status "not-found"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := OCIBackendConfig{
+				URL:                 "https://registry.example.com",
+				MaxIdleConns:        200,
+				MaxIdleConnsPerHost: 100,
+				DialTimeout:         10 * time.Second,
+				RequestTimeout:      300 * time.Second,
+				FallthroughStatuses: tt.statuses,
+			}
+
+			err := backend.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+// TestOCIBackendConfig_FallsThrough tests which pull backend statuses continue the cascade
+func TestOCIBackendConfig_FallsThrough(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		status   int
+		expected bool
+	}{
+		{"default not found", nil, 404, true},
+		{"default unauthorized", nil, 401, true},
+		{"default forbidden", nil, 403, true},
+		{"default server error", nil, 503, true},
+		{"default rate limited", nil, 429, false},
+		{"default bad request", nil, 400, false},
+		{"configured rate limited", []string{"404", "429", "5xx"}, 429, true},
+		{"configured forbidden is terminal", []string{"404", "429", "5xx"}, 403, false},
+		{"configured class", []string{"4xx"}, 418, true},
+		{"configured class excludes others", []string{"4xx"}, 502, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := OCIBackendConfig{FallthroughStatuses: tt.statuses}
+			if got := backend.FallsThrough(tt.status); got != tt.expected {
+				t.Errorf("FallsThrough(%d) = %v, want %v", tt.status, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestTransportConfig_Validate tests backend transport validation
+func TestTransportConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  TransportConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "empty config",
+			config:  TransportConfig{},
+			wantErr: false,
+		},
+		{
+			name:    "dns refresh",
+			config:  TransportConfig{DNSRefreshInterval: time.Minute, DNSFailureCooldown: 30 * time.Second},
+			wantErr: false,
+		},
+		{
+			name:    "prefer ipv4 without fallback",
+			config:  TransportConfig{IPFamily: IPFamilyPreferIPv4, FallbackDelay: -1},
+			wantErr: false,
+		},
+		{
+			name:    "invalid ip family",
+			config:  TransportConfig{IPFamily: "ipv5"},
+			wantErr: true,
+			errMsg:  "invalid ip_family",
+		},
+		{
+			name:    "negative dns refresh interval",
+			config:  TransportConfig{DNSRefreshInterval: -time.Second},
+			wantErr: true,
+			errMsg:  "dns_refresh_interval must be non-negative",
+		},
+		{
+			name:    "negative tls handshake timeout",
+			config:  TransportConfig{TLSHandshakeTimeout: -time.Second},
+			wantErr: true,
+			errMsg:  "tls_handshake_timeout must be non-negative",
+		},
+		{
+			name:    "negative expect continue timeout",
+			config:  TransportConfig{ExpectContinueTimeout: -time.Second},
+			wantErr: true,
+			errMsg:  "expect_continue_timeout must be non-negative",
+		},
+		{
+			name:    "keep-alive probes disabled",
+			config:  TransportConfig{KeepAlive: -1},
+			wantErr: false,
+		},
+		{
+			name:    "negative dns failure cooldown",
+			config:  TransportConfig{DNSFailureCooldown: -time.Second},
+			wantErr: true,
+			errMsg:  "dns_failure_cooldown must be non-negative",
+		},
+		{
+			name:    "stream watchdog",
+			config:  TransportConfig{MaxStreamDuration: time.Hour, MinBytesPerSec: 1024, MinThroughputWindow: time.Minute},
+			wantErr: false,
+		},
+		{
+			name:    "negative max stream duration",
+			config:  TransportConfig{MaxStreamDuration: -time.Second},
+			wantErr: true,
+			errMsg:  "max_stream_duration must be non-negative",
+		},
+		{
+			name:    "negative min bytes per sec",
+			config:  TransportConfig{MinBytesPerSec: -1},
+			wantErr: true,
+			errMsg:  "min_bytes_per_sec must be non-negative",
+		},
+		{
+			name:    "negative min throughput window",
+			config:  TransportConfig{MinThroughputWindow: -time.Second},
+			wantErr: true,
+			errMsg:  "min_throughput_window must be non-negative",
+		},
+		{
+			name:    "max in-flight bytes",
+			config:  TransportConfig{MaxInFlightBytes: 10 << 30},
+			wantErr: false,
+		},
+		{
+			name:    "negative max in-flight bytes",
+			config:  TransportConfig{MaxInFlightBytes: -1},
+			wantErr: true,
+			errMsg:  "max_in_flight_bytes must be non-negative",
+		},
+		{
+			name:    "follow redirects",
+			config:  TransportConfig{Redirects: RedirectsFollow, MaxRedirects: 3},
+			wantErr: false,
+		},
+		{
+			name:    "invalid redirects",
+			config:  TransportConfig{Redirects: "always"},
+			wantErr: true,
+			errMsg:  "invalid redirects",
+		},
+		{
+			name:    "negative max redirects",
+			config:  TransportConfig{Redirects: RedirectsFollow, MaxRedirects: -1},
+			wantErr: true,
+			errMsg:  "max_redirects must be non-negative",
+		},
+		{
+			name:    "host header and tls server name",
+			config:  TransportConfig{HostHeader: "registry.internal:8443", TLSServerName: "registry.internal"},
+			wantErr: false,
+		},
+		{
+			name:    "host header with path",
+			config:  TransportConfig{HostHeader: "registry.internal/v2"},
+			wantErr: true,
+			errMsg:  "invalid host_header",
+		},
+		{
+			name:    "tls server name with port",
+			config:  TransportConfig{TLSServerName: "registry.internal:443"},
+			wantErr: true,
+			errMsg:  "invalid tls_server_name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+// TestAdminConfig_Validate tests admin configuration validation
+func TestAdminConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  AdminConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "empty config",
+			config:  AdminConfig{},
+			wantErr: false,
+		},
+		{
+			name:    "impersonation with admins",
+			config:  AdminConfig{Users: []string{"alice"}, Impersonation: true},
+			wantErr: false,
+		},
+		{
+			name:    "impersonation without admins",
+			config:  AdminConfig{Impersonation: true},
+			wantErr: true,
+			errMsg:  "requires at least one admin user",
+		},
+		{
+			name:    "empty username",
+			config:  AdminConfig{Users: []string{"alice", ""}},
+			wantErr: true,
+			errMsg:  "empty usernames",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+// TestAdminConfig_IsAdmin tests case-insensitive admin matching
+func TestAdminConfig_IsAdmin(t *testing.T) {
+	cfg := AdminConfig{Users: []string{"Alice"}}
+
+	if !cfg.IsAdmin("alice") {
+		t.Error("IsAdmin(alice) = false, want true")
+	}
+	if cfg.IsAdmin("bob") {
+		t.Error("IsAdmin(bob) = true, want false")
+	}
+}
+
+func TestConfig_Validate_FeatureFlags(t *testing.T) {
+	backend := OCIBackendConfig{
+		URL:                 "http://registry:5000",
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 100,
+		DialTimeout:         10 * time.Second,
+		RequestTimeout:      300 * time.Second,
+	}
+
+	tests := []struct {
+		name    string
+		flags   map[string]bool
+		wantErr bool
+	}{
+		{name: "no flags", flags: nil, wantErr: false},
+		{name: "snake_case flags", flags: map[string]bool{"content_cache": true, "token_service_v2": false}, wantErr: false},
+		{name: "dashes", flags: map[string]bool{"content-cache": true}, wantErr: true},
+		{name: "empty name", flags: map[string]bool{"": true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Server: ServerConfig{
+					Port:              8080,
+					ReadTimeout:       60 * time.Second,
+					WriteTimeout:      300 * time.Second,
+					MaxConcurrentReqs: 1000,
+				},
+				GitHub: GitHubConfig{
+					APIURL:       "https://api.github.com",
+					AuthCacheTTL: 30 * time.Minute,
+				},
+				Protocols: ProtocolsConfig{
+					OCI: OCIConfig{
+						Enabled:      true,
+						PullBackends: []OCIBackendConfig{backend},
+						PushBackend:  backend,
+					},
+				},
+				Logging:      LoggingConfig{Level: "info", Format: "json"},
+				FeatureFlags: tt.flags,
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr && (err == nil || !strings.Contains(err.Error(), "feature_flags")) {
+				t.Errorf("expected feature_flags error, got: %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestPlatformFilterConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter PlatformFilterConfig
+		errMsg string
+	}{
+		{
+			name:   "configured platforms",
+			filter: PlatformFilterConfig{Enabled: true, Platforms: []string{"linux/amd64", "linux/arm64/v8"}},
+		},
+		{
+			name:   "client hints only",
+			filter: PlatformFilterConfig{Enabled: true, ClientHints: true},
+		},
+		{
+			name:   "nothing to filter by",
+			filter: PlatformFilterConfig{Enabled: true},
+			errMsg: "platforms or client_hints is required",
+		},
+		{
+			name:   "platform without arch",
+			filter: PlatformFilterConfig{Enabled: true, Platforms: []string{"linux"}},
+			errMsg: `invalid platform "linux"`,
+		},
+		{
+			name:   "platform with empty part",
+			filter: PlatformFilterConfig{Enabled: true, Platforms: []string{"linux//v8"}},
+			errMsg: `invalid platform "linux//v8"`,
+		},
+		{
+			name:   "negative cache ttl",
+			filter: PlatformFilterConfig{Enabled: true, ClientHints: true, CacheTTL: -time.Second},
+			errMsg: "cache_ttl must be non-negative",
+		},
+	}
 
-		err := cfg.Validate()
-		if err != nil {
-			t.Errorf("should allow same path_prefix with different hosts, got error: %v", err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestMirrorNamespaceConfig_Validate(t *testing.T) {
+	pullBackends := []OCIBackendConfig{{Name: "dockerhub"}, {Name: "quay"}}
+
+	tests := []struct {
+		name   string
+		mirror MirrorNamespaceConfig
+		errMsg string
+	}{
+		{
+			name:   "default prefix",
+			mirror: MirrorNamespaceConfig{Enabled: true, Prefix: "mirror"},
+		},
+		{
+			name: "registries mapped to pull backends",
+			mirror: MirrorNamespaceConfig{Enabled: true, Prefix: "upstream-images", Registries: []MirrorRegistryConfig{
+				{Registry: "quay.io", Backend: "quay"},
+				{Registry: "registry.k8s.io", Backend: "dockerhub"},
+			}},
+		},
+		{
+			name:   "prefix with slash",
+			mirror: MirrorNamespaceConfig{Enabled: true, Prefix: "mirror/images"},
+			errMsg: `invalid prefix "mirror/images"`,
+		},
+		{
+			name:   "uppercase prefix",
+			mirror: MirrorNamespaceConfig{Enabled: true, Prefix: "Mirror"},
+			errMsg: `invalid prefix "Mirror"`,
+		},
+		{
+			name:   "registry with path",
+			mirror: MirrorNamespaceConfig{Enabled: true, Prefix: "mirror", Registries: []MirrorRegistryConfig{{Registry: "quay.io/org", Backend: "quay"}}},
+			errMsg: `invalid registry "quay.io/org"`,
+		},
+		{
+			name: "duplicate registry",
+			mirror: MirrorNamespaceConfig{Enabled: true, Prefix: "mirror", Registries: []MirrorRegistryConfig{
+				{Registry: "quay.io", Backend: "quay"},
+				{Registry: "quay.io", Backend: "dockerhub"},
+			}},
+			errMsg: `duplicate registry "quay.io"`,
+		},
+		{
+			name:   "unknown backend",
+			mirror: MirrorNamespaceConfig{Enabled: true, Prefix: "mirror", Registries: []MirrorRegistryConfig{{Registry: "quay.io", Backend: "push"}}},
+			errMsg: `backend "push" is not a pull backend`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.mirror.Validate(pullBackends)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+// TestOCIConfig_Validate_Replication tests replication configuration validation
+func TestOCIConfig_Validate_Replication(t *testing.T) {
+	backend := func(name string) OCIBackendConfig {
+		return OCIBackendConfig{
+			Name:                name,
+			URL:                 "http://" + name + ":5000",
+			MaxIdleConns:        200,
+			MaxIdleConnsPerHost: 100,
+			DialTimeout:         10 * time.Second,
+			RequestTimeout:      300 * time.Second,
 		}
-	})
+	}
 
-	t.Run("unique path_prefix with empty host - valid", func(t *testing.T) {
-		cfg := ProtocolsConfig{
-			Maven: MavenConfig{
-				Enabled:    true,
-				Host:       "",
-				PathPrefix: "/maven",
-				Backend: MavenBackendConfig{
-					URL:                 "https://maven.example.com",
-					MaxIdleConns:        200,
-					MaxIdleConnsPerHost: 100,
-					DialTimeout:         10 * time.Second,
-					RequestTimeout:      300 * time.Second,
-				},
-			},
-			NPM: NPMConfig{
-				Enabled:    true,
-				Host:       "",
-				PathPrefix: "/npm",
-				Backend: NPMBackendConfig{
-					URL:                 "https://npm.example.com",
-					MaxIdleConns:        200,
-					MaxIdleConnsPerHost: 100,
-					DialTimeout:         10 * time.Second,
-					RequestTimeout:      300 * time.Second,
-				},
+	tests := []struct {
+		name        string
+		replication ReplicationConfig
+		errMsg      string
+	}{
+		{
+			name:        "disabled with empty target",
+			replication: ReplicationConfig{},
+		},
+		{
+			name: "enabled",
+			replication: ReplicationConfig{
+				Enabled:           true,
+				Target:            backend("dr"),
+				ReconcileInterval: time.Hour,
 			},
-		}
+		},
+		{
+			name:        "target without name",
+			replication: ReplicationConfig{Enabled: true, Target: backend("")},
+			errMsg:      "replication: target: name is required",
+		},
+		{
+			name:        "target with push backend name",
+			replication: ReplicationConfig{Enabled: true, Target: backend("registry")},
+			errMsg:      "name must differ from the push backend name",
+		},
+		{
+			name:        "invalid target",
+			replication: ReplicationConfig{Enabled: true, Target: OCIBackendConfig{Name: "dr"}},
+			errMsg:      "replication: target: url is required",
+		},
+		{
+			name:        "negative workers",
+			replication: ReplicationConfig{Enabled: true, Target: backend("dr"), Workers: -1},
+			errMsg:      "workers must be non-negative",
+		},
+		{
+			name:        "negative queue size",
+			replication: ReplicationConfig{Enabled: true, Target: backend("dr"), QueueSize: -1},
+			errMsg:      "queue_size must be non-negative",
+		},
+		{
+			name:        "negative reconcile interval",
+			replication: ReplicationConfig{Enabled: true, Target: backend("dr"), ReconcileInterval: -time.Second},
+			errMsg:      "reconcile_interval must be non-negative",
+		},
+	}
 
-		err := cfg.Validate()
-		if err != nil {
-			t.Errorf("should allow unique path_prefix, got error: %v", err)
-		}
-	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := OCIConfig{
+				PullBackends: []OCIBackendConfig{backend("registry")},
+				PushBackend:  backend("registry"),
+				Replication:  tt.replication,
+			}
+
+			err := cfg.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got %v", tt.errMsg, err)
+			}
+		})
+	}
 }
 
-// TestValidateBackendCommon tests the common backend validation helper
-func TestValidateBackendCommon(t *testing.T) {
-	// Valid circuit breaker config for tests
-	validCB := CircuitBreakerConfig{
-		Enabled:          true,
-		MaxRequests:      10,
-		Interval:         60 * time.Second,
-		Timeout:          30 * time.Second,
-		FailureThreshold: 0.5,
+func TestLFSConfig_Validate(t *testing.T) {
+	backend := LFSBackendConfig{
+		URL:                 "https://git.internal.example.com",
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 100,
+		DialTimeout:         10 * time.Second,
+		RequestTimeout:      300 * time.Second,
+	}
+
+	tests := []struct {
+		name    string
+		config  LFSConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid config with path_prefix",
+			config:  LFSConfig{PathPrefix: "/lfs", Backend: backend},
+			wantErr: false,
+		},
+		{
+			name:    "valid config with host and empty path_prefix",
+			config:  LFSConfig{Host: "lfs.example.com", Backend: backend},
+			wantErr: false,
+		},
+		{
+			name:    "invalid - empty host requires path_prefix",
+			config:  LFSConfig{Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix is required when host is empty",
+		},
+		{
+			name:    "invalid - path_prefix must start with /",
+			config:  LFSConfig{PathPrefix: "lfs", Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix must start with '/'",
+		},
+		{
+			name:    "invalid - backend without URL",
+			config:  LFSConfig{PathPrefix: "/lfs", Backend: LFSBackendConfig{}},
+			wantErr: true,
+			errMsg:  "backend:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestCocoaPodsConfig_Validate(t *testing.T) {
+	backend := CocoaPodsBackendConfig{
+		Name:                "trunk",
+		URL:                 "https://cdn.cocoapods.org",
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 100,
+		DialTimeout:         10 * time.Second,
+		RequestTimeout:      300 * time.Second,
+	}
+	binaryBackend := func(name string) *CocoaPodsBackendConfig {
+		binaries := backend
+		binaries.Name = name
+		binaries.URL = "http://nexus:8081/repository/pods"
+		return &binaries
+	}
+
+	tests := []struct {
+		name    string
+		config  CocoaPodsConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid config with path_prefix",
+			config:  CocoaPodsConfig{PathPrefix: "/cocoapods", Backend: backend},
+			wantErr: false,
+		},
+		{
+			name:    "valid config with host and empty path_prefix",
+			config:  CocoaPodsConfig{Host: "pods.example.com", Backend: backend},
+			wantErr: false,
+		},
+		{
+			name:    "invalid - empty host requires path_prefix",
+			config:  CocoaPodsConfig{Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix is required when host is empty",
+		},
+		{
+			name:    "invalid - path_prefix must start with /",
+			config:  CocoaPodsConfig{PathPrefix: "cocoapods", Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix must start with '/'",
+		},
+		{
+			name:    "valid config with binary backend",
+			config:  CocoaPodsConfig{PathPrefix: "/cocoapods", Backend: backend, BinaryBackend: binaryBackend("pod-binaries")},
+			wantErr: false,
+		},
+		{
+			name:    "invalid - binary backend without name",
+			config:  CocoaPodsConfig{PathPrefix: "/cocoapods", Backend: backend, BinaryBackend: binaryBackend("")},
+			wantErr: true,
+			errMsg:  "binary_backend: name is required",
+		},
+		{
+			name:    "invalid - binary backend named like backend",
+			config:  CocoaPodsConfig{PathPrefix: "/cocoapods", Backend: backend, BinaryBackend: binaryBackend("trunk")},
+			wantErr: true,
+			errMsg:  "name must differ",
+		},
+		{
+			name:    "invalid - backend without URL",
+			config:  CocoaPodsConfig{PathPrefix: "/cocoapods", Backend: CocoaPodsBackendConfig{}},
+			wantErr: true,
+			errMsg:  "backend:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestHexConfig_Validate(t *testing.T) {
+	backend := HexBackendConfig{
+		Name:                "mini-repo",
+		URL:                 "http://mini-repo:4000/repos/acme",
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 100,
+		DialTimeout:         10 * time.Second,
+		RequestTimeout:      300 * time.Second,
+	}
+	upstream := backend
+	upstream.Name = "hexpm"
+	upstream.URL = "https://repo.hex.pm"
+	publish := backend
+	publish.Name = "mini-repo-api"
+	publish.URL = "http://mini-repo:4000/api/repos/acme"
+
+	tests := []struct {
+		name    string
+		config  HexConfig
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid config with path_prefix",
+			config:  HexConfig{PathPrefix: "/hex", Backend: backend},
+			wantErr: false,
+		},
+		{
+			name:    "valid config with upstream",
+			config:  HexConfig{Host: "hex.example.com", Backend: backend, Upstream: &upstream, PublishBackend: &publish},
+			wantErr: false,
+		},
+		{
+			name:    "invalid - empty host requires path_prefix",
+			config:  HexConfig{Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix is required when host is empty",
+		},
+		{
+			name:    "invalid - path_prefix must start with /",
+			config:  HexConfig{PathPrefix: "hex", Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix must start with '/'",
+		},
+		{
+			name:    "invalid - backend without URL",
+			config:  HexConfig{PathPrefix: "/hex", Backend: HexBackendConfig{}},
+			wantErr: true,
+			errMsg:  "backend:",
+		},
+		{
+			name:    "invalid - upstream without name",
+			config:  HexConfig{PathPrefix: "/hex", Backend: backend, Upstream: &HexBackendConfig{URL: upstream.URL, MaxIdleConns: 1, MaxIdleConnsPerHost: 1, DialTimeout: time.Second, RequestTimeout: time.Second}},
+			wantErr: true,
+			errMsg:  "upstream: name is required",
+		},
+		{
+			name:    "invalid - upstream named like the backend",
+			config:  HexConfig{PathPrefix: "/hex", Backend: backend, Upstream: &backend},
+			wantErr: true,
+			errMsg:  "upstream name must differ",
+		},
+		{
+			name:    "invalid - publish backend named like the upstream",
+			config:  HexConfig{PathPrefix: "/hex", Backend: backend, Upstream: &upstream, PublishBackend: &upstream},
+			wantErr: true,
+			errMsg:  "publish_backend: name must differ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
 	}
+}
 
-	// Disabled circuit breaker for simpler tests
-	disabledCB := CircuitBreakerConfig{
-		Enabled: false,
+func TestPubConfig_Validate(t *testing.T) {
+	backend := PubBackendConfig{
+		Name:                "unpub",
+		URL:                 "http://unpub:4000",
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 100,
+		DialTimeout:         10 * time.Second,
+		RequestTimeout:      300 * time.Second,
 	}
 
 	tests := []struct {
-		name                string
-		backendURL          string
-		maxIdleConns        int
-		maxIdleConnsPerHost int
-		dialTimeout         time.Duration
-		requestTimeout      time.Duration
-		circuitBreaker      CircuitBreakerConfig
-		wantErr             bool
-		errMsg              string
+		name    string
+		config  PubConfig
+		wantErr bool
+		errMsg  string
 	}{
 		{
-			name:                "valid config",
-			backendURL:          "https://example.com",
-			maxIdleConns:        200,
-			maxIdleConnsPerHost: 100,
-			dialTimeout:         10 * time.Second,
-			requestTimeout:      300 * time.Second,
-			circuitBreaker:      disabledCB,
-			wantErr:             false,
+			name:    "valid config with path_prefix",
+			config:  PubConfig{PathPrefix: "/pub", Backend: backend},
+			wantErr: false,
 		},
 		{
-			name:                "valid config with circuit breaker",
-			backendURL:          "https://example.com",
-			maxIdleConns:        200,
-			maxIdleConnsPerHost: 100,
-			dialTimeout:         10 * time.Second,
-			requestTimeout:      300 * time.Second,
-			circuitBreaker:      validCB,
-			wantErr:             false,
+			name:    "valid config with host",
+			config:  PubConfig{Host: "pub.example.com", Backend: backend},
+			wantErr: false,
 		},
 		{
-			name:                "missing URL",
-			backendURL:          "",
-			maxIdleConns:        200,
-			maxIdleConnsPerHost: 100,
-			dialTimeout:         10 * time.Second,
-			requestTimeout:      300 * time.Second,
-			circuitBreaker:      disabledCB,
-			wantErr:             true,
-			errMsg:              "url is required",
+			name:    "invalid - empty host requires path_prefix",
+			config:  PubConfig{Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix is required when host is empty",
 		},
 		{
-			name:                "invalid URL",
-			backendURL:          "://invalid",
-			maxIdleConns:        200,
-			maxIdleConnsPerHost: 100,
-			dialTimeout:         10 * time.Second,
-			requestTimeout:      300 * time.Second,
-			circuitBreaker:      disabledCB,
-			wantErr:             true,
-			errMsg:              "invalid url",
+			name:    "invalid - path_prefix must start with /",
+			config:  PubConfig{PathPrefix: "pub", Backend: backend},
+			wantErr: true,
+			errMsg:  "path_prefix must start with '/'",
 		},
 		{
-			name:                "maxIdleConns too low",
-			backendURL:          "https://example.com",
-			maxIdleConns:        0,
-			maxIdleConnsPerHost: 100,
-			dialTimeout:         10 * time.Second,
-			requestTimeout:      300 * time.Second,
-			circuitBreaker:      disabledCB,
-			wantErr:             true,
-			errMsg:              "maxIdleConns must be at least 1",
+			name:    "invalid - backend without URL",
+			config:  PubConfig{PathPrefix: "/pub", Backend: PubBackendConfig{}},
+			wantErr: true,
+			errMsg:  "backend:",
 		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr && err != nil && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestVagrantConfig_Validate(t *testing.T) {
+	backend := func(name string) VagrantBackendConfig {
+		return VagrantBackendConfig{
+			Name:                name,
+			URL:                 "http://" + name + ":8080",
+			MaxIdleConns:        200,
+			MaxIdleConnsPerHost: 100,
+			DialTimeout:         10 * time.Second,
+			RequestTimeout:      300 * time.Second,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		config  VagrantConfig
+		wantErr bool
+		errMsg  string
+	}{
 		{
-			name:                "maxIdleConnsPerHost too low",
-			backendURL:          "https://example.com",
-			maxIdleConns:        200,
-			maxIdleConnsPerHost: 0,
-			dialTimeout:         10 * time.Second,
-			requestTimeout:      300 * time.Second,
-			circuitBreaker:      disabledCB,
-			wantErr:             true,
-			errMsg:              "maxIdleConnsPerHost must be at least 1",
+			name:    "valid config with path_prefix",
+			config:  VagrantConfig{PathPrefix: "/vagrant", Backend: backend("catalog")},
+			wantErr: false,
 		},
 		{
-			name:                "maxIdleConnsPerHost exceeds maxIdleConns",
-			backendURL:          "https://example.com",
-			maxIdleConns:        100,
-			maxIdleConnsPerHost: 200,
-			dialTimeout:         10 * time.Second,
-			requestTimeout:      300 * time.Second,
-			circuitBreaker:      disabledCB,
-			wantErr:             true,
-			errMsg:              "maxIdleConnsPerHost cannot exceed maxIdleConns",
+			name:    "valid config with download backends",
+			config:  VagrantConfig{Host: "boxes.example.com", Backend: backend("catalog"), DownloadBackends: []VagrantBackendConfig{backend("files"), backend("s3")}},
+			wantErr: false,
 		},
 		{
-			name:                "invalid dialTimeout",
-			backendURL:          "https://example.com",
-			maxIdleConns:        200,
-			maxIdleConnsPerHost: 100,
-			dialTimeout:         0,
-			requestTimeout:      300 * time.Second,
-			circuitBreaker:      disabledCB,
-			wantErr:             true,
-			errMsg:              "invalid dialTimeout",
+			name:    "invalid - empty host requires path_prefix",
+			config:  VagrantConfig{Backend: backend("catalog")},
+			wantErr: true,
+			errMsg:  "path_prefix is required when host is empty",
 		},
 		{
-			name:                "negative dialTimeout",
-			backendURL:          "https://example.com",
-			maxIdleConns:        200,
-			maxIdleConnsPerHost: 100,
-			dialTimeout:         -5 * time.Second,
-			requestTimeout:      300 * time.Second,
-			circuitBreaker:      disabledCB,
-			wantErr:             true,
-			errMsg:              "invalid dialTimeout",
+			name:    "invalid - path_prefix must start with /",
+			config:  VagrantConfig{PathPrefix: "vagrant", Backend: backend("catalog")},
+			wantErr: true,
+			errMsg:  "path_prefix must start with '/'",
 		},
 		{
-			name:                "invalid requestTimeout",
-			backendURL:          "https://example.com",
-			maxIdleConns:        200,
-			maxIdleConnsPerHost: 100,
-			dialTimeout:         10 * time.Second,
-			requestTimeout:      0,
-			circuitBreaker:      disabledCB,
-			wantErr:             true,
-			errMsg:              "invalid requestTimeout",
+			name:    "invalid - backend without URL",
+			config:  VagrantConfig{PathPrefix: "/vagrant", Backend: VagrantBackendConfig{}},
+			wantErr: true,
+			errMsg:  "backend:",
 		},
 		{
-			name:                "negative requestTimeout",
-			backendURL:          "https://example.com",
-			maxIdleConns:        200,
-			maxIdleConnsPerHost: 100,
-			dialTimeout:         10 * time.Second,
-			requestTimeout:      -300 * time.Second,
-			circuitBreaker:      disabledCB,
-			wantErr:             true,
-			errMsg:              "invalid requestTimeout",
+			name:    "invalid - download backend without name",
+			config:  VagrantConfig{PathPrefix: "/vagrant", Backend: backend("catalog"), DownloadBackends: []VagrantBackendConfig{backend("")}},
+			wantErr: true,
+			errMsg:  "download_backends[0]: invalid name",
 		},
 		{
-			name:                "invalid circuit breaker config",
-			backendURL:          "https://example.com",
-			maxIdleConns:        200,
-			maxIdleConnsPerHost: 100,
-			dialTimeout:         10 * time.Second,
-			requestTimeout:      300 * time.Second,
-			circuitBreaker: CircuitBreakerConfig{
-				Enabled:          true,
-				MaxRequests:      0, // Invalid
-				Interval:         60 * time.Second,
-				Timeout:          30 * time.Second,
-				FailureThreshold: 0.5,
-			},
+			name:    "invalid - download backend named like the catalog backend",
+			config:  VagrantConfig{PathPrefix: "/vagrant", Backend: backend("catalog"), DownloadBackends: []VagrantBackendConfig{backend("catalog")}},
 			wantErr: true,
-			errMsg:  "circuit breaker",
+			errMsg:  "duplicate backend name",
+		},
+		{
+			name: "invalid - download backend without URL",
+			config: VagrantConfig{PathPrefix: "/vagrant", Backend: backend("catalog"), DownloadBackends: []VagrantBackendConfig{
+				{Name: "files"},
+			}},
+			wantErr: true,
+			errMsg:  "download_backends[0] (files):",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validateBackendCommon(
-				tt.backendURL,
-				tt.maxIdleConns,
-				tt.maxIdleConnsPerHost,
-				tt.dialTimeout,
-				tt.requestTimeout,
-				tt.circuitBreaker,
-			)
+			err := tt.config.Validate()
 			if tt.wantErr && err == nil {
 				t.Error("expected error but got none")
 			}
@@ -986,61 +4022,30 @@ func TestValidateBackendCommon(t *testing.T) {
 	}
 }
 
-// TestBackendConfig_Validate_Integration tests that all backend types use validateBackendCommon
-func TestBackendConfig_Validate_Integration(t *testing.T) {
-	// This test ensures that all backend types (OCI, Maven, NPM) properly use validateBackendCommon
-	// by testing that they all reject the same invalid configurations
-
-	invalidURL := "://invalid"
-
-	t.Run("OCIBackendConfig rejects invalid URL", func(t *testing.T) {
-		backend := OCIBackendConfig{
-			URL:                 invalidURL,
-			MaxIdleConns:        200,
-			MaxIdleConnsPerHost: 100,
-			DialTimeout:         10 * time.Second,
-			RequestTimeout:      300 * time.Second,
-		}
-		err := backend.Validate()
-		if err == nil {
-			t.Error("expected error for invalid URL")
-		}
-		if !strings.Contains(err.Error(), "invalid url") {
-			t.Errorf("unexpected error: %v", err)
-		}
-	})
-
-	t.Run("MavenBackendConfig rejects invalid URL", func(t *testing.T) {
-		backend := MavenBackendConfig{
-			URL:                 invalidURL,
-			MaxIdleConns:        200,
-			MaxIdleConnsPerHost: 100,
-			DialTimeout:         10 * time.Second,
-			RequestTimeout:      300 * time.Second,
-		}
-		err := backend.Validate()
-		if err == nil {
-			t.Error("expected error for invalid URL")
-		}
-		if !strings.Contains(err.Error(), "invalid url") {
-			t.Errorf("unexpected error: %v", err)
-		}
-	})
+func TestNamingConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config NamingConfig
+		errMsg string
+	}{
+		{name: "no patterns", config: NamingConfig{}},
+		{name: "patterns", config: NamingConfig{Patterns: []string{"^myorg/", `^com\.myorg(\.|$)`}, Message: "see the naming guidelines"}},
+		{name: "empty pattern", config: NamingConfig{Patterns: []string{""}}, errMsg: "patterns must not be empty"},
+		{name: "invalid pattern", config: NamingConfig{Patterns: []string{"^myorg/("}}, errMsg: `invalid pattern "^myorg/("`},
+	}
 
-	t.Run("NPMBackendConfig rejects invalid URL", func(t *testing.T) {
-		backend := NPMBackendConfig{
-			URL:                 invalidURL,
-			MaxIdleConns:        200,
-			MaxIdleConnsPerHost: 100,
-			DialTimeout:         10 * time.Second,
-			RequestTimeout:      300 * time.Second,
-		}
-		err := backend.Validate()
-		if err == nil {
-			t.Error("expected error for invalid URL")
-		}
-		if !strings.Contains(err.Error(), "invalid url") {
-			t.Errorf("unexpected error: %v", err)
-		}
-	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
 }
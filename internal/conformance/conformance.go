@@ -0,0 +1,216 @@
+// Package conformance verifies that a running Artifusion instance speaks each artifact
+// protocol correctly, before an upgrade is rolled out.
+//
+// A suite is an ordered list of protocol-level checks (push, pull, metadata and error
+// flows) run against the instance's protocol endpoint. Suites write test artifacts,
+// so they are meant for staging instances whose backends are the in-memory fakes
+// returned by NewFakeBackend rather than real registries.
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported protocols
+const (
+	ProtocolOCI   = "oci"
+	ProtocolNPM   = "npm"
+	ProtocolMaven = "maven"
+)
+
+// maxResponseSize bounds the response bodies read by checks
+const maxResponseSize = 16 << 20
+
+// Options configures a conformance run
+type Options struct {
+	// Target is the base URL of the protocol on the instance under test, e.g.
+	// http://localhost:8080 for OCI or http://localhost:8080/npm for path-routed npm
+	Target string
+
+	// Username and Token are sent as basic auth (GitHub token as password)
+	Username string
+	Token    string
+
+	// Client sends the requests (nil = a client with a 30s timeout)
+	Client *http.Client
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name     string
+	Err      error // nil if the check passed or was skipped
+	Skipped  bool  // An earlier check failed
+	Duration time.Duration
+}
+
+// Passed reports whether every check passed
+func Passed(results []Result) bool {
+	for _, r := range results {
+		if r.Err != nil || r.Skipped {
+			return false
+		}
+	}
+	return true
+}
+
+// check is one step of a suite. Checks run in order and share the suite state,
+// so each may depend on the artifacts earlier checks pushed.
+type check struct {
+	name string
+	run  func(ctx context.Context, s *session) error
+}
+
+// suites lists the checks of each protocol
+var suites = map[string]func() []check{
+	ProtocolOCI:   ociChecks,
+	ProtocolNPM:   npmChecks,
+	ProtocolMaven: mavenChecks,
+}
+
+// Protocols returns the protocols with a conformance suite
+func Protocols() []string {
+	protocols := make([]string, 0, len(suites))
+	for protocol := range suites {
+		protocols = append(protocols, protocol)
+	}
+	sort.Strings(protocols)
+	return protocols
+}
+
+// Run runs the conformance suite of protocol against opts.Target. After the first
+// failed check the remaining checks are skipped, as they build on each other.
+func Run(ctx context.Context, protocol string, opts Options) ([]Result, error) {
+	suite, ok := suites[protocol]
+	if !ok {
+		return nil, fmt.Errorf("unknown protocol %q (supported: %s)", protocol, strings.Join(Protocols(), ", "))
+	}
+
+	s := newSession(opts)
+	var results []Result
+	failed := false
+	for _, c := range suite() {
+		if failed {
+			results = append(results, Result{Name: c.name, Skipped: true})
+			continue
+		}
+
+		start := time.Now()
+		err := c.run(ctx, s)
+		results = append(results, Result{Name: c.name, Err: err, Duration: time.Since(start)})
+		failed = err != nil
+	}
+	return results, nil
+}
+
+// session holds the client and the state checks share during a run
+type session struct {
+	target   string
+	username string
+	token    string
+	client   *http.Client
+
+	// Unique suffix of the artifacts pushed by this run
+	runID string
+
+	// Protocol-specific state set by earlier checks
+	values map[string]string
+}
+
+func newSession(opts Options) *session {
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &session{
+		target:   strings.TrimSuffix(opts.Target, "/"),
+		username: opts.Username,
+		token:    opts.Token,
+		client:   client,
+		runID:    strconv.FormatInt(time.Now().UnixNano(), 10),
+		values:   make(map[string]string),
+	}
+}
+
+// response is a fully read response
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// do sends a request to path (relative to the target, or an absolute URL)
+func (s *session) do(ctx context.Context, method, path string, header http.Header, body []byte) (*response, error) {
+	url := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		url = s.target + path
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if s.token != "" {
+		req.SetBasicAuth(s.username, s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: reading response: %w", method, path, err)
+	}
+	return &response{status: resp.StatusCode, header: resp.Header, body: data}, nil
+}
+
+// expect sends a request and fails unless the response status is one of want
+func (s *session) expect(ctx context.Context, method, path string, header http.Header, body []byte, want ...int) (*response, error) {
+	resp, err := s.do(ctx, method, path, header, body)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range want {
+		if resp.status == status {
+			return resp, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s: status %d, want %s%s", method, path, resp.status, statusList(want), snippet(resp.body))
+}
+
+func statusList(statuses []int) string {
+	parts := make([]string, len(statuses))
+	for i, status := range statuses {
+		parts[i] = strconv.Itoa(status)
+	}
+	return strings.Join(parts, " or ")
+}
+
+// snippet formats the start of a response body for error messages
+func snippet(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	const max = 200
+	if len(body) > max {
+		return fmt.Sprintf(" (body: %s...)", body[:max])
+	}
+	return fmt.Sprintf(" (body: %s)", body)
+}
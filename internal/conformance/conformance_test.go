@@ -0,0 +1,92 @@
+package conformance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRun_FakeBackends(t *testing.T) {
+	for _, protocol := range Protocols() {
+		t.Run(protocol, func(t *testing.T) {
+			backend, err := NewFakeBackend(protocol)
+			if err != nil {
+				t.Fatalf("NewFakeBackend() error = %v", err)
+			}
+			server := httptest.NewServer(backend)
+			defer server.Close()
+
+			results, err := Run(context.Background(), protocol, Options{Target: server.URL})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if len(results) == 0 {
+				t.Fatal("Run() returned no results")
+			}
+			for _, result := range results {
+				if result.Err != nil || result.Skipped {
+					t.Errorf("check %q: err = %v, skipped = %v", result.Name, result.Err, result.Skipped)
+				}
+			}
+			if !Passed(results) {
+				t.Error("Passed() = false, want true")
+			}
+		})
+	}
+}
+
+func TestRun_SkipsAfterFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	results, err := Run(context.Background(), ProtocolOCI, Options{Target: server.URL})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if Passed(results) {
+		t.Fatal("Passed() = true, want false")
+	}
+	if results[0].Err == nil {
+		t.Error("first check passed against a failing server")
+	}
+	for _, result := range results[1:] {
+		if !result.Skipped {
+			t.Errorf("check %q ran after a failure", result.Name)
+		}
+	}
+}
+
+func TestRun_UnknownProtocol(t *testing.T) {
+	if _, err := Run(context.Background(), "pypi", Options{}); err == nil {
+		t.Error("Run() error = nil for an unknown protocol")
+	}
+	if _, err := NewFakeBackend("pypi"); err == nil {
+		t.Error("NewFakeBackend() error = nil for an unknown protocol")
+	}
+}
+
+func TestRun_BasicAuth(t *testing.T) {
+	backend, err := NewFakeBackend(ProtocolMaven)
+	if err != nil {
+		t.Fatalf("NewFakeBackend() error = %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, token, ok := r.BasicAuth(); !ok || username != "ci" || token != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		backend.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	results, err := Run(context.Background(), ProtocolMaven, Options{Target: server.URL, Username: "ci", Token: "secret"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !Passed(results) {
+		t.Errorf("Passed() = false with credentials: %+v", results)
+	}
+}
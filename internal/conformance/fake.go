@@ -0,0 +1,322 @@
+package conformance
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// NewFakeBackend returns an in-memory registry for protocol, to configure as the
+// backend of the instance under test. It implements just enough of the protocol for
+// the conformance suite, and keeps everything pushed to it until the process exits.
+func NewFakeBackend(protocol string) (http.Handler, error) {
+	switch protocol {
+	case ProtocolOCI:
+		return &fakeOCI{
+			blobs:     make(map[string][]byte),
+			manifests: make(map[string]fakeManifest),
+			uploads:   make(map[string][]byte),
+		}, nil
+	case ProtocolNPM:
+		return &fakeNPM{
+			packuments: make(map[string]map[string]any),
+			tarballs:   make(map[string][]byte),
+		}, nil
+	case ProtocolMaven:
+		return &fakeMaven{files: make(map[string][]byte)}, nil
+	default:
+		return nil, fmt.Errorf("unknown protocol %q (supported: %s)", protocol, strings.Join(Protocols(), ", "))
+	}
+}
+
+// fakeOCI is an in-memory OCI distribution registry
+type fakeOCI struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte       // By digest
+	manifests map[string]fakeManifest // By repository + ":" + tag or digest
+	uploads   map[string][]byte       // By upload session ID
+	nextID    int
+}
+
+type fakeManifest struct {
+	body        []byte
+	contentType string
+	digest      string
+}
+
+func (f *fakeOCI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Docker-Distribution-Api-Version", "registry/2.0")
+	path := r.URL.Path
+	if path == "/v2/" || path == "/v2" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	path = strings.TrimPrefix(path, "/v2/")
+
+	switch {
+	case strings.Contains(path, "/blobs/uploads/"):
+		repository, id, _ := strings.Cut(path, "/blobs/uploads/")
+		f.serveUpload(w, r, repository, id)
+	case strings.Contains(path, "/blobs/"):
+		_, digest, _ := strings.Cut(path, "/blobs/")
+		blob, ok := f.blobs[digest]
+		if !ok {
+			fakeOCIError(w, http.StatusNotFound, "BLOB_UNKNOWN", "blob unknown to registry")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(blob)))
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(blob)
+		}
+	case strings.Contains(path, "/manifests/"):
+		repository, reference, _ := strings.Cut(path, "/manifests/")
+		f.serveManifest(w, r, repository, reference)
+	case strings.HasSuffix(path, "/tags/list"):
+		repository := strings.TrimSuffix(path, "/tags/list")
+		tags := []string{}
+		for key := range f.manifests {
+			name, reference, _ := strings.Cut(key, ":")
+			if name == repository && !strings.HasPrefix(reference, "sha256:") {
+				tags = append(tags, reference)
+			}
+		}
+		sort.Strings(tags)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"name": repository, "tags": tags})
+	default:
+		fakeOCIError(w, http.StatusNotFound, "NAME_UNKNOWN", "repository name not known to registry")
+	}
+}
+
+func (f *fakeOCI) serveUpload(w http.ResponseWriter, r *http.Request, repository, id string) {
+	if r.Method == http.MethodPost {
+		f.nextID++
+		id = strconv.Itoa(f.nextID)
+		f.uploads[id] = nil
+		w.Header().Set("Location", "/v2/"+repository+"/blobs/uploads/"+id)
+		w.Header().Set("Range", "0-0")
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	data, ok := f.uploads[id]
+	if !ok {
+		fakeOCIError(w, http.StatusNotFound, "BLOB_UPLOAD_UNKNOWN", "blob upload unknown to registry")
+		return
+	}
+	chunk, err := io.ReadAll(r.Body)
+	if err != nil {
+		fakeOCIError(w, http.StatusBadRequest, "BLOB_UPLOAD_INVALID", err.Error())
+		return
+	}
+	data = append(data, chunk...)
+
+	switch r.Method {
+	case http.MethodPatch:
+		f.uploads[id] = data
+		w.Header().Set("Location", "/v2/"+repository+"/blobs/uploads/"+id)
+		w.Header().Set("Range", fmt.Sprintf("0-%d", len(data)-1))
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodPut:
+		digest := r.URL.Query().Get("digest")
+		if digest != sha256Digest(data) {
+			fakeOCIError(w, http.StatusBadRequest, "DIGEST_INVALID", "provided digest did not match uploaded content")
+			return
+		}
+		delete(f.uploads, id)
+		f.blobs[digest] = data
+		w.Header().Set("Location", "/v2/"+repository+"/blobs/"+digest)
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeOCI) serveManifest(w http.ResponseWriter, r *http.Request, repository, reference string) {
+	if r.Method == http.MethodPut {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			fakeOCIError(w, http.StatusBadRequest, "MANIFEST_INVALID", err.Error())
+			return
+		}
+		manifest := fakeManifest{body: body, contentType: r.Header.Get("Content-Type"), digest: sha256Digest(body)}
+		f.manifests[repository+":"+reference] = manifest
+		f.manifests[repository+":"+manifest.digest] = manifest
+		w.Header().Set("Location", "/v2/"+repository+"/manifests/"+manifest.digest)
+		w.Header().Set("Docker-Content-Digest", manifest.digest)
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	manifest, ok := f.manifests[repository+":"+reference]
+	if !ok {
+		fakeOCIError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest unknown to registry")
+		return
+	}
+	w.Header().Set("Content-Type", manifest.contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(manifest.body)))
+	w.Header().Set("Docker-Content-Digest", manifest.digest)
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(manifest.body)
+	}
+}
+
+func fakeOCIError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"errors": []map[string]string{{"code": code, "message": message}},
+	})
+}
+
+// fakeNPM is an in-memory npm registry
+type fakeNPM struct {
+	mu         sync.Mutex
+	packuments map[string]map[string]any // By package name
+	tarballs   map[string][]byte         // By path
+}
+
+func (f *fakeNPM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path, err := url.PathUnescape(r.URL.Path)
+	if err != nil {
+		fakeNPMError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch {
+	case path == "/-/ping":
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+	case strings.Contains(path, "/-/"):
+		tarball, ok := f.tarballs[path]
+		if !ok {
+			fakeNPMError(w, http.StatusNotFound, "not found")
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(tarball)
+	case r.Method == http.MethodPut:
+		f.publish(w, r, strings.TrimPrefix(path, "/"))
+	default:
+		packument, ok := f.packuments[strings.TrimPrefix(path, "/")]
+		if !ok {
+			fakeNPMError(w, http.StatusNotFound, "not found")
+			return
+		}
+		contentType := "application/json"
+		if strings.Contains(r.Header.Get("Accept"), npmAbbreviatedMediaType) {
+			contentType = npmAbbreviatedMediaType
+		}
+		w.Header().Set("Content-Type", contentType)
+		_ = json.NewEncoder(w).Encode(packument)
+	}
+}
+
+// publish adds the versions and tarballs of a publish request to the packument of
+// name, pointing dist.tarball at this registry as a real one would
+func (f *fakeNPM) publish(w http.ResponseWriter, r *http.Request, name string) {
+	var body struct {
+		DistTags    map[string]string         `json:"dist-tags"`
+		Versions    map[string]map[string]any `json:"versions"`
+		Attachments map[string]struct {
+			Data string `json:"data"`
+		} `json:"_attachments"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		fakeNPMError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	packument, ok := f.packuments[name]
+	if !ok {
+		packument = map[string]any{
+			"_id":       name,
+			"name":      name,
+			"dist-tags": map[string]string{},
+			"versions":  map[string]any{},
+		}
+	}
+	distTags := packument["dist-tags"].(map[string]string)
+	versions := packument["versions"].(map[string]any)
+
+	baseURL := "http://" + r.Host
+	for filename, attachment := range body.Attachments {
+		data, err := base64.StdEncoding.DecodeString(attachment.Data)
+		if err != nil {
+			fakeNPMError(w, http.StatusBadRequest, "invalid attachment "+filename)
+			return
+		}
+		f.tarballs["/"+name+"/-/"+filename] = data
+	}
+	for version, manifest := range body.Versions {
+		if dist, ok := manifest["dist"].(map[string]any); ok {
+			dist["tarball"] = baseURL + "/" + name + "/-/" + name[strings.LastIndex(name, "/")+1:] + "-" + version + ".tgz"
+		}
+		versions[version] = manifest
+	}
+	for tag, version := range body.DistTags {
+		distTags[tag] = version
+	}
+	f.packuments[name] = packument
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(`{"ok":true}`))
+}
+
+func fakeNPMError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// fakeMaven is an in-memory Maven repository
+type fakeMaven struct {
+	mu    sync.Mutex
+	files map[string][]byte // By path
+}
+
+func (f *fakeMaven) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.files[r.URL.Path] = data
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet, http.MethodHead:
+		data, ok := f.files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,140 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Coordinates of the artifact the Maven suite deploys
+const (
+	mavenGroupPath  = "com/example/artifusion"
+	mavenArtifactID = "artifusion-conformance"
+)
+
+// mavenChecks covers the Maven deploy and resolve flows: uploading a jar, its POM
+// and checksum and the artifact metadata, then downloading them back, and the error
+// response for unknown artifacts.
+func mavenChecks() []check {
+	return []check{
+		{name: "deploy jar", run: mavenDeployJar},
+		{name: "deploy pom", run: mavenDeployPOM},
+		{name: "deploy metadata", run: mavenDeployMetadata},
+		{name: "check jar exists", run: mavenHeadJar},
+		{name: "download jar", run: mavenDownloadJar},
+		{name: "download checksum", run: mavenDownloadChecksum},
+		{name: "download metadata", run: mavenDownloadMetadata},
+		{name: "unknown artifact", run: mavenUnknownArtifact},
+	}
+}
+
+// mavenPath returns the path of a file of the deployed version
+func (s *session) mavenPath(extension string) string {
+	version := "1.0." + s.runID
+	return "/" + mavenGroupPath + "/" + mavenArtifactID + "/" + version + "/" + mavenArtifactID + "-" + version + "." + extension
+}
+
+func mavenDeployJar(ctx context.Context, s *session) error {
+	jar := []byte("artifusion conformance jar " + s.runID + "\n")
+	sum := sha1.Sum(jar)
+	checksum := hex.EncodeToString(sum[:])
+
+	header := http.Header{"Content-Type": {"application/java-archive"}}
+	if _, err := s.expect(ctx, http.MethodPut, s.mavenPath("jar"), header, jar, http.StatusOK, http.StatusCreated); err != nil {
+		return err
+	}
+	if _, err := s.expect(ctx, http.MethodPut, s.mavenPath("jar.sha1"), nil, []byte(checksum), http.StatusOK, http.StatusCreated); err != nil {
+		return err
+	}
+
+	s.values["jar"] = string(jar)
+	s.values["jar_sha1"] = checksum
+	return nil
+}
+
+func mavenDeployPOM(ctx context.Context, s *session) error {
+	pom := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <modelVersion>4.0.0</modelVersion>
+  <groupId>%s</groupId>
+  <artifactId>%s</artifactId>
+  <version>1.0.%s</version>
+</project>
+`, strings.ReplaceAll(mavenGroupPath, "/", "."), mavenArtifactID, s.runID)
+
+	header := http.Header{"Content-Type": {"application/xml"}}
+	_, err := s.expect(ctx, http.MethodPut, s.mavenPath("pom"), header, []byte(pom), http.StatusOK, http.StatusCreated)
+	return err
+}
+
+func mavenDeployMetadata(ctx context.Context, s *session) error {
+	version := "1.0." + s.runID
+	metadata := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<metadata>
+  <groupId>%s</groupId>
+  <artifactId>%s</artifactId>
+  <versioning>
+    <latest>%s</latest>
+    <release>%s</release>
+    <versions>
+      <version>%s</version>
+    </versions>
+  </versioning>
+</metadata>
+`, strings.ReplaceAll(mavenGroupPath, "/", "."), mavenArtifactID, version, version, version)
+
+	path := "/" + mavenGroupPath + "/" + mavenArtifactID + "/maven-metadata.xml"
+	header := http.Header{"Content-Type": {"application/xml"}}
+	_, err := s.expect(ctx, http.MethodPut, path, header, []byte(metadata), http.StatusOK, http.StatusCreated)
+	return err
+}
+
+func mavenHeadJar(ctx context.Context, s *session) error {
+	_, err := s.expect(ctx, http.MethodHead, s.mavenPath("jar"), nil, nil, http.StatusOK)
+	return err
+}
+
+func mavenDownloadJar(ctx context.Context, s *session) error {
+	path := s.mavenPath("jar")
+	resp, err := s.expect(ctx, http.MethodGet, path, nil, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(resp.body, []byte(s.values["jar"])) {
+		return fmt.Errorf("GET %s: jar differs from the deployed one", path)
+	}
+	return nil
+}
+
+func mavenDownloadChecksum(ctx context.Context, s *session) error {
+	path := s.mavenPath("jar.sha1")
+	resp, err := s.expect(ctx, http.MethodGet, path, nil, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	if checksum := strings.TrimSpace(string(resp.body)); checksum != s.values["jar_sha1"] {
+		return fmt.Errorf("GET %s: checksum is %q, want %s", path, checksum, s.values["jar_sha1"])
+	}
+	return nil
+}
+
+func mavenDownloadMetadata(ctx context.Context, s *session) error {
+	path := "/" + mavenGroupPath + "/" + mavenArtifactID + "/maven-metadata.xml"
+	resp, err := s.expect(ctx, http.MethodGet, path, nil, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	if version := "1.0." + s.runID; !bytes.Contains(resp.body, []byte("<version>"+version+"</version>")) {
+		return fmt.Errorf("GET %s: deployed version %s not listed", path, version)
+	}
+	return nil
+}
+
+func mavenUnknownArtifact(ctx context.Context, s *session) error {
+	_, err := s.expect(ctx, http.MethodGet, "/"+mavenGroupPath+"/"+mavenArtifactID+"-missing-"+s.runID+"/1.0/missing.jar", nil, nil, http.StatusNotFound)
+	return err
+}
@@ -0,0 +1,169 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// npmPackage is the package the npm suite publishes
+const npmPackage = "artifusion-conformance"
+
+// npmAbbreviatedMediaType is the Accept type npm install uses for metadata
+const npmAbbreviatedMediaType = "application/vnd.npm.install-v1+json"
+
+// npmChecks covers the npm publish and install flows: publishing a version, reading
+// the full and abbreviated packument, downloading the tarball it points to, and the
+// error response for unknown packages.
+func npmChecks() []check {
+	return []check{
+		{name: "ping", run: npmPing},
+		{name: "publish", run: npmPublish},
+		{name: "packument", run: npmPackument},
+		{name: "abbreviated packument", run: npmAbbreviatedPackument},
+		{name: "tarball download", run: npmTarball},
+		{name: "unknown package", run: npmUnknownPackage},
+	}
+}
+
+func npmPing(ctx context.Context, s *session) error {
+	_, err := s.expect(ctx, http.MethodGet, "/-/ping", nil, nil, http.StatusOK)
+	return err
+}
+
+func npmPublish(ctx context.Context, s *session) error {
+	version := "0.0." + s.runID
+	filename := npmPackage + "-" + version + ".tgz"
+	tarball := []byte("artifusion conformance tarball " + s.runID + "\n")
+
+	shasum := sha1.Sum(tarball)
+	integrity := sha512.Sum512(tarball)
+	manifest := map[string]any{
+		"name":        npmPackage,
+		"version":     version,
+		"description": "Artifusion conformance test package",
+		"dist": map[string]any{
+			"shasum":    hex.EncodeToString(shasum[:]),
+			"integrity": "sha512-" + base64.StdEncoding.EncodeToString(integrity[:]),
+			"tarball":   s.target + "/" + npmPackage + "/-/" + filename,
+		},
+	}
+	body, err := json.Marshal(map[string]any{
+		"_id":       npmPackage,
+		"name":      npmPackage,
+		"dist-tags": map[string]string{"latest": version},
+		"versions":  map[string]any{version: manifest},
+		"_attachments": map[string]any{
+			filename: map[string]any{
+				"content_type": "application/octet-stream",
+				"data":         base64.StdEncoding.EncodeToString(tarball),
+				"length":       len(tarball),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	if _, err := s.expect(ctx, http.MethodPut, "/"+npmPackage, header, body, http.StatusOK, http.StatusCreated); err != nil {
+		return err
+	}
+
+	s.values["version"] = version
+	s.values["tarball"] = string(tarball)
+	s.values["shasum"] = hex.EncodeToString(shasum[:])
+	return nil
+}
+
+// npmPackumentVersion reads the published version from a packument
+func npmPackumentVersion(path string, body []byte, version string) (map[string]any, error) {
+	var packument struct {
+		Name     string                    `json:"name"`
+		Versions map[string]map[string]any `json:"versions"`
+	}
+	if err := json.Unmarshal(body, &packument); err != nil {
+		return nil, fmt.Errorf("GET %s: invalid packument: %w", path, err)
+	}
+	if packument.Name != npmPackage {
+		return nil, fmt.Errorf("GET %s: name is %q, want %s", path, packument.Name, npmPackage)
+	}
+	manifest, ok := packument.Versions[version]
+	if !ok {
+		return nil, fmt.Errorf("GET %s: published version %s not listed", path, version)
+	}
+	return manifest, nil
+}
+
+func npmPackument(ctx context.Context, s *session) error {
+	path := "/" + npmPackage
+	resp, err := s.expect(ctx, http.MethodGet, path, nil, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	manifest, err := npmPackumentVersion(path, resp.body, s.values["version"])
+	if err != nil {
+		return err
+	}
+
+	dist, _ := manifest["dist"].(map[string]any)
+	tarball, _ := dist["tarball"].(string)
+	if tarball == "" {
+		return fmt.Errorf("GET %s: version %s has no dist.tarball", path, s.values["version"])
+	}
+	// The tarball must be served through the instance, not straight from the backend
+	if !s.sameOrigin(tarball) {
+		return fmt.Errorf("GET %s: dist.tarball %s doesn't point at the target", path, tarball)
+	}
+	s.values["tarball_url"] = tarball
+	return nil
+}
+
+func npmAbbreviatedPackument(ctx context.Context, s *session) error {
+	path := "/" + npmPackage
+	header := http.Header{"Accept": {npmAbbreviatedMediaType}}
+	resp, err := s.expect(ctx, http.MethodGet, path, header, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	_, err = npmPackumentVersion(path, resp.body, s.values["version"])
+	return err
+}
+
+func npmTarball(ctx context.Context, s *session) error {
+	resp, err := s.expect(ctx, http.MethodGet, s.values["tarball_url"], nil, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(resp.body, []byte(s.values["tarball"])) {
+		shasum := sha1.Sum(resp.body)
+		return fmt.Errorf("GET %s: shasum is %x, want %s", s.values["tarball_url"], shasum, s.values["shasum"])
+	}
+	return nil
+}
+
+func npmUnknownPackage(ctx context.Context, s *session) error {
+	_, err := s.expect(ctx, http.MethodGet, "/"+npmPackage+"-missing-"+s.runID, nil, nil, http.StatusNotFound)
+	return err
+}
+
+// sameOrigin reports whether rawURL has the target's scheme and host
+func (s *session) sameOrigin(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	target, err := url.Parse(s.target)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Scheme, target.Scheme) && strings.EqualFold(u.Host, target.Host)
+}
@@ -0,0 +1,289 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+)
+
+// OCI media types used by the pushed test image
+const (
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociConfigMediaType   = "application/vnd.oci.image.config.v1+json"
+	ociLayerMediaType    = "application/vnd.oci.image.layer.v1.tar"
+)
+
+// ociRepository is the repository the OCI suite pushes to
+const ociRepository = "artifusion-conformance/test"
+
+// ociChecks covers the pull and push workflows of the OCI distribution spec: blob
+// uploads (monolithic and chunked), manifest push and pull by tag and digest, tag
+// listing, and the error responses for unknown content.
+func ociChecks() []check {
+	return []check{
+		{name: "api version check", run: ociAPIVersion},
+		{name: "push blob (monolithic)", run: ociPushConfig},
+		{name: "push blob (chunked)", run: ociPushLayer},
+		{name: "check blob exists", run: ociHeadBlob},
+		{name: "pull blob", run: ociPullBlob},
+		{name: "push manifest", run: ociPushManifest},
+		{name: "pull manifest by tag", run: ociPullManifest},
+		{name: "check manifest by digest", run: ociHeadManifest},
+		{name: "list tags", run: ociListTags},
+		{name: "unknown manifest", run: ociUnknownManifest},
+		{name: "unknown blob", run: ociUnknownBlob},
+	}
+}
+
+func ociAPIVersion(ctx context.Context, s *session) error {
+	resp, err := s.expect(ctx, http.MethodGet, "/v2/", nil, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	if version := resp.header.Get("Docker-Distribution-Api-Version"); version != "registry/2.0" {
+		return fmt.Errorf("Docker-Distribution-Api-Version is %q, want registry/2.0", version)
+	}
+	return nil
+}
+
+func ociPushConfig(ctx context.Context, s *session) error {
+	config := []byte(fmt.Sprintf(`{"architecture":"amd64","os":"linux","config":{"Labels":{"artifusion.conformance.run":%q}}}`, s.runID))
+
+	location, err := s.ociStartUpload(ctx)
+	if err != nil {
+		return err
+	}
+	digest := sha256Digest(config)
+	if err := s.ociFinishUpload(ctx, location, digest, config); err != nil {
+		return err
+	}
+
+	s.values["config"] = string(config)
+	s.values["config_digest"] = digest
+	return nil
+}
+
+func ociPushLayer(ctx context.Context, s *session) error {
+	layer := []byte("artifusion conformance layer " + s.runID + "\n")
+	half := len(layer) / 2
+
+	location, err := s.ociStartUpload(ctx)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{
+		"Content-Type":  {"application/octet-stream"},
+		"Content-Range": {fmt.Sprintf("0-%d", half-1)},
+	}
+	resp, err := s.expect(ctx, http.MethodPatch, location, header, layer[:half], http.StatusAccepted)
+	if err != nil {
+		return err
+	}
+	if location, err = s.resolve(resp.header.Get("Location")); err != nil {
+		return fmt.Errorf("PATCH upload: %w", err)
+	}
+
+	digest := sha256Digest(layer)
+	if err := s.ociFinishUpload(ctx, location, digest, layer[half:]); err != nil {
+		return err
+	}
+
+	s.values["layer"] = string(layer)
+	s.values["layer_digest"] = digest
+	return nil
+}
+
+func ociHeadBlob(ctx context.Context, s *session) error {
+	path := "/v2/" + ociRepository + "/blobs/" + s.values["layer_digest"]
+	resp, err := s.expect(ctx, http.MethodHead, path, nil, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	if length := resp.header.Get("Content-Length"); length != strconv.Itoa(len(s.values["layer"])) {
+		return fmt.Errorf("HEAD %s: Content-Length is %q, want %d", path, length, len(s.values["layer"]))
+	}
+	return nil
+}
+
+func ociPullBlob(ctx context.Context, s *session) error {
+	path := "/v2/" + ociRepository + "/blobs/" + s.values["layer_digest"]
+	resp, err := s.expect(ctx, http.MethodGet, path, nil, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	if digest := sha256Digest(resp.body); digest != s.values["layer_digest"] {
+		return fmt.Errorf("GET %s: content digest is %s", path, digest)
+	}
+	return nil
+}
+
+func ociPushManifest(ctx context.Context, s *session) error {
+	manifest, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     ociManifestMediaType,
+		"config": map[string]any{
+			"mediaType": ociConfigMediaType,
+			"digest":    s.values["config_digest"],
+			"size":      len(s.values["config"]),
+		},
+		"layers": []map[string]any{{
+			"mediaType": ociLayerMediaType,
+			"digest":    s.values["layer_digest"],
+			"size":      len(s.values["layer"]),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	tag := "run-" + s.runID
+	digest := sha256Digest(manifest)
+	path := "/v2/" + ociRepository + "/manifests/" + tag
+	header := http.Header{"Content-Type": {ociManifestMediaType}}
+	resp, err := s.expect(ctx, http.MethodPut, path, header, manifest, http.StatusCreated)
+	if err != nil {
+		return err
+	}
+	if got := resp.header.Get("Docker-Content-Digest"); got != "" && got != digest {
+		return fmt.Errorf("PUT %s: Docker-Content-Digest is %s, want %s", path, got, digest)
+	}
+
+	s.values["manifest"] = string(manifest)
+	s.values["manifest_digest"] = digest
+	s.values["tag"] = tag
+	return nil
+}
+
+func ociPullManifest(ctx context.Context, s *session) error {
+	path := "/v2/" + ociRepository + "/manifests/" + s.values["tag"]
+	header := http.Header{"Accept": {ociManifestMediaType}}
+	resp, err := s.expect(ctx, http.MethodGet, path, header, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(resp.body, []byte(s.values["manifest"])) {
+		return fmt.Errorf("GET %s: manifest differs from the pushed one", path)
+	}
+	if contentType := resp.header.Get("Content-Type"); contentType != ociManifestMediaType {
+		return fmt.Errorf("GET %s: Content-Type is %q, want %s", path, contentType, ociManifestMediaType)
+	}
+	if digest := resp.header.Get("Docker-Content-Digest"); digest != s.values["manifest_digest"] {
+		return fmt.Errorf("GET %s: Docker-Content-Digest is %q, want %s", path, digest, s.values["manifest_digest"])
+	}
+	return nil
+}
+
+func ociHeadManifest(ctx context.Context, s *session) error {
+	path := "/v2/" + ociRepository + "/manifests/" + s.values["manifest_digest"]
+	header := http.Header{"Accept": {ociManifestMediaType}}
+	_, err := s.expect(ctx, http.MethodHead, path, header, nil, http.StatusOK)
+	return err
+}
+
+func ociListTags(ctx context.Context, s *session) error {
+	path := "/v2/" + ociRepository + "/tags/list"
+	resp, err := s.expect(ctx, http.MethodGet, path, nil, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+
+	var list struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal(resp.body, &list); err != nil {
+		return fmt.Errorf("GET %s: invalid tag list: %w", path, err)
+	}
+	if !slices.Contains(list.Tags, s.values["tag"]) {
+		return fmt.Errorf("GET %s: pushed tag %s not listed", path, s.values["tag"])
+	}
+	return nil
+}
+
+func ociUnknownManifest(ctx context.Context, s *session) error {
+	path := "/v2/" + ociRepository + "/manifests/missing-" + s.runID
+	header := http.Header{"Accept": {ociManifestMediaType}}
+	resp, err := s.expect(ctx, http.MethodGet, path, header, nil, http.StatusNotFound)
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		Errors []struct {
+			Code string `json:"code"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(resp.body, &body); err != nil || len(body.Errors) == 0 {
+		return fmt.Errorf("GET %s: 404 without an OCI error body%s", path, snippet(resp.body))
+	}
+	return nil
+}
+
+func ociUnknownBlob(ctx context.Context, s *session) error {
+	path := "/v2/" + ociRepository + "/blobs/" + sha256Digest([]byte("missing-"+s.runID))
+	_, err := s.expect(ctx, http.MethodGet, path, nil, nil, http.StatusNotFound)
+	return err
+}
+
+// ociStartUpload opens a blob upload session and returns its location
+func (s *session) ociStartUpload(ctx context.Context) (string, error) {
+	resp, err := s.expect(ctx, http.MethodPost, "/v2/"+ociRepository+"/blobs/uploads/", nil, nil, http.StatusAccepted)
+	if err != nil {
+		return "", err
+	}
+	location, err := s.resolve(resp.header.Get("Location"))
+	if err != nil {
+		return "", fmt.Errorf("POST upload: %w", err)
+	}
+	return location, nil
+}
+
+// ociFinishUpload completes the upload session at location with the last chunk
+func (s *session) ociFinishUpload(ctx context.Context, location, digest string, chunk []byte) error {
+	u, err := url.Parse(location)
+	if err != nil {
+		return err
+	}
+	query := u.Query()
+	query.Set("digest", digest)
+	u.RawQuery = query.Encode()
+
+	header := http.Header{"Content-Type": {"application/octet-stream"}}
+	resp, err := s.expect(ctx, http.MethodPut, u.String(), header, chunk, http.StatusCreated)
+	if err != nil {
+		return err
+	}
+	if got := resp.header.Get("Docker-Content-Digest"); got != "" && got != digest {
+		return fmt.Errorf("PUT upload: Docker-Content-Digest is %s, want %s", got, digest)
+	}
+	return nil
+}
+
+// resolve turns a Location header into an absolute URL, relative to the target
+func (s *session) resolve(location string) (string, error) {
+	if location == "" {
+		return "", fmt.Errorf("no Location header")
+	}
+	base, err := url.Parse(s.target + "/")
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("invalid Location %q: %w", location, err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+func sha256Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
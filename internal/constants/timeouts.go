@@ -25,6 +25,14 @@ const (
 	// A multiplier of 2 means cleanup runs twice as often as TTL expiration
 	CacheCleanupMultiplier = 2
 
+	// AuthCacheRefreshTimeout bounds a background auth cache refresh, which runs
+	// detached from the request that triggered it
+	AuthCacheRefreshTimeout = 30 * time.Second
+
+	// BackendDNSLookupTimeout bounds a single backend hostname lookup when backend
+	// addresses are resolved by the proxy (transport.dns_refresh_interval)
+	BackendDNSLookupTimeout = 10 * time.Second
+
 	// Request Timeout Configuration
 	// DefaultRequestTimeout is the default timeout for all HTTP requests
 	// This provides a reasonable upper bound for most requests
@@ -64,3 +72,37 @@ const (
 	// GitHubMaxIdleConnsPerHost is the maximum number of idle connections per host
 	GitHubMaxIdleConnsPerHost = 10
 )
+
+// Write-back Configuration
+const (
+	// WriteBackConcurrency bounds the background publishes of artifacts fetched from a
+	// read-through upstream (write_back). Artifacts fetched while all slots are busy
+	// are not written back; they are fetched from the upstream again next time.
+	WriteBackConcurrency = 4
+)
+
+// Trash Configuration
+const (
+	// TrashPurgeInterval is how often trashed artifacts whose retention expired are
+	// deleted from the push backend. Failed deletes are retried on the next run.
+	TrashPurgeInterval = time.Minute
+)
+
+// Provenance Configuration
+const (
+	// ProvenancePushTimeout bounds pushing the provenance annotations of a pushed
+	// manifest to the push backend
+	ProvenancePushTimeout = 30 * time.Second
+)
+
+// Connection Warming Configuration
+const (
+	// WarmConnectionsTimeout bounds pre-establishing a backend's warm connections
+	WarmConnectionsTimeout = 30 * time.Second
+)
+
+// Credential Sharing Configuration
+const (
+	// CredentialSharingWebhookTimeout bounds delivering a credential sharing alert
+	CredentialSharingWebhookTimeout = 10 * time.Second
+)
@@ -0,0 +1,81 @@
+package detector
+
+import (
+	"net/http"
+	"strings"
+)
+
+// APKDetector detects Alpine APK repository requests
+type APKDetector struct {
+	host       string
+	pathPrefix string
+}
+
+// NewAPKDetector creates a new APK detector
+// host: optional domain for host-based routing (e.g., "apk.example.com")
+// pathPrefix: path prefix for path-based routing - required when host is empty
+func NewAPKDetector(host, pathPrefix string) *APKDetector {
+	// Normalize pathPrefix: ensure starts with /, no trailing /
+	// SECURITY: No silent defaults - pathPrefix must be explicit from config
+	if pathPrefix != "" {
+		if !strings.HasPrefix(pathPrefix, "/") {
+			pathPrefix = "/" + pathPrefix
+		}
+		pathPrefix = strings.TrimSuffix(pathPrefix, "/")
+	}
+
+	return &APKDetector{
+		host:       host,
+		pathPrefix: pathPrefix,
+	}
+}
+
+// Detect checks if the request is an APK repository request
+func (d *APKDetector) Detect(r *http.Request) bool {
+	// Check 0: Host matching (if configured)
+	if d.host != "" {
+		requestHost := getRequestHost(r)
+		if requestHost != d.host {
+			return false
+		}
+	}
+
+	path := r.URL.Path
+
+	// Check 1: Path prefix matching (if configured)
+	if d.pathPrefix != "" {
+		if !strings.HasPrefix(path, d.pathPrefix+"/") && path != d.pathPrefix {
+			// Path doesn't match prefix
+			return false
+		}
+		// Path matches prefix - route to this protocol handler
+		// The handler will validate the specific request and handle auth
+		return true
+	}
+
+	// No pathPrefix configured - use protocol-specific detection
+	// This handles host-only routing mode
+
+	// Check 2: Repository layout - each <branch>/<repo>/<arch>/ directory holds the
+	// signed APKINDEX.tar.gz and the packages
+	if strings.HasSuffix(path, "/APKINDEX.tar.gz") || strings.HasSuffix(path, ".apk") {
+		return true
+	}
+
+	// Check 3: User-Agent header (e.g. "apk-tools/2.14.4, lib/3.1.4")
+	if strings.HasPrefix(r.Header.Get("User-Agent"), "apk-tools/") {
+		return true
+	}
+
+	return false
+}
+
+// Protocol returns the protocol name
+func (d *APKDetector) Protocol() Protocol {
+	return ProtocolAPK
+}
+
+// Priority returns the detection priority (below Terraform)
+func (d *APKDetector) Priority() int {
+	return 50
+}
@@ -0,0 +1,44 @@
+package detector
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPKDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		requestHost string
+		pathPrefix  string
+		path        string
+		userAgent   string
+		want        bool
+	}{
+		{name: "path prefix", pathPrefix: "/apk", path: "/apk/v3.20/main/x86_64/APKINDEX.tar.gz", want: true},
+		{name: "path prefix root", pathPrefix: "/apk", path: "/apk", want: true},
+		{name: "other path prefix", pathPrefix: "/apk", path: "/apt/dists/bookworm/InRelease", want: false},
+		{name: "index", host: "apk.example.com", path: "/v3.20/main/x86_64/APKINDEX.tar.gz", want: true},
+		{name: "package", host: "apk.example.com", path: "/edge/community/aarch64/ripgrep-14.1.0-r0.apk", want: true},
+		{name: "apk user agent", host: "apk.example.com", path: "/", userAgent: "apk-tools/2.14.4, lib/3.1.4", want: true},
+		{name: "unrelated path", host: "apk.example.com", path: "/index.yaml", want: false},
+		{name: "other host", host: "apk.example.com", requestHost: "npm.example.com", path: "/v3.20/main/x86_64/APKINDEX.tar.gz", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Host = "apk.example.com"
+			if tt.requestHost != "" {
+				r.Host = tt.requestHost
+			}
+			if tt.userAgent != "" {
+				r.Header.Set("User-Agent", tt.userAgent)
+			}
+
+			if got := NewAPKDetector(tt.host, tt.pathPrefix).Detect(r); got != tt.want {
+				t.Errorf("Detect(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package detector
+
+import (
+	"net/http"
+	"strings"
+)
+
+// APTDetector detects Debian/APT repository requests
+type APTDetector struct {
+	host       string
+	pathPrefix string
+}
+
+// NewAPTDetector creates a new APT detector
+// host: optional domain for host-based routing (e.g., "apt.example.com")
+// pathPrefix: path prefix for path-based routing - required when host is empty
+func NewAPTDetector(host, pathPrefix string) *APTDetector {
+	// Normalize pathPrefix: ensure starts with /, no trailing /
+	// SECURITY: No silent defaults - pathPrefix must be explicit from config
+	if pathPrefix != "" {
+		if !strings.HasPrefix(pathPrefix, "/") {
+			pathPrefix = "/" + pathPrefix
+		}
+		pathPrefix = strings.TrimSuffix(pathPrefix, "/")
+	}
+
+	return &APTDetector{
+		host:       host,
+		pathPrefix: pathPrefix,
+	}
+}
+
+// Detect checks if the request is an APT repository request
+func (d *APTDetector) Detect(r *http.Request) bool {
+	// Check 0: Host matching (if configured)
+	if d.host != "" {
+		requestHost := getRequestHost(r)
+		if requestHost != d.host {
+			return false
+		}
+	}
+
+	path := r.URL.Path
+
+	// Check 1: Path prefix matching (if configured)
+	if d.pathPrefix != "" {
+		if !strings.HasPrefix(path, d.pathPrefix+"/") && path != d.pathPrefix {
+			// Path doesn't match prefix
+			return false
+		}
+		// Path matches prefix - route to this protocol handler
+		// The handler will validate the specific request and handle auth
+		return true
+	}
+
+	// No pathPrefix configured - use protocol-specific detection
+	// This handles host-only routing mode
+
+	// Check 2: Repository layout - dists/ holds the (signed) indexes, pool/ the packages
+	if strings.HasPrefix(path, "/dists/") || strings.HasPrefix(path, "/pool/") {
+		return true
+	}
+
+	// Check 3: User-Agent header (e.g. "Debian APT-HTTP/1.3 (2.6.1)")
+	userAgent := r.Header.Get("User-Agent")
+	if strings.Contains(userAgent, "APT-HTTP/") || strings.Contains(userAgent, "APT-CURL/") {
+		return true
+	}
+
+	return false
+}
+
+// Protocol returns the protocol name
+func (d *APTDetector) Protocol() Protocol {
+	return ProtocolAPT
+}
+
+// Priority returns the detection priority (below Helm)
+func (d *APTDetector) Priority() int {
+	return 70
+}
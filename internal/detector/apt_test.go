@@ -0,0 +1,45 @@
+package detector
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPTDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		requestHost string
+		pathPrefix  string
+		path        string
+		userAgent   string
+		want        bool
+	}{
+		{name: "path prefix", pathPrefix: "/apt", path: "/apt/dists/bookworm/InRelease", want: true},
+		{name: "path prefix root", pathPrefix: "/apt", path: "/apt", want: true},
+		{name: "other path prefix", pathPrefix: "/apt", path: "/npm/lodash", want: false},
+		{name: "signed release", host: "apt.example.com", path: "/dists/bookworm/InRelease", want: true},
+		{name: "packages index", host: "apt.example.com", path: "/dists/bookworm/main/binary-amd64/Packages.xz", want: true},
+		{name: "package", host: "apt.example.com", path: "/pool/main/n/nginx/nginx_1.24.0-1_amd64.deb", want: true},
+		{name: "apt user agent", host: "apt.example.com", path: "/", userAgent: "Debian APT-HTTP/1.3 (2.6.1)", want: true},
+		{name: "unrelated path", host: "apt.example.com", path: "/index.yaml", want: false},
+		{name: "other host", host: "apt.example.com", requestHost: "npm.example.com", path: "/dists/bookworm/InRelease", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Host = "apt.example.com"
+			if tt.requestHost != "" {
+				r.Host = tt.requestHost
+			}
+			if tt.userAgent != "" {
+				r.Header.Set("User-Agent", tt.userAgent)
+			}
+
+			if got := NewAPTDetector(tt.host, tt.pathPrefix).Detect(r); got != tt.want {
+				t.Errorf("Detect(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package detector
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CocoaPodsDetector detects CocoaPods CDN spec repo requests
+type CocoaPodsDetector struct {
+	host       string
+	pathPrefix string
+}
+
+// NewCocoaPodsDetector creates a new CocoaPods detector
+// host: optional domain for host-based routing (e.g., "pods.example.com")
+// pathPrefix: path prefix for path-based routing - required when host is empty
+func NewCocoaPodsDetector(host, pathPrefix string) *CocoaPodsDetector {
+	// Normalize pathPrefix: ensure starts with /, no trailing /
+	// SECURITY: No silent defaults - pathPrefix must be explicit from config
+	if pathPrefix != "" {
+		if !strings.HasPrefix(pathPrefix, "/") {
+			pathPrefix = "/" + pathPrefix
+		}
+		pathPrefix = strings.TrimSuffix(pathPrefix, "/")
+	}
+
+	return &CocoaPodsDetector{
+		host:       host,
+		pathPrefix: pathPrefix,
+	}
+}
+
+// Detect checks if the request is a CocoaPods request
+func (d *CocoaPodsDetector) Detect(r *http.Request) bool {
+	// Check 0: Host matching (if configured)
+	if d.host != "" {
+		requestHost := getRequestHost(r)
+		if requestHost != d.host {
+			return false
+		}
+	}
+
+	path := r.URL.Path
+
+	// Check 1: Path prefix matching (if configured)
+	if d.pathPrefix != "" {
+		if !strings.HasPrefix(path, d.pathPrefix+"/") && path != d.pathPrefix {
+			// Path doesn't match prefix
+			return false
+		}
+		// Path matches prefix - route to this protocol handler
+		// The handler will validate the specific request and handle auth
+		return true
+	}
+
+	// No pathPrefix configured - use protocol-specific detection
+	// This handles host-only routing mode
+
+	// Check 2: CDN layout - the version file, the sharded version lists and the
+	// podspecs below Specs/
+	if path == "/CocoaPods-version.yml" || path == "/deprecated_podspecs.txt" ||
+		strings.HasPrefix(path, "/all_pods_versions_") || strings.HasPrefix(path, "/Specs/") {
+		return true
+	}
+
+	// Check 3: User-Agent header (e.g. "CocoaPods/1.15.2 cocoapods-downloader/2.1")
+	if strings.HasPrefix(r.Header.Get("User-Agent"), "CocoaPods/") {
+		return true
+	}
+
+	return false
+}
+
+// Protocol returns the protocol name
+func (d *CocoaPodsDetector) Protocol() Protocol {
+	return ProtocolCocoaPods
+}
+
+// Priority returns the detection priority (below APK, above Git LFS)
+func (d *CocoaPodsDetector) Priority() int {
+	return 48
+}
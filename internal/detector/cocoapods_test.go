@@ -0,0 +1,41 @@
+package detector
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCocoaPodsDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		pathPrefix string
+		path       string
+		userAgent  string
+		want       bool
+	}{
+		{name: "path prefix version file", pathPrefix: "/cocoapods", path: "/cocoapods/CocoaPods-version.yml", want: true},
+		{name: "path prefix podspec", pathPrefix: "/cocoapods", path: "/cocoapods/Specs/7/1/5/Alamofire/5.9.1/Alamofire.podspec.json", want: true},
+		{name: "path prefix binary", pathPrefix: "/cocoapods", path: "/cocoapods/binaries/Acme/1.0/Acme.xcframework.zip", want: true},
+		{name: "other path prefix", pathPrefix: "/cocoapods", path: "/npm/lodash", want: false},
+		{name: "host version file", host: "pods.example.com", path: "/CocoaPods-version.yml", want: true},
+		{name: "host version list", host: "pods.example.com", path: "/all_pods_versions_7_1_5.txt", want: true},
+		{name: "host podspec", host: "pods.example.com", path: "/Specs/7/1/5/Alamofire/5.9.1/Alamofire.podspec.json", want: true},
+		{name: "host user agent", host: "pods.example.com", path: "/binaries/Acme.zip", userAgent: "CocoaPods/1.15.2 cocoapods-downloader/2.1", want: true},
+		{name: "host unrelated path", host: "pods.example.com", path: "/index.html", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Host = "pods.example.com"
+			if tt.userAgent != "" {
+				r.Header.Set("User-Agent", tt.userAgent)
+			}
+
+			if got := NewCocoaPodsDetector(tt.host, tt.pathPrefix).Detect(r); got != tt.want {
+				t.Errorf("Detect(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
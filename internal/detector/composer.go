@@ -0,0 +1,85 @@
+package detector
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ComposerDetector detects PHP Composer repository requests
+type ComposerDetector struct {
+	host       string
+	pathPrefix string
+}
+
+// NewComposerDetector creates a new Composer detector
+// host: optional domain for host-based routing (e.g., "composer.example.com")
+// pathPrefix: path prefix for path-based routing - required when host is empty
+func NewComposerDetector(host, pathPrefix string) *ComposerDetector {
+	// Normalize pathPrefix: ensure starts with /, no trailing /
+	// SECURITY: No silent defaults - pathPrefix must be explicit from config
+	if pathPrefix != "" {
+		if !strings.HasPrefix(pathPrefix, "/") {
+			pathPrefix = "/" + pathPrefix
+		}
+		pathPrefix = strings.TrimSuffix(pathPrefix, "/")
+	}
+
+	return &ComposerDetector{
+		host:       host,
+		pathPrefix: pathPrefix,
+	}
+}
+
+// Detect checks if the request is a Composer repository request
+func (d *ComposerDetector) Detect(r *http.Request) bool {
+	// Check 0: Host matching (if configured)
+	if d.host != "" {
+		requestHost := getRequestHost(r)
+		if requestHost != d.host {
+			return false
+		}
+	}
+
+	path := r.URL.Path
+
+	// Check 1: Path prefix matching (if configured)
+	if d.pathPrefix != "" {
+		if !strings.HasPrefix(path, d.pathPrefix+"/") && path != d.pathPrefix {
+			// Path doesn't match prefix
+			return false
+		}
+		// Path matches prefix - route to this protocol handler
+		// The handler will validate the specific request and handle auth
+		return true
+	}
+
+	// No pathPrefix configured - use protocol-specific detection
+	// This handles host-only routing mode
+
+	// Check 2: Repository layout - the root packages.json, per-package metadata
+	// (p2/, or p/ for providers), Satis includes and dist archives
+	if path == "/packages.json" ||
+		strings.HasPrefix(path, "/p2/") ||
+		strings.HasPrefix(path, "/p/") ||
+		strings.HasPrefix(path, "/include/") ||
+		strings.HasPrefix(path, "/dist/") {
+		return true
+	}
+
+	// Check 3: User-Agent header (e.g. "Composer/2.7.1 (Linux; ...; PHP 8.3.2)")
+	if strings.HasPrefix(r.Header.Get("User-Agent"), "Composer/") {
+		return true
+	}
+
+	return false
+}
+
+// Protocol returns the protocol name
+func (d *ComposerDetector) Protocol() Protocol {
+	return ProtocolComposer
+}
+
+// Priority returns the detection priority (below APT)
+func (d *ComposerDetector) Priority() int {
+	return 65
+}
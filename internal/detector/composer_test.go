@@ -0,0 +1,46 @@
+package detector
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestComposerDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		requestHost string
+		pathPrefix  string
+		path        string
+		userAgent   string
+		want        bool
+	}{
+		{name: "path prefix", pathPrefix: "/composer", path: "/composer/packages.json", want: true},
+		{name: "path prefix root", pathPrefix: "/composer", path: "/composer", want: true},
+		{name: "other path prefix", pathPrefix: "/composer", path: "/npm/lodash", want: false},
+		{name: "root metadata", host: "composer.example.com", path: "/packages.json", want: true},
+		{name: "package metadata", host: "composer.example.com", path: "/p2/monolog/monolog.json", want: true},
+		{name: "provider include", host: "composer.example.com", path: "/p/provider-latest$0123abcd.json", want: true},
+		{name: "satis dist", host: "composer.example.com", path: "/dist/acme/billing/acme-billing-1.2.0-abc123.zip", want: true},
+		{name: "composer user agent", host: "composer.example.com", path: "/", userAgent: "Composer/2.7.1 (Linux; 6.1; PHP 8.3.2; cURL 8.5.0)", want: true},
+		{name: "unrelated path", host: "composer.example.com", path: "/index.yaml", want: false},
+		{name: "other host", host: "composer.example.com", requestHost: "npm.example.com", path: "/packages.json", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Host = "composer.example.com"
+			if tt.requestHost != "" {
+				r.Host = tt.requestHost
+			}
+			if tt.userAgent != "" {
+				r.Header.Set("User-Agent", tt.userAgent)
+			}
+
+			if got := NewComposerDetector(tt.host, tt.pathPrefix).Detect(r); got != tt.want {
+				t.Errorf("Detect(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package detector
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CondaDetector detects Conda channel requests
+type CondaDetector struct {
+	host       string
+	pathPrefix string
+}
+
+// NewCondaDetector creates a new Conda detector
+// host: optional domain for host-based routing (e.g., "conda.example.com")
+// pathPrefix: path prefix for path-based routing - required when host is empty
+func NewCondaDetector(host, pathPrefix string) *CondaDetector {
+	// Normalize pathPrefix: ensure starts with /, no trailing /
+	// SECURITY: No silent defaults - pathPrefix must be explicit from config
+	if pathPrefix != "" {
+		if !strings.HasPrefix(pathPrefix, "/") {
+			pathPrefix = "/" + pathPrefix
+		}
+		pathPrefix = strings.TrimSuffix(pathPrefix, "/")
+	}
+
+	return &CondaDetector{
+		host:       host,
+		pathPrefix: pathPrefix,
+	}
+}
+
+// Detect checks if the request is a Conda channel request
+func (d *CondaDetector) Detect(r *http.Request) bool {
+	// Check 0: Host matching (if configured)
+	if d.host != "" {
+		requestHost := getRequestHost(r)
+		if requestHost != d.host {
+			return false
+		}
+	}
+
+	path := r.URL.Path
+
+	// Check 1: Path prefix matching (if configured)
+	if d.pathPrefix != "" {
+		if !strings.HasPrefix(path, d.pathPrefix+"/") && path != d.pathPrefix {
+			// Path doesn't match prefix
+			return false
+		}
+		// Path matches prefix - route to this protocol handler
+		// The handler will validate the specific request and handle auth
+		return true
+	}
+
+	// No pathPrefix configured - use protocol-specific detection
+	// This handles host-only routing mode
+
+	// Check 2: Channel layout - indexes and packages below <channel>/<subdir>/, and
+	// the channel's channeldata.json
+	for _, suffix := range []string{"/repodata.json", "/current_repodata.json", "/repodata.json.zst", "/repodata.json.bz2", "/channeldata.json", ".conda", ".tar.bz2"} {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+
+	// Check 3: User-Agent header (e.g. "conda/23.7.4 requests/2.31.0 CPython/3.11.4 ...",
+	// or "mamba/1.5.1 conda/23.7.4 ..." for mamba)
+	userAgent := r.Header.Get("User-Agent")
+	if strings.HasPrefix(userAgent, "conda/") || strings.HasPrefix(userAgent, "mamba/") {
+		return true
+	}
+
+	return false
+}
+
+// Protocol returns the protocol name
+func (d *CondaDetector) Protocol() Protocol {
+	return ProtocolConda
+}
+
+// Priority returns the detection priority (below Composer)
+func (d *CondaDetector) Priority() int {
+	return 60
+}
@@ -0,0 +1,47 @@
+package detector
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCondaDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		requestHost string
+		pathPrefix  string
+		path        string
+		userAgent   string
+		want        bool
+	}{
+		{name: "path prefix", pathPrefix: "/conda", path: "/conda/conda-forge/linux-64/repodata.json", want: true},
+		{name: "path prefix root", pathPrefix: "/conda", path: "/conda", want: true},
+		{name: "other path prefix", pathPrefix: "/conda", path: "/npm/lodash", want: false},
+		{name: "repodata", host: "conda.example.com", path: "/conda-forge/noarch/repodata.json", want: true},
+		{name: "current repodata", host: "conda.example.com", path: "/conda-forge/linux-64/current_repodata.json", want: true},
+		{name: "channeldata", host: "conda.example.com", path: "/conda-forge/channeldata.json", want: true},
+		{name: "conda package", host: "conda.example.com", path: "/conda-forge/linux-64/numpy-1.26.4-py311h64a7726_0.conda", want: true},
+		{name: "tar.bz2 package", host: "conda.example.com", path: "/conda-forge/noarch/six-1.16.0-pyh6c4a22f_0.tar.bz2", want: true},
+		{name: "conda user agent", host: "conda.example.com", path: "/", userAgent: "conda/23.7.4 requests/2.31.0 CPython/3.11.4 Linux/6.1.0", want: true},
+		{name: "mamba user agent", host: "conda.example.com", path: "/", userAgent: "mamba/1.5.1 conda/23.7.4 requests/2.31.0", want: true},
+		{name: "unrelated path", host: "conda.example.com", path: "/packages.json", want: false},
+		{name: "other host", host: "conda.example.com", requestHost: "npm.example.com", path: "/conda-forge/noarch/repodata.json", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Host = "conda.example.com"
+			if tt.requestHost != "" {
+				r.Host = tt.requestHost
+			}
+			if tt.userAgent != "" {
+				r.Header.Set("User-Agent", tt.userAgent)
+			}
+			if got := NewCondaDetector(tt.host, tt.pathPrefix).Detect(r); got != tt.want {
+				t.Errorf("Detect(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
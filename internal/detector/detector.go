@@ -8,10 +8,23 @@ import (
 type Protocol string
 
 const (
-	ProtocolOCI     Protocol = "oci"
-	ProtocolMaven   Protocol = "maven"
-	ProtocolNPM     Protocol = "npm"
-	ProtocolUnknown Protocol = "unknown"
+	ProtocolOCI       Protocol = "oci"
+	ProtocolMaven     Protocol = "maven"
+	ProtocolNPM       Protocol = "npm"
+	ProtocolRubyGems  Protocol = "rubygems"
+	ProtocolHelm      Protocol = "helm"
+	ProtocolAPT       Protocol = "apt"
+	ProtocolComposer  Protocol = "composer"
+	ProtocolConda     Protocol = "conda"
+	ProtocolTerraform Protocol = "terraform"
+	ProtocolAPK       Protocol = "apk"
+	ProtocolRaw       Protocol = "raw"
+	ProtocolLFS       Protocol = "lfs"
+	ProtocolCocoaPods Protocol = "cocoapods"
+	ProtocolHex       Protocol = "hex"
+	ProtocolPub       Protocol = "pub"
+	ProtocolVagrant   Protocol = "vagrant"
+	ProtocolUnknown   Protocol = "unknown"
 )
 
 // Detector is an interface for protocol detection
@@ -39,8 +52,15 @@ func NewChain(detectors ...Detector) *Chain {
 	}
 }
 
-// Detect runs all detectors in priority order and returns the first match
+// Detect runs all detectors in priority order and returns the first match. A CORS
+// preflight is detected as the request it announces (Access-Control-Request-Method),
+// so it reaches the same protocol as the request that follows it.
 func (c *Chain) Detect(r *http.Request) Protocol {
+	if method := r.Header.Get("Access-Control-Request-Method"); r.Method == http.MethodOptions && method != "" {
+		r = r.WithContext(r.Context())
+		r.Method = method
+	}
+
 	// Sort by priority (already sorted when added if using Register)
 	for _, detector := range c.detectors {
 		if detector.Detect(r) {
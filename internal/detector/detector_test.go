@@ -0,0 +1,65 @@
+package detector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// publishDetector detects only PUT requests, like publish-only content checks
+type publishDetector struct{}
+
+func (publishDetector) Detect(r *http.Request) bool { return r.Method == http.MethodPut }
+func (publishDetector) Protocol() Protocol          { return ProtocolNPM }
+func (publishDetector) Priority() int               { return 100 }
+
+func TestChain_Detect_MethodIndependent(t *testing.T) {
+	chain := NewChain(NewRubyGemsDetector("", "/rubygems"), publishDetector{})
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		preflightMethod string
+		want            Protocol
+	}{
+		{name: "get", method: http.MethodGet, path: "/rubygems/info/rails", want: ProtocolRubyGems},
+		{name: "head", method: http.MethodHead, path: "/rubygems/info/rails", want: ProtocolRubyGems},
+		{name: "options", method: http.MethodOptions, path: "/rubygems/info/rails", want: ProtocolRubyGems},
+		{name: "preflight", method: http.MethodOptions, path: "/rubygems/info/rails", preflightMethod: http.MethodGet, want: ProtocolRubyGems},
+		{name: "preflight detected as announced method", method: http.MethodOptions, path: "/-/upload", preflightMethod: http.MethodPut, want: ProtocolNPM},
+		{name: "options without preflight", method: http.MethodOptions, path: "/-/upload", want: ProtocolUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.preflightMethod != "" {
+				r.Header.Set("Origin", "https://ui.example.com")
+				r.Header.Set("Access-Control-Request-Method", tt.preflightMethod)
+			}
+			if got := chain.Detect(r); got != tt.want {
+				t.Errorf("Detect() = %v, want %v", got, tt.want)
+			}
+			if r.Method != tt.method {
+				t.Errorf("Detect() changed the request method to %s", r.Method)
+			}
+		})
+	}
+}
+
+func TestMethods(t *testing.T) {
+	if got := Methods(ProtocolAPT); len(got) != 3 || got[0] != http.MethodGet {
+		t.Errorf("Methods(apt) = %v, want the read methods", got)
+	}
+	methods := Methods(ProtocolOCI)
+	for _, want := range []string{http.MethodPut, http.MethodPatch, http.MethodOptions} {
+		found := false
+		for _, m := range methods {
+			found = found || m == want
+		}
+		if !found {
+			t.Errorf("Methods(oci) = %v, want %s included", methods, want)
+		}
+	}
+}
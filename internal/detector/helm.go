@@ -0,0 +1,90 @@
+package detector
+
+import (
+	"net/http"
+	"strings"
+)
+
+// HelmDetector detects Helm chart repository requests
+type HelmDetector struct {
+	host       string
+	pathPrefix string
+}
+
+// NewHelmDetector creates a new Helm detector
+// host: optional domain for host-based routing (e.g., "charts.example.com")
+// pathPrefix: path prefix for path-based routing - required when host is empty
+func NewHelmDetector(host, pathPrefix string) *HelmDetector {
+	// Normalize pathPrefix: ensure starts with /, no trailing /
+	// SECURITY: No silent defaults - pathPrefix must be explicit from config
+	if pathPrefix != "" {
+		if !strings.HasPrefix(pathPrefix, "/") {
+			pathPrefix = "/" + pathPrefix
+		}
+		pathPrefix = strings.TrimSuffix(pathPrefix, "/")
+	}
+
+	return &HelmDetector{
+		host:       host,
+		pathPrefix: pathPrefix,
+	}
+}
+
+// Detect checks if the request is a Helm chart repository request
+func (d *HelmDetector) Detect(r *http.Request) bool {
+	// Check 0: Host matching (if configured)
+	if d.host != "" {
+		requestHost := getRequestHost(r)
+		if requestHost != d.host {
+			return false
+		}
+	}
+
+	path := r.URL.Path
+
+	// Check 1: Path prefix matching (if configured)
+	if d.pathPrefix != "" {
+		if !strings.HasPrefix(path, d.pathPrefix+"/") && path != d.pathPrefix {
+			// Path doesn't match prefix
+			return false
+		}
+		// Path matches prefix - route to this protocol handler
+		// The handler will validate the specific request and handle auth
+		return true
+	}
+
+	// No pathPrefix configured - use protocol-specific detection
+	// This handles host-only routing mode
+
+	// Check 2: OCI-based charts on the chart host (the OCI handler serves them)
+	if d.host != "" && (strings.HasPrefix(path, "/v2/") || path == "/v2") {
+		return true
+	}
+
+	// Check 3: Repository index and ChartMuseum API
+	if strings.HasSuffix(path, "/index.yaml") || strings.HasPrefix(path, "/api/charts") {
+		return true
+	}
+
+	// Check 4: Chart archives and provenance files
+	if strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tgz.prov") {
+		return true
+	}
+
+	// Check 5: User-Agent header
+	if strings.HasPrefix(r.Header.Get("User-Agent"), "Helm/") {
+		return true
+	}
+
+	return false
+}
+
+// Protocol returns the protocol name
+func (d *HelmDetector) Protocol() Protocol {
+	return ProtocolHelm
+}
+
+// Priority returns the detection priority (below RubyGems)
+func (d *HelmDetector) Priority() int {
+	return 75
+}
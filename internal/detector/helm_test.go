@@ -0,0 +1,47 @@
+package detector
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHelmDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		requestHost string
+		pathPrefix  string
+		path        string
+		userAgent   string
+		want        bool
+	}{
+		{name: "path prefix", pathPrefix: "/helm", path: "/helm/index.yaml", want: true},
+		{name: "path prefix root", pathPrefix: "/helm", path: "/helm", want: true},
+		{name: "other path prefix", pathPrefix: "/helm", path: "/npm/lodash", want: false},
+		{name: "index", host: "charts.example.com", path: "/index.yaml", want: true},
+		{name: "chart archive", host: "charts.example.com", path: "/charts/nginx-15.4.2.tgz", want: true},
+		{name: "provenance file", host: "charts.example.com", path: "/nginx-15.4.2.tgz.prov", want: true},
+		{name: "chartmuseum upload", host: "charts.example.com", path: "/api/charts", want: true},
+		{name: "oci chart", host: "charts.example.com", path: "/v2/platform/nginx/manifests/15.4.2", want: true},
+		{name: "helm user agent", host: "charts.example.com", path: "/", userAgent: "Helm/3.14.0", want: true},
+		{name: "unrelated path", host: "charts.example.com", path: "/info/rails", want: false},
+		{name: "other host", host: "charts.example.com", requestHost: "npm.example.com", path: "/index.yaml", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Host = "charts.example.com"
+			if tt.requestHost != "" {
+				r.Host = tt.requestHost
+			}
+			if tt.userAgent != "" {
+				r.Header.Set("User-Agent", tt.userAgent)
+			}
+
+			if got := NewHelmDetector(tt.host, tt.pathPrefix).Detect(r); got != tt.want {
+				t.Errorf("Detect(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package detector
+
+import (
+	"net/http"
+	"strings"
+)
+
+// HexDetector detects Hex repository and API requests
+type HexDetector struct {
+	host       string
+	pathPrefix string
+}
+
+// NewHexDetector creates a new Hex detector
+// host: optional domain for host-based routing (e.g., "hex.example.com")
+// pathPrefix: path prefix for path-based routing - required when host is empty
+func NewHexDetector(host, pathPrefix string) *HexDetector {
+	// Normalize pathPrefix: ensure starts with /, no trailing /
+	// SECURITY: No silent defaults - pathPrefix must be explicit from config
+	if pathPrefix != "" {
+		if !strings.HasPrefix(pathPrefix, "/") {
+			pathPrefix = "/" + pathPrefix
+		}
+		pathPrefix = strings.TrimSuffix(pathPrefix, "/")
+	}
+
+	return &HexDetector{
+		host:       host,
+		pathPrefix: pathPrefix,
+	}
+}
+
+// Detect checks if the request is a Hex request
+func (d *HexDetector) Detect(r *http.Request) bool {
+	// Check 0: Host matching (if configured)
+	if d.host != "" {
+		requestHost := getRequestHost(r)
+		if requestHost != d.host {
+			return false
+		}
+	}
+
+	path := r.URL.Path
+
+	// Check 1: Path prefix matching (if configured)
+	if d.pathPrefix != "" {
+		if !strings.HasPrefix(path, d.pathPrefix+"/") && path != d.pathPrefix {
+			// Path doesn't match prefix
+			return false
+		}
+		// Path matches prefix - route to this protocol handler
+		// The handler will validate the specific request and handle auth
+		return true
+	}
+
+	// No pathPrefix configured - use protocol-specific detection
+	// This handles host-only routing mode
+
+	// Check 2: Repository resources (registry files and tarballs) and the publish API
+	if path == "/names" || path == "/versions" || path == "/public_key" ||
+		strings.HasPrefix(path, "/packages/") || strings.HasPrefix(path, "/tarballs/") ||
+		path == "/api/publish" {
+		return true
+	}
+
+	// Check 3: User-Agent header (e.g. "Hex/2.0.6 (Elixir/1.16.0) (OTP/26.2)", or
+	// "(rebar3/3.22.1) (hex_core/0.10.0)" for rebar3)
+	if userAgent := r.Header.Get("User-Agent"); strings.HasPrefix(userAgent, "Hex/") || strings.Contains(userAgent, "hex_core/") {
+		return true
+	}
+
+	return false
+}
+
+// Protocol returns the protocol name
+func (d *HexDetector) Protocol() Protocol {
+	return ProtocolHex
+}
+
+// Priority returns the detection priority (below CocoaPods, above Git LFS)
+func (d *HexDetector) Priority() int {
+	return 46
+}
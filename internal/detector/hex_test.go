@@ -0,0 +1,42 @@
+package detector
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHexDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		pathPrefix string
+		path       string
+		userAgent  string
+		want       bool
+	}{
+		{name: "path prefix package", pathPrefix: "/hex", path: "/hex/packages/jason", want: true},
+		{name: "path prefix tarball", pathPrefix: "/hex", path: "/hex/tarballs/jason-1.4.1.tar", want: true},
+		{name: "path prefix publish", pathPrefix: "/hex", path: "/hex/api/publish", want: true},
+		{name: "other path prefix", pathPrefix: "/hex", path: "/npm/lodash", want: false},
+		{name: "host names", host: "hex.example.com", path: "/names", want: true},
+		{name: "host package", host: "hex.example.com", path: "/packages/jason", want: true},
+		{name: "host tarball", host: "hex.example.com", path: "/tarballs/jason-1.4.1.tar", want: true},
+		{name: "host mix user agent", host: "hex.example.com", path: "/installs/hex-1.ez", userAgent: "Hex/2.0.6 (Elixir/1.16.0) (OTP/26.2)", want: true},
+		{name: "host rebar3 user agent", host: "hex.example.com", path: "/docs/jason-1.4.1.tar.gz", userAgent: "(rebar3/3.22.1) (hex_core/0.10.0)", want: true},
+		{name: "host unrelated path", host: "hex.example.com", path: "/index.html", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Host = "hex.example.com"
+			if tt.userAgent != "" {
+				r.Header.Set("User-Agent", tt.userAgent)
+			}
+
+			if got := NewHexDetector(tt.host, tt.pathPrefix).Detect(r); got != tt.want {
+				t.Errorf("Detect(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package detector
+
+import (
+	"net/http"
+	"strings"
+)
+
+// LFSMediaType is the media type of Git LFS API requests and responses
+const LFSMediaType = "application/vnd.git-lfs+json"
+
+// LFSDetector detects Git LFS API and object transfer requests
+type LFSDetector struct {
+	host       string
+	pathPrefix string
+}
+
+// NewLFSDetector creates a new Git LFS detector
+// host: optional domain for host-based routing (e.g., "lfs.example.com")
+// pathPrefix: path prefix for path-based routing - required when host is empty
+func NewLFSDetector(host, pathPrefix string) *LFSDetector {
+	// Normalize pathPrefix: ensure starts with /, no trailing /
+	// SECURITY: No silent defaults - pathPrefix must be explicit from config
+	if pathPrefix != "" {
+		if !strings.HasPrefix(pathPrefix, "/") {
+			pathPrefix = "/" + pathPrefix
+		}
+		pathPrefix = strings.TrimSuffix(pathPrefix, "/")
+	}
+
+	return &LFSDetector{
+		host:       host,
+		pathPrefix: pathPrefix,
+	}
+}
+
+// Detect checks if the request is a Git LFS request
+func (d *LFSDetector) Detect(r *http.Request) bool {
+	// Check 0: Host matching (if configured)
+	if d.host != "" {
+		requestHost := getRequestHost(r)
+		if requestHost != d.host {
+			return false
+		}
+	}
+
+	path := r.URL.Path
+
+	// Check 1: Path prefix matching (if configured)
+	if d.pathPrefix != "" {
+		if !strings.HasPrefix(path, d.pathPrefix+"/") && path != d.pathPrefix {
+			// Path doesn't match prefix
+			return false
+		}
+		// Path matches prefix - route to this protocol handler
+		// The handler will validate the specific request and handle auth
+		return true
+	}
+
+	// No pathPrefix configured - use protocol-specific detection
+	// This handles host-only routing mode
+
+	// Check 2: LFS API paths - git-lfs derives its endpoint from the remote URL as
+	// <remote>/info/lfs and calls <endpoint>/objects/batch and <endpoint>/locks
+	if strings.Contains(path, "/info/lfs/") || strings.HasSuffix(path, "/objects/batch") {
+		return true
+	}
+
+	// Check 3: LFS API media type
+	if strings.HasPrefix(r.Header.Get("Accept"), LFSMediaType) || strings.HasPrefix(r.Header.Get("Content-Type"), LFSMediaType) {
+		return true
+	}
+
+	// Check 4: User-Agent header (e.g. "git-lfs/3.5.1 (GitHub; linux amd64; go 1.22.2)")
+	if strings.HasPrefix(r.Header.Get("User-Agent"), "git-lfs/") {
+		return true
+	}
+
+	return false
+}
+
+// Protocol returns the protocol name
+func (d *LFSDetector) Protocol() Protocol {
+	return ProtocolLFS
+}
+
+// Priority returns the detection priority (below Hex, above pub)
+func (d *LFSDetector) Priority() int {
+	return 45
+}
@@ -0,0 +1,53 @@
+package detector
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLFSDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		pathPrefix string
+		path       string
+		headers    map[string]string
+		want       bool
+	}{
+		{name: "path prefix batch", pathPrefix: "/lfs", path: "/lfs/myorg/app.git/info/lfs/objects/batch", want: true},
+		{name: "path prefix transfer", pathPrefix: "/lfs", path: "/lfs/myorg/app.git/gitlab-lfs/objects/abc123", want: true},
+		{name: "other path prefix", pathPrefix: "/lfs", path: "/npm/lodash", want: false},
+		{name: "host batch", host: "lfs.example.com", path: "/myorg/app.git/info/lfs/objects/batch", want: true},
+		{name: "host batch without info/lfs", host: "lfs.example.com", path: "/myorg/app/objects/batch", want: true},
+		{name: "host locks", host: "lfs.example.com", path: "/myorg/app.git/info/lfs/locks/verify", want: true},
+		{
+			name:    "host media type",
+			host:    "lfs.example.com",
+			path:    "/objects/abc123/verify",
+			headers: map[string]string{"Accept": "application/vnd.git-lfs+json; charset=utf-8"},
+			want:    true,
+		},
+		{
+			name:    "host user agent",
+			host:    "lfs.example.com",
+			path:    "/objects/abc123",
+			headers: map[string]string{"User-Agent": "git-lfs/3.5.1 (GitHub; linux amd64; go 1.22.2)"},
+			want:    true,
+		},
+		{name: "host unrelated path", host: "lfs.example.com", path: "/index.html", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", tt.path, nil)
+			r.Host = "lfs.example.com"
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+
+			if got := NewLFSDetector(tt.host, tt.pathPrefix).Detect(r); got != tt.want {
+				t.Errorf("Detect(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
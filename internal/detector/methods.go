@@ -0,0 +1,29 @@
+package detector
+
+import "net/http"
+
+// readMethods are the methods of read-only protocols
+var readMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+
+// protocolMethods lists the methods of protocols clients also publish to
+var protocolMethods = map[Protocol][]string{
+	ProtocolOCI:       {http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions},
+	ProtocolMaven:     {http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodOptions},
+	ProtocolNPM:       {http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
+	ProtocolRubyGems:  {http.MethodGet, http.MethodHead, http.MethodPost, http.MethodDelete, http.MethodOptions},
+	ProtocolHelm:      {http.MethodGet, http.MethodHead, http.MethodPost, http.MethodDelete, http.MethodOptions},
+	ProtocolRaw:       {http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
+	ProtocolLFS:       {http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodOptions},
+	ProtocolCocoaPods: {http.MethodGet, http.MethodHead, http.MethodPut, http.MethodOptions},
+	ProtocolHex:       {http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodOptions},
+	ProtocolPub:       {http.MethodGet, http.MethodHead, http.MethodPost, http.MethodOptions},
+}
+
+// Methods returns the HTTP methods requests of protocol p may use, as listed in the
+// Allow header of OPTIONS responses. Individual repositories may accept fewer.
+func Methods(p Protocol) []string {
+	if methods, ok := protocolMethods[p]; ok {
+		return methods
+	}
+	return readMethods
+}
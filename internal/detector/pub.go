@@ -0,0 +1,85 @@
+package detector
+
+import (
+	"net/http"
+	"strings"
+)
+
+// PubDetector detects Dart pub hosted repository requests
+type PubDetector struct {
+	host       string
+	pathPrefix string
+}
+
+// NewPubDetector creates a new Dart pub detector
+// host: optional domain for host-based routing (e.g., "pub.example.com")
+// pathPrefix: path prefix for path-based routing - required when host is empty
+func NewPubDetector(host, pathPrefix string) *PubDetector {
+	// Normalize pathPrefix: ensure starts with /, no trailing /
+	// SECURITY: No silent defaults - pathPrefix must be explicit from config
+	if pathPrefix != "" {
+		if !strings.HasPrefix(pathPrefix, "/") {
+			pathPrefix = "/" + pathPrefix
+		}
+		pathPrefix = strings.TrimSuffix(pathPrefix, "/")
+	}
+
+	return &PubDetector{
+		host:       host,
+		pathPrefix: pathPrefix,
+	}
+}
+
+// Detect checks if the request is a Dart pub request
+func (d *PubDetector) Detect(r *http.Request) bool {
+	// Check 0: Host matching (if configured)
+	if d.host != "" {
+		requestHost := getRequestHost(r)
+		if requestHost != d.host {
+			return false
+		}
+	}
+
+	path := r.URL.Path
+
+	// Check 1: Path prefix matching (if configured)
+	if d.pathPrefix != "" {
+		if !strings.HasPrefix(path, d.pathPrefix+"/") && path != d.pathPrefix {
+			// Path doesn't match prefix
+			return false
+		}
+		// Path matches prefix - route to this protocol handler
+		// The handler will validate the specific request and handle auth
+		return true
+	}
+
+	// No pathPrefix configured - use protocol-specific detection
+	// This handles host-only routing mode
+
+	// Check 2: Package API, archive downloads and the publish flow
+	if strings.HasPrefix(path, "/api/packages/") || strings.HasPrefix(path, "/packages/") {
+		return true
+	}
+
+	// Check 3: Accept header of the versioned API
+	if strings.Contains(r.Header.Get("Accept"), "application/vnd.pub.") {
+		return true
+	}
+
+	// Check 4: User-Agent header (e.g. "Dart pub 3.5.0")
+	if strings.HasPrefix(r.Header.Get("User-Agent"), "Dart pub") {
+		return true
+	}
+
+	return false
+}
+
+// Protocol returns the protocol name
+func (d *PubDetector) Protocol() Protocol {
+	return ProtocolPub
+}
+
+// Priority returns the detection priority (below Git LFS, above Vagrant)
+func (d *PubDetector) Priority() int {
+	return 44
+}
@@ -0,0 +1,45 @@
+package detector
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPubDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		pathPrefix string
+		path       string
+		accept     string
+		userAgent  string
+		want       bool
+	}{
+		{name: "path prefix package", pathPrefix: "/pub", path: "/pub/api/packages/http", want: true},
+		{name: "path prefix archive", pathPrefix: "/pub", path: "/pub/packages/http/versions/1.2.0.tar.gz", want: true},
+		{name: "path prefix publish", pathPrefix: "/pub", path: "/pub/api/packages/versions/new", want: true},
+		{name: "other path prefix", pathPrefix: "/pub", path: "/npm/lodash", want: false},
+		{name: "host package", host: "pub.example.com", path: "/api/packages/http", want: true},
+		{name: "host archive", host: "pub.example.com", path: "/packages/http/versions/1.2.0.tar.gz", want: true},
+		{name: "host versioned accept", host: "pub.example.com", path: "/api/archives/http-1.2.0.tar.gz", accept: "application/vnd.pub.v2+json", want: true},
+		{name: "host pub user agent", host: "pub.example.com", path: "/upload", userAgent: "Dart pub 3.5.0", want: true},
+		{name: "host unrelated path", host: "pub.example.com", path: "/index.html", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Host = "pub.example.com"
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			if tt.userAgent != "" {
+				r.Header.Set("User-Agent", tt.userAgent)
+			}
+
+			if got := NewPubDetector(tt.host, tt.pathPrefix).Detect(r); got != tt.want {
+				t.Errorf("Detect(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
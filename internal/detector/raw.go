@@ -0,0 +1,64 @@
+package detector
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RawDetector detects raw artifact repository requests. Raw files have no format
+// to recognize, so requests are only matched by host and path prefix.
+type RawDetector struct {
+	host       string
+	pathPrefix string
+}
+
+// NewRawDetector creates a new raw repository detector
+// host: optional domain for host-based routing (e.g., "files.example.com")
+// pathPrefix: path prefix for path-based routing - required when host is empty
+func NewRawDetector(host, pathPrefix string) *RawDetector {
+	// Normalize pathPrefix: ensure starts with /, no trailing /
+	// SECURITY: No silent defaults - pathPrefix must be explicit from config
+	if pathPrefix != "" {
+		if !strings.HasPrefix(pathPrefix, "/") {
+			pathPrefix = "/" + pathPrefix
+		}
+		pathPrefix = strings.TrimSuffix(pathPrefix, "/")
+	}
+
+	return &RawDetector{
+		host:       host,
+		pathPrefix: pathPrefix,
+	}
+}
+
+// Detect checks if the request is a raw repository request
+func (d *RawDetector) Detect(r *http.Request) bool {
+	// Check 0: Host matching (if configured)
+	if d.host != "" {
+		requestHost := getRequestHost(r)
+		if requestHost != d.host {
+			return false
+		}
+	}
+
+	path := r.URL.Path
+
+	// Check 1: Path prefix matching (if configured)
+	if d.pathPrefix != "" {
+		return strings.HasPrefix(path, d.pathPrefix+"/") || path == d.pathPrefix
+	}
+
+	// No pathPrefix configured - the whole host serves raw files
+	return d.host != ""
+}
+
+// Protocol returns the protocol name
+func (d *RawDetector) Protocol() Protocol {
+	return ProtocolRaw
+}
+
+// Priority returns the detection priority. Lowest of all protocols: on a dedicated
+// host without path prefix, every request matches.
+func (d *RawDetector) Priority() int {
+	return 40
+}
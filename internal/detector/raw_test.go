@@ -0,0 +1,38 @@
+package detector
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRawDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		requestHost string
+		pathPrefix  string
+		path        string
+		want        bool
+	}{
+		{name: "path prefix", pathPrefix: "/raw", path: "/raw/builds/app-1.0.tar.gz", want: true},
+		{name: "path prefix root", pathPrefix: "/raw", path: "/raw", want: true},
+		{name: "other path prefix", pathPrefix: "/raw", path: "/rawfiles/app-1.0.tar.gz", want: false},
+		{name: "host with path prefix", host: "files.example.com", pathPrefix: "/raw", path: "/raw/app.bin", want: true},
+		{name: "dedicated host", host: "files.example.com", path: "/builds/app-1.0.tar.gz", want: true},
+		{name: "other host", host: "files.example.com", requestHost: "npm.example.com", path: "/builds/app-1.0.tar.gz", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", tt.path, nil)
+			r.Host = "files.example.com"
+			if tt.requestHost != "" {
+				r.Host = tt.requestHost
+			}
+
+			if got := NewRawDetector(tt.host, tt.pathPrefix).Detect(r); got != tt.want {
+				t.Errorf("Detect(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
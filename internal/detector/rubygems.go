@@ -0,0 +1,106 @@
+package detector
+
+import (
+	"net/http"
+	"strings"
+)
+
+// rubyGemsEndpoints contains RubyGems-specific endpoint prefixes
+// Declared at package level to avoid repeated allocations
+var rubyGemsEndpoints = []string{
+	"/info/",                   // Compact index: versions of a gem
+	"/quick/Marshal.4.8/",      // Marshalled gemspecs
+	"/api/v1/gems",             // gem push, gem yank and gem metadata
+	"/api/v1/dependencies",     // Legacy dependency API
+	"/specs.4.8.gz",            // Full index
+	"/latest_specs.4.8.gz",     // Full index, latest versions
+	"/prerelease_specs.4.8.gz", // Full index, prerelease versions
+}
+
+// RubyGemsDetector detects RubyGems repository protocol requests
+type RubyGemsDetector struct {
+	host       string
+	pathPrefix string
+}
+
+// NewRubyGemsDetector creates a new RubyGems detector
+// host: optional domain for host-based routing (e.g., "gems.example.com")
+// pathPrefix: path prefix for path-based routing - required when host is empty
+func NewRubyGemsDetector(host, pathPrefix string) *RubyGemsDetector {
+	// Normalize pathPrefix: ensure starts with /, no trailing /
+	// SECURITY: No silent defaults - pathPrefix must be explicit from config
+	if pathPrefix != "" {
+		if !strings.HasPrefix(pathPrefix, "/") {
+			pathPrefix = "/" + pathPrefix
+		}
+		pathPrefix = strings.TrimSuffix(pathPrefix, "/")
+	}
+
+	return &RubyGemsDetector{
+		host:       host,
+		pathPrefix: pathPrefix,
+	}
+}
+
+// Detect checks if the request is a RubyGems repository request
+func (d *RubyGemsDetector) Detect(r *http.Request) bool {
+	// Check 0: Host matching (if configured)
+	if d.host != "" {
+		requestHost := getRequestHost(r)
+		if requestHost != d.host {
+			return false
+		}
+	}
+
+	path := r.URL.Path
+
+	// Check 1: Path prefix matching (if configured)
+	if d.pathPrefix != "" {
+		if !strings.HasPrefix(path, d.pathPrefix+"/") && path != d.pathPrefix {
+			// Path doesn't match prefix
+			return false
+		}
+		// Path matches prefix - route to this protocol handler
+		// The handler will validate the specific request and handle auth
+		return true
+	}
+
+	// No pathPrefix configured - use protocol-specific detection
+	// This handles host-only routing mode
+
+	// Check 2: Compact index files
+	if path == "/versions" || path == "/names" {
+		return true
+	}
+
+	// Check 3: RubyGems-specific endpoints
+	for _, endpoint := range rubyGemsEndpoints {
+		if strings.HasPrefix(path, endpoint) {
+			return true
+		}
+	}
+
+	// Check 4: Gem downloads
+	if strings.HasPrefix(path, "/gems/") && strings.HasSuffix(path, ".gem") {
+		return true
+	}
+
+	// Check 5: User-Agent header (RubyGems and Bundler)
+	userAgent := r.Header.Get("User-Agent")
+	if strings.Contains(userAgent, "RubyGems/") ||
+		strings.Contains(userAgent, "bundler/") {
+		return true
+	}
+
+	return false
+}
+
+// Protocol returns the protocol name
+func (d *RubyGemsDetector) Protocol() Protocol {
+	return ProtocolRubyGems
+}
+
+// Priority returns the detection priority (below NPM)
+func (d *RubyGemsDetector) Priority() int {
+	return 80
+}
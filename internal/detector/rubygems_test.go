@@ -0,0 +1,46 @@
+package detector
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRubyGemsDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		requestHost string
+		pathPrefix  string
+		path        string
+		userAgent   string
+		want        bool
+	}{
+		{name: "path prefix", pathPrefix: "/rubygems", path: "/rubygems/info/rails", want: true},
+		{name: "path prefix root", pathPrefix: "/rubygems", path: "/rubygems", want: true},
+		{name: "other path prefix", pathPrefix: "/rubygems", path: "/npm/lodash", want: false},
+		{name: "compact index versions", host: "gems.example.com", path: "/versions", want: true},
+		{name: "compact index info", host: "gems.example.com", path: "/info/rails", want: true},
+		{name: "gem download", host: "gems.example.com", path: "/gems/rails-7.1.0.gem", want: true},
+		{name: "gem push", host: "gems.example.com", path: "/api/v1/gems", want: true},
+		{name: "bundler user agent", host: "gems.example.com", path: "/", userAgent: "bundler/2.5.3 rubygems/3.5.3", want: true},
+		{name: "unrelated path", host: "gems.example.com", path: "/v2/", want: false},
+		{name: "other host", host: "gems.example.com", requestHost: "npm.example.com", path: "/info/rails", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Host = "gems.example.com"
+			if tt.requestHost != "" {
+				r.Host = tt.requestHost
+			}
+			if tt.userAgent != "" {
+				r.Header.Set("User-Agent", tt.userAgent)
+			}
+
+			if got := NewRubyGemsDetector(tt.host, tt.pathPrefix).Detect(r); got != tt.want {
+				t.Errorf("Detect(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
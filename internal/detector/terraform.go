@@ -0,0 +1,90 @@
+package detector
+
+import (
+	"net/http"
+	"strings"
+)
+
+// TerraformDiscoveryPath is where Terraform looks up a registry host's services
+const TerraformDiscoveryPath = "/.well-known/terraform.json"
+
+// TerraformDetector detects Terraform registry requests
+type TerraformDetector struct {
+	host       string
+	pathPrefix string
+}
+
+// NewTerraformDetector creates a new Terraform detector
+// host: optional domain for host-based routing (e.g., "terraform.example.com")
+// pathPrefix: path prefix for path-based routing - required when host is empty
+func NewTerraformDetector(host, pathPrefix string) *TerraformDetector {
+	// Normalize pathPrefix: ensure starts with /, no trailing /
+	// SECURITY: No silent defaults - pathPrefix must be explicit from config
+	if pathPrefix != "" {
+		if !strings.HasPrefix(pathPrefix, "/") {
+			pathPrefix = "/" + pathPrefix
+		}
+		pathPrefix = strings.TrimSuffix(pathPrefix, "/")
+	}
+
+	return &TerraformDetector{
+		host:       host,
+		pathPrefix: pathPrefix,
+	}
+}
+
+// Detect checks if the request is a Terraform registry request
+func (d *TerraformDetector) Detect(r *http.Request) bool {
+	// Check 0: Host matching (if configured)
+	if d.host != "" {
+		requestHost := getRequestHost(r)
+		if requestHost != d.host {
+			return false
+		}
+	}
+
+	path := r.URL.Path
+
+	// Check 1: Service discovery, which Terraform always requests at the host's root
+	if path == TerraformDiscoveryPath {
+		return true
+	}
+
+	// Check 2: Path prefix matching (if configured)
+	if d.pathPrefix != "" {
+		if !strings.HasPrefix(path, d.pathPrefix+"/") && path != d.pathPrefix {
+			// Path doesn't match prefix
+			return false
+		}
+		// Path matches prefix - route to this protocol handler
+		// The handler will validate the specific request and handle auth
+		return true
+	}
+
+	// No pathPrefix configured - use protocol-specific detection
+	// This handles host-only routing mode
+
+	// Check 3: Registry API paths advertised by service discovery
+	if strings.HasPrefix(path, "/modules/v1/") || strings.HasPrefix(path, "/providers/v1/") {
+		return true
+	}
+
+	// Check 4: User-Agent header (e.g. "Terraform/1.6.6 (+https://www.terraform.io)",
+	// or "OpenTofu/1.6.0" for OpenTofu)
+	userAgent := r.Header.Get("User-Agent")
+	if strings.HasPrefix(userAgent, "Terraform/") || strings.HasPrefix(userAgent, "OpenTofu/") {
+		return true
+	}
+
+	return false
+}
+
+// Protocol returns the protocol name
+func (d *TerraformDetector) Protocol() Protocol {
+	return ProtocolTerraform
+}
+
+// Priority returns the detection priority (below Conda)
+func (d *TerraformDetector) Priority() int {
+	return 55
+}
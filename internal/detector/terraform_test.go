@@ -0,0 +1,47 @@
+package detector
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTerraformDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		requestHost string
+		pathPrefix  string
+		path        string
+		userAgent   string
+		want        bool
+	}{
+		{name: "path prefix", pathPrefix: "/terraform", path: "/terraform/modules/v1/acme/vpc/aws/versions", want: true},
+		{name: "path prefix root", pathPrefix: "/terraform", path: "/terraform", want: true},
+		{name: "discovery with path prefix", pathPrefix: "/terraform", path: "/.well-known/terraform.json", want: true},
+		{name: "other path prefix", pathPrefix: "/terraform", path: "/npm/lodash", want: false},
+		{name: "other well-known path", pathPrefix: "/terraform", path: "/.well-known/security.txt", want: false},
+		{name: "discovery", host: "terraform.example.com", path: "/.well-known/terraform.json", want: true},
+		{name: "module API", host: "terraform.example.com", path: "/modules/v1/acme/vpc/aws/1.0.0/download", want: true},
+		{name: "provider API", host: "terraform.example.com", path: "/providers/v1/acme/cloud/versions", want: true},
+		{name: "terraform user agent", host: "terraform.example.com", path: "/archives/vpc.tar.gz", userAgent: "Terraform/1.6.6 (+https://www.terraform.io)", want: true},
+		{name: "opentofu user agent", host: "terraform.example.com", path: "/archives/vpc.tar.gz", userAgent: "OpenTofu/1.6.0", want: true},
+		{name: "unrelated path", host: "terraform.example.com", path: "/packages.json", want: false},
+		{name: "other host", host: "terraform.example.com", requestHost: "npm.example.com", path: "/.well-known/terraform.json", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Host = "terraform.example.com"
+			if tt.requestHost != "" {
+				r.Host = tt.requestHost
+			}
+			if tt.userAgent != "" {
+				r.Header.Set("User-Agent", tt.userAgent)
+			}
+			if got := NewTerraformDetector(tt.host, tt.pathPrefix).Detect(r); got != tt.want {
+				t.Errorf("Detect(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
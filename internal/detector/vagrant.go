@@ -0,0 +1,80 @@
+package detector
+
+import (
+	"net/http"
+	"strings"
+)
+
+// VagrantDetector detects Vagrant box catalog requests
+type VagrantDetector struct {
+	host       string
+	pathPrefix string
+}
+
+// NewVagrantDetector creates a new Vagrant box catalog detector
+// host: optional domain for host-based routing (e.g., "boxes.example.com")
+// pathPrefix: path prefix for path-based routing - required when host is empty
+func NewVagrantDetector(host, pathPrefix string) *VagrantDetector {
+	// Normalize pathPrefix: ensure starts with /, no trailing /
+	// SECURITY: No silent defaults - pathPrefix must be explicit from config
+	if pathPrefix != "" {
+		if !strings.HasPrefix(pathPrefix, "/") {
+			pathPrefix = "/" + pathPrefix
+		}
+		pathPrefix = strings.TrimSuffix(pathPrefix, "/")
+	}
+
+	return &VagrantDetector{
+		host:       host,
+		pathPrefix: pathPrefix,
+	}
+}
+
+// Detect checks if the request is a Vagrant request
+func (d *VagrantDetector) Detect(r *http.Request) bool {
+	// Check 0: Host matching (if configured)
+	if d.host != "" {
+		requestHost := getRequestHost(r)
+		if requestHost != d.host {
+			return false
+		}
+	}
+
+	path := r.URL.Path
+
+	// Check 1: Path prefix matching (if configured)
+	if d.pathPrefix != "" {
+		if !strings.HasPrefix(path, d.pathPrefix+"/") && path != d.pathPrefix {
+			// Path doesn't match prefix
+			return false
+		}
+		// Path matches prefix - route to this protocol handler
+		// The handler will validate the specific request and handle auth
+		return true
+	}
+
+	// No pathPrefix configured - use protocol-specific detection
+	// This handles host-only routing mode
+
+	// Check 2: Vagrant Cloud API (box, version and provider metadata and downloads)
+	if strings.HasPrefix(path, "/api/v2/box/") {
+		return true
+	}
+
+	// Check 3: User-Agent header (e.g. "Vagrant/2.4.1 (+https://www.vagrantup.com; ruby3.3.0)")
+	if strings.HasPrefix(r.Header.Get("User-Agent"), "Vagrant/") {
+		return true
+	}
+
+	return false
+}
+
+// Protocol returns the protocol name
+func (d *VagrantDetector) Protocol() Protocol {
+	return ProtocolVagrant
+}
+
+// Priority returns the detection priority (below pub, above raw)
+func (d *VagrantDetector) Priority() int {
+	return 43
+}
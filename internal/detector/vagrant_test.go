@@ -0,0 +1,38 @@
+package detector
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVagrantDetector_Detect(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		pathPrefix string
+		path       string
+		userAgent  string
+		want       bool
+	}{
+		{name: "path prefix catalog", pathPrefix: "/vagrant", path: "/vagrant/acme/base", want: true},
+		{name: "path prefix download", pathPrefix: "/vagrant", path: "/vagrant/downloads/boxes/base-1.0.0-virtualbox.box", want: true},
+		{name: "other path prefix", pathPrefix: "/vagrant", path: "/npm/lodash", want: false},
+		{name: "host api", host: "boxes.example.com", path: "/api/v2/box/acme/base", want: true},
+		{name: "host vagrant user agent", host: "boxes.example.com", path: "/acme/base", userAgent: "Vagrant/2.4.1 (+https://www.vagrantup.com; ruby3.3.0)", want: true},
+		{name: "host unrelated path", host: "boxes.example.com", path: "/index.html", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Host = "boxes.example.com"
+			if tt.userAgent != "" {
+				r.Header.Set("User-Agent", tt.userAgent)
+			}
+
+			if got := NewVagrantDetector(tt.host, tt.pathPrefix).Detect(r); got != tt.want {
+				t.Errorf("Detect(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -79,6 +79,50 @@ var (
 		StatusCode: http.StatusTooManyRequests,
 	}
 
+	// Authentication and authorization errors
+	ErrUnauthorized = &AppError{
+		Code:       "UNAUTHORIZED",
+		Message:    "Authentication required",
+		StatusCode: http.StatusUnauthorized,
+	}
+
+	ErrForbidden = &AppError{
+		Code:       "FORBIDDEN",
+		Message:    "Insufficient privileges",
+		StatusCode: http.StatusForbidden,
+	}
+
+	// Request errors
+	ErrBadRequest = &AppError{
+		Code:       "BAD_REQUEST",
+		Message:    "Invalid request",
+		StatusCode: http.StatusBadRequest,
+	}
+
+	ErrClientUpgradeRequired = &AppError{
+		Code:       "CLIENT_UPGRADE_REQUIRED",
+		Message:    "This client version is no longer supported, please upgrade",
+		StatusCode: http.StatusBadRequest,
+	}
+
+	ErrNotFound = &AppError{
+		Code:       "NOT_FOUND",
+		Message:    "Resource not found",
+		StatusCode: http.StatusNotFound,
+	}
+
+	ErrMethodNotAllowed = &AppError{
+		Code:       "METHOD_NOT_ALLOWED",
+		Message:    "Method not allowed",
+		StatusCode: http.StatusMethodNotAllowed,
+	}
+
+	ErrPayloadTooLarge = &AppError{
+		Code:       "PAYLOAD_TOO_LARGE",
+		Message:    "Request body too large",
+		StatusCode: http.StatusRequestEntityTooLarge,
+	}
+
 	// Protocol errors
 	ErrProtocolNotSupported = &AppError{
 		Code:       "PROTOCOL_NOT_SUPPORTED",
@@ -93,6 +137,18 @@ var (
 		StatusCode: http.StatusInternalServerError,
 	}
 
+	ErrServiceUnavailable = &AppError{
+		Code:       "SERVICE_UNAVAILABLE",
+		Message:    "Service temporarily unavailable",
+		StatusCode: http.StatusServiceUnavailable,
+	}
+
+	ErrRequestCanceled = &AppError{
+		Code:       "REQUEST_CANCELED",
+		Message:    "Request canceled by an administrator",
+		StatusCode: http.StatusServiceUnavailable,
+	}
+
 	// Concurrency errors
 	ErrTooManyConcurrentRequests = &AppError{
 		Code:       "TOO_MANY_CONCURRENT_REQUESTS",
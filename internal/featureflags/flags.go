@@ -0,0 +1,145 @@
+// Package featureflags provides runtime toggles for dark-launching risky subsystems.
+//
+// Flags are defined in configuration (feature_flags) with their default state.
+// Admins can override a flag at runtime through the API to enable a feature for a
+// canary period or roll it back instantly, without a restart or redeploy. Overrides
+// are held in memory, so a restart returns every flag to its configured default.
+//
+// Subsystems consult a flag on each use, so a toggle takes effect immediately:
+//
+//	if flags.Enabled(featureflags.SignedURLs) { ... }
+//
+// The subsystems enabled by their own configuration (see Subsystems) have flags
+// that default to enabled, so they are kill switches: disabling one turns the
+// subsystem off on its next use without touching its configuration.
+package featureflags
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Flags of the subsystems enabled by their own configuration, consulted on each
+// request they serve
+const (
+	SignedURLs        = "signed_urls"        // Minting and accepting signed download URLs
+	BrowserSessions   = "browser_sessions"   // Signing in and accepting browser sessions
+	CredentialSharing = "credential_sharing" // Detecting and blocking shared tokens
+	Replication       = "replication"        // Queueing and reconciling OCI push replication
+	ForwardProxy      = "forward_proxy"      // Serving forward-proxy requests and tunnels
+)
+
+// Subsystems lists the flags of the subsystems enabled by their own configuration
+var Subsystems = []string{SignedURLs, BrowserSessions, CredentialSharing, Replication, ForwardProxy}
+
+// WithSubsystems returns the configured defaults with the flags of Subsystems
+// added as enabled, unless configured otherwise. Subsystems stay governed by their
+// own configuration, and admins can roll one back at runtime by disabling its flag.
+func WithSubsystems(defaults map[string]bool) map[string]bool {
+	merged := make(map[string]bool, len(defaults)+len(Subsystems))
+	for _, name := range Subsystems {
+		merged[name] = true
+	}
+	for name, enabled := range defaults {
+		merged[name] = enabled
+	}
+	return merged
+}
+
+// Flag describes the current state of a feature flag
+type Flag struct {
+	Name       string `json:"name"`
+	Enabled    bool   `json:"enabled"`
+	Default    bool   `json:"default"`    // Configured state
+	Overridden bool   `json:"overridden"` // Enabled was set at runtime
+}
+
+// Flags holds the configured feature flags and their runtime overrides.
+// It is safe for concurrent use. A nil *Flags reports every flag as disabled.
+type Flags struct {
+	mu        sync.RWMutex
+	defaults  map[string]bool
+	overrides map[string]bool
+}
+
+// New creates feature flags with the configured defaults
+func New(defaults map[string]bool) *Flags {
+	f := &Flags{
+		defaults:  make(map[string]bool, len(defaults)),
+		overrides: make(map[string]bool),
+	}
+	for name, enabled := range defaults {
+		f.defaults[name] = enabled
+	}
+	return f
+}
+
+// Enabled reports whether the named flag is enabled.
+// Flags not defined in configuration are disabled, so new features stay dark by default.
+func (f *Flags) Enabled(name string) bool {
+	if f == nil {
+		return false
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if enabled, ok := f.overrides[name]; ok {
+		return enabled
+	}
+	return f.defaults[name]
+}
+
+// Set overrides the named flag at runtime. Only flags defined in configuration can be
+// set, so a typo cannot silently create a flag nothing consults.
+func (f *Flags) Set(name string, enabled bool) (Flag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.defaults[name]; !ok {
+		return Flag{}, fmt.Errorf("unknown feature flag: %s", name)
+	}
+	f.overrides[name] = enabled
+	return f.flag(name), nil
+}
+
+// Reset removes the runtime override of the named flag, restoring its configured state
+func (f *Flags) Reset(name string) (Flag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.defaults[name]; !ok {
+		return Flag{}, fmt.Errorf("unknown feature flag: %s", name)
+	}
+	delete(f.overrides, name)
+	return f.flag(name), nil
+}
+
+// All returns the state of every configured flag, sorted by name
+func (f *Flags) All() []Flag {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	flags := make([]Flag, 0, len(f.defaults))
+	for name := range f.defaults {
+		flags = append(flags, f.flag(name))
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+// flag returns the state of a configured flag. Callers must hold f.mu.
+func (f *Flags) flag(name string) Flag {
+	override, overridden := f.overrides[name]
+	enabled := f.defaults[name]
+	if overridden {
+		enabled = override
+	}
+	return Flag{
+		Name:       name,
+		Enabled:    enabled,
+		Default:    f.defaults[name],
+		Overridden: overridden,
+	}
+}
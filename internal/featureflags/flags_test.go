@@ -0,0 +1,67 @@
+package featureflags
+
+import "testing"
+
+func TestFlags(t *testing.T) {
+	flags := New(map[string]bool{"cache": false, "verification": true})
+
+	tests := []struct {
+		name    string
+		action  func() (Flag, error)
+		flag    string
+		wantErr bool
+		want    bool
+	}{
+		{name: "configured default off", flag: "cache", want: false},
+		{name: "configured default on", flag: "verification", want: true},
+		{name: "unknown flag is disabled", flag: "token_service", want: false},
+		{name: "override on", action: func() (Flag, error) { return flags.Set("cache", true) }, flag: "cache", want: true},
+		{name: "override off", action: func() (Flag, error) { return flags.Set("verification", false) }, flag: "verification", want: false},
+		{name: "reset restores default", action: func() (Flag, error) { return flags.Reset("verification") }, flag: "verification", want: true},
+		{name: "unknown flag cannot be set", action: func() (Flag, error) { return flags.Set("token_service", true) }, flag: "token_service", wantErr: true, want: false},
+		{name: "unknown flag cannot be reset", action: func() (Flag, error) { return flags.Reset("token_service") }, flag: "token_service", wantErr: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.action != nil {
+				_, err := tt.action()
+				if tt.wantErr != (err != nil) {
+					t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+				}
+			}
+			if got := flags.Enabled(tt.flag); got != tt.want {
+				t.Errorf("Enabled(%q) = %v, want %v", tt.flag, got, tt.want)
+			}
+		})
+	}
+
+	all := flags.All()
+	if len(all) != 2 || all[0].Name != "cache" || !all[0].Overridden || all[1].Overridden {
+		t.Errorf("unexpected flags: %+v", all)
+	}
+}
+
+func TestFlags_Nil(t *testing.T) {
+	var flags *Flags
+	if flags.Enabled("cache") {
+		t.Error("nil flags should report every flag as disabled")
+	}
+}
+
+func TestWithSubsystems(t *testing.T) {
+	flags := New(WithSubsystems(map[string]bool{"cache": false, Replication: false}))
+
+	if !flags.Enabled(SignedURLs) {
+		t.Errorf("Enabled(%q) = false, want subsystem flags enabled by default", SignedURLs)
+	}
+	if flags.Enabled(Replication) {
+		t.Errorf("Enabled(%q) = true, want the configured default", Replication)
+	}
+	if flags.Enabled("cache") {
+		t.Error(`Enabled("cache") = true, want the configured default`)
+	}
+	if _, err := flags.Set(ForwardProxy, false); err != nil || flags.Enabled(ForwardProxy) {
+		t.Errorf("Set(%q, false) = %v, want the subsystem disabled", ForwardProxy, err)
+	}
+}
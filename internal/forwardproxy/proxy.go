@@ -0,0 +1,361 @@
+// Package forwardproxy implements a forward-proxy listener for tools that cannot be
+// pointed at a custom registry URL.
+//
+// Clients use the listener as their HTTP(S) proxy. Requests to the configured registry
+// hosts are intercepted and routed through Artifusion's protocol handlers:
+//
+//   - Plain HTTP proxy requests (absolute-form request URIs) are routed directly.
+//   - CONNECT tunnels are terminated with the configured certificate, and the requests
+//     sent through the tunnel are routed the same way.
+//
+// Requests for any other host are rejected, so the listener is never an open proxy.
+package forwardproxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/featureflags"
+	"github.com/rs/zerolog"
+)
+
+// route describes how requests for an intercepted host reach a protocol handler
+type route struct {
+	protocol   string
+	host       string // Host of the protocol for host-based routing, empty for path-based routing
+	pathPrefix string
+}
+
+// Proxy is an http.Handler serving forward-proxy requests
+type Proxy struct {
+	handler   http.Handler
+	routes    map[string]route // Keyed by lowercase intercepted hostname
+	tlsConfig *tls.Config      // nil when CONNECT tunnels are not supported
+	server    config.ServerConfig
+	flags     *featureflags.Flags // nil = always enabled
+	logger    zerolog.Logger
+
+	mu      sync.Mutex
+	tunnels map[*http.Server]struct{}
+	closed  bool
+}
+
+// New creates a forward proxy routing intercepted requests to handler, which is
+// normally the main router so requests pass through the full middleware stack.
+func New(cfg *config.Config, handler http.Handler, logger zerolog.Logger) (*Proxy, error) {
+	p := &Proxy{
+		handler: handler,
+		routes:  make(map[string]route, len(cfg.ForwardProxy.Intercept)),
+		server:  cfg.Server,
+		logger:  logger.With().Str("component", "forward_proxy").Logger(),
+		tunnels: make(map[*http.Server]struct{}),
+	}
+
+	for _, intercept := range cfg.ForwardProxy.Intercept {
+		rt := route{protocol: intercept.Protocol}
+		switch intercept.Protocol {
+		case "oci":
+			rt.host = cfg.Protocols.OCI.Host
+		case "maven":
+			rt.host = cfg.Protocols.Maven.Host
+			rt.pathPrefix = cfg.Protocols.Maven.PathPrefix
+		case "npm":
+			rt.host = cfg.Protocols.NPM.Host
+			rt.pathPrefix = cfg.Protocols.NPM.PathPrefix
+		default:
+			return nil, fmt.Errorf("unknown protocol %q for intercepted host %s", intercept.Protocol, intercept.Host)
+		}
+		p.routes[strings.ToLower(intercept.Host)] = rt
+	}
+
+	if cfg.ForwardProxy.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ForwardProxy.TLSCertFile, cfg.ForwardProxy.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load forward proxy certificate: %w", err)
+		}
+		p.tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+			NextProtos:   []string{"http/1.1"},
+		}
+	}
+
+	return p, nil
+}
+
+// SetFeatureFlags gates the forward proxy on the featureflags.ForwardProxy flag,
+// checked on every request, including those sent through open tunnels. Must be
+// called before the proxy serves requests.
+func (p *Proxy) SetFeatureFlags(flags *featureflags.Flags) {
+	p.flags = flags
+}
+
+// ServeHTTP handles a forward-proxy request
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if p.disabled(w) {
+		return
+	}
+
+	if r.Method == http.MethodConnect {
+		p.serveConnect(w, r)
+		return
+	}
+
+	if !r.URL.IsAbs() {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage("Forward proxy requests must use an absolute URI"))
+		return
+	}
+
+	hostname, rt, ok := p.lookup(r.URL.Host)
+	if !ok {
+		p.reject(w, r, r.URL.Host)
+		return
+	}
+
+	p.route(w, r, hostname, rt, r.URL.Scheme)
+}
+
+// serveConnect terminates a CONNECT tunnel to an intercepted host and serves the
+// requests sent through it
+func (p *Proxy) serveConnect(w http.ResponseWriter, r *http.Request) {
+	hostname, rt, ok := p.lookup(r.Host)
+	if !ok {
+		p.reject(w, r, r.Host)
+		return
+	}
+
+	if p.tlsConfig == nil {
+		errors.ErrorResponse(w, errors.ErrForbidden.WithMessage("CONNECT is not supported without a forward proxy certificate"))
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		errors.ErrorResponse(w, errors.ErrInternal.WithMessage("Connection does not support CONNECT"))
+		return
+	}
+
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		p.logger.Error().Err(err).Str("host", r.Host).Msg("Failed to hijack CONNECT connection")
+		return
+	}
+
+	// The listener's deadlines were meant for the CONNECT request itself; the tunnel
+	// server applies its own per-request timeouts
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		p.logger.Warn().Err(err).Msg("Failed to clear CONNECT connection deadlines")
+	}
+
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	// Clients may start the TLS handshake before reading the CONNECT response
+	if buf.Reader.Buffered() > 0 {
+		conn = &bufferedConn{Conn: conn, reader: buf.Reader}
+	}
+
+	p.logger.Debug().
+		Str("host", hostname).
+		Str("protocol", rt.protocol).
+		Msg("Intercepting CONNECT tunnel")
+
+	tunnel := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p.disabled(w) {
+				return
+			}
+			p.route(w, r, hostname, rt, "https")
+		}),
+		ReadTimeout:       p.server.ReadTimeout,
+		WriteTimeout:      p.server.WriteTimeout,
+		IdleTimeout:       p.server.IdleTimeout,
+		MaxHeaderBytes:    p.server.MaxHeaderBytes,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	p.serveTunnel(tunnel, tls.Server(conn, p.tlsConfig))
+}
+
+// serveTunnel serves HTTP on a single tunneled connection until it is closed
+func (p *Proxy) serveTunnel(tunnel *http.Server, conn net.Conn) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		_ = conn.Close()
+		return
+	}
+	p.tunnels[tunnel] = struct{}{}
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.tunnels, tunnel)
+		p.mu.Unlock()
+	}()
+
+	listener := newConnListener(conn)
+	tunnel.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed || state == http.StateHijacked {
+			_ = listener.Close()
+		}
+	}
+
+	// Serve returns once the connection is closed and the listener is drained
+	_ = tunnel.Serve(listener)
+}
+
+// route rewrites an intercepted request so protocol detection selects the protocol of
+// the intercepted host, and passes it to the handler
+func (p *Proxy) route(w http.ResponseWriter, r *http.Request, hostname string, rt route, scheme string) {
+	req := r.Clone(r.Context())
+	req.URL.Scheme = ""
+	req.URL.Host = ""
+	req.RequestURI = ""
+
+	// Proxy-only headers are never forwarded, and client-supplied forwarding headers
+	// would override the routing below
+	req.Header.Del("Proxy-Authorization")
+	req.Header.Del("Proxy-Connection")
+	req.Header.Del("Forwarded")
+	req.Header.Del("X-Forwarded-Host")
+	req.Header.Set("X-Forwarded-Proto", scheme)
+
+	if rt.host != "" {
+		req.Host = rt.host
+	} else {
+		req.Host = hostname
+	}
+
+	// URLs in rewritten responses already carry the prefix when they come back through
+	// the proxy, so it is only added once
+	if rt.pathPrefix != "" && !hasPathPrefix(req.URL.Path, rt.pathPrefix) {
+		req.URL.Path = rt.pathPrefix + req.URL.Path
+		if req.URL.RawPath != "" {
+			req.URL.RawPath = rt.pathPrefix + req.URL.RawPath
+		}
+	}
+
+	p.logger.Debug().
+		Str("host", hostname).
+		Str("protocol", rt.protocol).
+		Str("method", req.Method).
+		Str("path", req.URL.Path).
+		Msg("Routing intercepted request")
+
+	p.handler.ServeHTTP(w, req)
+}
+
+// lookup returns the route for an intercepted host, given as host or host:port
+func (p *Proxy) lookup(hostport string) (string, route, bool) {
+	hostname := hostport
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		hostname = host
+	}
+	hostname = strings.ToLower(hostname)
+
+	rt, ok := p.routes[hostname]
+	return hostname, rt, ok
+}
+
+// disabled refuses a request while the forward proxy's feature flag is disabled,
+// and reports whether it did
+func (p *Proxy) disabled(w http.ResponseWriter) bool {
+	if p.flags == nil || p.flags.Enabled(featureflags.ForwardProxy) {
+		return false
+	}
+	errors.ErrorResponse(w, errors.ErrServiceUnavailable.WithMessage("Forward proxy is disabled"))
+	return true
+}
+
+// reject refuses a request for a host that is not intercepted
+func (p *Proxy) reject(w http.ResponseWriter, r *http.Request, host string) {
+	p.logger.Warn().
+		Str("host", host).
+		Str("method", r.Method).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("Forward proxy request for host that is not intercepted")
+
+	errors.ErrorResponse(w, errors.ErrForbidden.WithMessagef("Host %s is not served by this proxy", host))
+}
+
+// Shutdown gracefully shuts down open CONNECT tunnels. The listener serving the proxy
+// must be shut down first so no new tunnels are opened.
+func (p *Proxy) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	tunnels := make([]*http.Server, 0, len(p.tunnels))
+	for tunnel := range p.tunnels {
+		tunnels = append(tunnels, tunnel)
+	}
+	p.mu.Unlock()
+
+	var firstErr error
+	for _, tunnel := range tunnels {
+		if err := tunnel.Shutdown(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// hasPathPrefix reports whether path is prefix or lies below it
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// bufferedConn reads data buffered during the CONNECT request before the connection
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// connListener is a net.Listener returning a single connection. After the connection
+// is accepted, Accept blocks until the listener is closed.
+type connListener struct {
+	conn      net.Conn
+	mu        sync.Mutex
+	accepted  bool
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newConnListener(conn net.Conn) *connListener {
+	return &connListener{conn: conn, done: make(chan struct{})}
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	if !l.accepted {
+		l.accepted = true
+		l.mu.Unlock()
+		return l.conn, nil
+	}
+	l.mu.Unlock()
+
+	<-l.done
+	return nil, net.ErrClosed
+}
+
+func (l *connListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
@@ -0,0 +1,264 @@
+package forwardproxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/featureflags"
+	"github.com/rs/zerolog"
+)
+
+// recordingHandler records the last request it served
+type recordingHandler struct {
+	req *http.Request
+}
+
+func (h *recordingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.req = r
+	w.WriteHeader(http.StatusOK)
+}
+
+func testConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Protocols.Maven.Enabled = true
+	cfg.Protocols.Maven.Host = "maven.example.com"
+	cfg.Protocols.NPM.Enabled = true
+	cfg.Protocols.NPM.PathPrefix = "/npm"
+	cfg.ForwardProxy = config.ForwardProxyConfig{
+		Enabled: true,
+		Port:    3128,
+		Intercept: []config.InterceptConfig{
+			{Host: "registry.npmjs.org", Protocol: "npm"},
+			{Host: "repo.maven.apache.org", Protocol: "maven"},
+		},
+	}
+	return cfg
+}
+
+func TestProxy_ServeHTTP(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		disabled   bool
+		wantStatus int
+		wantHost   string
+		wantPath   string
+	}{
+		{
+			name:       "path-based protocol gets prefix",
+			method:     http.MethodGet,
+			target:     "http://registry.npmjs.org/lodash",
+			wantStatus: http.StatusOK,
+			wantHost:   "registry.npmjs.org",
+			wantPath:   "/npm/lodash",
+		},
+		{
+			name:       "prefix is only added once",
+			method:     http.MethodGet,
+			target:     "http://registry.npmjs.org/npm/lodash/-/lodash-4.17.21.tgz",
+			wantStatus: http.StatusOK,
+			wantHost:   "registry.npmjs.org",
+			wantPath:   "/npm/lodash/-/lodash-4.17.21.tgz",
+		},
+		{
+			name:       "host-based protocol gets its host",
+			method:     http.MethodGet,
+			target:     "http://REPO.maven.apache.org:80/maven2/junit/junit/4.13/junit-4.13.pom",
+			wantStatus: http.StatusOK,
+			wantHost:   "maven.example.com",
+			wantPath:   "/maven2/junit/junit/4.13/junit-4.13.pom",
+		},
+		{
+			name:       "host that is not intercepted",
+			method:     http.MethodGet,
+			target:     "http://example.com/",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "origin-form request",
+			method:     http.MethodGet,
+			target:     "/lodash",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "disabled by feature flag",
+			method:     http.MethodGet,
+			target:     "http://registry.npmjs.org/lodash",
+			disabled:   true,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "CONNECT without certificate",
+			method:     http.MethodConnect,
+			target:     "registry.npmjs.org:443",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &recordingHandler{}
+			proxy, err := New(testConfig(), handler, zerolog.Nop())
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			proxy.SetFeatureFlags(featureflags.New(featureflags.WithSubsystems(map[string]bool{featureflags.ForwardProxy: !tt.disabled})))
+
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Set("Proxy-Authorization", "Basic c2VjcmV0")
+			req.Header.Set("X-Forwarded-Host", "spoofed.example.com")
+			rec := httptest.NewRecorder()
+
+			proxy.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if handler.req != nil {
+					t.Error("rejected request reached the handler")
+				}
+				return
+			}
+
+			got := handler.req
+			if got.Host != tt.wantHost {
+				t.Errorf("Host = %q, want %q", got.Host, tt.wantHost)
+			}
+			if got.URL.Path != tt.wantPath {
+				t.Errorf("Path = %q, want %q", got.URL.Path, tt.wantPath)
+			}
+			if got.URL.IsAbs() {
+				t.Errorf("URL = %q, want origin-form", got.URL)
+			}
+			if got.Header.Get("Proxy-Authorization") != "" {
+				t.Error("Proxy-Authorization was forwarded")
+			}
+			if got.Header.Get("X-Forwarded-Host") != "" {
+				t.Error("client X-Forwarded-Host was forwarded")
+			}
+			if proto := got.Header.Get("X-Forwarded-Proto"); proto != "http" {
+				t.Errorf("X-Forwarded-Proto = %q, want http", proto)
+			}
+		})
+	}
+}
+
+func TestProxy_Connect(t *testing.T) {
+	certFile, keyFile, pool := writeTestCertificate(t, "registry.npmjs.org")
+
+	cfg := testConfig()
+	cfg.ForwardProxy.TLSCertFile = certFile
+	cfg.ForwardProxy.TLSKeyFile = keyFile
+
+	handler := &recordingHandler{}
+	proxy, err := New(cfg, handler, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	server := httptest.NewServer(proxy)
+	defer server.Close()
+
+	proxyURL, _ := url.Parse(server.URL)
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyURL(proxyURL),
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+		Timeout: 5 * time.Second,
+	}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get("https://registry.npmjs.org/lodash")
+	if err != nil {
+		t.Fatalf("request through tunnel failed: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if handler.req == nil {
+		t.Fatal("tunneled request did not reach the handler")
+	}
+	if handler.req.URL.Path != "/npm/lodash" {
+		t.Errorf("Path = %q, want /npm/lodash", handler.req.URL.Path)
+	}
+	if proto := handler.req.Header.Get("X-Forwarded-Proto"); proto != "https" {
+		t.Errorf("X-Forwarded-Proto = %q, want https", proto)
+	}
+
+	// Tunnels to hosts that are not intercepted are refused before the handshake
+	if _, err := client.Get("https://example.com/"); err == nil {
+		t.Error("expected tunnel to a host that is not intercepted to fail")
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate for host and returns the file
+// paths and a pool trusting it
+func writeTestCertificate(t *testing.T, host string) (string, string, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return certFile, keyFile, pool
+}
@@ -0,0 +1,46 @@
+package apk
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/auth"
+)
+
+// authenticateClient validates the client's GitHub PAT using shared authenticator.
+// apk sends Basic credentials from the repository URL in /etc/apk/repositories
+// (https://<user>:<token>@host/...).
+func (h *Handler) authenticateClient(r *http.Request) (*auth.AuthResult, *http.Request, error) {
+	authResult, newReq, err := h.authenticator.AuthenticateAndInjectContext(r)
+	if err != nil {
+		return nil, r, err
+	}
+
+	return authResult, newReq, nil
+}
+
+// handleAuthError returns an apk-compliant error response. apk only sends the
+// credentials of the repository URL when challenged for Basic authentication.
+func (h *Handler) handleAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	h.logger.Warn().Err(err).
+		Str("path", r.URL.Path).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("Authentication failed")
+
+	status, message := http.StatusUnauthorized, h.config.ClientAuth.UnauthorizedMessage("Authentication required")
+	if errors.Is(err, auth.ErrInsufficientPermissions) {
+		// The credentials are valid, so challenging for others wouldn't help
+		status, message = http.StatusForbidden, auth.InsufficientPermissionsMessage
+	} else {
+		// Set WWW-Authenticate challenge headers
+		auth.SetChallenges(w.Header(), &h.config.ClientAuth, auth.ChallengeOptions{
+			Schemes: []string{auth.SchemeBasic},
+			Realm:   "Artifusion APK Repository",
+		})
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	if _, writeErr := w.Write([]byte(message + "\n")); writeErr != nil {
+		h.logger.Error().Err(writeErr).Msg("Failed to write authentication error response")
+	}
+}
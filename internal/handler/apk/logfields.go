@@ -0,0 +1,86 @@
+package apk
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// indexFile is the name of the signed index in each repository directory
+const indexFile = "APKINDEX.tar.gz"
+
+// addLogFields adds what the request targets to its completion log line:
+// apk_repository and apk_arch for indexes, and apk_package, apk_version and
+// apk_arch for packages
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	p := h.backendPath(r)
+	if repository, arch, ok := parseIndexPath(p); ok {
+		middleware.AddLogField(ctx, "apk_repository", repository)
+		middleware.AddLogField(ctx, "apk_arch", arch)
+		return
+	}
+	if name, version, arch, ok := parsePackagePath(p); ok {
+		middleware.AddLogField(ctx, "apk_package", name)
+		middleware.AddLogField(ctx, "apk_version", version)
+		middleware.AddLogField(ctx, "apk_arch", arch)
+	}
+}
+
+// parseIndexPath extracts the repository and architecture from the path of an
+// index, <repository>/<arch>/APKINDEX.tar.gz
+//
+//	/v3.20/main/x86_64/APKINDEX.tar.gz          -> v3.20/main, x86_64
+//	/edge/testing/aarch64/APKINDEX.tar.gz       -> edge/testing, aarch64
+func parseIndexPath(p string) (repository, arch string, ok bool) {
+	dir, found := strings.CutSuffix(p, "/"+indexFile)
+	if !found {
+		return "", "", false
+	}
+	repository, arch = path.Split(dir)
+	repository = strings.Trim(repository, "/")
+	if repository == "" || arch == "" {
+		return "", "", false
+	}
+	return repository, arch, true
+}
+
+// parsePackagePath extracts the package name, version and architecture from the
+// path of a package, <arch>/<name>-<version>-r<release>.apk. Names may contain
+// dashes, so the version is taken from the end.
+//
+//	/v3.20/main/x86_64/nginx-1.26.1-r0.apk           -> nginx, 1.26.1-r0, x86_64
+//	/edge/community/aarch64/py3-requests-2.32.3-r1.apk -> py3-requests, 2.32.3-r1, aarch64
+func parsePackagePath(p string) (name, version, arch string, ok bool) {
+	dir, base := path.Split(p)
+	trimmed, found := strings.CutSuffix(base, ".apk")
+	if !found {
+		return "", "", "", false
+	}
+	arch = path.Base(dir)
+	if arch == "/" || arch == "." {
+		return "", "", "", false
+	}
+
+	rest, release, found := cutLast(trimmed, "-")
+	if !found || len(release) < 2 || release[0] != 'r' {
+		return "", "", "", false
+	}
+	name, pkgver, found := cutLast(rest, "-")
+	if !found || name == "" || pkgver == "" {
+		return "", "", "", false
+	}
+	return name, pkgver + "-" + release, arch, true
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,55 @@
+package apk
+
+import "testing"
+
+func TestParseIndexPath(t *testing.T) {
+	tests := []struct {
+		path           string
+		wantRepository string
+		wantArch       string
+		wantOK         bool
+	}{
+		{"/v3.20/main/x86_64/APKINDEX.tar.gz", "v3.20/main", "x86_64", true},
+		{"/edge/testing/aarch64/APKINDEX.tar.gz", "edge/testing", "aarch64", true},
+		{"/x86_64/APKINDEX.tar.gz", "", "", false},
+		{"/v3.20/main/x86_64/nginx-1.26.1-r0.apk", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			repository, arch, ok := parseIndexPath(tt.path)
+			if repository != tt.wantRepository || arch != tt.wantArch || ok != tt.wantOK {
+				t.Errorf("parseIndexPath(%q) = %q, %q, %v, want %q, %q, %v",
+					tt.path, repository, arch, ok, tt.wantRepository, tt.wantArch, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParsePackagePath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantName    string
+		wantVersion string
+		wantArch    string
+		wantOK      bool
+	}{
+		{"/v3.20/main/x86_64/nginx-1.26.1-r0.apk", "nginx", "1.26.1-r0", "x86_64", true},
+		{"/edge/community/aarch64/py3-requests-2.32.3-r1.apk", "py3-requests", "2.32.3-r1", "aarch64", true},
+		{"/v3.20/main/x86_64/linux-lts-6.6.31-r12.apk", "linux-lts", "6.6.31-r12", "x86_64", true},
+		{"/v3.20/main/x86_64/nginx-1.26.1.apk", "", "", "", false},
+		{"/v3.20/main/x86_64/nginx.apk", "", "", "", false},
+		{"/nginx-1.26.1-r0.apk", "", "", "", false},
+		{"/v3.20/main/x86_64/APKINDEX.tar.gz", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			name, version, arch, ok := parsePackagePath(tt.path)
+			if name != tt.wantName || version != tt.wantVersion || arch != tt.wantArch || ok != tt.wantOK {
+				t.Errorf("parsePackagePath(%q) = %q, %q, %q, %v, want %q, %q, %q, %v",
+					tt.path, name, version, arch, ok, tt.wantName, tt.wantVersion, tt.wantArch, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package apk
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/proxy/rewriter"
+)
+
+// proxyPassthrough proxies the request to the backend. Bodies are streamed
+// unmodified: APKINDEX.tar.gz carries its own signature (.SIGN.RSA.<key>.rsa.pub)
+// and lists the checksum of every package, so apk verifies both end to end against
+// the keys in /etc/apk/keys. Packages are fetched next to the index, so only
+// redirects need to point at the proxy.
+func (h *Handler) proxyPassthrough(w http.ResponseWriter, r *http.Request, backend *config.APKBackendConfig) error {
+	path := h.backendPath(r)
+
+	resp, err := h.executeProxyRequest(r, backend, path)
+	if err != nil {
+		return err
+	}
+	h.recordArtifact(r, path, resp.StatusCode)
+
+	// Rewrite Location header (e.g. mirrors redirecting packages to a CDN)
+	proxyURL := h.getEffectiveBaseURL(r)
+	if !rewriter.RewriteRedirectLocation(resp, backend, proxyURL) {
+		if location := resp.Headers.Get("Location"); location != "" {
+			if mapped, ok := rewriter.MapLocation(location, backend.URL, proxyURL); ok {
+				resp.Headers.Set("Location", mapped)
+			}
+		}
+	}
+
+	_, err = h.proxyClient.StreamResponse(w, resp, true)
+	return err
+}
+
+// backendPath returns the request path with the path prefix stripped
+func (h *Handler) backendPath(r *http.Request) string {
+	path := r.URL.Path
+	if h.config.PathPrefix != "" {
+		path = strings.TrimPrefix(path, h.config.PathPrefix)
+		// Ensure path starts with /
+		if path == "" || !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	return path
+}
+
+// executeProxyRequest sends the request to backend and records backend metrics,
+// returning the response without writing it
+func (h *Handler) executeProxyRequest(r *http.Request, backend *config.APKBackendConfig, path string) (*proxy.Response, error) {
+	// Create proxy request
+	proxyReq := &proxy.Request{
+		Method:      r.Method,
+		Path:        path,
+		Query:       r.URL.RawQuery,
+		Body:        r.Body,
+		Headers:     r.Header,
+		Backend:     backend,
+		OriginalReq: r,
+	}
+
+	// Track backend request timing
+	start := time.Now()
+
+	// Execute proxy request
+	resp, err := h.proxyClient.ProxyRequest(proxyReq)
+
+	// Record metrics regardless of success/failure
+	duration := time.Since(start)
+
+	if err != nil {
+		// Record backend error metrics
+		h.metrics.RecordBackendError(h.Name(), backend.Name, "network_error")
+		h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+		h.metrics.SetBackendHealth(backend.Name, false)
+
+		h.logger.Error().Err(err).
+			Str("backend", backend.Name).
+			Dur("duration", duration).
+			Msg("Backend request failed")
+
+		return nil, err
+	}
+
+	// Record backend latency for all requests
+	h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+
+	// Record backend health based on status code
+	if resp.StatusCode >= 500 {
+		// Server error - backend is unhealthy
+		h.metrics.RecordBackendErrorByStatus(backend.Name, resp.StatusCode)
+		h.metrics.SetBackendHealth(backend.Name, false)
+	} else if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+		// Success - backend is healthy
+		h.metrics.SetBackendHealth(backend.Name, true)
+	}
+	// 4xx errors don't affect backend health (client errors)
+
+	return resp, nil
+}
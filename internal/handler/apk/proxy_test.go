@@ -0,0 +1,93 @@
+package apk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// TestSelectBackendAndProxy tests that requests below the path prefix are proxied to
+// the repository with the backend's credentials instead of the client's
+func TestSelectBackendAndProxy(t *testing.T) {
+	var gotPath, gotUser, gotPassword string
+	repository := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotUser, gotPassword, _ = r.BasicAuth()
+		switch r.URL.Path {
+		case "/alpine/v3.20/main/x86_64/APKINDEX.tar.gz", "/alpine/v3.20/main/x86_64/nginx-1.26.1-r0.apk":
+			_, _ = w.Write([]byte("content"))
+		case "/alpine/v3.20/main/x86_64/moved-1.0-r0.apk":
+			http.Redirect(w, r, "/alpine/v3.20/main/x86_64/nginx-1.26.1-r0.apk", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer repository.Close()
+
+	cfg := &config.APKConfig{
+		PathPrefix: "/apk",
+		Backend: config.APKBackendConfig{
+			Name:                "alpine",
+			URL:                 repository.URL + "/alpine",
+			Auth:                &config.AuthConfig{Type: "basic", Username: "mirror", Password: "secret"},
+			MaxIdleConns:        1,
+			MaxIdleConnsPerHost: 1,
+			DialTimeout:         time.Second,
+			RequestTimeout:      10 * time.Second,
+		},
+	}
+
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("apk_proxy_test"), logger)
+
+	tests := []struct {
+		name         string
+		path         string
+		wantStatus   int
+		wantPath     string
+		wantLocation string
+	}{
+		{name: "index", path: "/apk/v3.20/main/x86_64/APKINDEX.tar.gz", wantStatus: http.StatusOK, wantPath: "/alpine/v3.20/main/x86_64/APKINDEX.tar.gz"},
+		{name: "package", path: "/apk/v3.20/main/x86_64/nginx-1.26.1-r0.apk", wantStatus: http.StatusOK, wantPath: "/alpine/v3.20/main/x86_64/nginx-1.26.1-r0.apk"},
+		{
+			name:         "redirect",
+			path:         "/apk/v3.20/main/x86_64/moved-1.0-r0.apk",
+			wantStatus:   http.StatusFound,
+			wantPath:     "/alpine/v3.20/main/x86_64/moved-1.0-r0.apk",
+			wantLocation: "https://example.com/apk/v3.20/main/x86_64/nginx-1.26.1-r0.apk",
+		},
+		{name: "missing package", path: "/apk/v3.20/main/x86_64/missing-1.0-r0.apk", wantStatus: http.StatusNotFound, wantPath: "/alpine/v3.20/main/x86_64/missing-1.0-r0.apk"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotPath, gotUser, gotPassword = "", "", ""
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.SetBasicAuth("alice", "ghp_client_token")
+			if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+				t.Fatalf("selectBackendAndProxy failed: %v", err)
+			}
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if gotPath != tt.wantPath {
+				t.Errorf("backend path = %q, want %q", gotPath, tt.wantPath)
+			}
+			if gotUser != "mirror" || gotPassword != "secret" {
+				t.Errorf("backend credentials = %q:%q, want mirror:secret", gotUser, gotPassword)
+			}
+			if location := w.Header().Get("Location"); location != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", location, tt.wantLocation)
+			}
+		})
+	}
+}
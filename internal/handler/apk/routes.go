@@ -0,0 +1,31 @@
+package apk
+
+import (
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// selectBackendAndProxy determines the appropriate backend and proxies the request
+func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request, authResult *auth.AuthResult) error {
+	// Use single backend for both read and write operations
+	backend := &h.config.Backend
+
+	// Log operation type for debugging
+	operationType := "read"
+	if auth.IsWriteMethod(r.Method) {
+		operationType = "write"
+	}
+
+	h.logger.Debug().
+		Str("backend", backend.Name).
+		Str("url", backend.URL).
+		Str("operation", operationType).
+		Str("username", authResult.Username).
+		Msg("Routing to APK backend")
+	middleware.AddLogField(r.Context(), "backend", backend.Name)
+
+	// Note: Backend authentication is handled by proxy client
+	return h.proxyPassthrough(w, r, backend)
+}
@@ -0,0 +1,102 @@
+package apt
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/detector"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/metadata"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// Handler handles Debian/APT repository requests: the indexes under dists/
+// (Release, InRelease, Release.gpg, Packages) and the packages under pool/
+type Handler struct {
+	config        *config.APTConfig
+	authenticator *auth.ClientAuthenticator
+	proxyClient   *proxy.Client
+	metrics       *metrics.Metrics
+	metadata      *metadata.Store // nil = disabled
+	logger        zerolog.Logger
+}
+
+// NewHandler creates a new APT handler
+func NewHandler(
+	cfg *config.APTConfig,
+	authenticator *auth.ClientAuthenticator,
+	proxyClient *proxy.Client,
+	metricsCollector *metrics.Metrics,
+	logger zerolog.Logger,
+) *Handler {
+	return &Handler{
+		config:        cfg,
+		authenticator: authenticator,
+		proxyClient:   proxyClient,
+		metrics:       metricsCollector,
+		logger:        logger.With().Str("protocol", "apt").Logger(),
+	}
+}
+
+// ServeHTTP handles APT repository requests
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug().
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Msg("APT request received")
+
+	// Tag the request's log line with the suite or package it targets
+	h.addLogFields(r)
+
+	// Step 1: Authenticate client
+	authResult, updatedReq, err := h.authenticateClient(r)
+	if err != nil {
+		h.handleAuthError(w, r, err)
+		return
+	}
+
+	// Step 2: Proxy request to the backend
+	if err := h.selectBackendAndProxy(w, updatedReq, authResult); err != nil {
+		h.logger.Error().Err(err).
+			Str("path", updatedReq.URL.Path).
+			Str("method", updatedReq.Method).
+			Msg("Failed to proxy request")
+
+		errors.ErrorResponse(w, errors.ErrInternal.WithInternal(err))
+	}
+}
+
+// Name returns the handler name
+func (h *Handler) Name() string {
+	return "apt"
+}
+
+// getEffectiveBaseURL constructs the base URL for this APT handler based on:
+// - Host-based routing: uses configured host + detected scheme
+// - Path-based routing: uses request host (proxy-aware) + detected scheme
+// - Includes configured path_prefix if set
+func (h *Handler) getEffectiveBaseURL(r *http.Request) string {
+	scheme := detector.GetRequestScheme(r)
+
+	var host string
+	if h.config.Host != "" {
+		// Host-based routing: use configured host
+		host = h.config.Host
+	} else {
+		// Path-based routing: detect host from request (proxy-aware)
+		host = detector.GetRequestHost(r)
+	}
+
+	baseURL := fmt.Sprintf("%s://%s", scheme, host)
+
+	// Add path prefix if configured
+	if h.config.PathPrefix != "" {
+		baseURL += h.config.PathPrefix
+	}
+
+	return baseURL
+}
@@ -0,0 +1,64 @@
+package apt
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// addLogFields adds what the request targets to its completion log line:
+// apt_suite for indexes under dists/, and apt_package, apt_version and apt_arch for
+// packages under pool/
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	p := h.backendPath(r)
+	if suite, ok := parseSuite(p); ok {
+		middleware.AddLogField(ctx, "apt_suite", suite)
+		return
+	}
+	if name, version, arch, ok := parsePackagePath(p); ok {
+		middleware.AddLogField(ctx, "apt_package", name)
+		middleware.AddLogField(ctx, "apt_version", version)
+		middleware.AddLogField(ctx, "apt_arch", arch)
+	}
+}
+
+// parseSuite extracts the suite (distribution) from an index path
+//
+//	/dists/bookworm/InRelease                        -> bookworm
+//	/dists/stable/main/binary-amd64/Packages.gz      -> stable
+func parseSuite(p string) (string, bool) {
+	rest, found := strings.CutPrefix(p, "/dists/")
+	if !found {
+		return "", false
+	}
+	suite, _, _ := strings.Cut(rest, "/")
+	return suite, suite != ""
+}
+
+// parsePackagePath extracts the package name, version and architecture from the
+// path of a package under pool/, named <name>_<version>_<arch>.deb
+//
+//	/pool/main/n/nginx/nginx_1.24.0-1_amd64.deb      -> nginx, 1.24.0-1, amd64
+//	/pool/main/libs/libssl3_3.0.11-1~deb12u2_arm64.deb -> libssl3, 3.0.11-1~deb12u2, arm64
+func parsePackagePath(p string) (name, version, arch string, ok bool) {
+	if !strings.HasPrefix(p, "/pool/") {
+		return "", "", "", false
+	}
+
+	base := path.Base(p)
+	for _, ext := range []string{".deb", ".udeb", ".ddeb"} {
+		if trimmed, found := strings.CutSuffix(base, ext); found {
+			parts := strings.Split(trimmed, "_")
+			if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+				return "", "", "", false
+			}
+			return parts[0], parts[1], parts[2], true
+		}
+	}
+	return "", "", "", false
+}
@@ -0,0 +1,52 @@
+package apt
+
+import "testing"
+
+func TestParseSuite(t *testing.T) {
+	tests := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"/dists/bookworm/InRelease", "bookworm", true},
+		{"/dists/stable/main/binary-amd64/Packages.gz", "stable", true},
+		{"/dists/", "", false},
+		{"/pool/main/n/nginx/nginx_1.24.0-1_amd64.deb", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := parseSuite(tt.path)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseSuite(%q) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParsePackagePath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantName    string
+		wantVersion string
+		wantArch    string
+		wantOK      bool
+	}{
+		{"/pool/main/n/nginx/nginx_1.24.0-1_amd64.deb", "nginx", "1.24.0-1", "amd64", true},
+		{"/pool/main/libs/libssl3_3.0.11-1~deb12u2_arm64.deb", "libssl3", "3.0.11-1~deb12u2", "arm64", true},
+		{"/pool/main/d/debian-installer/di-utils_1.148_all.udeb", "di-utils", "1.148", "all", true},
+		{"/pool/main/n/nginx/nginx_1.24.0-1.dsc", "", "", "", false},
+		{"/pool/main/n/nginx/nginx.deb", "", "", "", false},
+		{"/dists/bookworm/nginx_1.24.0-1_amd64.deb", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			name, version, arch, ok := parsePackagePath(tt.path)
+			if name != tt.wantName || version != tt.wantVersion || arch != tt.wantArch || ok != tt.wantOK {
+				t.Errorf("parsePackagePath(%q) = %q, %q, %q, %v, want %q, %q, %q, %v",
+					tt.path, name, version, arch, ok, tt.wantName, tt.wantVersion, tt.wantArch, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package apt
+
+import (
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/metadata"
+)
+
+// SetMetadata enables recording downloaded packages in the metadata database
+func (h *Handler) SetMetadata(store *metadata.Store) {
+	h.metadata = store
+}
+
+// recordArtifact records a package download the backend answered successfully as a
+// pull of its version
+func (h *Handler) recordArtifact(r *http.Request, path string, statusCode int) {
+	if h.metadata == nil || statusCode < 200 || statusCode >= 300 || r.Method != http.MethodGet {
+		return
+	}
+	if name, version, _, ok := parsePackagePath(path); ok {
+		h.metadata.RecordPull(h.Name(), name, version, "")
+	}
+}
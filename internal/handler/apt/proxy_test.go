@@ -0,0 +1,181 @@
+package apt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metadata"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// TestSelectBackendAndProxy tests that requests are proxied to the repository below
+// the routing's prefix with the backend's credentials instead of the client's, that
+// signed indexes pass through unmodified, that redirects to the repository point at
+// the proxy, and that package downloads are recorded
+func TestSelectBackendAndProxy(t *testing.T) {
+	var gotPath, gotUser, gotPassword string
+	var repositoryURL string
+	repository := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotUser, gotPassword, _ = r.BasicAuth()
+		switch r.URL.Path {
+		case "/debian/dists/bookworm/InRelease":
+			// Signed inline, and listing backend URLs that must not be rewritten
+			_, _ = w.Write([]byte("-----BEGIN PGP SIGNED MESSAGE-----\nOrigin: " + repositoryURL + "/debian\n"))
+		case "/debian/dists/bookworm/Release.gpg":
+			_, _ = w.Write([]byte("-----BEGIN PGP SIGNATURE-----\n"))
+		case "/debian/pool/main/n/nginx/nginx_1.24.0-1_amd64.deb":
+			_, _ = w.Write([]byte("deb"))
+		case "/debian/pool/main/c/curl/curl_8.5.0-2_amd64.deb":
+			http.Redirect(w, r, repositoryURL+"/debian/pool/main/c/curl/by-hash/SHA256/abc", http.StatusFound)
+		case "/debian/pool/main/m/mirrored/mirrored_1.0_all.deb":
+			http.Redirect(w, r, "https://cdn.example.net/pool/main/m/mirrored/mirrored_1.0_all.deb", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer repository.Close()
+	repositoryURL = repository.URL
+
+	tests := []struct {
+		name         string
+		path         string
+		wantStatus   int
+		wantPath     string
+		wantBody     string
+		wantLocation string // Relative to the routing's public URL when it starts with /
+	}{
+		{
+			name:       "inline signed index",
+			path:       "/dists/bookworm/InRelease",
+			wantStatus: http.StatusOK,
+			wantPath:   "/debian/dists/bookworm/InRelease",
+			wantBody:   "-----BEGIN PGP SIGNED MESSAGE-----\nOrigin: " + repository.URL + "/debian\n",
+		},
+		{
+			name:       "detached signature",
+			path:       "/dists/bookworm/Release.gpg",
+			wantStatus: http.StatusOK,
+			wantPath:   "/debian/dists/bookworm/Release.gpg",
+			wantBody:   "-----BEGIN PGP SIGNATURE-----\n",
+		},
+		{
+			name:       "package",
+			path:       "/pool/main/n/nginx/nginx_1.24.0-1_amd64.deb",
+			wantStatus: http.StatusOK,
+			wantPath:   "/debian/pool/main/n/nginx/nginx_1.24.0-1_amd64.deb",
+			wantBody:   "deb",
+		},
+		{
+			name:         "redirect within the repository",
+			path:         "/pool/main/c/curl/curl_8.5.0-2_amd64.deb",
+			wantStatus:   http.StatusFound,
+			wantPath:     "/debian/pool/main/c/curl/curl_8.5.0-2_amd64.deb",
+			wantLocation: "/pool/main/c/curl/by-hash/SHA256/abc",
+		},
+		{
+			name:         "redirect to another host",
+			path:         "/pool/main/m/mirrored/mirrored_1.0_all.deb",
+			wantStatus:   http.StatusFound,
+			wantPath:     "/debian/pool/main/m/mirrored/mirrored_1.0_all.deb",
+			wantLocation: "https://cdn.example.net/pool/main/m/mirrored/mirrored_1.0_all.deb",
+		},
+		{
+			name:       "missing package",
+			path:       "/pool/main/m/missing/missing_1.0_amd64.deb",
+			wantStatus: http.StatusNotFound,
+			wantPath:   "/debian/pool/main/m/missing/missing_1.0_amd64.deb",
+		},
+	}
+
+	routings := []struct {
+		name      string
+		host      string
+		prefix    string
+		publicURL string
+	}{
+		{name: "path prefix", prefix: "/apt", publicURL: "https://example.com/apt"},
+		{name: "host", host: "apt.example.com", publicURL: "https://apt.example.com"},
+	}
+
+	logger := zerolog.Nop()
+	m := metrics.NewMetrics("apt_proxy_test")
+
+	for _, routing := range routings {
+		t.Run(routing.name, func(t *testing.T) {
+			cfg := &config.APTConfig{
+				Host:       routing.host,
+				PathPrefix: routing.prefix,
+				Backend: config.APTBackendConfig{
+					Name:                "debian",
+					URL:                 repository.URL + "/debian",
+					Auth:                &config.AuthConfig{Type: "basic", Username: "mirror", Password: "secret"},
+					MaxIdleConns:        1,
+					MaxIdleConnsPerHost: 1,
+					DialTimeout:         time.Second,
+					RequestTimeout:      10 * time.Second,
+				},
+			}
+			store, err := metadata.Open(&config.MetadataConfig{Path: filepath.Join(t.TempDir(), "metadata.db"), FlushInterval: time.Minute}, logger)
+			if err != nil {
+				t.Fatalf("metadata.Open() error = %v", err)
+			}
+			defer func() { _ = store.Close() }()
+
+			h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), m, logger)
+			h.SetMetadata(store)
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					gotPath, gotUser, gotPassword = "", "", ""
+					w := httptest.NewRecorder()
+					r := httptest.NewRequest(http.MethodGet, routing.prefix+tt.path, nil)
+					if routing.host != "" {
+						r.Host = routing.host
+					}
+					r.SetBasicAuth("alice", "ghp_client_token")
+					if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+						t.Fatalf("selectBackendAndProxy failed: %v", err)
+					}
+
+					if w.Code != tt.wantStatus {
+						t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+					}
+					if gotPath != tt.wantPath {
+						t.Errorf("backend path = %q, want %q", gotPath, tt.wantPath)
+					}
+					if gotUser != "mirror" || gotPassword != "secret" {
+						t.Errorf("backend credentials = %q:%q, want mirror:secret", gotUser, gotPassword)
+					}
+					if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+						t.Errorf("body = %q, want %q unmodified", w.Body.String(), tt.wantBody)
+					}
+					wantLocation := tt.wantLocation
+					if len(wantLocation) > 0 && wantLocation[0] == '/' {
+						wantLocation = routing.publicURL + wantLocation
+					}
+					if location := w.Header().Get("Location"); location != wantLocation {
+						t.Errorf("Location = %q, want %q", location, wantLocation)
+					}
+				})
+			}
+
+			// Only the package the repository served is recorded, not indexes,
+			// redirects or missing packages
+			artifacts, err := store.List(metadata.Filter{})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(artifacts) != 1 || artifacts[0].Name != "nginx" || artifacts[0].Version != "1.24.0-1" || artifacts[0].Pulls != 1 {
+				t.Errorf("recorded %+v, want nginx 1.24.0-1 pulled once", artifacts)
+			}
+		})
+	}
+}
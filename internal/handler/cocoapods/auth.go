@@ -0,0 +1,46 @@
+package cocoapods
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/auth"
+)
+
+// authenticateClient validates the client's GitHub PAT using shared authenticator.
+// CocoaPods sends the credentials of the source's host from ~/.netrc as Basic auth
+// (login = GitHub username, password = PAT), for spec repo and binary downloads alike.
+func (h *Handler) authenticateClient(r *http.Request) (*auth.AuthResult, *http.Request, error) {
+	authResult, newReq, err := h.authenticator.AuthenticateAndInjectContext(r)
+	if err != nil {
+		return nil, r, err
+	}
+
+	return authResult, newReq, nil
+}
+
+// handleAuthError returns a Basic challenge, which CocoaPods answers with the
+// credentials from ~/.netrc
+func (h *Handler) handleAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	h.logger.Warn().Err(err).
+		Str("path", r.URL.Path).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("Authentication failed")
+
+	status, message := http.StatusUnauthorized, h.config.ClientAuth.UnauthorizedMessage("Authentication required")
+	if errors.Is(err, auth.ErrInsufficientPermissions) {
+		// The credentials are valid, so challenging for others wouldn't help
+		status, message = http.StatusForbidden, auth.InsufficientPermissionsMessage
+	} else {
+		// Set WWW-Authenticate challenge headers
+		auth.SetChallenges(w.Header(), &h.config.ClientAuth, auth.ChallengeOptions{
+			Schemes: []string{auth.SchemeBasic},
+			Realm:   "Artifusion CocoaPods",
+		})
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	if _, writeErr := w.Write([]byte(message + "\n")); writeErr != nil {
+		h.logger.Error().Err(writeErr).Msg("Failed to write authentication error response")
+	}
+}
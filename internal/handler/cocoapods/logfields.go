@@ -0,0 +1,48 @@
+package cocoapods
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// addLogFields adds what the request targets to its completion log line:
+// cocoapods_pod and cocoapods_version for podspecs, and cocoapods_binary for
+// binaries
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	p := h.backendPath(r)
+	if pod, version, ok := parsePodspecPath(p); ok {
+		middleware.AddLogField(ctx, "cocoapods_pod", pod)
+		middleware.AddLogField(ctx, "cocoapods_version", version)
+		return
+	}
+	if binary, ok := parseBinaryPath(p); ok && binary != "/" {
+		middleware.AddLogField(ctx, "cocoapods_binary", strings.TrimPrefix(binary, "/"))
+	}
+}
+
+// parsePodspecPath extracts the pod and version from the path of a podspec in the
+// CDN layout, Specs/<3 shard directories>/<pod>/<version>/<pod>.podspec.json. The
+// shards are the first hex digits of the MD5 of the pod name.
+//
+//	/Specs/d/a/3/Alamofire/5.9.1/Alamofire.podspec.json  -> Alamofire, 5.9.1
+//	/Specs/0/3/f/Firebase/10.25.0/Firebase.podspec.json  -> Firebase, 10.25.0
+func parsePodspecPath(p string) (pod, version string, ok bool) {
+	rest, found := strings.CutPrefix(p, "/Specs/")
+	if !found {
+		return "", "", false
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) != 6 {
+		return "", "", false
+	}
+	pod, version = parts[3], parts[4]
+	if pod == "" || version == "" || parts[5] != pod+".podspec.json" {
+		return "", "", false
+	}
+	return pod, version, true
+}
@@ -0,0 +1,52 @@
+package cocoapods
+
+import "testing"
+
+func TestParsePodspecPath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantPod     string
+		wantVersion string
+		wantOK      bool
+	}{
+		{"/Specs/d/a/3/Alamofire/5.9.1/Alamofire.podspec.json", "Alamofire", "5.9.1", true},
+		{"/Specs/0/3/f/Firebase/10.25.0/Firebase.podspec.json", "Firebase", "10.25.0", true},
+		{"/Specs/d/a/3/Alamofire/5.9.1/Other.podspec.json", "", "", false},
+		{"/Specs/d/a/3/Alamofire/5.9.1/", "", "", false},
+		{"/all_pods_versions_d_a_3.txt", "", "", false},
+		{"/CocoaPods-version.yml", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			pod, version, ok := parsePodspecPath(tt.path)
+			if pod != tt.wantPod || version != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("parsePodspecPath() = %q, %q, %v, want %q, %q, %v",
+					pod, version, ok, tt.wantPod, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseBinaryPath(t *testing.T) {
+	tests := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"/binaries/Acme/1.0/Acme.xcframework.zip", "/Acme/1.0/Acme.xcframework.zip", true},
+		{"/binaries/", "/", true},
+		{"/binaries", "/", true},
+		{"/binariesfoo/Acme.zip", "", false},
+		{"/Specs/d/a/3/Alamofire/5.9.1/Alamofire.podspec.json", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := parseBinaryPath(tt.path)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseBinaryPath() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
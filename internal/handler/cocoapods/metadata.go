@@ -0,0 +1,41 @@
+package cocoapods
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/metadata"
+)
+
+// SetMetadata enables recording downloaded podspecs and binaries in the metadata
+// database
+func (h *Handler) SetMetadata(store *metadata.Store) {
+	h.metadata = store
+}
+
+// recordArtifact records an artifact the backend served or stored successfully:
+// podspec downloads as pulls of their pod version, and binaries, which have no
+// version, by their path below /binaries (uploads as pushes)
+func (h *Handler) recordArtifact(r *http.Request, path string, binary bool, statusCode int) {
+	if h.metadata == nil || statusCode < 200 || statusCode >= 300 {
+		return
+	}
+
+	if !binary {
+		if pod, version, ok := parsePodspecPath(path); ok && r.Method == http.MethodGet {
+			h.metadata.RecordPull(h.Name(), pod, version, "")
+		}
+		return
+	}
+
+	if strings.HasSuffix(path, "/") {
+		return
+	}
+	name := binariesPath[1:] + path
+	switch r.Method {
+	case http.MethodGet:
+		h.metadata.RecordPull(h.Name(), name, "", "")
+	case http.MethodPut:
+		h.metadata.RecordPush(h.Name(), name, "", "", metadata.NewProvenance(r))
+	}
+}
@@ -0,0 +1,91 @@
+package cocoapods
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/proxy/rewriter"
+)
+
+// proxyPassthrough proxies the request to backend and streams the response
+// unmodified: podspecs pin the checksums of their sources, and CocoaPods refreshes
+// its spec index by comparing the version lists' ETags, so bodies and validators are
+// passed through as is. publicPath is where the backend is mounted below the path
+// prefix, for redirects to be pointed at the proxy.
+func (h *Handler) proxyPassthrough(w http.ResponseWriter, r *http.Request, backend *config.CocoaPodsBackendConfig, path, publicPath string) error {
+	resp, err := h.executeProxyRequest(r, backend, path)
+	if err != nil {
+		return err
+	}
+	h.recordArtifact(r, path, publicPath != "", resp.StatusCode)
+
+	// Rewrite Location header (e.g. a CDN redirecting to a mirror)
+	proxyURL := h.getEffectiveBaseURL(r) + publicPath
+	if !rewriter.RewriteRedirectLocation(resp, backend, proxyURL) {
+		if location := resp.Headers.Get("Location"); location != "" {
+			if mapped, ok := rewriter.MapLocation(location, backend.URL, proxyURL); ok {
+				resp.Headers.Set("Location", mapped)
+			}
+		}
+	}
+
+	_, err = h.proxyClient.StreamResponse(w, resp, true)
+	return err
+}
+
+// executeProxyRequest sends the request to backend and records backend metrics,
+// returning the response without writing it
+func (h *Handler) executeProxyRequest(r *http.Request, backend *config.CocoaPodsBackendConfig, path string) (*proxy.Response, error) {
+	// Create proxy request
+	proxyReq := &proxy.Request{
+		Method:        r.Method,
+		Path:          path,
+		Query:         r.URL.RawQuery,
+		Body:          r.Body,
+		ContentLength: r.ContentLength,
+		Headers:       r.Header,
+		Backend:       backend,
+		OriginalReq:   r,
+	}
+
+	// Track backend request timing
+	start := time.Now()
+
+	// Execute proxy request
+	resp, err := h.proxyClient.ProxyRequest(proxyReq)
+
+	// Record metrics regardless of success/failure
+	duration := time.Since(start)
+
+	if err != nil {
+		// Record backend error metrics
+		h.metrics.RecordBackendError(h.Name(), backend.Name, "network_error")
+		h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+		h.metrics.SetBackendHealth(backend.Name, false)
+
+		h.logger.Error().Err(err).
+			Str("backend", backend.Name).
+			Dur("duration", duration).
+			Msg("Backend request failed")
+
+		return nil, err
+	}
+
+	// Record backend latency for all requests
+	h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+
+	// Record backend health based on status code
+	if resp.StatusCode >= 500 {
+		// Server error - backend is unhealthy
+		h.metrics.RecordBackendErrorByStatus(backend.Name, resp.StatusCode)
+		h.metrics.SetBackendHealth(backend.Name, false)
+	} else if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+		// Success - backend is healthy
+		h.metrics.SetBackendHealth(backend.Name, true)
+	}
+	// 4xx errors don't affect backend health (client errors)
+
+	return resp, nil
+}
@@ -0,0 +1,133 @@
+package cocoapods
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// TestSelectBackendAndProxy tests that spec repo requests and binaries go to their
+// backends with the backends' credentials instead of the client's
+func TestSelectBackendAndProxy(t *testing.T) {
+	var gotMethod, gotPath, gotUser, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.Path
+		gotUser, _, _ = r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		switch r.URL.Path {
+		case "/cdn/CocoaPods-version.yml", "/cdn/Specs/d/a/3/Alamofire/5.9.1/Alamofire.podspec.json",
+			"/pods/Acme/1.0/Acme.xcframework.zip":
+			_, _ = w.Write([]byte("content"))
+		case "/pods/Acme/latest.zip":
+			http.Redirect(w, r, "/pods/Acme/1.0/Acme.xcframework.zip", http.StatusFound)
+		default:
+			if r.Method != http.MethodPut {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}
+	}))
+	defer server.Close()
+
+	backend := func(name, path, username string) config.CocoaPodsBackendConfig {
+		return config.CocoaPodsBackendConfig{
+			Name:                name,
+			URL:                 server.URL + path,
+			Auth:                &config.AuthConfig{Type: "basic", Username: username, Password: "secret"},
+			MaxIdleConns:        1,
+			MaxIdleConnsPerHost: 1,
+			DialTimeout:         time.Second,
+			RequestTimeout:      10 * time.Second,
+		}
+	}
+	binaries := backend("pod-binaries", "/pods", "uploader")
+	cfg := &config.CocoaPodsConfig{
+		PathPrefix:    "/cocoapods",
+		Backend:       backend("trunk", "/cdn", "mirror"),
+		BinaryBackend: &binaries,
+	}
+
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("cocoapods_proxy_test"), logger)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		wantStatus   int
+		wantPath     string // Path requested from the backend, "" = none
+		wantUser     string
+		wantLocation string
+	}{
+		{name: "version file", method: http.MethodGet, path: "/cocoapods/CocoaPods-version.yml", wantStatus: http.StatusOK, wantPath: "/cdn/CocoaPods-version.yml", wantUser: "mirror"},
+		{
+			name:       "podspec",
+			method:     http.MethodGet,
+			path:       "/cocoapods/Specs/d/a/3/Alamofire/5.9.1/Alamofire.podspec.json",
+			wantStatus: http.StatusOK,
+			wantPath:   "/cdn/Specs/d/a/3/Alamofire/5.9.1/Alamofire.podspec.json",
+			wantUser:   "mirror",
+		},
+		{name: "spec repo upload", method: http.MethodPut, path: "/cocoapods/Specs/d/a/3/Alamofire/5.9.2/Alamofire.podspec.json", body: "{}", wantStatus: http.StatusMethodNotAllowed},
+		{name: "binary", method: http.MethodGet, path: "/cocoapods/binaries/Acme/1.0/Acme.xcframework.zip", wantStatus: http.StatusOK, wantPath: "/pods/Acme/1.0/Acme.xcframework.zip", wantUser: "uploader"},
+		{name: "binary upload", method: http.MethodPut, path: "/cocoapods/binaries/Acme/1.1/Acme.xcframework.zip", body: "zip", wantStatus: http.StatusOK, wantPath: "/pods/Acme/1.1/Acme.xcframework.zip", wantUser: "uploader"},
+		{
+			name:         "binary redirect",
+			method:       http.MethodGet,
+			path:         "/cocoapods/binaries/Acme/latest.zip",
+			wantStatus:   http.StatusFound,
+			wantPath:     "/pods/Acme/latest.zip",
+			wantUser:     "uploader",
+			wantLocation: "https://example.com/cocoapods/binaries/Acme/1.0/Acme.xcframework.zip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotMethod, gotPath, gotUser, gotBody = "", "", "", ""
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.SetBasicAuth("alice", "ghp_client_token")
+			if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+				t.Fatalf("selectBackendAndProxy failed: %v", err)
+			}
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if gotPath != tt.wantPath {
+				t.Errorf("backend path = %q, want %q", gotPath, tt.wantPath)
+			}
+			if tt.wantPath != "" && (gotMethod != tt.method || gotUser != tt.wantUser || gotBody != tt.body) {
+				t.Errorf("backend request = %s by %q with %q, want %s by %q with %q", gotMethod, gotUser, gotBody, tt.method, tt.wantUser, tt.body)
+			}
+			if location := w.Header().Get("Location"); location != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", location, tt.wantLocation)
+			}
+		})
+	}
+
+	t.Run("binary without binary backend", func(t *testing.T) {
+		cfg.BinaryBackend = nil
+		defer func() { cfg.BinaryBackend = &binaries }()
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/cocoapods/binaries/Acme/1.0/Acme.xcframework.zip", nil)
+		if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+			t.Fatalf("selectBackendAndProxy failed: %v", err)
+		}
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package cocoapods
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// binariesPath is the path below the path prefix served by the binary backend
+const binariesPath = "/binaries"
+
+// specRepoMethods are the methods the spec repo serves, as listed in the Allow
+// header of 405 responses
+var specRepoMethods = []string{http.MethodGet, http.MethodHead}
+
+// selectBackendAndProxy determines the appropriate backend and proxies the request:
+// binaries to the binary backend, which may also accept uploads, and everything
+// else to the read-only spec repo
+func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request, authResult *auth.AuthResult) error {
+	path := h.backendPath(r)
+	backend := &h.config.Backend
+	publicPath := ""
+
+	if binaryPath, ok := parseBinaryPath(path); ok {
+		if h.config.BinaryBackend == nil {
+			errors.ErrorResponse(w, errors.ErrNotFound.WithMessage("No binary backend is configured"))
+			return nil
+		}
+		backend, path, publicPath = h.config.BinaryBackend, binaryPath, binariesPath
+	} else if !slices.Contains(specRepoMethods, r.Method) {
+		w.Header().Set("Allow", strings.Join(specRepoMethods, ", "))
+		errors.ErrorResponse(w, errors.ErrMethodNotAllowed.WithMessage(fmt.Sprintf("%s is not supported by the spec repo", r.Method)))
+		return nil
+	}
+
+	h.logger.Debug().
+		Str("backend", backend.Name).
+		Str("url", backend.URL).
+		Str("path", path).
+		Str("username", authResult.Username).
+		Msg("Routing to CocoaPods backend")
+	middleware.AddLogField(r.Context(), "backend", backend.Name)
+
+	// Note: Backend authentication is handled by proxy client
+	return h.proxyPassthrough(w, r, backend, path, publicPath)
+}
+
+// backendPath returns the request path with the path prefix stripped
+func (h *Handler) backendPath(r *http.Request) string {
+	path := r.URL.Path
+	if h.config.PathPrefix != "" {
+		path = strings.TrimPrefix(path, h.config.PathPrefix)
+		// Ensure path starts with /
+		if path == "" || !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	return path
+}
+
+// parseBinaryPath returns the path of a binary on the binary backend, and false
+// for paths outside of /binaries
+//
+//	/binaries/Acme/1.0/Acme.xcframework.zip -> /Acme/1.0/Acme.xcframework.zip
+func parseBinaryPath(path string) (string, bool) {
+	if path == binariesPath {
+		return "/", true
+	}
+	rest, ok := strings.CutPrefix(path, binariesPath+"/")
+	if !ok {
+		return "", false
+	}
+	return "/" + rest, true
+}
@@ -0,0 +1,62 @@
+package composer
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// addLogFields adds the package the request targets to its completion log line:
+// composer_package for package metadata and dist archives
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	if name, ok := parsePackagePath(h.backendPath(r)); ok {
+		middleware.AddLogField(ctx, "composer_package", name)
+	}
+}
+
+// parsePackagePath extracts the vendor/package name from the path of package
+// metadata (Composer 2 p2/ files or Composer 1 provider files) or a Satis dist
+// archive.
+//
+//	/p2/monolog/monolog.json                -> monolog/monolog
+//	/p2/monolog/monolog~dev.json            -> monolog/monolog
+//	/p/acme/api$3f2a9c.json                 -> acme/api
+//	/dist/acme/api/acme-api-1.2.0-a1b2.zip  -> acme/api
+func parsePackagePath(p string) (string, bool) {
+	var rest string
+	switch {
+	case strings.HasPrefix(p, "/p2/"):
+		rest = strings.TrimPrefix(p, "/p2/")
+	case strings.HasPrefix(p, "/p/"):
+		rest = strings.TrimPrefix(p, "/p/")
+	case strings.HasPrefix(p, "/dist/"):
+		segments := strings.SplitN(strings.TrimPrefix(p, "/dist/"), "/", 3)
+		if len(segments) < 3 || segments[0] == "" || segments[1] == "" {
+			return "", false
+		}
+		return segments[0] + "/" + segments[1], true
+	default:
+		return "", false
+	}
+
+	vendor, name, found := strings.Cut(rest, "/")
+	if !found || vendor == "" || strings.Contains(name, "/") {
+		return "", false
+	}
+	name, found = strings.CutSuffix(name, ".json")
+	if !found {
+		return "", false
+	}
+	// Provider files carry a content hash, dev versions a ~dev suffix
+	if i := strings.IndexAny(name, "$~"); i >= 0 {
+		name = name[:i]
+	}
+	if name == "" {
+		return "", false
+	}
+	return vendor + "/" + name, true
+}
@@ -0,0 +1,30 @@
+package composer
+
+import "testing"
+
+func TestParsePackagePath(t *testing.T) {
+	tests := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"/p2/monolog/monolog.json", "monolog/monolog", true},
+		{"/p2/monolog/monolog~dev.json", "monolog/monolog", true},
+		{"/p/acme/api$3f2a9c.json", "acme/api", true},
+		{"/dist/acme/api/acme-api-1.2.0-a1b2c3.zip", "acme/api", true},
+		{"/packages.json", "", false},
+		{"/p/provider-latest$3f2a9c.json", "", false},
+		{"/include/all$3f2a9c.json", "", false},
+		{"/dist/acme/api", "", false},
+		{"/p2/acme/.json", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := parsePackagePath(tt.path)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parsePackagePath(%q) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package composer
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// rootPath is the repository's root metadata, listing where package metadata lives
+const rootPath = "/packages.json"
+
+// rootURLFields are the root metadata fields holding URLs. Composer resolves
+// root-relative ones against the repository's host, not its URL, so they need the
+// path prefix.
+var rootURLFields = []string{"metadata-url", "providers-url", "providers-api", "notify-batch", "search", "list"}
+
+// determineProxyURL determines the proxy URL for Composer handler
+// Constructs URL dynamically from request headers + protocol config
+// Returns the full proxy URL including the path prefix (e.g., https://php.example.com/composer)
+func (h *Handler) determineProxyURL(r *http.Request) string {
+	return h.getEffectiveBaseURL(r)
+}
+
+// rewriteBody rewrites absolute backend URLs in metadata, such as Satis dist and
+// mirror URLs, to the proxy URL. PHP escapes slashes in JSON by default, so the
+// escaped spelling is rewritten too, as are both schemes of the backend URL.
+func (h *Handler) rewriteBody(body []byte, backendURL, proxyURL string) []byte {
+	address := stripScheme(backendURL)
+	escapedAddress := strings.ReplaceAll(address, "/", `\/`)
+	escapedProxyURL := strings.ReplaceAll(proxyURL, "/", `\/`)
+
+	rewritten := body
+	for _, scheme := range []string{"http://", "https://"} {
+		rewritten = bytes.ReplaceAll(rewritten, []byte(scheme+address), []byte(proxyURL))
+		escapedScheme := strings.ReplaceAll(scheme, "/", `\/`)
+		rewritten = bytes.ReplaceAll(rewritten, []byte(escapedScheme+escapedAddress), []byte(escapedProxyURL))
+	}
+
+	if !bytes.Equal(body, rewritten) {
+		h.logger.Debug().
+			Int("original_size", len(body)).
+			Int("rewritten_size", len(rewritten)).
+			Msg("Body rewritten")
+	}
+
+	return rewritten
+}
+
+// rewriteRoot rewrites the root metadata: root-relative URLs, which Composer
+// resolves against the host, are moved below the path prefix. With cascade, the
+// private repository's list of available packages is dropped and per-package
+// lookups are enabled, so packages it doesn't have are requested (and fall through
+// to the upstream) instead of being reported missing.
+func (h *Handler) rewriteRoot(body []byte, backendURL string, cascade bool) []byte {
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(body, &root); err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to parse root metadata, serving it unmodified")
+		return body
+	}
+
+	// Path of backends served below one, which root-relative URLs include
+	backendBase := ""
+	if u, err := url.Parse(backendURL); err == nil {
+		backendBase = strings.TrimSuffix(u.Path, "/")
+	}
+	relocate := func(raw json.RawMessage) (json.RawMessage, bool) {
+		var value string
+		if json.Unmarshal(raw, &value) != nil || !strings.HasPrefix(value, "/") || strings.HasPrefix(value, "//") {
+			return raw, false
+		}
+		value = h.config.PathPrefix + strings.TrimPrefix(value, backendBase)
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return raw, false
+		}
+		return encoded, true
+	}
+
+	changed := false
+	for _, field := range rootURLFields {
+		if raw, ok := root[field]; ok {
+			if relocated, ok := relocate(raw); ok {
+				root[field] = relocated
+				changed = true
+			}
+		}
+	}
+
+	// security-advisories holds the URL of the advisories API
+	if raw, ok := root["security-advisories"]; ok {
+		var advisories map[string]json.RawMessage
+		if json.Unmarshal(raw, &advisories) == nil {
+			if relocated, ok := relocate(advisories["api-url"]); ok {
+				advisories["api-url"] = relocated
+				if encoded, err := json.Marshal(advisories); err == nil {
+					root["security-advisories"] = encoded
+					changed = true
+				}
+			}
+		}
+	}
+
+	if cascade {
+		for _, field := range []string{"available-packages", "available-package-patterns"} {
+			if _, ok := root[field]; ok {
+				delete(root, field)
+				changed = true
+			}
+		}
+		if _, ok := root["metadata-url"]; !ok {
+			root["metadata-url"], _ = json.Marshal(h.config.PathPrefix + "/p2/%package%.json")
+			changed = true
+		}
+	}
+
+	if !changed {
+		return body
+	}
+	rewritten, err := json.Marshal(root)
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to encode root metadata, serving it unmodified")
+		return body
+	}
+	return rewritten
+}
+
+// rewriteURL rewrites a single URL from backend to proxy
+func (h *Handler) rewriteURL(url, backendURL, proxyURL string) string {
+	address := stripScheme(backendURL)
+
+	for _, scheme := range []string{"http://", "https://"} {
+		if strings.HasPrefix(url, scheme+address) {
+			rewritten := proxyURL + strings.TrimPrefix(url, scheme+address)
+
+			h.logger.Debug().
+				Str("original", url).
+				Str("rewritten", rewritten).
+				Msg("URL rewritten")
+
+			return rewritten
+		}
+	}
+
+	// URL doesn't point to our backend, return unchanged
+	return url
+}
+
+// isMetadataPath reports whether path is repository metadata: the root
+// packages.json, p2/ package metadata, provider files and Satis includes
+func isMetadataPath(path string) bool {
+	return strings.HasSuffix(path, ".json")
+}
+
+// stripScheme returns a backend URL without its scheme and trailing slash, keeping
+// the path of backends served below one (e.g. a Private Packagist organization)
+// Examples:
+//   - "https://repo.packagist.com/acme/" -> "repo.packagist.com/acme"
+//   - "http://satis:8080" -> "satis:8080"
+func stripScheme(url string) string {
+	address := strings.TrimPrefix(url, "http://")
+	address = strings.TrimPrefix(address, "https://")
+	return strings.TrimSuffix(address, "/")
+}
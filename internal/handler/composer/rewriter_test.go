@@ -0,0 +1,139 @@
+package composer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func TestRewriteBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		backendURL string
+		body       string
+		want       string
+	}{
+		{
+			name:       "escaped dist URLs",
+			backendURL: "https://satis.example.org",
+			body:       `{"dist":{"url":"https:\/\/satis.example.org\/dist\/acme\/api\/api-1.2.0.zip"}}`,
+			want:       `{"dist":{"url":"https:\/\/proxy.example.com\/composer\/dist\/acme\/api\/api-1.2.0.zip"}}`,
+		},
+		{
+			name:       "unescaped dist URLs",
+			backendURL: "https://satis.example.org/",
+			body:       `{"dist":{"url":"https://satis.example.org/dist/acme/api/api-1.2.0.zip"}}`,
+			want:       `{"dist":{"url":"https://proxy.example.com/composer/dist/acme/api/api-1.2.0.zip"}}`,
+		},
+		{
+			name:       "backend advertising the other scheme",
+			backendURL: "http://satis:8080",
+			body:       `{"url":"https:\/\/satis:8080\/dist\/acme\/api\/api-1.2.0.zip"}`,
+			want:       `{"url":"https:\/\/proxy.example.com\/composer\/dist\/acme\/api\/api-1.2.0.zip"}`,
+		},
+		{
+			name:       "archives hosted elsewhere",
+			backendURL: "https://repo.packagist.org",
+			body:       `{"url":"https:\/\/api.github.com\/repos\/Seldaek\/monolog\/zipball\/5e1b2a3"}`,
+			want:       `{"url":"https:\/\/api.github.com\/repos\/Seldaek\/monolog\/zipball\/5e1b2a3"}`,
+		},
+	}
+
+	h := &Handler{logger: zerolog.Nop()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := h.rewriteBody([]byte(tt.body), tt.backendURL, "https://proxy.example.com/composer")
+			if string(got) != tt.want {
+				t.Errorf("rewriteBody() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteRoot(t *testing.T) {
+	tests := []struct {
+		name       string
+		pathPrefix string
+		backendURL string
+		cascade    bool
+		body       string
+		want       map[string]any
+	}{
+		{
+			name:       "root-relative URLs below the path prefix",
+			pathPrefix: "/composer",
+			backendURL: "https://repo.packagist.org",
+			body:       `{"metadata-url":"/p2/%package%.json","notify-batch":"https://packagist.org/downloads/","security-advisories":{"metadata":true,"api-url":"/api/security-advisories/"}}`,
+			want: map[string]any{
+				"metadata-url":        "/composer/p2/%package%.json",
+				"notify-batch":        "https://packagist.org/downloads/",
+				"security-advisories": map[string]any{"metadata": true, "api-url": "/composer/api/security-advisories/"},
+			},
+		},
+		{
+			name:       "backend served below a path",
+			pathPrefix: "/composer",
+			backendURL: "https://repo.packagist.com/acme/",
+			body:       `{"metadata-url":"/acme/p2/%package%.json"}`,
+			want:       map[string]any{"metadata-url": "/composer/p2/%package%.json"},
+		},
+		{
+			name:       "cascade drops the available packages",
+			pathPrefix: "/composer",
+			backendURL: "http://satis:8080",
+			cascade:    true,
+			body:       `{"packages":[],"metadata-url":"/p2/%package%.json","available-packages":["acme/api"],"available-package-patterns":["acme/*"]}`,
+			want: map[string]any{
+				"packages":     []any{},
+				"metadata-url": "/composer/p2/%package%.json",
+			},
+		},
+		{
+			name:       "cascade enables package lookups",
+			backendURL: "http://satis:8080",
+			cascade:    true,
+			body:       `{"packages":[],"includes":{"include/all$3f2a.json":{"sha1":"3f2a"}}}`,
+			want: map[string]any{
+				"packages":     []any{},
+				"includes":     map[string]any{"include/all$3f2a.json": map[string]any{"sha1": "3f2a"}},
+				"metadata-url": "/p2/%package%.json",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{
+				config: &config.ComposerConfig{PathPrefix: tt.pathPrefix},
+				logger: zerolog.Nop(),
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal(h.rewriteRoot([]byte(tt.body), tt.backendURL, tt.cascade), &got); err != nil {
+				t.Fatalf("rewriteRoot() returned invalid JSON: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rewriteRoot() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteRoot_Unchanged(t *testing.T) {
+	h := &Handler{
+		config: &config.ComposerConfig{},
+		logger: zerolog.Nop(),
+	}
+
+	for _, body := range []string{
+		`{"packages": {"acme/api": {}}}`,
+		`not json`,
+	} {
+		if got := h.rewriteRoot([]byte(body), "http://satis:8080", false); string(got) != body {
+			t.Errorf("rewriteRoot(%s) = %s, want it unmodified", body, got)
+		}
+	}
+}
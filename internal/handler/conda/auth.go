@@ -0,0 +1,45 @@
+package conda
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/auth"
+)
+
+// authenticateClient validates the client's GitHub PAT using shared authenticator.
+// Conda sends Basic credentials embedded in the channel URL (https://<user>:<token>@host/...).
+func (h *Handler) authenticateClient(r *http.Request) (*auth.AuthResult, *http.Request, error) {
+	authResult, newReq, err := h.authenticator.AuthenticateAndInjectContext(r)
+	if err != nil {
+		return nil, r, err
+	}
+
+	return authResult, newReq, nil
+}
+
+// handleAuthError returns a Conda-compliant error response. Conda reports the 401
+// as a channel that requires credentials.
+func (h *Handler) handleAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	h.logger.Warn().Err(err).
+		Str("path", r.URL.Path).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("Authentication failed")
+
+	status, message := http.StatusUnauthorized, h.config.ClientAuth.UnauthorizedMessage("Authentication required")
+	if errors.Is(err, auth.ErrInsufficientPermissions) {
+		// The credentials are valid, so challenging for others wouldn't help
+		status, message = http.StatusForbidden, auth.InsufficientPermissionsMessage
+	} else {
+		// Set WWW-Authenticate challenge headers
+		auth.SetChallenges(w.Header(), &h.config.ClientAuth, auth.ChallengeOptions{
+			Schemes: []string{auth.SchemeBasic},
+			Realm:   "Artifusion Conda Channel",
+		})
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	if _, writeErr := w.Write([]byte(message + "\n")); writeErr != nil {
+		h.logger.Error().Err(writeErr).Msg("Failed to write authentication error response")
+	}
+}
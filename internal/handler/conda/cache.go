@@ -0,0 +1,70 @@
+package conda
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// maxCachedResponseSize is the largest response body the response cache holds;
+// larger indexes are always streamed from the backend
+const maxCachedResponseSize = 256 << 20
+
+// memoryCacheCleanupInterval is how often expired entries leave the memory cache
+const memoryCacheCleanupInterval = time.Minute
+
+// ResponseCache stores channel index responses (repodata), so the many solves
+// fetching a channel's indexes don't each download them from the backend.
+// NewMemoryCache returns the in-process implementation; a cache shared between
+// replicas can be plugged in with Handler.SetResponseCache.
+type ResponseCache interface {
+	// Get returns the response stored under key, if it has not expired
+	Get(key string) (*CachedResponse, bool)
+
+	// Set stores resp under key for ttl
+	Set(key string, resp *CachedResponse, ttl time.Duration)
+}
+
+// CachedResponse is a successful backend response held in a ResponseCache
+type CachedResponse struct {
+	Header http.Header
+	Body   []byte
+}
+
+// memoryCache is a ResponseCache held in process memory
+type memoryCache struct {
+	cache *cache.Cache
+}
+
+// NewMemoryCache creates a ResponseCache held in process memory
+func NewMemoryCache() ResponseCache {
+	return &memoryCache{cache: cache.New(cache.NoExpiration, memoryCacheCleanupInterval)}
+}
+
+func (c *memoryCache) Get(key string) (*CachedResponse, bool) {
+	value, found := c.cache.Get(key)
+	if !found {
+		return nil, false
+	}
+	return value.(*CachedResponse), true
+}
+
+func (c *memoryCache) Set(key string, resp *CachedResponse, ttl time.Duration) {
+	c.cache.Set(key, resp, ttl)
+}
+
+// SetResponseCache enables caching the repodata of channels with a
+// repodata_cache_ttl in cache
+func (h *Handler) SetResponseCache(cache ResponseCache) {
+	h.cache = cache
+}
+
+// cachedHeaders are the response headers stored with cached responses
+var cachedHeaders = []string{"Content-Type", "Content-Encoding", "ETag", "Last-Modified", "Cache-Control"}
+
+// responseCacheKey returns the cache key of a channel index request. Backends
+// compress indexes as the client accepts, so the key includes Accept-Encoding.
+func responseCacheKey(channel, path string, r *http.Request) string {
+	return channel + "\x00" + path + "\x00" + r.Header.Get("Accept-Encoding")
+}
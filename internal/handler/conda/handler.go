@@ -0,0 +1,104 @@
+package conda
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/detector"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/metadata"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// Handler handles Conda channel requests: the repodata.json and current_repodata.json
+// indexes of each subdir, channeldata.json and package archives (.conda, .tar.bz2).
+// Each configured channel is served below its name from its own backend.
+type Handler struct {
+	config        *config.CondaConfig
+	authenticator *auth.ClientAuthenticator
+	proxyClient   *proxy.Client
+	metrics       *metrics.Metrics
+	cache         ResponseCache   // nil = disabled
+	metadata      *metadata.Store // nil = disabled
+	logger        zerolog.Logger
+}
+
+// NewHandler creates a new Conda handler
+func NewHandler(
+	cfg *config.CondaConfig,
+	authenticator *auth.ClientAuthenticator,
+	proxyClient *proxy.Client,
+	metricsCollector *metrics.Metrics,
+	logger zerolog.Logger,
+) *Handler {
+	return &Handler{
+		config:        cfg,
+		authenticator: authenticator,
+		proxyClient:   proxyClient,
+		metrics:       metricsCollector,
+		logger:        logger.With().Str("protocol", "conda").Logger(),
+	}
+}
+
+// ServeHTTP handles Conda channel requests
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug().
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Msg("Conda request received")
+
+	// Tag the request's log line with the channel and package it targets
+	h.addLogFields(r)
+
+	// Step 1: Authenticate client
+	authResult, updatedReq, err := h.authenticateClient(r)
+	if err != nil {
+		h.handleAuthError(w, r, err)
+		return
+	}
+
+	// Step 2: Proxy request to the channel's backend
+	if err := h.selectBackendAndProxy(w, updatedReq, authResult); err != nil {
+		h.logger.Error().Err(err).
+			Str("path", updatedReq.URL.Path).
+			Str("method", updatedReq.Method).
+			Msg("Failed to proxy request")
+
+		errors.ErrorResponse(w, errors.ErrInternal.WithInternal(err))
+	}
+}
+
+// Name returns the handler name
+func (h *Handler) Name() string {
+	return "conda"
+}
+
+// getEffectiveBaseURL constructs the base URL for this Conda handler based on:
+// - Host-based routing: uses configured host + detected scheme
+// - Path-based routing: uses request host (proxy-aware) + detected scheme
+// - Includes configured path_prefix if set
+func (h *Handler) getEffectiveBaseURL(r *http.Request) string {
+	scheme := detector.GetRequestScheme(r)
+
+	var host string
+	if h.config.Host != "" {
+		// Host-based routing: use configured host
+		host = h.config.Host
+	} else {
+		// Path-based routing: detect host from request (proxy-aware)
+		host = detector.GetRequestHost(r)
+	}
+
+	baseURL := fmt.Sprintf("%s://%s", scheme, host)
+
+	// Add path prefix if configured
+	if h.config.PathPrefix != "" {
+		baseURL += h.config.PathPrefix
+	}
+
+	return baseURL
+}
@@ -0,0 +1,63 @@
+package conda
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// addLogFields adds what the request targets to its completion log line:
+// conda_channel, plus conda_subdir for indexes and packages, and conda_package and
+// conda_version for package archives
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	channel, path := splitChannelPath(h.backendPath(r))
+	if channel == "" {
+		return
+	}
+	middleware.AddLogField(ctx, "conda_channel", channel)
+
+	if subdir, name, version, _, ok := parsePackagePath(path); ok {
+		middleware.AddLogField(ctx, "conda_subdir", subdir)
+		middleware.AddLogField(ctx, "conda_package", name)
+		middleware.AddLogField(ctx, "conda_version", version)
+		return
+	}
+	if subdir, _, found := strings.Cut(strings.TrimPrefix(path, "/"), "/"); found && subdir != "" {
+		middleware.AddLogField(ctx, "conda_subdir", subdir)
+	}
+}
+
+// parsePackagePath extracts the subdir, package name, version and build string from
+// the path of a package archive within a channel, named <name>-<version>-<build>.
+// Names may contain dashes; versions and build strings don't.
+//
+//	/linux-64/numpy-1.26.4-py311h64a7726_0.conda  -> linux-64, numpy, 1.26.4, py311h64a7726_0
+//	/noarch/typing-extensions-4.9.0-hd8ed1ab_0.tar.bz2 -> noarch, typing-extensions, 4.9.0, hd8ed1ab_0
+func parsePackagePath(path string) (subdir, name, version, build string, ok bool) {
+	subdir, filename, found := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if !found || subdir == "" || strings.Contains(filename, "/") {
+		return "", "", "", "", false
+	}
+
+	base, found := strings.CutSuffix(filename, ".conda")
+	if !found {
+		base, found = strings.CutSuffix(filename, ".tar.bz2")
+	}
+	if !found {
+		return "", "", "", "", false
+	}
+
+	buildIdx := strings.LastIndex(base, "-")
+	if buildIdx <= 0 {
+		return "", "", "", "", false
+	}
+	versionIdx := strings.LastIndex(base[:buildIdx], "-")
+	if versionIdx <= 0 || buildIdx == len(base)-1 || versionIdx == buildIdx-1 {
+		return "", "", "", "", false
+	}
+	return subdir, base[:versionIdx], base[versionIdx+1 : buildIdx], base[buildIdx+1:], true
+}
@@ -0,0 +1,53 @@
+package conda
+
+import "testing"
+
+func TestParsePackagePath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantSubdir  string
+		wantName    string
+		wantVersion string
+		wantBuild   string
+		wantOK      bool
+	}{
+		{"/linux-64/numpy-1.26.4-py311h64a7726_0.conda", "linux-64", "numpy", "1.26.4", "py311h64a7726_0", true},
+		{"/noarch/typing-extensions-4.9.0-hd8ed1ab_0.tar.bz2", "noarch", "typing-extensions", "4.9.0", "hd8ed1ab_0", true},
+		{"/osx-arm64/r-base-4.3.2-h3ae1c1a_1.conda", "osx-arm64", "r-base", "4.3.2", "h3ae1c1a_1", true},
+		{"/linux-64/repodata.json", "", "", "", "", false},
+		{"/channeldata.json", "", "", "", "", false},
+		{"/linux-64/numpy-1.26.4.conda", "", "", "", "", false},
+		{"/linux-64/-1.0-0.conda", "", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			subdir, name, version, build, ok := parsePackagePath(tt.path)
+			if subdir != tt.wantSubdir || name != tt.wantName || version != tt.wantVersion || build != tt.wantBuild || ok != tt.wantOK {
+				t.Errorf("parsePackagePath(%q) = %q, %q, %q, %q, %v, want %q, %q, %q, %q, %v",
+					tt.path, subdir, name, version, build, ok,
+					tt.wantSubdir, tt.wantName, tt.wantVersion, tt.wantBuild, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSplitChannelPath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantChannel string
+		wantRest    string
+	}{
+		{"/conda-forge/linux-64/repodata.json", "conda-forge", "/linux-64/repodata.json"},
+		{"/bioconda/channeldata.json", "bioconda", "/channeldata.json"},
+		{"/bioconda", "bioconda", "/"},
+		{"/", "", "/"},
+	}
+
+	for _, tt := range tests {
+		channel, rest := splitChannelPath(tt.path)
+		if channel != tt.wantChannel || rest != tt.wantRest {
+			t.Errorf("splitChannelPath(%q) = %q, %q, want %q, %q", tt.path, channel, rest, tt.wantChannel, tt.wantRest)
+		}
+	}
+}
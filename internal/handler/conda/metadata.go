@@ -0,0 +1,24 @@
+package conda
+
+import (
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/metadata"
+)
+
+// SetMetadata enables recording downloaded packages in the metadata database
+func (h *Handler) SetMetadata(store *metadata.Store) {
+	h.metadata = store
+}
+
+// recordArtifact records a package download the backend answered successfully as a
+// pull of its version. Packages are recorded as <channel>/<name>, as the same name
+// may come from several channels.
+func (h *Handler) recordArtifact(r *http.Request, channel, path string, statusCode int) {
+	if h.metadata == nil || statusCode < 200 || statusCode >= 300 || r.Method != http.MethodGet {
+		return
+	}
+	if _, name, version, _, ok := parsePackagePath(path); ok {
+		h.metadata.RecordPull(h.Name(), channel+"/"+name, version, "")
+	}
+}
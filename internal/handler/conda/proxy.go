@@ -0,0 +1,196 @@
+package conda
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/proxy/rewriter"
+)
+
+// repodataFiles are the channel indexes of a subdir the response cache may hold
+var repodataFiles = []string{"repodata.json", "current_repodata.json", "repodata.json.zst", "repodata.json.bz2"}
+
+// proxyChannel proxies the request to the channel's backend. Repodata references
+// packages by file name relative to its subdir, so bodies are streamed unmodified
+// and only redirects need to point at the proxy.
+func (h *Handler) proxyChannel(w http.ResponseWriter, r *http.Request, channel *config.CondaChannelConfig, path string) error {
+	if h.cacheable(r, channel, path) {
+		return h.proxyCached(w, r, channel, path)
+	}
+
+	backend := &channel.Backend
+	resp, err := h.executeProxyRequest(r, backend, path)
+	if err != nil {
+		return err
+	}
+	h.recordArtifact(r, channel.Name, path, resp.StatusCode)
+	h.rewriteLocation(r, resp, channel)
+
+	_, err = h.proxyClient.StreamResponse(w, resp, true)
+	return err
+}
+
+// cacheable reports whether the request reads a repodata index of a channel whose
+// indexes are cached
+func (h *Handler) cacheable(r *http.Request, channel *config.CondaChannelConfig, path string) bool {
+	if h.cache == nil || channel.RepodataCacheTTL <= 0 {
+		return false
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	for _, file := range repodataFiles {
+		if strings.HasSuffix(path, "/"+file) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyCached serves a repodata index from the response cache, fetching and storing
+// it on a miss. Conditional requests are answered from the cached validators, so
+// conda's revalidation of its local index copy doesn't reach the backend either.
+func (h *Handler) proxyCached(w http.ResponseWriter, r *http.Request, channel *config.CondaChannelConfig, path string) error {
+	key := responseCacheKey(channel.Name, path, r)
+	if cached, found := h.cache.Get(key); found {
+		h.metrics.RecordResponseCache(h.Name(), "hit")
+		middleware.AddLogField(r.Context(), "cache", "hit")
+		serveCached(w, r, cached)
+		return nil
+	}
+	h.metrics.RecordResponseCache(h.Name(), "miss")
+	middleware.AddLogField(r.Context(), "cache", "miss")
+
+	// Fetch the full index to cache; the client's validators are checked against it
+	backendReq := r.Clone(r.Context())
+	backendReq.Method = http.MethodGet
+	backendReq.Header.Del("If-None-Match")
+	backendReq.Header.Del("If-Modified-Since")
+	backendReq.Header.Del("Range")
+
+	resp, err := h.executeProxyRequest(backendReq, &channel.Backend, path)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		h.rewriteLocation(r, resp, channel)
+		_, err = h.proxyClient.StreamResponse(w, resp, true)
+		return err
+	}
+
+	// Indexes too large to cache are streamed
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedResponseSize+1))
+	if err != nil {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			h.logger.Warn().Err(closeErr).Msg("Failed to close response body")
+		}
+		return err
+	}
+	if len(body) > maxCachedResponseSize {
+		h.logger.Debug().
+			Str("channel", channel.Name).
+			Str("path", path).
+			Msg("Repodata too large to cache, streaming it")
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		_, err = h.proxyClient.StreamResponse(w, resp, true)
+		return err
+	}
+	if err := resp.Body.Close(); err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to close response body")
+	}
+
+	cached := &CachedResponse{Header: make(http.Header), Body: body}
+	for _, name := range cachedHeaders {
+		if value := resp.Headers.Get(name); value != "" {
+			cached.Header.Set(name, value)
+		}
+	}
+	h.cache.Set(key, cached, channel.RepodataCacheTTL)
+
+	serveCached(w, r, cached)
+	return nil
+}
+
+// serveCached writes a cached response, answering conditional and range requests
+func serveCached(w http.ResponseWriter, r *http.Request, cached *CachedResponse) {
+	for name, values := range cached.Header {
+		w.Header()[name] = values
+	}
+	modTime, _ := http.ParseTime(cached.Header.Get("Last-Modified"))
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(cached.Body))
+}
+
+// rewriteLocation points redirects to the channel's backend at the proxy
+func (h *Handler) rewriteLocation(r *http.Request, resp *proxy.Response, channel *config.CondaChannelConfig) {
+	proxyURL := h.getEffectiveBaseURL(r) + "/" + channel.Name
+	if !rewriter.RewriteRedirectLocation(resp, &channel.Backend, proxyURL) {
+		if location := resp.Headers.Get("Location"); location != "" {
+			if mapped, ok := rewriter.MapLocation(location, channel.Backend.URL, proxyURL); ok {
+				resp.Headers.Set("Location", mapped)
+			}
+		}
+	}
+}
+
+// executeProxyRequest sends the request to backend and records backend metrics,
+// returning the response without writing it
+func (h *Handler) executeProxyRequest(r *http.Request, backend *config.CondaBackendConfig, path string) (*proxy.Response, error) {
+	// Create proxy request
+	proxyReq := &proxy.Request{
+		Method:      r.Method,
+		Path:        path,
+		Query:       r.URL.RawQuery,
+		Body:        r.Body,
+		Headers:     r.Header,
+		Backend:     backend,
+		OriginalReq: r,
+	}
+
+	// Track backend request timing
+	start := time.Now()
+
+	// Execute proxy request
+	resp, err := h.proxyClient.ProxyRequest(proxyReq)
+
+	// Record metrics regardless of success/failure
+	duration := time.Since(start)
+
+	if err != nil {
+		// Record backend error metrics
+		h.metrics.RecordBackendError(h.Name(), backend.Name, "network_error")
+		h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+		h.metrics.SetBackendHealth(backend.Name, false)
+
+		h.logger.Error().Err(err).
+			Str("backend", backend.Name).
+			Dur("duration", duration).
+			Msg("Backend request failed")
+
+		return nil, err
+	}
+
+	// Record backend latency for all requests
+	h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+
+	// Record backend health based on status code
+	if resp.StatusCode >= 500 {
+		// Server error - backend is unhealthy
+		h.metrics.RecordBackendErrorByStatus(backend.Name, resp.StatusCode)
+		h.metrics.SetBackendHealth(backend.Name, false)
+	} else if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+		// Success - backend is healthy
+		h.metrics.SetBackendHealth(backend.Name, true)
+	}
+	// 4xx errors don't affect backend health (client errors)
+
+	return resp, nil
+}
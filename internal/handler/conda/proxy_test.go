@@ -0,0 +1,97 @@
+package conda
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// TestSelectBackendAndProxy_RepodataCache tests that repodata is served from the
+// response cache once fetched, including conditional requests, while packages are
+// always proxied
+func TestSelectBackendAndProxy_RepodataCache(t *testing.T) {
+	var requests atomic.Int32
+	channel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/conda-forge/noarch/repodata.json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"abc"`)
+			_, _ = w.Write([]byte(`{"info":{"subdir":"noarch"},"packages":{}}`))
+		case "/conda-forge/noarch/six-1.16.0-pyh6c4a22f_0.tar.bz2":
+			_, _ = w.Write([]byte("package"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer channel.Close()
+
+	cfg := &config.CondaConfig{
+		PathPrefix: "/conda",
+		Channels: []config.CondaChannelConfig{{
+			Name: "conda-forge",
+			Backend: config.CondaBackendConfig{
+				Name:                "conda-forge",
+				URL:                 channel.URL + "/conda-forge",
+				MaxIdleConns:        1,
+				MaxIdleConnsPerHost: 1,
+				DialTimeout:         time.Second,
+				RequestTimeout:      10 * time.Second,
+			},
+			RepodataCacheTTL: time.Minute,
+		}},
+	}
+
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("conda_cache_test"), logger)
+	h.SetResponseCache(NewMemoryCache())
+
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		w := serve("/conda/conda-forge/noarch/repodata.json", nil)
+		if w.Code != http.StatusOK || w.Body.String() != `{"info":{"subdir":"noarch"},"packages":{}}` {
+			t.Errorf("repodata request %d = %d %s", i+1, w.Code, w.Body.String())
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("backend received %d repodata requests, want 1", n)
+	}
+
+	w := serve("/conda/conda-forge/noarch/repodata.json", http.Header{"If-None-Match": {`"abc"`}})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("conditional repodata request status = %d, want %d", w.Code, http.StatusNotModified)
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := serve("/conda/conda-forge/noarch/six-1.16.0-pyh6c4a22f_0.tar.bz2", nil); w.Code != http.StatusOK {
+			t.Errorf("package request status = %d, want %d", w.Code, http.StatusOK)
+		}
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("backend received %d requests, want 3 (packages are not cached)", n)
+	}
+
+	if w := serve("/conda/bioconda/noarch/repodata.json", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown channel status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,75 @@
+package conda
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// selectBackendAndProxy determines the channel the request targets and proxies it to
+// the channel's backend
+func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request, authResult *auth.AuthResult) error {
+	name, path := splitChannelPath(h.backendPath(r))
+	channel := h.channel(name)
+	if channel == nil {
+		errors.ErrorResponse(w, errors.ErrNotFound.WithMessage(fmt.Sprintf("Unknown Conda channel %q", name)))
+		return nil
+	}
+	backend := &channel.Backend
+
+	// Log operation type for debugging
+	operationType := "read"
+	if auth.IsWriteMethod(r.Method) {
+		operationType = "write"
+	}
+
+	h.logger.Debug().
+		Str("channel", channel.Name).
+		Str("backend", backend.Name).
+		Str("url", backend.URL).
+		Str("operation", operationType).
+		Str("username", authResult.Username).
+		Msg("Routing to Conda channel backend")
+	middleware.AddLogField(r.Context(), "backend", backend.Name)
+
+	// Note: Backend authentication is handled by proxy client
+	return h.proxyChannel(w, r, channel, path)
+}
+
+// channel returns the configured channel called name, or nil
+func (h *Handler) channel(name string) *config.CondaChannelConfig {
+	for i := range h.config.Channels {
+		if h.config.Channels[i].Name == name {
+			return &h.config.Channels[i]
+		}
+	}
+	return nil
+}
+
+// backendPath returns the request path with the path prefix stripped
+func (h *Handler) backendPath(r *http.Request) string {
+	path := r.URL.Path
+	if h.config.PathPrefix != "" {
+		path = strings.TrimPrefix(path, h.config.PathPrefix)
+		// Ensure path starts with /
+		if path == "" || !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	return path
+}
+
+// splitChannelPath splits a path below the path prefix into the channel name and
+// the path within the channel
+//
+//	/conda-forge/linux-64/repodata.json  -> conda-forge, /linux-64/repodata.json
+//	/bioconda                            -> bioconda, /
+func splitChannelPath(path string) (channel, rest string) {
+	channel, rest, _ = strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return channel, "/" + rest
+}
@@ -0,0 +1,121 @@
+package helm
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/detector"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/metadata"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// Handler handles Helm chart repository requests: classic repositories (index.yaml
+// and chart archives) are proxied to the backend, OCI-based charts are passed to
+// the OCI handler
+type Handler struct {
+	config        *config.HelmConfig
+	authenticator *auth.ClientAuthenticator
+	proxyClient   *proxy.Client
+	metrics       *metrics.Metrics
+	metadata      *metadata.Store // nil = disabled
+	oci           http.Handler    // nil = OCI protocol disabled
+	logger        zerolog.Logger
+}
+
+// NewHandler creates a new Helm handler
+func NewHandler(
+	cfg *config.HelmConfig,
+	authenticator *auth.ClientAuthenticator,
+	proxyClient *proxy.Client,
+	metricsCollector *metrics.Metrics,
+	logger zerolog.Logger,
+) *Handler {
+	return &Handler{
+		config:        cfg,
+		authenticator: authenticator,
+		proxyClient:   proxyClient,
+		metrics:       metricsCollector,
+		logger:        logger.With().Str("protocol", "helm").Logger(),
+	}
+}
+
+// SetOCIHandler sets the handler serving OCI-based charts (helm pull oci://...)
+// requested on the Helm host. The OCI handler authenticates them itself.
+func (h *Handler) SetOCIHandler(oci http.Handler) {
+	h.oci = oci
+}
+
+// ServeHTTP handles Helm chart repository requests
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug().
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Msg("Helm request received")
+
+	// OCI-based charts use the OCI Distribution API, always rooted at /v2
+	if r.URL.Path == "/v2" || strings.HasPrefix(r.URL.Path, "/v2/") {
+		if h.oci == nil {
+			errors.ErrorResponse(w, errors.ErrNotFound.WithMessage("OCI-based charts require the OCI protocol to be enabled"))
+			return
+		}
+		h.oci.ServeHTTP(w, r)
+		return
+	}
+
+	// Tag the request's log line with the chart it targets
+	h.addLogFields(r)
+
+	// Step 1: Authenticate client
+	authResult, updatedReq, err := h.authenticateClient(r)
+	if err != nil {
+		h.handleAuthError(w, r, err)
+		return
+	}
+
+	// Step 2: Proxy request to the backend
+	if err := h.selectBackendAndProxy(w, updatedReq, authResult); err != nil {
+		h.logger.Error().Err(err).
+			Str("path", updatedReq.URL.Path).
+			Str("method", updatedReq.Method).
+			Msg("Failed to proxy request")
+
+		errors.ErrorResponse(w, errors.ErrInternal.WithInternal(err))
+	}
+}
+
+// Name returns the handler name
+func (h *Handler) Name() string {
+	return "helm"
+}
+
+// getEffectiveBaseURL constructs the base URL for this Helm handler based on:
+// - Host-based routing: uses configured host + detected scheme
+// - Path-based routing: uses request host (proxy-aware) + detected scheme
+// - Includes configured path_prefix if set
+func (h *Handler) getEffectiveBaseURL(r *http.Request) string {
+	scheme := detector.GetRequestScheme(r)
+
+	var host string
+	if h.config.Host != "" {
+		// Host-based routing: use configured host
+		host = h.config.Host
+	} else {
+		// Path-based routing: detect host from request (proxy-aware)
+		host = detector.GetRequestHost(r)
+	}
+
+	baseURL := fmt.Sprintf("%s://%s", scheme, host)
+
+	// Add path prefix if configured
+	if h.config.PathPrefix != "" {
+		baseURL += h.config.PathPrefix
+	}
+
+	return baseURL
+}
@@ -0,0 +1,54 @@
+package helm
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// addLogFields adds the chart the request targets to its completion log line:
+// helm_chart and helm_version for chart archives and provenance files
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	name, version, ok := parseChartPath(h.backendPath(r))
+	if !ok {
+		return
+	}
+	middleware.AddLogField(ctx, "helm_chart", name)
+	middleware.AddLogField(ctx, "helm_version", version)
+}
+
+// parseChartPath extracts the chart name and version from the path of a chart
+// archive or its provenance file. Repositories place archives anywhere, so only the
+// file name is considered.
+//
+//	/nginx-15.4.2.tgz                    -> nginx, 15.4.2
+//	/charts/cert-manager-v1.14.4.tgz     -> cert-manager, v1.14.4
+//	/api/charts/app-1.0.0-rc.1.tgz.prov  -> app, 1.0.0-rc.1
+func parseChartPath(p string) (name, version string, ok bool) {
+	filename := path.Base(p)
+	base, found := strings.CutSuffix(filename, ".tgz.prov")
+	if !found {
+		base, found = strings.CutSuffix(filename, ".tgz")
+	}
+	if !found {
+		return "", "", false
+	}
+
+	// Chart names may contain dashes; the version starts at the first dash followed
+	// by a digit (or a "v" and a digit) and may itself contain dashes
+	for i := 1; i < len(base)-1; i++ {
+		if base[i] != '-' {
+			continue
+		}
+		rest := strings.TrimPrefix(base[i+1:], "v")
+		if rest != "" && rest[0] >= '0' && rest[0] <= '9' {
+			return base[:i], base[i+1:], true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,31 @@
+package helm
+
+import "testing"
+
+func TestParseChartPath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantName    string
+		wantVersion string
+		wantOK      bool
+	}{
+		{"/nginx-15.4.2.tgz", "nginx", "15.4.2", true},
+		{"/charts/cert-manager-v1.14.4.tgz", "cert-manager", "v1.14.4", true},
+		{"/charts/kube-prometheus-stack-57.0.1.tgz", "kube-prometheus-stack", "57.0.1", true},
+		{"/app-1.0.0-rc.1.tgz", "app", "1.0.0-rc.1", true},
+		{"/nginx-15.4.2.tgz.prov", "nginx", "15.4.2", true},
+		{"/index.yaml", "", "", false},
+		{"/nginx.tgz", "", "", false},
+		{"/-1.0.0.tgz", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			name, version, ok := parseChartPath(tt.path)
+			if name != tt.wantName || version != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("parseChartPath(%q) = %q, %q, %v, want %q, %q, %v",
+					tt.path, name, version, ok, tt.wantName, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package helm
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/metadata"
+)
+
+// SetMetadata enables recording downloaded charts in the metadata database
+func (h *Handler) SetMetadata(store *metadata.Store) {
+	h.metadata = store
+}
+
+// recordArtifact records a chart archive download the backend answered successfully
+// as a pull of its version. Uploads (ChartMuseum's POST /api/charts) carry the
+// chart's name and version inside the archive, so they are not recorded.
+func (h *Handler) recordArtifact(r *http.Request, path string, statusCode int) {
+	if h.metadata == nil || statusCode < 200 || statusCode >= 300 || r.Method != http.MethodGet {
+		return
+	}
+	if !strings.HasSuffix(path, ".tgz") {
+		return
+	}
+	if name, version, ok := parseChartPath(path); ok {
+		h.metadata.RecordPull(h.Name(), name, version, "")
+	}
+}
@@ -0,0 +1,72 @@
+package helm
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// determineProxyURL determines the proxy URL for Helm handler
+// Constructs URL dynamically from request headers + protocol config
+// Returns the full proxy URL including the path prefix (e.g., https://charts.example.com/helm)
+func (h *Handler) determineProxyURL(r *http.Request) string {
+	return h.getEffectiveBaseURL(r)
+}
+
+// rewriteBody rewrites backend URLs in a repository index, i.e. the absolute chart
+// archive URLs listed under urls, to the proxy URL. Relative URLs already resolve
+// against the proxy. Both schemes of the backend URL are rewritten, since backends
+// behind a TLS terminator often advertise https while being reached over http.
+func (h *Handler) rewriteBody(body []byte, backendURL, proxyURL string) []byte {
+	address := stripScheme(backendURL)
+
+	rewritten := bytes.ReplaceAll(body, []byte("http://"+address), []byte(proxyURL))
+	rewritten = bytes.ReplaceAll(rewritten, []byte("https://"+address), []byte(proxyURL))
+
+	if !bytes.Equal(body, rewritten) {
+		h.logger.Debug().
+			Int("original_size", len(body)).
+			Int("rewritten_size", len(rewritten)).
+			Msg("Body rewritten")
+	}
+
+	return rewritten
+}
+
+// rewriteURL rewrites a single URL from backend to proxy
+func (h *Handler) rewriteURL(url, backendURL, proxyURL string) string {
+	address := stripScheme(backendURL)
+
+	for _, scheme := range []string{"http://", "https://"} {
+		if strings.HasPrefix(url, scheme+address) {
+			rewritten := proxyURL + strings.TrimPrefix(url, scheme+address)
+
+			h.logger.Debug().
+				Str("original", url).
+				Str("rewritten", rewritten).
+				Msg("URL rewritten")
+
+			return rewritten
+		}
+	}
+
+	// URL doesn't point to our backend, return unchanged
+	return url
+}
+
+// isIndexPath reports whether path is a repository index. Repositories may be
+// served below a path (e.g. /stable/index.yaml).
+func isIndexPath(path string) bool {
+	return strings.HasSuffix(path, "/index.yaml")
+}
+
+// stripScheme returns a backend URL without its scheme and trailing slash, keeping
+// the path of backends served below one (e.g. a Nexus repository)
+// Examples:
+//   - "https://charts.bitnami.com/bitnami/" -> "charts.bitnami.com/bitnami"
+//   - "http://chartmuseum:8080" -> "chartmuseum:8080"
+func stripScheme(url string) string {
+	address := strings.TrimPrefix(url, "http://")
+	address = strings.TrimPrefix(address, "https://")
+	return strings.TrimSuffix(address, "/")
+}
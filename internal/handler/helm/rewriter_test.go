@@ -0,0 +1,69 @@
+package helm
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRewriteBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		backendURL string
+		body       string
+		want       string
+	}{
+		{
+			name:       "absolute chart URLs",
+			backendURL: "https://charts.example.org/stable",
+			body:       "entries:\n  nginx:\n  - urls:\n    - https://charts.example.org/stable/nginx-15.4.2.tgz\n",
+			want:       "entries:\n  nginx:\n  - urls:\n    - https://proxy.example.com/helm/nginx-15.4.2.tgz\n",
+		},
+		{
+			name:       "backend advertising the other scheme",
+			backendURL: "http://chartmuseum:8080/",
+			body:       "    - https://chartmuseum:8080/charts/app-1.0.0.tgz\n",
+			want:       "    - https://proxy.example.com/helm/charts/app-1.0.0.tgz\n",
+		},
+		{
+			name:       "relative chart URLs",
+			backendURL: "http://chartmuseum:8080",
+			body:       "    - charts/app-1.0.0.tgz\n",
+			want:       "    - charts/app-1.0.0.tgz\n",
+		},
+		{
+			name:       "charts hosted elsewhere",
+			backendURL: "http://chartmuseum:8080",
+			body:       "    - https://github.com/org/charts/releases/download/app-1.0.0/app-1.0.0.tgz\n",
+			want:       "    - https://github.com/org/charts/releases/download/app-1.0.0/app-1.0.0.tgz\n",
+		},
+	}
+
+	h := &Handler{logger: zerolog.Nop()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := h.rewriteBody([]byte(tt.body), tt.backendURL, "https://proxy.example.com/helm")
+			if string(got) != tt.want {
+				t.Errorf("rewriteBody() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsIndexPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/index.yaml", true},
+		{"/stable/index.yaml", true},
+		{"/nginx-15.4.2.tgz", false},
+		{"/api/charts", false},
+	}
+
+	for _, tt := range tests {
+		if got := isIndexPath(tt.path); got != tt.want {
+			t.Errorf("isIndexPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package hex
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+)
+
+// authenticateClient validates the client's GitHub PAT using shared authenticator.
+// Hex sends the repository's auth key (mix hex.repo add --auth-key) and its API key
+// as the bare Authorization header value, so a header without a scheme is treated
+// as a bearer token.
+func (h *Handler) authenticateClient(r *http.Request) (*auth.AuthResult, *http.Request, error) {
+	if value := r.Header.Get("Authorization"); value != "" && !strings.Contains(value, " ") {
+		r.Header.Set("Authorization", "Bearer "+value)
+	}
+
+	authResult, newReq, err := h.authenticator.AuthenticateAndInjectContext(r)
+	if err != nil {
+		return nil, r, err
+	}
+
+	return authResult, newReq, nil
+}
+
+// handleAuthError returns a Hex-compliant error response: mix prints the message of
+// the API's JSON error body
+func (h *Handler) handleAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	h.logger.Warn().Err(err).
+		Str("path", r.URL.Path).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("Authentication failed")
+
+	status, message := http.StatusUnauthorized, h.config.ClientAuth.UnauthorizedMessage("Authentication required: use a GitHub token as the repository's auth key")
+	if errors.Is(err, auth.ErrInsufficientPermissions) {
+		// The credentials are valid, so challenging for others wouldn't help
+		status, message = http.StatusForbidden, auth.InsufficientPermissionsMessage
+	} else {
+		// Set WWW-Authenticate challenge headers
+		auth.SetChallenges(w.Header(), &h.config.ClientAuth, auth.ChallengeOptions{
+			Schemes: []string{auth.SchemeBasic},
+			Realm:   "Artifusion Hex Repository",
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if encodeErr := json.NewEncoder(w).Encode(map[string]any{
+		"status":  status,
+		"message": message,
+	}); encodeErr != nil {
+		h.logger.Error().Err(encodeErr).Msg("Failed to write authentication error response")
+	}
+}
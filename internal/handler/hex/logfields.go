@@ -0,0 +1,46 @@
+package hex
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// addLogFields adds the package the request targets to its completion log line:
+// hex_package for package resources and tarballs, and hex_version for tarballs
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	p := h.backendPath(r)
+	if name, version, ok := parseTarballPath(p); ok {
+		middleware.AddLogField(ctx, "hex_package", name)
+		middleware.AddLogField(ctx, "hex_version", version)
+		return
+	}
+	if name, ok := strings.CutPrefix(p, "/packages/"); ok && name != "" && !strings.Contains(name, "/") {
+		middleware.AddLogField(ctx, "hex_package", name)
+	}
+}
+
+// parseTarballPath extracts the package and version from the path of a tarball.
+// Package names can't contain dashes, so the name ends at the first one.
+//
+//	/tarballs/jason-1.4.1.tar          -> jason, 1.4.1
+//	/tarballs/phoenix-1.8.0-rc.0.tar   -> phoenix, 1.8.0-rc.0
+func parseTarballPath(p string) (name, version string, ok bool) {
+	file, found := strings.CutPrefix(p, "/tarballs/")
+	if !found || strings.Contains(file, "/") {
+		return "", "", false
+	}
+	file, found = strings.CutSuffix(file, ".tar")
+	if !found {
+		return "", "", false
+	}
+	name, version, found = strings.Cut(file, "-")
+	if !found || name == "" || version == "" {
+		return "", "", false
+	}
+	return name, version, true
+}
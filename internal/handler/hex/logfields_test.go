@@ -0,0 +1,30 @@
+package hex
+
+import "testing"
+
+func TestParseTarballPath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantName    string
+		wantVersion string
+		wantOK      bool
+	}{
+		{"/tarballs/jason-1.4.1.tar", "jason", "1.4.1", true},
+		{"/tarballs/phoenix-1.8.0-rc.0.tar", "phoenix", "1.8.0-rc.0", true},
+		{"/tarballs/acme_client-0.1.0.tar", "acme_client", "0.1.0", true},
+		{"/tarballs/jason.tar", "", "", false},
+		{"/tarballs/jason-1.4.1.tar.gz", "", "", false},
+		{"/tarballs/nested/jason-1.4.1.tar", "", "", false},
+		{"/packages/jason", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			name, version, ok := parseTarballPath(tt.path)
+			if name != tt.wantName || version != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("parseTarballPath() = %q, %q, %v, want %q, %q, %v",
+					name, version, ok, tt.wantName, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package hex
+
+import (
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/metadata"
+)
+
+// SetMetadata enables recording downloaded tarballs in the metadata database
+func (h *Handler) SetMetadata(store *metadata.Store) {
+	h.metadata = store
+}
+
+// recordPulled records a tarball the repository or upstream served successfully as
+// a pull of its package version. Publishes aren't recorded: the package and version
+// are only known from inside the uploaded tarball.
+func (h *Handler) recordPulled(r *http.Request, path string, statusCode int) {
+	if h.metadata == nil || r.Method != http.MethodGet || statusCode != http.StatusOK {
+		return
+	}
+	if name, version, ok := parseTarballPath(path); ok {
+		h.metadata.RecordPull(h.Name(), name, version, "")
+	}
+}
@@ -0,0 +1,125 @@
+package hex
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/proxy/rewriter"
+)
+
+// proxyRepository proxies a repository read to backend, cascading package resources
+// and tarballs it answers with 404 to the upstream. Responses are streamed
+// unmodified: registry resources are signed by the repository that serves them.
+func (h *Handler) proxyRepository(w http.ResponseWriter, r *http.Request, backend *config.HexBackendConfig, path string) error {
+	resp, err := h.executeProxyRequest(r, backend, path)
+	if err != nil {
+		return err
+	}
+
+	// Cascade packages the private repository doesn't have to the public one
+	if upstream := h.config.Upstream; upstream != nil && resp.StatusCode == http.StatusNotFound && isPackagePath(path) {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			h.logger.Warn().Err(closeErr).Msg("Failed to close response body")
+		}
+
+		h.logger.Debug().
+			Str("backend", backend.Name).
+			Str("upstream", upstream.Name).
+			Str("path", path).
+			Msg("Not found in backend, trying upstream")
+
+		resp, err = h.executeProxyRequest(r, upstream, path)
+		if err != nil {
+			return err
+		}
+		backend = upstream
+	}
+	h.recordPulled(r, path, resp.StatusCode)
+
+	return h.streamResponse(w, r, resp, backend, "")
+}
+
+// proxyAPI proxies an API request, such as a publish, to the publish backend
+func (h *Handler) proxyAPI(w http.ResponseWriter, r *http.Request, backend *config.HexBackendConfig, path string) error {
+	resp, err := h.executeProxyRequest(r, backend, path)
+	if err != nil {
+		return err
+	}
+
+	return h.streamResponse(w, r, resp, backend, apiPath)
+}
+
+// streamResponse streams the response of backend to the client. publicPath is where
+// the backend is mounted below the path prefix, for redirects to be pointed at the
+// proxy.
+func (h *Handler) streamResponse(w http.ResponseWriter, r *http.Request, resp *proxy.Response, backend *config.HexBackendConfig, publicPath string) error {
+	// Rewrite Location header (e.g. a repository redirecting tarballs to storage)
+	proxyURL := h.getEffectiveBaseURL(r) + publicPath
+	if !rewriter.RewriteRedirectLocation(resp, backend, proxyURL) {
+		if location := resp.Headers.Get("Location"); location != "" {
+			if mapped, ok := rewriter.MapLocation(location, backend.URL, proxyURL); ok {
+				resp.Headers.Set("Location", mapped)
+			}
+		}
+	}
+
+	_, err := h.proxyClient.StreamResponse(w, resp, true)
+	return err
+}
+
+// executeProxyRequest sends the request to backend and records backend metrics,
+// returning the response without writing it
+func (h *Handler) executeProxyRequest(r *http.Request, backend *config.HexBackendConfig, path string) (*proxy.Response, error) {
+	// Create proxy request
+	proxyReq := &proxy.Request{
+		Method:        r.Method,
+		Path:          path,
+		Query:         r.URL.RawQuery,
+		Body:          r.Body,
+		ContentLength: r.ContentLength,
+		Headers:       r.Header,
+		Backend:       backend,
+		OriginalReq:   r,
+	}
+
+	// Track backend request timing
+	start := time.Now()
+
+	// Execute proxy request
+	resp, err := h.proxyClient.ProxyRequest(proxyReq)
+
+	// Record metrics regardless of success/failure
+	duration := time.Since(start)
+
+	if err != nil {
+		// Record backend error metrics
+		h.metrics.RecordBackendError(h.Name(), backend.Name, "network_error")
+		h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+		h.metrics.SetBackendHealth(backend.Name, false)
+
+		h.logger.Error().Err(err).
+			Str("backend", backend.Name).
+			Dur("duration", duration).
+			Msg("Backend request failed")
+
+		return nil, err
+	}
+
+	// Record backend latency for all requests
+	h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+
+	// Record backend health based on status code
+	if resp.StatusCode >= 500 {
+		// Server error - backend is unhealthy
+		h.metrics.RecordBackendErrorByStatus(backend.Name, resp.StatusCode)
+		h.metrics.SetBackendHealth(backend.Name, false)
+	} else if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+		// Success - backend is healthy
+		h.metrics.SetBackendHealth(backend.Name, true)
+	}
+	// 4xx errors don't affect backend health (client errors)
+
+	return resp, nil
+}
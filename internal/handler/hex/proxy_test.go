@@ -0,0 +1,162 @@
+package hex
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// TestSelectBackendAndProxy tests that repository reads go to the private backend,
+// packages it doesn't have to the upstream, and API requests to the publish backend,
+// each with the backend's credentials instead of the client's
+func TestSelectBackendAndProxy(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		requested = append(requested, r.Method+" "+r.URL.Path+" "+user+" "+string(body))
+		switch r.URL.Path {
+		case "/repos/acme/names", "/repos/acme/packages/acme_client", "/repos/acme/tarballs/acme_client-0.1.0.tar",
+			"/hexpm/packages/jason", "/hexpm/tarballs/jason-1.4.1.tar":
+			_, _ = w.Write([]byte("content"))
+		case "/api/repos/acme/publish":
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	backend := func(name, path, username string) config.HexBackendConfig {
+		return config.HexBackendConfig{
+			Name:                name,
+			URL:                 server.URL + path,
+			Auth:                &config.AuthConfig{Type: "basic", Username: username, Password: "secret"},
+			MaxIdleConns:        1,
+			MaxIdleConnsPerHost: 1,
+			DialTimeout:         time.Second,
+			RequestTimeout:      10 * time.Second,
+		}
+	}
+	upstream := backend("hexpm", "/hexpm", "mirror")
+	publish := backend("mini-repo-api", "/api/repos/acme", "publisher")
+	cfg := &config.HexConfig{
+		PathPrefix:     "/hex",
+		Backend:        backend("mini-repo", "/repos/acme", "reader"),
+		Upstream:       &upstream,
+		PublishBackend: &publish,
+	}
+
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("hex_proxy_test"), logger)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantCalls  []string // Backend requests as "<method> <path> <user> <body>"
+	}{
+		{
+			name:       "private package",
+			method:     http.MethodGet,
+			path:       "/hex/packages/acme_client",
+			wantStatus: http.StatusOK,
+			wantCalls:  []string{"GET /repos/acme/packages/acme_client reader "},
+		},
+		{
+			name:       "private tarball",
+			method:     http.MethodGet,
+			path:       "/hex/tarballs/acme_client-0.1.0.tar",
+			wantStatus: http.StatusOK,
+			wantCalls:  []string{"GET /repos/acme/tarballs/acme_client-0.1.0.tar reader "},
+		},
+		{
+			name:       "public package",
+			method:     http.MethodGet,
+			path:       "/hex/packages/jason",
+			wantStatus: http.StatusOK,
+			wantCalls:  []string{"GET /repos/acme/packages/jason reader ", "GET /hexpm/packages/jason mirror "},
+		},
+		{
+			name:       "public tarball",
+			method:     http.MethodGet,
+			path:       "/hex/tarballs/jason-1.4.1.tar",
+			wantStatus: http.StatusOK,
+			wantCalls:  []string{"GET /repos/acme/tarballs/jason-1.4.1.tar reader ", "GET /hexpm/tarballs/jason-1.4.1.tar mirror "},
+		},
+		{
+			name:       "unknown package",
+			method:     http.MethodGet,
+			path:       "/hex/packages/missing",
+			wantStatus: http.StatusNotFound,
+			wantCalls:  []string{"GET /repos/acme/packages/missing reader ", "GET /hexpm/packages/missing mirror "},
+		},
+		{
+			name:       "names are not cascaded",
+			method:     http.MethodGet,
+			path:       "/hex/versions",
+			wantStatus: http.StatusNotFound,
+			wantCalls:  []string{"GET /repos/acme/versions reader "},
+		},
+		{
+			name:       "repository upload",
+			method:     http.MethodPut,
+			path:       "/hex/tarballs/acme_client-0.2.0.tar",
+			body:       "tar",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "publish",
+			method:     http.MethodPost,
+			path:       "/hex/api/publish",
+			body:       "tar",
+			wantStatus: http.StatusCreated,
+			wantCalls:  []string{"POST /api/repos/acme/publish publisher tar"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested = nil
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.SetBasicAuth("alice", "ghp_client_token")
+			if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+				t.Fatalf("selectBackendAndProxy failed: %v", err)
+			}
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if strings.Join(requested, "\n") != strings.Join(tt.wantCalls, "\n") {
+				t.Errorf("backend requests = %q, want %q", requested, tt.wantCalls)
+			}
+		})
+	}
+
+	t.Run("publish without publish backend", func(t *testing.T) {
+		cfg.PublishBackend = nil
+		defer func() { cfg.PublishBackend = &publish }()
+
+		requested = nil
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/hex/api/publish", strings.NewReader("tar"))
+		if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+			t.Fatalf("selectBackendAndProxy failed: %v", err)
+		}
+		if w.Code != http.StatusNotFound || len(requested) > 0 {
+			t.Errorf("status = %d with backend requests %q, want %d without", w.Code, requested, http.StatusNotFound)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package hex
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// apiPath is the path below the path prefix served by the publish backend
+const apiPath = "/api"
+
+// repositoryMethods are the methods the repository serves, as listed in the Allow
+// header of 405 responses
+var repositoryMethods = []string{http.MethodGet, http.MethodHead}
+
+// selectBackendAndProxy determines the appropriate backend and proxies the request:
+// the API to the publish backend, and everything else to the read-only repository
+func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request, authResult *auth.AuthResult) error {
+	path := h.backendPath(r)
+
+	if apiRequestPath, ok := parseAPIPath(path); ok {
+		backend := h.config.PublishBackend
+		if backend == nil {
+			errors.ErrorResponse(w, errors.ErrNotFound.WithMessage("No publish backend is configured"))
+			return nil
+		}
+
+		h.logger.Debug().
+			Str("backend", backend.Name).
+			Str("url", backend.URL).
+			Str("path", apiRequestPath).
+			Str("username", authResult.Username).
+			Msg("Routing to Hex publish backend")
+		middleware.AddLogField(r.Context(), "backend", backend.Name)
+
+		return h.proxyAPI(w, r, backend, apiRequestPath)
+	}
+
+	if !slices.Contains(repositoryMethods, r.Method) {
+		w.Header().Set("Allow", strings.Join(repositoryMethods, ", "))
+		errors.ErrorResponse(w, errors.ErrMethodNotAllowed.WithMessage(fmt.Sprintf("%s is not supported by the repository", r.Method)))
+		return nil
+	}
+
+	// Requests start at the private backend; packages it doesn't have cascade to the
+	// upstream (see proxyRepository)
+	backend := &h.config.Backend
+
+	h.logger.Debug().
+		Str("backend", backend.Name).
+		Str("url", backend.URL).
+		Str("path", path).
+		Str("username", authResult.Username).
+		Msg("Routing to Hex repository backend")
+	middleware.AddLogField(r.Context(), "backend", backend.Name)
+
+	// Note: Backend authentication is handled by proxy client
+	return h.proxyRepository(w, r, backend, path)
+}
+
+// backendPath returns the request path with the path prefix stripped
+func (h *Handler) backendPath(r *http.Request) string {
+	path := r.URL.Path
+	if h.config.PathPrefix != "" {
+		path = strings.TrimPrefix(path, h.config.PathPrefix)
+		// Ensure path starts with /
+		if path == "" || !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	return path
+}
+
+// parseAPIPath returns the path of an API request on the publish backend, and false
+// for paths outside of /api
+//
+//	/api/publish              -> /publish
+//	/api/packages/acme_client -> /packages/acme_client
+func parseAPIPath(path string) (string, bool) {
+	if path == apiPath {
+		return "/", true
+	}
+	rest, ok := strings.CutPrefix(path, apiPath+"/")
+	if !ok {
+		return "", false
+	}
+	return "/" + rest, true
+}
+
+// isPackagePath reports whether path is a package resource or tarball, the
+// repository paths that cascade to the upstream. The names and versions resources
+// list the private repository only.
+func isPackagePath(path string) bool {
+	return strings.HasPrefix(path, "/packages/") || strings.HasPrefix(path, "/tarballs/")
+}
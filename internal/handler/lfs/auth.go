@@ -0,0 +1,52 @@
+package lfs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/detector"
+)
+
+// authenticateClient validates the client's GitHub PAT using shared authenticator.
+// git-lfs asks the git credential helper for credentials and sends them as Basic
+// auth (username = GitHub username, password = PAT).
+func (h *Handler) authenticateClient(r *http.Request) (*auth.AuthResult, *http.Request, error) {
+	authResult, newReq, err := h.authenticator.AuthenticateAndInjectContext(r)
+	if err != nil {
+		return nil, r, err
+	}
+
+	return authResult, newReq, nil
+}
+
+// handleAuthError returns a Basic challenge with an LFS error body. git-lfs reads
+// the challenge from LFS-Authenticate before WWW-Authenticate and retries with
+// credentials from the git credential helper.
+func (h *Handler) handleAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	h.logger.Warn().Err(err).
+		Str("path", r.URL.Path).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("Authentication failed")
+
+	status, message := http.StatusUnauthorized, h.config.ClientAuth.UnauthorizedMessage("Authentication required")
+	if errors.Is(err, auth.ErrInsufficientPermissions) {
+		// The credentials are valid, so challenging for others wouldn't help
+		status, message = http.StatusForbidden, auth.InsufficientPermissionsMessage
+	} else {
+		// Set challenge headers
+		challenges := auth.SetChallenges(w.Header(), &h.config.ClientAuth, auth.ChallengeOptions{
+			Schemes: []string{auth.SchemeBasic},
+			Realm:   "Artifusion Git LFS",
+		})
+		for _, challenge := range challenges {
+			w.Header().Add("LFS-Authenticate", challenge)
+		}
+	}
+	w.Header().Set("Content-Type", detector.LFSMediaType)
+	w.WriteHeader(status)
+	if encodeErr := json.NewEncoder(w).Encode(map[string]string{"message": message}); encodeErr != nil {
+		h.logger.Error().Err(encodeErr).Msg("Failed to write authentication error response")
+	}
+}
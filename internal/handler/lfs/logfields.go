@@ -0,0 +1,59 @@
+package lfs
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// addLogFields adds what the request targets to its completion log line:
+// lfs_repository, and lfs_oid for requests about a single object. Batch requests
+// add lfs_objects once the backend has answered.
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	p := h.backendPath(r)
+	if repository, ok := parseRepository(p); ok {
+		middleware.AddLogField(ctx, "lfs_repository", repository)
+	}
+	if oid, ok := parseObjectID(p); ok {
+		middleware.AddLogField(ctx, "lfs_oid", oid)
+	}
+}
+
+// parseRepository extracts the repository from a path below the repository's git
+// URL, <repository>.git/..., or its LFS endpoint, <repository>/info/lfs/...
+//
+//	/myorg/app.git/info/lfs/objects/batch  -> myorg/app
+//	/group/sub/app.git/gitlab-lfs/objects/ -> group/sub/app
+//	/myorg/app/info/lfs/locks              -> myorg/app
+func parseRepository(p string) (string, bool) {
+	repository, _, found := strings.Cut(p, ".git/")
+	if !found {
+		repository, _, found = strings.Cut(p, "/info/lfs/")
+	}
+	repository = strings.Trim(repository, "/")
+	if !found || repository == "" {
+		return "", false
+	}
+	return repository, true
+}
+
+// parseObjectID extracts the oid of the object a transfer or verify request is
+// about: the SHA-256 following an objects/ path segment
+//
+//	/myorg/app.git/info/lfs/objects/<oid>         -> <oid>
+//	/myorg/app.git/info/lfs/objects/<oid>/verify  -> <oid>
+func parseObjectID(p string) (string, bool) {
+	_, rest, found := strings.Cut(p, "/objects/")
+	if !found {
+		return "", false
+	}
+	oid, _, _ := strings.Cut(rest, "/")
+	if len(oid) != 64 || strings.Trim(oid, "0123456789abcdef") != "" {
+		return "", false
+	}
+	return oid, true
+}
@@ -0,0 +1,51 @@
+package lfs
+
+import "testing"
+
+const testOID = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+
+func TestParseRepository(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"/myorg/app.git/info/lfs/objects/batch", "myorg/app", true},
+		{"/group/sub/app.git/gitlab-lfs/objects/" + testOID, "group/sub/app", true},
+		{"/myorg/app/info/lfs/locks", "myorg/app", true},
+		{"/objects/batch", "", false},
+		{"/.git/info/lfs/objects/batch", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := parseRepository(tt.path)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseRepository() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseObjectID(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"/myorg/app.git/info/lfs/objects/" + testOID, testOID, true},
+		{"/myorg/app.git/info/lfs/objects/" + testOID + "/verify", testOID, true},
+		{"/myorg/app.git/info/lfs/objects/batch", "", false},
+		{"/myorg/app.git/info/lfs/objects/" + testOID[:63] + "G", "", false},
+		{"/myorg/app.git/info/lfs/locks", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := parseObjectID(tt.path)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseObjectID() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package lfs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/proxy/rewriter"
+)
+
+// proxyPassthrough proxies the request to the backend, streaming both bodies
+// unmodified: object transfers, verify callbacks and the locking API. Objects are
+// content addressed, so git-lfs checks downloads against their oid itself.
+func (h *Handler) proxyPassthrough(w http.ResponseWriter, r *http.Request, backend *config.LFSBackendConfig) error {
+	resp, err := h.executeProxyRequest(r, backend, h.backendPath(r))
+	if err != nil {
+		return err
+	}
+
+	h.rewriteLocation(r, resp, backend)
+
+	_, err = h.proxyClient.StreamResponse(w, resp, true)
+	return err
+}
+
+// proxyBatch proxies a Batch API request and points the transfer actions in the
+// response that the backend serves at the proxy, so that clients upload and
+// download those objects through it with their GitHub token. Actions on other hosts,
+// such as presigned object storage URLs, are left for git-lfs to call directly.
+func (h *Handler) proxyBatch(w http.ResponseWriter, r *http.Request, backend *config.LFSBackendConfig) error {
+	backendPath := h.backendPath(r)
+
+	resp, err := h.executeProxyRequest(r, backend, backendPath)
+	if err != nil {
+		return err
+	}
+
+	h.rewriteLocation(r, resp, backend)
+
+	if resp.StatusCode != http.StatusOK {
+		_, err = h.proxyClient.StreamResponse(w, resp, true)
+		return err
+	}
+
+	body, err := h.proxyClient.ReadResponseBody(resp)
+	if err != nil {
+		w.WriteHeader(resp.StatusCode)
+		return err
+	}
+
+	// Relative hrefs are relative to the URL the backend was requested at
+	requested, _ := url.Parse(strings.TrimSuffix(backend.URL, "/") + backendPath)
+	if resp.HTTPResp != nil && resp.HTTPResp.Request != nil {
+		requested = resp.HTTPResp.Request.URL
+	}
+
+	rewritten, objects, err := h.rewriteBatchResponse(body, requested, h.getEffectiveBaseURL(r))
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to parse batch response, serving it unmodified")
+		return h.proxyClient.WriteResponse(w, resp, body, true)
+	}
+	middleware.AddLogField(r.Context(), "lfs_objects", strconv.Itoa(objects))
+
+	// The backend's validators describe the original document
+	resp.Headers.Del("ETag")
+	resp.Headers.Del("Digest")
+	resp.Headers.Del("Repr-Digest")
+	resp.Headers.Del("Accept-Ranges")
+	return h.proxyClient.WriteResponse(w, resp, rewritten, true)
+}
+
+// rewriteBatchResponse rewrites the actions of every object in a Batch API
+// response, keeping all other fields as the backend sent them. It returns the
+// rewritten response and the number of objects in it.
+func (h *Handler) rewriteBatchResponse(body []byte, requested *url.URL, proxyURL string) ([]byte, int, error) {
+	var batch map[string]json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, 0, err
+	}
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(batch["objects"], &objects); err != nil {
+		return nil, 0, err
+	}
+
+	for _, object := range objects {
+		var actions map[string]map[string]json.RawMessage
+		if json.Unmarshal(object["actions"], &actions) != nil || len(actions) == 0 {
+			continue
+		}
+		for _, action := range actions {
+			h.rewriteAction(action, requested, proxyURL)
+		}
+		object["actions"], _ = json.Marshal(actions)
+	}
+
+	var err error
+	if batch["objects"], err = json.Marshal(objects); err != nil {
+		return nil, 0, err
+	}
+	rewritten, err := json.Marshal(batch)
+	return rewritten, len(objects), err
+}
+
+// rewriteAction points an action (download, upload or verify) at the proxy if the
+// backend serves its href. The proxy authenticates to the backend with its own
+// credentials, so an Authorization header the backend issued for the action is
+// dropped: git-lfs would send it instead of the client's GitHub token.
+func (h *Handler) rewriteAction(action map[string]json.RawMessage, requested *url.URL, proxyURL string) {
+	var href string
+	if json.Unmarshal(action["href"], &href) != nil || href == "" {
+		return
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return
+	}
+	if requested != nil {
+		ref = requested.ResolveReference(ref)
+	}
+
+	mapped, ok := rewriter.MapLocation(ref.String(), h.config.Backend.URL, proxyURL)
+	if !ok {
+		return
+	}
+	action["href"], _ = json.Marshal(mapped)
+
+	var header map[string]string
+	if json.Unmarshal(action["header"], &header) != nil || len(header) == 0 {
+		return
+	}
+	for key := range header {
+		if strings.EqualFold(key, "Authorization") {
+			delete(header, key)
+		}
+	}
+	action["header"], _ = json.Marshal(header)
+}
+
+// rewriteLocation points a redirect to a URL the backend serves at the proxy
+func (h *Handler) rewriteLocation(r *http.Request, resp *proxy.Response, backend *config.LFSBackendConfig) {
+	proxyURL := h.getEffectiveBaseURL(r)
+	if rewriter.RewriteRedirectLocation(resp, backend, proxyURL) {
+		return
+	}
+	if location := resp.Headers.Get("Location"); location != "" {
+		if mapped, ok := rewriter.MapLocation(location, backend.URL, proxyURL); ok {
+			resp.Headers.Set("Location", mapped)
+		}
+	}
+}
+
+// backendPath returns the request path with the path prefix stripped
+func (h *Handler) backendPath(r *http.Request) string {
+	path := r.URL.Path
+	if h.config.PathPrefix != "" {
+		path = strings.TrimPrefix(path, h.config.PathPrefix)
+		// Ensure path starts with /
+		if path == "" || !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	return path
+}
+
+// executeProxyRequest sends the request to backend and records backend metrics,
+// returning the response without writing it
+func (h *Handler) executeProxyRequest(r *http.Request, backend *config.LFSBackendConfig, path string) (*proxy.Response, error) {
+	// Create proxy request
+	proxyReq := &proxy.Request{
+		Method:        r.Method,
+		Path:          path,
+		Query:         r.URL.RawQuery,
+		Body:          r.Body,
+		ContentLength: r.ContentLength,
+		Headers:       r.Header,
+		Backend:       backend,
+		OriginalReq:   r,
+	}
+
+	// Track backend request timing
+	start := time.Now()
+
+	// Execute proxy request
+	resp, err := h.proxyClient.ProxyRequest(proxyReq)
+
+	// Record metrics regardless of success/failure
+	duration := time.Since(start)
+
+	if err != nil {
+		// Record backend error metrics
+		h.metrics.RecordBackendError(h.Name(), backend.Name, "network_error")
+		h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+		h.metrics.SetBackendHealth(backend.Name, false)
+
+		h.logger.Error().Err(err).
+			Str("backend", backend.Name).
+			Dur("duration", duration).
+			Msg("Backend request failed")
+
+		return nil, err
+	}
+
+	// Record backend latency for all requests
+	h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+
+	// Record backend health based on status code
+	if resp.StatusCode >= 500 {
+		// Server error - backend is unhealthy
+		h.metrics.RecordBackendErrorByStatus(backend.Name, resp.StatusCode)
+		h.metrics.SetBackendHealth(backend.Name, false)
+	} else if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+		// Success - backend is healthy
+		h.metrics.SetBackendHealth(backend.Name, true)
+	}
+	// 4xx errors don't affect backend health (client errors)
+
+	return resp, nil
+}
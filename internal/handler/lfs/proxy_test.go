@@ -0,0 +1,203 @@
+package lfs
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+func newTestHandler(t *testing.T, backendURL, namespace string) *Handler {
+	t.Helper()
+	cfg := &config.LFSConfig{
+		PathPrefix: "/lfs",
+		Backend: config.LFSBackendConfig{
+			Name:                "git",
+			URL:                 backendURL + "/git",
+			Auth:                &config.AuthConfig{Type: "basic", Username: "mirror", Password: "secret"},
+			MaxIdleConns:        1,
+			MaxIdleConnsPerHost: 1,
+			DialTimeout:         time.Second,
+			RequestTimeout:      10 * time.Second,
+		},
+	}
+	logger := zerolog.Nop()
+	return NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics(namespace), logger)
+}
+
+// TestProxyBatch tests that the transfer actions of a batch response are pointed at
+// the proxy when the backend serves them, and left alone otherwise
+func TestProxyBatch(t *testing.T) {
+	var gotPath, gotUser, gotPassword, gotBody string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotUser, gotPassword, _ = r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+
+		w.Header().Set("Content-Type", "application/vnd.git-lfs+json")
+		w.Header().Set("ETag", `"batch"`)
+		_, _ = w.Write([]byte(`{
+			"transfer": "basic",
+			"objects": [
+				{
+					"oid": "` + testOID + `",
+					"size": 123,
+					"authenticated": true,
+					"actions": {
+						"upload": {
+							"href": "` + server.URL + `/git/myorg/app.git/info/lfs/objects/` + testOID + `",
+							"header": {"Authorization": "RemoteAuth backend-token", "X-Upload-Mode": "direct"},
+							"expires_in": 3600
+						},
+						"verify": {"href": "/git/myorg/app.git/info/lfs/objects/` + testOID + `/verify"}
+					}
+				},
+				{
+					"oid": "0000000000000000000000000000000000000000000000000000000000000000",
+					"size": 42,
+					"actions": {
+						"download": {
+							"href": "https://storage.example.net/bucket/0000?X-Amz-Signature=abc",
+							"header": {"Authorization": "Bearer storage-token"}
+						}
+					}
+				},
+				{
+					"oid": "1111111111111111111111111111111111111111111111111111111111111111",
+					"size": 7,
+					"error": {"code": 404, "message": "Object does not exist"}
+				}
+			]
+		}`))
+	}))
+	defer server.Close()
+
+	h := newTestHandler(t, server.URL, "lfs_batch_test")
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/lfs/myorg/app.git/info/lfs/objects/batch",
+		strings.NewReader(`{"operation":"upload","objects":[{"oid":"`+testOID+`","size":123}]}`))
+	r.SetBasicAuth("alice", "ghp_client_token")
+	r.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+	if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+		t.Fatalf("selectBackendAndProxy failed: %v", err)
+	}
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if gotPath != "/git/myorg/app.git/info/lfs/objects/batch" {
+		t.Errorf("backend path = %q", gotPath)
+	}
+	if gotUser != "mirror" || gotPassword != "secret" {
+		t.Errorf("backend credentials = %q:%q, want mirror:secret", gotUser, gotPassword)
+	}
+	if !strings.Contains(gotBody, `"operation":"upload"`) {
+		t.Errorf("backend body = %q", gotBody)
+	}
+	if w.Header().Get("ETag") != "" {
+		t.Error("ETag of the original response should be dropped")
+	}
+
+	var response struct {
+		Transfer string `json:"transfer"`
+		Objects  []struct {
+			OID     string `json:"oid"`
+			Actions map[string]struct {
+				Href      string            `json:"href"`
+				Header    map[string]string `json:"header"`
+				ExpiresIn int               `json:"expires_in"`
+			} `json:"actions"`
+			Error *struct {
+				Code int `json:"code"`
+			} `json:"error"`
+		} `json:"objects"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response: %v\n%s", err, w.Body.String())
+	}
+	if response.Transfer != "basic" || len(response.Objects) != 3 {
+		t.Fatalf("response = %+v", response)
+	}
+
+	upload := response.Objects[0].Actions["upload"]
+	if want := "https://example.com/lfs/myorg/app.git/info/lfs/objects/" + testOID; upload.Href != want {
+		t.Errorf("upload href = %q, want %q", upload.Href, want)
+	}
+	if _, ok := upload.Header["Authorization"]; ok || upload.Header["X-Upload-Mode"] != "direct" || upload.ExpiresIn != 3600 {
+		t.Errorf("upload action = %+v, want Authorization dropped and the rest kept", upload)
+	}
+	verify := response.Objects[0].Actions["verify"]
+	if want := "https://example.com/lfs/myorg/app.git/info/lfs/objects/" + testOID + "/verify"; verify.Href != want {
+		t.Errorf("verify href = %q, want %q", verify.Href, want)
+	}
+
+	download := response.Objects[1].Actions["download"]
+	if download.Href != "https://storage.example.net/bucket/0000?X-Amz-Signature=abc" || download.Header["Authorization"] != "Bearer storage-token" {
+		t.Errorf("external download action = %+v, want it unmodified", download)
+	}
+	if response.Objects[2].Error == nil || response.Objects[2].Error.Code != 404 {
+		t.Errorf("object error = %+v, want it kept", response.Objects[2].Error)
+	}
+}
+
+// TestProxyPassthrough tests that object transfers are streamed to the backend with
+// the backend's credentials instead of the client's
+func TestProxyPassthrough(t *testing.T) {
+	var gotMethod, gotPath, gotUser, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.Path
+		gotUser, _, _ = r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte("object content"))
+		}
+	}))
+	defer server.Close()
+
+	h := newTestHandler(t, server.URL, "lfs_passthrough_test")
+
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		wantBody string
+	}{
+		{name: "download", method: http.MethodGet, wantBody: "object content"},
+		{name: "upload", method: http.MethodPut, body: "object content"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotMethod, gotPath, gotUser, gotBody = "", "", "", ""
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, "/lfs/myorg/app.git/info/lfs/objects/"+testOID, strings.NewReader(tt.body))
+			r.SetBasicAuth("alice", "ghp_client_token")
+			if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+				t.Fatalf("selectBackendAndProxy failed: %v", err)
+			}
+
+			if w.Code != http.StatusOK || w.Body.String() != tt.wantBody {
+				t.Errorf("response = %d %q, want 200 %q", w.Code, w.Body.String(), tt.wantBody)
+			}
+			if gotMethod != tt.method || gotPath != "/git/myorg/app.git/info/lfs/objects/"+testOID {
+				t.Errorf("backend request = %s %s", gotMethod, gotPath)
+			}
+			if gotUser != "mirror" || gotBody != tt.body {
+				t.Errorf("backend user = %q, body = %q, want mirror, %q", gotUser, gotBody, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package lfs
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// selectBackendAndProxy determines the appropriate backend and proxies the request
+func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request, authResult *auth.AuthResult) error {
+	// Use single backend for the API and object transfers
+	backend := &h.config.Backend
+
+	// Log operation type for debugging
+	operationType := "read"
+	if auth.IsWriteMethod(r.Method) {
+		operationType = "write"
+	}
+
+	h.logger.Debug().
+		Str("backend", backend.Name).
+		Str("url", backend.URL).
+		Str("operation", operationType).
+		Str("username", authResult.Username).
+		Msg("Routing to Git LFS backend")
+	middleware.AddLogField(r.Context(), "backend", backend.Name)
+
+	// Note: Backend authentication is handled by proxy client
+	if isBatchRequest(r) {
+		return h.proxyBatch(w, r, backend)
+	}
+	return h.proxyPassthrough(w, r, backend)
+}
+
+// isBatchRequest reports whether r calls the Batch API,
+// POST <endpoint>/objects/batch
+func isBatchRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/objects/batch")
+}
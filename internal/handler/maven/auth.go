@@ -1,7 +1,7 @@
 package maven
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 
 	"github.com/mainuli/artifusion/internal/auth"
@@ -25,15 +25,19 @@ func (h *Handler) handleAuthError(w http.ResponseWriter, r *http.Request, err er
 		Str("remote_addr", r.RemoteAddr).
 		Msg("Authentication failed")
 
-	// Set WWW-Authenticate challenge header
-	realm := h.config.ClientAuth.Realm
-	if realm == "" {
-		realm = "Artifusion Maven Repository"
+	status, message := http.StatusUnauthorized, h.config.ClientAuth.UnauthorizedMessage("Authentication required")
+	if errors.Is(err, auth.ErrInsufficientPermissions) {
+		// The credentials are valid, so challenging for others wouldn't help
+		status, message = http.StatusForbidden, auth.InsufficientPermissionsMessage
+	} else {
+		// Set WWW-Authenticate challenge headers
+		auth.SetChallenges(w.Header(), &h.config.ClientAuth, auth.ChallengeOptions{
+			Schemes: []string{auth.SchemeBasic},
+			Realm:   "Artifusion Maven Repository",
+		})
 	}
-
-	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s"`, realm))
-	w.WriteHeader(http.StatusUnauthorized)
-	if _, writeErr := w.Write([]byte("Authentication required\n")); writeErr != nil {
+	w.WriteHeader(status)
+	if _, writeErr := w.Write([]byte(message + "\n")); writeErr != nil {
 		h.logger.Error().Err(writeErr).Msg("Failed to write authentication error response")
 	}
 }
@@ -8,7 +8,9 @@ import (
 	"github.com/mainuli/artifusion/internal/config"
 	"github.com/mainuli/artifusion/internal/detector"
 	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/metadata"
 	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/middleware"
 	"github.com/mainuli/artifusion/internal/proxy"
 	"github.com/rs/zerolog"
 )
@@ -19,6 +21,9 @@ type Handler struct {
 	authenticator *auth.ClientAuthenticator
 	proxyClient   *proxy.Client
 	metrics       *metrics.Metrics
+	writeBacks    *proxy.WriteBackQueue    // nil unless write_back is enabled
+	metadata      *metadata.Store          // nil = disabled
+	naming        *middleware.NamingPolicy // nil = disabled
 	logger        zerolog.Logger
 }
 
@@ -30,13 +35,18 @@ func NewHandler(
 	metricsCollector *metrics.Metrics,
 	logger zerolog.Logger,
 ) *Handler {
-	return &Handler{
+	h := &Handler{
 		config:        cfg,
 		authenticator: authenticator,
 		proxyClient:   proxyClient,
 		metrics:       metricsCollector,
+		naming:        middleware.NewNamingPolicy(&cfg.Naming),
 		logger:        logger.With().Str("protocol", "maven").Logger(),
 	}
+	if cfg.WriteBack {
+		h.writeBacks = proxy.NewWriteBackQueue(h.Name(), metricsCollector, h.logger)
+	}
+	return h
 }
 
 // ServeHTTP handles Maven repository requests
@@ -46,6 +56,9 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		Str("path", r.URL.Path).
 		Msg("Maven request received")
 
+	// Tag the request's log line with the artifact it targets
+	h.addLogFields(r)
+
 	// Step 1: Authenticate client
 	authResult, updatedReq, err := h.authenticateClient(r)
 	if err != nil {
@@ -53,7 +66,12 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Step 2: Select backend and proxy request
+	// Step 2: Enforce the naming convention on pushes
+	if !h.checkNaming(w, updatedReq) {
+		return
+	}
+
+	// Step 3: Select backend and proxy request
 	if err := h.selectBackendAndProxy(w, updatedReq, authResult); err != nil {
 		h.logger.Error().Err(err).
 			Str("path", updatedReq.URL.Path).
@@ -0,0 +1,196 @@
+package maven
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/metadata"
+)
+
+// metadataFile is the repository metadata file listing an artifact's versions, or the
+// plugins of a plugin group
+const metadataFile = "maven-metadata.xml"
+
+// listingEntry is one file or subdirectory of a synthesized directory listing
+type listingEntry struct {
+	Name      string `json:"name"` // Subdirectories end with a slash
+	Directory bool   `json:"directory"`
+}
+
+// directoryListing is the JSON form of a synthesized directory listing
+type directoryListing struct {
+	Path    string         `json:"path"`
+	Entries []listingEntry `json:"entries"`
+}
+
+// mavenMetadata holds the parts of maven-metadata.xml that name subdirectories
+type mavenMetadata struct {
+	Versions []string `xml:"versioning>versions>version"`
+	Plugins  []string `xml:"plugins>plugin>artifactId"`
+}
+
+// isDirectoryPath reports whether a repository path names a directory
+func isDirectoryPath(p string) bool {
+	return strings.HasSuffix(p, "/")
+}
+
+// serveDirectoryListing answers a read of a directory the backends don't list
+// (many serve 404 for directories) with a listing synthesized from the directory's
+// maven-metadata.xml and the artifacts recorded in the metadata database. The
+// listing is HTML, like the index pages of repository servers, or JSON for clients
+// accepting application/json. Directories nothing is known about stay 404.
+func (h *Handler) serveDirectoryListing(w http.ResponseWriter, r *http.Request, dirPath string) error {
+	entries := make(map[string]bool) // name -> directory
+	h.listMetadataFile(r, dirPath, entries)
+	h.listRecordedArtifacts(dirPath, entries)
+
+	if len(entries) == 0 {
+		errors.ErrorResponse(w, errors.ErrNotFound)
+		return nil
+	}
+
+	listing := directoryListing{Path: r.URL.Path}
+	for name, directory := range entries {
+		listing.Entries = append(listing.Entries, listingEntry{Name: name, Directory: directory})
+	}
+	slices.SortFunc(listing.Entries, func(a, b listingEntry) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	h.logger.Debug().
+		Str("path", dirPath).
+		Int("entries", len(listing.Entries)).
+		Msg("Serving synthesized directory listing")
+
+	var body []byte
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		encoded, err := json.Marshal(listing)
+		if err != nil {
+			return fmt.Errorf("failed to encode directory listing: %w", err)
+		}
+		body = append(encoded, '\n')
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		body = renderListingHTML(&listing)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// listMetadataFile adds the versions or plugins named by the directory's
+// maven-metadata.xml, and the file itself, from the first backend serving it
+func (h *Handler) listMetadataFile(r *http.Request, dirPath string, entries map[string]bool) {
+	backends := []*config.MavenBackendConfig{&h.config.Backend}
+	if h.config.Upstream != nil {
+		backends = append(backends, h.config.Upstream)
+	}
+
+	req := r.Clone(r.Context())
+	req.Method = http.MethodGet
+	req.Body = http.NoBody
+	req.ContentLength = 0
+	req.URL.RawQuery = ""
+
+	for _, backend := range backends {
+		resp, err := h.executeProxyRequest(req, backend, dirPath+metadataFile, "")
+		if err != nil {
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			if closeErr := resp.Body.Close(); closeErr != nil {
+				h.logger.Warn().Err(closeErr).Msg("Failed to close response body")
+			}
+			continue
+		}
+
+		body, err := h.proxyClient.ReadResponseBody(resp)
+		if err != nil {
+			continue
+		}
+		var parsed mavenMetadata
+		if err := xml.Unmarshal(body, &parsed); err != nil {
+			h.logger.Warn().Err(err).
+				Str("backend", backend.Name).
+				Str("path", dirPath+metadataFile).
+				Msg("Failed to parse repository metadata for directory listing")
+			continue
+		}
+
+		entries[metadataFile] = false
+		for _, name := range append(parsed.Versions, parsed.Plugins...) {
+			if name != "" && !strings.Contains(name, "/") {
+				entries[name+"/"] = true
+			}
+		}
+		return
+	}
+}
+
+// listRecordedArtifacts adds the subdirectories leading to artifacts recorded in the
+// metadata database, and the POM of a recorded version, the file recorded for it
+func (h *Handler) listRecordedArtifacts(dirPath string, entries map[string]bool) {
+	if h.metadata == nil {
+		return
+	}
+	artifacts, err := h.metadata.List(metadata.Filter{Protocol: h.Name()})
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to read metadata for directory listing")
+		return
+	}
+
+	var dir []string
+	if trimmed := strings.Trim(dirPath, "/"); trimmed != "" {
+		dir = strings.Split(trimmed, "/")
+	}
+
+	for _, artifact := range artifacts {
+		group, name, found := strings.Cut(artifact.Name, ":")
+		if !found || artifact.Version == "" {
+			continue
+		}
+		segments := append(strings.Split(group, "."), name, artifact.Version)
+		if len(dir) > len(segments) || !slices.Equal(segments[:len(dir)], dir) {
+			continue
+		}
+		if len(dir) < len(segments) {
+			entries[segments[len(dir)]+"/"] = true
+		} else {
+			entries[name+"-"+artifact.Version+".pom"] = false
+		}
+	}
+}
+
+// renderListingHTML renders a listing as the index page repository servers serve
+// for directories
+func renderListingHTML(listing *directoryListing) []byte {
+	title := html.EscapeString("Index of " + listing.Path)
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head><title>" + title + "</title></head>\n")
+	b.WriteString("<body>\n<h1>" + title + "</h1>\n<pre>\n")
+	b.WriteString("<a href=\"../\">../</a>\n")
+	for _, entry := range listing.Entries {
+		href := url.PathEscape(strings.TrimSuffix(entry.Name, "/"))
+		if entry.Directory {
+			href += "/"
+		}
+		b.WriteString("<a href=\"" + href + "\">" + html.EscapeString(entry.Name) + "</a>\n")
+	}
+	b.WriteString("</pre>\n</body>\n</html>\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,101 @@
+package maven
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// TestSelectBackendAndProxy_DirectoryListing tests that directories the backend
+// answers with 404 are listed from their maven-metadata.xml
+func TestSelectBackendAndProxy_DirectoryListing(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/com/example/app/maven-metadata.xml" {
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<metadata><groupId>com.example</groupId><artifactId>app</artifactId>
+<versioning><versions><version>1.0</version><version>1.1</version></versions></versioning></metadata>`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer backend.Close()
+
+	cfg := &config.MavenConfig{
+		PathPrefix: "/maven",
+		Backend: config.MavenBackendConfig{
+			Name:                "reposilite",
+			URL:                 backend.URL,
+			MaxIdleConns:        1,
+			MaxIdleConnsPerHost: 1,
+			DialTimeout:         time.Second,
+			RequestTimeout:      10 * time.Second,
+		},
+		DirectoryListing: true,
+	}
+
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("maven_listing_test"), logger)
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+			t.Fatalf("selectBackendAndProxy failed: %v", err)
+		}
+		return w
+	}
+
+	t.Run("json", func(t *testing.T) {
+		w := serve("/maven/com/example/app/", "application/json")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", w.Code)
+		}
+		var listing directoryListing
+		if err := json.Unmarshal(w.Body.Bytes(), &listing); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		var names []string
+		for _, entry := range listing.Entries {
+			names = append(names, entry.Name)
+		}
+		if got, want := strings.Join(names, ","), "1.0/,1.1/,maven-metadata.xml"; got != want {
+			t.Errorf("entries = %s, want %s", got, want)
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		w := serve("/maven/com/example/app/", "")
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+			t.Fatalf("response = %d %s, want 200 text/html", w.Code, w.Header().Get("Content-Type"))
+		}
+		for _, want := range []string{"Index of /maven/com/example/app/", `<a href="1.1/">1.1/</a>`, `<a href="maven-metadata.xml">`} {
+			if !strings.Contains(w.Body.String(), want) {
+				t.Errorf("body missing %q:\n%s", want, w.Body.String())
+			}
+		}
+	})
+
+	t.Run("unknown directory", func(t *testing.T) {
+		if w := serve("/maven/com/other/", ""); w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", w.Code)
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		if w := serve("/maven/com/example/app/1.0/app-1.0.jar", ""); w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", w.Code)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package maven
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// addLogFields adds the coordinates of the artifact the request targets to its
+// completion log line: maven_group and maven_artifact, plus maven_version for
+// files of a version
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	group, artifact, version, ok := parseGAV(h.backendPath(r))
+	if !ok {
+		return
+	}
+	middleware.AddLogField(ctx, "maven_group", group)
+	middleware.AddLogField(ctx, "maven_artifact", artifact)
+	middleware.AddLogField(ctx, "maven_version", version)
+}
+
+// parseGAV extracts the Maven coordinates from a repository path. version is
+// empty for artifact-level metadata.
+//
+//	/org/slf4j/slf4j-api/2.0.9/slf4j-api-2.0.9.jar        -> org.slf4j, slf4j-api, 2.0.9
+//	/org/slf4j/slf4j-api/maven-metadata.xml               -> org.slf4j, slf4j-api, ""
+//	/com/acme/lib/1.0-SNAPSHOT/maven-metadata.xml.sha1    -> com.acme, lib, 1.0-SNAPSHOT
+func parseGAV(path string) (group, artifact, version string, ok bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < 3 {
+		return "", "", "", false
+	}
+	file := segments[len(segments)-1]
+	dirs := segments[:len(segments)-1]
+
+	if strings.HasPrefix(file, "maven-metadata.xml") {
+		// Version-level metadata only exists for snapshots
+		if last := dirs[len(dirs)-1]; strings.HasSuffix(last, "-SNAPSHOT") && len(dirs) >= 3 {
+			return strings.Join(dirs[:len(dirs)-2], "."), dirs[len(dirs)-2], last, true
+		}
+		return strings.Join(dirs[:len(dirs)-1], "."), dirs[len(dirs)-1], "", true
+	}
+
+	// Artifact files are named <artifact>-<version>[-classifier].<ext>, with the
+	// timestamp in place of SNAPSHOT for snapshot builds
+	if len(dirs) < 3 {
+		return "", "", "", false
+	}
+	artifact, version = dirs[len(dirs)-2], dirs[len(dirs)-1]
+	if !strings.HasPrefix(file, artifact+"-"+strings.TrimSuffix(version, "SNAPSHOT")) {
+		return "", "", "", false
+	}
+	return strings.Join(dirs[:len(dirs)-2], "."), artifact, version, true
+}
@@ -0,0 +1,33 @@
+package maven
+
+import "testing"
+
+func TestParseGAV(t *testing.T) {
+	tests := []struct {
+		path         string
+		wantGroup    string
+		wantArtifact string
+		wantVersion  string
+		wantOK       bool
+	}{
+		{"/org/slf4j/slf4j-api/2.0.9/slf4j-api-2.0.9.jar", "org.slf4j", "slf4j-api", "2.0.9", true},
+		{"/org/slf4j/slf4j-api/2.0.9/slf4j-api-2.0.9-sources.jar.sha1", "org.slf4j", "slf4j-api", "2.0.9", true},
+		{"/org/slf4j/slf4j-api/maven-metadata.xml", "org.slf4j", "slf4j-api", "", true},
+		{"/com/acme/lib/1.0-SNAPSHOT/lib-1.0-20240101.120000-1.jar", "com.acme", "lib", "1.0-SNAPSHOT", true},
+		{"/com/acme/lib/1.0-SNAPSHOT/maven-metadata.xml.sha1", "com.acme", "lib", "1.0-SNAPSHOT", true},
+		{"/junit/junit/4.13.2/junit-4.13.2.pom", "junit", "junit", "4.13.2", true},
+		{"/org/slf4j/slf4j-api/2.0.9/other-2.0.9.jar", "", "", "", false},
+		{"/archetype-catalog.xml", "", "", "", false},
+		{"/org/slf4j/", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			group, artifact, version, ok := parseGAV(tt.path)
+			if group != tt.wantGroup || artifact != tt.wantArtifact || version != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("parseGAV(%q) = %q, %q, %q, %v, want %q, %q, %q, %v",
+					tt.path, group, artifact, version, ok, tt.wantGroup, tt.wantArtifact, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package maven
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/metadata"
+)
+
+// SetMetadata enables recording downloaded and deployed artifacts in the metadata
+// database
+func (h *Handler) SetMetadata(store *metadata.Store) {
+	h.metadata = store
+}
+
+// recordArtifact records a request for a version's POM the backend answered
+// successfully, as a pull or a deploy of the version. Every build resolving or
+// deploying a version transfers its POM exactly once, unlike its other files.
+// Artifacts are named group:artifact.
+func (h *Handler) recordArtifact(r *http.Request, path string, statusCode int) {
+	if h.metadata == nil || statusCode < 200 || statusCode >= 300 || !strings.HasSuffix(path, ".pom") {
+		return
+	}
+	group, artifact, version, ok := parseGAV(path)
+	if !ok || version == "" {
+		return
+	}
+
+	name := group + ":" + artifact
+	switch r.Method {
+	case http.MethodGet:
+		h.metadata.RecordPull(h.Name(), name, version, "")
+	case http.MethodPut:
+		h.metadata.RecordPush(h.Name(), name, version, "", metadata.NewProvenance(r))
+	}
+}
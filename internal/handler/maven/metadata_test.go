@@ -0,0 +1,56 @@
+package maven
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metadata"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/rs/zerolog"
+)
+
+func TestRecordArtifact(t *testing.T) {
+	store, err := metadata.Open(&config.MetadataConfig{Path: filepath.Join(t.TempDir(), "metadata.db"), FlushInterval: time.Minute}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("metadata.Open() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	h := &Handler{}
+	h.SetMetadata(store)
+
+	requests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodPut, "/com/acme/lib/1.0/lib-1.0.pom", http.StatusCreated},
+		{http.MethodPut, "/com/acme/lib/1.0/lib-1.0.jar", http.StatusCreated},
+		{http.MethodGet, "/com/acme/lib/1.0/lib-1.0.pom", http.StatusOK},
+		{http.MethodGet, "/com/acme/lib/1.0/lib-1.0.pom.sha1", http.StatusOK},
+		{http.MethodGet, "/com/acme/lib/1.0/lib-1.0.jar", http.StatusOK},
+		{http.MethodGet, "/com/acme/lib/2.0/lib-2.0.pom", http.StatusNotFound},
+		{http.MethodGet, "/com/acme/lib/maven-metadata.xml", http.StatusOK},
+	}
+	for _, req := range requests {
+		r := httptest.NewRequest(req.method, req.path, nil)
+		r = r.WithContext(middleware.SetUsername(r.Context(), "alice"))
+		h.recordArtifact(r, req.path, req.status)
+	}
+
+	artifacts, err := store.List(metadata.Filter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(artifacts) != 1 {
+		t.Fatalf("recorded %+v, want only com.acme:lib 1.0", artifacts)
+	}
+	got := artifacts[0]
+	if got.Name != "com.acme:lib" || got.Version != "1.0" || got.Pulls != 1 || got.Uploader() != "alice" {
+		t.Errorf("recorded %+v, want com.acme:lib 1.0 deployed by alice and pulled once", got)
+	}
+}
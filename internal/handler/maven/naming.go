@@ -0,0 +1,37 @@
+package maven
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/errors"
+)
+
+// checkNaming rejects deploys to groupIds that don't follow the configured naming
+// convention, returning false if it wrote the rejection. Files whose coordinates
+// can't be parsed are checked with their whole directory as the groupId, so they
+// can't be deployed outside of a conforming group.
+func (h *Handler) checkNaming(w http.ResponseWriter, r *http.Request) bool {
+	if h.naming == nil || !h.isWriteOperation(r.Method) {
+		return true
+	}
+	path := h.backendPath(r)
+	group, _, _, ok := parseGAV(path)
+	if !ok {
+		file := strings.Trim(path, "/")
+		if idx := strings.LastIndex(file, "/"); idx >= 0 {
+			group = strings.ReplaceAll(file[:idx], "/", ".")
+		}
+	}
+
+	err := h.naming.Check("groupId", group)
+	if err == nil {
+		return true
+	}
+	h.logger.Warn().Err(err).
+		Str("path", path).
+		Str("method", r.Method).
+		Msg("Deploy rejected by naming convention")
+	errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage(err.Error()))
+	return false
+}
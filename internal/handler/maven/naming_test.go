@@ -0,0 +1,47 @@
+package maven
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/rs/zerolog"
+)
+
+func TestCheckNaming(t *testing.T) {
+	cfg := &config.MavenConfig{
+		PathPrefix: "/maven",
+		Naming:     config.NamingConfig{Patterns: []string{`^com\.myorg(\.|$)`}},
+	}
+	h := &Handler{config: cfg, naming: middleware.NewNamingPolicy(&cfg.Naming), logger: zerolog.Nop()}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		wantOK bool
+	}{
+		{name: "conforming artifact", method: http.MethodPut, path: "/maven/com/myorg/lib/1.0/lib-1.0.jar", wantOK: true},
+		{name: "conforming subgroup metadata", method: http.MethodPut, path: "/maven/com/myorg/platform/core/maven-metadata.xml", wantOK: true},
+		{name: "nonconforming artifact", method: http.MethodPut, path: "/maven/org/example/lib/1.0/lib-1.0.jar"},
+		{name: "group with the right prefix", method: http.MethodPut, path: "/maven/com/myorganization/lib/1.0/lib-1.0.pom"},
+		{name: "unparseable file", method: http.MethodPut, path: "/maven/org/example/lib/1.0/other.jar"},
+		{name: "unparseable file in conforming group", method: http.MethodPut, path: "/maven/com/myorg/lib/1.0/other.jar", wantOK: true},
+		{name: "nonconforming read", method: http.MethodGet, path: "/maven/org/example/lib/1.0/lib-1.0.jar", wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if ok := h.checkNaming(w, httptest.NewRequest(tt.method, tt.path, nil)); ok != tt.wantOK {
+				t.Fatalf("checkNaming() = %v, want %v", ok, tt.wantOK)
+			}
+			if !tt.wantOK && (w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "does not follow the naming convention")) {
+				t.Errorf("status = %d, body = %s, want 400 explaining the convention", w.Code, w.Body.String())
+			}
+		})
+	}
+}
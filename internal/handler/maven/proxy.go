@@ -7,11 +7,121 @@ import (
 
 	"github.com/mainuli/artifusion/internal/config"
 	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/proxy/rewriter"
 )
 
-// proxyWithRewriting proxies the request to the backend with URL rewriting
-func (h *Handler) proxyWithRewriting(w http.ResponseWriter, r *http.Request, backend *config.MavenBackendConfig) error {
-	// Strip path prefix before sending to backend
+// proxyWithRewriting proxies the request to the backend with URL rewriting.
+// arm is the experiment arm the request was assigned to, or empty if none.
+func (h *Handler) proxyWithRewriting(w http.ResponseWriter, r *http.Request, backend *config.MavenBackendConfig, arm string) error {
+	path := h.backendPath(r)
+
+	resp, err := h.executeProxyRequest(r, backend, path, arm)
+	if err != nil {
+		return err
+	}
+
+	// Retry reads the backend doesn't have against the read-through upstream
+	fromUpstream := false
+	if upstream := h.config.Upstream; upstream != nil && resp.StatusCode == http.StatusNotFound && isReadMethod(r.Method) {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			h.logger.Warn().Err(closeErr).Msg("Failed to close response body")
+		}
+
+		h.logger.Debug().
+			Str("backend", backend.Name).
+			Str("upstream", upstream.Name).
+			Str("path", path).
+			Msg("Artifact not found in backend, trying upstream")
+
+		resp, err = h.executeProxyRequest(r, upstream, path, "")
+		if err != nil {
+			return err
+		}
+		backend = upstream
+		fromUpstream = true
+	}
+
+	// Backends that don't list directories answer them with 404
+	if h.config.DirectoryListing && resp.StatusCode == http.StatusNotFound && isReadMethod(r.Method) && isDirectoryPath(path) {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			h.logger.Warn().Err(closeErr).Msg("Failed to close response body")
+		}
+		return h.serveDirectoryListing(w, r, path)
+	}
+
+	writeBack := fromUpstream && h.shouldWriteBack(r, resp, path)
+	h.recordArtifact(r, path, resp.StatusCode)
+
+	// Determine proxy URL for rewriting (base URL + path prefix)
+	proxyURL := h.determineProxyURL(r)
+
+	// Rewrite Location header (for redirects)
+	if !rewriter.RewriteRedirectLocation(resp, backend, proxyURL) {
+		if location := resp.Headers.Get("Location"); location != "" {
+			rewritten := h.rewriteURL(
+				location,
+				backend.URL,
+				backend.URL,
+				proxyURL,
+			)
+			resp.Headers.Set("Location", rewritten)
+		}
+	}
+
+	// Get content type
+	contentType := resp.Headers.Get("Content-Type")
+
+	// Check if we should rewrite the body
+	if h.shouldRewriteBody(contentType) {
+		// Buffer and rewrite text content (XML, POM files, metadata)
+		body, err := h.proxyClient.ReadResponseBody(resp)
+		if err != nil {
+			// Close response body before returning to prevent resource leak
+			if closeErr := resp.Body.Close(); closeErr != nil {
+				h.logger.Warn().Err(closeErr).Msg("Failed to close response body after read error")
+			}
+			w.WriteHeader(resp.StatusCode)
+			return err
+		}
+
+		// Write back the upstream's original content, not the rewritten one
+		if writeBack {
+			h.writeBackBytes(r, path, body, contentType)
+		}
+
+		// Rewrite URLs in the decompressed body, compressing the result as the
+		// backend did
+		rewritten, err := proxy.RewriteEncodedBody(body, resp.Headers, func(decoded []byte) []byte {
+			return h.rewriteBody(decoded, backend.URL, backend.URL, proxyURL)
+		})
+		if err != nil {
+			h.logger.Warn().Err(err).
+				Str("content_encoding", resp.Headers.Get("Content-Encoding")).
+				Msg("Failed to decode response body for rewriting, returning original")
+		}
+
+		// Write modified response with validators of the rewritten document, so
+		// conditional requests for maven-metadata.xml revalidate against what was served
+		return h.proxyClient.WriteRewrittenResponse(w, r, resp, rewritten)
+	}
+
+	// Stream binary content (JARs, WARs, etc.) without modification
+	var capture *proxy.BodyCapture
+	if writeBack {
+		if capture, err = proxy.CaptureBody(resp); err != nil {
+			h.logger.Warn().Err(err).Str("path", path).Msg("Failed to capture artifact for write-back")
+		}
+	}
+
+	_, err = h.proxyClient.StreamResponse(w, resp, true)
+	if capture != nil {
+		h.writeBackCapture(r, path, capture, contentType)
+	}
+	return err
+}
+
+// backendPath returns the request path with the path prefix stripped
+func (h *Handler) backendPath(r *http.Request) string {
 	path := r.URL.Path
 	if h.config.PathPrefix != "" {
 		path = strings.TrimPrefix(path, h.config.PathPrefix)
@@ -20,7 +130,13 @@ func (h *Handler) proxyWithRewriting(w http.ResponseWriter, r *http.Request, bac
 			path = "/" + path
 		}
 	}
+	return path
+}
 
+// executeProxyRequest sends the request to backend and records backend metrics,
+// returning the response without writing it.
+// arm is the experiment arm the request was assigned to, or empty if none.
+func (h *Handler) executeProxyRequest(r *http.Request, backend *config.MavenBackendConfig, path, arm string) (*proxy.Response, error) {
 	// Create proxy request
 	proxyReq := &proxy.Request{
 		Method:      r.Method,
@@ -41,6 +157,14 @@ func (h *Handler) proxyWithRewriting(w http.ResponseWriter, r *http.Request, bac
 	// Record metrics regardless of success/failure
 	duration := time.Since(start)
 
+	if arm != "" {
+		statusCode := 0
+		if err == nil {
+			statusCode = resp.StatusCode
+		}
+		h.metrics.RecordExperimentRequest(h.Name(), arm, statusCode, duration)
+	}
+
 	if err != nil {
 		// Record backend error metrics
 		h.metrics.RecordBackendError(h.Name(), backend.Name, "network_error")
@@ -52,7 +176,7 @@ func (h *Handler) proxyWithRewriting(w http.ResponseWriter, r *http.Request, bac
 			Dur("duration", duration).
 			Msg("Backend request failed")
 
-		return err
+		return nil, err
 	}
 
 	// Record backend latency for all requests
@@ -69,49 +193,5 @@ func (h *Handler) proxyWithRewriting(w http.ResponseWriter, r *http.Request, bac
 	}
 	// 4xx errors don't affect backend health (client errors)
 
-	// Determine proxy URL for rewriting (base URL + path prefix)
-	proxyURL := h.determineProxyURL(r)
-
-	// Rewrite Location header (for redirects)
-	if location := resp.Headers.Get("Location"); location != "" {
-		rewritten := h.rewriteURL(
-			location,
-			h.config.Backend.URL,
-			h.config.Backend.URL,
-			proxyURL,
-		)
-		resp.Headers.Set("Location", rewritten)
-	}
-
-	// Get content type
-	contentType := resp.Headers.Get("Content-Type")
-
-	// Check if we should rewrite the body
-	if h.shouldRewriteBody(contentType) {
-		// Buffer and rewrite text content (XML, POM files, metadata)
-		body, err := h.proxyClient.ReadResponseBody(resp)
-		if err != nil {
-			// Close response body before returning to prevent resource leak
-			if closeErr := resp.Body.Close(); closeErr != nil {
-				h.logger.Warn().Err(closeErr).Msg("Failed to close response body after read error")
-			}
-			w.WriteHeader(resp.StatusCode)
-			return err
-		}
-
-		// Rewrite URLs in body
-		rewritten := h.rewriteBody(
-			body,
-			h.config.Backend.URL,
-			h.config.Backend.URL,
-			proxyURL,
-		)
-
-		// Write modified response
-		return h.proxyClient.WriteResponse(w, resp, rewritten, true)
-	}
-
-	// Stream binary content (JARs, WARs, etc.) without modification
-	_, err = h.proxyClient.StreamResponse(w, resp, true)
-	return err
+	return resp, nil
 }
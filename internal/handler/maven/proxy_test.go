@@ -0,0 +1,72 @@
+package maven
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// TestSelectBackendAndProxy_RewrittenETag tests that rewritten metadata is served with
+// an ETag of the rewritten document, which conditional requests revalidate against
+func TestSelectBackendAndProxy_RewrittenETag(t *testing.T) {
+	var backend *httptest.Server
+	backend = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Header().Set("ETag", `"backend"`)
+		if r.Header.Get("If-None-Match") == `"backend"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("<metadata><url>" + backend.URL + "/com/example/app</url></metadata>"))
+	}))
+	defer backend.Close()
+
+	cfg := &config.MavenConfig{
+		PathPrefix: "/maven",
+		Backend: config.MavenBackendConfig{
+			Name:                "reposilite",
+			URL:                 backend.URL,
+			MaxIdleConns:        1,
+			MaxIdleConnsPerHost: 1,
+			DialTimeout:         time.Second,
+			RequestTimeout:      10 * time.Second,
+		},
+	}
+
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("maven_proxy_test"), logger)
+
+	serve := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/maven/com/example/app/maven-metadata.xml", nil)
+		r.Host = "artifusion.example.com"
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+			t.Fatalf("selectBackendAndProxy failed: %v", err)
+		}
+		return w
+	}
+
+	w := serve("")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "https://artifusion.example.com/maven/com/example/app") {
+		t.Fatalf("response = %d %q, want 200 with rewritten URLs", w.Code, w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+	if want := proxy.RewrittenETag(w.Body.Bytes(), false); etag != want {
+		t.Fatalf("ETag = %q, want %q", etag, want)
+	}
+
+	if w := serve(etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("revalidation = %d %q, want 304 without body", w.Code, w.Body.String())
+	}
+}
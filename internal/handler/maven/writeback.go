@@ -0,0 +1,102 @@
+package maven
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/proxy"
+)
+
+// isReadMethod reports whether a request only reads from the repository
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// shouldWriteBack reports whether an upstream response is an artifact to publish
+// into the backend. Repository metadata is skipped: the backend maintains its own
+// maven-metadata.xml from the artifacts it holds.
+func (h *Handler) shouldWriteBack(r *http.Request, resp *proxy.Response, artifactPath string) bool {
+	if h.writeBacks == nil || r.Method != http.MethodGet || resp.StatusCode != http.StatusOK {
+		return false
+	}
+
+	// An encoded body is not the artifact itself
+	if resp.Headers.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	name := path.Base(artifactPath)
+	return name != "/" && !strings.HasPrefix(name, "maven-metadata")
+}
+
+// writeBackBytes publishes a buffered upstream artifact into the backend in the background
+func (h *Handler) writeBackBytes(r *http.Request, artifactPath string, body []byte, contentType string) {
+	ctx := context.WithoutCancel(r.Context())
+	h.writeBacks.Submit(artifactPath, func() error {
+		return h.publish(ctx, artifactPath, bytes.NewReader(body), contentType)
+	})
+}
+
+// writeBackCapture publishes a streamed upstream artifact into the backend in the
+// background, once it was streamed to the client completely
+func (h *Handler) writeBackCapture(r *http.Request, artifactPath string, capture *proxy.BodyCapture, contentType string) {
+	remove := func() {
+		if err := capture.Remove(); err != nil {
+			h.logger.Warn().Err(err).Msg("Failed to remove write-back capture file")
+		}
+	}
+
+	file, _, err := capture.Open()
+	if err != nil {
+		h.logger.Debug().Err(err).
+			Str("path", artifactPath).
+			Msg("Not writing back incompletely streamed artifact")
+		remove()
+		return
+	}
+
+	ctx := context.WithoutCancel(r.Context())
+	if !h.writeBacks.Submit(artifactPath, func() error {
+		defer remove()
+		return h.publish(ctx, artifactPath, file, contentType)
+	}) {
+		remove()
+	}
+}
+
+// publish deploys an artifact into the backend with a PUT, as a Maven deploy would
+func (h *Handler) publish(ctx context.Context, artifactPath string, body io.Reader, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, artifactPath, nil)
+	if err != nil {
+		return err
+	}
+
+	headers := http.Header{}
+	if contentType != "" {
+		headers.Set("Content-Type", contentType)
+	}
+
+	resp, err := h.proxyClient.ProxyRequest(&proxy.Request{
+		Method:      http.MethodPut,
+		Path:        artifactPath,
+		Body:        body,
+		Headers:     headers,
+		Backend:     &h.config.Backend,
+		OriginalReq: req,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("backend %s returned %d", h.config.Backend.Name, resp.StatusCode)
+	}
+	return nil
+}
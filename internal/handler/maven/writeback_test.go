@@ -0,0 +1,104 @@
+package maven
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// TestSelectBackendAndProxy_WriteBack tests that artifacts missing from the backend are
+// served from the upstream and published into the backend
+func TestSelectBackendAndProxy_WriteBack(t *testing.T) {
+	var mu sync.Mutex
+	published := map[string]string{}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			published[r.URL.Path] = string(body)
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer backend.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/com/example/app/1.0/app-1.0.jar":
+			w.Header().Set("Content-Type", "application/java-archive")
+			_, _ = w.Write([]byte("jar bytes"))
+		case "/com/example/app/maven-metadata.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte("<metadata/>"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	backendConfig := func(name, url string) config.MavenBackendConfig {
+		return config.MavenBackendConfig{
+			Name:                name,
+			URL:                 url,
+			MaxIdleConns:        1,
+			MaxIdleConnsPerHost: 1,
+			DialTimeout:         time.Second,
+			RequestTimeout:      10 * time.Second,
+		}
+	}
+	upstreamCfg := backendConfig("central", upstream.URL)
+	cfg := &config.MavenConfig{
+		PathPrefix: "/maven",
+		Backend:    backendConfig("reposilite", backend.URL),
+		Upstream:   &upstreamCfg,
+		WriteBack:  true,
+	}
+
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("maven_write_back_test"), logger)
+
+	tests := []struct {
+		path          string
+		wantBody      string
+		wantPublished bool
+	}{
+		{"/maven/com/example/app/1.0/app-1.0.jar", "jar bytes", true},
+		{"/maven/com/example/app/maven-metadata.xml", "<metadata/>", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+				t.Fatalf("selectBackendAndProxy failed: %v", err)
+			}
+			h.writeBacks.Wait()
+
+			if w.Code != http.StatusOK || w.Body.String() != tt.wantBody {
+				t.Errorf("response = %d %q, want 200 %q", w.Code, w.Body.String(), tt.wantBody)
+			}
+
+			mu.Lock()
+			body, ok := published[tt.path[len("/maven"):]]
+			mu.Unlock()
+			if ok != tt.wantPublished {
+				t.Fatalf("published = %v, want %v", ok, tt.wantPublished)
+			}
+			if ok && body != tt.wantBody {
+				t.Errorf("published %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
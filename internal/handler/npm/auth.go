@@ -2,7 +2,7 @@ package npm
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 
 	"github.com/mainuli/artifusion/internal/auth"
@@ -31,20 +31,24 @@ func (h *Handler) handleAuthError(w http.ResponseWriter, r *http.Request, err er
 		Str("remote_addr", r.RemoteAddr).
 		Msg("Authentication failed")
 
-	// Set WWW-Authenticate challenge header with Bearer scheme (NPM standard)
-	realm := h.config.ClientAuth.Realm
-	if realm == "" {
-		realm = "Artifusion NPM Registry"
+	status, message := http.StatusUnauthorized, h.config.ClientAuth.UnauthorizedMessage("Authentication required. Please provide a valid GitHub Personal Access Token.")
+	if errors.Is(err, auth.ErrInsufficientPermissions) {
+		// The credentials are valid, so challenging for others wouldn't help
+		status, message = http.StatusForbidden, auth.InsufficientPermissionsMessage
+	} else {
+		// Set WWW-Authenticate challenge headers. A Basic challenge makes yarn prompt for
+		// credentials; npm sends its configured Bearer token (_authToken) either way.
+		auth.SetChallenges(w.Header(), &h.config.ClientAuth, auth.ChallengeOptions{
+			Schemes: []string{auth.SchemeBasic, auth.SchemeBearer},
+			Realm:   "Artifusion NPM Registry",
+		})
 	}
-
-	// NPM uses Bearer token authentication
-	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s"`, realm))
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnauthorized)
+	w.WriteHeader(status)
 
 	// Return NPM-compatible error response
 	errResp := npmErrorResponse{
-		Error: "Authentication required. Please provide a valid GitHub Personal Access Token.",
+		Error: message,
 	}
 
 	if err := json.NewEncoder(w).Encode(errResp); err != nil {
@@ -0,0 +1,59 @@
+package npm
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// addLogFields adds the package the request targets to its completion log line:
+// npm_package, plus npm_version for version documents and tarballs
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	name, version, ok := parsePackagePath(h.backendPath(r))
+	if !ok {
+		return
+	}
+	middleware.AddLogField(ctx, "npm_package", name)
+	middleware.AddLogField(ctx, "npm_version", version)
+}
+
+// parsePackagePath extracts the package name and version (empty for the
+// packument) from a registry path. Registry endpoints such as /-/v1/search are
+// not package paths.
+//
+//	/lodash                          -> lodash, ""
+//	/lodash/4.17.21                  -> lodash, 4.17.21
+//	/@types%2fnode                   -> @types/node, ""
+//	/@types/node/20.1.0              -> @types/node, 20.1.0
+//	/lodash/-/lodash-4.17.21.tgz     -> lodash, 4.17.21
+func parsePackagePath(path string) (name, version string, ok bool) {
+	if name, version, ok := parseTarballPath(path); ok {
+		return name, version, true
+	}
+
+	unescaped, err := url.PathUnescape(strings.Trim(path, "/"))
+	if err != nil || unescaped == "" || strings.HasPrefix(unescaped, "-/") {
+		return "", "", false
+	}
+
+	segments := strings.Split(unescaped, "/")
+	scoped := strings.HasPrefix(segments[0], "@")
+	nameSegments := 1
+	if scoped {
+		nameSegments = 2
+	}
+	if len(segments) < nameSegments || len(segments) > nameSegments+1 {
+		return "", "", false
+	}
+
+	name = strings.Join(segments[:nameSegments], "/")
+	if len(segments) > nameSegments {
+		version = segments[nameSegments]
+	}
+	return name, version, true
+}
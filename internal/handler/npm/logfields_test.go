@@ -0,0 +1,36 @@
+package npm
+
+import "testing"
+
+func TestParsePackagePath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantName    string
+		wantVersion string
+		wantOK      bool
+	}{
+		{"/lodash", "lodash", "", true},
+		{"/lodash/4.17.21", "lodash", "4.17.21", true},
+		{"/lodash/latest", "lodash", "latest", true},
+		{"/@types%2fnode", "@types/node", "", true},
+		{"/@types%2Fnode", "@types/node", "", true},
+		{"/@types/node", "@types/node", "", true},
+		{"/@types/node/20.1.0", "@types/node", "20.1.0", true},
+		{"/lodash/-/lodash-4.17.21.tgz", "lodash", "4.17.21", true},
+		{"/@types/node/-/node-20.1.0.tgz", "@types/node", "20.1.0", true},
+		{"/-/v1/search", "", "", false},
+		{"/-/ping", "", "", false},
+		{"/", "", "", false},
+		{"/lodash/4.17.21/extra", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			name, version, ok := parsePackagePath(tt.path)
+			if name != tt.wantName || version != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("parsePackagePath(%q) = %q, %q, %v, want %q, %q, %v",
+					tt.path, name, version, ok, tt.wantName, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
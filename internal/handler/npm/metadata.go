@@ -0,0 +1,33 @@
+package npm
+
+import (
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/metadata"
+)
+
+// SetMetadata enables recording downloaded and published packages in the metadata
+// database
+func (h *Handler) SetMetadata(store *metadata.Store) {
+	h.metadata = store
+}
+
+// recordArtifact records a request the backend answered successfully: tarball
+// downloads as pulls of their version, and publishes as pushes of the package.
+// Publishes carry their versions in the body, so they are recorded without one.
+func (h *Handler) recordArtifact(r *http.Request, path string, statusCode int) {
+	if h.metadata == nil || statusCode < 200 || statusCode >= 300 {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if name, version, ok := parseTarballPath(path); ok {
+			h.metadata.RecordPull(h.Name(), name, version, "")
+		}
+	case http.MethodPut:
+		if name, version, ok := parsePackagePath(path); ok && version == "" {
+			h.metadata.RecordPush(h.Name(), name, "", "", metadata.NewProvenance(r))
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package npm
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// checkNaming rejects publishes of packages whose scope doesn't follow the
+// configured naming convention, returning false if it wrote the rejection.
+// Unscoped packages are checked with an empty scope.
+func (h *Handler) checkNaming(w http.ResponseWriter, r *http.Request) bool {
+	if h.naming == nil || !h.isWriteOperation(r.Method) {
+		return true
+	}
+	name, _, ok := parsePackagePath(h.backendPath(r))
+	if !ok {
+		return true
+	}
+	var scope string
+	if strings.HasPrefix(name, "@") {
+		scope, _, _ = strings.Cut(name, "/")
+	}
+
+	err := h.naming.Check("scope", scope)
+	if err == nil {
+		return true
+	}
+	h.logger.Warn().Err(err).
+		Str("package", name).
+		Str("method", r.Method).
+		Msg("Publish rejected by naming convention")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	if err := json.NewEncoder(w).Encode(npmErrorResponse{Error: err.Error()}); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode error response")
+	}
+	return false
+}
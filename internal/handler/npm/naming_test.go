@@ -0,0 +1,55 @@
+package npm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/rs/zerolog"
+)
+
+func TestCheckNaming(t *testing.T) {
+	cfg := &config.NPMConfig{
+		PathPrefix: "/npm",
+		Naming:     config.NamingConfig{Patterns: []string{"^@myorg$"}, Message: "publish under @myorg"},
+	}
+	h := &Handler{config: cfg, naming: middleware.NewNamingPolicy(&cfg.Naming), logger: zerolog.Nop()}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		wantOK bool
+	}{
+		{name: "conforming publish", method: http.MethodPut, path: "/npm/@myorg%2fui", wantOK: true},
+		{name: "nonconforming scope", method: http.MethodPut, path: "/npm/@other%2fui"},
+		{name: "scope with the right prefix", method: http.MethodPut, path: "/npm/@myorg-labs%2fui"},
+		{name: "unscoped publish", method: http.MethodPut, path: "/npm/left-pad"},
+		{name: "unscoped read", method: http.MethodGet, path: "/npm/left-pad", wantOK: true},
+		{name: "registry endpoint", method: http.MethodPost, path: "/npm/-/npm/v1/security/advisories/bulk", wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if ok := h.checkNaming(w, httptest.NewRequest(tt.method, tt.path, nil)); ok != tt.wantOK {
+				t.Fatalf("checkNaming() = %v, want %v", ok, tt.wantOK)
+			}
+			if tt.wantOK {
+				return
+			}
+
+			var body npmErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body = %s, want an npm error", w.Body.String())
+			}
+			if w.Code != http.StatusBadRequest || !strings.HasSuffix(body.Error, ": publish under @myorg") {
+				t.Errorf("status = %d, error = %q, want 400 with the configured message", w.Code, body.Error)
+			}
+		})
+	}
+}
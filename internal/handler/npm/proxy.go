@@ -1,20 +1,19 @@
 package npm
 
 import (
-	"bytes"
-	"compress/gzip"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/mainuli/artifusion/internal/config"
 	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/proxy/rewriter"
 )
 
-// proxyWithRewriting proxies the request to the backend with URL rewriting
-func (h *Handler) proxyWithRewriting(w http.ResponseWriter, r *http.Request, backend *config.NPMBackendConfig) error {
+// proxyWithRewriting proxies the request to the backend with URL rewriting.
+// arm is the experiment arm the request was assigned to, or empty if none.
+func (h *Handler) proxyWithRewriting(w http.ResponseWriter, r *http.Request, backend *config.NPMBackendConfig, arm string) error {
 	// Validate inputs
 	if r == nil {
 		return fmt.Errorf("request is nil")
@@ -26,7 +25,108 @@ func (h *Handler) proxyWithRewriting(w http.ResponseWriter, r *http.Request, bac
 		return fmt.Errorf("request URL is nil")
 	}
 
-	// Strip path prefix before sending to backend
+	path := h.backendPath(r)
+
+	resp, err := h.executeProxyRequest(r, backend, path, arm)
+	if err != nil {
+		return err
+	}
+
+	// Retry reads the backend doesn't have against the read-through upstream
+	fromUpstream := false
+	if upstream := h.config.Upstream; upstream != nil && resp.StatusCode == http.StatusNotFound && isReadMethod(r.Method) {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			h.logger.Warn().Err(closeErr).Msg("Failed to close response body")
+		}
+
+		h.logger.Debug().
+			Str("backend", backend.Name).
+			Str("upstream", upstream.Name).
+			Str("path", path).
+			Msg("Package not found in backend, trying upstream")
+
+		resp, err = h.executeProxyRequest(r, upstream, path, "")
+		if err != nil {
+			return err
+		}
+		backend = upstream
+		fromUpstream = true
+	}
+	h.recordArtifact(r, path, resp.StatusCode)
+
+	// Determine proxy URL for rewriting (base URL + path prefix)
+	proxyURL := h.determineProxyURL(r)
+
+	// Rewrite Location header (for redirects)
+	if !rewriter.RewriteRedirectLocation(resp, backend, proxyURL) {
+		if location := resp.Headers.Get("Location"); location != "" {
+			rewritten := h.rewriteURL(
+				location,
+				backend.URL,
+				proxyURL,
+			)
+			resp.Headers.Set("Location", rewritten)
+		}
+	}
+
+	// Get content type
+	contentType := resp.Headers.Get("Content-Type")
+
+	// Check if we should rewrite the body
+	if h.shouldRewriteBody(contentType) {
+		// Buffer and rewrite JSON content (package metadata)
+		body, err := h.proxyClient.ReadResponseBody(resp)
+		if err != nil {
+			// Close response body before returning to prevent resource leak
+			if closeErr := resp.Body.Close(); closeErr != nil {
+				h.logger.Warn().Err(closeErr).Msg("Failed to close response body after read error")
+			}
+			w.WriteHeader(resp.StatusCode)
+			return err
+		}
+
+		// Rewrite URLs in the decompressed body, compressing the result as the
+		// backend did
+		rewritten, err := proxy.RewriteEncodedBody(body, resp.Headers, func(decoded []byte) []byte {
+			rewritten, err := h.rewritePackageJSON(decoded, backend.URL, proxyURL)
+			if err != nil {
+				// If rewriting fails, log warning but still return original content
+				h.logger.Warn().Err(err).
+					Str("content_type", contentType).
+					Msg("Failed to rewrite response body, returning original")
+				return decoded
+			}
+			return rewritten
+		})
+		if err != nil {
+			h.logger.Warn().Err(err).
+				Str("content_encoding", resp.Headers.Get("Content-Encoding")).
+				Msg("Failed to decode response body for rewriting, returning original")
+		}
+
+		// Write modified response with validators of the rewritten document, so
+		// npm's conditional requests and integrity checks see consistent metadata
+		return h.proxyClient.WriteRewrittenResponse(w, r, resp, rewritten)
+	}
+
+	// Stream binary content (tarballs) without modification
+	var capture *proxy.BodyCapture
+	if fromUpstream && h.shouldWriteBack(r, resp, path) {
+		if capture, err = proxy.CaptureBody(resp); err != nil {
+			h.logger.Warn().Err(err).Str("path", path).Msg("Failed to capture tarball for write-back")
+		}
+	}
+
+	// StreamResponse handles body close
+	_, err = h.proxyClient.StreamResponse(w, resp, true)
+	if capture != nil {
+		h.writeBackCapture(r, path, capture)
+	}
+	return err
+}
+
+// backendPath returns the request path with the path prefix stripped
+func (h *Handler) backendPath(r *http.Request) string {
 	path := r.URL.Path
 	if h.config.PathPrefix != "" {
 		path = strings.TrimPrefix(path, h.config.PathPrefix)
@@ -35,7 +135,13 @@ func (h *Handler) proxyWithRewriting(w http.ResponseWriter, r *http.Request, bac
 			path = "/" + path
 		}
 	}
+	return path
+}
 
+// executeProxyRequest sends the request to backend and records backend metrics,
+// returning the response without writing it.
+// arm is the experiment arm the request was assigned to, or empty if none.
+func (h *Handler) executeProxyRequest(r *http.Request, backend *config.NPMBackendConfig, path, arm string) (*proxy.Response, error) {
 	// Create proxy request
 	proxyReq := &proxy.Request{
 		Method:      r.Method,
@@ -56,6 +162,14 @@ func (h *Handler) proxyWithRewriting(w http.ResponseWriter, r *http.Request, bac
 	// Record metrics regardless of success/failure
 	duration := time.Since(start)
 
+	if arm != "" {
+		statusCode := 0
+		if err == nil {
+			statusCode = resp.StatusCode
+		}
+		h.metrics.RecordExperimentRequest(h.Name(), arm, statusCode, duration)
+	}
+
 	if err != nil {
 		// Record backend error metrics
 		h.metrics.RecordBackendError(h.Name(), backend.Name, "network_error")
@@ -67,7 +181,7 @@ func (h *Handler) proxyWithRewriting(w http.ResponseWriter, r *http.Request, bac
 			Dur("duration", duration).
 			Msg("Backend request failed")
 
-		return err
+		return nil, err
 	}
 
 	// Record backend latency for all requests
@@ -84,96 +198,5 @@ func (h *Handler) proxyWithRewriting(w http.ResponseWriter, r *http.Request, bac
 	}
 	// 4xx errors don't affect backend health (client errors)
 
-	// Determine proxy URL for rewriting (base URL + path prefix)
-	proxyURL := h.determineProxyURL(r)
-
-	// Rewrite Location header (for redirects)
-	if location := resp.Headers.Get("Location"); location != "" {
-		rewritten := h.rewriteURL(
-			location,
-			h.config.Backend.URL,
-			proxyURL,
-		)
-		resp.Headers.Set("Location", rewritten)
-	}
-
-	// Get content type
-	contentType := resp.Headers.Get("Content-Type")
-
-	// Check if we should rewrite the body
-	if h.shouldRewriteBody(contentType) {
-		// Buffer and rewrite JSON content (package metadata)
-		body, err := h.proxyClient.ReadResponseBody(resp)
-		if err != nil {
-			// Close response body before returning to prevent resource leak
-			if closeErr := resp.Body.Close(); closeErr != nil {
-				h.logger.Warn().Err(closeErr).Msg("Failed to close response body after read error")
-			}
-			w.WriteHeader(resp.StatusCode)
-			return err
-		}
-
-		// Decompress gzip content if needed for URL rewriting
-		contentEncoding := resp.Headers.Get("Content-Encoding")
-		if decompressed, wasDecompressed := h.decompressIfNeeded(body, contentEncoding); wasDecompressed {
-			body = decompressed
-			// Remove Content-Encoding header since we decompressed
-			resp.Headers.Del("Content-Encoding")
-			// Also remove Content-Length since it will change after rewriting
-			resp.Headers.Del("Content-Length")
-		}
-
-		// Rewrite URLs in body
-		rewritten, err := h.rewritePackageJSON(
-			body,
-			h.config.Backend.URL,
-			proxyURL,
-		)
-		if err != nil {
-			// If rewriting fails, log warning but still return original content
-			h.logger.Warn().Err(err).
-				Str("content_type", contentType).
-				Msg("Failed to rewrite response body, returning original")
-			rewritten = body
-		}
-
-		// Write modified response (WriteResponse handles body close)
-		return h.proxyClient.WriteResponse(w, resp, rewritten, true)
-	}
-
-	// Stream binary content (tarballs) without modification
-	// StreamResponse handles body close
-	_, err = h.proxyClient.StreamResponse(w, resp, true)
-	return err
-}
-
-// decompressIfNeeded decompresses gzip-encoded content if needed
-// Returns the decompressed body and true if decompression occurred, or original body and false otherwise
-func (h *Handler) decompressIfNeeded(body []byte, contentEncoding string) ([]byte, bool) {
-	if contentEncoding != "gzip" {
-		return body, false
-	}
-
-	gzReader, err := gzip.NewReader(bytes.NewReader(body))
-	if err != nil {
-		h.logger.Warn().Err(err).Msg("Failed to create gzip reader, using raw body")
-		return body, false
-	}
-
-	decompressed, err := io.ReadAll(gzReader)
-	if closeErr := gzReader.Close(); closeErr != nil {
-		h.logger.Warn().Err(closeErr).Msg("Failed to close gzip reader")
-	}
-
-	if err != nil {
-		h.logger.Warn().Err(err).Msg("Failed to decompress gzip body, using raw body")
-		return body, false
-	}
-
-	h.logger.Debug().
-		Int("compressed_size", len(body)).
-		Int("decompressed_size", len(decompressed)).
-		Msg("Decompressed gzip response for rewriting")
-
-	return decompressed, true
+	return resp, nil
 }
@@ -0,0 +1,81 @@
+package npm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// TestSelectBackendAndProxy_CompressedMetadata tests that tarball URLs in package
+// metadata the backend compressed are rewritten, and the result compressed again
+func TestSelectBackendAndProxy_CompressedMetadata(t *testing.T) {
+	for _, encoding := range []string{"gzip", "deflate"} {
+		t.Run(encoding, func(t *testing.T) {
+			var backend *httptest.Server
+			backend = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				document, _ := json.Marshal(map[string]any{
+					"name": "lodash",
+					"versions": map[string]any{
+						"4.17.21": map[string]any{"dist": map[string]any{"tarball": backend.URL + "/lodash/-/lodash-4.17.21.tgz"}},
+					},
+				})
+				body, _ := proxy.EncodeBody(document, encoding)
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Encoding", encoding)
+				_, _ = w.Write(body)
+			}))
+			defer backend.Close()
+
+			cfg := &config.NPMConfig{
+				PathPrefix: "/npm",
+				Backend: config.NPMBackendConfig{
+					Name:                "verdaccio",
+					URL:                 backend.URL,
+					MaxIdleConns:        1,
+					MaxIdleConnsPerHost: 1,
+					DialTimeout:         time.Second,
+					RequestTimeout:      10 * time.Second,
+				},
+			}
+			logger := zerolog.Nop()
+			h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("npm_proxy_test_"+encoding), logger)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/npm/lodash", nil)
+			r.Host = "artifusion.example.com"
+			r.Header.Set("Accept-Encoding", encoding)
+			if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+				t.Fatalf("selectBackendAndProxy failed: %v", err)
+			}
+
+			if w.Header().Get("Content-Encoding") != encoding {
+				t.Fatalf("Content-Encoding = %q, want %q", w.Header().Get("Content-Encoding"), encoding)
+			}
+			decoded, err := proxy.DecodeBody(w.Body.Bytes(), encoding, proxy.MaxDecodedBodySize)
+			if err != nil {
+				t.Fatalf("response doesn't decode: %v", err)
+			}
+			var document struct {
+				Versions map[string]struct {
+					Dist struct {
+						Tarball string `json:"tarball"`
+					} `json:"dist"`
+				} `json:"versions"`
+			}
+			if err := json.Unmarshal(decoded, &document); err != nil {
+				t.Fatalf("invalid metadata: %v", err)
+			}
+			if got, want := document.Versions["4.17.21"].Dist.Tarball, "https://artifusion.example.com/npm/lodash/-/lodash-4.17.21.tgz"; got != want {
+				t.Errorf("tarball = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -2,9 +2,18 @@ package npm
 
 import (
 	"fmt"
+	"math/rand/v2"
 	"net/http"
 
 	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// Experiment arms, used as the arm label of the experiment metrics
+const (
+	armControl   = "control"
+	armCandidate = "candidate"
 )
 
 // selectBackendAndProxy determines the appropriate backend and proxies the request
@@ -19,8 +28,9 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 
 	method := r.Method
 
-	// Use single backend for both read and write operations (like Maven pattern)
-	backend := &h.config.Backend
+	// Use single backend for both read and write operations (like Maven pattern), unless
+	// a read is selected for the candidate backend of an experiment
+	backend, arm := h.selectBackend(method)
 
 	// Validate backend configuration
 	if backend.URL == "" {
@@ -38,12 +48,28 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 		Str("backend", backend.Name).
 		Str("url", backend.URL).
 		Str("operation", operationType).
+		Str("arm", arm).
 		Str("username", authResult.Username).
 		Msg("Routing to NPM backend")
+	middleware.AddLogField(r.Context(), "backend", backend.Name)
 
 	// Note: Backend authentication is handled by proxy client
 	// Proxy with URL rewriting
-	return h.proxyWithRewriting(w, r, backend)
+	return h.proxyWithRewriting(w, r, backend, arm)
+}
+
+// selectBackend returns the backend for a request and its experiment arm.
+// The arm is empty when the request is not part of an experiment: no candidate is
+// configured, or the request is a write, which always goes to the primary backend.
+func (h *Handler) selectBackend(method string) (*config.NPMBackendConfig, string) {
+	if h.config.Candidate == nil || auth.IsWriteMethod(method) {
+		return &h.config.Backend, ""
+	}
+
+	if rand.Float64()*100 < h.config.CandidatePercent {
+		return h.config.Candidate, armCandidate
+	}
+	return &h.config.Backend, armControl
 }
 
 // isWriteOperation determines if the request is a write operation
@@ -0,0 +1,223 @@
+package npm
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/proxy"
+)
+
+// isReadMethod reports whether a request only reads from the registry
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// parseTarballPath extracts the package name and version from a tarball path:
+//
+//	/lodash/-/lodash-4.17.21.tgz          -> lodash, 4.17.21
+//	/@types/node/-/node-20.1.0.tgz        -> @types/node, 20.1.0
+//	/@types%2fnode/-/node-20.1.0.tgz      -> @types/node, 20.1.0
+//
+// ok is false if the path is not a tarball path.
+func parseTarballPath(tarballPath string) (name, version string, ok bool) {
+	pkg, file, found := strings.Cut(strings.TrimPrefix(tarballPath, "/"), "/-/")
+	if !found || pkg == "" || strings.Contains(file, "/") || !strings.HasSuffix(file, ".tgz") {
+		return "", "", false
+	}
+
+	name, err := url.PathUnescape(pkg)
+	if err != nil {
+		return "", "", false
+	}
+
+	// The file is named after the unscoped package name
+	unscoped := name
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		unscoped = name[i+1:]
+	}
+	version, found = strings.CutPrefix(strings.TrimSuffix(file, ".tgz"), unscoped+"-")
+	if !found || version == "" {
+		return "", "", false
+	}
+	return name, version, true
+}
+
+// escapePackageName escapes a package name for use as a registry path segment
+func escapePackageName(name string) string {
+	return strings.Replace(name, "/", "%2f", 1)
+}
+
+// shouldWriteBack reports whether an upstream response is a tarball to publish into
+// the backend. Package metadata isn't written back: publishing the tarball creates it.
+func (h *Handler) shouldWriteBack(r *http.Request, resp *proxy.Response, tarballPath string) bool {
+	if h.writeBacks == nil || r.Method != http.MethodGet || resp.StatusCode != http.StatusOK {
+		return false
+	}
+
+	// An encoded body is not the tarball itself
+	if resp.Headers.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	_, _, ok := parseTarballPath(tarballPath)
+	return ok
+}
+
+// writeBackCapture publishes a streamed upstream tarball into the backend in the
+// background, once it was streamed to the client completely
+func (h *Handler) writeBackCapture(r *http.Request, tarballPath string, capture *proxy.BodyCapture) {
+	remove := func() {
+		if err := capture.Remove(); err != nil {
+			h.logger.Warn().Err(err).Msg("Failed to remove write-back capture file")
+		}
+	}
+
+	file, size, err := capture.Open()
+	if err != nil {
+		h.logger.Debug().Err(err).
+			Str("path", tarballPath).
+			Msg("Not writing back incompletely streamed tarball")
+		remove()
+		return
+	}
+
+	ctx := context.WithoutCancel(r.Context())
+	if !h.writeBacks.Submit(tarballPath, func() error {
+		defer remove()
+		return h.publish(ctx, tarballPath, file, size)
+	}) {
+		remove()
+	}
+}
+
+// publish publishes an upstream tarball into the backend as npm publish would, with
+// the version's manifest from the upstream. No dist-tags are set, so mirroring an old
+// version doesn't move "latest".
+func (h *Handler) publish(ctx context.Context, tarballPath string, tarball *os.File, size int64) error {
+	name, version, ok := parseTarballPath(tarballPath)
+	if !ok {
+		return fmt.Errorf("not a tarball path: %s", tarballPath)
+	}
+
+	manifest, err := h.fetchVersionManifest(ctx, name, version)
+	if err != nil {
+		return fmt.Errorf("failed to fetch manifest of %s@%s: %w", name, version, err)
+	}
+
+	// Stream the document so the base64 tarball isn't held in memory
+	body, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writePublishDocument(pw, name, version, manifest, tarballPath, tarball, size))
+	}()
+	defer func() { _ = body.Close() }()
+
+	publishPath := "/" + escapePackageName(name)
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+
+	resp, err := h.do(ctx, &h.config.Backend, http.MethodPut, publishPath, headers, body)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("backend %s returned %d", h.config.Backend.Name, resp.StatusCode)
+	}
+	return nil
+}
+
+// fetchVersionManifest fetches the manifest of a package version from the upstream
+func (h *Handler) fetchVersionManifest(ctx context.Context, name, version string) (json.RawMessage, error) {
+	headers := http.Header{}
+	headers.Set("Accept", "application/json")
+
+	resp, err := h.do(ctx, h.config.Upstream, http.MethodGet, "/"+escapePackageName(name)+"/"+url.PathEscape(version), headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream %s returned %d", h.config.Upstream.Name, resp.StatusCode)
+	}
+
+	var manifest json.RawMessage
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MaxJSONRewriteSize)).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// do sends a request of the handler's own to backend
+func (h *Handler) do(ctx context.Context, backend *config.NPMBackendConfig, method, path string, headers http.Header, body io.Reader) (*proxy.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.proxyClient.ProxyRequest(&proxy.Request{
+		Method:      method,
+		Path:        path,
+		Body:        body,
+		Headers:     headers,
+		Backend:     backend,
+		OriginalReq: req,
+	})
+}
+
+// writePublishDocument writes the npm publish document for one version with the
+// tarball attached
+func writePublishDocument(w io.Writer, name, version string, manifest json.RawMessage, tarballPath string, tarball io.Reader, size int64) error {
+	fields := []struct {
+		key   string
+		value any
+	}{
+		{"_id", name},
+		{"name", name},
+		{"versions", map[string]json.RawMessage{version: manifest}},
+		{"dist-tags", map[string]string{}},
+	}
+
+	if _, err := io.WriteString(w, "{"); err != nil {
+		return err
+	}
+	for _, f := range fields {
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%q:%s,", f.key, value); err != nil {
+			return err
+		}
+	}
+
+	filename := tarballPath[strings.LastIndex(tarballPath, "/")+1:]
+	attachment, err := json.Marshal(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, `"_attachments":{%s:{"content_type":"application/octet-stream","length":%d,"data":"`, attachment, size); err != nil {
+		return err
+	}
+
+	enc := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := io.Copy(enc, tarball); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, `"}}}`)
+	return err
+}
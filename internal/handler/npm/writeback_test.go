@@ -0,0 +1,82 @@
+package npm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseTarballPath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantName    string
+		wantVersion string
+		wantOK      bool
+	}{
+		{"/lodash/-/lodash-4.17.21.tgz", "lodash", "4.17.21", true},
+		{"/@types/node/-/node-20.1.0.tgz", "@types/node", "20.1.0", true},
+		{"/@types%2fnode/-/node-20.1.0.tgz", "@types/node", "20.1.0", true},
+		{"/left-pad/-/left-pad-1.3.0-beta.1.tgz", "left-pad", "1.3.0-beta.1", true},
+		{"/lodash", "", "", false},
+		{"/lodash/4.17.21", "", "", false},
+		{"/lodash/-/other-4.17.21.tgz", "", "", false},
+		{"/lodash/-/lodash-4.17.21.tar", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			name, version, ok := parseTarballPath(tt.path)
+			if ok != tt.wantOK || name != tt.wantName || version != tt.wantVersion {
+				t.Errorf("parseTarballPath() = %q, %q, %v, want %q, %q, %v",
+					name, version, ok, tt.wantName, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestWritePublishDocument(t *testing.T) {
+	manifest := json.RawMessage(`{"name":"@types/node","version":"20.1.0"}`)
+	tarball := "tarball bytes"
+
+	var buf bytes.Buffer
+	err := writePublishDocument(&buf, "@types/node", "20.1.0", manifest,
+		"/@types%2fnode/-/node-20.1.0.tgz", strings.NewReader(tarball), int64(len(tarball)))
+	if err != nil {
+		t.Fatalf("writePublishDocument failed: %v", err)
+	}
+
+	var doc struct {
+		ID          string                     `json:"_id"`
+		Name        string                     `json:"name"`
+		Versions    map[string]json.RawMessage `json:"versions"`
+		DistTags    map[string]string          `json:"dist-tags"`
+		Attachments map[string]struct {
+			Length int64  `json:"length"`
+			Data   string `json:"data"`
+		} `json:"_attachments"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("document is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	if doc.ID != "@types/node" || doc.Name != "@types/node" {
+		t.Errorf("_id/name = %q/%q, want @types/node", doc.ID, doc.Name)
+	}
+	if string(doc.Versions["20.1.0"]) != string(manifest) {
+		t.Errorf("versions[20.1.0] = %s, want %s", doc.Versions["20.1.0"], manifest)
+	}
+	if len(doc.DistTags) != 0 {
+		t.Errorf("dist-tags = %v, want none", doc.DistTags)
+	}
+
+	attachment, ok := doc.Attachments["node-20.1.0.tgz"]
+	if !ok {
+		t.Fatalf("attachment node-20.1.0.tgz missing: %v", doc.Attachments)
+	}
+	data, err := base64.StdEncoding.DecodeString(attachment.Data)
+	if err != nil || string(data) != tarball || attachment.Length != int64(len(tarball)) {
+		t.Errorf("attachment = %q (%d bytes), want %q", data, attachment.Length, tarball)
+	}
+}
@@ -2,7 +2,7 @@ package oci
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 
 	"github.com/mainuli/artifusion/internal/auth"
@@ -18,7 +18,7 @@ type OCIError struct {
 type OCIErrorDetail struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
-	Detail  string `json:"detail,omitempty"`
+	Detail  any    `json:"detail,omitempty"` // A string or any JSON value, per the distribution spec
 }
 
 // authenticateClient validates the client's GitHub PAT using shared authenticator
@@ -38,25 +38,28 @@ func (h *Handler) handleAuthError(w http.ResponseWriter, r *http.Request, err er
 		Str("remote_addr", r.RemoteAddr).
 		Msg("Authentication failed")
 
-	// Set WWW-Authenticate challenge header
-	// If realm is empty, use Basic auth (direct authentication without token exchange)
-	// Otherwise, use Bearer auth with token endpoint
-	realm := h.config.ClientAuth.Realm
+	// The credentials are valid, so challenging for others wouldn't help
+	if errors.Is(err, auth.ErrInsufficientPermissions) {
+		if writeErr := writeOCIError(w, http.StatusForbidden, ociErrorCode(http.StatusForbidden), auth.InsufficientPermissionsMessage, nil); writeErr != nil {
+			h.logger.Error().Err(writeErr).Msg("Failed to encode auth error response")
+		}
+		return
+	}
+
+	// Set WWW-Authenticate challenge headers. Without a token endpoint (realm), clients
+	// authenticate directly with Basic auth; with one, they exchange their credentials
+	// for a token scoped to the request's repository.
 	service := h.config.ClientAuth.Service
 	if service == "" {
 		service = "artifusion"
 	}
-
-	var authHeader string
-	if realm == "" {
-		// Use Basic auth for direct GitHub PAT authentication
-		authHeader = fmt.Sprintf(`Basic realm="%s"`, service)
-	} else {
-		// Use Bearer auth with token endpoint
-		authHeader = fmt.Sprintf(`Bearer realm="%s",service="%s"`, realm, service)
-	}
-
-	w.Header().Set("WWW-Authenticate", authHeader)
+	auth.SetChallenges(w.Header(), &h.config.ClientAuth, auth.ChallengeOptions{
+		Schemes:       []string{auth.SchemeBearer, auth.SchemeBasic},
+		Realm:         service,
+		TokenEndpoint: true,
+		Scope:         h.challengeScope(r),
+		BearerParams:  []string{"service", service},
+	})
 	w.Header().Set("Docker-Distribution-Api-Version", "registry/2.0")
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusUnauthorized)
@@ -66,7 +69,7 @@ func (h *Handler) handleAuthError(w http.ResponseWriter, r *http.Request, err er
 		Errors: []OCIErrorDetail{
 			{
 				Code:    "UNAUTHORIZED",
-				Message: "authentication required",
+				Message: h.config.ClientAuth.UnauthorizedMessage("authentication required"),
 				Detail:  "GitHub PAT required via Bearer or Basic auth",
 			},
 		},
@@ -77,6 +80,22 @@ func (h *Handler) handleAuthError(w http.ResponseWriter, r *http.Request, err er
 	}
 }
 
+// challengeScope returns the token scope (distribution token authentication spec)
+// the request needs: pull, or pull and push, of its repository, or the catalog
+func (h *Handler) challengeScope(r *http.Request) string {
+	if r.URL.Path == "/v2/_catalog" {
+		return "registry:catalog:*"
+	}
+	repository, _, ok := parseImagePath(r.URL.Path)
+	if !ok {
+		return ""
+	}
+	if h.isWriteOperation(r.Method, r.URL.Path) {
+		return "repository:" + repository + ":pull,push"
+	}
+	return "repository:" + repository + ":pull"
+}
+
 // injectBackendAuth injects backend authentication credentials
 func (h *Handler) injectBackendAuth(r *http.Request, backend *config.OCIBackendConfig) {
 	if backend.Auth == nil {
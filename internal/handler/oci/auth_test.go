@@ -0,0 +1,116 @@
+package oci
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func TestChallengeScope(t *testing.T) {
+	h := &Handler{}
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/v2/", ""},
+		{http.MethodGet, "/v2/_catalog", "registry:catalog:*"},
+		{http.MethodGet, "/v2/team/app/manifests/v1", "repository:team/app:pull"},
+		{http.MethodHead, "/v2/team/app/blobs/sha256:abc", "repository:team/app:pull"},
+		{http.MethodPut, "/v2/team/app/manifests/v1", "repository:team/app:pull,push"},
+		{http.MethodPost, "/v2/team/app/blobs/uploads/", "repository:team/app:pull,push"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if got := h.challengeScope(httptest.NewRequest(tt.method, tt.path, nil)); got != tt.want {
+				t.Errorf("challengeScope() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleAuthError(t *testing.T) {
+	h := &Handler{config: &config.OCIConfig{}, logger: zerolog.Nop()}
+	tests := []struct {
+		name          string
+		err           error
+		wantStatus    int
+		wantCode      string
+		wantChallenge bool
+	}{
+		{"invalid credentials", fmt.Errorf("invalid token format"), http.StatusUnauthorized, "UNAUTHORIZED", true},
+		{"insufficient permissions", fmt.Errorf("authentication failed: %w", auth.ErrInsufficientPermissions), http.StatusForbidden, "DENIED", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.handleAuthError(rec, httptest.NewRequest(http.MethodPut, "/v2/team/app/manifests/v1", nil), tt.err)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("WWW-Authenticate") != ""; got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %q, want challenge %v", rec.Header().Get("WWW-Authenticate"), tt.wantChallenge)
+			}
+			var body OCIError
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if len(body.Errors) != 1 || body.Errors[0].Code != tt.wantCode {
+				t.Errorf("errors = %+v, want code %s", body.Errors, tt.wantCode)
+			}
+		})
+	}
+}
+
+// TestHandleAuthError_ReadOnlyFineGrainedPAT verifies that a fine-grained PAT whose
+// packages write grant can't be established is refused pushes with 403, even when
+// GitHub answers a write request with 404 as it does for unknown packages
+func TestHandleAuthError_ReadOnlyFineGrainedPAT(t *testing.T) {
+	githubAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/user":
+			fmt.Fprint(w, `{"login": "alice"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/user/packages":
+			fmt.Fprint(w, "[]")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+	defer githubAPI.Close()
+
+	githubClient := auth.NewGitHubClient(githubAPI.URL, time.Minute, 0, zerolog.Nop())
+	h := &Handler{
+		config:        &config.OCIConfig{},
+		authenticator: auth.NewClientAuthenticator(githubClient, "", nil, zerolog.Nop()),
+		logger:        zerolog.Nop(),
+	}
+	token := "github_pat_" + strings.Repeat("a", 22) + "_" + strings.Repeat("b", 59)
+
+	pull := httptest.NewRequest(http.MethodGet, "/v2/team/app/manifests/v1", nil)
+	pull.Header.Set("Authorization", "Bearer "+token)
+	if _, _, err := h.authenticateClient(pull); err != nil {
+		t.Fatalf("pull authentication error = %v, want nil", err)
+	}
+
+	push := httptest.NewRequest(http.MethodPut, "/v2/team/app/manifests/v1", nil)
+	push.Header.Set("Authorization", "Bearer "+token)
+	_, _, err := h.authenticateClient(push)
+	if err == nil {
+		t.Fatal("push authentication succeeded, want insufficient permissions")
+	}
+	rec := httptest.NewRecorder()
+	h.handleAuthError(rec, push, err)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("push status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
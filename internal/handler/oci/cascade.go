@@ -0,0 +1,71 @@
+package oci
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// BackendsTriedHeader lists the pull backends a cascading read tried, in order, with
+// each backend's outcome. Only set when oci.expose_cascade_result is enabled.
+const BackendsTriedHeader = "X-Artifusion-Backends-Tried"
+
+// backendAttempt is the outcome of trying one pull backend during a cascade
+type backendAttempt struct {
+	Backend string `json:"backend"`
+	Status  int    `json:"status,omitempty"` // Backend response status, 0 if no response
+	Error   string `json:"error,omitempty"`  // Why no response was received
+}
+
+// cascadeErrorDetail is the structured detail of the error returned when no pull
+// backend served a read
+type cascadeErrorDetail struct {
+	Message  string           `json:"message"`
+	Backends []backendAttempt `json:"backends"`
+}
+
+// cascadeResult collects the outcome of each pull backend tried by a cascading read
+type cascadeResult struct {
+	attempts []backendAttempt
+}
+
+// recordStatus records a backend that responded with status
+func (c *cascadeResult) recordStatus(backend string, status int) {
+	c.attempts = append(c.attempts, backendAttempt{Backend: backend, Status: status})
+}
+
+// recordError records a backend that could not be reached. The error itself is not
+// exposed, as it may contain internal addresses.
+func (c *cascadeResult) recordError(backend string) {
+	c.attempts = append(c.attempts, backendAttempt{Backend: backend, Error: "unreachable"})
+}
+
+// header formats the attempts for BackendsTriedHeader, e.g. "ghcr=404, mirror=503"
+func (c *cascadeResult) header() string {
+	parts := make([]string, len(c.attempts))
+	for i, a := range c.attempts {
+		outcome := a.Error
+		if a.Status != 0 {
+			outcome = strconv.Itoa(a.Status)
+		}
+		parts[i] = a.Backend + "=" + outcome
+	}
+	return strings.Join(parts, ", ")
+}
+
+// setBackendsTriedHeader sets BackendsTriedHeader on w if cascade results are
+// exposed and any backend was tried
+func (h *Handler) setBackendsTriedHeader(w http.ResponseWriter, result *cascadeResult) {
+	if h.config.ExposeCascadeResult && len(result.attempts) > 0 {
+		w.Header().Set(BackendsTriedHeader, result.header())
+	}
+}
+
+// cascadeErrorDetail returns the detail of the error returned when no pull backend
+// served a read: message alone, or with the per-backend outcomes if they are exposed
+func (h *Handler) cascadeErrorDetail(message string, result *cascadeResult) any {
+	if !h.config.ExposeCascadeResult || len(result.attempts) == 0 {
+		return message
+	}
+	return cascadeErrorDetail{Message: message, Backends: result.attempts}
+}
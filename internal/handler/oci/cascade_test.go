@@ -0,0 +1,69 @@
+package oci
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+// TestCascadeResult_Header tests the format of the backends-tried header
+func TestCascadeResult_Header(t *testing.T) {
+	var result cascadeResult
+	result.recordStatus("ghcr", 404)
+	result.recordError("mirror")
+	result.recordStatus("local", 200)
+
+	want := "ghcr=404, mirror=unreachable, local=200"
+	if got := result.header(); got != want {
+		t.Errorf("header() = %q, want %q", got, want)
+	}
+}
+
+// TestCascadeResult_Exposure tests that backend outcomes are only surfaced when enabled
+func TestCascadeResult_Exposure(t *testing.T) {
+	var result cascadeResult
+	result.recordStatus("ghcr", 404)
+	result.recordStatus("mirror", 503)
+
+	tests := []struct {
+		name       string
+		expose     bool
+		wantHeader string
+		wantDetail string
+	}{
+		{
+			name:       "disabled",
+			expose:     false,
+			wantHeader: "",
+			wantDetail: `"Image not found"`,
+		},
+		{
+			name:       "enabled",
+			expose:     true,
+			wantHeader: "ghcr=404, mirror=503",
+			wantDetail: `{"message":"Image not found","backends":[{"backend":"ghcr","status":404},{"backend":"mirror","status":503}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{config: &config.OCIConfig{ExposeCascadeResult: tt.expose}}
+
+			w := httptest.NewRecorder()
+			h.setBackendsTriedHeader(w, &result)
+			if got := w.Header().Get(BackendsTriedHeader); got != tt.wantHeader {
+				t.Errorf("header = %q, want %q", got, tt.wantHeader)
+			}
+
+			detail, err := json.Marshal(h.cascadeErrorDetail("Image not found", &result))
+			if err != nil {
+				t.Fatalf("failed to marshal detail: %v", err)
+			}
+			if string(detail) != tt.wantDetail {
+				t.Errorf("detail = %s, want %s", detail, tt.wantDetail)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package oci
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/authz"
+)
+
+// Explain reports how the request would be routed for authResult without proxying it.
+// Write operations are checked against the naming convention and go to the push
+// backend; reads list each pull backend in cascade order with whether it is
+// eligible, and fail if every backend is filtered.
+func (h *Handler) Explain(r *http.Request, authResult *auth.AuthResult) []authz.Rule {
+	path := r.URL.Path
+
+	if h.isWriteOperation(r.Method, path) {
+		var rules []authz.Rule
+		if h.naming != nil {
+			rule := authz.Rule{Name: "naming", Result: authz.ResultPass, Detail: "allowed by the naming convention"}
+			if _, err := h.namingViolation(r); err != nil {
+				rule.Result = authz.ResultFail
+				rule.Detail = err.Error()
+			}
+			rules = append(rules, rule)
+		}
+		return append(rules, authz.Rule{
+			Name:   "push_backend",
+			Result: authz.ResultPass,
+			Detail: fmt.Sprintf("routed to push backend %q", h.config.PushBackend.Name),
+		})
+	}
+
+	rules := make([]authz.Rule, 0, len(h.config.PullBackends)+1)
+	eligible := 0
+
+	for i := range h.config.PullBackends {
+		backend := &h.config.PullBackends[i]
+		rule := authz.Rule{Name: "pull_backend:" + backend.Name}
+
+		if reason := h.backendSkipReason(path, backend, authResult); reason != "" {
+			rule.Result = authz.ResultSkip
+			rule.Detail = reason
+		} else {
+			eligible++
+			rule.Result = authz.ResultPass
+			rule.Detail = fmt.Sprintf("eligible (cascade position %d)", eligible)
+		}
+		rules = append(rules, rule)
+	}
+
+	if eligible == 0 {
+		rules = append(rules, authz.Rule{
+			Name:   "pull_backends",
+			Result: authz.ResultFail,
+			Detail: "all pull backends filtered by organization or team scope",
+		})
+	}
+
+	return rules
+}
@@ -0,0 +1,134 @@
+package oci
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/authz"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/rs/zerolog"
+)
+
+// TestExplain tests dry-run routing explanations for reads and writes
+func TestExplain(t *testing.T) {
+	h := &Handler{
+		config: &config.OCIConfig{
+			PullBackends: []config.OCIBackendConfig{
+				{Name: "local"},
+				{Name: "mirror", Teams: []string{"platform"}},
+				{Name: "ghcr", UpstreamNamespace: "ghcr.io", Scope: []string{"myorg"}},
+			},
+			PushBackend: config.OCIBackendConfig{Name: "push"},
+		},
+		logger: zerolog.Nop(),
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		teams      []string
+		wantResult map[string]string
+	}{
+		{
+			name:   "read as platform member in scope",
+			method: http.MethodGet,
+			path:   "/v2/myorg/app/manifests/latest",
+			teams:  []string{"platform"},
+			wantResult: map[string]string{
+				"pull_backend:local":  authz.ResultPass,
+				"pull_backend:mirror": authz.ResultPass,
+				"pull_backend:ghcr":   authz.ResultPass,
+			},
+		},
+		{
+			name:   "read as non-member out of scope",
+			method: http.MethodGet,
+			path:   "/v2/otherorg/app/manifests/latest",
+			wantResult: map[string]string{
+				"pull_backend:local":  authz.ResultPass,
+				"pull_backend:mirror": authz.ResultSkip,
+				"pull_backend:ghcr":   authz.ResultSkip,
+			},
+		},
+		{
+			name:   "push manifest",
+			method: http.MethodPut,
+			path:   "/v2/myorg/app/manifests/latest",
+			wantResult: map[string]string{
+				"push_backend": authz.ResultPass,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rules := h.Explain(req, &auth.AuthResult{Username: "alice", Teams: tt.teams})
+
+			if len(rules) != len(tt.wantResult) {
+				t.Fatalf("expected %d rules, got %d: %+v", len(tt.wantResult), len(rules), rules)
+			}
+			for _, rule := range rules {
+				if want := tt.wantResult[rule.Name]; rule.Result != want {
+					t.Errorf("rule %s: expected %s, got %s (%s)", rule.Name, want, rule.Result, rule.Detail)
+				}
+			}
+		})
+	}
+}
+
+// TestExplain_AllFiltered tests that a read fails when every pull backend is filtered
+func TestExplain_AllFiltered(t *testing.T) {
+	h := &Handler{
+		config: &config.OCIConfig{
+			PullBackends: []config.OCIBackendConfig{
+				{Name: "mirror", Teams: []string{"platform"}},
+			},
+		},
+		logger: zerolog.Nop(),
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/myorg/app/manifests/latest", nil)
+	rules := h.Explain(req, &auth.AuthResult{Username: "bob"})
+
+	last := rules[len(rules)-1]
+	if last.Name != "pull_backends" || last.Result != authz.ResultFail {
+		t.Errorf("expected pull_backends failure, got %+v", last)
+	}
+}
+
+// TestExplain_Naming tests that pushes are checked against the naming convention
+func TestExplain_Naming(t *testing.T) {
+	cfg := &config.OCIConfig{
+		Naming:      config.NamingConfig{Patterns: []string{"^myorg/"}},
+		PushBackend: config.OCIBackendConfig{Name: "push"},
+	}
+	h := &Handler{config: cfg, naming: middleware.NewNamingPolicy(&cfg.Naming), logger: zerolog.Nop()}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantNaming string
+	}{
+		{name: "conforming", path: "/v2/myorg/app/manifests/v1", wantNaming: authz.ResultPass},
+		{name: "nonconforming", path: "/v2/other/app/manifests/v1", wantNaming: authz.ResultFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, tt.path, nil)
+			rules := h.Explain(req, &auth.AuthResult{Username: "alice"})
+
+			if len(rules) != 2 || rules[0].Name != "naming" || rules[1].Name != "push_backend" {
+				t.Fatalf("expected naming and push_backend rules, got %+v", rules)
+			}
+			if rules[0].Result != tt.wantNaming {
+				t.Errorf("naming: expected %s, got %s (%s)", tt.wantNaming, rules[0].Result, rules[0].Detail)
+			}
+		})
+	}
+}
@@ -7,9 +7,12 @@ import (
 	"github.com/mainuli/artifusion/internal/auth"
 	"github.com/mainuli/artifusion/internal/config"
 	"github.com/mainuli/artifusion/internal/detector"
-	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/metadata"
 	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/middleware"
 	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/replication"
+	"github.com/mainuli/artifusion/internal/trash"
 	"github.com/rs/zerolog"
 )
 
@@ -19,6 +22,12 @@ type Handler struct {
 	authenticator *auth.ClientAuthenticator
 	proxyClient   *proxy.Client
 	metrics       *metrics.Metrics
+	notFound      *notFoundCache           // nil = disabled
+	platforms     *platformFilter          // nil = disabled
+	replicator    *replication.Replicator  // nil = disabled
+	trash         *trash.Trash             // nil = disabled
+	metadata      *metadata.Store          // nil = disabled
+	naming        *middleware.NamingPolicy // nil = disabled
 	logger        zerolog.Logger
 }
 
@@ -35,17 +44,27 @@ func NewHandler(
 		authenticator: authenticator,
 		proxyClient:   proxyClient,
 		metrics:       metricsCollector,
+		notFound:      newNotFoundCache(cfg.NotFoundCacheTTL),
+		platforms:     newPlatformFilter(&cfg.PlatformFilter),
+		naming:        middleware.NewNamingPolicy(&cfg.Naming),
 		logger:        logger.With().Str("protocol", "oci").Logger(),
 	}
 }
 
-// ServeHTTP handles OCI/Docker registry requests
-func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+// ServeHTTP handles OCI/Docker registry requests. Errors and panics before the
+// response has started are reported in the distribution spec error format.
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w := &responseTracker{ResponseWriter: rw}
+	defer h.recoverPanic(w, r)
+
 	h.logger.Debug().
 		Str("method", r.Method).
 		Str("path", r.URL.Path).
 		Msg("OCI request received")
 
+	// Tag the request's log line with the artifact it targets
+	h.addLogFields(r)
+
 	// Step 1: Authenticate client
 	authResult, updatedReq, err := h.authenticateClient(r)
 	if err != nil {
@@ -53,14 +72,14 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Step 2: Select backend and proxy request
-	if err := h.selectBackendAndProxy(w, updatedReq, authResult); err != nil {
-		h.logger.Error().Err(err).
-			Str("path", updatedReq.URL.Path).
-			Str("method", updatedReq.Method).
-			Msg("Failed to proxy request")
+	// Step 2: Enforce the naming convention on pushes
+	if !h.checkNaming(w, updatedReq) {
+		return
+	}
 
-		errors.ErrorResponse(w, errors.ErrInternal.WithInternal(err))
+	// Step 3: Select backend and proxy request
+	if err := h.selectBackendAndProxy(w, updatedReq, authResult); err != nil {
+		h.writeError(w, updatedReq, err)
 	}
 }
 
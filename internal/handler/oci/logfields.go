@@ -0,0 +1,57 @@
+package oci
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// addLogFields adds the image the request targets to its completion log line:
+// oci_repository, plus oci_tag or oci_digest for manifests and blobs
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	repository, reference, ok := parseImagePath(r.URL.Path)
+	if !ok {
+		return
+	}
+	middleware.AddLogField(ctx, "oci_repository", repository)
+	if strings.Contains(reference, ":") {
+		middleware.AddLogField(ctx, "oci_digest", reference)
+	} else {
+		middleware.AddLogField(ctx, "oci_tag", reference)
+	}
+}
+
+// parseImagePath extracts the repository and the tag or digest from a registry
+// API path. reference is empty for paths that don't name one, such as tag lists
+// and upload sessions.
+//
+//	/v2/team/app/manifests/v1             -> team/app, v1
+//	/v2/team/app/blobs/sha256:abc         -> team/app, sha256:abc
+//	/v2/team/app/tags/list                -> team/app, ""
+//	/v2/team/app/blobs/uploads/<uuid>     -> team/app, ""
+func parseImagePath(path string) (repository, reference string, ok bool) {
+	if repository, reference, ok := parseManifestPath(path); ok {
+		return repository, reference, true
+	}
+
+	rest, ok := strings.CutPrefix(path, "/v2/")
+	if !ok {
+		return "", "", false
+	}
+	for _, marker := range []string{"/blobs/uploads/", "/blobs/uploads", "/tags/list", "/referrers/"} {
+		if idx := strings.LastIndex(rest, marker); idx > 0 {
+			return rest[:idx], "", true
+		}
+	}
+	if idx := strings.LastIndex(rest, "/blobs/"); idx > 0 {
+		digest := rest[idx+len("/blobs/"):]
+		if digest != "" && !strings.Contains(digest, "/") {
+			return rest[:idx], digest, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,33 @@
+package oci
+
+import "testing"
+
+func TestParseImagePath(t *testing.T) {
+	tests := []struct {
+		path           string
+		wantRepository string
+		wantReference  string
+		wantOK         bool
+	}{
+		{"/v2/team/app/manifests/v1", "team/app", "v1", true},
+		{"/v2/library/nginx/manifests/sha256:abc", "library/nginx", "sha256:abc", true},
+		{"/v2/team/app/blobs/sha256:abc", "team/app", "sha256:abc", true},
+		{"/v2/team/app/blobs/uploads/", "team/app", "", true},
+		{"/v2/team/app/blobs/uploads/1234-5678", "team/app", "", true},
+		{"/v2/team/app/tags/list", "team/app", "", true},
+		{"/v2/team/app/referrers/sha256:abc", "team/app", "", true},
+		{"/v2/", "", "", false},
+		{"/v2/_catalog", "", "", false},
+		{"/health", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			repository, reference, ok := parseImagePath(tt.path)
+			if repository != tt.wantRepository || reference != tt.wantReference || ok != tt.wantOK {
+				t.Errorf("parseImagePath(%q) = %q, %q, %v, want %q, %q, %v",
+					tt.path, repository, reference, ok, tt.wantRepository, tt.wantReference, tt.wantOK)
+			}
+		})
+	}
+}
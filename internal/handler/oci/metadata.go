@@ -0,0 +1,33 @@
+package oci
+
+import (
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/metadata"
+)
+
+// SetMetadata enables recording pulled and pushed manifests in the metadata database
+func (h *Handler) SetMetadata(store *metadata.Store) {
+	h.metadata = store
+}
+
+// recordPulled records a manifest GET the backend answered successfully. Blobs
+// belong to the manifests referencing them and are not recorded.
+func (h *Handler) recordPulled(r *http.Request, headers http.Header) {
+	if h.metadata == nil || r.Method != http.MethodGet {
+		return
+	}
+	if repository, reference, ok := parseManifestPath(r.URL.Path); ok {
+		h.metadata.RecordPull(h.Name(), repository, reference, headers.Get("Docker-Content-Digest"))
+	}
+}
+
+// recordPushed records the manifest a successful write pushed and who pushed it
+func (h *Handler) recordPushed(r *http.Request, headers http.Header) {
+	if h.metadata == nil || r.Method != http.MethodPut {
+		return
+	}
+	if repository, reference, ok := parseManifestPath(r.URL.Path); ok {
+		h.metadata.RecordPush(h.Name(), repository, reference, headers.Get("Docker-Content-Digest"), metadata.NewProvenance(r))
+	}
+}
@@ -0,0 +1,121 @@
+package oci
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// parseMirrorPath splits a path in the mirror namespace into the registry and the
+// path of the image within the registry
+// Example: /v2/mirror/docker.io/library/nginx/manifests/latest -> docker.io, /v2/library/nginx/manifests/latest
+func (h *Handler) parseMirrorPath(path string) (string, string, bool) {
+	mirror := &h.config.MirrorNamespace
+	if !mirror.Enabled {
+		return "", "", false
+	}
+	rest, ok := strings.CutPrefix(path, "/v2/"+mirror.Prefix+"/")
+	if !ok {
+		return "", "", false
+	}
+	registry, image, ok := strings.Cut(rest, "/")
+	if !ok || registry == "" || image == "" {
+		return "", "", false
+	}
+	return registry, "/v2/" + image, true
+}
+
+// mirrorBackend returns the pull backend serving registry in the mirror namespace,
+// or nil if there is none
+func (h *Handler) mirrorBackend(registry string) *config.OCIBackendConfig {
+	name := ""
+	for _, mapping := range h.config.MirrorNamespace.Registries {
+		if mapping.Registry == registry {
+			name = mapping.Backend
+			break
+		}
+	}
+
+	for i := range h.config.PullBackends {
+		backend := &h.config.PullBackends[i]
+		if (name != "" && backend.Name == name) || (name == "" && backend.UpstreamNamespace == registry) {
+			return backend
+		}
+	}
+	return nil
+}
+
+// serveMirror serves requests in the mirror namespace from the pull backend for the
+// named registry, without cascading. It returns false if the request is outside of
+// the namespace, leaving it to the caller.
+func (h *Handler) serveMirror(w http.ResponseWriter, r *http.Request, authResult *auth.AuthResult) (bool, error) {
+	registry, path, ok := h.parseMirrorPath(r.URL.Path)
+	if !ok {
+		return false, nil
+	}
+
+	if h.isWriteOperation(r.Method, r.URL.Path) {
+		return true, writeOCIError(w, http.StatusMethodNotAllowed, "UNSUPPORTED",
+			"the operation is unsupported", "The mirror namespace is read-only")
+	}
+
+	backend := h.mirrorBackend(registry)
+	if backend == nil {
+		h.logger.Debug().
+			Str("registry", registry).
+			Msg("No pull backend for mirrored registry")
+		return true, writeOCIError(w, http.StatusNotFound, "NAME_UNKNOWN",
+			"repository name not known to registry", "No upstream configured for registry "+registry)
+	}
+	if reason := h.backendSkipReason(r.URL.Path, backend, authResult); reason != "" {
+		h.logger.Debug().
+			Str("backend", backend.Name).
+			Str("registry", registry).
+			Str("reason", reason).
+			Msg("Mirrored registry not accessible")
+		return true, writeOCIError(w, http.StatusNotFound, "NAME_UNKNOWN",
+			"repository name not known to registry", "Image not accessible: backend filtered by team")
+	}
+
+	// Filtered image indexes are only held by Artifusion
+	if h.serveFilteredIndex(w, r, authResult) {
+		return true, nil
+	}
+
+	rewrittenPath := h.rewritePath(path, backend)
+
+	h.logger.Debug().
+		Str("backend", backend.Name).
+		Str("registry", registry).
+		Str("original_path", r.URL.Path).
+		Str("rewritten_path", rewrittenPath).
+		Msg("Routing to mirrored registry")
+
+	middleware.AddLogField(r.Context(), "backend", backend.Name)
+	h.injectBackendAuth(r, backend)
+
+	resp, err := h.executeProxyRequest(r, backend, rewrittenPath)
+	if err != nil {
+		return true, err
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			h.logger.Warn().Err(closeErr).Msg("Failed to close response body")
+		}
+	}()
+
+	if resp.StatusCode == http.StatusOK {
+		h.recordPulled(r, resp.Headers)
+
+		// Multi-platform indexes pulled by tag may be filtered to the client's platforms
+		if filtered, err := h.filterIndexResponse(w, r, backend, rewrittenPath, resp); filtered {
+			return true, err
+		}
+	}
+
+	_, err = h.proxyClient.StreamResponse(w, resp, true)
+	return true, err
+}
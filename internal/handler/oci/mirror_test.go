@@ -0,0 +1,148 @@
+package oci
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+func TestParseMirrorPath(t *testing.T) {
+	h := &Handler{config: &config.OCIConfig{
+		MirrorNamespace: config.MirrorNamespaceConfig{Enabled: true, Prefix: "mirror"},
+	}}
+
+	tests := []struct {
+		path         string
+		wantRegistry string
+		wantPath     string
+		wantOK       bool
+	}{
+		{"/v2/mirror/docker.io/library/nginx/manifests/latest", "docker.io", "/v2/library/nginx/manifests/latest", true},
+		{"/v2/mirror/ghcr.io/myorg/app/blobs/sha256:abc", "ghcr.io", "/v2/myorg/app/blobs/sha256:abc", true},
+		{"/v2/mirror/localhost:5000/app/tags/list", "localhost:5000", "/v2/app/tags/list", true},
+		{"/v2/mirror/docker.io", "", "", false},
+		{"/v2/mirror/", "", "", false},
+		{"/v2/mirrored/docker.io/nginx/manifests/latest", "", "", false},
+		{"/v2/myorg/mirror/docker.io/nginx/manifests/latest", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			registry, path, ok := h.parseMirrorPath(tt.path)
+			if registry != tt.wantRegistry || path != tt.wantPath || ok != tt.wantOK {
+				t.Errorf("parseMirrorPath() = %q, %q, %v, want %q, %q, %v",
+					registry, path, ok, tt.wantRegistry, tt.wantPath, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestSelectBackendAndProxy_Mirror tests that reads in the mirror namespace go to the
+// backend for the named registry, regardless of org scope
+func TestSelectBackendAndProxy_Mirror(t *testing.T) {
+	var requested []string
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+		_, _ = w.Write([]byte(`{"schemaVersion": 2}`))
+	}))
+	defer registry.Close()
+
+	backend := func(name, namespace string) config.OCIBackendConfig {
+		return config.OCIBackendConfig{
+			Name:                name,
+			URL:                 registry.URL,
+			UpstreamNamespace:   namespace,
+			MaxIdleConns:        1,
+			MaxIdleConnsPerHost: 1,
+			DialTimeout:         time.Second,
+			RequestTimeout:      10 * time.Second,
+		}
+	}
+	dockerhub := backend("dockerhub", "docker.io")
+	dockerhub.PathRewrite.AddLibraryPrefix = true
+	ghcr := backend("ghcr", "ghcr.io")
+	ghcr.Scope = []string{"myorg"}
+
+	cfg := &config.OCIConfig{
+		PullBackends: []config.OCIBackendConfig{ghcr, dockerhub, backend("quay", "")},
+		PushBackend:  backend("push", ""),
+		MirrorNamespace: config.MirrorNamespaceConfig{
+			Enabled:    true,
+			Prefix:     "mirror",
+			Registries: []config.MirrorRegistryConfig{{Registry: "quay.io", Backend: "quay"}},
+		},
+	}
+
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("oci_mirror_test"), logger)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantPath   string // Path requested from the backend, "" = none
+	}{
+		{
+			name:       "official image",
+			method:     http.MethodGet,
+			path:       "/v2/mirror/docker.io/nginx/manifests/latest",
+			wantStatus: http.StatusOK,
+			wantPath:   "/v2/docker.io/library/nginx/manifests/latest",
+		},
+		{
+			name:       "org outside of backend scope",
+			method:     http.MethodGet,
+			path:       "/v2/mirror/ghcr.io/otherorg/app/manifests/v1",
+			wantStatus: http.StatusOK,
+			wantPath:   "/v2/ghcr.io/otherorg/app/manifests/v1",
+		},
+		{
+			name:       "registry mapped to backend",
+			method:     http.MethodGet,
+			path:       "/v2/mirror/quay.io/prometheus/node-exporter/manifests/v1",
+			wantStatus: http.StatusOK,
+			wantPath:   "/v2/prometheus/node-exporter/manifests/v1",
+		},
+		{
+			name:       "unknown registry",
+			method:     http.MethodGet,
+			path:       "/v2/mirror/registry.k8s.io/pause/manifests/3.9",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "push",
+			method:     http.MethodPut,
+			path:       "/v2/mirror/docker.io/nginx/manifests/latest",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested = nil
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if err := h.selectBackendAndProxy(w, r, nil); err != nil {
+				t.Fatalf("selectBackendAndProxy failed: %v", err)
+			}
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			switch {
+			case tt.wantPath == "" && len(requested) > 0:
+				t.Errorf("backend requested %v, want no request", requested)
+			case tt.wantPath != "" && (len(requested) != 1 || requested[0] != tt.wantPath):
+				t.Errorf("backend requested %v, want [%s]", requested, tt.wantPath)
+			}
+		})
+	}
+}
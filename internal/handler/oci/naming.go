@@ -0,0 +1,36 @@
+package oci
+
+import "net/http"
+
+// checkNaming rejects pushes to repositories that don't follow the configured
+// naming convention, returning false if it wrote the rejection
+func (h *Handler) checkNaming(w http.ResponseWriter, r *http.Request) bool {
+	repository, err := h.namingViolation(r)
+	if err == nil {
+		return true
+	}
+	h.logger.Warn().Err(err).
+		Str("repository", repository).
+		Str("method", r.Method).
+		Msg("Push rejected by naming convention")
+	_ = writeOCIError(w, http.StatusBadRequest, "NAME_INVALID", "invalid repository name", err.Error())
+	return false
+}
+
+// namingViolation returns the repository r pushes to and why its name breaks the
+// configured naming convention, or a nil error if r is allowed. Deletes are
+// allowed, so nonconforming repositories can be cleaned up, and the read-only
+// mirror namespace reports its own error.
+func (h *Handler) namingViolation(r *http.Request) (string, error) {
+	if h.naming == nil || r.Method == http.MethodDelete || !h.isWriteOperation(r.Method, r.URL.Path) {
+		return "", nil
+	}
+	if _, _, ok := h.parseMirrorPath(r.URL.Path); ok {
+		return "", nil
+	}
+	repository, _, ok := parseImagePath(r.URL.Path)
+	if !ok {
+		return "", nil
+	}
+	return repository, h.naming.Check("repository", repository)
+}
@@ -0,0 +1,60 @@
+package oci
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/rs/zerolog"
+)
+
+func TestCheckNaming(t *testing.T) {
+	cfg := &config.OCIConfig{
+		Naming:          config.NamingConfig{Patterns: []string{"^myorg/"}},
+		MirrorNamespace: config.MirrorNamespaceConfig{Enabled: true, Prefix: "mirror"},
+	}
+	h := &Handler{config: cfg, naming: middleware.NewNamingPolicy(&cfg.Naming), logger: zerolog.Nop()}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		wantOK bool
+	}{
+		{name: "conforming manifest push", method: http.MethodPut, path: "/v2/myorg/app/manifests/v1", wantOK: true},
+		{name: "conforming upload", method: http.MethodPost, path: "/v2/myorg/app/blobs/uploads/", wantOK: true},
+		{name: "nonconforming manifest push", method: http.MethodPut, path: "/v2/app/manifests/v1"},
+		{name: "nonconforming upload", method: http.MethodPost, path: "/v2/other/app/blobs/uploads/"},
+		{name: "nonconforming upload chunk", method: http.MethodPatch, path: "/v2/other/app/blobs/uploads/0b1c"},
+		{name: "nonconforming read", method: http.MethodGet, path: "/v2/library/nginx/manifests/latest", wantOK: true},
+		{name: "nonconforming delete", method: http.MethodDelete, path: "/v2/app/manifests/sha256:abc", wantOK: true},
+		{name: "mirror namespace", method: http.MethodPut, path: "/v2/mirror/docker.io/library/nginx/manifests/latest", wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if ok := h.checkNaming(w, httptest.NewRequest(tt.method, tt.path, nil)); ok != tt.wantOK {
+				t.Fatalf("checkNaming() = %v, want %v", ok, tt.wantOK)
+			}
+			if tt.wantOK {
+				return
+			}
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			var body OCIError
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.Errors) != 1 {
+				t.Fatalf("body = %s, want an OCI error", w.Body.String())
+			}
+			if detail, _ := body.Errors[0].Detail.(string); body.Errors[0].Code != "NAME_INVALID" || !strings.Contains(detail, "^myorg/") {
+				t.Errorf("error = %+v, want NAME_INVALID naming the convention", body.Errors[0])
+			}
+		})
+	}
+}
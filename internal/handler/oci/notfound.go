@@ -0,0 +1,96 @@
+package oci
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/constants"
+	"github.com/patrickmn/go-cache"
+)
+
+// notFoundCache remembers which pull backends recently returned 404 for an artifact,
+// so the cascade can skip them for a short time
+type notFoundCache struct {
+	cache *cache.Cache
+}
+
+// newNotFoundCache creates a not-found cache, or returns nil if ttl is 0 (disabled)
+func newNotFoundCache(ttl time.Duration) *notFoundCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &notFoundCache{cache: cache.New(ttl, ttl*constants.CacheCleanupMultiplier)}
+}
+
+func notFoundKey(backend, path string) string {
+	return backend + "\x00" + path
+}
+
+// missing reports whether backend recently returned 404 for path
+func (c *notFoundCache) missing(backend, path string) bool {
+	if c == nil {
+		return false
+	}
+	_, found := c.cache.Get(notFoundKey(backend, path))
+	return found
+}
+
+// remember records that backend returned 404 for path
+func (c *notFoundCache) remember(backend, path string) {
+	if c == nil {
+		return
+	}
+	c.cache.SetDefault(notFoundKey(backend, path), struct{}{})
+}
+
+// forget drops the 404s recorded for path by any of backends
+func (c *notFoundCache) forget(backends []string, path string) {
+	if c == nil {
+		return
+	}
+	for _, backend := range backends {
+		c.cache.Delete(notFoundKey(backend, path))
+	}
+}
+
+// pushedArtifactPath returns the read path of the artifact a successful write created,
+// or "" if the write doesn't create one:
+//
+//	PUT /v2/<name>/manifests/<reference>                    -> the same path
+//	PUT /v2/<name>/blobs/uploads/<uuid>?digest=<digest>     -> /v2/<name>/blobs/<digest>
+func pushedArtifactPath(r *http.Request) string {
+	if r.Method != http.MethodPut {
+		return ""
+	}
+
+	path := r.URL.Path
+	if strings.Contains(path, "/manifests/") {
+		return path
+	}
+
+	if idx := strings.Index(path, "/blobs/uploads/"); idx >= 0 {
+		if digest := r.URL.Query().Get("digest"); digest != "" {
+			return path[:idx] + "/blobs/" + digest
+		}
+	}
+	return ""
+}
+
+// forgetPushed drops recorded 404s for the artifact a successful write created, so
+// reads don't skip a backend that now has it
+func (h *Handler) forgetPushed(r *http.Request) {
+	if h.notFound == nil {
+		return
+	}
+	path := pushedArtifactPath(r)
+	if path == "" {
+		return
+	}
+
+	names := make([]string, len(h.config.PullBackends))
+	for i := range h.config.PullBackends {
+		names[i] = h.config.PullBackends[i].Name
+	}
+	h.notFound.forget(names, path)
+}
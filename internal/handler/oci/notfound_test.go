@@ -0,0 +1,148 @@
+package oci
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// TestPushedArtifactPath tests which read path a write makes available
+func TestPushedArtifactPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		expected string
+	}{
+		{
+			name:     "manifest push",
+			method:   http.MethodPut,
+			target:   "/v2/myorg/app/manifests/v1.0",
+			expected: "/v2/myorg/app/manifests/v1.0",
+		},
+		{
+			name:     "blob upload commit",
+			method:   http.MethodPut,
+			target:   "/v2/myorg/app/blobs/uploads/5f3c?digest=sha256:abc",
+			expected: "/v2/myorg/app/blobs/sha256:abc",
+		},
+		{
+			name:     "blob upload chunk",
+			method:   http.MethodPatch,
+			target:   "/v2/myorg/app/blobs/uploads/5f3c",
+			expected: "",
+		},
+		{
+			name:     "blob upload without digest",
+			method:   http.MethodPut,
+			target:   "/v2/myorg/app/blobs/uploads/5f3c",
+			expected: "",
+		},
+		{
+			name:     "manifest delete",
+			method:   http.MethodDelete,
+			target:   "/v2/myorg/app/manifests/sha256:abc",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			if got := pushedArtifactPath(r); got != tt.expected {
+				t.Errorf("pushedArtifactPath() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestNotFoundCache tests remembering and forgetting per-backend 404s
+func TestNotFoundCache(t *testing.T) {
+	if c := newNotFoundCache(0); c != nil {
+		t.Fatal("expected a nil cache when disabled")
+	}
+	var disabled *notFoundCache
+	disabled.remember("local", "/v2/app/manifests/latest")
+	if disabled.missing("local", "/v2/app/manifests/latest") {
+		t.Error("disabled cache reported a backend missing")
+	}
+
+	c := newNotFoundCache(time.Minute)
+	c.remember("local", "/v2/app/manifests/latest")
+
+	if !c.missing("local", "/v2/app/manifests/latest") {
+		t.Error("expected the remembered 404")
+	}
+	if c.missing("mirror", "/v2/app/manifests/latest") {
+		t.Error("404 of one backend reported for another")
+	}
+	if c.missing("local", "/v2/app/manifests/v2") {
+		t.Error("404 of one artifact reported for another")
+	}
+
+	c.forget([]string{"local", "mirror"}, "/v2/app/manifests/latest")
+	if c.missing("local", "/v2/app/manifests/latest") {
+		t.Error("expected the 404 to be forgotten")
+	}
+}
+
+// TestSelectBackendAndProxy_NotFoundCache tests that the cascade skips a backend that
+// recently returned 404 for the same artifact
+func TestSelectBackendAndProxy_NotFoundCache(t *testing.T) {
+	var localLog, upstreamLog requestLog
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		localLog.add(r.Method)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer local.Close()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamLog.add(r.Method)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	backend := func(name, url string) config.OCIBackendConfig {
+		return config.OCIBackendConfig{
+			Name:                name,
+			URL:                 url,
+			MaxIdleConns:        1,
+			MaxIdleConnsPerHost: 1,
+			DialTimeout:         time.Second,
+			RequestTimeout:      10 * time.Second,
+		}
+	}
+	cfg := &config.OCIConfig{
+		NotFoundCacheTTL: time.Minute,
+		PullBackends: []config.OCIBackendConfig{
+			backend("local", local.URL),
+			backend("upstream", upstream.URL),
+		},
+	}
+
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("oci_not_found_test"), logger)
+
+	for range 3 {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/v2/library/alpine/blobs/sha256:abc", nil)
+		if err := h.selectBackendAndProxy(w, r, nil); err != nil {
+			t.Fatalf("selectBackendAndProxy failed: %v", err)
+		}
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+	}
+
+	if n := localLog.count(http.MethodGet); n != 1 {
+		t.Errorf("local received %d GETs, want 1 before its 404 was remembered", n)
+	}
+	if n := upstreamLog.count(http.MethodGet); n != 3 {
+		t.Errorf("upstream received %d GETs, want 3", n)
+	}
+}
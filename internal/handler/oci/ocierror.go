@@ -0,0 +1,146 @@
+package oci
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// writeOCIError writes an error response in the distribution spec format, which
+// Docker clients parse to show the error instead of a generic status message
+// https://github.com/opencontainers/distribution-spec/blob/main/spec.md#error-codes
+func writeOCIError(w http.ResponseWriter, status int, code, message string, detail any) error {
+	w.Header().Set("Docker-Distribution-Api-Version", "registry/2.0")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	return encodeJSON(w, OCIError{
+		Errors: []OCIErrorDetail{
+			{
+				Code:    code,
+				Message: message,
+				Detail:  detail,
+			},
+		},
+	})
+}
+
+// ociErrorCode maps an HTTP status to the distribution spec error code clients
+// expect with it. UNKNOWN and UNAVAILABLE aren't in the spec but are what the
+// reference registry sends for server errors, and Docker understands them.
+func ociErrorCode(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return "UNAUTHORIZED"
+	case http.StatusForbidden:
+		return "DENIED"
+	case http.StatusNotFound:
+		return "NAME_UNKNOWN"
+	case http.StatusRequestEntityTooLarge:
+		return "SIZE_INVALID"
+	case http.StatusTooManyRequests:
+		return "TOOMANYREQUESTS"
+	case http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return "UNAVAILABLE"
+	}
+	if status >= 500 {
+		return "UNKNOWN"
+	}
+	return "UNSUPPORTED"
+}
+
+// writeError reports err, returned while serving r, as a distribution spec error.
+// Application errors keep their status and message; anything else is an internal
+// error whose details are only logged. Once the response has started, the status
+// can't be changed and the error is only logged.
+func (h *Handler) writeError(w *responseTracker, r *http.Request, err error) {
+	h.logger.Error().Err(err).
+		Str("path", r.URL.Path).
+		Str("method", r.Method).
+		Msg("Failed to proxy request")
+
+	if w.wroteHeader {
+		return
+	}
+
+	var appErr *errors.AppError
+	switch {
+	case stderrors.As(err, &appErr):
+	case stderrors.Is(err, context.DeadlineExceeded):
+		appErr = errors.ErrBackendTimeout
+	default:
+		appErr = errors.ErrInternal
+	}
+
+	w.Header().Set("X-Error-Code", appErr.Code)
+	if encodeErr := writeOCIError(w, appErr.StatusCode, ociErrorCode(appErr.StatusCode), appErr.Message, nil); encodeErr != nil {
+		h.logger.Error().Err(encodeErr).Msg("Failed to encode error response")
+	}
+}
+
+// recoverPanic turns a panic while serving r into a distribution spec error, so a
+// bug in one request path doesn't send Docker clients the plain-text response of
+// the recovery middleware. If the response has already started, the connection is
+// aborted instead: the client sees a truncated transfer rather than a body with an
+// error appended to it.
+func (h *Handler) recoverPanic(w *responseTracker, r *http.Request) {
+	p := recover()
+	if p == nil {
+		return
+	}
+	// Deliberate abort of the connection, e.g. a timed-out streamed response
+	if p == http.ErrAbortHandler {
+		panic(p)
+	}
+
+	h.logger.Error().
+		Str("request_id", middleware.GetRequestID(r.Context())).
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Interface("panic", p).
+		Bytes("stack_trace", debug.Stack()).
+		Msg("Panic recovered in OCI handler")
+
+	if w.wroteHeader {
+		panic(http.ErrAbortHandler)
+	}
+	h.writeError(w, r, fmt.Errorf("panic: %v", p))
+}
+
+// responseTracker records whether the response headers have been written, which
+// decides whether an error can still be reported to the client
+type responseTracker struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (t *responseTracker) WriteHeader(status int) {
+	// Informational responses precede the final one
+	if status >= 200 {
+		t.wroteHeader = true
+	}
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *responseTracker) Write(b []byte) (int, error) {
+	t.wroteHeader = true
+	return t.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes through to the underlying writer, if it supports them
+func (t *responseTracker) Flush() {
+	if f, ok := t.ResponseWriter.(http.Flusher); ok {
+		t.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (t *responseTracker) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
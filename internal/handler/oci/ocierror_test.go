@@ -0,0 +1,114 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/rs/zerolog"
+)
+
+// decodeOCIError decodes a distribution spec error response, failing the test if the
+// response isn't one
+func decodeOCIError(t *testing.T, rec *httptest.ResponseRecorder) OCIErrorDetail {
+	t.Helper()
+
+	if got := rec.Header().Get("Docker-Distribution-Api-Version"); got != "registry/2.0" {
+		t.Errorf("Docker-Distribution-Api-Version = %q, want registry/2.0", got)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	var body OCIError
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || len(body.Errors) != 1 {
+		t.Fatalf("body %q is not a distribution spec error: %v", rec.Body.String(), err)
+	}
+	return body.Errors[0]
+}
+
+func TestWriteError(t *testing.T) {
+	h := &Handler{logger: zerolog.Nop()}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"internal error", fmt.Errorf("dial tcp: connection refused"), http.StatusInternalServerError, "UNKNOWN"},
+		{"backend timeout", fmt.Errorf("backend request: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, "UNAVAILABLE"},
+		{"application error", errors.ErrPayloadTooLarge, http.StatusRequestEntityTooLarge, "SIZE_INVALID"},
+		{"forbidden", errors.ErrForbidden, http.StatusForbidden, "DENIED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.writeError(&responseTracker{ResponseWriter: rec}, httptest.NewRequest(http.MethodGet, "/v2/", nil), tt.err)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if detail := decodeOCIError(t, rec); detail.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", detail.Code, tt.wantCode)
+			}
+		})
+	}
+
+	t.Run("response already started", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		w := &responseTracker{ResponseWriter: rec}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("partial"))
+
+		h.writeError(w, httptest.NewRequest(http.MethodGet, "/v2/", nil), fmt.Errorf("copy failed"))
+		if rec.Body.String() != "partial" {
+			t.Errorf("body = %q, want the partial response untouched", rec.Body.String())
+		}
+	})
+}
+
+func TestRecoverPanic(t *testing.T) {
+	h := &Handler{logger: zerolog.Nop()}
+	r := httptest.NewRequest(http.MethodGet, "/v2/library/alpine/manifests/latest", nil)
+
+	serve := func(w *responseTracker, handler func(w http.ResponseWriter)) {
+		defer h.recoverPanic(w, r)
+		handler(w)
+	}
+
+	t.Run("before headers", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		serve(&responseTracker{ResponseWriter: rec}, func(http.ResponseWriter) {
+			var cfg map[string]string
+			cfg["boom"] = "nil map"
+		})
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+		}
+		if detail := decodeOCIError(t, rec); detail.Code != "UNKNOWN" {
+			t.Errorf("code = %q, want UNKNOWN", detail.Code)
+		}
+	})
+
+	t.Run("after headers", func(t *testing.T) {
+		defer func() {
+			if p := recover(); p != http.ErrAbortHandler {
+				t.Errorf("panic = %v, want http.ErrAbortHandler to abort the connection", p)
+			}
+		}()
+
+		rec := httptest.NewRecorder()
+		serve(&responseTracker{ResponseWriter: rec}, func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			panic("stream failed")
+		})
+		t.Error("expected the panic to be re-raised")
+	})
+}
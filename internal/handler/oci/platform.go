@@ -0,0 +1,325 @@
+package oci
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/constants"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/patrickmn/go-cache"
+)
+
+// PlatformHeader lets clients name the platforms they pull images for, as
+// comma-separated os/arch[/variant]
+const PlatformHeader = "X-Artifusion-Platform"
+
+// indexMediaTypes are the media types of multi-platform image indexes
+var indexMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+}
+
+// Annotations BuildKit sets on attestation manifests, which have no platform of
+// their own but describe the manifest with the referenced digest
+const (
+	attestationTypeAnnotation   = "vnd.docker.reference.type"
+	attestationDigestAnnotation = "vnd.docker.reference.digest"
+)
+
+// platform identifies the OS and architecture an image manifest is built for
+type platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// parsePlatform parses an os/arch[/variant] platform
+func parsePlatform(s string) (platform, bool) {
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if len(parts) < 2 || len(parts) > 3 || slices.Contains(parts, "") {
+		return platform{}, false
+	}
+	p := platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p, true
+}
+
+// matches reports whether a manifest for other runs on p. Without a variant, p
+// matches every variant of its architecture.
+func (p platform) matches(other platform) bool {
+	return p.OS == other.OS && p.Architecture == other.Architecture &&
+		(p.Variant == "" || p.Variant == other.Variant)
+}
+
+// filteredIndex is an image index served with only some of its manifests
+type filteredIndex struct {
+	body      []byte
+	mediaType string
+	backend   string // pull backend the original index came from
+}
+
+// platformFilter filters image indexes pulled by tag to the client's platforms and
+// keeps the filtered indexes, so clients resolving the tag can fetch them by digest
+type platformFilter struct {
+	config    *config.PlatformFilterConfig
+	platforms []platform
+	indexes   *cache.Cache // repository@digest -> *filteredIndex
+}
+
+// newPlatformFilter creates a platform filter, or returns nil if disabled
+func newPlatformFilter(cfg *config.PlatformFilterConfig) *platformFilter {
+	if !cfg.Enabled {
+		return nil
+	}
+	f := &platformFilter{
+		config:  cfg,
+		indexes: cache.New(cfg.CacheTTL, cfg.CacheTTL*constants.CacheCleanupMultiplier),
+	}
+	for _, s := range cfg.Platforms {
+		if p, ok := parsePlatform(s); ok {
+			f.platforms = append(f.platforms, p)
+		}
+	}
+	return f
+}
+
+// requestPlatforms returns the platforms to filter the request's indexes to and where
+// they came from ("client_hint" or "config"), or nil if the request isn't filtered
+func (f *platformFilter) requestPlatforms(r *http.Request) ([]platform, string) {
+	if f.config.ClientHints {
+		if platforms := hintedPlatforms(r); len(platforms) > 0 {
+			return platforms, "client_hint"
+		}
+	}
+	if len(f.platforms) > 0 {
+		return f.platforms, "config"
+	}
+	return nil, ""
+}
+
+// hintedPlatforms returns the platforms named in the platform header or, failing
+// that, the platform in Docker's User-Agent:
+//
+//	docker/24.0.7 go/go1.20.10 git-commit/311b9ff kernel/6.5.0 os/linux arch/amd64 ...
+func hintedPlatforms(r *http.Request) []platform {
+	var platforms []platform
+	if header := r.Header.Get(PlatformHeader); header != "" {
+		for _, s := range strings.Split(header, ",") {
+			if p, ok := parsePlatform(s); ok {
+				platforms = append(platforms, p)
+			}
+		}
+		return platforms
+	}
+
+	var p platform
+	for _, field := range strings.Fields(r.UserAgent()) {
+		if osName, ok := strings.CutPrefix(field, "os/"); ok {
+			p.OS = osName
+		} else if arch, ok := strings.CutPrefix(field, "arch/"); ok {
+			p.Architecture = arch
+		}
+	}
+	if p.OS == "" || p.Architecture == "" {
+		return nil
+	}
+	return []platform{p}
+}
+
+// indexDescriptor is the part of an index's manifest descriptor filtering looks at
+type indexDescriptor struct {
+	Digest      string            `json:"digest"`
+	Platform    *platform         `json:"platform"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// filterIndex returns the index with only the manifests for platforms, keeping
+// manifests without a platform and the attestations of kept manifests. It returns
+// nil if the index has no manifest to drop, or none for platforms, in which case
+// it is served unmodified.
+func filterIndex(body []byte, platforms []platform) ([]byte, error) {
+	var index map[string]json.RawMessage
+	if err := json.Unmarshal(body, &index); err != nil {
+		return nil, fmt.Errorf("parse index: %w", err)
+	}
+	var manifests []json.RawMessage
+	if err := json.Unmarshal(index["manifests"], &manifests); err != nil {
+		return nil, fmt.Errorf("parse index manifests: %w", err)
+	}
+
+	descriptors := make([]indexDescriptor, len(manifests))
+	for i, raw := range manifests {
+		if err := json.Unmarshal(raw, &descriptors[i]); err != nil {
+			return nil, fmt.Errorf("parse index manifest %d: %w", i, err)
+		}
+	}
+
+	// Platform manifests first, then the attestations referring to them
+	keptDigests := make(map[string]bool)
+	for _, d := range descriptors {
+		if d.Platform == nil || d.Annotations[attestationTypeAnnotation] != "" {
+			continue
+		}
+		if slices.ContainsFunc(platforms, func(p platform) bool { return p.matches(*d.Platform) }) {
+			keptDigests[d.Digest] = true
+		}
+	}
+	if len(keptDigests) == 0 {
+		return nil, nil
+	}
+
+	kept := make([]json.RawMessage, 0, len(manifests))
+	for i, d := range descriptors {
+		switch {
+		case d.Annotations[attestationTypeAnnotation] != "":
+			if !keptDigests[d.Annotations[attestationDigestAnnotation]] {
+				continue
+			}
+		case d.Platform != nil:
+			if !keptDigests[d.Digest] {
+				continue
+			}
+		}
+		kept = append(kept, manifests[i])
+	}
+	if len(kept) == len(manifests) {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(kept)
+	if err != nil {
+		return nil, err
+	}
+	index["manifests"] = encoded
+	return json.Marshal(index)
+}
+
+// filterIndexResponse serves an image index pulled by tag filtered to the client's
+// platforms. resp is the successful response of backend to the request (with its
+// body unread); HEAD requests fetch the index to compute the filtered digest. It
+// returns false if the response isn't filtered, leaving it to the caller.
+func (h *Handler) filterIndexResponse(w http.ResponseWriter, r *http.Request, backend *config.OCIBackendConfig, path string, resp *proxy.Response) (bool, error) {
+	if h.platforms == nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false, nil
+	}
+	repository, reference, ok := parseManifestPath(r.URL.Path)
+	if !ok || strings.Contains(reference, ":") {
+		return false, nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Headers.Get("Content-Type"))
+	if !slices.Contains(indexMediaTypes, mediaType) {
+		return false, nil
+	}
+	platforms, source := h.platforms.requestPlatforms(r)
+	if len(platforms) == 0 {
+		return false, nil
+	}
+
+	var body []byte
+	if r.Method == http.MethodGet {
+		var err error
+		if body, err = h.proxyClient.ReadResponseBody(resp); err != nil {
+			return true, err
+		}
+	} else {
+		get := r.Clone(r.Context())
+		get.Method = http.MethodGet
+		getResp, err := h.executeProxyRequest(get, backend, path)
+		if err != nil {
+			return false, nil
+		}
+		if getResp.StatusCode != http.StatusOK {
+			drainResponse(getResp)
+			return false, nil
+		}
+		if body, err = h.proxyClient.ReadResponseBody(getResp); err != nil {
+			return false, nil
+		}
+	}
+
+	filtered, err := filterIndex(body, platforms)
+	if err != nil {
+		h.logger.Warn().Err(err).
+			Str("repository", repository).
+			Str("reference", reference).
+			Msg("Failed to filter image index, serving it unmodified")
+	}
+	if filtered == nil {
+		if r.Method == http.MethodHead {
+			return false, nil
+		}
+		return true, h.proxyClient.WriteResponse(w, resp, body, true)
+	}
+
+	sum := sha256.Sum256(filtered)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	h.platforms.indexes.SetDefault(repository+"@"+digest, &filteredIndex{
+		body:      filtered,
+		mediaType: mediaType,
+		backend:   backend.Name,
+	})
+	h.metrics.RecordPlatformFilteredIndex(source)
+
+	h.logger.Debug().
+		Str("repository", repository).
+		Str("reference", reference).
+		Str("original_digest", resp.Headers.Get("Docker-Content-Digest")).
+		Str("digest", digest).
+		Str("source", source).
+		Msg("Filtered image index to client platforms")
+
+	resp.Headers.Set("Docker-Content-Digest", digest)
+	if resp.Headers.Get("ETag") != "" {
+		resp.Headers.Set("ETag", `"`+digest+`"`)
+	}
+	return true, h.proxyClient.WriteResponse(w, resp, filtered, true)
+}
+
+// serveFilteredIndex serves a manifest read by digest of an index filtered earlier,
+// which no backend has. It returns false if the digest isn't one of a filtered index
+// the client may read.
+func (h *Handler) serveFilteredIndex(w http.ResponseWriter, r *http.Request, authResult *auth.AuthResult) bool {
+	if h.platforms == nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+	repository, reference, ok := parseManifestPath(r.URL.Path)
+	if !ok || !strings.Contains(reference, ":") {
+		return false
+	}
+	cached, found := h.platforms.indexes.Get(repository + "@" + reference)
+	if !found {
+		return false
+	}
+	index := cached.(*filteredIndex)
+
+	// The client must be able to read the original index from its backend
+	i := slices.IndexFunc(h.config.PullBackends, func(b config.OCIBackendConfig) bool { return b.Name == index.backend })
+	if i < 0 || h.backendSkipReason(r.URL.Path, &h.config.PullBackends[i], authResult) != "" {
+		return false
+	}
+
+	middleware.AddLogField(r.Context(), "backend", index.backend)
+	w.Header().Set("Docker-Distribution-Api-Version", "registry/2.0")
+	w.Header().Set("Content-Type", index.mediaType)
+	w.Header().Set("Docker-Content-Digest", reference)
+	w.Header().Set("ETag", `"`+reference+`"`)
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(index.body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		if _, err := w.Write(index.body); err != nil {
+			h.logger.Error().Err(err).Msg("Failed to write filtered image index")
+		}
+	}
+	return true
+}
@@ -0,0 +1,253 @@
+package oci
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// testIndex is a BuildKit-style index: two platform manifests, each with an attestation
+const testIndex = `{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.oci.image.index.v1+json",
+  "manifests": [
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:amd64", "size": 100, "platform": {"architecture": "amd64", "os": "linux"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:arm64", "size": 100, "platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:att-amd64", "size": 50, "platform": {"architecture": "unknown", "os": "unknown"},
+     "annotations": {"vnd.docker.reference.digest": "sha256:amd64", "vnd.docker.reference.type": "attestation-manifest"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:att-arm64", "size": 50, "platform": {"architecture": "unknown", "os": "unknown"},
+     "annotations": {"vnd.docker.reference.digest": "sha256:arm64", "vnd.docker.reference.type": "attestation-manifest"}}
+  ],
+  "annotations": {"org.opencontainers.image.created": "2024-01-01T00:00:00Z"}
+}`
+
+// indexDigests returns the digests of the manifests in index
+func indexDigests(t *testing.T, index []byte) []string {
+	t.Helper()
+	var parsed struct {
+		Manifests []struct {
+			Digest string `json:"digest"`
+		} `json:"manifests"`
+	}
+	if err := json.Unmarshal(index, &parsed); err != nil {
+		t.Fatalf("invalid index: %v", err)
+	}
+	var digests []string
+	for _, m := range parsed.Manifests {
+		digests = append(digests, m.Digest)
+	}
+	return digests
+}
+
+func TestFilterIndex(t *testing.T) {
+	tests := []struct {
+		name      string
+		platforms []string
+		want      []string // nil = unmodified
+	}{
+		{
+			name:      "single platform with its attestation",
+			platforms: []string{"linux/amd64"},
+			want:      []string{"sha256:amd64", "sha256:att-amd64"},
+		},
+		{
+			name:      "platform without variant matches every variant",
+			platforms: []string{"linux/arm64"},
+			want:      []string{"sha256:arm64", "sha256:att-arm64"},
+		},
+		{
+			name:      "variant mismatch",
+			platforms: []string{"linux/amd64", "linux/arm64/v7"},
+			want:      []string{"sha256:amd64", "sha256:att-amd64"},
+		},
+		{
+			name:      "all platforms",
+			platforms: []string{"linux/amd64", "linux/arm64"},
+		},
+		{
+			name:      "no matching platform",
+			platforms: []string{"windows/amd64"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var platforms []platform
+			for _, s := range tt.platforms {
+				p, ok := parsePlatform(s)
+				if !ok {
+					t.Fatalf("invalid platform %q", s)
+				}
+				platforms = append(platforms, p)
+			}
+
+			got, err := filterIndex([]byte(testIndex), platforms)
+			if err != nil {
+				t.Fatalf("filterIndex failed: %v", err)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("filterIndex() = %s, want unmodified", got)
+				}
+				return
+			}
+			if digests := indexDigests(t, got); !slices.Equal(digests, tt.want) {
+				t.Errorf("filterIndex() kept %v, want %v", digests, tt.want)
+			}
+
+			// Everything besides the manifests is kept
+			var index map[string]any
+			if err := json.Unmarshal(got, &index); err != nil {
+				t.Fatalf("invalid filtered index: %v", err)
+			}
+			if index["mediaType"] != "application/vnd.oci.image.index.v1+json" || index["annotations"] == nil {
+				t.Errorf("filtered index lost fields: %s", got)
+			}
+		})
+	}
+}
+
+func TestHintedPlatforms(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		userAgent string
+		want      []platform
+	}{
+		{
+			name:   "platform header",
+			header: "linux/amd64, linux/arm/v7",
+			want:   []platform{{OS: "linux", Architecture: "amd64"}, {OS: "linux", Architecture: "arm", Variant: "v7"}},
+		},
+		{
+			name:      "header takes precedence over user agent",
+			header:    "linux/arm64",
+			userAgent: "docker/24.0.7 go/go1.20.10 os/linux arch/amd64",
+			want:      []platform{{OS: "linux", Architecture: "arm64"}},
+		},
+		{
+			name:      "docker user agent",
+			userAgent: "docker/24.0.7 go/go1.20.10 git-commit/311b9ff kernel/6.5.0 os/linux arch/amd64 UpstreamClient(Docker-Client/24.0.7 \\(linux\\))",
+			want:      []platform{{OS: "linux", Architecture: "amd64"}},
+		},
+		{
+			name:      "user agent without platform",
+			userAgent: "containerd/1.7.11",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v2/library/alpine/manifests/latest", nil)
+			if tt.header != "" {
+				r.Header.Set(PlatformHeader, tt.header)
+			}
+			r.Header.Set("User-Agent", tt.userAgent)
+
+			if got := hintedPlatforms(r); !slices.Equal(got, tt.want) {
+				t.Errorf("hintedPlatforms() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSelectBackendAndProxy_PlatformFilter tests that an index pulled by tag is
+// filtered, and that clients resolving the tag can fetch the filtered index by digest
+func TestSelectBackendAndProxy_PlatformFilter(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/library/alpine/manifests/latest" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.oci.image.index.v1+json")
+		w.Header().Set("Docker-Content-Digest", "sha256:original")
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(testIndex))
+		}
+	}))
+	defer registry.Close()
+
+	backend := config.OCIBackendConfig{
+		Name:                "registry",
+		URL:                 registry.URL,
+		MaxIdleConns:        1,
+		MaxIdleConnsPerHost: 1,
+		DialTimeout:         time.Second,
+		RequestTimeout:      10 * time.Second,
+	}
+	cfg := &config.OCIConfig{
+		PullBackends: []config.OCIBackendConfig{backend},
+		PlatformFilter: config.PlatformFilterConfig{
+			Enabled:     true,
+			Platforms:   []string{"linux/amd64"},
+			ClientHints: true,
+			CacheTTL:    time.Minute,
+		},
+	}
+
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("oci_platform_test"), logger)
+
+	serve := func(method, path, platforms string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, nil)
+		if platforms != "" {
+			r.Header.Set(PlatformHeader, platforms)
+		}
+		if err := h.selectBackendAndProxy(w, r, nil); err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		return w
+	}
+
+	// Pull by tag: filtered to the configured platform
+	get := serve(http.MethodGet, "/v2/library/alpine/manifests/latest", "")
+	if get.Code != http.StatusOK {
+		t.Fatalf("GET by tag status = %d, want %d", get.Code, http.StatusOK)
+	}
+	sum := sha256.Sum256(get.Body.Bytes())
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if got := get.Header().Get("Docker-Content-Digest"); got != digest {
+		t.Errorf("Docker-Content-Digest = %q, want digest of the filtered index %q", got, digest)
+	}
+	if digests := indexDigests(t, get.Body.Bytes()); !slices.Equal(digests, []string{"sha256:amd64", "sha256:att-amd64"}) {
+		t.Errorf("filtered index kept %v", digests)
+	}
+
+	// Resolving by HEAD returns the same digest
+	head := serve(http.MethodHead, "/v2/library/alpine/manifests/latest", "")
+	if got := head.Header().Get("Docker-Content-Digest"); got != digest {
+		t.Errorf("HEAD Docker-Content-Digest = %q, want %q", got, digest)
+	}
+
+	// The filtered index is served by digest, though the backend doesn't have it
+	byDigest := serve(http.MethodGet, "/v2/library/alpine/manifests/"+digest, "")
+	if byDigest.Code != http.StatusOK || byDigest.Body.String() != get.Body.String() {
+		t.Errorf("GET by digest = %d %s, want the filtered index", byDigest.Code, byDigest.Body.String())
+	}
+	if got := byDigest.Header().Get("Content-Type"); got != "application/vnd.oci.image.index.v1+json" {
+		t.Errorf("Content-Type by digest = %q", got)
+	}
+
+	// A client hint overrides the configured platforms
+	hinted := serve(http.MethodGet, "/v2/library/alpine/manifests/latest", "linux/arm64")
+	if digests := indexDigests(t, hinted.Body.Bytes()); !slices.Equal(digests, []string{"sha256:arm64", "sha256:att-arm64"}) {
+		t.Errorf("hinted index kept %v", digests)
+	}
+
+	// Indexes pulled by digest are never filtered
+	if w := serve(http.MethodGet, "/v2/library/alpine/manifests/sha256:original", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET of unknown digest status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
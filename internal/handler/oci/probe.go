@@ -0,0 +1,102 @@
+package oci
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+)
+
+// probeResult is the outcome of a HEAD probe of one pull backend
+type probeResult struct {
+	status int
+	err    error
+}
+
+// shouldProbe reports whether a read should probe the pull backends before the cascade
+func (h *Handler) shouldProbe(r *http.Request) bool {
+	return h.config.ProbeManifests &&
+		r.Method == http.MethodGet &&
+		strings.Contains(r.URL.Path, "/manifests/")
+}
+
+// probeBackends sends a HEAD request for the manifest to every eligible pull backend
+// in parallel and returns the index of the backend the cascade should start at: the
+// first, in cascade order, whose probe did not return a fallthrough status. Backends
+// before it are recorded in result with their probe status. Returns 0 if fewer than
+// two backends are eligible, as probing can't save a request then.
+//
+// Only the probes of backends up to the chosen one are waited for; the others finish
+// in the background and are ignored.
+func (h *Handler) probeBackends(r *http.Request, authResult *auth.AuthResult, result *cascadeResult) int {
+	backends := h.config.PullBackends
+	path := r.URL.Path
+
+	eligible := 0
+	for i := range backends {
+		if h.backendSkipReason(path, &backends[i], authResult) == "" {
+			eligible++
+		}
+	}
+	if eligible < 2 {
+		return 0
+	}
+
+	// Probes that lose the race are not canceled: a canceled request would count as
+	// a failure against the backend's health and circuit breaker
+	ctx := context.WithoutCancel(r.Context())
+
+	probes := make([]chan probeResult, len(backends))
+	for i := range backends {
+		backend := &backends[i]
+		if h.backendSkipReason(path, backend, authResult) != "" {
+			continue
+		}
+
+		// Clone before starting the probe: the cascade modifies r's headers while
+		// probes may still be running
+		probeReq := r.Clone(ctx)
+		probeReq.Method = http.MethodHead
+		probeReq.Body = http.NoBody
+		probeReq.ContentLength = 0
+		h.injectBackendAuth(probeReq, backend)
+
+		probes[i] = make(chan probeResult, 1)
+		go func(ch chan<- probeResult) {
+			resp, err := h.executeProxyRequest(probeReq, backend, h.rewritePath(path, backend))
+			if err != nil {
+				ch <- probeResult{err: err}
+				return
+			}
+			_ = resp.Body.Close()
+			ch <- probeResult{status: resp.StatusCode}
+		}(probes[i])
+	}
+
+	for i, ch := range probes {
+		if ch == nil {
+			continue
+		}
+		backend := &backends[i]
+
+		probe := <-ch
+		if probe.err != nil || !backend.FallsThrough(probe.status) {
+			h.logger.Debug().
+				Str("backend", backend.Name).
+				Int("status", probe.status).
+				Int("backends_ruled_out", len(result.attempts)).
+				Msg("Manifest probe selected backend to start cascade")
+			return i
+		}
+		result.recordStatus(backend.Name, probe.status)
+		if probe.status == http.StatusNotFound {
+			h.notFound.remember(backend.Name, path)
+		}
+	}
+
+	h.logger.Debug().
+		Int("backends_probed", eligible).
+		Msg("Manifest probe found no backend with the manifest")
+	return len(backends)
+}
@@ -0,0 +1,126 @@
+package oci
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+)
+
+// requestLog records the methods a test backend received
+type requestLog struct {
+	mu      sync.Mutex
+	methods []string
+}
+
+func (l *requestLog) add(method string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.methods = append(l.methods, method)
+}
+
+func (l *requestLog) count(method string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, m := range l.methods {
+		if m == method {
+			n++
+		}
+	}
+	return n
+}
+
+// TestSelectBackendAndProxy_ProbeManifests tests that manifest probing sends the GET
+// straight to the backend that has the manifest
+func TestSelectBackendAndProxy_ProbeManifests(t *testing.T) {
+	newBackend := func(status int, log *requestLog) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log.add(r.Method)
+			w.WriteHeader(status)
+		}))
+	}
+
+	var localLog, mirrorLog, upstreamLog requestLog
+	local := newBackend(http.StatusNotFound, &localLog)
+	defer local.Close()
+	mirror := newBackend(http.StatusServiceUnavailable, &mirrorLog)
+	defer mirror.Close()
+	upstream := newBackend(http.StatusOK, &upstreamLog)
+	defer upstream.Close()
+
+	backend := func(name, url string) config.OCIBackendConfig {
+		return config.OCIBackendConfig{
+			Name:                name,
+			URL:                 url,
+			MaxIdleConns:        1,
+			MaxIdleConnsPerHost: 1,
+			DialTimeout:         time.Second,
+			RequestTimeout:      10 * time.Second,
+		}
+	}
+	cfg := &config.OCIConfig{
+		ProbeManifests:      true,
+		ExposeCascadeResult: true,
+		PullBackends: []config.OCIBackendConfig{
+			backend("local", local.URL),
+			backend("mirror", mirror.URL),
+			backend("upstream", upstream.URL),
+		},
+	}
+
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("oci_probe_test"), logger)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v2/library/alpine/manifests/latest", nil)
+	if err := h.selectBackendAndProxy(w, r, nil); err != nil {
+		t.Fatalf("selectBackendAndProxy failed: %v", err)
+	}
+
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if got, want := w.Header().Get(BackendsTriedHeader), "local=404, mirror=503, upstream=200"; got != want {
+		t.Errorf("%s = %q, want %q", BackendsTriedHeader, got, want)
+	}
+	if n := localLog.count(http.MethodGet) + mirrorLog.count(http.MethodGet); n != 0 {
+		t.Errorf("backends without the manifest received %d GETs, want 0", n)
+	}
+	if n := upstreamLog.count(http.MethodGet); n != 1 {
+		t.Errorf("upstream received %d GETs, want 1", n)
+	}
+	if n := localLog.count(http.MethodHead); n != 1 {
+		t.Errorf("local received %d HEADs, want 1 probe", n)
+	}
+
+	// The backends ruled out by the probe still count towards the cascade depth
+	expected := `
+# HELP oci_probe_test_cascade_depth Number of pull backends a cascading read went through before it was answered
+# TYPE oci_probe_test_cascade_depth histogram
+oci_probe_test_cascade_depth_bucket{protocol="oci",result="success",le="1"} 0
+oci_probe_test_cascade_depth_bucket{protocol="oci",result="success",le="2"} 0
+oci_probe_test_cascade_depth_bucket{protocol="oci",result="success",le="3"} 1
+oci_probe_test_cascade_depth_bucket{protocol="oci",result="success",le="4"} 1
+oci_probe_test_cascade_depth_bucket{protocol="oci",result="success",le="5"} 1
+oci_probe_test_cascade_depth_bucket{protocol="oci",result="success",le="6"} 1
+oci_probe_test_cascade_depth_bucket{protocol="oci",result="success",le="7"} 1
+oci_probe_test_cascade_depth_bucket{protocol="oci",result="success",le="8"} 1
+oci_probe_test_cascade_depth_bucket{protocol="oci",result="success",le="9"} 1
+oci_probe_test_cascade_depth_bucket{protocol="oci",result="success",le="10"} 1
+oci_probe_test_cascade_depth_bucket{protocol="oci",result="success",le="+Inf"} 1
+oci_probe_test_cascade_depth_sum{protocol="oci",result="success"} 3
+oci_probe_test_cascade_depth_count{protocol="oci",result="success"} 1
+`
+	if err := testutil.CollectAndCompare(h.metrics.CascadeDepth, strings.NewReader(expected)); err != nil {
+		t.Errorf("unexpected cascade depth: %v", err)
+	}
+}
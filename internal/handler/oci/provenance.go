@@ -0,0 +1,239 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/constants"
+	"github.com/mainuli/artifusion/internal/metadata"
+	"github.com/mainuli/artifusion/internal/proxy"
+)
+
+const (
+	// provenanceArtifactType identifies the artifacts holding push provenance
+	provenanceArtifactType = "application/vnd.artifusion.provenance.v1"
+
+	// provenanceAnnotationPrefix prefixes the provenance annotation keys
+	provenanceAnnotationPrefix = "dev.artifusion.provenance."
+
+	// The OCI empty descriptor, used as the config and layer of annotation-only artifacts
+	emptyMediaType = "application/vnd.oci.empty.v1+json"
+	emptyDigest    = "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	emptyContent   = "{}"
+)
+
+// ociDescriptor references content by digest
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// provenanceManifest is an OCI artifact manifest carrying annotations about its subject
+type provenanceManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Subject       ociDescriptor     `json:"subject"`
+	Annotations   map[string]string `json:"annotations"`
+}
+
+// provenanceAnnotations returns the annotations describing a push
+func provenanceAnnotations(provenance *metadata.Provenance, pushedAt time.Time) map[string]string {
+	annotations := map[string]string{
+		"org.opencontainers.image.created":       pushedAt.UTC().Format(time.RFC3339),
+		provenanceAnnotationPrefix + "user":      provenance.User,
+		provenanceAnnotationPrefix + "pushed_at": pushedAt.UTC().Format(time.RFC3339),
+	}
+	optional := map[string]string{
+		"impersonated_by": provenance.ImpersonatedBy,
+		"token_type":      provenance.TokenType,
+		"repository":      provenance.Repository,
+	}
+	for key, value := range provenance.CI {
+		optional["ci."+key] = value
+	}
+	for key, value := range optional {
+		if value != "" {
+			annotations[provenanceAnnotationPrefix+key] = value
+		}
+	}
+	return annotations
+}
+
+// annotatePushed attaches the provenance of a manifest pushed under a tag to it, as
+// an OCI artifact whose subject is the manifest, in the background. The pushed
+// manifest is left unchanged; registries supporting the referrers API list the
+// artifact with the image (e.g. oras discover). Pushes by digest, such as the
+// platform manifests of a multi-arch push, are not annotated.
+func (h *Handler) annotatePushed(r *http.Request, headers http.Header) {
+	if !h.config.ProvenanceAnnotations || r.Method != http.MethodPut {
+		return
+	}
+	repository, reference, ok := parseManifestPath(r.URL.Path)
+	digest := headers.Get("Docker-Content-Digest")
+	if !ok || strings.Contains(reference, ":") || digest == "" {
+		return
+	}
+	provenance := metadata.NewProvenance(r)
+	pushedAt := time.Now()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), constants.ProvenancePushTimeout)
+		defer cancel()
+
+		if err := h.pushProvenance(ctx, repository, digest, provenanceAnnotations(provenance, pushedAt)); err != nil {
+			h.logger.Warn().Err(err).
+				Str("repository", repository).
+				Str("digest", digest).
+				Msg("Failed to push provenance annotations")
+		}
+	}()
+}
+
+// pushProvenance pushes an annotation artifact referring to the manifest with
+// digest in repository to the push backend
+func (h *Handler) pushProvenance(ctx context.Context, repository, digest string, annotations map[string]string) error {
+	subject, err := h.manifestDescriptor(ctx, repository, digest)
+	if err != nil {
+		return err
+	}
+	if err := h.ensureEmptyBlob(ctx, repository); err != nil {
+		return err
+	}
+
+	empty := ociDescriptor{MediaType: emptyMediaType, Digest: emptyDigest, Size: int64(len(emptyContent))}
+	manifest, err := json.Marshal(provenanceManifest{
+		SchemaVersion: 2,
+		MediaType:     "application/vnd.oci.image.manifest.v1+json",
+		ArtifactType:  provenanceArtifactType,
+		Config:        empty,
+		Layers:        []ociDescriptor{empty},
+		Subject:       subject,
+		Annotations:   annotations,
+	})
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(manifest)
+	manifestDigest := "sha256:" + hex.EncodeToString(sum[:])
+	resp, err := h.pushBackendDo(ctx, http.MethodPut, "/v2/"+repository+"/manifests/"+manifestDigest, "",
+		http.Header{"Content-Type": {"application/vnd.oci.image.manifest.v1+json"}}, bytes.NewReader(manifest), int64(len(manifest)))
+	if err != nil {
+		return fmt.Errorf("push provenance manifest: %w", err)
+	}
+	drainResponse(resp)
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("push provenance manifest: push backend returned %d", resp.StatusCode)
+	}
+
+	h.logger.Debug().
+		Str("repository", repository).
+		Str("subject", digest).
+		Str("digest", manifestDigest).
+		Msg("Pushed provenance annotations")
+	return nil
+}
+
+// manifestDescriptor returns the descriptor of the manifest with digest, as stored
+// by the push backend
+func (h *Handler) manifestDescriptor(ctx context.Context, repository, digest string) (ociDescriptor, error) {
+	accept := http.Header{"Accept": {strings.Join([]string{
+		"application/vnd.oci.image.index.v1+json",
+		"application/vnd.oci.image.manifest.v1+json",
+		"application/vnd.docker.distribution.manifest.list.v2+json",
+		"application/vnd.docker.distribution.manifest.v2+json",
+	}, ", ")}}
+	resp, err := h.pushBackendDo(ctx, http.MethodHead, "/v2/"+repository+"/manifests/"+digest, "", accept, nil, 0)
+	if err != nil {
+		return ociDescriptor{}, fmt.Errorf("check pushed manifest: %w", err)
+	}
+	drainResponse(resp)
+	if resp.StatusCode != http.StatusOK {
+		return ociDescriptor{}, fmt.Errorf("check pushed manifest: push backend returned %d", resp.StatusCode)
+	}
+
+	if resp.HTTPResp.ContentLength <= 0 {
+		return ociDescriptor{}, fmt.Errorf("check pushed manifest: push backend returned no size")
+	}
+	return ociDescriptor{MediaType: resp.Headers.Get("Content-Type"), Digest: digest, Size: resp.HTTPResp.ContentLength}, nil
+}
+
+// ensureEmptyBlob uploads the empty blob to repository unless it is there already
+func (h *Handler) ensureEmptyBlob(ctx context.Context, repository string) error {
+	resp, err := h.pushBackendDo(ctx, http.MethodHead, "/v2/"+repository+"/blobs/"+emptyDigest, "", nil, nil, 0)
+	if err != nil {
+		return fmt.Errorf("check empty blob: %w", err)
+	}
+	drainResponse(resp)
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	resp, err = h.pushBackendDo(ctx, http.MethodPost, "/v2/"+repository+"/blobs/uploads/", "", nil, nil, 0)
+	if err != nil {
+		return fmt.Errorf("start empty blob upload: %w", err)
+	}
+	drainResponse(resp)
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("start empty blob upload: push backend returned %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Headers.Get("Location"))
+	if err != nil || location.Path == "" {
+		return fmt.Errorf("start empty blob upload: invalid upload location %q", resp.Headers.Get("Location"))
+	}
+	query := location.Query()
+	query.Set("digest", emptyDigest)
+
+	resp, err = h.pushBackendDo(ctx, http.MethodPut, location.Path, query.Encode(),
+		http.Header{"Content-Type": {"application/octet-stream"}}, strings.NewReader(emptyContent), int64(len(emptyContent)))
+	if err != nil {
+		return fmt.Errorf("upload empty blob: %w", err)
+	}
+	drainResponse(resp)
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("upload empty blob: push backend returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// pushBackendDo sends a request to the push backend, outside of any client request
+func (h *Handler) pushBackendDo(ctx context.Context, method, path, query string, headers http.Header, body io.Reader, contentLength int64) (*proxy.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if headers == nil {
+		headers = http.Header{}
+	}
+
+	return h.proxyClient.ProxyRequest(&proxy.Request{
+		Method:        method,
+		Path:          path,
+		Query:         query,
+		Body:          body,
+		ContentLength: contentLength,
+		Headers:       headers,
+		Backend:       &h.config.PushBackend,
+		OriginalReq:   req,
+	})
+}
+
+// drainResponse discards and closes a response body so the connection can be reused
+func drainResponse(resp *proxy.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
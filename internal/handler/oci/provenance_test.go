@@ -0,0 +1,134 @@
+package oci
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metadata"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+func TestProvenanceAnnotations(t *testing.T) {
+	pushedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	got := provenanceAnnotations(&metadata.Provenance{
+		User:       "alice",
+		Repository: "myorg/app",
+		CI:         map[string]string{"run_id": "42"},
+	}, pushedAt)
+
+	want := map[string]string{
+		"org.opencontainers.image.created":     "2026-01-02T03:04:05Z",
+		"dev.artifusion.provenance.user":       "alice",
+		"dev.artifusion.provenance.pushed_at":  "2026-01-02T03:04:05Z",
+		"dev.artifusion.provenance.repository": "myorg/app",
+		"dev.artifusion.provenance.ci.run_id":  "42",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("provenanceAnnotations() = %v, want %v", got, want)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("annotation %s = %q, want %q", key, got[key], value)
+		}
+	}
+}
+
+// TestPushProvenance tests that the provenance artifact is pushed with the pushed
+// manifest as its subject, after uploading the empty blob it references
+func TestPushProvenance(t *testing.T) {
+	const (
+		digest   = "sha256:111"
+		manifest = `{"schemaVersion":2}`
+	)
+	var (
+		mu       sync.Mutex
+		requests []string
+		pushed   provenanceManifest
+	)
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/v2/app/manifests/"+digest:
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Header().Set("Content-Length", "19")
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/app/blobs/uploads/":
+			w.Header().Set("Location", "/v2/app/blobs/uploads/u1?state=x")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodPut && r.URL.Path == "/v2/app/blobs/uploads/u1":
+			body, _ := io.ReadAll(r.Body)
+			if r.URL.Query().Get("digest") != emptyDigest || string(body) != emptyContent || r.URL.Query().Get("state") != "x" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/v2/app/manifests/sha256:"):
+			if err := json.NewDecoder(r.Body).Decode(&pushed); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer registry.Close()
+
+	backend := config.OCIBackendConfig{
+		Name:                "local",
+		URL:                 registry.URL,
+		MaxIdleConns:        1,
+		MaxIdleConnsPerHost: 1,
+		DialTimeout:         time.Second,
+		RequestTimeout:      10 * time.Second,
+	}
+	cfg := &config.OCIConfig{PushBackend: backend, ProvenanceAnnotations: true}
+
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("oci_provenance_test"), logger)
+
+	annotations := map[string]string{provenanceAnnotationPrefix + "user": "alice"}
+	if err := h.pushProvenance(t.Context(), "app", digest, annotations); err != nil {
+		t.Fatalf("pushProvenance() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	wantRequests := []string{
+		"HEAD /v2/app/manifests/" + digest,
+		"HEAD /v2/app/blobs/" + emptyDigest,
+		"POST /v2/app/blobs/uploads/",
+		"PUT /v2/app/blobs/uploads/u1",
+	}
+	if len(requests) != len(wantRequests)+1 {
+		t.Fatalf("registry received %q, want %q and the manifest push", requests, wantRequests)
+	}
+	for i, want := range wantRequests {
+		if requests[i] != want {
+			t.Errorf("request %d = %q, want %q", i, requests[i], want)
+		}
+	}
+
+	wantSubject := ociDescriptor{MediaType: "application/vnd.oci.image.manifest.v1+json", Digest: digest, Size: int64(len(manifest))}
+	if pushed.Subject != wantSubject {
+		t.Errorf("subject = %+v, want %+v", pushed.Subject, wantSubject)
+	}
+	if pushed.ArtifactType != provenanceArtifactType {
+		t.Errorf("artifactType = %q, want %q", pushed.ArtifactType, provenanceArtifactType)
+	}
+	if pushed.Annotations[provenanceAnnotationPrefix+"user"] != "alice" {
+		t.Errorf("annotations = %v, want the user", pushed.Annotations)
+	}
+}
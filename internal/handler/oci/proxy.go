@@ -9,15 +9,9 @@ import (
 	"github.com/mainuli/artifusion/internal/proxy/rewriter"
 )
 
-// proxyTransparent proxies the request to the backend transparently
-// This streams the request and response without modification (zero-copy)
-func (h *Handler) proxyTransparent(w http.ResponseWriter, r *http.Request, backend *config.OCIBackendConfig, path string) error {
-	_, err := h.proxyTransparentWithResponse(w, r, backend, path)
-	return err
-}
-
-// proxyTransparentWithResponse proxies the request and returns the response
-// This allows callers to inspect the response status for fallback logic
+// proxyTransparentWithResponse proxies the request to the backend transparently and
+// returns the response. This streams the request and response without modification
+// (zero-copy) and lets callers inspect the response status afterwards.
 func (h *Handler) proxyTransparentWithResponse(w http.ResponseWriter, r *http.Request, backend *config.OCIBackendConfig, path string) (*http.Response, error) {
 	// Create proxy request
 	proxyReq := &proxy.Request{
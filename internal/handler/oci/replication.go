@@ -0,0 +1,43 @@
+package oci
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/replication"
+)
+
+// SetReplicator enables replication of pushed manifests to a secondary registry
+func (h *Handler) SetReplicator(r *replication.Replicator) {
+	h.replicator = r
+}
+
+// replicatePushed queues the manifest a successful write pushed for replication.
+// Blobs are replicated with the manifests referencing them, so blob uploads are
+// not queued themselves.
+func (h *Handler) replicatePushed(r *http.Request) {
+	if h.replicator == nil || r.Method != http.MethodPut {
+		return
+	}
+	if repository, reference, ok := parseManifestPath(r.URL.Path); ok {
+		h.replicator.Enqueue(repository, reference)
+	}
+}
+
+// parseManifestPath splits /v2/<name>/manifests/<reference> into name and reference
+func parseManifestPath(path string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(path, "/v2/")
+	if !ok {
+		return "", "", false
+	}
+	idx := strings.LastIndex(rest, "/manifests/")
+	if idx <= 0 {
+		return "", "", false
+	}
+
+	repository, reference := rest[:idx], rest[idx+len("/manifests/"):]
+	if reference == "" || strings.Contains(reference, "/") {
+		return "", "", false
+	}
+	return repository, reference, true
+}
@@ -0,0 +1,30 @@
+package oci
+
+import "testing"
+
+func TestParseManifestPath(t *testing.T) {
+	tests := []struct {
+		path           string
+		wantRepository string
+		wantReference  string
+		wantOK         bool
+	}{
+		{"/v2/app/manifests/v1", "app", "v1", true},
+		{"/v2/team/app/manifests/sha256:abc", "team/app", "sha256:abc", true},
+		{"/v2/manifests/app/manifests/latest", "manifests/app", "latest", true},
+		{"/v2/app/blobs/sha256:abc", "", "", false},
+		{"/v2/app/manifests/", "", "", false},
+		{"/v2//manifests/v1", "", "", false},
+		{"/other/app/manifests/v1", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			repository, reference, ok := parseManifestPath(tt.path)
+			if repository != tt.wantRepository || reference != tt.wantReference || ok != tt.wantOK {
+				t.Errorf("parseManifestPath(%q) = %q, %q, %v, want %q, %q, %v",
+					tt.path, repository, reference, ok, tt.wantRepository, tt.wantReference, tt.wantOK)
+			}
+		})
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/mainuli/artifusion/internal/auth"
 	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/middleware"
 )
 
 // selectBackendAndProxy determines the appropriate backend and proxies the request
@@ -15,6 +17,11 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 	path := r.URL.Path
 	method := r.Method
 
+	// The mirror namespace names the upstream registry, so it isn't cascaded
+	if handled, err := h.serveMirror(w, r, authResult); handled {
+		return err
+	}
+
 	// Check if this is a write operation
 	if h.isWriteOperation(method, path) {
 		// Write operations go directly to push backend (registry:2)
@@ -26,11 +33,26 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 			Str("operation", "write").
 			Msg("Routing to push backend")
 
+		middleware.AddLogField(r.Context(), "backend", backend.Name)
+
 		// Inject backend auth
 		h.injectBackendAuth(r, backend)
 
+		// With soft-delete enabled, deletes go to the trash instead
+		if h.trashDelete(w, r, authResult) {
+			return nil
+		}
+
 		// Proxy directly (no path rewriting for push backend)
-		return h.proxyTransparent(w, r, backend, path)
+		resp, err := h.proxyTransparentWithResponse(w, r, backend, path)
+		if resp != nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			h.forgetPushed(r)
+			h.replicatePushed(r)
+			h.restorePushed(r, resp)
+			h.recordPushed(r, resp.Header)
+			h.annotatePushed(r, resp.Header)
+		}
+		return err
 	}
 
 	// Read operations: cascade through pull backends with fallback
@@ -40,27 +62,19 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 	// Edge case: no backends configured (shouldn't happen due to validation)
 	if len(backends) == 0 {
 		h.logger.Error().Msg("No pull backends configured")
-		w.Header().Set("Docker-Distribution-Api-Version", "registry/2.0")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusServiceUnavailable)
-
-		errResponse := OCIError{
-			Errors: []OCIErrorDetail{
-				{
-					Code:    "UNAVAILABLE",
-					Message: "registry service unavailable",
-					Detail:  "No pull backends configured",
-				},
-			},
-		}
-
-		if err := encodeJSON(w, errResponse); err != nil {
+		if err := writeOCIError(w, http.StatusServiceUnavailable, "UNAVAILABLE",
+			"registry service unavailable", "No pull backends configured"); err != nil {
 			h.logger.Error().Err(err).Msg("Failed to encode error response")
 			return err
 		}
 		return nil
 	}
 
+	// Filtered image indexes are only held by Artifusion
+	if h.serveFilteredIndex(w, r, authResult) {
+		return nil
+	}
+
 	h.logger.Debug().
 		Int("backend_count", len(backends)).
 		Str("operation", "read").
@@ -69,17 +83,25 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 	// Track cascade attempts for better error reporting
 	backendsTried := 0
 	backendsSkipped := 0
+	var result cascadeResult
+
+	// Optionally probe all backends at once to skip those without the manifest
+	start := 0
+	if h.shouldProbe(r) {
+		start = h.probeBackends(r, authResult, &result)
+	}
 
 	// Try each backend in order
 	for i := range backends {
 		backend := &backends[i]
 
-		// Skip GHCR if org doesn't match scope or authenticated user's org
-		if backend.UpstreamNamespace == "ghcr.io" && !h.shouldTryGHCR(path, backend, authResult) {
+		// Skip backends filtered by team or org scope
+		if reason := h.backendSkipReason(path, backend, authResult); reason != "" {
 			h.logger.Debug().
 				Str("backend", backend.Name).
 				Str("path", path).
-				Msg("Skipping GHCR backend - org not in scope")
+				Str("reason", reason).
+				Msg("Skipping pull backend")
 			backendsSkipped++
 			continue
 		}
@@ -87,6 +109,27 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 		// Count this backend as tried
 		backendsTried++
 
+		// The probe already found the manifest missing from this backend
+		if i < start {
+			continue
+		}
+
+		// The backend recently returned 404 for this artifact
+		if h.notFound.missing(backend.Name, path) {
+			h.logger.Debug().
+				Str("backend", backend.Name).
+				Str("path", path).
+				Msg("Skipping pull backend, artifact recently not found")
+			result.recordStatus(backend.Name, http.StatusNotFound)
+			continue
+		}
+
+		// The artifact was deleted from this backend and is held in the trash
+		if h.hiddenByTrash(backend, path) {
+			result.recordStatus(backend.Name, http.StatusNotFound)
+			continue
+		}
+
 		// Rewrite path for oci-registry namespace routing
 		rewrittenPath := h.rewritePath(path, backend)
 
@@ -105,9 +148,14 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 		resp, err := h.executeProxyRequest(r, backend, rewrittenPath)
 
 		if err == nil && resp != nil {
+			result.recordStatus(backend.Name, resp.StatusCode)
+			if resp.StatusCode == http.StatusNotFound {
+				h.notFound.remember(backend.Name, path)
+			}
+
 			// Ensure response body is always closed (defense in depth)
 			// StreamResponse will read the body, but we defer close to ensure cleanup
-			bodyCloser := resp.HTTPResp.Body
+			bodyCloser := resp.Body
 			bodyClosed := false
 			closeBody := func() {
 				if !bodyClosed && bodyCloser != nil {
@@ -119,6 +167,17 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 			}
 			defer closeBody()
 
+			// A tag read of a manifest trashed by digest: treat it as not found
+			if resp.StatusCode == http.StatusOK && h.trashedManifest(backend, path, resp) {
+				h.logger.Debug().
+					Str("backend", backend.Name).
+					Str("path", path).
+					Msg("Manifest is in the trash, trying next")
+				result.recordStatus(backend.Name, http.StatusNotFound)
+				closeBody()
+				continue
+			}
+
 			// Check if request was successful
 			if resp.StatusCode >= 200 && resp.StatusCode < 400 {
 				h.logger.Debug().
@@ -126,6 +185,18 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 					Int("status", resp.StatusCode).
 					Msg("Backend returned success, streaming response")
 
+				h.metrics.RecordCascadeDepth(h.Name(), "success", backendsTried)
+				middleware.AddLogField(r.Context(), "backend", backend.Name)
+				h.setBackendsTriedHeader(w, &result)
+				if resp.StatusCode == http.StatusOK {
+					h.recordPulled(r, resp.Headers)
+
+					// Multi-platform indexes pulled by tag may be filtered to the client's platforms
+					if filtered, err := h.filterIndexResponse(w, r, backend, rewrittenPath, resp); filtered {
+						return err
+					}
+				}
+
 				// Stream the successful response to client
 				_, streamErr := h.proxyClient.StreamResponse(w, resp, true)
 				if streamErr != nil {
@@ -135,14 +206,10 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 				return nil
 			}
 
-			// Treat 404, 401, 403, and 5xx errors as "not found" and try next backend
-			// 404 = Not Found
-			// 401/403 = No access (treat as not found for cascade)
-			// 5xx = Backend error (try next backend)
-			if resp.StatusCode == http.StatusNotFound ||
-				resp.StatusCode == http.StatusUnauthorized ||
-				resp.StatusCode == http.StatusForbidden ||
-				resp.StatusCode >= 500 {
+			// Fall through to the next backend on the backend's fallthrough statuses
+			// (by default 404, 401/403 = no access, treated as not found for the
+			// cascade, and 5xx = backend error)
+			if backend.FallsThrough(resp.StatusCode) {
 
 				h.logger.Debug().
 					Str("backend", backend.Name).
@@ -151,13 +218,16 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 					Msg("Backend returned error, trying next")
 				// Body will be closed by defer
 			} else {
-				// Other 4xx errors: stream error response to client
+				// Other errors: stream error response to client
 				h.logger.Warn().
 					Str("backend", backend.Name).
 					Int("status", resp.StatusCode).
-					Msg("Backend returned client error, streaming error response")
+					Msg("Backend returned terminal error, streaming error response")
 
 				// Stream the error response to client
+				h.metrics.RecordCascadeDepth(h.Name(), "error", backendsTried)
+				middleware.AddLogField(r.Context(), "backend", backend.Name)
+				h.setBackendsTriedHeader(w, &result)
 				_, streamErr := h.proxyClient.StreamResponse(w, resp, true)
 				if streamErr != nil {
 					h.logger.Error().Err(streamErr).Msg("Failed to stream error response")
@@ -167,6 +237,7 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 			}
 		} else if err != nil {
 			// Network error or backend unreachable: try next backend
+			result.recordError(backend.Name)
 			h.logger.Warn().Err(err).
 				Str("backend", backend.Name).
 				Msg("Backend request failed, trying next")
@@ -174,6 +245,10 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 	}
 
 	// All backends failed - provide specific error based on what happened
+	if backendsTried > 0 {
+		h.metrics.RecordCascadeDepth(h.Name(), "not_found", backendsTried)
+	}
+
 	var errDetail string
 	var statusCode int
 
@@ -185,7 +260,7 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 			Int("backends_skipped", backendsSkipped).
 			Msg("All backends skipped due to scope filtering")
 
-		errDetail = fmt.Sprintf("Image not accessible: all %d backend(s) filtered by organization scope", backendsSkipped)
+		errDetail = fmt.Sprintf("Image not accessible: all %d backend(s) filtered by organization or team scope", backendsSkipped)
 		statusCode = http.StatusNotFound
 	} else if backendsTried == 0 {
 		// No backends tried and none skipped (shouldn't happen, but defensive)
@@ -212,6 +287,7 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 	}
 
 	// Return error response
+	h.setBackendsTriedHeader(w, &result)
 	w.Header().Set("Docker-Distribution-Api-Version", "registry/2.0")
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -221,7 +297,7 @@ func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request,
 			{
 				Code:    "NAME_UNKNOWN",
 				Message: "repository name not known to registry",
-				Detail:  errDetail,
+				Detail:  h.cascadeErrorDetail(errDetail, &result),
 			},
 		},
 	}
@@ -301,6 +377,39 @@ func (h *Handler) shouldTryGHCR(path string, backend *config.OCIBackendConfig, _
 	return imageOrg == requiredOrg
 }
 
+// backendSkipReason returns why a pull backend must be skipped for this request,
+// or an empty string if the backend is eligible.
+func (h *Handler) backendSkipReason(path string, backend *config.OCIBackendConfig, authResult *auth.AuthResult) string {
+	// Skip team-scoped backends unless the user belongs to one of the teams
+	if len(backend.Teams) > 0 && !inBackendTeams(backend, authResult) {
+		return "user not in backend teams"
+	}
+
+	// Skip GHCR if org doesn't match scope or authenticated user's org. Reads in the
+	// mirror namespace name their registry and are not scoped.
+	if _, _, mirrored := h.parseMirrorPath(path); mirrored {
+		return ""
+	}
+	if backend.UpstreamNamespace == "ghcr.io" && !h.shouldTryGHCR(path, backend, authResult) {
+		return "image org not in scope"
+	}
+
+	return ""
+}
+
+// inBackendTeams reports whether the authenticated user is a member of any of the backend's teams
+func inBackendTeams(backend *config.OCIBackendConfig, authResult *auth.AuthResult) bool {
+	if authResult == nil {
+		return false
+	}
+	for _, team := range backend.Teams {
+		if slices.Contains(authResult.Teams, team) {
+			return true
+		}
+	}
+	return false
+}
+
 // extractOrgFromPath extracts the organization/user from the image path
 // /v2/myorg/myimage/manifests/latest -> myorg
 // /v2/myuser/myrepo/blobs/sha256:abc -> myuser
@@ -0,0 +1,56 @@
+package oci
+
+import (
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+// TestInBackendTeams tests team-based backend routing decisions
+func TestInBackendTeams(t *testing.T) {
+	backend := &config.OCIBackendConfig{
+		Name:  "external-mirror",
+		Teams: []string{"platform", "sre"},
+	}
+
+	tests := []struct {
+		name       string
+		authResult *auth.AuthResult
+		expected   bool
+	}{
+		{
+			name:       "member of backend team",
+			authResult: &auth.AuthResult{Username: "alice", Teams: []string{"platform"}},
+			expected:   true,
+		},
+		{
+			name:       "member of second backend team",
+			authResult: &auth.AuthResult{Username: "bob", Teams: []string{"devs", "sre"}},
+			expected:   true,
+		},
+		{
+			name:       "not a member of any backend team",
+			authResult: &auth.AuthResult{Username: "carol", Teams: []string{"devs"}},
+			expected:   false,
+		},
+		{
+			name:       "GitHub Actions token has no teams",
+			authResult: &auth.AuthResult{Username: "github-actions[bot]", TokenType: auth.TokenTypeGitHubActions},
+			expected:   false,
+		},
+		{
+			name:       "no auth result",
+			authResult: nil,
+			expected:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inBackendTeams(backend, tt.authResult); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/trash"
+)
+
+// SetTrash enables soft-delete: manifest and blob deletes are held in t instead of
+// being forwarded to the push backend. PurgeTrashed carries them out once expired.
+func (h *Handler) SetTrash(t *trash.Trash) {
+	h.trash = t
+}
+
+// isTrashablePath reports whether path is a manifest or blob, as opposed to e.g. an
+// upload session, whose DELETE cancels the upload
+func isTrashablePath(path string) bool {
+	if strings.Contains(path, "/manifests/") {
+		return true
+	}
+	return strings.Contains(path, "/blobs/") && !strings.Contains(path, "/blobs/uploads/")
+}
+
+// trashDelete moves the manifest or blob a DELETE targets into the trash instead of
+// deleting it from the push backend, and answers as the registry would. It reports
+// false, leaving the request to be proxied, if the trash is disabled, the request
+// isn't a manifest or blob delete, or the push backend doesn't have the artifact
+// (so the client gets the backend's own error).
+func (h *Handler) trashDelete(w http.ResponseWriter, r *http.Request, authResult *auth.AuthResult) bool {
+	path := r.URL.Path
+	if h.trash == nil || r.Method != http.MethodDelete || !isTrashablePath(path) {
+		return false
+	}
+
+	headReq := r.Clone(r.Context())
+	headReq.Method = http.MethodHead
+	headReq.Body = http.NoBody
+	headReq.ContentLength = 0
+
+	resp, err := h.executeProxyRequest(headReq, &h.config.PushBackend, path)
+	status := http.StatusServiceUnavailable
+	if err == nil {
+		_ = resp.Body.Close()
+		status = resp.StatusCode
+	}
+
+	switch status {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return false
+	default:
+		// Never forward the delete when unsure whether it can be trashed
+		h.logger.Warn().Err(err).
+			Str("path", path).
+			Int("status", status).
+			Msg("Cannot check artifact on push backend, refusing delete")
+		if err := writeOCIError(w, http.StatusServiceUnavailable, "UNAVAILABLE",
+			"registry service unavailable", "The artifact could not be moved to the trash, try again later"); err != nil {
+			h.logger.Error().Err(err).Msg("Failed to encode error response")
+		}
+		return true
+	}
+
+	deletedBy := ""
+	if authResult != nil {
+		deletedBy = authResult.Username
+	}
+	entry := h.trash.Add(path, deletedBy)
+
+	h.logger.Info().
+		Str("path", path).
+		Str("deleted_by", entry.DeletedBy).
+		Time("purge_at", entry.PurgeAt).
+		Msg("Moved deleted artifact to trash")
+
+	w.Header().Set("Docker-Distribution-Api-Version", "registry/2.0")
+	w.WriteHeader(http.StatusAccepted)
+	return true
+}
+
+// hiddenByTrash reports whether backend's copy of the artifact at path is in the
+// trash. Only the push backend's artifacts are trashed; other pull backends may
+// still serve the same path.
+func (h *Handler) hiddenByTrash(backend *config.OCIBackendConfig, path string) bool {
+	return h.trash != nil && backend.URL == h.config.PushBackend.URL && h.trash.Contains(path)
+}
+
+// trashedManifest reports whether resp is the push backend's copy of a manifest
+// trashed under its digest, e.g. a tag read of a manifest deleted by digest
+func (h *Handler) trashedManifest(backend *config.OCIBackendConfig, path string, resp *proxy.Response) bool {
+	digest := resp.Headers.Get("Docker-Content-Digest")
+	if h.trash == nil || digest == "" {
+		return false
+	}
+	repository, _, ok := parseManifestPath(path)
+	if !ok {
+		return false
+	}
+	return h.hiddenByTrash(backend, "/v2/"+repository+"/manifests/"+digest)
+}
+
+// restorePushed takes the artifact a successful write pushed out of the trash,
+// under its path and, for manifests, its digest
+func (h *Handler) restorePushed(r *http.Request, resp *http.Response) {
+	if h.trash == nil {
+		return
+	}
+	path := pushedArtifactPath(r)
+	if path == "" {
+		return
+	}
+
+	h.trash.Remove(path)
+	if repository, _, ok := parseManifestPath(path); ok {
+		if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+			h.trash.Remove("/v2/" + repository + "/manifests/" + digest)
+		}
+	}
+}
+
+// PurgeTrashed forwards the delete of a trashed artifact whose retention expired to
+// the push backend. An artifact the backend no longer has counts as purged.
+func (h *Handler) PurgeTrashed(ctx context.Context, entry trash.Entry) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, entry.Path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.proxyClient.ProxyRequest(&proxy.Request{
+		Method:      http.MethodDelete,
+		Path:        entry.Path,
+		Headers:     http.Header{},
+		Backend:     &h.config.PushBackend,
+		OriginalReq: req,
+	})
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if (resp.StatusCode >= 200 && resp.StatusCode < 300) || resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return fmt.Errorf("push backend returned %d", resp.StatusCode)
+}
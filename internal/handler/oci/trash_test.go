@@ -0,0 +1,111 @@
+package oci
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/trash"
+	"github.com/rs/zerolog"
+)
+
+// TestSelectBackendAndProxy_Trash tests that deletes are held in the trash, hiding
+// the artifact from reads, until restored by a push or purged
+func TestSelectBackendAndProxy_Trash(t *testing.T) {
+	const digest = "sha256:111"
+	var registryLog requestLog
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registryLog.add(r.Method)
+		switch r.URL.Path {
+		case "/v2/app/manifests/v1", "/v2/app/manifests/" + digest:
+			w.Header().Set("Docker-Content-Digest", digest)
+			switch r.Method {
+			case http.MethodPut:
+				w.WriteHeader(http.StatusCreated)
+			case http.MethodDelete:
+				w.WriteHeader(http.StatusAccepted)
+			default:
+				w.WriteHeader(http.StatusOK)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer registry.Close()
+
+	backend := config.OCIBackendConfig{
+		Name:                "local",
+		URL:                 registry.URL,
+		MaxIdleConns:        1,
+		MaxIdleConnsPerHost: 1,
+		DialTimeout:         time.Second,
+		RequestTimeout:      10 * time.Second,
+	}
+	cfg := &config.OCIConfig{
+		PullBackends: []config.OCIBackendConfig{backend},
+		PushBackend:  backend,
+	}
+
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("oci_trash_test"), logger)
+	// Zero retention: trashed artifacts are purged on the next purge run
+	trashed, err := trash.New(&config.TrashConfig{Enabled: true}, logger)
+	if err != nil {
+		t.Fatalf("failed to create trash: %v", err)
+	}
+	h.SetTrash(trashed)
+
+	user := &auth.AuthResult{Username: "alice"}
+	do := func(method, path string, wantStatus int) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, nil)
+		if err := h.selectBackendAndProxy(w, r, user); err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		if w.Code != wantStatus {
+			t.Fatalf("%s %s status = %d, want %d", method, path, w.Code, wantStatus)
+		}
+	}
+
+	// A delete is accepted without reaching the backend, and hides the tag
+	do(http.MethodDelete, "/v2/app/manifests/v1", http.StatusAccepted)
+	if n := registryLog.count(http.MethodDelete); n != 0 {
+		t.Errorf("registry received %d DELETEs, want 0 while trashed", n)
+	}
+	if entries := trashed.Entries(); len(entries) != 1 || entries[0].DeletedBy != "alice" {
+		t.Errorf("trash entries = %+v, want the tag deleted by alice", entries)
+	}
+	do(http.MethodGet, "/v2/app/manifests/v1", http.StatusNotFound)
+
+	// Pushing the tag again restores it
+	do(http.MethodPut, "/v2/app/manifests/v1", http.StatusCreated)
+	do(http.MethodGet, "/v2/app/manifests/v1", http.StatusOK)
+
+	// Deleting the manifest by digest hides the tags pointing at it
+	do(http.MethodDelete, "/v2/app/manifests/"+digest, http.StatusAccepted)
+	do(http.MethodGet, "/v2/app/manifests/v1", http.StatusNotFound)
+
+	// Deletes of artifacts the backend doesn't have are proxied for its error
+	do(http.MethodDelete, "/v2/app/manifests/v2", http.StatusNotFound)
+	if n := registryLog.count(http.MethodDelete); n != 1 {
+		t.Errorf("registry received %d DELETEs, want 1 for the missing manifest", n)
+	}
+
+	// Once expired, the delete is forwarded and the entry dropped
+	if purged := trashed.Purge(context.Background(), h.PurgeTrashed); purged != 1 {
+		t.Errorf("purged %d entries, want 1", purged)
+	}
+	if n := registryLog.count(http.MethodDelete); n != 2 {
+		t.Errorf("registry received %d DELETEs, want 2 after the purge", n)
+	}
+	if trashed.Contains("/v2/app/manifests/" + digest) {
+		t.Error("purged manifest still in the trash")
+	}
+}
@@ -0,0 +1,54 @@
+package pub
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/auth"
+)
+
+// authenticateClient validates the client's GitHub PAT using shared authenticator.
+// dart pub sends the token registered with `dart pub token add` as a bearer token.
+func (h *Handler) authenticateClient(r *http.Request) (*auth.AuthResult, *http.Request, error) {
+	authResult, newReq, err := h.authenticator.AuthenticateAndInjectContext(r)
+	if err != nil {
+		return nil, r, err
+	}
+
+	return authResult, newReq, nil
+}
+
+// handleAuthError returns a pub-compliant error response. dart pub prints the message
+// of a Bearer challenge with realm "pub", and the message of the JSON error body.
+func (h *Handler) handleAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	h.logger.Warn().Err(err).
+		Str("path", r.URL.Path).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("Authentication failed")
+
+	// dart pub shows the challenge message to the user, of 403 responses too
+	status, code := http.StatusUnauthorized, "MissingAuthentication"
+	message := h.config.ClientAuth.UnauthorizedMessage("Authentication required: add a GitHub token with `dart pub token add`")
+	if errors.Is(err, auth.ErrInsufficientPermissions) {
+		// The credentials are valid but don't allow the operation
+		status, code, message = http.StatusForbidden, "InsufficientPermissions", auth.InsufficientPermissionsMessage
+	}
+
+	// Set WWW-Authenticate challenge header
+	auth.SetChallenges(w.Header(), &h.config.ClientAuth, auth.ChallengeOptions{
+		Schemes:      []string{auth.SchemeBearer},
+		Realm:        "pub",
+		BearerParams: []string{"message", message},
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if encodeErr := json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]string{
+			"code":    code,
+			"message": message,
+		},
+	}); encodeErr != nil {
+		h.logger.Error().Err(encodeErr).Msg("Failed to write authentication error response")
+	}
+}
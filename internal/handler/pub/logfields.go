@@ -0,0 +1,28 @@
+package pub
+
+import (
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// addLogFields adds the package the request targets to its completion log line:
+// pub_package for package API requests and archives, and pub_version when the path
+// names a version
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	p := h.backendPath(r)
+	name, version, ok := parseArchivePath(p)
+	if !ok {
+		name, version, ok = parsePackagePath(p)
+	}
+	if !ok {
+		return
+	}
+	middleware.AddLogField(ctx, "pub_package", name)
+	if version != "" {
+		middleware.AddLogField(ctx, "pub_version", version)
+	}
+}
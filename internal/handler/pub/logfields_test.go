@@ -0,0 +1,55 @@
+package pub
+
+import "testing"
+
+func TestParsePackagePath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantName    string
+		wantVersion string
+		wantOK      bool
+	}{
+		{"/api/packages/http", "http", "", true},
+		{"/api/packages/http/versions/1.2.0", "http", "1.2.0", true},
+		{"/api/packages/http/advisories", "", "", false},
+		{"/api/packages/versions/new", "", "", false},
+		{"/api/packages/", "", "", false},
+		{"/packages/http/versions/1.2.0.tar.gz", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			name, version, ok := parsePackagePath(tt.path)
+			if name != tt.wantName || version != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("parsePackagePath() = %q, %q, %v, want %q, %q, %v",
+					name, version, ok, tt.wantName, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseArchivePath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantName    string
+		wantVersion string
+		wantOK      bool
+	}{
+		{"/packages/http/versions/1.2.0.tar.gz", "http", "1.2.0", true},
+		{"/packages/acme_client/versions/2.0.0-dev.1.tar.gz", "acme_client", "2.0.0-dev.1", true},
+		{"/api/archives/http-1.2.0.tar.gz", "http", "1.2.0", true},
+		{"/api/archives/http.tar.gz", "", "", false},
+		{"/packages/http/versions/1.2.0", "", "", false},
+		{"/api/packages/http", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			name, version, ok := parseArchivePath(tt.path)
+			if name != tt.wantName || version != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("parseArchivePath() = %q, %q, %v, want %q, %q, %v",
+					name, version, ok, tt.wantName, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
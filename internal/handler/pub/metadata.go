@@ -0,0 +1,24 @@
+package pub
+
+import (
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/metadata"
+)
+
+// SetMetadata enables recording downloaded archives in the metadata database
+func (h *Handler) SetMetadata(store *metadata.Store) {
+	h.metadata = store
+}
+
+// recordPulled records an archive the backend served successfully as a pull of its
+// package version. Publishes aren't recorded: the package and version are only known
+// from the pubspec inside the uploaded archive.
+func (h *Handler) recordPulled(r *http.Request, path string, statusCode int) {
+	if h.metadata == nil || r.Method != http.MethodGet || statusCode != http.StatusOK {
+		return
+	}
+	if name, version, ok := parseArchivePath(path); ok {
+		h.metadata.RecordPull(h.Name(), name, version, "")
+	}
+}
@@ -0,0 +1,147 @@
+package pub
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/proxy/rewriter"
+)
+
+// proxyWithRewriting proxies the request to the backend, rewriting backend URLs in
+// redirects (such as the publish flow's finalization URL) and JSON responses to
+// point at the proxy. Archives are streamed unmodified.
+func (h *Handler) proxyWithRewriting(w http.ResponseWriter, r *http.Request, backend *config.PubBackendConfig) error {
+	path := h.backendPath(r)
+
+	resp, err := h.executeProxyRequest(r, backend, path)
+	if err != nil {
+		return err
+	}
+	h.recordPulled(r, path, resp.StatusCode)
+
+	// Determine proxy URL for rewriting (base URL + path prefix)
+	proxyURL := h.determineProxyURL(r)
+
+	// Rewrite Location header (archive redirects and the upload's finalization URL)
+	if !rewriter.RewriteRedirectLocation(resp, backend, proxyURL) {
+		if location := resp.Headers.Get("Location"); location != "" {
+			resp.Headers.Set("Location", h.rewriteURL(location, backend.URL, proxyURL))
+		}
+	}
+
+	// Archives and unsuccessful responses are passed through
+	if resp.StatusCode != http.StatusOK || !h.shouldRewriteBody(resp.Headers.Get("Content-Type")) {
+		_, err = h.proxyClient.StreamResponse(w, resp, true)
+		return err
+	}
+
+	// Buffer and rewrite the JSON response
+	body, err := h.proxyClient.ReadResponseBody(resp)
+	if err != nil {
+		w.WriteHeader(resp.StatusCode)
+		return err
+	}
+
+	// Decompress gzip content if needed for URL rewriting
+	if decompressed, wasDecompressed := h.decompressIfNeeded(body, resp.Headers.Get("Content-Encoding")); wasDecompressed {
+		body = decompressed
+		resp.Headers.Del("Content-Encoding")
+	}
+
+	rewritten := h.rewriteResponseJSON(body, backend.URL, proxyURL)
+	if !bytes.Equal(rewritten, body) {
+		// The backend's validators and digests describe the original document
+		resp.Headers.Del("ETag")
+		resp.Headers.Del("Digest")
+		resp.Headers.Del("Repr-Digest")
+		resp.Headers.Del("Accept-Ranges")
+	}
+
+	return h.proxyClient.WriteResponse(w, resp, rewritten, true)
+}
+
+// executeProxyRequest sends the request to backend and records backend metrics,
+// returning the response without writing it
+func (h *Handler) executeProxyRequest(r *http.Request, backend *config.PubBackendConfig, path string) (*proxy.Response, error) {
+	// Create proxy request
+	proxyReq := &proxy.Request{
+		Method:        r.Method,
+		Path:          path,
+		Query:         r.URL.RawQuery,
+		Body:          r.Body,
+		ContentLength: r.ContentLength,
+		Headers:       r.Header,
+		Backend:       backend,
+		OriginalReq:   r,
+	}
+
+	// Track backend request timing
+	start := time.Now()
+
+	// Execute proxy request
+	resp, err := h.proxyClient.ProxyRequest(proxyReq)
+
+	// Record metrics regardless of success/failure
+	duration := time.Since(start)
+
+	if err != nil {
+		// Record backend error metrics
+		h.metrics.RecordBackendError(h.Name(), backend.Name, "network_error")
+		h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+		h.metrics.SetBackendHealth(backend.Name, false)
+
+		h.logger.Error().Err(err).
+			Str("backend", backend.Name).
+			Dur("duration", duration).
+			Msg("Backend request failed")
+
+		return nil, err
+	}
+
+	// Record backend latency for all requests
+	h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+
+	// Record backend health based on status code
+	if resp.StatusCode >= 500 {
+		// Server error - backend is unhealthy
+		h.metrics.RecordBackendErrorByStatus(backend.Name, resp.StatusCode)
+		h.metrics.SetBackendHealth(backend.Name, false)
+	} else if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+		// Success - backend is healthy
+		h.metrics.SetBackendHealth(backend.Name, true)
+	}
+	// 4xx errors don't affect backend health (client errors)
+
+	return resp, nil
+}
+
+// decompressIfNeeded decompresses gzip-encoded content if needed
+// Returns the decompressed body and true if decompression occurred, or original body and false otherwise
+func (h *Handler) decompressIfNeeded(body []byte, contentEncoding string) ([]byte, bool) {
+	if contentEncoding != "gzip" {
+		return body, false
+	}
+
+	gzReader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to create gzip reader, using raw body")
+		return body, false
+	}
+
+	decompressed, err := io.ReadAll(gzReader)
+	if closeErr := gzReader.Close(); closeErr != nil {
+		h.logger.Warn().Err(closeErr).Msg("Failed to close gzip reader")
+	}
+
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to decompress gzip body, using raw body")
+		return body, false
+	}
+
+	return decompressed, true
+}
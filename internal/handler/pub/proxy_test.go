@@ -0,0 +1,179 @@
+package pub
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// TestSelectBackendAndProxy tests that the package API, archives and the publish flow
+// are proxied with the backend's credentials, and that archive, upload and
+// finalization URLs pointing at the backend are rewritten to the proxy
+func TestSelectBackendAndProxy(t *testing.T) {
+	var server *httptest.Server
+	var requested []string
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		requested = append(requested, r.Method+" "+r.URL.Path+" "+user+" "+string(body))
+
+		base := server.URL + "/acme"
+		switch r.URL.Path {
+		case "/acme/api/packages/acme_client":
+			w.Header().Set("Content-Type", "application/vnd.pub.v2+json")
+			w.Header().Set("ETag", `"listing"`)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"name": "acme_client",
+				"latest": map[string]any{
+					"version":     "1.1.0",
+					"archive_url": base + "/packages/acme_client/versions/1.1.0.tar.gz",
+				},
+				"versions": []any{
+					map[string]any{"version": "1.0.0", "archive_url": base + "/packages/acme_client/versions/1.0.0.tar.gz"},
+					map[string]any{"version": "1.1.0", "archive_url": base + "/packages/acme_client/versions/1.1.0.tar.gz"},
+				},
+			})
+		case "/acme/packages/acme_client/versions/1.1.0.tar.gz":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte("archive"))
+		case "/acme/api/packages/versions/new":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{"url": base + "/api/packages/versions/upload", "fields": map[string]any{}})
+		case "/acme/api/packages/versions/upload":
+			w.Header().Set("Location", base+"/api/packages/versions/finalize?id=1")
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.PubConfig{
+		PathPrefix: "/pub",
+		Backend: config.PubBackendConfig{
+			Name:                "unpub",
+			URL:                 server.URL + "/acme",
+			Auth:                &config.AuthConfig{Type: "basic", Username: "reader", Password: "secret"},
+			MaxIdleConns:        1,
+			MaxIdleConnsPerHost: 1,
+			DialTimeout:         time.Second,
+			RequestTimeout:      10 * time.Second,
+		},
+	}
+
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("pub_proxy_test"), logger)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		requested = nil
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Host = "artifusion.example.com"
+		r.Header.Set("Authorization", "Bearer ghp_client_token")
+		if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+			t.Fatalf("selectBackendAndProxy failed: %v", err)
+		}
+		return w
+	}
+
+	const proxyURL = "https://artifusion.example.com/pub"
+
+	t.Run("package listing", func(t *testing.T) {
+		w := serve(http.MethodGet, "/pub/api/packages/acme_client", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		if want := "GET /acme/api/packages/acme_client reader "; len(requested) != 1 || requested[0] != want {
+			t.Errorf("backend requests = %q, want %q", requested, want)
+		}
+
+		var listing struct {
+			Latest struct {
+				ArchiveURL string `json:"archive_url"`
+			} `json:"latest"`
+			Versions []struct {
+				ArchiveURL string `json:"archive_url"`
+			} `json:"versions"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &listing); err != nil {
+			t.Fatalf("invalid listing: %v", err)
+		}
+		if want := proxyURL + "/packages/acme_client/versions/1.1.0.tar.gz"; listing.Latest.ArchiveURL != want {
+			t.Errorf("latest archive_url = %q, want %q", listing.Latest.ArchiveURL, want)
+		}
+		for _, version := range listing.Versions {
+			if !strings.HasPrefix(version.ArchiveURL, proxyURL+"/packages/") {
+				t.Errorf("version archive_url = %q, want it below %s", version.ArchiveURL, proxyURL)
+			}
+		}
+		if w.Header().Get("ETag") != "" {
+			t.Error("expected the backend's ETag to be dropped from the rewritten listing")
+		}
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		w := serve(http.MethodGet, "/pub/packages/acme_client/versions/1.1.0.tar.gz", "")
+		if w.Code != http.StatusOK || w.Body.String() != "archive" {
+			t.Errorf("got %d %q, want %d %q", w.Code, w.Body.String(), http.StatusOK, "archive")
+		}
+	})
+
+	t.Run("publish flow", func(t *testing.T) {
+		w := serve(http.MethodGet, "/pub/api/packages/versions/new", "")
+		var upload struct {
+			URL string `json:"url"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &upload); err != nil {
+			t.Fatalf("invalid upload response: %v", err)
+		}
+		if want := proxyURL + "/api/packages/versions/upload"; upload.URL != want {
+			t.Errorf("upload url = %q, want %q", upload.URL, want)
+		}
+
+		w = serve(http.MethodPost, "/pub/api/packages/versions/upload", "multipart")
+		if w.Code != http.StatusNoContent {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNoContent)
+		}
+		if want := "POST /acme/api/packages/versions/upload reader multipart"; len(requested) != 1 || requested[0] != want {
+			t.Errorf("backend requests = %q, want %q", requested, want)
+		}
+		if want := proxyURL + "/api/packages/versions/finalize?id=1"; w.Header().Get("Location") != want {
+			t.Errorf("Location = %q, want %q", w.Header().Get("Location"), want)
+		}
+	})
+}
+
+func TestRewriteURL(t *testing.T) {
+	h := &Handler{logger: zerolog.Nop()}
+	const backendURL = "http://unpub:4000/acme/"
+	const proxyURL = "https://artifusion.example.com/pub"
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://unpub:4000/acme/packages/http/versions/1.2.0.tar.gz", proxyURL + "/packages/http/versions/1.2.0.tar.gz"},
+		{"https://unpub:4000/acme/api/packages/versions/upload", proxyURL + "/api/packages/versions/upload"},
+		{"http://unpub:4000/acme", proxyURL},
+		{"http://unpub:4000/acmecorp/packages/http", "http://unpub:4000/acmecorp/packages/http"},
+		{"https://storage.googleapis.com/pub-packages/http-1.2.0.tar.gz", "https://storage.googleapis.com/pub-packages/http-1.2.0.tar.gz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := h.rewriteURL(tt.url, backendURL, proxyURL); got != tt.want {
+				t.Errorf("rewriteURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package pub
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const (
+	// MaxJSONRewriteSize is the maximum size of JSON body to rewrite (10MB)
+	// Larger responses will use fallback text rewriting
+	MaxJSONRewriteSize = 10 * 1024 * 1024
+)
+
+// determineProxyURL determines the proxy URL for pub handler
+// Constructs URL dynamically from request headers + protocol config
+// Returns the full proxy URL including the path prefix (e.g., https://pub.example.com/pub)
+func (h *Handler) determineProxyURL(r *http.Request) string {
+	return h.getEffectiveBaseURL(r)
+}
+
+// rewriteResponseJSON rewrites backend URLs in a pub API response: archive_url of
+// the package listing's latest and versions entries (and of the deprecated single
+// version response), and the upload url of the publish flow's versions/new response.
+// Responses without a backend URL are returned unchanged.
+func (h *Handler) rewriteResponseJSON(body []byte, backendURL, proxyURL string) []byte {
+	// Early return for empty body
+	if len(body) == 0 {
+		return body
+	}
+
+	// Check size limit to prevent memory issues with large responses
+	if len(body) > MaxJSONRewriteSize {
+		h.logger.Warn().
+			Int("size", len(body)).
+			Int("max_size", MaxJSONRewriteSize).
+			Msg("Response body exceeds JSON rewrite size limit, using text fallback")
+		return h.rewriteBody(body, backendURL, proxyURL)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		// Not a JSON object, use text fallback
+		h.logger.Debug().Err(err).Msg("Failed to parse JSON, using text fallback")
+		return h.rewriteBody(body, backendURL, proxyURL)
+	}
+
+	changed := h.rewriteField(data, "archive_url", backendURL, proxyURL)
+	changed = h.rewriteField(data, "url", backendURL, proxyURL) || changed
+	if latest, ok := data["latest"].(map[string]interface{}); ok {
+		changed = h.rewriteField(latest, "archive_url", backendURL, proxyURL) || changed
+	}
+	if versions, ok := data["versions"].([]interface{}); ok {
+		for _, version := range versions {
+			if versionMap, ok := version.(map[string]interface{}); ok {
+				changed = h.rewriteField(versionMap, "archive_url", backendURL, proxyURL) || changed
+			}
+		}
+	}
+
+	if !changed {
+		return body
+	}
+
+	rewritten, err := json.Marshal(data)
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to marshal rewritten JSON, using text fallback")
+		return h.rewriteBody(body, backendURL, proxyURL)
+	}
+
+	h.logger.Debug().
+		Int("original_size", len(body)).
+		Int("rewritten_size", len(rewritten)).
+		Msg("Pub response JSON rewritten")
+
+	return rewritten
+}
+
+// rewriteField rewrites the URL in data[field], if it is a backend URL, and reports
+// whether it did
+func (h *Handler) rewriteField(data map[string]interface{}, field, backendURL, proxyURL string) bool {
+	value, ok := data[field].(string)
+	if !ok {
+		return false
+	}
+	rewritten := h.rewriteURL(value, backendURL, proxyURL)
+	if rewritten == value {
+		return false
+	}
+	data[field] = rewritten
+	return true
+}
+
+// rewriteURL rewrites a single URL from backend to proxy. URLs are matched against
+// the backend URL including its path, for either scheme, so repositories served
+// below a path keep working; URLs elsewhere (e.g. archives in cloud storage) are
+// returned unchanged.
+func (h *Handler) rewriteURL(url, backendURL, proxyURL string) string {
+	address := stripScheme(backendURL)
+
+	for _, scheme := range []string{"http://", "https://"} {
+		rest, ok := strings.CutPrefix(url, scheme+address)
+		if !ok || (rest != "" && !strings.HasPrefix(rest, "/") && !strings.HasPrefix(rest, "?")) {
+			continue
+		}
+		rewritten := proxyURL + rest
+
+		h.logger.Debug().
+			Str("original", url).
+			Str("rewritten", rewritten).
+			Msg("URL rewritten")
+
+		return rewritten
+	}
+
+	// URL doesn't point to our backend, return unchanged
+	return url
+}
+
+// rewriteBody is a simpler fallback method for text-based rewriting
+// Used when JSON parsing fails but content is still text
+func (h *Handler) rewriteBody(body []byte, backendURL, proxyURL string) []byte {
+	address := stripScheme(backendURL)
+
+	rewritten := body
+	for _, scheme := range []string{"http://", "https://"} {
+		rewritten = bytes.ReplaceAll(rewritten, []byte(scheme+address), []byte(proxyURL))
+	}
+
+	if !bytes.Equal(body, rewritten) {
+		h.logger.Debug().
+			Int("original_size", len(body)).
+			Int("rewritten_size", len(rewritten)).
+			Msg("Body rewritten (text mode)")
+	}
+
+	return rewritten
+}
+
+// shouldRewriteBody determines if response body should be rewritten: the JSON of the
+// package API and the publish flow, served as application/vnd.pub.v2+json or
+// application/json
+func (h *Handler) shouldRewriteBody(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+
+	return strings.Contains(contentType, "json")
+}
+
+// stripScheme returns a backend URL without its scheme and trailing slash, keeping
+// the path of backends served below one
+// Examples:
+//   - "https://pub.example.com/acme/" -> "pub.example.com/acme"
+//   - "http://unpub:4000" -> "unpub:4000"
+func stripScheme(url string) string {
+	address := strings.TrimPrefix(url, "http://")
+	address = strings.TrimPrefix(address, "https://")
+	return strings.TrimSuffix(address, "/")
+}
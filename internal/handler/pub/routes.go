@@ -0,0 +1,103 @@
+package pub
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// selectBackendAndProxy proxies the request to the backend: the package API, archive
+// downloads and every step of the publish flow are served by one repository
+func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request, authResult *auth.AuthResult) error {
+	backend := &h.config.Backend
+
+	operationType := "read"
+	if auth.IsWriteMethod(r.Method) {
+		operationType = "write"
+	}
+
+	h.logger.Debug().
+		Str("backend", backend.Name).
+		Str("url", backend.URL).
+		Str("operation", operationType).
+		Str("username", authResult.Username).
+		Msg("Routing to pub backend")
+	middleware.AddLogField(r.Context(), "backend", backend.Name)
+
+	// Note: Backend authentication is handled by proxy client
+	return h.proxyWithRewriting(w, r, backend)
+}
+
+// backendPath returns the request path with the path prefix stripped
+func (h *Handler) backendPath(r *http.Request) string {
+	path := r.URL.Path
+	if h.config.PathPrefix != "" {
+		path = strings.TrimPrefix(path, h.config.PathPrefix)
+		// Ensure path starts with /
+		if path == "" || !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	return path
+}
+
+// parsePackagePath extracts the package, and the version if any, from a package API
+// path. The publish flow's /api/packages/versions/... paths name no package.
+//
+//	/api/packages/http                  -> http, ""
+//	/api/packages/http/versions/1.2.0   -> http, 1.2.0
+//	/api/packages/versions/new          -> not a package path
+func parsePackagePath(p string) (name, version string, ok bool) {
+	rest, found := strings.CutPrefix(p, "/api/packages/")
+	if !found || rest == "" {
+		return "", "", false
+	}
+	name, rest, _ = strings.Cut(rest, "/")
+	if name == "versions" {
+		return "", "", false
+	}
+	if rest == "" {
+		return name, "", true
+	}
+	version, found = strings.CutPrefix(rest, "versions/")
+	if !found || version == "" || strings.Contains(version, "/") {
+		return "", "", false
+	}
+	return name, version, true
+}
+
+// parseArchivePath extracts the package and version from the path of an archive, in
+// either layout hosted repositories serve archives at
+//
+//	/packages/http/versions/1.2.0.tar.gz -> http, 1.2.0
+//	/api/archives/http-1.2.0.tar.gz      -> http, 1.2.0
+func parseArchivePath(p string) (name, version string, ok bool) {
+	if file, found := strings.CutPrefix(p, "/api/archives/"); found {
+		file, found = strings.CutSuffix(file, ".tar.gz")
+		if !found || strings.Contains(file, "/") {
+			return "", "", false
+		}
+		// Package names can't contain dashes, so the name ends at the first one
+		name, version, found = strings.Cut(file, "-")
+		if !found || name == "" || version == "" {
+			return "", "", false
+		}
+		return name, version, true
+	}
+
+	rest, found := strings.CutPrefix(p, "/packages/")
+	if !found {
+		return "", "", false
+	}
+	name, file, found := strings.Cut(rest, "/versions/")
+	if !found || name == "" || strings.Contains(name, "/") || strings.Contains(file, "/") {
+		return "", "", false
+	}
+	version, found = strings.CutSuffix(file, ".tar.gz")
+	if !found || version == "" {
+		return "", "", false
+	}
+	return name, version, true
+}
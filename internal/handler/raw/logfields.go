@@ -0,0 +1,18 @@
+package raw
+
+import (
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// addLogFields adds the repository the request targets to its completion log line
+// as raw_repository
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	if repository, _ := h.repository(h.backendPath(r)); repository != nil {
+		middleware.AddLogField(ctx, "raw_repository", repository.Path)
+	}
+}
@@ -0,0 +1,30 @@
+package raw
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/metadata"
+)
+
+// SetMetadata enables recording downloaded and uploaded files in the metadata
+// database
+func (h *Handler) SetMetadata(store *metadata.Store) {
+	h.metadata = store
+}
+
+// recordArtifact records a file the backend served or stored successfully. Files
+// have no version: they are recorded by their path below the path prefix, e.g.
+// builds/nightly/app.tar.gz.
+func (h *Handler) recordArtifact(r *http.Request, repositoryPath, path string, statusCode int) {
+	if h.metadata == nil || statusCode < 200 || statusCode >= 300 || strings.HasSuffix(path, "/") {
+		return
+	}
+	name := strings.TrimPrefix(strings.TrimSuffix(repositoryPath, "/")+path, "/")
+	switch r.Method {
+	case http.MethodGet:
+		h.metadata.RecordPull(h.Name(), name, "", "")
+	case http.MethodPut:
+		h.metadata.RecordPush(h.Name(), name, "", "", metadata.NewProvenance(r))
+	}
+}
@@ -0,0 +1,166 @@
+package raw
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/proxy/rewriter"
+)
+
+// allowedMethods are the methods of raw repository requests, as listed in the
+// Allow header of 405 responses
+var (
+	allowedMethods         = []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete}
+	allowedReadOnlyMethods = []string{http.MethodGet, http.MethodHead}
+)
+
+// proxyRepository proxies the request to the repository's backend. Files are
+// streamed unmodified in both directions; uploads are rejected once they exceed the
+// repository's size limit.
+func (h *Handler) proxyRepository(w http.ResponseWriter, r *http.Request, repository *config.RawRepositoryConfig, path string) error {
+	methods := allowedMethods
+	if repository.ReadOnly {
+		methods = allowedReadOnlyMethods
+	}
+	if !slices.Contains(methods, r.Method) {
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		errors.ErrorResponse(w, errors.ErrMethodNotAllowed.WithMessage(fmt.Sprintf("%s is not supported by raw repository %s", r.Method, repository.Path)))
+		return nil
+	}
+
+	var body *limitedBody
+	if r.Method == http.MethodPut && repository.MaxUploadSize > 0 {
+		// Declared sizes are checked up front; chunked uploads as they stream
+		if r.ContentLength > repository.MaxUploadSize {
+			errors.ErrorResponse(w, tooLarge(repository))
+			return nil
+		}
+		body = &limitedBody{ReadCloser: r.Body, remaining: repository.MaxUploadSize}
+		r.Body = body
+	}
+
+	backend := &repository.Backend
+	resp, err := h.executeProxyRequest(r, backend, path)
+	if err != nil {
+		if body != nil && body.exceeded {
+			errors.ErrorResponse(w, tooLarge(repository))
+			return nil
+		}
+		return err
+	}
+	h.recordArtifact(r, repository.Path, path, resp.StatusCode)
+	h.rewriteLocation(r, resp, repository)
+
+	_, err = h.proxyClient.StreamResponse(w, resp, true)
+	return err
+}
+
+// tooLarge returns the error rejecting an upload over the repository's size limit
+func tooLarge(repository *config.RawRepositoryConfig) *errors.AppError {
+	return errors.ErrPayloadTooLarge.WithMessage(fmt.Sprintf("Uploads to raw repository %s are limited to %d bytes", repository.Path, repository.MaxUploadSize))
+}
+
+// limitedBody is a request body failing once more than remaining bytes are read
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+// Read reads from the body, failing once the limit is exceeded
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, errBodyTooLarge
+	}
+	// Read one byte past the limit to detect bodies exceeding it
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		b.exceeded = true
+		return 0, errBodyTooLarge
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// errBodyTooLarge aborts uploads over the size limit
+var errBodyTooLarge = fmt.Errorf("request body exceeds the upload size limit")
+
+// rewriteLocation points redirects of the backend at the repository on the proxy
+func (h *Handler) rewriteLocation(r *http.Request, resp *proxy.Response, repository *config.RawRepositoryConfig) {
+	proxyURL := h.getEffectiveBaseURL(r)
+	if repository.Path != "/" {
+		proxyURL += repository.Path
+	}
+	if !rewriter.RewriteRedirectLocation(resp, &repository.Backend, proxyURL) {
+		if location := resp.Headers.Get("Location"); location != "" {
+			if mapped, ok := rewriter.MapLocation(location, repository.Backend.URL, proxyURL); ok {
+				resp.Headers.Set("Location", mapped)
+			}
+		}
+	}
+}
+
+// executeProxyRequest sends the request to backend and records backend metrics,
+// returning the response without writing it
+func (h *Handler) executeProxyRequest(r *http.Request, backend *config.RawBackendConfig, path string) (*proxy.Response, error) {
+	// Create proxy request
+	proxyReq := &proxy.Request{
+		Method:        r.Method,
+		Path:          path,
+		Query:         r.URL.RawQuery,
+		Body:          r.Body,
+		Headers:       r.Header,
+		Backend:       backend,
+		OriginalReq:   r,
+		ContentLength: r.ContentLength,
+	}
+
+	// Track backend request timing
+	start := time.Now()
+
+	// Execute proxy request
+	resp, err := h.proxyClient.ProxyRequest(proxyReq)
+
+	// Record metrics regardless of success/failure
+	duration := time.Since(start)
+
+	if err != nil {
+		// Record backend error metrics
+		h.metrics.RecordBackendError(h.Name(), backend.Name, "network_error")
+		h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+		h.metrics.SetBackendHealth(backend.Name, false)
+
+		h.logger.Error().Err(err).
+			Str("backend", backend.Name).
+			Dur("duration", duration).
+			Msg("Backend request failed")
+
+		return nil, err
+	}
+
+	// Record backend latency for all requests
+	h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+
+	// Record backend health based on status code
+	if resp.StatusCode >= 500 {
+		// Server error - backend is unhealthy
+		h.metrics.RecordBackendErrorByStatus(backend.Name, resp.StatusCode)
+		h.metrics.SetBackendHealth(backend.Name, false)
+	} else if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+		// Success - backend is healthy
+		h.metrics.SetBackendHealth(backend.Name, true)
+	}
+	// 4xx errors don't affect backend health (client errors)
+
+	return resp, nil
+}
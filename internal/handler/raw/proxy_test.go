@@ -0,0 +1,142 @@
+package raw
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// TestSelectBackendAndProxy tests that requests are proxied to the backend of the
+// repository they target, within its method and size limits
+func TestSelectBackendAndProxy(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	files := map[string]string{}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Aborted uploads may still be read after the client moved on: only record
+		// complete requests
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		requested = append(requested, r.Method+" "+r.URL.Path)
+		switch r.Method {
+		case http.MethodPut:
+			files[r.URL.Path] = string(data)
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			delete(files, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			data, ok := files[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(data))
+		}
+	}))
+	defer backend.Close()
+
+	repository := func(path, name string) config.RawRepositoryConfig {
+		return config.RawRepositoryConfig{
+			Path: path,
+			Backend: config.RawBackendConfig{
+				Name:                name,
+				URL:                 backend.URL + "/" + name,
+				MaxIdleConns:        1,
+				MaxIdleConnsPerHost: 1,
+				DialTimeout:         time.Second,
+				RequestTimeout:      10 * time.Second,
+			},
+		}
+	}
+	builds := repository("/builds", "builds")
+	builds.MaxUploadSize = 8
+	tools := repository("/tools", "tools")
+	tools.ReadOnly = true
+	files["/tools/jq"] = "jq binary"
+
+	cfg := &config.RawConfig{
+		PathPrefix:   "/raw",
+		Repositories: []config.RawRepositoryConfig{builds, tools},
+	}
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("raw_proxy_test"), logger)
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		body          string
+		chunked       bool
+		wantStatus    int
+		wantBody      string
+		wantRequested string // Request the backend received, "" = none
+	}{
+		{name: "upload", method: http.MethodPut, path: "/raw/builds/app.bin", body: "12345678", wantStatus: http.StatusCreated, wantRequested: "PUT /builds/app.bin"},
+		{name: "download", method: http.MethodGet, path: "/raw/builds/app.bin", wantStatus: http.StatusOK, wantBody: "12345678", wantRequested: "GET /builds/app.bin"},
+		{name: "upload over limit", method: http.MethodPut, path: "/raw/builds/big.bin", body: "123456789", wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "chunked upload over limit", method: http.MethodPut, path: "/raw/builds/big.bin", body: "123456789", chunked: true, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "delete", method: http.MethodDelete, path: "/raw/builds/app.bin", wantStatus: http.StatusNoContent, wantRequested: "DELETE /builds/app.bin"},
+		{name: "read-only download", method: http.MethodGet, path: "/raw/tools/jq", wantStatus: http.StatusOK, wantBody: "jq binary", wantRequested: "GET /tools/jq"},
+		{name: "read-only upload", method: http.MethodPut, path: "/raw/tools/jq", body: "evil", wantStatus: http.StatusMethodNotAllowed},
+		{name: "unsupported method", method: http.MethodPost, path: "/raw/builds/app.bin", wantStatus: http.StatusMethodNotAllowed},
+		{name: "unknown repository", method: http.MethodGet, path: "/raw/other/file", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			requested = nil
+			mu.Unlock()
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			r := httptest.NewRequest(tt.method, tt.path, body)
+			if tt.chunked {
+				r.Body = io.NopCloser(strings.NewReader(tt.body))
+				r.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+				t.Fatalf("selectBackendAndProxy failed: %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if tt.wantRequested == "" && len(requested) > 0 {
+				t.Errorf("backend requested %v, want no request", requested)
+			}
+			if tt.wantRequested != "" && (len(requested) != 1 || requested[0] != tt.wantRequested) {
+				t.Errorf("backend requested %v, want [%s]", requested, tt.wantRequested)
+			}
+		})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := files["/builds/big.bin"]; ok {
+		t.Error("upload over the size limit was stored")
+	}
+}
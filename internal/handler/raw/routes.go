@@ -0,0 +1,88 @@
+package raw
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// selectBackendAndProxy determines the repository the request targets and proxies
+// it to the repository's backend
+func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request, authResult *auth.AuthResult) error {
+	repository, path := h.repository(h.backendPath(r))
+	if repository == nil {
+		errors.ErrorResponse(w, errors.ErrNotFound.WithMessage("No raw repository serves this path"))
+		return nil
+	}
+	backend := &repository.Backend
+
+	// Log operation type for debugging
+	operationType := "read"
+	if auth.IsWriteMethod(r.Method) {
+		operationType = "write"
+	}
+
+	h.logger.Debug().
+		Str("repository", repository.Path).
+		Str("backend", backend.Name).
+		Str("url", backend.URL).
+		Str("operation", operationType).
+		Str("username", authResult.Username).
+		Msg("Routing to raw repository backend")
+	middleware.AddLogField(r.Context(), "backend", backend.Name)
+
+	// Note: Backend authentication is handled by proxy client
+	return h.proxyRepository(w, r, repository, path)
+}
+
+// repository returns the repository with the longest path matching path, and the
+// path within it, or nil if no repository matches
+//
+//	/builds/nightly/app.tar.gz with /builds and /  -> /builds, /nightly/app.tar.gz
+//	/tools/jq with /builds and /                   -> /, /tools/jq
+func (h *Handler) repository(path string) (*config.RawRepositoryConfig, string) {
+	var match *config.RawRepositoryConfig
+	for i := range h.config.Repositories {
+		repository := &h.config.Repositories[i]
+		if !matchesRepository(path, repository.Path) {
+			continue
+		}
+		if match == nil || len(repository.Path) > len(match.Path) {
+			match = repository
+		}
+	}
+	if match == nil {
+		return nil, ""
+	}
+
+	if match.Path == "/" {
+		return match, path
+	}
+	rest := strings.TrimPrefix(path, match.Path)
+	if rest == "" {
+		rest = "/"
+	}
+	return match, rest
+}
+
+// matchesRepository reports whether path is below the repository path
+func matchesRepository(path, repositoryPath string) bool {
+	return repositoryPath == "/" || path == repositoryPath || strings.HasPrefix(path, repositoryPath+"/")
+}
+
+// backendPath returns the request path with the path prefix stripped
+func (h *Handler) backendPath(r *http.Request) string {
+	path := r.URL.Path
+	if h.config.PathPrefix != "" {
+		path = strings.TrimPrefix(path, h.config.PathPrefix)
+		// Ensure path starts with /
+		if path == "" || !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	return path
+}
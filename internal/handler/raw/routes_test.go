@@ -0,0 +1,48 @@
+package raw
+
+import (
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+func TestRepository(t *testing.T) {
+	h := &Handler{config: &config.RawConfig{
+		Repositories: []config.RawRepositoryConfig{
+			{Path: "/"},
+			{Path: "/builds"},
+			{Path: "/builds/nightly"},
+		},
+	}}
+
+	tests := []struct {
+		path           string
+		wantRepository string
+		wantPath       string
+	}{
+		{"/builds/app-1.0.tar.gz", "/builds", "/app-1.0.tar.gz"},
+		{"/builds/nightly/app.tar.gz", "/builds/nightly", "/app.tar.gz"},
+		{"/builds", "/builds", "/"},
+		{"/buildscripts/deploy.sh", "/", "/buildscripts/deploy.sh"},
+		{"/tools/jq", "/", "/tools/jq"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			repository, path := h.repository(tt.path)
+			if repository == nil {
+				t.Fatalf("repository(%q) = nil, want %s", tt.path, tt.wantRepository)
+			}
+			if repository.Path != tt.wantRepository || path != tt.wantPath {
+				t.Errorf("repository(%q) = %s, %q, want %s, %q", tt.path, repository.Path, path, tt.wantRepository, tt.wantPath)
+			}
+		})
+	}
+
+	t.Run("no match", func(t *testing.T) {
+		h := &Handler{config: &config.RawConfig{Repositories: []config.RawRepositoryConfig{{Path: "/builds"}}}}
+		if repository, _ := h.repository("/tools/jq"); repository != nil {
+			t.Errorf("repository(/tools/jq) = %s, want nil", repository.Path)
+		}
+	})
+}
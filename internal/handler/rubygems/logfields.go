@@ -0,0 +1,65 @@
+package rubygems
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// addLogFields adds the gem the request targets to its completion log line:
+// rubygems_gem, plus rubygems_version for gem downloads and gemspecs
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	name, version, ok := parseGemPath(h.backendPath(r))
+	if !ok {
+		return
+	}
+	middleware.AddLogField(ctx, "rubygems_gem", name)
+	middleware.AddLogField(ctx, "rubygems_version", version)
+}
+
+// parseGemPath extracts the gem name and version (empty for version-less
+// documents) from a repository path. Index-wide documents such as /versions are
+// not gem paths.
+//
+//	/info/rails                                  -> rails, ""
+//	/gems/rails-7.1.0.gem                        -> rails, 7.1.0
+//	/gems/nokogiri-1.15.4-x86_64-linux.gem       -> nokogiri, 1.15.4
+//	/quick/Marshal.4.8/rails-7.1.0.gemspec.rz    -> rails, 7.1.0
+//	/api/v1/gems/rails.json                      -> rails, ""
+func parseGemPath(path string) (name, version string, ok bool) {
+	switch {
+	case strings.HasPrefix(path, "/info/"):
+		name = strings.TrimPrefix(path, "/info/")
+		return name, "", name != "" && !strings.Contains(name, "/")
+	case strings.HasPrefix(path, "/gems/"):
+		return parseGemFilename(strings.TrimPrefix(path, "/gems/"), ".gem")
+	case strings.HasPrefix(path, "/quick/Marshal.4.8/"):
+		return parseGemFilename(strings.TrimPrefix(path, "/quick/Marshal.4.8/"), ".gemspec.rz")
+	case strings.HasPrefix(path, "/api/v1/gems/") && strings.HasSuffix(path, ".json"):
+		name = strings.TrimSuffix(strings.TrimPrefix(path, "/api/v1/gems/"), ".json")
+		return name, "", name != "" && !strings.Contains(name, "/")
+	}
+	return "", "", false
+}
+
+// parseGemFilename splits a <name>-<version>[-<platform>]<ext> file name. Gem
+// names may contain dashes but versions start with a digit and contain none.
+func parseGemFilename(filename, ext string) (name, version string, ok bool) {
+	base, found := strings.CutSuffix(filename, ext)
+	if !found || strings.Contains(base, "/") {
+		return "", "", false
+	}
+
+	for i := 0; i < len(base)-1; i++ {
+		if base[i] == '-' && base[i+1] >= '0' && base[i+1] <= '9' {
+			name, version = base[:i], base[i+1:]
+			version, _, _ = strings.Cut(version, "-") // drop the platform
+			return name, version, name != ""
+		}
+	}
+	return "", "", false
+}
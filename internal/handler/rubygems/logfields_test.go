@@ -0,0 +1,35 @@
+package rubygems
+
+import "testing"
+
+func TestParseGemPath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantName    string
+		wantVersion string
+		wantOK      bool
+	}{
+		{"/info/rails", "rails", "", true},
+		{"/info/net-http", "net-http", "", true},
+		{"/gems/rails-7.1.0.gem", "rails", "7.1.0", true},
+		{"/gems/net-http-0.4.1.gem", "net-http", "0.4.1", true},
+		{"/gems/nokogiri-1.15.4-x86_64-linux.gem", "nokogiri", "1.15.4", true},
+		{"/gems/rails-7.1.0.rc1.gem", "rails", "7.1.0.rc1", true},
+		{"/quick/Marshal.4.8/rails-7.1.0.gemspec.rz", "rails", "7.1.0", true},
+		{"/api/v1/gems/rails.json", "rails", "", true},
+		{"/versions", "", "", false},
+		{"/api/v1/gems", "", "", false},
+		{"/gems/rails.gem", "", "", false},
+		{"/info/", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			name, version, ok := parseGemPath(tt.path)
+			if name != tt.wantName || version != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("parseGemPath(%q) = %q, %q, %v, want %q, %q, %v",
+					tt.path, name, version, ok, tt.wantName, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package rubygems
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/metadata"
+)
+
+// SetMetadata enables recording downloaded gems in the metadata database
+func (h *Handler) SetMetadata(store *metadata.Store) {
+	h.metadata = store
+}
+
+// recordArtifact records a gem download the backend answered successfully as a pull
+// of its version. Pushes carry the gem's name and version inside the uploaded
+// archive, so they are not recorded.
+func (h *Handler) recordArtifact(r *http.Request, path string, statusCode int) {
+	if h.metadata == nil || statusCode < 200 || statusCode >= 300 || r.Method != http.MethodGet {
+		return
+	}
+	if !strings.HasPrefix(path, "/gems/") {
+		return
+	}
+	if name, version, ok := parseGemPath(path); ok {
+		h.metadata.RecordPull(h.Name(), name, version, "")
+	}
+}
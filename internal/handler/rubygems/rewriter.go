@@ -0,0 +1,76 @@
+package rubygems
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// determineProxyURL determines the proxy URL for RubyGems handler
+// Constructs URL dynamically from request headers + protocol config
+// Returns the full proxy URL including the path prefix (e.g., https://gems.example.com/rubygems)
+func (h *Handler) determineProxyURL(r *http.Request) string {
+	return h.getEffectiveBaseURL(r)
+}
+
+// rewriteBody rewrites backend URLs in compact index and JSON API documents, such
+// as the gem_uri and project_uri fields of /api/v1/gems/<gem>.json, to the proxy
+// URL. Both schemes of the backend URL are rewritten, since backends behind a TLS
+// terminator often advertise https while being reached over http.
+func (h *Handler) rewriteBody(body []byte, backendURL, proxyURL string) []byte {
+	address := stripScheme(backendURL)
+
+	rewritten := bytes.ReplaceAll(body, []byte("http://"+address), []byte(proxyURL))
+	rewritten = bytes.ReplaceAll(rewritten, []byte("https://"+address), []byte(proxyURL))
+
+	if !bytes.Equal(body, rewritten) {
+		h.logger.Debug().
+			Int("original_size", len(body)).
+			Int("rewritten_size", len(rewritten)).
+			Msg("Body rewritten")
+	}
+
+	return rewritten
+}
+
+// rewriteURL rewrites a single URL from backend to proxy
+func (h *Handler) rewriteURL(url, backendURL, proxyURL string) string {
+	address := stripScheme(backendURL)
+
+	for _, scheme := range []string{"http://", "https://"} {
+		if strings.HasPrefix(url, scheme+address) {
+			rewritten := proxyURL + strings.TrimPrefix(url, scheme+address)
+
+			h.logger.Debug().
+				Str("original", url).
+				Str("rewritten", rewritten).
+				Msg("URL rewritten")
+
+			return rewritten
+		}
+	}
+
+	// URL doesn't point to our backend, return unchanged
+	return url
+}
+
+// shouldRewriteBody determines if response body should be rewritten: the compact
+// index is served as text/plain and the API as JSON
+func (h *Handler) shouldRewriteBody(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+
+	return strings.Contains(contentType, "text/plain") ||
+		strings.Contains(contentType, "application/json") ||
+		strings.Contains(contentType, "text/html")
+}
+
+// stripScheme returns a backend URL without its scheme and trailing slash, keeping
+// the path of backends served below one (e.g. a Nexus repository)
+// Examples:
+//   - "https://rubygems.org/" -> "rubygems.org"
+//   - "http://nexus:8081/repository/gems" -> "nexus:8081/repository/gems"
+func stripScheme(url string) string {
+	address := strings.TrimPrefix(url, "http://")
+	address = strings.TrimPrefix(address, "https://")
+	return strings.TrimSuffix(address, "/")
+}
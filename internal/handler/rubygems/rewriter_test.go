@@ -0,0 +1,45 @@
+package rubygems
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRewriteBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		backendURL string
+		body       string
+		want       string
+	}{
+		{
+			name:       "JSON API",
+			backendURL: "https://rubygems.org",
+			body:       `{"name":"rails","gem_uri":"https://rubygems.org/gems/rails-7.1.0.gem","project_uri":"https://rubygems.org/gems/rails"}`,
+			want:       `{"name":"rails","gem_uri":"https://proxy.example.com/rubygems/gems/rails-7.1.0.gem","project_uri":"https://proxy.example.com/rubygems/gems/rails"}`,
+		},
+		{
+			name:       "backend below a path advertising the other scheme",
+			backendURL: "http://nexus:8081/repository/gems/",
+			body:       "gem_uri: https://nexus:8081/repository/gems/gems/rails-7.1.0.gem",
+			want:       "gem_uri: https://proxy.example.com/rubygems/gems/rails-7.1.0.gem",
+		},
+		{
+			name:       "compact index without URLs",
+			backendURL: "https://rubygems.org",
+			body:       "---\n7.1.0 actionpack:= 7.1.0|checksum:abc\n",
+			want:       "---\n7.1.0 actionpack:= 7.1.0|checksum:abc\n",
+		},
+	}
+
+	h := &Handler{logger: zerolog.Nop()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := h.rewriteBody([]byte(tt.body), tt.backendURL, "https://proxy.example.com/rubygems")
+			if string(got) != tt.want {
+				t.Errorf("rewriteBody() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package terraform
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/auth"
+)
+
+// authenticateClient validates the client's GitHub PAT using shared authenticator.
+// Terraform sends the token configured for the registry host in its CLI
+// configuration (credentials "<host>" { token = "<token>" }) as a Bearer token.
+func (h *Handler) authenticateClient(r *http.Request) (*auth.AuthResult, *http.Request, error) {
+	authResult, newReq, err := h.authenticator.AuthenticateAndInjectContext(r)
+	if err != nil {
+		return nil, r, err
+	}
+
+	return authResult, newReq, nil
+}
+
+// handleAuthError returns a Terraform registry error response, whose errors Terraform
+// reports to the user
+func (h *Handler) handleAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	h.logger.Warn().Err(err).
+		Str("path", r.URL.Path).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("Authentication failed")
+
+	status, message := http.StatusUnauthorized, h.config.ClientAuth.UnauthorizedMessage("authentication required: configure a GitHub PAT as the token for this registry host")
+	if errors.Is(err, auth.ErrInsufficientPermissions) {
+		// The credentials are valid, so challenging for others wouldn't help
+		status, message = http.StatusForbidden, auth.InsufficientPermissionsMessage
+	} else {
+		// Set WWW-Authenticate challenge headers
+		auth.SetChallenges(w.Header(), &h.config.ClientAuth, auth.ChallengeOptions{
+			Schemes: []string{auth.SchemeBearer},
+			Realm:   "Artifusion Terraform Registry",
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	errResponse := registryError{
+		Errors: []string{message},
+	}
+	if encodeErr := json.NewEncoder(w).Encode(errResponse); encodeErr != nil {
+		h.logger.Error().Err(encodeErr).Msg("Failed to encode auth error response")
+	}
+}
+
+// registryError is the error response of the Terraform registry protocols
+type registryError struct {
+	Errors []string `json:"errors"`
+}
@@ -0,0 +1,57 @@
+package terraform
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// addLogFields adds what the request targets to its completion log line:
+// terraform_module or terraform_provider, plus terraform_version for downloads
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	kind, name, version, ok := parseRegistryPath(h.backendPath(r))
+	if !ok {
+		return
+	}
+	middleware.AddLogField(ctx, "terraform_"+kind, name)
+	if version != "" {
+		middleware.AddLogField(ctx, "terraform_version", version)
+	}
+}
+
+// parseRegistryPath extracts the kind ("module" or "provider"), the address and,
+// for download endpoints, the version from a registry API path below the path prefix
+//
+//	/modules/v1/acme/vpc/aws/versions                       -> module, acme/vpc/aws
+//	/modules/v1/acme/vpc/aws/1.2.0/download                 -> module, acme/vpc/aws, 1.2.0
+//	/providers/v1/acme/cloud/versions                       -> provider, acme/cloud
+//	/providers/v1/acme/cloud/2.0.1/download/linux/amd64     -> provider, acme/cloud, 2.0.1
+func parseRegistryPath(path string) (kind, name, version string, ok bool) {
+	if rest, found := strings.CutPrefix(path, modulesAPIPath); found {
+		parts := strings.Split(rest, "/")
+		if len(parts) < 4 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return "", "", "", false
+		}
+		if len(parts) == 5 && parts[4] == "download" {
+			version = parts[3]
+		}
+		return "module", strings.Join(parts[:3], "/"), version, true
+	}
+
+	if rest, found := strings.CutPrefix(path, providersAPIPath); found {
+		parts := strings.Split(rest, "/")
+		if len(parts) < 3 || parts[0] == "" || parts[1] == "" {
+			return "", "", "", false
+		}
+		if len(parts) == 6 && parts[3] == "download" {
+			version = parts[2]
+		}
+		return "provider", parts[0] + "/" + parts[1], version, true
+	}
+
+	return "", "", "", false
+}
@@ -0,0 +1,32 @@
+package terraform
+
+import "testing"
+
+func TestParseRegistryPath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantKind    string
+		wantName    string
+		wantVersion string
+		wantOK      bool
+	}{
+		{"/modules/v1/acme/vpc/aws/versions", "module", "acme/vpc/aws", "", true},
+		{"/modules/v1/acme/vpc/aws/1.2.0/download", "module", "acme/vpc/aws", "1.2.0", true},
+		{"/modules/v1/acme/vpc/aws/download", "module", "acme/vpc/aws", "", true},
+		{"/providers/v1/acme/cloud/versions", "provider", "acme/cloud", "", true},
+		{"/providers/v1/acme/cloud/2.0.1/download/linux/amd64", "provider", "acme/cloud", "2.0.1", true},
+		{"/modules/v1/acme/vpc", "", "", "", false},
+		{"/providers/v1//cloud/versions", "", "", "", false},
+		{"/archives/vpc-1.2.0.tar.gz", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			kind, name, version, ok := parseRegistryPath(tt.path)
+			if kind != tt.wantKind || name != tt.wantName || version != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("parseRegistryPath(%q) = %q, %q, %q, %v, want %q, %q, %q, %v",
+					tt.path, kind, name, version, ok, tt.wantKind, tt.wantName, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
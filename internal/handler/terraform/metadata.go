@@ -0,0 +1,25 @@
+package terraform
+
+import (
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/metadata"
+)
+
+// SetMetadata enables recording downloaded modules and providers in the metadata
+// database
+func (h *Handler) SetMetadata(store *metadata.Store) {
+	h.metadata = store
+}
+
+// recordArtifact records a module or provider version download the backend answered
+// successfully as a pull. Modules are recorded as <namespace>/<name>/<system> and
+// providers as <namespace>/<type>.
+func (h *Handler) recordArtifact(r *http.Request, path string, statusCode int) {
+	if h.metadata == nil || statusCode < 200 || statusCode >= 300 || r.Method != http.MethodGet {
+		return
+	}
+	if _, name, version, ok := parseRegistryPath(path); ok && version != "" {
+		h.metadata.RecordPull(h.Name(), name, version, "")
+	}
+}
@@ -0,0 +1,211 @@
+package terraform
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/proxy/rewriter"
+)
+
+// TerraformGetHeader carries a module version's download URL in the module registry
+// protocol
+const TerraformGetHeader = "X-Terraform-Get"
+
+// providerDownloadURLFields are the URL fields of a provider package's download
+// response
+var providerDownloadURLFields = []string{"download_url", "shasums_url", "shasums_signature_url"}
+
+// proxyRegistry proxies the request to the backend and points the URLs in its
+// response that the backend serves at the proxy: redirects, module download URLs
+// and provider download URLs. Download URLs on other hosts, such as release archives
+// of public providers, are left for Terraform to fetch directly.
+func (h *Handler) proxyRegistry(w http.ResponseWriter, r *http.Request, authResult *auth.AuthResult, path string) error {
+	backend := &h.config.Backend
+	backendPath := h.registryAPIPath(path)
+
+	resp, err := h.executeProxyRequest(r, backend, backendPath)
+	if err != nil {
+		return err
+	}
+	h.recordArtifact(r, path, resp.StatusCode)
+
+	// Relative URLs are relative to the URL the backend was requested at
+	requested, _ := url.Parse(strings.TrimSuffix(backend.URL, "/") + backendPath)
+	if resp.HTTPResp != nil && resp.HTTPResp.Request != nil {
+		requested = resp.HTTPResp.Request.URL
+	}
+	proxyURL := h.getEffectiveBaseURL(r)
+
+	if location := resp.Headers.Get("Location"); location != "" {
+		mapped, _ := h.publicURL(location, requested, proxyURL)
+		resp.Headers.Set("Location", mapped)
+	}
+	if location := resp.Headers.Get(TerraformGetHeader); location != "" {
+		resp.Headers.Set(TerraformGetHeader, h.downloadURL(location, requested, proxyURL, authResult))
+	}
+
+	// Only provider download responses hold URLs in their body
+	if resp.StatusCode != http.StatusOK || !isProviderDownloadPath(path) {
+		_, err = h.proxyClient.StreamResponse(w, resp, true)
+		return err
+	}
+
+	body, err := h.proxyClient.ReadResponseBody(resp)
+	if err != nil {
+		w.WriteHeader(resp.StatusCode)
+		return err
+	}
+
+	var download map[string]json.RawMessage
+	if err := json.Unmarshal(body, &download); err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to parse provider download response, serving it unmodified")
+		return h.proxyClient.WriteResponse(w, resp, body, true)
+	}
+	for _, field := range providerDownloadURLFields {
+		var location string
+		if json.Unmarshal(download[field], &location) != nil || location == "" {
+			continue
+		}
+		download[field], _ = json.Marshal(h.downloadURL(location, requested, proxyURL, authResult))
+	}
+	rewritten, err := json.Marshal(download)
+	if err != nil {
+		return h.proxyClient.WriteResponse(w, resp, body, true)
+	}
+
+	// The backend's validators describe the original document
+	resp.Headers.Del("ETag")
+	resp.Headers.Del("Digest")
+	resp.Headers.Del("Repr-Digest")
+	resp.Headers.Del("Accept-Ranges")
+	return h.proxyClient.WriteResponse(w, resp, rewritten, true)
+}
+
+// publicURL resolves a URL in a backend response against the URL the backend was
+// requested at and maps it to the proxy if the backend serves it, with the registry
+// APIs moved back below their advertised paths. It reports false for URLs the
+// proxy doesn't serve, which are returned resolved.
+func (h *Handler) publicURL(location string, requested *url.URL, proxyURL string) (string, bool) {
+	ref, err := url.Parse(location)
+	if err != nil {
+		return location, false
+	}
+	target := ref
+	if requested != nil {
+		target = requested.ResolveReference(ref)
+	}
+
+	mapped, ok := rewriter.MapLocation(target.String(), h.config.Backend.URL, proxyURL)
+	if !ok {
+		return target.String(), false
+	}
+
+	rest := strings.TrimPrefix(mapped, proxyURL)
+	if apiRest, found := strings.CutPrefix(rest, h.config.Backend.ModulesPath); found {
+		return proxyURL + modulesAPIPath + apiRest, true
+	}
+	if apiRest, found := strings.CutPrefix(rest, h.config.Backend.ProvidersPath); found {
+		return proxyURL + providersAPIPath + apiRest, true
+	}
+	return mapped, true
+}
+
+// downloadURL maps a module or provider download URL to the proxy and, with URL
+// signing enabled, signs it for the requesting user, as Terraform doesn't send
+// credentials when downloading archives. URLs the proxy doesn't serve are returned
+// resolved but unsigned.
+func (h *Handler) downloadURL(location string, requested *url.URL, proxyURL string, authResult *auth.AuthResult) string {
+	mapped, ok := h.publicURL(location, requested, proxyURL)
+	if !ok || h.signer == nil || !h.signer.Enabled() || authResult == nil {
+		return mapped
+	}
+
+	u, err := url.Parse(mapped)
+	if err != nil {
+		return mapped
+	}
+
+	// Module sources may select a subdirectory of the archive after "//", which
+	// Terraform strips before downloading
+	path, _, _ := strings.Cut(u.Path, "//")
+	params, _, err := h.signer.Sign(u.Host, path, authResult.Username, 0)
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to sign download URL")
+		return mapped
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// isProviderDownloadPath reports whether path, below the path prefix, is the
+// download endpoint of a provider package:
+// /providers/v1/<namespace>/<type>/<version>/download/<os>/<arch>
+func isProviderDownloadPath(path string) bool {
+	kind, _, version, ok := parseRegistryPath(path)
+	return ok && kind == "provider" && version != ""
+}
+
+// executeProxyRequest sends the request to backend and records backend metrics,
+// returning the response without writing it
+func (h *Handler) executeProxyRequest(r *http.Request, backend *config.TerraformBackendConfig, path string) (*proxy.Response, error) {
+	// Create proxy request
+	proxyReq := &proxy.Request{
+		Method:      r.Method,
+		Path:        path,
+		Query:       r.URL.RawQuery,
+		Body:        r.Body,
+		Headers:     r.Header,
+		Backend:     backend,
+		OriginalReq: r,
+	}
+
+	// Track backend request timing
+	start := time.Now()
+
+	// Execute proxy request
+	resp, err := h.proxyClient.ProxyRequest(proxyReq)
+
+	// Record metrics regardless of success/failure
+	duration := time.Since(start)
+
+	if err != nil {
+		// Record backend error metrics
+		h.metrics.RecordBackendError(h.Name(), backend.Name, "network_error")
+		h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+		h.metrics.SetBackendHealth(backend.Name, false)
+
+		h.logger.Error().Err(err).
+			Str("backend", backend.Name).
+			Dur("duration", duration).
+			Msg("Backend request failed")
+
+		return nil, err
+	}
+
+	// Record backend latency for all requests
+	h.metrics.RecordBackendLatency(backend.Name, r.Method, duration)
+
+	// Record backend health based on status code
+	if resp.StatusCode >= 500 {
+		// Server error - backend is unhealthy
+		h.metrics.RecordBackendErrorByStatus(backend.Name, resp.StatusCode)
+		h.metrics.SetBackendHealth(backend.Name, false)
+	} else if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+		// Success - backend is healthy
+		h.metrics.SetBackendHealth(backend.Name, true)
+	}
+	// 4xx errors don't affect backend health (client errors)
+
+	return resp, nil
+}
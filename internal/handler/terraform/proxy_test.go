@@ -0,0 +1,153 @@
+package terraform
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+func TestServeDiscovery(t *testing.T) {
+	h := NewHandler(&config.TerraformConfig{PathPrefix: "/terraform"}, nil, nil, nil, zerolog.Nop())
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/terraform.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var services map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &services); err != nil {
+		t.Fatalf("invalid discovery document: %v", err)
+	}
+	if services["modules.v1"] != "/terraform/modules/v1/" || services["providers.v1"] != "/terraform/providers/v1/" {
+		t.Errorf("services = %v", services)
+	}
+}
+
+// TestProxyRegistry tests that the registry APIs are mapped to the backend's paths
+// and that the URLs in their responses point at the proxy
+func TestProxyRegistry(t *testing.T) {
+	var backendURL string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/modules/acme/vpc/aws/download":
+			http.Redirect(w, r, "/api/modules/acme/vpc/aws/1.2.0/download", http.StatusFound)
+		case "/api/modules/acme/vpc/aws/1.2.0/download":
+			w.Header().Set(TerraformGetHeader, "../../../../../../archives/vpc-1.2.0.tar.gz//modules/vpc?archive=tar.gz")
+			w.WriteHeader(http.StatusNoContent)
+		case "/api/providers/acme/cloud/2.0.1/download/linux/amd64":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{
+				"os": "linux", "arch": "amd64", "filename": "terraform-provider-cloud_2.0.1_linux_amd64.zip",
+				"download_url": "` + backendURL + `/archives/terraform-provider-cloud_2.0.1_linux_amd64.zip",
+				"shasums_url": "/archives/terraform-provider-cloud_2.0.1_SHA256SUMS",
+				"shasums_signature_url": "https://github.com/acme/terraform-provider-cloud/releases/download/v2.0.1/SHA256SUMS.sig"
+			}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer backend.Close()
+	backendURL = backend.URL
+
+	cfg := &config.TerraformConfig{
+		PathPrefix: "/terraform",
+		Backend: config.TerraformBackendConfig{
+			Name:                "registry",
+			URL:                 backend.URL,
+			ModulesPath:         "/api/modules/",
+			ProvidersPath:       "/api/providers/",
+			MaxIdleConns:        1,
+			MaxIdleConnsPerHost: 1,
+			DialTimeout:         time.Second,
+			RequestTimeout:      10 * time.Second,
+		},
+	}
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("terraform_proxy_test"), logger)
+	h.SetURLSigner(auth.NewURLSigner(&config.SignedURLsConfig{
+		Secret:     strings.Repeat("s", config.MinSignedURLSecretLength),
+		DefaultTTL: time.Hour,
+		MaxTTL:     time.Hour,
+	}))
+	alice := &auth.AuthResult{Username: "alice"}
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://artifacts.example.com/terraform"+path, nil)
+		if err := h.selectBackendAndProxy(w, r, alice); err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		return w
+	}
+
+	// signedPath checks that a download URL is signed for alice and returns its path
+	signedPath := func(t *testing.T, location string) string {
+		t.Helper()
+		u, err := url.Parse(location)
+		if err != nil {
+			t.Fatalf("invalid URL %q: %v", location, err)
+		}
+		query := u.Query()
+		if query.Get(auth.SignedURLUserParam) != "alice" || query.Get(auth.SignedURLSignatureParam) == "" {
+			t.Errorf("URL %q is not signed for alice", location)
+		}
+		return u.Scheme + "://" + u.Host + u.Path
+	}
+
+	t.Run("latest module redirect", func(t *testing.T) {
+		w := serve("/modules/v1/acme/vpc/aws/download")
+		want := "https://artifacts.example.com/terraform/modules/v1/acme/vpc/aws/1.2.0/download"
+		if w.Code != http.StatusFound || w.Header().Get("Location") != want {
+			t.Errorf("got %d Location %q, want %d %q", w.Code, w.Header().Get("Location"), http.StatusFound, want)
+		}
+	})
+
+	t.Run("module download", func(t *testing.T) {
+		w := serve("/modules/v1/acme/vpc/aws/1.2.0/download")
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusNoContent)
+		}
+		location := w.Header().Get(TerraformGetHeader)
+		if got, want := signedPath(t, location), "https://artifacts.example.com/terraform/archives/vpc-1.2.0.tar.gz//modules/vpc"; got != want {
+			t.Errorf("%s = %q, want %q", TerraformGetHeader, got, want)
+		}
+		if !strings.Contains(location, "archive=tar.gz") {
+			t.Errorf("%s = %q lost its query", TerraformGetHeader, location)
+		}
+	})
+
+	t.Run("provider download", func(t *testing.T) {
+		w := serve("/providers/v1/acme/cloud/2.0.1/download/linux/amd64")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		var download map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &download); err != nil {
+			t.Fatalf("invalid download response: %v", err)
+		}
+		if got, want := signedPath(t, download["download_url"]), "https://artifacts.example.com/terraform/archives/terraform-provider-cloud_2.0.1_linux_amd64.zip"; got != want {
+			t.Errorf("download_url = %q, want %q", got, want)
+		}
+		if got, want := signedPath(t, download["shasums_url"]), "https://artifacts.example.com/terraform/archives/terraform-provider-cloud_2.0.1_SHA256SUMS"; got != want {
+			t.Errorf("shasums_url = %q, want %q", got, want)
+		}
+		if got, want := download["shasums_signature_url"], "https://github.com/acme/terraform-provider-cloud/releases/download/v2.0.1/SHA256SUMS.sig"; got != want {
+			t.Errorf("shasums_signature_url = %q, want it unchanged %q", got, want)
+		}
+		if download["filename"] != "terraform-provider-cloud_2.0.1_linux_amd64.zip" {
+			t.Errorf("download response lost fields: %s", w.Body.String())
+		}
+	})
+}
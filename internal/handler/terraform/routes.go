@@ -0,0 +1,90 @@
+package terraform
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// Paths below the path prefix at which the registry APIs are advertised by service
+// discovery. They are mapped to the backend's modules_path and providers_path.
+const (
+	modulesAPIPath   = "/modules/v1/"
+	providersAPIPath = "/providers/v1/"
+)
+
+// serveDiscovery answers Terraform's service discovery with the registry APIs below
+// the path prefix. The paths are resolved against the discovery URL, so the same
+// document serves every host the proxy is reached at.
+func (h *Handler) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		errors.ErrorResponse(w, errors.ErrBadRequest.WithMessage("Service discovery only supports GET and HEAD"))
+		return
+	}
+
+	services := map[string]string{
+		"modules.v1":   h.config.PathPrefix + modulesAPIPath,
+		"providers.v1": h.config.PathPrefix + providersAPIPath,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if err := json.NewEncoder(w).Encode(services); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode service discovery document")
+	}
+}
+
+// selectBackendAndProxy proxies the request to the backend registry
+func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request, authResult *auth.AuthResult) error {
+	backend := &h.config.Backend
+
+	// Log operation type for debugging
+	operationType := "read"
+	if auth.IsWriteMethod(r.Method) {
+		operationType = "write"
+	}
+
+	h.logger.Debug().
+		Str("backend", backend.Name).
+		Str("url", backend.URL).
+		Str("operation", operationType).
+		Str("username", authResult.Username).
+		Msg("Routing to Terraform backend")
+	middleware.AddLogField(r.Context(), "backend", backend.Name)
+
+	// Note: Backend authentication is handled by proxy client
+	return h.proxyRegistry(w, r, authResult, h.backendPath(r))
+}
+
+// backendPath returns the request path with the path prefix stripped
+func (h *Handler) backendPath(r *http.Request) string {
+	path := r.URL.Path
+	if h.config.PathPrefix != "" {
+		path = strings.TrimPrefix(path, h.config.PathPrefix)
+		// Ensure path starts with /
+		if path == "" || !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	return path
+}
+
+// registryAPIPath maps a path below the path prefix to the backend: the registry
+// APIs to where the backend serves them, anything else (such as archives the APIs
+// point to) to the same path on the backend
+func (h *Handler) registryAPIPath(path string) string {
+	if rest, ok := strings.CutPrefix(path, modulesAPIPath); ok {
+		return h.config.Backend.ModulesPath + rest
+	}
+	if rest, ok := strings.CutPrefix(path, providersAPIPath); ok {
+		return h.config.Backend.ProvidersPath + rest
+	}
+	return path
+}
@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/detector"
+	"github.com/mainuli/artifusion/internal/errors"
+)
+
+// Endpoint is where a protocol is served, as listed on the landing page
+type Endpoint struct {
+	Protocol   string
+	Host       string // Empty for path-based routing on any host
+	PathPrefix string
+}
+
+// URL returns the endpoint's base URL for a client that sent r
+func (e Endpoint) URL(r *http.Request) string {
+	host := e.Host
+	if host == "" {
+		host = detector.GetRequestHost(r)
+	}
+	return detector.GetRequestScheme(r) + "://" + host + e.PathPrefix
+}
+
+// Endpoints returns the endpoints of the enabled protocols
+func Endpoints(cfg *config.ProtocolsConfig) []Endpoint {
+	var endpoints []Endpoint
+	if cfg.OCI.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolOCI), Host: cfg.OCI.Host})
+	}
+	if cfg.Maven.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolMaven), Host: cfg.Maven.Host, PathPrefix: cfg.Maven.PathPrefix})
+	}
+	if cfg.NPM.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolNPM), Host: cfg.NPM.Host, PathPrefix: cfg.NPM.PathPrefix})
+	}
+	if cfg.RubyGems.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolRubyGems), Host: cfg.RubyGems.Host, PathPrefix: cfg.RubyGems.PathPrefix})
+	}
+	if cfg.Helm.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolHelm), Host: cfg.Helm.Host, PathPrefix: cfg.Helm.PathPrefix})
+	}
+	if cfg.APT.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolAPT), Host: cfg.APT.Host, PathPrefix: cfg.APT.PathPrefix})
+	}
+	if cfg.Composer.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolComposer), Host: cfg.Composer.Host, PathPrefix: cfg.Composer.PathPrefix})
+	}
+	if cfg.Conda.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolConda), Host: cfg.Conda.Host, PathPrefix: cfg.Conda.PathPrefix})
+	}
+	if cfg.Terraform.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolTerraform), Host: cfg.Terraform.Host, PathPrefix: cfg.Terraform.PathPrefix})
+	}
+	if cfg.APK.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolAPK), Host: cfg.APK.Host, PathPrefix: cfg.APK.PathPrefix})
+	}
+	if cfg.Raw.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolRaw), Host: cfg.Raw.Host, PathPrefix: cfg.Raw.PathPrefix})
+	}
+	if cfg.LFS.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolLFS), Host: cfg.LFS.Host, PathPrefix: cfg.LFS.PathPrefix})
+	}
+	if cfg.CocoaPods.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolCocoaPods), Host: cfg.CocoaPods.Host, PathPrefix: cfg.CocoaPods.PathPrefix})
+	}
+	if cfg.Hex.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolHex), Host: cfg.Hex.Host, PathPrefix: cfg.Hex.PathPrefix})
+	}
+	if cfg.Pub.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolPub), Host: cfg.Pub.Host, PathPrefix: cfg.Pub.PathPrefix})
+	}
+	if cfg.Vagrant.Enabled {
+		endpoints = append(endpoints, Endpoint{Protocol: string(detector.ProtocolVagrant), Host: cfg.Vagrant.Host, PathPrefix: cfg.Vagrant.PathPrefix})
+	}
+	return endpoints
+}
+
+// Unsupported answers requests no protocol handler claimed, in the error format
+// the client understands: OCI errors for container clients, registry JSON for
+// npm, a landing page listing the configured endpoints for browsers, and the
+// standard JSON error otherwise.
+type Unsupported struct {
+	endpoints []Endpoint
+}
+
+// NewUnsupported creates the handler for requests of unsupported protocols
+func NewUnsupported(endpoints []Endpoint) *Unsupported {
+	return &Unsupported{endpoints: endpoints}
+}
+
+// Clients Unsupported formats errors for
+const (
+	clientOCI     = "oci"
+	clientNPM     = "npm"
+	clientBrowser = "browser"
+	clientOther   = ""
+)
+
+// classifyClient guesses the kind of client that sent r from its path, Accept
+// header and User-Agent
+func classifyClient(r *http.Request) string {
+	accept := r.Header.Get("Accept")
+	userAgent := strings.ToLower(r.UserAgent())
+
+	switch {
+	case r.URL.Path == "/v2" || strings.HasPrefix(r.URL.Path, "/v2/"),
+		strings.Contains(accept, "application/vnd.oci."),
+		strings.Contains(accept, "application/vnd.docker."):
+		return clientOCI
+	case strings.Contains(accept, "application/vnd.npm."),
+		strings.HasPrefix(userAgent, "npm/"),
+		strings.HasPrefix(userAgent, "yarn/"),
+		strings.HasPrefix(userAgent, "pnpm/"):
+		return clientNPM
+	}
+
+	for _, prefix := range []string{"docker/", "containerd/", "buildkit/", "podman/", "skopeo/", "oras/", "helm/", "go-containerregistry/"} {
+		if strings.HasPrefix(userAgent, prefix) {
+			return clientOCI
+		}
+	}
+
+	if strings.Contains(accept, "text/html") {
+		return clientBrowser
+	}
+	return clientOther
+}
+
+// ServeHTTP writes the error response for the client's protocol
+func (u *Unsupported) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	appErr := errors.ErrProtocolNotSupported
+
+	switch classifyClient(r) {
+	case clientOCI:
+		// https://github.com/opencontainers/distribution-spec/blob/main/spec.md#error-codes
+		w.Header().Set("Docker-Distribution-Api-Version", "registry/2.0")
+		u.writeJSON(w, appErr, map[string]any{
+			"errors": []map[string]string{{
+				"code":    "UNSUPPORTED",
+				"message": "no container registry is served at this address",
+			}},
+		})
+
+	case clientNPM:
+		// CouchDB-style error, as returned by the npm registry
+		u.writeJSON(w, appErr, map[string]string{
+			"error":  "not_found",
+			"reason": "no npm registry is served at this address",
+		})
+
+	case clientBrowser:
+		u.writeLandingPage(w, r)
+
+	default:
+		errors.ErrorResponse(w, appErr)
+	}
+}
+
+func (u *Unsupported) writeJSON(w http.ResponseWriter, appErr *errors.AppError, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Error-Code", appErr.Code)
+	w.WriteHeader(appErr.StatusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// landingPage lists the configured endpoints
+var landingPage = template.Must(template.New("landing").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Artifusion</title>
+<style>body{font-family:sans-serif;max-width:40em;margin:3em auto;color:#222}code{background:#f3f3f3;padding:.1em .3em}</style>
+</head>
+<body>
+<h1>Artifusion</h1>
+{{if .NotFound}}<p>Nothing is served at <code>{{.Path}}</code>.</p>{{end}}
+{{if .Endpoints}}<p>This artifact registry proxy serves:</p>
+<ul>
+{{range .Endpoints}}<li>{{.Protocol}}: <code>{{.URL}}</code></li>
+{{end}}</ul>
+{{else}}<p>No protocols are enabled.</p>{{end}}
+<p>Authenticate with a GitHub token as the password.</p>
+</body>
+</html>
+`))
+
+// writeLandingPage renders the landing page: with 200 for the root, 404 elsewhere
+func (u *Unsupported) writeLandingPage(w http.ResponseWriter, r *http.Request) {
+	type endpointView struct {
+		Protocol string
+		URL      string
+	}
+	data := struct {
+		NotFound  bool
+		Path      string
+		Endpoints []endpointView
+	}{
+		NotFound: r.URL.Path != "/",
+		Path:     r.URL.Path,
+	}
+	for _, e := range u.endpoints {
+		data.Endpoints = append(data.Endpoints, endpointView{Protocol: e.Protocol, URL: e.URL(r)})
+	}
+
+	status := http.StatusOK
+	if data.NotFound {
+		status = errors.ErrProtocolNotSupported.StatusCode
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_ = landingPage.Execute(w, data)
+}
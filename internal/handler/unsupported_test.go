@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+func TestUnsupported_ContentNegotiation(t *testing.T) {
+	u := NewUnsupported([]Endpoint{
+		{Protocol: "oci", Host: "docker.example.com"},
+		{Protocol: "npm", PathPrefix: "/npm"},
+	})
+
+	tests := []struct {
+		name            string
+		path            string
+		headers         map[string]string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "docker client",
+			path:            "/v2/",
+			headers:         map[string]string{"User-Agent": "docker/27.0.3 go/go1.22"},
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "application/json",
+			wantBody:        `"code":"UNSUPPORTED"`,
+		},
+		{
+			name:            "oci accept header",
+			path:            "/team/app",
+			headers:         map[string]string{"Accept": "application/vnd.oci.image.index.v1+json"},
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "application/json",
+			wantBody:        `"errors"`,
+		},
+		{
+			name:            "npm client",
+			path:            "/lodash",
+			headers:         map[string]string{"User-Agent": "npm/10.2.4 node/v20.11.0 linux x64"},
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "application/json",
+			wantBody:        `"reason":"no npm registry`,
+		},
+		{
+			name:            "browser at root",
+			path:            "/",
+			headers:         map[string]string{"Accept": "text/html,application/xhtml+xml"},
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/html; charset=utf-8",
+			wantBody:        "https://docker.example.com",
+		},
+		{
+			name:            "browser elsewhere",
+			path:            "/unknown",
+			headers:         map[string]string{"Accept": "text/html"},
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "text/html; charset=utf-8",
+			wantBody:        "https://proxy.example.com/npm",
+		},
+		{
+			name:            "other client",
+			path:            "/unknown",
+			headers:         map[string]string{"User-Agent": "curl/8.5.0"},
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "application/json",
+			wantBody:        `"error":"PROTOCOL_NOT_SUPPORTED"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://proxy.example.com"+tt.path, nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+
+			u.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body missing %q:\n%s", tt.wantBody, rec.Body.String())
+			}
+			if tt.wantContentType == "application/json" && !json.Valid(rec.Body.Bytes()) {
+				t.Errorf("invalid JSON body: %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	cfg := &config.ProtocolsConfig{
+		OCI:   config.OCIConfig{Enabled: true},
+		Maven: config.MavenConfig{Enabled: false, PathPrefix: "/maven"},
+		NPM:   config.NPMConfig{Enabled: true, Host: "npm.example.com"},
+	}
+
+	endpoints := Endpoints(cfg)
+	if len(endpoints) != 2 {
+		t.Fatalf("Endpoints() = %v, want oci and npm", endpoints)
+	}
+	if endpoints[0].Protocol != "oci" || endpoints[1].Protocol != "npm" || endpoints[1].Host != "npm.example.com" {
+		t.Errorf("Endpoints() = %+v", endpoints)
+	}
+}
@@ -0,0 +1,51 @@
+package vagrant
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/auth"
+)
+
+// authenticateClient validates the client's GitHub PAT using shared authenticator.
+// Vagrant sends credentials embedded in the box URL (https://<user>:<token>@host/...)
+// as Basic credentials, and VAGRANT_CLOUD_TOKEN as a bearer token to the host of
+// VAGRANT_SERVER_URL.
+func (h *Handler) authenticateClient(r *http.Request) (*auth.AuthResult, *http.Request, error) {
+	authResult, newReq, err := h.authenticator.AuthenticateAndInjectContext(r)
+	if err != nil {
+		return nil, r, err
+	}
+
+	return authResult, newReq, nil
+}
+
+// handleAuthError returns a Basic challenge with an error body in the format of the
+// Vagrant Cloud API, whose errors vagrant prints
+func (h *Handler) handleAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	h.logger.Warn().Err(err).
+		Str("path", r.URL.Path).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("Authentication failed")
+
+	status, message := http.StatusUnauthorized, h.config.ClientAuth.UnauthorizedMessage("Authentication required: set VAGRANT_CLOUD_TOKEN or add credentials to the box URL")
+	if errors.Is(err, auth.ErrInsufficientPermissions) {
+		// The credentials are valid, so challenging for others wouldn't help
+		status, message = http.StatusForbidden, auth.InsufficientPermissionsMessage
+	} else {
+		// Set WWW-Authenticate challenge headers
+		auth.SetChallenges(w.Header(), &h.config.ClientAuth, auth.ChallengeOptions{
+			Schemes: []string{auth.SchemeBasic},
+			Realm:   "Artifusion Vagrant Boxes",
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if encodeErr := json.NewEncoder(w).Encode(map[string]any{
+		"errors":  []string{message},
+		"success": false,
+	}); encodeErr != nil {
+		h.logger.Error().Err(encodeErr).Msg("Failed to write authentication error response")
+	}
+}
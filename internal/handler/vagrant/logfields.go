@@ -0,0 +1,24 @@
+package vagrant
+
+import (
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// addLogFields adds the box the request targets to its completion log line:
+// vagrant_box for box metadata and API requests, and vagrant_version when the path
+// names a version
+func (h *Handler) addLogFields(r *http.Request) {
+	ctx := r.Context()
+	middleware.AddLogField(ctx, "protocol", h.Name())
+
+	box, version, ok := parseBoxPath(h.backendPath(r))
+	if !ok {
+		return
+	}
+	middleware.AddLogField(ctx, "vagrant_box", box)
+	if version != "" {
+		middleware.AddLogField(ctx, "vagrant_version", version)
+	}
+}
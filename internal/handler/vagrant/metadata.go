@@ -0,0 +1,24 @@
+package vagrant
+
+import (
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/metadata"
+)
+
+// SetMetadata enables recording box downloads in the metadata database
+func (h *Handler) SetMetadata(store *metadata.Store) {
+	h.metadata = store
+}
+
+// recordPulled records a Vagrant Cloud API download the catalog backend served (or
+// redirected to the box file) as a pull of the box version. Box files downloaded
+// from the URLs of box catalogs name no box and aren't recorded.
+func (h *Handler) recordPulled(r *http.Request, path string, statusCode int) {
+	if h.metadata == nil || r.Method != http.MethodGet || statusCode < 200 || statusCode >= 400 || !isDownloadPath(path) {
+		return
+	}
+	if box, version, ok := parseBoxPath(path); ok && version != "" {
+		h.metadata.RecordPull(h.Name(), box, version, "")
+	}
+}
@@ -0,0 +1,151 @@
+package vagrant
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// TestSelectBackendAndProxy tests that provider URLs in box metadata pointing at the
+// catalog or a download backend are rewritten to the proxy, and that box downloads
+// are served by the download backend with its own credentials
+func TestSelectBackendAndProxy(t *testing.T) {
+	var files *httptest.Server
+	var downloadUser string
+	files = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloadUser, _, _ = r.BasicAuth()
+		if r.URL.Path == "/boxes/base/1.0.0/virtualbox.box" {
+			_, _ = w.Write([]byte("box"))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer files.Close()
+
+	var catalog *httptest.Server
+	var catalogUser string
+	catalog = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		catalogUser, _, _ = r.BasicAuth()
+		switch r.URL.Path {
+		case "/acme/base":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"catalog"`)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"name": "acme/base",
+				"versions": []any{map[string]any{
+					"version": "1.0.0",
+					"providers": []any{
+						map[string]any{"name": "virtualbox", "url": files.URL + "/boxes/base/1.0.0/virtualbox.box"},
+						map[string]any{"name": "libvirt", "url": catalog.URL + "/files/base/1.0.0/libvirt.box"},
+						map[string]any{"name": "vmware_desktop", "url": "https://cdn.example.com/base.box"},
+					},
+				}},
+			})
+		case "/api/v2/box/acme/base/version/1.0.0/provider/virtualbox/amd64/download":
+			http.Redirect(w, r, files.URL+"/boxes/base/1.0.0/virtualbox.box", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer catalog.Close()
+
+	backend := func(name, url, username string) config.VagrantBackendConfig {
+		return config.VagrantBackendConfig{
+			Name:                name,
+			URL:                 url,
+			Auth:                &config.AuthConfig{Type: "basic", Username: username, Password: "secret"},
+			MaxIdleConns:        1,
+			MaxIdleConnsPerHost: 1,
+			DialTimeout:         time.Second,
+			RequestTimeout:      10 * time.Second,
+		}
+	}
+	cfg := &config.VagrantConfig{
+		PathPrefix:       "/vagrant",
+		Backend:          backend("catalog", catalog.URL, "catalog-reader"),
+		DownloadBackends: []config.VagrantBackendConfig{backend("files", files.URL+"/boxes", "files-reader")},
+	}
+
+	logger := zerolog.Nop()
+	h := NewHandler(cfg, nil, proxy.NewClient(logger, nil, nil), metrics.NewMetrics("vagrant_proxy_test"), logger)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Host = "artifusion.example.com"
+		if err := h.selectBackendAndProxy(w, r, &auth.AuthResult{Username: "alice"}); err != nil {
+			t.Fatalf("selectBackendAndProxy failed: %v", err)
+		}
+		return w
+	}
+
+	const proxyURL = "https://artifusion.example.com/vagrant"
+
+	t.Run("catalog", func(t *testing.T) {
+		w := serve("/vagrant/acme/base")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		if catalogUser != "catalog-reader" {
+			t.Errorf("catalog credentials = %q, want catalog-reader", catalogUser)
+		}
+
+		var box struct {
+			Versions []struct {
+				Providers []struct {
+					URL string `json:"url"`
+				} `json:"providers"`
+			} `json:"versions"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &box); err != nil {
+			t.Fatalf("invalid box metadata: %v", err)
+		}
+		want := []string{
+			proxyURL + "/downloads/files/base/1.0.0/virtualbox.box",
+			proxyURL + "/files/base/1.0.0/libvirt.box",
+			"https://cdn.example.com/base.box",
+		}
+		if len(box.Versions) != 1 || len(box.Versions[0].Providers) != len(want) {
+			t.Fatalf("unexpected box metadata: %s", w.Body.String())
+		}
+		for i, provider := range box.Versions[0].Providers {
+			if provider.URL != want[i] {
+				t.Errorf("providers[%d].url = %q, want %q", i, provider.URL, want[i])
+			}
+		}
+		if w.Header().Get("ETag") != "" {
+			t.Error("expected the backend's ETag to be dropped from the rewritten metadata")
+		}
+	})
+
+	t.Run("api download redirect", func(t *testing.T) {
+		w := serve("/vagrant/api/v2/box/acme/base/version/1.0.0/provider/virtualbox/amd64/download")
+		if want := proxyURL + "/downloads/files/base/1.0.0/virtualbox.box"; w.Header().Get("Location") != want {
+			t.Errorf("Location = %q, want %q", w.Header().Get("Location"), want)
+		}
+	})
+
+	t.Run("box download", func(t *testing.T) {
+		w := serve("/vagrant/downloads/files/base/1.0.0/virtualbox.box")
+		if w.Code != http.StatusOK || w.Body.String() != "box" {
+			t.Errorf("got %d %q, want %d %q", w.Code, w.Body.String(), http.StatusOK, "box")
+		}
+		if downloadUser != "files-reader" {
+			t.Errorf("download credentials = %q, want files-reader", downloadUser)
+		}
+	})
+
+	t.Run("unknown download backend", func(t *testing.T) {
+		if w := serve("/vagrant/downloads/other/base.box"); w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
@@ -0,0 +1,206 @@
+package vagrant
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const (
+	// MaxJSONRewriteSize is the maximum size of JSON body to rewrite (10MB)
+	// Larger responses will use fallback text rewriting
+	MaxJSONRewriteSize = 10 * 1024 * 1024
+)
+
+// providerURLFields are the fields of a provider holding its box's download URL: url
+// in box catalogs, download_url in Vagrant Cloud API responses
+var providerURLFields = []string{"url", "download_url"}
+
+// rewriteTarget maps the URLs of a backend to the proxy
+type rewriteTarget struct {
+	address  string // Backend URL without scheme and trailing slash
+	proxyURL string // Proxy URL serving the backend
+}
+
+// determineProxyURL determines the proxy URL for Vagrant handler
+// Constructs URL dynamically from request headers + protocol config
+// Returns the full proxy URL including the path prefix (e.g., https://boxes.example.com/vagrant)
+func (h *Handler) determineProxyURL(r *http.Request) string {
+	return h.getEffectiveBaseURL(r)
+}
+
+// rewriteTargets returns the catalog backend and the download backends with the
+// proxy URLs serving them, longest address first, so that a download backend below
+// the catalog backend's URL takes precedence over it
+func (h *Handler) rewriteTargets(proxyURL string) []rewriteTarget {
+	targets := []rewriteTarget{{address: stripScheme(h.config.Backend.URL), proxyURL: proxyURL}}
+	for _, backend := range h.config.DownloadBackends {
+		targets = append(targets, rewriteTarget{
+			address:  stripScheme(backend.URL),
+			proxyURL: proxyURL + strings.TrimSuffix(downloadsPath, "/") + "/" + backend.Name,
+		})
+	}
+	slices.SortStableFunc(targets, func(a, b rewriteTarget) int {
+		return len(b.address) - len(a.address)
+	})
+	return targets
+}
+
+// rewriteResponseJSON rewrites the download URLs of providers in box metadata: the
+// providers of a box's versions and current_version, of a single version, and a
+// single provider, as served by box catalogs and the Vagrant Cloud API. Responses
+// without a backend URL are returned unchanged.
+func (h *Handler) rewriteResponseJSON(body []byte, proxyURL string) []byte {
+	// Early return for empty body
+	if len(body) == 0 {
+		return body
+	}
+
+	targets := h.rewriteTargets(proxyURL)
+
+	// Check size limit to prevent memory issues with large responses
+	if len(body) > MaxJSONRewriteSize {
+		h.logger.Warn().
+			Int("size", len(body)).
+			Int("max_size", MaxJSONRewriteSize).
+			Msg("Response body exceeds JSON rewrite size limit, using text fallback")
+		return h.rewriteBody(body, targets)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		// Not a JSON object, use text fallback
+		h.logger.Debug().Err(err).Msg("Failed to parse JSON, using text fallback")
+		return h.rewriteBody(body, targets)
+	}
+
+	changed := h.rewriteProvider(data, targets)
+	changed = h.rewriteVersion(data, targets) || changed
+	if current, ok := data["current_version"].(map[string]interface{}); ok {
+		changed = h.rewriteVersion(current, targets) || changed
+	}
+	if versions, ok := data["versions"].([]interface{}); ok {
+		for _, version := range versions {
+			if versionMap, ok := version.(map[string]interface{}); ok {
+				changed = h.rewriteVersion(versionMap, targets) || changed
+			}
+		}
+	}
+
+	if !changed {
+		return body
+	}
+
+	rewritten, err := json.Marshal(data)
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to marshal rewritten JSON, using text fallback")
+		return h.rewriteBody(body, targets)
+	}
+
+	h.logger.Debug().
+		Int("original_size", len(body)).
+		Int("rewritten_size", len(rewritten)).
+		Msg("Vagrant box metadata rewritten")
+
+	return rewritten
+}
+
+// rewriteVersion rewrites the download URLs of a version's providers, and reports
+// whether it changed any
+func (h *Handler) rewriteVersion(version map[string]interface{}, targets []rewriteTarget) bool {
+	providers, ok := version["providers"].([]interface{})
+	if !ok {
+		return false
+	}
+	changed := false
+	for _, provider := range providers {
+		if providerMap, ok := provider.(map[string]interface{}); ok {
+			changed = h.rewriteProvider(providerMap, targets) || changed
+		}
+	}
+	return changed
+}
+
+// rewriteProvider rewrites the download URLs of a provider, and reports whether it
+// changed any
+func (h *Handler) rewriteProvider(provider map[string]interface{}, targets []rewriteTarget) bool {
+	changed := false
+	for _, field := range providerURLFields {
+		value, ok := provider[field].(string)
+		if !ok {
+			continue
+		}
+		if rewritten := h.rewriteURL(value, targets); rewritten != value {
+			provider[field] = rewritten
+			changed = true
+		}
+	}
+	return changed
+}
+
+// rewriteURL rewrites a single URL pointing at the catalog backend or a download
+// backend to the proxy URL serving it. URLs are matched against the backend URL
+// including its path, for either scheme; URLs elsewhere (e.g. boxes on a public
+// CDN) are returned unchanged.
+func (h *Handler) rewriteURL(url string, targets []rewriteTarget) string {
+	for _, target := range targets {
+		for _, scheme := range []string{"http://", "https://"} {
+			rest, ok := strings.CutPrefix(url, scheme+target.address)
+			if !ok || (rest != "" && !strings.HasPrefix(rest, "/") && !strings.HasPrefix(rest, "?")) {
+				continue
+			}
+			rewritten := target.proxyURL + rest
+
+			h.logger.Debug().
+				Str("original", url).
+				Str("rewritten", rewritten).
+				Msg("URL rewritten")
+
+			return rewritten
+		}
+	}
+
+	// URL doesn't point to our backends, return unchanged
+	return url
+}
+
+// rewriteBody is a simpler fallback method for text-based rewriting
+// Used when JSON parsing fails but content is still text
+func (h *Handler) rewriteBody(body []byte, targets []rewriteTarget) []byte {
+	rewritten := body
+	for _, target := range targets {
+		for _, scheme := range []string{"http://", "https://"} {
+			rewritten = bytes.ReplaceAll(rewritten, []byte(scheme+target.address), []byte(target.proxyURL))
+		}
+	}
+
+	if !bytes.Equal(body, rewritten) {
+		h.logger.Debug().
+			Int("original_size", len(body)).
+			Int("rewritten_size", len(rewritten)).
+			Msg("Body rewritten (text mode)")
+	}
+
+	return rewritten
+}
+
+// shouldRewriteBody determines if response body should be rewritten: box metadata
+// and Vagrant Cloud API responses are JSON
+func (h *Handler) shouldRewriteBody(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+
+	return strings.Contains(contentType, "json")
+}
+
+// stripScheme returns a backend URL without its scheme and trailing slash, keeping
+// the path of backends served below one
+// Examples:
+//   - "https://files.example.com/boxes/" -> "files.example.com/boxes"
+//   - "http://catalog:8080" -> "catalog:8080"
+func stripScheme(url string) string {
+	address := strings.TrimPrefix(url, "http://")
+	address = strings.TrimPrefix(address, "https://")
+	return strings.TrimSuffix(address, "/")
+}
@@ -0,0 +1,132 @@
+package vagrant
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// downloadsPath is the path below the path prefix that download backends are served
+// below, each at downloads/<name>/
+const downloadsPath = "/downloads/"
+
+// selectBackendAndProxy proxies box downloads below downloads/<name>/ to the download
+// backend of that name, and everything else to the catalog backend
+func (h *Handler) selectBackendAndProxy(w http.ResponseWriter, r *http.Request, authResult *auth.AuthResult) error {
+	path := h.backendPath(r)
+
+	backend := &h.config.Backend
+	download := false
+	if name, rest, ok := splitDownloadPath(path); ok {
+		backend = h.downloadBackend(name)
+		if backend == nil {
+			errors.ErrorResponse(w, errors.ErrNotFound.WithMessage(fmt.Sprintf("Unknown Vagrant download backend %q", name)))
+			return nil
+		}
+		path = rest
+		download = true
+	}
+
+	operationType := "read"
+	if auth.IsWriteMethod(r.Method) {
+		operationType = "write"
+	}
+
+	h.logger.Debug().
+		Str("backend", backend.Name).
+		Str("url", backend.URL).
+		Bool("download", download).
+		Str("operation", operationType).
+		Str("username", authResult.Username).
+		Msg("Routing to Vagrant backend")
+	middleware.AddLogField(r.Context(), "backend", backend.Name)
+
+	// Note: Backend authentication is handled by proxy client
+	if download {
+		return h.proxyDownload(w, r, backend, path)
+	}
+	return h.proxyWithRewriting(w, r, backend, path)
+}
+
+// downloadBackend returns the configured download backend called name, or nil
+func (h *Handler) downloadBackend(name string) *config.VagrantBackendConfig {
+	for i := range h.config.DownloadBackends {
+		if h.config.DownloadBackends[i].Name == name {
+			return &h.config.DownloadBackends[i]
+		}
+	}
+	return nil
+}
+
+// backendPath returns the request path with the path prefix stripped
+func (h *Handler) backendPath(r *http.Request) string {
+	path := r.URL.Path
+	if h.config.PathPrefix != "" {
+		path = strings.TrimPrefix(path, h.config.PathPrefix)
+		// Ensure path starts with /
+		if path == "" || !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	return path
+}
+
+// splitDownloadPath splits a path below downloads/ into the download backend's name
+// and the path on that backend
+//
+//	/downloads/boxes/base/1.0.0/virtualbox.box -> boxes, /base/1.0.0/virtualbox.box
+//	/acme/base                                 -> not a download path
+func splitDownloadPath(path string) (name, rest string, ok bool) {
+	trimmed, found := strings.CutPrefix(path, downloadsPath)
+	if !found {
+		return "", "", false
+	}
+	name, rest, _ = strings.Cut(trimmed, "/")
+	if name == "" {
+		return "", "", false
+	}
+	return name, "/" + rest, true
+}
+
+// parseBoxPath extracts the box (<organization>/<name>), and the version if any,
+// from the path of box metadata, in either the Vagrant Cloud API's layout or that
+// of box catalogs served at the box's name
+//
+//	/api/v2/box/acme/base                                      -> acme/base, ""
+//	/api/v2/box/acme/base/version/1.0.0/provider/virtualbox/.. -> acme/base, 1.0.0
+//	/acme/base                                                 -> acme/base, ""
+//	/acme/base.json                                            -> acme/base, ""
+func parseBoxPath(p string) (box, version string, ok bool) {
+	if rest, found := strings.CutPrefix(p, "/api/v2/box/"); found {
+		segments := strings.Split(rest, "/")
+		if len(segments) < 2 || segments[0] == "" || segments[1] == "" {
+			return "", "", false
+		}
+		box = segments[0] + "/" + segments[1]
+		if len(segments) >= 4 && segments[2] == "version" && segments[3] != "" {
+			version = segments[3]
+		}
+		return box, version, true
+	}
+
+	if strings.HasPrefix(p, downloadsPath) || strings.HasPrefix(p, "/api/") {
+		return "", "", false
+	}
+	segments := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	if len(segments) != 2 || segments[0] == "" || segments[1] == "" {
+		return "", "", false
+	}
+	return segments[0] + "/" + strings.TrimSuffix(segments[1], ".json"), "", true
+}
+
+// isDownloadPath reports whether a Vagrant Cloud API path downloads a provider's box
+//
+//	/api/v2/box/acme/base/version/1.0.0/provider/virtualbox/amd64/download
+func isDownloadPath(p string) bool {
+	return strings.HasPrefix(p, "/api/v2/box/") && strings.HasSuffix(p, "/download")
+}
@@ -0,0 +1,55 @@
+package vagrant
+
+import "testing"
+
+func TestParseBoxPath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantBox     string
+		wantVersion string
+		wantOK      bool
+	}{
+		{"/api/v2/box/acme/base", "acme/base", "", true},
+		{"/api/v2/box/acme/base/version/1.0.0", "acme/base", "1.0.0", true},
+		{"/api/v2/box/acme/base/version/1.0.0/provider/virtualbox/amd64/download", "acme/base", "1.0.0", true},
+		{"/api/v2/box/acme", "", "", false},
+		{"/acme/base", "acme/base", "", true},
+		{"/acme/base.json", "acme/base", "", true},
+		{"/downloads/files/base.box", "", "", false},
+		{"/acme/base/1.0.0/virtualbox.box", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			box, version, ok := parseBoxPath(tt.path)
+			if box != tt.wantBox || version != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("parseBoxPath() = %q, %q, %v, want %q, %q, %v",
+					box, version, ok, tt.wantBox, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSplitDownloadPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		wantName string
+		wantRest string
+		wantOK   bool
+	}{
+		{"/downloads/files/base/1.0.0/virtualbox.box", "files", "/base/1.0.0/virtualbox.box", true},
+		{"/downloads/files", "files", "/", true},
+		{"/downloads/", "", "", false},
+		{"/acme/base", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			name, rest, ok := splitDownloadPath(tt.path)
+			if name != tt.wantName || rest != tt.wantRest || ok != tt.wantOK {
+				t.Errorf("splitDownloadPath() = %q, %q, %v, want %q, %q, %v",
+					name, rest, ok, tt.wantName, tt.wantRest, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package webui
+
+import (
+	"net/http"
+	"strings"
+)
+
+// scopeCookies rewrites the backend's Set-Cookie headers so the browser keeps the
+// cookies on the proxy host under mountPath: the path is moved under the mount and
+// the domain dropped, so they are neither sent to other UIs or protocols on the
+// proxy host nor to the backend's own domain. On HTTPS they are marked Secure.
+// Cookies that can't be parsed are dropped.
+func scopeCookies(headers http.Header, mountPath string, secure bool) {
+	values := headers.Values("Set-Cookie")
+	if len(values) == 0 {
+		return
+	}
+
+	headers.Del("Set-Cookie")
+	for _, value := range values {
+		cookie, err := http.ParseSetCookie(value)
+		if err != nil {
+			continue
+		}
+
+		cookie.Path = mountPath + "/" + strings.TrimPrefix(cookie.Path, "/")
+		cookie.Domain = ""
+		if secure {
+			cookie.Secure = true
+		}
+		if v := cookie.String(); v != "" {
+			headers.Add("Set-Cookie", v)
+		}
+	}
+}
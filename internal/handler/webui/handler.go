@@ -0,0 +1,230 @@
+// Package webui serves the web UIs of protocol backends (e.g. Verdaccio, Nexus) to
+// authenticated users under a path prefix, so developers can browse packages
+// without the backends being exposed.
+package webui
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/detector"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/mainuli/artifusion/internal/proxy/rewriter"
+	"github.com/rs/zerolog"
+)
+
+// realm is the Basic auth realm browsers show when prompting for credentials
+const realm = "Artifusion"
+
+// mount is a web UI served at <path prefix>/<protocol>/
+type mount struct {
+	protocol string
+	backend  proxy.BackendConfig
+	paths    []string
+	home     string
+}
+
+// Handler proxies web UI requests to the mounted backends
+type Handler struct {
+	pathPrefix    string
+	mounts        map[string]*mount
+	authenticator *auth.ClientAuthenticator
+	proxyClient   *proxy.Client
+	logger        zerolog.Logger
+
+	// apiPath is where the browser session API is served ("" if browser sessions are disabled)
+	apiPath string
+}
+
+// NewHandler creates the web UI handler for the mounts in cfg, served by the
+// backends of the protocols in protocols
+func NewHandler(
+	cfg *config.WebUIConfig,
+	protocols *config.ProtocolsConfig,
+	authenticator *auth.ClientAuthenticator,
+	proxyClient *proxy.Client,
+	logger zerolog.Logger,
+) *Handler {
+	h := &Handler{
+		pathPrefix:    cfg.PathPrefix,
+		mounts:        make(map[string]*mount, len(cfg.Mounts)),
+		authenticator: authenticator,
+		proxyClient:   proxyClient,
+		logger:        logger.With().Str("component", "web_ui").Logger(),
+	}
+	for _, m := range cfg.Mounts {
+		h.mounts[m.Protocol] = &mount{
+			protocol: m.Protocol,
+			backend:  uiBackend(protocols, m),
+			paths:    m.Paths,
+			home:     m.Home,
+		}
+	}
+	return h
+}
+
+// SetBrowserSessions serves a sign-in page that obtains a browser session from the
+// session API at apiPath and keeps it in a cookie, so users sign in with GitHub
+// instead of entering a token. The authenticator must accept browser sessions.
+// Must be called before the handler is served.
+func (h *Handler) SetBrowserSessions(apiPath string) {
+	h.apiPath = strings.TrimSuffix(apiPath, "/")
+}
+
+// uiBackend returns the backend serving a mount's UI: a copy of the protocol's
+// backend, with its own connection pool and circuit breaker, at the mount's URL
+func uiBackend(protocols *config.ProtocolsConfig, m config.WebUIMountConfig) proxy.BackendConfig {
+	switch m.Protocol {
+	case "maven":
+		backend := protocols.Maven.Backend
+		backend.Name += "-ui"
+		if m.URL != "" {
+			backend.URL = m.URL
+		}
+		return &backend
+	default:
+		backend := protocols.NPM.Backend
+		backend.Name += "-ui"
+		if m.URL != "" {
+			backend.URL = m.URL
+		}
+		return &backend
+	}
+}
+
+// ServeHTTP authenticates the user and proxies the request to the mounted UI
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.apiPath != "" {
+		if h.serveSession(w, r) {
+			return
+		}
+		r = withSessionCookie(r)
+	}
+
+	protocol, backendPath, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, h.pathPrefix+"/"), "/")
+	m, ok := h.mounts[protocol]
+	if !ok || !strings.HasPrefix(r.URL.Path, h.pathPrefix+"/") {
+		errors.ErrorResponse(w, errors.ErrNotFound.WithMessage("No web UI is served at this path"))
+		return
+	}
+	middleware.AddLogField(r.Context(), "protocol", "web_ui")
+	middleware.AddLogField(r.Context(), "backend", m.backend.GetName())
+
+	authResult, authedReq, err := h.authenticator.AuthenticateAndInjectContext(r)
+	if err == nil && authResult.TokenType == auth.TokenTypeSignedURL {
+		err = fmt.Errorf("signed URLs are not accepted by web UIs")
+	}
+	if err != nil {
+		h.logger.Warn().Err(err).
+			Str("path", r.URL.Path).
+			Str("remote_addr", r.RemoteAddr).
+			Msg("Authentication failed")
+
+		if stderrors.Is(err, auth.ErrInsufficientPermissions) {
+			errors.ErrorResponse(w, errors.ErrForbidden.WithMessage(auth.InsufficientPermissionsMessage))
+			return
+		}
+		if h.apiPath != "" && wantsLogin(r) {
+			http.Redirect(w, r, h.pathPrefix+loginPath+"?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+			return
+		}
+		// Basic makes browsers prompt for the GitHub username and token
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s"`, realm))
+		errors.ErrorResponse(w, errors.ErrUnauthorized)
+		return
+	}
+
+	mountPath := h.pathPrefix + "/" + m.protocol
+	backendPath = "/" + backendPath
+	if backendPath == "/" && r.URL.Path != mountPath+"/" {
+		// Make relative links in the UI resolve under the mount
+		http.Redirect(w, r, mountPath+"/", http.StatusFound)
+		return
+	}
+	if backendPath == "/" && m.home != "/" {
+		http.Redirect(w, r, mountPath+m.home, http.StatusFound)
+		return
+	}
+	if !m.allows(backendPath) {
+		errors.ErrorResponse(w, errors.ErrNotFound.WithMessage("Path is not part of the web UI"))
+		return
+	}
+
+	publicURL := detector.GetRequestScheme(r) + "://" + detector.GetRequestHost(r) + mountPath
+	if err := h.proxy(w, authedReq, m, backendPath, mountPath, publicURL); err != nil {
+		h.logger.Error().Err(err).
+			Str("path", r.URL.Path).
+			Str("backend", m.backend.GetName()).
+			Msg("Failed to proxy web UI request")
+		errors.ErrorResponse(w, errors.ErrInternal.WithInternal(err))
+	}
+}
+
+// allows reports whether backendPath is one of the mount's UI paths
+func (m *mount) allows(backendPath string) bool {
+	if len(m.paths) == 0 {
+		return true
+	}
+	for _, path := range m.paths {
+		prefix := strings.TrimSuffix(path, "/")
+		if backendPath == prefix || strings.HasPrefix(backendPath, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// proxy forwards the request to the mount's backend without the user's GitHub
+// credentials and streams the response, keeping the backend's cookies scoped to
+// the mount
+func (h *Handler) proxy(w http.ResponseWriter, r *http.Request, m *mount, backendPath, mountPath, publicURL string) error {
+	headers := r.Header.Clone()
+	headers.Del("Authorization")
+	stripSessionCookie(headers)
+
+	// Lets UIs that support it generate URLs under the mount
+	headers.Set("X-Forwarded-Proto", detector.GetRequestScheme(r))
+	headers.Set("X-Forwarded-Host", detector.GetRequestHost(r))
+	headers.Set("X-Forwarded-Prefix", mountPath)
+
+	resp, err := h.proxyClient.ProxyRequest(&proxy.Request{
+		Method:        r.Method,
+		Path:          backendPath,
+		Query:         r.URL.RawQuery,
+		Body:          r.Body,
+		Headers:       headers,
+		Backend:       m.backend,
+		OriginalReq:   r,
+		ContentLength: r.ContentLength,
+	})
+	if err != nil {
+		return err
+	}
+
+	if location := resp.Headers.Get("Location"); location != "" {
+		resolved := location
+		if u, err := resp.HTTPResp.Location(); err == nil {
+			resolved = u.String()
+		}
+		if mapped, ok := rewriter.MapLocation(resolved, m.backend.GetURL(), publicURL); ok {
+			resp.Headers.Set("Location", mapped)
+		}
+	}
+	scopeCookies(resp.Headers, mountPath, detector.GetRequestScheme(r) == "https")
+
+	// The UI brings its own content security policy; the API default blocks it
+	w.Header().Del("Content-Security-Policy")
+
+	_, err = h.proxyClient.StreamResponse(w, resp, true)
+	if err != nil {
+		h.logger.Warn().Err(err).Str("path", r.URL.Path).Msg("Error streaming web UI response")
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package webui
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/detector"
+	"github.com/mainuli/artifusion/internal/errors"
+)
+
+// Paths of the browser session endpoints, relative to the path prefix. Mounts are
+// named after protocols, so they never collide.
+const (
+	loginPath       = "/login"
+	loginScriptPath = "/login.js"
+	sessionPath     = "/session"
+)
+
+// serveSession serves the browser session endpoints, reporting whether r was one:
+//
+//	GET    /login     sign-in page running the GitHub device flow against the API
+//	GET    /login.js  its script (the UI CSP forbids inline scripts)
+//	POST   /session   stores the session token of the Authorization header in a cookie
+//	DELETE /session   signs out
+func (h *Handler) serveSession(w http.ResponseWriter, r *http.Request) bool {
+	switch strings.TrimPrefix(r.URL.Path, h.pathPrefix) {
+	case loginPath:
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			errors.ErrorResponse(w, errors.ErrMethodNotAllowed)
+			return true
+		}
+		h.writeLoginPage(w, r)
+	case loginScriptPath:
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = w.Write([]byte(loginScript))
+	case sessionPath:
+		switch r.Method {
+		case http.MethodPost:
+			h.createSessionCookie(w, r)
+		case http.MethodDelete:
+			h.setSessionCookie(w, r, "", -1)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			errors.ErrorResponse(w, errors.ErrMethodNotAllowed)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// createSessionCookie stores the browser session token of the request's
+// Authorization header in an HttpOnly, SameSite=Strict cookie scoped to the web UI.
+// A cross-site page can neither set the header nor make the browser send the
+// cookie, so the cookie can't be planted or used by other sites.
+func (h *Handler) createSessionCookie(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || !strings.HasPrefix(token, auth.SessionTokenPrefix) {
+		errors.ErrorResponse(w, errors.ErrUnauthorized.WithMessage("a browser session token is required"))
+		return
+	}
+	result, err := h.authenticator.AuthenticateRequest(r)
+	if err != nil || result.TokenType != auth.TokenTypeSession {
+		errors.ErrorResponse(w, errors.ErrUnauthorized.WithMessage("invalid or expired browser session"))
+		return
+	}
+
+	h.setSessionCookie(w, r, token, 0)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setSessionCookie sets the session cookie to token, or deletes it for a negative
+// maxAge. The cookie lives as long as the browser session; the token expires on
+// its own.
+func (h *Handler) setSessionCookie(w http.ResponseWriter, r *http.Request, token string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookieName,
+		Value:    token,
+		Path:     h.pathPrefix + "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   detector.GetRequestScheme(r) == "https",
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// withSessionCookie returns r with the session token of its cookie as Bearer token
+// when it carries no credentials of its own
+func withSessionCookie(r *http.Request) *http.Request {
+	if r.Header.Get("Authorization") != "" {
+		return r
+	}
+	cookie, err := r.Cookie(auth.SessionCookieName)
+	if err != nil || !strings.HasPrefix(cookie.Value, auth.SessionTokenPrefix) {
+		return r
+	}
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+cookie.Value)
+	return r
+}
+
+// stripSessionCookie removes the session cookie from headers, keeping the backend's
+// own cookies
+func stripSessionCookie(headers http.Header) {
+	cookies := (&http.Request{Header: headers}).Cookies()
+	headers.Del("Cookie")
+	kept := make([]string, 0, len(cookies))
+	for _, cookie := range cookies {
+		if cookie.Name != auth.SessionCookieName {
+			kept = append(kept, cookie.String())
+		}
+	}
+	if len(kept) > 0 {
+		headers.Set("Cookie", strings.Join(kept, "; "))
+	}
+}
+
+// wantsLogin reports whether an unauthenticated request is a browser navigation
+// without credentials, or with an expired session, that should be sent to the
+// sign-in page rather than prompted for Basic auth
+func wantsLogin(r *http.Request) bool {
+	if r.Method != http.MethodGet || !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		return false
+	}
+	authorization := r.Header.Get("Authorization")
+	return authorization == "" || strings.HasPrefix(authorization, "Bearer "+auth.SessionTokenPrefix)
+}
+
+// loginPage signs in with the GitHub device flow: the user enters a code on GitHub
+// while the script polls the API, then stores the session token in the web UI
+// cookie and continues to Next
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Sign in - Artifusion</title>
+<style>body{font-family:sans-serif;max-width:40em;margin:3em auto;color:#222}code{background:#f3f3f3;padding:.1em .3em;font-size:1.4em}</style>
+<script src="{{.Prefix}}/login.js" defer></script>
+</head>
+<body>
+<h1>Sign in to Artifusion</h1>
+<div id="login" data-api="{{.API}}" data-prefix="{{.Prefix}}" data-next="{{.Next}}">
+<p id="status">Starting GitHub sign-in&hellip;</p>
+<p id="code" hidden>Enter <code id="user-code"></code> at <a id="verification-uri" target="_blank" rel="noopener"></a></p>
+</div>
+</body>
+</html>
+`))
+
+// writeLoginPage renders the sign-in page, continuing to the next query parameter
+// when it is a web UI path
+func (h *Handler) writeLoginPage(w http.ResponseWriter, r *http.Request) {
+	next := r.URL.Query().Get("next")
+	if !strings.HasPrefix(next, h.pathPrefix+"/") || strings.HasPrefix(next, "//") || strings.Contains(next, "\\") {
+		next = h.pathPrefix + "/"
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = loginPage.Execute(w, struct {
+		API    string
+		Prefix string
+		Next   string
+	}{API: h.apiPath, Prefix: h.pathPrefix, Next: next})
+}
+
+// loginScript runs the device flow of the sign-in page
+const loginScript = `"use strict";
+(function () {
+  const root = document.getElementById("login");
+  const status = document.getElementById("status");
+  const api = root.dataset.api, prefix = root.dataset.prefix, next = root.dataset.next;
+
+  function post(url, body, headers) {
+    return fetch(url, {
+      method: "POST",
+      credentials: "same-origin",
+      headers: Object.assign({"Content-Type": "application/json"}, headers || {}),
+      body: body ? JSON.stringify(body) : undefined,
+    });
+  }
+  function fail(message) { status.textContent = message; }
+  function sleep(seconds) { return new Promise(function (resolve) { setTimeout(resolve, seconds * 1000); }); }
+
+  async function signIn() {
+    const start = await post(api + "/session/device");
+    if (!start.ok) { return fail("GitHub sign-in is unavailable, try again later."); }
+    const code = await start.json();
+
+    document.getElementById("user-code").textContent = code.user_code;
+    const link = document.getElementById("verification-uri");
+    link.href = code.verification_uri;
+    link.textContent = code.verification_uri;
+    document.getElementById("code").hidden = false;
+    status.textContent = "Waiting for you to authorize on GitHub…";
+
+    let interval = code.interval;
+    const deadline = Date.now() + code.expires_in * 1000;
+    while (Date.now() < deadline) {
+      await sleep(interval);
+      const poll = await post(api + "/session/token", {device_code: code.device_code});
+      const body = await poll.json();
+      if (poll.status === 202) { interval = Math.max(interval, body.interval); continue; }
+      if (!poll.ok) { return fail(body.message || "Sign-in failed."); }
+
+      const stored = await post(prefix + "/session", null, {"Authorization": "Bearer " + body.token});
+      if (!stored.ok) { return fail("Could not store the session, try again."); }
+      window.location.assign(next);
+      return;
+    }
+    fail("The code expired, reload the page to try again.");
+  }
+
+  signIn().catch(function () { fail("Sign-in failed, reload the page to try again."); });
+})();
+`
@@ -34,6 +34,16 @@ type ReadinessResponse struct {
 	Time   time.Time         `json:"time"`
 }
 
+// VersionInfo describes the running build and its configuration shape
+type VersionInfo struct {
+	Version   string          `json:"version"`
+	GitCommit string          `json:"git_commit"`
+	BuildTime string          `json:"build_time"`
+	GoVersion string          `json:"go_version"`
+	Protocols []string        `json:"protocols"`
+	Features  map[string]bool `json:"features"`
+}
+
 // Checker is a function that performs a health check
 type Checker func(ctx context.Context) error
 
@@ -43,6 +53,7 @@ type Handler struct {
 	startTime time.Time
 	checkers  map[string]Checker
 	mu        sync.RWMutex
+	info      VersionInfo
 }
 
 // NewHandler creates a new health check handler
@@ -61,6 +72,25 @@ func (h *Handler) RegisterChecker(name string, checker Checker) {
 	h.checkers[name] = checker
 }
 
+// SetVersionInfo sets the build and configuration details served by VersionHandler.
+// Must be called before the handler serves requests.
+func (h *Handler) SetVersionInfo(info VersionInfo) {
+	h.info = info
+}
+
+// VersionHandler returns a handler reporting the running build, enabled protocols
+// and features, so fleet dashboards can tell replicas apart
+func (h *Handler) VersionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(h.info); err != nil {
+			// Log encoding error - response headers already sent, cannot change status
+			_ = err // Error already logged by encoder
+		}
+	}
+}
+
 // LivenessHandler returns a handler for the liveness probe
 // This endpoint should return 200 if the application is running
 func (h *Handler) LivenessHandler() http.HandlerFunc {
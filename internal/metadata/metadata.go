@@ -0,0 +1,341 @@
+// Package metadata records the artifacts clients pull and push in an embedded bbolt
+// database: their coordinates and digest, when they were first and last seen, how
+// often they were pulled and the provenance of their last push.
+//
+// Protocol handlers record pulls and pushes as they serve them. Records are buffered
+// in memory and written in one transaction per flush interval, so serving a request
+// never waits for the disk. The API queries the database for package browsing,
+// retention decisions and audits.
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+	bolt "go.etcd.io/bbolt"
+)
+
+// artifactsBucket holds one JSON-encoded Artifact per protocol, name and version
+var artifactsBucket = []byte("artifacts")
+
+// Artifact is what is known about an artifact version clients pulled or pushed
+type Artifact struct {
+	Protocol   string    `json:"protocol"`
+	Name       string    `json:"name"`              // e.g. team/app, @scope/pkg, org.slf4j:slf4j-api
+	Version    string    `json:"version,omitempty"` // Tag, digest reference or version
+	Digest     string    `json:"digest,omitempty"`  // Last content digest reported by the backend
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+	LastPulled time.Time `json:"last_pulled,omitzero"`
+	Pulls      uint64    `json:"pulls"`
+
+	// UploadedAt and Provenance describe the last push through Artifusion
+	UploadedAt time.Time   `json:"uploaded_at,omitzero"`
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// Uploader returns the user who last pushed the artifact, or "" if unknown
+func (a *Artifact) Uploader() string {
+	if a.Provenance == nil {
+		return ""
+	}
+	return a.Provenance.User
+}
+
+// merge folds the newer record of the same artifact into a
+func (a *Artifact) merge(newer *Artifact) {
+	if a.FirstSeen.IsZero() || (!newer.FirstSeen.IsZero() && newer.FirstSeen.Before(a.FirstSeen)) {
+		a.FirstSeen = newer.FirstSeen
+	}
+	if newer.LastSeen.After(a.LastSeen) {
+		a.LastSeen = newer.LastSeen
+	}
+	if newer.LastPulled.After(a.LastPulled) {
+		a.LastPulled = newer.LastPulled
+	}
+	a.Pulls += newer.Pulls
+	if newer.Digest != "" {
+		a.Digest = newer.Digest
+	}
+	if newer.UploadedAt.After(a.UploadedAt) {
+		a.UploadedAt = newer.UploadedAt
+		a.Provenance = newer.Provenance
+	}
+}
+
+// Filter selects the artifacts returned by List. Zero fields match every artifact.
+type Filter struct {
+	Protocol       string
+	Query          string    // Case-insensitive substring of the name
+	Uploader       string    // Last uploader, case-insensitive
+	NotPulledSince time.Time // Only artifacts not pulled since this time
+	Limit          int       // Maximum number of artifacts returned (0 = all)
+}
+
+// matches reports whether a satisfies the filter
+func (f *Filter) matches(a *Artifact) bool {
+	if f.Protocol != "" && a.Protocol != f.Protocol {
+		return false
+	}
+	if f.Query != "" && !strings.Contains(strings.ToLower(a.Name), strings.ToLower(f.Query)) {
+		return false
+	}
+	if f.Uploader != "" && !strings.EqualFold(a.Uploader(), f.Uploader) {
+		return false
+	}
+	if !f.NotPulledSince.IsZero() && !a.LastPulled.Before(f.NotPulledSince) {
+		return false
+	}
+	return true
+}
+
+// Store records artifacts in the metadata database. It is safe for concurrent use.
+// A nil *Store records nothing.
+type Store struct {
+	db            *bolt.DB
+	flushInterval time.Duration
+	logger        zerolog.Logger
+	now           func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]*Artifact // Not yet flushed, by key
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Open opens the metadata database at cfg.Path, creating it if missing. It fails if
+// another process holds the database open.
+func Open(cfg *config.MetadataConfig, logger zerolog.Logger) (*Store, error) {
+	db, err := bolt.Open(cfg.Path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open metadata database %s: %w", cfg.Path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(artifactsBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to initialize metadata database %s: %w", cfg.Path, err)
+	}
+
+	return &Store{
+		db:            db,
+		flushInterval: cfg.FlushInterval,
+		logger:        logger.With().Str("component", "metadata").Logger(),
+		now:           time.Now,
+		pending:       make(map[string]*Artifact),
+	}, nil
+}
+
+// key identifies an artifact version in the database. Names and versions never
+// contain NUL, and keys of a protocol sort together.
+func key(protocol, name, version string) string {
+	return protocol + "\x00" + name + "\x00" + version
+}
+
+// RecordPull records a successful pull of an artifact version. digest is the
+// content digest the backend reported, or empty if unknown.
+func (s *Store) RecordPull(protocol, name, version, digest string) {
+	if s == nil {
+		return
+	}
+	now := s.now()
+	s.record(&Artifact{
+		Protocol:   protocol,
+		Name:       name,
+		Version:    version,
+		Digest:     digest,
+		FirstSeen:  now,
+		LastSeen:   now,
+		LastPulled: now,
+		Pulls:      1,
+	})
+}
+
+// RecordPush records a successful push of an artifact version and who pushed it
+func (s *Store) RecordPush(protocol, name, version, digest string, provenance *Provenance) {
+	if s == nil {
+		return
+	}
+	now := s.now()
+	s.record(&Artifact{
+		Protocol:   protocol,
+		Name:       name,
+		Version:    version,
+		Digest:     digest,
+		FirstSeen:  now,
+		LastSeen:   now,
+		UploadedAt: now,
+		Provenance: provenance,
+	})
+}
+
+// record buffers a until the next flush
+func (s *Store) record(a *Artifact) {
+	k := key(a.Protocol, a.Name, a.Version)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pending, ok := s.pending[k]; ok {
+		pending.merge(a)
+		return
+	}
+	s.pending[k] = a
+}
+
+// Flush writes the buffered records to the database. Records that fail to be
+// written stay buffered for the next flush.
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = make(map[string]*Artifact)
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(artifactsBucket)
+		for k, recorded := range batch {
+			artifact := *recorded
+			if data := bucket.Get([]byte(k)); data != nil {
+				var stored Artifact
+				if err := json.Unmarshal(data, &stored); err != nil {
+					return fmt.Errorf("corrupt record %q: %w", k, err)
+				}
+				stored.merge(recorded)
+				artifact = stored
+			}
+
+			data, err := json.Marshal(&artifact)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(k), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Put the batch back, folding in what was recorded meanwhile
+		s.mu.Lock()
+		for k, recorded := range batch {
+			if newer, ok := s.pending[k]; ok {
+				recorded.merge(newer)
+			}
+			s.pending[k] = recorded
+		}
+		s.mu.Unlock()
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	return nil
+}
+
+// Get returns the record of an artifact version
+func (s *Store) Get(protocol, name, version string) (Artifact, bool, error) {
+	if err := s.Flush(); err != nil {
+		return Artifact{}, false, err
+	}
+
+	var artifact Artifact
+	found := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(artifactsBucket).Get([]byte(key(protocol, name, version)))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &artifact)
+	})
+	if err != nil {
+		return Artifact{}, false, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	return artifact, found, nil
+}
+
+// List returns the artifacts matching filter, ordered by protocol, name and version
+func (s *Store) List(filter Filter) ([]Artifact, error) {
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+
+	var prefix []byte
+	if filter.Protocol != "" {
+		prefix = []byte(filter.Protocol + "\x00")
+	}
+
+	artifacts := []Artifact{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(artifactsBucket).Cursor()
+		for k, data := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, data = cursor.Next() {
+			var artifact Artifact
+			if err := json.Unmarshal(data, &artifact); err != nil {
+				return fmt.Errorf("corrupt record %q: %w", k, err)
+			}
+			if !filter.matches(&artifact) {
+				continue
+			}
+			artifacts = append(artifacts, artifact)
+			if filter.Limit > 0 && len(artifacts) == filter.Limit {
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	return artifacts, nil
+}
+
+// Start flushes buffered records every flush interval until Close is called
+func (s *Store) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Flush(); err != nil {
+					s.logger.Error().Err(err).Msg("Failed to flush artifact metadata, retrying later")
+				}
+			}
+		}
+	}()
+}
+
+// Close stops flushing started by Start, writes the buffered records and closes
+// the database
+func (s *Store) Close() error {
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+	}
+
+	err := s.Flush()
+	if closeErr := s.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+package metadata
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func openTestStore(t *testing.T, path string, now *time.Time) *Store {
+	t.Helper()
+	s, err := Open(&config.MetadataConfig{Path: path, FlushInterval: time.Minute}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestStore_RecordsAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.db")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	start := now
+
+	s := openTestStore(t, path, &now)
+	s.RecordPush("oci", "team/app", "v1", "sha256:aaa", &Provenance{User: "alice", Repository: "myorg/app"})
+	now = now.Add(time.Hour)
+	s.RecordPull("oci", "team/app", "v1", "sha256:aaa")
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Counts buffered after a restart add to the persisted ones
+	s = openTestStore(t, path, &now)
+	defer func() { _ = s.Close() }()
+	now = now.Add(time.Hour)
+	s.RecordPull("oci", "team/app", "v1", "sha256:bbb")
+
+	got, ok, err := s.Get("oci", "team/app", "v1")
+	if err != nil || !ok {
+		t.Fatalf("Get() = %v, %v, want the artifact", ok, err)
+	}
+	want := Artifact{
+		Protocol:   "oci",
+		Name:       "team/app",
+		Version:    "v1",
+		Digest:     "sha256:bbb",
+		FirstSeen:  start,
+		LastSeen:   now,
+		LastPulled: now,
+		Pulls:      2,
+		UploadedAt: start,
+	}
+	provenance := got.Provenance
+	got.Provenance = nil
+	if got != want {
+		t.Errorf("Get() = %+v, want %+v", got, want)
+	}
+	if provenance == nil || provenance.User != "alice" || provenance.Repository != "myorg/app" {
+		t.Errorf("Get().Provenance = %+v, want alice's push from myorg/app", provenance)
+	}
+
+	if _, ok, _ := s.Get("oci", "team/app", "v2"); ok {
+		t.Error("Get() found a version that was never recorded")
+	}
+}
+
+func TestStore_List(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := openTestStore(t, filepath.Join(t.TempDir(), "metadata.db"), &now)
+	defer func() { _ = s.Close() }()
+
+	s.RecordPush("npm", "@myorg/app", "1.0.0", "", &Provenance{User: "alice"})
+	s.RecordPush("maven", "com.acme:lib", "1.0", "", &Provenance{User: "bob"})
+	s.RecordPull("oci", "team/app", "v1", "")
+	now = now.Add(48 * time.Hour)
+	s.RecordPull("oci", "team/worker", "v1", "")
+	s.RecordPull("npm", "@myorg/app", "1.0.0", "")
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{name: "all", want: []string{"maven com.acme:lib", "npm @myorg/app", "oci team/app", "oci team/worker"}},
+		{name: "protocol", filter: Filter{Protocol: "oci"}, want: []string{"oci team/app", "oci team/worker"}},
+		{name: "query", filter: Filter{Query: "APP"}, want: []string{"npm @myorg/app", "oci team/app"}},
+		{name: "uploader", filter: Filter{Uploader: "Bob"}, want: []string{"maven com.acme:lib"}},
+		{
+			name:   "not pulled since",
+			filter: Filter{NotPulledSince: now.Add(-24 * time.Hour)},
+			want:   []string{"maven com.acme:lib", "oci team/app"},
+		},
+		{name: "limit", filter: Filter{Limit: 1}, want: []string{"maven com.acme:lib"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			artifacts, err := s.List(tt.filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var got []string
+			for _, a := range artifacts {
+				got = append(got, a.Protocol+" "+a.Name)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("List() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("List()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStore_NilRecordsNothing(t *testing.T) {
+	var s *Store
+	s.RecordPull("oci", "team/app", "v1", "")
+	s.RecordPush("oci", "team/app", "v1", "", &Provenance{User: "alice"})
+}
@@ -0,0 +1,65 @@
+package metadata
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/auth"
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+// maxCIValueLength bounds the CI metadata values taken from request headers
+const maxCIValueLength = 256
+
+// ciHeaders maps the request headers CI jobs can send to the CI metadata keys they
+// are recorded under. GitHub Actions workflows set them from the GITHUB_* variables,
+// e.g. X-GitHub-Run-Id: ${{ github.run_id }}
+var ciHeaders = map[string]string{
+	"X-GitHub-Run-Id":      "run_id",
+	"X-GitHub-Run-Attempt": "run_attempt",
+	"X-GitHub-Workflow":    "workflow",
+	"X-GitHub-Repository":  "repository",
+	"X-GitHub-Sha":         "sha",
+	"X-GitHub-Ref":         "ref",
+	"X-GitHub-Actor":       "actor",
+}
+
+// Provenance describes who pushed an artifact
+type Provenance struct {
+	User           string `json:"user"`
+	ImpersonatedBy string `json:"impersonated_by,omitempty"` // Admin acting as User
+	TokenType      string `json:"token_type,omitempty"`
+
+	// Repository is the repository of a GitHub Actions token, verified with GitHub
+	Repository string `json:"repository,omitempty"`
+
+	// CI is the CI metadata the client reported in X-GitHub-* headers. It is not
+	// verified: any client can send the headers.
+	CI map[string]string `json:"ci,omitempty"`
+}
+
+// NewProvenance returns the provenance of a push from its authenticated request
+func NewProvenance(r *http.Request) *Provenance {
+	provenance := &Provenance{User: middleware.GetUsername(r.Context())}
+	if result := auth.ResultFromContext(r.Context()); result != nil {
+		provenance.User = result.Username
+		provenance.ImpersonatedBy = result.ImpersonatedBy
+		provenance.TokenType = result.TokenType
+		provenance.Repository = result.Repository
+	}
+
+	for header, key := range ciHeaders {
+		value := strings.TrimSpace(r.Header.Get(header))
+		if value == "" {
+			continue
+		}
+		if len(value) > maxCIValueLength {
+			value = value[:maxCIValueLength]
+		}
+		if provenance.CI == nil {
+			provenance.CI = make(map[string]string)
+		}
+		provenance.CI[key] = value
+	}
+	return provenance
+}
@@ -0,0 +1,34 @@
+package metadata
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/middleware"
+)
+
+func TestNewProvenance(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/v2/team/app/manifests/v1", nil)
+	r = r.WithContext(middleware.SetUsername(r.Context(), "alice"))
+	r.Header.Set("X-GitHub-Run-Id", " 12345 ")
+	r.Header.Set("X-GitHub-Sha", "abc123")
+	r.Header.Set("X-GitHub-Ref", strings.Repeat("r", 300))
+	r.Header.Set("X-Unrelated", "ignored")
+
+	got := NewProvenance(r)
+
+	if got.User != "alice" {
+		t.Errorf("User = %q, want alice", got.User)
+	}
+	want := map[string]string{"run_id": "12345", "sha": "abc123", "ref": strings.Repeat("r", maxCIValueLength)}
+	if len(got.CI) != len(want) {
+		t.Fatalf("CI = %v, want %v", got.CI, want)
+	}
+	for key, value := range want {
+		if got.CI[key] != value {
+			t.Errorf("CI[%s] = %q, want %q", key, got.CI[key], value)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"runtime"
 	"sync/atomic"
 	"time"
 
@@ -17,28 +18,95 @@ type Metrics struct {
 	ResponseSize    *prometheus.HistogramVec
 	ActiveRequests  prometheus.Gauge
 
+	// Timeout middleware metrics. Handler goroutines keep running after their
+	// request times out until the handler notices the cancelled context.
+	TimeoutHandlers          prometheus.Gauge
+	TimeoutAbandonedHandlers prometheus.Gauge
+	RequestTimeouts          *prometheus.CounterVec
+
 	// Auth metrics
-	AuthCacheHits   prometheus.Counter
-	AuthCacheMisses prometheus.Counter
-	AuthCacheSize   prometheus.Gauge
-	GitHubAPICalls  *prometheus.CounterVec
-	AuthDuration    *prometheus.HistogramVec
+	AuthCacheHits      prometheus.Counter
+	AuthCacheMisses    prometheus.Counter
+	AuthCacheSize      prometheus.Gauge
+	AuthCacheEvictions prometheus.Counter
+	AuthCacheCoalesced prometheus.Counter
+	GitHubAPICalls     *prometheus.CounterVec
+	AuthDuration       *prometheus.HistogramVec
+
+	// AuthFailurePolicy counts validations GitHub could not answer, by decision
+	AuthFailurePolicy *prometheus.CounterVec
+
+	// CredentialSharing counts requests from a token's source IPs beyond the limit
+	CredentialSharing *prometheus.CounterVec
+
+	// AuthLockouts counts lockouts after repeated failed authentications, by key
+	AuthLockouts *prometheus.CounterVec
+
+	// AuthLockoutRejections counts requests rejected while locked out, by key
+	AuthLockoutRejections *prometheus.CounterVec
+
+	// ContentPolicyDenials counts requests denied by the content policy, by reason
+	ContentPolicyDenials *prometheus.CounterVec
+
+	// ClientRequests counts requests by client type (from the User-Agent) and protocol
+	ClientRequests *prometheus.CounterVec
+
+	// ClientPolicyDenials counts requests denied by the client policy, by client type
+	// and reason
+	ClientPolicyDenials *prometheus.CounterVec
 
 	// Backend metrics
-	BackendRequests    *prometheus.CounterVec
-	BackendDuration    *prometheus.HistogramVec
-	BackendErrors      *prometheus.CounterVec
-	BackendHealthGauge *prometheus.GaugeVec
-	BackendLatency     *prometheus.HistogramVec
-	BackendErrorRate   *prometheus.CounterVec
-	ConnectionPoolSize *prometheus.GaugeVec
+	BackendRequests     *prometheus.CounterVec
+	BackendDuration     *prometheus.HistogramVec
+	BackendErrors       *prometheus.CounterVec
+	BackendHealthGauge  *prometheus.GaugeVec
+	BackendLatency      *prometheus.HistogramVec
+	BackendErrorRate    *prometheus.CounterVec
+	ConnectionPoolSize  *prometheus.GaugeVec
+	ConnectionsAcquired *prometheus.CounterVec
+	StreamAborts        *prometheus.CounterVec
+	DigestMismatches    *prometheus.CounterVec
+	InFlightBytes       *prometheus.GaugeVec
+	CascadeDepth        *prometheus.HistogramVec
+	WriteBacks          *prometheus.CounterVec
+
+	// ResponseCacheRequests counts lookups in protocol response caches, by result
+	ResponseCacheRequests *prometheus.CounterVec
+
+	// PlatformFilteredIndexes counts OCI image indexes filtered to the client's
+	// platforms, by where the platforms came from
+	PlatformFilteredIndexes *prometheus.CounterVec
+
+	// Replication metrics (OCI pushes copied to a secondary registry)
+	Replications         *prometheus.CounterVec
+	ReplicationLag       *prometheus.HistogramVec
+	ReplicationQueue     *prometheus.GaugeVec
+	ReplicationOutOfSync *prometheus.GaugeVec
+
+	// Backend experiment metrics (control vs candidate backend)
+	ExperimentRequests *prometheus.CounterVec
+	ExperimentDuration *prometheus.HistogramVec
+
+	// Synthetic check metrics (canary downloads through Artifusion itself)
+	SyntheticChecks        *prometheus.CounterVec
+	SyntheticCheckDuration *prometheus.HistogramVec
+	SyntheticCheckUp       *prometheus.GaugeVec
 
 	// Rate limiting metrics
-	RateLimitExceeded *prometheus.CounterVec
+	RateLimitExceeded     *prometheus.CounterVec
+	RateLimitUserLimiters prometheus.Gauge
+
+	// Request queue metrics
+	QueueLength       *prometheus.GaugeVec
+	QueueWaitDuration *prometheus.HistogramVec
 
 	// Circuit breaker metrics
 	CircuitBreakerState *prometheus.GaugeVec
 
+	// Build and configuration metrics
+	BuildInfo      *prometheus.GaugeVec
+	FeatureEnabled *prometheus.GaugeVec
+
 	// Internal tracking
 	activeRequests atomic.Int32
 }
@@ -94,6 +162,31 @@ func NewMetrics(namespace string) *Metrics {
 			},
 		),
 
+		TimeoutHandlers: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "timeout_handler_goroutines",
+				Help:      "Number of handler goroutines started by the timeout middleware that are still running",
+			},
+		),
+
+		TimeoutAbandonedHandlers: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "timeout_abandoned_handler_goroutines",
+				Help:      "Number of handler goroutines still running after their request timed out",
+			},
+		),
+
+		RequestTimeouts: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "request_timeouts_total",
+				Help:      "Total number of requests that exceeded the request timeout, by whether the response had started",
+			},
+			[]string{"phase"}, // before_response, during_response
+		),
+
 		// Auth metrics
 		AuthCacheHits: promauto.NewCounter(
 			prometheus.CounterOpts{
@@ -119,6 +212,85 @@ func NewMetrics(namespace string) *Metrics {
 			},
 		),
 
+		AuthFailurePolicy: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "auth_github_unavailable_total",
+				Help:      "Total number of token validations GitHub could not answer, by failure policy decision",
+			},
+			[]string{"decision"}, // "fail_open" or "fail_closed"
+		),
+
+		CredentialSharing: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "auth_credential_sharing_total",
+				Help:      "Total number of requests using a token from more source IPs than allowed",
+			},
+			[]string{"action"}, // "alert" or "block"
+		),
+
+		AuthLockouts: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "auth_lockouts_total",
+				Help:      "Total number of lockouts after repeated failed authentications",
+			},
+			[]string{"key"}, // "ip" or "token"
+		),
+
+		AuthLockoutRejections: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "auth_lockout_rejected_total",
+				Help:      "Total number of requests rejected because their client IP or token was locked out",
+			},
+			[]string{"key"}, // "ip" or "token"
+		),
+
+		ContentPolicyDenials: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "content_policy_denied_total",
+				Help:      "Total number of requests denied by the content policy",
+			},
+			[]string{"protocol", "reason"}, // reason: "extension" or "content_type"
+		),
+
+		ClientRequests: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "client_requests_total",
+				Help:      "Total number of requests by client type",
+			},
+			[]string{"client", "protocol"}, // client: docker, containerd, npm, yarn, pnpm, maven, gradle or other
+		),
+
+		ClientPolicyDenials: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "client_policy_denied_total",
+				Help:      "Total number of requests denied by the client policy",
+			},
+			[]string{"client", "reason"}, // reason: "min_version" or "rule"
+		),
+
+		AuthCacheEvictions: promauto.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "auth_cache_evictions_total",
+				Help:      "Total number of auth cache entries removed by expiry or invalidation",
+			},
+		),
+
+		AuthCacheCoalesced: promauto.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "auth_cache_coalesced_total",
+				Help:      "Total number of auth cache misses served by a concurrent validation of the same token",
+			},
+		),
+
 		GitHubAPICalls: promauto.NewCounterVec(
 			prometheus.CounterOpts{
 				Namespace: namespace,
@@ -204,6 +376,166 @@ func NewMetrics(namespace string) *Metrics {
 			[]string{"backend", "state"}, // state: idle, active
 		),
 
+		ConnectionsAcquired: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "connections_acquired_total",
+				Help:      "Total number of backend connections acquired by requests",
+			},
+			[]string{"backend", "conn"}, // conn: new, reused
+		),
+
+		StreamAborts: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "backend_stream_aborts_total",
+				Help:      "Total number of backend response streams aborted by the stream watchdog",
+			},
+			[]string{"backend", "reason"}, // reason: max_duration, min_throughput
+		),
+
+		DigestMismatches: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "backend_content_digest_mismatches_total",
+				Help:      "Total number of backend responses whose body did not match their Content-Digest",
+			},
+			[]string{"backend"},
+		),
+
+		InFlightBytes: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "backend_in_flight_bytes",
+				Help:      "Bytes left to stream of the backend responses being transferred to clients",
+			},
+			[]string{"backend"},
+		),
+
+		CascadeDepth: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "cascade_depth",
+				Help:      "Number of pull backends a cascading read went through before it was answered",
+				Buckets:   prometheus.LinearBuckets(1, 1, 10),
+			},
+			[]string{"protocol", "result"}, // result: success, error, not_found
+		),
+
+		WriteBacks: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "write_back_total",
+				Help:      "Total number of artifacts fetched from a read-through upstream to publish into the backend",
+			},
+			[]string{"protocol", "result"}, // result: published, failed, dropped
+		),
+
+		ResponseCacheRequests: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "response_cache_requests_total",
+				Help:      "Total number of requests looked up in a protocol response cache",
+			},
+			[]string{"protocol", "result"}, // result: hit, miss
+		),
+
+		PlatformFilteredIndexes: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "oci_platform_filtered_indexes_total",
+				Help:      "Total number of OCI image indexes served filtered to the client's platforms",
+			},
+			[]string{"source"}, // source: client_hint, config
+		),
+
+		// Replication metrics
+		Replications: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "replication_total",
+				Help:      "Total number of manifests replicated to a secondary registry",
+			},
+			[]string{"target", "result"}, // result: replicated, failed, dropped
+		),
+
+		ReplicationLag: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "replication_lag_seconds",
+				Help:      "Time from a push (or its detection by reconciliation) until it was replicated",
+				Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12), // 0.5s to ~17m
+			},
+			[]string{"target"},
+		),
+
+		ReplicationQueue: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "replication_queue_length",
+				Help:      "Number of manifests waiting to be replicated",
+			},
+			[]string{"target"},
+		),
+
+		ReplicationOutOfSync: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "replication_out_of_sync_tags",
+				Help:      "Number of tags missing or different on the replication target at the last reconciliation",
+			},
+			[]string{"target"},
+		),
+
+		// Backend experiment metrics
+		ExperimentRequests: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "experiment_requests_total",
+				Help:      "Total number of read requests served by each arm of a backend experiment",
+			},
+			[]string{"protocol", "arm", "status"}, // arm: control, candidate
+		),
+
+		ExperimentDuration: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "experiment_request_duration_seconds",
+				Help:      "Backend latency of read requests for each arm of a backend experiment",
+				Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+			},
+			[]string{"protocol", "arm"},
+		),
+
+		// Synthetic check metrics
+		SyntheticChecks: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "synthetic_check_total",
+				Help:      "Total number of synthetic check runs",
+			},
+			[]string{"check", "result"}, // result: success, failure
+		),
+
+		SyntheticCheckDuration: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "synthetic_check_duration_seconds",
+				Help:      "Duration of synthetic check runs, including failed ones",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"check"},
+		),
+
+		SyntheticCheckUp: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "synthetic_check_up",
+				Help:      "Whether the last run of a synthetic check succeeded (1) or failed (0)",
+			},
+			[]string{"check"},
+		),
+
 		// Rate limiting metrics
 		RateLimitExceeded: promauto.NewCounterVec(
 			prometheus.CounterOpts{
@@ -214,6 +546,34 @@ func NewMetrics(namespace string) *Metrics {
 			[]string{"limit_type"}, // "global" or "per_user"
 		),
 
+		RateLimitUserLimiters: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "rate_limit_user_limiters",
+				Help:      "Number of per-user rate limiters currently tracked",
+			},
+		),
+
+		// Request queue metrics
+		QueueLength: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "queue_length",
+				Help:      "Number of requests currently waiting in a queue",
+			},
+			[]string{"queue"}, // "concurrency" or "rate_limit"
+		),
+
+		QueueWaitDuration: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "queue_wait_seconds",
+				Help:      "Time requests spent waiting in a queue",
+				Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+			},
+			[]string{"queue", "outcome"}, // outcome: admitted, timeout, canceled, full
+		),
+
 		// Circuit breaker metrics
 		CircuitBreakerState: promauto.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -223,6 +583,25 @@ func NewMetrics(namespace string) *Metrics {
 			},
 			[]string{"backend"},
 		),
+
+		// Build and configuration metrics
+		BuildInfo: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "build_info",
+				Help:      "Build information of the running binary (always 1)",
+			},
+			[]string{"version", "go_version", "commit"},
+		),
+
+		FeatureEnabled: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "feature_enabled",
+				Help:      "Whether an optional feature is enabled (0=disabled, 1=enabled)",
+			},
+			[]string{"feature"},
+		),
 	}
 
 	return m
@@ -261,6 +640,16 @@ func (m *Metrics) SetAuthCacheSize(size int) {
 	m.AuthCacheSize.Set(float64(size))
 }
 
+// RecordAuthCacheEviction records an auth cache entry being expired or invalidated
+func (m *Metrics) RecordAuthCacheEviction() {
+	m.AuthCacheEvictions.Inc()
+}
+
+// RecordAuthCacheCoalesced records an auth cache miss that waited on a concurrent validation
+func (m *Metrics) RecordAuthCacheCoalesced() {
+	m.AuthCacheCoalesced.Inc()
+}
+
 // RecordGitHubAPICall records a GitHub API call
 func (m *Metrics) RecordGitHubAPICall(endpoint string, statusCode int) {
 	m.GitHubAPICalls.WithLabelValues(endpoint, statusCodeToString(statusCode)).Inc()
@@ -291,6 +680,61 @@ func (m *Metrics) RecordRateLimitExceeded(limitType string) {
 	m.RateLimitExceeded.WithLabelValues(limitType).Inc()
 }
 
+// RecordAuthFailurePolicy records a decision made while GitHub was unavailable
+func (m *Metrics) RecordAuthFailurePolicy(decision string) {
+	m.AuthFailurePolicy.WithLabelValues(decision).Inc()
+}
+
+// RecordCredentialSharing records a request using a token from a source IP beyond
+// the limit, and whether it was blocked
+func (m *Metrics) RecordCredentialSharing(blocked bool) {
+	action := "alert"
+	if blocked {
+		action = "block"
+	}
+	m.CredentialSharing.WithLabelValues(action).Inc()
+}
+
+// RecordAuthLockout records a lockout of a client IP or token (key "ip" or "token")
+func (m *Metrics) RecordAuthLockout(key string) {
+	m.AuthLockouts.WithLabelValues(key).Inc()
+}
+
+// RecordAuthLockoutRejection records a request rejected by a lockout of key
+func (m *Metrics) RecordAuthLockoutRejection(key string) {
+	m.AuthLockoutRejections.WithLabelValues(key).Inc()
+}
+
+// RecordContentPolicyDenial records a request denied by the content policy
+func (m *Metrics) RecordContentPolicyDenial(protocol, reason string) {
+	m.ContentPolicyDenials.WithLabelValues(protocol, reason).Inc()
+}
+
+// RecordClientRequest records a request of a client type
+func (m *Metrics) RecordClientRequest(client, protocol string) {
+	m.ClientRequests.WithLabelValues(client, protocol).Inc()
+}
+
+// RecordClientPolicyDenial records a request denied by the client policy
+func (m *Metrics) RecordClientPolicyDenial(client, reason string) {
+	m.ClientPolicyDenials.WithLabelValues(client, reason).Inc()
+}
+
+// SetRateLimitUserLimiters sets the number of tracked per-user rate limiters
+func (m *Metrics) SetRateLimitUserLimiters(count int) {
+	m.RateLimitUserLimiters.Set(float64(count))
+}
+
+// SetQueueLength sets the number of requests waiting in a queue
+func (m *Metrics) SetQueueLength(queue string, length int) {
+	m.QueueLength.WithLabelValues(queue).Set(float64(length))
+}
+
+// RecordQueueWait records how long a request waited in a queue and how it left
+func (m *Metrics) RecordQueueWait(queue, outcome string, duration time.Duration) {
+	m.QueueWaitDuration.WithLabelValues(queue, outcome).Observe(duration.Seconds())
+}
+
 // SetCircuitBreakerState sets the circuit breaker state
 func (m *Metrics) SetCircuitBreakerState(backend string, state int) {
 	m.CircuitBreakerState.WithLabelValues(backend).Set(float64(state))
@@ -320,6 +764,108 @@ func (m *Metrics) SetConnectionPoolSize(backend, state string, size int) {
 	m.ConnectionPoolSize.WithLabelValues(backend, state).Set(float64(size))
 }
 
+// RecordConnectionAcquired records a request getting a new or reused backend connection
+func (m *Metrics) RecordConnectionAcquired(backend string, reused bool) {
+	conn := "new"
+	if reused {
+		conn = "reused"
+	}
+	m.ConnectionsAcquired.WithLabelValues(backend, conn).Inc()
+}
+
+// RecordStreamAbort records a backend response stream aborted by the stream watchdog
+func (m *Metrics) RecordStreamAbort(backend, reason string) {
+	m.StreamAborts.WithLabelValues(backend, reason).Inc()
+}
+
+// SetBackendInFlightBytes sets the bytes left to stream of a backend's responses
+// being transferred to clients
+func (m *Metrics) SetBackendInFlightBytes(backend string, bytes int64) {
+	m.InFlightBytes.WithLabelValues(backend).Set(float64(bytes))
+}
+
+// RecordDigestMismatch records a backend response body not matching its Content-Digest
+func (m *Metrics) RecordDigestMismatch(backend string) {
+	m.DigestMismatches.WithLabelValues(backend).Inc()
+}
+
+// RecordCascadeDepth records how many pull backends a cascading read went through
+func (m *Metrics) RecordCascadeDepth(protocol, result string, depth int) {
+	m.CascadeDepth.WithLabelValues(protocol, result).Observe(float64(depth))
+}
+
+// RecordWriteBack records the outcome of writing an upstream artifact back into the backend
+func (m *Metrics) RecordWriteBack(protocol, result string) {
+	m.WriteBacks.WithLabelValues(protocol, result).Inc()
+}
+
+// RecordResponseCache records a lookup in a protocol response cache
+func (m *Metrics) RecordResponseCache(protocol, result string) {
+	m.ResponseCacheRequests.WithLabelValues(protocol, result).Inc()
+}
+
+// RecordPlatformFilteredIndex records an image index served filtered to the platforms
+// from source
+func (m *Metrics) RecordPlatformFilteredIndex(source string) {
+	m.PlatformFilteredIndexes.WithLabelValues(source).Inc()
+}
+
+// RecordReplication records the outcome of replicating a manifest. lag is the time
+// since the push and is only recorded for replicated manifests.
+func (m *Metrics) RecordReplication(target, result string, lag time.Duration) {
+	m.Replications.WithLabelValues(target, result).Inc()
+	if result == "replicated" {
+		m.ReplicationLag.WithLabelValues(target).Observe(lag.Seconds())
+	}
+}
+
+// SetReplicationQueueLength records the number of manifests waiting to be replicated
+func (m *Metrics) SetReplicationQueueLength(target string, length int) {
+	m.ReplicationQueue.WithLabelValues(target).Set(float64(length))
+}
+
+// SetReplicationOutOfSync records the number of tags the last reconciliation found
+// missing or different on the replication target
+func (m *Metrics) SetReplicationOutOfSync(target string, tags int) {
+	m.ReplicationOutOfSync.WithLabelValues(target).Set(float64(tags))
+}
+
+// RecordExperimentRequest records a read request served by the control or candidate
+// backend of an experiment. statusCode 0 records a network error.
+func (m *Metrics) RecordExperimentRequest(protocol, arm string, statusCode int, duration time.Duration) {
+	status := "error"
+	if statusCode != 0 {
+		status = statusCodeToString(statusCode)
+	}
+	m.ExperimentRequests.WithLabelValues(protocol, arm, status).Inc()
+	m.ExperimentDuration.WithLabelValues(protocol, arm).Observe(duration.Seconds())
+}
+
+// RecordSyntheticCheck records the outcome and duration of a synthetic check run
+func (m *Metrics) RecordSyntheticCheck(check string, success bool, duration time.Duration) {
+	result, up := "failure", 0.0
+	if success {
+		result, up = "success", 1
+	}
+	m.SyntheticChecks.WithLabelValues(check, result).Inc()
+	m.SyntheticCheckDuration.WithLabelValues(check).Observe(duration.Seconds())
+	m.SyntheticCheckUp.WithLabelValues(check).Set(up)
+}
+
+// SetBuildInfo records the version and commit of the running binary
+func (m *Metrics) SetBuildInfo(version, commit string) {
+	m.BuildInfo.WithLabelValues(version, runtime.Version(), commit).Set(1)
+}
+
+// SetFeatureEnabled records whether an optional feature is enabled
+func (m *Metrics) SetFeatureEnabled(feature string, enabled bool) {
+	value := 0.0
+	if enabled {
+		value = 1
+	}
+	m.FeatureEnabled.WithLabelValues(feature).Set(value)
+}
+
 // statusCodeToString converts status code to string
 func statusCodeToString(code int) string {
 	if code >= 200 && code < 300 {
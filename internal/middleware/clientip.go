@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/utils"
+)
+
+// ClientIPKey is the context key for the client IP resolved with trusted proxies
+const ClientIPKey ContextKey = "client_ip"
+
+// ClientIP resolves each request's client IP with proxies and adds it to the request
+// context, for security decisions to read with TrustedClientIP
+func ClientIP(proxies *utils.TrustedProxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), ClientIPKey, proxies.ClientIP(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// TrustedClientIP returns the client IP of r resolved by the ClientIP middleware.
+// Unlike utils.GetClientIP, it can't be chosen by the client. Requests that didn't
+// pass through the middleware get their connection's peer address.
+func TrustedClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(ClientIPKey).(string); ok {
+		return ip
+	}
+	return (*utils.TrustedProxies)(nil).ClientIP(r)
+}
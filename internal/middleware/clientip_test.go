@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/utils"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := utils.NewTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	handler := ClientIP(proxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = TrustedClientIP(r)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.5:1234"
+	r.Header.Set("X-Forwarded-For", "192.0.2.1, 203.0.113.1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if got != "203.0.113.1" {
+		t.Errorf("TrustedClientIP() = %q, want the address appended by the proxy", got)
+	}
+
+	// Without the middleware, forwarding headers are not trusted
+	if got := TrustedClientIP(r); got != "10.0.0.5" {
+		t.Errorf("TrustedClientIP() without middleware = %q, want the peer address", got)
+	}
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/useragent"
+	"github.com/rs/zerolog"
+)
+
+// Client policy denial reasons, used as the reason label of the denial metric
+const (
+	clientPolicyMinVersion = "min_version"
+	clientPolicyRule       = "rule"
+)
+
+// compiledClientRule is a compiled config.ClientPolicyRule
+type compiledClientRule struct {
+	protocol    string                 // empty matches all protocols
+	constraints []useragent.Constraint // empty matches all versions
+	message     string
+}
+
+// matches reports whether the rule denies client's requests of protocol
+func (rule compiledClientRule) matches(client useragent.Client, protocol string) bool {
+	if rule.protocol != "" && rule.protocol != protocol {
+		return false
+	}
+	if len(rule.constraints) == 0 {
+		return true
+	}
+	for _, constraint := range rule.constraints {
+		if constraint.Matches(client.Version) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientPolicy classifies the client of each request by its User-Agent, counting
+// requests per client type, asks clients older than their minimum version to
+// upgrade, and denies the clients the configured rules match.
+//
+// Thread safety: All methods are safe for concurrent use.
+type ClientPolicy struct {
+	rules          map[string][]compiledClientRule // by client type
+	minVersions    map[string]string               // by client type
+	upgradeMessage string
+	metrics        *metrics.Metrics
+	logger         zerolog.Logger
+}
+
+// NewClientPolicy compiles the rules of a validated cfg, which only apply if it is
+// enabled; m may be nil
+func NewClientPolicy(cfg *config.ClientPolicyConfig, m *metrics.Metrics, logger zerolog.Logger) *ClientPolicy {
+	p := &ClientPolicy{
+		rules:   make(map[string][]compiledClientRule),
+		metrics: m,
+		logger:  logger,
+	}
+	if !cfg.Enabled {
+		return p
+	}
+	p.minVersions = cfg.MinVersions
+	p.upgradeMessage = cfg.UpgradeMessage
+	for _, rule := range cfg.Rules {
+		compiled := compiledClientRule{protocol: rule.Protocol, message: rule.Message}
+		for _, versions := range rule.DenyVersions {
+			constraint, _ := useragent.ParseConstraint(versions) // Validated
+			compiled.constraints = append(compiled.constraints, constraint)
+		}
+		p.rules[rule.Client] = append(p.rules[rule.Client], compiled)
+	}
+	return p
+}
+
+// Enforce classifies the client of r, a request of protocol, adding it to the
+// request's log line. If the client is older than its minimum version it answers
+// with a 400 explaining the required upgrade, and if a rule denies the client with a
+// 403, and returns false.
+func (p *ClientPolicy) Enforce(w http.ResponseWriter, r *http.Request, protocol string) bool {
+	client := useragent.Parse(r.UserAgent())
+	AddLogField(r.Context(), "client", client.String())
+	if p.metrics != nil {
+		p.metrics.RecordClientRequest(client.Type, protocol)
+	}
+
+	if minVersion, ok := p.minVersions[client.Type]; ok && client.Version != "" &&
+		useragent.CompareVersions(client.Version, minVersion) < 0 {
+		p.deny(r, client, protocol, clientPolicyMinVersion)
+
+		message := fmt.Sprintf("%s %s is no longer supported, please upgrade to %s %s or later",
+			client.Type, client.Version, client.Type, minVersion)
+		if p.upgradeMessage != "" {
+			message += ". " + p.upgradeMessage
+		}
+		errors.ErrorResponse(w, errors.ErrClientUpgradeRequired.WithMessage(message))
+		return false
+	}
+
+	for _, rule := range p.rules[client.Type] {
+		if !rule.matches(client, protocol) {
+			continue
+		}
+		p.deny(r, client, protocol, clientPolicyRule)
+
+		message := "Requests from " + client.String() + " are not allowed"
+		if rule.message != "" {
+			message += ": " + rule.message
+		}
+		errors.ErrorResponse(w, errors.ErrForbidden.WithMessage(message))
+		return false
+	}
+	return true
+}
+
+// deny records a request denied for reason
+func (p *ClientPolicy) deny(r *http.Request, client useragent.Client, protocol, reason string) {
+	if p.metrics != nil {
+		p.metrics.RecordClientPolicyDenial(client.Type, reason)
+	}
+	p.logger.Warn().
+		Str("client", client.String()).
+		Str("protocol", protocol).
+		Str("path", r.URL.Path).
+		Str("reason", reason).
+		Str("request_id", GetRequestID(r.Context())).
+		Msg("Request denied by client policy")
+}
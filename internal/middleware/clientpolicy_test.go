@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func TestClientPolicy_Enforce(t *testing.T) {
+	cfg := &config.ClientPolicyConfig{
+		Enabled: true,
+		Rules: []config.ClientPolicyRule{
+			{Client: "npm", DenyVersions: []string{"<6.14.6", ">=7.0.0 <7.1.2"}, Message: "upgrade npm"},
+			{Client: "yarn", Protocol: "maven"},
+		},
+		MinVersions:    map[string]string{"docker": "20.10.0", "npm": "6.0.0"},
+		UpgradeMessage: "See https://wiki.example.com/upgrade",
+	}
+
+	tests := []struct {
+		name       string
+		userAgent  string
+		protocol   string
+		wantOK     bool
+		wantStatus int
+	}{
+		{name: "docker below min version", userAgent: "docker/19.03.15 go/go1.13.15", protocol: "oci", wantStatus: http.StatusBadRequest},
+		{name: "docker at min version", userAgent: "docker/20.10.0 go/go1.13.15", protocol: "oci", wantOK: true},
+		{name: "npm below min version and denied", userAgent: "npm/5.6.0 node/v8.17.0", protocol: "npm", wantStatus: http.StatusBadRequest},
+		{name: "docker without version", userAgent: "docker", protocol: "oci", wantOK: true},
+		{name: "vulnerable npm", userAgent: "npm/6.14.5 node/v12.22.12 linux x64", protocol: "npm"},
+		{name: "vulnerable npm range", userAgent: "npm/7.1.1 node/v14.21.3 linux x64", protocol: "npm"},
+		{name: "current npm", userAgent: "npm/10.2.4 node/v20.11.0 darwin arm64", protocol: "npm", wantOK: true},
+		{name: "npm without version", userAgent: "npm", protocol: "npm", wantOK: true},
+		{name: "yarn on denied protocol", userAgent: "yarn/1.22.19 npm/? node/v18.17.1", protocol: "maven"},
+		{name: "yarn on other protocol", userAgent: "yarn/1.22.19 npm/? node/v18.17.1", protocol: "npm", wantOK: true},
+		{name: "unrecognized client", userAgent: "curl/8.4.0", protocol: "npm", wantOK: true},
+	}
+
+	policy := NewClientPolicy(cfg, nil, zerolog.Nop())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/npm/express", nil)
+			r.Header.Set("User-Agent", tt.userAgent)
+			rec := httptest.NewRecorder()
+
+			if ok := policy.Enforce(rec, r, tt.protocol); ok != tt.wantOK {
+				t.Fatalf("Enforce() = %v, want %v", ok, tt.wantOK)
+			}
+			if tt.wantStatus == 0 {
+				tt.wantStatus = http.StatusForbidden
+			}
+			if !tt.wantOK && rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	t.Run("message", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/npm/express", nil)
+		r.Header.Set("User-Agent", "npm/6.14.5 node/v12.22.12 linux x64")
+		rec := httptest.NewRecorder()
+		policy.Enforce(rec, r, "npm")
+		if body := rec.Body.String(); !strings.Contains(body, "npm/6.14.5") || !strings.Contains(body, "upgrade npm") {
+			t.Errorf("body = %s, want the client and rule message", body)
+		}
+	})
+
+	t.Run("upgrade message", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/v2/", nil)
+		r.Header.Set("User-Agent", "docker/19.03.15 go/go1.13.15")
+		rec := httptest.NewRecorder()
+		policy.Enforce(rec, r, "oci")
+		body := rec.Body.String()
+		for _, want := range []string{"CLIENT_UPGRADE_REQUIRED", "docker 19.03.15 is no longer supported, please upgrade to docker 20.10.0 or later", "https://wiki.example.com/upgrade"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("body = %s, want %q", body, want)
+			}
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		disabled := *cfg
+		disabled.Enabled = false
+		r := httptest.NewRequest(http.MethodGet, "/npm/express", nil)
+		r.Header.Set("User-Agent", "npm/6.14.5 node/v12.22.12 linux x64")
+		if !NewClientPolicy(&disabled, nil, zerolog.Nop()).Enforce(httptest.NewRecorder(), r, "npm") {
+			t.Error("Enforce() denied a request with the policy disabled")
+		}
+	})
+}
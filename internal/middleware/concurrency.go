@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"sync/atomic"
+	"time"
 
 	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/metrics"
 )
 
 // ConcurrencyLimiter limits the number of concurrent requests using a semaphore pattern
@@ -12,6 +15,11 @@ type ConcurrencyLimiter struct {
 	semaphore     chan struct{}
 	maxConcurrent int
 	active        atomic.Int32 // Track active requests for metrics
+
+	// Optional bounded queue for requests over the limit (disabled if queueTimeout is 0)
+	queueTimeout time.Duration
+	maxQueued    int
+	queue        requestQueue
 }
 
 // NewConcurrencyLimiter creates a new concurrency limiter
@@ -19,35 +27,89 @@ func NewConcurrencyLimiter(maxConcurrent int) *ConcurrencyLimiter {
 	return &ConcurrencyLimiter{
 		semaphore:     make(chan struct{}, maxConcurrent),
 		maxConcurrent: maxConcurrent,
+		queue:         requestQueue{name: queueConcurrency},
 	}
 }
 
+// EnableQueue makes requests over the limit wait up to timeout for a free slot instead
+// of being rejected immediately. At most maxQueued requests wait at once (0 = unbounded);
+// further requests are rejected. m may be nil to disable queue metrics.
+//
+// Must be called before the limiter is used.
+func (cl *ConcurrencyLimiter) EnableQueue(timeout time.Duration, maxQueued int, m *metrics.Metrics) {
+	cl.queueTimeout = timeout
+	cl.maxQueued = maxQueued
+	cl.queue.metrics = m
+}
+
 // Middleware returns a middleware handler that limits concurrent requests
 func (cl *ConcurrencyLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		select {
 		case cl.semaphore <- struct{}{}:
 			// Successfully acquired semaphore slot
-			cl.active.Add(1)
-			defer func() {
-				<-cl.semaphore // Release semaphore slot
-				cl.active.Add(-1)
-			}()
-
-			next.ServeHTTP(w, r)
 
 		default:
-			// Semaphore full, reject request
-			errors.ErrorResponse(w, errors.ErrTooManyConcurrentRequests)
+			// Semaphore full: wait in the queue if enabled, otherwise reject
+			if cl.queueTimeout <= 0 || !cl.acquireQueued(r.Context()) {
+				errors.ErrorResponse(w, errors.ErrTooManyConcurrentRequests)
+				return
+			}
 		}
+
+		cl.active.Add(1)
+		defer func() {
+			<-cl.semaphore // Release semaphore slot
+			cl.active.Add(-1)
+		}()
+
+		next.ServeHTTP(w, r)
 	})
 }
 
+// acquireQueued waits for a semaphore slot until the queue timeout or the request's
+// deadline, whichever comes first. Returns true if a slot was acquired.
+func (cl *ConcurrencyLimiter) acquireQueued(ctx context.Context) bool {
+	start := time.Now()
+	length := cl.queue.enter()
+
+	if cl.maxQueued > 0 && int(length) > cl.maxQueued {
+		cl.queue.leave(queueOutcomeFull, 0)
+		return false
+	}
+
+	wait := maxQueueWait(ctx, cl.queueTimeout)
+	if wait <= 0 {
+		cl.queue.leave(queueOutcomeTimeout, 0)
+		return false
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case cl.semaphore <- struct{}{}:
+		cl.queue.leave(queueOutcomeAdmitted, time.Since(start))
+		return true
+	case <-timer.C:
+		cl.queue.leave(queueOutcomeTimeout, time.Since(start))
+		return false
+	case <-ctx.Done():
+		cl.queue.leave(queueOutcomeCanceled, time.Since(start))
+		return false
+	}
+}
+
 // ActiveRequests returns the current number of active requests
 func (cl *ConcurrencyLimiter) ActiveRequests() int32 {
 	return cl.active.Load()
 }
 
+// QueuedRequests returns the current number of requests waiting for a slot
+func (cl *ConcurrencyLimiter) QueuedRequests() int32 {
+	return cl.queue.length.Load()
+}
+
 // MaxConcurrent returns the maximum allowed concurrent requests
 func (cl *ConcurrencyLimiter) MaxConcurrent() int {
 	return cl.maxConcurrent
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingHandler holds requests until release is closed
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// TestConcurrencyLimiter_RejectsWithoutQueue tests immediate rejection over the limit
+func TestConcurrencyLimiter_RejectsWithoutQueue(t *testing.T) {
+	cl := NewConcurrencyLimiter(1)
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	handler := cl.Middleware(blockingHandler(started, release))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/test", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", rec.Code)
+	}
+
+	close(release)
+	wg.Wait()
+}
+
+// TestConcurrencyLimiter_QueueAdmits tests that a queued request is admitted when a slot frees up
+func TestConcurrencyLimiter_QueueAdmits(t *testing.T) {
+	cl := NewConcurrencyLimiter(1)
+	cl.EnableQueue(2*time.Second, 10, nil)
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	handler := cl.Middleware(blockingHandler(started, release))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/test", nil))
+		close(done)
+	}()
+
+	// Wait for the second request to be queued, then free the slot
+	deadline := time.Now().Add(time.Second)
+	for cl.QueuedRequests() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if cl.QueuedRequests() != 1 {
+		t.Fatalf("expected 1 queued request, got %d", cl.QueuedRequests())
+	}
+	close(release)
+
+	<-done
+	wg.Wait()
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected queued request to succeed, got %d", rec.Code)
+	}
+	if cl.QueuedRequests() != 0 {
+		t.Errorf("expected empty queue, got %d", cl.QueuedRequests())
+	}
+}
+
+// TestConcurrencyLimiter_QueueBounds tests queue timeout, queue length and deadline handling
+func TestConcurrencyLimiter_QueueBounds(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		maxQueued int
+		queued    int32         // requests already waiting
+		ctxTTL    time.Duration // request deadline (0 = none)
+		maxWait   time.Duration
+	}{
+		{name: "queue timeout", timeout: 50 * time.Millisecond, maxQueued: 10, maxWait: time.Second},
+		{name: "queue full", timeout: 10 * time.Second, maxQueued: 1, queued: 1, maxWait: 100 * time.Millisecond},
+		{name: "request deadline shorter than queue timeout", timeout: 10 * time.Second, maxQueued: 10, ctxTTL: 50 * time.Millisecond, maxWait: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := NewConcurrencyLimiter(1)
+			cl.EnableQueue(tt.timeout, tt.maxQueued, nil)
+			// Fill the only slot
+			cl.semaphore <- struct{}{}
+			defer func() { <-cl.semaphore }()
+			cl.queue.length.Store(tt.queued)
+
+			handler := cl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.ctxTTL > 0 {
+				ctx, cancel := context.WithTimeout(req.Context(), tt.ctxTTL)
+				defer cancel()
+				req = req.WithContext(ctx)
+			}
+
+			start := time.Now()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Errorf("expected 503, got %d", rec.Code)
+			}
+			if elapsed := time.Since(start); elapsed > tt.maxWait {
+				t.Errorf("request waited %v, expected at most %v", elapsed, tt.maxWait)
+			}
+			if cl.QueuedRequests() != tt.queued {
+				t.Errorf("expected %d queued requests, got %d", tt.queued, cl.QueuedRequests())
+			}
+		})
+	}
+}
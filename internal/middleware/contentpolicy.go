@@ -0,0 +1,266 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/rs/zerolog"
+)
+
+// Content policy denial reasons, used as the reason label of the denial metric
+const (
+	contentPolicyExtension   = "extension"
+	contentPolicyContentType = "content_type"
+)
+
+// errContentDenied is returned by writes of a response body replaced by the content
+// policy, so streaming stops instead of draining the backend
+var errContentDenied = fmt.Errorf("response content type denied by content policy")
+
+// contentPolicyRule is a compiled config.ContentPolicyRule
+type contentPolicyRule struct {
+	path *regexp.Regexp // nil matches all paths
+	cfg  config.ContentPolicyRule
+}
+
+// ContentPolicy enforces the configured allow/deny rules on the file extensions
+// requested and the content types served per protocol.
+//
+// Thread safety: All methods are safe for concurrent use.
+type ContentPolicy struct {
+	rules      map[string][]contentPolicyRule // by protocol
+	clientAuth map[string]*config.ClientAuthConfig
+	metrics    *metrics.Metrics
+	logger     zerolog.Logger
+}
+
+// NewContentPolicy compiles the rules of a validated cfg; m may be nil
+func NewContentPolicy(cfg *config.ContentPolicyConfig, m *metrics.Metrics, logger zerolog.Logger) *ContentPolicy {
+	p := &ContentPolicy{
+		rules:   make(map[string][]contentPolicyRule),
+		metrics: m,
+		logger:  logger,
+	}
+	for _, rule := range cfg.Rules {
+		compiled := contentPolicyRule{cfg: rule}
+		if rule.Path != "" {
+			compiled.path = regexp.MustCompile(rule.Path)
+		}
+		p.rules[rule.Protocol] = append(p.rules[rule.Protocol], compiled)
+	}
+	return p
+}
+
+// SetErrorHelp appends the forbidden guidance of each protocol's client auth
+// configuration (keyed by protocol) to its denials.
+// Must be called before the policy is used concurrently.
+func (p *ContentPolicy) SetErrorHelp(clientAuth map[string]*config.ClientAuthConfig) {
+	p.clientAuth = clientAuth
+}
+
+// Enforce applies the rules of protocol to r. If the requested extension is denied
+// it answers with a 403 and returns false; otherwise it returns a writer to serve r
+// through, which replaces successful responses of a denied content type with a 403.
+func (p *ContentPolicy) Enforce(w http.ResponseWriter, r *http.Request, protocol string) (http.ResponseWriter, bool) {
+	rules := p.matchingRules(protocol, r.URL.Path)
+	if len(rules) == 0 {
+		return w, true
+	}
+
+	if !rulesAllowExtension(rules, r.URL.Path) {
+		p.deny(w, r, protocol, contentPolicyExtension, "")
+		return w, false
+	}
+
+	return &contentPolicyWriter{
+		ResponseWriter: w,
+		policy:         p,
+		rules:          rules,
+		r:              r,
+		protocol:       protocol,
+		header:         w.Header().Clone(),
+	}, true
+}
+
+// CheckExtension reports whether any rule of protocol applies to requestPath and,
+// if so, whether they allow its extension, for dry-run access checks. Content
+// types are only known once a backend answers, so they are not checked.
+func (p *ContentPolicy) CheckExtension(protocol, requestPath string) (applies, allowed bool) {
+	rules := p.matchingRules(protocol, requestPath)
+	if len(rules) == 0 {
+		return false, true
+	}
+	return true, rulesAllowExtension(rules, requestPath)
+}
+
+// matchingRules returns the rules of protocol applying to requestPath
+func (p *ContentPolicy) matchingRules(protocol, requestPath string) []contentPolicyRule {
+	var rules []contentPolicyRule
+	for _, rule := range p.rules[protocol] {
+		if rule.path == nil || rule.path.MatchString(requestPath) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// rulesAllowExtension reports whether every one of rules allows serving requestPath
+func rulesAllowExtension(rules []contentPolicyRule, requestPath string) bool {
+	for _, rule := range rules {
+		if !extensionAllowed(rule.cfg, requestPath) {
+			return false
+		}
+	}
+	return true
+}
+
+// deny records a denied request and answers it with a 403
+func (p *ContentPolicy) deny(w http.ResponseWriter, r *http.Request, protocol, reason, contentType string) {
+	if p.metrics != nil {
+		p.metrics.RecordContentPolicyDenial(protocol, reason)
+	}
+	p.logger.Warn().
+		Str("protocol", protocol).
+		Str("path", r.URL.Path).
+		Str("reason", reason).
+		Str("content_type", contentType).
+		Str("request_id", GetRequestID(r.Context())).
+		Msg("Request denied by content policy")
+
+	message := "File type not served by this repository"
+	if reason == contentPolicyContentType {
+		message = "Content type not served by this repository"
+	}
+	if clientAuth := p.clientAuth[protocol]; clientAuth != nil {
+		message = clientAuth.ForbiddenMessage(message)
+	}
+	errors.ErrorResponse(w, errors.ErrForbidden.WithMessage(message))
+}
+
+// extensionAllowed reports whether rule allows serving requestPath
+func extensionAllowed(rule config.ContentPolicyRule, requestPath string) bool {
+	name := strings.ToLower(path.Base(requestPath))
+	hasExtension := func(extensions []string) bool {
+		for _, ext := range extensions {
+			if strings.HasSuffix(name, strings.ToLower(ext)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if hasExtension(rule.DenyExtensions) {
+		return false
+	}
+	return len(rule.AllowExtensions) == 0 || hasExtension(rule.AllowExtensions)
+}
+
+// contentTypeAllowed reports whether rule allows serving a response of contentType
+// (the Content-Type header, empty if none)
+func contentTypeAllowed(rule config.ContentPolicyRule, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = ""
+	}
+	matches := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(strings.ToLower(pattern), mediaType); matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	if mediaType != "" && matches(rule.DenyContentTypes) {
+		return false
+	}
+	return len(rule.AllowContentTypes) == 0 || (mediaType != "" && matches(rule.AllowContentTypes))
+}
+
+// contentPolicyWriter checks the content type of a response when its status is
+// written, replacing denied successful responses with a 403
+type contentPolicyWriter struct {
+	http.ResponseWriter
+	policy   *ContentPolicy
+	rules    []contentPolicyRule
+	r        *http.Request
+	protocol string
+	header   http.Header // headers set before the handler ran
+
+	wroteHeader bool
+	denied      bool
+}
+
+func (cw *contentPolicyWriter) WriteHeader(status int) {
+	// Informational responses precede the final one
+	if status < 200 && !cw.wroteHeader {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	if status >= 200 && status < 300 {
+		contentType := cw.Header().Get("Content-Type")
+		for _, rule := range cw.rules {
+			if !contentTypeAllowed(rule.cfg, contentType) {
+				cw.denied = true
+				// Drop the headers set by the handler, which describe the denied body
+				for key := range cw.Header() {
+					if _, preset := cw.header[key]; !preset {
+						cw.Header().Del(key)
+					}
+				}
+				cw.policy.deny(cw.ResponseWriter, cw.r, cw.protocol, contentPolicyContentType, contentType)
+				return
+			}
+		}
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *contentPolicyWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.denied {
+		return 0, errContentDenied
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes through unless the response was denied
+func (cw *contentPolicyWriter) Flush() {
+	if cw.denied {
+		return
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack passes connection takeovers through for upgraded connections, which carry
+// no content type
+func (cw *contentPolicyWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (cw *contentPolicyWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func TestContentPolicy_Enforce(t *testing.T) {
+	cfg := &config.ContentPolicyConfig{
+		Enabled: true,
+		Rules: []config.ContentPolicyRule{
+			{Protocol: "maven", DenyExtensions: []string{".exe", ".MSI"}},
+			{Protocol: "apt", AllowExtensions: []string{".deb", ".gz", ".xz"}},
+			{
+				Protocol:          "oci",
+				Path:              "^/v2/.+/manifests/",
+				AllowContentTypes: []string{"application/json", "application/*+json"},
+			},
+			{Protocol: "npm", DenyContentTypes: []string{"text/html"}},
+		},
+	}
+	policy := NewContentPolicy(cfg, nil, zerolog.Nop())
+
+	tests := []struct {
+		name        string
+		protocol    string
+		path        string
+		status      int
+		contentType string
+		wantStatus  int
+		wantServed  bool // handler reached
+	}{
+		{name: "allowed extension", protocol: "maven", path: "/maven/com/example/app/1.0/app-1.0.jar", status: 200, contentType: "application/java-archive", wantStatus: 200, wantServed: true},
+		{name: "denied extension", protocol: "maven", path: "/maven/tools/setup.exe", wantStatus: 403},
+		{name: "denied extension, case-insensitive", protocol: "maven", path: "/maven/tools/Setup.msi", wantStatus: 403},
+		{name: "other protocol unaffected", protocol: "helm", path: "/helm/setup.exe", status: 200, contentType: "application/octet-stream", wantStatus: 200, wantServed: true},
+		{name: "allow list", protocol: "apt", path: "/apt/pool/main/c/curl/curl_8.5.0_amd64.deb", status: 200, contentType: "application/vnd.debian.binary-package", wantStatus: 200, wantServed: true},
+		{name: "outside allow list", protocol: "apt", path: "/apt/pool/main/tool.zip", wantStatus: 403},
+		{name: "json manifest", protocol: "oci", path: "/v2/library/alpine/manifests/latest", status: 200, contentType: "application/vnd.oci.image.manifest.v1+json", wantStatus: 200, wantServed: true},
+		{name: "non-json manifest", protocol: "oci", path: "/v2/library/alpine/manifests/latest", status: 200, contentType: "application/octet-stream", wantStatus: 403, wantServed: true},
+		{name: "manifest without content type", protocol: "oci", path: "/v2/library/alpine/manifests/latest", status: 200, wantStatus: 403, wantServed: true},
+		{name: "manifest error passes", protocol: "oci", path: "/v2/library/alpine/manifests/latest", status: 404, contentType: "text/plain", wantStatus: 404, wantServed: true},
+		{name: "blob outside path", protocol: "oci", path: "/v2/library/alpine/blobs/sha256:abc", status: 200, contentType: "application/octet-stream", wantStatus: 200, wantServed: true},
+		{name: "denied content type", protocol: "npm", path: "/npm/express", status: 200, contentType: "text/html; charset=utf-8", wantStatus: 403, wantServed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			rec.Header().Set("X-Content-Type-Options", "nosniff")
+
+			served := false
+			w, ok := policy.Enforce(rec, r, tt.protocol)
+			if ok {
+				served = true
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.Header().Set("ETag", `"backend"`)
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, "backend body")
+			}
+
+			if served != tt.wantServed {
+				t.Errorf("handler reached = %v, want %v", served, tt.wantServed)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+				t.Error("header set before the handler was dropped")
+			}
+			if tt.wantStatus == http.StatusForbidden {
+				if strings.Contains(rec.Body.String(), "backend body") || rec.Header().Get("ETag") != "" {
+					t.Errorf("denied response leaked backend content: %q, headers %v", rec.Body.String(), rec.Header())
+				}
+			}
+		})
+	}
+}
+
+func TestContentPolicy_ErrorHelp(t *testing.T) {
+	cfg := &config.ContentPolicyConfig{
+		Enabled: true,
+		Rules:   []config.ContentPolicyRule{{Protocol: "maven", DenyExtensions: []string{".exe"}}},
+	}
+	policy := NewContentPolicy(cfg, nil, zerolog.Nop())
+	policy.SetErrorHelp(map[string]*config.ClientAuthConfig{
+		"maven": {ErrorHelp: config.ErrorHelpConfig{Forbidden: "Ask #build-infra to allow new file types."}},
+	})
+
+	rec := httptest.NewRecorder()
+	if _, ok := policy.Enforce(rec, httptest.NewRequest(http.MethodGet, "/maven/tools/setup.exe", nil), "maven"); ok {
+		t.Fatal("Enforce() allowed a denied extension")
+	}
+	if want := "File type not served by this repository. Ask #build-infra to allow new file types."; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("body = %q, want it to contain %q", rec.Body.String(), want)
+	}
+}
+
+func TestContentPolicy_CheckExtension(t *testing.T) {
+	policy := NewContentPolicy(&config.ContentPolicyConfig{
+		Enabled: true,
+		Rules: []config.ContentPolicyRule{
+			{Protocol: "maven", DenyExtensions: []string{".exe"}},
+			{Protocol: "oci", Path: "^/v2/.+/manifests/", AllowContentTypes: []string{"application/json"}},
+		},
+	}, nil, zerolog.Nop())
+
+	tests := []struct {
+		name        string
+		protocol    string
+		path        string
+		wantApplies bool
+		wantAllowed bool
+	}{
+		{name: "allowed extension", protocol: "maven", path: "/maven/com/example/app/1.0/app-1.0.jar", wantApplies: true, wantAllowed: true},
+		{name: "denied extension", protocol: "maven", path: "/maven/tools/setup.exe", wantApplies: true},
+		{name: "content types only", protocol: "oci", path: "/v2/library/alpine/manifests/latest", wantApplies: true, wantAllowed: true},
+		{name: "outside path", protocol: "oci", path: "/v2/library/alpine/blobs/sha256:abc", wantAllowed: true},
+		{name: "other protocol", protocol: "helm", path: "/helm/setup.exe", wantAllowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applies, allowed := policy.CheckExtension(tt.protocol, tt.path)
+			if applies != tt.wantApplies || allowed != tt.wantAllowed {
+				t.Errorf("CheckExtension() = %v, %v, want %v, %v", applies, allowed, tt.wantApplies, tt.wantAllowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/utils"
+)
+
+// errRequestCanceled is the cause of the context of requests canceled with
+// InFlightTracker.Cancel
+var errRequestCanceled = fmt.Errorf("request canceled by an administrator")
+
+// InFlightRequest is a snapshot of a request being served
+type InFlightRequest struct {
+	ID             string    `json:"id"` // Unique among requests served since startup
+	RequestID      string    `json:"request_id"`
+	Method         string    `json:"method"`
+	Path           string    `json:"path"`
+	ClientIP       string    `json:"client_ip"`
+	User           string    `json:"user,omitempty"`    // Empty until authenticated
+	Backend        string    `json:"backend,omitempty"` // Empty until routed to a backend
+	BytesReceived  int64     `json:"bytes_received"`    // Request body read so far
+	BytesSent      int64     `json:"bytes_sent"`        // Response body written so far
+	StartedAt      time.Time `json:"started_at"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+}
+
+// inFlightRequest is the tracker's live state of a request
+type inFlightRequest struct {
+	id        string
+	requestID string
+	method    string
+	path      string
+	clientIP  string
+	fields    *LogFields // nil for requests not logged by the Logger middleware
+	received  atomic.Int64
+	sent      atomic.Int64
+	startedAt time.Time
+
+	cancel      context.CancelCauseFunc
+	writer      *countingWriter
+	headersSent atomic.Bool // Whether the response had started when canceled
+}
+
+// InFlightTracker keeps track of the requests being served, with the user and
+// backend handlers attached to their log lines and the bytes transferred so far, so
+// operators can see what is stuck during incidents. It must run after the Logger
+// middleware to see users and backends, and after the Timeout middleware so that
+// canceled requests are answered by the tracker rather than abandoned.
+type InFlightTracker struct {
+	mu       sync.Mutex
+	requests map[string]*inFlightRequest
+	nextID   atomic.Uint64
+}
+
+// NewInFlightTracker creates a new in-flight request tracker
+func NewInFlightTracker() *InFlightTracker {
+	return &InFlightTracker{requests: make(map[string]*inFlightRequest)}
+}
+
+// Middleware returns a middleware handler that tracks requests while they are served
+func (t *InFlightTracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancelCause(r.Context())
+		defer cancel(nil)
+		r = r.WithContext(ctx)
+
+		req := &inFlightRequest{
+			id:        strconv.FormatUint(t.nextID.Add(1), 10),
+			requestID: GetRequestID(ctx),
+			method:    r.Method,
+			path:      r.URL.Path,
+			clientIP:  utils.GetClientIP(r),
+			startedAt: time.Now(),
+			cancel:    cancel,
+			writer:    &countingWriter{ResponseWriter: w},
+		}
+		req.writer.count = &req.sent
+		req.fields, _ = ctx.Value(logFieldsKey).(*LogFields)
+
+		t.mu.Lock()
+		t.requests[req.id] = req
+		t.mu.Unlock()
+		defer func() {
+			t.mu.Lock()
+			delete(t.requests, req.id)
+			t.mu.Unlock()
+		}()
+
+		// Requests without a body keep http.NoBody, which clients don't send
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &countingBody{ReadCloser: r.Body, count: &req.received}
+		}
+
+		next.ServeHTTP(req.writer, r)
+
+		if context.Cause(ctx) != errRequestCanceled {
+			return
+		}
+		if req.headersSent.Load() {
+			// Close the connection so the client sees the response is incomplete
+			panic(http.ErrAbortHandler)
+		}
+		// The rest of the request body may be unread
+		w.Header().Set("Connection", "close")
+		errors.ErrorResponse(w, errors.ErrRequestCanceled)
+	})
+}
+
+// Cancel cancels the context of the in-flight request with the given ID, making
+// its backend requests fail. Whatever the handler writes afterwards is discarded:
+// the client gets a REQUEST_CANCELED error, or the connection is closed if the
+// response had already started. It returns the request as it was when canceled and
+// false if no request with the ID is in flight.
+func (t *InFlightTracker) Cancel(id string) (InFlightRequest, bool) {
+	t.mu.Lock()
+	req, ok := t.requests[id]
+	t.mu.Unlock()
+	if !ok {
+		return InFlightRequest{}, false
+	}
+
+	snapshot := req.snapshot(time.Now())
+	req.headersSent.Store(req.writer.cancel())
+	req.cancel(errRequestCanceled)
+	return snapshot, true
+}
+
+// Requests returns a snapshot of the requests being served, longest running first
+func (t *InFlightTracker) Requests() []InFlightRequest {
+	t.mu.Lock()
+	live := make([]*inFlightRequest, 0, len(t.requests))
+	for _, req := range t.requests {
+		live = append(live, req)
+	}
+	t.mu.Unlock()
+
+	now := time.Now()
+	requests := make([]InFlightRequest, 0, len(live))
+	for _, req := range live {
+		requests = append(requests, req.snapshot(now))
+	}
+
+	slices.SortFunc(requests, func(a, b InFlightRequest) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+	return requests
+}
+
+// snapshot returns the state of the request at now
+func (req *inFlightRequest) snapshot(now time.Time) InFlightRequest {
+	snapshot := InFlightRequest{
+		ID:             req.id,
+		RequestID:      req.requestID,
+		Method:         req.method,
+		Path:           req.path,
+		ClientIP:       req.clientIP,
+		BytesReceived:  req.received.Load(),
+		BytesSent:      req.sent.Load(),
+		StartedAt:      req.startedAt,
+		ElapsedSeconds: now.Sub(req.startedAt).Seconds(),
+	}
+	if req.fields != nil {
+		snapshot.User = req.fields.get("username")
+		snapshot.Backend = req.fields.get("backend")
+	}
+	return snapshot
+}
+
+// countingBody counts the bytes read from a request body
+type countingBody struct {
+	io.ReadCloser
+	count *atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.count.Add(int64(n))
+	return n, err
+}
+
+// countingWriter counts the bytes written to a response. Once canceled, it drops
+// all writes, so the tracker can answer a canceled request while its handler is
+// still unwinding. Canceling never waits for a write in progress, which may be
+// blocked on a slow client.
+type countingWriter struct {
+	http.ResponseWriter
+	count *atomic.Int64
+
+	canceled    atomic.Bool
+	wroteHeader atomic.Bool
+}
+
+func (cw *countingWriter) WriteHeader(statusCode int) {
+	if !cw.start() {
+		return
+	}
+	cw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	if !cw.start() {
+		return 0, errRequestCanceled
+	}
+	n, err := cw.ResponseWriter.Write(b)
+	cw.count.Add(int64(n))
+	return n, err
+}
+
+// Flush passes flushes through, so streamed responses reach the client as they
+// are written
+func (cw *countingWriter) Flush() {
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok && cw.start() {
+		f.Flush()
+	}
+}
+
+// Hijack passes connection takeovers through for upgraded connections
+func (cw *countingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	if !cw.start() {
+		return nil, nil, errRequestCanceled
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (cw *countingWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// start marks the response as started before anything reaches the underlying
+// writer, and returns false if the writer was canceled. Marking before checking,
+// while cancel checks after marking, means that either the write is dropped or
+// cancel sees the response as started.
+func (cw *countingWriter) start() bool {
+	cw.wroteHeader.Store(true)
+	return !cw.canceled.Load()
+}
+
+// cancel makes the writer drop all further writes. It returns true if the
+// response may have started.
+func (cw *countingWriter) cancel() bool {
+	cw.canceled.Store(true)
+	return cw.wroteHeader.Load()
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestInFlightTracker(t *testing.T) {
+	tracker := NewInFlightTracker()
+
+	reading := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := SetUsername(r.Context(), "alice")
+		AddLogField(ctx, "backend", "nexus")
+		_, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte("partial"))
+		close(reading)
+		<-release
+	})
+	chain := RequestID(Logger(zerolog.Nop(), false, false)(tracker.Middleware(handler)))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest(http.MethodPut, "/raw/builds/app.tar.gz", strings.NewReader("0123456789"))
+		req.Header.Set("X-Request-ID", "req-1")
+		chain.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	<-reading
+	requests := tracker.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("Requests() returned %d requests, want 1", len(requests))
+	}
+	got := requests[0]
+	if got.ID == "" || got.RequestID != "req-1" || got.Method != http.MethodPut || got.Path != "/raw/builds/app.tar.gz" {
+		t.Errorf("request = %+v", got)
+	}
+	if got.User != "alice" || got.Backend != "nexus" {
+		t.Errorf("user, backend = %q, %q, want alice, nexus", got.User, got.Backend)
+	}
+	if got.BytesReceived != 10 || got.BytesSent != 7 {
+		t.Errorf("bytes received, sent = %d, %d, want 10, 7", got.BytesReceived, got.BytesSent)
+	}
+	if got.ElapsedSeconds < 0 || got.StartedAt.IsZero() {
+		t.Errorf("started at %v, elapsed %v", got.StartedAt, got.ElapsedSeconds)
+	}
+
+	close(release)
+	<-done
+	if requests := tracker.Requests(); len(requests) != 0 {
+		t.Errorf("Requests() after completion = %+v, want none", requests)
+	}
+}
+
+func TestInFlightTracker_Cancel(t *testing.T) {
+	tests := []struct {
+		name          string
+		startResponse bool
+		wantStatus    int
+		wantAbort     bool
+	}{
+		{name: "before response", wantStatus: http.StatusServiceUnavailable},
+		{name: "during response", startResponse: true, wantAbort: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewInFlightTracker()
+
+			started := make(chan struct{})
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.startResponse {
+					_, _ = w.Write([]byte("partial"))
+				}
+				close(started)
+				<-r.Context().Done()
+				// Discarded: the tracker answers canceled requests
+				http.Error(w, "backend request failed", http.StatusInternalServerError)
+			})
+
+			rec := httptest.NewRecorder()
+			aborted := make(chan bool, 1)
+			go func() {
+				defer func() { aborted <- recover() == http.ErrAbortHandler }()
+				tracker.Middleware(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/app/blobs/sha256:abc", nil))
+			}()
+			<-started
+
+			if _, ok := tracker.Cancel("unknown"); ok {
+				t.Error("Cancel() of an unknown request succeeded")
+			}
+			requests := tracker.Requests()
+			if len(requests) != 1 {
+				t.Fatalf("Requests() returned %d requests, want 1", len(requests))
+			}
+			canceled, ok := tracker.Cancel(requests[0].ID)
+			if !ok || canceled.Path != "/v2/app/blobs/sha256:abc" {
+				t.Fatalf("Cancel() = %+v, %v", canceled, ok)
+			}
+
+			if got := <-aborted; got != tt.wantAbort {
+				t.Errorf("connection aborted = %v, want %v", got, tt.wantAbort)
+			}
+			if tt.wantAbort {
+				if rec.Body.String() != "partial" {
+					t.Errorf("body = %q, want the partial response only", rec.Body.String())
+				}
+				return
+			}
+			if rec.Code != tt.wantStatus || rec.Header().Get("X-Error-Code") != "REQUEST_CANCELED" {
+				t.Errorf("response = %d %s, want %d REQUEST_CANCELED", rec.Code, rec.Header().Get("X-Error-Code"), tt.wantStatus)
+			}
+		})
+	}
+}
+
+// blockingWriter is a ResponseWriter whose writes block until released, like
+// writes to a client that stopped reading
+type blockingWriter struct {
+	*httptest.ResponseRecorder
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(b []byte) (int, error) {
+	close(w.writing)
+	<-w.release
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestInFlightTracker_CancelDuringBlockedWrite(t *testing.T) {
+	tracker := NewInFlightTracker()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("partial"))
+		// Discarded: the writer was canceled while the first write was blocked
+		_, _ = w.Write([]byte("rest"))
+	})
+
+	w := &blockingWriter{ResponseRecorder: httptest.NewRecorder(), writing: make(chan struct{}), release: make(chan struct{})}
+	aborted := make(chan bool, 1)
+	go func() {
+		defer func() { aborted <- recover() == http.ErrAbortHandler }()
+		tracker.Middleware(handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v2/app/blobs/sha256:abc", nil))
+	}()
+	<-w.writing
+
+	canceled := make(chan bool, 1)
+	go func() {
+		_, ok := tracker.Cancel(tracker.Requests()[0].ID)
+		canceled <- ok
+	}()
+	select {
+	case ok := <-canceled:
+		if !ok {
+			t.Fatal("Cancel() of the blocked request failed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Cancel() waited for the blocked write")
+	}
+
+	close(w.release)
+	if !<-aborted {
+		t.Error("connection not aborted, want abort of the started response")
+	}
+	if w.Body.String() != "partial" {
+		t.Errorf("body = %q, want the partial response only", w.Body.String())
+	}
+}
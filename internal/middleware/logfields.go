@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// logFieldsKey is the context key of the request's LogFields
+const logFieldsKey ContextKey = "log_fields"
+
+// LogFields collects fields handlers attach to the request's completion log line,
+// e.g. the image or package parsed from the path and the backend that served it.
+// The Logger middleware creates it before calling the handlers, so fields added on
+// derived contexts further down the chain still reach the log line.
+type LogFields struct {
+	mu     sync.Mutex
+	keys   []string // In the order first added
+	values map[string]string
+}
+
+// withLogFields returns ctx carrying a new, empty LogFields
+func withLogFields(ctx context.Context) (context.Context, *LogFields) {
+	fields := &LogFields{values: make(map[string]string)}
+	return context.WithValue(ctx, logFieldsKey, fields), fields
+}
+
+// AddLogField adds key=value to the completion log line of the request ctx belongs
+// to, replacing an earlier value of key. Empty values are ignored, as are contexts
+// of requests not logged by the Logger middleware.
+func AddLogField(ctx context.Context, key, value string) {
+	fields, ok := ctx.Value(logFieldsKey).(*LogFields)
+	if !ok || value == "" {
+		return
+	}
+
+	fields.mu.Lock()
+	defer fields.mu.Unlock()
+
+	if _, exists := fields.values[key]; !exists {
+		fields.keys = append(fields.keys, key)
+	}
+	fields.values[key] = value
+}
+
+// get returns the value of key, or "" if it hasn't been added
+func (f *LogFields) get(key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.values[key]
+}
+
+// apply adds the collected fields to event
+func (f *LogFields) apply(event *zerolog.Event) *zerolog.Event {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, key := range f.keys {
+		event = event.Str(key, f.values[key])
+	}
+	return event
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestLogger_HandlerLogFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Handlers add fields on contexts derived from the logged request
+		ctx := SetUsername(r.Context(), "alice")
+		AddLogField(ctx, "oci_repository", "team/app")
+		AddLogField(ctx, "backend", "docker-hub")
+		AddLogField(ctx, "backend", "ghcr") // Replaces the earlier value
+		AddLogField(ctx, "oci_tag", "")     // Ignored
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/team/app/manifests/v1", nil)
+	Logger(logger, false, false)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected request and completion log lines, got %d", len(lines))
+	}
+	var completion map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &completion); err != nil {
+		t.Fatalf("Invalid log line: %v", err)
+	}
+
+	want := map[string]string{"username": "alice", "oci_repository": "team/app", "backend": "ghcr"}
+	for key, value := range want {
+		if completion[key] != value {
+			t.Errorf("%s = %v, want %q", key, completion[key], value)
+		}
+	}
+	if _, ok := completion["oci_tag"]; ok {
+		t.Error("Empty field was logged")
+	}
+}
+
+func TestAddLogField_WithoutLogger(t *testing.T) {
+	// Must not panic outside the Logger middleware
+	AddLogField(context.Background(), "backend", "ghcr")
+}
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"bufio"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -28,9 +30,36 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
-// sanitizeHeaders redacts sensitive headers to prevent leaking secrets into logs.
+// Flush passes flushes through, so streamed responses reach the client as they
+// are written
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack passes connection takeovers through for upgraded connections, which are
+// logged as 101 Switching Protocols
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, buf, err := h.Hijack()
+	if err == nil {
+		rw.status = http.StatusSwitchingProtocols
+	}
+	return conn, buf, err
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// SanitizeHeaders redacts sensitive headers to prevent leaking secrets into logs.
 // Returns a sanitized copy safe for logging.
-func sanitizeHeaders(headers http.Header) map[string]interface{} {
+func SanitizeHeaders(headers http.Header) map[string]interface{} {
 	// Sensitive headers that must be redacted to prevent credential leakage
 	sensitiveHeaders := map[string]bool{
 		"authorization":       true,
@@ -95,21 +124,21 @@ func Logger(logger zerolog.Logger, includeHeaders bool, _ bool) func(http.Handle
 				Str("user_agent", r.UserAgent())
 
 			if includeHeaders {
-				// SECURITY: Use sanitizeHeaders to prevent leaking Authorization, Cookie, etc.
-				event = event.Interface("headers", sanitizeHeaders(r.Header))
+				// SECURITY: Use SanitizeHeaders to prevent leaking Authorization, Cookie, etc.
+				event = event.Interface("headers", SanitizeHeaders(r.Header))
 			}
 
 			event.Msg(requestLine)
 
+			// Collect the fields handlers add while processing the request
+			ctx, fields := withLogFields(r.Context())
+
 			// Process request
-			next.ServeHTTP(wrapped, r)
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
 
 			// Calculate duration
 			duration := time.Since(start)
 
-			// Get username from context if authenticated
-			username := GetUsername(r.Context())
-
 			// Log request completion - format: IP "METHOD /path" status=200 duration=0.16ms bytes=107
 			completionLine := fmt.Sprintf("%s \"%s %s\"", clientIP, r.Method, r.URL.Path)
 
@@ -121,9 +150,8 @@ func Logger(logger zerolog.Logger, includeHeaders bool, _ bool) func(http.Handle
 				Int64("bytes", wrapped.bytesWritten).
 				Str("user_agent", r.UserAgent())
 
-			if username != "" {
-				completionEvent = completionEvent.Str("username", username)
-			}
+			// Username, protocol-specific fields and the backend, as added by handlers
+			completionEvent = fields.apply(completionEvent)
 
 			completionEvent.Msg(completionLine)
 		})
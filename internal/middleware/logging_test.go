@@ -157,10 +157,10 @@ func TestSanitizeHeaders(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := sanitizeHeaders(tt.input)
+			result := SanitizeHeaders(tt.input)
 
 			if !reflect.DeepEqual(result, tt.expected) {
-				t.Errorf("sanitizeHeaders() = %v, want %v", result, tt.expected)
+				t.Errorf("SanitizeHeaders() = %v, want %v", result, tt.expected)
 			}
 		})
 	}
@@ -192,7 +192,7 @@ func TestSanitizeHeaders_SecurityVerification(t *testing.T) {
 				headerName: []string{"super-secret-value-that-must-not-be-logged"},
 			}
 
-			result := sanitizeHeaders(input)
+			result := SanitizeHeaders(input)
 
 			if value, ok := result[headerName]; ok {
 				if value != "[REDACTED]" {
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+// NamingPolicy enforces a protocol's naming convention on the names pushes use.
+// A nil policy accepts any name.
+//
+// Thread safety: All methods are safe for concurrent use.
+type NamingPolicy struct {
+	patterns []*regexp.Regexp
+	message  string
+}
+
+// NewNamingPolicy compiles the patterns of a validated cfg, returning nil if there
+// are none
+func NewNamingPolicy(cfg *config.NamingConfig) *NamingPolicy {
+	if len(cfg.Patterns) == 0 {
+		return nil
+	}
+	p := &NamingPolicy{message: cfg.Message}
+	for _, pattern := range cfg.Patterns {
+		p.patterns = append(p.patterns, regexp.MustCompile(pattern))
+	}
+	return p
+}
+
+// Check returns an error explaining the convention if name, a kind such as
+// "repository", doesn't match any of the policy's patterns
+func (p *NamingPolicy) Check(kind, name string) error {
+	if p == nil {
+		return nil
+	}
+	patterns := make([]string, len(p.patterns))
+	for i, pattern := range p.patterns {
+		if pattern.MatchString(name) {
+			return nil
+		}
+		patterns[i] = pattern.String()
+	}
+
+	err := fmt.Errorf("%s %q does not follow the naming convention of this registry (must match %s)",
+		kind, name, strings.Join(patterns, " or "))
+	if p.message != "" {
+		err = fmt.Errorf("%w: %s", err, p.message)
+	}
+	return err
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+func TestNamingPolicy_Check(t *testing.T) {
+	policy := NewNamingPolicy(&config.NamingConfig{
+		Patterns: []string{"^myorg/", "^sandbox/"},
+		Message:  "see https://wiki.example.com/naming",
+	})
+
+	for _, name := range []string{"myorg/app", "sandbox/test/app"} {
+		if err := policy.Check("repository", name); err != nil {
+			t.Errorf("Check(%q) error = %v, want nil", name, err)
+		}
+	}
+
+	err := policy.Check("repository", "app")
+	want := `repository "app" does not follow the naming convention of this registry (must match ^myorg/ or ^sandbox/): see https://wiki.example.com/naming`
+	if err == nil || err.Error() != want {
+		t.Errorf("Check() error = %v, want %q", err, want)
+	}
+
+	// Without patterns any name is accepted
+	disabled := NewNamingPolicy(&config.NamingConfig{})
+	if disabled != nil {
+		t.Fatalf("NewNamingPolicy() without patterns = %v, want nil", disabled)
+	}
+	if err := disabled.Check("scope", ""); err != nil {
+		t.Errorf("Check() on nil policy error = %v, want nil", err)
+	}
+
+	// Patterns are unanchored unless they say otherwise
+	unanchored := NewNamingPolicy(&config.NamingConfig{Patterns: []string{"myorg"}})
+	if err := unanchored.Check("repository", "team/myorg-app"); err != nil {
+		t.Errorf("Check() error = %v, want unanchored match", err)
+	}
+	if err := unanchored.Check("repository", "team/app"); err == nil || strings.Contains(err.Error(), ": ") {
+		t.Errorf("Check() error = %v, want rejection without message", err)
+	}
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+// IsPreflight reports whether r is a CORS preflight request
+func IsPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// OptionsResponder answers OPTIONS requests to protocol paths locally, and adds the
+// CORS headers of allowed origins to the responses of their requests.
+//
+// Thread safety: All methods are safe for concurrent use.
+type OptionsResponder struct {
+	answerLocally    bool
+	anyOrigin        bool
+	origins          map[string]bool
+	allowedHeaders   string
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+}
+
+// NewOptionsResponder creates the responder of a validated cfg
+func NewOptionsResponder(cfg *config.OptionsRequestsConfig) *OptionsResponder {
+	o := &OptionsResponder{
+		answerLocally:    cfg.AnswerLocally,
+		origins:          make(map[string]bool),
+		allowedHeaders:   strings.Join(cfg.CORS.AllowedHeaders, ", "),
+		exposedHeaders:   strings.Join(cfg.CORS.ExposedHeaders, ", "),
+		allowCredentials: cfg.CORS.AllowCredentials,
+		maxAge:           strconv.Itoa(int(cfg.CORS.MaxAge.Seconds())),
+	}
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		if origin == "*" {
+			o.anyOrigin = true
+			continue
+		}
+		o.origins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+	return o
+}
+
+// Handle answers r with 204 No Content and the protocol's methods if it is an OPTIONS
+// request to answer locally, and returns false. Otherwise it returns a writer to
+// serve r through, which adds the CORS headers if r is from an allowed origin.
+func (o *OptionsResponder) Handle(w http.ResponseWriter, r *http.Request, methods []string) (http.ResponseWriter, bool) {
+	allowOrigin := o.allowOrigin(r.Header.Get("Origin"))
+	corsEnabled := o.anyOrigin || len(o.origins) > 0
+
+	if r.Method == http.MethodOptions && (o.answerLocally || (corsEnabled && IsPreflight(r))) {
+		allow := strings.Join(methods, ", ")
+		header := w.Header()
+		header.Set("Allow", allow)
+		if IsPreflight(r) && allowOrigin != "" {
+			o.setCORSHeaders(header, allowOrigin)
+			header.Set("Access-Control-Allow-Methods", allow)
+			header.Set("Access-Control-Allow-Headers", o.allowedHeaders)
+			header.Set("Access-Control-Max-Age", o.maxAge)
+		}
+		if corsEnabled {
+			header.Add("Vary", "Origin")
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return w, false
+	}
+
+	if allowOrigin == "" {
+		return w, true
+	}
+	return &corsWriter{ResponseWriter: w, responder: o, origin: allowOrigin}, true
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, or empty if
+// it is not allowed
+func (o *OptionsResponder) allowOrigin(origin string) string {
+	switch {
+	case origin == "":
+		return ""
+	case o.origins[strings.ToLower(origin)]:
+		return origin
+	case o.anyOrigin:
+		return "*"
+	}
+	return ""
+}
+
+// setCORSHeaders sets the headers of every response to an allowed origin
+func (o *OptionsResponder) setCORSHeaders(header http.Header, allowOrigin string) {
+	header.Set("Access-Control-Allow-Origin", allowOrigin)
+	if o.allowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if o.exposedHeaders != "" {
+		header.Set("Access-Control-Expose-Headers", o.exposedHeaders)
+	}
+}
+
+// corsWriter sets the CORS headers when the response is written, replacing any the
+// backend sent
+type corsWriter struct {
+	http.ResponseWriter
+	responder   *OptionsResponder
+	origin      string
+	wroteHeader bool
+}
+
+func (cw *corsWriter) WriteHeader(statusCode int) {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+		header := cw.ResponseWriter.Header()
+		for key := range header {
+			if strings.HasPrefix(key, "Access-Control-") {
+				header.Del(key)
+			}
+		}
+		cw.responder.setCORSHeaders(header, cw.origin)
+		header.Add("Vary", "Origin")
+	}
+	cw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (cw *corsWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes through for streamed responses
+func (cw *corsWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack passes connection takeovers through for upgraded connections
+func (cw *corsWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (cw *corsWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+func TestOptionsResponder_Handle(t *testing.T) {
+	methods := []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+	cfg := &config.OptionsRequestsConfig{
+		AnswerLocally: true,
+		CORS: config.CORSConfig{
+			AllowedOrigins:   []string{"https://ui.example.com"},
+			AllowedHeaders:   []string{"Authorization", "Accept"},
+			ExposedHeaders:   []string{"Docker-Content-Digest"},
+			AllowCredentials: true,
+			MaxAge:           10 * time.Minute,
+		},
+	}
+
+	tests := []struct {
+		name        string
+		cfg         *config.OptionsRequestsConfig
+		method      string
+		origin      string
+		preflight   bool
+		wantProceed bool
+		wantAllow   string
+		wantOrigin  string
+	}{
+		{name: "options answered locally", cfg: cfg, method: http.MethodOptions, wantAllow: "GET, HEAD, OPTIONS"},
+		{name: "preflight from allowed origin", cfg: cfg, method: http.MethodOptions, origin: "https://ui.example.com", preflight: true, wantAllow: "GET, HEAD, OPTIONS", wantOrigin: "https://ui.example.com"},
+		{name: "preflight from other origin", cfg: cfg, method: http.MethodOptions, origin: "https://evil.example.com", preflight: true, wantAllow: "GET, HEAD, OPTIONS"},
+		{name: "get from allowed origin", cfg: cfg, method: http.MethodGet, origin: "https://ui.example.com", wantProceed: true, wantOrigin: "https://ui.example.com"},
+		{name: "get from other origin", cfg: cfg, method: http.MethodGet, origin: "https://evil.example.com", wantProceed: true},
+		{name: "options passed through", cfg: &config.OptionsRequestsConfig{}, method: http.MethodOptions, wantProceed: true},
+		{name: "any origin", cfg: &config.OptionsRequestsConfig{CORS: config.CORSConfig{AllowedOrigins: []string{"*"}}}, method: http.MethodOptions, origin: "https://ci.example.com", preflight: true, wantAllow: "GET, HEAD, OPTIONS", wantOrigin: "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/v2/library/alpine/manifests/latest", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			}
+			rec := httptest.NewRecorder()
+
+			w, proceed := NewOptionsResponder(tt.cfg).Handle(rec, r, methods)
+			if proceed != tt.wantProceed {
+				t.Fatalf("Handle() proceed = %v, want %v", proceed, tt.wantProceed)
+			}
+			if proceed {
+				// The backend's CORS headers are replaced
+				w.Header().Set("Access-Control-Allow-Origin", "https://registry.example.com")
+				w.WriteHeader(http.StatusOK)
+			} else if rec.Code != http.StatusNoContent {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+			}
+
+			if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+			gotOrigin := rec.Header().Get("Access-Control-Allow-Origin")
+			if proceed && tt.wantOrigin == "" {
+				tt.wantOrigin = "https://registry.example.com" // Left to the backend
+			}
+			if gotOrigin != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", gotOrigin, tt.wantOrigin)
+			}
+			if tt.preflight && tt.wantOrigin == "https://ui.example.com" {
+				if rec.Header().Get("Access-Control-Allow-Credentials") != "true" ||
+					rec.Header().Get("Access-Control-Allow-Headers") != "Authorization, Accept" ||
+					rec.Header().Get("Access-Control-Max-Age") != "600" {
+					t.Errorf("preflight headers = %v", rec.Header())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/metrics"
+)
+
+// Queue names used in metrics
+const (
+	queueConcurrency = "concurrency"
+	queueRateLimit   = "rate_limit"
+)
+
+// Queue outcomes used in metrics
+const (
+	queueOutcomeAdmitted = "admitted"
+	queueOutcomeTimeout  = "timeout"
+	queueOutcomeCanceled = "canceled"
+	queueOutcomeFull     = "full"
+)
+
+// requestQueue tracks requests waiting for admission and reports them to metrics.
+// A nil metrics collector disables reporting.
+type requestQueue struct {
+	name    string
+	length  atomic.Int32
+	metrics *metrics.Metrics
+}
+
+// enter registers a waiting request and returns the new queue length
+func (q *requestQueue) enter() int32 {
+	length := q.length.Add(1)
+	if q.metrics != nil {
+		q.metrics.SetQueueLength(q.name, int(length))
+	}
+	return length
+}
+
+// leave unregisters a waiting request and records how long it waited
+func (q *requestQueue) leave(outcome string, waited time.Duration) {
+	length := q.length.Add(-1)
+	if q.metrics != nil {
+		q.metrics.SetQueueLength(q.name, int(length))
+		q.metrics.RecordQueueWait(q.name, outcome, waited)
+	}
+}
+
+// maxQueueWait returns how long a request may wait in a queue: the queue timeout,
+// shortened to the request's remaining deadline so a request is never held past
+// the point where it would time out anyway.
+func maxQueueWait(ctx context.Context, timeout time.Duration) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining < timeout {
+			return remaining
+		}
+	}
+	return timeout
+}
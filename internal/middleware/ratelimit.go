@@ -9,16 +9,33 @@ import (
 	"github.com/mainuli/artifusion/internal/config"
 	"github.com/mainuli/artifusion/internal/constants"
 	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/metrics"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/time/rate"
 )
 
-// userLimiter wraps a rate limiter with last access time for cleanup
+// Rate limit types, used as the limit_type metric label
+const (
+	limitTypeGlobal       = "global"
+	limitTypePerUser      = "per_user"
+	limitTypePerUserWrite = "per_user_write"
+)
+
+// userLimiter wraps a user's rate limiters with last access time for cleanup
 type userLimiter struct {
 	limiter    *rate.Limiter
+	write      *rate.Limiter // nil = writes share limiter
 	lastAccess time.Time
 }
 
+// forWrite returns the limiter for the user's write or read requests
+func (ul *userLimiter) forWrite(write bool) *rate.Limiter {
+	if write && ul.write != nil {
+		return ul.write
+	}
+	return ul.limiter
+}
+
 // RateLimiter implements global and per-user rate limiting using token bucket algorithm
 type RateLimiter struct {
 	config        *config.RateLimitConfig
@@ -28,14 +45,19 @@ type RateLimiter struct {
 	cleanupTicker *time.Ticker
 	stopCleanup   chan struct{}
 	stopOnce      sync.Once // Ensures Stop() is called only once
+	queue         requestQueue
+	metrics       *metrics.Metrics // Optional (nil = no metrics)
 }
 
-// NewRateLimiter creates a new rate limiter
-func NewRateLimiter(cfg *config.RateLimitConfig) *RateLimiter {
+// NewRateLimiter creates a new rate limiter.
+// If m is non-nil, rejections, per-user limiter count and queue activity are recorded.
+func NewRateLimiter(cfg *config.RateLimitConfig, m *metrics.Metrics) *RateLimiter {
 	rl := &RateLimiter{
 		config:      cfg,
 		perUser:     make(map[string]*userLimiter),
 		stopCleanup: make(chan struct{}),
+		queue:       requestQueue{name: queueRateLimit, metrics: m},
+		metrics:     m,
 	}
 
 	// Create global rate limiter if enabled
@@ -53,11 +75,11 @@ func NewRateLimiter(cfg *config.RateLimitConfig) *RateLimiter {
 }
 
 // isInfrastructureEndpoint checks if a path is an infrastructure endpoint
-// that should be exempt from rate limiting (health checks, readiness, metrics).
+// that should be exempt from rate limiting (health checks, readiness, metrics, version).
 // These endpoints are called by Kubernetes probes and monitoring systems and
 // must remain accessible even under high load conditions.
 func isInfrastructureEndpoint(path string) bool {
-	return path == "/health" || path == "/ready" || path == "/metrics"
+	return path == "/health" || path == "/ready" || path == "/metrics" || path == "/version"
 }
 
 // Middleware returns a middleware handler that enforces rate limits
@@ -72,7 +94,8 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 		}
 
 		// Check global rate limit first
-		if rl.config.Enabled && !rl.global.Allow() {
+		if rl.config.Enabled && !rl.allow(r.Context(), rl.global) {
+			rl.recordRejection(limitTypeGlobal)
 			errors.ErrorResponse(w, errors.ErrGlobalRateLimitExceeded)
 			return
 		}
@@ -82,8 +105,14 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 			// Extract username from context (set by auth middleware)
 			username := getUsernameFromContext(r.Context())
 			if username != "" {
-				limiter := rl.getUserLimiter(username)
-				if !limiter.Allow() {
+				write := rl.writeLimitEnabled() && isWriteMethod(r.Method)
+				limiter := rl.getUserLimiter(username, write)
+				if !rl.allow(r.Context(), limiter) {
+					if write {
+						rl.recordRejection(limitTypePerUserWrite)
+					} else {
+						rl.recordRejection(limitTypePerUser)
+					}
 					errors.ErrorResponse(w, errors.ErrUserRateLimitExceeded)
 					return
 				}
@@ -94,8 +123,83 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	})
 }
 
-// getUserLimiter gets or creates a rate limiter for a specific user
-func (rl *RateLimiter) getUserLimiter(username string) *rate.Limiter {
+// writeLimitEnabled reports whether write requests have a per-user limit of their own
+func (rl *RateLimiter) writeLimitEnabled() bool {
+	return rl.config.PerUserEnabled && rl.config.PerUserWriteRequests > 0
+}
+
+// isWriteMethod reports whether the HTTP method modifies artifacts, and so counts
+// against the per-user write limit (mirrors auth.IsWriteMethod, which imports this
+// package)
+func isWriteMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// recordRejection records a rate limit rejection if metrics are enabled
+func (rl *RateLimiter) recordRejection(limitType string) {
+	if rl.metrics != nil {
+		rl.metrics.RecordRateLimitExceeded(limitType)
+	}
+}
+
+// recordUserLimiters records the number of tracked per-user limiters if metrics are enabled
+func (rl *RateLimiter) recordUserLimiters(count int) {
+	if rl.metrics != nil {
+		rl.metrics.SetRateLimitUserLimiters(count)
+	}
+}
+
+// allow reports whether a request may proceed under limiter.
+//
+// If queueing is enabled, a request without an available token reserves the next one
+// and waits for it, provided the wait fits within the queue timeout and the request's
+// deadline. Requests that could not be served in time are rejected immediately rather
+// than held only to fail later.
+func (rl *RateLimiter) allow(ctx context.Context, limiter *rate.Limiter) bool {
+	if rl.config.QueueTimeout <= 0 {
+		return limiter.Allow()
+	}
+
+	now := time.Now()
+	reservation := limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return false
+	}
+
+	delay := reservation.DelayFrom(now)
+	if delay == 0 {
+		return true
+	}
+
+	if delay > maxQueueWait(ctx, rl.config.QueueTimeout) {
+		// Return the token so the rejection does not penalize later requests
+		reservation.CancelAt(now)
+		return false
+	}
+
+	rl.queue.enter()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		rl.queue.leave(queueOutcomeAdmitted, delay)
+		return true
+	case <-ctx.Done():
+		reservation.Cancel()
+		rl.queue.leave(queueOutcomeCanceled, time.Since(now))
+		return false
+	}
+}
+
+// getUserLimiter gets or creates the rate limiters for a specific user, returning the
+// one for write or read requests
+func (rl *RateLimiter) getUserLimiter(username string, write bool) *rate.Limiter {
 	now := time.Now()
 
 	// Try read lock first (fast path)
@@ -103,7 +207,7 @@ func (rl *RateLimiter) getUserLimiter(username string) *rate.Limiter {
 	ul, exists := rl.perUser[username]
 	if exists {
 		// Important: Copy the limiter reference while holding read lock
-		limiterRef := ul.limiter
+		limiterRef := ul.forWrite(write)
 		rl.mu.RUnlock()
 
 		// Update last access time with write lock
@@ -125,7 +229,7 @@ func (rl *RateLimiter) getUserLimiter(username string) *rate.Limiter {
 	// Double-check after acquiring write lock to prevent duplicate creation
 	if ul, exists := rl.perUser[username]; exists {
 		ul.lastAccess = now
-		return ul.limiter
+		return ul.forWrite(write)
 	}
 
 	// Create new per-user limiter with current timestamp
@@ -133,9 +237,13 @@ func (rl *RateLimiter) getUserLimiter(username string) *rate.Limiter {
 		limiter:    rate.NewLimiter(rate.Limit(rl.config.PerUserRequests), rl.config.PerUserBurst),
 		lastAccess: now,
 	}
+	if rl.writeLimitEnabled() {
+		newLimiter.write = rate.NewLimiter(rate.Limit(rl.config.PerUserWriteRequests), rl.config.PerUserWriteBurst)
+	}
 	rl.perUser[username] = newLimiter
+	rl.recordUserLimiters(len(rl.perUser))
 
-	return newLimiter.limiter
+	return newLimiter.forWrite(write)
 }
 
 // cleanupStaleUserLimiters periodically removes limiters that haven't been used recently
@@ -161,6 +269,8 @@ func (rl *RateLimiter) cleanupStaleUserLimiters() {
 			remainingCount := len(rl.perUser)
 			rl.mu.Unlock()
 
+			rl.recordUserLimiters(remainingCount)
+
 			// Log cleanup activity if any limiters were removed
 			if removedCount > 0 {
 				log.Debug().
@@ -188,6 +298,86 @@ func (rl *RateLimiter) Stop() {
 	}
 }
 
+// BucketStatus describes the current state of a token bucket rate limit
+type BucketStatus struct {
+	Enabled      bool    `json:"enabled"`
+	LimitPerSec  float64 `json:"limit_per_sec,omitempty"`
+	Burst        int     `json:"burst,omitempty"`
+	Remaining    int     `json:"remaining"`                // Requests that can be made right now
+	RetryAfterMs int64   `json:"retry_after_ms,omitempty"` // Wait until the next request is allowed (0 if Remaining > 0)
+}
+
+// GlobalStatus returns the current state of the global rate limit bucket
+func (rl *RateLimiter) GlobalStatus() BucketStatus {
+	if !rl.config.Enabled {
+		return BucketStatus{}
+	}
+	return bucketStatus(rl.global, time.Now())
+}
+
+// UserStatus returns the current state of a user's rate limit bucket.
+// It does not create a limiter: users without one report a full bucket.
+func (rl *RateLimiter) UserStatus(username string) BucketStatus {
+	if !rl.config.PerUserEnabled {
+		return BucketStatus{}
+	}
+
+	rl.mu.RLock()
+	ul, exists := rl.perUser[username]
+	rl.mu.RUnlock()
+
+	if !exists {
+		return BucketStatus{
+			Enabled:     true,
+			LimitPerSec: rl.config.PerUserRequests,
+			Burst:       rl.config.PerUserBurst,
+			Remaining:   rl.config.PerUserBurst,
+		}
+	}
+	return bucketStatus(ul.limiter, time.Now())
+}
+
+// UserWriteStatus returns the current state of a user's write rate limit bucket.
+// It reports disabled when writes share the per-user limit (see UserStatus).
+func (rl *RateLimiter) UserWriteStatus(username string) BucketStatus {
+	if !rl.writeLimitEnabled() {
+		return BucketStatus{}
+	}
+
+	rl.mu.RLock()
+	ul, exists := rl.perUser[username]
+	rl.mu.RUnlock()
+
+	if !exists {
+		return BucketStatus{
+			Enabled:     true,
+			LimitPerSec: rl.config.PerUserWriteRequests,
+			Burst:       rl.config.PerUserWriteBurst,
+			Remaining:   rl.config.PerUserWriteBurst,
+		}
+	}
+	return bucketStatus(ul.write, time.Now())
+}
+
+// bucketStatus snapshots a token bucket at the given time
+func bucketStatus(limiter *rate.Limiter, now time.Time) BucketStatus {
+	tokens := limiter.TokensAt(now)
+	status := BucketStatus{
+		Enabled:     true,
+		LimitPerSec: float64(limiter.Limit()),
+		Burst:       limiter.Burst(),
+	}
+
+	if tokens >= 1 {
+		status.Remaining = int(tokens)
+	} else if limiter.Limit() > 0 {
+		wait := time.Duration((1 - tokens) / float64(limiter.Limit()) * float64(time.Second))
+		status.RetryAfterMs = wait.Milliseconds()
+	}
+
+	return status
+}
+
 // getUsernameFromContext extracts the authenticated username from the request context
 // This should be set by the authentication middleware
 func getUsernameFromContext(ctx context.Context) string {
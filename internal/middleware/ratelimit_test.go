@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 // TestRateLimiter_GlobalLimit tests global rate limiting
@@ -18,7 +20,7 @@ func TestRateLimiter_GlobalLimit(t *testing.T) {
 		PerUserEnabled: false,
 	}
 
-	rl := NewRateLimiter(cfg)
+	rl := NewRateLimiter(cfg, nil)
 	defer rl.Stop()
 
 	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -55,7 +57,7 @@ func TestRateLimiter_PerUserLimit(t *testing.T) {
 		PerUserBurst:    5,
 	}
 
-	rl := NewRateLimiter(cfg)
+	rl := NewRateLimiter(cfg, nil)
 	defer rl.Stop()
 
 	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -110,7 +112,7 @@ func TestRateLimiter_PerUserLimit_MultipleUsers(t *testing.T) {
 		PerUserBurst:    3,
 	}
 
-	rl := NewRateLimiter(cfg)
+	rl := NewRateLimiter(cfg, nil)
 	defer rl.Stop()
 
 	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -160,7 +162,7 @@ func TestRateLimiter_BothLimits(t *testing.T) {
 		PerUserBurst:    2,
 	}
 
-	rl := NewRateLimiter(cfg)
+	rl := NewRateLimiter(cfg, nil)
 	defer rl.Stop()
 
 	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -206,7 +208,7 @@ func TestRateLimiter_NoUsername(t *testing.T) {
 		PerUserBurst:    2,
 	}
 
-	rl := NewRateLimiter(cfg)
+	rl := NewRateLimiter(cfg, nil)
 	defer rl.Stop()
 
 	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -243,7 +245,7 @@ func TestRateLimiter_Disabled(t *testing.T) {
 		PerUserEnabled: false,
 	}
 
-	rl := NewRateLimiter(cfg)
+	rl := NewRateLimiter(cfg, nil)
 	defer rl.Stop()
 
 	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -275,7 +277,7 @@ func TestRateLimiter_RefillRate(t *testing.T) {
 		PerUserBurst:    2,
 	}
 
-	rl := NewRateLimiter(cfg)
+	rl := NewRateLimiter(cfg, nil)
 	defer rl.Stop()
 
 	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -333,7 +335,7 @@ func TestRateLimiter_GetUserLimiter_DoubleCheckedLocking(t *testing.T) {
 		PerUserBurst:    10,
 	}
 
-	rl := NewRateLimiter(cfg)
+	rl := NewRateLimiter(cfg, nil)
 	defer rl.Stop()
 
 	// Launch multiple goroutines trying to get limiter for same user concurrently
@@ -342,7 +344,7 @@ func TestRateLimiter_GetUserLimiter_DoubleCheckedLocking(t *testing.T) {
 
 	for i := 0; i < numGoroutines; i++ {
 		go func() {
-			limiter := rl.getUserLimiter("testuser")
+			limiter := rl.getUserLimiter("testuser", false)
 			if limiter == nil {
 				t.Error("getUserLimiter returned nil")
 			}
@@ -363,6 +365,56 @@ func TestRateLimiter_GetUserLimiter_DoubleCheckedLocking(t *testing.T) {
 	rl.mu.RUnlock()
 }
 
+// TestRateLimiter_Metrics tests that rejections and per-user limiters are recorded
+func TestRateLimiter_Metrics(t *testing.T) {
+	cfg := &config.RateLimitConfig{
+		Enabled:         true,
+		RequestsPerSec:  0.1,
+		Burst:           3,
+		PerUserEnabled:  true,
+		PerUserRequests: 0.1,
+		PerUserBurst:    1,
+	}
+
+	m := metrics.NewMetrics("ratelimit_metrics_test")
+	rl := NewRateLimiter(cfg, m)
+	defer rl.Stop()
+
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// user1 exhausts its limit, user2 takes the last global token, user3 is
+	// rejected by the global limit before a limiter is created for it
+	for _, tt := range []struct {
+		username string
+		want     int
+	}{
+		{"user1", http.StatusOK},
+		{"user1", http.StatusTooManyRequests},
+		{"user2", http.StatusOK},
+		{"user3", http.StatusTooManyRequests},
+	} {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req = req.WithContext(SetUsername(req.Context(), tt.username))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Fatalf("%s: expected %d, got %d", tt.username, tt.want, rec.Code)
+		}
+	}
+
+	if got := testutil.ToFloat64(m.RateLimitExceeded.WithLabelValues(limitTypeGlobal)); got != 1 {
+		t.Errorf("global rejections = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.RateLimitExceeded.WithLabelValues(limitTypePerUser)); got != 1 {
+		t.Errorf("per-user rejections = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.RateLimitUserLimiters); got != 2 {
+		t.Errorf("per-user limiters = %v, want 2", got)
+	}
+}
+
 // TestRateLimiter_Stop tests cleanup on stop
 func TestRateLimiter_Stop(t *testing.T) {
 	cfg := &config.RateLimitConfig{
@@ -372,7 +424,7 @@ func TestRateLimiter_Stop(t *testing.T) {
 		PerUserBurst:    10,
 	}
 
-	rl := NewRateLimiter(cfg)
+	rl := NewRateLimiter(cfg, nil)
 
 	// Stop should not panic
 	rl.Stop()
@@ -391,7 +443,7 @@ func TestRateLimiter_InfrastructureEndpoints(t *testing.T) {
 		Burst:          1, // Very low
 	}
 
-	rl := NewRateLimiter(cfg)
+	rl := NewRateLimiter(cfg, nil)
 	defer rl.Stop()
 
 	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -443,6 +495,7 @@ func TestIsInfrastructureEndpoint(t *testing.T) {
 		{"/health", true},
 		{"/ready", true},
 		{"/metrics", true},
+		{"/version", true},
 		{"/v2/", false},
 		{"/maven/", false},
 		{"/npm/", false},
@@ -474,7 +527,7 @@ func TestRateLimiter_InfrastructureEndpoints_WithPerUserLimit(t *testing.T) {
 		PerUserBurst:    1,
 	}
 
-	rl := NewRateLimiter(cfg)
+	rl := NewRateLimiter(cfg, nil)
 	defer rl.Stop()
 
 	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -521,3 +574,198 @@ func TestRateLimiter_InfrastructureEndpoints_WithPerUserLimit(t *testing.T) {
 		t.Errorf("/api/test second request: expected 429 (per-user rate limited), got %d", rec2.Code)
 	}
 }
+
+// TestRateLimiter_Status tests bucket status snapshots for introspection
+func TestRateLimiter_Status(t *testing.T) {
+	cfg := &config.RateLimitConfig{
+		Enabled:         true,
+		RequestsPerSec:  1,
+		Burst:           3,
+		PerUserEnabled:  true,
+		PerUserRequests: 2,
+		PerUserBurst:    5,
+	}
+
+	rl := NewRateLimiter(cfg, nil)
+	defer rl.Stop()
+
+	// Unknown users report a full bucket without a limiter being created
+	userStatus := rl.UserStatus("alice")
+	if !userStatus.Enabled || userStatus.Remaining != 5 || userStatus.Burst != 5 {
+		t.Errorf("unexpected status for new user: %+v", userStatus)
+	}
+	if len(rl.perUser) != 0 {
+		t.Error("UserStatus should not create a user limiter")
+	}
+
+	// Drain the global bucket
+	for i := 0; i < 3; i++ {
+		rl.global.Allow()
+	}
+
+	globalStatus := rl.GlobalStatus()
+	if globalStatus.Remaining != 0 {
+		t.Errorf("expected 0 remaining, got %d", globalStatus.Remaining)
+	}
+	if globalStatus.RetryAfterMs <= 0 || globalStatus.RetryAfterMs > 1000 {
+		t.Errorf("expected retry after within 1s, got %dms", globalStatus.RetryAfterMs)
+	}
+
+	// Consumed user tokens are reflected
+	limiter := rl.getUserLimiter("alice", false)
+	limiter.Allow()
+	limiter.Allow()
+	if remaining := rl.UserStatus("alice").Remaining; remaining != 3 {
+		t.Errorf("expected 3 remaining for alice, got %d", remaining)
+	}
+}
+
+// TestRateLimiter_Status_Disabled tests status when limits are disabled
+func TestRateLimiter_Status_Disabled(t *testing.T) {
+	rl := NewRateLimiter(&config.RateLimitConfig{}, nil)
+	defer rl.Stop()
+
+	if rl.GlobalStatus().Enabled {
+		t.Error("expected global status disabled")
+	}
+	if rl.UserStatus("alice").Enabled {
+		t.Error("expected per-user status disabled")
+	}
+}
+
+// TestRateLimiter_Queue tests that rate-limited requests wait for a token when queueing is enabled
+func TestRateLimiter_Queue(t *testing.T) {
+	cfg := &config.RateLimitConfig{
+		Enabled:        true,
+		RequestsPerSec: 20, // one token every 50ms
+		Burst:          1,
+		QueueTimeout:   time.Second,
+	}
+
+	rl := NewRateLimiter(cfg, nil)
+	defer rl.Stop()
+
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/test", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("request %d: expected queued request to succeed, got %d", i, rec.Code)
+		}
+	}
+
+	// Requests 2 and 3 each waited roughly one refill interval
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("expected requests to be delayed, took %v", elapsed)
+	}
+}
+
+// TestRateLimiter_Queue_RejectsBeyondTimeout tests immediate rejection when the wait exceeds the queue timeout
+func TestRateLimiter_Queue_RejectsBeyondTimeout(t *testing.T) {
+	cfg := &config.RateLimitConfig{
+		Enabled:        true,
+		RequestsPerSec: 1, // one token per second
+		Burst:          1,
+		QueueTimeout:   100 * time.Millisecond,
+	}
+
+	rl := NewRateLimiter(cfg, nil)
+	defer rl.Stop()
+
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/test", nil))
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429, got %d", rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("expected immediate rejection, waited %v", elapsed)
+	}
+
+	// The rejected request must not have consumed the next token
+	if status := rl.GlobalStatus(); status.RetryAfterMs > 1000 {
+		t.Errorf("rejected request consumed a token: retry after %dms", status.RetryAfterMs)
+	}
+}
+
+// TestRateLimiter_PerUserWriteLimit tests that writes draw from their own per-user
+// bucket, so exhausting one class leaves the other untouched
+func TestRateLimiter_PerUserWriteLimit(t *testing.T) {
+	cfg := &config.RateLimitConfig{
+		PerUserEnabled:       true,
+		PerUserRequests:      1,
+		PerUserBurst:         3,
+		PerUserWriteRequests: 1,
+		PerUserWriteBurst:    1,
+	}
+
+	rl := NewRateLimiter(cfg, nil)
+	defer rl.Stop()
+
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method string) int {
+		req := httptest.NewRequest(method, "/test", nil)
+		req = req.WithContext(SetUsername(req.Context(), "alice"))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve(http.MethodPut); code != http.StatusOK {
+		t.Fatalf("first write: expected 200, got %d", code)
+	}
+	if code := serve(http.MethodPut); code != http.StatusTooManyRequests {
+		t.Errorf("second write: expected 429, got %d", code)
+	}
+
+	// Reads still have their full burst
+	for i := 0; i < 3; i++ {
+		if code := serve(http.MethodGet); code != http.StatusOK {
+			t.Errorf("read %d: expected 200, got %d", i, code)
+		}
+	}
+	if code := serve(http.MethodGet); code != http.StatusTooManyRequests {
+		t.Errorf("read beyond burst: expected 429, got %d", code)
+	}
+
+	if status := rl.UserWriteStatus("alice"); !status.Enabled || status.Remaining != 0 || status.Burst != 1 {
+		t.Errorf("unexpected write status: %+v", status)
+	}
+	if status := rl.UserWriteStatus("bob"); status.Remaining != 1 {
+		t.Errorf("expected a full write bucket for a new user, got %+v", status)
+	}
+}
+
+// TestRateLimiter_PerUserWriteLimit_Disabled tests that writes share the per-user
+// bucket when no write limit is configured
+func TestRateLimiter_PerUserWriteLimit_Disabled(t *testing.T) {
+	cfg := &config.RateLimitConfig{
+		PerUserEnabled:  true,
+		PerUserRequests: 1,
+		PerUserBurst:    1,
+	}
+
+	rl := NewRateLimiter(cfg, nil)
+	defer rl.Stop()
+
+	if rl.getUserLimiter("alice", true) != rl.getUserLimiter("alice", false) {
+		t.Error("expected writes to share the per-user limiter")
+	}
+	if rl.UserWriteStatus("alice").Enabled {
+		t.Error("write status should be disabled without per_user_write_requests")
+	}
+}
@@ -13,6 +13,11 @@ func Recovery(logger zerolog.Logger) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
+					// Deliberate abort of the connection, e.g. a timed-out streamed response
+					if err == http.ErrAbortHandler {
+						panic(err)
+					}
+
 					// Get request ID for correlation
 					requestID := GetRequestID(r.Context())
 
@@ -54,7 +54,9 @@ func GetUsername(ctx context.Context) string {
 	return ""
 }
 
-// SetUsername adds the authenticated username to the context
+// SetUsername adds the authenticated username to the context and to the request's
+// completion log line
 func SetUsername(ctx context.Context, username string) context.Context {
+	AddLogField(ctx, "username", username)
 	return context.WithValue(ctx, UsernameKey, username)
 }
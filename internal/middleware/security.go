@@ -2,54 +2,103 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
+
+	"github.com/mainuli/artifusion/internal/config"
 )
 
-// SecurityHeaders adds security-related HTTP headers to all responses
+// PathClass classifies requests by the kind of client they are served to, which
+// decides the security headers their responses get
+type PathClass int
+
+const (
+	// PathClassDefault covers Artifusion's own endpoints (API, health, landing page)
+	PathClassDefault PathClass = iota
+	// PathClassProtocol covers package protocol requests served to CLI clients
+	PathClassProtocol
+	// PathClassUI covers the web UI
+	PathClassUI
+)
+
+// SecurityHeaders returns middleware adding security-related HTTP headers to all
+// responses, following the configured policy. classify assigns each request its
+// path class; nil classifies every request as PathClassDefault.
 // This middleware implements defense-in-depth security practices
-func SecurityHeaders(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Prevent MIME type sniffing
-		// Instructs browsers to respect the Content-Type header
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-
-		// Prevent clickjacking attacks
-		// Prevents the page from being embedded in frames/iframes
-		w.Header().Set("X-Frame-Options", "DENY")
-
-		// Enable XSS protection in older browsers
-		// Modern browsers have this enabled by default, but this ensures compatibility
-		w.Header().Set("X-XSS-Protection", "1; mode=block")
-
-		// Enforce HTTPS for all future requests (HSTS)
-		// Only set when request is over HTTPS or behind HTTPS proxy
-		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
-			// max-age=31536000: Enforce HTTPS for 1 year
-			// includeSubDomains: Apply to all subdomains
-			w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
-		}
-
-		// Content Security Policy
-		// Restrict resource loading to prevent XSS and data injection attacks
-		// default-src 'none': Block all content by default (appropriate for API server)
-		// For a more permissive policy, you could use:
-		// default-src 'self'; script-src 'self'; style-src 'self'
-		w.Header().Set("Content-Security-Policy", "default-src 'none'")
-
-		// Referrer policy
-		// Control how much referrer information is sent with requests
-		// strict-origin-when-cross-origin: Send full URL for same-origin, origin only for cross-origin HTTPS
-		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
-
-		// Permissions Policy (formerly Feature Policy)
-		// Disable potentially dangerous browser features
-		// This prevents the page from accessing camera, microphone, geolocation, etc.
-		w.Header().Set("Permissions-Policy", "camera=(), microphone=(), geolocation=(), payment=()")
-
-		// X-Permitted-Cross-Domain-Policies
-		// Prevent Adobe Flash and PDF from loading content from this domain
-		w.Header().Set("X-Permitted-Cross-Domain-Policies", "none")
-
-		// Call the next handler
-		next.ServeHTTP(w, r)
-	})
+func SecurityHeaders(cfg *config.SecurityHeadersConfig, classify func(*http.Request) PathClass) func(http.Handler) http.Handler {
+	hsts := hstsHeader(cfg)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Prevent MIME type sniffing
+			// Instructs browsers to respect the Content-Type header
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+
+			// Enforce HTTPS for all future requests (HSTS)
+			// Only set when request is over HTTPS or behind HTTPS proxy
+			if hsts != "" && (r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https") {
+				w.Header().Set("Strict-Transport-Security", hsts)
+			}
+
+			class := PathClassDefault
+			if classify != nil {
+				class = classify(r)
+			}
+
+			// Package clients (docker, mvn, npm, ...) don't render responses, so the
+			// browser-oriented headers below are skipped for protocol requests
+			if class == PathClassProtocol {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Content Security Policy and clickjacking protection
+			// The default policy blocks all content (appropriate for an API server);
+			// the web UI loads its own scripts and styles and may frame itself
+			if class == PathClassUI {
+				w.Header().Set("Content-Security-Policy", cfg.UIContentSecurityPolicy)
+				w.Header().Set("X-Frame-Options", cfg.UIFrameOptions)
+			} else {
+				w.Header().Set("Content-Security-Policy", cfg.ContentSecurityPolicy)
+				w.Header().Set("X-Frame-Options", cfg.FrameOptions)
+			}
+
+			// Enable XSS protection in older browsers
+			// Modern browsers have this enabled by default, but this ensures compatibility
+			w.Header().Set("X-XSS-Protection", "1; mode=block")
+
+			// Referrer policy
+			// Control how much referrer information is sent with requests
+			// strict-origin-when-cross-origin: Send full URL for same-origin, origin only for cross-origin HTTPS
+			w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
+
+			// Permissions Policy (formerly Feature Policy)
+			// Disable potentially dangerous browser features
+			// This prevents the page from accessing camera, microphone, geolocation, etc.
+			w.Header().Set("Permissions-Policy", "camera=(), microphone=(), geolocation=(), payment=()")
+
+			// X-Permitted-Cross-Domain-Policies
+			// Prevent Adobe Flash and PDF from loading content from this domain
+			w.Header().Set("X-Permitted-Cross-Domain-Policies", "none")
+
+			// Call the next handler
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// hstsHeader returns the Strict-Transport-Security value of the policy, or empty if
+// HSTS is disabled
+func hstsHeader(cfg *config.SecurityHeadersConfig) string {
+	if cfg.HSTSDisabled {
+		return ""
+	}
+
+	value := "max-age=" + strconv.FormatInt(int64(cfg.HSTSMaxAge.Seconds()), 10)
+	if !cfg.HSTSExcludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if cfg.HSTSPreload {
+		value += "; preload"
+	}
+	return value
 }
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	cfg := &config.SecurityHeadersConfig{
+		HSTSMaxAge:              config.DefaultHSTSMaxAge,
+		HSTSPreload:             true,
+		ContentSecurityPolicy:   config.DefaultContentSecurityPolicy,
+		FrameOptions:            config.FrameOptionsDeny,
+		UIContentSecurityPolicy: "default-src 'self'",
+		UIFrameOptions:          config.FrameOptionsSameOrigin,
+	}
+	classify := func(r *http.Request) PathClass {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v2/"):
+			return PathClassProtocol
+		case strings.HasPrefix(r.URL.Path, "/ui/"):
+			return PathClassUI
+		}
+		return PathClassDefault
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		wantCSP   string
+		wantFrame string
+	}{
+		{name: "api", path: "/api/v1/whoami", wantCSP: "default-src 'none'", wantFrame: "DENY"},
+		{name: "ui", path: "/ui/maven/", wantCSP: "default-src 'self'", wantFrame: "SAMEORIGIN"},
+		{name: "protocol skips browser headers", path: "/v2/library/alpine/manifests/latest", wantCSP: "", wantFrame: ""},
+	}
+
+	handler := SecurityHeaders(cfg, classify)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("X-Forwarded-Proto", "https")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Security-Policy"); got != tt.wantCSP {
+				t.Errorf("Content-Security-Policy = %q, want %q", got, tt.wantCSP)
+			}
+			if got := rec.Header().Get("X-Frame-Options"); got != tt.wantFrame {
+				t.Errorf("X-Frame-Options = %q, want %q", got, tt.wantFrame)
+			}
+			if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("X-Content-Type-Options = %q, want nosniff", got)
+			}
+			if got, want := rec.Header().Get("Strict-Transport-Security"), "max-age=31536000; includeSubDomains; preload"; got != want {
+				t.Errorf("Strict-Transport-Security = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSecurityHeaders_HSTS(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.SecurityHeadersConfig
+		https bool
+		want  string
+	}{
+		{name: "plain HTTP", cfg: config.SecurityHeadersConfig{HSTSMaxAge: config.DefaultHSTSMaxAge}, https: false, want: ""},
+		{name: "disabled", cfg: config.SecurityHeadersConfig{HSTSDisabled: true, HSTSMaxAge: config.DefaultHSTSMaxAge}, https: true, want: ""},
+		{name: "without subdomains", cfg: config.SecurityHeadersConfig{HSTSMaxAge: time.Hour, HSTSExcludeSubdomains: true}, https: true, want: "max-age=3600"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := SecurityHeaders(&tt.cfg, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.https {
+				req.Header.Set("X-Forwarded-Proto", "https")
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Strict-Transport-Security"); got != tt.want {
+				t.Errorf("Strict-Transport-Security = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,12 +3,16 @@ package middleware
 import (
 	"bufio"
 	"context"
+	"io"
 	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mainuli/artifusion/internal/errors"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/utils"
 )
 
 // Timeout Middleware Design Notes
@@ -212,17 +216,18 @@ func (tw *timeoutWriter) timeout() bool {
 	return tw.wroteHeader
 }
 
+// flush holds tw.mu while flushing, like Write, so a flush never races with the
+// connection being torn down after a timeout
 func (tw *timeoutWriter) flush(fl http.Flusher) {
 	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
 	if tw.timedOut {
-		tw.mu.Unlock()
 		return
 	}
 	if !tw.wroteHeader {
 		tw.writeHeaderLocked(http.StatusOK)
 	}
-	tw.mu.Unlock()
-
 	fl.Flush()
 }
 
@@ -456,12 +461,66 @@ func (w *contextCloseNotifier) Push(target string, opts *http.PushOptions) error
 	return http.ErrNotSupported
 }
 
+// States of a handler goroutine started by Timeout
+const (
+	handlerRunning int32 = iota
+	handlerFinished
+	handlerAbandoned // Still running after its request timed out
+)
+
+// timeoutBody wraps the request body so a handler that outlives its request stops
+// consuming it: once the deadline fires, reads fail and the underlying body is
+// closed, releasing the connection's read side.
+type timeoutBody struct {
+	io.ReadCloser
+	timedOut  atomic.Bool
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	if b.timedOut.Load() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return b.ReadCloser.Read(p)
+}
+
+func (b *timeoutBody) Close() error {
+	b.closeOnce.Do(func() {
+		b.closeErr = b.ReadCloser.Close()
+	})
+	return b.closeErr
+}
+
+// timeout fails further reads and closes the underlying body. Closing a server
+// request body waits for a read in progress, which a stalled upload may never
+// finish, so it is closed in the background rather than holding up the timeout
+// response.
+func (b *timeoutBody) timeout() {
+	b.timedOut.Store(true)
+	go func() { _ = b.Close() }()
+}
+
 // Timeout enforces a maximum duration on every request. Once the deadline
 // passes we send a timeout response (if possible) and drop further writes from
 // the handler to keep the underlying ResponseWriter safe.
-func Timeout(duration time.Duration) func(http.Handler) http.Handler {
+//
+// The handler runs in its own goroutine, which can outlive the request until the
+// handler notices the cancelled context. To bound what it holds on to, the request
+// body is closed when the deadline fires, and a response already streaming is
+// aborted so the connection is closed rather than left with a truncated body that
+// looks complete. m may be nil to disable the goroutine metrics.
+//
+// WebSocket upgrade requests are passed through without a deadline: the upgraded
+// connection lives as long as both sides keep it open.
+func Timeout(duration time.Duration, m *metrics.Metrics) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if utils.IsWebSocketUpgrade(r.Header) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			ctx, cancel := context.WithTimeout(r.Context(), duration)
 			defer cancel()
 
@@ -484,14 +543,36 @@ func Timeout(duration time.Duration) func(http.Handler) http.Handler {
 				}
 			}
 
+			req := r.WithContext(ctx)
+			var body *timeoutBody
+			if r.Body != nil && r.Body != http.NoBody {
+				body = &timeoutBody{ReadCloser: r.Body}
+				req.Body = body
+			}
+
+			// Whoever moves state away from handlerRunning first decides whether the
+			// handler goroutine was abandoned by a timeout
+			var state atomic.Int32
+
+			if m != nil {
+				m.TimeoutHandlers.Inc()
+			}
 			go func() {
+				defer func() {
+					if m != nil {
+						m.TimeoutHandlers.Dec()
+						if !state.CompareAndSwap(handlerRunning, handlerFinished) {
+							m.TimeoutAbandonedHandlers.Dec()
+						}
+					}
+				}()
 				defer func() {
 					if p := recover(); p != nil {
 						panicChan <- p
 					}
 				}()
 
-				next.ServeHTTP(wrapped, r.WithContext(ctx))
+				next.ServeHTTP(wrapped, req)
 				close(done)
 			}()
 
@@ -501,13 +582,36 @@ func Timeout(duration time.Duration) func(http.Handler) http.Handler {
 			case p := <-panicChan:
 				panic(p)
 			case <-ctx.Done():
-				if ctx.Err() == context.DeadlineExceeded {
-					headersWritten := core.timeout()
-					if !headersWritten {
-						errors.ErrorResponse(w, errors.ErrBackendTimeout.WithMessage(
-							"Request exceeded maximum allowed duration"))
+				if ctx.Err() != context.DeadlineExceeded {
+					return
+				}
+
+				headersWritten := core.timeout()
+				if body != nil {
+					// Unblock a read in progress, so the handler sees the timeout and
+					// closing the body completes
+					_ = http.NewResponseController(w).SetReadDeadline(time.Now())
+					body.timeout()
+				}
+				if m != nil && state.CompareAndSwap(handlerRunning, handlerAbandoned) {
+					m.TimeoutAbandonedHandlers.Inc()
+				}
+
+				if headersWritten {
+					if m != nil {
+						m.RequestTimeouts.WithLabelValues("during_response").Inc()
 					}
+					// Close the connection so the client sees the response is incomplete
+					panic(http.ErrAbortHandler)
+				}
+
+				if m != nil {
+					m.RequestTimeouts.WithLabelValues("before_response").Inc()
 				}
+				// The rest of the request body may be unread
+				w.Header().Set("Connection", "close")
+				errors.ErrorResponse(w, errors.ErrBackendTimeout.WithMessage(
+					"Request exceeded maximum allowed duration"))
 			}
 		})
 	}
@@ -1,11 +1,18 @@
 package middleware
 
 import (
+	"bufio"
 	"context"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
 )
 
 // TestContextCloseNotifier_Interface verifies that contextCloseNotifier implements http.CloseNotifier
@@ -150,7 +157,7 @@ func TestTimeoutMiddleware_WithCloseNotifier(t *testing.T) {
 	})
 
 	// Wrap with Timeout middleware
-	wrappedHandler := Timeout(timeoutDuration)(handler)
+	wrappedHandler := Timeout(timeoutDuration, nil)(handler)
 
 	// Create test request
 	req := httptest.NewRequest("GET", "/test", nil)
@@ -191,7 +198,7 @@ func TestTimeoutMiddleware_CloseNotifyOnCancel(t *testing.T) {
 	})
 
 	// Wrap with Timeout middleware
-	wrappedHandler := Timeout(timeoutDuration)(handler)
+	wrappedHandler := Timeout(timeoutDuration, nil)(handler)
 
 	// Create test request with cancellable context
 	ctx, cancel := context.WithCancel(context.Background())
@@ -241,7 +248,7 @@ func TestTimeoutMiddleware_NoCloseNotifyWithBackgroundContext(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	wrappedHandler := Timeout(timeoutDuration)(handler)
+	wrappedHandler := Timeout(timeoutDuration, nil)(handler)
 
 	req := httptest.NewRequest("GET", "/test", nil)
 	rec := httptest.NewRecorder()
@@ -262,3 +269,254 @@ type mockFlushWriter struct {
 func (m *mockFlushWriter) Flush() {
 	m.flushed = true
 }
+
+// waitFor polls cond until it holds or a second passes
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestTimeoutMiddleware_HandlerGoroutineGauges verifies that a handler outliving its
+// request is counted as abandoned until it returns
+func TestTimeoutMiddleware_HandlerGoroutineGauges(t *testing.T) {
+	m := metrics.NewMetrics("timeout_gauges_test")
+	release := make(chan struct{})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		<-release // Ignore the cancellation for a while, like a slow cleanup
+	})
+	wrappedHandler := Timeout(20*time.Millisecond, m)(handler)
+
+	rec := httptest.NewRecorder()
+	wrappedHandler.ServeHTTP(rec, httptest.NewRequest("GET", "/test", nil))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504, got %d", rec.Code)
+	}
+	if rec.Header().Get("Connection") != "close" {
+		t.Error("Expected Connection: close on the timeout response")
+	}
+	if got := testutil.ToFloat64(m.TimeoutHandlers); got != 1 {
+		t.Errorf("timeout_handler_goroutines = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.TimeoutAbandonedHandlers); got != 1 {
+		t.Errorf("timeout_abandoned_handler_goroutines = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.RequestTimeouts.WithLabelValues("before_response")); got != 1 {
+		t.Errorf("request_timeouts_total{phase=before_response} = %v, want 1", got)
+	}
+
+	close(release)
+	waitFor(t, "handler goroutine to exit", func() bool {
+		return testutil.ToFloat64(m.TimeoutHandlers) == 0 && testutil.ToFloat64(m.TimeoutAbandonedHandlers) == 0
+	})
+}
+
+// TestTimeoutMiddleware_ClosesBodyOnTimeout verifies that a handler still reading the
+// request body after the deadline gets an error instead of the rest of the body
+func TestTimeoutMiddleware_ClosesBodyOnTimeout(t *testing.T) {
+	readErr := make(chan error, 1)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond) // Let the middleware handle the deadline
+		_, err := io.ReadAll(r.Body)
+		readErr <- err
+	})
+
+	bodyReader, bodyWriter := io.Pipe()
+	defer func() { _ = bodyWriter.Close() }()
+	go func() { _, _ = bodyWriter.Write([]byte("partial upload")) }()
+
+	req := httptest.NewRequest("PUT", "/test", bodyReader)
+	Timeout(20*time.Millisecond, nil)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	select {
+	case err := <-readErr:
+		if err != http.ErrHandlerTimeout {
+			t.Errorf("Expected ErrHandlerTimeout reading the body, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Handler blocked reading the body after the timeout")
+	}
+}
+
+// TestTimeoutMiddleware_StalledBody verifies that a handler blocked reading a body
+// the client stopped sending doesn't hold up the timeout response, and that its
+// read is interrupted
+func TestTimeoutMiddleware_StalledBody(t *testing.T) {
+	readErr := make(chan error, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		readErr <- err
+	})
+	server := httptest.NewServer(Timeout(50*time.Millisecond, nil)(handler))
+	defer server.Close()
+
+	bodyReader, bodyWriter := io.Pipe()
+	defer func() { _ = bodyWriter.Close() }()
+	go func() { _, _ = bodyWriter.Write([]byte("partial upload")) }()
+
+	req, err := http.NewRequest(http.MethodPut, server.URL+"/test", bodyReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responses := make(chan int, 1)
+	go func() {
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			responses <- 0
+			return
+		}
+		_ = resp.Body.Close()
+		responses <- resp.StatusCode
+	}()
+
+	select {
+	case status := <-responses:
+		if status != http.StatusGatewayTimeout {
+			t.Errorf("Expected status 504, got %d", status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout response blocked by the stalled request body")
+	}
+	select {
+	case err := <-readErr:
+		if err == nil {
+			t.Error("Expected the handler's body read to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Handler still blocked reading the stalled body")
+	}
+}
+
+// TestTimeoutMiddleware_AbortsStreamedResponse verifies that a response that had
+// started streaming when the deadline fired is aborted, so the client sees an
+// incomplete body rather than a clean end of a truncated one
+func TestTimeoutMiddleware_AbortsStreamedResponse(t *testing.T) {
+	m := metrics.NewMetrics("timeout_stream_test")
+	handlerDone := make(chan struct{})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(handlerDone)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		for {
+			if _, err := w.Write([]byte("chunk\n")); err != nil {
+				return // ErrHandlerTimeout once the deadline fired
+			}
+			w.(http.Flusher).Flush()
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+
+	server := httptest.NewServer(Recovery(zerolog.Nop())(Timeout(50*time.Millisecond, m)(handler)))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Errorf("Expected a read error on the aborted stream, got a clean end after %d bytes", len(body))
+	}
+	if len(body) == 0 {
+		t.Error("Expected the chunks streamed before the deadline")
+	}
+
+	select {
+	case <-handlerDone:
+	case <-time.After(time.Second):
+		t.Fatal("Handler kept streaming after the timeout")
+	}
+	waitFor(t, "handler goroutine to exit", func() bool {
+		return testutil.ToFloat64(m.TimeoutHandlers) == 0
+	})
+	if got := testutil.ToFloat64(m.RequestTimeouts.WithLabelValues("during_response")); got != 1 {
+		t.Errorf("request_timeouts_total{phase=during_response} = %v, want 1", got)
+	}
+}
+
+// TestTimeoutMiddleware_CompletedStream verifies that a streamed response finishing
+// within the deadline is delivered intact and leaves no goroutine behind
+func TestTimeoutMiddleware_CompletedStream(t *testing.T) {
+	m := metrics.NewMetrics("timeout_completed_stream_test")
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			_, _ = w.Write([]byte("chunk\n"))
+			w.(http.Flusher).Flush()
+		}
+	})
+
+	server := httptest.NewServer(Timeout(time.Second, m)(handler))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil || len(body) != 30 {
+		t.Errorf("Expected 30 bytes without error, got %d bytes, err %v", len(body), err)
+	}
+
+	waitFor(t, "handler goroutine to exit", func() bool {
+		return testutil.ToFloat64(m.TimeoutHandlers) == 0
+	})
+	if got := testutil.ToFloat64(m.TimeoutAbandonedHandlers); got != 0 {
+		t.Errorf("timeout_abandoned_handler_goroutines = %v, want 0", got)
+	}
+}
+
+// TestTimeoutMiddleware_UpgradeBypassesDeadline verifies that WebSocket upgrade
+// requests keep running past the timeout, with the connection still hijackable
+// through the Logger
+func TestTimeoutMiddleware_UpgradeBypassesDeadline(t *testing.T) {
+	var hijackable, deadlineSet bool
+	handler := Logger(zerolog.Nop(), false, false)(Timeout(10*time.Millisecond, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, hijackable = w.(http.Hijacker)
+			_, deadlineSet = r.Context().Deadline()
+			time.Sleep(30 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		})))
+
+	req := httptest.NewRequest(http.MethodGet, "/-/web/socket", nil)
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 from the handler past the timeout", rec.Code)
+	}
+	if deadlineSet {
+		t.Error("upgrade request got a deadline")
+	}
+	if !hijackable {
+		t.Error("upgrade request's ResponseWriter is not an http.Hijacker")
+	}
+}
+
+// hijackRecorder is a ResponseRecorder that claims to support Hijack
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, http.ErrNotSupported
+}
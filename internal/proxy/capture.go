@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// BodyCapture copies a response body to a temporary file while it is streamed to the
+// client, so the artifact can be used after the response without buffering it in
+// memory or fetching it twice
+type BodyCapture struct {
+	body     io.ReadCloser
+	file     *os.File
+	size     int64
+	expected int64 // Content-Length of the response, -1 if unknown
+	complete bool  // The body was read to EOF
+	err      error // First error writing the temporary file
+}
+
+// CaptureBody replaces resp.Body with a reader that also writes the body to a
+// temporary file. The caller must call Remove once done with the capture.
+func CaptureBody(resp *Response) (*BodyCapture, error) {
+	file, err := os.CreateTemp("", "artifusion-capture-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create capture file: %w", err)
+	}
+
+	expected := int64(-1)
+	if resp.HTTPResp != nil {
+		expected = resp.HTTPResp.ContentLength
+	}
+
+	c := &BodyCapture{
+		body:     resp.Body,
+		file:     file,
+		expected: expected,
+	}
+	resp.Body = c
+	return c, nil
+}
+
+func (c *BodyCapture) Read(p []byte) (int, error) {
+	n, err := c.body.Read(p)
+	if n > 0 && c.err == nil {
+		if _, werr := c.file.Write(p[:n]); werr != nil {
+			c.err = werr
+		}
+		c.size += int64(n)
+	}
+	if errors.Is(err, io.EOF) {
+		c.complete = true
+	}
+	return n, err
+}
+
+func (c *BodyCapture) Close() error {
+	return c.body.Close()
+}
+
+// Open returns the captured body and its size, or an error if the body was not read
+// completely or could not be written. The returned file is owned by the capture.
+func (c *BodyCapture) Open() (*os.File, int64, error) {
+	switch {
+	case c.err != nil:
+		return nil, 0, fmt.Errorf("failed to write capture file: %w", c.err)
+	case !c.complete:
+		return nil, 0, fmt.Errorf("body was not read completely")
+	case c.expected >= 0 && c.size != c.expected:
+		return nil, 0, fmt.Errorf("captured %d bytes, expected %d", c.size, c.expected)
+	}
+
+	if _, err := c.file.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, fmt.Errorf("failed to rewind capture file: %w", err)
+	}
+	return c.file, c.size, nil
+}
+
+// Remove closes and deletes the temporary file
+func (c *BodyCapture) Remove() error {
+	closeErr := c.file.Close()
+	if err := os.Remove(c.file.Name()); err != nil {
+		return err
+	}
+	return closeErr
+}
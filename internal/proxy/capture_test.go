@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBodyCapture(t *testing.T) {
+	tests := []struct {
+		name          string
+		contentLength int64
+		readAll       bool
+		wantErr       bool
+	}{
+		{"complete", 8, true, false},
+		{"unknown length", -1, true, false},
+		{"not read completely", 8, false, true},
+		{"truncated", 100, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &Response{
+				Body:     io.NopCloser(strings.NewReader("artifact")),
+				HTTPResp: &http.Response{ContentLength: tt.contentLength},
+			}
+			capture, err := CaptureBody(resp)
+			if err != nil {
+				t.Fatalf("CaptureBody failed: %v", err)
+			}
+			defer func() {
+				if err := capture.Remove(); err != nil {
+					t.Errorf("Remove failed: %v", err)
+				}
+			}()
+
+			var streamed []byte
+			if tt.readAll {
+				streamed, err = io.ReadAll(resp.Body)
+			} else {
+				streamed = make([]byte, 3)
+				_, err = io.ReadFull(resp.Body, streamed)
+			}
+			if err != nil {
+				t.Fatalf("reading body failed: %v", err)
+			}
+			_ = resp.Body.Close()
+
+			file, size, err := capture.Open()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			captured, err := io.ReadAll(file)
+			if err != nil {
+				t.Fatalf("reading capture failed: %v", err)
+			}
+			if string(captured) != string(streamed) || size != int64(len(streamed)) {
+				t.Errorf("captured %q (%d bytes), want %q", captured, size, streamed)
+			}
+		})
+	}
+}
@@ -1,33 +1,81 @@
 package proxy
 
 import (
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
+	"github.com/mainuli/artifusion/internal/config"
 	"github.com/mainuli/artifusion/internal/metrics"
 	"github.com/rs/zerolog"
 	"github.com/sony/gobreaker"
 )
 
+// CircuitBreakers protects backend calls with per-backend circuit breakers.
+// CircuitBreakerManager is the implementation used by the proxy.
+type CircuitBreakers interface {
+	// Execute runs fn unless the backend's circuit breaker is open
+	Execute(backend BackendConfig, fn func() (interface{}, error)) (interface{}, error)
+}
+
+// CircuitBreaker guards the calls to one backend. *gobreaker.CircuitBreaker
+// implements it.
+type CircuitBreaker interface {
+	Execute(fn func() (interface{}, error)) (interface{}, error)
+	State() gobreaker.State
+	Counts() gobreaker.Counts
+}
+
+// CircuitBreakerFactory creates a backend's circuit breaker of one type. settings
+// carry the backend name, the configured limits, the failure-ratio trip condition
+// and the state change reporting shared by all types.
+type CircuitBreakerFactory func(settings gobreaker.Settings, cfg *config.CircuitBreakerConfig) CircuitBreaker
+
 // CircuitBreakerManager manages circuit breakers for multiple backends
 type CircuitBreakerManager struct {
-	breakers map[string]*gobreaker.CircuitBreaker
-	mu       sync.RWMutex
-	logger   zerolog.Logger
-	metrics  *metrics.Metrics
+	breakers  map[string]CircuitBreaker
+	factories map[string]CircuitBreakerFactory
+	mu        sync.RWMutex
+	logger    zerolog.Logger
+	metrics   *metrics.Metrics
+
+	// onRecovery is called in its own goroutine when a backend's breaker closes
+	// (nil = not set)
+	onRecovery func(backend BackendConfig)
 }
 
-// NewCircuitBreakerManager creates a new circuit breaker manager
+// NewCircuitBreakerManager creates a new circuit breaker manager supporting the
+// failure_ratio and latency breaker types
 func NewCircuitBreakerManager(logger zerolog.Logger, metrics *metrics.Metrics) *CircuitBreakerManager {
 	return &CircuitBreakerManager{
-		breakers: make(map[string]*gobreaker.CircuitBreaker),
-		logger:   logger.With().Str("component", "circuit_breaker").Logger(),
-		metrics:  metrics,
+		breakers: make(map[string]CircuitBreaker),
+		factories: map[string]CircuitBreakerFactory{
+			config.CircuitBreakerFailureRatio: newFailureRatioBreaker,
+			config.CircuitBreakerLatency:      newLatencyBreaker,
+		},
+		logger:  logger.With().Str("component", "circuit_breaker").Logger(),
+		metrics: metrics,
 	}
 }
 
+// RegisterType registers the factory of a circuit breaker type backends can select
+// with circuit_breaker.type. Must be called before the first request.
+func (cbm *CircuitBreakerManager) RegisterType(name string, factory CircuitBreakerFactory) {
+	cbm.mu.Lock()
+	defer cbm.mu.Unlock()
+	cbm.factories[name] = factory
+}
+
+// SetRecoveryHook registers fn to be called, in its own goroutine, whenever a
+// backend's circuit breaker closes after the backend recovered. Must be called
+// before the first request.
+func (cbm *CircuitBreakerManager) SetRecoveryHook(fn func(backend BackendConfig)) {
+	cbm.onRecovery = fn
+}
+
 // GetOrCreate gets or creates a circuit breaker for a backend
-func (cbm *CircuitBreakerManager) GetOrCreate(backend BackendConfig) *gobreaker.CircuitBreaker {
+func (cbm *CircuitBreakerManager) GetOrCreate(backend BackendConfig) CircuitBreaker {
 	cbConfig := backend.GetCircuitBreaker()
 	if cbConfig == nil || !cbConfig.Enabled {
 		return nil
@@ -76,10 +124,29 @@ func (cbm *CircuitBreakerManager) GetOrCreate(backend BackendConfig) *gobreaker.
 			if cbm.metrics != nil {
 				cbm.metrics.SetCircuitBreakerState(name, StateToInt(to))
 			}
+
+			// Called with the breaker locked, so the hook must not block it
+			if to == gobreaker.StateClosed && cbm.onRecovery != nil {
+				go cbm.onRecovery(backend)
+			}
 		},
 	}
 
-	cb = gobreaker.NewCircuitBreaker(settings)
+	breakerType := cbConfig.Type
+	if breakerType == "" {
+		breakerType = config.CircuitBreakerFailureRatio
+	}
+	factory, ok := cbm.factories[breakerType]
+	if !ok {
+		// Configuration validation rejects unknown types; fail safe if one slips through
+		cbm.logger.Error().
+			Str("backend", backendName).
+			Str("type", breakerType).
+			Msg("Unknown circuit breaker type, using failure_ratio")
+		factory = newFailureRatioBreaker
+	}
+
+	cb = factory(settings, cbConfig)
 	cbm.breakers[backendName] = cb
 
 	return cb
@@ -167,3 +234,52 @@ func StateToInt(state gobreaker.State) int {
 		return -1
 	}
 }
+
+// newFailureRatioBreaker creates a breaker that trips when the ratio of failed
+// calls reaches the configured failure threshold
+func newFailureRatioBreaker(settings gobreaker.Settings, _ *config.CircuitBreakerConfig) CircuitBreaker {
+	return gobreaker.NewCircuitBreaker(settings)
+}
+
+// latencyBreaker is a failure-ratio breaker that also counts successful calls
+// slower than a threshold as failures. It catches backends that degrade by slowing
+// down rather than by failing, which a failure-ratio breaker never trips on.
+type latencyBreaker struct {
+	*gobreaker.CircuitBreaker
+	slowCallThreshold time.Duration
+}
+
+// slowCallError marks a successful call that took too long, carrying its result
+// through gobreaker so the call still succeeds for the caller
+type slowCallError struct {
+	result interface{}
+}
+
+func (e *slowCallError) Error() string { return "slow call" }
+
+// newLatencyBreaker creates a breaker that trips when the ratio of failed and slow
+// calls reaches the configured failure threshold
+func newLatencyBreaker(settings gobreaker.Settings, cfg *config.CircuitBreakerConfig) CircuitBreaker {
+	return &latencyBreaker{
+		CircuitBreaker:    gobreaker.NewCircuitBreaker(settings),
+		slowCallThreshold: cfg.SlowCallThreshold,
+	}
+}
+
+// Execute runs fn, counting it as failed if it errors or succeeds too slowly
+func (lb *latencyBreaker) Execute(fn func() (interface{}, error)) (interface{}, error) {
+	result, err := lb.CircuitBreaker.Execute(func() (interface{}, error) {
+		start := time.Now()
+		result, err := fn()
+		if err == nil && time.Since(start) >= lb.slowCallThreshold {
+			return nil, &slowCallError{result: result}
+		}
+		return result, err
+	})
+
+	var slow *slowCallError
+	if errors.As(err, &slow) {
+		return slow.result, nil
+	}
+	return result, err
+}
@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/sony/gobreaker"
+)
+
+func TestCircuitBreakerManager_Types(t *testing.T) {
+	slow := func() (interface{}, error) {
+		time.Sleep(20 * time.Millisecond)
+		return "ok", nil
+	}
+
+	tests := []struct {
+		name     string
+		cbType   string
+		wantOpen bool
+	}{
+		{name: "failure ratio ignores slow successes", cbType: config.CircuitBreakerFailureRatio, wantOpen: false},
+		{name: "latency trips on slow successes", cbType: config.CircuitBreakerLatency, wantOpen: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &config.MavenBackendConfig{
+				Name: "maven-" + tt.cbType,
+				CircuitBreaker: config.CircuitBreakerConfig{
+					Enabled:           true,
+					MaxRequests:       1,
+					Interval:          time.Minute,
+					Timeout:           time.Minute,
+					FailureThreshold:  0.5,
+					Type:              tt.cbType,
+					SlowCallThreshold: 10 * time.Millisecond,
+				},
+			}
+			cbm := NewCircuitBreakerManager(zerolog.Nop(), nil)
+
+			// Slow calls still return their result to the caller
+			for i := 0; i < 3; i++ {
+				result, err := cbm.Execute(backend, slow)
+				if err != nil || result != "ok" {
+					t.Fatalf("call %d = (%v, %v), want (ok, nil)", i, result, err)
+				}
+			}
+
+			if open := cbm.GetState(backend.Name) == gobreaker.StateOpen; open != tt.wantOpen {
+				t.Fatalf("open = %v, want %v", open, tt.wantOpen)
+			}
+			_, err := cbm.Execute(backend, slow)
+			if rejected := errors.Is(err, gobreaker.ErrOpenState); rejected != tt.wantOpen {
+				t.Errorf("next call error = %v, want rejected = %v", err, tt.wantOpen)
+			}
+		})
+	}
+}
+
+type countingBreaker struct {
+	CircuitBreaker
+	calls int
+}
+
+func (b *countingBreaker) Execute(fn func() (interface{}, error)) (interface{}, error) {
+	b.calls++
+	return fn()
+}
+
+func TestCircuitBreakerManager_RegisterType(t *testing.T) {
+	cbm := NewCircuitBreakerManager(zerolog.Nop(), nil)
+	custom := &countingBreaker{}
+	cbm.RegisterType("counting", func(gobreaker.Settings, *config.CircuitBreakerConfig) CircuitBreaker {
+		return custom
+	})
+
+	backend := &config.NPMBackendConfig{
+		Name:           "npm",
+		CircuitBreaker: config.CircuitBreakerConfig{Enabled: true, Type: "counting"},
+	}
+	if _, err := cbm.Execute(backend, func() (interface{}, error) { return nil, nil }); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if custom.calls != 1 {
+		t.Errorf("custom breaker calls = %d, want 1", custom.calls)
+	}
+}
@@ -1,6 +1,9 @@
 package proxy
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -10,6 +13,9 @@ import (
 	"time"
 
 	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/utils"
+	"github.com/mainuli/artifusion/internal/vault"
 	"github.com/rs/zerolog"
 )
 
@@ -23,26 +29,54 @@ type BackendConfig interface {
 	GetIdleConnTimeout() time.Duration
 	GetDialTimeout() time.Duration
 	GetRequestTimeout() time.Duration
+	GetResponseHeaderTimeout() time.Duration
 	GetCircuitBreaker() *config.CircuitBreakerConfig
+	GetTransport() *config.TransportConfig
 }
 
 // Client handles backend proxying with connection pooling
 type Client struct {
-	httpClients       map[string]*http.Client
-	mu                sync.RWMutex
-	logger            zerolog.Logger
-	circuitBreakerMgr *CircuitBreakerManager
+	httpClients     map[string]*http.Client
+	mu              sync.RWMutex
+	logger          zerolog.Logger
+	circuitBreakers CircuitBreakers  // Optional (nil = no circuit breakers)
+	metrics         *metrics.Metrics // Optional (nil = no connection pool metrics)
+	transfers       *transferBudgets
+	shadowLog       *ShadowLog    // Optional (nil = no shadow logging)
+	vault           *vault.Client // Optional (nil = no Vault references)
+
+	// Installation token sources of backends authenticating as GitHub Apps, by
+	// backend name (guarded by mu)
+	githubApps map[string]*githubAppTokens
 }
 
-// NewClient creates a new proxy client
-func NewClient(logger zerolog.Logger, cbManager *CircuitBreakerManager) *Client {
+// NewClient creates a new proxy client.
+// If breakers is non-nil, backend calls are protected by their circuit breakers.
+// If m is non-nil, each backend's connection pool usage is recorded.
+func NewClient(logger zerolog.Logger, breakers CircuitBreakers, m *metrics.Metrics) *Client {
 	return &Client{
-		httpClients:       make(map[string]*http.Client),
-		logger:            logger,
-		circuitBreakerMgr: cbManager,
+		httpClients:     make(map[string]*http.Client),
+		logger:          logger,
+		circuitBreakers: breakers,
+		metrics:         m,
+		transfers:       newTransferBudgets(m),
+		githubApps:      make(map[string]*githubAppTokens),
 	}
 }
 
+// SetVault resolves the Vault references in backend credentials with v, on every
+// request so rotated credentials are picked up.
+// Must be called before the client is used concurrently.
+func (c *Client) SetVault(v *vault.Client) {
+	c.vault = v
+}
+
+// SetShadowLog records the requests to backends with an active shadow log window.
+// Must be called before the client is used concurrently.
+func (c *Client) SetShadowLog(shadowLog *ShadowLog) {
+	c.shadowLog = shadowLog
+}
+
 // Request represents a proxy request
 type Request struct {
 	Method      string
@@ -52,6 +86,10 @@ type Request struct {
 	Headers     http.Header
 	Backend     BackendConfig
 	OriginalReq *http.Request
+
+	// ContentLength is the length of Body if known and Body is a stream, so it is sent
+	// with a Content-Length instead of chunked (0 = unknown)
+	ContentLength int64
 }
 
 // Response represents a proxy response
@@ -60,6 +98,8 @@ type Response struct {
 	Headers    http.Header
 	Body       io.ReadCloser
 	HTTPResp   *http.Response
+
+	backend BackendConfig // Backend the response is from, for transfer accounting
 }
 
 // hopByHopHeaders lists HTTP/1.1 hop-by-hop headers per RFC 7230 Section 6.1.
@@ -127,8 +167,8 @@ func removeHopByHopHeaders(headers http.Header) http.Header {
 // ProxyRequest proxies a request to the backend with connection pooling and circuit breaker protection
 func (c *Client) ProxyRequest(req *Request) (*Response, error) {
 	// If circuit breaker is enabled for this backend, wrap the request
-	if c.circuitBreakerMgr != nil {
-		result, err := c.circuitBreakerMgr.Execute(req.Backend, func() (interface{}, error) {
+	if c.circuitBreakers != nil {
+		result, err := c.circuitBreakers.Execute(req.Backend, func() (interface{}, error) {
 			return c.doProxyRequest(req)
 		})
 
@@ -158,6 +198,12 @@ func (c *Client) doProxyRequest(req *Request) (*Response, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create backend request: %w", err)
 	}
+	if req.ContentLength > 0 {
+		backendReq.ContentLength = req.ContentLength
+	}
+	if hostHeader := req.Backend.GetTransport().HostHeader; hostHeader != "" {
+		backendReq.Host = hostHeader
+	}
 
 	// SECURITY: Filter hop-by-hop headers before forwarding (RFC 7230 Section 6.1)
 	// This prevents HTTP request smuggling and connection poisoning attacks
@@ -173,6 +219,14 @@ func (c *Client) doProxyRequest(req *Request) (*Response, error) {
 		}
 	}
 
+	// WebSocket upgrades are tunneled; the Upgrade and Connection headers were
+	// dropped as hop-by-hop headers, which also refuses other upgrades such as h2c
+	upgrade := utils.IsWebSocketUpgrade(req.Headers)
+	if upgrade {
+		backendReq.Header.Set("Connection", "Upgrade")
+		backendReq.Header.Set("Upgrade", "websocket")
+	}
+
 	// Inject backend authentication if configured
 	if err := c.injectBackendAuth(backendReq, req.Backend); err != nil {
 		return nil, fmt.Errorf("failed to inject backend auth: %w", err)
@@ -181,12 +235,34 @@ func (c *Client) doProxyRequest(req *Request) (*Response, error) {
 	// Get or create HTTP client for this backend
 	client := c.getOrCreateClient(req.Backend)
 
+	// The stream watchdog aborts the body by canceling the backend request
+	transportCfg := req.Backend.GetTransport()
+	var cancel context.CancelCauseFunc
+	if streamWatchdogEnabled(transportCfg) && !upgrade {
+		var ctx context.Context
+		ctx, cancel = context.WithCancelCause(backendReq.Context())
+		backendReq = backendReq.WithContext(ctx)
+	}
+
 	// Execute request
 	startTime := time.Now()
-	resp, err := client.Do(backendReq)
+	var resp *http.Response
+	if upgrade {
+		// Bypass the client: its request timeout would close the tunnel, and an
+		// upgraded connection lives as long as both sides keep it open
+		resp, err = client.Transport.RoundTrip(backendReq)
+	} else {
+		resp, err = client.Do(backendReq)
+	}
 	duration := time.Since(startTime)
+	if c.shadowLog != nil {
+		c.shadowLog.record(req, backendReq, resp, err, startTime, duration)
+	}
 
 	if err != nil {
+		if cancel != nil {
+			cancel(err)
+		}
 		c.logger.Error().Err(err).
 			Str("backend", req.Backend.GetName()).
 			Str("url", backendURL).
@@ -201,29 +277,78 @@ func (c *Client) doProxyRequest(req *Request) (*Response, error) {
 		Dur("duration", duration).
 		Msg("Backend response received")
 
+	body := resp.Body
+	if cancel != nil {
+		backendName := req.Backend.GetName()
+		body = newWatchdogBody(resp.Body, backendReq.Context(), cancel, transportCfg, func(reason string, read int64) {
+			c.logger.Warn().
+				Str("backend", backendName).
+				Str("url", backendURL).
+				Str("reason", reason).
+				Int64("bytes_read", read).
+				Dur("elapsed", time.Since(startTime)).
+				Msg("Aborting stalled backend stream")
+			if c.metrics != nil {
+				c.metrics.RecordStreamAbort(backendName, reason)
+			}
+		})
+	}
+
+	if transportCfg.VerifyContentDigest && !upgrade {
+		backendName := req.Backend.GetName()
+		if verified := newDigestBody(body, backendReq.Method, resp, func(err error) {
+			c.logger.Error().Err(err).
+				Str("backend", backendName).
+				Str("url", backendURL).
+				Msg("Backend response failed Content-Digest verification")
+			if c.metrics != nil {
+				c.metrics.RecordDigestMismatch(backendName)
+			}
+		}); verified != nil {
+			body = verified
+		}
+	}
+
 	return &Response{
 		StatusCode: resp.StatusCode,
 		Headers:    resp.Header,
-		Body:       resp.Body,
+		Body:       body,
 		HTTPResp:   resp,
+		backend:    req.Backend,
 	}, nil
 }
 
 // StreamResponse streams the response to the client with zero-copy
 func (c *Client) StreamResponse(w http.ResponseWriter, resp *Response, copyHeaders bool) (int64, error) {
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		return c.tunnel(w, resp, copyHeaders)
+	}
+
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			c.logger.Warn().Err(err).Msg("Failed to close response body after streaming")
 		}
 	}()
 
-	// Copy response headers if requested
+	// Copy response headers if requested, declaring the backend's trailers so they
+	// can be copied after the body
 	if copyHeaders {
 		for key, values := range resp.Headers {
 			for _, value := range values {
 				w.Header().Add(key, value)
 			}
 		}
+		if resp.HTTPResp != nil {
+			for key := range resp.HTTPResp.Trailer {
+				w.Header().Add("Trailer", key)
+			}
+		}
+	}
+
+	// Wait for the backend's transfer budget before committing to the response
+	transfer, err := c.startTransfer(resp)
+	if err != nil {
+		return 0, err
 	}
 
 	// Write status code
@@ -231,7 +356,21 @@ func (c *Client) StreamResponse(w http.ResponseWriter, resp *Response, copyHeade
 
 	// Stream response body (zero-copy, no buffering)
 	// CRITICAL: For multi-GB files, streaming prevents memory exhaustion
-	bytesWritten, err := io.Copy(w, resp.Body)
+	dst := io.Writer(w)
+	if flusher, ok := w.(http.Flusher); ok && flushImmediately(resp) {
+		dst = &flushWriter{w: w, flusher: flusher}
+	}
+	if transfer != nil {
+		transfer.w = dst
+		dst = transfer
+		defer transfer.close()
+	}
+	bytesWritten, err := io.Copy(dst, resp.Body)
+	if errors.Is(err, errDigestMismatch) {
+		// Abort the connection so the client can't mistake the truncated body for a
+		// complete one; the recovery middleware lets http.ErrAbortHandler through
+		panic(http.ErrAbortHandler)
+	}
 	if err != nil {
 		c.logger.Error().Err(err).
 			Int64("bytes_written", bytesWritten).
@@ -239,6 +378,15 @@ func (c *Client) StreamResponse(w http.ResponseWriter, resp *Response, copyHeade
 		return bytesWritten, err
 	}
 
+	// Trailers are complete once the body has been read
+	if copyHeaders && resp.HTTPResp != nil {
+		for key, values := range resp.HTTPResp.Trailer {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+	}
+
 	c.logger.Debug().
 		Int64("bytes", bytesWritten).
 		Msg("Response streamed successfully")
@@ -246,6 +394,40 @@ func (c *Client) StreamResponse(w http.ResponseWriter, resp *Response, copyHeade
 	return bytesWritten, nil
 }
 
+// startTransfer reserves the body of resp in its backend's transfer budget, waiting
+// while the backend's transfers in progress exceed max_in_flight_bytes. It returns
+// nil if the body's length is unknown, which leaves the transfer unaccounted.
+func (c *Client) startTransfer(resp *Response) (*transferWriter, error) {
+	httpResp := resp.HTTPResp
+	if resp.backend == nil || httpResp == nil || httpResp.ContentLength <= 0 ||
+		httpResp.Request == nil || httpResp.Request.Method == http.MethodHead {
+		return nil, nil
+	}
+
+	backend := resp.backend.GetName()
+	maxInFlight := resp.backend.GetTransport().MaxInFlightBytes
+	if maxInFlight > 0 && c.transfers.inFlight(backend)+httpResp.ContentLength > maxInFlight {
+		c.logger.Debug().
+			Str("backend", backend).
+			Int64("content_length", httpResp.ContentLength).
+			Int64("max_in_flight_bytes", maxInFlight).
+			Msg("Waiting for backend transfer budget")
+	}
+	if err := c.transfers.acquire(httpResp.Request.Context(), backend, httpResp.ContentLength, maxInFlight); err != nil {
+		c.logger.Warn().Err(err).
+			Str("backend", backend).
+			Int64("content_length", httpResp.ContentLength).
+			Msg("Gave up waiting for backend transfer budget")
+		return nil, err
+	}
+
+	return &transferWriter{
+		budgets:   c.transfers,
+		backend:   backend,
+		remaining: httpResp.ContentLength,
+	}, nil
+}
+
 // ReadResponseBody reads the full response body into memory
 // Use only for small responses that need to be modified (e.g., XML rewriting)
 func (c *Client) ReadResponseBody(resp *Response) ([]byte, error) {
@@ -268,7 +450,8 @@ func (c *Client) ReadResponseBody(resp *Response) ([]byte, error) {
 	return body, nil
 }
 
-// WriteResponse writes a modified response body to the client
+// WriteResponse writes a modified response body to the client. Responses to HEAD
+// requests are written without a body or Content-Length.
 func (c *Client) WriteResponse(w http.ResponseWriter, resp *Response, body []byte, copyHeaders bool) error {
 	// Copy response headers if requested
 	if copyHeaders {
@@ -279,8 +462,21 @@ func (c *Client) WriteResponse(w http.ResponseWriter, resp *Response, body []byt
 		}
 	}
 
-	// Update Content-Length
+	// A HEAD response has no body to measure the rewritten length of, and the
+	// backend's length and digest described the original body
+	if resp.HTTPResp != nil && resp.HTTPResp.Request != nil && resp.HTTPResp.Request.Method == http.MethodHead {
+		w.Header().Del("Content-Length")
+		w.Header().Del(contentDigestHeader)
+		w.WriteHeader(resp.StatusCode)
+		return nil
+	}
+
+	// Update Content-Length, and the backend's Content-Digest which described the
+	// original body
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(body)))
+	if w.Header().Get(contentDigestHeader) != "" {
+		w.Header().Set(contentDigestHeader, contentDigest(body))
+	}
 
 	// Write status code
 	w.WriteHeader(resp.StatusCode)
@@ -322,6 +518,16 @@ func validateAuthCredentials(auth *config.AuthConfig) error {
 		if strings.ContainsAny(auth.Token, "\r\n") {
 			return fmt.Errorf("token cannot contain newlines")
 		}
+	case "github_app":
+		if auth.AppID <= 0 || auth.InstallationID <= 0 {
+			return fmt.Errorf("github_app auth requires app_id and installation_id")
+		}
+		if (auth.PrivateKey == "") == (auth.PrivateKeyFile == "") {
+			return fmt.Errorf("github_app auth requires one of private_key or private_key_file")
+		}
+		if strings.ContainsAny(auth.Username, "\r\n") {
+			return fmt.Errorf("username cannot contain newlines")
+		}
 	case "header":
 		if auth.HeaderName == "" || auth.HeaderValue == "" {
 			return fmt.Errorf("header auth requires both header_name and header_value")
@@ -340,6 +546,30 @@ func validateAuthCredentials(auth *config.AuthConfig) error {
 	return nil
 }
 
+// resolveVaultReferences returns auth with its Vault references replaced by their
+// current values
+func (c *Client) resolveVaultReferences(auth *config.AuthConfig) (*config.AuthConfig, error) {
+	if len(vault.References(auth)) == 0 {
+		return auth, nil
+	}
+	if c.vault == nil {
+		return nil, fmt.Errorf("vault is not enabled")
+	}
+
+	resolved := *auth
+	for _, field := range vault.AuthFields(&resolved) {
+		if !vault.IsReference(*field) {
+			continue
+		}
+		value, err := c.vault.Lookup(*field)
+		if err != nil {
+			return nil, err
+		}
+		*field = value
+	}
+	return &resolved, nil
+}
+
 // injectBackendAuth adds authentication headers to the backend request if configured
 func (c *Client) injectBackendAuth(req *http.Request, backend BackendConfig) error {
 	// Check if backend has authentication configured
@@ -358,6 +588,11 @@ func (c *Client) injectBackendAuth(req *http.Request, backend BackendConfig) err
 		return nil
 	}
 
+	auth, err := c.resolveVaultReferences(auth)
+	if err != nil {
+		return fmt.Errorf("failed to resolve backend credentials of %s: %w", backend.GetName(), err)
+	}
+
 	// Validate credentials
 	if err := validateAuthCredentials(auth); err != nil {
 		return fmt.Errorf("invalid backend auth configuration for %s: %w", backend.GetName(), err)
@@ -374,6 +609,18 @@ func (c *Client) injectBackendAuth(req *http.Request, backend BackendConfig) err
 		// Bearer token authentication
 		req.Header.Set("Authorization", "Bearer "+auth.Token)
 		injectedAuthType = "bearer"
+	case "github_app":
+		// Short-lived GitHub App installation token
+		token, err := c.githubAppToken(req.Context(), backend, auth)
+		if err != nil {
+			return err
+		}
+		if auth.Username != "" {
+			req.SetBasicAuth(auth.Username, token)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		injectedAuthType = "github_app"
 	case "header":
 		// Custom header authentication
 		req.Header.Set(auth.HeaderName, auth.HeaderValue)
@@ -420,6 +667,8 @@ func (c *Client) getOrCreateClient(backend BackendConfig) *http.Client {
 		return client
 	}
 
+	transportCfg := backend.GetTransport()
+
 	// Create HTTP transport with aggressive connection pooling for high concurrency
 	transport := &http.Transport{
 		// Connection pooling
@@ -429,26 +678,50 @@ func (c *Client) getOrCreateClient(backend BackendConfig) *http.Client {
 
 		// Connection establishment
 		DialContext: (&net.Dialer{
-			Timeout:   backend.GetDialTimeout(),
-			KeepAlive: 30 * time.Second,
+			Timeout:       backend.GetDialTimeout(),
+			KeepAlive:     transportCfg.KeepAlive,
+			FallbackDelay: transportCfg.FallbackDelay,
 		}).DialContext,
 
-		// TLS optimization
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
+		// Fail hung backends before the request timeout
+		ResponseHeaderTimeout: backend.GetResponseHeaderTimeout(),
+
+		// TLS and protocol negotiation
+		TLSHandshakeTimeout:   transportCfg.TLSHandshakeTimeout,
+		ExpectContinueTimeout: transportCfg.ExpectContinueTimeout,
+		ForceAttemptHTTP2:     transportCfg.ForceAttemptHTTP2,
 
 		// Reuse connections
 		DisableKeepAlives: false,
 	}
 
+	if transportCfg.TLSServerName != "" {
+		transport.TLSClientConfig = &tls.Config{ServerName: transportCfg.TLSServerName}
+	}
+
+	// Resolve backend addresses in the proxy to rotate across them and skip dead IPs,
+	// or to control the IP families dialed
+	if transportCfg.DNSRefreshInterval > 0 {
+		dns := newDNSCache(transportCfg, c.logger.With().Str("backend", backend.GetName()).Logger())
+		transport.DialContext = dns.dialContext(transport.DialContext)
+	} else if transportCfg.IPFamily != "" {
+		transport.DialContext = newFamilyDialer(transport.DialContext, transportCfg.IPFamily, transportCfg.FallbackDelay).DialContext
+	}
+
+	// Instrument the connection pool
+	var roundTripper http.RoundTripper = transport
+	if c.metrics != nil {
+		tracker := newPoolTracker(backend.GetName(), c.metrics)
+		transport.DialContext = tracker.dialContext(transport.DialContext)
+		roundTripper = &trackedTransport{base: transport, tracker: tracker}
+	}
+
 	// Create HTTP client
 	client = &http.Client{
-		Transport: transport,
+		Transport: roundTripper,
 		Timeout:   backend.GetRequestTimeout(),
-		// Don't follow redirects by default - let caller decide
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
+		// Pass redirects through to the client unless the backend follows them
+		CheckRedirect: redirectPolicy(backend, c.logger),
 	}
 
 	c.httpClients[backend.GetName()] = client
@@ -458,6 +731,7 @@ func (c *Client) getOrCreateClient(backend BackendConfig) *http.Client {
 		Int("max_idle_conns", backend.GetMaxIdleConns()).
 		Int("max_idle_conns_per_host", backend.GetMaxIdleConnsPerHost()).
 		Dur("timeout", backend.GetRequestTimeout()).
+		Dur("response_header_timeout", backend.GetResponseHeaderTimeout()).
 		Msg("Created HTTP client for backend")
 
 	return client
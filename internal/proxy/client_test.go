@@ -2,8 +2,13 @@ package proxy
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
 )
 
 func TestRemoveHopByHopHeaders(t *testing.T) {
@@ -390,3 +395,87 @@ func TestRemoveHopByHopHeaders_RequestSmugglingPrevention(t *testing.T) {
 		}
 	})
 }
+
+func TestProxyRequest_ResponseHeaderTimeout(t *testing.T) {
+	// The backend accepts the connection but never sends response headers
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	backendCfg := &config.MavenBackendConfig{
+		Name:                  "hung",
+		URL:                   backend.URL,
+		MaxIdleConns:          1,
+		MaxIdleConnsPerHost:   1,
+		DialTimeout:           time.Second,
+		RequestTimeout:        30 * time.Second,
+		ResponseHeaderTimeout: 50 * time.Millisecond,
+	}
+
+	client := NewClient(zerolog.Nop(), nil, nil)
+	start := time.Now()
+	_, err := client.ProxyRequest(&Request{
+		Method:      http.MethodGet,
+		Path:        "/artifact.jar",
+		Headers:     http.Header{},
+		Backend:     backendCfg,
+		OriginalReq: httptest.NewRequest(http.MethodGet, "/artifact.jar", nil),
+	})
+	if err == nil {
+		t.Fatal("expected the request to fail on the response header timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request failed after %v, want it to fail well before the request timeout", elapsed)
+	}
+}
+
+func TestProxyRequest_HostHeaderAndTLSServerName(t *testing.T) {
+	var gotHost string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+	}))
+	defer backend.Close()
+
+	backendCfg := &config.NPMBackendConfig{
+		Name:                "shared-lb",
+		URL:                 backend.URL,
+		MaxIdleConns:        1,
+		MaxIdleConnsPerHost: 1,
+		DialTimeout:         time.Second,
+		RequestTimeout:      5 * time.Second,
+		Transport: config.TransportConfig{
+			HostHeader:    "npm.internal.example.com",
+			TLSServerName: "lb.internal.example.com",
+		},
+	}
+
+	client := NewClient(zerolog.Nop(), nil, nil)
+	resp, err := client.ProxyRequest(&Request{
+		Method:      http.MethodGet,
+		Path:        "/lodash",
+		Headers:     http.Header{},
+		Backend:     backendCfg,
+		OriginalReq: httptest.NewRequest(http.MethodGet, "/lodash", nil),
+	})
+	if err != nil {
+		t.Fatalf("ProxyRequest() error = %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if gotHost != "npm.internal.example.com" {
+		t.Errorf("backend got Host %q, want the configured host_header", gotHost)
+	}
+	transport, ok := client.getOrCreateClient(backendCfg).Transport.(*http.Transport)
+	if !ok {
+		t.Fatal("client transport is not an *http.Transport")
+	}
+	if transport.TLSClientConfig == nil || transport.TLSClientConfig.ServerName != "lb.internal.example.com" {
+		t.Errorf("TLSClientConfig = %+v, want ServerName lb.internal.example.com", transport.TLSClientConfig)
+	}
+}
@@ -0,0 +1,201 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+// defaultFallbackDelay matches net.Dialer's default Happy Eyeballs delay
+const defaultFallbackDelay = 300 * time.Millisecond
+
+// familyDialer resolves hostnames itself so it can dial addresses of the configured
+// IP family (transport.ip_family) in the configured order
+type familyDialer struct {
+	dial          dialFunc
+	lookup        func(ctx context.Context, host string) ([]net.IPAddr, error)
+	family        string
+	fallbackDelay time.Duration
+}
+
+// newFamilyDialer creates a family dialer using the system resolver
+func newFamilyDialer(dial dialFunc, family string, fallbackDelay time.Duration) *familyDialer {
+	return &familyDialer{
+		dial:          dial,
+		lookup:        net.DefaultResolver.LookupIPAddr,
+		family:        family,
+		fallbackDelay: fallbackDelay,
+	}
+}
+
+// DialContext dials addr, racing the preferred and the fallback family if both apply
+func (d *familyDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return d.dial(ctx, network, addr)
+	}
+
+	addrs, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP
+	}
+
+	primaries, fallbacks := splitByFamily(ips, d.family)
+	if len(primaries) == 0 && len(fallbacks) == 0 {
+		return nil, noAddressesError(host, d.family)
+	}
+
+	return dialParallel(ctx, primaries, fallbacks, d.fallbackDelay, func(ctx context.Context, ip net.IP) (net.Conn, error) {
+		return d.dial(ctx, network, net.JoinHostPort(ip.String(), port))
+	})
+}
+
+// splitByFamily splits addresses into those to dial first and those to fall back to,
+// keeping their order. Addresses of a family excluded by family are dropped.
+func splitByFamily(ips []net.IP, family string) (primaries, fallbacks []net.IP) {
+	for _, ip := range ips {
+		isIPv4 := ip.To4() != nil
+		switch family {
+		case config.IPFamilyIPv4:
+			if isIPv4 {
+				primaries = append(primaries, ip)
+			}
+		case config.IPFamilyIPv6:
+			if !isIPv4 {
+				primaries = append(primaries, ip)
+			}
+		case config.IPFamilyPreferIPv4:
+			if isIPv4 {
+				primaries = append(primaries, ip)
+			} else {
+				fallbacks = append(fallbacks, ip)
+			}
+		case config.IPFamilyPreferIPv6:
+			if !isIPv4 {
+				primaries = append(primaries, ip)
+			} else {
+				fallbacks = append(fallbacks, ip)
+			}
+		default:
+			primaries = append(primaries, ip)
+		}
+	}
+	return primaries, fallbacks
+}
+
+// noAddressesError reports that host has no addresses usable with family
+func noAddressesError(host, family string) error {
+	msg := "no addresses"
+	switch family {
+	case config.IPFamilyIPv4:
+		msg = "no IPv4 addresses"
+	case config.IPFamilyIPv6:
+		msg = "no IPv6 addresses"
+	}
+	return &net.DNSError{Err: msg, Name: host, IsNotFound: true}
+}
+
+// dialParallel dials primaries in order and, once the fallback delay passes or the
+// primaries fail, dials the fallbacks in parallel (Happy Eyeballs). The first
+// connection wins and the other attempt is canceled. A negative delay dials the
+// fallbacks only after every primary failed.
+func dialParallel(ctx context.Context, primaries, fallbacks []net.IP, delay time.Duration, dial func(ctx context.Context, ip net.IP) (net.Conn, error)) (net.Conn, error) {
+	if len(fallbacks) == 0 || len(primaries) == 0 || delay < 0 {
+		return dialSerial(ctx, append(primaries, fallbacks...), dial)
+	}
+	if delay == 0 {
+		delay = defaultFallbackDelay
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn    net.Conn
+		err     error
+		primary bool
+	}
+	results := make(chan result)
+	start := func(ips []net.IP, primary bool) {
+		go func() {
+			conn, err := dialSerial(ctx, ips, dial)
+			results <- result{conn: conn, err: err, primary: primary}
+		}()
+	}
+
+	start(primaries, true)
+	pending := 1
+	fallbackStarted := false
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var primaryErr, fallbackErr error
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				start(fallbacks, false)
+			}
+
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				// Close the connection of an attempt that succeeds after losing the race
+				if pending > 0 {
+					go func(n int) {
+						for range n {
+							if late := <-results; late.conn != nil {
+								_ = late.conn.Close()
+							}
+						}
+					}(pending)
+				}
+				return res.conn, nil
+			}
+
+			if res.primary {
+				primaryErr = res.err
+			} else {
+				fallbackErr = res.err
+			}
+
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				start(fallbacks, false)
+			} else if pending == 0 {
+				return nil, errors.Join(primaryErr, fallbackErr)
+			}
+		}
+	}
+}
+
+// dialSerial dials addresses in order and returns the first connection
+func dialSerial(ctx context.Context, ips []net.IP, dial func(ctx context.Context, ip net.IP) (net.Conn, error)) (net.Conn, error) {
+	var firstErr error
+	for _, ip := range ips {
+		conn, err := dial(ctx, ip)
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if firstErr == nil {
+		firstErr = errors.New("no addresses to dial")
+	}
+	return nil, firstErr
+}
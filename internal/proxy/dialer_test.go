@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+func TestSplitByFamily(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("2001:db8::1"),
+		net.ParseIP("10.0.0.1"),
+		net.ParseIP("2001:db8::2"),
+		net.ParseIP("10.0.0.2"),
+	}
+
+	tests := []struct {
+		family        string
+		wantPrimaries []string
+		wantFallbacks []string
+	}{
+		{"", []string{"2001:db8::1", "10.0.0.1", "2001:db8::2", "10.0.0.2"}, nil},
+		{config.IPFamilyIPv4, []string{"10.0.0.1", "10.0.0.2"}, nil},
+		{config.IPFamilyIPv6, []string{"2001:db8::1", "2001:db8::2"}, nil},
+		{config.IPFamilyPreferIPv4, []string{"10.0.0.1", "10.0.0.2"}, []string{"2001:db8::1", "2001:db8::2"}},
+		{config.IPFamilyPreferIPv6, []string{"2001:db8::1", "2001:db8::2"}, []string{"10.0.0.1", "10.0.0.2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run("family="+tt.family, func(t *testing.T) {
+			primaries, fallbacks := splitByFamily(ips, tt.family)
+			if got := ipStrings(primaries); !slices.Equal(got, tt.wantPrimaries) {
+				t.Errorf("primaries = %v, want %v", got, tt.wantPrimaries)
+			}
+			if got := ipStrings(fallbacks); !slices.Equal(got, tt.wantFallbacks) {
+				t.Errorf("fallbacks = %v, want %v", got, tt.wantFallbacks)
+			}
+		})
+	}
+}
+
+func TestFamilyDialer_IPv4Only(t *testing.T) {
+	resolver := &fakeResolver{addrs: []string{"2001:db8::1", "10.0.0.1"}}
+	dialer := &fakeDialer{}
+	d := newFamilyDialer(dialer.dial, config.IPFamilyIPv4, 0)
+	d.lookup = resolver.lookup
+
+	conn, err := d.DialContext(context.Background(), "tcp", "registry.example.com:443")
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	_ = conn.Close()
+
+	if want := []string{"10.0.0.1:443"}; !slices.Equal(dialer.dialed, want) {
+		t.Errorf("dialed %v, want %v", dialer.dialed, want)
+	}
+}
+
+func TestFamilyDialer_NoAddressesOfFamily(t *testing.T) {
+	resolver := &fakeResolver{addrs: []string{"10.0.0.1"}}
+	d := newFamilyDialer((&fakeDialer{}).dial, config.IPFamilyIPv6, 0)
+	d.lookup = resolver.lookup
+
+	if _, err := d.DialContext(context.Background(), "tcp", "registry.example.com:443"); err == nil {
+		t.Fatal("expected an error when the host has no IPv6 addresses")
+	}
+}
+
+func TestFamilyDialer_FallsBackFromStalledFamily(t *testing.T) {
+	resolver := &fakeResolver{addrs: []string{"2001:db8::1", "10.0.0.1"}}
+
+	// IPv6 dials hang until canceled, like a broken IPv6 route
+	dial := func(ctx context.Context, _, addr string) (net.Conn, error) {
+		if addr == "[2001:db8::1]:443" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		client, server := net.Pipe()
+		_ = server.Close()
+		return client, nil
+	}
+
+	d := newFamilyDialer(dial, config.IPFamilyPreferIPv6, 20*time.Millisecond)
+	d.lookup = resolver.lookup
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	conn, err := d.DialContext(ctx, "tcp", "registry.example.com:443")
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	_ = conn.Close()
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("dial took %v, want the IPv4 fallback shortly after the fallback delay", elapsed)
+	}
+}
@@ -0,0 +1,225 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// contentDigestHeader carries the digest of the message content (RFC 9530), either as
+// a header or as a trailer
+const contentDigestHeader = "Content-Digest"
+
+// errDigestMismatch is returned by a verified body whose content doesn't match the
+// backend's Content-Digest
+var errDigestMismatch = errors.New("backend response does not match its Content-Digest")
+
+// digestAlgorithms lists the supported Content-Digest algorithms, strongest first
+var digestAlgorithms = []struct {
+	name string
+	new  func() hash.Hash
+}{
+	{name: "sha-512", new: sha512.New},
+	{name: "sha-256", new: sha256.New},
+}
+
+// digestBody verifies a backend response body against its Content-Digest while it
+// streams.
+//
+// The last byte read is held back until the digest is verified at EOF: on mismatch
+// it is never returned, so a client receiving a Content-Length response sees a short
+// body even if the proxy can't abort the connection in time.
+type digestBody struct {
+	body       io.ReadCloser
+	resp       *http.Response
+	hashes     map[string]hash.Hash
+	onMismatch func(err error)
+
+	last     byte // held back until more data or a verified EOF follows
+	held     bool
+	verified bool
+	err      error
+}
+
+// newDigestBody wraps body, the (possibly already wrapped) body of resp, in a
+// verifying body. It returns nil if resp has no Content-Digest to verify, as header or
+// declared trailer, in a supported algorithm. onMismatch is called once on mismatch.
+func newDigestBody(body io.ReadCloser, method string, resp *http.Response, onMismatch func(err error)) *digestBody {
+	// Bodyless responses, and bodies the transport decompressed, can't match the
+	// digest of the content sent
+	if method == http.MethodHead || resp.StatusCode == http.StatusNoContent ||
+		resp.StatusCode == http.StatusNotModified || resp.Uncompressed {
+		return nil
+	}
+
+	hashes := make(map[string]hash.Hash)
+	if value := resp.Header.Get(contentDigestHeader); value != "" {
+		digests := parseContentDigest(value)
+		for _, alg := range digestAlgorithms {
+			if _, ok := digests[alg.name]; ok {
+				hashes[alg.name] = alg.new()
+				break
+			}
+		}
+	} else if _, declared := resp.Trailer[contentDigestHeader]; declared {
+		// The algorithm is only known at EOF
+		for _, alg := range digestAlgorithms {
+			hashes[alg.name] = alg.new()
+		}
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	return &digestBody{
+		body:       body,
+		resp:       resp,
+		hashes:     hashes,
+		onMismatch: onMismatch,
+	}
+}
+
+// Read reads from the backend body, holding back its last byte until verified
+func (b *digestBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if b.verified {
+		// Release the byte held back when p had no room left at EOF
+		b.err = io.EOF
+		if b.held {
+			b.held = false
+			p[0] = b.last
+			return 1, io.EOF
+		}
+		return 0, io.EOF
+	}
+
+	// Read behind the held byte, which goes first into p
+	start := 0
+	if b.held {
+		start = 1
+	}
+	var scratch [1]byte
+	dst := p[start:]
+	if len(dst) == 0 {
+		dst = scratch[:]
+	}
+
+	n, err := b.body.Read(dst)
+	for _, h := range b.hashes {
+		h.Write(dst[:n])
+	}
+
+	out := 0
+	if n > 0 {
+		if b.held {
+			p[0] = b.last
+			out = 1
+		}
+		if start == len(p) {
+			// One byte released, one read into scratch and held
+			b.last = scratch[0]
+		} else {
+			out += n - 1
+			b.last = dst[n-1]
+		}
+		b.held = true
+	}
+
+	if err != io.EOF {
+		return out, err
+	}
+
+	if verr := b.verify(); verr != nil {
+		b.err = verr
+		b.held = false
+		if b.onMismatch != nil {
+			b.onMismatch(verr)
+		}
+		return out, verr
+	}
+
+	b.verified = true
+	if b.held && out < len(p) {
+		p[out] = b.last
+		out++
+		b.held = false
+	}
+	if b.held {
+		return out, nil
+	}
+	b.err = io.EOF
+	return out, io.EOF
+}
+
+// verify compares the content read with the Content-Digest header or trailer, using
+// the strongest algorithm both support
+func (b *digestBody) verify() error {
+	value := b.resp.Header.Get(contentDigestHeader)
+	if value == "" {
+		value = b.resp.Trailer.Get(contentDigestHeader)
+	}
+	digests := parseContentDigest(value)
+
+	for _, alg := range digestAlgorithms {
+		h, hashed := b.hashes[alg.name]
+		want, sent := digests[alg.name]
+		if !hashed || !sent {
+			continue
+		}
+		if !bytes.Equal(h.Sum(nil), want) {
+			return fmt.Errorf("%w (%s)", errDigestMismatch, alg.name)
+		}
+		return nil
+	}
+
+	// A declared trailer that never arrived, or only in unsupported algorithms
+	return nil
+}
+
+// Close closes the backend body
+func (b *digestBody) Close() error {
+	return b.body.Close()
+}
+
+// contentDigest returns the SHA-256 Content-Digest value of body
+func contentDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+}
+
+// parseContentDigest parses a Content-Digest value, a structured field dictionary of
+// algorithms to base64 byte sequences such as "sha-256=:<base64>:", into decoded
+// digests by lowercase algorithm. Malformed members are skipped.
+func parseContentDigest(value string) map[string][]byte {
+	digests := make(map[string][]byte)
+	for _, member := range strings.Split(value, ",") {
+		name, digest, ok := strings.Cut(strings.TrimSpace(member), "=")
+		if !ok {
+			continue
+		}
+		// Parameters aren't used by any registered algorithm
+		digest, _, _ = strings.Cut(digest, ";")
+		digest = strings.TrimSpace(digest)
+		if len(digest) < 2 || digest[0] != ':' || digest[len(digest)-1] != ':' {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(digest[1 : len(digest)-1])
+		if err != nil {
+			continue
+		}
+		digests[strings.ToLower(strings.TrimSpace(name))] = decoded
+	}
+	return digests
+}
@@ -0,0 +1,198 @@
+package proxy
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func TestDigestBody(t *testing.T) {
+	const content = "artifact contents"
+	valid := contentDigest([]byte(content))
+	corrupt := contentDigest([]byte("tampered contents"))
+
+	tests := []struct {
+		name     string
+		header   string
+		trailer  string
+		readSize int
+		wantNil  bool
+		wantErr  bool
+	}{
+		{name: "valid header", header: valid, readSize: 4096},
+		{name: "valid header, byte reads", header: valid, readSize: 1},
+		{name: "valid trailer", trailer: valid, readSize: 4},
+		{name: "sha-512 preferred", header: "sha-256=:AAAA:, sha-512=:" + sha512Base64(content) + ":", readSize: 4096},
+		{name: "mismatch", header: corrupt, readSize: 4096, wantErr: true},
+		{name: "mismatch, byte reads", header: corrupt, readSize: 1, wantErr: true},
+		{name: "trailer mismatch", trailer: corrupt, readSize: 3, wantErr: true},
+		{name: "no digest", readSize: 4096, wantNil: true},
+		{name: "unsupported algorithm", header: "md5=:AAAA:", readSize: 4096, wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+			if tt.header != "" {
+				resp.Header.Set(contentDigestHeader, tt.header)
+			}
+			if tt.trailer != "" {
+				// Declared up front, filled in once the body has been read
+				resp.Trailer = http.Header{contentDigestHeader: nil}
+			}
+			body := &trailerBody{Reader: strings.NewReader(content), resp: resp, trailer: tt.trailer}
+
+			mismatches := 0
+			b := newDigestBody(body, http.MethodGet, resp, func(error) { mismatches++ })
+			if (b == nil) != tt.wantNil {
+				t.Fatalf("newDigestBody() = %v, want nil = %v", b, tt.wantNil)
+			}
+			if b == nil {
+				return
+			}
+
+			got, err := readAllSize(b, tt.readSize)
+			if tt.wantErr {
+				if !errors.Is(err, errDigestMismatch) {
+					t.Fatalf("error = %v, want digest mismatch", err)
+				}
+				if len(got) >= len(content) {
+					t.Errorf("read %d bytes, want the last byte withheld", len(got))
+				}
+				if mismatches != 1 {
+					t.Errorf("onMismatch called %d times, want 1", mismatches)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != content {
+				t.Errorf("body = %q, want %q", got, content)
+			}
+		})
+	}
+}
+
+func TestStreamResponse_ContentDigest(t *testing.T) {
+	const content = "chart-1.0.0.tgz contents"
+
+	tests := []struct {
+		name    string
+		trailer string
+		wantErr bool
+	}{
+		{name: "trailer copied through", trailer: contentDigest([]byte(content))},
+		{name: "mismatch aborts transfer", trailer: contentDigest([]byte("tampered")), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Trailer", contentDigestHeader)
+				w.WriteHeader(http.StatusOK)
+				_, _ = io.WriteString(w, content)
+				w.Header().Set(contentDigestHeader, tt.trailer)
+			}))
+			defer backend.Close()
+
+			backendCfg := &config.NPMBackendConfig{
+				Name:           "npm",
+				URL:            backend.URL,
+				MaxIdleConns:   10,
+				DialTimeout:    time.Second,
+				RequestTimeout: 10 * time.Second,
+				Transport:      config.TransportConfig{VerifyContentDigest: true},
+			}
+			client := NewClient(zerolog.Nop(), nil, nil)
+			client.httpClients[backendCfg.Name] = backend.Client()
+
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				resp, err := client.ProxyRequest(&Request{
+					Method:      http.MethodGet,
+					Path:        "/pkg.tgz",
+					Headers:     http.Header{},
+					Backend:     backendCfg,
+					OriginalReq: r,
+				})
+				if err != nil {
+					t.Errorf("proxy request failed: %v", err)
+					return
+				}
+				_, _ = client.StreamResponse(w, resp, true)
+			}))
+			defer proxy.Close()
+
+			resp, err := http.Get(proxy.URL)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			got, err := io.ReadAll(resp.Body)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("read %q without error, want the transfer aborted", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != content {
+				t.Errorf("body = %q, want %q", got, content)
+			}
+			if trailer := resp.Trailer.Get(contentDigestHeader); trailer != tt.trailer {
+				t.Errorf("Content-Digest trailer = %q, want %q", trailer, tt.trailer)
+			}
+		})
+	}
+}
+
+// trailerBody sets a Content-Digest trailer on resp when reaching EOF, as the
+// transport does for chunked responses
+type trailerBody struct {
+	*strings.Reader
+	resp    *http.Response
+	trailer string
+}
+
+func (b *trailerBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err == io.EOF && b.trailer != "" {
+		b.resp.Trailer.Set(contentDigestHeader, b.trailer)
+	}
+	return n, err
+}
+
+func (b *trailerBody) Close() error { return nil }
+
+// readAllSize reads r to EOF in reads of size bytes
+func readAllSize(r io.Reader, size int) ([]byte, error) {
+	var out []byte
+	buf := make([]byte, size)
+	for {
+		n, err := r.Read(buf)
+		out = append(out, buf[:n]...)
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return out, err
+		}
+	}
+}
+
+func sha512Base64(content string) string {
+	sum := sha512.Sum512([]byte(content))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,263 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/constants"
+	"github.com/rs/zerolog"
+	"golang.org/x/sync/singleflight"
+)
+
+// dialFunc matches http.Transport.DialContext
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dnsCache resolves backend hostnames itself instead of leaving it to each dial.
+//
+// Addresses are re-resolved once they are older than the refresh interval, so a
+// backend moving to new IPs is picked up without a restart. Dials rotate across all
+// resolved addresses; an address that fails to connect is skipped for the cooldown
+// and the dial moves on to the next one, so a dead IP doesn't fail the request.
+type dnsCache struct {
+	lookup        func(ctx context.Context, host string) ([]net.IPAddr, error)
+	refresh       time.Duration
+	cooldown      time.Duration
+	family        string        // transport.ip_family
+	fallbackDelay time.Duration // transport.fallback_delay
+	now           func() time.Time
+	logger        zerolog.Logger
+
+	group singleflight.Group
+	mu    sync.Mutex
+	hosts map[string]*hostAddrs
+}
+
+// hostAddrs holds the resolved addresses of a host and their health
+type hostAddrs struct {
+	addrs       []net.IP
+	resolved    time.Time
+	next        int                  // Rotation offset for the next dial
+	failedUntil map[string]time.Time // Addresses skipped until the given time
+}
+
+// newDNSCache creates a DNS cache for a backend's transport settings using the
+// system resolver
+func newDNSCache(cfg *config.TransportConfig, logger zerolog.Logger) *dnsCache {
+	return &dnsCache{
+		lookup:        net.DefaultResolver.LookupIPAddr,
+		refresh:       cfg.DNSRefreshInterval,
+		cooldown:      cfg.DNSFailureCooldown,
+		family:        cfg.IPFamily,
+		fallbackDelay: cfg.FallbackDelay,
+		now:           time.Now,
+		logger:        logger,
+		hosts:         make(map[string]*hostAddrs),
+	}
+}
+
+// dialContext wraps dial so hostnames are dialed through the cache. Addresses that
+// are already IPs are dialed directly.
+func (c *dnsCache) dialContext(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		ips, err := c.addresses(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		primaries, fallbacks := splitByFamily(ips, c.family)
+		if len(primaries) == 0 && len(fallbacks) == 0 {
+			return nil, noAddressesError(host, c.family)
+		}
+
+		return dialParallel(ctx, primaries, fallbacks, c.fallbackDelay, func(ctx context.Context, ip net.IP) (net.Conn, error) {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				c.markHealthy(host, ip)
+				return conn, nil
+			}
+
+			// The caller giving up, or another address winning the race, says nothing
+			// about the address
+			if ctx.Err() == nil {
+				c.markFailed(host, ip, err)
+			}
+			return nil, err
+		})
+	}
+}
+
+// addresses returns the addresses to try for host, in order: healthy addresses
+// starting at the rotation offset, then addresses in cooldown as a last resort
+func (c *dnsCache) addresses(ctx context.Context, host string) ([]net.IP, error) {
+	entry, err := c.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	ordered := make([]net.IP, 0, len(entry.addrs))
+	var failed []net.IP
+	for i := range entry.addrs {
+		ip := entry.addrs[(entry.next+i)%len(entry.addrs)]
+		if until, ok := entry.failedUntil[ip.String()]; ok && now.Before(until) {
+			failed = append(failed, ip)
+			continue
+		}
+		ordered = append(ordered, ip)
+	}
+	entry.next = (entry.next + 1) % len(entry.addrs)
+
+	return append(ordered, failed...), nil
+}
+
+// resolve returns the cached entry for host, re-resolving it when it is stale.
+// If re-resolution fails the previous addresses are kept.
+func (c *dnsCache) resolve(ctx context.Context, host string) (*hostAddrs, error) {
+	c.mu.Lock()
+	entry, ok := c.hosts[host]
+	fresh := ok && c.now().Sub(entry.resolved) < c.refresh
+	c.mu.Unlock()
+
+	if fresh {
+		return entry, nil
+	}
+
+	// Concurrent dials share one lookup. The lookup is detached from the first
+	// caller's context so its cancellation doesn't fail the other callers.
+	_, err, _ := c.group.Do(host, func() (interface{}, error) {
+		lookupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), constants.BackendDNSLookupTimeout)
+		defer cancel()
+
+		addrs, err := c.lookup(lookupCtx, host)
+		if err == nil && len(addrs) == 0 {
+			err = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+		}
+		c.store(host, addrs, err)
+		return nil, err
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok = c.hosts[host]
+	if !ok {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// store records the result of resolving host
+func (c *dnsCache) store(host string, addrs []net.IPAddr, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.hosts[host]
+	if err != nil {
+		if ok {
+			// Keep serving the last known addresses and retry after the next interval
+			entry.resolved = c.now()
+			c.logger.Warn().Err(err).
+				Str("host", host).
+				Int("addresses", len(entry.addrs)).
+				Msg("Failed to re-resolve backend host, keeping previous addresses")
+		}
+		return
+	}
+
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.IP
+	}
+
+	if !ok {
+		entry = &hostAddrs{failedUntil: make(map[string]time.Time)}
+		c.hosts[host] = entry
+	} else if !sameAddrs(entry.addrs, ips) {
+		c.logger.Info().
+			Str("host", host).
+			Strs("previous", ipStrings(entry.addrs)).
+			Strs("current", ipStrings(ips)).
+			Msg("Backend host addresses changed")
+	}
+
+	entry.addrs = ips
+	entry.resolved = c.now()
+	if entry.next >= len(ips) {
+		entry.next = 0
+	}
+
+	// Drop health state of addresses that are gone
+	for addr := range entry.failedUntil {
+		if !containsIP(ips, addr) {
+			delete(entry.failedUntil, addr)
+		}
+	}
+}
+
+// markFailed puts an address into cooldown after a failed dial
+func (c *dnsCache) markFailed(host string, ip net.IP, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.hosts[host]; ok {
+		entry.failedUntil[ip.String()] = c.now().Add(c.cooldown)
+	}
+
+	c.logger.Warn().Err(err).
+		Str("host", host).
+		Str("address", ip.String()).
+		Dur("cooldown", c.cooldown).
+		Msg("Backend address failed to connect, trying next address")
+}
+
+// markHealthy clears the cooldown of an address after a successful dial
+func (c *dnsCache) markHealthy(host string, ip net.IP) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.hosts[host]; ok {
+		delete(entry.failedUntil, ip.String())
+	}
+}
+
+// sameAddrs reports whether a and b contain the same addresses in the same order
+func sameAddrs(a, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsIP reports whether ips contains the address addr
+func containsIP(ips []net.IP, addr string) bool {
+	for _, ip := range ips {
+		if ip.String() == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// ipStrings formats addresses for logging
+func ipStrings(ips []net.IP) []string {
+	out := make([]string, len(ips))
+	for i, ip := range ips {
+		out[i] = ip.String()
+	}
+	return out
+}
@@ -0,0 +1,191 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// fakeResolver returns the configured addresses, or err
+type fakeResolver struct {
+	addrs   []string
+	err     error
+	lookups int
+}
+
+func (r *fakeResolver) lookup(_ context.Context, _ string) ([]net.IPAddr, error) {
+	r.lookups++
+	if r.err != nil {
+		return nil, r.err
+	}
+	addrs := make([]net.IPAddr, len(r.addrs))
+	for i, addr := range r.addrs {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(addr)}
+	}
+	return addrs, nil
+}
+
+// fakeDialer records dialed addresses and fails dials to down addresses
+type fakeDialer struct {
+	down   map[string]bool
+	dialed []string
+}
+
+func (d *fakeDialer) dial(_ context.Context, _, addr string) (net.Conn, error) {
+	d.dialed = append(d.dialed, addr)
+	if d.down[addr] {
+		return nil, errors.New("connection refused")
+	}
+	client, server := net.Pipe()
+	_ = server.Close()
+	return client, nil
+}
+
+func newTestDNSCache(resolver *fakeResolver, now *time.Time) *dnsCache {
+	c := newDNSCache(&config.TransportConfig{
+		DNSRefreshInterval: time.Minute,
+		DNSFailureCooldown: 30 * time.Second,
+	}, zerolog.Nop())
+	c.lookup = resolver.lookup
+	c.now = func() time.Time { return *now }
+	return c
+}
+
+func TestDNSCache_RotatesAcrossAddresses(t *testing.T) {
+	now := time.Now()
+	resolver := &fakeResolver{addrs: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}}
+	dialer := &fakeDialer{}
+	dial := newTestDNSCache(resolver, &now).dialContext(dialer.dial)
+
+	for range 3 {
+		conn, err := dial(context.Background(), "tcp", "registry.example.com:443")
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		_ = conn.Close()
+	}
+
+	want := []string{"10.0.0.1:443", "10.0.0.2:443", "10.0.0.3:443"}
+	if !slices.Equal(dialer.dialed, want) {
+		t.Errorf("dialed %v, want %v", dialer.dialed, want)
+	}
+	if resolver.lookups != 1 {
+		t.Errorf("lookups = %d, want 1 within the refresh interval", resolver.lookups)
+	}
+}
+
+func TestDNSCache_SkipsFailedAddresses(t *testing.T) {
+	now := time.Now()
+	resolver := &fakeResolver{addrs: []string{"10.0.0.1", "10.0.0.2"}}
+	dialer := &fakeDialer{down: map[string]bool{"10.0.0.1:443": true}}
+	dial := newTestDNSCache(resolver, &now).dialContext(dialer.dial)
+
+	// The first dial fails over from the dead address to the next one
+	conn, err := dial(context.Background(), "tcp", "registry.example.com:443")
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	_ = conn.Close()
+
+	// While in cooldown the dead address is tried last, so it isn't dialed again
+	dialer.dialed = nil
+	for range 2 {
+		conn, err := dial(context.Background(), "tcp", "registry.example.com:443")
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		_ = conn.Close()
+	}
+	if want := []string{"10.0.0.2:443", "10.0.0.2:443"}; !slices.Equal(dialer.dialed, want) {
+		t.Errorf("dialed %v during cooldown, want %v", dialer.dialed, want)
+	}
+
+	// After the cooldown the address is back in rotation
+	now = now.Add(31 * time.Second)
+	delete(dialer.down, "10.0.0.1:443")
+	dialer.dialed = nil
+	for range 2 {
+		conn, err := dial(context.Background(), "tcp", "registry.example.com:443")
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		_ = conn.Close()
+	}
+	if !slices.Contains(dialer.dialed, "10.0.0.1:443") {
+		t.Errorf("dialed %v after cooldown, want 10.0.0.1:443 back in rotation", dialer.dialed)
+	}
+}
+
+func TestDNSCache_AllAddressesDown(t *testing.T) {
+	now := time.Now()
+	resolver := &fakeResolver{addrs: []string{"10.0.0.1", "10.0.0.2"}}
+	dialer := &fakeDialer{down: map[string]bool{"10.0.0.1:443": true, "10.0.0.2:443": true}}
+	dial := newTestDNSCache(resolver, &now).dialContext(dialer.dial)
+
+	if _, err := dial(context.Background(), "tcp", "registry.example.com:443"); err == nil {
+		t.Fatal("expected dial to fail when every address is down")
+	}
+	if len(dialer.dialed) != 2 {
+		t.Errorf("dialed %v, want every address tried", dialer.dialed)
+	}
+}
+
+func TestDNSCache_Refresh(t *testing.T) {
+	now := time.Now()
+	resolver := &fakeResolver{addrs: []string{"10.0.0.1"}}
+	dialer := &fakeDialer{}
+	dial := newTestDNSCache(resolver, &now).dialContext(dialer.dial)
+
+	dialOnce := func() {
+		t.Helper()
+		conn, err := dial(context.Background(), "tcp", "registry.example.com:443")
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		_ = conn.Close()
+	}
+
+	dialOnce()
+
+	// The backend moves; the new address is used once the entry is stale
+	resolver.addrs = []string{"10.0.0.9"}
+	now = now.Add(time.Minute)
+	dialOnce()
+	if last := dialer.dialed[len(dialer.dialed)-1]; last != "10.0.0.9:443" {
+		t.Errorf("dialed %s after refresh, want 10.0.0.9:443", last)
+	}
+
+	// A failed re-resolution keeps the previous addresses
+	resolver.err = errors.New("SERVFAIL")
+	now = now.Add(time.Minute)
+	dialOnce()
+	if last := dialer.dialed[len(dialer.dialed)-1]; last != "10.0.0.9:443" {
+		t.Errorf("dialed %s after failed refresh, want 10.0.0.9:443", last)
+	}
+	if resolver.lookups != 3 {
+		t.Errorf("lookups = %d, want 3", resolver.lookups)
+	}
+}
+
+func TestDNSCache_IPAddressesBypassCache(t *testing.T) {
+	now := time.Now()
+	resolver := &fakeResolver{}
+	dialer := &fakeDialer{}
+	dial := newTestDNSCache(resolver, &now).dialContext(dialer.dial)
+
+	conn, err := dial(context.Background(), "tcp", "192.0.2.10:8080")
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	_ = conn.Close()
+
+	if resolver.lookups != 0 {
+		t.Errorf("lookups = %d, want 0 for an IP address", resolver.lookups)
+	}
+}
@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// MaxDecodedBodySize bounds the decompressed size of backend bodies buffered for
+// rewriting (64MB), so a small compressed body can't expand without limit in memory
+const MaxDecodedBodySize = 64 * 1024 * 1024
+
+// ErrDecodedBodyTooLarge is returned for compressed bodies decompressing to more
+// than the limit
+var ErrDecodedBodyTooLarge = errors.New("decompressed body exceeds size limit")
+
+// ErrUnsupportedEncoding is returned for Content-Encodings that can't be decoded,
+// such as br or stacked encodings
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// normalizeEncoding returns a Content-Encoding value in lowercase, with "" for identity
+func normalizeEncoding(contentEncoding string) string {
+	encoding := strings.ToLower(strings.TrimSpace(contentEncoding))
+	if encoding == "identity" {
+		return ""
+	}
+	return encoding
+}
+
+// DecodeBody decompresses a body with the given Content-Encoding (gzip, x-gzip or
+// deflate; identity bodies are returned as they are), reading at most limit bytes
+// of decompressed content
+func DecodeBody(body []byte, contentEncoding string, limit int64) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+	switch normalizeEncoding(contentEncoding) {
+	case "":
+		return body, nil
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		// deflate is zlib-wrapped (RFC 9110), but some servers send raw deflate
+		reader, err = zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			reader, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, contentEncoding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s body: %w", contentEncoding, err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	decoded, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s body: %w", contentEncoding, err)
+	}
+	if int64(len(decoded)) > limit {
+		return nil, ErrDecodedBodyTooLarge
+	}
+	return decoded, nil
+}
+
+// EncodeBody compresses a body with the given Content-Encoding (gzip, x-gzip or
+// deflate; identity bodies are returned as they are)
+func EncodeBody(body []byte, contentEncoding string) ([]byte, error) {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch normalizeEncoding(contentEncoding) {
+	case "":
+		return body, nil
+	case "gzip", "x-gzip":
+		writer = gzip.NewWriter(&buf)
+	case "deflate":
+		writer = zlib.NewWriter(&buf)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, contentEncoding)
+	}
+
+	if _, err := writer.Write(body); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RewriteEncodedBody applies rewrite to the decompressed content of a backend body
+// encoded with the Content-Encoding in headers, and compresses the result the same
+// way: the backend only compressed it because the client's Accept-Encoding, which
+// the proxy forwards, allowed it. Bodies rewrite leaves unchanged are returned as
+// received. If the result can't be compressed again it is returned uncompressed and
+// Content-Encoding is removed from headers. On error (an unsupported encoding, a
+// corrupt body or one decompressing beyond MaxDecodedBodySize) body is returned
+// unmodified with the error, for the caller to serve as it is.
+func RewriteEncodedBody(body []byte, headers http.Header, rewrite func([]byte) []byte) ([]byte, error) {
+	encoding := normalizeEncoding(headers.Get("Content-Encoding"))
+	if encoding == "" {
+		return rewrite(body), nil
+	}
+
+	decoded, err := DecodeBody(body, encoding, MaxDecodedBodySize)
+	if err != nil {
+		return body, err
+	}
+	rewritten := rewrite(decoded)
+	if bytes.Equal(rewritten, decoded) {
+		return body, nil
+	}
+
+	encoded, err := EncodeBody(rewritten, encoding)
+	if err != nil {
+		headers.Del("Content-Encoding")
+		return rewritten, nil
+	}
+	return encoded, nil
+}
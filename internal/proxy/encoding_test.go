@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDecodeBody(t *testing.T) {
+	content := []byte(`{"dist":{"tarball":"http://verdaccio:4873/a/-/a-1.0.0.tgz"}}`)
+	gzipped, _ := EncodeBody(content, "gzip")
+	zlibbed, _ := EncodeBody(content, "deflate")
+	var raw bytes.Buffer
+	fw, _ := flate.NewWriter(&raw, flate.DefaultCompression)
+	_, _ = fw.Write(content)
+	_ = fw.Close()
+
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		limit    int64
+		wantErr  error
+	}{
+		{name: "identity", body: content, encoding: "", limit: 1024},
+		{name: "gzip", body: gzipped, encoding: "gzip", limit: 1024},
+		{name: "x-gzip", body: gzipped, encoding: "X-Gzip", limit: 1024},
+		{name: "zlib deflate", body: zlibbed, encoding: "deflate", limit: 1024},
+		{name: "raw deflate", body: raw.Bytes(), encoding: "deflate", limit: 1024},
+		{name: "over limit", body: gzipped, encoding: "gzip", limit: 10, wantErr: ErrDecodedBodyTooLarge},
+		{name: "unsupported", body: content, encoding: "br", limit: 1024, wantErr: ErrUnsupportedEncoding},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := DecodeBody(tt.body, tt.encoding, tt.limit)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("DecodeBody() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeBody() failed: %v", err)
+			}
+			if !bytes.Equal(decoded, content) {
+				t.Errorf("DecodeBody() = %q, want %q", decoded, content)
+			}
+		})
+	}
+}
+
+func TestRewriteEncodedBody(t *testing.T) {
+	content := []byte("http://verdaccio:4873/a/-/a-1.0.0.tgz")
+	rewrite := func(body []byte) []byte {
+		return bytes.ReplaceAll(body, []byte("http://verdaccio:4873"), []byte("https://proxy.example.com/npm"))
+	}
+	const want = "https://proxy.example.com/npm/a/-/a-1.0.0.tgz"
+
+	for _, encoding := range []string{"", "gzip", "deflate"} {
+		t.Run("encoding "+encoding, func(t *testing.T) {
+			body, _ := EncodeBody(content, encoding)
+			headers := http.Header{}
+			if encoding != "" {
+				headers.Set("Content-Encoding", encoding)
+			}
+
+			rewritten, err := RewriteEncodedBody(body, headers, rewrite)
+			if err != nil {
+				t.Fatalf("RewriteEncodedBody() failed: %v", err)
+			}
+			if headers.Get("Content-Encoding") != encoding {
+				t.Errorf("Content-Encoding = %q, want %q", headers.Get("Content-Encoding"), encoding)
+			}
+			decoded, err := DecodeBody(rewritten, encoding, MaxDecodedBodySize)
+			if err != nil {
+				t.Fatalf("rewritten body doesn't decode: %v", err)
+			}
+			if string(decoded) != want {
+				t.Errorf("rewritten content = %q, want %q", decoded, want)
+			}
+		})
+	}
+
+	t.Run("unchanged body is returned as received", func(t *testing.T) {
+		body, _ := EncodeBody([]byte("no backend URLs"), "gzip")
+		rewritten, err := RewriteEncodedBody(body, http.Header{"Content-Encoding": {"gzip"}}, rewrite)
+		if err != nil || !bytes.Equal(rewritten, body) {
+			t.Errorf("RewriteEncodedBody() = %v, %v, want the original body", rewritten, err)
+		}
+	})
+
+	t.Run("corrupt body is returned unmodified", func(t *testing.T) {
+		body := []byte("not gzip " + strings.Repeat("x", 16))
+		rewritten, err := RewriteEncodedBody(body, http.Header{"Content-Encoding": {"gzip"}}, rewrite)
+		if err == nil || !bytes.Equal(rewritten, body) {
+			t.Errorf("RewriteEncodedBody() = %q, %v, want the original body and an error", rewritten, err)
+		}
+	})
+}
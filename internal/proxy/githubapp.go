@@ -0,0 +1,201 @@
+package proxy
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// defaultGitHubAPIURL is the API installation tokens are minted with
+	defaultGitHubAPIURL = "https://api.github.com"
+
+	// githubAppTokenRefreshAhead is how long before expiry an installation token is
+	// replaced. Tokens are valid for an hour.
+	githubAppTokenRefreshAhead = 5 * time.Minute
+
+	// githubAppJWTLifetime is the lifetime of the app JWTs authenticating token
+	// requests (GitHub allows at most 10 minutes)
+	githubAppJWTLifetime = 9 * time.Minute
+)
+
+// githubAppTokens mints and caches the installation tokens of one backend's GitHub
+// App.
+//
+// Thread safety: All methods are safe for concurrent use.
+type githubAppTokens struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	client         *github.Client
+	logger         zerolog.Logger
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// newGitHubAppTokens creates the installation token source of auth
+func newGitHubAppTokens(auth *config.AuthConfig, logger zerolog.Logger) (*githubAppTokens, error) {
+	keyPEM := []byte(auth.PrivateKey)
+	if auth.PrivateKeyFile != "" {
+		var err error
+		if keyPEM, err = os.ReadFile(auth.PrivateKeyFile); err != nil {
+			return nil, fmt.Errorf("failed to read GitHub App private key: %w", err)
+		}
+	}
+	key, err := parseGitHubAppKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	client := github.NewClient(&http.Client{Timeout: 10 * time.Second})
+	if apiURL := strings.TrimSuffix(auth.GitHubAPIURL, "/"); apiURL != "" && apiURL != defaultGitHubAPIURL {
+		if client, err = client.WithEnterpriseURLs(apiURL, apiURL); err != nil {
+			return nil, fmt.Errorf("invalid GitHub API URL: %w", err)
+		}
+	}
+
+	return &githubAppTokens{
+		appID:          auth.AppID,
+		installationID: auth.InstallationID,
+		key:            key,
+		client:         client,
+		logger:         logger,
+	}, nil
+}
+
+// parseGitHubAppKey parses a GitHub App private key, as downloaded (PKCS #1) or
+// converted to PKCS #8
+func parseGitHubAppKey(keyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("GitHub App private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub App private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("GitHub App private key must be an RSA key")
+	}
+	return key, nil
+}
+
+// Token returns a valid installation token, minting a new one if the cached token
+// expires soon. Should minting fail, the cached token is used while it is valid.
+func (t *githubAppTokens) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if t.token != "" && t.expiresAt.Sub(now) > githubAppTokenRefreshAhead {
+		return t.token, nil
+	}
+
+	token, expiresAt, err := t.mint(ctx, now)
+	if err != nil {
+		if t.token != "" && t.expiresAt.After(now) {
+			t.logger.Warn().Err(err).
+				Int64("app_id", t.appID).
+				Time("expires_at", t.expiresAt).
+				Msg("Failed to refresh GitHub App installation token, using the current one")
+			return t.token, nil
+		}
+		return "", err
+	}
+
+	t.token, t.expiresAt = token, expiresAt
+	t.logger.Debug().
+		Int64("app_id", t.appID).
+		Int64("installation_id", t.installationID).
+		Time("expires_at", expiresAt).
+		Msg("Minted GitHub App installation token")
+	return token, nil
+}
+
+// mint requests a new installation token, authenticating as the app
+func (t *githubAppTokens) mint(ctx context.Context, now time.Time) (string, time.Time, error) {
+	jwt, err := t.appJWT(now)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	// Other requests wait for this token, so a client going away must not abort it;
+	// the HTTP client's timeout bounds the call
+	ctx = context.WithoutCancel(ctx)
+	installationToken, _, err := t.client.WithAuthToken(jwt).Apps.CreateInstallationToken(ctx, t.installationID, nil)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to mint GitHub App installation token: %w", err)
+	}
+	if installationToken.GetToken() == "" {
+		return "", time.Time{}, fmt.Errorf("failed to mint GitHub App installation token: empty token")
+	}
+	return installationToken.GetToken(), installationToken.GetExpiresAt().Time, nil
+}
+
+// appJWT returns a JWT authenticating as the app, backdated against clock skew
+func (t *githubAppTokens) appJWT(now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(githubAppJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(t.appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, t.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GitHub App JWT: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// githubAppToken returns an installation token of the GitHub App of backend,
+// creating its token source on first use
+func (c *Client) githubAppToken(ctx context.Context, backend BackendConfig, auth *config.AuthConfig) (string, error) {
+	c.mu.RLock()
+	tokens, exists := c.githubApps[backend.GetName()]
+	c.mu.RUnlock()
+
+	if !exists {
+		c.mu.Lock()
+		if tokens, exists = c.githubApps[backend.GetName()]; !exists {
+			var err error
+			tokens, err = newGitHubAppTokens(auth, c.logger.With().Str("backend", backend.GetName()).Logger())
+			if err != nil {
+				c.mu.Unlock()
+				return "", err
+			}
+			c.githubApps[backend.GetName()] = tokens
+		}
+		c.mu.Unlock()
+	}
+
+	return tokens.Token(ctx)
+}
@@ -0,0 +1,173 @@
+package proxy
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// fakeGitHubApps serves installation tokens of app 7, installation 42, valid for
+// tokenLifetime, to JWTs signed with key
+type fakeGitHubApps struct {
+	*httptest.Server
+	minted        atomic.Int32
+	tokenLifetime atomic.Int64
+	unavailable   atomic.Bool
+}
+
+func newFakeGitHubApps(t *testing.T, key *rsa.PrivateKey) *fakeGitHubApps {
+	t.Helper()
+	f := &fakeGitHubApps{}
+	f.tokenLifetime.Store(int64(time.Hour))
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f.unavailable.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/app/installations/42/access_tokens") {
+			http.NotFound(w, r)
+			return
+		}
+
+		// Verify the app JWT
+		jwt, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		parts := strings.Split(jwt, ".")
+		if len(parts) != 3 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		var claims struct {
+			Iss string `json:"iss"`
+			Exp int64  `json:"exp"`
+		}
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		if rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature) != nil ||
+			json.Unmarshal(payload, &claims) != nil || claims.Iss != "7" || claims.Exp < time.Now().Unix() {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		n := f.minted.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"token":"ghs_installation%d","expires_at":%q}`, n,
+			time.Now().Add(time.Duration(f.tokenLifetime.Load())).UTC().Format(time.RFC3339))
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestClient_GitHubAppAuth(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	github := newFakeGitHubApps(t, key)
+
+	var gotAuth atomic.Value
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth.Store(r.Header.Get("Authorization"))
+	}))
+	defer backend.Close()
+
+	newBackend := func(name, username string) *config.NPMBackendConfig {
+		return &config.NPMBackendConfig{
+			Name:           name,
+			URL:            backend.URL,
+			DialTimeout:    time.Second,
+			RequestTimeout: 5 * time.Second,
+			Auth: &config.AuthConfig{
+				Type:           "github_app",
+				Username:       username,
+				AppID:          7,
+				InstallationID: 42,
+				PrivateKey:     keyPEM,
+				GitHubAPIURL:   github.URL,
+			},
+		}
+	}
+
+	client := NewClient(zerolog.Nop(), nil, nil)
+	proxy := func(backendCfg *config.NPMBackendConfig) (string, error) {
+		t.Helper()
+		resp, err := client.ProxyRequest(&Request{
+			Method:      http.MethodGet,
+			Path:        "/@myorg/lib",
+			Headers:     http.Header{"Authorization": {"Bearer ghp_client"}},
+			Backend:     backendCfg,
+			OriginalReq: httptest.NewRequest(http.MethodGet, "/@myorg/lib", nil),
+		})
+		if err != nil {
+			return "", err
+		}
+		_ = resp.Body.Close()
+		return gotAuth.Load().(string), nil
+	}
+
+	t.Run("bearer token is cached", func(t *testing.T) {
+		backendCfg := newBackend("github-npm", "")
+		for range 3 {
+			got, err := proxy(backendCfg)
+			if err != nil {
+				t.Fatalf("ProxyRequest() error = %v", err)
+			}
+			if got != "Bearer ghs_installation1" {
+				t.Errorf("backend Authorization = %q, want the installation token", got)
+			}
+		}
+		if n := github.minted.Load(); n != 1 {
+			t.Errorf("minted %d tokens, want 1", n)
+		}
+	})
+
+	t.Run("basic auth refreshes before expiry", func(t *testing.T) {
+		github.minted.Store(0)
+		github.tokenLifetime.Store(int64(2 * time.Minute)) // Within the refresh window
+		backendCfg := newBackend("ghcr", "x-access-token")
+		if _, err := proxy(backendCfg); err != nil {
+			t.Fatalf("ProxyRequest() error = %v", err)
+		}
+		got, err := proxy(backendCfg)
+		if err != nil {
+			t.Fatalf("ProxyRequest() error = %v", err)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", got)
+		if username, password, ok := req.BasicAuth(); !ok || username != "x-access-token" || password != "ghs_installation2" {
+			t.Errorf("backend Authorization = %q, want the refreshed token as the x-access-token password", got)
+		}
+
+		// An unexpired token outlives a GitHub outage
+		github.unavailable.Store(true)
+		defer github.unavailable.Store(false)
+		if _, err := proxy(backendCfg); err != nil {
+			t.Errorf("ProxyRequest() during outage error = %v, want the current token used", err)
+		}
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		backendCfg := newBackend("broken", "")
+		backendCfg.Auth.PrivateKey = "not a key"
+		if _, err := proxy(backendCfg); err == nil || !strings.Contains(err.Error(), "not PEM encoded") {
+			t.Errorf("ProxyRequest() error = %v, want the key rejected", err)
+		}
+	})
+}
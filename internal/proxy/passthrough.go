@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"time"
+)
+
+// flushImmediately reports whether a response is streamed to the client as it
+// arrives rather than through the server's write buffer: event streams and
+// long-poll responses of unknown length, like httputil.ReverseProxy does
+func flushImmediately(resp *Response) bool {
+	if mediaType, _, err := mime.ParseMediaType(resp.Headers.Get("Content-Type")); err == nil && mediaType == "text/event-stream" {
+		return true
+	}
+	return resp.HTTPResp != nil && resp.HTTPResp.ContentLength == -1
+}
+
+// flushWriter flushes after every write
+type flushWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if n > 0 {
+		f.flusher.Flush()
+	}
+	return n, err
+}
+
+// tunnel completes a WebSocket upgrade the backend accepted: it takes over the
+// client connection, sends the backend's 101 response and copies data both ways
+// until either side closes. It returns the bytes sent to the client.
+func (c *Client) tunnel(w http.ResponseWriter, resp *Response, copyHeaders bool) (int64, error) {
+	backendConn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		_ = resp.Body.Close()
+		return 0, fmt.Errorf("backend switched protocols without a writable connection")
+	}
+	defer func() { _ = backendConn.Close() }()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return 0, fmt.Errorf("client connection does not support protocol upgrades")
+	}
+
+	if copyHeaders {
+		for key, values := range resp.Headers {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+	}
+
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		return 0, fmt.Errorf("failed to take over client connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// The server's read and write timeouts were meant for the upgrade request
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		c.logger.Warn().Err(err).Msg("Failed to clear upgraded connection deadlines")
+	}
+
+	if _, err := fmt.Fprintf(buf, "HTTP/1.1 %d %s\r\n", http.StatusSwitchingProtocols, http.StatusText(http.StatusSwitchingProtocols)); err != nil {
+		return 0, err
+	}
+	if err := w.Header().Write(buf); err != nil {
+		return 0, err
+	}
+	if _, err := buf.WriteString("\r\n"); err != nil {
+		return 0, err
+	}
+	if err := buf.Flush(); err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	var sent, received int64
+	errc := make(chan error, 2)
+	go func() {
+		var err error
+		// buf.Reader holds anything the client sent after the upgrade request
+		received, err = io.Copy(backendConn, buf.Reader)
+		errc <- err
+	}()
+	go func() {
+		var err error
+		sent, err = io.Copy(conn, backendConn)
+		errc <- err
+	}()
+
+	// Once one direction ends, closing both connections ends the other
+	err = <-errc
+	_ = conn.Close()
+	_ = backendConn.Close()
+	<-errc
+
+	c.logger.Debug().
+		Int64("bytes_sent", sent).
+		Int64("bytes_received", received).
+		Dur("duration", time.Since(start)).
+		Msg("Upgraded connection closed")
+
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return sent, err
+	}
+	return sent, nil
+}
@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// proxyServer serves every request by proxying it to backendCfg and streaming the response
+func proxyServer(t *testing.T, backendCfg BackendConfig) *httptest.Server {
+	t.Helper()
+	client := NewClient(zerolog.Nop(), nil, nil)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := client.ProxyRequest(&Request{
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			Headers:     r.Header,
+			Backend:     backendCfg,
+			OriginalReq: r,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		_, _ = client.StreamResponse(w, resp, true)
+	}))
+}
+
+func TestStreamResponse_WebSocketTunnel(t *testing.T) {
+	// The backend accepts the upgrade and echoes lines back after a delay longer
+	// than the backend request timeout
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" || !strings.EqualFold(r.Header.Get("Connection"), "Upgrade") {
+			http.Error(w, "upgrade headers not forwarded", http.StatusBadRequest)
+			return
+		}
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Accept: test\r\n\r\n")
+		_ = buf.Flush()
+
+		line, err := buf.ReadString('\n')
+		if err != nil {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+		_, _ = conn.Write([]byte("echo: " + line))
+	}))
+	defer backend.Close()
+
+	server := proxyServer(t, &config.NPMBackendConfig{
+		Name:                "verdaccio",
+		URL:                 backend.URL,
+		MaxIdleConns:        1,
+		MaxIdleConnsPerHost: 1,
+		DialTimeout:         time.Second,
+		RequestTimeout:      50 * time.Millisecond,
+	})
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, err = io.WriteString(conn, "GET /-/web/socket HTTP/1.1\r\nHost: proxy\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\nhello\n")
+	if err != nil {
+		t.Fatalf("write upgrade request: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("ReadResponse() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "test" {
+		t.Errorf("Sec-WebSocket-Accept = %q, want the backend's header", got)
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("read through tunnel: %v", err)
+	}
+	if line != "echo: hello\n" {
+		t.Errorf("tunnel read %q, want %q", line, "echo: hello\n")
+	}
+}
+
+func TestStreamResponse_FlushesEventStream(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	server := proxyServer(t, &config.NPMBackendConfig{
+		Name:                "events",
+		URL:                 backend.URL,
+		MaxIdleConns:        1,
+		MaxIdleConnsPerHost: 1,
+		DialTimeout:         time.Second,
+		RequestTimeout:      10 * time.Second,
+	})
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/-/events")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// The first event arrives while the backend still holds the response open
+	got := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		got <- line
+	}()
+	select {
+	case line := <-got:
+		if line != "data: first\n" {
+			t.Errorf("read %q, want the first event", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not flushed to the client")
+	}
+}
@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mainuli/artifusion/internal/metrics"
+)
+
+// Connection pool states, used as the state label of the connection pool metrics
+const (
+	poolStateActive = "active"
+	poolStateIdle   = "idle"
+)
+
+// poolTracker tracks the connections of one backend's transport.
+//
+// Open connections are counted at the dialer, so connections closed by the pool
+// (idle timeout, MaxIdleConnsPerHost overflow, server close) are accounted for.
+// Active connections are counted with httptrace from GotConn until the connection
+// is returned to the pool or the request finishes. Idle = open - active.
+type poolTracker struct {
+	backend string
+	metrics *metrics.Metrics
+	open    atomic.Int64
+	active  atomic.Int64
+}
+
+// newPoolTracker creates a connection tracker for backend reporting to m
+func newPoolTracker(backend string, m *metrics.Metrics) *poolTracker {
+	return &poolTracker{backend: backend, metrics: m}
+}
+
+// dialContext wraps dial so connections are counted while open
+func (p *poolTracker) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		p.open.Add(1)
+		p.report()
+		return &trackedConn{Conn: conn, tracker: p}, nil
+	}
+}
+
+// report publishes the current pool state
+func (p *poolTracker) report() {
+	open := p.open.Load()
+	active := p.active.Load()
+	idle := max(open-active, 0)
+
+	p.metrics.SetConnectionPoolSize(p.backend, poolStateActive, int(active))
+	p.metrics.SetConnectionPoolSize(p.backend, poolStateIdle, int(idle))
+}
+
+// trackedConn decrements the open connection count once when closed
+type trackedConn struct {
+	net.Conn
+	tracker *poolTracker
+	closed  sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.closed.Do(func() {
+		c.tracker.open.Add(-1)
+		c.tracker.report()
+	})
+	return c.Conn.Close()
+}
+
+// trackedTransport attaches a connection trace to every request
+type trackedTransport struct {
+	base    http.RoundTripper
+	tracker *poolTracker
+}
+
+func (t *trackedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	lease := &connLease{tracker: t.tracker}
+	trace := &httptrace.ClientTrace{
+		GotConn: lease.acquire,
+		PutIdleConn: func(error) {
+			lease.release()
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		lease.release()
+		return nil, err
+	}
+
+	// Upgraded connections leave the pool and belong to the caller, and their body
+	// must stay writable, so the lease ends here
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		lease.release()
+		return resp, nil
+	}
+
+	// Connections that are closed rather than returned to the pool never see
+	// PutIdleConn, so the lease also ends when the body is closed
+	resp.Body = &leaseBody{ReadCloser: resp.Body, lease: lease}
+	return resp, nil
+}
+
+// connLease tracks the connection held by a single request
+type connLease struct {
+	tracker *poolTracker
+	mu      sync.Mutex
+	held    bool
+}
+
+// acquire marks a connection as active. The transport may retry a request on a
+// new connection, so a request only ever holds one.
+func (l *connLease) acquire(info httptrace.GotConnInfo) {
+	l.tracker.metrics.RecordConnectionAcquired(l.tracker.backend, info.Reused)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held {
+		return
+	}
+	l.held = true
+	l.tracker.active.Add(1)
+	l.tracker.report()
+}
+
+// release returns the held connection, if any
+func (l *connLease) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.held {
+		return
+	}
+	l.held = false
+	l.tracker.active.Add(-1)
+	l.tracker.report()
+}
+
+// leaseBody ends the connection lease when the response body is closed
+type leaseBody struct {
+	io.ReadCloser
+	lease *connLease
+}
+
+func (b *leaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.lease.release()
+	return err
+}
@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestPoolTracker tests that connections are reported as active while in use, idle
+// once returned to the pool, and removed when closed
+func TestPoolTracker(t *testing.T) {
+	m := metrics.NewMetrics("pool_tracker_test")
+	tracker := newPoolTracker("backend", m)
+
+	gauge := func(state string) float64 {
+		return testutil.ToFloat64(m.ConnectionPoolSize.WithLabelValues("backend", state))
+	}
+	acquired := func(conn string) float64 {
+		return testutil.ToFloat64(m.ConnectionsAcquired.WithLabelValues("backend", conn))
+	}
+
+	var activeDuringRequest float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		activeDuringRequest = gauge(poolStateActive)
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	transport := &http.Transport{DialContext: tracker.dialContext((&net.Dialer{}).DialContext)}
+	client := &http.Client{Transport: &trackedTransport{base: transport, tracker: tracker}}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		if activeDuringRequest != 1 {
+			t.Errorf("request %d: expected 1 active connection during request, got %v", i, activeDuringRequest)
+		}
+		if got := gauge(poolStateActive); got != 0 {
+			t.Errorf("request %d: expected 0 active connections after request, got %v", i, got)
+		}
+		if got := gauge(poolStateIdle); got != 1 {
+			t.Errorf("request %d: expected 1 idle connection after request, got %v", i, got)
+		}
+	}
+
+	if got := acquired("new"); got != 1 {
+		t.Errorf("expected 1 new connection, got %v", got)
+	}
+	if got := acquired("reused"); got != 1 {
+		t.Errorf("expected 1 reused connection, got %v", got)
+	}
+
+	transport.CloseIdleConnections()
+	if got := gauge(poolStateIdle); got != 0 {
+		t.Errorf("expected 0 idle connections after closing idle connections, got %v", got)
+	}
+}
@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// redirectPolicy returns the CheckRedirect function for a backend's HTTP client.
+// By default redirects are passed through to the client. With transport.redirects
+// set to follow they are followed in the proxy, up to transport.max_redirects, so
+// the client receives the final target; backend credentials are not sent to hosts
+// other than the backend's.
+func redirectPolicy(backend BackendConfig, logger zerolog.Logger) func(req *http.Request, via []*http.Request) error {
+	transportCfg := backend.GetTransport()
+	if transportCfg.Redirects != config.RedirectsFollow {
+		return func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	maxRedirects := transportCfg.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = config.DefaultMaxRedirects
+	}
+
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			return fmt.Errorf("backend %s: stopped after %d redirects", backend.GetName(), maxRedirects)
+		}
+
+		if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+			// Storage URLs are usually presigned and reject requests carrying
+			// other credentials
+			stripBackendAuth(req.Header, backend)
+		}
+
+		logger.Debug().
+			Str("backend", backend.GetName()).
+			Str("location", req.URL.Redacted()).
+			Int("redirects", len(via)).
+			Msg("Following backend redirect")
+
+		return nil
+	}
+}
+
+// stripBackendAuth removes the headers injectBackendAuth may have set
+func stripBackendAuth(header http.Header, backend BackendConfig) {
+	header.Del("Authorization")
+
+	authBackend, ok := backend.(authProvider)
+	if !ok {
+		return
+	}
+	if auth := authBackend.GetAuth(); auth != nil && strings.EqualFold(auth.Type, "header") && auth.HeaderName != "" {
+		header.Del(auth.HeaderName)
+	}
+}
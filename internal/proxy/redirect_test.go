@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func TestProxyRequest_RedirectPolicy(t *testing.T) {
+	// storage stands in for the presigned storage URL an S3-backed registry redirects to
+	var storageAuth, storageToken string
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		storageAuth = r.Header.Get("Authorization")
+		storageToken = r.Header.Get("X-Registry-Token")
+		_, _ = w.Write([]byte("blob"))
+	}))
+	defer storage.Close()
+
+	var backendToken string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blob":
+			backendToken = r.Header.Get("X-Registry-Token")
+			http.Redirect(w, r, storage.URL+"/bucket/blob?X-Amz-Signature=abc", http.StatusTemporaryRedirect)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		}
+	}))
+	defer backend.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		redirects    string
+		maxRedirects int
+		wantStatus   int
+		wantBody     string
+		wantErr      bool
+	}{
+		{name: "pass through by default", path: "/blob", wantStatus: http.StatusTemporaryRedirect},
+		{name: "pass through", path: "/blob", redirects: config.RedirectsPassThrough, wantStatus: http.StatusTemporaryRedirect},
+		{name: "follow", path: "/blob", redirects: config.RedirectsFollow, wantStatus: http.StatusOK, wantBody: "blob"},
+		{name: "follow stops after max redirects", path: "/loop", redirects: config.RedirectsFollow, maxRedirects: 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storageAuth, storageToken, backendToken = "", "", ""
+
+			backendCfg := &config.MavenBackendConfig{
+				Name:                tt.name,
+				URL:                 backend.URL,
+				Auth:                &config.AuthConfig{Type: "header", HeaderName: "X-Registry-Token", HeaderValue: "secret"},
+				MaxIdleConns:        1,
+				MaxIdleConnsPerHost: 1,
+				DialTimeout:         time.Second,
+				RequestTimeout:      5 * time.Second,
+				Transport:           config.TransportConfig{Redirects: tt.redirects, MaxRedirects: tt.maxRedirects},
+			}
+
+			client := NewClient(zerolog.Nop(), nil, nil)
+			resp, err := client.ProxyRequest(&Request{
+				Method:      http.MethodGet,
+				Path:        tt.path,
+				Headers:     http.Header{"Authorization": []string{"Basic Y2xpZW50OnRva2Vu"}},
+				Backend:     backendCfg,
+				OriginalReq: httptest.NewRequest(http.MethodGet, tt.path, nil),
+			})
+			if tt.wantErr {
+				if err == nil {
+					_ = resp.Body.Close()
+					t.Fatal("ProxyRequest() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProxyRequest() error = %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("StatusCode = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusTemporaryRedirect {
+				if location := resp.Headers.Get("Location"); location != storage.URL+"/bucket/blob?X-Amz-Signature=abc" {
+					t.Errorf("Location = %q, want the storage URL", location)
+				}
+			} else if body, _ := io.ReadAll(resp.Body); string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+			if backendToken != "secret" {
+				t.Errorf("backend got X-Registry-Token %q, want the configured credential", backendToken)
+			}
+			if storageAuth != "" || storageToken != "" {
+				t.Errorf("storage got Authorization %q, X-Registry-Token %q, want backend credentials stripped", storageAuth, storageToken)
+			}
+		})
+	}
+}
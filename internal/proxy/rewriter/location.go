@@ -0,0 +1,90 @@
+package rewriter
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/proxy"
+)
+
+// RewriteRedirectLocation rewrites the Location header of resp with MapLocation if
+// the backend has transport.rewrite_redirects enabled, resolving relative locations
+// against the URL the backend was requested at. It reports whether the backend has
+// the option enabled; callers apply their own rewriting otherwise.
+func RewriteRedirectLocation(resp *proxy.Response, backend proxy.BackendConfig, publicURL string) bool {
+	if !backend.GetTransport().RewriteRedirects {
+		return false
+	}
+
+	location := resp.Headers.Get("Location")
+	if location == "" {
+		return true
+	}
+	resolved := location
+	if resp.HTTPResp != nil && resp.HTTPResp.Request != nil {
+		if u, err := resp.HTTPResp.Location(); err == nil {
+			resolved = u.String()
+		}
+	}
+
+	if mapped, ok := MapLocation(resolved, backend.GetURL(), publicURL); ok {
+		resp.Headers.Set("Location", mapped)
+	}
+	return true
+}
+
+// MapLocation maps a Location header from a backend to the proxy, for backends with
+// transport.rewrite_redirects enabled. Relative locations and absolute ones on the
+// backend's host are resolved against backendURL; if the result lies under the
+// backend URL's path it is moved under publicURL, keeping the rest of the path, the
+// query and the fragment. It reports false, leaving the location to the caller, for
+// locations on other hosts or outside the backend's path, which the proxy can't serve.
+func MapLocation(location, backendURL, publicURL string) (string, bool) {
+	base, err := url.Parse(backendURL)
+	if err != nil || base.Host == "" {
+		return location, false
+	}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return location, false
+	}
+
+	target := base.ResolveReference(ref)
+	if !sameHost(target, base) {
+		return location, false
+	}
+
+	prefix := strings.TrimSuffix(base.EscapedPath(), "/")
+	rest := target.EscapedPath()
+	if prefix != "" {
+		if rest != prefix && !strings.HasPrefix(rest, prefix+"/") {
+			return location, false
+		}
+		rest = strings.TrimPrefix(rest, prefix)
+	}
+
+	mapped := strings.TrimSuffix(publicURL, "/") + rest
+	if target.RawQuery != "" {
+		mapped += "?" + target.RawQuery
+	}
+	if target.Fragment != "" {
+		mapped += "#" + target.EscapedFragment()
+	}
+	return mapped, true
+}
+
+// sameHost reports whether a and b address the same host and port, with the
+// scheme's default port where none is given
+func sameHost(a, b *url.URL) bool {
+	return strings.EqualFold(a.Hostname(), b.Hostname()) && effectivePort(a) == effectivePort(b)
+}
+
+func effectivePort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	if strings.EqualFold(u.Scheme, "https") {
+		return "443"
+	}
+	return "80"
+}
@@ -0,0 +1,120 @@
+package rewriter
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/proxy"
+)
+
+func TestMapLocation(t *testing.T) {
+	const (
+		backendURL = "http://nexus:8081/repository/maven-public"
+		publicURL  = "https://artifacts.example.com/maven"
+	)
+
+	tests := []struct {
+		name     string
+		location string
+		want     string
+		wantOK   bool
+	}{
+		{
+			name:     "absolute under backend path",
+			location: "http://nexus:8081/repository/maven-public/org/app/1.0/app-1.0.jar",
+			want:     "https://artifacts.example.com/maven/org/app/1.0/app-1.0.jar",
+			wantOK:   true,
+		},
+		{
+			name:     "host case and default port",
+			location: "http://NEXUS:8081/repository/maven-public/org/",
+			want:     "https://artifacts.example.com/maven/org/",
+			wantOK:   true,
+		},
+		{
+			name:     "relative path with query and fragment",
+			location: "/repository/maven-public/org/app/?page=2#top",
+			want:     "https://artifacts.example.com/maven/org/app/?page=2#top",
+			wantOK:   true,
+		},
+		{
+			name:     "backend path itself",
+			location: "/repository/maven-public",
+			want:     "https://artifacts.example.com/maven",
+			wantOK:   true,
+		},
+		{
+			name:     "escaped path kept",
+			location: "/repository/maven-public/a%2Fb",
+			want:     "https://artifacts.example.com/maven/a%2Fb",
+			wantOK:   true,
+		},
+		{
+			name:     "outside backend path",
+			location: "/repository/maven-public-snapshots/org/",
+			want:     "/repository/maven-public-snapshots/org/",
+		},
+		{
+			name:     "other host",
+			location: "https://storage.example.com/repository/maven-public/org/",
+			want:     "https://storage.example.com/repository/maven-public/org/",
+		},
+		{
+			name:     "other port",
+			location: "http://nexus:8082/repository/maven-public/org/",
+			want:     "http://nexus:8082/repository/maven-public/org/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := MapLocation(tt.location, backendURL, publicURL)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("MapLocation(%q) = %q, %v, want %q, %v", tt.location, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	if got, ok := MapLocation("/v2/app/blobs/uploads/1", "http://registry:5000", "https://registry.example.com"); !ok || got != "https://registry.example.com/v2/app/blobs/uploads/1" {
+		t.Errorf("MapLocation() without backend path = %q, %v", got, ok)
+	}
+}
+
+func TestRewriteRedirectLocation(t *testing.T) {
+	requestURL, _ := url.Parse("http://npm-backend:4873/npm/lodash/-/")
+
+	tests := []struct {
+		name        string
+		enabled     bool
+		wantHandled bool
+		want        string
+	}{
+		{name: "disabled", want: "lodash-4.17.21.tgz"},
+		{name: "enabled", enabled: true, wantHandled: true, want: "https://npm.example.com/npm/lodash/-/lodash-4.17.21.tgz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &config.NPMBackendConfig{
+				URL:       "http://npm-backend:4873/npm",
+				Transport: config.TransportConfig{RewriteRedirects: tt.enabled},
+			}
+			headers := http.Header{"Location": []string{"lodash-4.17.21.tgz"}}
+			resp := &proxy.Response{
+				StatusCode: http.StatusFound,
+				Headers:    headers,
+				HTTPResp:   &http.Response{Header: headers, Request: &http.Request{URL: requestURL}},
+			}
+
+			handled := RewriteRedirectLocation(resp, backend, "https://npm.example.com/npm")
+			if handled != tt.wantHandled {
+				t.Errorf("RewriteRedirectLocation() = %v, want %v", handled, tt.wantHandled)
+			}
+			if got := resp.Headers.Get("Location"); got != tt.want {
+				t.Errorf("Location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -29,6 +29,10 @@ func (r *URLRewriter) RewriteResponseHeaders(resp *proxy.Response, backend proxy
 
 // RewriteLocation rewrites Location headers from backend URLs to public URL
 func (r *URLRewriter) RewriteLocation(resp *proxy.Response, backend proxy.BackendConfig) {
+	if RewriteRedirectLocation(resp, backend, r.publicURL) {
+		return
+	}
+
 	location := resp.Headers.Get("Location")
 	if location == "" {
 		return
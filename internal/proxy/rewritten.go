@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// rewrittenHeaders describe the backend's original body and don't hold for a
+// rewritten one. Content-Digest is recomputed by WriteResponse.
+var rewrittenHeaders = []string{"Content-Length", "Content-MD5", "Digest", "Repr-Digest", "Accept-Ranges"}
+
+// WriteRewrittenResponse writes body, rewritten from the backend's response, to the
+// client. The backend's ETag, length and digests describe the original body, so the
+// ETag is replaced with a digest of the bytes written (weak if the backend's was), and
+// a GET whose If-None-Match matches it is answered with 304 Not Modified: clients
+// revalidating their cached copy with the ETag they were served never reach the
+// backend's validators. body is written as it is, so it must match the response's
+// Content-Encoding; callers that decompressed it to rewrite it remove the header.
+func (c *Client) WriteRewrittenResponse(w http.ResponseWriter, r *http.Request, resp *Response, body []byte) error {
+	weak := strings.HasPrefix(resp.Headers.Get("ETag"), "W/")
+	for _, header := range rewrittenHeaders {
+		resp.Headers.Del(header)
+	}
+
+	// HEAD responses carry no body to compute the validators of
+	if r.Method == http.MethodHead {
+		resp.Headers.Del("ETag")
+		resp.Headers.Del(contentDigestHeader)
+		for key, values := range resp.Headers {
+			w.Header()[key] = append(w.Header()[key], values...)
+		}
+		w.WriteHeader(resp.StatusCode)
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Headers.Del("ETag")
+		return c.WriteResponse(w, resp, body, true)
+	}
+
+	etag := RewrittenETag(body, weak)
+	resp.Headers.Set("ETag", etag)
+
+	if r.Method == http.MethodGet && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		resp.Headers.Del(contentDigestHeader)
+		for key, values := range resp.Headers {
+			w.Header()[key] = append(w.Header()[key], values...)
+		}
+		w.WriteHeader(http.StatusNotModified)
+
+		c.logger.Debug().
+			Str("etag", etag).
+			Msg("Rewritten response not modified")
+		return nil
+	}
+
+	return c.WriteResponse(w, resp, body, true)
+}
+
+// RewrittenETag returns the entity tag of a rewritten body: a digest of its bytes,
+// so that it changes whenever the backend's document or the rewriting does
+func RewrittenETag(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + etag
+	}
+	return etag
+}
+
+// etagMatches reports whether an If-None-Match value matches etag, using the weak
+// comparison If-None-Match calls for (RFC 9110 13.1.2)
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
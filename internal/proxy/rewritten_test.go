@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestWriteRewrittenResponse(t *testing.T) {
+	client := NewClient(zerolog.Nop(), nil, nil)
+	body := []byte(`{"tarball":"https://proxy.example.com/npm/a/-/a-1.0.0.tgz"}`)
+	etag := RewrittenETag(body, false)
+
+	backendResponse := func(backendETag string) *Response {
+		headers := http.Header{}
+		headers.Set("Content-Type", "application/json")
+		headers.Set("Content-Length", "999")
+		headers.Set("ETag", backendETag)
+		headers.Set("Repr-Digest", "sha-256=:b3JpZ2luYWw=:")
+		headers.Set("Last-Modified", "Mon, 05 Oct 2026 10:00:00 GMT")
+		return &Response{StatusCode: http.StatusOK, Headers: headers, Body: io.NopCloser(strings.NewReader(""))}
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		ifNoneMatch string
+		backendETag string
+		wantStatus  int
+		wantETag    string
+		wantBody    string
+	}{
+		{name: "replaces backend etag", method: http.MethodGet, backendETag: `"backend"`, wantStatus: http.StatusOK, wantETag: etag, wantBody: string(body)},
+		{name: "keeps weakness", method: http.MethodGet, backendETag: `W/"backend"`, wantStatus: http.StatusOK, wantETag: "W/" + etag, wantBody: string(body)},
+		{name: "matching if-none-match", method: http.MethodGet, ifNoneMatch: `"other", ` + etag, backendETag: `"backend"`, wantStatus: http.StatusNotModified, wantETag: etag},
+		{name: "weakly matching if-none-match", method: http.MethodGet, ifNoneMatch: "W/" + etag, backendETag: `"backend"`, wantStatus: http.StatusNotModified, wantETag: etag},
+		{name: "backend etag in if-none-match", method: http.MethodGet, ifNoneMatch: `"backend"`, backendETag: `"backend"`, wantStatus: http.StatusOK, wantETag: etag, wantBody: string(body)},
+		{name: "head", method: http.MethodHead, backendETag: `"backend"`, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/npm/a", nil)
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+
+			if err := client.WriteRewrittenResponse(w, r, backendResponse(tt.backendETag), body); err != nil {
+				t.Fatalf("WriteRewrittenResponse failed: %v", err)
+			}
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", got, tt.wantETag)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if w.Header().Get("Repr-Digest") != "" {
+				t.Error("expected the backend's Repr-Digest to be dropped")
+			}
+			if w.Header().Get("Last-Modified") == "" {
+				t.Error("expected Last-Modified to be kept")
+			}
+			wantLength := ""
+			if tt.wantStatus == http.StatusOK && tt.method == http.MethodGet {
+				wantLength = strconv.Itoa(len(body))
+			}
+			if got := w.Header().Get("Content-Length"); got != wantLength {
+				t.Errorf("Content-Length = %q, want %q", got, wantLength)
+			}
+		})
+	}
+}
+
+func TestWriteResponse_Head(t *testing.T) {
+	client := NewClient(zerolog.Nop(), nil, nil)
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set("Content-Length", "999")
+	resp := &Response{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       http.NoBody,
+		HTTPResp:   &http.Response{Request: httptest.NewRequest(http.MethodHead, "/gems/rails", nil)},
+	}
+
+	w := httptest.NewRecorder()
+	if err := client.WriteResponse(w, resp, []byte{}, true); err != nil {
+		t.Fatalf("WriteResponse failed: %v", err)
+	}
+	if w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("response = %d %q, want 200 without a body", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Length"); got != "" {
+		t.Errorf("Content-Length = %q, want none for the unmeasured rewritten body", got)
+	}
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Error("expected the backend's headers to be kept")
+	}
+}
@@ -0,0 +1,317 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/middleware"
+	"github.com/rs/zerolog"
+)
+
+// Errors returned by ShadowLog.Start
+var (
+	ErrShadowLogUnknownBackend = errors.New("unknown backend")
+	ErrShadowLogActive         = errors.New("a shadow log window is already active for this backend")
+)
+
+// sensitiveQueryParams are substrings of query parameter names whose values are
+// redacted from shadow logged URLs (e.g. access_token, X-Amz-Signature, api_key)
+var sensitiveQueryParams = []string{"token", "sig", "key", "password", "secret", "credential", "auth"}
+
+// unsafeFileChars are replaced in backend names to form log file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// ShadowWindow describes a shadow log window of one backend
+type ShadowWindow struct {
+	Backend   string    `json:"backend"`
+	File      string    `json:"file"`
+	StartedBy string    `json:"started_by"`
+	StartedAt time.Time `json:"started_at"`
+	Until     time.Time `json:"until"`
+	Entries   int64     `json:"entries"`
+}
+
+// shadowWindow is an active window and its log file
+type shadowWindow struct {
+	mu      sync.Mutex
+	info    ShadowWindow
+	file    *os.File
+	encoder *json.Encoder
+	timer   *time.Timer
+	err     error // First error writing the log file
+}
+
+// shadowEntry is one backend request and its response, as written to the log file
+type shadowEntry struct {
+	Time            time.Time              `json:"time"`
+	RequestID       string                 `json:"request_id,omitempty"`
+	User            string                 `json:"user,omitempty"`
+	Backend         string                 `json:"backend"`
+	Method          string                 `json:"method"`
+	URL             string                 `json:"url"`
+	RequestHeaders  map[string]interface{} `json:"request_headers"`
+	Status          int                    `json:"status,omitempty"`
+	ResponseHeaders map[string]interface{} `json:"response_headers,omitempty"`
+	DurationMs      float64                `json:"duration_ms"` // Until the response headers
+	Error           string                 `json:"error,omitempty"`
+}
+
+// ShadowLog records the sanitized metadata of the requests to selected backends to
+// files for bounded time windows, so their behavior can be compared before and
+// after a registry migration.
+//
+// Thread safety: All methods are safe for concurrent use.
+type ShadowLog struct {
+	dir         string
+	maxDuration time.Duration
+	backends    map[string]bool
+	logger      zerolog.Logger
+
+	mu      sync.RWMutex
+	windows map[string]*shadowWindow
+}
+
+// NewShadowLog creates the shadow log of cfg for the named backends, creating its
+// directory if needed
+func NewShadowLog(cfg *config.ShadowLogConfig, backends []string, logger zerolog.Logger) (*ShadowLog, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create shadow log directory: %w", err)
+	}
+
+	known := make(map[string]bool, len(backends))
+	for _, name := range backends {
+		known[name] = true
+	}
+	return &ShadowLog{
+		dir:         cfg.Dir,
+		maxDuration: cfg.MaxDuration,
+		backends:    known,
+		logger:      logger.With().Str("component", "shadow_log").Logger(),
+		windows:     make(map[string]*shadowWindow),
+	}, nil
+}
+
+// MaxDuration returns the longest window Start accepts
+func (s *ShadowLog) MaxDuration() time.Duration {
+	return s.maxDuration
+}
+
+// Start starts logging the requests to backend for duration, writing them to a new
+// file of the log directory
+func (s *ShadowLog) Start(backend string, duration time.Duration, startedBy string) (ShadowWindow, error) {
+	if !s.backends[backend] {
+		return ShadowWindow{}, ErrShadowLogUnknownBackend
+	}
+	if duration <= 0 || duration > s.maxDuration {
+		return ShadowWindow{}, fmt.Errorf("duration must be positive and at most %s", s.maxDuration)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.windows[backend]; ok {
+		return ShadowWindow{}, ErrShadowLogActive
+	}
+
+	now := time.Now().UTC()
+	name := fmt.Sprintf("%s-%s.jsonl", unsafeFileChars.ReplaceAllString(backend, "_"), now.Format("20060102T150405Z"))
+	path := filepath.Join(s.dir, name)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return ShadowWindow{}, fmt.Errorf("failed to create shadow log file: %w", err)
+	}
+
+	w := &shadowWindow{
+		info: ShadowWindow{
+			Backend:   backend,
+			File:      path,
+			StartedBy: startedBy,
+			StartedAt: now,
+			Until:     now.Add(duration),
+		},
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}
+	w.timer = time.AfterFunc(duration, func() {
+		s.stop(backend, w)
+	})
+	s.windows[backend] = w
+
+	s.logger.Info().
+		Str("backend", backend).
+		Str("file", path).
+		Str("started_by", startedBy).
+		Time("until", w.info.Until).
+		Msg("Shadow log window started")
+	return w.info, nil
+}
+
+// Stop ends the window of backend before its time, returning it if one was active
+func (s *ShadowLog) Stop(backend string) (ShadowWindow, bool) {
+	s.mu.RLock()
+	w, ok := s.windows[backend]
+	s.mu.RUnlock()
+	if !ok {
+		return ShadowWindow{}, false
+	}
+	w.timer.Stop()
+	return s.stop(backend, w), true
+}
+
+// Windows returns the active windows, sorted by backend
+func (s *ShadowLog) Windows() []ShadowWindow {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	windows := make([]ShadowWindow, 0, len(s.windows))
+	for _, w := range s.windows {
+		w.mu.Lock()
+		windows = append(windows, w.info)
+		w.mu.Unlock()
+	}
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].Backend < windows[j].Backend
+	})
+	return windows
+}
+
+// Close ends all active windows
+func (s *ShadowLog) Close() {
+	s.mu.RLock()
+	backends := make([]string, 0, len(s.windows))
+	for backend := range s.windows {
+		backends = append(backends, backend)
+	}
+	s.mu.RUnlock()
+
+	for _, backend := range backends {
+		s.Stop(backend)
+	}
+}
+
+// stop removes window w of backend, unless it was already replaced, and closes its
+// file
+func (s *ShadowLog) stop(backend string, w *shadowWindow) ShadowWindow {
+	s.mu.Lock()
+	if s.windows[backend] == w {
+		delete(s.windows, backend)
+	}
+	s.mu.Unlock()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file != nil {
+		if err := w.file.Close(); err != nil && w.err == nil {
+			w.err = err
+		}
+		w.file = nil
+
+		event := s.logger.Info()
+		if w.err != nil {
+			event = s.logger.Warn().Err(w.err)
+		}
+		event.
+			Str("backend", backend).
+			Str("file", w.info.File).
+			Int64("entries", w.info.Entries).
+			Msg("Shadow log window ended")
+	}
+	return w.info
+}
+
+// active returns the active window of backend, if any
+func (s *ShadowLog) active(backend string) *shadowWindow {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.windows[backend]
+}
+
+// record writes a backend request and its response (nil if err is set) to the
+// active window of the request's backend, if any
+func (s *ShadowLog) record(req *Request, backendReq *http.Request, resp *http.Response, err error, start time.Time, duration time.Duration) {
+	backend := req.Backend.GetName()
+	w := s.active(backend)
+	if w == nil {
+		return
+	}
+
+	ctx := req.OriginalReq.Context()
+	entry := shadowEntry{
+		Time:           start.UTC(),
+		RequestID:      middleware.GetRequestID(ctx),
+		User:           middleware.GetUsername(ctx),
+		Backend:        backend,
+		Method:         backendReq.Method,
+		URL:            sanitizeURL(backendReq.URL),
+		RequestHeaders: sanitizeBackendHeaders(backendReq.Header, req.Backend),
+		DurationMs:     float64(duration.Microseconds()) / 1000,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Status = resp.StatusCode
+		entry.ResponseHeaders = sanitizeBackendHeaders(resp.Header, req.Backend)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil || w.err != nil {
+		return // Ended concurrently, or the file failed
+	}
+	if err := w.encoder.Encode(entry); err != nil {
+		w.err = err
+		s.logger.Error().Err(err).
+			Str("backend", backend).
+			Str("file", w.info.File).
+			Msg("Failed to write shadow log, dropping further entries")
+		return
+	}
+	w.info.Entries++
+}
+
+// sanitizeBackendHeaders redacts sensitive headers, including a custom
+// authentication header of backend
+func sanitizeBackendHeaders(headers http.Header, backend BackendConfig) map[string]interface{} {
+	sanitized := middleware.SanitizeHeaders(headers)
+	if authBackend, ok := backend.(authProvider); ok {
+		if auth := authBackend.GetAuth(); auth != nil && auth.HeaderName != "" {
+			name := http.CanonicalHeaderKey(auth.HeaderName)
+			if _, ok := sanitized[name]; ok {
+				sanitized[name] = config.RedactedValue
+			}
+		}
+	}
+	return sanitized
+}
+
+// sanitizeURL returns u without its password, with the values of query parameters
+// that look like credentials redacted
+func sanitizeURL(u *url.URL) string {
+	redacted := *u
+	if redacted.RawQuery != "" {
+		params := strings.Split(redacted.RawQuery, "&")
+		for i, param := range params {
+			name, _, _ := strings.Cut(param, "=")
+			if unescaped, err := url.QueryUnescape(name); err == nil {
+				name = unescaped
+			}
+			lower := strings.ToLower(name)
+			if slices.ContainsFunc(sensitiveQueryParams, func(s string) bool { return strings.Contains(lower, s) }) {
+				params[i] = url.QueryEscape(name) + "=" + config.RedactedValue
+			}
+		}
+		redacted.RawQuery = strings.Join(params, "&")
+	}
+	return redacted.Redacted()
+}
@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/mainuli/artifusion/internal/metrics"
+)
+
+// transferBudgets accounts the bytes of backend responses currently being streamed
+// to clients, per backend, and holds back new transfers that would exceed a
+// backend's max_in_flight_bytes
+type transferBudgets struct {
+	mu       sync.Mutex
+	backends map[string]*transferBudget
+	metrics  *metrics.Metrics // Optional (nil = no in-flight bytes gauge)
+}
+
+// transferBudget is the accounting of one backend
+type transferBudget struct {
+	inFlight  int64 // Bytes left to stream of the transfers in progress
+	transfers int   // Transfers in progress
+	waiters   int
+
+	// released is closed when bytes are released while transfers are waiting
+	released chan struct{}
+}
+
+func newTransferBudgets(m *metrics.Metrics) *transferBudgets {
+	return &transferBudgets{
+		backends: make(map[string]*transferBudget),
+		metrics:  m,
+	}
+}
+
+// acquire reserves size bytes for a transfer from backend, waiting while the
+// transfers in progress leave less than that of max (0 = unlimited). A transfer is
+// always admitted when none is in progress, so one larger than max still streams.
+// It returns the cause of ctx if ctx is done first.
+func (t *transferBudgets) acquire(ctx context.Context, backend string, size, max int64) error {
+	t.mu.Lock()
+	b := t.backends[backend]
+	if b == nil {
+		b = &transferBudget{released: make(chan struct{})}
+		t.backends[backend] = b
+	}
+
+	for max > 0 && b.transfers > 0 && b.inFlight+size > max {
+		b.waiters++
+		released := b.released
+		t.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			t.mu.Lock()
+			b.waiters--
+			t.mu.Unlock()
+			return context.Cause(ctx)
+		}
+
+		t.mu.Lock()
+		b.waiters--
+	}
+
+	b.inFlight += size
+	b.transfers++
+	t.record(backend, b)
+	t.mu.Unlock()
+	return nil
+}
+
+// release returns n bytes of a transfer's reservation; done also ends the transfer
+func (t *transferBudgets) release(backend string, n int64, done bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.backends[backend]
+	b.inFlight -= n
+	if done {
+		b.transfers--
+	}
+	t.record(backend, b)
+
+	if b.waiters > 0 {
+		close(b.released)
+		b.released = make(chan struct{})
+	}
+}
+
+// inFlight returns the bytes left to stream of backend's transfers in progress
+func (t *transferBudgets) inFlight(backend string) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if b := t.backends[backend]; b != nil {
+		return b.inFlight
+	}
+	return 0
+}
+
+func (t *transferBudgets) record(backend string, b *transferBudget) {
+	if t.metrics != nil {
+		t.metrics.SetBackendInFlightBytes(backend, b.inFlight)
+	}
+}
+
+// transferWriter releases a transfer's reservation as its bytes are written
+type transferWriter struct {
+	w         io.Writer
+	budgets   *transferBudgets
+	backend   string
+	remaining int64
+}
+
+func (tw *transferWriter) Write(p []byte) (int, error) {
+	n, err := tw.w.Write(p)
+	if released := min(int64(n), tw.remaining); released > 0 {
+		tw.remaining -= released
+		tw.budgets.release(tw.backend, released, false)
+	}
+	return n, err
+}
+
+// close ends the transfer, releasing what is left of its reservation
+func (tw *transferWriter) close() {
+	tw.budgets.release(tw.backend, tw.remaining, true)
+	tw.remaining = 0
+}
@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func TestTransferBudgets(t *testing.T) {
+	budgets := newTransferBudgets(nil)
+	ctx := context.Background()
+
+	// A transfer larger than the budget is admitted when none is in progress
+	if err := budgets.acquire(ctx, "maven", 150, 100); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	budgets.release("maven", 150, true)
+
+	if err := budgets.acquire(ctx, "maven", 60, 100); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	// Other backends have their own budget
+	if err := budgets.acquire(ctx, "npm", 60, 100); err != nil {
+		t.Fatalf("acquire for another backend failed: %v", err)
+	}
+
+	// A second transfer waits until enough of the first was streamed
+	admitted := make(chan error, 1)
+	go func() { admitted <- budgets.acquire(ctx, "maven", 60, 100) }()
+
+	select {
+	case err := <-admitted:
+		t.Fatalf("transfer admitted over the budget (err = %v)", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	budgets.release("maven", 20, false)
+	select {
+	case err := <-admitted:
+		if err != nil {
+			t.Fatalf("acquire failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("transfer not admitted after the budget was released")
+	}
+	if got := budgets.inFlight("maven"); got != 100 {
+		t.Errorf("in-flight bytes = %d, want 100", got)
+	}
+
+	// Waiting ends with the request
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := budgets.acquire(waitCtx, "maven", 1, 100); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquire error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := budgets.inFlight("maven"); got != 100 {
+		t.Errorf("in-flight bytes after abandoned wait = %d, want 100", got)
+	}
+}
+
+func TestStreamResponse_TransferBudget(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("0123456789"))
+	}))
+	defer backend.Close()
+
+	backendCfg := &config.MavenBackendConfig{
+		Name:                "budgeted",
+		URL:                 backend.URL,
+		MaxIdleConns:        1,
+		MaxIdleConnsPerHost: 1,
+		DialTimeout:         time.Second,
+		RequestTimeout:      30 * time.Second,
+		Transport:           config.TransportConfig{MaxInFlightBytes: 15},
+	}
+	client := NewClient(zerolog.Nop(), nil, nil)
+
+	get := func(ctx context.Context) (*httptest.ResponseRecorder, error) {
+		t.Helper()
+		resp, err := client.ProxyRequest(&Request{
+			Method:      http.MethodGet,
+			Path:        "/artifact.jar",
+			Headers:     http.Header{},
+			Backend:     backendCfg,
+			OriginalReq: httptest.NewRequest(http.MethodGet, "/artifact.jar", nil).WithContext(ctx),
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		_, err = client.StreamResponse(rec, resp, true)
+		return rec, err
+	}
+
+	rec, err := get(context.Background())
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if rec.Body.String() != "0123456789" {
+		t.Errorf("body = %q, want %q", rec.Body.String(), "0123456789")
+	}
+	if got := client.transfers.inFlight("budgeted"); got != 0 {
+		t.Errorf("in-flight bytes after transfer = %d, want 0", got)
+	}
+
+	// With another transfer holding most of the budget, the response waits
+	if err := client.transfers.acquire(context.Background(), "budgeted", 10, 15); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	rec, err = get(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("stream error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("body = %q, want nothing streamed while waiting", rec.Body.String())
+	}
+}
@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/vault"
+	"github.com/rs/zerolog"
+)
+
+func TestClient_VaultCredentials(t *testing.T) {
+	var password atomic.Value
+	password.Store("first")
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			_, _ = w.Write([]byte(`{"data":{"renewable":false}}`))
+		case "/v1/secret/data/nexus":
+			_, _ = fmt.Fprintf(w, `{"data":{"data":{"password":%q},"metadata":{"version":1}}}`, password.Load())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer vaultServer.Close()
+
+	var gotAuth atomic.Value
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth.Store(r.Header.Get("Authorization"))
+	}))
+	defer backend.Close()
+
+	backendCfg := &config.NPMBackendConfig{
+		Name:           "nexus-npm",
+		URL:            backend.URL,
+		DialTimeout:    time.Second,
+		RequestTimeout: 5 * time.Second,
+		Auth:           &config.AuthConfig{Type: "basic", Username: "deployer", Password: "vault:secret/data/nexus#password"},
+	}
+	proxy := func(client *Client) (string, error) {
+		t.Helper()
+		resp, err := client.ProxyRequest(&Request{
+			Method:      http.MethodGet,
+			Path:        "/express",
+			Headers:     http.Header{},
+			Backend:     backendCfg,
+			OriginalReq: httptest.NewRequest(http.MethodGet, "/express", nil),
+		})
+		if err != nil {
+			return "", err
+		}
+		_ = resp.Body.Close()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", gotAuth.Load().(string))
+		_, backendPassword, _ := req.BasicAuth()
+		return backendPassword, nil
+	}
+
+	// Without Vault, the reference must never be sent
+	if _, err := proxy(NewClient(zerolog.Nop(), nil, nil)); err == nil || !strings.Contains(err.Error(), "vault is not enabled") {
+		t.Errorf("ProxyRequest() without Vault error = %v, want the reference rejected", err)
+	}
+
+	vaultClient := vault.New(&config.VaultConfig{Address: vaultServer.URL, Token: "hvs.test", RefreshInterval: time.Minute}, zerolog.Nop())
+	if err := vaultClient.Resolve(context.Background(), vault.References(backendCfg.Auth)); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	client := NewClient(zerolog.Nop(), nil, nil)
+	client.SetVault(vaultClient)
+
+	if got, err := proxy(client); err != nil || got != "first" {
+		t.Errorf("backend password = %q, %v, want the Vault secret", got, err)
+	}
+
+	// Rotated in Vault: used after the next refresh, without a restart
+	password.Store("second")
+	vaultClient.Refresh(context.Background())
+	if got, err := proxy(client); err != nil || got != "second" {
+		t.Errorf("backend password = %q, %v, want the rotated secret", got, err)
+	}
+	if backendCfg.Auth.Password != "vault:secret/data/nexus#password" {
+		t.Errorf("config password = %q, want the reference kept", backendCfg.Auth.Password)
+	}
+}
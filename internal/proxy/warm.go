@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/constants"
+)
+
+// WarmConnections pre-establishes the configured warm connections of each backend
+// (transport.warm_connections), leaving them in the backend's idle pool. Backends
+// without warm connections are skipped. It returns once every backend is warmed.
+func (c *Client) WarmConnections(ctx context.Context, backends ...BackendConfig) {
+	var wg sync.WaitGroup
+	for _, backend := range backends {
+		if backend.GetTransport().WarmConnections <= 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.warm(ctx, backend)
+		}()
+	}
+	wg.Wait()
+}
+
+// warm sends concurrent HEAD requests for the backend's root, released together so
+// that each dials a connection of its own. Any response counts: the handshakes are
+// what is being paid ahead of time.
+func (c *Client) warm(ctx context.Context, backend BackendConfig) {
+	// Idle connections above max_idle_conns_per_host would be closed right away
+	n := min(backend.GetTransport().WarmConnections, backend.GetMaxIdleConnsPerHost())
+
+	ctx, cancel := context.WithTimeout(ctx, constants.WarmConnectionsTimeout)
+	defer cancel()
+
+	start := time.Now()
+	release := make(chan struct{})
+	var warmed atomic.Int32
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-release
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, "/", nil)
+			if err != nil {
+				return
+			}
+			resp, err := c.doProxyRequest(&Request{
+				Method:      http.MethodHead,
+				Path:        "/",
+				Headers:     http.Header{},
+				Backend:     backend,
+				OriginalReq: req,
+			})
+			if err != nil {
+				return
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			warmed.Add(1)
+		}()
+	}
+	close(release)
+	wg.Wait()
+
+	c.logger.Info().
+		Str("backend", backend.GetName()).
+		Int32("warmed", warmed.Load()).
+		Int("requested", n).
+		Dur("duration", time.Since(start)).
+		Msg("Backend connections warmed")
+}
@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func TestWarmConnections(t *testing.T) {
+	tests := []struct {
+		name                string
+		warmConnections     int
+		maxIdleConnsPerHost int
+		wantConns           int32
+	}{
+		{name: "disabled", warmConnections: 0, maxIdleConnsPerHost: 10, wantConns: 0},
+		{name: "warms configured connections", warmConnections: 3, maxIdleConnsPerHost: 10, wantConns: 3},
+		{name: "capped at idle pool size", warmConnections: 5, maxIdleConnsPerHost: 2, wantConns: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conns atomic.Int32
+			backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			backend.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					conns.Add(1)
+				}
+			}
+			backend.StartTLS()
+			defer backend.Close()
+
+			backendCfg := &config.NPMBackendConfig{
+				Name:                "npm",
+				URL:                 backend.URL,
+				MaxIdleConns:        10,
+				MaxIdleConnsPerHost: tt.maxIdleConnsPerHost,
+				DialTimeout:         time.Second,
+				RequestTimeout:      10 * time.Second,
+				Transport:           config.TransportConfig{WarmConnections: tt.warmConnections},
+			}
+			client := NewClient(zerolog.Nop(), nil, nil)
+			client.httpClients[backendCfg.Name] = backend.Client()
+
+			client.WarmConnections(context.Background(), backendCfg)
+			if got := conns.Load(); got != tt.wantConns {
+				t.Fatalf("connections after warming = %d, want %d", got, tt.wantConns)
+			}
+			if tt.wantConns == 0 {
+				return
+			}
+
+			// Sequential requests reuse the warm connections
+			for range tt.wantConns {
+				resp, err := client.ProxyRequest(&Request{
+					Method:      http.MethodGet,
+					Path:        "/pkg",
+					Headers:     http.Header{},
+					Backend:     backendCfg,
+					OriginalReq: httptest.NewRequest(http.MethodGet, "/pkg", nil),
+				})
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				_ = resp.Body.Close()
+			}
+			if got := conns.Load(); got != tt.wantConns {
+				t.Errorf("connections after requests = %d, want %d (warm connections reused)", got, tt.wantConns)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+// Stream watchdog abort reasons, used as the reason label of the stream abort metric
+const (
+	streamAbortMaxDuration   = "max_duration"
+	streamAbortMinThroughput = "min_throughput"
+)
+
+// errStreamAborted is the cause of a backend request canceled by the stream watchdog
+var errStreamAborted = errors.New("backend stream aborted by watchdog")
+
+// streamWatchdogEnabled reports whether cfg enables the stream watchdog
+func streamWatchdogEnabled(cfg *config.TransportConfig) bool {
+	return cfg.MaxStreamDuration > 0 || cfg.MinBytesPerSec > 0
+}
+
+// watchdogBody aborts a backend response body that streams for too long or too slowly.
+//
+// A stalled upstream blocks Read indefinitely, so the limits are enforced from a
+// separate goroutine by canceling the backend request's context, which unblocks Read.
+type watchdogBody struct {
+	body   io.ReadCloser
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	read      atomic.Int64
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newWatchdogBody starts watching body. cancel must cancel the context of the backend
+// request body belongs to; onAbort is called with the reason when the stream is aborted.
+func newWatchdogBody(body io.ReadCloser, ctx context.Context, cancel context.CancelCauseFunc, cfg *config.TransportConfig, onAbort func(reason string, read int64)) *watchdogBody {
+	b := &watchdogBody{
+		body:   body,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go b.watch(cfg.MaxStreamDuration, cfg.MinBytesPerSec, cfg.MinThroughputWindow, onAbort)
+	return b
+}
+
+// watch enforces the limits until the body is closed
+func (b *watchdogBody) watch(maxDuration time.Duration, minBytesPerSec int64, window time.Duration, onAbort func(reason string, read int64)) {
+	var deadline <-chan time.Time
+	if maxDuration > 0 {
+		timer := time.NewTimer(maxDuration)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	var tick <-chan time.Time
+	var minBytesPerWindow int64
+	if minBytesPerSec > 0 && window > 0 {
+		ticker := time.NewTicker(window)
+		defer ticker.Stop()
+		tick = ticker.C
+		minBytesPerWindow = int64(float64(minBytesPerSec) * window.Seconds())
+	}
+
+	var lastRead int64
+	for {
+		select {
+		case <-b.done:
+			return
+
+		case <-deadline:
+			b.abort(streamAbortMaxDuration, fmt.Errorf("%w: still streaming after %v", errStreamAborted, maxDuration), onAbort)
+			return
+
+		case <-tick:
+			read := b.read.Load()
+			if read-lastRead < minBytesPerWindow {
+				b.abort(streamAbortMinThroughput, fmt.Errorf("%w: %d bytes in %v, below %d bytes/s",
+					errStreamAborted, read-lastRead, window, minBytesPerSec), onAbort)
+				return
+			}
+			lastRead = read
+		}
+	}
+}
+
+// abort cancels the backend request unless the body was closed in the meantime
+func (b *watchdogBody) abort(reason string, cause error, onAbort func(reason string, read int64)) {
+	select {
+	case <-b.done:
+		return
+	default:
+	}
+	b.cancel(cause)
+	onAbort(reason, b.read.Load())
+}
+
+func (b *watchdogBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.read.Add(int64(n))
+
+	// Report why the stream ended rather than a bare "context canceled"
+	if err != nil && err != io.EOF {
+		if cause := context.Cause(b.ctx); errors.Is(cause, errStreamAborted) {
+			return n, cause
+		}
+	}
+	return n, err
+}
+
+func (b *watchdogBody) Close() error {
+	err := b.body.Close()
+	b.closeOnce.Do(func() {
+		close(b.done)
+		b.cancel(context.Canceled)
+	})
+	return err
+}
@@ -0,0 +1,128 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func TestProxyRequest_StreamWatchdog(t *testing.T) {
+	// The backend sends headers and a first chunk, then stalls
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("first chunk"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	tests := []struct {
+		name      string
+		transport config.TransportConfig
+	}{
+		{
+			name:      "max stream duration",
+			transport: config.TransportConfig{MaxStreamDuration: 100 * time.Millisecond},
+		},
+		{
+			name: "min throughput",
+			transport: config.TransportConfig{
+				MinBytesPerSec:      1024,
+				MinThroughputWindow: 50 * time.Millisecond,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backendCfg := &config.MavenBackendConfig{
+				Name:                "stalled",
+				URL:                 backend.URL,
+				MaxIdleConns:        1,
+				MaxIdleConnsPerHost: 1,
+				DialTimeout:         time.Second,
+				RequestTimeout:      30 * time.Second,
+				Transport:           tt.transport,
+			}
+
+			client := NewClient(zerolog.Nop(), nil, nil)
+			resp, err := client.ProxyRequest(&Request{
+				Method:      http.MethodGet,
+				Path:        "/artifact.jar",
+				Headers:     http.Header{},
+				Backend:     backendCfg,
+				OriginalReq: httptest.NewRequest(http.MethodGet, "/artifact.jar", nil),
+			})
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			start := time.Now()
+			body, err := io.ReadAll(resp.Body)
+			if !errors.Is(err, errStreamAborted) {
+				t.Fatalf("read error = %v, want the stream aborted by the watchdog", err)
+			}
+			if string(body) != "first chunk" {
+				t.Errorf("body = %q, want the data received before the abort", body)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("stream aborted after %v, want it aborted shortly after the limit", elapsed)
+			}
+		})
+	}
+}
+
+func TestProxyRequest_StreamWatchdog_CompletesFastStream(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("artifact"))
+	}))
+	defer backend.Close()
+
+	backendCfg := &config.MavenBackendConfig{
+		Name:                "fast",
+		URL:                 backend.URL,
+		MaxIdleConns:        1,
+		MaxIdleConnsPerHost: 1,
+		DialTimeout:         time.Second,
+		RequestTimeout:      30 * time.Second,
+		Transport: config.TransportConfig{
+			MaxStreamDuration:   10 * time.Second,
+			MinBytesPerSec:      1,
+			MinThroughputWindow: 10 * time.Second,
+		},
+	}
+
+	client := NewClient(zerolog.Nop(), nil, nil)
+	resp, err := client.ProxyRequest(&Request{
+		Method:      http.MethodGet,
+		Path:        "/artifact.jar",
+		Headers:     http.Header{},
+		Backend:     backendCfg,
+		OriginalReq: httptest.NewRequest(http.MethodGet, "/artifact.jar", nil),
+	})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if string(body) != "artifact" {
+		t.Errorf("body = %q, want %q", body, "artifact")
+	}
+	if err := resp.Body.Close(); err != nil {
+		t.Errorf("close failed: %v", err)
+	}
+}
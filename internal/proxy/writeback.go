@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"sync"
+
+	"github.com/mainuli/artifusion/internal/constants"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/rs/zerolog"
+)
+
+// Write-back results, used as the result label of the write-back metric
+const (
+	writeBackPublished = "published"
+	writeBackFailed    = "failed"
+	writeBackDropped   = "dropped"
+)
+
+// WriteBackQueue publishes artifacts fetched from a read-through upstream into the
+// primary backend in the background, at most constants.WriteBackConcurrency at a time
+type WriteBackQueue struct {
+	protocol string
+	slots    chan struct{}
+	metrics  *metrics.Metrics // nil = disabled
+	logger   zerolog.Logger
+	wg       sync.WaitGroup
+}
+
+// NewWriteBackQueue creates a write-back queue for a protocol handler
+func NewWriteBackQueue(protocol string, m *metrics.Metrics, logger zerolog.Logger) *WriteBackQueue {
+	return &WriteBackQueue{
+		protocol: protocol,
+		slots:    make(chan struct{}, constants.WriteBackConcurrency),
+		metrics:  m,
+		logger:   logger,
+	}
+}
+
+// Submit runs publish for the artifact at path in the background. It returns false
+// without running publish if all slots are busy; the caller keeps ownership of any
+// resources publish would have released.
+func (q *WriteBackQueue) Submit(path string, publish func() error) bool {
+	select {
+	case q.slots <- struct{}{}:
+	default:
+		q.logger.Debug().
+			Str("path", path).
+			Msg("Write-back slots busy, not writing artifact back")
+		q.record(writeBackDropped)
+		return false
+	}
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		defer func() { <-q.slots }()
+
+		if err := publish(); err != nil {
+			q.logger.Warn().Err(err).
+				Str("path", path).
+				Msg("Failed to write upstream artifact back into backend")
+			q.record(writeBackFailed)
+			return
+		}
+
+		q.logger.Debug().
+			Str("path", path).
+			Msg("Wrote upstream artifact back into backend")
+		q.record(writeBackPublished)
+	}()
+	return true
+}
+
+// Wait blocks until all submitted publishes have finished
+func (q *WriteBackQueue) Wait() {
+	q.wg.Wait()
+}
+
+func (q *WriteBackQueue) record(result string) {
+	if q.metrics != nil {
+		q.metrics.RecordWriteBack(q.protocol, result)
+	}
+}
@@ -0,0 +1,257 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/proxy"
+)
+
+// manifestMediaTypes are the manifest formats requested from the push backend, so it
+// returns the manifest as pushed rather than converting it
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+const (
+	// maxManifestSize bounds the manifests read into memory, matching the limit
+	// registries apply to manifest uploads
+	maxManifestSize = 4 << 20
+
+	// maxIndexDepth bounds the nesting of image indexes followed when copying
+	maxIndexDepth = 4
+)
+
+// descriptor references a blob or manifest by digest
+type descriptor struct {
+	MediaType string   `json:"mediaType"`
+	Digest    string   `json:"digest"`
+	Size      int64    `json:"size"`
+	URLs      []string `json:"urls,omitempty"`
+}
+
+// manifest holds the fields of an image manifest or index that reference content
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    *descriptor  `json:"config,omitempty"`
+	Layers    []descriptor `json:"layers,omitempty"`
+	Manifests []descriptor `json:"manifests,omitempty"`
+}
+
+// replicate copies the manifest at repository:reference from the push backend to
+// the target, along with the blobs and child manifests it references. Content is
+// pushed before the manifest referencing it, as registries require.
+func (r *Replicator) replicate(ctx context.Context, repository, reference string) error {
+	return r.copyManifest(ctx, repository, reference, 0)
+}
+
+func (r *Replicator) copyManifest(ctx context.Context, repository, reference string, depth int) error {
+	if depth > maxIndexDepth {
+		return fmt.Errorf("image index nested deeper than %d levels", maxIndexDepth)
+	}
+
+	manifestPath := "/v2/" + repository + "/manifests/" + reference
+	headers := http.Header{"Accept": {strings.Join(manifestMediaTypes, ", ")}}
+
+	resp, err := r.do(ctx, r.source, http.MethodGet, manifestPath, "", headers, nil, 0)
+	if err != nil {
+		return fmt.Errorf("fetch manifest %s: %w", reference, err)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch manifest %s: push backend returned %d", reference, resp.StatusCode)
+	}
+	if err != nil {
+		return fmt.Errorf("fetch manifest %s: %w", reference, err)
+	}
+	if len(body) > maxManifestSize {
+		return fmt.Errorf("manifest %s exceeds %d bytes", reference, maxManifestSize)
+	}
+
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return fmt.Errorf("parse manifest %s: %w", reference, err)
+	}
+
+	for _, child := range m.Manifests {
+		if err := r.copyManifest(ctx, repository, child.Digest, depth+1); err != nil {
+			return err
+		}
+	}
+
+	blobs := m.Layers
+	if m.Config != nil {
+		blobs = append([]descriptor{*m.Config}, blobs...)
+	}
+	for _, blob := range blobs {
+		// Non-distributable layers (e.g. Windows base layers) live at their URLs
+		if len(blob.URLs) > 0 {
+			continue
+		}
+		if err := r.copyBlob(ctx, repository, blob); err != nil {
+			return err
+		}
+	}
+
+	contentType := resp.Headers.Get("Content-Type")
+	if contentType == "" {
+		contentType = m.MediaType
+	}
+	putHeaders := http.Header{"Content-Type": {contentType}}
+
+	putResp, err := r.do(ctx, r.target, http.MethodPut, manifestPath, "", putHeaders, bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return fmt.Errorf("push manifest %s: %w", reference, err)
+	}
+	drain(putResp)
+	if putResp.StatusCode != http.StatusCreated && putResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("push manifest %s: target returned %d", reference, putResp.StatusCode)
+	}
+	return nil
+}
+
+// copyBlob copies a blob to the target unless the target already has it, with a
+// monolithic upload streamed from the push backend
+func (r *Replicator) copyBlob(ctx context.Context, repository string, blob descriptor) error {
+	blobPath := "/v2/" + repository + "/blobs/" + blob.Digest
+
+	headResp, err := r.do(ctx, r.target, http.MethodHead, blobPath, "", nil, nil, 0)
+	if err != nil {
+		return fmt.Errorf("check blob %s: %w", blob.Digest, err)
+	}
+	drain(headResp)
+	if headResp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	content, size, err := r.fetchBlob(ctx, blobPath)
+	if err != nil {
+		return fmt.Errorf("fetch blob %s: %w", blob.Digest, err)
+	}
+	defer func() { _ = content.Close() }()
+	if size <= 0 {
+		size = blob.Size
+	}
+
+	startResp, err := r.do(ctx, r.target, http.MethodPost, "/v2/"+repository+"/blobs/uploads/", "", nil, nil, 0)
+	if err != nil {
+		return fmt.Errorf("start upload of blob %s: %w", blob.Digest, err)
+	}
+	drain(startResp)
+	if startResp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("start upload of blob %s: target returned %d", blob.Digest, startResp.StatusCode)
+	}
+
+	uploadPath, uploadQuery, err := uploadLocation(startResp.Headers.Get("Location"), blob.Digest)
+	if err != nil {
+		return fmt.Errorf("start upload of blob %s: %w", blob.Digest, err)
+	}
+
+	putHeaders := http.Header{"Content-Type": {"application/octet-stream"}}
+	putResp, err := r.do(ctx, r.target, http.MethodPut, uploadPath, uploadQuery, putHeaders, content, size)
+	if err != nil {
+		return fmt.Errorf("upload blob %s: %w", blob.Digest, err)
+	}
+	drain(putResp)
+	if putResp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("upload blob %s: target returned %d", blob.Digest, putResp.StatusCode)
+	}
+	return nil
+}
+
+// fetchBlob opens a blob on the push backend, following a redirect to the storage
+// holding it. It returns the blob's length, or 0 if unknown.
+func (r *Replicator) fetchBlob(ctx context.Context, blobPath string) (io.ReadCloser, int64, error) {
+	resp, err := r.do(ctx, r.source, http.MethodGet, blobPath, "", nil, nil, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, resp.HTTPResp.ContentLength, nil
+
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		drain(resp)
+		location, err := resp.HTTPResp.Location()
+		if err != nil {
+			return nil, 0, fmt.Errorf("push backend redirect: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, location.String(), nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		redirected, err := r.redirectClient.Do(req)
+		if err != nil {
+			return nil, 0, err
+		}
+		if redirected.StatusCode != http.StatusOK {
+			_ = redirected.Body.Close()
+			return nil, 0, fmt.Errorf("blob storage returned %d", redirected.StatusCode)
+		}
+		return redirected.Body, redirected.ContentLength, nil
+
+	default:
+		drain(resp)
+		return nil, 0, fmt.Errorf("push backend returned %d", resp.StatusCode)
+	}
+}
+
+// uploadLocation returns the path and query to PUT a monolithic upload to, from the
+// Location of a started upload session. Registries may return an absolute URL or a
+// path, with session state in the query that must be preserved.
+func uploadLocation(location, digest string) (string, string, error) {
+	if location == "" {
+		return "", "", fmt.Errorf("target returned no upload location")
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid upload location %q: %w", location, err)
+	}
+
+	query := u.Query()
+	query.Set("digest", digest)
+	return u.Path, query.Encode(), nil
+}
+
+// do sends a request to backend through the proxy client, which applies the
+// backend's auth, connection pool and circuit breaker
+func (r *Replicator) do(ctx context.Context, backend *config.OCIBackendConfig, method, path, query string, headers http.Header, body io.Reader, contentLength int64) (*proxy.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if headers == nil {
+		headers = http.Header{}
+	}
+
+	return r.proxyClient.ProxyRequest(&proxy.Request{
+		Method:        method,
+		Path:          path,
+		Query:         query,
+		Body:          body,
+		ContentLength: contentLength,
+		Headers:       headers,
+		Backend:       backend,
+		OriginalReq:   req,
+	})
+}
+
+// drain discards and closes a response body so the connection can be reused
+func drain(resp *proxy.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,201 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+)
+
+// listPageSize is the page size requested from the catalog and tag list endpoints
+const listPageSize = 100
+
+// reconcileLoop reconciles at startup and then every interval until the replicator
+// is stopped
+func (r *Replicator) reconcileLoop(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if !r.enabled() {
+			r.logger.Debug().Msg("Replication disabled by feature flag, skipping reconciliation")
+		} else if _, err := r.Reconcile(r.ctx); err != nil && r.ctx.Err() == nil {
+			r.logger.Warn().Err(err).Msg("Replication reconciliation failed")
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile compares every tag on the push backend with the target and queues those
+// missing or pointing at a different manifest on the target. It returns the number
+// of tags found out of sync.
+func (r *Replicator) Reconcile(ctx context.Context) (int, error) {
+	start := time.Now()
+
+	repositories, err := r.listRepositories(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list repositories: %w", err)
+	}
+
+	outOfSync := 0
+	for _, repository := range repositories {
+		tags, err := r.listTags(ctx, repository)
+		if err != nil {
+			return outOfSync, fmt.Errorf("list tags of %s: %w", repository, err)
+		}
+
+		for _, tag := range tags {
+			inSync, err := r.inSync(ctx, repository, tag)
+			if err != nil {
+				return outOfSync, fmt.Errorf("compare %s:%s: %w", repository, tag, err)
+			}
+			if inSync {
+				continue
+			}
+
+			outOfSync++
+			r.enqueue(job{repository: repository, reference: tag, pushed: time.Now()})
+		}
+	}
+
+	if r.metrics != nil {
+		r.metrics.SetReplicationOutOfSync(r.target.Name, outOfSync)
+	}
+
+	r.logger.Info().
+		Int("repositories", len(repositories)).
+		Int("out_of_sync", outOfSync).
+		Dur("duration", time.Since(start)).
+		Msg("Replication reconciliation complete")
+
+	return outOfSync, nil
+}
+
+// inSync reports whether the target has the same manifest as the push backend for
+// repository:tag. Manifests are compared by digest.
+func (r *Replicator) inSync(ctx context.Context, repository, tag string) (bool, error) {
+	sourceDigest, err := r.manifestDigest(ctx, r.source, repository, tag)
+	if err != nil || sourceDigest == "" {
+		return true, err // Deleted since listed, or no digest to compare; nothing to do
+	}
+
+	targetDigest, err := r.manifestDigest(ctx, r.target, repository, tag)
+	if err != nil {
+		return false, err
+	}
+	return targetDigest == sourceDigest, nil
+}
+
+// manifestDigest returns the digest of the manifest at repository:reference on
+// backend, or "" if backend doesn't have it
+func (r *Replicator) manifestDigest(ctx context.Context, backend *config.OCIBackendConfig, repository, reference string) (string, error) {
+	headers := http.Header{"Accept": {strings.Join(manifestMediaTypes, ", ")}}
+	resp, err := r.do(ctx, backend, http.MethodHead, "/v2/"+repository+"/manifests/"+reference, "", headers, nil, 0)
+	if err != nil {
+		return "", err
+	}
+	drain(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Headers.Get("Docker-Content-Digest"), nil
+	case http.StatusNotFound:
+		return "", nil
+	default:
+		return "", fmt.Errorf("%s returned %d", backend.Name, resp.StatusCode)
+	}
+}
+
+// listRepositories returns every repository on the push backend
+func (r *Replicator) listRepositories(ctx context.Context) ([]string, error) {
+	var repositories []string
+	err := r.paginate(ctx, "/v2/_catalog", func(body io.Reader) error {
+		var page struct {
+			Repositories []string `json:"repositories"`
+		}
+		if err := json.NewDecoder(body).Decode(&page); err != nil {
+			return err
+		}
+		repositories = append(repositories, page.Repositories...)
+		return nil
+	})
+	return repositories, err
+}
+
+// listTags returns every tag of repository on the push backend
+func (r *Replicator) listTags(ctx context.Context, repository string) ([]string, error) {
+	var tags []string
+	err := r.paginate(ctx, "/v2/"+repository+"/tags/list", func(body io.Reader) error {
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.NewDecoder(body).Decode(&page); err != nil {
+			return err
+		}
+		tags = append(tags, page.Tags...)
+		return nil
+	})
+	return tags, err
+}
+
+// paginate fetches each page of a list endpoint on the push backend, following the
+// Link header, and passes each page's body to collect
+func (r *Replicator) paginate(ctx context.Context, path string, collect func(body io.Reader) error) error {
+	query := url.Values{"n": {strconv.Itoa(listPageSize)}}.Encode()
+
+	for {
+		resp, err := r.do(ctx, r.source, http.MethodGet, path, query, nil, nil, 0)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			drain(resp)
+			return fmt.Errorf("push backend returned %d", resp.StatusCode)
+		}
+
+		err = collect(resp.Body)
+		drain(resp)
+		if err != nil {
+			return err
+		}
+
+		next := nextPage(resp.Headers.Get("Link"))
+		if next == nil {
+			return nil
+		}
+		path, query = next.Path, next.RawQuery
+	}
+}
+
+// nextPage returns the URL of the next page from a Link header of the form
+// `</v2/_catalog?last=b&n=100>; rel="next"`, or nil on the last page
+func nextPage(link string) *url.URL {
+	if !strings.Contains(link, `rel="next"`) {
+		return nil
+	}
+	start := strings.Index(link, "<")
+	end := strings.Index(link, ">")
+	if start < 0 || end < start {
+		return nil
+	}
+
+	u, err := url.Parse(link[start+1 : end])
+	if err != nil {
+		return nil
+	}
+	return u
+}
@@ -0,0 +1,200 @@
+package replication
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/featureflags"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/rs/zerolog"
+)
+
+// Replication results, used as the result label of the replication metric
+const (
+	resultReplicated = "replicated"
+	resultFailed     = "failed"
+	resultDropped    = "dropped"
+)
+
+// job is a manifest waiting to be replicated
+type job struct {
+	repository string
+	reference  string
+	pushed     time.Time // When the push happened or reconciliation detected it
+}
+
+// Replicator copies OCI pushes from the push backend to a secondary registry for
+// disaster recovery. Pushes are queued by Enqueue and replicated in the background
+// by a pool of workers; a reconciliation job periodically queues tags that are
+// missing or differ on the target.
+type Replicator struct {
+	source      *config.OCIBackendConfig
+	target      *config.OCIBackendConfig
+	proxyClient *proxy.Client
+	metrics     *metrics.Metrics    // nil = disabled
+	flags       *featureflags.Flags // nil = always enabled
+	logger      zerolog.Logger
+
+	// redirectClient follows blob redirects to storage (e.g. presigned S3 URLs),
+	// which the proxy client leaves to the caller
+	redirectClient *http.Client
+
+	queue   chan job
+	mu      sync.Mutex
+	pending map[string]bool // Queued repository:reference pairs, to coalesce repeated pushes
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a replicator for cfg.Replication and starts its workers and, if
+// configured, its reconciliation job. Stop releases them.
+func New(cfg *config.OCIConfig, proxyClient *proxy.Client, m *metrics.Metrics, logger zerolog.Logger) *Replicator {
+	replication := &cfg.Replication
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := &Replicator{
+		source:         &cfg.PushBackend,
+		target:         &replication.Target,
+		proxyClient:    proxyClient,
+		metrics:        m,
+		logger:         logger.With().Str("component", "replication").Str("target", replication.Target.Name).Logger(),
+		redirectClient: &http.Client{Timeout: cfg.PushBackend.RequestTimeout},
+		queue:          make(chan job, replication.QueueSize),
+		pending:        make(map[string]bool),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	for range replication.Workers {
+		r.wg.Add(1)
+		go r.work()
+	}
+
+	if replication.ReconcileInterval > 0 {
+		r.wg.Add(1)
+		go r.reconcileLoop(replication.ReconcileInterval)
+	}
+
+	return r
+}
+
+// Stop stops the workers and the reconciliation job, abandoning queued manifests;
+// the next reconciliation after a restart replicates them
+func (r *Replicator) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// SetFeatureFlags gates replication on the featureflags.Replication flag, checked
+// on every push and reconciliation. While it is disabled, pushes are not queued and
+// reconciliation is skipped; manifests already queued are still replicated, and
+// the first reconciliation after it is enabled again catches up. Must be called
+// before pushes are enqueued.
+func (r *Replicator) SetFeatureFlags(flags *featureflags.Flags) {
+	r.flags = flags
+}
+
+// enabled reports whether replication is enabled by its feature flag
+func (r *Replicator) enabled() bool {
+	return r.flags == nil || r.flags.Enabled(featureflags.Replication)
+}
+
+// Enqueue queues the manifest pushed to repository under reference (a tag or digest)
+// for replication. Pushes already queued are coalesced; when the queue is full the
+// push is dropped and left to reconciliation.
+func (r *Replicator) Enqueue(repository, reference string) {
+	if !r.enabled() {
+		r.logger.Debug().
+			Str("repository", repository).
+			Str("reference", reference).
+			Msg("Replication disabled by feature flag, leaving push to reconciliation")
+		return
+	}
+	r.enqueue(job{repository: repository, reference: reference, pushed: time.Now()})
+}
+
+func (r *Replicator) enqueue(j job) bool {
+	key := j.repository + ":" + j.reference
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pending[key] {
+		return true
+	}
+
+	select {
+	case r.queue <- j:
+		r.pending[key] = true
+		r.recordQueueLength()
+		return true
+	default:
+		r.logger.Warn().
+			Str("repository", j.repository).
+			Str("reference", j.reference).
+			Msg("Replication queue full, leaving push to reconciliation")
+		r.record(resultDropped, 0)
+		return false
+	}
+}
+
+// work replicates queued manifests until the replicator is stopped
+func (r *Replicator) work() {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case j := <-r.queue:
+			// Forget the job before copying, so a push during replication (e.g. a
+			// tag moved again) is queued rather than coalesced into this copy
+			r.mu.Lock()
+			delete(r.pending, j.repository+":"+j.reference)
+			r.recordQueueLength()
+			r.mu.Unlock()
+
+			r.process(j)
+		}
+	}
+}
+
+// process replicates one queued manifest and records the outcome
+func (r *Replicator) process(j job) {
+	logger := r.logger.With().
+		Str("repository", j.repository).
+		Str("reference", j.reference).
+		Logger()
+
+	if err := r.replicate(r.ctx, j.repository, j.reference); err != nil {
+		if r.ctx.Err() != nil {
+			return // Stopped mid-copy; not a replication failure
+		}
+		logger.Warn().Err(err).Msg("Failed to replicate manifest")
+		r.record(resultFailed, 0)
+		return
+	}
+
+	lag := time.Since(j.pushed)
+	logger.Debug().Dur("lag", lag).Msg("Replicated manifest")
+	r.record(resultReplicated, lag)
+}
+
+// recordQueueLength records the queue length; callers hold r.mu
+func (r *Replicator) recordQueueLength() {
+	if r.metrics != nil {
+		r.metrics.SetReplicationQueueLength(r.target.Name, len(r.queue))
+	}
+}
+
+func (r *Replicator) record(result string, lag time.Duration) {
+	if r.metrics != nil {
+		r.metrics.RecordReplication(r.target.Name, result, lag)
+	}
+}
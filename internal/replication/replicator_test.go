@@ -0,0 +1,423 @@
+package replication
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/mainuli/artifusion/internal/proxy"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+)
+
+// fakeRegistry is an in-memory registry implementing the distribution API subset
+// replication uses
+type fakeRegistry struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu        sync.Mutex
+	blobs     map[string][]byte           // repository@digest -> content
+	manifests map[string]registryManifest // repository:reference -> manifest
+	uploads   int
+	failing   bool
+}
+
+type registryManifest struct {
+	contentType string
+	body        []byte
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	reg := &fakeRegistry{
+		t:         t,
+		blobs:     make(map[string][]byte),
+		manifests: make(map[string]registryManifest),
+	}
+	reg.server = httptest.NewServer(http.HandlerFunc(reg.serveHTTP))
+	t.Cleanup(reg.server.Close)
+	return reg
+}
+
+func digestOf(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// addBlob stores content in repository and returns its descriptor
+func (reg *fakeRegistry) addBlob(repository, mediaType string, content []byte) descriptor {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	digest := digestOf(content)
+	reg.blobs[repository+"@"+digest] = content
+	return descriptor{MediaType: mediaType, Digest: digest, Size: int64(len(content))}
+}
+
+// addManifest stores m in repository under tag (if any) and its digest
+func (reg *fakeRegistry) addManifest(repository, tag string, m manifest) descriptor {
+	body, err := json.Marshal(m)
+	if err != nil {
+		reg.t.Fatalf("marshal manifest: %v", err)
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	digest := digestOf(body)
+	stored := registryManifest{contentType: m.MediaType, body: body}
+	reg.manifests[repository+":"+digest] = stored
+	if tag != "" {
+		reg.manifests[repository+":"+tag] = stored
+	}
+	return descriptor{MediaType: m.MediaType, Digest: digest, Size: int64(len(body))}
+}
+
+func (reg *fakeRegistry) manifest(repository, reference string) (registryManifest, bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	m, ok := reg.manifests[repository+":"+reference]
+	return m, ok
+}
+
+func (reg *fakeRegistry) uploadCount() int {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return reg.uploads
+}
+
+func (reg *fakeRegistry) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if reg.failing {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v2/")
+	switch {
+	case path == "_catalog":
+		var repositories []string
+		for key := range reg.manifests {
+			repository, _, _ := strings.Cut(key, ":")
+			if !slices.Contains(repositories, repository) {
+				repositories = append(repositories, repository)
+			}
+		}
+		slices.Sort(repositories)
+		_ = json.NewEncoder(w).Encode(map[string][]string{"repositories": repositories})
+
+	case strings.HasSuffix(path, "/tags/list"):
+		repository := strings.TrimSuffix(path, "/tags/list")
+		var tags []string
+		for key := range reg.manifests {
+			if reference, ok := strings.CutPrefix(key, repository+":"); ok && !strings.HasPrefix(reference, "sha256:") {
+				tags = append(tags, reference)
+			}
+		}
+		slices.Sort(tags)
+		_ = json.NewEncoder(w).Encode(map[string]any{"name": repository, "tags": tags})
+
+	case strings.Contains(path, "/manifests/"):
+		repository, reference, _ := strings.Cut(path, "/manifests/")
+		reg.serveManifest(w, r, repository, reference)
+
+	case strings.Contains(path, "/blobs/uploads/"):
+		repository, _, _ := strings.Cut(path, "/blobs/uploads/")
+		reg.serveUpload(w, r, repository)
+
+	case strings.Contains(path, "/blobs/"):
+		repository, digest, _ := strings.Cut(path, "/blobs/")
+		content, ok := reg.blobs[repository+"@"+digest]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(content)
+		}
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (reg *fakeRegistry) serveManifest(w http.ResponseWriter, r *http.Request, repository, reference string) {
+	if r.Method == http.MethodPut {
+		body, _ := io.ReadAll(r.Body)
+		var m manifest
+		if err := json.Unmarshal(body, &m); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// Like a real registry, refuse manifests referencing missing content
+		for _, child := range m.Manifests {
+			if _, ok := reg.manifests[repository+":"+child.Digest]; !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		blobs := m.Layers
+		if m.Config != nil {
+			blobs = append(blobs, *m.Config)
+		}
+		for _, blob := range blobs {
+			if _, ok := reg.blobs[repository+"@"+blob.Digest]; !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		stored := registryManifest{contentType: r.Header.Get("Content-Type"), body: body}
+		reg.manifests[repository+":"+reference] = stored
+		reg.manifests[repository+":"+digestOf(body)] = stored
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	m, ok := reg.manifests[repository+":"+reference]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", m.contentType)
+	w.Header().Set("Docker-Content-Digest", digestOf(m.body))
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(m.body)
+	}
+}
+
+func (reg *fakeRegistry) serveUpload(w http.ResponseWriter, r *http.Request, repository string) {
+	switch r.Method {
+	case http.MethodPost:
+		reg.uploads++
+		w.Header().Set("Location", fmt.Sprintf("%s/v2/%s/blobs/uploads/%d?_state=s%d", reg.server.URL, repository, reg.uploads, reg.uploads))
+		w.WriteHeader(http.StatusAccepted)
+
+	case http.MethodPut:
+		if r.URL.Query().Get("_state") == "" || r.ContentLength <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		digest := r.URL.Query().Get("digest")
+		if digestOf(body) != digest {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reg.blobs[repository+"@"+digest] = body
+		w.WriteHeader(http.StatusCreated)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func testBackend(name, url string) config.OCIBackendConfig {
+	return config.OCIBackendConfig{
+		Name:                name,
+		URL:                 url,
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 10,
+		DialTimeout:         time.Second,
+		RequestTimeout:      10 * time.Second,
+	}
+}
+
+// newTestReplicator creates a replicator from source to target without a
+// reconciliation loop
+func newTestReplicator(t *testing.T, source, target *fakeRegistry, m *metrics.Metrics) *Replicator {
+	cfg := &config.OCIConfig{
+		PushBackend: testBackend("registry", source.server.URL),
+		Replication: config.ReplicationConfig{
+			Enabled:   true,
+			Target:    testBackend("dr", target.server.URL),
+			Workers:   1,
+			QueueSize: 10,
+		},
+	}
+	r := New(cfg, proxy.NewClient(zerolog.Nop(), nil, nil), m, zerolog.Nop())
+	t.Cleanup(r.Stop)
+	return r
+}
+
+// waitFor fails the test if cond doesn't hold within a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// addImage stores a single-platform image with a config and two layers
+func addImage(reg *fakeRegistry, repository, tag, platform string) descriptor {
+	cfgBlob := reg.addBlob(repository, "application/vnd.oci.image.config.v1+json", []byte(`{"os":"`+platform+`"}`))
+	shared := reg.addBlob(repository, "application/vnd.oci.image.layer.v1.tar+gzip", []byte("base layer"))
+	layer := reg.addBlob(repository, "application/vnd.oci.image.layer.v1.tar+gzip", []byte("app layer "+platform))
+	return reg.addManifest(repository, tag, manifest{
+		MediaType: "application/vnd.oci.image.manifest.v1+json",
+		Config:    &cfgBlob,
+		Layers:    []descriptor{shared, layer},
+	})
+}
+
+func TestReplicator_ReplicatesPushesAndReconciles(t *testing.T) {
+	source := newFakeRegistry(t)
+	target := newFakeRegistry(t)
+	m := metrics.NewMetrics("replication_test")
+	r := newTestReplicator(t, source, target, m)
+
+	// A pushed image is replicated with its blobs
+	addImage(source, "team/app", "v1", "linux")
+	r.Enqueue("team/app", "v1")
+
+	waitFor(t, "team/app:v1 on the target", func() bool {
+		_, ok := target.manifest("team/app", "v1")
+		return ok
+	})
+	want, _ := source.manifest("team/app", "v1")
+	got, _ := target.manifest("team/app", "v1")
+	if string(got.body) != string(want.body) || got.contentType != want.contentType {
+		t.Errorf("replicated manifest = %s (%s), want %s (%s)", got.body, got.contentType, want.body, want.contentType)
+	}
+	if uploads := target.uploadCount(); uploads != 3 {
+		t.Errorf("uploaded %d blobs, want 3", uploads)
+	}
+	waitFor(t, "the replication to be recorded", func() bool {
+		return testutil.ToFloat64(m.Replications.WithLabelValues("dr", resultReplicated)) == 1
+	})
+
+	// A multi-platform index pushed while replication was down is found by
+	// reconciliation; blobs the target already has aren't uploaded again
+	index := manifest{MediaType: "application/vnd.oci.image.index.v1+json"}
+	index.Manifests = []descriptor{
+		addImage(source, "team/app", "", "linux"),
+		addImage(source, "team/app", "", "windows"),
+	}
+	source.addManifest("team/app", "latest", index)
+
+	outOfSync, err := r.Reconcile(t.Context())
+	if err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if outOfSync != 1 {
+		t.Errorf("reconcile found %d tags out of sync, want 1", outOfSync)
+	}
+
+	waitFor(t, "team/app:latest on the target", func() bool {
+		_, ok := target.manifest("team/app", "latest")
+		return ok
+	})
+	if uploads := target.uploadCount(); uploads != 5 {
+		t.Errorf("uploaded %d blobs, want 5 (the windows config and layer added)", uploads)
+	}
+
+	// Once replicated, everything is in sync
+	outOfSync, err = r.Reconcile(t.Context())
+	if err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if outOfSync != 0 {
+		t.Errorf("reconcile found %d tags out of sync after replication, want 0", outOfSync)
+	}
+	if got := testutil.ToFloat64(m.ReplicationOutOfSync.WithLabelValues("dr")); got != 0 {
+		t.Errorf("out of sync gauge = %v, want 0", got)
+	}
+}
+
+func TestReplicator_TargetFailure(t *testing.T) {
+	source := newFakeRegistry(t)
+	target := newFakeRegistry(t)
+	target.failing = true
+	m := metrics.NewMetrics("replication_failure_test")
+	r := newTestReplicator(t, source, target, m)
+
+	addImage(source, "team/app", "v1", "linux")
+	r.Enqueue("team/app", "v1")
+
+	waitFor(t, "the failure to be recorded", func() bool {
+		return testutil.ToFloat64(m.Replications.WithLabelValues("dr", resultFailed)) == 1
+	})
+	if _, err := r.Reconcile(t.Context()); err == nil {
+		t.Error("expected reconcile to fail while the target is down")
+	}
+}
+
+func TestUploadLocation(t *testing.T) {
+	tests := []struct {
+		location  string
+		wantPath  string
+		wantQuery string
+		wantErr   bool
+	}{
+		{
+			location:  "http://dr:5000/v2/app/blobs/uploads/1234?_state=abc",
+			wantPath:  "/v2/app/blobs/uploads/1234",
+			wantQuery: "_state=abc&digest=sha256%3Aaa",
+		},
+		{
+			location:  "/v2/app/blobs/uploads/1234",
+			wantPath:  "/v2/app/blobs/uploads/1234",
+			wantQuery: "digest=sha256%3Aaa",
+		},
+		{location: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			path, query, err := uploadLocation(tt.location, "sha256:aa")
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if path != tt.wantPath || query != tt.wantQuery {
+				t.Errorf("uploadLocation() = %q, %q, want %q, %q", path, query, tt.wantPath, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{`</v2/_catalog?last=b&n=100>; rel="next"`, "/v2/_catalog?last=b&n=100"},
+		{`<http://registry:5000/v2/app/tags/list?last=v9&n=100>; rel="next"`, "http://registry:5000/v2/app/tags/list?last=v9&n=100"},
+		{"", ""},
+		{`</v2/_catalog?last=b>; rel="prev"`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			got := ""
+			if next := nextPage(tt.link); next != nil {
+				got = next.String()
+			}
+			if got != tt.want {
+				t.Errorf("nextPage(%q) = %q, want %q", tt.link, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package synthetic
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// ociManifestAccept is the Accept header of OCI manifest requests
+const ociManifestAccept = "application/vnd.oci.image.index.v1+json, " +
+	"application/vnd.oci.image.manifest.v1+json, " +
+	"application/vnd.docker.distribution.manifest.list.v2+json, " +
+	"application/vnd.docker.distribution.manifest.v2+json"
+
+// ociManifest holds the fields of image indexes and manifests the OCI check follows
+type ociManifest struct {
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		} `json:"platform"`
+	} `json:"manifests"`
+	Config struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Layers []struct {
+		Digest string `json:"digest"`
+	} `json:"layers"`
+}
+
+// checkOCI pulls image like docker pull: its manifest (the linux/amd64 one of an
+// index), config and layers, verifying every digest
+func (r *Runner) checkOCI(ctx context.Context, image string) error {
+	host := r.protocols.OCI.Host
+	name, reference := parseImage(image)
+
+	manifest, err := r.pullManifest(ctx, host, name, reference)
+	if err != nil {
+		return err
+	}
+	if len(manifest.Manifests) > 0 {
+		digest := manifest.Manifests[0].Digest
+		for _, m := range manifest.Manifests {
+			if m.Platform.OS == "linux" && m.Platform.Architecture == "amd64" {
+				digest = m.Digest
+				break
+			}
+		}
+		if manifest, err = r.pullManifest(ctx, host, name, digest); err != nil {
+			return err
+		}
+	}
+	if manifest.Config.Digest == "" {
+		return fmt.Errorf("manifest of %s has no config", image)
+	}
+
+	blobs := []string{manifest.Config.Digest}
+	for _, layer := range manifest.Layers {
+		blobs = append(blobs, layer.Digest)
+	}
+	for _, digest := range blobs {
+		resp, err := r.get(ctx, host, "/v2/"+name+"/blobs/"+digest, "")
+		if err != nil {
+			return err
+		}
+		err = verifyDigest(resp.Body, digest)
+		_ = resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("blob %s: %w", digest, err)
+		}
+	}
+	return nil
+}
+
+// pullManifest fetches a manifest, verifying its digest against the reference when
+// pulled by digest and against Docker-Content-Digest when the registry reports it
+func (r *Runner) pullManifest(ctx context.Context, host, name, reference string) (*ociManifest, error) {
+	resp, body, err := r.getDocument(ctx, host, "/v2/"+name+"/manifests/"+reference, ociManifestAccept)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if strings.Contains(reference, ":") {
+		digest = reference
+	}
+	if digest != "" {
+		if err := verifyDigest(bytes.NewReader(body), digest); err != nil {
+			return nil, fmt.Errorf("manifest %s:%s: %w", name, reference, err)
+		}
+	}
+
+	var manifest ociManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("manifest %s:%s: %w", name, reference, err)
+	}
+	return &manifest, nil
+}
+
+// parseImage splits an image reference into its repository and tag or digest
+func parseImage(image string) (name, reference string) {
+	if name, digest, ok := strings.Cut(image, "@"); ok {
+		return name, digest
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
+
+// verifyDigest reads content and checks it against an OCI digest (sha256 or sha512)
+func verifyDigest(content io.Reader, digest string) error {
+	algorithm, expected, _ := strings.Cut(digest, ":")
+	var h hash.Hash
+	switch algorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported digest %q", digest)
+	}
+	if _, err := io.Copy(h, content); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != expected {
+		return fmt.Errorf("digest mismatch: got %s:%s, want %s", algorithm, got, digest)
+	}
+	return nil
+}
+
+// npmPackument holds the fields of an npm package document the npm check follows
+type npmPackument struct {
+	DistTags map[string]string `json:"dist-tags"`
+	Versions map[string]struct {
+		Dist struct {
+			Tarball   string `json:"tarball"`
+			Shasum    string `json:"shasum"`
+			Integrity string `json:"integrity"`
+		} `json:"dist"`
+	} `json:"versions"`
+}
+
+// checkNPM installs pkg like npm install: it fetches the package document, checks
+// the tarball URL was rewritten to Artifusion and downloads the tarball, verifying
+// its integrity
+func (r *Runner) checkNPM(ctx context.Context, pkg string) error {
+	npm := &r.protocols.NPM
+	name, version := pkg, ""
+	if i := strings.LastIndex(pkg, "@"); i > 0 {
+		name, version = pkg[:i], pkg[i+1:]
+	}
+
+	_, body, err := r.getDocument(ctx, npm.Host, npm.PathPrefix+"/"+strings.Replace(name, "/", "%2f", 1), "application/json")
+	if err != nil {
+		return err
+	}
+	var packument npmPackument
+	if err := json.Unmarshal(body, &packument); err != nil {
+		return fmt.Errorf("package %s: %w", name, err)
+	}
+	if version == "" {
+		version = packument.DistTags["latest"]
+	}
+	dist, ok := packument.Versions[version]
+	if !ok {
+		return fmt.Errorf("package %s has no version %q", name, version)
+	}
+
+	public := r.publicURL(npm.Host, npm.PathPrefix) + "/"
+	if !strings.HasPrefix(dist.Dist.Tarball, public) {
+		return fmt.Errorf("tarball URL %q of %s@%s is not rewritten to %s", dist.Dist.Tarball, name, version, public)
+	}
+	tarball, err := url.Parse(dist.Dist.Tarball)
+	if err != nil {
+		return fmt.Errorf("tarball URL of %s@%s: %w", name, version, err)
+	}
+
+	resp, err := r.get(ctx, npm.Host, tarball.EscapedPath(), "")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err := verifyNPMIntegrity(resp.Body, dist.Dist.Integrity, dist.Dist.Shasum); err != nil {
+		return fmt.Errorf("tarball of %s@%s: %w", name, version, err)
+	}
+	return nil
+}
+
+// verifyNPMIntegrity checks a tarball against its sha512 integrity or, for old
+// packages without one, its sha1 shasum
+func verifyNPMIntegrity(content io.Reader, integrity, shasum string) error {
+	if expected, ok := strings.CutPrefix(integrity, "sha512-"); ok {
+		h := sha512.New()
+		if _, err := io.Copy(h, content); err != nil {
+			return err
+		}
+		if got := base64.StdEncoding.EncodeToString(h.Sum(nil)); got != expected {
+			return fmt.Errorf("integrity mismatch: got sha512-%s, want %s", got, integrity)
+		}
+		return nil
+	}
+	if shasum == "" {
+		return fmt.Errorf("no integrity or shasum to verify")
+	}
+	return verifySHA1(content, shasum)
+}
+
+// checkMaven resolves coordinates (group:artifact:version) like a Maven build: it
+// downloads the POM and its .sha1 checksum and verifies one against the other
+func (r *Runner) checkMaven(ctx context.Context, coordinates string) error {
+	maven := &r.protocols.Maven
+	parts := strings.Split(coordinates, ":")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid coordinates %q", coordinates)
+	}
+	group, artifact, version := parts[0], parts[1], parts[2]
+	path := maven.PathPrefix + "/" + strings.ReplaceAll(group, ".", "/") + "/" + artifact + "/" + version + "/" +
+		artifact + "-" + version + ".pom"
+
+	_, checksum, err := r.getDocument(ctx, maven.Host, path+".sha1", "")
+	if err != nil {
+		return err
+	}
+	resp, err := r.get(ctx, maven.Host, path, "")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// Checksum files may be followed by the file name
+	expected, _, _ := strings.Cut(strings.TrimSpace(string(checksum)), " ")
+	if err := verifySHA1(resp.Body, expected); err != nil {
+		return fmt.Errorf("POM of %s: %w", coordinates, err)
+	}
+	return nil
+}
+
+// verifySHA1 reads content and checks it against a hex sha1 checksum
+func verifySHA1(content io.Reader, expected string) error {
+	h := sha1.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, expected) {
+		return fmt.Errorf("sha1 mismatch: got %s, want %s", got, expected)
+	}
+	return nil
+}
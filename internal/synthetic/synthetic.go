@@ -0,0 +1,201 @@
+// Package synthetic runs synthetic transactions: canary downloads Artifusion performs
+// against itself, exactly like a client would, on a schedule.
+//
+// TCP and backend health checks pass while authentication, routing or URL rewriting
+// is broken for real clients. A synthetic check authenticates with a real GitHub
+// token, goes through the full middleware and protocol handler chain, follows the
+// URLs Artifusion rewrote and verifies the digests of what it downloaded. Each run
+// is recorded in metrics, so alerts can fire on the first broken pull.
+package synthetic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/metrics"
+	"github.com/rs/zerolog"
+)
+
+// maxDocumentSize bounds the manifests and package documents checks parse
+const maxDocumentSize = 16 << 20
+
+// Runner runs the configured synthetic checks every interval
+type Runner struct {
+	cfg       *config.SyntheticChecksConfig
+	protocols *config.ProtocolsConfig
+	client    *http.Client
+	metrics   *metrics.Metrics // nil = disabled
+	logger    zerolog.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a runner for the synthetic checks in cfg, against the routing of
+// protocols
+func New(cfg *config.SyntheticChecksConfig, protocols *config.ProtocolsConfig, m *metrics.Metrics, logger zerolog.Logger) *Runner {
+	return &Runner{
+		cfg:       cfg,
+		protocols: protocols,
+		client:    &http.Client{},
+		metrics:   m,
+		logger:    logger.With().Str("component", "synthetic").Logger(),
+	}
+}
+
+// Start runs the checks every interval, the first time one interval after Start so
+// the server is listening, until Stop is called
+func (r *Runner) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.RunAll(ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops running checks, canceling runs in progress
+func (r *Runner) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+		<-r.done
+	}
+}
+
+// RunAll runs every check concurrently and returns the error of each failed check
+// by name
+func (r *Runner) RunAll(ctx context.Context) map[string]error {
+	failures := make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := range r.cfg.Checks {
+		check := &r.cfg.Checks[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.run(ctx, check); err != nil {
+				mu.Lock()
+				failures[check.Name] = err
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return failures
+}
+
+// run runs one check and records its outcome
+func (r *Runner) run(ctx context.Context, check *config.SyntheticCheckConfig) error {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	var err error
+	switch check.Protocol {
+	case "oci":
+		err = r.checkOCI(ctx, check.Artifact)
+	case "npm":
+		err = r.checkNPM(ctx, check.Artifact)
+	case "maven":
+		err = r.checkMaven(ctx, check.Artifact)
+	default:
+		err = fmt.Errorf("unsupported protocol %q", check.Protocol)
+	}
+	duration := time.Since(start)
+
+	if r.metrics != nil {
+		r.metrics.RecordSyntheticCheck(check.Name, err == nil, duration)
+	}
+	if err != nil {
+		r.logger.Warn().Err(err).
+			Str("check", check.Name).
+			Str("protocol", check.Protocol).
+			Str("artifact", check.Artifact).
+			Dur("duration", duration).
+			Msg("Synthetic check failed")
+		return err
+	}
+
+	r.logger.Debug().
+		Str("check", check.Name).
+		Dur("duration", duration).
+		Msg("Synthetic check succeeded")
+	return nil
+}
+
+// get sends an authenticated GET for path to Artifusion, with the Host header of
+// host-based routing when host is set. Responses other than 200 are returned as
+// errors.
+func (r *Runner) get(ctx context.Context, host, path, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(r.cfg.URL, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if host != "" {
+		req.Host = host
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	req.Header.Set("Authorization", "Bearer "+r.cfg.Token)
+	req.Header.Set("User-Agent", "artifusion-synthetic-check")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDocumentSize))
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: status %d", path, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// getDocument GETs path and returns its body, up to maxDocumentSize
+func (r *Runner) getDocument(ctx context.Context, host, path, accept string) (*http.Response, []byte, error) {
+	resp, err := r.get(ctx, host, path, accept)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentSize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("GET %s: %w", path, err)
+	}
+	if len(body) > maxDocumentSize {
+		return nil, nil, fmt.Errorf("GET %s: response exceeds %d bytes", path, maxDocumentSize)
+	}
+	return resp, body, nil
+}
+
+// publicURL returns the URL clients of a protocol routed by host and path prefix
+// reach Artifusion at, which rewritten URLs must point at
+func (r *Runner) publicURL(host, pathPrefix string) string {
+	base := strings.TrimSuffix(r.cfg.URL, "/")
+	if host != "" {
+		scheme, _, _ := strings.Cut(base, "://")
+		base = scheme + "://" + host
+	}
+	return base + pathPrefix
+}
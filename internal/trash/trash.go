@@ -0,0 +1,271 @@
+// Package trash holds deleted artifacts for a retention period before the delete is
+// carried out, so accidental deletions can be restored.
+//
+// Protocol handlers add deleted artifacts to the trash instead of forwarding the
+// delete, and hide trashed artifacts from reads. Once an entry's retention expires,
+// the purge function supplied to Start forwards the delete to the backend.
+package trash
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/mainuli/artifusion/internal/constants"
+	"github.com/rs/zerolog"
+)
+
+// Entry is a deleted artifact held in the trash
+type Entry struct {
+	Path      string    `json:"path"` // Request path of the artifact, e.g. /v2/team/app/manifests/v1
+	DeletedBy string    `json:"deleted_by"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"` // When the delete is forwarded to the backend
+}
+
+// PurgeFunc carries out the delete of a trashed artifact whose retention expired.
+// An error keeps the entry in the trash to be retried.
+type PurgeFunc func(ctx context.Context, entry Entry) error
+
+// Trash holds deleted artifacts by path. It is safe for concurrent use. A nil
+// *Trash holds nothing.
+type Trash struct {
+	retention time.Duration
+	stateFile string // Empty = not persisted
+	logger    zerolog.Logger
+	now       func() time.Time
+
+	mu      sync.RWMutex
+	entries map[string]Entry
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a trash for cfg, loading the entries persisted in cfg.StateFile if set
+func New(cfg *config.TrashConfig, logger zerolog.Logger) (*Trash, error) {
+	t := &Trash{
+		retention: cfg.Retention,
+		stateFile: cfg.StateFile,
+		logger:    logger.With().Str("component", "trash").Logger(),
+		now:       time.Now,
+		entries:   make(map[string]Entry),
+	}
+
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Add moves the artifact at path into the trash, deleted by deletedBy. Deleting an
+// artifact already in the trash keeps its original entry.
+func (t *Trash) Add(path, deletedBy string) Entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if entry, ok := t.entries[path]; ok {
+		return entry
+	}
+
+	now := t.now()
+	entry := Entry{
+		Path:      path,
+		DeletedBy: deletedBy,
+		DeletedAt: now,
+		PurgeAt:   now.Add(t.retention),
+	}
+	t.entries[path] = entry
+	t.save()
+	return entry
+}
+
+// Contains reports whether the artifact at path is in the trash
+func (t *Trash) Contains(path string) bool {
+	if t == nil {
+		return false
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	_, ok := t.entries[path]
+	return ok
+}
+
+// Remove takes the artifact at path out of the trash, restoring it. It reports
+// false if the artifact wasn't in the trash.
+func (t *Trash) Remove(path string) (Entry, bool) {
+	if t == nil {
+		return Entry{}, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[path]
+	if !ok {
+		return Entry{}, false
+	}
+	delete(t.entries, path)
+	t.save()
+	return entry, true
+}
+
+// Entries returns the artifacts in the trash, oldest deletion first
+func (t *Trash) Entries() []Entry {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(t.entries))
+	for _, entry := range t.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].DeletedAt.Equal(entries[j].DeletedAt) {
+			return entries[i].DeletedAt.Before(entries[j].DeletedAt)
+		}
+		return entries[i].Path < entries[j].Path
+	})
+	return entries
+}
+
+// Purge calls purge for every entry whose retention expired and drops the entries
+// purged successfully. It returns the number of entries purged.
+func (t *Trash) Purge(ctx context.Context, purge PurgeFunc) int {
+	now := t.now()
+
+	t.mu.RLock()
+	var expired []Entry
+	for _, entry := range t.entries {
+		if !entry.PurgeAt.After(now) {
+			expired = append(expired, entry)
+		}
+	}
+	t.mu.RUnlock()
+
+	purged := 0
+	for _, entry := range expired {
+		if err := purge(ctx, entry); err != nil {
+			t.logger.Warn().Err(err).
+				Str("path", entry.Path).
+				Msg("Failed to purge trashed artifact, retrying later")
+			continue
+		}
+
+		t.mu.Lock()
+		// Unless restored (and possibly deleted again) while purging
+		if current, ok := t.entries[entry.Path]; ok && current.DeletedAt.Equal(entry.DeletedAt) {
+			delete(t.entries, entry.Path)
+			t.save()
+		}
+		t.mu.Unlock()
+
+		t.logger.Info().
+			Str("path", entry.Path).
+			Str("deleted_by", entry.DeletedBy).
+			Msg("Purged trashed artifact")
+		purged++
+	}
+	return purged
+}
+
+// Start purges expired entries with purge every constants.TrashPurgeInterval until
+// Stop is called
+func (t *Trash) Start(purge PurgeFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	t.done = make(chan struct{})
+
+	go func() {
+		defer close(t.done)
+
+		ticker := time.NewTicker(constants.TrashPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				t.Purge(ctx, purge)
+			}
+		}
+	}()
+}
+
+// Stop stops purging started by Start
+func (t *Trash) Stop() {
+	if t.cancel == nil {
+		return
+	}
+	t.cancel()
+	<-t.done
+}
+
+// load reads the persisted entries, if any
+func (t *Trash) load() error {
+	if t.stateFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(t.stateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read trash state: %w", err)
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse trash state %s: %w", t.stateFile, err)
+	}
+	for _, entry := range entries {
+		t.entries[entry.Path] = entry
+	}
+	return nil
+}
+
+// save persists the entries, replacing the state file atomically so a crash never
+// leaves it truncated. Callers hold t.mu. Failures are logged: the trash keeps
+// working in memory.
+func (t *Trash) save() {
+	if t.stateFile == "" {
+		return
+	}
+
+	entries := make([]Entry, 0, len(t.entries))
+	for _, entry := range t.entries {
+		entries = append(entries, entry)
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		t.logger.Error().Err(err).Msg("Failed to encode trash state")
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(t.stateFile), filepath.Base(t.stateFile)+".*")
+	if err != nil {
+		t.logger.Error().Err(err).Msg("Failed to persist trash state")
+		return
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), t.stateFile)
+	}
+	if err != nil {
+		t.logger.Error().Err(err).Msg("Failed to persist trash state")
+	}
+}
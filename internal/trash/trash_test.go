@@ -0,0 +1,123 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func newTestTrash(t *testing.T, cfg *config.TrashConfig, now *time.Time) *Trash {
+	t.Helper()
+	trash, err := New(cfg, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("failed to create trash: %v", err)
+	}
+	trash.now = func() time.Time { return *now }
+	return trash
+}
+
+func TestTrash_AddRemove(t *testing.T) {
+	now := time.Now()
+	trash := newTestTrash(t, &config.TrashConfig{Retention: time.Hour}, &now)
+
+	entry := trash.Add("/v2/app/manifests/v1", "alice")
+	if !entry.PurgeAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("purge at %v, want %v", entry.PurgeAt, now.Add(time.Hour))
+	}
+	if !trash.Contains("/v2/app/manifests/v1") {
+		t.Error("trashed artifact not in the trash")
+	}
+
+	// Deleting again keeps the original deletion
+	now = now.Add(time.Minute)
+	if again := trash.Add("/v2/app/manifests/v1", "bob"); again != entry {
+		t.Errorf("second delete = %+v, want the original entry %+v", again, entry)
+	}
+
+	restored, ok := trash.Remove("/v2/app/manifests/v1")
+	if !ok || restored != entry {
+		t.Errorf("Remove() = %+v, %v, want %+v, true", restored, ok, entry)
+	}
+	if trash.Contains("/v2/app/manifests/v1") {
+		t.Error("restored artifact still in the trash")
+	}
+	if _, ok := trash.Remove("/v2/app/manifests/v1"); ok {
+		t.Error("expected Remove of an artifact not in the trash to report false")
+	}
+
+	var disabled *Trash
+	if disabled.Contains("/v2/app/manifests/v1") {
+		t.Error("nil trash reports an artifact as trashed")
+	}
+}
+
+func TestTrash_Purge(t *testing.T) {
+	now := time.Now()
+	trash := newTestTrash(t, &config.TrashConfig{Retention: time.Hour}, &now)
+	trash.Add("/v2/app/manifests/old", "alice")
+	now = now.Add(30 * time.Minute)
+	trash.Add("/v2/app/manifests/new", "alice")
+
+	var purged []string
+	failing := true
+	purge := func(_ context.Context, entry Entry) error {
+		if failing {
+			return errors.New("backend unavailable")
+		}
+		purged = append(purged, entry.Path)
+		return nil
+	}
+
+	// Failed purges keep the entry for the next run
+	now = now.Add(45 * time.Minute)
+	if n := trash.Purge(context.Background(), purge); n != 0 {
+		t.Errorf("purged %d entries while failing, want 0", n)
+	}
+	if !trash.Contains("/v2/app/manifests/old") {
+		t.Error("entry dropped although its purge failed")
+	}
+
+	// Only expired entries are purged
+	failing = false
+	if n := trash.Purge(context.Background(), purge); n != 1 {
+		t.Errorf("purged %d entries, want 1", n)
+	}
+	if len(purged) != 1 || purged[0] != "/v2/app/manifests/old" {
+		t.Errorf("purged %v, want only the expired entry", purged)
+	}
+	if trash.Contains("/v2/app/manifests/old") || !trash.Contains("/v2/app/manifests/new") {
+		t.Errorf("entries after purge = %+v, want only the unexpired entry", trash.Entries())
+	}
+}
+
+func TestTrash_StateFile(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "trash.json")
+	cfg := &config.TrashConfig{Retention: time.Hour, StateFile: stateFile}
+	now := time.Now().Truncate(time.Second)
+
+	trash := newTestTrash(t, cfg, &now)
+	trash.Add("/v2/app/manifests/v1", "alice")
+	trash.Add("/v2/app/blobs/sha256:abc", "alice")
+	trash.Remove("/v2/app/blobs/sha256:abc")
+
+	// A restart keeps the trash
+	restarted := newTestTrash(t, cfg, &now)
+	entries := restarted.Entries()
+	if len(entries) != 1 || entries[0].Path != "/v2/app/manifests/v1" || entries[0].DeletedBy != "alice" {
+		t.Errorf("entries after restart = %+v, want the trashed manifest", entries)
+	}
+
+	// A corrupt state file is an error rather than an empty trash
+	if err := os.WriteFile(stateFile, []byte("{"), 0o600); err != nil {
+		t.Fatalf("failed to corrupt state file: %v", err)
+	}
+	if _, err := New(cfg, zerolog.Nop()); err == nil {
+		t.Error("expected an error loading a corrupt state file")
+	}
+}
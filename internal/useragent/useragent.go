@@ -0,0 +1,169 @@
+// Package useragent classifies package manager and container clients by their
+// User-Agent header, and compares their versions.
+package useragent
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Client types, used as the client label of metrics and in client policy rules
+const (
+	Docker     = "docker"
+	Containerd = "containerd"
+	NPM        = "npm"
+	Yarn       = "yarn"
+	PNPM       = "pnpm"
+	Maven      = "maven"
+	Gradle     = "gradle"
+	Other      = "other"
+)
+
+// products maps the lowercase product of a User-Agent's first token to its client
+// type, e.g. "Apache-Maven/3.9.6 (Java 17.0.9; Linux 6.5.0)"
+var products = map[string]string{
+	"docker":       Docker,
+	"containerd":   Containerd,
+	"npm":          NPM,
+	"yarn":         Yarn,
+	"pnpm":         PNPM,
+	"apache-maven": Maven,
+	"gradle":       Gradle,
+}
+
+// Client is a classified client
+type Client struct {
+	Type    string // One of the client types, Other if unrecognized
+	Version string // e.g. "24.0.7", without a leading "v"; empty if unknown
+}
+
+// Parse classifies the client that sent userAgent. Clients lead their User-Agent
+// with product/version, e.g. "npm/10.2.4 node/v20.11.0 darwin arm64".
+func Parse(userAgent string) Client {
+	first, _, _ := strings.Cut(strings.TrimSpace(userAgent), " ")
+	product, version, _ := strings.Cut(first, "/")
+
+	clientType, ok := products[strings.ToLower(product)]
+	if !ok {
+		return Client{Type: Other}
+	}
+	return Client{Type: clientType, Version: strings.TrimPrefix(version, "v")}
+}
+
+// String returns type/version, or the type if the version is unknown
+func (c Client) String() string {
+	if c.Version == "" {
+		return c.Type
+	}
+	return c.Type + "/" + c.Version
+}
+
+// CompareVersions compares the dotted numeric versions a and b, returning -1, 0 or
+// +1. Missing components count as 0 ("8" = "8.0.0"), and pre-release and build
+// suffixes are ignored ("1.2.3-rc.1" = "1.2.3").
+func CompareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := range max(len(pa), len(pb)) {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts returns the numeric components of version, up to the first
+// non-numeric one
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+
+	var parts []int
+	for _, component := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(component)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// IsVersion reports whether version is a dotted numeric version, e.g. "6.14.6" or
+// "v1.7"
+func IsVersion(version string) bool {
+	return len(versionParts(version)) > 0 && strings.Trim(version, "v0123456789.") == ""
+}
+
+// Constraint matches versions, e.g. "<6.14.6" or ">=7.0.0 <7.1.2" (all terms must
+// match). Operators are <, <=, >, >= and =; a version without one must be equal.
+type Constraint struct {
+	terms []constraintTerm
+}
+
+type constraintTerm struct {
+	op      string
+	version string
+}
+
+// ParseConstraint parses a version constraint
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+	for _, field := range strings.Fields(s) {
+		op := "="
+		for _, candidate := range []string{"<=", ">=", "<", ">", "="} {
+			if rest, ok := strings.CutPrefix(field, candidate); ok {
+				op, field = candidate, rest
+				break
+			}
+		}
+		if !IsVersion(field) {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q: want e.g. <6.14.6 or \">=7.0.0 <7.1.2\"", s)
+		}
+		c.terms = append(c.terms, constraintTerm{op: op, version: field})
+	}
+	if len(c.terms) == 0 {
+		return Constraint{}, fmt.Errorf("empty version constraint")
+	}
+	return c, nil
+}
+
+// Matches reports whether version satisfies every term of c. Unknown versions
+// match nothing.
+func (c Constraint) Matches(version string) bool {
+	if len(versionParts(version)) == 0 {
+		return false
+	}
+	for _, term := range c.terms {
+		cmp := CompareVersions(version, term.version)
+		var ok bool
+		switch term.op {
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		default:
+			ok = cmp == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package useragent
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      Client
+	}{
+		{"docker/24.0.7 go/go1.20.10 git-commit/311b9ff kernel/6.5.0 os/linux arch/amd64 UpstreamClient(Docker-Client/24.0.7 \\(linux\\))", Client{Docker, "24.0.7"}},
+		{"containerd/v1.7.2", Client{Containerd, "1.7.2"}},
+		{"npm/10.2.4 node/v20.11.0 darwin arm64 workspaces/false", Client{NPM, "10.2.4"}},
+		{"yarn/1.22.19 npm/? node/v18.17.1 linux x64", Client{Yarn, "1.22.19"}},
+		{"pnpm/8.15.1 npm/? node/v20.11.0 darwin arm64", Client{PNPM, "8.15.1"}},
+		{"Apache-Maven/3.9.6 (Java 17.0.9; Linux 6.5.0)", Client{Maven, "3.9.6"}},
+		{"Gradle/8.5 (Mac OS X;14.2;aarch64) (Eclipse Adoptium;17.0.9;17.0.9+9)", Client{Gradle, "8.5"}},
+		{"curl/8.4.0", Client{Type: Other}},
+		{"", Client{Type: Other}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userAgent, func(t *testing.T) {
+			if got := Parse(tt.userAgent); got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"6.14.5", "6.14.6", -1},
+		{"6.14.10", "6.14.6", 1},
+		{"8", "8.0.0", 0},
+		{"v1.7.2", "1.7.2", 0},
+		{"1.2.3-rc.1", "1.2.3", 0},
+		{"24.0.7", "3.9.6", 1},
+	}
+
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"<6.14.6", "6.14.5", true},
+		{"<6.14.6", "6.14.6", false},
+		{"<=6.14.6", "6.14.6", true},
+		{">=7.0.0 <7.1.2", "7.1.1", true},
+		{">=7.0.0 <7.1.2", "7.1.2", false},
+		{">=7.0.0 <7.1.2", "6.0.0", false},
+		{"1.22.19", "1.22.19", true},
+		{"=1.22", "1.22.0", true},
+		{"<6.14.6", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint+" "+tt.version, func(t *testing.T) {
+			constraint, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("ParseConstraint() error = %v", err)
+			}
+			if got := constraint.Matches(tt.version); got != tt.want {
+				t.Errorf("Matches(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"", "<", "<=abc", "~1.2", "1.x"} {
+		if _, err := ParseConstraint(invalid); err == nil {
+			t.Errorf("ParseConstraint(%q) succeeded, want error", invalid)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"fmt"
 	"net"
 	"net/http"
 	"strings"
@@ -116,3 +117,116 @@ func parseForwardedForIP(forwarded string) string {
 
 	return ""
 }
+
+// TrustedProxies resolves client IPs for security decisions (lockouts, rate limits,
+// credential sharing), where the forwarding headers GetClientIP reads can't be
+// trusted: any client can set them. Only requests whose connection comes from a
+// trusted proxy have their forwarding headers read, and X-Forwarded-For chains are
+// walked from the right, skipping trusted proxies, so addresses a client prepends
+// are ignored.
+//
+// A nil *TrustedProxies trusts no proxy.
+//
+// Thread safety: All methods are safe for concurrent use.
+type TrustedProxies struct {
+	networks []*net.IPNet
+}
+
+// NewTrustedProxies creates the resolver of proxies, given as IP addresses or CIDR
+// ranges (e.g. "10.0.0.0/8")
+func NewTrustedProxies(proxies []string) (*TrustedProxies, error) {
+	t := &TrustedProxies{}
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q (expected IP address or CIDR)", proxy)
+			}
+			if ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q (expected IP address or CIDR)", proxy)
+		}
+		t.networks = append(t.networks, network)
+	}
+	return t, nil
+}
+
+// ClientIP returns the client IP of r: the connection's peer address, or, if the
+// peer is a trusted proxy, the address the proxies forwarded for.
+//
+// Examples with 10.0.0.0/8 trusted:
+//   - RemoteAddr 203.0.113.1, X-Forwarded-For: 198.51.100.1 → "203.0.113.1"
+//   - RemoteAddr 10.0.0.5, X-Forwarded-For: 198.51.100.1, 203.0.113.1 → "203.0.113.1"
+//   - RemoteAddr 10.0.0.5, X-Forwarded-For: 203.0.113.1, 10.0.0.7 → "203.0.113.1"
+func (t *TrustedProxies) ClientIP(r *http.Request) string {
+	peer := stripPort(r.RemoteAddr)
+	if !t.trusts(peer) {
+		return peer
+	}
+
+	// Each proxy appends the address it received the request from
+	if forwarded := forwardedChain(r.Header.Values("X-Forwarded-For"), parseIP); len(forwarded) > 0 {
+		return t.firstUntrusted(forwarded)
+	}
+	if ip := parseIP(r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+	if forwarded := forwardedChain(r.Header.Values("Forwarded"), parseForwardedForIP); len(forwarded) > 0 {
+		return t.firstUntrusted(forwarded)
+	}
+	return peer
+}
+
+// trusts reports whether ip is a trusted proxy
+func (t *TrustedProxies) trusts(ip string) bool {
+	if t == nil {
+		return false
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range t.networks {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstUntrusted returns the rightmost address of chain that isn't a trusted proxy,
+// or the leftmost if all are
+func (t *TrustedProxies) firstUntrusted(chain []string) string {
+	for i := len(chain) - 1; i > 0; i-- {
+		if !t.trusts(chain[i]) {
+			return chain[i]
+		}
+	}
+	return chain[0]
+}
+
+// forwardedChain parses the comma-separated elements of forwarding header values
+// with parse, stopping at the first invalid one: what a client sent before it
+// can't be relied on
+func forwardedChain(values []string, parse func(string) string) []string {
+	var elements []string
+	for _, value := range values {
+		elements = append(elements, strings.Split(value, ",")...)
+	}
+
+	var chain []string
+	for i := len(elements) - 1; i >= 0; i-- {
+		ip := parse(elements[i])
+		if ip == "" {
+			break
+		}
+		chain = append([]string{ip}, chain...)
+	}
+	return chain
+}
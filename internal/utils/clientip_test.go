@@ -323,3 +323,90 @@ func TestParseForwardedForIP(t *testing.T) {
 		})
 	}
 }
+
+func TestTrustedProxies_ClientIP(t *testing.T) {
+	proxies, err := NewTrustedProxies([]string{"10.0.0.0/8", "192.0.2.10"})
+	if err != nil {
+		t.Fatalf("NewTrustedProxies() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		proxies    *TrustedProxies
+		remoteAddr string
+		headers    map[string]string
+		expectedIP string
+	}{
+		{
+			name:       "untrusted peer's forwarding headers are ignored",
+			proxies:    proxies,
+			remoteAddr: "203.0.113.1:54321",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"},
+			expectedIP: "203.0.113.1",
+		},
+		{
+			name:       "nil trusts no proxy",
+			remoteAddr: "10.0.0.5:54321",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			expectedIP: "10.0.0.5",
+		},
+		{
+			name:       "address appended by trusted proxy",
+			proxies:    proxies,
+			remoteAddr: "10.0.0.5:54321",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.1"},
+			expectedIP: "203.0.113.1",
+		},
+		{
+			name:       "trusted proxy chain is skipped",
+			proxies:    proxies,
+			remoteAddr: "10.0.0.5:54321",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.1, 192.0.2.10, 10.0.0.7"},
+			expectedIP: "203.0.113.1",
+		},
+		{
+			name:       "invalid element ends the chain",
+			proxies:    proxies,
+			remoteAddr: "10.0.0.5:54321",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.1, garbage, 10.0.0.7"},
+			expectedIP: "10.0.0.7",
+		},
+		{
+			name:       "X-Real-IP from trusted proxy",
+			proxies:    proxies,
+			remoteAddr: "192.0.2.10:54321",
+			headers:    map[string]string{"X-Real-IP": "203.0.113.1"},
+			expectedIP: "203.0.113.1",
+		},
+		{
+			name:       "Forwarded from trusted proxy",
+			proxies:    proxies,
+			remoteAddr: "10.0.0.5:54321",
+			headers:    map[string]string{"Forwarded": "for=198.51.100.1, for=203.0.113.1;proto=https"},
+			expectedIP: "203.0.113.1",
+		},
+		{
+			name:       "trusted proxy without forwarding headers",
+			proxies:    proxies,
+			remoteAddr: "10.0.0.5:54321",
+			expectedIP: "10.0.0.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			if got := tt.proxies.ClientIP(req); got != tt.expectedIP {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.expectedIP)
+			}
+		})
+	}
+
+	if _, err := NewTrustedProxies([]string{"proxy.example.com"}); err == nil {
+		t.Error("NewTrustedProxies() with a hostname succeeded, want error")
+	}
+}
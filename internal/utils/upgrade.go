@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+)
+
+// IsWebSocketUpgrade reports whether the request headers ask to upgrade the
+// connection to WebSocket, the only protocol upgrade the proxy tunnels to backends
+func IsWebSocketUpgrade(h http.Header) bool {
+	if !strings.EqualFold(strings.TrimSpace(h.Get("Upgrade")), "websocket") {
+		return false
+	}
+	for _, value := range h.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestIsWebSocketUpgrade(t *testing.T) {
+	tests := []struct {
+		name       string
+		connection []string
+		upgrade    string
+		want       bool
+	}{
+		{name: "websocket", connection: []string{"Upgrade"}, upgrade: "websocket", want: true},
+		{name: "token list and case", connection: []string{"keep-alive, upgrade"}, upgrade: "WebSocket", want: true},
+		{name: "repeated connection header", connection: []string{"keep-alive", "Upgrade"}, upgrade: "websocket", want: true},
+		{name: "h2c", connection: []string{"Upgrade, HTTP2-Settings"}, upgrade: "h2c"},
+		{name: "upgrade without connection token", connection: []string{"keep-alive"}, upgrade: "websocket"},
+		{name: "plain request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for _, v := range tt.connection {
+				h.Add("Connection", v)
+			}
+			if tt.upgrade != "" {
+				h.Set("Upgrade", tt.upgrade)
+			}
+			if got := IsWebSocketUpgrade(h); got != tt.want {
+				t.Errorf("IsWebSocketUpgrade() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+// Package vault resolves backend credentials from HashiCorp Vault.
+//
+// Backend auth fields may hold vault:<path>#<field> references instead of plaintext
+// secrets. The client reads every referenced secret at startup and keeps it current:
+// leases of dynamic secrets are renewed, and their secrets re-read once a lease
+// can't be renewed past the next check; secrets without a lease (KV) are re-read on
+// every check, so credentials rotated in Vault are used without a restart.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// Prefix marks a config value as a Vault reference
+const Prefix = "vault:"
+
+// maxResponseSize bounds the Vault responses read
+const maxResponseSize = 1 << 20
+
+// IsReference reports whether value is a Vault reference
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// parseReference splits a vault:<path>#<field> reference
+func parseReference(ref string) (path, field string, err error) {
+	path, field, ok := strings.Cut(strings.TrimPrefix(ref, Prefix), "#")
+	path = strings.Trim(path, "/")
+	if !IsReference(ref) || !ok || path == "" || field == "" {
+		return "", "", fmt.Errorf("invalid Vault reference %q: want vault:<path>#<field>", ref)
+	}
+	return path, field, nil
+}
+
+// AuthFields returns the fields of auth that may hold Vault references
+func AuthFields(auth *config.AuthConfig) []*string {
+	return []*string{&auth.Username, &auth.Password, &auth.Token, &auth.HeaderValue, &auth.PrivateKey}
+}
+
+// References returns the Vault references in auth
+func References(auth *config.AuthConfig) []string {
+	var refs []string
+	for _, field := range AuthFields(auth) {
+		if IsReference(*field) {
+			refs = append(refs, *field)
+		}
+	}
+	return refs
+}
+
+// secret is a secret read from Vault. Secrets are replaced, never modified.
+type secret struct {
+	data      map[string]any
+	leaseID   string // Empty if not leased
+	renewable bool
+	expiresAt time.Time // Lease expiry
+}
+
+// Client reads secrets from Vault and keeps them current.
+//
+// Thread safety: All methods are safe for concurrent use.
+type Client struct {
+	cfg    *config.VaultConfig
+	http   *http.Client
+	logger zerolog.Logger
+
+	mu             sync.RWMutex
+	secrets        map[string]*secret // By path
+	tokenRenewable bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a client of the Vault server of a validated cfg
+func New(cfg *config.VaultConfig, logger zerolog.Logger) *Client {
+	return &Client{
+		cfg:     cfg,
+		http:    &http.Client{Timeout: 10 * time.Second},
+		logger:  logger.With().Str("component", "vault").Logger(),
+		secrets: make(map[string]*secret),
+	}
+}
+
+// Resolve checks the client's token and reads the secrets of refs, failing if a
+// secret or one of the referenced fields doesn't exist
+func (c *Client) Resolve(ctx context.Context, refs []string) error {
+	var lookup struct {
+		Data struct {
+			Renewable bool `json:"renewable"`
+		} `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, "auth/token/lookup-self", nil, &lookup); err != nil {
+		return fmt.Errorf("failed to look up Vault token: %w", err)
+	}
+	c.mu.Lock()
+	// A token file is renewed by whoever writes it, e.g. Vault Agent
+	c.tokenRenewable = lookup.Data.Renewable && c.cfg.TokenFile == ""
+	c.mu.Unlock()
+
+	for _, ref := range refs {
+		path, _, err := parseReference(ref)
+		if err != nil {
+			return err
+		}
+		c.mu.RLock()
+		_, exists := c.secrets[path]
+		c.mu.RUnlock()
+		if !exists {
+			if err := c.read(ctx, path); err != nil {
+				return err
+			}
+		}
+		if _, err := c.Lookup(ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lookup returns the current value of ref, which must have been resolved
+func (c *Client) Lookup(ref string) (string, error) {
+	path, field, err := parseReference(ref)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.RLock()
+	s, ok := c.secrets[path]
+	c.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("Vault secret %s was not resolved at startup", path)
+	}
+
+	switch value := s.data[field].(type) {
+	case string:
+		return value, nil
+	case nil:
+		return "", fmt.Errorf("Vault secret %s has no field %q", path, field)
+	default:
+		return "", fmt.Errorf("field %q of Vault secret %s is not a string", field, path)
+	}
+}
+
+// Start keeps the token and secrets current, checking every refresh interval,
+// until Stop is called
+func (c *Client) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.done = make(chan struct{})
+
+	go func() {
+		defer close(c.done)
+
+		ticker := time.NewTicker(c.cfg.RefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.Refresh(ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops keeping secrets current
+func (c *Client) Stop() {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.done
+	}
+}
+
+// Refresh renews the token and the leases expiring before the next check, and
+// re-reads secrets without a lease and those whose lease can't be renewed. Secrets
+// that fail to refresh keep their current value.
+func (c *Client) Refresh(ctx context.Context) {
+	c.mu.RLock()
+	tokenRenewable := c.tokenRenewable
+	secrets := make(map[string]*secret, len(c.secrets))
+	for path, s := range c.secrets {
+		secrets[path] = s
+	}
+	c.mu.RUnlock()
+
+	if tokenRenewable {
+		if err := c.do(ctx, http.MethodPost, "auth/token/renew-self", struct{}{}, nil); err != nil {
+			c.logger.Warn().Err(err).Msg("Failed to renew Vault token")
+		}
+	}
+
+	// Leases must outlive the next check
+	renewBefore := time.Now().Add(2 * c.cfg.RefreshInterval)
+	for path, s := range secrets {
+		if s.leaseID != "" && s.expiresAt.After(renewBefore) {
+			continue
+		}
+		if s.leaseID != "" && s.renewable {
+			expiresAt, err := c.renew(ctx, path, s)
+			if err == nil && expiresAt.After(renewBefore) {
+				continue
+			}
+			if err != nil {
+				c.logger.Warn().Err(err).Str("path", path).Msg("Failed to renew Vault lease, reading new credentials")
+			}
+		}
+
+		if err := c.read(ctx, path); err != nil {
+			c.logger.Error().Err(err).
+				Str("path", path).
+				Time("lease_expires_at", s.expiresAt).
+				Msg("Failed to refresh Vault secret, keeping the current credentials")
+		}
+	}
+}
+
+// read reads the secret at path, replacing the current one
+func (c *Client) read(ctx context.Context, path string) error {
+	var resp struct {
+		LeaseID       string         `json:"lease_id"`
+		Renewable     bool           `json:"renewable"`
+		LeaseDuration int64          `json:"lease_duration"`
+		Data          map[string]any `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return fmt.Errorf("failed to read Vault secret %s: %w", path, err)
+	}
+
+	// KV v2 nests the secret's fields next to its metadata
+	data := resp.Data
+	if nested, ok := data["data"].(map[string]any); ok {
+		if _, ok := data["metadata"].(map[string]any); ok {
+			data = nested
+		}
+	}
+
+	s := &secret{data: data, leaseID: resp.LeaseID, renewable: resp.Renewable}
+	if resp.LeaseID != "" {
+		s.expiresAt = time.Now().Add(time.Duration(resp.LeaseDuration) * time.Second)
+	}
+
+	c.mu.Lock()
+	previous := c.secrets[path]
+	c.secrets[path] = s
+	c.mu.Unlock()
+
+	if previous != nil {
+		c.logger.Debug().Str("path", path).Bool("leased", s.leaseID != "").Msg("Refreshed Vault secret")
+	}
+	return nil
+}
+
+// renew renews the lease of s, the secret at path, and returns its new expiry
+func (c *Client) renew(ctx context.Context, path string, s *secret) (time.Time, error) {
+	var resp struct {
+		LeaseDuration int64 `json:"lease_duration"`
+	}
+	body := map[string]any{"lease_id": s.leaseID, "increment": int64(2 * c.cfg.RefreshInterval / time.Second)}
+	if err := c.do(ctx, http.MethodPut, "sys/leases/renew", body, &resp); err != nil {
+		return time.Time{}, err
+	}
+
+	renewed := *s
+	renewed.expiresAt = time.Now().Add(time.Duration(resp.LeaseDuration) * time.Second)
+
+	c.mu.Lock()
+	if c.secrets[path] == s {
+		c.secrets[path] = &renewed
+	}
+	c.mu.Unlock()
+	return renewed.expiresAt, nil
+}
+
+// token returns the Vault token, re-reading the token file
+func (c *Client) token() (string, error) {
+	if c.cfg.TokenFile == "" {
+		return c.cfg.Token, nil
+	}
+	token, err := os.ReadFile(c.cfg.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Vault token file: %w", err)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// do sends a request to the Vault API at path (without /v1/), encoding body and
+// decoding the response into out if they aren't nil
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	token, err := c.token()
+	if err != nil {
+		return err
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.cfg.Address, "/")+"/v1/"+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	req.Header.Set("X-Vault-Request", "true")
+	if c.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.cfg.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.Unmarshal(respBody, &vaultErr)
+		if len(vaultErr.Errors) > 0 {
+			return fmt.Errorf("%s: %s", resp.Status, strings.Join(vaultErr.Errors, "; "))
+		}
+		return fmt.Errorf("%s", resp.Status)
+	}
+
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("invalid Vault response: %w", err)
+		}
+	}
+	return nil
+}
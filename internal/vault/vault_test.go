@@ -0,0 +1,215 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mainuli/artifusion/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// fakeVault serves a KV v2 secret at secret/data/nexus and a dynamic secret with
+// a lease at database/creds/registry to the token "hvs.test"
+type fakeVault struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	kvPassword    string
+	dynamicReads  int
+	renewals      int
+	leaseDuration int64 // Granted on renewal
+	renewable     bool
+	tokenRenewals int
+}
+
+func newFakeVault(t *testing.T) *fakeVault {
+	t.Helper()
+	f := &fakeVault{kvPassword: "first", leaseDuration: 3600, renewable: true}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if r.Header.Get("X-Vault-Token") != "hvs.test" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		var resp any
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			resp = map[string]any{"data": map[string]any{"renewable": true, "ttl": 3600}}
+		case "/v1/auth/token/renew-self":
+			f.tokenRenewals++
+			resp = map[string]any{"auth": map[string]any{"lease_duration": 3600}}
+		case "/v1/secret/data/nexus":
+			resp = map[string]any{"data": map[string]any{
+				"data":     map[string]any{"username": "deployer", "password": f.kvPassword},
+				"metadata": map[string]any{"version": 1},
+			}}
+		case "/v1/database/creds/registry":
+			f.dynamicReads++
+			resp = map[string]any{
+				"lease_id":       fmt.Sprintf("database/creds/registry/%d", f.dynamicReads),
+				"renewable":      f.renewable,
+				"lease_duration": 60,
+				"data":           map[string]any{"username": fmt.Sprintf("v-registry-%d", f.dynamicReads), "password": "secret"},
+			}
+		case "/v1/sys/leases/renew":
+			var body struct {
+				LeaseID string `json:"lease_id"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			f.renewals++
+			resp = map[string]any{"lease_id": body.LeaseID, "renewable": true, "lease_duration": f.leaseDuration}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestClient_Resolve(t *testing.T) {
+	f := newFakeVault(t)
+	client := New(&config.VaultConfig{Address: f.URL, Token: "hvs.test", RefreshInterval: time.Minute}, zerolog.Nop())
+
+	refs := []string{"vault:secret/data/nexus#username", "vault:secret/data/nexus#password", "vault:database/creds/registry#username"}
+	if err := client.Resolve(context.Background(), refs); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	for ref, want := range map[string]string{
+		"vault:secret/data/nexus#username":       "deployer",
+		"vault:/secret/data/nexus#password":      "first",
+		"vault:database/creds/registry#username": "v-registry-1",
+	} {
+		if got, err := client.Lookup(ref); err != nil || got != want {
+			t.Errorf("Lookup(%q) = %q, %v, want %q", ref, got, err, want)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		ref    string
+		token  string
+		errMsg string
+	}{
+		{name: "missing field", ref: "vault:secret/data/nexus#token", errMsg: `has no field "token"`},
+		{name: "missing secret", ref: "vault:secret/data/other#password", errMsg: "404"},
+		{name: "invalid reference", ref: "vault:secret/data/nexus", errMsg: "want vault:<path>#<field>"},
+		{name: "invalid token", ref: "vault:secret/data/nexus#password", token: "hvs.other", errMsg: "permission denied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := "hvs.test"
+			if tt.token != "" {
+				token = tt.token
+			}
+			client := New(&config.VaultConfig{Address: f.URL, Token: token, RefreshInterval: time.Minute}, zerolog.Nop())
+			if err := client.Resolve(context.Background(), []string{tt.ref}); err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Resolve() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+
+	if _, err := client.Lookup("vault:secret/data/unresolved#password"); err == nil {
+		t.Error("Lookup() of an unresolved secret succeeded, want error")
+	}
+}
+
+func TestClient_Refresh(t *testing.T) {
+	f := newFakeVault(t)
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("hvs.test\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	client := New(&config.VaultConfig{Address: f.URL, TokenFile: tokenFile, RefreshInterval: time.Minute}, zerolog.Nop())
+
+	refs := []string{"vault:secret/data/nexus#password", "vault:database/creds/registry#username"}
+	if err := client.Resolve(context.Background(), refs); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	lookup := func(ref string) string {
+		t.Helper()
+		value, err := client.Lookup(ref)
+		if err != nil {
+			t.Fatalf("Lookup(%q) error = %v", ref, err)
+		}
+		return value
+	}
+
+	// The KV secret is rotated, and the lease (60s) expires before the next check:
+	// it's renewed for long enough
+	f.mu.Lock()
+	f.kvPassword = "second"
+	f.mu.Unlock()
+	client.Refresh(context.Background())
+	if got := lookup("vault:secret/data/nexus#password"); got != "second" {
+		t.Errorf("password = %q, want the rotated password", got)
+	}
+	if got := lookup("vault:database/creds/registry#username"); got != "v-registry-1" || f.renewals != 1 {
+		t.Errorf("username = %q after %d renewals, want the renewed credentials", got, f.renewals)
+	}
+
+	// The lease outlives the next check: left alone
+	client.Refresh(context.Background())
+	if f.renewals != 1 || f.dynamicReads != 1 {
+		t.Errorf("renewals = %d, reads = %d, want the lease left alone", f.renewals, f.dynamicReads)
+	}
+
+	// Renewals capped at the max TTL: new credentials are read
+	f.mu.Lock()
+	f.leaseDuration = 30
+	f.mu.Unlock()
+	client.mu.Lock()
+	for path, s := range client.secrets {
+		if s.leaseID != "" {
+			expiring := *s
+			expiring.expiresAt = time.Now().Add(time.Minute)
+			client.secrets[path] = &expiring
+		}
+	}
+	client.mu.Unlock()
+	client.Refresh(context.Background())
+	if got := lookup("vault:database/creds/registry#username"); got != "v-registry-2" {
+		t.Errorf("username = %q, want new credentials once the lease can't be renewed", got)
+	}
+
+	// Tokens from a file are renewed by whoever writes the file
+	if f.tokenRenewals != 0 {
+		t.Errorf("token renewals = %d, want 0 for a token file", f.tokenRenewals)
+	}
+
+	// Vault unavailable: the current credentials are kept
+	f.Close()
+	client.Refresh(context.Background())
+	if got := lookup("vault:secret/data/nexus#password"); got != "second" {
+		t.Errorf("password = %q after a failed refresh, want the current password kept", got)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	auth := &config.AuthConfig{
+		Type:     "basic",
+		Username: "deployer",
+		Password: "vault:secret/data/nexus#password",
+		Token:    "vault:secret/data/nexus#token",
+	}
+	got := References(auth)
+	if len(got) != 2 || got[0] != auth.Password || got[1] != auth.Token {
+		t.Errorf("References() = %v, want the password and token references", got)
+	}
+}